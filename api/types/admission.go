@@ -0,0 +1,31 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// AdmissionError is returned when a job is rejected instead of queued, because the data sources which could execute it
+// are all rate limited or out of quota, so it would fail or time out. RetryAfter is when they are expected to be
+// available again, or zero if that is not known.
+type AdmissionError struct {
+	Reason     string
+	RetryAfter time.Duration
+}
+
+func (e *AdmissionError) Error() string {
+	if e.RetryAfter <= 0 {
+		return fmt.Sprintf("job not admitted: %s", e.Reason)
+	}
+	return fmt.Sprintf("job not admitted: %s, retry after %ds", e.Reason, e.RetryAfterSeconds())
+}
+
+// RetryAfterSeconds returns RetryAfter in whole seconds as used by the Retry-After header, rounded up so that clients
+// don't retry too early. It is zero if RetryAfter is not known.
+func (e *AdmissionError) RetryAfterSeconds() int {
+	if e.RetryAfter <= 0 {
+		return 0
+	}
+	return int(math.Ceil(e.RetryAfter.Seconds()))
+}
@@ -0,0 +1,44 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"slices"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// MaxAttestationNonceLength is the maximum length of the nonce of an attestation request
+const MaxAttestationNonceLength = 256
+
+// AttestationResponse is returned by the attestation endpoint. Quote is a fresh SGX quote, encoded in base64, whose
+// report data is ReportData, i.e. AttestationReportData of the worker ID, the capabilities hash and the nonce. A
+// verifier checks the quote, recomputes the report data from the nonce it sent and compares both.
+type AttestationResponse struct {
+	WorkerID         string `json:"worker_id"`
+	CapabilitiesHash string `json:"capabilities_hash"`
+	Nonce            string `json:"nonce"`
+	ReportData       string `json:"report_data"`
+	Quote            string `json:"quote"`
+}
+
+// CapabilitiesHash returns the hex encoded SHA-256 hash of the capabilities, independent of the order of the
+// capabilities of each job type
+func CapabilitiesHash(caps teetypes.WorkerCapabilities) string {
+	sorted := make(teetypes.WorkerCapabilities, len(caps))
+	for jobType, jobCaps := range caps {
+		sorted[jobType] = slices.Sorted(slices.Values(jobCaps))
+	}
+	// Maps are encoded with sorted keys, so the encoding is canonical
+	data, _ := json.Marshal(sorted)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// AttestationReportData returns the report data embedded in the quote of an attestation: the SHA-256 hash of the
+// worker ID, the capabilities hash and the nonce, separated by newlines
+func AttestationReportData(workerID, capabilitiesHash, nonce string) []byte {
+	sum := sha256.Sum256([]byte(workerID + "\n" + capabilitiesHash + "\n" + nonce))
+	return sum[:]
+}
@@ -0,0 +1,24 @@
+package types_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+var _ = Describe("CapabilitiesHash", func() {
+	It("should not depend on the order of the capabilities", func() {
+		a := teetypes.WorkerCapabilities{teetypes.WebJob: {teetypes.CapScraper, "other"}, teetypes.TelemetryJob: {teetypes.CapTelemetry}}
+		b := teetypes.WorkerCapabilities{teetypes.TelemetryJob: {teetypes.CapTelemetry}, teetypes.WebJob: {"other", teetypes.CapScraper}}
+		Expect(types.CapabilitiesHash(a)).To(Equal(types.CapabilitiesHash(b)))
+		Expect(types.CapabilitiesHash(a)).To(HaveLen(64))
+	})
+
+	It("should change when the capabilities change", func() {
+		a := teetypes.WorkerCapabilities{teetypes.WebJob: {teetypes.CapScraper}}
+		b := teetypes.WorkerCapabilities{teetypes.WebJob: {teetypes.CapScraper}, teetypes.TelemetryJob: {teetypes.CapTelemetry}}
+		Expect(types.CapabilitiesHash(a)).NotTo(Equal(types.CapabilitiesHash(b)))
+	})
+})
@@ -0,0 +1,100 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// AuditEvent is what happened to a job recorded in an AuditEntry
+type AuditEvent string
+
+const (
+	// AuditJobAccepted is recorded when a job is accepted by the worker, i.e. when /job/add returns its UUID
+	AuditJobAccepted AuditEvent = "accepted"
+	// AuditJobFinished is recorded when the result of a job is stored, once for every run of a recurring job
+	AuditJobFinished AuditEvent = "finished"
+)
+
+// MaxAuditEntries is the maximum number of entries returned by a single request for the audit log
+const MaxAuditEntries = 1000
+
+// AuditEntry is an entry of the audit log of a worker. Entries are numbered from 1, and chained with the hash of the
+// previous entry, so an entry can't be removed, changed or reordered without breaking the chain.
+type AuditEntry struct {
+	Seq        uint64              `json:"seq"`
+	Time       time.Time           `json:"time"`
+	Event      AuditEvent          `json:"event"`
+	JobUUID    string              `json:"job_uuid"`
+	JobType    teetypes.JobType    `json:"job_type"`
+	Capability teetypes.Capability `json:"capability,omitempty"`
+	// Requester is the worker ID of the miner which submitted the job
+	Requester  string     `json:"requester"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// ResultHash is the hex encoded SHA-256 hash of the data of the result, before it was sealed
+	ResultHash string `json:"result_hash,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Cached     bool   `json:"cached,omitempty"`
+	PrevHash   string `json:"prev_hash"`
+	Hash       string `json:"hash"`
+}
+
+// ComputeHash returns the hex encoded SHA-256 hash of the entry, which covers all of its fields but Hash, including
+// PrevHash
+func (e AuditEntry) ComputeHash() string {
+	e.Hash = ""
+	data, _ := json.Marshal(e)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// VerifyAuditChain checks that the entries are consecutive, that each one is chained to the one before, the first one
+// to prevHash, and that their hashes match their content
+func VerifyAuditChain(prevHash string, entries []AuditEntry) error {
+	for i, e := range entries {
+		if i > 0 && e.Seq != entries[i-1].Seq+1 {
+			return fmt.Errorf("entry %d follows entry %d", e.Seq, entries[i-1].Seq)
+		}
+		if e.PrevHash != prevHash {
+			return fmt.Errorf("entry %d is not chained to the previous entry", e.Seq)
+		}
+		if e.ComputeHash() != e.Hash {
+			return fmt.Errorf("hash of entry %d does not match its content", e.Seq)
+		}
+		prevHash = e.Hash
+	}
+	return nil
+}
+
+// AuditLog is a range of the audit log of a worker. Verified is true if the chain of the log up to the last entry of
+// the range is intact, otherwise Error tells where it is broken. If the range was requested with a nonce in
+// enclave mode, Quote is an SGX quote encoded in base64 whose report data is AuditReportData of the worker ID, Last,
+// LastHash and the nonce, so the worker vouches for the last entry of the range and, through the chain, for all the
+// entries before it.
+type AuditLog struct {
+	Entries []AuditEntry `json:"entries"`
+	// Head is the number of entries in the log
+	Head uint64 `json:"head"`
+	// Last and LastHash are the number and the hash of the last entry of the range, which is not in Entries if it is
+	// the entry of another requester
+	Last     uint64 `json:"last"`
+	LastHash string `json:"last_hash,omitempty"`
+	Verified bool   `json:"verified"`
+	Error    string `json:"error,omitempty"`
+	WorkerID string `json:"worker_id,omitempty"`
+	Nonce    string `json:"nonce,omitempty"`
+	Quote    string `json:"quote,omitempty"`
+}
+
+// AuditReportData returns the report data embedded in the quote of a range of the audit log: the SHA-256 hash of the
+// worker ID, the number and the hash of the last entry of the range, and the nonce, separated by newlines
+func AuditReportData(workerID string, seq uint64, hash, nonce string) []byte {
+	sum := sha256.Sum256([]byte(workerID + "\n" + strconv.FormatUint(seq, 10) + "\n" + hash + "\n" + nonce))
+	return sum[:]
+}
@@ -0,0 +1,50 @@
+package types_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+var _ = Describe("VerifyAuditChain", func() {
+	var entries []types.AuditEntry
+
+	BeforeEach(func() {
+		entries = nil
+		prevHash := ""
+		for i, event := range []types.AuditEvent{types.AuditJobAccepted, types.AuditJobFinished, types.AuditJobAccepted} {
+			e := types.AuditEntry{
+				Seq:       uint64(i + 1),
+				Time:      time.Date(2025, 1, 1, 0, i, 0, 0, time.UTC),
+				Event:     event,
+				JobUUID:   "job",
+				Requester: "miner",
+				PrevHash:  prevHash,
+			}
+			e.Hash = e.ComputeHash()
+			prevHash = e.Hash
+			entries = append(entries, e)
+		}
+	})
+
+	It("should accept an intact chain", func() {
+		Expect(types.VerifyAuditChain("", entries)).To(Succeed())
+		Expect(types.VerifyAuditChain(entries[0].Hash, entries[1:])).To(Succeed())
+	})
+
+	It("should reject a changed entry", func() {
+		entries[1].Error = "failed"
+		Expect(types.VerifyAuditChain("", entries)).To(MatchError(ContainSubstring("hash of entry 2")))
+	})
+
+	It("should reject a removed entry", func() {
+		Expect(types.VerifyAuditChain("", []types.AuditEntry{entries[0], entries[2]})).To(HaveOccurred())
+	})
+
+	It("should reject a chain which doesn't follow the given hash", func() {
+		Expect(types.VerifyAuditChain("", entries[1:])).To(MatchError(ContainSubstring("not chained")))
+	})
+})
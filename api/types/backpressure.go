@@ -0,0 +1,24 @@
+package types
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// QueueMetrics is how busy the interactive queue of a worker is, reported in the telemetry and readiness payloads so
+// that schedulers can route jobs to the workers which can execute them soonest
+type QueueMetrics struct {
+	// Depth is the number of jobs waiting to be executed, including those deferred for lack of a slot or of memory
+	Depth int `json:"depth"`
+	// Running is the number of jobs being executed
+	Running int `json:"running"`
+	// Workers is the number of jobs the worker executes at the same time
+	Workers int `json:"workers"`
+	// WaitEstimatesMs is the estimated time a new job of each job type and capability would wait before being
+	// executed, from the queued jobs and the median duration of the recent jobs
+	WaitEstimatesMs map[teetypes.JobType]map[string]int64 `json:"wait_estimates_ms"`
+	// Backpressure is true if the queue exceeds the thresholds of the worker, see QUEUE_BACKPRESSURE_DEPTH and
+	// QUEUE_BACKPRESSURE_WAIT_SECONDS. Interactive jobs submitted meanwhile are rejected with a Retry-After.
+	Backpressure bool `json:"backpressure"`
+	// RetryAfterSeconds is when jobs are expected to be admitted again while Backpressure is true
+	RetryAfterSeconds int `json:"retry_after_seconds,omitempty"`
+}
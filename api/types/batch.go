@@ -0,0 +1,52 @@
+package types
+
+// BatchRequest submits several jobs, each encrypted like the job of a JobRequest, in a single request
+type BatchRequest struct {
+	Jobs []JobRequest `json:"jobs"`
+}
+
+// BatchJob is the outcome of submitting a single job of a batch. Either UID or Error is set.
+type BatchJob struct {
+	UID    string    `json:"uid,omitempty"`
+	Cached bool      `json:"cached,omitempty"` // True if the job was answered with the result of an identical earlier job
+	Error  *JobError `json:"error,omitempty"`
+}
+
+// BatchResponse is returned when a batch is submitted. Jobs has an entry for every submitted job, in the order they
+// were submitted. BatchID is empty if none of the jobs was accepted.
+type BatchResponse struct {
+	BatchID string     `json:"batch_id,omitempty"`
+	Jobs    []BatchJob `json:"jobs"`
+}
+
+// BatchJobState is the state of a job of a batch
+type BatchJobState string
+
+const (
+	// BatchJobPending means the job has been accepted but has no result yet
+	BatchJobPending BatchJobState = "pending"
+	// BatchJobSucceeded means the job has a successful result
+	BatchJobSucceeded BatchJobState = "succeeded"
+	// BatchJobFailed means the result of the job is an error
+	BatchJobFailed BatchJobState = "failed"
+	// BatchJobExpired means the job has finished, but its result is not available anymore, e.g. because it expired
+	// from the result cache or it was submitted with no-store and has been read
+	BatchJobExpired BatchJobState = "expired"
+)
+
+// BatchJobStatus is the state of a single job of a batch
+type BatchJobStatus struct {
+	UID   string        `json:"uid"`
+	State BatchJobState `json:"state"`
+}
+
+// BatchStatus is the aggregate status of the jobs of a batch. The counts add up to Total.
+type BatchStatus struct {
+	BatchID   string           `json:"batch_id"`
+	Total     int              `json:"total"`
+	Pending   int              `json:"pending"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Expired   int              `json:"expired"`
+	Jobs      []BatchJobStatus `json:"jobs"`
+}
@@ -0,0 +1,37 @@
+package types
+
+import "time"
+
+// BenchmarkStatus is the outcome of a benchmark workload
+type BenchmarkStatus string
+
+const (
+	// BenchmarkOK means the workload ran
+	BenchmarkOK BenchmarkStatus = "ok"
+	// BenchmarkFailed means the workload could not run, e.g. because a provider was unreachable
+	BenchmarkFailed BenchmarkStatus = "failed"
+	// BenchmarkNotConfigured means the workload measures a provider the worker is not configured to use
+	BenchmarkNotConfigured BenchmarkStatus = "not_configured"
+)
+
+// BenchmarkResult is the result of a single benchmark workload. Local workloads report their throughput, outbound
+// workloads the latency of the provider.
+type BenchmarkResult struct {
+	Name         string          `json:"name"`
+	Status       BenchmarkStatus `json:"status"`
+	Error        string          `json:"error,omitempty"`
+	OpsPerSecond float64         `json:"ops_per_second,omitempty"`
+	LatencyMs    int64           `json:"latency_ms,omitempty"`
+}
+
+// BenchmarkReport is the outcome of a self-benchmark of the worker. Score is the throughput of the local workloads
+// relative to a reference machine, which scores 100. Capacity scales the score by the number of jobs the worker
+// executes concurrently, so schedulers can compare workers of different sizes.
+type BenchmarkReport struct {
+	Score      float64           `json:"score"`
+	Capacity   float64           `json:"capacity"`
+	MaxJobs    int               `json:"max_jobs"`
+	Results    []BenchmarkResult `json:"results"`
+	StartedAt  time.Time         `json:"started_at"`
+	DurationMs int64             `json:"duration_ms"`
+}
@@ -0,0 +1,77 @@
+package types
+
+import (
+	"errors"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// ErrNotConfigured is returned when probing a dependency which the worker is not configured to use
+var ErrNotConfigured = errors.New("not configured")
+
+// AuthSource is the kind of authentication a worker uses to provide a capability
+type AuthSource string
+
+const (
+	AuthSourceNone       AuthSource = "none"
+	AuthSourceCredential AuthSource = "credential"
+	AuthSourceAPI        AuthSource = "api"
+	AuthSourceApify      AuthSource = "apify"
+	// AuthSourceSyndication is the public endpoints behind embeddable widgets, which need no authentication
+	AuthSourceSyndication AuthSource = "syndication"
+)
+
+// LowerTrust returns true if the data obtained through the source is less trustworthy than data fetched with
+// credentials, e.g. because it comes from a public CDN cache which can be stale or incomplete
+func (s AuthSource) LowerTrust() bool {
+	return s == AuthSourceSyndication
+}
+
+// RateLimitEstimate is the estimated number of requests a worker can make for a capability within a time window.
+// It takes into account the number of configured accounts or API keys, but not their current usage.
+type RateLimitEstimate struct {
+	Requests      int `json:"requests"`
+	WindowSeconds int `json:"window_seconds"`
+}
+
+// CapabilityDetail describes how a worker provides a single capability. A capability which can be provided
+// through several auth sources has one CapabilityDetail per auth source.
+type CapabilityDetail struct {
+	Capability  teetypes.Capability `json:"capability"`
+	AuthSource  AuthSource          `json:"auth_source"`
+	RateLimit   *RateLimitEstimate  `json:"rate_limit,omitempty"`
+	FullArchive bool                `json:"full_archive"`
+	// Priority is the position of the auth source in the order in which the job type tries them, starting at 1. It is
+	// only set for capabilities which the job type can provide through more than one auth source.
+	Priority int `json:"priority,omitempty"`
+}
+
+// CapabilityDetails maps each job type to the details of its capabilities
+type CapabilityDetails map[teetypes.JobType][]CapabilityDetail
+
+// NewCapabilityDetails returns the details for capabilities which are all provided through the same auth source
+func NewCapabilityDetails(caps teetypes.WorkerCapabilities, source AuthSource) CapabilityDetails {
+	details := make(CapabilityDetails, len(caps))
+	for jobType, jobCaps := range caps {
+		for _, c := range jobCaps {
+			details[jobType] = append(details[jobType], CapabilityDetail{Capability: c, AuthSource: source})
+		}
+	}
+	return details
+}
+
+// KeyCapabilities is the capability matrix of a single provider API key: its tier and the endpoints it has access
+// to. The key is identified by a fingerprint, never by the key itself.
+type KeyCapabilities struct {
+	Provider    string          `json:"provider"`
+	Fingerprint string          `json:"fingerprint"`
+	Tier        string          `json:"tier"`
+	Endpoints   map[string]bool `json:"endpoints"`
+}
+
+// CapabilitiesResponse is returned by the capabilities endpoint
+type CapabilitiesResponse struct {
+	WorkerID     string                      `json:"worker_id"`
+	Capabilities teetypes.WorkerCapabilities `json:"capabilities"`
+	Details      CapabilityDetails           `json:"details"`
+}
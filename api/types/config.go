@@ -0,0 +1,16 @@
+package types
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// ConfigResponse is returned by the config endpoint. Configuration is the effective job configuration of the worker
+// with its credentials redacted: lists of accounts and keys are reported as <key>_count, single keys as
+// <key>_configured, and durations in seconds. RetiredWorkerIDs are the worker IDs the worker had before its worker ID
+// was rotated.
+type ConfigResponse struct {
+	WorkerID         string             `json:"worker_id"`
+	RetiredWorkerIDs []RetiredWorkerID  `json:"retired_worker_ids,omitempty"`
+	JobTypes         []teetypes.JobType `json:"job_types"`
+	Configuration    map[string]any     `json:"configuration"`
+}
@@ -0,0 +1,47 @@
+package types
+
+import "sync"
+
+// ApifyCost is what Apify actor runs cost. It is reported in telemetry, aggregated by job type and capability, so
+// operators can budget their Apify spend.
+type ApifyCost struct {
+	Runs         uint    `json:"runs"`
+	ComputeUnits float64 `json:"compute_units"`
+	DatasetReads uint    `json:"dataset_reads"`
+	UsageUSD     float64 `json:"usage_usd"`
+}
+
+// Add adds the cost of other runs
+func (c *ApifyCost) Add(other ApifyCost) {
+	c.Runs += other.Runs
+	c.ComputeUnits += other.ComputeUnits
+	c.DatasetReads += other.DatasetReads
+	c.UsageUSD += other.UsageUSD
+}
+
+// ApifyCostRecorder collects the cost of the Apify actor runs of a job while it is executed. It is safe for
+// concurrent use, and a nil recorder records nothing.
+type ApifyCostRecorder struct {
+	mu   sync.Mutex
+	cost ApifyCost
+}
+
+// AddRun records the cost of a finished actor run
+func (r *ApifyCostRecorder) AddRun(computeUnits float64, datasetReads uint, usageUSD float64) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cost.Add(ApifyCost{Runs: 1, ComputeUnits: computeUnits, DatasetReads: datasetReads, UsageUSD: usageUSD})
+}
+
+// Cost returns the total cost of the recorded runs
+func (r *ApifyCostRecorder) Cost() ApifyCost {
+	if r == nil {
+		return ApifyCost{}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.cost
+}
@@ -0,0 +1,76 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// NextCursorArgumentKey is the job argument holding the cursor of the page to start from, as returned in the
+// NextCursor of the result of the previous page
+const NextCursorArgumentKey = "next_cursor"
+
+// NextCursorVersion is the version of the NextCursor envelope produced by this worker. Cursors of other versions are
+// rejected.
+const NextCursorVersion = 1
+
+// nextCursorSalt is the salt the NextCursor envelope is sealed with, so it can't be mistaken for any other sealed data
+const nextCursorSalt = "next-cursor"
+
+var (
+	// ErrInvalidCursor is returned for a next_cursor which was not issued by a worker sharing the key ring, was issued
+	// for another job type or capability, or has an unknown version
+	ErrInvalidCursor = errors.New("invalid next_cursor")
+	// ErrExpiredCursor is returned for a next_cursor which was issued longer ago than its time to live
+	ErrExpiredCursor = errors.New("expired next_cursor")
+)
+
+// NextCursor is the envelope of the cursor returned by a job for its next page. It binds the cursor of the data source
+// to the job type and capability which issued it, and is sealed so clients can't tamper with it.
+type NextCursor struct {
+	Version    int                 `json:"v"`
+	JobType    teetypes.JobType    `json:"job_type"`
+	Capability teetypes.Capability `json:"capability"`
+	// Cursor is the cursor of the data source, e.g. a Twitter cursor or the offset in an Apify dataset
+	Cursor string `json:"cursor"`
+	// Offset is the number of items of the page of Cursor which were already returned, by a result which was truncated
+	Offset   int       `json:"offset,omitempty"`
+	IssuedAt time.Time `json:"issued_at"`
+}
+
+// Seal returns the sealed cursor, which is passed as the next_cursor argument by clients
+func (c NextCursor) Seal() (string, error) {
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling next cursor: %w", err)
+	}
+	return tee.SealWithKey(nextCursorSalt, data)
+}
+
+// OpenNextCursor unseals a cursor returned by NextCursor.Seal, and checks that it was issued for the given job type and
+// capability at most ttl ago. A ttl of 0 means that the cursor does not expire.
+func OpenNextCursor(sealed string, jobType teetypes.JobType, capability teetypes.Capability, ttl time.Duration, now time.Time) (NextCursor, error) {
+	var c NextCursor
+	data, err := tee.UnsealWithKey(nextCursorSalt, sealed)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, ErrInvalidCursor
+	}
+	switch {
+	case c.Version != NextCursorVersion:
+		return c, fmt.Errorf("%w: unsupported version %d", ErrInvalidCursor, c.Version)
+	case c.JobType != jobType:
+		return c, fmt.Errorf("%w: issued for %s jobs", ErrInvalidCursor, c.JobType)
+	case c.Capability != capability:
+		return c, fmt.Errorf("%w: issued for capability %s", ErrInvalidCursor, c.Capability)
+	case ttl > 0 && now.Sub(c.IssuedAt) > ttl:
+		return c, fmt.Errorf("%w: issued at %s", ErrExpiredCursor, c.IssuedAt.UTC().Format(time.RFC3339))
+	}
+	return c, nil
+}
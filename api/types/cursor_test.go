@@ -0,0 +1,71 @@
+package types_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("NextCursor", func() {
+	issuedAt := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var sealed string
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		tee.SealStandaloneMode = false
+
+		var err error
+		sealed, err = types.NextCursor{
+			Version:    types.NextCursorVersion,
+			JobType:    teetypes.TwitterApiJob,
+			Capability: "searchbyquery",
+			Cursor:     "provider-cursor",
+			IssuedAt:   issuedAt,
+		}.Seal()
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should return the cursor of the data source of a valid cursor", func() {
+		c, err := types.OpenNextCursor(sealed, teetypes.TwitterApiJob, "searchbyquery", time.Hour, issuedAt.Add(time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Cursor).To(Equal("provider-cursor"))
+		Expect(c.IssuedAt).To(BeTemporally("==", issuedAt))
+	})
+
+	It("should not expire cursors without a time to live", func() {
+		_, err := types.OpenNextCursor(sealed, teetypes.TwitterApiJob, "searchbyquery", 0, issuedAt.AddDate(1, 0, 0))
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("should reject expired cursors", func() {
+		_, err := types.OpenNextCursor(sealed, teetypes.TwitterApiJob, "searchbyquery", time.Hour, issuedAt.Add(2*time.Hour))
+		Expect(err).To(MatchError(types.ErrExpiredCursor))
+	})
+
+	It("should reject cursors issued for another job type or capability", func() {
+		_, err := types.OpenNextCursor(sealed, teetypes.RedditJob, "searchbyquery", time.Hour, issuedAt)
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+
+		_, err = types.OpenNextCursor(sealed, teetypes.TwitterApiJob, "getfollowers", time.Hour, issuedAt)
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+	})
+
+	It("should reject cursors which were not sealed by a worker", func() {
+		_, err := types.OpenNextCursor("eyJvZmZzZXQiOjEwMH0=", teetypes.TwitterApiJob, "searchbyquery", time.Hour, issuedAt)
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+	})
+
+	It("should reject cursors of another version", func() {
+		sealed, err := types.NextCursor{Version: types.NextCursorVersion + 1, JobType: teetypes.TwitterApiJob, Capability: "searchbyquery", IssuedAt: issuedAt}.Seal()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = types.OpenNextCursor(sealed, teetypes.TwitterApiJob, "searchbyquery", time.Hour, issuedAt)
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+	})
+})
@@ -0,0 +1,35 @@
+package types
+
+import (
+	"encoding/json"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// QueueStatus is the number of jobs a worker has accepted but not finished yet
+type QueueStatus struct {
+	// Pending is the number of jobs without a result, whatever their execution class
+	Pending int `json:"pending"`
+	// Interactive is the number of interactive jobs which are queued or running
+	Interactive int `json:"interactive"`
+	// EconomyQueued is the number of economy jobs waiting for the worker to be idle
+	EconomyQueued int `json:"economy_queued"`
+	// EconomyRunning is the number of economy jobs which have been dispatched to the workers
+	EconomyRunning int `json:"economy_running"`
+	// Recurring is the number of scheduled jobs
+	Recurring int `json:"recurring"`
+	// Deferred is the number of jobs waiting because their job type is at its concurrency limit
+	Deferred int `json:"deferred"`
+	// MemoryDeferred is the number of jobs waiting because running them would exceed MEMORY_CEILING_BYTES
+	MemoryDeferred int `json:"memory_deferred"`
+}
+
+// DashboardOverview is what the standalone dashboard shows about the worker: its queue, its statistics and the
+// arguments accepted by each job type, which are used to build the job submission forms
+type DashboardOverview struct {
+	WorkerID     string                        `json:"worker_id"`
+	Queue        QueueStatus                   `json:"queue"`
+	Capabilities teetypes.WorkerCapabilities   `json:"capabilities"`
+	Arguments    map[teetypes.JobType][]string `json:"arguments"`
+	Stats        json.RawMessage               `json:"stats"`
+}
@@ -0,0 +1,97 @@
+package discord
+
+import "time"
+
+// Types of channels, as named in results. Messages can only be read from text and announcement channels.
+const (
+	ChannelText         = "text"
+	ChannelVoice        = "voice"
+	ChannelCategory     = "category"
+	ChannelAnnouncement = "announcement"
+	ChannelStage        = "stage"
+	ChannelForum        = "forum"
+	ChannelMedia        = "media"
+	ChannelOther        = "other"
+)
+
+// User is the author of a message, or a user mentioned in it
+type User struct {
+	ID         string `json:"id"`
+	Username   string `json:"username"`
+	GlobalName string `json:"global_name,omitempty"`
+	Bot        bool   `json:"bot,omitempty"`
+}
+
+// Attachment is a file attached to a message
+type Attachment struct {
+	ID          string `json:"id"`
+	Filename    string `json:"filename"`
+	URL         string `json:"url"`
+	ContentType string `json:"content_type,omitempty"`
+	Size        int    `json:"size"`
+}
+
+// Embed is the rich content of a message, e.g. the preview of a link
+type Embed struct {
+	Type        string `json:"type,omitempty"`
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	URL         string `json:"url,omitempty"`
+}
+
+// Reaction is the number of times a message was reacted to with an emoji. Emoji is the character of a standard emoji,
+// or the name of a custom emoji of the server.
+type Reaction struct {
+	Emoji string `json:"emoji"`
+	Count int    `json:"count"`
+}
+
+// Message is a message of a channel. Content is Markdown, as returned by the Discord API.
+type Message struct {
+	ID        string `json:"id"`
+	ChannelID string `json:"channel_id"`
+	GuildID   string `json:"guild_id,omitempty"`
+	// URL is the link to the message in the Discord client
+	URL             string       `json:"url"`
+	Author          User         `json:"author"`
+	Content         string       `json:"content"`
+	Timestamp       time.Time    `json:"timestamp"`
+	EditedTimestamp *time.Time   `json:"edited_timestamp,omitempty"`
+	Pinned          bool         `json:"pinned,omitempty"`
+	Mentions        []User       `json:"mentions,omitempty"`
+	Attachments     []Attachment `json:"attachments,omitempty"`
+	Embeds          []Embed      `json:"embeds,omitempty"`
+	Reactions       []Reaction   `json:"reactions,omitempty"`
+	// ReferencedMessageID is the message this one replies to, or which it crossposts for announcement channels
+	ReferencedMessageID string `json:"referenced_message_id,omitempty"`
+}
+
+// Channel is a channel of a server (guild)
+type Channel struct {
+	ID       string `json:"id"`
+	GuildID  string `json:"guild_id,omitempty"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Topic    string `json:"topic,omitempty"`
+	ParentID string `json:"parent_id,omitempty"` // The category of the channel
+	Position int    `json:"position"`
+	NSFW     bool   `json:"nsfw,omitempty"`
+}
+
+// Readable returns true if messages can be read from the channel
+func (c Channel) Readable() bool {
+	return c.Type == ChannelText || c.Type == ChannelAnnouncement
+}
+
+// Guild is a Discord server, with the channels the bot can see
+type Guild struct {
+	ID          string `json:"id"`
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	IconURL     string `json:"icon_url,omitempty"`
+	// MemberCount and PresenceCount are the approximate numbers of members and of members online
+	MemberCount   int       `json:"member_count"`
+	PresenceCount int       `json:"presence_count"`
+	Features      []string  `json:"features"`
+	Channels      []Channel `json:"channels"`
+}
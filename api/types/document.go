@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// Document is an item from any source, normalized to a common schema so results from different sources can be
+// consumed together
+type Document struct {
+	// Source is the source the document was found on, e.g. twitter or reddit
+	Source    string     `json:"source"`
+	ID        string     `json:"id,omitempty"`
+	URL       string     `json:"url,omitempty"`
+	Author    string     `json:"author,omitempty"`
+	Title     string     `json:"title,omitempty"`
+	Text      string     `json:"text"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ResearchSourceStats is what a single source contributed to a research bundle
+type ResearchSourceStats struct {
+	Source string `json:"source"`
+	// Items is the number of documents of the source which are part of the bundle
+	Items int `json:"items"`
+	// Duplicates is the number of documents of the source which were dropped because another document had the same URL or text
+	Duplicates int       `json:"duplicates"`
+	ErrorCode  ErrorCode `json:"error_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMs int64     `json:"duration_ms"`
+}
+
+// ResearchBundle is the result of a research job, the deduplicated documents found on all sources for a topic
+type ResearchBundle struct {
+	Query     string                `json:"query"`
+	Documents []Document            `json:"documents"`
+	Sources   []ResearchSourceStats `json:"sources"`
+}
@@ -3,6 +3,7 @@ package types
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 
 	"github.com/masa-finance/tee-worker/pkg/tee"
 )
@@ -10,6 +11,8 @@ import (
 type EncryptedRequest struct {
 	EncryptedResult  string `json:"encrypted_result"`
 	EncryptedRequest string `json:"encrypted_request"`
+	// ResultFormat is the format of the decrypted result. If empty, it is negotiated with the Accept header.
+	ResultFormat ResultFormat `json:"result_format,omitempty"`
 }
 
 func (payload EncryptedRequest) Unseal() (string, error) {
@@ -32,5 +35,23 @@ func (payload EncryptedRequest) Unseal() (string, error) {
 }
 
 type JobError struct {
-	Error string `json:"error"`
+	Error      string              `json:"error"`
+	FanOut     []FanOutStatus      `json:"fan_out,omitempty"`
+	Usage      *Usage              `json:"usage,omitempty"`
+	Violations []ArgumentViolation `json:"violations,omitempty"`
+	Trace      *JobTrace           `json:"trace,omitempty"`
+	// RetryAfterSeconds is set when the job was not admitted, see AdmissionError, or a quota of the miner was used up
+	RetryAfterSeconds int `json:"retry_after_seconds,omitempty"`
+	// QuotaResetAt is when the quota which was used up resets, see QuotaError
+	QuotaResetAt *time.Time `json:"quota_reset_at,omitempty"`
+	// TimeoutSeconds is the timeout the failed job was executed with, see JOB_TIMEOUT_SECONDS
+	TimeoutSeconds int `json:"timeout_seconds,omitempty"`
+	// Validation is set when none of the items of the result of the job were valid
+	Validation *ResultValidation `json:"validation,omitempty"`
+}
+
+// ArgumentViolation describes a job argument which was rejected before the job was queued
+type ArgumentViolation struct {
+	Argument string `json:"argument"`
+	Reason   string `json:"reason"`
 }
@@ -0,0 +1,44 @@
+package types
+
+import teetypes "github.com/masa-finance/tee-types/types"
+
+// Sources of the expected duration of a JobEstimate
+const (
+	// EstimateFromHistory means the duration is the median of the jobs of the capability which finished within the last
+	// hour, or of the job type if none of the capability did
+	EstimateFromHistory = "history"
+	// EstimateFromDefaults means no job of the type finished within the last hour, so the duration is derived from the
+	// number of API calls and a default latency per call
+	EstimateFromDefaults = "defaults"
+)
+
+// JobEstimate is what a job is expected to cost, as returned by /job/estimate without executing the job, so schedulers
+// can place jobs on the workers which can serve them soonest and most cheaply
+type JobEstimate struct {
+	JobType    teetypes.JobType `json:"job_type"`
+	Capability string           `json:"capability"`
+
+	// ExpectedDurationMs and P90DurationMs are the expected duration of the job and the duration within which 90% of
+	// such jobs finish, see DurationSource. TimeoutSeconds is the timeout the job would be executed with.
+	ExpectedDurationMs int64   `json:"expected_duration_ms"`
+	P90DurationMs      int64   `json:"p90_duration_ms"`
+	DurationSource     string  `json:"duration_source"`
+	ErrorRate          float64 `json:"error_rate"`
+	TimeoutSeconds     int     `json:"timeout_seconds"`
+
+	// APICalls is the estimated number of requests made to the data source, from the number of results the job asks for
+	APICalls int64 `json:"api_calls"`
+	// ApifyComputeUnits and ApifyUsageUSD are estimated from the actor runs of earlier jobs of the capability, and are
+	// left out if no such job started a run
+	ApifyComputeUnits float64 `json:"apify_compute_units,omitempty"`
+	ApifyUsageUSD     float64 `json:"apify_usage_usd,omitempty"`
+
+	// CanServe is false if the job would be rejected if it was submitted now, with the reason and, if known, when it
+	// is expected to be admitted again. Delayed is true if the job would be admitted but wait for a slot of its type.
+	CanServe          bool   `json:"can_serve"`
+	Reason            string `json:"reason,omitempty"`
+	RetryAfterSeconds int    `json:"retry_after_seconds,omitempty"`
+	Delayed           bool   `json:"delayed"`
+	// QueuedJobs is the number of jobs waiting to be executed
+	QueuedJobs int `json:"queued_jobs"`
+}
@@ -0,0 +1,157 @@
+package types
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/masa-finance/tee-worker/pkg/errcode"
+)
+
+// ErrorCode is a machine-readable classification of a job error
+type ErrorCode = errcode.Code
+
+const (
+	ErrorCodeRateLimited     = errcode.RateLimited
+	ErrorCodeAuth            = errcode.Auth
+	ErrorCodeTimeout         = errcode.Timeout
+	ErrorCodeNotFound        = errcode.NotFound
+	ErrorCodeInvalidArgument = errcode.InvalidArgument
+	ErrorCodeUnknown         = errcode.Unknown
+)
+
+// CodedError is an error with the ErrorCode ClassifyError returns for it, see errcode.Error
+type CodedError = errcode.Error
+
+// NewCodedError returns an error with the given code and message, to be compared with errors.Is
+func NewCodedError(code ErrorCode, message string) error {
+	return errcode.New(code, message)
+}
+
+// The messages of errors which are not typed are matched as a last resort. The patterns only match whole words, so
+// e.g. "author not found" is not an authentication error, and an ID containing 404 is not a missing resource.
+var (
+	rateLimitedMessage     = regexp.MustCompile(`\b(rate limit(ed)?|too many requests|429)\b`)
+	authMessage            = regexp.MustCompile(`\b(auth|authentication|authorization|unauthori[sz]ed|unauthenticated|forbidden|credentials|api keys?|401|403)\b`)
+	timeoutMessage         = regexp.MustCompile(`\b(timeout|timed out|deadline exceeded)\b`)
+	notFoundMessage        = regexp.MustCompile(`\b(not found|404)\b`)
+	invalidArgumentMessage = regexp.MustCompile(`\binvalid\b`)
+)
+
+// ClassifyError returns the ErrorCode that best describes err
+func ClassifyError(err error) ErrorCode {
+	var codedErr *CodedError
+	if errors.As(err, &codedErr) {
+		return codedErr.Code
+	}
+	var quotaErr *QuotaError
+	if errors.As(err, &quotaErr) {
+		return ErrorCodeRateLimited
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrJobTimedOut) {
+		return ErrorCodeTimeout
+	}
+	if errors.Is(err, ErrInvalidCursor) || errors.Is(err, ErrExpiredCursor) {
+		return ErrorCodeInvalidArgument
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case rateLimitedMessage.MatchString(msg):
+		return ErrorCodeRateLimited
+	case authMessage.MatchString(msg):
+		return ErrorCodeAuth
+	case timeoutMessage.MatchString(msg):
+		return ErrorCodeTimeout
+	case notFoundMessage.MatchString(msg):
+		return ErrorCodeNotFound
+	case invalidArgumentMessage.MatchString(msg):
+		return ErrorCodeInvalidArgument
+	default:
+		return ErrorCodeUnknown
+	}
+}
+
+// FanOutStatus is the outcome of a single provider call or query within a job that fans out to several of them
+type FanOutStatus struct {
+	Provider  string    `json:"provider,omitempty"`
+	Query     string    `json:"query,omitempty"`
+	Success   bool      `json:"success"`
+	ErrorCode ErrorCode `json:"error_code,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	Items     int       `json:"items"`
+}
+
+// MultiError aggregates the outcome of every leg of a fan-out job, so partial successes are visible to the client
+type MultiError struct {
+	Statuses []FanOutStatus
+}
+
+// Succeeded records a successful leg which returned the given number of items
+func (m *MultiError) Succeeded(provider, query string, items int) {
+	m.Statuses = append(m.Statuses, FanOutStatus{Provider: provider, Query: query, Success: true, Items: items})
+}
+
+// Failed records a failed leg
+func (m *MultiError) Failed(provider, query string, err error) {
+	m.Statuses = append(m.Statuses, FanOutStatus{
+		Provider:  provider,
+		Query:     query,
+		ErrorCode: ClassifyError(err),
+		Error:     err.Error(),
+	})
+}
+
+// AllFailed returns true if at least one leg was recorded and none of them succeeded
+func (m *MultiError) AllFailed() bool {
+	if m == nil || len(m.Statuses) == 0 {
+		return false
+	}
+	for _, s := range m.Statuses {
+		if s.Success {
+			return false
+		}
+	}
+	return true
+}
+
+// HasFailures returns true if any leg failed
+func (m *MultiError) HasFailures() bool {
+	if m == nil {
+		return false
+	}
+	for _, s := range m.Statuses {
+		if !s.Success {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrOrNil returns the MultiError as an error if all legs failed, nil otherwise
+func (m *MultiError) ErrOrNil() error {
+	if m.AllFailed() {
+		return m
+	}
+	return nil
+}
+
+// Error implements the error interface, flattening the failed legs into a single message
+func (m *MultiError) Error() string {
+	msgs := make([]string, 0, len(m.Statuses))
+	for _, s := range m.Statuses {
+		if s.Success {
+			continue
+		}
+		label := s.Provider
+		if s.Query != "" {
+			label = fmt.Sprintf("%s (%s)", s.Provider, s.Query)
+		}
+		msgs = append(msgs, fmt.Sprintf("%s: %s", label, s.Error))
+	}
+	return strings.Join(msgs, "; ")
+}
@@ -0,0 +1,87 @@
+package types_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+var _ = Describe("MultiError", func() {
+	It("should not be an error when at least one leg succeeded", func() {
+		m := &types.MultiError{}
+		m.Failed("credentials", "#AI", errors.New("rate limit exceeded"))
+		m.Succeeded("api", "#AI", 10)
+
+		Expect(m.AllFailed()).To(BeFalse())
+		Expect(m.HasFailures()).To(BeTrue())
+		Expect(m.ErrOrNil()).To(BeNil())
+	})
+
+	It("should aggregate all failures when every leg failed", func() {
+		m := &types.MultiError{}
+		m.Failed("credentials", "#AI", errors.New("rate limit exceeded"))
+		m.Failed("api", "#AI", errors.New("invalid API key"))
+
+		err := m.ErrOrNil()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("credentials (#AI): rate limit exceeded; api (#AI): invalid API key"))
+		Expect(m.Statuses[0].ErrorCode).To(Equal(types.ErrorCodeRateLimited))
+		Expect(m.Statuses[1].ErrorCode).To(Equal(types.ErrorCodeAuth))
+	})
+
+	It("should not be an error when nothing was recorded", func() {
+		var m *types.MultiError
+		Expect(m.ErrOrNil()).To(BeNil())
+		Expect(m.HasFailures()).To(BeFalse())
+	})
+})
+
+var _ = Describe("ClassifyError", func() {
+	DescribeTable("classifies errors",
+		func(msg string, code types.ErrorCode) {
+			Expect(types.ClassifyError(errors.New(msg))).To(Equal(code))
+		},
+		Entry("rate limit", "response status code 429", types.ErrorCodeRateLimited),
+		Entry("auth", "no Twitter accounts or API keys available", types.ErrorCodeAuth),
+		Entry("timeout", "context deadline exceeded", types.ErrorCodeTimeout),
+		Entry("not found", "user not found", types.ErrorCodeNotFound),
+		Entry("unknown", "something broke", types.ErrorCodeUnknown),
+		Entry("a word starting like auth", "author not found", types.ErrorCodeNotFound),
+		Entry("an ID containing a status code", "tweet 1404293 has no poll", types.ErrorCodeUnknown),
+	)
+
+	It("classifies typed errors however they are wrapped", func() {
+		errNotFound := types.NewCodedError(types.ErrorCodeNotFound, "rate limit of the user not found")
+		Expect(types.ClassifyError(fmt.Errorf("error fetching user: %w", errNotFound))).To(Equal(types.ErrorCodeNotFound))
+		Expect(errors.Is(fmt.Errorf("wrapped: %w", errNotFound), errNotFound)).To(BeTrue())
+
+		Expect(types.ClassifyError(fmt.Errorf("request failed: %w", context.DeadlineExceeded))).To(Equal(types.ErrorCodeTimeout))
+		Expect(types.ClassifyError(fmt.Errorf("error fetching tweet 1: %w", types.ErrJobTimedOut))).To(Equal(types.ErrorCodeTimeout))
+		Expect(types.ClassifyError(&types.QuotaError{Miner: "miner1"})).To(Equal(types.ErrorCodeRateLimited))
+	})
+})
+
+var _ = Describe("JobResult", func() {
+	It("should report partial results", func() {
+		res := types.JobResult{FanOut: []types.FanOutStatus{
+			{Provider: "credentials", ErrorCode: types.ErrorCodeRateLimited, Error: "rate limit exceeded"},
+			{Provider: "api", Success: true, Items: 5},
+		}}
+		Expect(res.Partial()).To(BeTrue())
+
+		res.Error = "failed"
+		Expect(res.Partial()).To(BeFalse())
+	})
+
+	It("should omit the fan-out when empty", func() {
+		dat, err := json.Marshal(types.JobError{Error: "failed"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dat)).To(Equal(`{"error":"failed"}`))
+	})
+})
@@ -0,0 +1,94 @@
+package github
+
+import "time"
+
+// User is a GitHub user or organization. The owners of repositories, issues and pull requests only have the login,
+// ID, type and URLs, while profiles have all the fields.
+type User struct {
+	Login       string     `json:"login"`
+	ID          int64      `json:"id"`
+	Type        string     `json:"type"`
+	HTMLURL     string     `json:"html_url"`
+	AvatarURL   string     `json:"avatar_url"`
+	Name        string     `json:"name,omitempty"`
+	Company     string     `json:"company,omitempty"`
+	Blog        string     `json:"blog,omitempty"`
+	Location    string     `json:"location,omitempty"`
+	Bio         string     `json:"bio,omitempty"`
+	PublicRepos int        `json:"public_repos,omitempty"`
+	PublicGists int        `json:"public_gists,omitempty"`
+	Followers   int        `json:"followers,omitempty"`
+	Following   int        `json:"following,omitempty"`
+	CreatedAt   *time.Time `json:"created_at,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// License is the license of a repository
+type License struct {
+	Key    string `json:"key"`
+	Name   string `json:"name"`
+	SPDXID string `json:"spdx_id"`
+}
+
+// Repository is the metadata of a GitHub repository
+type Repository struct {
+	ID              int64     `json:"id"`
+	Name            string    `json:"name"`
+	FullName        string    `json:"full_name"`
+	Owner           User      `json:"owner"`
+	HTMLURL         string    `json:"html_url"`
+	Description     *string   `json:"description"`
+	Homepage        *string   `json:"homepage"`
+	Language        *string   `json:"language"`
+	Topics          []string  `json:"topics"`
+	License         *License  `json:"license"`
+	Fork            bool      `json:"fork"`
+	Archived        bool      `json:"archived"`
+	DefaultBranch   string    `json:"default_branch"`
+	StargazersCount int       `json:"stargazers_count"`
+	WatchersCount   int       `json:"watchers_count"`
+	ForksCount      int       `json:"forks_count"`
+	OpenIssuesCount int       `json:"open_issues_count"`
+	Size            int       `json:"size"` // In kilobytes
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	PushedAt        time.Time `json:"pushed_at"`
+}
+
+// Label is a label of an issue or a pull request
+type Label struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+// Issue is an issue or a pull request of a repository. Body is Markdown, as returned by the GitHub API.
+type Issue struct {
+	ID       int64   `json:"id"`
+	Number   int     `json:"number"`
+	Title    string  `json:"title"`
+	Body     *string `json:"body"`
+	State    string  `json:"state"`
+	HTMLURL  string  `json:"html_url"`
+	User     User    `json:"user"`
+	Labels   []Label `json:"labels"`
+	Comments int     `json:"comments"`
+	// PullRequest is set for pull requests
+	PullRequest bool       `json:"pull_request"`
+	Draft       bool       `json:"draft,omitempty"`
+	Merged      bool       `json:"merged,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+	ClosedAt    *time.Time `json:"closed_at"`
+	MergedAt    *time.Time `json:"merged_at,omitempty"`
+}
+
+// CodeResult is a file matching a code search
+type CodeResult struct {
+	Name       string     `json:"name"`
+	Path       string     `json:"path"`
+	SHA        string     `json:"sha"`
+	HTMLURL    string     `json:"html_url"`
+	Repository Repository `json:"repository"`
+	// Fragments are the matching parts of the file
+	Fragments []string `json:"fragments,omitempty"`
+}
@@ -0,0 +1,55 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// RetainArgumentKey is the job argument used by clients to hold the result of a job as soon as it is stored. It is
+// either true, for the default HoldTagRetained, or the tag of the hold.
+const RetainArgumentKey = "retain"
+
+// HoldTag describes why a result is held
+type HoldTag string
+
+const (
+	// HoldTagRetained marks a result which the client wants to keep beyond the result cache retention
+	HoldTagRetained HoldTag = "retained"
+	// HoldTagLegalHold marks a result which must be preserved, e.g. for a dispute or an audit
+	HoldTagLegalHold HoldTag = "legal-hold"
+)
+
+// ParseHoldTag returns the hold tag with the given name. An empty name is HoldTagRetained.
+func ParseHoldTag(name string) (HoldTag, error) {
+	switch tag := HoldTag(name); tag {
+	case "":
+		return HoldTagRetained, nil
+	case HoldTagRetained, HoldTagLegalHold:
+		return tag, nil
+	default:
+		return "", fmt.Errorf("hold tag must be %q or %q, got %q", HoldTagRetained, HoldTagLegalHold, name)
+	}
+}
+
+// HoldTagFromArguments returns the tag of the hold requested with RetainArgumentKey, and false if none was requested
+func HoldTagFromArguments(args JobArguments) (HoldTag, bool, error) {
+	switch v := args[RetainArgumentKey].(type) {
+	case nil:
+		return "", false, nil
+	case bool:
+		return HoldTagRetained, v, nil
+	case string:
+		tag, err := ParseHoldTag(v)
+		return tag, err == nil, err
+	default:
+		return "", false, fmt.Errorf("%s must be a boolean or a hold tag, got %T", RetainArgumentKey, v)
+	}
+}
+
+// ResultHold retains a job result until it is explicitly released. Held results are excluded from the result cache
+// limits and expiry, and are kept across restarts of the worker.
+type ResultHold struct {
+	JobID  string    `json:"job_id"`
+	Tag    HoldTag   `json:"tag"`
+	HeldAt time.Time `json:"held_at"`
+}
@@ -3,7 +3,9 @@ package types
 import (
 	"crypto/sha256"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"time"
 
 	teetypes "github.com/masa-finance/tee-types/types"
@@ -11,6 +13,13 @@ import (
 	"golang.org/x/exp/rand"
 )
 
+var (
+	// ErrJobTimedOut is the error of a job which did not finish within its timeout
+	ErrJobTimedOut = errors.New("job timed out")
+	// ErrJobCancelled is the error of the result of a cancelled job
+	ErrJobCancelled = errors.New("job cancelled")
+)
+
 type JobArguments map[string]interface{}
 
 func (ja JobArguments) Unmarshal(i interface{}) error {
@@ -22,13 +31,49 @@ func (ja JobArguments) Unmarshal(i interface{}) error {
 }
 
 type Job struct {
-	Type         teetypes.JobType `json:"type"`
-	Arguments    JobArguments     `json:"arguments"`
-	UUID         string           `json:"-"`
-	Nonce        string           `json:"quote"`
-	WorkerID     string           `json:"worker_id"`
-	TargetWorker string           `json:"target_worker"`
-	Timeout      time.Duration    `json:"timeout"`
+	Type         teetypes.JobType    `json:"type"`
+	Arguments    JobArguments        `json:"arguments"`
+	UUID         string              `json:"-"`
+	Nonce        string              `json:"quote"`
+	WorkerID     string              `json:"worker_id"`
+	TargetWorker string              `json:"target_worker"`
+	Timeout      time.Duration       `json:"timeout"`
+	Attempt      int                 `json:"-"` // Number of times this job has been retried by the job server
+	Network      Network             `json:"-"` // Wraps the HTTP transports of the job to meter its traffic, set by the job server for each attempt
+	Provenance   *ProvenanceRecorder `json:"-"` // Collects the provenance of the result, set by the job server for each attempt
+	ApifyCost    *ApifyCostRecorder  `json:"-"` // Collects the cost of the Apify actor runs, set by the job server for each attempt
+	Trace        *TraceRecorder      `json:"-"` // Collects the execution trace of jobs submitted with DebugArgumentKey, kept across attempts
+	Stream       *ResultStream       `json:"-"` // Receives the batches of results emitted while the job runs, kept across attempts
+	Cancelled    <-chan struct{}     `json:"-"` // Closed when the job is cancelled while it runs, set by the job server for each attempt
+	ResultOffset int                 `json:"-"` // Number of items of the page returned by earlier truncated results, set from the next_cursor argument
+}
+
+// Network wraps the transports of the HTTP clients of a job, so the job server can count and cap the network traffic
+// of the job, see internal/bandwidth. A nil Network leaves the transports unchanged.
+type Network func(http.RoundTripper) http.RoundTripper
+
+// Transport wraps base, or http.DefaultTransport if nil
+func (n Network) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if n == nil {
+		return base
+	}
+	return n(base)
+}
+
+// Client returns a copy of base, or of a zero http.Client if nil, whose transport is wrapped
+func (n Network) Client(base *http.Client) *http.Client {
+	c := &http.Client{}
+	if base != nil {
+		*c = *base
+	}
+	if n == nil {
+		return c
+	}
+	c.Transport = n.Transport(c.Transport)
+	return c
 }
 
 func (j Job) String() string {
@@ -68,14 +113,34 @@ func (job *Job) GenerateJobSignature() (string, error) {
 }
 
 type JobResponse struct {
-	UID string `json:"uid"`
+	UID    string `json:"uid"`
+	Cached bool   `json:"cached"` // True if the job was answered with the result of an identical earlier job
 }
 
 type JobResult struct {
-	Error      string `json:"error"`
-	Data       []byte `json:"data"`
-	Job        Job    `json:"job"`
-	NextCursor string `json:"next_cursor"`
+	Error         string            `json:"error"`
+	Data          []byte            `json:"data"`
+	Job           Job               `json:"job"`
+	NextCursor    string            `json:"next_cursor"`
+	HasMore       bool              `json:"has_more,omitempty"`       // True if there may be more results, see PaginatedResult
+	TotalEstimate *int              `json:"total_estimate,omitempty"` // Estimated number of results over all pages, if the source reports it
+	FanOut        []FanOutStatus    `json:"fan_out,omitempty"`        // Per-provider / per-query outcome for jobs that fan out
+	Usage         *Usage            `json:"usage,omitempty"`          // Resources used to execute the job
+	Provenance    *ResultProvenance `json:"provenance,omitempty"`     // How the result was produced
+	Trace         *JobTrace         `json:"trace,omitempty"`          // Execution trace, for jobs submitted with DebugArgumentKey
+	Cached        bool              `json:"cached,omitempty"`         // True if the result was reused from an identical earlier job
+	Validation    *ResultValidation `json:"validation,omitempty"`     // Items dropped because they failed the validation of the job type
+}
+
+// Usage describes the resources used to execute a job
+type Usage struct {
+	BytesDownloaded int64 `json:"bytes_downloaded"`
+	BytesUploaded   int64 `json:"bytes_uploaded"`
+	// BandwidthCap is the number of bytes the job was allowed to transfer, or 0 if it was not capped
+	BandwidthCap int64 `json:"bandwidth_cap,omitempty"`
+	// Truncated is true if the job stopped early because it reached its bandwidth cap, and returned the results it had
+	// collected so far, or if its result was cut at the result limits
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 // Success returns true if the job was successful.
@@ -83,9 +148,55 @@ func (jr JobResult) Success() bool {
 	return jr.Error == ""
 }
 
-// Seal returns the sealed job result.
+// Partial returns true if the job was successful, but some of the providers or queries it fanned out to failed.
+func (jr JobResult) Partial() bool {
+	if !jr.Success() {
+		return false
+	}
+	for _, s := range jr.FanOut {
+		if !s.Success {
+			return true
+		}
+	}
+	return false
+}
+
+// Truncated returns true if the job was successful, but stopped early because it reached its bandwidth cap, or its
+// result was cut at the result limits.
+func (jr JobResult) Truncated() bool {
+	return jr.Success() && jr.Usage != nil && jr.Usage.Truncated
+}
+
+// Seal returns the sealed job result. If the job requested its provenance, the sealed payload is a ResultEnvelope
+// holding the data and the provenance, so the provenance is covered by the same seal as the data. If the job
+// requested a paginated result, the data is a PaginatedResult.
 func (jr JobResult) Seal() (string, error) {
-	return tee.SealWithKey(jr.Job.Nonce, jr.Data)
+	data := jr.Data
+	if paginated, _ := jr.Job.Arguments[PaginatedArgumentKey].(bool); paginated {
+		var err error
+		if data, err = json.Marshal(jr.Paginated()); err != nil {
+			return "", fmt.Errorf("error marshalling paginated result: %w", err)
+		}
+	}
+	if requested, _ := jr.Job.Arguments[ProvenanceArgumentKey].(bool); requested && jr.Provenance != nil {
+		return tee.SealEnvelope(jr.Job.Nonce, data, jr.Provenance)
+	}
+	return tee.SealWithKey(jr.Job.Nonce, data)
+}
+
+// Paginated returns the data of the job result in a PaginatedResult envelope
+func (jr JobResult) Paginated() PaginatedResult {
+	items := json.RawMessage(jr.Data)
+	if len(items) == 0 {
+		items = json.RawMessage("[]")
+	}
+	return PaginatedResult{
+		Items:         items,
+		NextCursor:    jr.NextCursor,
+		HasMore:       jr.HasMore,
+		TotalEstimate: jr.TotalEstimate,
+		Truncated:     jr.Truncated(),
+	}
 }
 
 // Unmarshal unmarshals the job result data.
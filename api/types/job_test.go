@@ -0,0 +1,100 @@
+package types_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("JobResult", func() {
+	var result types.JobResult
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		tee.SealStandaloneMode = false
+
+		result = types.JobResult{
+			Data: []byte(`[{"id":"1"}]`),
+			Job:  types.Job{Type: teetypes.TwitterApiJob, Nonce: "nonce"},
+			Provenance: &types.ResultProvenance{
+				JobType:    teetypes.TwitterApiJob,
+				Capability: "searchbyquery",
+				AuthSource: types.AuthSourceAPI,
+				StartedAt:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			},
+		}
+	})
+
+	It("should only seal the data if no provenance was requested", func() {
+		sealed, err := result.Seal()
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := tee.UnsealWithKey("nonce", sealed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`[{"id":"1"}]`))
+	})
+
+	It("should seal the data together with its provenance if it was requested", func() {
+		result.Job.Arguments = types.JobArguments{types.ProvenanceArgumentKey: true}
+		sealed, err := result.Seal()
+		Expect(err).NotTo(HaveOccurred())
+
+		var provenance types.ResultProvenance
+		data, err := tee.UnsealEnvelope("nonce", sealed, &provenance)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`[{"id":"1"}]`))
+		Expect(provenance.AuthSource).To(Equal(types.AuthSourceAPI))
+		Expect(provenance.Capability).To(Equal(teetypes.Capability("searchbyquery")))
+		Expect(provenance.StartedAt).To(Equal(result.Provenance.StartedAt))
+	})
+
+	It("should seal the data in a paginated envelope if it was requested", func() {
+		total := 25
+		result.Job.Arguments = types.JobArguments{types.PaginatedArgumentKey: true}
+		result.NextCursor = "next"
+		result.HasMore = true
+		result.TotalEstimate = &total
+		sealed, err := result.Seal()
+		Expect(err).NotTo(HaveOccurred())
+
+		data, err := tee.UnsealWithKey("nonce", sealed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(data).To(MatchJSON(`{"items":[{"id":"1"}],"next_cursor":"next","has_more":true,"total_estimate":25}`))
+	})
+
+	It("should have an empty list of items in the paginated envelope of an empty result", func() {
+		result.Data = nil
+		dat, err := json.Marshal(result.Paginated())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dat).To(MatchJSON(`{"items":[],"has_more":false}`))
+	})
+})
+
+var _ = Describe("ProvenanceRecorder", func() {
+	It("should record the last auth source and all actor runs", func() {
+		r := &types.ProvenanceRecorder{}
+		r.SetAuthSource(types.AuthSourceCredential)
+		r.SetAuthSource(types.AuthSourceApify)
+		r.AddActorRun("run-1")
+		r.AddActorRun("run-2")
+
+		Expect(r.AuthSource()).To(Equal(types.AuthSourceApify))
+		Expect(r.ActorRunIDs()).To(Equal([]string{"run-1", "run-2"}))
+	})
+
+	It("should record nothing if it is nil", func() {
+		var r *types.ProvenanceRecorder
+		r.SetAuthSource(types.AuthSourceAPI)
+		r.AddActorRun("run-1")
+
+		Expect(r.AuthSource()).To(BeEmpty())
+		Expect(r.ActorRunIDs()).To(BeNil())
+	})
+})
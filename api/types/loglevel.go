@@ -0,0 +1,8 @@
+package types
+
+// LogLevels is returned by the log level endpoints: the default log level, and the log levels of the modules which
+// have their own
+type LogLevels struct {
+	Level   string            `json:"level"`
+	Modules map[string]string `json:"modules,omitempty"`
+}
@@ -0,0 +1,64 @@
+package mastodon
+
+import "time"
+
+// Account is a Mastodon account, as returned by the Mastodon REST API
+type Account struct {
+	ID             string    `json:"id"`
+	Username       string    `json:"username"`
+	Acct           string    `json:"acct"`
+	DisplayName    string    `json:"display_name"`
+	Note           string    `json:"note"`
+	URL            string    `json:"url"`
+	Avatar         string    `json:"avatar"`
+	Header         string    `json:"header"`
+	Locked         bool      `json:"locked"`
+	Bot            bool      `json:"bot"`
+	CreatedAt      time.Time `json:"created_at"`
+	FollowersCount int       `json:"followers_count"`
+	FollowingCount int       `json:"following_count"`
+	StatusesCount  int       `json:"statuses_count"`
+	Fields         []Field   `json:"fields"`
+}
+
+// Field is a profile metadata field of an account
+type Field struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Tag is a hashtag used in a status
+type Tag struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+}
+
+// MediaAttachment is a file attached to a status
+type MediaAttachment struct {
+	ID          string  `json:"id"`
+	Type        string  `json:"type"`
+	URL         string  `json:"url"`
+	PreviewURL  string  `json:"preview_url"`
+	Description *string `json:"description"`
+}
+
+// Status is a post on a Mastodon instance. Content is HTML, as returned by the instance.
+type Status struct {
+	ID               string            `json:"id"`
+	URI              string            `json:"uri"`
+	URL              *string           `json:"url"`
+	CreatedAt        time.Time         `json:"created_at"`
+	Account          Account           `json:"account"`
+	Content          string            `json:"content"`
+	SpoilerText      string            `json:"spoiler_text"`
+	Sensitive        bool              `json:"sensitive"`
+	Visibility       string            `json:"visibility"`
+	Language         *string           `json:"language"`
+	InReplyToID      *string           `json:"in_reply_to_id"`
+	RepliesCount     int               `json:"replies_count"`
+	ReblogsCount     int               `json:"reblogs_count"`
+	FavouritesCount  int               `json:"favourites_count"`
+	Tags             []Tag             `json:"tags"`
+	MediaAttachments []MediaAttachment `json:"media_attachments"`
+	Reblog           *Status           `json:"reblog"`
+}
@@ -0,0 +1,22 @@
+package types
+
+import "encoding/json"
+
+// PaginatedArgumentKey is the job argument used by clients to request a sealed result which is a PaginatedResult, the
+// same for all job types, instead of the bare data
+const PaginatedArgumentKey = "paginated"
+
+// PaginatedResult is the envelope of a page of the results of a job
+type PaginatedResult struct {
+	// Items is the data of the job, an array for the job types which return a list of results
+	Items json.RawMessage `json:"items"`
+	// NextCursor is passed as the next_cursor argument of the same job to get the next page
+	NextCursor string `json:"next_cursor,omitempty"`
+	// HasMore is true if there may be more results than the ones in Items
+	HasMore bool `json:"has_more"`
+	// TotalEstimate is the estimated number of results over all the pages, if the source reports it
+	TotalEstimate *int `json:"total_estimate,omitempty"`
+	// Truncated is true if Items was cut short, e.g. at the result limits of the worker. NextCursor then continues with
+	// the items which were left out.
+	Truncated bool `json:"truncated,omitempty"`
+}
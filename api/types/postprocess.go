@@ -0,0 +1,34 @@
+package types
+
+import "encoding/json"
+
+// PostProcessedResult is the result of a job which requested LLM post-processing. Raw is the result of the scraper,
+// Processed holds the LLM response for each of its items, in the same order, which is empty for the items of chunks
+// which failed or were skipped.
+type PostProcessedResult struct {
+	Raw       json.RawMessage    `json:"raw"`
+	Processed []string           `json:"processed"`
+	Report    *PostProcessReport `json:"report,omitempty"`
+}
+
+// PostProcessReport tells how the items of a post-processed result were sent to the LLM processor. They are processed
+// in chunks, each in its own run of the processor, and items which don't fit in the context window of the model are
+// split into pieces whose responses are joined with newlines.
+type PostProcessReport struct {
+	Chunks       int `json:"chunks"`
+	FailedChunks int `json:"failed_chunks,omitempty"`
+	// SkippedChunks were not processed because they would have exceeded the token budget
+	SkippedChunks   int `json:"skipped_chunks,omitempty"`
+	SplitItems      int `json:"split_items,omitempty"`
+	EstimatedTokens int `json:"estimated_tokens"`
+}
+
+// PostProcessProgress is emitted to the result stream of a job once each chunk of its items has been processed by the
+// LLM processor. Items are the indices of the items of the chunk in the result, and Processed their LLM responses.
+type PostProcessProgress struct {
+	Chunk     int      `json:"chunk"`
+	Chunks    int      `json:"chunks"`
+	Items     []int    `json:"items"`
+	Processed []string `json:"processed"`
+	Error     string   `json:"error,omitempty"`
+}
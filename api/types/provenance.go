@@ -0,0 +1,101 @@
+package types
+
+import (
+	"encoding/json"
+	"slices"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// ProvenanceArchived marks data which was not fetched from the original source, but from an archived copy
+const ProvenanceArchived = "archived"
+
+// Provenance describes where a result was fetched from, when it is not the original source
+type Provenance struct {
+	Type              string    `json:"type"`
+	Source            string    `json:"source"`
+	SnapshotURL       string    `json:"snapshot_url"`
+	SnapshotTimestamp time.Time `json:"snapshot_timestamp"`
+}
+
+// ProvenanceArgumentKey is the job argument used by clients to request a sealed result which embeds its ResultProvenance
+const ProvenanceArgumentKey = "provenance"
+
+// ResultProvenance describes how the result of a job was produced, so validators can e.g. tell tweets fetched with
+// API keys from scraped ones
+type ResultProvenance struct {
+	JobType    teetypes.JobType    `json:"job_type"`
+	Capability teetypes.Capability `json:"capability,omitempty"`
+	// AuthSource is how the worker authenticated with the data source. It is empty if it is not known.
+	AuthSource AuthSource `json:"auth_source,omitempty"`
+	// LowerTrust is true if the data was obtained through an auth source whose data is less trustworthy, see
+	// AuthSource.LowerTrust
+	LowerTrust bool `json:"lower_trust,omitempty"`
+	// ActorRunIDs are the IDs of the Apify actor runs which produced the data
+	ActorRunIDs []string  `json:"actor_run_ids,omitempty"`
+	StartedAt   time.Time `json:"started_at"`
+	FinishedAt  time.Time `json:"finished_at"`
+	WorkerID    string    `json:"worker_id"`
+	// WorkerVersion is the protocol version of the worker, and ApplicationVersion the version of the build
+	WorkerVersion      string `json:"worker_version"`
+	ApplicationVersion string `json:"application_version"`
+	// CapabilityVersion identifies the set of capabilities the worker advertised when the job was executed
+	CapabilityVersion string `json:"capability_version"`
+}
+
+// ResultEnvelope is the payload of the sealed result of a job which requested its provenance
+type ResultEnvelope struct {
+	Data       json.RawMessage   `json:"data"`
+	Provenance *ResultProvenance `json:"metadata"`
+}
+
+// ProvenanceRecorder collects the provenance of a job while it is executed. It is safe for concurrent use, and a nil
+// recorder records nothing.
+type ProvenanceRecorder struct {
+	mu          sync.Mutex
+	authSource  AuthSource
+	actorRunIDs []string
+}
+
+// SetAuthSource records how the worker authenticated with the data source. If a job falls back to another
+// source, the last one is recorded.
+func (r *ProvenanceRecorder) SetAuthSource(source AuthSource) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.authSource = source
+}
+
+// AddActorRun records the ID of an Apify actor run
+func (r *ProvenanceRecorder) AddActorRun(runID string) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.actorRunIDs = append(r.actorRunIDs, runID)
+}
+
+// AuthSource returns the recorded auth source
+func (r *ProvenanceRecorder) AuthSource() AuthSource {
+	if r == nil {
+		return ""
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.authSource
+}
+
+// ActorRunIDs returns the recorded actor run IDs
+func (r *ProvenanceRecorder) ActorRunIDs() []string {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.actorRunIDs)
+}
@@ -0,0 +1,45 @@
+package types
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+// Quotas of a miner, see QuotaError
+const (
+	QuotaJobsPerHour       = "jobs_per_hour"
+	QuotaResultBytesPerDay = "result_bytes_per_day"
+)
+
+// QuotaError is returned when a miner submits a job after using up one of the quotas of its API key. The quota is
+// available again at ResetAt.
+type QuotaError struct {
+	Miner   string
+	Quota   string
+	Limit   int64
+	ResetAt time.Time
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("quota exceeded: miner %s has used its %d %s, resets at %s", e.Miner, e.Limit, e.Quota, e.ResetAt.UTC().Format(time.RFC3339))
+}
+
+// RetryAfterSeconds returns the time until the quota resets in whole seconds as used by the Retry-After header,
+// rounded up so that clients don't retry too early
+func (e *QuotaError) RetryAfterSeconds(now time.Time) int {
+	return max(int(math.Ceil(e.ResetAt.Sub(now).Seconds())), 0)
+}
+
+// QuotaUsage is what a miner has used of the quotas of its API key in their current windows. The limits are left out
+// if the miner has no such quota, and the reset times if the miner has not used the quota in the current window.
+type QuotaUsage struct {
+	JobsPerHour        int        `json:"jobs_per_hour,omitempty"`
+	Jobs               int        `json:"jobs"`
+	JobsResetAt        *time.Time `json:"jobs_reset_at,omitempty"`
+	ResultBytesPerDay  int64      `json:"result_bytes_per_day,omitempty"`
+	ResultBytes        int64      `json:"result_bytes"`
+	ResultBytesResetAt *time.Time `json:"result_bytes_reset_at,omitempty"`
+	// Rejected is the number of jobs rejected since the worker started because a quota was used up
+	Rejected int `json:"rejected"`
+}
@@ -46,6 +46,7 @@ type Post struct {
 	UpVotes             int       `json:"upVotes"`
 	IsVideo             bool      `json:"isVideo"`
 	IsAd                bool      `json:"isAd"`
+	Stickied            bool      `json:"stickied,omitempty"`
 	Over18              bool      `json:"over18"`
 	CreatedAt           time.Time `json:"createdAt"`
 	ScrapedAt           time.Time `json:"scrapedAt"`
@@ -150,3 +151,14 @@ func (t *Response) MarshalJSON() ([]byte, error) {
 		return nil, fmt.Errorf("unknown Reddit response type during marshal: %s", t.TypeSwitch.Type)
 	}
 }
+
+// SubredditResponse is the result of a scrapesubreddit job, the posts of a subreddit listing together with the
+// metadata of the subreddit
+type SubredditResponse struct {
+	Subreddit string     `json:"subreddit"`
+	Sort      string     `json:"sort"`
+	Time      string     `json:"time"`
+	Community *Community `json:"community,omitempty"`
+	Pinned    []*Post    `json:"pinned"`
+	Posts     []*Post    `json:"posts"`
+}
@@ -0,0 +1,66 @@
+package types
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// ResultFormat is the format in which a decrypted job result is returned
+type ResultFormat string
+
+const (
+	// ResultFormatJSON returns the result as the job produced it, i.e. a single JSON document
+	ResultFormatJSON ResultFormat = "json"
+	// ResultFormatNDJSON returns each record of the result as a JSON document on a line of its own
+	ResultFormatNDJSON ResultFormat = "ndjson"
+	// ResultFormatCSV returns the records of the result as the rows of a CSV table, with a header row
+	ResultFormatCSV ResultFormat = "csv"
+	// ResultFormatProtobuf returns each record of the result as a length-delimited google.protobuf.Value message
+	ResultFormatProtobuf ResultFormat = "protobuf"
+)
+
+// resultFormatContentTypes are the content types of the result formats. The first is the one returned, the others are
+// only accepted in the Accept header.
+var resultFormatContentTypes = map[ResultFormat][]string{
+	ResultFormatJSON:     {"application/json"},
+	ResultFormatNDJSON:   {"application/x-ndjson", "application/ndjson", "application/jsonl"},
+	ResultFormatCSV:      {"text/csv"},
+	ResultFormatProtobuf: {"application/x-protobuf", "application/protobuf"},
+}
+
+// ContentType returns the content type of a response in the format
+func (f ResultFormat) ContentType() string {
+	return resultFormatContentTypes[f][0]
+}
+
+// ParseResultFormat returns the result format with the given name. An empty name is the JSON format.
+func ParseResultFormat(name string) (ResultFormat, error) {
+	f := ResultFormat(strings.ToLower(strings.TrimSpace(name)))
+	if f == "" {
+		return ResultFormatJSON, nil
+	}
+	if _, ok := resultFormatContentTypes[f]; !ok {
+		return "", fmt.Errorf("unknown result format %q, expected one of json, ndjson, csv or protobuf", name)
+	}
+	return f, nil
+}
+
+// ResultFormatFromAccept returns the format of the first media type of an Accept header which is the content type of
+// a result format. Quality values are ignored. If no media type matches, e.g. for */*, the JSON format is returned.
+func ResultFormatFromAccept(accept string) ResultFormat {
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		for f, contentTypes := range resultFormatContentTypes {
+			for _, contentType := range contentTypes {
+				if mediaType == contentType {
+					return f
+				}
+			}
+		}
+	}
+	return ResultFormatJSON
+}
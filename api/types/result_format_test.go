@@ -0,0 +1,30 @@
+package types_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+var _ = Describe("ResultFormat", func() {
+	It("should parse the names of the result formats", func() {
+		Expect(types.ParseResultFormat("")).To(Equal(types.ResultFormatJSON))
+		Expect(types.ParseResultFormat(" NDJSON ")).To(Equal(types.ResultFormatNDJSON))
+		Expect(types.ParseResultFormat("csv")).To(Equal(types.ResultFormatCSV))
+		_, err := types.ParseResultFormat("xml")
+		Expect(err).To(MatchError(ContainSubstring("unknown result format")))
+	})
+
+	DescribeTable("should negotiate the result format with the Accept header",
+		func(accept string, expected types.ResultFormat) {
+			Expect(types.ResultFormatFromAccept(accept)).To(Equal(expected))
+		},
+		Entry("without Accept header", "", types.ResultFormatJSON),
+		Entry("with any media type", "*/*", types.ResultFormatJSON),
+		Entry("with NDJSON", "application/x-ndjson", types.ResultFormatNDJSON),
+		Entry("with an alternative NDJSON media type", "application/jsonl", types.ResultFormatNDJSON),
+		Entry("with parameters", "text/csv; charset=utf-8", types.ResultFormatCSV),
+		Entry("with the first known media type", "text/html, application/protobuf, text/csv", types.ResultFormatProtobuf),
+	)
+})
@@ -0,0 +1,54 @@
+package rss
+
+import "time"
+
+// Feed is an RSS or Atom feed, normalized to the same fields whatever its format
+type Feed struct {
+	URL         string     `json:"url"` // The URL the feed was fetched from
+	Format      string     `json:"format"`
+	Title       string     `json:"title"`
+	Link        string     `json:"link,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Updated     *time.Time `json:"updated,omitempty"`
+	Items       []Item     `json:"items"`
+}
+
+// Formats of the feeds
+const (
+	FormatRSS  = "rss"
+	FormatAtom = "atom"
+	FormatRDF  = "rdf" // RSS 1.0
+)
+
+// Item is an item of an RSS feed or an entry of an Atom feed
+type Item struct {
+	// ID is the guid of the item, or its link if it has none
+	ID        string     `json:"id"`
+	Title     string     `json:"title"`
+	Link      string     `json:"link,omitempty"`
+	Author    string     `json:"author,omitempty"`
+	Published *time.Time `json:"published,omitempty"`
+	Updated   *time.Time `json:"updated,omitempty"`
+	Summary   string     `json:"summary,omitempty"`
+	// Content is the full content of the item, usually HTML, if the feed includes it
+	Content    string      `json:"content,omitempty"`
+	Categories []string    `json:"categories,omitempty"`
+	Enclosures []Enclosure `json:"enclosures,omitempty"`
+	// FeedURL is the URL of the feed of the item, set in the results of searchfeeds jobs
+	FeedURL string `json:"feed_url,omitempty"`
+}
+
+// Enclosure is a file attached to an item, e.g. the audio of a podcast episode
+type Enclosure struct {
+	URL    string `json:"url"`
+	Type   string `json:"type,omitempty"`
+	Length int64  `json:"length,omitempty"`
+}
+
+// SearchResult is the result of a searchfeeds job
+type SearchResult struct {
+	Query string `json:"query"`
+	List  string `json:"list"`
+	// Items are the matching items of all the feeds of the list, newest first
+	Items []Item `json:"items"`
+}
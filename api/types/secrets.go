@@ -0,0 +1,35 @@
+package types
+
+import "time"
+
+// SecretStatus is the outcome of validating a configured secret
+type SecretStatus string
+
+const (
+	// SecretValid means the provider accepted the secret
+	SecretValid SecretStatus = "valid"
+	// SecretInvalid means the provider rejected the secret, e.g. because it is wrong or was revoked
+	SecretInvalid SecretStatus = "invalid"
+	// SecretUnverified means the secret could not be checked, e.g. because the provider was unreachable or the
+	// secret can't be checked without side effects
+	SecretUnverified SecretStatus = "unverified"
+	// SecretQuotaExhausted means the secret is valid, but it has run out of quota or is rate limited
+	SecretQuotaExhausted SecretStatus = "quota_exhausted"
+)
+
+// SecretDiagnostic is the result of validating a single secret. The secret is identified by a fingerprint, never by
+// the secret itself.
+type SecretDiagnostic struct {
+	Secret      string       `json:"secret"`
+	Fingerprint string       `json:"fingerprint,omitempty"`
+	Status      SecretStatus `json:"status"`
+	Error       string       `json:"error,omitempty"`
+	CheckedAt   time.Time    `json:"checked_at"`
+}
+
+// StatusResponse is returned by the status endpoint. Healthy is false if any secret is invalid or out of quota.
+type StatusResponse struct {
+	WorkerID string             `json:"worker_id"`
+	Healthy  bool               `json:"healthy"`
+	Secrets  []SecretDiagnostic `json:"secrets"`
+}
@@ -0,0 +1,62 @@
+package types
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidSessions is returned when importing sessions which can't be unsealed or decoded, e.g. because they were
+// exported by a worker with another sealing key
+var ErrInvalidSessions = errors.New("invalid sessions")
+
+// TwitterSessions are the sealed session cookies of the Twitter accounts of a worker. They are exported by one worker
+// and imported by another with the same sealing key, e.g. when migrating or scaling out, so that the accounts don't
+// have to log in again.
+type TwitterSessions struct {
+	// Sessions are the sealed cookies of all accounts
+	Sessions string `json:"sessions"`
+	// Accounts are the usernames of the accounts whose sessions were exported. They are informational only, and
+	// ignored on import.
+	Accounts []string `json:"accounts,omitempty"`
+}
+
+// TwitterSessionsImport reports the outcome of importing sessions. Sessions of accounts which the importing worker
+// is not configured with, or which are not logged in, are skipped.
+type TwitterSessionsImport struct {
+	Imported []string `json:"imported"`
+	Skipped  []string `json:"skipped,omitempty"`
+}
+
+// Statuses of a Twitter account reported by the account health check
+const (
+	// TwitterAccountOK means the session works, possibly after logging in again
+	TwitterAccountOK = "ok"
+	// TwitterAccountChallengeRequired means Twitter asks the account to confirm its identity, e.g. by email or with a
+	// code, before it can log in
+	TwitterAccountChallengeRequired = "challenge-required"
+	// TwitterAccountLocked means the account was suspended or locked by Twitter
+	TwitterAccountLocked = "locked"
+	// TwitterAccountRateLimited means the account is rate limited, so it was not checked
+	TwitterAccountRateLimited = "rate-limited"
+	// TwitterAccountFailed means the session could not be verified for another reason, e.g. a wrong password
+	TwitterAccountFailed = "failed"
+)
+
+// TwitterAccountStatus is the outcome of checking a Twitter account
+type TwitterAccountStatus struct {
+	Username string `json:"username"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	// LoggedIn is true if the account had to log in again because its session had expired
+	LoggedIn bool `json:"logged_in,omitempty"`
+	// CookiesRefreshed is true if the cookies of the working session were saved
+	CookiesRefreshed bool `json:"cookies_refreshed,omitempty"`
+	// UnavailableUntil is when the account is used again, if it was taken out of rotation
+	UnavailableUntil *time.Time `json:"unavailable_until,omitempty"`
+}
+
+// TwitterAuthCheck reports the status of every Twitter account of a worker
+type TwitterAuthCheck struct {
+	CheckedAt time.Time              `json:"checked_at"`
+	Accounts  []TwitterAccountStatus `json:"accounts"`
+}
@@ -0,0 +1,58 @@
+package types
+
+// JobStatus is the state of a job which is reported to clients while it is being executed
+type JobStatus string
+
+const (
+	// JobStatusQueued means the job has been accepted and waits for a free worker
+	JobStatusQueued JobStatus = "queued"
+	// JobStatusRunning means a worker has started executing the job
+	JobStatusRunning JobStatus = "running"
+	// JobStatusRetrying means an attempt failed with a transient error and the job has been queued again
+	JobStatusRetrying JobStatus = "retrying"
+)
+
+// JobEvent is a change of the status of a job
+type JobEvent struct {
+	JobUUID string    `json:"job_id"`
+	Status  JobStatus `json:"status"`
+	Attempt int       `json:"attempt,omitempty"` // Number of the retry, 0 for the first attempt
+}
+
+// SocketMessageType identifies the kind of a message exchanged over the job WebSocket
+type SocketMessageType string
+
+const (
+	// SocketSubmit is sent by the client to add an encrypted job, like POST /job/add
+	SocketSubmit SocketMessageType = "submit"
+	// SocketCancel is sent by the client to cancel a job which has not finished yet
+	SocketCancel SocketMessageType = "cancel"
+
+	// SocketAccepted acknowledges a submitted job with its UUID
+	SocketAccepted SocketMessageType = "accepted"
+	// SocketProgress reports a change of the status of a job
+	SocketProgress SocketMessageType = "progress"
+	// SocketResult delivers the sealed result of a job, or its error
+	SocketResult SocketMessageType = "result"
+	// SocketCancelled acknowledges a cancelled job
+	SocketCancelled SocketMessageType = "cancelled"
+	// SocketError reports a message which could not be processed, e.g. a rejected job
+	SocketError SocketMessageType = "error"
+)
+
+// SocketMessage is a message exchanged over the job WebSocket. Which fields are set depends on its type.
+type SocketMessage struct {
+	Type SocketMessageType `json:"type"`
+	// RequestID is chosen by the client when submitting a job, and is echoed in the replies until the job UUID is known
+	RequestID string      `json:"request_id,omitempty"`
+	JobUUID   string      `json:"job_id,omitempty"`
+	Job       *JobRequest `json:"job,omitempty"`
+	Status    JobStatus   `json:"status,omitempty"`
+	Attempt   int         `json:"attempt,omitempty"`
+	// Result is the sealed result of a successful job, as returned by GET /job/status
+	Result  string    `json:"result,omitempty"`
+	Partial bool      `json:"partial,omitempty"`
+	Cached  bool      `json:"cached,omitempty"`
+	Usage   *Usage    `json:"usage,omitempty"`
+	Error   *JobError `json:"error,omitempty"`
+}
@@ -0,0 +1,151 @@
+package types
+
+import (
+	"maps"
+	"sync"
+	"time"
+)
+
+// MaxStreamBatches is the maximum number of batches kept per job. The oldest batch is dropped once it is exceeded, so
+// clients which fall behind can tell from the sequence numbers that they missed batches.
+const MaxStreamBatches = 1000
+
+// StreamBatch is a batch of results emitted by a job while it runs
+type StreamBatch struct {
+	Seq  int       `json:"seq"` // Numbered from 1 in the order the batches were emitted
+	Time time.Time `json:"time"`
+	// Data is sealed like the result of the job in a StreamResponse
+	Data string `json:"data"`
+}
+
+// StreamResponse is the response of GET /job/<uuid>/stream
+type StreamResponse struct {
+	JobUUID string        `json:"job_uuid"`
+	Batches []StreamBatch `json:"batches"`
+	// Done is set once the job has its final result and won't emit any more batches
+	Done bool `json:"done"`
+}
+
+// ResultStream collects the batches of results a long-running job emits while it runs, e.g. the new posts found by
+// every poll of a Reddit monitor job, so clients can read them before the job ends. It also keeps the watermarks of
+// the sources polled by the job, i.e. the newest item emitted from each of them. Like the TraceRecorder it is kept
+// across attempts, and across the runs of a recurring job. It is safe for concurrent use, and a nil stream discards
+// everything.
+type ResultStream struct {
+	mu         sync.Mutex
+	batches    []streamBatch
+	seq        int
+	watermarks map[string]string
+	done       bool
+	// changed is closed and replaced whenever a batch is emitted or the stream ends
+	changed chan struct{}
+}
+
+// streamBatch is a batch before it is sealed
+type streamBatch struct {
+	seq  int
+	time time.Time
+	data []byte
+}
+
+// NewResultStream returns an empty stream
+func NewResultStream() *ResultStream {
+	return &ResultStream{watermarks: make(map[string]string), changed: make(chan struct{})}
+}
+
+// Emit adds a batch of results to the stream
+func (s *ResultStream) Emit(data []byte) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seq++
+	if len(s.batches) >= MaxStreamBatches {
+		s.batches = s.batches[1:]
+	}
+	s.batches = append(s.batches, streamBatch{seq: s.seq, time: time.Now(), data: data})
+	s.notify()
+}
+
+// End marks the stream as done, once the job has its final result
+func (s *ResultStream) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.done {
+		s.done = true
+		s.notify()
+	}
+}
+
+// Done returns true once the stream has ended
+func (s *ResultStream) Done() bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.done
+}
+
+// notify wakes up the readers waiting for a change. The caller must hold the lock.
+func (s *ResultStream) notify() {
+	close(s.changed)
+	s.changed = make(chan struct{})
+}
+
+// Since returns the batches emitted after the batch with the given sequence number, whether the stream is done, and a
+// channel which is closed on the next change of the stream
+func (s *ResultStream) Since(after int, seal func([]byte) (string, error)) ([]StreamBatch, bool, <-chan struct{}, error) {
+	if s == nil {
+		return nil, true, nil, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	batches := []StreamBatch{}
+	for _, b := range s.batches {
+		if b.seq <= after {
+			continue
+		}
+		sealed, err := seal(b.data)
+		if err != nil {
+			return nil, false, nil, err
+		}
+		batches = append(batches, StreamBatch{Seq: b.seq, Time: b.time, Data: sealed})
+	}
+	return batches, s.done, s.changed, nil
+}
+
+// Watermark returns the watermark of a source, or an empty string if nothing was emitted from it yet
+func (s *ResultStream) Watermark(source string) string {
+	if s == nil {
+		return ""
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.watermarks[source]
+}
+
+// SetWatermark sets the watermark of a source
+func (s *ResultStream) SetWatermark(source, watermark string) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.watermarks[source] = watermark
+}
+
+// Watermarks returns the watermarks of all the sources
+func (s *ResultStream) Watermarks() map[string]string {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return maps.Clone(s.watermarks)
+}
@@ -0,0 +1,76 @@
+package types_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+var _ = Describe("ResultStream", func() {
+	identity := func(data []byte) (string, error) { return string(data), nil }
+
+	It("should discard everything if nil", func() {
+		var s *types.ResultStream
+		s.Emit([]byte("batch"))
+		s.SetWatermark("r/golang", "abc")
+		s.End()
+		Expect(s.Watermark("r/golang")).To(BeEmpty())
+		Expect(s.Done()).To(BeTrue())
+
+		batches, done, _, err := s.Since(0, identity)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batches).To(BeEmpty())
+		Expect(done).To(BeTrue())
+	})
+
+	It("should return the batches emitted after a sequence number", func() {
+		s := types.NewResultStream()
+		s.Emit([]byte("one"))
+		s.Emit([]byte("two"))
+
+		batches, done, _, err := s.Since(1, identity)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(done).To(BeFalse())
+		Expect(batches).To(HaveLen(1))
+		Expect(batches[0].Seq).To(Equal(2))
+		Expect(batches[0].Data).To(Equal("two"))
+	})
+
+	It("should notify the readers of new batches and of its end", func() {
+		s := types.NewResultStream()
+		_, _, changed, err := s.Since(0, identity)
+		Expect(err).NotTo(HaveOccurred())
+		Consistently(changed).ShouldNot(BeClosed())
+
+		s.Emit([]byte("one"))
+		Expect(changed).To(BeClosed())
+
+		_, _, changed, _ = s.Since(1, identity)
+		s.End()
+		Expect(changed).To(BeClosed())
+		Expect(s.Done()).To(BeTrue())
+	})
+
+	It("should drop the oldest batches once it is full", func() {
+		s := types.NewResultStream()
+		for i := range types.MaxStreamBatches + 2 {
+			s.Emit(fmt.Appendf(nil, "%d", i+1))
+		}
+
+		batches, _, _, err := s.Since(0, identity)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batches).To(HaveLen(types.MaxStreamBatches))
+		Expect(batches[0].Seq).To(Equal(3))
+	})
+
+	It("should keep the watermarks of the sources", func() {
+		s := types.NewResultStream()
+		s.SetWatermark("r/golang", "abc")
+		s.SetWatermark("q:tee", "def")
+		Expect(s.Watermark("r/golang")).To(Equal("abc"))
+		Expect(s.Watermarks()).To(Equal(map[string]string{"r/golang": "abc", "q:tee": "def"}))
+	})
+})
@@ -0,0 +1,51 @@
+package types
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// TelemetrySignatureHeader is the header carrying the signature of the statistics a worker pushes to its collector,
+// see TelemetrySignature
+const TelemetrySignatureHeader = "X-Telemetry-Signature"
+
+// TelemetryPush is the body of the requests with which a worker pushes its statistics to a collector. In enclave mode,
+// Quote is a fresh SGX quote, encoded in base64, whose report data is TelemetryReportData of the push.
+type TelemetryPush struct {
+	WorkerID string          `json:"worker_id"`
+	SentAt   int64           `json:"sent_at"`
+	Stats    json.RawMessage `json:"stats"`
+	Quote    string          `json:"quote,omitempty"`
+}
+
+// TelemetryReportData returns the report data embedded in the quote of a push: the SHA-256 hash of the worker ID, the
+// time the push was sent and the hex encoded SHA-256 hash of the statistics, separated by newlines
+func TelemetryReportData(workerID string, sentAt int64, stats []byte) []byte {
+	statsHash := sha256.Sum256(stats)
+	sum := sha256.Sum256([]byte(workerID + "\n" + strconv.FormatInt(sentAt, 10) + "\n" + hex.EncodeToString(statsHash[:])))
+	return sum[:]
+}
+
+// TelemetrySignature returns the value of the TelemetrySignatureHeader of a push: sha256= followed by the hex encoded
+// HMAC-SHA256 of the body, keyed with the shared secret
+func TelemetrySignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyTelemetrySignature returns true if the signature of a push matches its body, in constant time
+func VerifyTelemetrySignature(secret string, body []byte, signature string) bool {
+	expected := TelemetrySignature(secret, body)
+	return strings.HasPrefix(signature, "sha256=") && hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// StatsDimensions is returned by the stats dimensions endpoints: the dimensions by which the statistics are broken
+// down, sorted by name
+type StatsDimensions struct {
+	Dimensions []string `json:"dimensions"`
+}
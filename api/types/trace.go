@@ -0,0 +1,204 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+)
+
+// DebugArgumentKey is the job argument used by clients to request an execution trace of the job
+const DebugArgumentKey = "debug"
+
+// MaxTraceEvents is the maximum number of events recorded for a job. Further events are counted, but not recorded.
+const MaxTraceEvents = 1000
+
+// TraceKind is the kind of a trace event
+type TraceKind string
+
+const (
+	// TracePhase is a step of the execution of the job, e.g. waiting in the queue or executing the scraper
+	TracePhase TraceKind = "phase"
+	// TraceStrategy records how the worker authenticated with the data source, and with which credential
+	TraceStrategy TraceKind = "strategy"
+	// TraceHTTP is an HTTP request made by the job
+	TraceHTTP TraceKind = "http"
+	// TraceActorRun is an Apify actor run started by the job
+	TraceActorRun TraceKind = "actor_run"
+	// TraceRetry records that the job failed with a transient error and is executed again after a backoff
+	TraceRetry TraceKind = "retry"
+)
+
+// TraceEvent is a single event of the execution trace of a job. Credentials are identified by a fingerprint, and
+// URLs are recorded without their query, so the trace never contains secrets.
+type TraceEvent struct {
+	Time       time.Time `json:"time"`
+	Attempt    int       `json:"attempt"`
+	Kind       TraceKind `json:"kind"`
+	Name       string    `json:"name,omitempty"`
+	Credential string    `json:"credential,omitempty"`
+	Method     string    `json:"method,omitempty"`
+	URL        string    `json:"url,omitempty"`
+	StatusCode int       `json:"status_code,omitempty"`
+	DurationMs int64     `json:"duration_ms,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// JobTrace is the execution trace of a job, across all of its attempts
+type JobTrace struct {
+	JobUUID       string       `json:"job_uuid"`
+	Events        []TraceEvent `json:"events"`
+	DroppedEvents int          `json:"dropped_events,omitempty"`
+}
+
+// TraceRecorder collects the execution trace of a job which was submitted with DebugArgumentKey. Unlike the other
+// recorders it is kept across attempts. It is safe for concurrent use, and a nil recorder records nothing.
+type TraceRecorder struct {
+	mu       sync.Mutex
+	jobUUID  string
+	attempt  int
+	queuedAt time.Time
+	events   []TraceEvent
+	dropped  int
+}
+
+// NewTraceRecorder returns a recorder for the trace of the given job
+func NewTraceRecorder(jobUUID string) *TraceRecorder {
+	return &TraceRecorder{jobUUID: jobUUID}
+}
+
+// DebugRequested returns true if the job arguments request an execution trace
+func DebugRequested(args JobArguments) bool {
+	debug, _ := args[DebugArgumentKey].(bool)
+	return debug
+}
+
+func (r *TraceRecorder) add(e TraceEvent) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(r.events) >= MaxTraceEvents {
+		r.dropped++
+		return
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	e.Attempt = r.attempt
+	r.events = append(r.events, e)
+}
+
+// Queued records that the job was queued, so the time it waits is recorded as the queue phase once it starts
+func (r *TraceRecorder) Queued(at time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.queuedAt = at
+}
+
+// Started records that the given attempt of the job started, and how long it waited in the queue
+func (r *TraceRecorder) Started(attempt int, at time.Time) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	r.attempt = attempt
+	queuedAt := r.queuedAt
+	r.mu.Unlock()
+
+	if !queuedAt.IsZero() {
+		r.Phase("queue", queuedAt, at, nil)
+	}
+}
+
+// Phase records a step of the execution of the job
+func (r *TraceRecorder) Phase(name string, startedAt, finishedAt time.Time, err error) {
+	e := TraceEvent{Time: startedAt, Kind: TracePhase, Name: name, DurationMs: finishedAt.Sub(startedAt).Milliseconds()}
+	if err != nil {
+		e.Error = err.Error()
+	}
+	r.add(e)
+}
+
+// Strategy records the auth source used by the job, and a fingerprint of the account or key it used, if any
+func (r *TraceRecorder) Strategy(source AuthSource, credential string) {
+	e := TraceEvent{Kind: TraceStrategy, Name: string(source)}
+	if credential != "" {
+		e.Credential = CredentialFingerprint(credential)
+	}
+	r.add(e)
+}
+
+// HTTPRequest describes an HTTP request made by a job, once its response headers have been received or it failed
+type HTTPRequest struct {
+	Method     string
+	URL        *url.URL
+	StatusCode int
+	StartedAt  time.Time
+	Duration   time.Duration
+	Err        error
+}
+
+// HTTP records an HTTP request. It is meant to be used as the observer of the bandwidth meter of the job.
+func (r *TraceRecorder) HTTP(req HTTPRequest) {
+	e := TraceEvent{
+		Time:       req.StartedAt,
+		Kind:       TraceHTTP,
+		Method:     req.Method,
+		URL:        redactURL(req.URL),
+		StatusCode: req.StatusCode,
+		DurationMs: req.Duration.Milliseconds(),
+	}
+	if req.Err != nil {
+		// Errors of the HTTP client quote the full URL, including the query
+		err := req.Err
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		e.Error = err.Error()
+	}
+	r.add(e)
+}
+
+// ActorRun records an Apify actor run
+func (r *TraceRecorder) ActorRun(runID string) {
+	r.add(TraceEvent{Kind: TraceActorRun, Name: runID})
+}
+
+// Retry records that the job is executed again after the given delay, because it failed with err
+func (r *TraceRecorder) Retry(delay time.Duration, err error) {
+	r.add(TraceEvent{Kind: TraceRetry, DurationMs: delay.Milliseconds(), Error: err.Error()})
+}
+
+// Trace returns the events recorded so far, or nil for a nil recorder
+func (r *TraceRecorder) Trace() *JobTrace {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &JobTrace{JobUUID: r.jobUUID, Events: slices.Clone(r.events), DroppedEvents: r.dropped}
+}
+
+// CredentialFingerprint identifies an account or key in a trace without revealing it, like the fingerprints of
+// Twitter API keys
+func CredentialFingerprint(credential string) string {
+	sum := sha256.Sum256([]byte(credential))
+	return hex.EncodeToString(sum[:4])
+}
+
+// redactURL returns the URL without user info, query and fragment, which may hold credentials
+func redactURL(u *url.URL) string {
+	if u == nil {
+		return ""
+	}
+	return (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path}).String()
+}
@@ -0,0 +1,11 @@
+package types
+
+// ResultValidation reports the items of a job result which failed the validation of their job type and were dropped
+type ResultValidation struct {
+	// Items is the number of items of the result before validation
+	Items int `json:"items"`
+	// Invalid is the number of items which were dropped
+	Invalid int `json:"invalid"`
+	// Reasons is the number of dropped items by the reason they are invalid, e.g. "tweet_id is missing"
+	Reasons map[string]int `json:"reasons,omitempty"`
+}
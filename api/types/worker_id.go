@@ -0,0 +1,37 @@
+package types
+
+import (
+	"crypto/sha256"
+	"time"
+)
+
+// RetiredWorkerID is a worker ID which was replaced by a rotation. Jobs targeting it are accepted until ValidUntil.
+type RetiredWorkerID struct {
+	ID         string    `json:"id"`
+	RotatedAt  time.Time `json:"rotated_at"`
+	ValidUntil time.Time `json:"valid_until"`
+	ReplacedBy string    `json:"replaced_by"`
+	Reason     string    `json:"reason,omitempty"`
+}
+
+// WorkerIDRotation is returned by the worker ID rotation endpoint. RetiredWorkerIDs are all the previous worker IDs,
+// which lets the key distributor and the miners carry the reputation of the worker over to the new one. Outside of
+// standalone mode, Quote is a fresh SGX quote whose report data is ReportData, i.e. WorkerIDRotationReportData of the
+// rotation, proving that both worker IDs belong to the same enclave.
+type WorkerIDRotation struct {
+	WorkerID           string            `json:"worker_id"`
+	PreviousWorkerID   string            `json:"previous_worker_id"`
+	RotatedAt          time.Time         `json:"rotated_at"`
+	PreviousValidUntil time.Time         `json:"previous_valid_until"`
+	Reason             string            `json:"reason,omitempty"`
+	RetiredWorkerIDs   []RetiredWorkerID `json:"retired_worker_ids"`
+	ReportData         string            `json:"report_data,omitempty"`
+	Quote              string            `json:"quote,omitempty"`
+}
+
+// WorkerIDRotationReportData returns the report data embedded in the quote of a worker ID rotation: the SHA-256 hash
+// of the previous worker ID, the new worker ID and the time of the rotation in RFC 3339 format, separated by newlines
+func WorkerIDRotationReportData(previousWorkerID, workerID string, rotatedAt time.Time) []byte {
+	sum := sha256.Sum256([]byte(previousWorkerID + "\n" + workerID + "\n" + rotatedAt.UTC().Format(time.RFC3339Nano)))
+	return sum[:]
+}
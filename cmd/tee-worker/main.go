@@ -2,19 +2,84 @@ package main
 
 import (
 	"context"
+	"errors"
+	"flag"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/apify"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
+	"github.com/masa-finance/tee-worker/internal/tracing"
+	"github.com/masa-finance/tee-worker/pkg/client"
 	"github.com/masa-finance/tee-worker/pkg/tee"
 	"github.com/sirupsen/logrus"
 )
 
 func main() {
+	validateOnly := flag.Bool("validate-config", false, "validate the configuration, report every problem found and exit")
+	setSecret := flag.String("set-secret", "", "store the value read from stdin as the named secret in the sealed secrets file and exit")
+	deleteSecret := flag.String("delete-secret", "", "delete the named secret from the sealed secrets file and exit")
+	listSecrets := flag.Bool("list-secrets", false, "list the names of the secrets in the sealed secrets file and exit")
+	flag.Parse()
+
+	// The secrets are managed before the configuration is validated, since it may reference secrets which don't exist yet
+	if *setSecret != "" || *deleteSecret != "" || *listSecrets {
+		if err := manageSecrets(config.SecretsFilePath(os.Getenv), *setSecret, *deleteSecret, *listSecrets); err != nil {
+			logrus.Fatalf("Failed to update the secrets file: %v", err)
+		}
+		return
+	}
+
 	jc := config.ReadConfig()
+
+	// Misconfigured workers fail at startup with all their problems, instead of when their jobs run into them
+	if problems := config.Validate(os.Environ()); len(problems) > 0 {
+		for _, p := range problems {
+			logrus.Errorf("Invalid configuration: %s", p)
+		}
+		logrus.Fatalf("Found %d configuration problems. Exiting...", len(problems))
+	}
+	if *validateOnly {
+		logrus.Info("Configuration is valid")
+		return
+	}
+
 	listenAddress := jc.ListenAddress()
 
 	tee.SealStandaloneMode = jc.IsStandaloneMode()
 
+	// All the HTTP clients created afterwards share the tuned connection pool
+	httpConfig := jc.GetHTTPClientConfig()
+	client.SetDefaults(
+		client.MaxConnsPerHost(httpConfig.MaxConnsPerHost),
+		client.MaxIdleConns(httpConfig.MaxIdleConns),
+		client.MaxIdleConnsPerHost(httpConfig.MaxIdleConnsPerHost),
+		client.IdleConnTimeout(httpConfig.IdleConnTimeout),
+		client.DialTimeout(httpConfig.DialTimeout),
+		client.TLSSessionCache(httpConfig.TLSSessionCacheSize),
+		client.HTTP2(httpConfig.HTTP2),
+	)
+
+	// The scrapers present the same browser profiles to each site until they are blocked
+	if err := fingerprint.Configure(jc.GetString("fingerprint_profiles_file", "")); err != nil {
+		logrus.Fatalf("Invalid FINGERPRINT_PROFILES_FILE: %v", err)
+	}
+
+	// The actors have to be configured before any worker is created, and pinned builds must exist
+	if err := apify.Configure(jc.GetStringSlice("apify_actors", nil)); err != nil {
+		logrus.Fatalf("Invalid APIFY_ACTORS: %v", err)
+	}
+	if apiKey := jc.GetString("apify_api_key", ""); apiKey != "" && len(apify.ActorBuilds) > 0 {
+		if err := client.ValidateActorBuilds(apiKey); errors.Is(err, client.ErrActorBuildNotFound) {
+			logrus.Fatalf("Invalid APIFY_ACTORS: %v", err)
+		} else if err != nil {
+			logrus.Warnf("Failed to validate the pinned Apify actor builds: %v", err)
+		}
+	}
+
 	if tee.KeyDistributorPubKey != "" {
 		logrus.Info("This instance will allow only ", tee.KeyDistributorPubKey, " to set the sealing keys")
 	}
@@ -28,8 +93,23 @@ func main() {
 	// Set the worker ID in the job configuration
 	jc["worker_id"] = tee.WorkerID
 
+	// Shut down gracefully on SIGINT and SIGTERM, so state such as the statistics is saved before exiting
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Export the spans of the API requests and the jobs, if OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Setup(ctx, jc.GetString("otel_exporter_otlp_endpoint", ""), tee.WorkerID)
+	if err != nil {
+		logrus.Fatalf("Failed to set up tracing: %v", err)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			logrus.Warnf("Failed to flush the spans: %v", err)
+		}
+	}()
+
 	// Start the API
-	if err := api.Start(context.Background(), listenAddress, jc.DataDir(), jc.IsStandaloneMode(), jc); err != nil {
+	if err := api.Start(ctx, listenAddress, jc.DataDir(), jc.IsStandaloneMode(), jc); err != nil {
 		panic(err)
 	}
 
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// manageSecrets runs the secrets file commands of the flags. Values are read from stdin, so they don't end up in the
+// shell history or the process list.
+func manageSecrets(path, set, del string, list bool) error {
+	secrets := tee.NewSecretsFile(path)
+
+	switch {
+	case set != "":
+		value, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil && err != io.EOF {
+			return fmt.Errorf("error reading the secret from stdin: %w", err)
+		}
+		value = strings.TrimRight(value, "\r\n")
+		if value == "" {
+			return fmt.Errorf("the value of secret %s read from stdin is empty", set)
+		}
+		return secrets.SetSecret(set, value)
+
+	case del != "":
+		return secrets.DeleteSecret(del)
+
+	case list:
+		names, err := secrets.Names()
+		if err != nil {
+			return err
+		}
+		for _, name := range names {
+			fmt.Println(name)
+		}
+	}
+	return nil
+}
@@ -1,8 +1,12 @@
 package api_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
 	"time"
 
@@ -15,6 +19,7 @@ import (
 	. "github.com/masa-finance/tee-worker/internal/api"
 	"github.com/masa-finance/tee-worker/internal/config"
 	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 )
 
 var _ = Describe("API", func() {
@@ -106,6 +111,96 @@ var _ = Describe("API", func() {
 		Expect(result).NotTo(BeEmpty())
 	})
 
+	It("should return the result in the requested format", func() {
+		jobSignature, err := clientInstance.CreateJobSignature(types.Job{Type: teetypes.TelemetryJob})
+		Expect(err).NotTo(HaveOccurred())
+
+		// Seal a result with the nonce of the job, like the job server does
+		jobJSON, err := tee.Unseal(string(jobSignature))
+		Expect(err).NotTo(HaveOccurred())
+		var job types.Job
+		Expect(json.Unmarshal(jobJSON, &job)).To(Succeed())
+		encryptedResult, err := tee.SealWithKey(job.Nonce, []byte(`[{"id":"1","text":"gm, \"frens\"","likes":3},{"id":"2","tags":["a","b"]}]`))
+		Expect(err).NotTo(HaveOccurred())
+
+		result, err := clientInstance.Decrypt(jobSignature, encryptedResult)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(HavePrefix(`[{"id":"1"`))
+
+		result, err = clientInstance.DecryptAs(jobSignature, encryptedResult, types.ResultFormatNDJSON)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("{\"id\":\"1\",\"text\":\"gm, \\\"frens\\\"\",\"likes\":3}\n{\"id\":\"2\",\"tags\":[\"a\",\"b\"]}\n"))
+
+		result, err = clientInstance.DecryptAs(jobSignature, encryptedResult, types.ResultFormatCSV)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal("id,likes,tags,text\n1,3,,\"gm, \"\"frens\"\"\"\n2,,\"[\"\"a\"\",\"\"b\"\"]\",\n"))
+
+		post := func(format types.ResultFormat, accept string) (*http.Response, string) {
+			body, _ := json.Marshal(types.EncryptedRequest{EncryptedResult: encryptedResult, EncryptedRequest: string(jobSignature), ResultFormat: format})
+			req, _ := http.NewRequest(http.MethodPost, "http://localhost:40912/job/result", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("Accept", accept)
+			resp, err := http.DefaultClient.Do(req)
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			data, _ := io.ReadAll(resp.Body)
+			return resp, string(data)
+		}
+
+		resp, body := post("", "text/csv;q=0.9, application/json")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/csv"))
+		Expect(body).To(HavePrefix("id,likes,tags,text\n"))
+
+		resp, body = post("protobuf", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(Equal("application/x-protobuf"))
+		Expect(body).NotTo(BeEmpty())
+
+		resp, body = post("xml", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusBadRequest))
+		Expect(body).To(ContainSubstring("unknown result format"))
+	})
+
+	It("should report the capabilities of all job types", func() {
+		caps, err := clientInstance.GetCapabilities()
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(caps.Capabilities).To(HaveKey(teetypes.TelemetryJob))
+		Expect(caps.Details).To(HaveKey(teetypes.TiktokJob))
+		Expect(caps.Details[teetypes.TiktokJob]).To(ContainElement(types.CapabilityDetail{
+			Capability: teetypes.CapTranscription,
+			AuthSource: types.AuthSourceNone,
+		}))
+	})
+
+	It("should submit a batch of jobs and report its status", func() {
+		telemetry, err := clientInstance.CreateJobSignature(types.Job{Type: teetypes.TelemetryJob})
+		Expect(err).NotTo(HaveOccurred())
+		unknown, err := clientInstance.CreateJobSignature(types.Job{Type: "not-existing scraper"})
+		Expect(err).NotTo(HaveOccurred())
+
+		batch, err := clientInstance.SubmitBatch([]client.JobSignature{telemetry, "not a signature", unknown})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(batch.BatchID).NotTo(BeEmpty())
+		Expect(batch.Jobs).To(HaveLen(3))
+		Expect(batch.Jobs[0].UID).NotTo(BeEmpty())
+		Expect(batch.Jobs[1].UID).To(BeEmpty())
+		Expect(batch.Jobs[1].Error).NotTo(BeNil())
+		Expect(batch.Jobs[2].UID).NotTo(BeEmpty())
+
+		Eventually(func() (*types.BatchStatus, error) {
+			return clientInstance.GetBatchStatus(batch.BatchID)
+		}, 10*time.Second).Should(And(
+			HaveField("Total", 2),
+			HaveField("Succeeded", 1),
+			HaveField("Failed", 1),
+		))
+
+		_, err = clientInstance.GetBatchStatus("unknown")
+		Expect(err).To(MatchError("batch not found"))
+	})
+
 	It("bubble up errors", func() {
 		// Step 1: Create the job request
 		job := types.Job{
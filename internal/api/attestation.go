@@ -0,0 +1,52 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// RemoteReportFunc generates an SGX quote embedding the given report data, e.g. enclave.GetRemoteReport
+type RemoteReportFunc func(reportData []byte) ([]byte, error)
+
+// Attestation returns a fresh quote binding the worker ID, the hash of the current capabilities and the nonce chosen
+// by the caller, so miners can check that the worker is still running in the enclave before assigning it sensitive
+// jobs. If remoteReport is nil, i.e. in standalone mode, no quote can be generated.
+func Attestation(jobServer *jobserver.JobServer, remoteReport RemoteReportFunc) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		nonce := c.QueryParam("nonce")
+		if nonce == "" {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: "nonce is required"})
+		}
+		if len(nonce) > types.MaxAttestationNonceLength {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("nonce must be at most %d bytes", types.MaxAttestationNonceLength)})
+		}
+		if remoteReport == nil {
+			return c.JSON(http.StatusServiceUnavailable, types.JobError{Error: "attestation is not available in standalone mode"})
+		}
+
+		workerID := tee.CurrentWorkerID()
+		capabilitiesHash := types.CapabilitiesHash(jobServer.GetWorkerCapabilities())
+		reportData := types.AttestationReportData(workerID, capabilitiesHash, nonce)
+		quote, err := remoteReport(reportData)
+		if err != nil {
+			logrus.Errorf("Error while generating the attestation quote: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: "failed to generate quote"})
+		}
+
+		return c.JSON(http.StatusOK, types.AttestationResponse{
+			WorkerID:         workerID,
+			CapabilitiesHash: capabilitiesHash,
+			Nonce:            nonce,
+			ReportData:       hex.EncodeToString(reportData),
+			Quote:            base64.StdEncoding.EncodeToString(quote),
+		})
+	}
+}
@@ -0,0 +1,91 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Attestation Endpoint", func() {
+	var (
+		jobServer  *jobserver.JobServer
+		reportData []byte
+		reportErr  error
+		quote      RemoteReportFunc
+	)
+
+	BeforeEach(func() {
+		jobServer = jobserver.NewJobServer(1, config.JobConfiguration{})
+		reportData, reportErr = nil, nil
+		quote = func(data []byte) ([]byte, error) {
+			reportData = data
+			return append([]byte("quote:"), data...), reportErr
+		}
+
+		originalWorkerID := tee.WorkerID
+		tee.WorkerID = "worker-1"
+		DeferCleanup(func() { tee.WorkerID = originalWorkerID })
+	})
+
+	get := func(remoteReport RemoteReportFunc, query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/attestation"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(Attestation(jobServer, remoteReport)(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	It("should return a quote binding the worker ID, the capabilities and the nonce", func() {
+		rec := get(quote, "?nonce=abc123")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var resp types.AttestationResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.WorkerID).To(Equal("worker-1"))
+		Expect(resp.Nonce).To(Equal("abc123"))
+		Expect(resp.CapabilitiesHash).To(Equal(types.CapabilitiesHash(jobServer.GetWorkerCapabilities())))
+
+		expected := types.AttestationReportData("worker-1", resp.CapabilitiesHash, "abc123")
+		Expect(reportData).To(Equal(expected))
+		Expect(resp.ReportData).To(Equal(hex.EncodeToString(expected)))
+		Expect(resp.Quote).To(Equal(base64.StdEncoding.EncodeToString(append([]byte("quote:"), expected...))))
+	})
+
+	It("should bind every quote to its own nonce", func() {
+		get(quote, "?nonce=first")
+		first := reportData
+		get(quote, "?nonce=second")
+		Expect(reportData).NotTo(Equal(first))
+	})
+
+	It("should require a nonce of limited length", func() {
+		Expect(get(quote, "").Code).To(Equal(http.StatusBadRequest))
+		Expect(get(quote, "?nonce="+strings.Repeat("a", types.MaxAttestationNonceLength+1)).Code).To(Equal(http.StatusBadRequest))
+		Expect(reportData).To(BeNil())
+	})
+
+	It("should not be available in standalone mode", func() {
+		Expect(get(nil, "?nonce=abc123").Code).To(Equal(http.StatusServiceUnavailable))
+	})
+
+	It("should fail if no quote can be generated", func() {
+		reportErr = errors.New("no SGX device")
+		rec := get(quote, "?nonce=abc123")
+		Expect(rec.Code).To(Equal(http.StatusInternalServerError))
+		Expect(rec.Body.String()).NotTo(ContainSubstring("no SGX device"))
+	})
+})
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditLog returns a range of the audit log of the jobs, from the entry number given by the from query parameter to
+// the one given by to, both optional. The requester query parameter only returns the entries of the jobs of that
+// miner. If a nonce is given and
+// remoteReport is not nil, the range is returned with a quote vouching for its last entry, see types.AuditLog.
+func AuditLog(jobServer *jobserver.JobServer, remoteReport RemoteReportFunc) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		var from, to uint64
+		for _, p := range []struct {
+			name string
+			v    *uint64
+		}{{"from", &from}, {"to", &to}} {
+			if s := c.QueryParam(p.name); s != "" {
+				v, err := strconv.ParseUint(s, 10, 64)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("invalid %s: %q", p.name, s)})
+				}
+				*p.v = v
+			}
+		}
+		nonce := c.QueryParam("nonce")
+		if len(nonce) > types.MaxAttestationNonceLength {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("nonce must be at most %d bytes", types.MaxAttestationNonceLength)})
+		}
+
+		log, err := jobServer.AuditLog(from, to, c.QueryParam("requester"))
+		switch {
+		case errors.Is(err, types.ErrNotConfigured):
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "the audit log is not enabled"})
+		case err != nil:
+			logrus.Errorf("Error while reading the audit log: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+
+		log.WorkerID = tee.CurrentWorkerID()
+		if nonce != "" && remoteReport != nil {
+			quote, err := remoteReport(types.AuditReportData(log.WorkerID, log.Last, log.LastHash, nonce))
+			if err != nil {
+				logrus.Errorf("Error while generating the quote of the audit log: %s", err)
+				return c.JSON(http.StatusInternalServerError, types.JobError{Error: "failed to generate quote"})
+			}
+			log.Nonce = nonce
+			log.Quote = base64.StdEncoding.EncodeToString(quote)
+		}
+		return c.JSON(http.StatusOK, log)
+	}
+}
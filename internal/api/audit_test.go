@@ -0,0 +1,85 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Audit Endpoint", func() {
+	var (
+		jobServer  *jobserver.JobServer
+		reportData []byte
+		quote      RemoteReportFunc
+	)
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		originalWorkerID := tee.WorkerID
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		tee.WorkerID = "worker-1"
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+			tee.WorkerID = originalWorkerID
+		})
+
+		jobServer = jobserver.NewJobServer(1, config.JobConfiguration{"data_dir": GinkgoT().TempDir(), "audit_log_enabled": true})
+		reportData = nil
+		quote = func(data []byte) ([]byte, error) {
+			reportData = data
+			return append([]byte("quote:"), data...), nil
+		}
+	})
+
+	get := func(jobServer *jobserver.JobServer, query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodGet, "/audit"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(AuditLog(jobServer, quote)(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	It("should return the log with a quote of its last entry bound to the nonce", func() {
+		rec := get(jobServer, "?from=1&nonce=abc123")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var log types.AuditLog
+		Expect(json.Unmarshal(rec.Body.Bytes(), &log)).To(Succeed())
+		Expect(log.Verified).To(BeTrue())
+		Expect(log.WorkerID).To(Equal("worker-1"))
+		Expect(log.Nonce).To(Equal("abc123"))
+
+		expected := types.AuditReportData("worker-1", log.Last, log.LastHash, "abc123")
+		Expect(reportData).To(Equal(expected))
+		Expect(log.Quote).To(Equal(base64.StdEncoding.EncodeToString(append([]byte("quote:"), expected...))))
+	})
+
+	It("should not generate a quote without a nonce", func() {
+		Expect(get(jobServer, "").Code).To(Equal(http.StatusOK))
+		Expect(reportData).To(BeNil())
+	})
+
+	It("should reject an invalid range", func() {
+		Expect(get(jobServer, "?from=first").Code).To(Equal(http.StatusBadRequest))
+		Expect(get(jobServer, "?to=-1").Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should return 404 if the audit log is not enabled", func() {
+		Expect(get(jobserver.NewJobServer(1, config.JobConfiguration{}), "").Code).To(Equal(http.StatusNotFound))
+	})
+})
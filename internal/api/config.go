@@ -0,0 +1,33 @@
+package api
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// WorkerConfig returns the effective job configuration, as returned by current, with the credentials redacted, and
+// the job types the worker can run. This lets operators compare the configuration of their workers without access
+// to the enclaves.
+func WorkerConfig(jobServer *jobserver.JobServer, current func() config.JobConfiguration) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		jobTypes := []teetypes.JobType{}
+		for jobType := range jobServer.GetWorkerCapabilities() {
+			jobTypes = append(jobTypes, jobType)
+		}
+		slices.Sort(jobTypes)
+
+		return c.JSON(http.StatusOK, types.ConfigResponse{
+			WorkerID:         tee.CurrentWorkerID(),
+			RetiredWorkerIDs: retiredWorkerIDs(),
+			JobTypes:         jobTypes,
+			Configuration:    current().Redacted(),
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"embed"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// dashboardFiles is the web dashboard served in standalone mode. It submits jobs and fetches their results through
+// the public job endpoints, so it needs nothing else from the server than the overview.
+//
+//go:embed dashboard/index.html
+var dashboardFiles embed.FS
+
+// dashboard serves the page of the web dashboard
+func dashboard(c echo.Context) error {
+	page, err := dashboardFiles.ReadFile("dashboard/index.html")
+	if err != nil {
+		return c.String(http.StatusInternalServerError, err.Error())
+	}
+	return c.HTMLBlob(http.StatusOK, page)
+}
+
+// dashboardOverview returns what the dashboard shows about the worker: its queue depth, statistics and the arguments
+// accepted by each job type
+func dashboardOverview(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		stats, err := jobServer.GetStats()
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+
+		capabilities := jobServer.GetWorkerCapabilities()
+		arguments := make(map[teetypes.JobType][]string, len(capabilities))
+		for jobType := range capabilities {
+			if keys, ok := jobServer.ArgumentKeys(jobType); ok {
+				arguments[jobType] = keys
+			}
+		}
+
+		return c.JSON(http.StatusOK, types.DashboardOverview{
+			WorkerID:     tee.CurrentWorkerID(),
+			Queue:        jobServer.GetQueueStatus(),
+			Capabilities: capabilities,
+			Arguments:    arguments,
+			Stats:        stats,
+		})
+	}
+}
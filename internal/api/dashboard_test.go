@@ -0,0 +1,72 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+var _ = Describe("Dashboard", func() {
+	const baseURL = "http://127.0.0.1:40913"
+
+	get := func(path string, apiKey string) (*http.Response, []byte) {
+		req, err := http.NewRequest(http.MethodGet, baseURL+path, nil)
+		Expect(err).NotTo(HaveOccurred())
+		if apiKey != "" {
+			req.Header.Set("X-API-Key", apiKey)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return resp, body
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		go func() {
+			defer GinkgoRecover()
+			Start(ctx, "127.0.0.1:40913", "", true, config.JobConfiguration{"api_key": "secret"})
+		}()
+
+		Eventually(func() error {
+			_, err := http.Get(baseURL + HealthCheckPath)
+			return err
+		}, 10*time.Second).Should(Succeed())
+	})
+
+	It("should serve the page without an API key", func() {
+		resp, body := get(DashboardPath, "")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(resp.Header.Get("Content-Type")).To(HavePrefix("text/html"))
+		Expect(string(body)).To(ContainSubstring("/ui/overview"))
+	})
+
+	It("should return the queue, capabilities and arguments of the worker", func() {
+		resp, _ := get(DashboardPath+"/overview", "")
+		Expect(resp.StatusCode).To(Equal(http.StatusUnauthorized))
+
+		resp, body := get(DashboardPath+"/overview", "secret")
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+
+		var overview types.DashboardOverview
+		Expect(json.Unmarshal(body, &overview)).To(Succeed())
+		Expect(overview.Queue).To(Equal(types.QueueStatus{}))
+		Expect(overview.Capabilities).To(HaveKey(teetypes.TiktokJob))
+		Expect(overview.Arguments[teetypes.TiktokJob]).To(ContainElements("type", "video_url"))
+		Expect(string(overview.Stats)).To(ContainSubstring(`"stats"`))
+	})
+})
@@ -0,0 +1,554 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/graph-gophers/graphql-go"
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// GraphQLPath is the endpoint of the GraphQL API, which is only served if GRAPHQL_ENABLED is set
+const GraphQLPath = "/graphql"
+
+const (
+	// graphqlMaxDepth is the deepest nesting of fields a request may select
+	graphqlMaxDepth = 10
+	// graphqlMaxQueryLength is the maximum length of a query in bytes, which bounds the number of fields it selects
+	graphqlMaxQueryLength = 8 << 10
+)
+
+// graphqlQuerySchema is the schema of the GraphQL API without its mutations, which is served over GET. It exposes
+// the same data as the REST endpoints, with the same field names.
+const graphqlQuerySchema = `
+"Any JSON value"
+scalar JSON
+
+type Query {
+  capabilities: Capabilities!
+  stats: Stats!
+  queue: QueueStatus!
+  "The status and sealed result of a job. wait is the number of seconds to wait for a pending job to finish."
+  job(id: ID!, wait: Int): JobStatus
+  batch(id: ID!): BatchStatus
+}
+
+type RateLimitEstimate {
+  requests: Int!
+  window_seconds: Int!
+}
+
+"How the worker provides a capability"
+type CapabilityDetail {
+  capability: String!
+  auth_source: String!
+  rate_limit: RateLimitEstimate
+  full_archive: Boolean!
+}
+
+"A job type the worker can execute"
+type JobType {
+  job_type: String!
+  capabilities: [String!]!
+  details: [CapabilityDetail!]!
+  "The arguments accepted by the job type, if known"
+  arguments: [String!]
+}
+
+type Capabilities {
+  worker_id: String!
+  job_types: [JobType!]!
+}
+
+"The statistics of the worker, as reported by the telemetry job"
+type Stats {
+  boot_time: Int!
+  last_operation_time: Int!
+  current_time: Int!
+  worker_id: String!
+  worker_version: String!
+  application_version: String!
+  stats: JSON
+  breakdowns: JSON
+  reported_capabilities: JSON
+  key_capabilities: JSON
+  apify_costs: JSON
+  benchmark: JSON
+}
+
+type QueueStatus {
+  pending: Int!
+  interactive: Int!
+  economy_queued: Int!
+  economy_running: Int!
+  recurring: Int!
+  deferred: Int!
+  memory_deferred: Int!
+}
+
+"The outcome of a provider or query a job fanned out to"
+type FanOutStatus {
+  provider: String
+  query: String
+  success: Boolean!
+  error_code: String
+  error: String
+  items: Int!
+}
+
+"The resources used to execute a job. The numbers of bytes are floats, since they can exceed the range of Int."
+type Usage {
+  bytes_downloaded: Float!
+  bytes_uploaded: Float!
+  bandwidth_cap: Float
+  truncated: Boolean
+}
+
+type JobStatus {
+  id: ID!
+  "pending, succeeded or failed"
+  state: String!
+  "The sealed result of a successful job"
+  result: String
+  error: String
+  partial: Boolean!
+  cached: Boolean!
+  fan_out: [FanOutStatus!]
+  usage: Usage
+}
+
+type BatchJob {
+  uid: ID!
+  state: String!
+}
+
+type BatchStatus {
+  batch_id: ID!
+  total: Int!
+  pending: Int!
+  succeeded: Int!
+  failed: Int!
+  expired: Int!
+  jobs: [BatchJob!]!
+}
+`
+
+// graphqlMutationSchema adds the mutations to graphqlQuerySchema
+const graphqlMutationSchema = `
+type Mutation {
+  "Adds a job to the queue, like /job/add. Several jobs can be added in one request with aliases."
+  add_job(encrypted_job: String!): JobSubmission
+}
+
+type JobSubmission {
+  uid: ID!
+  cached: Boolean!
+}
+`
+
+// graphqlJSON is the JSON scalar, for values such as maps keyed by job type. It is only used in results.
+type graphqlJSON json.RawMessage
+
+func (graphqlJSON) ImplementsGraphQLType(name string) bool {
+	return name == "JSON"
+}
+
+func (j *graphqlJSON) UnmarshalGraphQL(any) error {
+	return errors.New("JSON values can't be arguments")
+}
+
+func (j graphqlJSON) MarshalJSON() ([]byte, error) {
+	return j, nil
+}
+
+// jsonValue returns a nullable JSON value, which is null if the field was left out
+func jsonValue(raw json.RawMessage) *graphqlJSON {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	j := graphqlJSON(raw)
+	return &j
+}
+
+// optionalString returns a nullable string, which is null if it is empty
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type graphqlRateLimit struct {
+	Requests      int32
+	WindowSeconds int32
+}
+
+type graphqlCapabilityDetail struct {
+	Capability  string
+	AuthSource  string
+	RateLimit   *graphqlRateLimit
+	FullArchive bool
+}
+
+// graphqlJobType is a job type as returned by the capabilities query
+type graphqlJobType struct {
+	JobType      string
+	Capabilities []string
+	Details      []*graphqlCapabilityDetail
+	Arguments    *[]string
+}
+
+type graphqlCapabilities struct {
+	WorkerID string
+	JobTypes []*graphqlJobType
+}
+
+// graphqlStats are the statistics of the worker, decoded from the JSON reported by the telemetry job
+type graphqlStats struct {
+	BootTime           int32           `json:"boot_time"`
+	LastOperationTime  int32           `json:"last_operation_time"`
+	CurrentTime        int32           `json:"current_time"`
+	WorkerID           string          `json:"worker_id"`
+	WorkerVersion      string          `json:"worker_version"`
+	ApplicationVersion string          `json:"application_version"`
+	RawStats           json.RawMessage `json:"stats"`
+	RawBreakdowns      json.RawMessage `json:"breakdowns"`
+	RawReported        json.RawMessage `json:"reported_capabilities"`
+	RawKeyCapabilities json.RawMessage `json:"key_capabilities"`
+	RawApifyCosts      json.RawMessage `json:"apify_costs"`
+	RawBenchmark       json.RawMessage `json:"benchmark"`
+}
+
+func (s *graphqlStats) Stats() *graphqlJSON                { return jsonValue(s.RawStats) }
+func (s *graphqlStats) Breakdowns() *graphqlJSON           { return jsonValue(s.RawBreakdowns) }
+func (s *graphqlStats) ReportedCapabilities() *graphqlJSON { return jsonValue(s.RawReported) }
+func (s *graphqlStats) KeyCapabilities() *graphqlJSON      { return jsonValue(s.RawKeyCapabilities) }
+func (s *graphqlStats) ApifyCosts() *graphqlJSON           { return jsonValue(s.RawApifyCosts) }
+func (s *graphqlStats) Benchmark() *graphqlJSON            { return jsonValue(s.RawBenchmark) }
+
+type graphqlQueueStatus struct {
+	Pending        int32
+	Interactive    int32
+	EconomyQueued  int32
+	EconomyRunning int32
+	Recurring      int32
+	Deferred       int32
+	MemoryDeferred int32
+}
+
+type graphqlFanOutStatus struct {
+	Provider  *string
+	Query     *string
+	Success   bool
+	ErrorCode *string
+	Error     *string
+	Items     int32
+}
+
+type graphqlUsage struct {
+	BytesDownloaded float64
+	BytesUploaded   float64
+	BandwidthCap    *float64
+	Truncated       *bool
+}
+
+// graphqlJobStatus is the status of a job as returned by the job query. Result is the sealed result, like the one
+// returned by /job/status.
+type graphqlJobStatus struct {
+	ID      graphql.ID
+	State   string
+	Result  *string
+	Error   *string
+	Partial bool
+	Cached  bool
+	FanOut  *[]*graphqlFanOutStatus
+	Usage   *graphqlUsage
+}
+
+type graphqlBatchJob struct {
+	UID   graphql.ID
+	State string
+}
+
+type graphqlBatchStatus struct {
+	BatchID   graphql.ID
+	Total     int32
+	Pending   int32
+	Succeeded int32
+	Failed    int32
+	Expired   int32
+	Jobs      []*graphqlBatchJob
+}
+
+type graphqlJobSubmission struct {
+	UID    graphql.ID
+	Cached bool
+}
+
+// graphqlResolver resolves the fields of the Query and Mutation types
+type graphqlResolver struct {
+	jc        config.JobConfiguration
+	jobServer *jobserver.JobServer
+	maxWait   time.Duration
+}
+
+// Capabilities returns the capabilities, capability details and accepted arguments of each job type, sorted by job
+// type
+func (r *graphqlResolver) Capabilities() *graphqlCapabilities {
+	caps := r.jobServer.GetWorkerCapabilities()
+	details := r.jobServer.GetCapabilityDetails()
+
+	res := &graphqlCapabilities{WorkerID: tee.CurrentWorkerID(), JobTypes: make([]*graphqlJobType, 0, len(caps))}
+	for _, jt := range slices.Sorted(maps.Keys(caps)) {
+		t := &graphqlJobType{JobType: jt.String(), Capabilities: make([]string, len(caps[jt])), Details: []*graphqlCapabilityDetail{}}
+		for i, c := range caps[jt] {
+			t.Capabilities[i] = string(c)
+		}
+		for _, d := range details[jt] {
+			detail := &graphqlCapabilityDetail{Capability: string(d.Capability), AuthSource: string(d.AuthSource), FullArchive: d.FullArchive}
+			if d.RateLimit != nil {
+				detail.RateLimit = &graphqlRateLimit{Requests: int32(d.RateLimit.Requests), WindowSeconds: int32(d.RateLimit.WindowSeconds)}
+			}
+			t.Details = append(t.Details, detail)
+		}
+		if args, ok := r.jobServer.ArgumentKeys(jt); ok {
+			t.Arguments = &args
+		}
+		res.JobTypes = append(res.JobTypes, t)
+	}
+	return res
+}
+
+func (r *graphqlResolver) Stats() (*graphqlStats, error) {
+	dat, err := r.jobServer.GetStats()
+	if err != nil {
+		return nil, err
+	}
+	stats := &graphqlStats{}
+	if err := json.Unmarshal(dat, stats); err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+func (r *graphqlResolver) Queue() *graphqlQueueStatus {
+	q := r.jobServer.GetQueueStatus()
+	return &graphqlQueueStatus{
+		Pending:        int32(q.Pending),
+		Interactive:    int32(q.Interactive),
+		EconomyQueued:  int32(q.EconomyQueued),
+		EconomyRunning: int32(q.EconomyRunning),
+		Recurring:      int32(q.Recurring),
+		Deferred:       int32(q.Deferred),
+		MemoryDeferred: int32(q.MemoryDeferred),
+	}
+}
+
+// Job returns the status of a job, waiting up to wait seconds, at most RESULT_MAX_WAIT_SECONDS, for a pending job to
+// finish
+func (r *graphqlResolver) Job(ctx context.Context, args struct {
+	ID   graphql.ID
+	Wait *int32
+}) (*graphqlJobStatus, error) {
+	var wait time.Duration
+	if args.Wait != nil {
+		if *args.Wait < 0 {
+			return nil, fmt.Errorf("invalid wait %d: must not be negative", *args.Wait)
+		}
+		wait = time.Duration(*args.Wait) * time.Second
+	}
+	return graphqlJobResult(ctx, r.jobServer, string(args.ID), min(wait, r.maxWait))
+}
+
+func (r *graphqlResolver) Batch(args struct{ ID graphql.ID }) (*graphqlBatchStatus, error) {
+	status, err := r.jobServer.GetBatchStatus(string(args.ID))
+	if err != nil {
+		return nil, err
+	}
+
+	res := &graphqlBatchStatus{
+		BatchID:   graphql.ID(status.BatchID),
+		Total:     int32(status.Total),
+		Pending:   int32(status.Pending),
+		Succeeded: int32(status.Succeeded),
+		Failed:    int32(status.Failed),
+		Expired:   int32(status.Expired),
+		Jobs:      make([]*graphqlBatchJob, len(status.Jobs)),
+	}
+	for i, j := range status.Jobs {
+		res.Jobs[i] = &graphqlBatchJob{UID: graphql.ID(j.UID), State: string(j.State)}
+	}
+	return res, nil
+}
+
+// AddJob adds a job to the queue, like /job/add
+func (r *graphqlResolver) AddJob(ctx context.Context, args struct{ EncryptedJob string }) (*graphqlJobSubmission, error) {
+	job, err := types.JobRequest{EncryptedJob: args.EncryptedJob}.DecryptJob()
+	if err != nil {
+		return nil, fmt.Errorf("error while decrypting job: %w", err)
+	}
+	if err := checkMiner(ctx, job); err != nil {
+		return nil, err
+	}
+	if violations := argumentViolations(r.jc, r.jobServer, job); len(violations) > 0 {
+		reasons := make([]string, len(violations))
+		for i, v := range violations {
+			reasons[i] = v.Argument + ": " + v.Reason
+		}
+		return nil, fmt.Errorf("invalid job arguments: %s", strings.Join(reasons, "; "))
+	}
+
+	res, err := r.jobServer.SubmitJobContext(ctx, *job)
+	if err != nil {
+		return nil, err
+	}
+	return &graphqlJobSubmission{UID: graphql.ID(res.UID), Cached: res.Cached}, nil
+}
+
+// graphqlJobResult returns the status of a job, waiting up to wait for a pending job to finish
+func graphqlJobResult(ctx context.Context, jobServer *jobserver.JobServer, id string, wait time.Duration) (*graphqlJobStatus, error) {
+	if !ownsJob(ctx, jobServer, id) {
+		return nil, errors.New("Job not found")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, wait)
+	defer cancel()
+
+	res, ok := jobServer.WaitForJobResult(ctx, id)
+	if !ok {
+		if jobServer.JobState(id) == types.BatchJobPending {
+			return &graphqlJobStatus{ID: graphql.ID(id), State: string(types.BatchJobPending)}, nil
+		}
+		return nil, errors.New("Job not found")
+	}
+
+	status := &graphqlJobStatus{ID: graphql.ID(id), FanOut: graphqlFanOut(res.FanOut), Usage: graphqlUsageOf(res.Usage)}
+	if res.Error != "" {
+		status.State = string(types.BatchJobFailed)
+		status.Error = &res.Error
+		return status, nil
+	}
+
+	sealed, err := res.Seal()
+	if err != nil {
+		logrus.Errorf("Error while sealing GraphQL job result for job %s: %s", id, err)
+		return nil, err
+	}
+	status.State = string(types.BatchJobSucceeded)
+	status.Result = &sealed
+	status.Partial = res.Partial() || res.Truncated()
+	status.Cached = res.Cached
+	return status, nil
+}
+
+func graphqlFanOut(statuses []types.FanOutStatus) *[]*graphqlFanOutStatus {
+	if len(statuses) == 0 {
+		return nil
+	}
+	fanOut := make([]*graphqlFanOutStatus, len(statuses))
+	for i, s := range statuses {
+		fanOut[i] = &graphqlFanOutStatus{
+			Provider:  optionalString(s.Provider),
+			Query:     optionalString(s.Query),
+			Success:   s.Success,
+			ErrorCode: optionalString(string(s.ErrorCode)),
+			Error:     optionalString(s.Error),
+			Items:     int32(s.Items),
+		}
+	}
+	return &fanOut
+}
+
+func graphqlUsageOf(usage *types.Usage) *graphqlUsage {
+	if usage == nil {
+		return nil
+	}
+	res := &graphqlUsage{BytesDownloaded: float64(usage.BytesDownloaded), BytesUploaded: float64(usage.BytesUploaded)}
+	if usage.BandwidthCap > 0 {
+		bandwidthCap := float64(usage.BandwidthCap)
+		res.BandwidthCap = &bandwidthCap
+	}
+	if usage.Truncated {
+		res.Truncated = &usage.Truncated
+	}
+	return res
+}
+
+// graphqlRequest is a GraphQL request, as sent in the body of a POST request
+type graphqlRequest struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+	Variables     map[string]any `json:"variables"`
+}
+
+// graphqlHandler serves the GraphQL API. POST requests contain a request or a list of requests, which are executed in
+// order and answered with a list of responses. GET requests execute the query parameter, which cannot be a mutation,
+// or return the schema if there is none.
+func graphqlHandler(jc config.JobConfiguration, jobServer *jobserver.JobServer) func(c echo.Context) error {
+	resolver := &graphqlResolver{jc: jc, jobServer: jobServer, maxWait: jc.GetDuration("result_max_wait_seconds", 30)}
+	opts := []graphql.SchemaOpt{
+		graphql.UseStringDescriptions(),
+		graphql.UseFieldResolvers(),
+		graphql.MaxDepth(graphqlMaxDepth),
+		graphql.MaxQueryLength(graphqlMaxQueryLength),
+	}
+	schema := graphql.MustParseSchema(graphqlQuerySchema+graphqlMutationSchema, resolver, opts...)
+	querySchema := graphql.MustParseSchema(graphqlQuerySchema, resolver, opts...)
+
+	return func(c echo.Context) error {
+		ctx := c.Request().Context()
+
+		if c.Request().Method == http.MethodGet {
+			query := c.QueryParam("query")
+			if query == "" {
+				return c.String(http.StatusOK, strings.TrimSpace(graphqlQuerySchema+graphqlMutationSchema)+"\n")
+			}
+
+			var variables map[string]any
+			if vars := c.QueryParam("variables"); vars != "" {
+				if err := json.Unmarshal([]byte(vars), &variables); err != nil {
+					return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("invalid variables: %s", err)})
+				}
+			}
+			return c.JSON(http.StatusOK, querySchema.Exec(ctx, query, c.QueryParam("operationName"), variables))
+		}
+
+		body, err := io.ReadAll(c.Request().Body)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("error reading request body: %s", err)})
+		}
+
+		if trimmed := bytes.TrimSpace(body); len(trimmed) > 0 && trimmed[0] == '[' {
+			var reqs []graphqlRequest
+			if err := json.Unmarshal(body, &reqs); err != nil {
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("invalid GraphQL request: %s", err)})
+			}
+			responses := make([]*graphql.Response, len(reqs))
+			for i, req := range reqs {
+				responses[i] = schema.Exec(ctx, req.Query, req.OperationName, req.Variables)
+			}
+			return c.JSON(http.StatusOK, responses)
+		}
+
+		var req graphqlRequest
+		if err := json.Unmarshal(body, &req); err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("invalid GraphQL request: %s", err)})
+		}
+		return c.JSON(http.StatusOK, schema.Exec(ctx, req.Query, req.OperationName, req.Variables))
+	}
+}
@@ -0,0 +1,146 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+var _ = Describe("GraphQL API", func() {
+	const baseURL = "http://127.0.0.1:40914"
+
+	post := func(body any) []byte {
+		dat, err := json.Marshal(body)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := http.Post(baseURL+GraphQLPath, "application/json", bytes.NewReader(dat))
+		Expect(err).NotTo(HaveOccurred())
+		defer resp.Body.Close()
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		res, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		return res
+	}
+
+	type response struct {
+		Data   map[string]json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+
+	BeforeEach(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+
+		go func() {
+			defer GinkgoRecover()
+			Start(ctx, "127.0.0.1:40914", "", true, config.JobConfiguration{"graphql_enabled": true})
+		}()
+
+		Eventually(func() error {
+			_, err := http.Get(baseURL + HealthCheckPath)
+			return err
+		}, 10*time.Second).Should(Succeed())
+	})
+
+	It("returns only the selected fields of the capabilities, queue and stats", func() {
+		var res response
+		Expect(json.Unmarshal(post(map[string]any{
+			"query": `{ capabilities { job_types { job_type arguments } } queue { pending } stats { boot_time } }`,
+		}), &res)).To(Succeed())
+		Expect(res.Errors).To(BeEmpty())
+
+		var caps struct {
+			JobTypes []map[string]any `json:"job_types"`
+		}
+		Expect(json.Unmarshal(res.Data["capabilities"], &caps)).To(Succeed())
+		Expect(caps.JobTypes).NotTo(BeEmpty())
+		for _, jt := range caps.JobTypes {
+			Expect(jt).To(HaveKey("job_type"))
+			Expect(jt).NotTo(HaveKey("capabilities"))
+		}
+		Expect(string(res.Data["queue"])).To(Equal(`{"pending":0}`))
+		Expect(string(res.Data["stats"])).To(MatchRegexp(`^\{"boot_time":\d+\}$`))
+	})
+
+	It("adds jobs and reports their status in batched requests", func() {
+		c, err := client.NewClient(baseURL)
+		Expect(err).NotTo(HaveOccurred())
+		signature, err := c.CreateJobSignature(types.Job{Type: teetypes.TelemetryJob, Arguments: map[string]any{}})
+		Expect(err).NotTo(HaveOccurred())
+
+		var added []response
+		Expect(json.Unmarshal(post([]map[string]any{{
+			"query":     `mutation ($job: String!) { first: add_job(encrypted_job: $job) { uid } second: add_job(encrypted_job: "invalid") { uid } }`,
+			"variables": map[string]any{"job": string(signature)},
+		}}), &added)).To(Succeed())
+		Expect(added).To(HaveLen(1))
+		Expect(added[0].Errors).To(HaveLen(1))
+		Expect(string(added[0].Data["second"])).To(Equal("null"))
+
+		var submission types.JobResponse
+		Expect(json.Unmarshal(added[0].Data["first"], &submission)).To(Succeed())
+		Expect(submission.UID).NotTo(BeEmpty())
+
+		var res []response
+		Expect(json.Unmarshal(post([]map[string]any{
+			{"query": `query ($id: ID!) { job(id: $id, wait: 5) { id state result error } }`, "variables": map[string]any{"id": submission.UID}},
+			{"query": `{ job(id: "unknown") { state } }`},
+		}), &res)).To(Succeed())
+		Expect(res).To(HaveLen(2))
+
+		Expect(res[0].Errors).To(BeEmpty())
+		var status map[string]any
+		Expect(json.Unmarshal(res[0].Data["job"], &status)).To(Succeed())
+		Expect(status).To(HaveKeyWithValue("id", submission.UID))
+		Expect(status).To(HaveKeyWithValue("state", "succeeded"))
+		Expect(status).To(HaveKeyWithValue("result", Not(BeEmpty())))
+
+		Expect(res[1].Errors).To(HaveLen(1))
+		Expect(res[1].Errors[0].Message).To(Equal("Job not found"))
+	})
+
+	It("serves the schema and queries, but not mutations, over GET", func() {
+		resp, err := http.Get(baseURL + GraphQLPath)
+		Expect(err).NotTo(HaveOccurred())
+		sdl, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(sdl)).To(ContainSubstring("type Query {"))
+		Expect(string(sdl)).To(ContainSubstring("add_job(encrypted_job: String!): JobSubmission"))
+
+		get := func(query string) response {
+			resp, err := http.Get(baseURL + GraphQLPath + "?query=" + url.QueryEscape(query))
+			Expect(err).NotTo(HaveOccurred())
+			defer resp.Body.Close()
+			var res response
+			Expect(json.NewDecoder(resp.Body).Decode(&res)).To(Succeed())
+			return res
+		}
+		Expect(get(`{ queue { recurring } }`).Errors).To(BeEmpty())
+		Expect(get(`mutation { add_job(encrypted_job: "x") { uid } }`).Errors[0].Message).To(ContainSubstring("no mutations are offered by the schema"))
+	})
+
+	It("rejects queries over the maximum length without executing them", func() {
+		var res response
+		Expect(json.Unmarshal(post(map[string]any{
+			"query": `{ queue { pending } }` + strings.Repeat(" ", 8<<10),
+		}), &res)).To(Succeed())
+		Expect(res.Data).To(BeEmpty())
+		Expect(res.Errors).NotTo(BeEmpty())
+	})
+})
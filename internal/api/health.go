@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/labstack/echo/v4"
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/jobserver"
 )
 
@@ -101,6 +102,8 @@ type ReadyzResponse struct {
 	Service string                 `json:"service"`
 	Ready   bool                   `json:"ready"`
 	Checks  ReadinessChecks        `json:"checks"`
+	// Queue is how busy the job queue is. Backpressure doesn't make the worker unready, since it still executes jobs.
+	Queue *types.QueueMetrics `json:"queue,omitempty"`
 }
 
 // ReadinessChecks contains individual readiness check results
@@ -108,6 +111,8 @@ type ReadinessChecks struct {
 	JobServer string                 `json:"job_server"`
 	ErrorRate string                 `json:"error_rate"`
 	Stats     map[string]interface{} `json:"stats,omitempty"`
+	// Dependencies is the status of each probed dependency
+	Dependencies map[string]DependencyStatus `json:"dependencies,omitempty"`
 }
 
 // Healthz is the liveness probe endpoint
@@ -121,8 +126,9 @@ func Healthz() func(c echo.Context) error {
 	}
 }
 
-// Readyz is the readiness probe endpoint
-func Readyz(jobServer *jobserver.JobServer, healthMetrics *HealthMetrics) func(c echo.Context) error {
+// Readyz is the readiness probe endpoint. The worker is not ready if any of the given dependency probes fails.
+func Readyz(jobServer *jobserver.JobServer, healthMetrics *HealthMetrics, probes ...DependencyProbe) func(c echo.Context) error {
+	checker := newDependencyChecker(probes)
 	return func(c echo.Context) error {
 		response := ReadyzResponse{
 			Service: "tee-worker",
@@ -137,6 +143,9 @@ func Readyz(jobServer *jobserver.JobServer, healthMetrics *HealthMetrics) func(c
 			return c.JSON(http.StatusServiceUnavailable, response)
 		}
 		
+		queue := jobServer.QueueMetrics()
+		response.Queue = &queue
+
 		// Check error rate
 		if !healthMetrics.IsHealthy() {
 			response.Ready = false
@@ -146,11 +155,24 @@ func Readyz(jobServer *jobserver.JobServer, healthMetrics *HealthMetrics) func(c
 			return c.JSON(http.StatusServiceUnavailable, response)
 		}
 		
-		// All checks passed
 		response.Checks.JobServer = "ok"
 		response.Checks.ErrorRate = "healthy"
 		response.Checks.Stats = healthMetrics.GetStats()
+
+		// Check the dependencies
+		if len(probes) > 0 {
+			response.Checks.Dependencies = checker.check()
+			for _, status := range response.Checks.Dependencies {
+				if status.Status == DependencyFailing {
+					response.Ready = false
+				}
+			}
+		}
+		if !response.Ready {
+			return c.JSON(http.StatusServiceUnavailable, response)
+		}
 		
+		// All checks passed
 		return c.JSON(http.StatusOK, response)
 	}
 }
\ No newline at end of file
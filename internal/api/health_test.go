@@ -144,6 +144,7 @@ var _ = Describe("Health Checks", func() {
 				Expect(rec.Body.String()).To(ContainSubstring(`"ready":true`))
 				Expect(rec.Body.String()).To(ContainSubstring(`"job_server":"ok"`))
 				Expect(rec.Body.String()).To(ContainSubstring(`"error_rate":"healthy"`))
+				Expect(rec.Body.String()).To(ContainSubstring(`"queue":{"depth":0,"running":0,"workers":10`))
 			})
 		})
 
@@ -0,0 +1,74 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/logging"
+)
+
+// logLevels returns the current log levels
+func logLevels() types.LogLevels {
+	level, modules := logging.Levels()
+	levels := types.LogLevels{Level: level.String()}
+	if len(modules) > 0 {
+		levels.Modules = make(map[string]string, len(modules))
+		for module, l := range modules {
+			levels.Modules[module] = l.String()
+		}
+	}
+	return levels
+}
+
+// getLogLevel returns the default log level and the log levels of the modules
+func getLogLevel(c echo.Context) error {
+	return c.JSON(http.StatusOK, logLevels())
+}
+
+// setLogLevel sets the log level given by the level query parameter, of the module query parameter if it is given,
+// otherwise the default one. The log level of Echo follows the one of the api module. Miners can't change log levels.
+func setLogLevel(e *echo.Echo) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if minerFromContext(c.Request().Context()) != "" {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: "the log level can't be changed with the API key of a miner"})
+		}
+
+		level, err := logging.ParseLevel(c.QueryParam("level"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+		if module := c.QueryParam("module"); module != "" {
+			if err := logging.SetModuleLevel(module, level); err != nil {
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+			}
+			logrus.Infof("Log level of module %s set to %s", module, level)
+		} else {
+			logging.SetLevel(level)
+			logrus.Infof("Log level set to %s", level)
+		}
+
+		e.Logger.SetLevel(parseLogLevel(logging.Level("api").String()))
+		return c.JSON(http.StatusOK, logLevels())
+	}
+}
+
+// resetLogLevel makes the module given by the module query parameter use the default log level again
+func resetLogLevel(e *echo.Echo) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if minerFromContext(c.Request().Context()) != "" {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: "the log level can't be changed with the API key of a miner"})
+		}
+
+		module := c.QueryParam("module")
+		if err := logging.ResetModuleLevel(module); err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+		logrus.Infof("Log level of module %s reset to the default", module)
+
+		e.Logger.SetLevel(parseLogLevel(logging.Level("api").String()))
+		return c.JSON(http.StatusOK, logLevels())
+	}
+}
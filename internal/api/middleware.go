@@ -1,20 +1,70 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
 	"net/http"
+	"slices"
 	"strings"
 
 	"github.com/labstack/echo/v4"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
 )
 
 const HealthCheckPath = "/healthz"
 const ReadinessCheckPath = "/readyz"
 
-// APIKeyAuthMiddleware returns an Echo middleware that checks for the API key in the request headers.
+// DashboardPath is the page of the standalone web dashboard. The page itself is served without an API key, since
+// browsers can't send one when navigating to it; the requests it makes include the key entered by the user.
+const DashboardPath = "/ui"
+
+// minerRoutes are the routes which accept the API key of a miner: submitting and estimating jobs, and getting the status
+// and results of the miner's own jobs. Every other route, e.g. the administration, the audit log or the Twitter
+// sessions, requires API_KEY.
+var minerRoutes = map[string]bool{
+	"/job/generate":         true,
+	"/job/add":              true,
+	"/job/estimate":         true,
+	"/job/status/:job_id":   true,
+	"/job/:job_id/stream":   true,
+	"/job/result":           true,
+	"/job/ws":               true,
+	"/jobs/batch":           true,
+	"/jobs/batch/:batch_id": true,
+	GraphQLPath:             true,
+}
+
+// peerRoutes are the routes which accept PEER_API_KEY, i.e. the ones the Delegator of a peer worker calls to attest this
+// worker and to delegate jobs to it
+var peerRoutes = map[string]bool{
+	"/capabilities":       true,
+	"/attestation":        true,
+	"/job/add":            true,
+	"/job/status/:job_id": true,
+}
+
+// APIKeyAuthMiddleware returns an Echo middleware that checks for the API key in the request headers. Requests can be
+// authenticated with API_KEY, or with the API key of a miner in MINER_API_KEYS, which is only accepted on minerRoutes,
+// only allows submitting the jobs of that miner and subjects them to its quotas, or with PEER_API_KEY, which is only
+// accepted on peerRoutes and marks the request as coming from a peer worker.
 func APIKeyAuthMiddleware(config config.JobConfiguration) echo.MiddlewareFunc {
 	apiKey := config.GetString("api_key", "")
-	if apiKey == "" {
+	peerKey := config.GetString("peer_api_key", "")
+	minerKeys := make(map[string]string)
+	for _, k := range config.GetMinerAPIKeys() {
+		minerKeys[k.Key] = k.Miner
+	}
+	// Without API_KEY and miner keys the API is open, but the requests of peers are still recognised by their key
+	open := apiKey == "" && len(minerKeys) == 0
+	if open && peerKey == "" {
 		// No API key set; allow all requests (no-op)
 		return func(next echo.HandlerFunc) echo.HandlerFunc {
 			return func(c echo.Context) error {
@@ -25,18 +75,39 @@ func APIKeyAuthMiddleware(config config.JobConfiguration) echo.MiddlewareFunc {
 
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
 		return func(c echo.Context) error {
-			// Skip auth for health check endpoints
+			// Skip auth for health check endpoints and the dashboard page
 			path := c.Request().URL.Path
-			if path == HealthCheckPath || path == ReadinessCheckPath {
+			if path == HealthCheckPath || path == ReadinessCheckPath || path == DashboardPath {
 				return next(c)
 			}
 
 			// Check Authorization: Bearer <API_KEY> or X-API-Key header
-			header := c.Request().Header.Get("Authorization")
-			if header == "Bearer "+apiKey {
-				return next(c)
+			bearer, _ := strings.CutPrefix(c.Request().Header.Get("Authorization"), "Bearer ")
+			for _, key := range []string{bearer, c.Request().Header.Get("X-API-Key")} {
+				if key == "" {
+					continue
+				}
+				if key == apiKey {
+					return next(c)
+				}
+				if key == peerKey {
+					if !peerRoutes[c.Path()] {
+						return echo.NewHTTPError(http.StatusForbidden, "the API key of the peers can't be used for this endpoint")
+					}
+					req := c.Request()
+					c.SetRequest(req.WithContext(context.WithValue(req.Context(), peerContextKey{}, true)))
+					return next(c)
+				}
+				if miner, ok := minerKeys[key]; ok {
+					if !minerRoutes[c.Path()] {
+						return echo.NewHTTPError(http.StatusForbidden, "the API key of a miner can't be used for this endpoint")
+					}
+					req := c.Request()
+					c.SetRequest(req.WithContext(context.WithValue(req.Context(), minerContextKey{}, miner)))
+					return next(c)
+				}
 			}
-			if c.Request().Header.Get("X-API-Key") == apiKey {
+			if open {
 				return next(c)
 			}
 			return echo.NewHTTPError(http.StatusUnauthorized, "missing or invalid API key")
@@ -44,6 +115,46 @@ func APIKeyAuthMiddleware(config config.JobConfiguration) echo.MiddlewareFunc {
 	}
 }
 
+type minerContextKey struct{}
+
+// minerFromContext returns the miner whose API key authenticated the request, or an empty string if the request was
+// not authenticated with the API key of a miner
+func minerFromContext(ctx context.Context) string {
+	miner, _ := ctx.Value(minerContextKey{}).(string)
+	return miner
+}
+
+type peerContextKey struct{}
+
+// fromPeer returns true if the request was authenticated with PEER_API_KEY, i.e. it was sent by a peer worker
+func fromPeer(ctx context.Context) bool {
+	peer, _ := ctx.Value(peerContextKey{}).(bool)
+	return peer
+}
+
+// errWrongMiner is returned when a job is submitted with the API key of another miner than the one it is from
+var errWrongMiner = errors.New("the job is not from the miner of the API key")
+
+// checkMiner returns errWrongMiner if the request was authenticated with the API key of a miner, and the job is from
+// another miner, so miners can't use up each other's quotas
+func checkMiner(ctx context.Context, job *types.Job) error {
+	if miner := minerFromContext(ctx); miner != "" && job.WorkerID != miner {
+		return errWrongMiner
+	}
+	return nil
+}
+
+// ownsJob returns false if the request was authenticated with the API key of a miner, and the job is not from that miner
+// or is unknown, so miners can't get or cancel each other's jobs
+func ownsJob(ctx context.Context, jobServer *jobserver.JobServer, uuid string) bool {
+	miner := minerFromContext(ctx)
+	if miner == "" {
+		return true
+	}
+	requester, ok := jobServer.JobRequester(uuid)
+	return ok && requester == miner
+}
+
 // HealthMetricsMiddleware tracks success and error rates for readiness probe
 func HealthMetricsMiddleware(healthMetrics *HealthMetrics) echo.MiddlewareFunc {
 	return func(next echo.HandlerFunc) echo.HandlerFunc {
@@ -73,3 +184,97 @@ func HealthMetricsMiddleware(healthMetrics *HealthMetrics) echo.MiddlewareFunc {
 		}
 	}
 }
+
+// ArgumentKeysProvider returns the arguments accepted by a job type, and false if they are not known
+type ArgumentKeysProvider interface {
+	ArgumentKeys(jobType teetypes.JobType) ([]string, bool)
+}
+
+// JobValidationMiddleware limits the size of request bodies, and rejects jobs with unknown arguments or a
+// max_results above the limit of their job type before they reach the job server or are estimated. Jobs submitted to
+// /job/add are decrypted to be validated. Jobs which can't be decoded are passed on, so the handler reports the error.
+func JobValidationMiddleware(jc config.JobConfiguration, provider ArgumentKeysProvider) echo.MiddlewareFunc {
+	maxBodyBytes := maxRequestBodyBytes(jc)
+	tooLarge := types.JobError{Error: fmt.Sprintf("request body exceeds %d bytes", maxBodyBytes)}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			if req.ContentLength > int64(maxBodyBytes) {
+				return c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+			}
+			req.Body = http.MaxBytesReader(c.Response(), req.Body, int64(maxBodyBytes))
+
+			var decode func([]byte) (*types.Job, error)
+			switch c.Path() {
+			case "/job/generate", "/job/estimate":
+				decode = func(body []byte) (*types.Job, error) {
+					job := &types.Job{}
+					return job, json.Unmarshal(body, job)
+				}
+			case "/job/add":
+				decode = func(body []byte) (*types.Job, error) {
+					jobRequest := types.JobRequest{}
+					if err := json.Unmarshal(body, &jobRequest); err != nil {
+						return nil, err
+					}
+					return jobRequest.DecryptJob()
+				}
+			default:
+				return next(c)
+			}
+
+			body, err := io.ReadAll(req.Body)
+			if err != nil {
+				var maxBytesErr *http.MaxBytesError
+				if errors.As(err, &maxBytesErr) {
+					return c.JSON(http.StatusRequestEntityTooLarge, tooLarge)
+				}
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("error reading request body: %s", err)})
+			}
+			req.Body = io.NopCloser(bytes.NewReader(body))
+
+			job, err := decode(body)
+			if err != nil {
+				return next(c)
+			}
+			if violations := argumentViolations(jc, provider, job); len(violations) > 0 {
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: "invalid job arguments", Violations: violations})
+			}
+			return next(c)
+		}
+	}
+}
+
+// maxRequestBodyBytes returns the configured limit of the size of request bodies and WebSocket messages
+func maxRequestBodyBytes(jc config.JobConfiguration) int {
+	maxBodyBytes, err := jc.GetInt("max_request_body_bytes", 1<<20)
+	if err != nil || maxBodyBytes <= 0 {
+		return 1 << 20
+	}
+	return maxBodyBytes
+}
+
+// argumentViolations returns the arguments of a job which are unknown to its job type, or exceed its limits
+func argumentViolations(jc config.JobConfiguration, provider ArgumentKeysProvider, job *types.Job) []types.ArgumentViolation {
+	var violations []types.ArgumentViolation
+
+	if keys, ok := provider.ArgumentKeys(job.Type); ok {
+		for _, k := range slices.Sorted(maps.Keys(job.Arguments)) {
+			if _, found := slices.BinarySearch(keys, k); !found {
+				violations = append(violations, types.ArgumentViolation{Argument: k, Reason: "unknown argument"})
+			}
+		}
+	}
+
+	if limit := jc.GetMaxResultsLimit(job.Type.String()); limit > 0 {
+		if maxResults, ok := job.Arguments["max_results"].(float64); ok && maxResults > float64(limit) {
+			violations = append(violations, types.ArgumentViolation{
+				Argument: "max_results",
+				Reason:   fmt.Sprintf("must be at most %d for %s jobs", limit, job.Type),
+			})
+		}
+	}
+
+	return violations
+}
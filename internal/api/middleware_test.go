@@ -37,6 +37,16 @@ var _ = Describe("APIKeyAuthMiddleware", func() {
 			Expect(rec.Code).To(Equal(http.StatusOK))
 			Expect(rec.Body.String()).To(Equal("passed"))
 		})
+
+		It("should still allow all requests when only the API key of the peers is set", func() {
+			e.Use(APIKeyAuthMiddleware(map[string]interface{}{"peer_api_key": "peerkey"}))
+			e.GET("/test", handler)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+		})
 	})
 
 	Context("when API key is configured", func() {
@@ -85,6 +95,67 @@ var _ = Describe("APIKeyAuthMiddleware", func() {
 			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
 		})
 
+		It("should accept the API keys of miners on the routes of their jobs only", func() {
+			e = echo.New()
+			e.Use(APIKeyAuthMiddleware(map[string]interface{}{"api_key": "test123", "miner_api_keys": []string{"miner1:minerkey:10"}}))
+			e.GET("/test", handler)
+			e.POST("/job/add", handler)
+			e.GET("/job/:job_id/trace", handler)
+			e.GET("/twitter/sessions", handler)
+
+			req := httptest.NewRequest(http.MethodPost, "/job/add", nil)
+			req.Header.Set("X-API-Key", "minerkey")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			req = httptest.NewRequest(http.MethodPost, "/job/add", nil)
+			req.Header.Set("Authorization", "Bearer miner1")
+			rec = httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusUnauthorized))
+
+			for _, path := range []string{"/test", "/job/some-job/trace", "/twitter/sessions"} {
+				req = httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("X-API-Key", "minerkey")
+				rec = httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusForbidden), path)
+
+				req = httptest.NewRequest(http.MethodGet, path, nil)
+				req.Header.Set("X-API-Key", "test123")
+				rec = httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK), path)
+			}
+		})
+
+		It("should accept the API key of the peers on the routes of delegated jobs only", func() {
+			e = echo.New()
+			e.Use(APIKeyAuthMiddleware(map[string]interface{}{"api_key": "test123", "peer_api_key": "peerkey"}))
+			e.GET("/test", handler)
+			e.GET("/attestation", handler)
+			e.POST("/job/add", handler)
+
+			for _, path := range []string{"/attestation", "/job/add"} {
+				method := http.MethodGet
+				if path == "/job/add" {
+					method = http.MethodPost
+				}
+				req := httptest.NewRequest(method, path, nil)
+				req.Header.Set("Authorization", "Bearer peerkey")
+				rec := httptest.NewRecorder()
+				e.ServeHTTP(rec, req)
+				Expect(rec.Code).To(Equal(http.StatusOK), path)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("X-API-Key", "peerkey")
+			rec := httptest.NewRecorder()
+			e.ServeHTTP(rec, req)
+			Expect(rec.Code).To(Equal(http.StatusForbidden))
+		})
+
 		It("should allow health check endpoints without API key", func() {
 			e.GET("/healthz", handler)
 			e.GET("/readyz", handler)
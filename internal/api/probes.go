@@ -0,0 +1,141 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+const (
+	// DependencyOK means the dependency works
+	DependencyOK = "ok"
+	// DependencyFailing means the dependency is configured but doesn't work, which makes the worker not ready
+	DependencyFailing = "failing"
+	// DependencyNotConfigured means the worker is not configured to use the dependency
+	DependencyNotConfigured = "not_configured"
+)
+
+// dependencyProbeTimeout is how long a probe can take before the dependency is considered failing
+const dependencyProbeTimeout = 10 * time.Second
+
+// DependencyProbe checks a dependency of the worker. Check returns types.ErrNotConfigured if the worker is not
+// configured to use the dependency. Probe results are reused for Interval, so frequent readiness checks don't
+// hammer the dependencies or use up their quotas.
+type DependencyProbe struct {
+	Name     string
+	Check    func() error
+	Interval time.Duration
+}
+
+// DependencyStatus is the result of a DependencyProbe
+type DependencyStatus struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	LatencyMs int64     `json:"latency_ms"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// DependencyProbes returns the probes of the dependencies the worker needs to execute jobs: the sealing key, the
+// Apify API key, at least one working Twitter auth method and the TikTok transcription endpoint.
+func DependencyProbes(jc config.JobConfiguration, jobServer *jobserver.JobServer) []DependencyProbe {
+	interval := jc.GetDuration("health_probe_interval_seconds", 300)
+	return []DependencyProbe{
+		// The sealing key is checked on every request, since it doesn't need any network access
+		{Name: "sealing_key", Check: probeSealingKey},
+		{Name: "apify", Check: func() error { return probeApify(jc.GetString("apify_api_key", "")) }, Interval: interval},
+		{Name: "twitter", Check: func() error { return jobServer.ProbeDependencies(teetypes.TwitterJob) }, Interval: interval},
+		{Name: "tiktok_transcription", Check: func() error { return jobServer.ProbeDependencies(teetypes.TiktokJob) }, Interval: interval},
+	}
+}
+
+// probeSealingKey checks that results can be sealed
+func probeSealingKey() error {
+	if !tee.SealingAvailable() {
+		return errors.New("no sealing key has been set")
+	}
+	return nil
+}
+
+// probeApify checks that the Apify API key is valid
+func probeApify(apiKey string) error {
+	if apiKey == "" {
+		return types.ErrNotConfigured
+	}
+	c, err := client.NewApifyClient(apiKey)
+	if err != nil {
+		return err
+	}
+	return c.ValidateApiKey()
+}
+
+// dependencyChecker runs the dependency probes concurrently, and caches their results for the interval of each probe
+type dependencyChecker struct {
+	sync.Mutex
+	probes   []DependencyProbe
+	timeout  time.Duration
+	statuses map[string]DependencyStatus
+}
+
+func newDependencyChecker(probes []DependencyProbe) *dependencyChecker {
+	return &dependencyChecker{probes: probes, timeout: dependencyProbeTimeout, statuses: make(map[string]DependencyStatus)}
+}
+
+// check returns the status of each dependency, probing again those whose cached results are too old
+func (dc *dependencyChecker) check() map[string]DependencyStatus {
+	dc.Lock()
+	defer dc.Unlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, p := range dc.probes {
+		if cached, ok := dc.statuses[p.Name]; ok && time.Since(cached.CheckedAt) < p.Interval {
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			status := dc.probe(p)
+			mu.Lock()
+			dc.statuses[p.Name] = status
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return maps.Clone(dc.statuses)
+}
+
+// probe runs a single probe. A probe which times out keeps running in the background, but its result is discarded.
+func (dc *dependencyChecker) probe(p DependencyProbe) DependencyStatus {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Check()
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(dc.timeout):
+		err = fmt.Errorf("timed out after %s", dc.timeout)
+	}
+
+	status := DependencyStatus{Status: DependencyOK, LatencyMs: time.Since(start).Milliseconds(), CheckedAt: start.UTC()}
+	switch {
+	case errors.Is(err, types.ErrNotConfigured):
+		status.Status = DependencyNotConfigured
+	case err != nil:
+		status.Status = DependencyFailing
+		status.Error = err.Error()
+	}
+	return status
+}
@@ -0,0 +1,104 @@
+package api_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Dependency probes", func() {
+	var (
+		jobServer *jobserver.JobServer
+		hm        *HealthMetrics
+	)
+
+	BeforeEach(func() {
+		jobServer = jobserver.NewJobServer(10, config.JobConfiguration{})
+		hm = NewHealthMetrics()
+	})
+
+	readyz := func(handler echo.HandlerFunc) (int, ReadyzResponse) {
+		req := httptest.NewRequest(http.MethodGet, "/readyz", nil)
+		rec := httptest.NewRecorder()
+		Expect(handler(echo.New().NewContext(req, rec))).To(Succeed())
+
+		var response ReadyzResponse
+		Expect(json.Unmarshal(rec.Body.Bytes(), &response)).To(Succeed())
+		return rec.Code, response
+	}
+
+	It("should be ready if all configured dependencies work", func() {
+		handler := Readyz(jobServer, hm,
+			DependencyProbe{Name: "ok", Check: func() error { return nil }},
+			DependencyProbe{Name: "unused", Check: func() error { return types.ErrNotConfigured }},
+		)
+
+		code, response := readyz(handler)
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(response.Ready).To(BeTrue())
+		Expect(response.Checks.Dependencies["ok"].Status).To(Equal(DependencyOK))
+		Expect(response.Checks.Dependencies["unused"].Status).To(Equal(DependencyNotConfigured))
+	})
+
+	It("should not be ready if a dependency fails", func() {
+		handler := Readyz(jobServer, hm,
+			DependencyProbe{Name: "ok", Check: func() error { return nil }},
+			DependencyProbe{Name: "apify", Check: func() error { return errors.New("invalid Apify API token") }},
+		)
+
+		code, response := readyz(handler)
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Ready).To(BeFalse())
+		Expect(response.Checks.ErrorRate).To(Equal("healthy"))
+		Expect(response.Checks.Dependencies["apify"].Status).To(Equal(DependencyFailing))
+		Expect(response.Checks.Dependencies["apify"].Error).To(Equal("invalid Apify API token"))
+	})
+
+	It("should reuse probe results within their interval", func() {
+		var cached, uncached int
+		handler := Readyz(jobServer, hm,
+			DependencyProbe{Name: "cached", Check: func() error { cached++; return nil }, Interval: time.Hour},
+			DependencyProbe{Name: "uncached", Check: func() error { uncached++; return nil }},
+		)
+
+		readyz(handler)
+		readyz(handler)
+		Expect(cached).To(Equal(1))
+		Expect(uncached).To(Equal(2))
+	})
+
+	It("should report unconfigured dependencies and a missing sealing key", func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+
+		// The TikTok transcription endpoint is left out, since probing it needs network access
+		probes := slices.DeleteFunc(DependencyProbes(config.JobConfiguration{}, jobServer), func(p DependencyProbe) bool {
+			return p.Name == "tiktok_transcription"
+		})
+
+		code, response := readyz(Readyz(jobServer, hm, probes...))
+		Expect(code).To(Equal(http.StatusServiceUnavailable))
+		Expect(response.Checks.Dependencies["sealing_key"].Status).To(Equal(DependencyFailing))
+		Expect(response.Checks.Dependencies["apify"].Status).To(Equal(DependencyNotConfigured))
+		Expect(response.Checks.Dependencies["twitter"].Status).To(Equal(DependencyNotConfigured))
+
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		_, response = readyz(Readyz(jobServer, hm, probes...))
+		Expect(response.Checks.Dependencies["sealing_key"].Status).To(Equal(DependencyOK))
+	})
+})
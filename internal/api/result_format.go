@@ -0,0 +1,153 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"google.golang.org/protobuf/encoding/protodelim"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// csvValueColumn is the column of the records of a CSV result which are not JSON objects
+const csvValueColumn = "value"
+
+// negotiateResultFormat returns the format requested in the body of a result request, or else the one of the Accept
+// header
+func negotiateResultFormat(requested types.ResultFormat, accept string) (types.ResultFormat, error) {
+	if requested != "" {
+		return types.ParseResultFormat(string(requested))
+	}
+	return types.ResultFormatFromAccept(accept), nil
+}
+
+// encodeResult writes a JSON job result in the given format. The records of the result are the elements of a JSON
+// array, or else the result itself.
+func encodeResult(w io.Writer, format types.ResultFormat, result []byte) error {
+	if format == types.ResultFormatJSON {
+		_, err := w.Write(result)
+		return err
+	}
+
+	records, err := resultRecords(result)
+	if err != nil {
+		return err
+	}
+
+	switch format {
+	case types.ResultFormatNDJSON:
+		var buf bytes.Buffer
+		for _, record := range records {
+			buf.Reset()
+			if err := json.Compact(&buf, record); err != nil {
+				return err
+			}
+			buf.WriteByte('\n')
+			if _, err := w.Write(buf.Bytes()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case types.ResultFormatCSV:
+		return encodeCSV(w, records)
+	case types.ResultFormatProtobuf:
+		for _, record := range records {
+			var v any
+			if err := json.Unmarshal(record, &v); err != nil {
+				return err
+			}
+			value, err := structpb.NewValue(v)
+			if err != nil {
+				return fmt.Errorf("failed to convert record to protobuf: %w", err)
+			}
+			if _, err := protodelim.MarshalTo(w, value); err != nil {
+				return err
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown result format %q", format)
+	}
+}
+
+// resultRecords splits a JSON job result into its records
+func resultRecords(result []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(result)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+	if trimmed[0] == '[' {
+		var records []json.RawMessage
+		if err := json.Unmarshal(trimmed, &records); err != nil {
+			return nil, fmt.Errorf("result is not valid JSON: %w", err)
+		}
+		return records, nil
+	}
+	if !json.Valid(trimmed) {
+		return nil, fmt.Errorf("result is not valid JSON")
+	}
+	return []json.RawMessage{trimmed}, nil
+}
+
+// encodeCSV writes the records as a CSV table. The columns are the sorted keys of the records which are objects, and
+// csvValueColumn for the other records. Strings are written as is and other values as JSON, so nested objects and
+// arrays end up in a single cell.
+func encodeCSV(w io.Writer, records []json.RawMessage) error {
+	rows := make([]map[string]json.RawMessage, len(records))
+	var columns []string
+	seen := make(map[string]bool)
+	for i, record := range records {
+		row := make(map[string]json.RawMessage)
+		if bytes.HasPrefix(bytes.TrimSpace(record), []byte("{")) {
+			if err := json.Unmarshal(record, &row); err != nil {
+				return err
+			}
+		} else {
+			row[csvValueColumn] = record
+		}
+		for column := range row {
+			if !seen[column] {
+				seen[column] = true
+				columns = append(columns, column)
+			}
+		}
+		rows[i] = row
+	}
+	slices.Sort(columns)
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	cells := make([]string, len(columns))
+	for _, row := range rows {
+		for i, column := range columns {
+			cells[i] = csvCell(row[column])
+		}
+		if err := cw.Write(cells); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell returns the content of the cell of a JSON value
+func csvCell(value json.RawMessage) string {
+	if len(value) == 0 || string(value) == "null" {
+		return ""
+	}
+	var s string
+	if err := json.Unmarshal(value, &s); err == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, value); err != nil {
+		return string(value)
+	}
+	return buf.String()
+}
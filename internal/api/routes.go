@@ -1,12 +1,23 @@
 package api
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
 	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/internal/peers"
+	"github.com/masa-finance/tee-worker/internal/secrets"
 	"github.com/masa-finance/tee-worker/pkg/tee"
 	"github.com/sirupsen/logrus"
 )
@@ -19,7 +30,11 @@ func generate(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, types.JobResult{Error: err.Error()})
 	}
 
-	job.WorkerID = tee.WorkerID // attach worker ID to job
+	job.WorkerID = tee.CurrentWorkerID() // attach worker ID to job
+	// Jobs generated with the API key of a miner are from that miner, so they can be submitted with the same key
+	if miner := minerFromContext(c.Request().Context()); miner != "" {
+		job.WorkerID = miner
+	}
 
 	encryptedSignature, err := job.GenerateJobSignature()
 	if err != nil {
@@ -30,15 +45,41 @@ func generate(c echo.Context) error {
 	return c.String(http.StatusOK, encryptedSignature)
 }
 
+// estimate returns what a job is expected to cost, and whether the worker can serve it now, without executing it. The
+// request body is the job as for /job/generate.
+func estimate(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		job := types.Job{}
+		if err := c.Bind(&job); err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+
+		job.WorkerID = tee.CurrentWorkerID()
+		if miner := minerFromContext(c.Request().Context()); miner != "" {
+			job.WorkerID = miner
+		}
+
+		res, err := jobServer.EstimateJob(job)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
 // add adds a job to the job server.
 //
 // The request body should contain a JobRequest, which will be decrypted and
 // passed to the job server. The response body will contain a JobResponse with
-// the UUID of the added job.
+// the UUID of the added job, and whether it was answered with a cached result.
 //
 // If there is an error, the response body will contain a JobError with an
 // appropriate error message.
-func add(jobServer *jobserver.JobServer) func(c echo.Context) error {
+//
+// If delegator is not nil, jobs which this worker is over capacity for, or which
+// it does not admit, are forwarded to a peer worker. Jobs which were delegated
+// by a peer are always executed locally.
+func add(jobServer *jobserver.JobServer, delegator *peers.Delegator) func(c echo.Context) error {
 	return func(c echo.Context) error {
 		jobRequest := types.JobRequest{}
 		if err := c.Bind(&jobRequest); err != nil {
@@ -51,37 +92,191 @@ func add(jobServer *jobserver.JobServer) func(c echo.Context) error {
 			logrus.Errorf("Error while decrypting job %s: %s", jobRequest, err)
 			return c.JSON(http.StatusInternalServerError, types.JobError{Error: fmt.Sprintf("Error while decrypting job: %s", err.Error())})
 		}
+		if err := checkMiner(c.Request().Context(), job); err != nil {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: err.Error()})
+		}
 
-		uuid, err := jobServer.AddJob(*job)
+		// Delegated jobs count against the quotas of the miner on this worker, since the peer only sees PEER_API_KEY.
+		// Jobs delegated by a peer are executed here instead of being delegated again; the header is only trusted from
+		// the peers, so other clients can't use it to keep their jobs from being delegated.
+		delegated := fromPeer(c.Request().Context()) && c.Request().Header.Get(peers.DelegatedHeader) != ""
+		canDelegate := delegator != nil && !delegated
+		delegate := func() (types.JobResponse, error) {
+			if err := jobServer.AdmitDelegatedJob(job.WorkerID); err != nil {
+				return types.JobResponse{}, err
+			}
+			res, err := delegator.Forward(jobRequest, *job)
+			if err != nil {
+				jobServer.RefundDelegatedJob(job.WorkerID)
+			}
+			return res, err
+		}
+		rejectQuota := func(err error, quotaErr *types.QuotaError) error {
+			c.Response().Header().Set("Retry-After", strconv.Itoa(quotaErr.RetryAfterSeconds(time.Now())))
+			return c.JSON(http.StatusTooManyRequests, addJobError(err))
+		}
+
+		if canDelegate && jobServer.OverCapacity(job.Type) {
+			res, err := delegate()
+			if err == nil {
+				return c.JSON(http.StatusOK, res)
+			}
+			var quotaErr *types.QuotaError
+			if errors.As(err, &quotaErr) {
+				logrus.Infof("Not delegating job of miner %s: %s", job.WorkerID, err)
+				return rejectQuota(err, quotaErr)
+			}
+		}
+
+		res, err := jobServer.SubmitJobContext(c.Request().Context(), *job)
 		if err != nil {
 			logrus.Errorf("Error while adding job %s: %s", *job, err)
+			// Quotas apply to the miner on every worker, so the job is not delegated
+			var quotaErr *types.QuotaError
+			if errors.As(err, &quotaErr) {
+				return rejectQuota(err, quotaErr)
+			}
+			var admissionErr *types.AdmissionError
+			if errors.As(err, &admissionErr) {
+				if canDelegate {
+					if res, err := delegate(); err == nil {
+						return c.JSON(http.StatusOK, res)
+					}
+				}
+				if secs := admissionErr.RetryAfterSeconds(); secs > 0 {
+					c.Response().Header().Set("Retry-After", strconv.Itoa(secs))
+				}
+				return c.JSON(http.StatusTooManyRequests, addJobError(err))
+			}
 			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
 		}
 
 		// check if uuid is empty
-		if uuid == "" {
+		if res.UID == "" {
 			logrus.Errorf("Failed to add job %s: UUID is empty", *job)
 			return c.JSON(http.StatusInternalServerError, types.JobError{Error: "Failed to add job"})
 		}
 
-		return c.JSON(http.StatusOK, types.JobResponse{UID: uuid})
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// addJobError returns the error of a job which could not be added. For a job which was not admitted or exceeded a
+// quota of its miner, it has the time after which the job should be submitted again.
+func addJobError(err error) types.JobError {
+	jobErr := types.JobError{Error: err.Error()}
+	var admissionErr *types.AdmissionError
+	if errors.As(err, &admissionErr) {
+		jobErr.RetryAfterSeconds = admissionErr.RetryAfterSeconds()
+	}
+	var quotaErr *types.QuotaError
+	if errors.As(err, &quotaErr) {
+		jobErr.RetryAfterSeconds = quotaErr.RetryAfterSeconds(time.Now())
+		jobErr.QuotaResetAt = &quotaErr.ResetAt
+	}
+	return jobErr
+}
+
+// PartialResultHeader is set on a job status response when some of the providers or queries the job fanned out to failed,
+// or when the job stopped early because it reached its bandwidth cap
+const PartialResultHeader = "X-Partial-Result"
+
+// CachedResultHeader is set on a job status response when the result was reused from an identical earlier job instead
+// of executing the job
+const CachedResultHeader = "X-Cached-Result"
+
+// UsageHeader carries the JSON-encoded usage block of a successful job, which is not part of the sealed result
+const UsageHeader = "X-Usage"
+
+// JobTimeoutHeader is set on a job status response to the timeout the job was executed with, in seconds, which depends
+// on its job type and capability
+const JobTimeoutHeader = "X-Job-Timeout"
+
+// waitParam parses the wait query parameter of the status endpoint, which is either a duration such as "30s" or a
+// number of seconds. The wait is capped at maxWait.
+func waitParam(c echo.Context, maxWait time.Duration) (time.Duration, error) {
+	s := c.QueryParam("wait")
+	if s == "" {
+		return 0, nil
+	}
+
+	wait, err := time.ParseDuration(s)
+	if err != nil {
+		secs, convErr := strconv.Atoi(s)
+		if convErr != nil {
+			return 0, fmt.Errorf("invalid wait %q: must be a duration such as 30s or a number of seconds", s)
+		}
+		wait = time.Duration(secs) * time.Second
+	}
+	if wait < 0 {
+		return 0, fmt.Errorf("invalid wait %q: must not be negative", s)
 	}
+
+	return min(wait, maxWait), nil
 }
 
 // status returns the result of a job. If the job is not found, it returns an
 // error with a status code of 404. If there is an error with the job, it
 // returns an error with a status code of 500. If the job has not finished, it
 // returns an empty string with a status code of 200. Otherwise, it returns the
-// sealed result of the job with a status code of 200.
-func status(jobServer *jobserver.JobServer) func(c echo.Context) error {
+// sealed result of the job with a status code of 200. Errors of jobs that fan
+// out to several providers or queries include the outcome of each of them.
+//
+// If the wait query parameter is set and the job is still pending, it waits up to
+// that long (capped at maxWait) for the job to finish before responding.
+//
+// The status of a job delegated to a peer is requested from the peer, and its
+// response is passed on as is.
+//
+// Requests authenticated with the API key of a miner only get the status of the
+// jobs of that miner, the other jobs are not found.
+func status(jobServer *jobserver.JobServer, delegator *peers.Delegator, maxWait time.Duration) func(c echo.Context) error {
 	return func(c echo.Context) error {
-		res, exists := jobServer.GetJobResult(c.Param("job_id"))
+		miner := minerFromContext(c.Request().Context())
+		if delegator != nil {
+			if requester, delegated := delegator.Requester(c.Param("job_id")); delegated && miner != "" && requester != miner {
+				return c.JSON(http.StatusNotFound, types.JobError{Error: "Job not found"})
+			}
+
+			resp, delegated, err := delegator.Status(c.Param("job_id"), c.QueryString())
+			if err != nil {
+				logrus.Errorf("Error while proxying the status of a delegated job: %s", err)
+				return c.JSON(http.StatusBadGateway, types.JobError{Error: err.Error()})
+			}
+			if delegated {
+				defer resp.Body.Close()
+				for _, header := range []string{echo.HeaderContentType, PartialResultHeader, CachedResultHeader, UsageHeader, JobTimeoutHeader} {
+					if v := resp.Header.Get(header); v != "" {
+						c.Response().Header().Set(header, v)
+					}
+				}
+				c.Response().WriteHeader(resp.StatusCode)
+				_, err := io.Copy(c.Response(), resp.Body)
+				return err
+			}
+		}
+
+		wait, err := waitParam(c, maxWait)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+
+		// The result is deleted once it is read, so the job is checked before its result is taken
+		if !ownsJob(c.Request().Context(), jobServer, c.Param("job_id")) {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "Job not found"})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), wait)
+		defer cancel()
+
+		res, exists := jobServer.WaitForJobResult(ctx, c.Param("job_id"))
 		if !exists {
 			return c.JSON(http.StatusNotFound, types.JobError{Error: "Job not found"})
 		}
 
+		timeoutSeconds := int(res.Job.Timeout / time.Second)
 		if res.Error != "" {
-			return c.JSON(http.StatusInternalServerError, types.JobError{Error: res.Error})
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: res.Error, FanOut: res.FanOut, Usage: res.Usage, Trace: res.Trace, TimeoutSeconds: timeoutSeconds, Validation: res.Validation})
 		}
 
 		sealedData, err := res.Seal()
@@ -90,11 +285,311 @@ func status(jobServer *jobserver.JobServer) func(c echo.Context) error {
 			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
 		}
 
+		// The per-provider details are not sealed, so only flag that some of them failed
+		if res.Partial() || res.Truncated() {
+			c.Response().Header().Set(PartialResultHeader, "true")
+		}
+		if res.Cached {
+			c.Response().Header().Set(CachedResultHeader, "true")
+		}
+		if res.Usage != nil {
+			if usage, err := json.Marshal(res.Usage); err == nil {
+				c.Response().Header().Set(UsageHeader, string(usage))
+			}
+		}
+		if timeoutSeconds > 0 {
+			c.Response().Header().Set(JobTimeoutHeader, strconv.Itoa(timeoutSeconds))
+		}
+
 		return c.String(http.StatusOK, sealedData)
 
 	}
 }
 
+// trace returns the execution trace of a job submitted with the debug argument, including the events recorded so
+// far if the job is still running. The result of the job is not consumed.
+func trace(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		t, ok := jobServer.GetJobTrace(c.Param("job_id"))
+		if !ok {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "no trace for this job, it was not submitted with debug or its trace has expired"})
+		}
+		return c.JSON(http.StatusOK, t)
+	}
+}
+
+// stream returns the batches of results emitted by a job after the batch given by the after query parameter, each
+// sealed like the result of the job. If there are none yet, it waits up to the wait query parameter (capped at
+// maxWait) for the next batch, so clients can long-poll the stream until it is done.
+func stream(jobServer *jobserver.JobServer, maxWait time.Duration) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		after := 0
+		if s := c.QueryParam("after"); s != "" {
+			var err error
+			if after, err = strconv.Atoi(s); err != nil || after < 0 {
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("invalid after %q: must be a batch sequence number", s)})
+			}
+		}
+
+		wait, err := waitParam(c, maxWait)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+
+		if !ownsJob(c.Request().Context(), jobServer, c.Param("job_id")) {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "Job not found"})
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request().Context(), wait)
+		defer cancel()
+
+		resp, ok, err := jobServer.GetJobStream(ctx, c.Param("job_id"), after)
+		if !ok {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "Job not found"})
+		}
+		if err != nil {
+			logrus.Errorf("Error while sealing the stream of job %s: %s", c.Param("job_id"), err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, resp)
+	}
+}
+
+// unschedule stops re-executing a recurring job. If the job is not recurring, it returns an
+// error with a status code of 404. The latest result can still be retrieved until it expires.
+func unschedule(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if err := jobServer.RemoveRecurringJob(c.Param("job_id")); err != nil {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// hold retains the result of a job until it is released, optionally with a tag, e.g. ?tag=legal-hold
+func hold(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		tag, err := types.ParseHoldTag(c.QueryParam("tag"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+
+		h, err := jobServer.HoldResult(c.Param("job_id"), tag)
+		switch {
+		case errors.Is(err, jobserver.ErrResultNotFound):
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		case errors.Is(err, jobserver.ErrTooManyHeldResults):
+			return c.JSON(http.StatusTooManyRequests, types.JobError{Error: err.Error()})
+		case err != nil:
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, h)
+	}
+}
+
+// release removes the hold of a job result, which then expires like any other
+func release(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		if err := jobServer.ReleaseResult(c.Param("job_id")); err != nil {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		}
+		return c.NoContent(http.StatusNoContent)
+	}
+}
+
+// addBatch adds several jobs to the job server in a single request.
+//
+// The request body should contain a BatchRequest with at most max_batch_jobs
+// jobs. Each job is decrypted, validated and added on its own, so a rejected
+// job doesn't prevent the others from being added. The response body contains
+// a BatchResponse with the UUID or the error of each job, and the ID of the
+// batch to get the status of the accepted jobs with. If no job was accepted,
+// the status code is 400.
+func addBatch(jobServer *jobserver.JobServer, jc config.JobConfiguration) func(c echo.Context) error {
+	maxJobs, err := jc.GetInt("max_batch_jobs", 100)
+	if err != nil || maxJobs <= 0 {
+		maxJobs = 100
+	}
+
+	return func(c echo.Context) error {
+		batch := types.BatchRequest{}
+		if err := c.Bind(&batch); err != nil {
+			logrus.Errorf("Error while binding batch: %s", err)
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+		if len(batch.Jobs) == 0 {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: "batch has no jobs"})
+		}
+		if len(batch.Jobs) > maxJobs {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("batch has %d jobs, at most %d are allowed", len(batch.Jobs), maxJobs)})
+		}
+
+		res := types.BatchResponse{Jobs: make([]types.BatchJob, len(batch.Jobs))}
+		var accepted []string
+		for i, jobRequest := range batch.Jobs {
+			job, err := jobRequest.DecryptJob()
+			if err != nil {
+				logrus.Errorf("Error while decrypting job %d of batch: %s", i, err)
+				res.Jobs[i].Error = &types.JobError{Error: fmt.Sprintf("Error while decrypting job: %s", err.Error())}
+				continue
+			}
+			if err := checkMiner(c.Request().Context(), job); err != nil {
+				res.Jobs[i].Error = &types.JobError{Error: err.Error()}
+				continue
+			}
+
+			if violations := argumentViolations(jc, jobServer, job); len(violations) > 0 {
+				res.Jobs[i].Error = &types.JobError{Error: "invalid job arguments", Violations: violations}
+				continue
+			}
+
+			submitted, err := jobServer.SubmitJobContext(c.Request().Context(), *job)
+			if err != nil {
+				logrus.Errorf("Error while adding job %s: %s", *job, err)
+				jobErr := addJobError(err)
+				res.Jobs[i].Error = &jobErr
+				continue
+			}
+
+			res.Jobs[i].UID = submitted.UID
+			res.Jobs[i].Cached = submitted.Cached
+			accepted = append(accepted, submitted.UID)
+		}
+
+		if len(accepted) == 0 {
+			return c.JSON(http.StatusBadRequest, res)
+		}
+
+		res.BatchID = jobServer.AddBatch(accepted)
+		return c.JSON(http.StatusOK, res)
+	}
+}
+
+// batchStatus returns how many jobs of a batch are pending, have succeeded, have
+// failed or have a result which has expired, together with the state of each job.
+// The results themselves are retrieved with /job/status. If the batch is not
+// found, it returns an error with a status code of 404.
+func batchStatus(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		status, err := jobServer.GetBatchStatus(c.Param("batch_id"))
+		if err != nil {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, status)
+	}
+}
+
+// capabilities returns the capabilities of all registered job types, together with
+// the auth source, estimated rate limit and full-archive availability of each of them.
+func capabilities(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, types.CapabilitiesResponse{
+			WorkerID:     tee.CurrentWorkerID(),
+			Capabilities: jobServer.GetWorkerCapabilities(),
+			Details:      jobServer.GetCapabilityDetails(),
+		})
+	}
+}
+
+// workerStatus returns the diagnostics of the secrets validated most recently, so that orchestration can take workers
+// with unusable secrets out of rotation
+func workerStatus(secretDiagnostics func() []types.SecretDiagnostic) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		diagnostics := secretDiagnostics()
+		return c.JSON(http.StatusOK, types.StatusResponse{
+			WorkerID: tee.CurrentWorkerID(),
+			Healthy:  secrets.Healthy(diagnostics),
+			Secrets:  diagnostics,
+		})
+	}
+}
+
+// latestBenchmark returns the report of the latest self-benchmark of the worker
+func latestBenchmark(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		report, ok := jobServer.LatestBenchmark()
+		if !ok {
+			return c.JSON(http.StatusNotFound, types.JobError{Error: "the worker has not been benchmarked yet"})
+		}
+		return c.JSON(http.StatusOK, report)
+	}
+}
+
+// runBenchmark benchmarks the worker and returns the report. A request made while a benchmark is running waits for it
+// to finish, and then runs another one.
+func runBenchmark(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, jobServer.RunBenchmark())
+	}
+}
+
+// exportTwitterSessions returns the sealed sessions of the Twitter accounts, so they can be imported by another worker
+// with the same sealing key instead of logging in again
+func exportTwitterSessions(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		sessions, err := jobServer.ExportTwitterSessions()
+		switch {
+		case errors.Is(err, types.ErrNotConfigured):
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		case err != nil:
+			logrus.Errorf("Error while exporting Twitter sessions: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+		logrus.Infof("Exported the Twitter sessions of %d accounts", len(sessions.Accounts))
+		return c.JSON(http.StatusOK, sessions)
+	}
+}
+
+// importTwitterSessions replaces the sessions of the Twitter accounts with sessions exported by another worker. If the
+// sessions can't be unsealed, e.g. because they were exported by a worker with another sealing key, it returns an
+// error with a status code of 400.
+func importTwitterSessions(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	return func(c echo.Context) error {
+		sessions := types.TwitterSessions{}
+		if err := c.Bind(&sessions); err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+
+		imported, err := jobServer.ImportTwitterSessions(sessions)
+		switch {
+		case errors.Is(err, types.ErrInvalidSessions):
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		case errors.Is(err, types.ErrNotConfigured):
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		case err != nil:
+			logrus.Errorf("Error while importing Twitter sessions: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, imported)
+	}
+}
+
+// checkTwitterAccounts verifies the session of every Twitter account, refreshing its cookies, and reports the status
+// of each, so that operators can replace accounts which no longer work. Checks log in to Twitter, so only one runs at
+// a time, and miners can't start them.
+func checkTwitterAccounts(jobServer *jobserver.JobServer) func(c echo.Context) error {
+	var running sync.Mutex
+	return func(c echo.Context) error {
+		if minerFromContext(c.Request().Context()) != "" {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: "the Twitter accounts can't be checked with the API key of a miner"})
+		}
+		if !running.TryLock() {
+			return c.JSON(http.StatusConflict, types.JobError{Error: "a check of the Twitter accounts is already running"})
+		}
+		defer running.Unlock()
+
+		check, err := jobServer.CheckTwitterAccounts()
+		switch {
+		case errors.Is(err, types.ErrNotConfigured):
+			return c.JSON(http.StatusNotFound, types.JobError{Error: err.Error()})
+		case err != nil:
+			logrus.Errorf("Error while checking the Twitter accounts: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
+		}
+		return c.JSON(http.StatusOK, check)
+	}
+}
+
 func result(c echo.Context) error {
 	payload := types.EncryptedRequest{
 		EncryptedResult:  "",
@@ -106,13 +601,28 @@ func result(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
 	}
 
+	format, err := negotiateResultFormat(payload.ResultFormat, c.Request().Header.Get(echo.HeaderAccept))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+	}
+
 	result, err := payload.Unseal()
 	if err != nil {
 		logrus.Errorf("Error while unsealing payload for getting result: %s", err)
 		return c.JSON(http.StatusInternalServerError, types.JobError{Error: err.Error()})
 	}
 
-	return c.String(http.StatusOK, result)
+	if format == types.ResultFormatJSON {
+		return c.String(http.StatusOK, result)
+	}
+
+	// The result is converted before responding, so a result which cannot be converted is reported as an error
+	var buf bytes.Buffer
+	if err := encodeResult(&buf, format, []byte(result)); err != nil {
+		logrus.Errorf("Error while converting result to %s: %s", format, err)
+		return c.JSON(http.StatusUnprocessableEntity, types.JobError{Error: fmt.Sprintf("result cannot be converted to %s: %s", format, err)})
+	}
+	return c.Blob(http.StatusOK, format.ContentType(), buf.Bytes())
 }
 
 func setKey(dataDir string) func(c echo.Context) error {
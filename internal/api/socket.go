@@ -0,0 +1,207 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+)
+
+// JobSocket serves the job WebSocket, over which a client can submit and cancel any number of jobs and receive
+// their progress and results without a request per job. Submitted jobs are encrypted and validated like those sent
+// to /job/add. The result of each job is pushed as soon as it is available, sealed like the response of
+// /job/status. Messages are limited to the size of request bodies.
+func JobSocket(jobServer *jobserver.JobServer, jc config.JobConfiguration) echo.HandlerFunc {
+	maxMessageBytes := maxRequestBodyBytes(jc)
+
+	return func(c echo.Context) error {
+		server := websocket.Server{Handler: func(ws *websocket.Conn) {
+			ws.MaxPayloadBytes = maxMessageBytes
+			s := &jobSocket{ws: ws, jobServer: jobServer, jc: jc, maxMessageBytes: maxMessageBytes, miner: minerFromContext(c.Request().Context())}
+			s.serve()
+		}}
+		server.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+}
+
+// jobSocket is a single connection to the job WebSocket
+type jobSocket struct {
+	ws              *websocket.Conn
+	jobServer       *jobserver.JobServer
+	jc              config.JobConfiguration
+	maxMessageBytes int
+	miner           string // The miner whose API key authenticated the connection, if any
+
+	ctx       context.Context
+	following sync.WaitGroup // jobs whose result has not been sent yet
+}
+
+// serve handles the messages of the client until the connection is closed
+func (s *jobSocket) serve() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.ctx = ctx
+	defer s.following.Wait()
+	defer cancel()
+
+	for {
+		var msg types.SocketMessage
+		err := websocket.JSON.Receive(s.ws, &msg)
+
+		var syntaxErr *json.SyntaxError
+		var typeErr *json.UnmarshalTypeError
+		switch {
+		case errors.Is(err, websocket.ErrFrameTooLarge):
+			s.sendError(msg, fmt.Sprintf("message exceeds %d bytes", s.maxMessageBytes))
+			continue
+		case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+			s.sendError(msg, fmt.Sprintf("invalid message: %s", err))
+			continue
+		case err != nil:
+			// The client closed the connection
+			logrus.Debugf("Closing job WebSocket: %s", err)
+			return
+		}
+
+		switch msg.Type {
+		case types.SocketSubmit:
+			s.submit(msg)
+		case types.SocketCancel:
+			s.cancel(msg)
+		default:
+			s.sendError(msg, fmt.Sprintf("unknown message type %q", msg.Type))
+		}
+	}
+}
+
+// submit adds the job of a submit message to the job server, and follows it until it has its result
+func (s *jobSocket) submit(msg types.SocketMessage) {
+	if msg.Job == nil {
+		s.sendError(msg, "missing job")
+		return
+	}
+
+	job, err := msg.Job.DecryptJob()
+	if err != nil {
+		logrus.Errorf("Error while decrypting job received over WebSocket: %s", err)
+		s.sendError(msg, fmt.Sprintf("Error while decrypting job: %s", err))
+		return
+	}
+
+	if s.miner != "" && job.WorkerID != s.miner {
+		s.sendError(msg, errWrongMiner.Error())
+		return
+	}
+
+	if violations := argumentViolations(s.jc, s.jobServer, job); len(violations) > 0 {
+		s.send(types.SocketMessage{
+			Type:      types.SocketError,
+			RequestID: msg.RequestID,
+			Error:     &types.JobError{Error: "invalid job arguments", Violations: violations},
+		})
+		return
+	}
+
+	uuid, err := s.jobServer.AddJob(*job)
+	if err != nil {
+		logrus.Errorf("Error while adding job %s: %s", *job, err)
+		jobErr := addJobError(err)
+		s.send(types.SocketMessage{Type: types.SocketError, RequestID: msg.RequestID, Error: &jobErr})
+		return
+	}
+
+	// Events are only received once subscribed, so the job may already be running when it is acknowledged
+	events, unsubscribe := s.jobServer.SubscribeJobEvents(uuid)
+	s.send(types.SocketMessage{Type: types.SocketAccepted, RequestID: msg.RequestID, JobUUID: uuid, Status: types.JobStatusQueued})
+
+	s.following.Add(1)
+	go func() {
+		defer s.following.Done()
+		defer unsubscribe()
+		s.follow(uuid, events)
+	}()
+}
+
+// follow forwards the events of a job until it has finished, and then sends its result
+func (s *jobSocket) follow(uuid string, events <-chan types.JobEvent) {
+	done := s.jobServer.JobDone(uuid)
+	for done != nil {
+		select {
+		case <-s.ctx.Done():
+			return
+		case event := <-events:
+			s.send(types.SocketMessage{Type: types.SocketProgress, JobUUID: uuid, Status: event.Status, Attempt: event.Attempt})
+		case <-done:
+			done = nil
+		}
+	}
+
+	res, ok := s.jobServer.GetJobResult(uuid)
+	if !ok {
+		s.send(types.SocketMessage{Type: types.SocketResult, JobUUID: uuid, Error: &types.JobError{Error: "Job not found"}})
+		return
+	}
+	s.send(resultMessage(uuid, res))
+}
+
+// resultMessage returns the message delivering the result of a job, which matches the response of /job/status
+func resultMessage(uuid string, res types.JobResult) types.SocketMessage {
+	msg := types.SocketMessage{Type: types.SocketResult, JobUUID: uuid, Usage: res.Usage}
+	if res.Error != "" {
+		msg.Error = &types.JobError{Error: res.Error, FanOut: res.FanOut, Usage: res.Usage, Trace: res.Trace, Validation: res.Validation}
+		return msg
+	}
+
+	sealedData, err := res.Seal()
+	if err != nil {
+		logrus.Errorf("Error while sealing result of job %s: %s", uuid, err)
+		msg.Error = &types.JobError{Error: err.Error()}
+		return msg
+	}
+	msg.Result = sealedData
+	msg.Partial = res.Partial() || res.Truncated()
+	msg.Cached = res.Cached
+	return msg
+}
+
+// cancel cancels the job of a cancel message. The result of the job, a cancellation error, is sent separately to
+// the connection which submitted it.
+func (s *jobSocket) cancel(msg types.SocketMessage) {
+	if s.miner != "" {
+		if requester, ok := s.jobServer.JobRequester(msg.JobUUID); !ok || requester != s.miner {
+			s.sendError(msg, jobserver.ErrJobNotFound.Error())
+			return
+		}
+	}
+	if err := s.jobServer.CancelJob(msg.JobUUID); err != nil {
+		s.sendError(msg, err.Error())
+		return
+	}
+	s.send(types.SocketMessage{Type: types.SocketCancelled, JobUUID: msg.JobUUID})
+}
+
+// sendError replies to a message which could not be processed
+func (s *jobSocket) sendError(msg types.SocketMessage, reason string) {
+	s.send(types.SocketMessage{
+		Type:      types.SocketError,
+		RequestID: msg.RequestID,
+		JobUUID:   msg.JobUUID,
+		Error:     &types.JobError{Error: reason},
+	})
+}
+
+// send sends a message to the client. It is safe to call concurrently.
+func (s *jobSocket) send(msg types.SocketMessage) {
+	if err := websocket.JSON.Send(s.ws, msg); err != nil {
+		logrus.Debugf("Error while sending %s message over job WebSocket: %s", msg.Type, err)
+	}
+}
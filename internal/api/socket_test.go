@@ -0,0 +1,147 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/net/websocket"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("JobSocket", func() {
+	var (
+		jobServer *jobserver.JobServer
+		ws        *websocket.Conn
+	)
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		config.MinersWhiteList = ""
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		jc := config.JobConfiguration{"max_request_body_bytes": 4096}
+		jobServer = jobserver.NewJobServer(1, jc)
+
+		e := echo.New()
+		e.GET("/job/ws", JobSocket(jobServer, jc))
+		server := httptest.NewServer(e)
+		DeferCleanup(server.Close)
+
+		var err error
+		ws, err = websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/job/ws", "", server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(ws.Close)
+	})
+
+	run := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go jobServer.Run(ctx)
+	}
+
+	submit := func(requestID string, job types.Job) {
+		dat, err := json.Marshal(job)
+		Expect(err).NotTo(HaveOccurred())
+		sealed, err := tee.Seal(dat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(websocket.JSON.Send(ws, types.SocketMessage{
+			Type:      types.SocketSubmit,
+			RequestID: requestID,
+			Job:       &types.JobRequest{EncryptedJob: sealed},
+		})).To(Succeed())
+	}
+
+	receive := func() types.SocketMessage {
+		Expect(ws.SetReadDeadline(time.Now().Add(5 * time.Second))).To(Succeed())
+		msg := types.SocketMessage{}
+		Expect(websocket.JSON.Receive(ws, &msg)).To(Succeed())
+		return msg
+	}
+
+	It("delivers the progress and the sealed result of submitted jobs", func() {
+		run()
+		submit("r1", types.Job{Type: teetypes.TelemetryJob})
+
+		accepted := receive()
+		Expect(accepted.Type).To(Equal(types.SocketAccepted))
+		Expect(accepted.RequestID).To(Equal("r1"))
+		Expect(accepted.Status).To(Equal(types.JobStatusQueued))
+		Expect(accepted.JobUUID).NotTo(BeEmpty())
+
+		msg := receive()
+		for msg.Type == types.SocketProgress {
+			Expect(msg.JobUUID).To(Equal(accepted.JobUUID))
+			msg = receive()
+		}
+		Expect(msg.Type).To(Equal(types.SocketResult))
+		Expect(msg.JobUUID).To(Equal(accepted.JobUUID))
+		Expect(msg.Error).To(BeNil())
+		Expect(msg.Result).NotTo(BeEmpty())
+	})
+
+	It("rejects invalid messages and jobs without closing the connection", func() {
+		Expect(websocket.Message.Send(ws, `{"type":`)).To(Succeed())
+		msg := receive()
+		Expect(msg.Type).To(Equal(types.SocketError))
+		Expect(msg.Error.Error).To(ContainSubstring("invalid message"))
+
+		Expect(websocket.Message.Send(ws, `{"type":"submit","request_id":"big","job":{"encrypted_job":"`+strings.Repeat("a", 4096)+`"}}`)).To(Succeed())
+		msg = receive()
+		Expect(msg.Type).To(Equal(types.SocketError))
+		Expect(msg.Error.Error).To(Equal("message exceeds 4096 bytes"))
+
+		submit("r2", types.Job{Type: teetypes.TelemetryJob, Arguments: map[string]any{"query": "masa"}})
+		msg = receive()
+		Expect(msg.Type).To(Equal(types.SocketError))
+		Expect(msg.RequestID).To(Equal("r2"))
+		Expect(msg.Error.Violations).To(Equal([]types.ArgumentViolation{{Argument: "query", Reason: "unknown argument"}}))
+
+		Expect(websocket.JSON.Send(ws, types.SocketMessage{Type: "status", RequestID: "r3"})).To(Succeed())
+		msg = receive()
+		Expect(msg.Type).To(Equal(types.SocketError))
+		Expect(msg.RequestID).To(Equal("r3"))
+		Expect(msg.Error.Error).To(Equal(`unknown message type "status"`))
+	})
+
+	It("cancels jobs", func() {
+		// The job server is not running, so the job stays queued
+		submit("r4", types.Job{Type: teetypes.TelemetryJob})
+		accepted := receive()
+		Expect(accepted.Type).To(Equal(types.SocketAccepted))
+
+		Expect(websocket.JSON.Send(ws, types.SocketMessage{Type: types.SocketCancel, JobUUID: accepted.JobUUID})).To(Succeed())
+		received := map[types.SocketMessageType]types.SocketMessage{}
+		for range 2 {
+			msg := receive()
+			received[msg.Type] = msg
+		}
+		Expect(received).To(HaveKey(types.SocketCancelled))
+		Expect(received).To(HaveKey(types.SocketResult))
+		Expect(received[types.SocketResult].JobUUID).To(Equal(accepted.JobUUID))
+		Expect(received[types.SocketResult].Error.Error).To(Equal(jobserver.ErrJobCancelled.Error()))
+
+		Expect(websocket.JSON.Send(ws, types.SocketMessage{Type: types.SocketCancel, JobUUID: accepted.JobUUID})).To(Succeed())
+		msg := receive()
+		Expect(msg.Type).To(Equal(types.SocketError))
+		Expect(msg.Error.Error).To(Equal(jobserver.ErrJobFinished.Error()))
+	})
+})
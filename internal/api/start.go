@@ -1,20 +1,33 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"crypto/tls"
+	"errors"
 	"fmt"
 	"net/http"
 	"runtime"
 	"strings"
+	"sync/atomic"
 
+	"github.com/edgelesssys/ego/attestation"
 	"github.com/edgelesssys/ego/enclave"
 	"github.com/labstack/echo-contrib/pprof"
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/credentials"
 	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/internal/logging"
+	"github.com/masa-finance/tee-worker/internal/peers"
+	"github.com/masa-finance/tee-worker/internal/secrets"
+	"github.com/masa-finance/tee-worker/internal/telemetry"
+	"github.com/masa-finance/tee-worker/internal/tracing"
 	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
 )
 
 func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool, jc config.JobConfiguration) error {
@@ -23,19 +36,47 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 	e := echo.New()
 
 	// Default loglevel
-	logLevel := jc.GetString("log_level", "info")
-	e.Logger.SetLevel(parseLogLevel(logLevel))
+	e.Logger.SetLevel(parseLogLevel(logging.Level("api").String()))
 
 	// Jobserver instance
 	maxJobs, _ := jc.GetInt("max_jobs", 10)
 	jobServer := jobserver.NewJobServer(maxJobs, jc)
 
 	go jobServer.Run(ctx)
+	defer jobServer.Shutdown()
+
+	// Validate the provider secrets, so misconfigured workers can be spotted before their jobs fail
+	var secretDiagnostics atomic.Pointer[[]types.SecretDiagnostic]
+	validateSecrets := func(jc config.JobConfiguration) {
+		diagnostics := secrets.Validate(jc)
+		secrets.Log(diagnostics)
+		secretDiagnostics.Store(&diagnostics)
+	}
+	validateSecrets(jc)
+
+	// Configuration reported by /config, which includes the reloaded credentials
+	var effectiveConfig atomic.Pointer[config.JobConfiguration]
+	effectiveConfig.Store(&jc)
+
+	// Reload the credentials when they are changed in the .env file, and validate the new ones
+	credentialsManager := credentials.NewManager(jc, func(jc config.JobConfiguration, changed []string) {
+		jobServer.ReloadCredentials(jc, changed)
+		effectiveConfig.Store(&jc)
+		validateSecrets(jc)
+	})
+	go credentialsManager.Run(ctx, jc.GetDuration("credentials_reload_interval_seconds", 30))
+
+	// Benchmark the worker in the background, so its capacity is reported in telemetry
+	if jc.GetBool("benchmark_on_startup", false) {
+		go jobServer.RunBenchmark()
+	}
 
 	// Initialize health metrics
 	healthMetrics := NewHealthMetrics()
 
 	// Middleware
+	// The span of each API request is the parent of the spans of the jobs it submits, see OTEL_EXPORTER_OTLP_ENDPOINT
+	e.Use(tracing.Middleware())
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
 
@@ -45,6 +86,9 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 	// Health metrics tracking middleware
 	e.Use(HealthMetricsMiddleware(healthMetrics))
 
+	// Request size limits and job argument validation
+	e.Use(JobValidationMiddleware(jc, jobServer))
+
 	// Initialize empty key ring
 	tee.CurrentKeyRing = tee.NewKeyRing()
 
@@ -57,7 +101,7 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 
 	// Health check endpoints (no auth required)
 	e.GET("/healthz", Healthz())
-	e.GET("/readyz", Readyz(jobServer, healthMetrics))
+	e.GET("/readyz", Readyz(jobServer, healthMetrics, DependencyProbes(jc, jobServer)...))
 
 	debug := e.Group("/debug")
 	debug.PUT("/loglevel", func(c echo.Context) error {
@@ -77,6 +121,17 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 		return c.String(http.StatusOK, fmt.Sprintf("log level set to %s", levelStr))
 	})
 
+	// Log levels, which can be set per module to raise the verbosity of one subsystem without restarting
+	admin := e.Group("/admin")
+	admin.GET("/loglevel", getLogLevel)
+	admin.PUT("/loglevel", setLogLevel(e))
+	admin.DELETE("/loglevel", resetLogLevel(e))
+	// Dimensions the statistics are broken down by, which can be changed to investigate an issue without restarting
+	admin.GET("/stats/dimensions", GetStatsDimensions(jobServer))
+	admin.PUT("/stats/dimensions", SetStatsDimensions(jobServer))
+	// Health check of the Twitter accounts, so dead accounts can be replaced before jobs run into them
+	admin.POST("/twitter/auth-check", checkTwitterAccounts(jobServer))
+
 	if standalone {
 		// Set up profiling if allowed
 		if jc.GetBool("profiling_enabled", false) {
@@ -99,19 +154,95 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 			return c.String(http.StatusBadRequest, "pprof not supported")
 		})
 
+		// Web dashboard to submit jobs and monitor the worker
+		e.GET(DashboardPath, dashboard)
+		e.GET(DashboardPath+"/overview", dashboardOverview(jobServer))
+	}
+
+	// Capability discovery, aggregated from all registered job types
+	e.GET("/capabilities", capabilities(jobServer))
+
+	// Fresh attestation bound to a nonce of the caller. Quotes can only be generated inside the enclave.
+	var remoteReport RemoteReportFunc
+	if !standalone {
+		remoteReport = enclave.GetRemoteReport
+	}
+	e.GET("/attestation", Attestation(jobServer, remoteReport))
+
+	// Hash-chained log of the jobs accepted and finished by the worker, for the resolution of disputes
+	e.GET("/audit", AuditLog(jobServer, remoteReport))
+
+	// Statistics pushed to a collector, for workers the indexer can't reach to pull them with telemetry jobs
+	if pushConfig := jc.GetTelemetryPushConfig(); pushConfig.URL != "" {
+		pusher, err := telemetry.NewPusher(pushConfig, jobServer.GetStats, telemetry.QuoteFunc(remoteReport))
+		if err != nil {
+			logrus.Errorf("Not pushing statistics to %s: %v", pushConfig.URL, err)
+		} else {
+			go pusher.Run(ctx)
+		}
+	}
+
+	// Rotation of the worker ID, which renames the worker, e.g. to migrate it to new hardware
+	admin.POST("/worker-id/rotate", RotateWorkerID(jobServer, dataDIR, jc.GetDuration("worker_id_rotation_grace_seconds", 86400), remoteReport))
+
+	// Self-benchmark of the worker: the latest report, or a new benchmark on demand
+	e.GET("/benchmark", latestBenchmark(jobServer))
+	e.POST("/benchmark", runBenchmark(jobServer))
+
+	// Optional GraphQL API exposing job submission, job status, capabilities and statistics as a typed schema
+	if jc.GetBool("graphql_enabled", false) {
+		graphqlAPI := graphqlHandler(jc, jobServer)
+		e.GET(GraphQLPath, graphqlAPI)
+		e.POST(GraphQLPath, graphqlAPI)
 	}
 
+	// Validation status of the secrets, checked at startup and whenever the credentials are reloaded
+	e.GET("/status", workerStatus(func() []types.SecretDiagnostic { return *secretDiagnostics.Load() }))
+
+	// Effective configuration with the credentials redacted, including the credentials reloaded at runtime
+	e.GET("/config", WorkerConfig(jobServer, func() config.JobConfiguration { return *effectiveConfig.Load() }))
+
+	// Sealed Twitter sessions, to move the logged in accounts to another worker with the same sealing key
+	e.GET("/twitter/sessions", exportTwitterSessions(jobServer))
+	e.POST("/twitter/sessions", importTwitterSessions(jobServer))
+
 	/*
 		- POST /job/generate: Generate a job payload
 		- POST /job/add: Add a job to the queue
-		- GET /job/status/:job_id: Get the status of a job
+		- POST /job/estimate: Estimate what a job costs and whether the worker can serve it now, without executing it
+		- GET /job/status/:job_id: Get the status of a job, optionally waiting for it to finish
+		- GET /job/:job_id/trace: Get the execution trace of a job submitted with debug
+		- GET /job/:job_id/stream: Get the batches of results emitted by a job while it runs, optionally waiting for them
+		- DELETE /job/schedule/:job_id: Stop re-executing a recurring job
+		- PUT /job/hold/:job_id: Retain the result of a job until it is released
+		- DELETE /job/hold/:job_id: Release a retained result
 		- POST /job/result: Get the result of a job, decrypt it and return it
+		- GET /job/ws: WebSocket to submit and cancel jobs, and receive their progress and results
+		- POST /jobs/batch: Add several jobs to the queue at once
+		- GET /jobs/batch/:batch_id: Get the aggregate status of the jobs of a batch
 	*/
+	delegator, err := newDelegator(standalone, jc)
+	if err != nil {
+		e.Logger.Error("Failed to set up the peer workers: ", err)
+		return err
+	}
+
 	job := e.Group("/job")
 	job.POST("/generate", generate)
-	job.POST("/add", add(jobServer))
-	job.GET("/status/:job_id", status(jobServer))
+	job.POST("/add", add(jobServer, delegator))
+	job.POST("/estimate", estimate(jobServer))
+	job.GET("/status/:job_id", status(jobServer, delegator, jc.GetDuration("result_max_wait_seconds", 30)))
+	job.GET("/:job_id/trace", trace(jobServer))
+	job.GET("/:job_id/stream", stream(jobServer, jc.GetDuration("result_max_wait_seconds", 30)))
+	job.DELETE("/schedule/:job_id", unschedule(jobServer))
+	job.PUT("/hold/:job_id", hold(jobServer))
+	job.DELETE("/hold/:job_id", release(jobServer))
 	job.POST("/result", result)
+	job.GET("/ws", JobSocket(jobServer, jc))
+
+	jobs := e.Group("/jobs")
+	jobs.POST("/batch", addBatch(jobServer, jc))
+	jobs.GET("/batch/:batch_id", batchStatus(jobServer))
 
 	go func() {
 		<-ctx.Done()
@@ -143,6 +274,12 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 			TLSConfig: tlsCfg,
 			//ReadTimeout: 30 * time.Second, // use custom timeouts
 		}
+		go func() {
+			<-ctx.Done()
+			if err := s.Close(); err != nil {
+				e.Logger.Error("Failed to close server: ", err)
+			}
+		}()
 		if err := s.ListenAndServeTLS("", ""); err != http.ErrServerClosed {
 			e.Logger.Error(err)
 			return err
@@ -152,6 +289,49 @@ func Start(ctx context.Context, listenAddress, dataDIR string, standalone bool,
 	return nil
 }
 
+// newDelegator returns the Delegator forwarding jobs to the configured peer workers, or nil if there are none. In
+// enclave mode, the peers must attest that they run in an enclave with the same signer as this worker, both in their
+// quotes and in the report embedded in their TLS certificates, so PEER_API_KEY is only sent to such enclaves.
+func newDelegator(standalone bool, jc config.JobConfiguration) (*peers.Delegator, error) {
+	cfg := jc.GetPeerConfig()
+	if len(cfg.Workers) == 0 {
+		return nil, nil
+	}
+
+	var verify peers.QuoteVerifier
+	var signerID []byte
+	var tlsConfig *tls.Config
+	if !standalone {
+		self, err := enclave.GetSelfReport()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the report of this enclave: %w", err)
+		}
+		signerID = self.SignerID
+		tlsConfig = enclave.CreateAttestationClientTLSConfig(func(report attestation.Report) error {
+			switch {
+			case !bytes.Equal(report.SignerID, self.SignerID):
+				return errors.New("the peer is signed by another signer")
+			case !bytes.Equal(report.ProductID, self.ProductID):
+				return errors.New("the peer is another product")
+			case report.SecurityVersion < self.SecurityVersion:
+				return errors.New("the peer has an older security version")
+			case report.Debug != self.Debug:
+				return errors.New("the peer does not run in the same debug mode")
+			}
+			return nil
+		})
+		verify = func(quote []byte) ([]byte, []byte, error) {
+			report, err := enclave.VerifyRemoteReport(quote)
+			if err != nil {
+				return nil, nil, err
+			}
+			return report.Data, report.SignerID, nil
+		}
+	}
+
+	return peers.NewDelegator(cfg, tee.WorkerID, verify, signerID, jc.GetDuration("result_cache_max_age_seconds", 600), tlsConfig)
+}
+
 // parseLogLevel parses a logLevel into a log level appropriate for Echo. This is different from config.ParseLogLevel since that one uses a log level appropriate for logrus.
 func parseLogLevel(logLevel string) log.Lvl {
 	switch strings.ToLower(logLevel) {
@@ -0,0 +1,45 @@
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+)
+
+// statsDimensions returns the dimensions the statistics are broken down by
+func statsDimensions(jobServer *jobserver.JobServer) types.StatsDimensions {
+	dims := types.StatsDimensions{Dimensions: []string{}}
+	for _, d := range jobServer.StatsDimensions() {
+		dims.Dimensions = append(dims.Dimensions, string(d))
+	}
+	return dims
+}
+
+// GetStatsDimensions returns the dimensions the statistics are broken down by
+func GetStatsDimensions(jobServer *jobserver.JobServer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, statsDimensions(jobServer))
+	}
+}
+
+// SetStatsDimensions breaks the statistics down by the comma-separated dimensions of the dimensions query parameter,
+// like STATS_DIMENSIONS. An empty list disables the breakdowns. Miners can't change the dimensions.
+func SetStatsDimensions(jobServer *jobserver.JobServer) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if minerFromContext(c.Request().Context()) != "" {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: "the stats dimensions can't be changed with the API key of a miner"})
+		}
+
+		dims, err := stats.ParseDimensions(strings.Split(c.QueryParam("dimensions"), ","))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: err.Error()})
+		}
+		jobServer.SetStatsDimensions(dims)
+		return c.JSON(http.StatusOK, statsDimensions(jobServer))
+	}
+}
@@ -0,0 +1,60 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+)
+
+var _ = Describe("Stats dimensions endpoint", func() {
+	var jobServer *jobserver.JobServer
+
+	BeforeEach(func() {
+		jobServer = jobserver.NewJobServer(1, config.JobConfiguration{"stats_dimensions": []string{"capability"}})
+	})
+
+	call := func(handler echo.HandlerFunc, method, query string) (int, types.StatsDimensions) {
+		e := echo.New()
+		req := httptest.NewRequest(method, "/admin/stats/dimensions"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(handler(e.NewContext(req, rec))).To(Succeed())
+
+		var dims types.StatsDimensions
+		if rec.Code == http.StatusOK {
+			Expect(json.Unmarshal(rec.Body.Bytes(), &dims)).To(Succeed())
+		}
+		return rec.Code, dims
+	}
+
+	It("should return the configured dimensions", func() {
+		code, dims := call(GetStatsDimensions(jobServer), http.MethodGet, "")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(dims.Dimensions).To(Equal([]string{"capability"}))
+	})
+
+	It("should change the dimensions at runtime", func() {
+		code, dims := call(SetStatsDimensions(jobServer), http.MethodPut, "?dimensions=result_type,provider")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(dims.Dimensions).To(Equal([]string{"provider", "result_type"}))
+		Expect(jobServer.StatsDimensions()).To(HaveLen(2))
+
+		code, dims = call(SetStatsDimensions(jobServer), http.MethodPut, "?dimensions=")
+		Expect(code).To(Equal(http.StatusOK))
+		Expect(dims.Dimensions).To(BeEmpty())
+	})
+
+	It("should reject unknown dimensions without changing them", func() {
+		code, _ := call(SetStatsDimensions(jobServer), http.MethodPut, "?dimensions=provider,country")
+		Expect(code).To(Equal(http.StatusBadRequest))
+		Expect(jobServer.StatsDimensions()).To(HaveLen(1))
+	})
+})
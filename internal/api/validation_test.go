@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+type fakeArgumentKeys map[teetypes.JobType][]string
+
+func (f fakeArgumentKeys) ArgumentKeys(jobType teetypes.JobType) ([]string, bool) {
+	keys, ok := f[jobType]
+	return keys, ok
+}
+
+var _ = Describe("JobValidationMiddleware", func() {
+	var e *echo.Echo
+
+	BeforeEach(func() {
+		e = echo.New()
+		jc := config.JobConfiguration{
+			"max_request_body_bytes":    512,
+			"twitter_max_results_limit": 100,
+		}
+		e.Use(JobValidationMiddleware(jc, fakeArgumentKeys{
+			teetypes.TwitterJob: {"max_results", "query", "type"},
+		}))
+		handler := func(c echo.Context) error {
+			job := types.Job{}
+			if err := c.Bind(&job); err != nil {
+				return c.String(http.StatusBadRequest, err.Error())
+			}
+			return c.String(http.StatusOK, "passed")
+		}
+		e.POST("/job/generate", handler)
+		e.POST("/job/add", handler)
+		e.POST("/other", handler)
+	})
+
+	post := func(path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	violations := func(rec *httptest.ResponseRecorder) []types.ArgumentViolation {
+		jobErr := types.JobError{}
+		Expect(json.Unmarshal(rec.Body.Bytes(), &jobErr)).To(Succeed())
+		Expect(jobErr.Error).To(Equal("invalid job arguments"))
+		return jobErr.Violations
+	}
+
+	It("passes valid jobs on to the handler", func() {
+		rec := post("/job/generate", `{"type":"twitter","arguments":{"type":"searchbyquery","query":"masa","max_results":100}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(rec.Body.String()).To(Equal("passed"))
+	})
+
+	It("rejects unknown arguments and max_results above the limit", func() {
+		rec := post("/job/generate", `{"type":"twitter","arguments":{"query":"masa","max_result":10,"max_results":101}}`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(violations(rec)).To(Equal([]types.ArgumentViolation{
+			{Argument: "max_result", Reason: "unknown argument"},
+			{Argument: "max_results", Reason: "must be at most 100 for twitter jobs"},
+		}))
+	})
+
+	It("only checks the arguments of job types it knows", func() {
+		rec := post("/job/generate", `{"type":"web","arguments":{"anything":true,"max_results":1000}}`)
+		Expect(rec.Code).To(Equal(http.StatusOK))
+	})
+
+	It("validates encrypted jobs", func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		dat, err := json.Marshal(types.Job{Type: teetypes.TwitterJob, Arguments: map[string]any{"query": "masa", "count": 5}})
+		Expect(err).NotTo(HaveOccurred())
+		sealed, err := tee.Seal(dat)
+		Expect(err).NotTo(HaveOccurred())
+		body, err := json.Marshal(types.JobRequest{EncryptedJob: sealed})
+		Expect(err).NotTo(HaveOccurred())
+
+		rec := post("/job/add", string(body))
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(violations(rec)).To(Equal([]types.ArgumentViolation{{Argument: "count", Reason: "unknown argument"}}))
+	})
+
+	It("leaves jobs which can't be decoded to the handler", func() {
+		rec := post("/job/generate", `{"type":`)
+		Expect(rec.Code).To(Equal(http.StatusBadRequest))
+		Expect(rec.Body.String()).NotTo(ContainSubstring("invalid job arguments"))
+	})
+
+	It("rejects request bodies above the size limit", func() {
+		body := `{"type":"twitter","arguments":{"query":"` + strings.Repeat("a", 512) + `"}}`
+		for _, path := range []string{"/job/generate", "/other"} {
+			rec := post(path, body)
+			Expect(rec.Code).To(Equal(http.StatusRequestEntityTooLarge))
+		}
+	})
+})
@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// maxRotationReasonLength is the maximum length of the reason of a worker ID rotation
+const maxRotationReasonLength = 256
+
+// retiredWorkerIDs returns the worker IDs replaced by a rotation
+func retiredWorkerIDs() []types.RetiredWorkerID {
+	var retired []types.RetiredWorkerID
+	for _, r := range tee.RetiredWorkerIDs() {
+		retired = append(retired, types.RetiredWorkerID(r))
+	}
+	return retired
+}
+
+// RotateWorkerID renames the worker by replacing its persistent worker ID with a new one, see tee.RotateWorkerID for
+// what a rotation doesn't change. The previous worker ID stays valid for the
+// grace_seconds query parameter, or grace by default, so the jobs already assigned to it are still accepted. The
+// reason query parameter is kept in the history of the worker. Outside of standalone mode, the response has a quote
+// binding both worker IDs, which the operator forwards to the key distributor to register the new worker ID. Miners
+// can't rotate the worker ID.
+func RotateWorkerID(jobServer *jobserver.JobServer, dataDir string, grace time.Duration, remoteReport RemoteReportFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if minerFromContext(c.Request().Context()) != "" {
+			return c.JSON(http.StatusForbidden, types.JobError{Error: "the worker ID can't be rotated with the API key of a miner"})
+		}
+
+		reason := c.QueryParam("reason")
+		if len(reason) > maxRotationReasonLength {
+			return c.JSON(http.StatusBadRequest, types.JobError{Error: fmt.Sprintf("reason must be at most %d bytes", maxRotationReasonLength)})
+		}
+		if s := c.QueryParam("grace_seconds"); s != "" {
+			seconds, err := strconv.Atoi(s)
+			if err != nil || seconds < 0 {
+				return c.JSON(http.StatusBadRequest, types.JobError{Error: "grace_seconds must be a non-negative integer"})
+			}
+			grace = time.Duration(seconds) * time.Second
+		}
+
+		retired, err := tee.RotateWorkerID(dataDir, reason, grace)
+		if err != nil {
+			logrus.Errorf("Error while rotating the worker ID: %s", err)
+			return c.JSON(http.StatusInternalServerError, types.JobError{Error: "failed to rotate the worker ID"})
+		}
+		jobServer.SetWorkerID(retired.ReplacedBy)
+		logrus.Warnf("Worker ID rotated from %s to %s, the previous one is valid until %s", retired.ID, retired.ReplacedBy, retired.ValidUntil.Format(time.RFC3339))
+
+		rotation := types.WorkerIDRotation{
+			WorkerID:           retired.ReplacedBy,
+			PreviousWorkerID:   retired.ID,
+			RotatedAt:          retired.RotatedAt,
+			PreviousValidUntil: retired.ValidUntil,
+			Reason:             retired.Reason,
+			RetiredWorkerIDs:   retiredWorkerIDs(),
+		}
+		if remoteReport != nil {
+			// The rotation is done either way, so a failed quote is reported without failing the request
+			reportData := types.WorkerIDRotationReportData(retired.ID, retired.ReplacedBy, retired.RotatedAt)
+			if quote, err := remoteReport(reportData); err != nil {
+				logrus.Errorf("Error while generating the quote of the worker ID rotation: %s", err)
+			} else {
+				rotation.ReportData = hex.EncodeToString(reportData)
+				rotation.Quote = base64.StdEncoding.EncodeToString(quote)
+			}
+		}
+		return c.JSON(http.StatusOK, rotation)
+	}
+}
@@ -0,0 +1,101 @@
+package api_test
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/api"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Worker ID rotation endpoint", func() {
+	var (
+		jobServer *jobserver.JobServer
+		dataDir   string
+		workerID  string
+		reportErr error
+		quote     RemoteReportFunc
+	)
+
+	BeforeEach(func() {
+		jobServer = jobserver.NewJobServer(1, config.JobConfiguration{})
+		dataDir = GinkgoT().TempDir()
+		reportErr = nil
+		quote = func(data []byte) ([]byte, error) {
+			return append([]byte("quote:"), data...), reportErr
+		}
+
+		// The retired worker IDs can't be reset, so each spec starts with a worker ID of its own
+		workerID = uuid.NewString()
+		originalWorkerID, originalStandalone := tee.WorkerID, tee.SealStandaloneMode
+		tee.WorkerID, tee.SealStandaloneMode = workerID, true
+		DeferCleanup(func() { tee.WorkerID, tee.SealStandaloneMode = originalWorkerID, originalStandalone })
+	})
+
+	rotate := func(remoteReport RemoteReportFunc, query string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/worker-id/rotate"+query, nil)
+		rec := httptest.NewRecorder()
+		Expect(RotateWorkerID(jobServer, dataDir, time.Hour, remoteReport)(e.NewContext(req, rec))).To(Succeed())
+		return rec
+	}
+
+	It("should rotate the worker ID and return a quote binding both worker IDs", func() {
+		rec := rotate(quote, "?reason=migration")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+
+		var resp types.WorkerIDRotation
+		Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+		Expect(resp.PreviousWorkerID).To(Equal(workerID))
+		Expect(resp.WorkerID).To(Equal(tee.CurrentWorkerID()))
+		Expect(resp.Reason).To(Equal("migration"))
+		Expect(resp.PreviousValidUntil).To(BeTemporally("~", resp.RotatedAt.Add(time.Hour)))
+		Expect(resp.RetiredWorkerIDs).NotTo(BeEmpty())
+		Expect(resp.RetiredWorkerIDs[len(resp.RetiredWorkerIDs)-1].ID).To(Equal(workerID))
+
+		expected := types.WorkerIDRotationReportData(workerID, resp.WorkerID, resp.RotatedAt)
+		Expect(resp.ReportData).To(Equal(hex.EncodeToString(expected)))
+		Expect(resp.Quote).To(Equal(base64.StdEncoding.EncodeToString(append([]byte("quote:"), expected...))))
+
+		// Jobs for the previous worker ID are still accepted during the grace period
+		Expect(tee.IsWorkerID(workerID)).To(BeTrue())
+	})
+
+	It("should override the grace period", func() {
+		rec := rotate(nil, "?grace_seconds=0")
+		Expect(rec.Code).To(Equal(http.StatusOK))
+		Expect(tee.IsWorkerID(workerID)).To(BeFalse())
+	})
+
+	It("should rotate the worker ID without a quote in standalone mode or if the quote fails", func() {
+		reportErr = errors.New("no SGX device")
+		for _, remoteReport := range []RemoteReportFunc{nil, quote} {
+			rec := rotate(remoteReport, "")
+			Expect(rec.Code).To(Equal(http.StatusOK))
+
+			var resp types.WorkerIDRotation
+			Expect(json.Unmarshal(rec.Body.Bytes(), &resp)).To(Succeed())
+			Expect(resp.WorkerID).To(Equal(tee.CurrentWorkerID()))
+			Expect(resp.Quote).To(BeEmpty())
+		}
+	})
+
+	It("should reject invalid arguments without rotating the worker ID", func() {
+		Expect(rotate(quote, "?grace_seconds=-1").Code).To(Equal(http.StatusBadRequest))
+		Expect(rotate(quote, "?grace_seconds=abc").Code).To(Equal(http.StatusBadRequest))
+		Expect(tee.CurrentWorkerID()).To(Equal(workerID))
+	})
+})
@@ -1,6 +1,12 @@
 package apify
 
-import teetypes "github.com/masa-finance/tee-types/types"
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
 
 type ActorId string
 
@@ -11,6 +17,7 @@ type actorIds struct {
 	LLMDatasetProcessor   ActorId
 	TwitterFollowers      ActorId
 	WebScraper            ActorId
+	WebScreenshot         ActorId
 }
 
 var ActorIds = actorIds{
@@ -20,6 +27,88 @@ var ActorIds = actorIds{
 	LLMDatasetProcessor:   "dusan.vystrcil~llm-dataset-processor",
 	TwitterFollowers:      "kaitoeasyapi~premium-x-follower-scraper-following-data",
 	WebScraper:            "apify~website-content-crawler",
+	WebScreenshot:         "apify~screenshot-url",
+}
+
+// ActorBuilds are the builds the actors are pinned to, i.e. a build tag such as "latest" or a build number such as
+// "0.3.67". Actors which are not pinned run their default build.
+var ActorBuilds = map[ActorId]string{}
+
+var (
+	actorIdPattern = regexp.MustCompile(`^[A-Za-z0-9._~-]+$`)
+	buildPattern   = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+)
+
+// actorsByName returns the actors used by the worker by the name used to configure them in APIFY_ACTORS
+func actorsByName() map[string]*ActorId {
+	return map[string]*ActorId{
+		"reddit_scraper":          &ActorIds.RedditScraper,
+		"tiktok_search_scraper":   &ActorIds.TikTokSearchScraper,
+		"tiktok_trending_scraper": &ActorIds.TikTokTrendingScraper,
+		"llm_dataset_processor":   &ActorIds.LLMDatasetProcessor,
+		"twitter_followers":       &ActorIds.TwitterFollowers,
+		"web_scraper":             &ActorIds.WebScraper,
+		"web_screenshot":          &ActorIds.WebScreenshot,
+	}
+}
+
+// Configure replaces the actors used by the worker and pins them to builds. Each spec has the form
+// name=actor[@build], e.g. web_scraper=apify~website-content-crawler@0.3.67; the actor can be left out to pin the
+// default actor, e.g. web_scraper=@0.3.67. Nothing is changed if one of the specs is invalid.
+func Configure(specs []string) error {
+	byName := actorsByName()
+	ids := map[string]ActorId{}
+	builds := map[string]string{}
+
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(spec, "=")
+		name = strings.TrimSpace(name)
+		if !ok {
+			return fmt.Errorf("invalid actor %q, expected name=actor[@build]", spec)
+		}
+		if _, known := byName[name]; !known {
+			return fmt.Errorf("unknown actor %q", name)
+		}
+		if _, dup := ids[name]; dup {
+			return fmt.Errorf("actor %q configured twice", name)
+		}
+
+		id, build, pinned := strings.Cut(strings.TrimSpace(value), "@")
+		if id == "" {
+			id = string(*byName[name])
+		}
+		if !actorIdPattern.MatchString(id) {
+			return fmt.Errorf("invalid ID %q of actor %q", id, name)
+		}
+		if pinned && !buildPattern.MatchString(build) {
+			return fmt.Errorf("invalid build %q of actor %q", build, name)
+		}
+		ids[name] = ActorId(id)
+		builds[name] = build
+	}
+
+	for name, id := range ids {
+		*byName[name] = id
+		if builds[name] != "" {
+			ActorBuilds[id] = builds[name]
+		}
+	}
+	Actors = actorConfigs()
+	return nil
+}
+
+// Allowed returns true if the actor is one of the actors used by the worker. No other actors are ever run.
+func Allowed(id ActorId) bool {
+	for _, actorId := range actorsByName() {
+		if *actorId == id {
+			return true
+		}
+	}
+	return false
 }
 
 type defaultActorInput map[string]any
@@ -32,35 +121,46 @@ type ActorConfig struct {
 }
 
 // Actors is a list of actor configurations for Apify.  Omitting LLM for now as it's not a standalone actor / has no dedicated capabilities
-var Actors = []ActorConfig{
-	{
-		ActorId:      ActorIds.RedditScraper,
-		DefaultInput: defaultActorInput{},
-		Capabilities: teetypes.RedditCaps,
-		JobType:      teetypes.RedditJob,
-	},
-	{
-		ActorId:      ActorIds.TikTokSearchScraper,
-		DefaultInput: defaultActorInput{"proxy": map[string]any{"useApifyProxy": true}},
-		Capabilities: []teetypes.Capability{teetypes.CapSearchByQuery},
-		JobType:      teetypes.TiktokJob,
-	},
-	{
-		ActorId:      ActorIds.TikTokTrendingScraper,
-		DefaultInput: defaultActorInput{},
-		Capabilities: []teetypes.Capability{teetypes.CapSearchByTrending},
-		JobType:      teetypes.TiktokJob,
-	},
-	{
-		ActorId:      ActorIds.TwitterFollowers,
-		DefaultInput: defaultActorInput{"maxFollowers": 200, "maxFollowings": 200},
-		Capabilities: teetypes.TwitterApifyCaps,
-		JobType:      teetypes.TwitterApifyJob,
-	},
-	{
-		ActorId:      ActorIds.WebScraper,
-		DefaultInput: defaultActorInput{"startUrls": []map[string]any{{"url": "https://docs.learnbittensor.org"}}},
-		Capabilities: teetypes.WebCaps,
-		JobType:      teetypes.WebJob,
-	},
+var Actors = actorConfigs()
+
+// actorConfigs returns the configurations of the actors with the current ActorIds
+func actorConfigs() []ActorConfig {
+	return []ActorConfig{
+		{
+			ActorId:      ActorIds.RedditScraper,
+			DefaultInput: defaultActorInput{},
+			Capabilities: teetypes.RedditCaps,
+			JobType:      teetypes.RedditJob,
+		},
+		{
+			ActorId:      ActorIds.TikTokSearchScraper,
+			DefaultInput: defaultActorInput{"proxy": map[string]any{"useApifyProxy": true}},
+			Capabilities: []teetypes.Capability{teetypes.CapSearchByQuery},
+			JobType:      teetypes.TiktokJob,
+		},
+		{
+			ActorId:      ActorIds.TikTokTrendingScraper,
+			DefaultInput: defaultActorInput{},
+			Capabilities: []teetypes.Capability{teetypes.CapSearchByTrending},
+			JobType:      teetypes.TiktokJob,
+		},
+		{
+			ActorId:      ActorIds.TwitterFollowers,
+			DefaultInput: defaultActorInput{"maxFollowers": 200, "maxFollowings": 200},
+			Capabilities: teetypes.TwitterApifyCaps,
+			JobType:      teetypes.TwitterApifyJob,
+		},
+		{
+			ActorId:      ActorIds.WebScraper,
+			DefaultInput: defaultActorInput{"startUrls": []map[string]any{{"url": "https://docs.learnbittensor.org"}}},
+			Capabilities: teetypes.WebCaps,
+			JobType:      teetypes.WebJob,
+		},
+		{
+			ActorId:      ActorIds.WebScreenshot,
+			DefaultInput: defaultActorInput{"urls": []map[string]any{{"url": "https://docs.learnbittensor.org"}}},
+			Capabilities: []teetypes.Capability{"screenshot"},
+			JobType:      teetypes.WebJob,
+		},
+	}
 }
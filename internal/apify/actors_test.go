@@ -0,0 +1,54 @@
+package apify_test
+
+import (
+	"slices"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/apify"
+)
+
+var _ = Describe("Configure", func() {
+	BeforeEach(func() {
+		ids, builds, actors := apify.ActorIds, apify.ActorBuilds, apify.Actors
+		apify.ActorBuilds = map[apify.ActorId]string{}
+		DeferCleanup(func() { apify.ActorIds, apify.ActorBuilds, apify.Actors = ids, builds, actors })
+	})
+
+	It("keeps the default actors without overrides", func() {
+		Expect(apify.Configure(nil)).To(Succeed())
+		Expect(apify.ActorIds.WebScraper).To(Equal(apify.ActorId("apify~website-content-crawler")))
+		Expect(apify.ActorBuilds).To(BeEmpty())
+		Expect(apify.Allowed(apify.ActorIds.WebScraper)).To(BeTrue())
+		Expect(apify.Allowed("someone~else")).To(BeFalse())
+	})
+
+	It("replaces actors and pins their builds", func() {
+		Expect(apify.Configure([]string{"web_scraper=me~crawler@1.2.3", " reddit_scraper=@latest "})).To(Succeed())
+
+		Expect(apify.ActorIds.WebScraper).To(Equal(apify.ActorId("me~crawler")))
+		Expect(apify.ActorBuilds).To(Equal(map[apify.ActorId]string{
+			"me~crawler":            "1.2.3",
+			"trudax~reddit-scraper": "latest",
+		}))
+		Expect(apify.Allowed("me~crawler")).To(BeTrue())
+		Expect(apify.Allowed("apify~website-content-crawler")).To(BeFalse())
+
+		for _, actor := range apify.Actors {
+			if actor.JobType == teetypes.WebJob && slices.Contains(actor.Capabilities, teetypes.CapScraper) {
+				Expect(actor.ActorId).To(Equal(apify.ActorId("me~crawler")))
+			}
+		}
+	})
+
+	It("rejects invalid specs without changing anything", func() {
+		for _, spec := range []string{"web_scraper", "unknown=me~crawler", "web_scraper=me/crawler", "web_scraper=me~crawler@", "web_scraper=@1.0&x=y"} {
+			Expect(apify.Configure([]string{"reddit_scraper=@latest", spec})).NotTo(Succeed(), spec)
+		}
+		Expect(apify.Configure([]string{"web_scraper=@1", "web_scraper=@2"})).NotTo(Succeed())
+		Expect(apify.ActorBuilds).To(BeEmpty())
+		Expect(apify.ActorIds.WebScraper).To(Equal(apify.ActorId("apify~website-content-crawler")))
+	})
+})
@@ -0,0 +1,13 @@
+package apify_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestApify(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Apify Suite")
+}
@@ -0,0 +1,214 @@
+package bandwidth
+
+/*
+Package bandwidth accounts for the network traffic of each job. The job server creates a Meter for every
+attempt of a job and keeps it, the job only gets its Transport method as types.Job.Network. Scrapers wrap
+the transport of the HTTP clients they use for the job with it:
+
+   httpClient := j.Network.Client(&http.Client{Timeout: 30 * time.Second})
+
+Bytes are counted as seen by the HTTP client, i.e. request and response headers and bodies after
+transparent decompression. If the meter has a cap, requests fail and response bodies stop being read
+with ErrCapExceeded once the cap is exceeded. A nil Meter is valid and counts nothing.
+
+An observer can be set with Observe to be told about every request made through the meter, e.g. to
+trace the job.
+*/
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// ErrCapExceeded is returned by metered transports once the bandwidth cap of the job has been exceeded
+var ErrCapExceeded = errors.New("bandwidth cap exceeded")
+
+// Meter counts the bytes downloaded and uploaded by a job
+type Meter struct {
+	limit      int64 // 0 means unlimited
+	downloaded atomic.Int64
+	uploaded   atomic.Int64
+	exceeded   atomic.Bool
+	observer   atomic.Pointer[func(Request)]
+}
+
+// Request describes a request made through a meter, once its response headers have been received or it failed
+type Request = types.HTTPRequest
+
+// NewMeter returns a meter which caps the traffic at limit bytes in total. A limit of 0 disables the cap.
+func NewMeter(limit int64) *Meter {
+	return &Meter{limit: max(limit, 0)}
+}
+
+// Limit returns the cap of the meter, or 0 if it is unlimited
+func (m *Meter) Limit() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.limit
+}
+
+// Downloaded returns the number of bytes received
+func (m *Meter) Downloaded() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.downloaded.Load()
+}
+
+// Uploaded returns the number of bytes sent
+func (m *Meter) Uploaded() int64 {
+	if m == nil {
+		return 0
+	}
+	return m.uploaded.Load()
+}
+
+// Observe sets a function which is called for every request made through the meter. It must be safe for
+// concurrent use.
+func (m *Meter) Observe(observer func(Request)) {
+	if m == nil {
+		return
+	}
+	m.observer.Store(&observer)
+}
+
+// observe passes the request to the observer, if there is one
+func (m *Meter) observe(req *http.Request, startedAt time.Time, resp *http.Response, err error) {
+	observer := m.observer.Load()
+	if observer == nil {
+		return
+	}
+	r := Request{Method: req.Method, URL: req.URL, StartedAt: startedAt, Duration: time.Since(startedAt), Err: err}
+	if resp != nil {
+		r.StatusCode = resp.StatusCode
+	}
+	(*observer)(r)
+}
+
+// Exceeded returns true if the cap has been exceeded
+func (m *Meter) Exceeded() bool {
+	return m != nil && m.exceeded.Load()
+}
+
+// add records the given traffic and returns ErrCapExceeded if the cap is now exceeded
+func (m *Meter) add(counter *atomic.Int64, n int64) error {
+	counter.Add(n)
+	if m.limit > 0 && m.downloaded.Load()+m.uploaded.Load() > m.limit {
+		m.exceeded.Store(true)
+	}
+	if m.exceeded.Load() {
+		return ErrCapExceeded
+	}
+	return nil
+}
+
+// Transport wraps base (http.DefaultTransport if nil) so that its traffic is counted by the meter
+func (m *Meter) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if m == nil {
+		return base
+	}
+	return &transport{meter: m, base: base}
+}
+
+// Client returns a copy of base (or of a zero http.Client if nil) whose traffic is counted by the meter
+func (m *Meter) Client(base *http.Client) *http.Client {
+	c := &http.Client{}
+	if base != nil {
+		*c = *base
+	}
+	if m == nil {
+		return c
+	}
+	c.Transport = m.Transport(c.Transport)
+	return c
+}
+
+type transport struct {
+	meter *Meter
+	base  http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.meter.Exceeded() {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, ErrCapExceeded
+	}
+
+	if err := t.meter.add(&t.meter.uploaded, requestHeaderSize(req)); err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+
+	if req.Body != nil && req.Body != http.NoBody {
+		// The request must not be modified, so the body is counted on a copy
+		req = req.Clone(req.Context())
+		req.Body = &countingReader{ReadCloser: req.Body, meter: t.meter, counter: &t.meter.uploaded}
+	}
+
+	startedAt := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	t.meter.observe(req, startedAt, resp, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := t.meter.add(&t.meter.downloaded, responseHeaderSize(resp)); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	resp.Body = &countingReader{ReadCloser: resp.Body, meter: t.meter, counter: &t.meter.downloaded}
+
+	return resp, nil
+}
+
+// countingReader counts the bytes read from a body, and fails once the cap of the meter is exceeded
+type countingReader struct {
+	io.ReadCloser
+	meter   *Meter
+	counter *atomic.Int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	if r.meter.Exceeded() {
+		return 0, ErrCapExceeded
+	}
+	n, err := r.ReadCloser.Read(p)
+	if capErr := r.meter.add(r.counter, int64(n)); capErr != nil {
+		return n, capErr
+	}
+	return n, err
+}
+
+// requestHeaderSize approximates the size of the request line and headers of an HTTP/1.1 request
+func requestHeaderSize(req *http.Request) int64 {
+	size := len(req.Method) + len(req.URL.RequestURI()) + len(" HTTP/1.1\r\n") + len("Host: \r\n") + len(req.URL.Host) + 2
+	return int64(size) + headerSize(req.Header)
+}
+
+// responseHeaderSize approximates the size of the status line and headers of an HTTP/1.1 response
+func responseHeaderSize(resp *http.Response) int64 {
+	return int64(len("HTTP/1.1 ")+len(resp.Status)+2) + headerSize(resp.Header)
+}
+
+func headerSize(h http.Header) int64 {
+	var size int
+	for k, vs := range h {
+		for _, v := range vs {
+			size += len(k) + len(": \r\n") + len(v)
+		}
+	}
+	return int64(size)
+}
@@ -0,0 +1,94 @@
+package bandwidth_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+)
+
+var _ = Describe("Meter", func() {
+	var server *httptest.Server
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = io.Copy(io.Discard, r.Body)
+			_, _ = w.Write([]byte(strings.Repeat("x", 1000)))
+		}))
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("counts the bytes downloaded and uploaded", func() {
+		m := bandwidth.NewMeter(0)
+		c := m.Client(nil)
+
+		resp, err := c.Post(server.URL, "text/plain", strings.NewReader(strings.Repeat("y", 500)))
+		Expect(err).NotTo(HaveOccurred())
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(body).To(HaveLen(1000))
+		Expect(m.Downloaded()).To(BeNumerically(">", 1000))
+		Expect(m.Uploaded()).To(BeNumerically(">", 500))
+		Expect(m.Exceeded()).To(BeFalse())
+	})
+
+	It("fails once the cap is exceeded", func() {
+		m := bandwidth.NewMeter(600)
+		c := m.Client(nil)
+
+		resp, err := c.Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		Expect(err).To(MatchError(bandwidth.ErrCapExceeded))
+		Expect(m.Exceeded()).To(BeTrue())
+		Expect(m.Limit()).To(Equal(int64(600)))
+
+		_, err = c.Get(server.URL)
+		Expect(err).To(MatchError(bandwidth.ErrCapExceeded))
+	})
+
+	It("does not modify the base client", func() {
+		base := &http.Client{}
+		c := bandwidth.NewMeter(0).Client(base)
+		Expect(c).NotTo(BeIdenticalTo(base))
+		Expect(base.Transport).To(BeNil())
+		Expect(c.Transport).NotTo(BeNil())
+	})
+
+	It("counts nothing if the meter is nil", func() {
+		var m *bandwidth.Meter
+		Expect(m.Transport(nil)).To(Equal(http.DefaultTransport))
+
+		resp, err := m.Client(nil).Get(server.URL)
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+		Expect(m.Downloaded()).To(BeZero())
+		Expect(m.Exceeded()).To(BeFalse())
+	})
+	It("tells the observer about every request", func() {
+		m := bandwidth.NewMeter(0)
+		var requests []bandwidth.Request
+		m.Observe(func(r bandwidth.Request) { requests = append(requests, r) })
+
+		resp, err := m.Client(nil).Get(server.URL + "/path?q=1")
+		Expect(err).NotTo(HaveOccurred())
+		resp.Body.Close()
+
+		Expect(requests).To(HaveLen(1))
+		Expect(requests[0].Method).To(Equal(http.MethodGet))
+		Expect(requests[0].URL.Path).To(Equal("/path"))
+		Expect(requests[0].StatusCode).To(Equal(http.StatusOK))
+		Expect(requests[0].Err).NotTo(HaveOccurred())
+	})
+})
@@ -74,8 +74,8 @@ func DetectCapabilities(jc config.JobConfiguration, jobServer JobServerInterface
 			jobToSet := map[teetypes.JobType]*util.Set[teetypes.Capability]{}
 
 			for _, actor := range apify.Actors {
-				// Web requires a valid Gemini API key
-				if actor.JobType == teetypes.WebJob && !hasLLMKey {
+				// Web scraping requires a valid Gemini API key, screenshots don't
+				if actor.ActorId == apify.ActorIds.WebScraper && !hasLLMKey {
 					logrus.Debug("Skipping Web actor due to missing Gemini key")
 					continue
 				}
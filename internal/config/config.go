@@ -2,7 +2,10 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
 	"path/filepath"
 	"strconv"
@@ -10,7 +13,11 @@ import (
 	"time"
 
 	"github.com/joho/godotenv"
+	teetypes "github.com/masa-finance/tee-types/types"
 	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/internal/logging"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 )
 
 var (
@@ -22,6 +29,12 @@ var (
 
 const defaultDataDir = "/home/masa"
 const defaultListenAddress = ":8080"
+const defaultMastodonInstance = "https://mastodon.social"
+const defaultGitHubAPIURL = "https://api.github.com"
+const defaultDiscordAPIURL = "https://discord.com/api/v10"
+const defaultResultCacheSpillBytes = 1 << 20
+const defaultTwitterMaxMediaBytes = 50 << 20
+const defaultResearchWebSearchURL = "https://html.duckduckgo.com/html/?q={query}"
 
 // TODO: Revamp this whole thing, a map[string]any is not really maintainable
 type JobConfiguration map[string]any
@@ -36,6 +49,20 @@ func ReadConfig() JobConfiguration {
 	jc["log_level"] = level.String()
 	SetLogLevel(level)
 
+	// Modules can log at their own level, e.g. LOG_MODULE_LEVELS=twitter:debug,api:warn
+	if s := os.Getenv("LOG_MODULE_LEVELS"); s != "" {
+		for _, entry := range strings.Split(s, ",") {
+			module, levelStr, _ := strings.Cut(strings.TrimSpace(entry), ":")
+			moduleLevel, err := logging.ParseLevel(levelStr)
+			if err == nil {
+				err = logging.SetModuleLevel(module, moduleLevel)
+			}
+			if err != nil {
+				logrus.Errorf("Error parsing LOG_MODULE_LEVELS entry %q: %v", entry, err)
+			}
+		}
+	}
+
 	dataDir := os.Getenv("DATA_DIR")
 	if dataDir == "" {
 		dataDir = "/home/masa"
@@ -55,6 +82,12 @@ func ReadConfig() JobConfiguration {
 		fmt.Println("Failed reading env file. Running in simulation mode, reading from environment variables")
 	}
 
+	// Credentials may reference secrets of the sealed secrets file instead of holding them, e.g. APIFY_API_KEY=secret:apify
+	if Secrets == nil {
+		Secrets = tee.NewSecretsFile(SecretsFilePath(os.Getenv))
+	}
+	getenv := WithSecrets(os.Getenv)
+
 	bufSizeStr := os.Getenv("STATS_BUF_SIZE")
 	if bufSizeStr == "" {
 		bufSizeStr = "128"
@@ -102,6 +135,61 @@ func ReadConfig() JobConfiguration {
 	}
 	jc["result_cache_max_age_seconds"] = time.Duration(resultCacheMaxAge) * time.Second
 
+	// Chunking of the items processed by the LLM processor, see GetLLMChunkConfig
+	for _, setting := range []struct {
+		name string
+		def  int
+		min  int
+	}{
+		{"LLM_CONTEXT_TOKENS", 32768, 1},
+		{"LLM_CHUNK_ITEMS", 100, 1},
+		{"LLM_MAX_CONCURRENT_CHUNKS", 4, 1},
+		{"LLM_TOKEN_BUDGET", 0, 0},
+	} {
+		v := setting.def
+		if s := os.Getenv(setting.name); s != "" {
+			if n, err := strconv.Atoi(s); err == nil && n >= setting.min {
+				v = n
+			}
+		}
+		jc[strings.ToLower(setting.name)] = v
+	}
+
+	// How long the previous worker ID stays valid after the worker ID is rotated
+	workerIDRotationGrace := 86400
+	if s := os.Getenv("WORKER_ID_ROTATION_GRACE_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			workerIDRotationGrace = v
+		}
+	}
+	jc["worker_id_rotation_grace_seconds"] = time.Duration(workerIDRotationGrace) * time.Second
+
+	// Total size of the result data in the cache, 0 for no limit
+	resultCacheMaxBytes := 0
+	if s := os.Getenv("RESULT_CACHE_MAX_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultCacheMaxBytes = v
+		}
+	}
+	jc["result_cache_max_bytes"] = resultCacheMaxBytes
+
+	// Results with more data than this are spilled to sealed files in the data directory, 0 to keep them in memory
+	resultCacheSpillBytes := defaultResultCacheSpillBytes
+	if s := os.Getenv("RESULT_CACHE_SPILL_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultCacheSpillBytes = v
+		}
+	}
+	jc["result_cache_spill_bytes"] = resultCacheSpillBytes
+
+	resultMaxHeld := 1000
+	if s := os.Getenv("RESULT_MAX_HELD"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultMaxHeld = v
+		}
+	}
+	jc["result_max_held"] = resultMaxHeld
+
 	jobTimeout := 300
 	if s := os.Getenv("JOB_TIMEOUT_SECONDS"); s != "" {
 		if v, err := strconv.Atoi(s); err == nil && v > 0 {
@@ -110,12 +198,536 @@ func ReadConfig() JobConfiguration {
 	}
 	jc["job_timeout_seconds"] = time.Duration(jobTimeout) * time.Second
 
+	// Timeouts can be configured per job type and per capability, e.g. TWITTER_APIFY_TIMEOUT_SECONDS or
+	// TWITTER_APIFY_GETFOLLOWERS_TIMEOUT_SECONDS
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		prefix, ok := strings.CutSuffix(name, "_TIMEOUT_SECONDS")
+		if !ok || prefix == "" || isNumericSetting(name) {
+			continue
+		}
+		key := timeoutConfigKey(strings.ToLower(prefix))
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			jc[key] = time.Duration(v) * time.Second
+		} else {
+			logrus.Errorf("Error parsing %s: %q. Using the default timeout.", name, value)
+		}
+	}
+
+	// Circuit breakers withdraw a capability after consecutive failures of its jobs, 0 disables them. Like the
+	// timeouts, they can be configured per job type and per capability, e.g. TWITTER_CIRCUIT_BREAKER_THRESHOLD or
+	// TWITTER_APIFY_GETFOLLOWERS_CIRCUIT_BREAKER_COOLDOWN_SECONDS
+	circuitBreakerThreshold := 5
+	if s := os.Getenv("CIRCUIT_BREAKER_THRESHOLD"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			circuitBreakerThreshold = v
+		}
+	}
+	jc["circuit_breaker_threshold"] = circuitBreakerThreshold
+
+	circuitBreakerCooldown := 60
+	if s := os.Getenv("CIRCUIT_BREAKER_COOLDOWN_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			circuitBreakerCooldown = v
+		}
+	}
+	jc["circuit_breaker_cooldown_seconds"] = time.Duration(circuitBreakerCooldown) * time.Second
+
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		if prefix, ok := strings.CutSuffix(name, "_CIRCUIT_BREAKER_THRESHOLD"); ok && prefix != "" {
+			if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+				jc[circuitBreakerConfigKey(strings.ToLower(prefix), "threshold")] = v
+			} else {
+				logrus.Errorf("Error parsing %s: %q. Using the default threshold.", name, value)
+			}
+		}
+		if prefix, ok := strings.CutSuffix(name, "_CIRCUIT_BREAKER_COOLDOWN_SECONDS"); ok && prefix != "" {
+			if v, err := strconv.Atoi(value); err == nil && v > 0 {
+				jc[circuitBreakerConfigKey(strings.ToLower(prefix), "cooldown")] = time.Duration(v) * time.Second
+			} else {
+				logrus.Errorf("Error parsing %s: %q. Using the default cooldown.", name, value)
+			}
+		}
+	}
+
+	jobTimeoutGrace := 30
+	if s := os.Getenv("JOB_TIMEOUT_GRACE_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			jobTimeoutGrace = v
+		}
+	}
+	jc["job_timeout_grace_seconds"] = time.Duration(jobTimeoutGrace) * time.Second
+
+	// Retry policy. Max retries are configured per job type, e.g. TWITTER_MAX_RETRIES or WEB_MAX_RETRIES
+	for jobType := range teetypes.JobCapabilityMap {
+		key := retryConfigKey(jobType.String())
+		if s := os.Getenv(strings.ToUpper(key)); s != "" {
+			if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+				jc[key] = v
+			} else {
+				logrus.Errorf("Error parsing %s: %q. Retries disabled for %s.", strings.ToUpper(key), s, jobType)
+			}
+		}
+	}
+
+	// Request validation. The max_results limit is configured per job type, e.g. TWITTER_MAX_RESULTS_LIMIT
+	maxBodyBytes := 1 << 20
+	if s := os.Getenv("MAX_REQUEST_BODY_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			maxBodyBytes = v
+		}
+	}
+	jc["max_request_body_bytes"] = maxBodyBytes
+
+	maxBatchJobs := 100
+	if s := os.Getenv("MAX_BATCH_JOBS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			maxBatchJobs = v
+		}
+	}
+	jc["max_batch_jobs"] = maxBatchJobs
+
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		jobType, ok := strings.CutSuffix(name, "_MAX_RESULTS_LIMIT")
+		if !ok || jobType == "" {
+			continue
+		}
+		key := maxResultsLimitConfigKey(strings.ToLower(jobType))
+		if v, err := strconv.Atoi(value); err == nil && v >= 0 {
+			jc[key] = v
+		} else {
+			logrus.Errorf("Error parsing %s: %q. No max_results limit for %s.", name, value, strings.ToLower(jobType))
+		}
+	}
+
+	// Concurrency limits are configured per job type, e.g. WEB_MAX_CONCURRENT or TWITTER_MAX_CONCURRENT
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		jobType, ok := strings.CutSuffix(name, "_MAX_CONCURRENT")
+		if !ok || jobType == "" {
+			continue
+		}
+		key := maxConcurrentConfigKey(strings.ToLower(jobType))
+		if v, err := strconv.Atoi(value); err == nil && v > 0 {
+			jc[key] = v
+		} else {
+			logrus.Errorf("Error parsing %s: %q. Running one %s job at a time.", name, value, strings.ToLower(jobType))
+		}
+	}
+
+	retryBackoff := 2
+	if s := os.Getenv("RETRY_BACKOFF_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			retryBackoff = v
+		}
+	}
+	jc["retry_backoff_seconds"] = time.Duration(retryBackoff) * time.Second
+
+	retryMaxBackoff := 60
+	if s := os.Getenv("RETRY_MAX_BACKOFF_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			retryMaxBackoff = v
+		}
+	}
+	jc["retry_max_backoff_seconds"] = time.Duration(retryMaxBackoff) * time.Second
+
+	// Economy execution class config
+	economyQueueSize := 1000
+	if s := os.Getenv("ECONOMY_QUEUE_SIZE"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			economyQueueSize = v
+		}
+	}
+	jc["economy_queue_size"] = economyQueueSize
+
+	economyMaxWait := 3600
+	if s := os.Getenv("ECONOMY_MAX_WAIT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			economyMaxWait = v
+		}
+	}
+	jc["economy_max_wait_seconds"] = time.Duration(economyMaxWait) * time.Second
+
+	// Worker IDs whose jobs may ask for high priority, e.g. PRIORITY_WORKER_IDS=worker1,worker2
+	if s := os.Getenv("PRIORITY_WORKER_IDS"); s != "" {
+		workerIDs := []string{}
+		for _, id := range strings.Split(s, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				workerIDs = append(workerIDs, id)
+			}
+		}
+		jc["priority_worker_ids"] = workerIDs
+	}
+
+	// Mastodon instances, e.g. MASTODON_INSTANCES=https://mastodon.social,https://fosstodon.org
+	mastodonInstances := []string{defaultMastodonInstance}
+	if s := os.Getenv("MASTODON_INSTANCES"); s != "" {
+		mastodonInstances = []string{}
+		for _, instance := range strings.Split(s, ",") {
+			if instance = strings.TrimRight(strings.TrimSpace(instance), "/"); instance != "" {
+				mastodonInstances = append(mastodonInstances, instance)
+			}
+		}
+	}
+	jc["mastodon_instances"] = mastodonInstances
+
+	// Base URL of the GitHub REST API, e.g. for GitHub Enterprise Server
+	if s := os.Getenv("GITHUB_API_URL"); s != "" {
+		jc["github_api_url"] = strings.TrimRight(strings.TrimSpace(s), "/")
+	}
+
+	// Base URL of the Discord API, e.g. for a proxy
+	if s := os.Getenv("DISCORD_API_URL"); s != "" {
+		jc["discord_api_url"] = strings.TrimRight(strings.TrimSpace(s), "/")
+	}
+
+	// Collector the statistics are pushed to, for workers the indexer can't reach to pull them
+	if s := os.Getenv("TELEMETRY_PUSH_URL"); s != "" {
+		jc["telemetry_push_url"] = strings.TrimSpace(s)
+	}
+	jc["telemetry_push_secret"] = strings.TrimSpace(getenv("TELEMETRY_PUSH_SECRET"))
+	telemetryPushInterval := defaultTelemetryPushInterval
+	if s := os.Getenv("TELEMETRY_PUSH_INTERVAL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			telemetryPushInterval = v
+		}
+	}
+	jc["telemetry_push_interval_seconds"] = telemetryPushInterval
+	telemetryPushMaxRetries := defaultTelemetryPushMaxRetries
+	if s := os.Getenv("TELEMETRY_PUSH_MAX_RETRIES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			telemetryPushMaxRetries = v
+		}
+	}
+	jc["telemetry_push_max_retries"] = telemetryPushMaxRetries
+
+	// Feed lists searched by rss jobs: RSS_FEEDS is the default list, RSS_FEEDS_<NAME> are lists selected by name,
+	// e.g. RSS_FEEDS_CRYPTO=https://cointelegraph.com/rss,https://decrypt.co/feed
+	feedLists := map[string][]string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		list := DefaultRSSFeedList
+		if name != "RSS_FEEDS" {
+			suffix, ok := strings.CutPrefix(name, "RSS_FEEDS_")
+			if !ok || suffix == "" {
+				continue
+			}
+			list = strings.ToLower(suffix)
+		}
+		for _, feed := range strings.Split(value, ",") {
+			if feed = strings.TrimSpace(feed); feed != "" {
+				feedLists[list] = append(feedLists[list], feed)
+			}
+		}
+	}
+	jc["rss_feed_lists"] = feedLists
+
+	// Order in which the general Twitter job tries the auth sources of a capability, e.g.
+	// TWITTER_AUTH_PRIORITY_GETFOLLOWERS=credential,apify
+	authPriority := map[string][]string{}
+	for _, env := range os.Environ() {
+		name, value, _ := strings.Cut(env, "=")
+		capability, ok := strings.CutPrefix(name, "TWITTER_AUTH_PRIORITY_")
+		if !ok || capability == "" {
+			continue
+		}
+		var sources []string
+		for _, source := range strings.Split(value, ",") {
+			if source = strings.ToLower(strings.TrimSpace(source)); source != "" {
+				sources = append(sources, source)
+			}
+		}
+		authPriority[strings.ToLower(capability)] = sources
+	}
+	jc["twitter_auth_priority"] = authPriority
+
+	// Public, unauthenticated endpoints of the embedded tweet widgets, used when the accounts and API keys fail
+	jc["twitter_syndication_fallback"] = os.Getenv("TWITTER_SYNDICATION_FALLBACK") == "true"
+
+	// Search page crawled by the web leg of research jobs, e.g. RESEARCH_WEB_SEARCH_URL=https://www.bing.com/search?q={query}
+	researchWebSearchURL := defaultResearchWebSearchURL
+	if s := os.Getenv("RESEARCH_WEB_SEARCH_URL"); s != "" {
+		researchWebSearchURL = s
+	}
+	jc["research_web_search_url"] = researchWebSearchURL
+
+	// Recurring jobs config
+	maxRecurringJobs := 100
+	if s := os.Getenv("MAX_RECURRING_JOBS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			maxRecurringJobs = v
+		}
+	}
+	jc["max_recurring_jobs"] = maxRecurringJobs
+
+	// Bandwidth caps in bytes. 0 disables the cap.
+	jobMaxBandwidth := 0
+	if s := os.Getenv("BANDWIDTH_JOB_MAX_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			jobMaxBandwidth = v
+		}
+	}
+	jc["bandwidth_job_max_bytes"] = jobMaxBandwidth
+
+	clientMaxBandwidth := 0
+	if s := os.Getenv("BANDWIDTH_CLIENT_MAX_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			clientMaxBandwidth = v
+		}
+	}
+	jc["bandwidth_client_max_bytes"] = clientMaxBandwidth
+
+	// Limits of the results of jobs which return a list of items, beyond which the result is truncated. 0 is unlimited.
+	resultMaxBytes := 0
+	if s := os.Getenv("RESULT_MAX_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultMaxBytes = v
+		}
+	}
+	jc["result_max_bytes"] = resultMaxBytes
+
+	resultMaxItems := 0
+	if s := os.Getenv("RESULT_MAX_ITEMS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultMaxItems = v
+		}
+	}
+	jc["result_max_items"] = resultMaxItems
+
+	// Memory ceiling in bytes, which the estimated memory of the running jobs must stay below. 0 disables the guard.
+	memoryCeiling := 0
+	if s := os.Getenv("MEMORY_CEILING_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			memoryCeiling = v
+		}
+	}
+	jc["memory_ceiling_bytes"] = memoryCeiling
+
+	// Backpressure thresholds of the interactive queue, above which jobs are rejected with a Retry-After. 0 disables them.
+	backpressureDepth := 0
+	if s := os.Getenv("QUEUE_BACKPRESSURE_DEPTH"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			backpressureDepth = v
+		}
+	}
+	jc["queue_backpressure_depth"] = backpressureDepth
+
+	backpressureWait := 0
+	if s := os.Getenv("QUEUE_BACKPRESSURE_WAIT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			backpressureWait = v
+		}
+	}
+	jc["queue_backpressure_wait_seconds"] = time.Duration(backpressureWait) * time.Second
+
+	clientBandwidthWindow := 3600
+	if s := os.Getenv("BANDWIDTH_CLIENT_WINDOW_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			clientBandwidthWindow = v
+		}
+	}
+	jc["bandwidth_client_window_seconds"] = time.Duration(clientBandwidthWindow) * time.Second
+
+	resultMaxWait := 30
+	if s := os.Getenv("RESULT_MAX_WAIT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			resultMaxWait = v
+		}
+	}
+	jc["result_max_wait_seconds"] = time.Duration(resultMaxWait) * time.Second
+
+	dedupTTL := 0
+	if s := os.Getenv("DEDUP_TTL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			dedupTTL = v
+		}
+	}
+	jc["dedup_ttl_seconds"] = time.Duration(dedupTTL) * time.Second
+
+	nextCursorTTL := 86400
+	if s := os.Getenv("NEXT_CURSOR_TTL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			nextCursorTTL = v
+		}
+	}
+	jc["next_cursor_ttl_seconds"] = time.Duration(nextCursorTTL) * time.Second
+
+	// Sealed, hash-chained log of the jobs in DATA_DIR
+	jc["audit_log_enabled"] = os.Getenv("AUDIT_LOG_ENABLED") == "true"
+
+	// Content policy: jobs can only opt in to items flagged as NSFW if the operator allows it
+	jc["content_policy_allow_nsfw"] = os.Getenv("CONTENT_POLICY_ALLOW_NSFW") == "true"
+
+	webCrawlDelay := 1
+	if s := os.Getenv("WEB_CRAWL_DELAY_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			webCrawlDelay = v
+		}
+	}
+	jc["web_crawl_delay_seconds"] = time.Duration(webCrawlDelay) * time.Second
+
+	// Politeness of web jobs: honoring robots.txt, the request rate per domain and how many domains are scraped at once
+	jc["web_respect_robots_txt"] = os.Getenv("WEB_RESPECT_ROBOTS_TXT") == "true"
+	webDomainMaxRequestsPerMinute := 0
+	if s := os.Getenv("WEB_DOMAIN_MAX_REQUESTS_PER_MINUTE"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			webDomainMaxRequestsPerMinute = v
+		}
+	}
+	jc["web_domain_max_requests_per_minute"] = webDomainMaxRequestsPerMinute
+	webMaxConcurrentDomains := 0
+	if s := os.Getenv("WEB_MAX_CONCURRENT_DOMAINS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			webMaxConcurrentDomains = v
+		}
+	}
+	jc["web_max_concurrent_domains"] = webMaxConcurrentDomains
+
+	healthProbeInterval := 300
+	if s := os.Getenv("HEALTH_PROBE_INTERVAL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			healthProbeInterval = v
+		}
+	}
+	jc["health_probe_interval_seconds"] = time.Duration(healthProbeInterval) * time.Second
+
+	// Connection pool of the HTTP clients used by the scrapers, see HTTPClientConfig
+	httpMaxConnsPerHost := 100
+	if s := os.Getenv("HTTP_MAX_CONNS_PER_HOST"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			httpMaxConnsPerHost = v
+		}
+	}
+	jc["http_max_conns_per_host"] = httpMaxConnsPerHost
+
+	httpMaxIdleConns := 100
+	if s := os.Getenv("HTTP_MAX_IDLE_CONNS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			httpMaxIdleConns = v
+		}
+	}
+	jc["http_max_idle_conns"] = httpMaxIdleConns
+
+	httpMaxIdleConnsPerHost := 10
+	if s := os.Getenv("HTTP_MAX_IDLE_CONNS_PER_HOST"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			httpMaxIdleConnsPerHost = v
+		}
+	}
+	jc["http_max_idle_conns_per_host"] = httpMaxIdleConnsPerHost
+
+	httpTLSSessionCacheSize := 64
+	if s := os.Getenv("HTTP_TLS_SESSION_CACHE_SIZE"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			httpTLSSessionCacheSize = v
+		}
+	}
+	jc["http_tls_session_cache_size"] = httpTLSSessionCacheSize
+
+	httpIdleConnTimeout := 120
+	if s := os.Getenv("HTTP_IDLE_CONN_TIMEOUT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			httpIdleConnTimeout = v
+		}
+	}
+	jc["http_idle_conn_timeout_seconds"] = time.Duration(httpIdleConnTimeout) * time.Second
+
+	httpDialTimeout := 30
+	if s := os.Getenv("HTTP_DIAL_TIMEOUT_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			httpDialTimeout = v
+		}
+	}
+	jc["http_dial_timeout_seconds"] = time.Duration(httpDialTimeout) * time.Second
+
+	jc["http2_enabled"] = os.Getenv("HTTP2_ENABLED") != "false"
+
+	// Simulation mode, see jobs.SimulationProfile
+	if s := os.Getenv("SIMULATION_PROFILE"); s != "" {
+		jc["simulation_profile"] = s
+	}
+
+	// Stats breakdowns, e.g. STATS_DIMENSIONS=capability,provider
+	if s := os.Getenv("STATS_DIMENSIONS"); s != "" {
+		dimensions := strings.Split(s, ",")
+		for i, d := range dimensions {
+			dimensions[i] = strings.TrimSpace(d)
+		}
+		jc["stats_dimensions"] = dimensions
+	}
+
+	statsMaxDimensionValues := 20
+	if s := os.Getenv("STATS_MAX_DIMENSION_VALUES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			statsMaxDimensionValues = v
+		}
+	}
+	jc["stats_max_dimension_values"] = statsMaxDimensionValues
+
+	// How often the cumulative statistics are saved to DATA_DIR, 0 disables saving them
+	statsPersistInterval := 60
+	if s := os.Getenv("STATS_PERSIST_INTERVAL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			statsPersistInterval = v
+		}
+	}
+	jc["stats_persist_interval_seconds"] = time.Duration(statsPersistInterval) * time.Second
+
+	// How often the credentials files in DATA_DIR are checked for changes, 0 disables reloading them
+	credentialsReloadInterval := 30
+	if s := os.Getenv("CREDENTIALS_RELOAD_INTERVAL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			credentialsReloadInterval = v
+		}
+	}
+	jc["credentials_reload_interval_seconds"] = time.Duration(credentialsReloadInterval) * time.Second
+
+	// Whether the worker benchmarks itself when it starts, it can still be benchmarked on demand with /benchmark
+	jc["benchmark_on_startup"] = os.Getenv("BENCHMARK_ON_STARTUP") != "false"
+
+	// Whether the GraphQL API is served alongside the REST endpoints
+	jc["graphql_enabled"] = os.Getenv("GRAPHQL_ENABLED") == "true"
+
 	// API Key for authentication
-	apiKey := os.Getenv("API_KEY")
+	apiKey := getenv("API_KEY")
 	if apiKey != "" {
 		jc["api_key"] = apiKey
 	}
 
+	// API keys of the miners, with their quotas, e.g. MINER_API_KEYS=miner1:key1:100:1073741824,miner2:key2
+	if s := getenv("MINER_API_KEYS"); s != "" {
+		var minerKeys []string
+		for _, entry := range strings.Split(s, ",") {
+			if entry = strings.TrimSpace(entry); entry != "" {
+				minerKeys = append(minerKeys, entry)
+			}
+		}
+		jc["miner_api_keys"] = minerKeys
+	}
+	minerJobsPerHour := 0
+	if s := os.Getenv("MINER_JOBS_PER_HOUR"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			minerJobsPerHour = v
+		}
+	}
+	jc["miner_jobs_per_hour"] = minerJobsPerHour
+	minerResultBytesPerDay := 0
+	if s := os.Getenv("MINER_RESULT_BYTES_PER_DAY"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			minerResultBytesPerDay = v
+		}
+	}
+	jc["miner_result_bytes_per_day"] = minerResultBytesPerDay
+	minerMaxRecurringJobs := defaultMinerMaxRecurringJobs
+	if s := os.Getenv("MINER_MAX_RECURRING_JOBS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			minerMaxRecurringJobs = v
+		}
+	}
+	jc["miner_max_recurring_jobs"] = minerMaxRecurringJobs
+
 	webScraperBlacklist := os.Getenv("WEBSCRAPER_BLACKLIST")
 	if webScraperBlacklist != "" {
 		blacklistURLs := strings.Split(webScraperBlacklist, ",")
@@ -125,47 +737,103 @@ func ReadConfig() JobConfiguration {
 		jc["webscraper_blacklist"] = blacklistURLs
 	}
 
-	twitterAccount := os.Getenv("TWITTER_ACCOUNTS")
-	if twitterAccount != "" {
-		twitterAccounts := strings.Split(twitterAccount, ",")
-		for i, u := range twitterAccounts {
-			twitterAccounts[i] = strings.TrimSpace(u)
+	// Overrides of the Apify actors, and the builds they are pinned to
+	if apifyActors := os.Getenv("APIFY_ACTORS"); apifyActors != "" {
+		actors := strings.Split(apifyActors, ",")
+		for i, a := range actors {
+			actors[i] = strings.TrimSpace(a)
 		}
-		jc["twitter_accounts"] = twitterAccounts
-	} else {
-		jc["twitter_accounts"] = []string{}
+		jc["apify_actors"] = actors
 	}
 
-	twitterApiKeys := os.Getenv("TWITTER_API_KEYS")
-	if twitterApiKeys != "" {
-		logrus.Info("Twitter API keys found")
-		apiKeys := strings.Split(twitterApiKeys, ",")
-		for i, u := range apiKeys {
-			apiKeys[i] = strings.TrimSpace(u)
+	// Trusted workers that jobs are delegated to when this worker is over capacity for their job type
+	if peerWorkers := os.Getenv("PEER_WORKERS"); peerWorkers != "" {
+		peers := strings.Split(peerWorkers, ",")
+		for i, p := range peers {
+			peers[i] = strings.TrimRight(strings.TrimSpace(p), "/")
 		}
-		jc["twitter_api_keys"] = apiKeys
-	} else {
-		jc["twitter_api_keys"] = []string{}
+		jc["peer_workers"] = peers
 	}
 
-	jc["twitter_skip_login_verification"] = os.Getenv("TWITTER_SKIP_LOGIN_VERIFICATION") == "true"
+	// API key shared by the peer workers, which is only accepted for attesting workers and delegating jobs to them, so
+	// the peers never hold API_KEY
+	if peerKey := getenv("PEER_API_KEY"); peerKey != "" {
+		jc["peer_api_key"] = peerKey
+	}
 
-	// Apify API key loading
-	apifyApiKey := os.Getenv("APIFY_API_KEY")
-	if apifyApiKey != "" {
-		logrus.Info("Apify API key found")
-		jc["apify_api_key"] = apifyApiKey
-	} else {
-		jc["apify_api_key"] = ""
+	// How long the attestation of a peer is trusted before it is verified again
+	peerAttestationTTL := 600
+	if s := os.Getenv("PEER_ATTESTATION_TTL_SECONDS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			peerAttestationTTL = v
+		}
+	}
+	jc["peer_attestation_ttl_seconds"] = time.Duration(peerAttestationTTL) * time.Second
+
+	// OpenTelemetry traces are exported to this OTLP/HTTP endpoint. The exporter reads the other OTEL_* variables itself.
+	if endpoint := os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT"); endpoint != "" {
+		jc["otel_exporter_otlp_endpoint"] = endpoint
 	}
 
-	geminiApiKey := os.Getenv("GEMINI_API_KEY")
-	if geminiApiKey != "" {
+	// Twitter accounts and API keys, and the Apify and Gemini API keys
+	maps.Copy(jc, CredentialsFromEnv(os.Getenv))
+	if len(jc.GetStringSlice("twitter_api_keys", nil)) > 0 {
+		logrus.Info("Twitter API keys found")
+	}
+	if jc.GetString("apify_api_key", "") != "" {
+		logrus.Info("Apify API key found")
+	}
+	if jc.GetString("gemini_api_key", "") != "" {
 		logrus.Info("Gemini API key found")
-		jc["gemini_api_key"] = geminiApiKey
-	} else {
-		jc["gemini_api_key"] = ""
 	}
+	if jc.GetString("github_token", "") != "" {
+		logrus.Info("GitHub token found")
+	}
+	if jc.GetString("discord_bot_token", "") != "" {
+		logrus.Info("Discord bot token found")
+	}
+
+	jc["twitter_skip_login_verification"] = os.Getenv("TWITTER_SKIP_LOGIN_VERIFICATION") == "true"
+
+	twitterMaxIdsPerJob := 100
+	if s := os.Getenv("TWITTER_MAX_IDS_PER_JOB"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			twitterMaxIdsPerJob = v
+		}
+	}
+	jc["twitter_max_ids_per_job"] = twitterMaxIdsPerJob
+
+	twitterMaxFollowerSnapshots := 10
+	if s := os.Getenv("TWITTER_MAX_FOLLOWER_SNAPSHOTS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 1 {
+			twitterMaxFollowerSnapshots = v
+		}
+	}
+	jc["twitter_max_follower_snapshots"] = twitterMaxFollowerSnapshots
+
+	twitterMaxTrendSnapshots := 288
+	if s := os.Getenv("TWITTER_MAX_TREND_SNAPSHOTS"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v > 0 {
+			twitterMaxTrendSnapshots = v
+		}
+	}
+	jc["twitter_max_trend_snapshots"] = twitterMaxTrendSnapshots
+
+	twitterThreadMaxDepth := 3
+	if s := os.Getenv("TWITTER_THREAD_MAX_DEPTH"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			twitterThreadMaxDepth = v
+		}
+	}
+	jc["twitter_thread_max_depth"] = twitterThreadMaxDepth
+
+	twitterMaxMediaBytes := defaultTwitterMaxMediaBytes
+	if s := os.Getenv("TWITTER_MAX_MEDIA_BYTES"); s != "" {
+		if v, err := strconv.Atoi(s); err == nil && v >= 0 {
+			twitterMaxMediaBytes = v
+		}
+	}
+	jc["twitter_max_media_bytes"] = twitterMaxMediaBytes
 
 	tikTokLang := os.Getenv("TIKTOK_DEFAULT_LANGUAGE")
 	if tikTokLang == "" {
@@ -178,13 +846,53 @@ func ReadConfig() JobConfiguration {
 
 	if userAgent := os.Getenv("TIKTOK_API_USER_AGENT"); userAgent != "" {
 		jc["tiktok_api_user_agent"] = userAgent
-	} // Default for userAgent is set in NewTikTokTranscriber
+	} // Without it, the user agent of the fingerprint profile of the transcription API is used
+
+	// JSON file with the browser profiles presented by the scrapers, instead of the default ones
+	jc["fingerprint_profiles_file"] = os.Getenv("FINGERPRINT_PROFILES_FILE")
 
 	jc["profiling_enabled"] = os.Getenv("ENABLE_PPROF") == "true"
 
 	return jc
 }
 
+// CredentialsFromEnv reads the credentials which can be reloaded at runtime, i.e. the Twitter accounts and API keys
+// and the Apify and Gemini API keys, from the environment variables returned by getenv. Variables which reference a
+// secret are resolved with Secrets.
+func CredentialsFromEnv(getenv func(string) string) JobConfiguration {
+	jc := JobConfiguration{}
+	getenv = WithSecrets(getenv)
+
+	twitterAccount := getenv("TWITTER_ACCOUNTS")
+	if twitterAccount != "" {
+		twitterAccounts := strings.Split(twitterAccount, ",")
+		for i, u := range twitterAccounts {
+			twitterAccounts[i] = strings.TrimSpace(u)
+		}
+		jc["twitter_accounts"] = twitterAccounts
+	} else {
+		jc["twitter_accounts"] = []string{}
+	}
+
+	twitterApiKeys := getenv("TWITTER_API_KEYS")
+	if twitterApiKeys != "" {
+		apiKeys := strings.Split(twitterApiKeys, ",")
+		for i, u := range apiKeys {
+			apiKeys[i] = strings.TrimSpace(u)
+		}
+		jc["twitter_api_keys"] = apiKeys
+	} else {
+		jc["twitter_api_keys"] = []string{}
+	}
+
+	jc["apify_api_key"] = getenv("APIFY_API_KEY")
+	jc["gemini_api_key"] = getenv("GEMINI_API_KEY")
+	jc["github_token"] = strings.TrimSpace(getenv("GITHUB_TOKEN"))
+	jc["discord_bot_token"] = DiscordBotToken(getenv("DISCORD_BOT_TOKEN"))
+
+	return jc
+}
+
 // Unmarshal unmarshals the job configuration into the supplied interface.
 func (jc JobConfiguration) Unmarshal(v any) error {
 	data, err := json.Marshal(jc)
@@ -198,6 +906,53 @@ func (jc JobConfiguration) Unmarshal(v any) error {
 	return nil
 }
 
+// secretKeys are the keys of the JobConfiguration holding credentials, which are never exposed by Redacted
+var secretKeys = map[string]struct{}{
+	"api_key":               {},
+	"peer_api_key":          {},
+	"miner_api_keys":        {},
+	"apify_api_key":         {},
+	"gemini_api_key":        {},
+	"github_token":          {},
+	"discord_bot_token":     {},
+	"telemetry_push_secret": {},
+	"twitter_accounts":      {},
+	"twitter_api_keys":      {},
+}
+
+// Redacted returns a copy of the job configuration which is safe to expose. Lists of credentials are replaced by
+// their length under <key>_count, single credentials by whether they are set under <key>_configured, durations are
+// converted to seconds and passwords are removed from URLs.
+func (jc JobConfiguration) Redacted() map[string]any {
+	redacted := make(map[string]any, len(jc))
+	for key, value := range jc {
+		if _, ok := secretKeys[key]; ok {
+			switch v := value.(type) {
+			case []string:
+				redacted[key+"_count"] = len(v)
+			case string:
+				redacted[key+"_configured"] = v != ""
+			default:
+				redacted[key+"_configured"] = v != nil
+			}
+			continue
+		}
+
+		switch v := value.(type) {
+		case time.Duration:
+			redacted[key] = v.Seconds()
+		case string:
+			if u, err := url.Parse(v); err == nil && u.User != nil {
+				v = u.Redacted()
+			}
+			redacted[key] = v
+		default:
+			redacted[key] = v
+		}
+	}
+	return redacted
+}
+
 func (jc JobConfiguration) DataDir() string {
 	return jc.GetString("data_dir", defaultDataDir)
 }
@@ -276,17 +1031,414 @@ type TwitterScraperConfig struct {
 	ApifyApiKey           string
 	DataDir               string
 	SkipLoginVerification bool
+	MaxIdsPerJob          int
+	// MaxFollowerSnapshots is the number of follower snapshots kept per account, see jobs.CapGetFollowerDelta
+	MaxFollowerSnapshots int
+	// MaxTrendSnapshots is the number of trend snapshots kept, see jobs.CapGetTrendHistory
+	MaxTrendSnapshots int
+	// MaxMediaBytes is the maximum total size of the media downloaded by a downloadmedia job, 0 for no limit
+	MaxMediaBytes int64
+	// MaxThreadDepth is the largest number of levels of replies a getthread job may walk, see jobs.CapGetThread
+	MaxThreadDepth int
+	// AuthPriority maps capabilities to the auth sources the general Twitter job tries first for them, in order
+	AuthPriority map[string][]string
+	// SyndicationFallback lets the general Twitter job fetch tweets and profiles without credentials once all its auth
+	// sources failed
+	SyndicationFallback bool
 }
 
+// TwitterAuthPriorityCapabilities are the capabilities which the general Twitter job can provide through more than one
+// auth source, so the order in which it tries them can be configured with TWITTER_AUTH_PRIORITY_<CAPABILITY>
+var TwitterAuthPriorityCapabilities = []string{"getbyid", "getbyids", "getfollowers", "getfollowing", "getpoll", "getprofilebyid", "searchbyfullarchive", "searchbyquery"}
+
+// TwitterAuthSources are the auth sources which can be listed in TWITTER_AUTH_PRIORITY_<CAPABILITY>
+var TwitterAuthSources = []string{"credential", "api", "apify"}
+
 // GetTwitterConfig constructs a TwitterScraperConfig directly from the JobConfiguration
 // This eliminates the need for JSON marshaling/unmarshaling
 func (jc JobConfiguration) GetTwitterConfig() TwitterScraperConfig {
+	maxIdsPerJob, err := jc.GetInt("twitter_max_ids_per_job", 100)
+	if err != nil || maxIdsPerJob <= 0 {
+		maxIdsPerJob = 100
+	}
+
+	maxFollowerSnapshots, err := jc.GetInt("twitter_max_follower_snapshots", 10)
+	if err != nil || maxFollowerSnapshots <= 1 {
+		maxFollowerSnapshots = 10
+	}
+
+	maxTrendSnapshots, err := jc.GetInt("twitter_max_trend_snapshots", 288)
+	if err != nil || maxTrendSnapshots <= 0 {
+		maxTrendSnapshots = 288
+	}
+
+	maxMediaBytes, err := jc.GetInt("twitter_max_media_bytes", defaultTwitterMaxMediaBytes)
+	if err != nil || maxMediaBytes < 0 {
+		maxMediaBytes = defaultTwitterMaxMediaBytes
+	}
+
+	maxThreadDepth, err := jc.GetInt("twitter_thread_max_depth", 3)
+	if err != nil || maxThreadDepth < 0 {
+		maxThreadDepth = 3
+	}
+
+	authPriority, _ := jc["twitter_auth_priority"].(map[string][]string)
+
 	return TwitterScraperConfig{
 		Accounts:              jc.GetStringSlice("twitter_accounts", []string{}),
 		ApiKeys:               jc.GetStringSlice("twitter_api_keys", []string{}),
 		ApifyApiKey:           jc.GetString("apify_api_key", ""),
 		DataDir:               jc.GetString("data_dir", ""),
 		SkipLoginVerification: jc.GetBool("skip_login_verification", false),
+		MaxIdsPerJob:          maxIdsPerJob,
+		MaxFollowerSnapshots:  maxFollowerSnapshots,
+		MaxTrendSnapshots:     maxTrendSnapshots,
+		MaxMediaBytes:         int64(maxMediaBytes),
+		MaxThreadDepth:        maxThreadDepth,
+		AuthPriority:          authPriority,
+		SyndicationFallback:   jc.GetBool("twitter_syndication_fallback", false),
+	}
+}
+
+// RetryConfig represents the retry policy for a single job type
+type RetryConfig struct {
+	MaxRetries int
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+}
+
+// retryConfigKey returns the JobConfiguration key holding the max retries for a job type, e.g. twitter_credential_max_retries
+func retryConfigKey(jobType string) string {
+	return strings.ReplaceAll(jobType, "-", "_") + "_max_retries"
+}
+
+// GetRetryConfig constructs the RetryConfig for the given job type. Retries are disabled unless configured.
+func (jc JobConfiguration) GetRetryConfig(jobType string) RetryConfig {
+	maxRetries, err := jc.GetInt(retryConfigKey(jobType), 0)
+	if err != nil || maxRetries < 0 {
+		maxRetries = 0
+	}
+
+	return RetryConfig{
+		MaxRetries: maxRetries,
+		Backoff:    jc.GetDuration("retry_backoff_seconds", 2),
+		MaxBackoff: jc.GetDuration("retry_max_backoff_seconds", 60),
+	}
+}
+
+// DefaultCapabilityTimeouts are the default timeouts of the capabilities which need much more or much less time than
+// JOB_TIMEOUT_SECONDS, e.g. scraping followers with Apify takes many minutes while getting a tweet by ID takes seconds
+var DefaultCapabilityTimeouts = map[teetypes.Capability]time.Duration{
+	teetypes.CapGetFollowers:   15 * time.Minute,
+	teetypes.CapGetFollowing:   15 * time.Minute,
+	teetypes.CapGetById:        time.Minute,
+	teetypes.CapGetProfileById: time.Minute,
+	teetypes.CapGetProfile:     time.Minute,
+	teetypes.CapGetSpace:       time.Minute,
+	teetypes.CapGetTrends:      time.Minute,
+	// jobs.CapSampleFollowers, which reads up to 100 pages of followers
+	"samplefollowers": 15 * time.Minute,
+	// jobs.CapGetTrendHistory, which takes snapshots of the trends at intervals of minutes
+	"gettrendhistory": 30 * time.Minute,
+}
+
+// timeoutConfigKey returns the JobConfiguration key holding the timeout of a job type or of one of its capabilities,
+// e.g. twitter_apify_timeout or twitter_apify_getfollowers_timeout
+func timeoutConfigKey(name string) string {
+	return strings.ReplaceAll(name, "-", "_") + "_timeout"
+}
+
+// GetJobTimeout returns the timeout of jobs of the given type and capability. It is the first of the timeout
+// configured for the capability of the job type, the timeout configured for the job type, the default timeout of the
+// capability (see DefaultCapabilityTimeouts) and JOB_TIMEOUT_SECONDS.
+func (jc JobConfiguration) GetJobTimeout(jobType string, capability string) time.Duration {
+	capability = strings.ToLower(capability)
+	if capability != "" {
+		if timeout := jc.GetDuration(timeoutConfigKey(jobType+"_"+capability), 0); timeout > 0 {
+			return timeout
+		}
+	}
+	if timeout := jc.GetDuration(timeoutConfigKey(jobType), 0); timeout > 0 {
+		return timeout
+	}
+	if timeout, ok := DefaultCapabilityTimeouts[teetypes.Capability(capability)]; ok {
+		return timeout
+	}
+	return jc.GetDuration("job_timeout_seconds", 300)
+}
+
+// CircuitBreakerConfig is the circuit breaker of a capability of a job type. After Threshold consecutive failures of
+// its jobs, the capability is withdrawn for Cooldown. A Threshold of 0 disables the circuit breaker.
+type CircuitBreakerConfig struct {
+	Threshold int
+	Cooldown  time.Duration
+}
+
+// circuitBreakerConfigKey returns the JobConfiguration key holding a setting of the circuit breaker of a job type or
+// of one of its capabilities, e.g. twitter_apify_getfollowers_circuit_breaker_threshold
+func circuitBreakerConfigKey(name, setting string) string {
+	return strings.ReplaceAll(name, "-", "_") + "_circuit_breaker_" + setting
+}
+
+// GetCircuitBreakerConfig returns the circuit breaker of jobs of the given type and capability. Each of its settings
+// is the first of the one configured for the capability of the job type, the one configured for the job type and
+// the default one, i.e. CIRCUIT_BREAKER_THRESHOLD and CIRCUIT_BREAKER_COOLDOWN_SECONDS.
+func (jc JobConfiguration) GetCircuitBreakerConfig(jobType string, capability string) CircuitBreakerConfig {
+	names := []string{jobType}
+	if capability = strings.ToLower(capability); capability != "" {
+		names = []string{jobType + "_" + capability, jobType}
+	}
+
+	threshold, err := jc.GetInt("circuit_breaker_threshold", 5)
+	if err != nil || threshold < 0 {
+		threshold = 5
+	}
+	cooldown := jc.GetDuration("circuit_breaker_cooldown_seconds", 60)
+	for i := len(names) - 1; i >= 0; i-- {
+		if v, err := jc.GetInt(circuitBreakerConfigKey(names[i], "threshold"), -1); err == nil && v >= 0 {
+			threshold = v
+		}
+		if v := jc.GetDuration(circuitBreakerConfigKey(names[i], "cooldown"), 0); v > 0 {
+			cooldown = v
+		}
+	}
+
+	return CircuitBreakerConfig{Threshold: threshold, Cooldown: cooldown}
+}
+
+// maxResultsLimitConfigKey returns the JobConfiguration key holding the max_results limit for a job type, e.g. twitter_credential_max_results_limit
+func maxResultsLimitConfigKey(jobType string) string {
+	return strings.ReplaceAll(jobType, "-", "_") + "_max_results_limit"
+}
+
+// GetMaxResultsLimit returns the largest max_results argument accepted for jobs of the given type, or 0 if there is no limit
+func (jc JobConfiguration) GetMaxResultsLimit(jobType string) int {
+	limit, err := jc.GetInt(maxResultsLimitConfigKey(jobType), 0)
+	if err != nil || limit < 0 {
+		return 0
+	}
+	return limit
+}
+
+// maxConcurrentConfigKey returns the JobConfiguration key holding the concurrency limit for a job type, e.g. twitter_credential_max_concurrent
+func maxConcurrentConfigKey(jobType string) string {
+	return strings.ReplaceAll(jobType, "-", "_") + "_max_concurrent"
+}
+
+// GetMaxConcurrent returns how many jobs of the given type may run at the same time. By default jobs of the same
+// type run one at a time.
+func (jc JobConfiguration) GetMaxConcurrent(jobType string) int {
+	limit, err := jc.GetInt(maxConcurrentConfigKey(jobType), 1)
+	if err != nil || limit <= 0 {
+		return 1
+	}
+	return limit
+}
+
+// BandwidthConfig represents the bandwidth caps of jobs. A cap of 0 disables it.
+type BandwidthConfig struct {
+	// JobMaxBytes is the maximum number of bytes a single job can download and upload
+	JobMaxBytes int64
+	// ClientMaxBytes is the maximum number of bytes the jobs of a single client (worker ID) can transfer within ClientWindow
+	ClientMaxBytes int64
+	ClientWindow   time.Duration
+}
+
+// GetBandwidthConfig constructs a BandwidthConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetBandwidthConfig() BandwidthConfig {
+	jobMax, err := jc.GetInt("bandwidth_job_max_bytes", 0)
+	if err != nil || jobMax < 0 {
+		jobMax = 0
+	}
+	clientMax, err := jc.GetInt("bandwidth_client_max_bytes", 0)
+	if err != nil || clientMax < 0 {
+		clientMax = 0
+	}
+
+	return BandwidthConfig{
+		JobMaxBytes:    int64(jobMax),
+		ClientMaxBytes: int64(clientMax),
+		ClientWindow:   jc.GetDuration("bandwidth_client_window_seconds", 3600),
+	}
+}
+
+// ResultLimitConfig is the size of the results of jobs which return a list of items, beyond which they are truncated
+type ResultLimitConfig struct {
+	// MaxBytes is the maximum size of the JSON encoded result, or 0 if it is unlimited
+	MaxBytes int
+	// MaxItems is the maximum number of items of the result, or 0 if it is unlimited
+	MaxItems int
+}
+
+// GetResultLimitConfig constructs a ResultLimitConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetResultLimitConfig() ResultLimitConfig {
+	maxBytes, err := jc.GetInt("result_max_bytes", 0)
+	if err != nil || maxBytes < 0 {
+		maxBytes = 0
+	}
+	maxItems, err := jc.GetInt("result_max_items", 0)
+	if err != nil || maxItems < 0 {
+		maxItems = 0
+	}
+	return ResultLimitConfig{MaxBytes: maxBytes, MaxItems: maxItems}
+}
+
+// MinerAPIKey is the API key of a miner, with the quotas of the jobs submitted by the miner. A quota of 0 is unlimited.
+type MinerAPIKey struct {
+	// Miner is the worker ID of the miner, which the jobs submitted with the key must have
+	Miner             string
+	Key               string
+	JobsPerHour       int
+	ResultBytesPerDay int64
+	// MaxRecurringJobs is the number of recurring jobs the miner may have scheduled at the same time, 0 for no limit
+	// other than MAX_RECURRING_JOBS. It is the same for every miner, see MINER_MAX_RECURRING_JOBS.
+	MaxRecurringJobs int
+}
+
+// defaultMinerMaxRecurringJobs is the number of recurring jobs a miner with an API key may have scheduled by default
+const defaultMinerMaxRecurringJobs = 10
+
+// ParseMinerAPIKey parses an entry of MINER_API_KEYS in miner:key[:jobs_per_hour[:result_bytes_per_day]] format.
+// Quotas which are left out or empty are taken from the defaults. Errors never include the key.
+func ParseMinerAPIKey(entry string, defaults MinerAPIKey) (MinerAPIKey, error) {
+	parts := strings.Split(strings.TrimSpace(entry), ":")
+	if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+		return MinerAPIKey{}, errors.New("must be in miner:key[:jobs_per_hour[:result_bytes_per_day]] format")
+	}
+
+	k := MinerAPIKey{Miner: parts[0], Key: parts[1], JobsPerHour: defaults.JobsPerHour, ResultBytesPerDay: defaults.ResultBytesPerDay, MaxRecurringJobs: defaults.MaxRecurringJobs}
+	if len(parts) > 2 && parts[2] != "" {
+		v, err := strconv.Atoi(parts[2])
+		if err != nil || v < 0 {
+			return MinerAPIKey{}, fmt.Errorf("jobs per hour of miner %s must be a non-negative integer", k.Miner)
+		}
+		k.JobsPerHour = v
+	}
+	if len(parts) > 3 && parts[3] != "" {
+		v, err := strconv.ParseInt(parts[3], 10, 64)
+		if err != nil || v < 0 {
+			return MinerAPIKey{}, fmt.Errorf("result bytes per day of miner %s must be a non-negative integer", k.Miner)
+		}
+		k.ResultBytesPerDay = v
+	}
+	return k, nil
+}
+
+// GetMinerAPIKeys returns the API keys of the miners. Invalid entries are skipped.
+func (jc JobConfiguration) GetMinerAPIKeys() []MinerAPIKey {
+	jobsPerHour, err := jc.GetInt("miner_jobs_per_hour", 0)
+	if err != nil || jobsPerHour < 0 {
+		jobsPerHour = 0
+	}
+	resultBytesPerDay, err := jc.GetInt("miner_result_bytes_per_day", 0)
+	if err != nil || resultBytesPerDay < 0 {
+		resultBytesPerDay = 0
+	}
+	maxRecurringJobs, err := jc.GetInt("miner_max_recurring_jobs", defaultMinerMaxRecurringJobs)
+	if err != nil || maxRecurringJobs < 0 {
+		maxRecurringJobs = defaultMinerMaxRecurringJobs
+	}
+	defaults := MinerAPIKey{JobsPerHour: jobsPerHour, ResultBytesPerDay: int64(resultBytesPerDay), MaxRecurringJobs: maxRecurringJobs}
+
+	var keys []MinerAPIKey
+	for i, entry := range jc.GetStringSlice("miner_api_keys", nil) {
+		k, err := ParseMinerAPIKey(entry, defaults)
+		if err != nil {
+			logrus.Errorf("Ignoring entry %d of MINER_API_KEYS: %v", i+1, err)
+			continue
+		}
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// HTTPClientConfig represents the connection pool settings shared by the HTTP clients of the scrapers
+type HTTPClientConfig struct {
+	// MaxConnsPerHost is the maximum number of connections per host, 0 for no limit
+	MaxConnsPerHost uint
+	// MaxIdleConns is the maximum number of idle connections kept in the pool, 0 for no limit
+	MaxIdleConns uint
+	// MaxIdleConnsPerHost is the maximum number of idle connections per host kept in the pool
+	MaxIdleConnsPerHost uint
+	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	// TLSSessionCacheSize is the number of TLS sessions kept for resumption, 0 to disable resumption
+	TLSSessionCacheSize uint
+	HTTP2               bool
+}
+
+// GetHTTPClientConfig constructs an HTTPClientConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetHTTPClientConfig() HTTPClientConfig {
+	count := func(key string, def int) uint {
+		v, err := jc.GetInt(key, def)
+		if err != nil || v < 0 {
+			v = def
+		}
+		return uint(v)
+	}
+
+	return HTTPClientConfig{
+		MaxConnsPerHost:     count("http_max_conns_per_host", 100),
+		MaxIdleConns:        count("http_max_idle_conns", 100),
+		MaxIdleConnsPerHost: count("http_max_idle_conns_per_host", 10),
+		IdleConnTimeout:     jc.GetDuration("http_idle_conn_timeout_seconds", 120),
+		DialTimeout:         jc.GetDuration("http_dial_timeout_seconds", 30),
+		TLSSessionCacheSize: count("http_tls_session_cache_size", 64),
+		HTTP2:               jc.GetBool("http2_enabled", true),
+	}
+}
+
+// PeerConfig represents the configuration of the delegation of jobs to peer workers
+type PeerConfig struct {
+	// Workers are the base URLs of the trusted peers, which share PEER_API_KEY and the sealing key of this worker
+	Workers []string
+	// AttestationTTL is how long the attestation of a peer is trusted before it is verified again
+	AttestationTTL time.Duration
+	// APIKey authenticates this worker to the peers
+	APIKey string
+}
+
+// GetPeerConfig constructs a PeerConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetPeerConfig() PeerConfig {
+	return PeerConfig{
+		Workers:        jc.GetStringSlice("peer_workers", nil),
+		AttestationTTL: jc.GetDuration("peer_attestation_ttl_seconds", 600),
+		APIKey:         jc.GetString("peer_api_key", ""),
+	}
+}
+
+// BackpressureConfig represents the thresholds of the interactive queue above which the worker signals backpressure
+// and rejects new jobs. A threshold of 0 disables it.
+type BackpressureConfig struct {
+	// MaxDepth is the number of jobs waiting to be executed
+	MaxDepth int
+	// MaxWait is the estimated time a new job of a capability would wait before being executed
+	MaxWait time.Duration
+}
+
+// GetBackpressureConfig constructs a BackpressureConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetBackpressureConfig() BackpressureConfig {
+	maxDepth, err := jc.GetInt("queue_backpressure_depth", 0)
+	if err != nil || maxDepth < 0 {
+		maxDepth = 0
+	}
+
+	return BackpressureConfig{
+		MaxDepth: maxDepth,
+		MaxWait:  jc.GetDuration("queue_backpressure_wait_seconds", 0),
+	}
+}
+
+// ContentPolicyConfig is the policy for the items of the results which are flagged as NSFW or sensitive by their source
+type ContentPolicyConfig struct {
+	// AllowNSFW lets jobs opt in to flagged items with include_nsfw. Otherwise they are always filtered out.
+	AllowNSFW bool
+}
+
+// GetContentPolicyConfig returns the content policy of the worker
+func (jc JobConfiguration) GetContentPolicyConfig() ContentPolicyConfig {
+	return ContentPolicyConfig{
+		AllowNSFW: jc.GetBool("content_policy_allow_nsfw", false),
 	}
 }
 
@@ -303,6 +1455,123 @@ func (jc JobConfiguration) GetRedditConfig() RedditConfig {
 	}
 }
 
+// MastodonConfig represents the configuration needed for Mastodon scraping
+type MastodonConfig struct {
+	// Instances are the base URLs of the instances which can be queried. The first one is used by default.
+	Instances []string
+}
+
+// GetMastodonConfig constructs a MastodonConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetMastodonConfig() MastodonConfig {
+	return MastodonConfig{
+		Instances: jc.GetStringSlice("mastodon_instances", []string{defaultMastodonInstance}),
+	}
+}
+
+const (
+	defaultTelemetryPushInterval   = 60
+	defaultTelemetryPushMaxRetries = 3
+)
+
+// TelemetryPushConfig represents the configuration of the push of the statistics to a collector
+type TelemetryPushConfig struct {
+	// URL is the collector the statistics are posted to. Pushing is disabled if it is empty.
+	URL string
+	// Secret is the key of the HMAC-SHA256 signature of every push
+	Secret   string
+	Interval time.Duration
+	// MaxRetries is how many times a push which failed is retried, waiting Backoff before the first retry and twice
+	// as long before each of the next ones
+	MaxRetries int
+	Backoff    time.Duration
+}
+
+// GetTelemetryPushConfig constructs a TelemetryPushConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetTelemetryPushConfig() TelemetryPushConfig {
+	maxRetries, err := jc.GetInt("telemetry_push_max_retries", defaultTelemetryPushMaxRetries)
+	if err != nil || maxRetries < 0 {
+		maxRetries = defaultTelemetryPushMaxRetries
+	}
+	return TelemetryPushConfig{
+		URL:        jc.GetString("telemetry_push_url", ""),
+		Secret:     jc.GetString("telemetry_push_secret", ""),
+		Interval:   jc.GetDuration("telemetry_push_interval_seconds", defaultTelemetryPushInterval),
+		MaxRetries: maxRetries,
+		Backoff:    time.Second,
+	}
+}
+
+// GitHubConfig represents the configuration needed for GitHub scraping
+type GitHubConfig struct {
+	// BaseURL is the base URL of the GitHub REST API
+	BaseURL string
+	// Token raises the rate limit of the GitHub API, and is needed to search code. It may be empty.
+	Token string
+}
+
+// GetGitHubConfig constructs a GitHubConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetGitHubConfig() GitHubConfig {
+	return GitHubConfig{
+		BaseURL: jc.GetString("github_api_url", defaultGitHubAPIURL),
+		Token:   jc.GetString("github_token", ""),
+	}
+}
+
+// DiscordConfig represents the configuration needed for Discord scraping
+type DiscordConfig struct {
+	// BaseURL is the base URL of the Discord API
+	BaseURL string
+	// BotToken is the token of the bot reading the servers. Discord jobs are not available without it.
+	BotToken string
+}
+
+// GetDiscordConfig constructs a DiscordConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetDiscordConfig() DiscordConfig {
+	return DiscordConfig{
+		BaseURL:  jc.GetString("discord_api_url", defaultDiscordAPIURL),
+		BotToken: jc.GetString("discord_bot_token", ""),
+	}
+}
+
+// DiscordBotToken returns the bot token of DISCORD_BOT_TOKEN, which may be given with the "Bot " prefix of the
+// Authorization header
+func DiscordBotToken(s string) string {
+	return strings.TrimPrefix(strings.TrimSpace(s), "Bot ")
+}
+
+// DefaultRSSFeedList is the name of the feed list configured with RSS_FEEDS, which is searched if a job doesn't select one
+const DefaultRSSFeedList = "default"
+
+// RSSConfig represents the configuration needed for rss jobs
+type RSSConfig struct {
+	// DataDir is where fetched feeds are cached. Caching is disabled if it is empty.
+	DataDir string
+	// FeedLists are the URLs of the feeds searched by searchfeeds jobs, by list name
+	FeedLists map[string][]string
+}
+
+// GetRSSConfig constructs an RSSConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetRSSConfig() RSSConfig {
+	feedLists, _ := jc["rss_feed_lists"].(map[string][]string)
+	return RSSConfig{
+		DataDir:   jc.GetString("data_dir", ""),
+		FeedLists: feedLists,
+	}
+}
+
+// ResearchConfig represents the configuration needed for research jobs
+type ResearchConfig struct {
+	// WebSearchURL is the search page crawled for web results. {query} is replaced with the escaped topic.
+	WebSearchURL string
+}
+
+// GetResearchConfig constructs a ResearchConfig directly from the JobConfiguration
+func (jc JobConfiguration) GetResearchConfig() ResearchConfig {
+	return ResearchConfig{
+		WebSearchURL: jc.GetString("research_web_search_url", defaultResearchWebSearchURL),
+	}
+}
+
 // LlmApiKey represents an LLM API key with validation capabilities
 type LlmApiKey string
 
@@ -311,7 +1580,7 @@ func (k LlmApiKey) IsValid() bool {
 	if k == "" {
 		return false
 	}
-	
+
 	// TODO: Add actual Gemini API key validation with a handler
 	// For now, just check if it's not empty
 	return true
@@ -325,17 +1594,53 @@ type LlmConfig struct {
 type WebConfig struct {
 	LlmConfig
 	ApifyApiKey string
+	// CrawlDelay is the minimum time between requests to the same domain when the worker fetches robots.txt and sitemaps
+	CrawlDelay time.Duration
+	// RespectRobotsTxt makes every web job honor robots.txt, jobs can also ask for it with respect_robots_txt
+	RespectRobotsTxt bool
+	// DomainMaxRequestsPerMinute caps the request rate of a job to the domains it scrapes, 0 is unlimited
+	DomainMaxRequestsPerMinute int
+	// MaxConcurrentDomains is how many domains are scraped at the same time by all web jobs, 0 is unlimited
+	MaxConcurrentDomains int
+}
+
+// LLMChunkConfig configures how the items of large results are processed by the LLM processor
+type LLMChunkConfig struct {
+	// ContextTokens is the context window of the model. Items which don't fit with the prompt and the response are
+	// split.
+	ContextTokens int
+	// ChunkItems is the number of items processed in each run of the LLM processor
+	ChunkItems int
+	// Concurrency is the number of runs of the LLM processor of a job at the same time
+	Concurrency int
+	// TokenBudget is the number of tokens a job may use, or 0 for no limit
+	TokenBudget int
+}
+
+// GetLLMChunkConfig returns the chunking of the items processed by the LLM processor
+func (jc JobConfiguration) GetLLMChunkConfig() LLMChunkConfig {
+	var cfg LLMChunkConfig
+	cfg.ContextTokens, _ = jc.GetInt("llm_context_tokens", 32768)
+	cfg.ChunkItems, _ = jc.GetInt("llm_chunk_items", 100)
+	cfg.Concurrency, _ = jc.GetInt("llm_max_concurrent_chunks", 4)
+	cfg.TokenBudget, _ = jc.GetInt("llm_token_budget", 0)
+	return cfg
 }
 
 // GetWebConfig constructs a WebConfig directly from the JobConfiguration
 // This eliminates the need for JSON marshaling/unmarshaling
 func (jc JobConfiguration) GetWebConfig() WebConfig {
-	return WebConfig{
+	cfg := WebConfig{
 		LlmConfig: LlmConfig{
 			GeminiApiKey: LlmApiKey(jc.GetString("gemini_api_key", "")),
 		},
-		ApifyApiKey: jc.GetString("apify_api_key", ""),
+		ApifyApiKey:      jc.GetString("apify_api_key", ""),
+		CrawlDelay:       jc.GetDuration("web_crawl_delay_seconds", 1),
+		RespectRobotsTxt: jc.GetBool("web_respect_robots_txt", false),
 	}
+	cfg.DomainMaxRequestsPerMinute, _ = jc.GetInt("web_domain_max_requests_per_minute", 0)
+	cfg.MaxConcurrentDomains, _ = jc.GetInt("web_max_concurrent_domains", 0)
+	return cfg
 }
 
 // ParseLogLevel parses a string and returns the corresponding logrus.Level.
@@ -355,7 +1660,7 @@ func ParseLogLevel(logLevel string) logrus.Level {
 	}
 }
 
-// SetLogLevel sets the log level for the application.
+// SetLogLevel sets the log level for the application. Modules with their own log level keep it, see LOG_MODULE_LEVELS.
 func SetLogLevel(level logrus.Level) {
-	logrus.SetLevel(level)
+	logging.SetLevel(level)
 }
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// SecretReferencePrefix marks the credentials which name a secret of the secrets provider instead of holding its
+// value, e.g. APIFY_API_KEY=secret:apify
+const SecretReferencePrefix = "secret:"
+
+// Secrets provides the secrets referenced by the configuration. ReadConfig sets it to the sealed secrets file, see
+// SECRETS_FILE; other backends can be plugged in by replacing it before the configuration is read.
+var Secrets tee.SecretsProvider
+
+// SecretVariables are the environment variables holding credentials, whose values can reference a secret
+var SecretVariables = []string{"API_KEY", "PEER_API_KEY", "MINER_API_KEYS", "TWITTER_ACCOUNTS", "TWITTER_API_KEYS", "APIFY_API_KEY", "GEMINI_API_KEY", "GITHUB_TOKEN", "DISCORD_BOT_TOKEN", "TELEMETRY_PUSH_SECRET"}
+
+// SecretsFilePath returns the path of the sealed secrets file, SECRETS_FILE or secrets.sealed in the data directory
+func SecretsFilePath(getenv func(string) string) string {
+	if path := getenv("SECRETS_FILE"); path != "" {
+		return path
+	}
+	dataDir := getenv("DATA_DIR")
+	if dataDir == "" {
+		dataDir = defaultDataDir
+	}
+	return filepath.Join(dataDir, tee.SecretsFileName)
+}
+
+// resolveSecret returns the value of a credential, looked up with Secrets if it references a secret
+func resolveSecret(value string) (string, error) {
+	name, ok := strings.CutPrefix(value, SecretReferencePrefix)
+	if !ok {
+		return value, nil
+	}
+	if name == "" {
+		return "", errors.New("the name of the secret is empty")
+	}
+	if Secrets == nil {
+		return "", errors.New("no secrets provider is configured")
+	}
+	secret, err := Secrets.GetSecret(name)
+	if err != nil {
+		return "", fmt.Errorf("secret %s: %w", name, err)
+	}
+	return secret, nil
+}
+
+// WithSecrets wraps getenv so that the credentials in SecretVariables which reference a secret return its value.
+// References which can't be resolved are logged and read as empty, so the credential is not used.
+func WithSecrets(getenv func(string) string) func(string) string {
+	return func(key string) string {
+		value := getenv(key)
+		if !strings.HasPrefix(value, SecretReferencePrefix) || !slices.Contains(SecretVariables, key) {
+			return value
+		}
+		secret, err := resolveSecret(value)
+		if err != nil {
+			logrus.Errorf("Failed to resolve %s: %v", key, err)
+			return ""
+		}
+		return secret
+	}
+}
@@ -0,0 +1,363 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
+	"github.com/masa-finance/tee-worker/internal/logging"
+)
+
+// Problem is an invalid setting found by Validate
+type Problem struct {
+	Variable string
+	Reason   string
+}
+
+func (p Problem) String() string {
+	return p.Variable + ": " + p.Reason
+}
+
+// numericSetting is a numeric environment variable with the smallest value ReadConfig accepts for it
+type numericSetting struct {
+	name string
+	min  int
+}
+
+// numericSettings are the numeric environment variables read by ReadConfig, which silently falls back to the default
+// for values out of range
+var numericSettings = []numericSetting{
+	{"STATS_BUF_SIZE", 1},
+	{"RESULT_CACHE_MAX_SIZE", 1},
+	{"RESULT_CACHE_MAX_AGE_SECONDS", 1},
+	{"RESULT_CACHE_MAX_BYTES", 0},
+	{"WORKER_ID_ROTATION_GRACE_SECONDS", 0},
+	{"LLM_CONTEXT_TOKENS", 1},
+	{"LLM_CHUNK_ITEMS", 1},
+	{"LLM_MAX_CONCURRENT_CHUNKS", 1},
+	{"LLM_TOKEN_BUDGET", 0},
+	{"RESULT_CACHE_SPILL_BYTES", 0},
+	{"RESULT_MAX_HELD", 0},
+	{"JOB_TIMEOUT_SECONDS", 1},
+	{"JOB_TIMEOUT_GRACE_SECONDS", 0},
+	{"CIRCUIT_BREAKER_THRESHOLD", 0},
+	{"CIRCUIT_BREAKER_COOLDOWN_SECONDS", 1},
+	{"MAX_REQUEST_BODY_BYTES", 1},
+	{"MAX_BATCH_JOBS", 1},
+	{"RETRY_BACKOFF_SECONDS", 1},
+	{"RETRY_MAX_BACKOFF_SECONDS", 1},
+	{"ECONOMY_QUEUE_SIZE", 1},
+	{"ECONOMY_MAX_WAIT_SECONDS", 1},
+	{"MAX_RECURRING_JOBS", 1},
+	{"BANDWIDTH_JOB_MAX_BYTES", 0},
+	{"BANDWIDTH_CLIENT_MAX_BYTES", 0},
+	{"BANDWIDTH_CLIENT_WINDOW_SECONDS", 1},
+	{"RESULT_MAX_BYTES", 0},
+	{"RESULT_MAX_ITEMS", 0},
+	{"MEMORY_CEILING_BYTES", 0},
+	{"QUEUE_BACKPRESSURE_DEPTH", 0},
+	{"QUEUE_BACKPRESSURE_WAIT_SECONDS", 0},
+	{"MINER_JOBS_PER_HOUR", 0},
+	{"MINER_RESULT_BYTES_PER_DAY", 0},
+	{"MINER_MAX_RECURRING_JOBS", 0},
+	{"RESULT_MAX_WAIT_SECONDS", 0},
+	{"DEDUP_TTL_SECONDS", 0},
+	{"NEXT_CURSOR_TTL_SECONDS", 0},
+	{"WEB_CRAWL_DELAY_SECONDS", 0},
+	{"WEB_DOMAIN_MAX_REQUESTS_PER_MINUTE", 0},
+	{"WEB_MAX_CONCURRENT_DOMAINS", 0},
+	{"HEALTH_PROBE_INTERVAL_SECONDS", 1},
+	{"HTTP_MAX_CONNS_PER_HOST", 0},
+	{"HTTP_MAX_IDLE_CONNS", 0},
+	{"HTTP_MAX_IDLE_CONNS_PER_HOST", 0},
+	{"HTTP_TLS_SESSION_CACHE_SIZE", 0},
+	{"HTTP_IDLE_CONN_TIMEOUT_SECONDS", 1},
+	{"HTTP_DIAL_TIMEOUT_SECONDS", 1},
+	{"STATS_MAX_DIMENSION_VALUES", 1},
+	{"STATS_PERSIST_INTERVAL_SECONDS", 0},
+	{"CREDENTIALS_RELOAD_INTERVAL_SECONDS", 0},
+	{"PEER_ATTESTATION_TTL_SECONDS", 1},
+	{"TWITTER_MAX_IDS_PER_JOB", 1},
+	{"TWITTER_MAX_FOLLOWER_SNAPSHOTS", 2},
+	{"TWITTER_MAX_TREND_SNAPSHOTS", 1},
+	{"TWITTER_THREAD_MAX_DEPTH", 0},
+	{"TWITTER_MAX_MEDIA_BYTES", 0},
+	{"TELEMETRY_PUSH_INTERVAL_SECONDS", 1},
+	{"TELEMETRY_PUSH_MAX_RETRIES", 0},
+}
+
+// perJobTypeSettings are the suffixes of the numeric environment variables configured per job type, e.g.
+// TWITTER_MAX_CONCURRENT, with the smallest value ReadConfig accepts for them
+var perJobTypeSettings = []numericSetting{
+	{"_MAX_RETRIES", 0},
+	{"_MAX_RESULTS_LIMIT", 0},
+	{"_MAX_CONCURRENT", 1},
+	{"_TIMEOUT_SECONDS", 1},
+	{"_CIRCUIT_BREAKER_THRESHOLD", 0},
+	{"_CIRCUIT_BREAKER_COOLDOWN_SECONDS", 1},
+}
+
+// isNumericSetting returns true if the environment variable is one of numericSettings, so it is not mistaken for one
+// of perJobTypeSettings, e.g. JOB_TIMEOUT_SECONDS
+func isNumericSetting(name string) bool {
+	return slices.ContainsFunc(numericSettings, func(s numericSetting) bool { return s.name == name })
+}
+
+// Validate checks the settings of the environment, given as KEY=value pairs like os.Environ returns them, and reports
+// every problem it finds: malformed credentials and API keys, secrets which can't be resolved, invalid URLs, an unusable
+// DATA_DIR and numbers out of range. ReadConfig ignores most of these, so the worker would otherwise only fail once a job runs into them. It has
+// to be called after ReadConfig, which loads DATA_DIR/.env into the environment.
+func Validate(environ []string) []Problem {
+	env := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		env[name] = value
+	}
+
+	var problems []Problem
+	add := func(name, format string, args ...any) {
+		problems = append(problems, Problem{Variable: name, Reason: fmt.Sprintf(format, args...)})
+	}
+
+	if s := env["LOG_LEVEL"]; s != "" {
+		switch strings.ToLower(s) {
+		case "debug", "info", "warn", "error":
+		default:
+			add("LOG_LEVEL", "must be one of debug, info, warn or error, got %q", s)
+		}
+	}
+	for _, entry := range splitList(env["LOG_MODULE_LEVELS"]) {
+		module, level, ok := strings.Cut(entry, ":")
+		if !ok {
+			add("LOG_MODULE_LEVELS", "%q must be formatted as module:level", entry)
+		} else if !slices.Contains(logging.Modules, module) {
+			add("LOG_MODULE_LEVELS", "unknown module %q, valid modules are %s", module, strings.Join(logging.Modules, ", "))
+		} else if _, err := logging.ParseLevel(level); err != nil {
+			add("LOG_MODULE_LEVELS", "%s", err)
+		}
+	}
+
+	if dir := env["DATA_DIR"]; dir != "" {
+		if err := checkWritableDir(dir); err != nil {
+			add("DATA_DIR", "%s", err)
+		}
+	}
+	if path := env["FINGERPRINT_PROFILES_FILE"]; path != "" {
+		if _, err := fingerprint.LoadProfiles(path); err != nil {
+			add("FINGERPRINT_PROFILES_FILE", "%s", err)
+		}
+	}
+
+	// Credentials, which are checked with the values of the secrets they reference
+	for _, name := range SecretVariables {
+		if !strings.HasPrefix(env[name], SecretReferencePrefix) {
+			continue
+		}
+		secret, err := resolveSecret(env[name])
+		if err != nil {
+			add(name, "%s", err)
+		}
+		env[name] = secret
+	}
+	for i, account := range splitList(env["TWITTER_ACCOUNTS"]) {
+		username, password, ok := strings.Cut(account, ":")
+		if !ok || strings.Contains(password, ":") || strings.TrimSpace(username) == "" || strings.TrimSpace(password) == "" {
+			add("TWITTER_ACCOUNTS", "account %d must be in username:password format", i+1)
+		}
+	}
+	for i, key := range splitList(env["TWITTER_API_KEYS"]) {
+		// Keys with a colon are consumer key and secret pairs, the others bearer tokens
+		if consumerKey, consumerSecret, ok := strings.Cut(key, ":"); ok {
+			if consumerKey == "" || consumerSecret == "" || strings.Contains(consumerSecret, ":") {
+				add("TWITTER_API_KEYS", "key %d must be a bearer token or in consumer_key:consumer_secret format", i+1)
+			}
+		} else if !isToken(key) {
+			add("TWITTER_API_KEYS", "key %d is not a bearer token", i+1)
+		}
+	}
+	if key := strings.TrimSpace(env["APIFY_API_KEY"]); key != "" && (!strings.HasPrefix(key, "apify_api_") || !isToken(key)) {
+		add("APIFY_API_KEY", "must be an Apify API token starting with apify_api_")
+	}
+	if key := strings.TrimSpace(env["GEMINI_API_KEY"]); key != "" && (!strings.HasPrefix(key, "AIza") || !isToken(key)) {
+		add("GEMINI_API_KEY", "must be a Google API key starting with AIza")
+	}
+	if key := strings.TrimSpace(env["GITHUB_TOKEN"]); key != "" && !isToken(key) {
+		add("GITHUB_TOKEN", "is not a GitHub token")
+	}
+	// Bot tokens are made of three base64 parts separated by dots
+	if key := DiscordBotToken(env["DISCORD_BOT_TOKEN"]); key != "" && (!isToken(key) || strings.Count(key, ".") != 2) {
+		add("DISCORD_BOT_TOKEN", "is not a Discord bot token")
+	}
+	miners := map[string]bool{}
+	for i, entry := range splitList(env["MINER_API_KEYS"]) {
+		k, err := ParseMinerAPIKey(entry, MinerAPIKey{})
+		switch {
+		case err != nil:
+			add("MINER_API_KEYS", "key %d: %s", i+1, err)
+		case !isToken(k.Key):
+			add("MINER_API_KEYS", "key %d is not a token", i+1)
+		case miners[k.Miner]:
+			add("MINER_API_KEYS", "miner %s has more than one key", k.Miner)
+		}
+		miners[k.Miner] = true
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		capability, ok := strings.CutPrefix(name, "TWITTER_AUTH_PRIORITY_")
+		if !ok {
+			continue
+		}
+		if !slices.Contains(TwitterAuthPriorityCapabilities, strings.ToLower(capability)) {
+			add(name, "unknown capability %q, the auth priority can be configured for %s", capability, strings.Join(TwitterAuthPriorityCapabilities, ", "))
+			continue
+		}
+		sources := splitList(env[name])
+		if len(sources) == 0 {
+			add(name, "must list at least one auth source")
+		}
+		seen := map[string]bool{}
+		for _, source := range sources {
+			source = strings.ToLower(source)
+			if !slices.Contains(TwitterAuthSources, source) {
+				add(name, "unknown auth source %q, valid sources are %s", source, strings.Join(TwitterAuthSources, ", "))
+			} else if seen[source] {
+				add(name, "auth source %s is listed more than once", source)
+			}
+			seen[source] = true
+		}
+	}
+
+	// Endpoints
+	peers := splitList(env["PEER_WORKERS"])
+	for _, peer := range peers {
+		if err := checkURL(peer); err != nil {
+			add("PEER_WORKERS", "%s", err)
+		}
+	}
+	if peerKey := strings.TrimSpace(env["PEER_API_KEY"]); len(peers) > 0 && peerKey == "" {
+		add("PEER_API_KEY", "must be set to authenticate the jobs delegated to PEER_WORKERS")
+	} else if peerKey != "" && peerKey == strings.TrimSpace(env["API_KEY"]) {
+		add("PEER_API_KEY", "must differ from API_KEY, which must not be shared with the peers")
+	}
+	for _, instance := range splitList(env["MASTODON_INSTANCES"]) {
+		if err := checkURL(instance); err != nil {
+			add("MASTODON_INSTANCES", "%s", err)
+		}
+	}
+	if u := strings.TrimSpace(env["GITHUB_API_URL"]); u != "" {
+		if err := checkURL(u); err != nil {
+			add("GITHUB_API_URL", "%s", err)
+		}
+	}
+	if u := strings.TrimSpace(env["DISCORD_API_URL"]); u != "" {
+		if err := checkURL(u); err != nil {
+			add("DISCORD_API_URL", "%s", err)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		if name != "RSS_FEEDS" && !strings.HasPrefix(name, "RSS_FEEDS_") {
+			continue
+		}
+		for _, feed := range splitList(env[name]) {
+			if err := checkURL(feed); err != nil {
+				add(name, "%s", err)
+			}
+		}
+	}
+	if s := env["RESEARCH_WEB_SEARCH_URL"]; s != "" {
+		if err := checkURL(s); err != nil {
+			add("RESEARCH_WEB_SEARCH_URL", "%s", err)
+		} else if !strings.Contains(s, "{query}") {
+			add("RESEARCH_WEB_SEARCH_URL", "must contain the {query} placeholder")
+		}
+	}
+	if u := strings.TrimSpace(env["TELEMETRY_PUSH_URL"]); u != "" {
+		if err := checkURL(u); err != nil {
+			add("TELEMETRY_PUSH_URL", "%s", err)
+		} else if strings.TrimSpace(env["TELEMETRY_PUSH_SECRET"]) == "" {
+			add("TELEMETRY_PUSH_SECRET", "must be set to sign the statistics pushed to TELEMETRY_PUSH_URL")
+		}
+	}
+	if s := env["OTEL_EXPORTER_OTLP_ENDPOINT"]; s != "" {
+		if err := checkURL(s); err != nil {
+			add("OTEL_EXPORTER_OTLP_ENDPOINT", "%s", err)
+		}
+	}
+
+	// Numbers
+	checkNumber := func(name, value string, min int) {
+		if v, err := strconv.Atoi(strings.TrimSpace(value)); err != nil {
+			add(name, "must be an integer, got %q", value)
+		} else if v < min {
+			add(name, "must be at least %d, got %d", min, v)
+		}
+	}
+	for _, setting := range numericSettings {
+		if s, ok := env[setting.name]; ok && s != "" {
+			checkNumber(setting.name, s, setting.min)
+		}
+	}
+	for _, name := range slices.Sorted(maps.Keys(env)) {
+		for _, setting := range perJobTypeSettings {
+			if jobType, ok := strings.CutSuffix(name, setting.name); ok && jobType != "" && !isNumericSetting(name) {
+				checkNumber(name, env[name], setting.min)
+			}
+		}
+	}
+
+	return problems
+}
+
+// splitList splits a comma-separated list, dropping the empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// isToken returns true if the key is made of printable characters other than spaces and quotes, which are usually
+// left over from copying it
+func isToken(key string) bool {
+	return key != "" && !strings.ContainsFunc(key, func(r rune) bool {
+		return !unicode.IsPrint(r) || unicode.IsSpace(r) || r == '"' || r == '\''
+	})
+}
+
+// checkURL returns an error unless the URL is an absolute http or https URL
+func checkURL(s string) error {
+	u, err := url.Parse(s)
+	if err != nil {
+		return fmt.Errorf("invalid URL: %w", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid URL %q: must be an http or https URL", u.Redacted())
+	}
+	return nil
+}
+
+// checkWritableDir returns an error unless the directory exists and files can be created in it
+func checkWritableDir(dir string) error {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("directory %s is not accessible: %w", dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", dir)
+	}
+	f, err := os.CreateTemp(dir, ".validate-*")
+	if err != nil {
+		return fmt.Errorf("directory %s is not writable: %w", dir, err)
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
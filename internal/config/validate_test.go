@@ -0,0 +1,155 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Validate", func() {
+	variables := func(problems []config.Problem) []string {
+		var names []string
+		for _, p := range problems {
+			names = append(names, p.Variable)
+		}
+		return names
+	}
+
+	It("accepts a valid configuration", func() {
+		Expect(config.Validate([]string{
+			"DATA_DIR=" + GinkgoT().TempDir(),
+			"LOG_LEVEL=debug",
+			"TWITTER_ACCOUNTS=foo:bar, baz:qux",
+			"TWITTER_API_KEYS=AAAAAAAAAAAAAAAAAAAAA,consumer:secret",
+			"APIFY_API_KEY=apify_api_abcdef",
+			"GEMINI_API_KEY=AIzaSyabcdef",
+			"DISCORD_BOT_TOKEN=Bot MTA5ODc2NTQzMjEwOTg3NjU0.GhIjKl.abcdefghijklmnopqrstuvwxyz0123456789AB",
+			"PEER_WORKERS=https://peer1:8080,http://peer2",
+			"PEER_API_KEY=peerkey",
+			"RESEARCH_WEB_SEARCH_URL=https://www.bing.com/search?q={query}",
+			"JOB_TIMEOUT_SECONDS=60",
+			"DEDUP_TTL_SECONDS=0",
+			"TWITTER_MAX_CONCURRENT=4",
+			"WEB_MAX_RETRIES=0",
+		})).To(BeEmpty())
+	})
+
+	It("accepts an empty configuration", func() {
+		Expect(config.Validate(nil)).To(BeEmpty())
+	})
+
+	It("reports every problem at once", func() {
+		file := filepath.Join(GinkgoT().TempDir(), "file")
+		Expect(os.WriteFile(file, nil, 0600)).To(Succeed())
+
+		problems := config.Validate([]string{
+			"DATA_DIR=" + file,
+			"LOG_LEVEL=verbose",
+			"LOG_MODULE_LEVELS=twitter:debug,reddit:debug,web:verbose",
+			"TWITTER_ACCOUNTS=foo:bar,foo,:baz",
+			"TWITTER_API_KEYS=\"quoted\",consumer:",
+			"APIFY_API_KEY=abcdef",
+			"GEMINI_API_KEY=AIza key",
+			"DISCORD_BOT_TOKEN=not-a-bot-token",
+			"MINER_API_KEYS=miner1:key1:ten,miner2,miner3:key3,miner3:key4",
+			"TWITTER_AUTH_PRIORITY_GETFOLLOWERS=apify,cookies,Apify",
+			"TWITTER_AUTH_PRIORITY_GETTRENDS=api",
+			"PEER_WORKERS=peer1:8080",
+			"MASTODON_INSTANCES=ftp://mastodon.social",
+			"RSS_FEEDS_NEWS=https://news.example/rss,news.example/atom",
+			"RESEARCH_WEB_SEARCH_URL=https://www.bing.com/search",
+			"TELEMETRY_PUSH_URL=https://collector.example/push",
+			"TELEMETRY_PUSH_INTERVAL_SECONDS=0",
+			"OTEL_EXPORTER_OTLP_ENDPOINT=://collector",
+			"JOB_TIMEOUT_SECONDS=0",
+			"RESULT_CACHE_MAX_BYTES=lots",
+			"TWITTER_MAX_FOLLOWER_SNAPSHOTS=1",
+			"TWITTER_MAX_CONCURRENT=0",
+			"TWITTER_APIFY_GETFOLLOWERS_TIMEOUT_SECONDS=0",
+			"WEB_MAX_RESULTS_LIMIT=-1",
+		})
+		Expect(variables(problems)).To(Equal([]string{
+			"LOG_LEVEL",
+			"LOG_MODULE_LEVELS",
+			"LOG_MODULE_LEVELS",
+			"DATA_DIR",
+			"TWITTER_ACCOUNTS",
+			"TWITTER_ACCOUNTS",
+			"TWITTER_API_KEYS",
+			"TWITTER_API_KEYS",
+			"APIFY_API_KEY",
+			"GEMINI_API_KEY",
+			"DISCORD_BOT_TOKEN",
+			"MINER_API_KEYS",
+			"MINER_API_KEYS",
+			"MINER_API_KEYS",
+			"TWITTER_AUTH_PRIORITY_GETFOLLOWERS",
+			"TWITTER_AUTH_PRIORITY_GETFOLLOWERS",
+			"TWITTER_AUTH_PRIORITY_GETTRENDS",
+			"PEER_WORKERS",
+			"PEER_API_KEY",
+			"MASTODON_INSTANCES",
+			"RSS_FEEDS_NEWS",
+			"RESEARCH_WEB_SEARCH_URL",
+			"TELEMETRY_PUSH_SECRET",
+			"OTEL_EXPORTER_OTLP_ENDPOINT",
+			"RESULT_CACHE_MAX_BYTES",
+			"JOB_TIMEOUT_SECONDS",
+			"TWITTER_MAX_FOLLOWER_SNAPSHOTS",
+			"TELEMETRY_PUSH_INTERVAL_SECONDS",
+			"TWITTER_APIFY_GETFOLLOWERS_TIMEOUT_SECONDS",
+			"TWITTER_MAX_CONCURRENT",
+			"WEB_MAX_RESULTS_LIMIT",
+		}))
+		Expect(problems[0].String()).To(Equal(`LOG_LEVEL: must be one of debug, info, warn or error, got "verbose"`))
+	})
+
+	It("doesn't leak the credentials in the problems", func() {
+		problems := config.Validate([]string{"TWITTER_ACCOUNTS=secretuser", "APIFY_API_KEY=secretkey", "MINER_API_KEYS=miner:secret:-1"})
+		Expect(problems).To(HaveLen(3))
+		for _, p := range problems {
+			Expect(p.String()).NotTo(ContainSubstring("secret"))
+		}
+	})
+
+	Context("when credentials reference secrets", func() {
+		var previous tee.SecretsProvider
+
+		BeforeEach(func() {
+			previous = config.Secrets
+			secrets := tee.NewSecretsFile(filepath.Join(GinkgoT().TempDir(), tee.SecretsFileName))
+			Expect(secrets.SetSecret("apify", "apify_api_abcdef")).To(Succeed())
+			Expect(secrets.SetSecret("gemini", "not-a-gemini-key")).To(Succeed())
+			config.Secrets = secrets
+		})
+
+		AfterEach(func() {
+			config.Secrets = previous
+		})
+
+		It("checks the values of the secrets", func() {
+			problems := config.Validate([]string{"APIFY_API_KEY=secret:apify", "GEMINI_API_KEY=secret:gemini", "TWITTER_API_KEYS=secret:twitter"})
+			Expect(variables(problems)).To(Equal([]string{"TWITTER_API_KEYS", "GEMINI_API_KEY"}))
+			Expect(problems[0].Reason).To(ContainSubstring("secret twitter: secret not found"))
+			Expect(problems[1].String()).NotTo(ContainSubstring("not-a-gemini-key"))
+		})
+
+		It("reads the credentials from the secrets", func() {
+			jc := config.CredentialsFromEnv(func(key string) string {
+				return map[string]string{"APIFY_API_KEY": "secret:apify", "GEMINI_API_KEY": "secret:missing"}[key]
+			})
+			Expect(jc.GetString("apify_api_key", "")).To(Equal("apify_api_abcdef"))
+			Expect(jc.GetString("gemini_api_key", "")).To(BeEmpty())
+		})
+	})
+
+	It("reports a data directory which doesn't exist", func() {
+		problems := config.Validate([]string{"DATA_DIR=" + filepath.Join(GinkgoT().TempDir(), "missing")})
+		Expect(problems).To(HaveLen(1))
+		Expect(problems[0].Reason).To(ContainSubstring("not accessible"))
+	})
+})
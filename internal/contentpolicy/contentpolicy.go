@@ -0,0 +1,168 @@
+package contentpolicy
+
+import (
+	"encoding/json"
+	"fmt"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// ArgumentKey is the job argument with which a job opts in to the items flagged as NSFW or sensitive by their source
+const ArgumentKey = "include_nsfw"
+
+// Flagger tells whether an item of a result, decoded from JSON, is flagged as NSFW or sensitive by its source
+type Flagger func(item map[string]any) bool
+
+// flaggers are the Flaggers of the job types whose results can contain flagged items
+var flaggers = map[teetypes.JobType]Flagger{
+	// Posts, users and communities are marked as over 18 by Reddit
+	teetypes.RedditJob: func(item map[string]any) bool {
+		return item["over18"] == true
+	},
+	// TikTok shows a warning before sensitive videos, and hides videos which didn't pass its review
+	teetypes.TiktokJob: func(item map[string]any) bool {
+		warnings, _ := item["warnInfo"].([]any)
+		return len(warnings) > 0 || item["showNotPass"] == true
+	},
+}
+
+// Applies returns true if the results of the job type can contain flagged items
+func Applies(jobType teetypes.JobType) bool {
+	_, ok := flaggers[jobType]
+	return ok
+}
+
+// IncludeFromArguments returns whether a job opted in to flagged items
+func IncludeFromArguments(args map[string]any) (bool, error) {
+	v, ok := args[ArgumentKey]
+	if !ok || v == nil {
+		return false, nil
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean, got %T", ArgumentKey, v)
+	}
+	return b, nil
+}
+
+// Filter removes the flagged items from a JSON-encoded result of a job type, wherever they are in the result, and
+// returns the number of items it removed. Items replying to a removed item, i.e. whose parentId is the id of a removed
+// item, such as the comments of a removed Reddit post, are removed with it. A result which is itself a flagged item
+// becomes null. Data that is not valid JSON is returned untouched.
+func Filter(jobType teetypes.JobType, data []byte) ([]byte, int, error) {
+	flagged, ok := flaggers[jobType]
+	if !ok || len(data) == 0 {
+		return data, 0, nil
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data, 0, nil
+	}
+
+	f := filter{flagged: flagged, removed: map[string]bool{}}
+	if !f.collect(v) {
+		return data, 0, nil
+	}
+	f.collectReplies(v)
+
+	v, removed := f.remove(v)
+	if removed {
+		return []byte("null"), 1, nil
+	}
+	filtered, err := json.Marshal(v)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error marshalling filtered result: %w", err)
+	}
+	return filtered, f.count, nil
+}
+
+// filter removes the flagged items of a decoded result
+type filter struct {
+	flagged Flagger
+	// removed are the ids of the items which are removed
+	removed map[string]bool
+	count   int
+}
+
+// collect records the ids of the flagged items, and returns true if there is any
+func (f *filter) collect(v any) bool {
+	found := false
+	walk(v, func(item map[string]any) {
+		if f.flagged(item) {
+			found = true
+			if id, _ := item["id"].(string); id != "" {
+				f.removed[id] = true
+			}
+		}
+	})
+	return found
+}
+
+// collectReplies records the ids of the replies to removed items, until the replies to replies are all found
+func (f *filter) collectReplies(v any) {
+	for found := true; found; {
+		found = false
+		walk(v, func(item map[string]any) {
+			if id, _ := item["id"].(string); id != "" && !f.removed[id] && f.isReply(item) {
+				f.removed[id] = true
+				found = true
+			}
+		})
+	}
+}
+
+func (f *filter) isReply(item map[string]any) bool {
+	parent, _ := item["parentId"].(string)
+	return parent != "" && f.removed[parent]
+}
+
+func (f *filter) isRemoved(item map[string]any) bool {
+	return f.flagged(item) || f.isReply(item)
+}
+
+// remove returns v without the removed items, and whether v is itself removed
+func (f *filter) remove(v any) (any, bool) {
+	switch val := v.(type) {
+	case map[string]any:
+		if f.isRemoved(val) {
+			return nil, true
+		}
+		for k, child := range val {
+			if c, removed := f.remove(child); removed {
+				delete(val, k)
+				f.count++
+			} else {
+				val[k] = c
+			}
+		}
+		return val, false
+	case []any:
+		kept := make([]any, 0, len(val))
+		for _, child := range val {
+			if c, removed := f.remove(child); removed {
+				f.count++
+			} else {
+				kept = append(kept, c)
+			}
+		}
+		return kept, false
+	}
+	return v, false
+}
+
+// walk calls fn with every object of v
+func walk(v any, fn func(map[string]any)) {
+	switch val := v.(type) {
+	case map[string]any:
+		fn(val)
+		for _, child := range val {
+			walk(child, fn)
+		}
+	case []any:
+		for _, child := range val {
+			walk(child, fn)
+		}
+	}
+}
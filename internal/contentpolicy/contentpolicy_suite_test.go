@@ -0,0 +1,13 @@
+package contentpolicy_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestContentPolicy(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Content Policy Suite")
+}
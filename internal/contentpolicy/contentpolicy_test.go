@@ -0,0 +1,92 @@
+package contentpolicy_test
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/contentpolicy"
+)
+
+var _ = Describe("Content policy", func() {
+	Describe("IncludeFromArguments", func() {
+		It("defaults to filtering flagged items", func() {
+			include, err := contentpolicy.IncludeFromArguments(map[string]any{"query": "foo"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(include).To(BeFalse())
+		})
+
+		It("parses the opt-in", func() {
+			include, err := contentpolicy.IncludeFromArguments(map[string]any{"include_nsfw": true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(include).To(BeTrue())
+		})
+
+		It("rejects values which are not booleans", func() {
+			_, err := contentpolicy.IncludeFromArguments(map[string]any{"include_nsfw": "yes"})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Filter", func() {
+		It("only applies to the job types which flag their items", func() {
+			Expect(contentpolicy.Applies(teetypes.RedditJob)).To(BeTrue())
+			Expect(contentpolicy.Applies(teetypes.TiktokJob)).To(BeTrue())
+			Expect(contentpolicy.Applies(teetypes.WebJob)).To(BeFalse())
+
+			data := []byte(`[{"id":"1","over18":true}]`)
+			out, n, err := contentpolicy.Filter(teetypes.WebJob, data)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(BeZero())
+			Expect(out).To(Equal(data))
+		})
+
+		It("removes NSFW Reddit posts together with their comments", func() {
+			out, n, err := contentpolicy.Filter(teetypes.RedditJob, []byte(`[
+				{"id":"t3_a","dataType":"post","over18":true},
+				{"id":"t1_b","dataType":"comment","parentId":"t3_a"},
+				{"id":"t1_c","dataType":"comment","parentId":"t1_b"},
+				{"id":"t3_d","dataType":"post","over18":false},
+				{"id":"t1_e","dataType":"comment","parentId":"t3_d"}
+			]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(3))
+			Expect(out).To(MatchJSON(`[
+				{"id":"t3_d","dataType":"post","over18":false},
+				{"id":"t1_e","dataType":"comment","parentId":"t3_d"}
+			]`))
+		})
+
+		It("removes flagged items nested in other items", func() {
+			out, n, err := contentpolicy.Filter(teetypes.RedditJob, []byte(`{"posts":[{"id":"1","community":{"name":"x","over18":true}}]}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(1))
+			Expect(out).To(MatchJSON(`{"posts":[{"id":"1"}]}`))
+		})
+
+		It("removes TikTok videos with a warning or which didn't pass review", func() {
+			out, n, err := contentpolicy.Filter(teetypes.TiktokJob, []byte(`[
+				{"id":"1","warnInfo":[{"text":"sensitive"}]},
+				{"id":"2","showNotPass":true},
+				{"id":"3","warnInfo":[],"showNotPass":false}
+			]`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(2))
+			Expect(out).To(MatchJSON(`[{"id":"3","warnInfo":[],"showNotPass":false}]`))
+		})
+
+		It("replaces a result which is a flagged item with null", func() {
+			out, n, err := contentpolicy.Filter(teetypes.RedditJob, []byte(`{"id":"1","over18":true}`))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(Equal(1))
+			Expect(out).To(MatchJSON(`null`))
+		})
+
+		It("leaves data which is not JSON untouched", func() {
+			out, n, err := contentpolicy.Filter(teetypes.TiktokJob, []byte("a transcript"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(n).To(BeZero())
+			Expect(string(out)).To(Equal("a transcript"))
+		})
+	})
+})
@@ -0,0 +1,112 @@
+// Package credentials reloads the credentials of the worker while it is running, so they can be rotated without a
+// restart, which would also lose the state of the workers such as the rate limits of the Twitter accounts. Only the
+// workers using the credentials which changed are created again.
+package credentials
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+// Manager watches the .env file in the data directory, and reloads the credentials when they are changed in it. The
+// secrets they reference are resolved whenever the file is read, so changed secrets are reloaded as well.
+type Manager struct {
+	path   string
+	base   config.JobConfiguration
+	reload func(jc config.JobConfiguration, changed []string)
+
+	// current are the credentials in the file when it was last read
+	current config.JobConfiguration
+	// fileSeen is true once the file has been read. Until then the credentials are read from the environment alone, so
+	// changed secrets are reloaded without a .env file.
+	fileSeen bool
+}
+
+// NewManager returns a manager which calls reload with a copy of jc holding the new credentials, and the keys of those
+// which changed, whenever they change
+func NewManager(jc config.JobConfiguration, reload func(jc config.JobConfiguration, changed []string)) *Manager {
+	m := &Manager{path: filepath.Join(jc.DataDir(), ".env"), base: jc, reload: reload}
+	if current, err := m.read(); err == nil {
+		m.current = current
+	}
+	return m
+}
+
+// Run checks the file for changes every interval until ctx is done. An interval of 0 disables reloading.
+func (m *Manager) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		logrus.Info("Credentials reloading is disabled")
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.Check()
+		}
+	}
+}
+
+// Check reads the file and reloads the credentials if they have changed since it was last read. It returns true if
+// they were reloaded.
+func (m *Manager) Check() bool {
+	creds, err := m.read()
+	if err != nil {
+		logrus.Debugf("Failed to read credentials from %s: %v", m.path, err)
+		return false
+	}
+	var changed []string
+	for key, value := range creds {
+		if !reflect.DeepEqual(value, m.current[key]) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return false
+	}
+	slices.Sort(changed)
+	m.current = creds
+
+	logrus.Infof("Credentials %v in %s have changed, reloading them", changed, m.path)
+	jc := maps.Clone(m.base)
+	maps.Copy(jc, creds)
+	m.reload(jc, changed)
+	return true
+}
+
+// read returns the credentials in the file. Credentials which are not in the file are taken from the environment,
+// which includes those read from the file at startup. A file which was read before and can't be read anymore is an
+// error, so the credentials are not reset to those of the environment.
+func (m *Manager) read() (config.JobConfiguration, error) {
+	env, err := godotenv.Read(m.path)
+	if errors.Is(err, fs.ErrNotExist) && !m.fileSeen {
+		env, err = nil, nil
+	} else if err == nil {
+		m.fileSeen = true
+	}
+	if err != nil {
+		return nil, err
+	}
+	return config.CredentialsFromEnv(func(key string) string {
+		if v, ok := env[key]; ok {
+			return v
+		}
+		return os.Getenv(key)
+	}), nil
+}
@@ -0,0 +1,117 @@
+package credentials_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/credentials"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Manager", func() {
+	var (
+		dataDir  string
+		base     config.JobConfiguration
+		reloaded []config.JobConfiguration
+		changed  [][]string
+		manager  *credentials.Manager
+	)
+
+	writeEnv := func(content string) {
+		Expect(os.WriteFile(filepath.Join(dataDir, ".env"), []byte(content), 0600)).To(Succeed())
+	}
+
+	BeforeEach(func() {
+		dataDir = GinkgoT().TempDir()
+		writeEnv("TWITTER_ACCOUNTS=alice:secret\nAPIFY_API_KEY=apify-1\n")
+
+		base = config.JobConfiguration{
+			"data_dir":         dataDir,
+			"max_jobs":         5,
+			"twitter_accounts": []string{"alice:secret"},
+			"apify_api_key":    "apify-1",
+		}
+		reloaded, changed = nil, nil
+		manager = credentials.NewManager(base, func(jc config.JobConfiguration, keys []string) {
+			reloaded = append(reloaded, jc)
+			changed = append(changed, keys)
+		})
+	})
+
+	It("should not reload unchanged credentials", func() {
+		Expect(manager.Check()).To(BeFalse())
+
+		// Settings other than the credentials are not reloaded
+		writeEnv("TWITTER_ACCOUNTS=alice:secret\nAPIFY_API_KEY=apify-1\nLOG_LEVEL=debug\n")
+		Expect(manager.Check()).To(BeFalse())
+		Expect(reloaded).To(BeEmpty())
+	})
+
+	It("should reload changed credentials on top of the configuration", func() {
+		writeEnv("TWITTER_ACCOUNTS=alice:secret, bob:hunter2\nAPIFY_API_KEY=apify-2\nGEMINI_API_KEY=gemini\n")
+		Expect(manager.Check()).To(BeTrue())
+
+		Expect(reloaded).To(HaveLen(1))
+		Expect(reloaded[0].GetStringSlice("twitter_accounts", nil)).To(Equal([]string{"alice:secret", "bob:hunter2"}))
+		Expect(reloaded[0].GetString("apify_api_key", "")).To(Equal("apify-2"))
+		Expect(reloaded[0].GetString("gemini_api_key", "")).To(Equal("gemini"))
+		Expect(reloaded[0]).To(HaveKeyWithValue("max_jobs", 5))
+		Expect(changed[0]).To(Equal([]string{"apify_api_key", "gemini_api_key", "twitter_accounts"}))
+
+		// The configuration the manager was created with is left alone
+		Expect(base.GetString("apify_api_key", "")).To(Equal("apify-1"))
+
+		Expect(manager.Check()).To(BeFalse())
+		Expect(reloaded).To(HaveLen(1))
+	})
+
+	It("should keep the credentials if the file can't be read", func() {
+		Expect(os.Remove(filepath.Join(dataDir, ".env"))).To(Succeed())
+		Expect(manager.Check()).To(BeFalse())
+		Expect(reloaded).To(BeEmpty())
+	})
+
+	It("should reload changed secrets without a .env file", func() {
+		previous := config.Secrets
+		DeferCleanup(func() { config.Secrets = previous })
+		secrets := tee.NewSecretsFile(filepath.Join(dataDir, tee.SecretsFileName))
+		Expect(secrets.SetSecret("apify", "apify-1")).To(Succeed())
+		config.Secrets = secrets
+		GinkgoT().Setenv("APIFY_API_KEY", "secret:apify")
+
+		Expect(os.Remove(filepath.Join(dataDir, ".env"))).To(Succeed())
+		manager = credentials.NewManager(base, func(jc config.JobConfiguration, keys []string) {
+			reloaded = append(reloaded, jc)
+			changed = append(changed, keys)
+		})
+		Expect(manager.Check()).To(BeFalse())
+
+		Expect(secrets.SetSecret("apify", "apify-2")).To(Succeed())
+		Expect(manager.Check()).To(BeTrue())
+		Expect(reloaded[0].GetString("apify_api_key", "")).To(Equal("apify-2"))
+		Expect(changed[0]).To(Equal([]string{"apify_api_key"}))
+	})
+
+	It("should check the file periodically", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		done := make(chan struct{})
+		var count int
+		manager = credentials.NewManager(base, func(config.JobConfiguration, []string) {
+			count++
+			if count == 1 {
+				close(done)
+			}
+		})
+		go manager.Run(ctx, 10*time.Millisecond)
+
+		writeEnv("TWITTER_ACCOUNTS=carol:secret\n")
+		Eventually(done).Should(BeClosed())
+	})
+})
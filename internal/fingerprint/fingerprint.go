@@ -0,0 +1,187 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Profile is the browser a scraper presents itself as: its user agent, the languages it accepts and the size of its
+// window. Sites which fingerprint their visitors see the same profile for as long as it isn't blocked.
+type Profile struct {
+	Name           string `json:"name"`
+	UserAgent      string `json:"user_agent"`
+	AcceptLanguage string `json:"accept_language,omitempty"`
+	ViewportWidth  int    `json:"viewport_width,omitempty"`
+	ViewportHeight int    `json:"viewport_height,omitempty"`
+}
+
+// Headers returns the request headers of the profile
+func (p Profile) Headers() map[string]string {
+	headers := map[string]string{"User-Agent": p.UserAgent}
+	if p.AcceptLanguage != "" {
+		headers["Accept-Language"] = p.AcceptLanguage
+	}
+	return headers
+}
+
+// Apply sets the request headers of the profile
+func (p Profile) Apply(h http.Header) {
+	for k, v := range p.Headers() {
+		h.Set(k, v)
+	}
+}
+
+// DefaultProfiles are used unless FINGERPRINT_PROFILES_FILE is set. They are common desktop browsers, so that the
+// scrapers don't stand out.
+var DefaultProfiles = []Profile{
+	{
+		Name:           "chrome-windows",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		AcceptLanguage: "en-US,en;q=0.9",
+		ViewportWidth:  1920,
+		ViewportHeight: 1080,
+	},
+	{
+		Name:           "chrome-macos",
+		UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36",
+		AcceptLanguage: "en-US,en;q=0.9",
+		ViewportWidth:  1440,
+		ViewportHeight: 900,
+	},
+	{
+		Name:           "firefox-windows",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64; rv:131.0) Gecko/20100101 Firefox/131.0",
+		AcceptLanguage: "en-US,en;q=0.5",
+		ViewportWidth:  1366,
+		ViewportHeight: 768,
+	},
+	{
+		Name:           "safari-macos",
+		UserAgent:      "Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.6 Safari/605.1.15",
+		AcceptLanguage: "en-GB,en;q=0.9",
+		ViewportWidth:  1280,
+		ViewportHeight: 800,
+	},
+	{
+		Name:           "edge-windows",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/129.0.0.0 Safari/537.36 Edg/129.0.0.0",
+		AcceptLanguage: "en-US,en;q=0.9",
+		ViewportWidth:  1536,
+		ViewportHeight: 864,
+	},
+}
+
+// Manager assigns a profile to each identity a scraper uses, e.g. a Twitter account or the domain of a web scrape.
+// The profile of an identity is derived from its key, so it stays the same across jobs and restarts, until it is
+// rotated to the next profile after the identity was blocked.
+type Manager struct {
+	mu        sync.Mutex
+	profiles  []Profile
+	rotations map[string]int
+}
+
+// NewManager creates a Manager assigning the given profiles, or DefaultProfiles if there are none
+func NewManager(profiles []Profile) *Manager {
+	if len(profiles) == 0 {
+		profiles = DefaultProfiles
+	}
+	return &Manager{profiles: profiles, rotations: make(map[string]int)}
+}
+
+// For returns the profile of the identity with the given key
+func (m *Manager) For(key string) Profile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.profile(key)
+}
+
+// Rotate moves the identity with the given key to the next profile, after it was blocked, and returns it
+func (m *Manager) Rotate(key string) Profile {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rotations[key]++
+	p := m.profile(key)
+	logrus.Debugf("Rotated the fingerprint profile of %s to %s", key, p.Name)
+	return p
+}
+
+func (m *Manager) profile(key string) Profile {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return m.profiles[(int(h.Sum32()%uint32(len(m.profiles)))+m.rotations[key])%len(m.profiles)]
+}
+
+// LoadProfiles reads a JSON array of profiles. Every profile needs a user agent.
+func LoadProfiles(path string) ([]Profile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading fingerprint profiles: %w", err)
+	}
+	var profiles []Profile
+	if err := json.Unmarshal(data, &profiles); err != nil {
+		return nil, fmt.Errorf("error parsing fingerprint profiles: %w", err)
+	}
+	if len(profiles) == 0 {
+		return nil, errors.New("no fingerprint profiles in " + path)
+	}
+	for i, p := range profiles {
+		if strings.TrimSpace(p.UserAgent) == "" {
+			return nil, fmt.Errorf("fingerprint profile %d (%q) has no user_agent", i, p.Name)
+		}
+		if p.ViewportWidth < 0 || p.ViewportHeight < 0 {
+			return nil, fmt.Errorf("fingerprint profile %d (%q) has a negative viewport", i, p.Name)
+		}
+	}
+	return profiles, nil
+}
+
+// profiles is the Manager shared by all the scrapers, which are recreated when the credentials are reloaded
+var profiles atomic.Pointer[Manager]
+
+func init() {
+	profiles.Store(NewManager(nil))
+}
+
+// Configure replaces the profiles assigned by the shared Manager with those in the file at path, or with
+// DefaultProfiles if path is empty. The profiles are left unchanged if the file is invalid.
+func Configure(path string) error {
+	if path == "" {
+		profiles.Store(NewManager(nil))
+		return nil
+	}
+	loaded, err := LoadProfiles(path)
+	if err != nil {
+		return err
+	}
+	profiles.Store(NewManager(loaded))
+	return nil
+}
+
+// For returns the profile of the identity with the given key from the shared Manager
+func For(key string) Profile {
+	return profiles.Load().For(key)
+}
+
+// Rotate moves the identity with the given key to the next profile of the shared Manager
+func Rotate(key string) Profile {
+	return profiles.Load().Rotate(key)
+}
+
+// TwitterAccountKey is the key of the profile of a Twitter account
+func TwitterAccountKey(username string) string {
+	return "twitter:" + strings.ToLower(username)
+}
+
+// DomainKey is the key of the profile presented to a domain
+func DomainKey(domain string) string {
+	return "domain:" + strings.ToLower(strings.TrimPrefix(domain, "www."))
+}
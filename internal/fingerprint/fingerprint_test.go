@@ -0,0 +1,89 @@
+package fingerprint_test
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
+)
+
+var _ = Describe("Fingerprint profiles", func() {
+	profiles := []fingerprint.Profile{
+		{Name: "a", UserAgent: "agent-a", AcceptLanguage: "en-US"},
+		{Name: "b", UserAgent: "agent-b"},
+		{Name: "c", UserAgent: "agent-c"},
+	}
+
+	It("assigns the same profile to an identity until it is rotated", func() {
+		m := fingerprint.NewManager(profiles)
+		key := fingerprint.TwitterAccountKey("Alice")
+		p := m.For(key)
+		Expect(m.For(fingerprint.TwitterAccountKey("alice"))).To(Equal(p))
+		Expect(fingerprint.NewManager(profiles).For(key)).To(Equal(p))
+
+		rotated := m.Rotate(key)
+		Expect(rotated).NotTo(Equal(p))
+		Expect(m.For(key)).To(Equal(rotated))
+
+		// Rotating through all the profiles comes back to the first one
+		m.Rotate(key)
+		Expect(m.Rotate(key)).To(Equal(p))
+	})
+
+	It("spreads the identities over the profiles", func() {
+		m := fingerprint.NewManager(profiles)
+		seen := map[string]bool{}
+		for _, domain := range []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com", "g.com", "h.com"} {
+			seen[m.For(fingerprint.DomainKey(domain)).Name] = true
+		}
+		Expect(len(seen)).To(BeNumerically(">", 1))
+		Expect(fingerprint.DomainKey("www.Example.com")).To(Equal(fingerprint.DomainKey("example.com")))
+	})
+
+	It("uses the default profiles without any", func() {
+		p := fingerprint.NewManager(nil).For("any")
+		Expect(fingerprint.DefaultProfiles).To(ContainElement(p))
+		Expect(p.UserAgent).NotTo(BeEmpty())
+		Expect(p.ViewportWidth).To(BeNumerically(">", 0))
+	})
+
+	It("sets the headers of a profile", func() {
+		h := http.Header{}
+		profiles[0].Apply(h)
+		Expect(h.Get("User-Agent")).To(Equal("agent-a"))
+		Expect(h.Get("Accept-Language")).To(Equal("en-US"))
+		Expect(profiles[1].Headers()).To(Equal(map[string]string{"User-Agent": "agent-b"}))
+	})
+
+	Describe("Configure", func() {
+		AfterEach(func() {
+			Expect(fingerprint.Configure("")).To(Succeed())
+		})
+
+		It("loads the profiles from a file", func() {
+			path := filepath.Join(GinkgoT().TempDir(), "profiles.json")
+			Expect(os.WriteFile(path, []byte(`[{"name": "only", "user_agent": "custom-agent", "viewport_width": 800}]`), 0o600)).To(Succeed())
+
+			Expect(fingerprint.Configure(path)).To(Succeed())
+			Expect(fingerprint.For(fingerprint.DomainKey("example.com")).UserAgent).To(Equal("custom-agent"))
+			Expect(fingerprint.Rotate(fingerprint.DomainKey("example.com")).UserAgent).To(Equal("custom-agent"))
+		})
+
+		It("rejects invalid files and keeps the profiles", func() {
+			dir := GinkgoT().TempDir()
+			noAgent := filepath.Join(dir, "no-agent.json")
+			Expect(os.WriteFile(noAgent, []byte(`[{"name": "broken"}]`), 0o600)).To(Succeed())
+			empty := filepath.Join(dir, "empty.json")
+			Expect(os.WriteFile(empty, []byte(`[]`), 0o600)).To(Succeed())
+
+			Expect(fingerprint.Configure(noAgent)).To(MatchError(ContainSubstring("has no user_agent")))
+			Expect(fingerprint.Configure(empty)).To(HaveOccurred())
+			Expect(fingerprint.Configure(filepath.Join(dir, "missing.json"))).To(HaveOccurred())
+			Expect(fingerprint.DefaultProfiles).To(ContainElement(fingerprint.For("any")))
+		})
+	})
+})
@@ -0,0 +1,160 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/sirupsen/logrus"
+)
+
+// apifyQuotaCheckInterval is how often the monthly usage of an Apify account is checked. The check doesn't consume
+// any usage, but it is made in the background so jobs are never delayed by it.
+const apifyQuotaCheckInterval = time.Minute
+
+// GetApifyLimits is a function variable that can be replaced in tests.
+var GetApifyLimits = func(apiKey string) (*client.ApifyLimits, error) {
+	return client.GetApifyLimits(apiKey)
+}
+
+// apifyQuota caches whether the monthly usage of an Apify account is exhausted
+type apifyQuota struct {
+	mu             sync.Mutex
+	apiKey         string
+	checkedAt      time.Time
+	checking       bool
+	exhaustedUntil time.Time
+}
+
+// apifyQuotas holds the quota of each Apify API key, shared by all the workers using the key
+var apifyQuotas sync.Map // map[string]*apifyQuota
+
+func apifyQuotaFor(apiKey string) *apifyQuota {
+	q, _ := apifyQuotas.LoadOrStore(apiKey, &apifyQuota{apiKey: apiKey})
+	return q.(*apifyQuota)
+}
+
+// exhaustedFor returns how long the monthly usage remains exhausted, as of the last check, or zero if it isn't. If the
+// last check is older than apifyQuotaCheckInterval, a new one is started in the background. Until the first check has
+// finished, and if a check fails, the usage is assumed not to be exhausted.
+func (q *apifyQuota) exhaustedFor() time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	now := time.Now()
+	if !q.checking && now.Sub(q.checkedAt) >= apifyQuotaCheckInterval {
+		q.checking = true
+		go q.check()
+	}
+	if now.Before(q.exhaustedUntil) {
+		return q.exhaustedUntil.Sub(now)
+	}
+	return 0
+}
+
+func (q *apifyQuota) check() {
+	limits, err := GetApifyLimits(q.apiKey)
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.checking = false
+	q.checkedAt = time.Now()
+	if err != nil {
+		logrus.Warnf("Error while checking the Apify usage limits: %s", err)
+		q.exhaustedUntil = time.Time{}
+		return
+	}
+	if limits.Exhausted() {
+		if q.exhaustedUntil.IsZero() {
+			logrus.Warnf("Apify monthly usage exhausted until %s, jobs using Apify are not admitted", limits.CycleEndAt)
+		}
+		q.exhaustedUntil = limits.CycleEndAt
+		return
+	}
+	q.exhaustedUntil = time.Time{}
+}
+
+// jobCapability returns the capability of a job, or the default capability of its job type
+func jobCapability(j types.Job) teetypes.Capability {
+	if c, ok := j.Arguments["type"].(string); ok && c != "" {
+		return teetypes.Capability(strings.ToLower(c))
+	}
+	return teetypes.JobDefaultCapabilityMap[j.Type]
+}
+
+// apifyExhaustedFor returns how long the monthly usage of the Apify account remains exhausted, or zero if it isn't or
+// if no API key is configured
+func apifyExhaustedFor(apiKey string) time.Duration {
+	if apiKey == "" {
+		return 0
+	}
+	return apifyQuotaFor(apiKey).exhaustedFor()
+}
+
+// admitApify rejects jobs using Apify while the monthly usage of the account is exhausted
+func admitApify(j types.Job, apiKey string) error {
+	if wait := apifyExhaustedFor(apiKey); wait > 0 {
+		return &types.AdmissionError{Reason: fmt.Sprintf("the Apify quota used by %s jobs is exhausted", j.Type), RetryAfter: wait}
+	}
+	return nil
+}
+
+// Admit rejects jobs whose auth sources are all unavailable: all accounts are rate limited or suspended, or the Apify
+// quota is exhausted. API keys are always considered available, since their rate limits are not tracked.
+func (ts *TwitterScraper) Admit(j types.Job) error {
+	var retryAfter time.Duration
+	for _, source := range ts.authSourcesFor(j.Type, jobCapability(j)) {
+		var wait time.Duration
+		switch source {
+		case types.AuthSourceCredential:
+			if ts.accountManager != nil {
+				wait = ts.accountManager.UnavailableFor()
+			}
+		case types.AuthSourceApify:
+			wait = apifyExhaustedFor(ts.configuration.ApifyApiKey)
+		}
+		if wait == 0 {
+			return nil
+		}
+		if retryAfter == 0 || wait < retryAfter {
+			retryAfter = wait
+		}
+	}
+	if retryAfter == 0 {
+		return nil
+	}
+	return &types.AdmissionError{Reason: fmt.Sprintf("all Twitter accounts and quotas usable for %s jobs are rate limited, suspended or exhausted", j.Type), RetryAfter: retryAfter}
+}
+
+// Admit rejects searches while the Apify quota is exhausted. Transcriptions don't use Apify.
+func (ttt *TikTokTranscriber) Admit(j types.Job) error {
+	if !slices.Contains(teetypes.TiktokSearchCaps, jobCapability(j)) {
+		return nil
+	}
+	return admitApify(j, ttt.configuration.ApifyApiKey)
+}
+
+// Admit rejects jobs while the Apify quota is exhausted, since all Reddit capabilities use Apify
+func (rs *RedditScraper) Admit(j types.Job) error {
+	return admitApify(j, rs.configuration.ApifyApiKey)
+}
+
+// Admit rejects jobs while the simulated rate limit is reached
+func (sw *SimulatedWorker) Admit(j types.Job) error {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	now := time.Now()
+	if !sw.rateLimitedLocked(now) {
+		return nil
+	}
+	retryAfter := time.Duration(sw.profile.RateLimit.WindowSeconds) * time.Second
+	if len(sw.requests) > 0 {
+		retryAfter = sw.requests[0].Add(retryAfter).Sub(now)
+	}
+	return &types.AdmissionError{Reason: fmt.Sprintf("simulated rate limit of %s jobs reached", j.Type), RetryAfter: retryAfter}
+}
@@ -0,0 +1,76 @@
+package jobs_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+var _ = Describe("Admission", func() {
+	var limits *client.ApifyLimits
+	var limitsErr error
+
+	BeforeEach(func() {
+		cycleEnd := time.Now().Add(48 * time.Hour)
+		limits = &client.ApifyLimits{MonthlyUsageUsd: 50, MaxMonthlyUsageUsd: 49, CycleEndAt: cycleEnd}
+		limitsErr = nil
+
+		original := GetApifyLimits
+		GetApifyLimits = func(apiKey string) (*client.ApifyLimits, error) {
+			return limits, limitsErr
+		}
+		DeferCleanup(func() { GetApifyLimits = original })
+	})
+
+	// admitted returns the error of Admit once the background check of the Apify quota has finished
+	admitted := func(admit func(types.Job) error, j types.Job) error {
+		_ = admit(j)
+		var err error
+		Eventually(func() error {
+			err = admit(j)
+			return err
+		}).Should(HaveOccurred())
+		return err
+	}
+
+	It("rejects Reddit jobs while the Apify quota is exhausted, until the end of the usage cycle", func() {
+		r := NewRedditScraper(config.JobConfiguration{"apify_api_key": "reddit-exhausted"}, nil)
+		err := admitted(r.Admit, types.Job{Type: teetypes.RedditJob, Arguments: map[string]any{"type": "searchposts"}})
+
+		var admissionErr *types.AdmissionError
+		Expect(errors.As(err, &admissionErr)).To(BeTrue())
+		Expect(admissionErr.RetryAfter).To(BeNumerically("~", 48*time.Hour, time.Minute))
+	})
+
+	It("admits jobs if the Apify quota is not exhausted or cannot be checked", func() {
+		limits.MonthlyUsageUsd = 10
+		r := NewRedditScraper(config.JobConfiguration{"apify_api_key": "reddit-available"}, nil)
+		j := types.Job{Type: teetypes.RedditJob, Arguments: map[string]any{"type": "searchposts"}}
+		Consistently(func() error { return r.Admit(j) }, 200*time.Millisecond).Should(Succeed())
+
+		limitsErr = errors.New("unavailable")
+		r = NewRedditScraper(config.JobConfiguration{"apify_api_key": "reddit-unknown"}, nil)
+		Consistently(func() error { return r.Admit(j) }, 200*time.Millisecond).Should(Succeed())
+	})
+
+	It("only rejects the TikTok capabilities which use Apify", func() {
+		t := NewTikTokTranscriber(config.JobConfiguration{"apify_api_key": "tiktok-exhausted"}, nil)
+		Expect(admitted(t.Admit, types.Job{Type: teetypes.TiktokJob, Arguments: map[string]any{"type": "searchbyquery"}})).To(HaveOccurred())
+		Expect(t.Admit(types.Job{Type: teetypes.TiktokJob, Arguments: map[string]any{"type": "transcription"}})).To(Succeed())
+		Expect(t.Admit(types.Job{Type: teetypes.TiktokJob})).To(Succeed())
+	})
+
+	It("rejects Twitter Apify jobs, but not jobs which can use API keys", func() {
+		ts := NewTwitterScraper(config.JobConfiguration{"apify_api_key": "twitter-exhausted", "twitter_api_keys": []string{"key"}}, nil)
+		Expect(admitted(ts.Admit, types.Job{Type: teetypes.TwitterApifyJob, Arguments: map[string]any{"type": "getfollowers"}})).To(HaveOccurred())
+		Expect(ts.Admit(types.Job{Type: teetypes.TwitterApiJob, Arguments: map[string]any{"type": "searchbyquery"}})).To(Succeed())
+	})
+})
@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+// apifyOptions returns the options of the Apify clients used by a job. Their traffic is counted towards the bandwidth
+// of the job, the IDs of the actor runs they start are recorded in its provenance and trace, and the cost of the runs
+// is recorded for telemetry. Runs which are still running when the job times out or is cancelled are aborted.
+func apifyOptions(j types.Job) []client.Option {
+	opts := []client.Option{
+		client.WrapTransport(j.Network.Transport),
+		client.OnActorRun(func(runID string) {
+			j.Provenance.AddActorRun(runID)
+			j.Trace.ActorRun(runID)
+		}),
+		client.OnActorRunCost(func(cost client.ActorRunCost) {
+			j.ApifyCost.AddRun(cost.ComputeUnits, cost.DatasetReads, cost.UsageUSD)
+		}),
+		client.ActorRunCancel(j.Cancelled),
+	}
+	if j.Timeout > 0 {
+		opts = append(opts, client.ActorRunDeadline(time.Now().Add(j.Timeout)))
+	}
+	return opts
+}
+
+// validateApifyApiKey checks that an Apify API key is valid
+func validateApifyApiKey(apiKey string) error {
+	c, err := client.NewApifyClient(apiKey)
+	if err != nil {
+		return err
+	}
+	return c.ValidateApiKey()
+}
@@ -0,0 +1,96 @@
+package jobs
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+
+	teeargs "github.com/masa-finance/tee-types/args"
+)
+
+// argumentKeys returns the sorted JSON keys of the fields of the given argument structs, together with any extra
+// keys which are read from the job arguments directly
+func argumentKeys(structs []any, extra ...string) []string {
+	keys := slices.Clone(extra)
+	for _, s := range structs {
+		t := reflect.TypeOf(s)
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+			if name != "" && name != "-" {
+				keys = append(keys, name)
+			}
+		}
+	}
+	slices.Sort(keys)
+	return slices.Compact(keys)
+}
+
+// ArgumentKeys returns the arguments accepted by web jobs, including those of screenshots
+func (w *WebScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{teeargs.WebArguments{}, WebScreenshotArguments{}}, archiveFallbackArgumentKey, extractArgumentKey, maxRequestsPerMinuteArgumentKey, modeArgumentKey, renderJSArgumentKey, respectRobotsTxtArgumentKey, urlsArgumentKey)
+}
+
+// ArgumentKeys returns the arguments accepted by the Twitter job types, including the capabilities which are not
+// part of tee-types yet
+func (ts *TwitterScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{
+		teeargs.TwitterSearchArguments{},
+		TwitterGetByIdsArguments{},
+		TwitterGetPollArguments{},
+		TwitterGetThreadArguments{},
+		TwitterFollowerDeltaArguments{},
+		TwitterListTweetsArguments{},
+		TwitterCommunityTweetsArguments{},
+		TwitterSearchSpacesArguments{},
+		TwitterDownloadMediaArguments{},
+		TwitterTweetRangeArguments{},
+	})
+}
+
+// ArgumentKeys returns the arguments accepted by TikTok jobs
+func (t *TikTokTranscriber) ArgumentKeys() []string {
+	return argumentKeys([]any{
+		teeargs.TikTokTranscriptionArguments{},
+		teeargs.TikTokSearchByQueryArguments{},
+		teeargs.TikTokSearchByTrendingArguments{},
+		TikTokListLanguagesArguments{},
+	}, "type", strictLanguageArgumentKey)
+}
+
+// ArgumentKeys returns the arguments accepted by Reddit jobs
+func (r *RedditScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{teeargs.RedditArguments{}, RedditScrapeSubredditArguments{}, RedditGetUserActivityArguments{}, RedditMonitorArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by mastodon jobs
+func (ms *MastodonScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{MastodonArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by github jobs
+func (gs *GitHubScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{GitHubArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by discord jobs
+func (ds *DiscordScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{DiscordArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by rss jobs
+func (rs *RSSScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{RSSArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by research jobs
+func (rs *ResearchScraper) ArgumentKeys() []string {
+	return argumentKeys([]any{ResearchArguments{}})
+}
+
+// ArgumentKeys returns the arguments accepted by telemetry jobs, which have none apart from their type
+func (t TelemetryJob) ArgumentKeys() []string {
+	return []string{"type"}
+}
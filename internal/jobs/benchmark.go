@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+// BenchmarkWorkloadDuration is how long each local benchmark workload runs
+var BenchmarkWorkloadDuration = 250 * time.Millisecond
+
+// BenchmarkLatencyTargets are the URLs requested to measure the latency of the providers. They can be replaced in tests.
+var BenchmarkLatencyTargets = map[string]string{
+	"apify_latency":   "https://api.apify.com/v2",
+	"twitter_latency": "https://api.x.com/2",
+}
+
+const (
+	// benchmarkLatencySamples is the number of requests made to each provider, the median latency is reported
+	benchmarkLatencySamples = 3
+	benchmarkLatencyTimeout = 10 * time.Second
+)
+
+// localWorkload is a synthetic micro-job which only uses the CPU. Reference is the number of operations per second on
+// the reference machine, which scores 100.
+type localWorkload struct {
+	name      string
+	reference float64
+	run       func() error
+}
+
+// RunBenchmark runs the synthetic micro-jobs and measures the latency of the providers the worker is configured to use.
+// The capacity of the worker is the score of the local workloads, scaled by maxJobs.
+func RunBenchmark(jc config.JobConfiguration, maxJobs int) types.BenchmarkReport {
+	start := time.Now()
+	report := types.BenchmarkReport{MaxJobs: maxJobs, StartedAt: start.UTC()}
+
+	var ratios []float64
+	for _, w := range localWorkloads() {
+		res := runLocalWorkload(w, BenchmarkWorkloadDuration)
+		if res.Status == types.BenchmarkOK {
+			ratios = append(ratios, res.OpsPerSecond/w.reference)
+		}
+		report.Results = append(report.Results, res)
+	}
+
+	twitterConfigured := len(jc.GetStringSlice("twitter_accounts", nil)) > 0 || len(jc.GetStringSlice("twitter_api_keys", nil)) > 0
+	report.Results = append(report.Results,
+		measureLatency("apify_latency", jc.GetString("apify_api_key", "") != ""),
+		measureLatency("twitter_latency", twitterConfigured),
+	)
+
+	report.Score = round1(geometricMean(ratios) * 100)
+	report.Capacity = round1(report.Score * float64(maxJobs) / 100)
+	report.DurationMs = time.Since(start).Milliseconds()
+
+	logrus.Infof("Benchmark finished in %dms with score %.1f and capacity %.1f", report.DurationMs, report.Score, report.Capacity)
+	return report
+}
+
+// localWorkloads returns the CPU-bound workloads: parsing scraper output, converting TikTok subtitles and marshalling
+// results
+func localWorkloads() []localWorkload {
+	postJSON := []byte(`{"dataType":"post","id":"t3_abc123","parsedId":"abc123","url":"https://www.reddit.com/r/golang/comments/abc123/","username":"gopher","title":"Benchmarking the worker","communityName":"r/golang","parsedCommunityName":"golang","body":"` + strings.Repeat("lorem ipsum dolor sit amet ", 20) + `","numberOfComments":42,"upVotes":1337,"createdAt":"2025-01-01T12:00:00Z","scrapedAt":"2025-01-01T12:05:00Z"}`)
+
+	var vtt strings.Builder
+	vtt.WriteString("WEBVTT\n\n")
+	for i := range 50 {
+		fmt.Fprintf(&vtt, "00:00:%02d.000 --> 00:00:%02d.500\n<v Speaker>caption <c.color969696>number</c> %d of the video\n\n", i, i, i)
+	}
+	vttContent := vtt.String()
+
+	posts := make([]*reddit.Post, 100)
+	for i := range posts {
+		posts[i] = &reddit.Post{ID: fmt.Sprintf("t3_%d", i), Title: "Benchmarking the worker", Body: strings.Repeat("lorem ipsum ", 20), UpVotes: i, DataType: string(reddit.PostResponse)}
+	}
+
+	return []localWorkload{
+		{name: "local_parsing", reference: 80000, run: func() error {
+			var resp reddit.Response
+			return json.Unmarshal(postJSON, &resp)
+		}},
+		{name: "vtt_conversion", reference: 80000, run: func() error {
+			_, err := convertVTTToPlainText(vttContent)
+			return err
+		}},
+		{name: "json_marshal", reference: 5000, run: func() error {
+			_, err := json.Marshal(posts)
+			return err
+		}},
+	}
+}
+
+// runLocalWorkload runs a workload repeatedly for the given duration, and reports how many times it ran per second
+func runLocalWorkload(w localWorkload, d time.Duration) types.BenchmarkResult {
+	res := types.BenchmarkResult{Name: w.name, Status: types.BenchmarkOK}
+
+	ops := 0
+	start := time.Now()
+	for time.Since(start) < d {
+		if err := w.run(); err != nil {
+			res.Status = types.BenchmarkFailed
+			res.Error = err.Error()
+			return res
+		}
+		ops++
+	}
+
+	res.OpsPerSecond = round1(float64(ops) / time.Since(start).Seconds())
+	return res
+}
+
+// measureLatency reports the median latency of requests to a provider. Any HTTP response counts, since only the
+// network path to the provider is measured.
+func measureLatency(name string, configured bool) types.BenchmarkResult {
+	res := types.BenchmarkResult{Name: name, Status: types.BenchmarkOK}
+	if !configured {
+		res.Status = types.BenchmarkNotConfigured
+		return res
+	}
+
+	httpClient := &http.Client{Timeout: benchmarkLatencyTimeout}
+	latencies := make([]time.Duration, 0, benchmarkLatencySamples)
+	for range benchmarkLatencySamples {
+		start := time.Now()
+		resp, err := httpClient.Get(BenchmarkLatencyTargets[name])
+		if err != nil {
+			res.Status = types.BenchmarkFailed
+			res.Error = err.Error()
+			return res
+		}
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		latencies = append(latencies, time.Since(start))
+	}
+
+	slices.Sort(latencies)
+	res.LatencyMs = latencies[len(latencies)/2].Milliseconds()
+	return res
+}
+
+// geometricMean returns the geometric mean of the values, so a single fast workload doesn't dominate the score
+func geometricMean(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += math.Log(v)
+	}
+	return math.Exp(sum / float64(len(values)))
+}
+
+func round1(v float64) float64 {
+	return math.Round(v*10) / 10
+}
@@ -0,0 +1,79 @@
+package jobs_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+var _ = Describe("RunBenchmark", func() {
+	var (
+		server          *httptest.Server
+		originalTargets map[string]string
+		originalWindow  time.Duration
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		originalTargets = jobs.BenchmarkLatencyTargets
+		originalWindow = jobs.BenchmarkWorkloadDuration
+		jobs.BenchmarkLatencyTargets = map[string]string{"apify_latency": server.URL, "twitter_latency": server.URL}
+		jobs.BenchmarkWorkloadDuration = 20 * time.Millisecond
+	})
+
+	AfterEach(func() {
+		jobs.BenchmarkLatencyTargets = originalTargets
+		jobs.BenchmarkWorkloadDuration = originalWindow
+		server.Close()
+	})
+
+	resultsByName := func(report types.BenchmarkReport) map[string]types.BenchmarkResult {
+		results := make(map[string]types.BenchmarkResult)
+		for _, r := range report.Results {
+			results[r.Name] = r
+		}
+		return results
+	}
+
+	It("should score the local workloads and scale the capacity by the number of jobs", func() {
+		report := jobs.RunBenchmark(config.JobConfiguration{}, 4)
+
+		results := resultsByName(report)
+		for _, name := range []string{"local_parsing", "vtt_conversion", "json_marshal"} {
+			Expect(results[name].Status).To(Equal(types.BenchmarkOK), name)
+			Expect(results[name].OpsPerSecond).To(BeNumerically(">", 0), name)
+		}
+		Expect(report.Score).To(BeNumerically(">", 0))
+		Expect(report.MaxJobs).To(Equal(4))
+		Expect(report.Capacity).To(BeNumerically("~", report.Score*4/100, 0.1))
+		Expect(report.StartedAt).To(BeTemporally("~", time.Now(), 5*time.Second))
+	})
+
+	It("should only measure the latency of configured providers", func() {
+		report := jobs.RunBenchmark(config.JobConfiguration{"apify_api_key": "apify-key"}, 1)
+
+		results := resultsByName(report)
+		Expect(results["apify_latency"].Status).To(Equal(types.BenchmarkOK))
+		Expect(results["twitter_latency"].Status).To(Equal(types.BenchmarkNotConfigured))
+	})
+
+	It("should report unreachable providers as failed without affecting the score", func() {
+		server.Close()
+
+		report := jobs.RunBenchmark(config.JobConfiguration{"twitter_api_keys": []string{"key"}}, 1)
+
+		results := resultsByName(report)
+		Expect(results["twitter_latency"].Status).To(Equal(types.BenchmarkFailed))
+		Expect(results["twitter_latency"].Error).NotTo(BeEmpty())
+		Expect(report.Score).To(BeNumerically(">", 0))
+	})
+})
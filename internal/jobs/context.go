@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// jobContext returns the context a job runs in. It is done at the timeout of the job, with types.ErrJobTimedOut as
+// its cause, or when the job is cancelled, with types.ErrJobCancelled as its cause. A job without a timeout only
+// ends when it is cancelled. The returned function releases the context, and has to be called once the job is done.
+func jobContext(j types.Job) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancelCause(context.Background())
+	stopTimeout := func() {}
+	if j.Timeout > 0 {
+		var timeoutCtx context.Context
+		timeoutCtx, stopTimeout = context.WithTimeoutCause(ctx, j.Timeout, types.ErrJobTimedOut)
+		ctx = timeoutCtx
+	}
+
+	done := make(chan struct{})
+	if j.Cancelled != nil {
+		go func() {
+			select {
+			case <-j.Cancelled:
+				cancel(types.ErrJobCancelled)
+			case <-done:
+			}
+		}()
+	}
+
+	return ctx, sync.OnceFunc(func() {
+		close(done)
+		stopTimeout()
+		cancel(context.Canceled)
+	})
+}
@@ -0,0 +1,339 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/discord"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/discordapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// DiscordJob reads the public and announcement channels of the Discord servers a bot is a member of. It is not part of
+// tee-types yet, so its arguments are validated here.
+const DiscordJob teetypes.JobType = "discord"
+
+// Capabilities of the discord job type
+const (
+	// CapGetChannelMessages lists the messages of a channel, newest first
+	CapGetChannelMessages teetypes.Capability = "getchannelmessages"
+	// CapGetGuildInfo fetches a server and the channels the bot can see
+	CapGetGuildInfo teetypes.Capability = "getguildinfo"
+	// CapSearchMessages searches the history of a channel. Bots can't use the search of the Discord API, so the
+	// history is scanned by the worker.
+	CapSearchMessages teetypes.Capability = "searchmessages"
+)
+
+// DiscordCaps are the capabilities of the discord job type, which all need a bot token
+var DiscordCaps = []teetypes.Capability{CapGetChannelMessages, CapGetGuildInfo, CapSearchMessages}
+
+const (
+	defaultDiscordMaxResults = 50
+	maxDiscordMaxResults     = 1000
+	defaultDiscordMaxScanned = 500
+	maxDiscordMaxScanned     = 5000
+
+	// The Discord API allows a bot 50 requests per second overall
+	discordRateLimitRequests      = 50
+	discordRateLimitWindowSeconds = 1
+)
+
+// DiscordClient defines the interface for the Discord client. This allows for mocking in tests.
+type DiscordClient interface {
+	GetChannel(channelID string) (*discord.Channel, error)
+	GetChannelMessages(channelID, before string, limit int) ([]discord.Message, error)
+	GetGuild(guildID string) (*discord.Guild, error)
+	GetGuildChannels(guildID string) ([]discord.Channel, error)
+}
+
+// NewDiscordClient is a function variable that can be replaced in tests.
+// It defaults to the actual implementation.
+var NewDiscordClient = func(ctx context.Context, cfg config.DiscordConfig, network types.Network) DiscordClient {
+	c := discordapi.NewClient(cfg.BaseURL, cfg.BotToken)
+	c.HTTPClient = network.Client(c.HTTPClient)
+	c.Context = ctx
+	return c
+}
+
+// DiscordArguments are the arguments of a discord job
+type DiscordArguments struct {
+	QueryType teetypes.Capability `json:"type"`
+	// ChannelID is the channel whose messages are listed or searched
+	ChannelID string `json:"channel_id"`
+	// GuildID is the server of getguildinfo
+	GuildID string `json:"guild_id"`
+	// Query are the words a message must all contain to match a search, in any case
+	Query string `json:"query"`
+	// Author restricts a search to the messages of a user, given by ID or username
+	Author     string `json:"author"`
+	MaxResults int    `json:"max_results"`
+	// MaxScanned is the number of messages a search looks at, whether they match or not
+	MaxScanned int    `json:"max_scanned"`
+	NextCursor string `json:"next_cursor"`
+
+	// terms are the lowercase words of Query
+	terms []string
+}
+
+type DiscordScraper struct {
+	configuration  config.DiscordConfig
+	statsCollector *stats.StatsCollector
+}
+
+func NewDiscordScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *DiscordScraper {
+	config := jc.GetDiscordConfig()
+	logrus.Infof("Discord scraper initialized with API %s, bot token configured: %t", config.BaseURL, config.BotToken != "")
+	return &DiscordScraper{
+		configuration:  config,
+		statsCollector: statsCollector,
+	}
+}
+
+// GetStructuredCapabilities returns the capabilities of the Discord scraper, which are only available with a bot token
+func (ds *DiscordScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	capabilities := make(teetypes.WorkerCapabilities)
+	if ds.configuration.BotToken != "" {
+		capabilities[DiscordJob] = DiscordCaps
+	}
+	return capabilities
+}
+
+// GetCapabilityDetails returns the auth source of each capability, which is the API, and the global rate limit of bots
+func (ds *DiscordScraper) GetCapabilityDetails() types.CapabilityDetails {
+	details := types.NewCapabilityDetails(ds.GetStructuredCapabilities(), types.AuthSourceAPI)
+	for i := range details[DiscordJob] {
+		details[DiscordJob][i].RateLimit = &types.RateLimitEstimate{
+			Requests:      discordRateLimitRequests,
+			WindowSeconds: discordRateLimitWindowSeconds,
+		}
+	}
+	return details
+}
+
+// parseDiscordArguments unmarshals and validates the arguments of a discord job
+func parseDiscordArguments(args types.JobArguments) (*DiscordArguments, error) {
+	parsed := &DiscordArguments{}
+	if err := args.Unmarshal(parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal discord arguments: %w", err)
+	}
+
+	parsed.QueryType = teetypes.Capability(strings.ToLower(string(parsed.QueryType)))
+	if parsed.QueryType == teetypes.CapEmpty {
+		parsed.QueryType = CapGetChannelMessages
+	}
+	if !slices.Contains(DiscordCaps, parsed.QueryType) {
+		return nil, fmt.Errorf("invalid type %q for discord job, valid types are %v", parsed.QueryType, DiscordCaps)
+	}
+
+	parsed.ChannelID = strings.TrimSpace(parsed.ChannelID)
+	parsed.GuildID = strings.TrimSpace(parsed.GuildID)
+	switch parsed.QueryType {
+	case CapGetGuildInfo:
+		if !isSnowflake(parsed.GuildID) {
+			return nil, fmt.Errorf("guild_id must be the ID of a server, got %q", parsed.GuildID)
+		}
+	default:
+		if !isSnowflake(parsed.ChannelID) {
+			return nil, fmt.Errorf("channel_id must be the ID of a channel, got %q", parsed.ChannelID)
+		}
+	}
+
+	parsed.Author = strings.TrimPrefix(strings.TrimSpace(parsed.Author), "@")
+	parsed.terms = strings.Fields(strings.ToLower(parsed.Query))
+	if parsed.QueryType == CapSearchMessages && len(parsed.terms) == 0 && parsed.Author == "" {
+		return nil, errors.New("query or author is required")
+	}
+
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultDiscordMaxResults
+	}
+	if parsed.MaxResults < 0 || parsed.MaxResults > maxDiscordMaxResults {
+		return nil, fmt.Errorf("max_results must be between 1 and %d, got %d", maxDiscordMaxResults, parsed.MaxResults)
+	}
+	if parsed.MaxScanned == 0 {
+		parsed.MaxScanned = defaultDiscordMaxScanned
+	}
+	if parsed.MaxScanned < 0 || parsed.MaxScanned > maxDiscordMaxScanned {
+		return nil, fmt.Errorf("max_scanned must be between 1 and %d, got %d", maxDiscordMaxScanned, parsed.MaxScanned)
+	}
+
+	// The cursor is the ID of the last message of the previous page
+	if parsed.NextCursor != "" && !isSnowflake(parsed.NextCursor) {
+		return nil, fmt.Errorf("invalid next_cursor %q", parsed.NextCursor)
+	}
+
+	return parsed, nil
+}
+
+// isSnowflake returns true if s is a Discord ID, which is a decimal 64-bit number
+func isSnowflake(s string) bool {
+	return s != "" && len(s) <= 20 && !strings.ContainsFunc(s, func(r rune) bool { return r < '0' || r > '9' })
+}
+
+// matches returns true if the message contains all the terms of the search, in its content or in its embeds, and was
+// sent by its author, if set
+func (a *DiscordArguments) matches(m discord.Message) bool {
+	if a.Author != "" && m.Author.ID != a.Author && !strings.EqualFold(m.Author.Username, a.Author) {
+		return false
+	}
+	text := []string{m.Content}
+	for _, e := range m.Embeds {
+		text = append(text, e.Title, e.Description)
+	}
+	lower := strings.ToLower(strings.Join(text, "\n"))
+	for _, term := range a.terms {
+		if !strings.Contains(lower, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// addStat adds to a statistic, broken down by the capability of the job if enabled
+func (ds *DiscordScraper) addStat(j types.Job, typ stats.StatType, num uint) {
+	ds.statsCollector.AddWithDimensions(j.WorkerID, typ, num, stats.DimensionsForJob(j))
+}
+
+func (ds *DiscordScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for Discord scrape")
+
+	if ds.configuration.BotToken == "" {
+		msg := errors.New("discord jobs require DISCORD_BOT_TOKEN")
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+
+	args, err := parseDiscordArguments(j.Arguments)
+	if err != nil {
+		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+	logrus.Debugf("discord job args: %+v", *args)
+
+	ctx, cancel := jobContext(j)
+	defer cancel()
+	client := NewDiscordClient(ctx, ds.configuration, j.Network)
+
+	var (
+		data       any
+		nextCursor string
+	)
+	switch args.QueryType {
+	case CapGetGuildInfo:
+		data, err = ds.getGuildInfo(j, client, args.GuildID)
+
+	case CapGetChannelMessages, CapSearchMessages:
+		var ch *discord.Channel
+		ds.addStat(j, stats.DiscordQueries, 1)
+		ch, err = client.GetChannel(args.ChannelID)
+		if err == nil && !ch.Readable() {
+			msg := fmt.Errorf("channel %s is a %s channel, only text and announcement channels can be read", ch.ID, ch.Type)
+			return types.JobResult{Error: msg.Error()}, msg
+		}
+		if err != nil {
+			break
+		}
+
+		var messages []discord.Message
+		if args.QueryType == CapGetChannelMessages {
+			messages, nextCursor, _, err = ds.scanMessages(j, client, ch, args.NextCursor, args.MaxResults, args.MaxResults, nil)
+		} else {
+			var scanned int
+			messages, nextCursor, scanned, err = ds.scanMessages(j, client, ch, args.NextCursor, args.MaxResults, args.MaxScanned, args.matches)
+			ds.addStat(j, stats.DiscordScannedMessages, uint(scanned))
+		}
+		if err == nil {
+			ds.addStat(j, stats.DiscordMessages, uint(len(messages)))
+			data = messages
+		}
+	}
+
+	if err != nil {
+		ds.handleError(j, err)
+		return types.JobResult{Error: fmt.Sprintf("error while scraping Discord: %s", err.Error())}, fmt.Errorf("error scraping Discord: %w", err)
+	}
+
+	res, err := pageResult(data, nextCursor)
+	res.Job = j
+	return res, err
+}
+
+// getGuildInfo returns a server with the channels the bot can see, in the order they are shown in the Discord client
+func (ds *DiscordScraper) getGuildInfo(j types.Job, client DiscordClient, guildID string) (*discord.Guild, error) {
+	ds.addStat(j, stats.DiscordQueries, 2)
+	guild, err := client.GetGuild(guildID)
+	if err != nil {
+		return nil, err
+	}
+	channels, err := client.GetGuildChannels(guildID)
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(channels, func(a, b discord.Channel) int { return a.Position - b.Position })
+	guild.Channels = channels
+	ds.addStat(j, stats.DiscordGuilds, 1)
+	return guild, nil
+}
+
+// scanMessages pages through the messages of a channel, newest first, starting before the message of the cursor, until
+// maxResults messages have been collected or maxScanned messages have been looked at. Only the messages for which keep
+// returns true are collected, if it is set. The next cursor is the ID of the last message looked at, and is only set if
+// there may be older messages. It also returns the number of messages looked at.
+func (ds *DiscordScraper) scanMessages(j types.Job, client DiscordClient, ch *discord.Channel, before string, maxResults, maxScanned int, keep func(discord.Message) bool) ([]discord.Message, string, int, error) {
+	messages := make([]discord.Message, 0, min(maxResults, discordapi.MaxPageSize))
+	cursor, scanned := before, 0
+
+	for len(messages) < maxResults && scanned < maxScanned {
+		limit := min(discordapi.MaxPageSize, maxScanned-scanned)
+		if keep == nil {
+			limit = min(limit, maxResults-len(messages))
+		}
+		ds.addStat(j, stats.DiscordQueries, 1)
+		page, err := client.GetChannelMessages(ch.ID, cursor, limit)
+		if errors.Is(err, bandwidth.ErrCapExceeded) && len(messages) > 0 {
+			// Return the messages collected so far, the next cursor continues after them
+			logrus.Warnf("Bandwidth cap of job %s exceeded, returning %d messages", j.UUID, len(messages))
+			break
+		}
+		if err != nil {
+			return nil, "", scanned, err
+		}
+
+		looked := 0
+		for _, m := range page {
+			if len(messages) == maxResults {
+				break
+			}
+			looked++
+			cursor = m.ID
+			if keep == nil || keep(m) {
+				m.GuildID = ch.GuildID
+				m.URL = "https://discord.com/channels/" + ch.GuildID + "/" + m.ChannelID + "/" + m.ID
+				messages = append(messages, m)
+			}
+		}
+		scanned += looked
+		// A short page is the start of the history, unless messages of it are left
+		if len(page) < limit && looked == len(page) {
+			return messages, "", scanned, nil
+		}
+	}
+	return messages, cursor, scanned, nil
+}
+
+func (ds *DiscordScraper) handleError(j types.Job, err error) {
+	if errors.Is(err, discordapi.ErrRateLimited) {
+		ds.addStat(j, stats.DiscordRateErrors, 1)
+		logrus.Warn("Rate limited by Discord")
+		return
+	}
+	ds.addStat(j, stats.DiscordErrors, 1)
+}
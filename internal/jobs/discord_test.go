@@ -0,0 +1,246 @@
+package jobs_test
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/discord"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/discordapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// MockDiscordClient is a mock implementation of the DiscordClient.
+type MockDiscordClient struct {
+	GetChannelFunc         func(channelID string) (*discord.Channel, error)
+	GetChannelMessagesFunc func(channelID, before string, limit int) ([]discord.Message, error)
+	GetGuildFunc           func(guildID string) (*discord.Guild, error)
+	GetGuildChannelsFunc   func(guildID string) ([]discord.Channel, error)
+}
+
+func (m *MockDiscordClient) GetChannel(channelID string) (*discord.Channel, error) {
+	return m.GetChannelFunc(channelID)
+}
+
+func (m *MockDiscordClient) GetChannelMessages(channelID, before string, limit int) ([]discord.Message, error) {
+	return m.GetChannelMessagesFunc(channelID, before, limit)
+}
+
+func (m *MockDiscordClient) GetGuild(guildID string) (*discord.Guild, error) {
+	return m.GetGuildFunc(guildID)
+}
+
+func (m *MockDiscordClient) GetGuildChannels(guildID string) ([]discord.Channel, error) {
+	return m.GetGuildChannelsFunc(guildID)
+}
+
+// messagePage returns the messages of a channel with total messages, whose IDs go from total down to 1, sent before
+// the message with the given ID. Every fifth message mentions a release.
+func messagePage(total int, before string, limit int) []discord.Message {
+	start := total
+	if before != "" {
+		start, _ = strconv.Atoi(before)
+		start--
+	}
+	var messages []discord.Message
+	for id := start; id > 0 && len(messages) < limit; id-- {
+		content := "gm"
+		if id%5 == 0 {
+			content = "New Release is out"
+		}
+		messages = append(messages, discord.Message{ID: strconv.Itoa(id), ChannelID: "10", Author: discord.User{ID: "7", Username: "alice"}, Content: content, Timestamp: time.Unix(int64(id), 0)})
+	}
+	return messages
+}
+
+var _ = Describe("DiscordScraper", func() {
+	var (
+		scraper        *jobs.DiscordScraper
+		statsCollector *stats.StatsCollector
+		mockClient     *MockDiscordClient
+		clientConfig   config.DiscordConfig
+		job            types.Job
+	)
+
+	BeforeEach(func() {
+		statsCollector = stats.StartCollector(128, config.JobConfiguration{})
+		scraper = jobs.NewDiscordScraper(config.JobConfiguration{"discord_bot_token": "bot-token"}, statsCollector)
+
+		mockClient = &MockDiscordClient{
+			GetChannelFunc: func(channelID string) (*discord.Channel, error) {
+				return &discord.Channel{ID: channelID, GuildID: "1", Name: "announcements", Type: discord.ChannelAnnouncement}, nil
+			},
+		}
+		clientConfig = config.DiscordConfig{}
+		original := jobs.NewDiscordClient
+		DeferCleanup(func() { jobs.NewDiscordClient = original })
+		jobs.NewDiscordClient = func(_ context.Context, cfg config.DiscordConfig, _ types.Network) jobs.DiscordClient {
+			clientConfig = cfg
+			return mockClient
+		}
+
+		job = types.Job{
+			UUID:     "test-uuid",
+			Type:     jobs.DiscordJob,
+			WorkerID: "discord-test",
+		}
+	})
+
+	It("should only advertise its capabilities with a bot token", func() {
+		Expect(scraper.GetStructuredCapabilities()[jobs.DiscordJob]).To(ConsistOf(jobs.DiscordCaps))
+		for _, detail := range scraper.GetCapabilityDetails()[jobs.DiscordJob] {
+			Expect(detail.AuthSource).To(Equal(types.AuthSourceAPI))
+			Expect(detail.RateLimit.Requests).To(Equal(50))
+		}
+
+		scraper = jobs.NewDiscordScraper(config.JobConfiguration{}, statsCollector)
+		Expect(scraper.GetStructuredCapabilities()).To(BeEmpty())
+		job.Arguments = map[string]any{"type": "getchannelmessages", "channel_id": "10"}
+		_, err := scraper.ExecuteJob(job)
+		Expect(err).To(MatchError(ContainSubstring("DISCORD_BOT_TOKEN")))
+	})
+
+	It("should page through the messages of a channel", func() {
+		var befores []string
+		mockClient.GetChannelMessagesFunc = func(channelID, before string, limit int) ([]discord.Message, error) {
+			Expect(channelID).To(Equal("10"))
+			befores = append(befores, before)
+			return messagePage(250, before, limit), nil
+		}
+
+		job.Arguments = map[string]any{"type": "getchannelmessages", "channel_id": "10", "max_results": 150}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientConfig.BotToken).To(Equal("bot-token"))
+		Expect(clientConfig.BaseURL).To(Equal(discordapi.DefaultBaseURL))
+		Expect(befores).To(Equal([]string{"", "151"}))
+
+		var messages []discord.Message
+		Expect(json.Unmarshal(res.Data, &messages)).To(Succeed())
+		Expect(messages).To(HaveLen(150))
+		Expect(messages[0].ID).To(Equal("250"))
+		Expect(messages[0].GuildID).To(Equal("1"))
+		Expect(messages[0].URL).To(Equal("https://discord.com/channels/1/10/250"))
+		Expect(res.NextCursor).To(Equal("101"))
+		Expect(res.HasMore).To(BeTrue())
+
+		// The next job continues with older messages until the start of the history
+		job.Arguments = map[string]any{"type": "getchannelmessages", "channel_id": "10", "max_results": 500, "next_cursor": res.NextCursor}
+		res, err = scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(res.Data, &messages)).To(Succeed())
+		Expect(messages).To(HaveLen(100))
+		Expect(messages[0].ID).To(Equal("100"))
+		Expect(res.HasMore).To(BeFalse())
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.DiscordMessages]
+		}).Should(BeNumerically("==", 250))
+	})
+
+	It("should search the history of a channel within the scan budget", func() {
+		mockClient.GetChannelMessagesFunc = func(channelID, before string, limit int) ([]discord.Message, error) {
+			return messagePage(1000, before, limit), nil
+		}
+
+		job.Arguments = map[string]any{"type": "searchmessages", "channel_id": "10", "query": "release OUT", "max_scanned": 120}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var messages []discord.Message
+		Expect(json.Unmarshal(res.Data, &messages)).To(Succeed())
+		Expect(messages).To(HaveLen(24))
+		for _, m := range messages {
+			Expect(m.Content).To(ContainSubstring("Release"))
+		}
+		Expect(res.NextCursor).To(Equal("881"))
+
+		// The search stops once it has found enough messages, in the middle of a page
+		job.Arguments = map[string]any{"type": "searchmessages", "channel_id": "10", "query": "release", "author": "@Alice", "max_results": 3}
+		res, err = scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(res.Data, &messages)).To(Succeed())
+		Expect(messages).To(HaveLen(3))
+		Expect(res.NextCursor).To(Equal("990"))
+
+		job.Arguments = map[string]any{"type": "searchmessages", "channel_id": "10", "query": "release", "author": "bob"}
+		res, err = scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(json.Unmarshal(res.Data, &messages)).To(Succeed())
+		Expect(messages).To(BeEmpty())
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.DiscordScannedMessages]
+		}).Should(BeNumerically("==", 120+11+500))
+	})
+
+	It("should refuse to read channels which have no messages", func() {
+		mockClient.GetChannelFunc = func(channelID string) (*discord.Channel, error) {
+			return &discord.Channel{ID: channelID, Type: discord.ChannelVoice}, nil
+		}
+
+		job.Arguments = map[string]any{"type": "getchannelmessages", "channel_id": "11"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).To(HaveOccurred())
+		Expect(res.Error).To(ContainSubstring("only text and announcement channels"))
+	})
+
+	It("should get servers with their channels in order", func() {
+		mockClient.GetGuildFunc = func(guildID string) (*discord.Guild, error) {
+			Expect(guildID).To(Equal("1"))
+			return &discord.Guild{ID: guildID, Name: "Masa", MemberCount: 1200}, nil
+		}
+		mockClient.GetGuildChannelsFunc = func(guildID string) ([]discord.Channel, error) {
+			return []discord.Channel{{ID: "12", Name: "support", Position: 2}, {ID: "10", Name: "announcements", Position: 0}}, nil
+		}
+
+		job.Arguments = map[string]any{"type": "getguildinfo", "guild_id": "1"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var guild discord.Guild
+		Expect(json.Unmarshal(res.Data, &guild)).To(Succeed())
+		Expect(guild.Name).To(Equal("Masa"))
+		Expect(guild.Channels[0].Name).To(Equal("announcements"))
+		Expect(guild.Channels[1].Name).To(Equal("support"))
+	})
+
+	It("should count rate limit errors", func() {
+		mockClient.GetGuildFunc = func(guildID string) (*discord.Guild, error) {
+			return nil, discordapi.ErrRateLimited
+		}
+
+		job.Arguments = map[string]any{"type": "getguildinfo", "guild_id": "1"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).To(MatchError(discordapi.ErrRateLimited))
+		Expect(res.Error).To(ContainSubstring("rate limit exceeded"))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.DiscordRateErrors]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]any) {
+			job.Arguments = args
+			res, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).NotTo(BeEmpty())
+		},
+		Entry("unknown type", map[string]any{"type": "getmembers", "guild_id": "1"}),
+		Entry("missing channel", map[string]any{"type": "getchannelmessages"}),
+		Entry("channel name instead of ID", map[string]any{"type": "getchannelmessages", "channel_id": "#general"}),
+		Entry("missing server", map[string]any{"type": "getguildinfo", "channel_id": "10"}),
+		Entry("search without query or author", map[string]any{"type": "searchmessages", "channel_id": "10"}),
+		Entry("too many results", map[string]any{"type": "getchannelmessages", "channel_id": "10", "max_results": 1001}),
+		Entry("too many scanned messages", map[string]any{"type": "searchmessages", "channel_id": "10", "query": "gm", "max_scanned": 5001}),
+		Entry("invalid cursor", map[string]any{"type": "getchannelmessages", "channel_id": "10", "next_cursor": "abc"}),
+	)
+})
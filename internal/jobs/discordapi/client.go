@@ -0,0 +1,306 @@
+package discordapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/discord"
+	"github.com/masa-finance/tee-worker/pkg/retry"
+)
+
+// DefaultBaseURL is the base URL of the Discord API
+const DefaultBaseURL = "https://discord.com/api/v10"
+
+// MaxPageSize is the maximum number of messages returned by the Discord API in a single request
+const MaxPageSize = 100
+
+// defaultMaxRetryWait is the longest wait asked for by a rate limited response which is waited for before retrying
+const defaultMaxRetryWait = 5 * time.Second
+
+// maxAttempts is the number of times a rate limited request is attempted
+const maxAttempts = 3
+
+var (
+	// ErrRateLimited is returned when the bot is rate limited for longer than the client waits
+	ErrRateLimited = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	// ErrNotFound is returned when the channel or server does not exist, or the bot is not a member of the server
+	ErrNotFound = types.NewCodedError(types.ErrorCodeNotFound, "not found")
+	// ErrUnauthorized is returned when the bot token is invalid
+	ErrUnauthorized = types.NewCodedError(types.ErrorCodeAuth, "unauthorized")
+	// ErrForbidden is returned when the bot is not allowed to read the channel or server
+	ErrForbidden = types.NewCodedError(types.ErrorCodeAuth, "missing access")
+)
+
+// Client reads the servers and channels a Discord bot is a member of, through the Discord API
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+	// MaxRetryWait is the longest wait asked for by a rate limited response which is waited for before retrying.
+	// Longer waits fail with ErrRateLimited.
+	MaxRetryWait time.Duration
+	// Context stops the requests and the waits before retrying them once it is done
+	Context context.Context
+}
+
+// NewClient creates a new client for the API at the given base URL, e.g. DefaultBaseURL, authenticated with the bot
+// token
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:      strings.TrimRight(baseURL, "/"),
+		Token:        token,
+		HTTPClient:   &http.Client{Timeout: 30 * time.Second},
+		MaxRetryWait: defaultMaxRetryWait,
+		Context:      context.Background(),
+	}
+}
+
+// user is a user as returned by the Discord API
+type user struct {
+	ID         string  `json:"id"`
+	Username   string  `json:"username"`
+	GlobalName *string `json:"global_name"`
+	Bot        bool    `json:"bot"`
+}
+
+func (u user) convert() discord.User {
+	res := discord.User{ID: u.ID, Username: u.Username, Bot: u.Bot}
+	if u.GlobalName != nil {
+		res.GlobalName = *u.GlobalName
+	}
+	return res
+}
+
+// message is a message as returned by the Discord API
+type message struct {
+	ID              string               `json:"id"`
+	ChannelID       string               `json:"channel_id"`
+	Author          user                 `json:"author"`
+	Content         string               `json:"content"`
+	Timestamp       time.Time            `json:"timestamp"`
+	EditedTimestamp *time.Time           `json:"edited_timestamp"`
+	Pinned          bool                 `json:"pinned"`
+	Mentions        []user               `json:"mentions"`
+	Attachments     []discord.Attachment `json:"attachments"`
+	Embeds          []discord.Embed      `json:"embeds"`
+	Reactions       []struct {
+		Count int `json:"count"`
+		Emoji struct {
+			Name string `json:"name"`
+		} `json:"emoji"`
+	} `json:"reactions"`
+	MessageReference *struct {
+		MessageID string `json:"message_id"`
+	} `json:"message_reference"`
+}
+
+func (m message) convert() discord.Message {
+	res := discord.Message{
+		ID:              m.ID,
+		ChannelID:       m.ChannelID,
+		Author:          m.Author.convert(),
+		Content:         m.Content,
+		Timestamp:       m.Timestamp,
+		EditedTimestamp: m.EditedTimestamp,
+		Pinned:          m.Pinned,
+		Attachments:     m.Attachments,
+		Embeds:          m.Embeds,
+	}
+	for _, u := range m.Mentions {
+		res.Mentions = append(res.Mentions, u.convert())
+	}
+	for _, r := range m.Reactions {
+		res.Reactions = append(res.Reactions, discord.Reaction{Emoji: r.Emoji.Name, Count: r.Count})
+	}
+	if m.MessageReference != nil {
+		res.ReferencedMessageID = m.MessageReference.MessageID
+	}
+	return res
+}
+
+// channel is a channel as returned by the Discord API
+type channel struct {
+	ID       string  `json:"id"`
+	GuildID  string  `json:"guild_id"`
+	Name     string  `json:"name"`
+	Type     int     `json:"type"`
+	Topic    *string `json:"topic"`
+	ParentID *string `json:"parent_id"`
+	Position int     `json:"position"`
+	NSFW     bool    `json:"nsfw"`
+}
+
+// channelTypes maps the types of channels of the Discord API to their names in results
+var channelTypes = map[int]string{
+	0:  discord.ChannelText,
+	2:  discord.ChannelVoice,
+	4:  discord.ChannelCategory,
+	5:  discord.ChannelAnnouncement,
+	13: discord.ChannelStage,
+	15: discord.ChannelForum,
+	16: discord.ChannelMedia,
+}
+
+func (c channel) convert() discord.Channel {
+	res := discord.Channel{ID: c.ID, GuildID: c.GuildID, Name: c.Name, Type: discord.ChannelOther, Position: c.Position, NSFW: c.NSFW}
+	if t, ok := channelTypes[c.Type]; ok {
+		res.Type = t
+	}
+	if c.Topic != nil {
+		res.Topic = *c.Topic
+	}
+	if c.ParentID != nil {
+		res.ParentID = *c.ParentID
+	}
+	return res
+}
+
+// GetChannel returns a channel
+func (c *Client) GetChannel(channelID string) (*discord.Channel, error) {
+	var ch channel
+	if err := c.get("/channels/"+url.PathEscape(channelID), nil, &ch); err != nil {
+		return nil, err
+	}
+	res := ch.convert()
+	return &res, nil
+}
+
+// GetChannelMessages returns up to limit messages of a channel sent before the message with the given ID, or the
+// latest messages if before is empty, newest first. The bot needs the permission to read the message history of the
+// channel.
+func (c *Client) GetChannelMessages(channelID, before string, limit int) ([]discord.Message, error) {
+	params := url.Values{}
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	params.Set("limit", strconv.Itoa(limit))
+	if before != "" {
+		params.Set("before", before)
+	}
+
+	var messages []message
+	if err := c.get("/channels/"+url.PathEscape(channelID)+"/messages", params, &messages); err != nil {
+		return nil, err
+	}
+	res := make([]discord.Message, len(messages))
+	for i, m := range messages {
+		res[i] = m.convert()
+	}
+	return res, nil
+}
+
+// GetGuild returns a server with its approximate member counts, without its channels
+func (c *Client) GetGuild(guildID string) (*discord.Guild, error) {
+	var g struct {
+		ID                       string   `json:"id"`
+		Name                     string   `json:"name"`
+		Description              *string  `json:"description"`
+		Icon                     *string  `json:"icon"`
+		ApproximateMemberCount   int      `json:"approximate_member_count"`
+		ApproximatePresenceCount int      `json:"approximate_presence_count"`
+		Features                 []string `json:"features"`
+	}
+	if err := c.get("/guilds/"+url.PathEscape(guildID), url.Values{"with_counts": {"true"}}, &g); err != nil {
+		return nil, err
+	}
+
+	res := &discord.Guild{
+		ID:            g.ID,
+		Name:          g.Name,
+		MemberCount:   g.ApproximateMemberCount,
+		PresenceCount: g.ApproximatePresenceCount,
+		Features:      g.Features,
+	}
+	if g.Description != nil {
+		res.Description = *g.Description
+	}
+	if g.Icon != nil {
+		res.IconURL = "https://cdn.discordapp.com/icons/" + g.ID + "/" + *g.Icon + ".png"
+	}
+	if res.Features == nil {
+		res.Features = []string{}
+	}
+	return res, nil
+}
+
+// GetGuildChannels returns the channels of a server the bot can see
+func (c *Client) GetGuildChannels(guildID string) ([]discord.Channel, error) {
+	var channels []channel
+	if err := c.get("/guilds/"+url.PathEscape(guildID)+"/channels", nil, &channels); err != nil {
+		return nil, err
+	}
+	res := make([]discord.Channel, len(channels))
+	for i, ch := range channels {
+		res[i] = ch.convert()
+	}
+	return res, nil
+}
+
+// get queries the API, waiting and retrying if the bot is rate limited for up to MaxRetryWait
+func (c *Client) get(path string, params url.Values, v any) error {
+	return retry.Do(c.Context, retry.Policy{MaxAttempts: maxAttempts}, func() error {
+		wait, err := c.do(path, params, v)
+		if !errors.Is(err, ErrRateLimited) || wait > c.MaxRetryWait {
+			return retry.Permanent(err)
+		}
+		return retry.After(err, wait)
+	})
+}
+
+// do queries the API once. If the bot is rate limited, it returns how long to wait before the next request.
+func (c *Client) do(path string, params url.Values, v any) (time.Duration, error) {
+	u := c.BaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(c.Context, http.MethodGet, u, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bot "+c.Token)
+	// Discord requires bots to identify themselves in this format
+	req.Header.Set("User-Agent", "DiscordBot (https://github.com/masa-finance/tee-worker, 1.0)")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("error querying %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, fmt.Errorf("error reading response from %s: %w", c.BaseURL, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		var limited struct {
+			RetryAfter float64 `json:"retry_after"`
+		}
+		_ = json.Unmarshal(body, &limited)
+		return time.Duration(limited.RetryAfter * float64(time.Second)), ErrRateLimited
+	case http.StatusUnauthorized:
+		return 0, ErrUnauthorized
+	case http.StatusForbidden:
+		return 0, ErrForbidden
+	case http.StatusNotFound:
+		return 0, ErrNotFound
+	default:
+		return 0, fmt.Errorf("%s returned status code %d: %s", c.BaseURL, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return 0, fmt.Errorf("error parsing response from %s: %w", c.BaseURL, err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,145 @@
+package discordapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types/discord"
+	"github.com/masa-finance/tee-worker/internal/jobs/discordapi"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server  *httptest.Server
+		handler http.HandlerFunc
+		c       *discordapi.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("Bot bot-token"))
+			Expect(r.Header.Get("User-Agent")).To(HavePrefix("DiscordBot "))
+			handler(w, r)
+		}))
+		c = discordapi.NewClient(server.URL+"/", "bot-token")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should get channels", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/channels/10"))
+			_, _ = w.Write([]byte(`{"id":"10","guild_id":"1","name":"announcements","type":5,"topic":null,"position":2}`))
+		}
+
+		ch, err := c.GetChannel("10")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ch.Name).To(Equal("announcements"))
+		Expect(ch.Type).To(Equal(discord.ChannelAnnouncement))
+		Expect(ch.Readable()).To(BeTrue())
+		Expect(ch.GuildID).To(Equal("1"))
+	})
+
+	It("should list the messages of a channel", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/channels/10/messages"))
+			Expect(r.URL.Query().Get("limit")).To(Equal("50"))
+			Expect(r.URL.Query().Get("before")).To(Equal("500"))
+			_, _ = w.Write([]byte(`[
+				{"id":"499","channel_id":"10","author":{"id":"7","username":"alice","global_name":"Alice"},"content":"gm","timestamp":"2025-01-02T00:00:00+00:00",
+				 "reactions":[{"count":3,"emoji":{"id":null,"name":"🔥"}}],"message_reference":{"message_id":"400"},
+				 "embeds":[{"type":"link","title":"Release notes","url":"https://example.com"}]},
+				{"id":"498","channel_id":"10","author":{"id":"8","username":"bot","global_name":null,"bot":true},"content":"hi","timestamp":"2025-01-01T00:00:00+00:00","edited_timestamp":null}
+			]`))
+		}
+
+		messages, err := c.GetChannelMessages("10", "500", 50)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(messages).To(HaveLen(2))
+		Expect(messages[0].Author.GlobalName).To(Equal("Alice"))
+		Expect(messages[0].Reactions).To(Equal([]discord.Reaction{{Emoji: "🔥", Count: 3}}))
+		Expect(messages[0].ReferencedMessageID).To(Equal("400"))
+		Expect(messages[0].Embeds[0].Title).To(Equal("Release notes"))
+		Expect(messages[1].Author.Bot).To(BeTrue())
+		Expect(messages[1].EditedTimestamp).To(BeNil())
+	})
+
+	It("should get servers with their member counts and channels", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/guilds/1":
+				Expect(r.URL.Query().Get("with_counts")).To(Equal("true"))
+				_, _ = w.Write([]byte(`{"id":"1","name":"Masa","icon":"abc","approximate_member_count":1200,"approximate_presence_count":300,"features":["COMMUNITY"]}`))
+			case "/guilds/1/channels":
+				_, _ = w.Write([]byte(`[{"id":"10","name":"general","type":0},{"id":"11","name":"voice","type":2},{"id":"12","name":"thread","type":11}]`))
+			default:
+				Fail("unexpected path " + r.URL.Path)
+			}
+		}
+
+		guild, err := c.GetGuild("1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(guild.Name).To(Equal("Masa"))
+		Expect(guild.MemberCount).To(Equal(1200))
+		Expect(guild.IconURL).To(Equal("https://cdn.discordapp.com/icons/1/abc.png"))
+
+		channels, err := c.GetGuildChannels("1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(channels).To(HaveLen(3))
+		Expect(channels[0].Type).To(Equal(discord.ChannelText))
+		Expect(channels[1].Readable()).To(BeFalse())
+		Expect(channels[2].Type).To(Equal(discord.ChannelOther))
+	})
+
+	It("should wait and retry when briefly rate limited", func() {
+		requests := 0
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			if requests == 1 {
+				w.WriteHeader(http.StatusTooManyRequests)
+				_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":0.01,"global":false}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"10","name":"general","type":0}`))
+		}
+
+		ch, err := c.GetChannel("10")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ch.Name).To(Equal("general"))
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should not wait for long rate limits", func() {
+		c.MaxRetryWait = time.Second
+		requests := 0
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"message":"You are being rate limited.","retry_after":60,"global":true}`))
+		}
+
+		_, err := c.GetChannel("10")
+		Expect(err).To(MatchError(discordapi.ErrRateLimited))
+		Expect(requests).To(Equal(1))
+	})
+
+	DescribeTable("should map error responses",
+		func(status int, body string, expected error) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(body))
+			}
+			_, err := c.GetChannelMessages("10", "", 100)
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("missing access", http.StatusForbidden, `{"message":"Missing Access","code":50001}`, discordapi.ErrForbidden),
+		Entry("unknown channel", http.StatusNotFound, `{"message":"Unknown Channel","code":10003}`, discordapi.ErrNotFound),
+		Entry("invalid token", http.StatusUnauthorized, `{"message":"401: Unauthorized","code":0}`, discordapi.ErrUnauthorized),
+	)
+})
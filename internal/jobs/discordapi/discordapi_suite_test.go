@@ -0,0 +1,13 @@
+package discordapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiscordAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Discord API Client Suite")
+}
@@ -0,0 +1,354 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/github"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/githubapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// GitHubJob scrapes public data from GitHub. It is not part of tee-types yet, so its arguments are validated here.
+const GitHubJob teetypes.JobType = "github"
+
+// Capabilities of the github job type
+const (
+	// CapGetRepo fetches the metadata of a repository
+	CapGetRepo teetypes.Capability = "getrepo"
+	// CapGetIssues lists the issues of a repository, without its pull requests
+	CapGetIssues teetypes.Capability = "getissues"
+	// CapGetPullRequests lists the pull requests of a repository
+	CapGetPullRequests teetypes.Capability = "getpullrequests"
+	// CapSearchCode searches code, which the GitHub API only allows with a token
+	CapSearchCode teetypes.Capability = "searchcode"
+)
+
+// GitHubCaps are the capabilities of the github job type which don't need a token
+var GitHubCaps = []teetypes.Capability{CapGetRepo, CapGetIssues, CapGetPullRequests, teetypes.CapGetProfile}
+
+const (
+	defaultGitHubMaxResults = 30
+	maxGitHubMaxResults     = 500
+
+	// The GitHub API allows 60 requests per hour and IP address without a token, 5000 per hour with a token, and 10
+	// code searches per minute
+	githubRateLimitRequests            = 60
+	githubTokenRateLimitRequests       = 5000
+	githubRateLimitWindowSeconds       = 60 * 60
+	githubSearchRateLimitRequests      = 10
+	githubSearchRateLimitWindowSeconds = 60
+)
+
+// githubStates are the states of the issues and pull requests which can be listed
+var githubStates = []string{"open", "closed", "all"}
+
+// GitHubClient defines the interface for the GitHub client. This allows for mocking in tests.
+type GitHubClient interface {
+	GetRepository(owner, repo string) (*github.Repository, error)
+	ListIssues(owner, repo, state string, perPage, page int) ([]github.Issue, error)
+	ListPullRequests(owner, repo, state string, perPage, page int) ([]github.Issue, error)
+	GetUser(login string) (*github.User, error)
+	SearchCode(query string, perPage, page int) ([]github.CodeResult, int, error)
+}
+
+// NewGitHubClient is a function variable that can be replaced in tests.
+// It defaults to the actual implementation.
+var NewGitHubClient = func(cfg config.GitHubConfig, network types.Network) GitHubClient {
+	c := githubapi.NewClient(cfg.BaseURL, cfg.Token)
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// GitHubArguments are the arguments of a github job
+type GitHubArguments struct {
+	QueryType teetypes.Capability `json:"type"`
+	// Query is the repository (owner/repo or its URL), the user or the code search query, depending on the query type
+	Query string `json:"query"`
+	// State of the issues and pull requests to list: open, closed or all
+	State      string `json:"state"`
+	MaxResults int    `json:"max_results"`
+	NextCursor string `json:"next_cursor"`
+
+	// owner and repo are parsed from the query of the repository query types
+	owner, repo string
+	// offset is parsed from NextCursor
+	offset int
+}
+
+type GitHubScraper struct {
+	configuration  config.GitHubConfig
+	statsCollector *stats.StatsCollector
+}
+
+func NewGitHubScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *GitHubScraper {
+	config := jc.GetGitHubConfig()
+	logrus.Infof("GitHub scraper initialized with API %s, token configured: %t", config.BaseURL, config.Token != "")
+	return &GitHubScraper{
+		configuration:  config,
+		statsCollector: statsCollector,
+	}
+}
+
+// capabilities returns the capabilities available with the configuration, code search only with a token
+func (gs *GitHubScraper) capabilities() []teetypes.Capability {
+	if gs.configuration.Token == "" {
+		return GitHubCaps
+	}
+	return append(slices.Clone(GitHubCaps), CapSearchCode)
+}
+
+// GetStructuredCapabilities returns the capabilities of the GitHub scraper
+func (gs *GitHubScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{GitHubJob: gs.capabilities()}
+}
+
+// GetCapabilityDetails returns the auth source of each capability, the API for a token, and the rate limits of the
+// GitHub API
+func (gs *GitHubScraper) GetCapabilityDetails() types.CapabilityDetails {
+	source, requests := types.AuthSourceNone, githubRateLimitRequests
+	if gs.configuration.Token != "" {
+		source, requests = types.AuthSourceAPI, githubTokenRateLimitRequests
+	}
+	details := types.NewCapabilityDetails(gs.GetStructuredCapabilities(), source)
+	for i, detail := range details[GitHubJob] {
+		limit := &types.RateLimitEstimate{Requests: requests, WindowSeconds: githubRateLimitWindowSeconds}
+		if detail.Capability == CapSearchCode {
+			limit = &types.RateLimitEstimate{Requests: githubSearchRateLimitRequests, WindowSeconds: githubSearchRateLimitWindowSeconds}
+		}
+		details[GitHubJob][i].RateLimit = limit
+	}
+	return details
+}
+
+// parseArguments unmarshals and validates the arguments of a github job
+func (gs *GitHubScraper) parseArguments(args types.JobArguments) (*GitHubArguments, error) {
+	parsed := &GitHubArguments{}
+	if err := args.Unmarshal(parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal github arguments: %w", err)
+	}
+
+	parsed.QueryType = teetypes.Capability(strings.ToLower(string(parsed.QueryType)))
+	if parsed.QueryType == teetypes.CapEmpty {
+		parsed.QueryType = CapGetRepo
+	}
+	if caps := gs.capabilities(); !slices.Contains(caps, parsed.QueryType) {
+		if parsed.QueryType == CapSearchCode {
+			return nil, errors.New("searchcode requires a GitHub token")
+		}
+		return nil, fmt.Errorf("invalid type %q for github job, valid types are %v", parsed.QueryType, caps)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if parsed.Query == "" {
+		return nil, errors.New("query is required")
+	}
+
+	switch parsed.QueryType {
+	case CapGetRepo, CapGetIssues, CapGetPullRequests:
+		owner, repo, err := parseGitHubRepository(parsed.Query)
+		if err != nil {
+			return nil, err
+		}
+		parsed.owner, parsed.repo = owner, repo
+	case teetypes.CapGetProfile:
+		parsed.Query = strings.TrimPrefix(parsed.Query, "@")
+	}
+
+	parsed.State = strings.ToLower(strings.TrimSpace(parsed.State))
+	if parsed.State == "" {
+		parsed.State = "open"
+	}
+	if !slices.Contains(githubStates, parsed.State) {
+		return nil, fmt.Errorf("state must be one of %v, got %q", githubStates, parsed.State)
+	}
+
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultGitHubMaxResults
+	}
+	if parsed.MaxResults < 0 || parsed.MaxResults > maxGitHubMaxResults {
+		return nil, fmt.Errorf("max_results must be between 1 and %d, got %d", maxGitHubMaxResults, parsed.MaxResults)
+	}
+
+	if parsed.NextCursor != "" {
+		offset, err := strconv.Atoi(parsed.NextCursor)
+		if err != nil || offset < 0 {
+			return nil, fmt.Errorf("invalid next_cursor %q", parsed.NextCursor)
+		}
+		parsed.offset = offset
+	}
+
+	return parsed, nil
+}
+
+// parseGitHubRepository returns the owner and name of a repository given as owner/repo or as its URL
+func parseGitHubRepository(query string) (string, string, error) {
+	path := query
+	if i := strings.Index(path, "github.com/"); i >= 0 {
+		path = path[i+len("github.com/"):]
+	}
+	path = strings.TrimSuffix(strings.Trim(path, "/"), ".git")
+	owner, repo, ok := strings.Cut(path, "/")
+	if !ok || owner == "" || repo == "" || strings.Contains(repo, "/") {
+		return "", "", fmt.Errorf("query must be a repository in owner/repo format, got %q", query)
+	}
+	return owner, repo, nil
+}
+
+// addStat adds to a statistic, broken down by the capability of the job if enabled
+func (gs *GitHubScraper) addStat(j types.Job, typ stats.StatType, num uint) {
+	gs.statsCollector.AddWithDimensions(j.WorkerID, typ, num, stats.DimensionsForJob(j))
+}
+
+func (gs *GitHubScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for GitHub scrape")
+
+	args, err := gs.parseArguments(j.Arguments)
+	if err != nil {
+		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+	logrus.Debugf("github job args: %+v", *args)
+
+	client := NewGitHubClient(gs.configuration, j.Network)
+
+	var (
+		data       any
+		nextCursor string
+		total      int
+	)
+	switch args.QueryType {
+	case CapGetRepo:
+		gs.addStat(j, stats.GitHubQueries, 1)
+		var repo *github.Repository
+		repo, err = client.GetRepository(args.owner, args.repo)
+		if err == nil {
+			gs.addStat(j, stats.GitHubRepos, 1)
+			data = repo
+		}
+
+	case CapGetIssues:
+		var issues []github.Issue
+		issues, nextCursor, err = fetchGitHubPages(gs, j, args, func(perPage, page int) ([]github.Issue, error) {
+			return client.ListIssues(args.owner, args.repo, args.State, perPage, page)
+		}, func(is github.Issue) bool { return !is.PullRequest }, 0)
+		if err == nil {
+			gs.addStat(j, stats.GitHubIssues, uint(len(issues)))
+			data = issues
+		}
+
+	case CapGetPullRequests:
+		var pulls []github.Issue
+		pulls, nextCursor, err = fetchGitHubPages(gs, j, args, func(perPage, page int) ([]github.Issue, error) {
+			return client.ListPullRequests(args.owner, args.repo, args.State, perPage, page)
+		}, nil, 0)
+		if err == nil {
+			gs.addStat(j, stats.GitHubPullRequests, uint(len(pulls)))
+			data = pulls
+		}
+
+	case teetypes.CapGetProfile:
+		gs.addStat(j, stats.GitHubQueries, 1)
+		var user *github.User
+		user, err = client.GetUser(args.Query)
+		if err == nil {
+			gs.addStat(j, stats.GitHubProfiles, 1)
+			data = user
+		}
+
+	case CapSearchCode:
+		var results []github.CodeResult
+		results, nextCursor, err = fetchGitHubPages(gs, j, args, func(perPage, page int) ([]github.CodeResult, error) {
+			var res []github.CodeResult
+			var err error
+			res, total, err = client.SearchCode(args.Query, perPage, page)
+			return res, err
+		}, nil, githubapi.MaxSearchResults)
+		if err == nil {
+			gs.addStat(j, stats.GitHubCodeResults, uint(len(results)))
+			data = results
+		}
+	}
+
+	if err != nil {
+		gs.handleError(j, err)
+		return types.JobResult{Error: fmt.Sprintf("error while scraping GitHub: %s", err.Error())}, fmt.Errorf("error scraping GitHub: %w", err)
+	}
+
+	res, err := pageResult(data, nextCursor)
+	if total > 0 {
+		total = min(total, githubapi.MaxSearchResults)
+		res.TotalEstimate = &total
+	}
+	res.Job = j
+	return res, err
+}
+
+// fetchGitHubPages pages through the items returned by fetch, starting at the offset of the cursor, until MaxResults
+// items have been collected. Only the items for which keep returns true are collected, if it is set, and only the first
+// limit items are looked at, if it is not 0. The next cursor is the offset of the first item which was not looked at,
+// and is only set if there may be more items.
+func fetchGitHubPages[T any](gs *GitHubScraper, j types.Job, args *GitHubArguments, fetch func(perPage, page int) ([]T, error), keep func(T) bool, limit int) ([]T, string, error) {
+	items := make([]T, 0, args.MaxResults)
+	offset := args.offset
+
+	// Pages have a fixed size, so the page of any offset can be computed
+	for len(items) < args.MaxResults {
+		if limit > 0 && offset >= limit {
+			offset = -1
+			break
+		}
+		gs.addStat(j, stats.GitHubQueries, 1)
+		page, err := fetch(githubapi.MaxPageSize, offset/githubapi.MaxPageSize+1)
+		if errors.Is(err, bandwidth.ErrCapExceeded) && len(items) > 0 {
+			// Return the items collected so far, the next cursor continues after them
+			logrus.Warnf("Bandwidth cap of job %s exceeded, returning %d items", j.UUID, len(items))
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		skip := offset % githubapi.MaxPageSize
+		if skip >= len(page) {
+			offset = -1
+			break
+		}
+		for _, item := range page[skip:] {
+			if len(items) == args.MaxResults {
+				break
+			}
+			offset++
+			if keep == nil || keep(item) {
+				items = append(items, item)
+			}
+		}
+		if len(page) < githubapi.MaxPageSize && offset%githubapi.MaxPageSize >= len(page) {
+			offset = -1
+			break
+		}
+	}
+
+	if offset < 0 {
+		return items, "", nil
+	}
+	return items, strconv.Itoa(offset), nil
+}
+
+func (gs *GitHubScraper) handleError(j types.Job, err error) {
+	if errors.Is(err, githubapi.ErrRateLimited) {
+		gs.addStat(j, stats.GitHubRateErrors, 1)
+		logrus.Warnf("Rate limited by GitHub, token configured: %t", gs.configuration.Token != "")
+		return
+	}
+	gs.addStat(j, stats.GitHubErrors, 1)
+}
@@ -0,0 +1,254 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/github"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/githubapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// MockGitHubClient is a mock implementation of the GitHubClient.
+type MockGitHubClient struct {
+	GetRepositoryFunc    func(owner, repo string) (*github.Repository, error)
+	ListIssuesFunc       func(owner, repo, state string, perPage, page int) ([]github.Issue, error)
+	ListPullRequestsFunc func(owner, repo, state string, perPage, page int) ([]github.Issue, error)
+	GetUserFunc          func(login string) (*github.User, error)
+	SearchCodeFunc       func(query string, perPage, page int) ([]github.CodeResult, int, error)
+}
+
+func (m *MockGitHubClient) GetRepository(owner, repo string) (*github.Repository, error) {
+	return m.GetRepositoryFunc(owner, repo)
+}
+
+func (m *MockGitHubClient) ListIssues(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+	return m.ListIssuesFunc(owner, repo, state, perPage, page)
+}
+
+func (m *MockGitHubClient) ListPullRequests(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+	return m.ListPullRequestsFunc(owner, repo, state, perPage, page)
+}
+
+func (m *MockGitHubClient) GetUser(login string) (*github.User, error) {
+	return m.GetUserFunc(login)
+}
+
+func (m *MockGitHubClient) SearchCode(query string, perPage, page int) ([]github.CodeResult, int, error) {
+	return m.SearchCodeFunc(query, perPage, page)
+}
+
+// issuePage returns the page of a list of total issues numbered from total down to 1, every third being a pull request
+func issuePage(total, perPage, page int) []github.Issue {
+	var issues []github.Issue
+	for i := (page - 1) * perPage; i < page*perPage && i < total; i++ {
+		issues = append(issues, github.Issue{Number: total - i, PullRequest: i%3 == 2})
+	}
+	return issues
+}
+
+var _ = Describe("GitHubScraper", func() {
+	var (
+		scraper        *jobs.GitHubScraper
+		statsCollector *stats.StatsCollector
+		mockClient     *MockGitHubClient
+		clientConfig   config.GitHubConfig
+		job            types.Job
+	)
+
+	newScraper := func(token string) *jobs.GitHubScraper {
+		return jobs.NewGitHubScraper(config.JobConfiguration{"github_token": token}, statsCollector)
+	}
+
+	BeforeEach(func() {
+		statsCollector = stats.StartCollector(128, config.JobConfiguration{})
+		scraper = newScraper("")
+
+		mockClient = &MockGitHubClient{}
+		clientConfig = config.GitHubConfig{}
+		original := jobs.NewGitHubClient
+		DeferCleanup(func() { jobs.NewGitHubClient = original })
+		jobs.NewGitHubClient = func(cfg config.GitHubConfig, _ types.Network) jobs.GitHubClient {
+			clientConfig = cfg
+			return mockClient
+		}
+
+		job = types.Job{
+			UUID:     "test-uuid",
+			Type:     jobs.GitHubJob,
+			WorkerID: "github-test",
+		}
+	})
+
+	It("should only advertise code search with a token", func() {
+		Expect(scraper.GetStructuredCapabilities()[jobs.GitHubJob]).To(ConsistOf(jobs.GitHubCaps))
+		details := scraper.GetCapabilityDetails()[jobs.GitHubJob]
+		Expect(details[0].AuthSource).To(Equal(types.AuthSourceNone))
+		Expect(details[0].RateLimit.Requests).To(Equal(60))
+
+		scraper = newScraper("ghp_token")
+		Expect(scraper.GetStructuredCapabilities()[jobs.GitHubJob]).To(ContainElement(jobs.CapSearchCode))
+		for _, detail := range scraper.GetCapabilityDetails()[jobs.GitHubJob] {
+			Expect(detail.AuthSource).To(Equal(types.AuthSourceAPI))
+			if detail.Capability == jobs.CapSearchCode {
+				Expect(detail.RateLimit.Requests).To(Equal(10))
+			} else {
+				Expect(detail.RateLimit.Requests).To(Equal(5000))
+			}
+		}
+	})
+
+	It("should get repositories given as URLs", func() {
+		mockClient.GetRepositoryFunc = func(owner, repo string) (*github.Repository, error) {
+			Expect(owner).To(Equal("masa-finance"))
+			Expect(repo).To(Equal("tee-worker"))
+			return &github.Repository{FullName: owner + "/" + repo, StargazersCount: 42}, nil
+		}
+
+		job.Arguments = map[string]any{"type": "getrepo", "query": "https://github.com/masa-finance/tee-worker.git"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientConfig.BaseURL).To(Equal(githubapi.DefaultBaseURL))
+
+		var repo github.Repository
+		Expect(json.Unmarshal(res.Data, &repo)).To(Succeed())
+		Expect(repo.FullName).To(Equal("masa-finance/tee-worker"))
+		Expect(res.HasMore).To(BeFalse())
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.GitHubRepos]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	It("should list issues without pull requests across pages", func() {
+		var pages []int
+		mockClient.ListIssuesFunc = func(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+			Expect(state).To(Equal("closed"))
+			pages = append(pages, page)
+			return issuePage(250, perPage, page), nil
+		}
+
+		job.Arguments = map[string]any{"type": "getissues", "query": "masa-finance/tee-worker", "state": "closed", "max_results": 80}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]int{1, 2}))
+
+		var issues []github.Issue
+		Expect(json.Unmarshal(res.Data, &issues)).To(Succeed())
+		Expect(issues).To(HaveLen(80))
+		for _, is := range issues {
+			Expect(is.PullRequest).To(BeFalse())
+		}
+		// 80 issues are the first 120 items, two out of three being issues
+		Expect(res.NextCursor).To(Equal("119"))
+		Expect(res.HasMore).To(BeTrue())
+
+		// The next job continues in the middle of the second page
+		pages = nil
+		job.Arguments = map[string]any{"type": "getissues", "query": "masa-finance/tee-worker", "state": "closed", "max_results": 500, "next_cursor": res.NextCursor}
+		res, err = scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]int{2, 3}))
+
+		var rest []github.Issue
+		Expect(json.Unmarshal(res.Data, &rest)).To(Succeed())
+		Expect(rest[0].Number).To(Equal(issues[79].Number - 2))
+		Expect(len(issues) + len(rest)).To(Equal(250 - 250/3))
+		Expect(res.HasMore).To(BeFalse())
+	})
+
+	It("should return the issues collected so far when the bandwidth cap is exceeded", func() {
+		mockClient.ListPullRequestsFunc = func(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+			if page > 1 {
+				return nil, bandwidth.ErrCapExceeded
+			}
+			return issuePage(250, perPage, page), nil
+		}
+
+		job.Arguments = map[string]any{"type": "getpullrequests", "query": "masa-finance/tee-worker", "max_results": 200}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var pulls []github.Issue
+		Expect(json.Unmarshal(res.Data, &pulls)).To(Succeed())
+		Expect(pulls).To(HaveLen(githubapi.MaxPageSize))
+		Expect(res.NextCursor).To(Equal(fmt.Sprint(githubapi.MaxPageSize)))
+	})
+
+	It("should get profiles", func() {
+		mockClient.GetUserFunc = func(login string) (*github.User, error) {
+			Expect(login).To(Equal("octocat"))
+			return &github.User{Login: login, Followers: 42}, nil
+		}
+
+		job.Arguments = map[string]any{"type": string(teetypes.CapGetProfile), "query": "@octocat"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var user github.User
+		Expect(json.Unmarshal(res.Data, &user)).To(Succeed())
+		Expect(user.Followers).To(Equal(42))
+	})
+
+	It("should search code with a token and stop at the search limit", func() {
+		scraper = newScraper("ghp_token")
+		mockClient.SearchCodeFunc = func(query string, perPage, page int) ([]github.CodeResult, int, error) {
+			Expect(page).To(Equal(10))
+			results := make([]github.CodeResult, perPage)
+			for i := range results {
+				results[i] = github.CodeResult{Path: fmt.Sprintf("file%d.go", i)}
+			}
+			return results, 5000, nil
+		}
+
+		job.Arguments = map[string]any{"type": "searchcode", "query": "ReadConfig", "max_results": 500, "next_cursor": "900"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientConfig.Token).To(Equal("ghp_token"))
+
+		var results []github.CodeResult
+		Expect(json.Unmarshal(res.Data, &results)).To(Succeed())
+		Expect(results).To(HaveLen(100))
+		Expect(res.HasMore).To(BeFalse())
+		Expect(*res.TotalEstimate).To(Equal(githubapi.MaxSearchResults))
+	})
+
+	It("should count rate limit errors", func() {
+		mockClient.GetUserFunc = func(login string) (*github.User, error) {
+			return nil, githubapi.ErrRateLimited
+		}
+
+		job.Arguments = map[string]any{"type": string(teetypes.CapGetProfile), "query": "octocat"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).To(MatchError(githubapi.ErrRateLimited))
+		Expect(res.Error).To(ContainSubstring("rate limit exceeded"))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.GitHubRateErrors]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]any) {
+			job.Arguments = args
+			res, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).NotTo(BeEmpty())
+		},
+		Entry("unknown type", map[string]any{"type": "getstars", "query": "octocat"}),
+		Entry("code search without a token", map[string]any{"type": "searchcode", "query": "ReadConfig"}),
+		Entry("missing query", map[string]any{"type": "getrepo"}),
+		Entry("repository without owner", map[string]any{"type": "getissues", "query": "tee-worker"}),
+		Entry("invalid state", map[string]any{"type": "getissues", "query": "masa-finance/tee-worker", "state": "merged"}),
+		Entry("too many results", map[string]any{"type": "getissues", "query": "masa-finance/tee-worker", "max_results": 501}),
+		Entry("invalid cursor", map[string]any{"type": "getissues", "query": "masa-finance/tee-worker", "next_cursor": "abc"}),
+	)
+})
@@ -0,0 +1,216 @@
+package githubapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/github"
+)
+
+// DefaultBaseURL is the base URL of the GitHub REST API
+const DefaultBaseURL = "https://api.github.com"
+
+// MaxPageSize is the maximum number of items returned by the GitHub API in a single request
+const MaxPageSize = 100
+
+// MaxSearchResults is the number of results of a search the GitHub API returns at most, whatever the page
+const MaxSearchResults = 1000
+
+var (
+	// ErrRateLimited is returned when the rate limit of the token, or of the IP address without a token, is exhausted
+	ErrRateLimited = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	// ErrNotFound is returned when the repository or user does not exist, or is private
+	ErrNotFound = types.NewCodedError(types.ErrorCodeNotFound, "not found")
+	// ErrUnauthorized is returned when the token is invalid, or the endpoint requires one, like code search
+	ErrUnauthorized = types.NewCodedError(types.ErrorCodeAuth, "unauthorized")
+)
+
+// Client queries the public data of the GitHub REST API. A token is optional, and raises the rate limit from 60 to
+// 5000 requests per hour.
+type Client struct {
+	BaseURL    string
+	Token      string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new client for the API at the given base URL, e.g. DefaultBaseURL, authenticated with the token
+// if it is not empty
+func NewClient(baseURL, token string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		Token:      token,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetRepository returns the metadata of a repository
+func (c *Client) GetRepository(owner, repo string) (*github.Repository, error) {
+	var repository github.Repository
+	if err := c.get(repoPath(owner, repo), nil, "", &repository); err != nil {
+		return nil, err
+	}
+	return &repository, nil
+}
+
+// issue is an issue or a pull request as returned by the issues and pulls endpoints
+type issue struct {
+	github.Issue
+	PullRequest *struct {
+		MergedAt *time.Time `json:"merged_at"`
+	} `json:"pull_request"`
+}
+
+// ListIssues returns a page of the issues of a repository in the given state (open, closed or all), newest first.
+// The GitHub API lists pull requests as issues too, they have PullRequest set.
+func (c *Client) ListIssues(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+	params := pageParams(perPage, page)
+	params.Set("state", state)
+
+	var issues []issue
+	if err := c.get(repoPath(owner, repo)+"/issues", params, "", &issues); err != nil {
+		return nil, err
+	}
+	res := make([]github.Issue, len(issues))
+	for i, is := range issues {
+		res[i] = is.Issue
+		if is.PullRequest != nil {
+			res[i].PullRequest = true
+			res[i].MergedAt = is.PullRequest.MergedAt
+			res[i].Merged = is.PullRequest.MergedAt != nil
+		}
+	}
+	return res, nil
+}
+
+// ListPullRequests returns a page of the pull requests of a repository in the given state (open, closed or all),
+// newest first
+func (c *Client) ListPullRequests(owner, repo, state string, perPage, page int) ([]github.Issue, error) {
+	params := pageParams(perPage, page)
+	params.Set("state", state)
+
+	var pulls []github.Issue
+	if err := c.get(repoPath(owner, repo)+"/pulls", params, "", &pulls); err != nil {
+		return nil, err
+	}
+	for i := range pulls {
+		pulls[i].PullRequest = true
+		pulls[i].Merged = pulls[i].MergedAt != nil
+	}
+	return pulls, nil
+}
+
+// GetUser returns the profile of a user or organization
+func (c *Client) GetUser(login string) (*github.User, error) {
+	var user github.User
+	if err := c.get("/users/"+url.PathEscape(login), nil, "", &user); err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
+// SearchCode returns a page of the files matching a code search query, e.g. "ReadConfig repo:masa-finance/tee-worker",
+// with the matching fragments, and the total number of results. The GitHub API only searches code with a token.
+func (c *Client) SearchCode(query string, perPage, page int) ([]github.CodeResult, int, error) {
+	params := pageParams(perPage, page)
+	params.Set("q", query)
+
+	var result struct {
+		TotalCount int `json:"total_count"`
+		Items      []struct {
+			github.CodeResult
+			TextMatches []struct {
+				Fragment string `json:"fragment"`
+			} `json:"text_matches"`
+		} `json:"items"`
+	}
+	// The text-match media type adds the matching fragments to the results
+	if err := c.get("/search/code", params, "application/vnd.github.text-match+json", &result); err != nil {
+		return nil, 0, err
+	}
+	res := make([]github.CodeResult, len(result.Items))
+	for i, item := range result.Items {
+		res[i] = item.CodeResult
+		for _, m := range item.TextMatches {
+			res[i].Fragments = append(res[i].Fragments, m.Fragment)
+		}
+	}
+	return res, result.TotalCount, nil
+}
+
+func repoPath(owner, repo string) string {
+	return "/repos/" + url.PathEscape(owner) + "/" + url.PathEscape(repo)
+}
+
+func pageParams(perPage, page int) url.Values {
+	params := url.Values{}
+	if perPage <= 0 || perPage > MaxPageSize {
+		perPage = MaxPageSize
+	}
+	params.Set("per_page", strconv.Itoa(perPage))
+	if page > 1 {
+		params.Set("page", strconv.Itoa(page))
+	}
+	return params
+}
+
+func (c *Client) get(path string, params url.Values, accept string, v any) error {
+	u := c.BaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	if accept == "" {
+		accept = "application/vnd.github+json"
+	}
+	req.Header.Set("Accept", accept)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	// GitHub rejects requests without a User-Agent
+	req.Header.Set("User-Agent", "tee-worker")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %w", c.BaseURL, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusForbidden:
+		// Exhausted primary rate limits have no requests remaining, secondary rate limits say so in the message
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" || strings.Contains(strings.ToLower(string(body)), "rate limit") {
+			return ErrRateLimited
+		}
+		return fmt.Errorf("%s returned status code %d: %s", c.BaseURL, resp.StatusCode, string(body))
+	case http.StatusUnauthorized:
+		return ErrUnauthorized
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("%s returned status code %d: %s", c.BaseURL, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing response from %s: %w", c.BaseURL, err)
+	}
+	return nil
+}
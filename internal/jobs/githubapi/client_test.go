@@ -0,0 +1,119 @@
+package githubapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/githubapi"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server  *httptest.Server
+		handler http.HandlerFunc
+		c       *githubapi.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("User-Agent")).NotTo(BeEmpty())
+			handler(w, r)
+		}))
+		c = githubapi.NewClient(server.URL+"/", "")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should get repositories", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/repos/masa-finance/tee-worker"))
+			Expect(r.Header.Get("Authorization")).To(BeEmpty())
+			_, _ = w.Write([]byte(`{"id":1,"full_name":"masa-finance/tee-worker","owner":{"login":"masa-finance"},"stargazers_count":42,"topics":["tee"]}`))
+		}
+
+		repo, err := c.GetRepository("masa-finance", "tee-worker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.FullName).To(Equal("masa-finance/tee-worker"))
+		Expect(repo.Owner.Login).To(Equal("masa-finance"))
+		Expect(repo.StargazersCount).To(Equal(42))
+	})
+
+	It("should list issues and flag pull requests", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/repos/masa-finance/tee-worker/issues"))
+			Expect(r.URL.Query().Get("state")).To(Equal("closed"))
+			Expect(r.URL.Query().Get("per_page")).To(Equal("10"))
+			Expect(r.URL.Query().Get("page")).To(Equal("3"))
+			_, _ = w.Write([]byte(`[
+				{"number":2,"title":"fix","pull_request":{"merged_at":"2025-01-02T00:00:00Z"}},
+				{"number":1,"title":"bug","user":{"login":"alice"}}
+			]`))
+		}
+
+		issues, err := c.ListIssues("masa-finance", "tee-worker", "closed", 10, 3)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(issues).To(HaveLen(2))
+		Expect(issues[0].PullRequest).To(BeTrue())
+		Expect(issues[0].Merged).To(BeTrue())
+		Expect(issues[1].PullRequest).To(BeFalse())
+		Expect(issues[1].User.Login).To(Equal("alice"))
+	})
+
+	It("should list pull requests", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/repos/masa-finance/tee-worker/pulls"))
+			_, _ = w.Write([]byte(`[{"number":2,"draft":true},{"number":1,"merged_at":"2025-01-02T00:00:00Z"}]`))
+		}
+
+		pulls, err := c.ListPullRequests("masa-finance", "tee-worker", "all", 100, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pulls).To(HaveLen(2))
+		Expect(pulls[0].PullRequest).To(BeTrue())
+		Expect(pulls[0].Draft).To(BeTrue())
+		Expect(pulls[0].Merged).To(BeFalse())
+		Expect(pulls[1].Merged).To(BeTrue())
+	})
+
+	It("should search code with a token", func() {
+		c.Token = "ghp_token"
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/search/code"))
+			Expect(r.URL.Query().Get("q")).To(Equal("ReadConfig repo:masa-finance/tee-worker"))
+			Expect(r.Header.Get("Authorization")).To(Equal("Bearer ghp_token"))
+			Expect(r.Header.Get("Accept")).To(ContainSubstring("text-match"))
+			_, _ = w.Write([]byte(`{"total_count":1,"items":[{"path":"internal/config/config.go","repository":{"full_name":"masa-finance/tee-worker"},"text_matches":[{"fragment":"func ReadConfig()"}]}]}`))
+		}
+
+		results, total, err := c.SearchCode("ReadConfig repo:masa-finance/tee-worker", 30, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(total).To(Equal(1))
+		Expect(results).To(HaveLen(1))
+		Expect(results[0].Path).To(Equal("internal/config/config.go"))
+		Expect(results[0].Repository.FullName).To(Equal("masa-finance/tee-worker"))
+		Expect(results[0].Fragments).To(Equal([]string{"func ReadConfig()"}))
+	})
+
+	DescribeTable("should map error responses",
+		func(status int, header, body string, expected error) {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				if header != "" {
+					w.Header().Set("X-RateLimit-Remaining", header)
+				}
+				w.WriteHeader(status)
+				_, _ = w.Write([]byte(body))
+			}
+			_, err := c.GetUser("alice")
+			Expect(err).To(MatchError(expected))
+		},
+		Entry("exhausted rate limit", http.StatusForbidden, "0", `{"message":"API rate limit exceeded"}`, githubapi.ErrRateLimited),
+		Entry("secondary rate limit", http.StatusForbidden, "", `{"message":"You have exceeded a secondary rate limit"}`, githubapi.ErrRateLimited),
+		Entry("too many requests", http.StatusTooManyRequests, "", "", githubapi.ErrRateLimited),
+		Entry("unknown user", http.StatusNotFound, "", `{"message":"Not Found"}`, githubapi.ErrNotFound),
+		Entry("invalid token", http.StatusUnauthorized, "", `{"message":"Bad credentials"}`, githubapi.ErrUnauthorized),
+	)
+})
@@ -0,0 +1,13 @@
+package githubapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGitHubAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "GitHub API Client Suite")
+}
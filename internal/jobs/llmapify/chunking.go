@@ -0,0 +1,250 @@
+package llmapify
+
+/*
+The LLM processor answers every item of a dataset with the response of the model to the prompt, in which the fields
+of the item are substituted. ProcessInChunks lets it handle datasets of any size: items which don't fit in the context
+window of the model are split into pieces whose responses are merged again, and the items are processed in chunks,
+each in its own run of the processor, a few of them at the same time, until the token budget is spent.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// bytesPerToken is the average number of bytes of a token, used to estimate the number of tokens of a text
+const bytesPerToken = 4
+
+// Defaults of ChunkOptions
+const (
+	DefaultContextTokens    = 32768
+	DefaultChunkItems       = 100
+	DefaultChunkConcurrency = 4
+)
+
+// ErrContextTooSmall is returned when the prompt and the response alone don't fit in the context window
+var ErrContextTooSmall = errors.New("the prompt and max_tokens don't fit in the context window of the model")
+
+// ChunkOptions configure ProcessInChunks
+type ChunkOptions struct {
+	// ContextTokens is the context window of the model, which has to hold the prompt, an item and the response
+	ContextTokens int
+	// ChunkItems is the number of items processed in each run of the LLM processor
+	ChunkItems int
+	// Concurrency is the number of chunks processed at the same time
+	Concurrency int
+	// TokenBudget is the number of tokens, sent and received, the chunks may use, or 0 for no limit. The chunks
+	// which would exceed it are not processed.
+	TokenBudget int
+	// OnChunk is called with the responses of every chunk once it has been processed, if set. It is not called
+	// concurrently.
+	OnChunk func(ChunkProgress)
+}
+
+// ChunkProgress is the outcome of a chunk
+type ChunkProgress struct {
+	// Chunk is the number of the chunk, from 1 to Chunks in the order of the items
+	Chunk  int
+	Chunks int
+	// Items are the indices of the items of the chunk, and Responses their merged responses
+	Items     []int
+	Responses []string
+	Err       error
+}
+
+// ChunkReport tells how the items were processed
+type ChunkReport struct {
+	Chunks          int
+	FailedChunks    int
+	SkippedChunks   int
+	SplitItems      int
+	EstimatedTokens int
+}
+
+// ChunkRunner processes the items of a chunk in a single run of the LLM processor, e.g. with ApifyClient.ProcessItems
+type ChunkRunner func(items []json.RawMessage) ([]*teetypes.LLMProcessorResult, error)
+
+// EstimateTokens returns the approximate number of tokens of a text
+func EstimateTokens(text []byte) int {
+	return (len(text) + bytesPerToken - 1) / bytesPerToken
+}
+
+// chunk is a group of items processed in the same run. rows are the items and the pieces of the items which were
+// split, and owners the index of the item of each row.
+type chunk struct {
+	rows   []json.RawMessage
+	owners []int
+	tokens int
+}
+
+// ProcessInChunks processes the items with run and returns the response for each of them, in the same order. Items
+// which don't fit in the context window are split, and the items of a failed or skipped chunk have an empty
+// response. It only fails if no chunk could be processed.
+func ProcessInChunks(items []json.RawMessage, prompt string, maxTokens uint, opts ChunkOptions, run ChunkRunner) ([]string, ChunkReport, error) {
+	if opts.ContextTokens <= 0 {
+		opts.ContextTokens = DefaultContextTokens
+	}
+	if opts.ChunkItems <= 0 {
+		opts.ChunkItems = DefaultChunkItems
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultChunkConcurrency
+	}
+
+	// Every row is sent with the prompt, and gets a response of up to maxTokens
+	rowOverhead := EstimateTokens([]byte(prompt)) + int(maxTokens)
+	itemTokens := opts.ContextTokens - rowOverhead
+	if itemTokens <= 0 {
+		return nil, ChunkReport{}, ErrContextTooSmall
+	}
+
+	var (
+		report ChunkReport
+		chunks []*chunk
+		cur    = &chunk{}
+	)
+	for i, item := range items {
+		pieces := splitItem(item, itemTokens)
+		if len(pieces) > 1 {
+			report.SplitItems++
+		}
+		// The pieces of an item are kept in the same chunk, so their responses can be merged
+		if len(cur.rows) > 0 && len(cur.rows)+len(pieces) > opts.ChunkItems {
+			chunks = append(chunks, cur)
+			cur = &chunk{}
+		}
+		for _, p := range pieces {
+			cur.rows = append(cur.rows, p)
+			cur.owners = append(cur.owners, i)
+			cur.tokens += EstimateTokens(p) + rowOverhead
+		}
+	}
+	if len(cur.rows) > 0 {
+		chunks = append(chunks, cur)
+	}
+	report.Chunks = len(chunks)
+
+	// The chunks are processed in order until the budget is spent
+	runnable := len(chunks)
+	for i, c := range chunks {
+		if opts.TokenBudget > 0 && report.EstimatedTokens+c.tokens > opts.TokenBudget {
+			runnable = i
+			break
+		}
+		report.EstimatedTokens += c.tokens
+	}
+	report.SkippedChunks = len(chunks) - runnable
+
+	responses := make([][]string, len(items))
+	var (
+		mu       sync.Mutex
+		wg       sync.WaitGroup
+		errs     []error
+		sem      = make(chan struct{}, opts.Concurrency)
+		progress = func(p ChunkProgress) {}
+	)
+	if opts.OnChunk != nil {
+		progress = opts.OnChunk
+	}
+	for n, c := range chunks[:runnable] {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results, err := run(c.rows)
+
+			mu.Lock()
+			defer mu.Unlock()
+			p := ChunkProgress{Chunk: n + 1, Chunks: len(chunks), Err: err}
+			if err != nil {
+				report.FailedChunks++
+				errs = append(errs, err)
+			} else {
+				for row, owner := range c.owners {
+					if row < len(results) && results[row] != nil {
+						responses[owner] = append(responses[owner], results[row].LLMResponse)
+					}
+				}
+			}
+			for i, owner := range c.owners {
+				if i == 0 || c.owners[i-1] != owner {
+					p.Items = append(p.Items, owner)
+					p.Responses = append(p.Responses, strings.Join(responses[owner], "\n"))
+				}
+			}
+			progress(p)
+		}()
+	}
+	wg.Wait()
+
+	if runnable > 0 && report.FailedChunks == runnable {
+		return nil, report, errors.Join(errs...)
+	}
+
+	merged := make([]string, len(items))
+	for i, r := range responses {
+		merged[i] = strings.Join(r, "\n")
+	}
+	return merged, report, nil
+}
+
+// splitItem splits an item of more than limit tokens into pieces which fit, by splitting its longest string field
+// into consecutive parts. The other fields are kept in every piece, so the prompt can still reference them. Items which
+// can't be split this way are returned as they are.
+func splitItem(item json.RawMessage, limit int) []json.RawMessage {
+	tokens := EstimateTokens(item)
+	if tokens <= limit {
+		return []json.RawMessage{item}
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(item, &fields); err != nil {
+		return []json.RawMessage{item}
+	}
+	var longest string
+	for _, k := range slices.Sorted(maps.Keys(fields)) {
+		if s, ok := fields[k].(string); ok && len(s) > len(fieldString(fields, longest)) {
+			longest = k
+		}
+	}
+	text := fieldString(fields, longest)
+	// The field is encoded as a JSON string, which makes it at least as long as the text
+	perPiece := (limit - (tokens - EstimateTokens([]byte(text)))) * bytesPerToken
+	if text == "" || perPiece <= 0 {
+		return []json.RawMessage{item}
+	}
+
+	var pieces []json.RawMessage
+	for len(text) > 0 {
+		n := min(perPiece, len(text))
+		// Pieces end on a rune boundary
+		for n < len(text) && n > 0 && !utf8.RuneStart(text[n]) {
+			n--
+		}
+		if n == 0 {
+			n = min(perPiece, len(text))
+		}
+		fields[longest] = text[:n]
+		text = text[n:]
+		piece, err := json.Marshal(fields)
+		if err != nil {
+			return []json.RawMessage{item}
+		}
+		pieces = append(pieces, piece)
+	}
+	return pieces
+}
+
+func fieldString(fields map[string]any, key string) string {
+	s, _ := fields[key].(string)
+	return s
+}
@@ -0,0 +1,164 @@
+package llmapify_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/llmapify"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+var _ = Describe("ProcessInChunks", func() {
+	// items returns n items with the given text
+	items := func(n int, text string) []json.RawMessage {
+		res := make([]json.RawMessage, n)
+		for i := range res {
+			res[i] = json.RawMessage(fmt.Sprintf(`{"id":"%d","text":%q}`, i, text))
+		}
+		return res
+	}
+
+	// echo answers every item with its id and text
+	echo := func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+		res := make([]*teetypes.LLMProcessorResult, len(chunk))
+		for i, item := range chunk {
+			var fields map[string]string
+			Expect(json.Unmarshal(item, &fields)).To(Succeed())
+			res[i] = &teetypes.LLMProcessorResult{LLMResponse: fields["id"] + ":" + fields["text"]}
+		}
+		return res, nil
+	}
+
+	It("processes the items in chunks and keeps their order", func() {
+		var (
+			mu     sync.Mutex
+			sizes  []int
+			chunks []llmapify.ChunkProgress
+		)
+		resp, report, err := llmapify.ProcessInChunks(items(5, "tweet"), "summarize", 10, llmapify.ChunkOptions{
+			ChunkItems: 2,
+			OnChunk:    func(p llmapify.ChunkProgress) { chunks = append(chunks, p) },
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			mu.Lock()
+			sizes = append(sizes, len(chunk))
+			mu.Unlock()
+			return echo(chunk)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp).To(Equal([]string{"0:tweet", "1:tweet", "2:tweet", "3:tweet", "4:tweet"}))
+		Expect(sizes).To(ConsistOf(2, 2, 1))
+		Expect(report.Chunks).To(Equal(3))
+		Expect(report.EstimatedTokens).To(BeNumerically(">", 0))
+
+		Expect(chunks).To(HaveLen(3))
+		for _, c := range chunks {
+			Expect(c.Chunks).To(Equal(3))
+			Expect(c.Items).To(HaveLen(len(c.Responses)))
+			for i, item := range c.Items {
+				Expect(c.Responses[i]).To(Equal(fmt.Sprintf("%d:tweet", item)))
+			}
+		}
+	})
+
+	It("splits the items which don't fit in the context window and merges their responses", func() {
+		text := strings.Repeat("é", 300)
+		var rows int
+		resp, report, err := llmapify.ProcessInChunks(items(2, text), "summarize", 10, llmapify.ChunkOptions{
+			ContextTokens: 100,
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			rows += len(chunk)
+			return echo(chunk)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.SplitItems).To(Equal(2))
+		Expect(rows).To(BeNumerically(">", 2))
+
+		for i, r := range resp {
+			var merged strings.Builder
+			for _, line := range strings.Split(r, "\n") {
+				id, part, ok := strings.Cut(line, ":")
+				Expect(ok).To(BeTrue())
+				Expect(id).To(Equal(fmt.Sprint(i)))
+				merged.WriteString(part)
+			}
+			Expect(merged.String()).To(Equal(text))
+		}
+	})
+
+	It("skips the chunks which would exceed the token budget", func() {
+		var calls atomic.Int32
+		resp, report, err := llmapify.ProcessInChunks(items(4, "tweet"), "summarize", 10, llmapify.ChunkOptions{
+			ChunkItems:  1,
+			TokenBudget: 50,
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			calls.Add(1)
+			return echo(chunk)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.SkippedChunks).To(BeNumerically(">", 0))
+		Expect(int(calls.Load())).To(Equal(report.Chunks - report.SkippedChunks))
+		Expect(report.EstimatedTokens).To(BeNumerically("<=", 50))
+		Expect(resp[0]).To(Equal("0:tweet"))
+		Expect(resp[3]).To(BeEmpty())
+	})
+
+	It("processes at most Concurrency chunks at the same time", func() {
+		var running, peak atomic.Int32
+		_, _, err := llmapify.ProcessInChunks(items(8, "tweet"), "summarize", 10, llmapify.ChunkOptions{
+			ChunkItems:  1,
+			Concurrency: 2,
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			n := running.Add(1)
+			defer running.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(10 * time.Millisecond)
+			return echo(chunk)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(peak.Load()).To(BeNumerically("<=", 2))
+	})
+
+	It("leaves the responses of failed chunks empty", func() {
+		resp, report, err := llmapify.ProcessInChunks(items(2, "tweet"), "summarize", 10, llmapify.ChunkOptions{
+			ChunkItems: 1,
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			if strings.Contains(string(chunk[0]), `"id":"1"`) {
+				return nil, errors.New("quota exceeded")
+			}
+			return echo(chunk)
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.FailedChunks).To(Equal(1))
+		Expect(resp).To(Equal([]string{"0:tweet", ""}))
+	})
+
+	It("fails if all the chunks fail", func() {
+		_, _, err := llmapify.ProcessInChunks(items(2, "tweet"), "summarize", 10, llmapify.ChunkOptions{
+			ChunkItems: 1,
+		}, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			return nil, errors.New("quota exceeded")
+		})
+		Expect(err).To(MatchError(ContainSubstring("quota exceeded")))
+	})
+
+	It("fails if the prompt and the response don't fit in the context window", func() {
+		_, _, err := llmapify.ProcessInChunks(items(1, "tweet"), "summarize", 200, llmapify.ChunkOptions{
+			ContextTokens: 100,
+		}, echo)
+		Expect(err).To(MatchError(llmapify.ErrContextTooSmall))
+	})
+})
@@ -17,6 +17,7 @@ import (
 var (
 	ErrProviderKeyRequired  = errors.New("llm provider key is required")
 	ErrFailedToCreateClient = errors.New("failed to create apify client")
+	ErrDatasetsNotSupported = errors.New("apify client cannot create datasets")
 )
 
 type ApifyClient struct {
@@ -27,13 +28,13 @@ type ApifyClient struct {
 
 // NewInternalClient is a function variable that can be replaced in tests.
 // It defaults to the actual implementation.
-var NewInternalClient = func(apiKey string) (client.Apify, error) {
-	return client.NewApifyClient(apiKey)
+var NewInternalClient = func(apiKey string, opts ...client.Option) (client.Apify, error) {
+	return client.NewApifyClient(apiKey, opts...)
 }
 
 // NewClient creates a new LLM Apify client
-func NewClient(apiToken string, llmConfig config.LlmConfig, statsCollector *stats.StatsCollector) (*ApifyClient, error) {
-	client, err := NewInternalClient(apiToken)
+func NewClient(apiToken string, llmConfig config.LlmConfig, statsCollector *stats.StatsCollector, opts ...client.Option) (*ApifyClient, error) {
+	client, err := NewInternalClient(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrFailedToCreateClient, err)
 	}
@@ -55,12 +56,50 @@ func (c *ApifyClient) ValidateApiKey() error {
 }
 
 func (c *ApifyClient) Process(workerID string, args teeargs.LLMProcessorArguments, cursor client.Cursor) ([]*teetypes.LLMProcessorResult, client.Cursor, error) {
+	return c.process(workerID, args, "", cursor)
+}
+
+// ProcessItems runs the LLM processor over items which were not scraped by an actor, by storing them in a new dataset
+// first. An empty model uses the default model of the processor.
+func (c *ApifyClient) ProcessItems(workerID string, items []json.RawMessage, prompt, model string, maxTokens uint) ([]*teetypes.LLMProcessorResult, error) {
+	writer, ok := c.client.(client.DatasetWriter)
+	if !ok {
+		return nil, ErrDatasetsNotSupported
+	}
+
+	datasetId, err := writer.CreateDataset(items)
+	if err != nil {
+		if c.statsCollector != nil {
+			c.statsCollector.Add(workerID, stats.LLMErrors, 1)
+		}
+		return nil, err
+	}
+
+	args := teeargs.LLMProcessorArguments{
+		DatasetId:   datasetId,
+		Prompt:      prompt,
+		MaxTokens:   maxTokens,
+		Temperature: teeargs.LLMDefaultTemperature,
+		Items:       uint(len(items)),
+	}
+	if args.MaxTokens == 0 {
+		args.MaxTokens = teeargs.LLMDefaultMaxTokens
+	}
+
+	resp, _, err := c.process(workerID, args, model, client.EmptyCursor)
+	return resp, err
+}
+
+func (c *ApifyClient) process(workerID string, args teeargs.LLMProcessorArguments, model string, cursor client.Cursor) ([]*teetypes.LLMProcessorResult, client.Cursor, error) {
 	if c.statsCollector != nil {
 		c.statsCollector.Add(workerID, stats.LLMQueries, 1)
 	}
 
 	input := args.ToLLMProcessorRequest()
 	input.LLMProviderApiKey = string(c.llmConfig.GeminiApiKey)
+	if model != "" {
+		input.Model = model
+	}
 
 	limit := uint(args.Items)
 	dataset, nextCursor, err := c.client.RunActorAndGetResponse(apify.ActorIds.LLMDatasetProcessor, input, cursor, limit)
@@ -47,6 +47,17 @@ func (m *MockApifyClient) ProbeActorAccess(actorID apify.ActorId, input map[stri
 	return false, errors.New("ProbeActorAccessFunc not defined")
 }
 
+// MockDatasetApifyClient is a MockApifyClient which can create datasets
+type MockDatasetApifyClient struct {
+	MockApifyClient
+	Items []json.RawMessage
+}
+
+func (m *MockDatasetApifyClient) CreateDataset(items []json.RawMessage) (string, error) {
+	m.Items = items
+	return "created-dataset-id", nil
+}
+
 var _ = Describe("LLMApifyClient", func() {
 	var (
 		mockClient *MockApifyClient
@@ -58,7 +69,7 @@ var _ = Describe("LLMApifyClient", func() {
 		apifyKey = os.Getenv("APIFY_API_KEY")
 		mockClient = &MockApifyClient{}
 		// Replace the client creation function with one that returns the mock
-		llmapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+		llmapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 			return mockClient, nil
 		}
 		var err error
@@ -209,6 +220,43 @@ var _ = Describe("LLMApifyClient", func() {
 		})
 	})
 
+	Describe("ProcessItems", func() {
+		It("should process the items in a new dataset with the requested model", func() {
+			datasetClient := &MockDatasetApifyClient{}
+			datasetClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				request, ok := input.(teetypes.LLMProcessorRequest)
+				Expect(ok).To(BeTrue())
+				Expect(request.InputDatasetId).To(Equal("created-dataset-id"))
+				Expect(request.Prompt).To(Equal("test-prompt"))
+				Expect(request.Model).To(Equal("gemini-2.0-flash"))
+				Expect(request.MaxTokens).To(Equal(teeargs.LLMDefaultMaxTokens))
+				Expect(limit).To(Equal(uint(2)))
+
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{
+					json.RawMessage(`{"llmresponse":"first"}`),
+					json.RawMessage(`{"llmresponse":"second"}`),
+				}}}, client.EmptyCursor, nil
+			}
+			llmapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
+				return datasetClient, nil
+			}
+			llmClient, err := llmapify.NewClient("test-token", config.LlmConfig{GeminiApiKey: "test-llm-key"}, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			items := []json.RawMessage{json.RawMessage(`{"id":1}`), json.RawMessage(`{"id":2}`)}
+			results, err := llmClient.ProcessItems("test-worker", items, "test-prompt", "gemini-2.0-flash", 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(datasetClient.Items).To(Equal(items))
+			Expect(results).To(HaveLen(2))
+			Expect(results[1].LLMResponse).To(Equal("second"))
+		})
+
+		It("should fail if the apify client cannot create datasets", func() {
+			_, err := llmClient.ProcessItems("test-worker", []json.RawMessage{json.RawMessage(`{}`)}, "test-prompt", "", 0)
+			Expect(err).To(MatchError(llmapify.ErrDatasetsNotSupported))
+		})
+	})
+
 	Describe("ValidateApiKey", func() {
 		It("should validate the API key", func() {
 			mockClient.ValidateApiKeyFunc = func() error {
@@ -235,7 +283,7 @@ var _ = Describe("LLMApifyClient", func() {
 			}
 
 			// Reset to use real client
-			llmapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+			llmapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 				return client.NewApifyClient(apiKey)
 			}
 
@@ -251,7 +299,7 @@ var _ = Describe("LLMApifyClient", func() {
 			}
 
 			// Reset to use real client
-			llmapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+			llmapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 				return client.NewApifyClient(apiKey)
 			}
 
@@ -0,0 +1,255 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/mastodon"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/mastodonapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// MastodonJob scrapes public data from Mastodon instances. It is not part of tee-types yet, so its arguments are validated here.
+const MastodonJob teetypes.JobType = "mastodon"
+
+// CapGetHashtag fetches the public timeline of a hashtag
+const CapGetHashtag teetypes.Capability = "gethashtag"
+
+// MastodonCaps are the capabilities of the mastodon job type
+var MastodonCaps = []teetypes.Capability{teetypes.CapSearchByQuery, teetypes.CapGetProfile, CapGetHashtag}
+
+const (
+	defaultMastodonMaxResults = 20
+	maxMastodonMaxResults     = 200
+
+	// Unauthenticated requests are limited to 300 per 5 minutes and IP address on a default Mastodon installation
+	mastodonRateLimitRequests      = 300
+	mastodonRateLimitWindowSeconds = 5 * 60
+)
+
+// MastodonClient defines the interface for the Mastodon client. This allows for mocking in tests.
+type MastodonClient interface {
+	SearchStatuses(query string, limit int, maxID string) ([]mastodon.Status, error)
+	LookupAccount(acct string) (*mastodon.Account, error)
+	HashtagTimeline(tag string, limit int, maxID string) ([]mastodon.Status, error)
+}
+
+// NewMastodonClient is a function variable that can be replaced in tests.
+// It defaults to the actual implementation.
+var NewMastodonClient = func(baseURL string, network types.Network) MastodonClient {
+	c := mastodonapi.NewClient(baseURL)
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// MastodonArguments are the arguments of a mastodon job
+type MastodonArguments struct {
+	QueryType teetypes.Capability `json:"type"`
+	// Query is the search query, the account (user or user@domain) or the hashtag, depending on the query type
+	Query string `json:"query"`
+	// Instance is the base URL of the instance to query. It must be one of the configured instances.
+	Instance   string `json:"instance"`
+	MaxResults int    `json:"max_results"`
+	NextCursor string `json:"next_cursor"`
+}
+
+type MastodonScraper struct {
+	configuration  config.MastodonConfig
+	statsCollector *stats.StatsCollector
+}
+
+func NewMastodonScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *MastodonScraper {
+	config := jc.GetMastodonConfig()
+	logrus.Infof("Mastodon scraper initialized with instances %v", config.Instances)
+	return &MastodonScraper{
+		configuration:  config,
+		statsCollector: statsCollector,
+	}
+}
+
+// GetStructuredCapabilities returns the capabilities of the Mastodon scraper, which are available as long as an instance is configured
+func (ms *MastodonScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	capabilities := make(teetypes.WorkerCapabilities)
+	if len(ms.configuration.Instances) > 0 {
+		capabilities[MastodonJob] = MastodonCaps
+	}
+	return capabilities
+}
+
+// GetCapabilityDetails returns the auth source of each capability. Only public data is scraped, so no authentication is needed.
+func (ms *MastodonScraper) GetCapabilityDetails() types.CapabilityDetails {
+	details := types.NewCapabilityDetails(ms.GetStructuredCapabilities(), types.AuthSourceNone)
+	for i := range details[MastodonJob] {
+		details[MastodonJob][i].RateLimit = &types.RateLimitEstimate{
+			Requests:      mastodonRateLimitRequests * len(ms.configuration.Instances),
+			WindowSeconds: mastodonRateLimitWindowSeconds,
+		}
+	}
+	return details
+}
+
+// parseArguments unmarshals and validates the arguments of a mastodon job
+func (ms *MastodonScraper) parseArguments(args types.JobArguments) (*MastodonArguments, error) {
+	parsed := &MastodonArguments{}
+	if err := args.Unmarshal(parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal mastodon arguments: %w", err)
+	}
+
+	parsed.QueryType = teetypes.Capability(strings.ToLower(string(parsed.QueryType)))
+	if parsed.QueryType == teetypes.CapEmpty {
+		parsed.QueryType = teetypes.CapSearchByQuery
+	}
+	if !slices.Contains(MastodonCaps, parsed.QueryType) {
+		return nil, fmt.Errorf("invalid type %q for mastodon job, valid types are %v", parsed.QueryType, MastodonCaps)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if parsed.Query == "" {
+		return nil, errors.New("query is required")
+	}
+
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultMastodonMaxResults
+	}
+	if parsed.MaxResults < 0 || parsed.MaxResults > maxMastodonMaxResults {
+		return nil, fmt.Errorf("max_results must be between 1 and %d, got %d", maxMastodonMaxResults, parsed.MaxResults)
+	}
+
+	instance, err := ms.resolveInstance(parsed.Instance)
+	if err != nil {
+		return nil, err
+	}
+	parsed.Instance = instance
+
+	return parsed, nil
+}
+
+// resolveInstance returns the configured instance matching the requested one, or the first configured instance if none was requested.
+// Only configured instances can be queried, so jobs can't make the worker send requests to arbitrary hosts.
+func (ms *MastodonScraper) resolveInstance(requested string) (string, error) {
+	if len(ms.configuration.Instances) == 0 {
+		return "", errors.New("no Mastodon instances configured")
+	}
+	if requested == "" {
+		return ms.configuration.Instances[0], nil
+	}
+
+	host := instanceHost(requested)
+	for _, instance := range ms.configuration.Instances {
+		if strings.EqualFold(instanceHost(instance), host) {
+			return instance, nil
+		}
+	}
+	return "", fmt.Errorf("instance %q is not configured", requested)
+}
+
+// instanceHost returns the host of an instance given either as a base URL or as a bare domain
+func instanceHost(instance string) string {
+	if !strings.Contains(instance, "://") {
+		instance = "https://" + instance
+	}
+	u, err := url.Parse(instance)
+	if err != nil {
+		return instance
+	}
+	return u.Host
+}
+
+// addStat adds to a statistic, broken down by the capability of the job and the instance as provider if enabled
+func (ms *MastodonScraper) addStat(j types.Job, instance string, typ stats.StatType, num uint) {
+	dims := stats.DimensionsForJob(j)
+	dims.Provider = instanceHost(instance)
+	ms.statsCollector.AddWithDimensions(j.WorkerID, typ, num, dims)
+}
+
+func (ms *MastodonScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for Mastodon scrape")
+
+	args, err := ms.parseArguments(j.Arguments)
+	if err != nil {
+		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+	logrus.Debugf("mastodon job args: %+v", *args)
+
+	client := NewMastodonClient(args.Instance, j.Network)
+
+	var data any
+	var nextCursor string
+	switch args.QueryType {
+	case teetypes.CapSearchByQuery:
+		data, nextCursor, err = ms.fetchStatuses(j, args, client.SearchStatuses)
+
+	case CapGetHashtag:
+		args.Query = strings.TrimPrefix(args.Query, "#")
+		data, nextCursor, err = ms.fetchStatuses(j, args, client.HashtagTimeline)
+
+	case teetypes.CapGetProfile:
+		ms.addStat(j, args.Instance, stats.MastodonQueries, 1)
+		var account *mastodon.Account
+		account, err = client.LookupAccount(strings.TrimPrefix(args.Query, "@"))
+		if err == nil {
+			ms.addStat(j, args.Instance, stats.MastodonProfiles, 1)
+			data = account
+		}
+	}
+
+	if err != nil {
+		ms.handleError(j, args.Instance, err)
+		return types.JobResult{Error: fmt.Sprintf("error while scraping Mastodon: %s", err.Error())}, fmt.Errorf("error scraping Mastodon: %w", err)
+	}
+
+	res, err := pageResult(data, nextCursor)
+	res.Job = j
+	return res, err
+}
+
+// fetchStatuses pages through the statuses returned by fetch until MaxResults statuses have been collected.
+// The next cursor is the ID of the last status, and is only set if there may be more statuses.
+func (ms *MastodonScraper) fetchStatuses(j types.Job, args *MastodonArguments, fetch func(query string, limit int, maxID string) ([]mastodon.Status, error)) ([]mastodon.Status, string, error) {
+	statuses := make([]mastodon.Status, 0, args.MaxResults)
+	maxID := args.NextCursor
+
+	for len(statuses) < args.MaxResults {
+		limit := min(args.MaxResults-len(statuses), mastodonapi.MaxPageSize)
+
+		ms.addStat(j, args.Instance, stats.MastodonQueries, 1)
+		page, err := fetch(args.Query, limit, maxID)
+		if errors.Is(err, bandwidth.ErrCapExceeded) && len(statuses) > 0 {
+			// Return the statuses collected so far, the next cursor continues after them
+			logrus.Warnf("Bandwidth cap of job %s exceeded, returning %d statuses", j.UUID, len(statuses))
+			break
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		statuses = append(statuses, page...)
+		if len(page) < limit {
+			maxID = ""
+			break
+		}
+		maxID = page[len(page)-1].ID
+	}
+
+	ms.addStat(j, args.Instance, stats.MastodonStatuses, uint(len(statuses)))
+	return statuses, maxID, nil
+}
+
+func (ms *MastodonScraper) handleError(j types.Job, instance string, err error) {
+	if errors.Is(err, mastodonapi.ErrRateLimited) {
+		ms.addStat(j, instance, stats.MastodonRateErrors, 1)
+		logrus.Warnf("Rate limited by Mastodon instance %s", instance)
+		return
+	}
+	ms.addStat(j, instance, stats.MastodonErrors, 1)
+}
@@ -0,0 +1,201 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/mastodon"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/mastodonapi"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// MockMastodonClient is a mock implementation of the MastodonClient.
+type MockMastodonClient struct {
+	SearchStatusesFunc  func(query string, limit int, maxID string) ([]mastodon.Status, error)
+	LookupAccountFunc   func(acct string) (*mastodon.Account, error)
+	HashtagTimelineFunc func(tag string, limit int, maxID string) ([]mastodon.Status, error)
+}
+
+func (m *MockMastodonClient) SearchStatuses(query string, limit int, maxID string) ([]mastodon.Status, error) {
+	return m.SearchStatusesFunc(query, limit, maxID)
+}
+
+func (m *MockMastodonClient) LookupAccount(acct string) (*mastodon.Account, error) {
+	return m.LookupAccountFunc(acct)
+}
+
+func (m *MockMastodonClient) HashtagTimeline(tag string, limit int, maxID string) ([]mastodon.Status, error) {
+	return m.HashtagTimelineFunc(tag, limit, maxID)
+}
+
+// statusPage returns limit statuses with decreasing IDs, starting below maxID
+func statusPage(limit int, maxID string) []mastodon.Status {
+	start := 1000
+	if maxID != "" {
+		fmt.Sscanf(maxID, "%d", &start)
+	}
+	page := make([]mastodon.Status, 0, limit)
+	for i := 1; i <= limit; i++ {
+		page = append(page, mastodon.Status{ID: fmt.Sprintf("%d", start-i)})
+	}
+	return page
+}
+
+var _ = Describe("MastodonScraper", func() {
+	var (
+		scraper        *jobs.MastodonScraper
+		statsCollector *stats.StatsCollector
+		mockClient     *MockMastodonClient
+		clientBaseURL  string
+		job            types.Job
+	)
+
+	BeforeEach(func() {
+		statsCollector = stats.StartCollector(128, config.JobConfiguration{})
+		scraper = jobs.NewMastodonScraper(config.JobConfiguration{
+			"mastodon_instances": []string{"https://mastodon.social", "https://fosstodon.org"},
+		}, statsCollector)
+
+		mockClient = &MockMastodonClient{}
+		clientBaseURL = ""
+		jobs.NewMastodonClient = func(baseURL string, _ types.Network) jobs.MastodonClient {
+			clientBaseURL = baseURL
+			return mockClient
+		}
+
+		job = types.Job{
+			UUID:     "test-uuid",
+			Type:     jobs.MastodonJob,
+			WorkerID: "mastodon-test",
+		}
+	})
+
+	It("should report its capabilities", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[jobs.MastodonJob]).To(ConsistOf(jobs.MastodonCaps))
+
+		details := scraper.GetCapabilityDetails()[jobs.MastodonJob]
+		Expect(details).To(HaveLen(len(jobs.MastodonCaps)))
+		Expect(details[0].AuthSource).To(Equal(types.AuthSourceNone))
+		Expect(details[0].RateLimit.Requests).To(Equal(600))
+	})
+
+	It("should search statuses across pages on the default instance", func() {
+		var limits []int
+		mockClient.SearchStatusesFunc = func(query string, limit int, maxID string) ([]mastodon.Status, error) {
+			Expect(query).To(Equal("fediverse"))
+			limits = append(limits, limit)
+			return statusPage(limit, maxID), nil
+		}
+
+		job.Arguments = map[string]any{"type": "searchbyquery", "query": "fediverse", "max_results": 50}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientBaseURL).To(Equal("https://mastodon.social"))
+		Expect(limits).To(Equal([]int{mastodonapi.MaxPageSize, 10}))
+
+		var statuses []mastodon.Status
+		Expect(json.Unmarshal(res.Data, &statuses)).To(Succeed())
+		Expect(statuses).To(HaveLen(50))
+		Expect(res.NextCursor).To(Equal(statuses[49].ID))
+		Expect(res.HasMore).To(BeTrue())
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.MastodonStatuses]
+		}).Should(BeNumerically("==", 50))
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.MastodonQueries]
+		}).Should(BeNumerically("==", 2))
+	})
+
+	It("should return the statuses collected so far when the bandwidth cap is exceeded", func() {
+		mockClient.SearchStatusesFunc = func(query string, limit int, maxID string) ([]mastodon.Status, error) {
+			if maxID != "" {
+				return nil, fmt.Errorf("error reading response: %w", bandwidth.ErrCapExceeded)
+			}
+			return statusPage(limit, maxID), nil
+		}
+
+		job.Arguments = map[string]any{"type": "searchbyquery", "query": "fediverse", "max_results": 100}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var statuses []mastodon.Status
+		Expect(json.Unmarshal(res.Data, &statuses)).To(Succeed())
+		Expect(statuses).To(HaveLen(mastodonapi.MaxPageSize))
+		Expect(res.NextCursor).To(Equal(statuses[len(statuses)-1].ID))
+	})
+
+	It("should fetch hashtag timelines on the requested instance and stop at the last page", func() {
+		mockClient.HashtagTimelineFunc = func(tag string, limit int, maxID string) ([]mastodon.Status, error) {
+			Expect(tag).To(Equal("golang"))
+			Expect(maxID).To(Equal("500"))
+			return statusPage(3, maxID), nil
+		}
+
+		job.Arguments = map[string]any{"type": "gethashtag", "query": "#golang", "instance": "fosstodon.org", "next_cursor": "500"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clientBaseURL).To(Equal("https://fosstodon.org"))
+		Expect(res.NextCursor).To(BeEmpty())
+		Expect(res.HasMore).To(BeFalse())
+
+		var statuses []mastodon.Status
+		Expect(json.Unmarshal(res.Data, &statuses)).To(Succeed())
+		Expect(statuses).To(HaveLen(3))
+	})
+
+	It("should get profiles", func() {
+		mockClient.LookupAccountFunc = func(acct string) (*mastodon.Account, error) {
+			Expect(acct).To(Equal("alice@example.social"))
+			return &mastodon.Account{ID: "1", Username: "alice", Acct: acct}, nil
+		}
+
+		job.Arguments = map[string]any{"type": "getprofile", "query": "@alice@example.social"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var account mastodon.Account
+		Expect(json.Unmarshal(res.Data, &account)).To(Succeed())
+		Expect(account.Username).To(Equal("alice"))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.MastodonProfiles]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	It("should record rate limit errors", func() {
+		mockClient.LookupAccountFunc = func(acct string) (*mastodon.Account, error) {
+			return nil, mastodonapi.ErrRateLimited
+		}
+
+		job.Arguments = map[string]any{"type": "getprofile", "query": "alice"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).To(MatchError(mastodonapi.ErrRateLimited))
+		Expect(res.Error).To(ContainSubstring("rate limit exceeded"))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.MastodonRateErrors]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]any) {
+			job.Arguments = args
+			res, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).NotTo(BeEmpty())
+		},
+		Entry("unknown type", map[string]any{"type": "getfollowers", "query": "alice"}),
+		Entry("missing query", map[string]any{"type": "searchbyquery"}),
+		Entry("too many results", map[string]any{"type": "searchbyquery", "query": "a", "max_results": 1000}),
+		Entry("unconfigured instance", map[string]any{"type": "searchbyquery", "query": "a", "instance": "https://evil.example"}),
+	)
+})
@@ -0,0 +1,122 @@
+package mastodonapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/mastodon"
+)
+
+// MaxPageSize is the maximum number of statuses returned by the Mastodon API in a single request
+const MaxPageSize = 40
+
+var (
+	// ErrRateLimited is returned when the instance responds with 429 Too Many Requests
+	ErrRateLimited = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	// ErrNotFound is returned when the account or hashtag does not exist on the instance
+	ErrNotFound = types.NewCodedError(types.ErrorCodeNotFound, "not found")
+)
+
+// Client queries the public REST API of a single Mastodon instance. No access token is needed.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new client for the instance at the given base URL, e.g. https://mastodon.social
+func NewClient(baseURL string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// SearchStatuses returns the statuses matching the query, older than maxID if it is not empty.
+// Note that many instances only return statuses for authenticated requests, or only those which opted into search.
+func (c *Client) SearchStatuses(query string, limit int, maxID string) ([]mastodon.Status, error) {
+	params := pageParams(limit, maxID)
+	params.Set("q", query)
+	params.Set("type", "statuses")
+
+	var result struct {
+		Statuses []mastodon.Status `json:"statuses"`
+	}
+	if err := c.get("/api/v2/search", params, &result); err != nil {
+		return nil, err
+	}
+	return result.Statuses, nil
+}
+
+// LookupAccount returns the account with the given username (user) or address (user@domain)
+func (c *Client) LookupAccount(acct string) (*mastodon.Account, error) {
+	params := url.Values{}
+	params.Set("acct", acct)
+
+	var account mastodon.Account
+	if err := c.get("/api/v1/accounts/lookup", params, &account); err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// HashtagTimeline returns the public statuses with the given hashtag, older than maxID if it is not empty
+func (c *Client) HashtagTimeline(tag string, limit int, maxID string) ([]mastodon.Status, error) {
+	var statuses []mastodon.Status
+	if err := c.get("/api/v1/timelines/tag/"+url.PathEscape(tag), pageParams(limit, maxID), &statuses); err != nil {
+		return nil, err
+	}
+	return statuses, nil
+}
+
+func pageParams(limit int, maxID string) url.Values {
+	params := url.Values{}
+	if limit <= 0 || limit > MaxPageSize {
+		limit = MaxPageSize
+	}
+	params.Set("limit", strconv.Itoa(limit))
+	if maxID != "" {
+		params.Set("max_id", maxID)
+	}
+	return params
+}
+
+func (c *Client) get(path string, params url.Values, v any) error {
+	req, err := http.NewRequest(http.MethodGet, c.BaseURL+path+"?"+params.Encode(), nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error querying %s: %w", c.BaseURL, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response from %s: %w", c.BaseURL, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusTooManyRequests:
+		return ErrRateLimited
+	case http.StatusNotFound:
+		return ErrNotFound
+	default:
+		return fmt.Errorf("%s returned status code %d: %s", c.BaseURL, resp.StatusCode, string(body))
+	}
+
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing response from %s: %w", c.BaseURL, err)
+	}
+	return nil
+}
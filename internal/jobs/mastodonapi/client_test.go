@@ -0,0 +1,91 @@
+package mastodonapi_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/mastodonapi"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server  *httptest.Server
+		handler http.HandlerFunc
+		c       *mastodonapi.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r)
+		}))
+		c = mastodonapi.NewClient(server.URL + "/")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should search statuses", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v2/search"))
+			Expect(r.URL.Query().Get("q")).To(Equal("fediverse"))
+			Expect(r.URL.Query().Get("type")).To(Equal("statuses"))
+			Expect(r.URL.Query().Get("limit")).To(Equal("10"))
+			Expect(r.URL.Query().Get("max_id")).To(Equal("123"))
+			_, _ = w.Write([]byte(`{"accounts":[],"hashtags":[],"statuses":[{"id":"100","content":"<p>hello</p>","account":{"acct":"alice"}}]}`))
+		}
+
+		statuses, err := c.SearchStatuses("fediverse", 10, "123")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(1))
+		Expect(statuses[0].ID).To(Equal("100"))
+		Expect(statuses[0].Account.Acct).To(Equal("alice"))
+	})
+
+	It("should look up accounts", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v1/accounts/lookup"))
+			Expect(r.URL.Query().Get("acct")).To(Equal("alice@example.social"))
+			_, _ = w.Write([]byte(`{"id":"1","username":"alice","acct":"alice@example.social","followers_count":42}`))
+		}
+
+		account, err := c.LookupAccount("alice@example.social")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(account.Username).To(Equal("alice"))
+		Expect(account.FollowersCount).To(Equal(42))
+	})
+
+	It("should fetch hashtag timelines, capping the page size", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/api/v1/timelines/tag/golang"))
+			Expect(r.URL.Query().Get("limit")).To(Equal("40"))
+			Expect(r.URL.Query().Has("max_id")).To(BeFalse())
+			_, _ = w.Write([]byte(`[{"id":"2"},{"id":"1"}]`))
+		}
+
+		statuses, err := c.HashtagTimeline("golang", 100, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses).To(HaveLen(2))
+	})
+
+	It("should map error status codes", func() {
+		status := http.StatusTooManyRequests
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}
+
+		_, err := c.LookupAccount("alice")
+		Expect(err).To(MatchError(mastodonapi.ErrRateLimited))
+
+		status = http.StatusNotFound
+		_, err = c.LookupAccount("alice")
+		Expect(err).To(MatchError(mastodonapi.ErrNotFound))
+
+		status = http.StatusInternalServerError
+		_, err = c.LookupAccount("alice")
+		Expect(err).To(MatchError(ContainSubstring("status code 500")))
+	})
+})
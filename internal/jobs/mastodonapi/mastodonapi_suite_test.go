@@ -0,0 +1,13 @@
+package mastodonapi_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestMastodonAPI(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Mastodon API Client Suite")
+}
@@ -0,0 +1,39 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+// pageResult returns the result of a job which returned a page of items, and the cursor of the next page, which is
+// empty on the last page. The scrapers of all the job types return their pages through it, so the results of all of
+// them can be served as a types.PaginatedResult.
+func pageResult(items any, nextCursor string) (types.JobResult, error) {
+	dat, err := json.Marshal(items)
+	if err != nil {
+		return types.JobResult{Error: "error marshalling results"}, fmt.Errorf("error marshalling results: %w", err)
+	}
+	res := types.JobResult{Data: dat}
+	setNextCursor(&res, nextCursor)
+	return res, nil
+}
+
+// apifyPageResult is pageResult for the pages of the dataset of an Apify actor run, whose cursor tells the number of
+// items of the dataset
+func apifyPageResult(items any, nextCursor client.Cursor) (types.JobResult, error) {
+	res, err := pageResult(items, nextCursor.String())
+	if total := nextCursor.Total(); err == nil && total > 0 {
+		estimate := int(total)
+		res.TotalEstimate = &estimate
+	}
+	return res, err
+}
+
+// setNextCursor sets the cursor of the next page of a job result, and whether there is one
+func setNextCursor(res *types.JobResult, nextCursor string) {
+	res.NextCursor = nextCursor
+	res.HasMore = nextCursor != ""
+}
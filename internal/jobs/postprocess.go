@@ -0,0 +1,167 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/llmapify"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/client"
+
+	teeargs "github.com/masa-finance/tee-types/args"
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// PostProcessArgumentKey is the job argument used to run the result of any job through the LLM processor
+const PostProcessArgumentKey = "post_process"
+
+// ErrPostProcessNotConfigured is returned when post-processing is requested from a worker without Apify and Gemini keys
+var ErrPostProcessNotConfigured = errors.New("post-processing requires the Apify and Gemini API keys")
+
+// PostProcessArguments are the fields of the post_process job argument
+type PostProcessArguments struct {
+	Prompt    string `json:"prompt"`
+	Model     string `json:"model,omitempty"`
+	MaxTokens uint   `json:"max_tokens,omitempty"`
+	// TokenBudget is the number of tokens the job may use, which can't exceed the budget of the worker
+	TokenBudget int `json:"token_budget,omitempty"`
+}
+
+// LLMItemProcessor is the interface of the LLM processor client used for post-processing, to allow mocking in tests
+type LLMItemProcessor interface {
+	ProcessItems(workerID string, items []json.RawMessage, prompt, model string, maxTokens uint) ([]*teetypes.LLMProcessorResult, error)
+}
+
+// NewLLMItemProcessor is a function variable to allow injection in tests
+var NewLLMItemProcessor = func(apiKey string, llmConfig config.LlmConfig, statsCollector *stats.StatsCollector, opts ...client.Option) (LLMItemProcessor, error) {
+	return llmapify.NewClient(apiKey, llmConfig, statsCollector, opts...)
+}
+
+// PostProcessFromArguments extracts the post-processing request from the job arguments. It returns nil if none was
+// requested.
+func PostProcessFromArguments(args types.JobArguments) (*PostProcessArguments, error) {
+	v, ok := args[PostProcessArgumentKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object with a prompt, a model and max_tokens, got %T", PostProcessArgumentKey, v)
+	}
+
+	var p PostProcessArguments
+	for k, f := range m {
+		switch k {
+		case "prompt":
+			if p.Prompt, ok = f.(string); !ok {
+				return nil, fmt.Errorf("%s.prompt must be a string, got %T", PostProcessArgumentKey, f)
+			}
+		case "model":
+			if p.Model, ok = f.(string); !ok {
+				return nil, fmt.Errorf("%s.model must be a string, got %T", PostProcessArgumentKey, f)
+			}
+		case "max_tokens":
+			n, ok := f.(float64)
+			if !ok || n <= 0 || n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s.max_tokens must be a positive integer, got %v", PostProcessArgumentKey, f)
+			}
+			p.MaxTokens = uint(n)
+		case "token_budget":
+			n, ok := f.(float64)
+			if !ok || n <= 0 || n != math.Trunc(n) {
+				return nil, fmt.Errorf("%s.token_budget must be a positive integer, got %v", PostProcessArgumentKey, f)
+			}
+			p.TokenBudget = int(n)
+		default:
+			return nil, fmt.Errorf("unknown %s field %q, valid fields are prompt, model, max_tokens and token_budget", PostProcessArgumentKey, k)
+		}
+	}
+
+	if strings.TrimSpace(p.Prompt) == "" {
+		return nil, fmt.Errorf("%s.prompt is required", PostProcessArgumentKey)
+	}
+	return &p, nil
+}
+
+// PostProcessConfigured returns true if the worker has the keys needed to post-process results
+func PostProcessConfigured(jc config.JobConfiguration) bool {
+	cfg := jc.GetWebConfig()
+	return cfg.ApifyApiKey != "" && cfg.GeminiApiKey.IsValid()
+}
+
+// PostProcess runs the items of a JSON-encoded job result through the LLM processor, and returns both the result and
+// the LLM responses as a types.PostProcessedResult. Results which are not a JSON array, e.g. a single profile, are
+// processed as a single item. The items are processed in chunks as configured by config.LLMChunkConfig, and the
+// responses of every chunk are emitted to the result stream of the job as a types.PostProcessProgress.
+func PostProcess(jc config.JobConfiguration, statsCollector *stats.StatsCollector, j types.Job, data []byte, p PostProcessArguments) ([]byte, error) {
+	if !PostProcessConfigured(jc) {
+		return nil, ErrPostProcessNotConfigured
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		items = []json.RawMessage{data}
+	}
+
+	processed := make([]string, len(items))
+	var report *types.PostProcessReport
+	if len(items) > 0 {
+		cfg := jc.GetWebConfig()
+		llmClient, err := NewLLMItemProcessor(cfg.ApifyApiKey, cfg.LlmConfig, statsCollector, apifyOptions(j)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create LLM Apify client: %w", err)
+		}
+
+		maxTokens := p.MaxTokens
+		if maxTokens == 0 {
+			maxTokens = teeargs.LLMDefaultMaxTokens
+		}
+		chunkCfg := jc.GetLLMChunkConfig()
+		budget := chunkCfg.TokenBudget
+		if p.TokenBudget > 0 && (budget == 0 || p.TokenBudget < budget) {
+			budget = p.TokenBudget
+		}
+		opts := llmapify.ChunkOptions{
+			ContextTokens: chunkCfg.ContextTokens,
+			ChunkItems:    chunkCfg.ChunkItems,
+			Concurrency:   chunkCfg.Concurrency,
+			TokenBudget:   budget,
+			OnChunk: func(c llmapify.ChunkProgress) {
+				progress := types.PostProcessProgress{Chunk: c.Chunk, Chunks: c.Chunks, Items: c.Items, Processed: c.Responses}
+				if c.Err != nil {
+					progress.Error = c.Err.Error()
+				}
+				if dat, err := json.Marshal(progress); err == nil {
+					j.Stream.Emit(dat)
+				}
+			},
+		}
+
+		resp, chunkReport, err := llmapify.ProcessInChunks(items, p.Prompt, maxTokens, opts, func(chunk []json.RawMessage) ([]*teetypes.LLMProcessorResult, error) {
+			return llmClient.ProcessItems(j.WorkerID, chunk, p.Prompt, p.Model, maxTokens)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("error processing LLM: %w", err)
+		}
+		processed = resp
+		report = &types.PostProcessReport{
+			Chunks:          chunkReport.Chunks,
+			FailedChunks:    chunkReport.FailedChunks,
+			SkippedChunks:   chunkReport.SkippedChunks,
+			SplitItems:      chunkReport.SplitItems,
+			EstimatedTokens: chunkReport.EstimatedTokens,
+		}
+	}
+
+	dat, err := json.Marshal(types.PostProcessedResult{Raw: data, Processed: processed, Report: report})
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling post-processed result: %w", err)
+	}
+	return dat, nil
+}
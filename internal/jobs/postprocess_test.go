@@ -0,0 +1,51 @@
+package jobs_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+var _ = Describe("Post-processing arguments", func() {
+	It("parses the post_process argument", func() {
+		p, err := jobs.PostProcessFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+
+		p, err = jobs.PostProcessFromArguments(types.JobArguments{"post_process": map[string]any{
+			"prompt": "extract the sentiment", "model": "gemini-2.0-flash", "max_tokens": float64(100),
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(&jobs.PostProcessArguments{Prompt: "extract the sentiment", Model: "gemini-2.0-flash", MaxTokens: 100}))
+
+		p, err = jobs.PostProcessFromArguments(types.JobArguments{"post_process": map[string]any{
+			"prompt": "extract the sentiment", "token_budget": float64(10000),
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.TokenBudget).To(Equal(10000))
+	})
+
+	DescribeTable("rejects invalid post_process arguments",
+		func(v any) {
+			_, err := jobs.PostProcessFromArguments(types.JobArguments{"post_process": v})
+			Expect(err).To(HaveOccurred())
+		},
+		Entry("not an object", "summarize"),
+		Entry("no prompt", map[string]any{"model": "gemini-2.0-flash"}),
+		Entry("blank prompt", map[string]any{"prompt": "  "}),
+		Entry("model not a string", map[string]any{"prompt": "summarize", "model": 1.0}),
+		Entry("fractional max_tokens", map[string]any{"prompt": "summarize", "max_tokens": 1.5}),
+		Entry("zero max_tokens", map[string]any{"prompt": "summarize", "max_tokens": 0.0}),
+		Entry("zero token_budget", map[string]any{"prompt": "summarize", "token_budget": 0.0}),
+		Entry("unknown field", map[string]any{"prompt": "summarize", "temperature": 0.5}),
+	)
+
+	It("requires the Apify and Gemini API keys", func() {
+		Expect(jobs.PostProcessConfigured(config.JobConfiguration{"apify_api_key": "apify", "gemini_api_key": "gemini"})).To(BeTrue())
+		Expect(jobs.PostProcessConfigured(config.JobConfiguration{"apify_api_key": "apify"})).To(BeFalse())
+		Expect(jobs.PostProcessConfigured(config.JobConfiguration{"gemini_api_key": "gemini"})).To(BeFalse())
+	})
+})
@@ -1,10 +1,9 @@
 package jobs
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
-
+	"slices"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -27,12 +26,14 @@ type RedditApifyClient interface {
 	SearchPosts(workerID string, queries []string, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
 	SearchCommunities(workerID string, queries []string, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
 	SearchUsers(workerID string, queries []string, skipPosts bool, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	ScrapeSubreddit(workerID string, subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	ScrapeUserActivity(workerID string, username string, since time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
 }
 
 // NewRedditApifyClient is a function variable that can be replaced in tests.
 // It defaults to the actual implementation.
-var NewRedditApifyClient = func(apiKey string, statsCollector *stats.StatsCollector) (RedditApifyClient, error) {
-	return redditapify.NewClient(apiKey, statsCollector)
+var NewRedditApifyClient = func(apiKey string, statsCollector *stats.StatsCollector, opts ...client.Option) (RedditApifyClient, error) {
+	return redditapify.NewClient(apiKey, statsCollector, opts...)
 }
 
 type RedditScraper struct {
@@ -54,6 +55,16 @@ func NewRedditScraper(jc config.JobConfiguration, statsCollector *stats.StatsCol
 func (r *RedditScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
 	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for Reddit scrape")
 
+	if isCapabilityJob(j, CapScrapeSubreddit) {
+		return r.executeScrapeSubreddit(j)
+	}
+	if isCapabilityJob(j, CapGetUserActivity) {
+		return r.executeGetUserActivity(j)
+	}
+	if isCapabilityJob(j, CapMonitor) {
+		return r.executeMonitor(j)
+	}
+
 	jobArgs, err := teeargs.UnmarshalJobArguments(teetypes.JobType(j.Type), map[string]any(j.Arguments))
 	if err != nil {
 		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
@@ -67,7 +78,7 @@ func (r *RedditScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
 	}
 	logrus.Debugf("reddit job args: %+v", *redditArgs)
 
-	redditClient, err := NewRedditApifyClient(r.configuration.ApifyApiKey, r.statsCollector)
+	redditClient, err := NewRedditApifyClient(r.configuration.ApifyApiKey, r.statsCollector, apifyOptions(j)...)
 	if err != nil {
 		return types.JobResult{Error: "error while scraping Reddit"}, fmt.Errorf("error creating Reddit Apify client: %w", err)
 	}
@@ -110,15 +121,9 @@ func processRedditResponse(j types.Job, resp []*reddit.Response, cursor client.C
 		return types.JobResult{Error: fmt.Sprintf("error while scraping Reddit: %s", err.Error())}, fmt.Errorf("error scraping Reddit: %w", err)
 	}
 
-	data, err := json.Marshal(resp)
-	if err != nil {
-		return types.JobResult{Error: fmt.Sprintf("error marshalling Reddit response")}, fmt.Errorf("error marshalling Reddit response: %w", err)
-	}
-	return types.JobResult{
-		Data:       data,
-		Job:        j,
-		NextCursor: cursor.String(),
-	}, nil
+	res, err := apifyPageResult(resp, cursor)
+	res.Job = j
+	return res, err
 }
 
 // GetStructuredCapabilities returns the structured capabilities supported by this Twitter scraper
@@ -129,8 +134,13 @@ func (rs *RedditScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities
 	// Add Apify-specific capabilities based on available API key
 	// TODO: We should verify whether each of the actors is actually available through this API key
 	if rs.configuration.ApifyApiKey != "" {
-		capabilities[teetypes.RedditJob] = teetypes.RedditCaps
+		capabilities[teetypes.RedditJob] = append(slices.Clone(teetypes.RedditCaps), CapScrapeSubreddit, CapGetUserActivity, CapMonitor)
 	}
 
 	return capabilities
 }
+
+// GetCapabilityDetails returns the auth source of each capability. All Reddit capabilities are provided through Apify.
+func (rs *RedditScraper) GetCapabilityDetails() types.CapabilityDetails {
+	return types.NewCapabilityDetails(rs.GetStructuredCapabilities(), types.AuthSourceApify)
+}
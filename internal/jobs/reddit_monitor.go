@@ -0,0 +1,235 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/internal/jobs/redditapify"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/masa-finance/tee-worker/pkg/retry"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// CapMonitor polls a set of subreddits and search queries on an interval within a single job, until the job times out
+// or is cancelled. Every poll emits the posts which are newer than the watermark of their source on the result stream
+// of the job, see GET /job/<uuid>/stream. Like scrapesubreddit, it is handled by the RedditScraper before the arguments
+// are validated against the tee-types capabilities.
+const CapMonitor teetypes.Capability = "monitor"
+
+const (
+	defaultMonitorInterval   = 60 * time.Second
+	minMonitorInterval       = 10 * time.Second
+	defaultMonitorMaxResults = 25
+)
+
+// RedditMonitorArguments are the arguments of a monitor job
+type RedditMonitorArguments struct {
+	QueryType  string   `json:"type"`
+	Subreddits []string `json:"subreddits"` // The names of the subreddits, with or without the r/ prefix
+	Queries    []string `json:"queries"`    // Searches of all of Reddit
+	// IntervalSeconds is the time between the start of two polls, default 60 and at least 10
+	IntervalSeconds int  `json:"interval_seconds"`
+	MaxResults      uint `json:"max_results"` // Max number of posts fetched per source and poll, default 25
+	IncludeNSFW     bool `json:"include_nsfw"`
+}
+
+// RedditMonitorBatch is a batch emitted on the result stream of a monitor job
+type RedditMonitorBatch struct {
+	Poll  int            `json:"poll"` // Numbered from 1
+	Posts []*reddit.Post `json:"posts"`
+}
+
+// RedditMonitorResult is the result of a monitor job
+type RedditMonitorResult struct {
+	Polls int `json:"polls"`
+	// Posts are all the posts emitted by the job, in the order they were emitted
+	Posts []*reddit.Post `json:"posts"`
+	// Watermarks are the IDs of the newest post seen per source, keyed by r/<subreddit> or q:<query>
+	Watermarks map[string]string `json:"watermarks"`
+}
+
+// monitorSource is a subreddit or a search query polled by a monitor job
+type monitorSource struct {
+	subreddit string
+	query     string
+}
+
+// key identifies the source in the watermarks
+func (s monitorSource) key() string {
+	if s.subreddit != "" {
+		return "r/" + s.subreddit
+	}
+	return "q:" + s.query
+}
+
+// parseMonitorArguments unmarshals and validates the arguments of a monitor job
+func parseMonitorArguments(args map[string]any) (*RedditMonitorArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal monitor arguments: %w", err)
+	}
+
+	parsed := &RedditMonitorArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal monitor arguments: %w", err)
+	}
+
+	for i, subreddit := range parsed.Subreddits {
+		subreddit = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(subreddit), "/"), "r/")
+		if !subredditNamePattern.MatchString(subreddit) {
+			return nil, fmt.Errorf("invalid subreddit %q", subreddit)
+		}
+		parsed.Subreddits[i] = subreddit
+	}
+	queries := parsed.Queries[:0]
+	for _, query := range parsed.Queries {
+		if query = strings.TrimSpace(query); query != "" {
+			queries = append(queries, query)
+		}
+	}
+	parsed.Queries = queries
+	if len(parsed.Subreddits) == 0 && len(parsed.Queries) == 0 {
+		return nil, fmt.Errorf("at least one subreddit or query is required")
+	}
+
+	if parsed.IntervalSeconds == 0 {
+		parsed.IntervalSeconds = int(defaultMonitorInterval / time.Second)
+	}
+	if time.Duration(parsed.IntervalSeconds)*time.Second < minMonitorInterval {
+		return nil, fmt.Errorf("interval_seconds must be at least %d, got %d", int(minMonitorInterval/time.Second), parsed.IntervalSeconds)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultMonitorMaxResults
+	}
+
+	return parsed, nil
+}
+
+// newerPost returns true if the post ID is newer than the watermark. Reddit assigns the base 36 IDs of the posts in
+// increasing order, so they are compared as numbers.
+func newerPost(id, watermark string) bool {
+	n, err := strconv.ParseUint(strings.TrimPrefix(id, "t3_"), 36, 64)
+	if err != nil {
+		return false
+	}
+	if watermark == "" {
+		return true
+	}
+	wm, err := strconv.ParseUint(strings.TrimPrefix(watermark, "t3_"), 36, 64)
+	return err != nil || n > wm
+}
+
+// executeMonitor polls the subreddits and queries of the job until it times out or is cancelled, and returns all the
+// new posts it emitted. The watermarks are kept in the result stream of the job, so the runs of a recurring monitor job
+// and the retries of a failed one don't emit the same posts again.
+func (r *RedditScraper) executeMonitor(j types.Job) (types.JobResult, error) {
+	args, err := parseMonitorArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	redditClient, err := NewRedditApifyClient(r.configuration.ApifyApiKey, r.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error while scraping Reddit"}, fmt.Errorf("error creating Reddit Apify client: %w", err)
+	}
+
+	sources := make([]monitorSource, 0, len(args.Subreddits)+len(args.Queries))
+	for _, subreddit := range args.Subreddits {
+		sources = append(sources, monitorSource{subreddit: subreddit})
+	}
+	for _, query := range args.Queries {
+		sources = append(sources, monitorSource{query: query})
+	}
+
+	commonArgs := redditapify.CommonArgs{
+		Sort:        teetypes.RedditSortNew,
+		IncludeNSFW: args.IncludeNSFW,
+		MaxItems:    args.MaxResults,
+		MaxPosts:    args.MaxResults,
+	}
+	poll := func(source monitorSource) ([]*reddit.Response, error) {
+		var resp []*reddit.Response
+		var err error
+		if source.subreddit != "" {
+			resp, _, err = redditClient.ScrapeSubreddit(j.WorkerID, source.subreddit, teetypes.RedditSortNew, redditapify.TimeWindowDay, commonArgs, client.EmptyCursor, args.MaxResults)
+		} else {
+			resp, _, err = redditClient.SearchPosts(j.WorkerID, []string{source.query}, time.Time{}, commonArgs, client.EmptyCursor, args.MaxResults)
+		}
+		return resp, err
+	}
+
+	if j.Stream == nil {
+		// The job is not run by the job server, so the watermarks only last for this execution
+		j.Stream = types.NewResultStream()
+	}
+
+	interval := time.Duration(args.IntervalSeconds) * time.Second
+	deadline := time.Now().Add(j.Timeout)
+	ctx, cancel := jobContext(j)
+	defer cancel()
+	result := RedditMonitorResult{Posts: []*reddit.Post{}}
+
+	for {
+		result.Polls++
+		started := time.Now()
+
+		var posts []*reddit.Post
+		for _, source := range sources {
+			resp, err := poll(source)
+			if err != nil {
+				// Later polls may succeed, but a source which can't be polled at all is an error
+				if result.Polls == 1 {
+					return processRedditResponse(j, nil, client.EmptyCursor, err)
+				}
+				logrus.Warnf("Poll %d of %s failed for Reddit monitor job %s: %v", result.Polls, source.key(), j.UUID, err)
+				continue
+			}
+
+			watermark := j.Stream.Watermark(source.key())
+			newest := watermark
+			for _, item := range resp {
+				if item.Post == nil || item.Post.Stickied || !newerPost(item.Post.ID, watermark) {
+					continue
+				}
+				posts = append(posts, item.Post)
+				if newerPost(item.Post.ID, newest) {
+					newest = item.Post.ID
+				}
+			}
+			if newest != "" {
+				j.Stream.SetWatermark(source.key(), newest)
+			}
+		}
+
+		if len(posts) > 0 {
+			data, err := json.Marshal(RedditMonitorBatch{Poll: result.Polls, Posts: posts})
+			if err != nil {
+				return types.JobResult{Error: "error marshalling Reddit response"}, fmt.Errorf("error marshalling Reddit response: %w", err)
+			}
+			j.Stream.Emit(data)
+			result.Posts = append(result.Posts, posts...)
+		}
+
+		// Stop if the next poll would not start before the job times out, or if the job is cancelled while waiting
+		next := started.Add(interval)
+		if j.Timeout <= 0 || !next.Before(deadline) || retry.Wait(ctx, time.Until(next)) != nil {
+			break
+		}
+	}
+
+	result.Watermarks = j.Stream.Watermarks()
+	data, err := json.Marshal(result)
+	if err != nil {
+		return types.JobResult{Error: "error marshalling Reddit response"}, fmt.Errorf("error marshalling Reddit response: %w", err)
+	}
+	return types.JobResult{Data: data, Job: j}, nil
+}
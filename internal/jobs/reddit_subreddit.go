@@ -0,0 +1,147 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/internal/jobs/redditapify"
+	"github.com/masa-finance/tee-worker/pkg/client"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// CapScrapeSubreddit scrapes the posts of a single subreddit created within a time window, rather than searching all
+// of Reddit. Like getbyids for Twitter, it is handled by the RedditScraper before the arguments are validated against
+// the tee-types capabilities.
+const CapScrapeSubreddit teetypes.Capability = "scrapesubreddit"
+
+const (
+	defaultSubredditMaxItems = 10
+	defaultSubredditSort     = teetypes.RedditSortTop
+	defaultSubredditTime     = redditapify.TimeWindowDay
+)
+
+// subredditSorts are the sort orders of subreddit listings
+var subredditSorts = []teetypes.RedditSortType{teetypes.RedditSortHot, teetypes.RedditSortNew, teetypes.RedditSortTop, teetypes.RedditSortRising}
+
+// subredditNamePattern matches the names Reddit allows for subreddits
+var subredditNamePattern = regexp.MustCompile(`^[A-Za-z0-9_]{2,21}$`)
+
+// RedditScrapeSubredditArguments are the arguments of a scrapesubreddit job
+type RedditScrapeSubredditArguments struct {
+	QueryType   string                  `json:"type"`
+	Subreddit   string                  `json:"subreddit"` // The name of the subreddit, with or without the r/ prefix
+	Sort        teetypes.RedditSortType `json:"sort"`      // hot, new, top or rising, default top
+	Time        redditapify.TimeWindow  `json:"time"`      // day, week, month or year, default day
+	IncludeNSFW bool                    `json:"include_nsfw"`
+	MaxItems    uint                    `json:"max_items"`   // Max number of posts to scrape (total), default 10
+	MaxResults  uint                    `json:"max_results"` // Max number of posts per page, default MaxItems
+	NextCursor  string                  `json:"next_cursor"`
+}
+
+// parseScrapeSubredditArguments unmarshals and validates the arguments of a scrapesubreddit job
+func parseScrapeSubredditArguments(args map[string]any) (*RedditScrapeSubredditArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal scrapesubreddit arguments: %w", err)
+	}
+
+	parsed := &RedditScrapeSubredditArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal scrapesubreddit arguments: %w", err)
+	}
+
+	parsed.Subreddit = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(parsed.Subreddit), "/"), "r/")
+	if !subredditNamePattern.MatchString(parsed.Subreddit) {
+		return nil, fmt.Errorf("invalid subreddit %q", parsed.Subreddit)
+	}
+
+	parsed.Sort = teetypes.RedditSortType(strings.ToLower(string(parsed.Sort)))
+	if parsed.Sort == "" {
+		parsed.Sort = defaultSubredditSort
+	}
+	if !slices.Contains(subredditSorts, parsed.Sort) {
+		return nil, fmt.Errorf("invalid sort %q, must be one of %v", parsed.Sort, subredditSorts)
+	}
+
+	parsed.Time = redditapify.TimeWindow(strings.ToLower(string(parsed.Time)))
+	if parsed.Time == "" {
+		parsed.Time = defaultSubredditTime
+	}
+	if !slices.Contains(redditapify.AllTimeWindows, parsed.Time) {
+		return nil, fmt.Errorf("invalid time %q, must be one of %v", parsed.Time, redditapify.AllTimeWindows)
+	}
+
+	if parsed.MaxItems == 0 {
+		parsed.MaxItems = defaultSubredditMaxItems
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = parsed.MaxItems
+	}
+
+	return parsed, nil
+}
+
+// executeScrapeSubreddit returns a page of the posts of a subreddit, with the metadata of the subreddit
+func (r *RedditScraper) executeScrapeSubreddit(j types.Job) (types.JobResult, error) {
+	args, err := parseScrapeSubredditArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	redditClient, err := NewRedditApifyClient(r.configuration.ApifyApiKey, r.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error while scraping Reddit"}, fmt.Errorf("error creating Reddit Apify client: %w", err)
+	}
+
+	commonArgs := redditapify.CommonArgs{
+		Sort:        args.Sort,
+		IncludeNSFW: args.IncludeNSFW,
+		MaxItems:    args.MaxItems,
+		MaxPosts:    args.MaxItems,
+	}
+	resp, cursor, err := redditClient.ScrapeSubreddit(j.WorkerID, args.Subreddit, args.Sort, args.Time, commonArgs, client.Cursor(args.NextCursor), args.MaxResults)
+	if err != nil {
+		return processRedditResponse(j, nil, cursor, err)
+	}
+
+	res, err := apifyPageResult(subredditResponse(args, resp), cursor)
+	res.Job = j
+	return res, err
+}
+
+// subredditResponse splits the items scraped from a subreddit into the subreddit itself, its pinned posts and its
+// other posts
+func subredditResponse(args *RedditScrapeSubredditArguments, resp []*reddit.Response) reddit.SubredditResponse {
+	res := reddit.SubredditResponse{
+		Subreddit: args.Subreddit,
+		Sort:      string(args.Sort),
+		Time:      string(args.Time),
+		Pinned:    []*reddit.Post{},
+		Posts:     []*reddit.Post{},
+	}
+
+	for _, item := range resp {
+		switch {
+		case item.Post != nil && item.Post.Stickied:
+			res.Pinned = append(res.Pinned, item.Post)
+		case item.Post != nil:
+			res.Posts = append(res.Posts, item.Post)
+		case item.Community != nil:
+			name := strings.TrimPrefix(item.Community.Name, "r/")
+			if res.Community == nil || strings.EqualFold(name, args.Subreddit) {
+				res.Community = item.Community
+			}
+		}
+	}
+
+	return res
+}
@@ -1,6 +1,7 @@
 package jobs_test
 
 import (
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"time"
@@ -22,10 +23,12 @@ import (
 
 // MockRedditApifyClient is a mock implementation of the RedditApifyClient.
 type MockRedditApifyClient struct {
-	ScrapeUrlsFunc        func(urls []teetypes.RedditStartURL, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
-	SearchPostsFunc       func(queries []string, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
-	SearchCommunitiesFunc func(queries []string, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
-	SearchUsersFunc       func(queries []string, skipPosts bool, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	ScrapeUrlsFunc         func(urls []teetypes.RedditStartURL, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	SearchPostsFunc        func(queries []string, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	SearchCommunitiesFunc  func(queries []string, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	SearchUsersFunc        func(queries []string, skipPosts bool, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	ScrapeSubredditFunc    func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
+	ScrapeUserActivityFunc func(username string, since time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error)
 }
 
 func (m *MockRedditApifyClient) ScrapeUrls(_ string, urls []teetypes.RedditStartURL, after time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
@@ -60,6 +63,20 @@ func (m *MockRedditApifyClient) SearchUsers(_ string, queries []string, skipPost
 	return nil, "", nil
 }
 
+func (m *MockRedditApifyClient) ScrapeSubreddit(_ string, subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+	if m != nil && m.ScrapeSubredditFunc != nil {
+		return m.ScrapeSubredditFunc(subreddit, sort, window, args, cursor, maxResults)
+	}
+	return nil, "", nil
+}
+
+func (m *MockRedditApifyClient) ScrapeUserActivity(_ string, username string, since time.Time, args redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+	if m != nil && m.ScrapeUserActivityFunc != nil {
+		return m.ScrapeUserActivityFunc(username, since, args, cursor, maxResults)
+	}
+	return nil, "", nil
+}
+
 var _ = Describe("RedditScraper", func() {
 	var (
 		scraper        *jobs.RedditScraper
@@ -77,7 +94,7 @@ var _ = Describe("RedditScraper", func() {
 		mockClient = &MockRedditApifyClient{}
 
 		// Replace the client creation function with one that returns the mock
-		jobs.NewRedditApifyClient = func(apiKey string, _ *stats.StatsCollector) (jobs.RedditApifyClient, error) {
+		jobs.NewRedditApifyClient = func(apiKey string, _ *stats.StatsCollector, _ ...client.Option) (jobs.RedditApifyClient, error) {
 			return mockClient, nil
 		}
 
@@ -113,6 +130,7 @@ var _ = Describe("RedditScraper", func() {
 			result, err := scraper.ExecuteJob(job)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(result.NextCursor).To(Equal("next"))
+			Expect(result.HasMore).To(BeTrue())
 			var resp []*reddit.Response
 			err = json.Unmarshal(result.Data, &resp)
 			Expect(err).NotTo(HaveOccurred())
@@ -122,6 +140,26 @@ var _ = Describe("RedditScraper", func() {
 			Expect(resp[0].User.ID).To(Equal("user1"))
 		})
 
+		It("should estimate the total number of results from the cursor of the dataset", func() {
+			job.Arguments = map[string]any{
+				"type": teetypes.RedditScrapeUrls,
+				"urls": []string{"https://www.reddit.com/r/golang/comments/1abc/title/"},
+			}
+			next := client.Cursor(base64.StdEncoding.EncodeToString([]byte(`{"offset":1,"total":7}`)))
+			mockClient.ScrapeUrlsFunc = func(urls []teetypes.RedditStartURL, after time.Time, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				return []*reddit.Response{{TypeSwitch: &reddit.TypeSwitch{Type: reddit.UserResponse}, User: &reddit.User{ID: "user1", DataType: string(reddit.UserResponse)}}}, next, nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.HasMore).To(BeTrue())
+			Expect(result.TotalEstimate).To(HaveValue(Equal(7)))
+
+			page := result.Paginated()
+			Expect(page.NextCursor).To(Equal(next.String()))
+			Expect(page.Items).To(MatchJSON(result.Data))
+		})
+
 		It("should call SearchUsers for the correct QueryType", func() {
 			job.Arguments = map[string]any{
 				"type":    teetypes.RedditSearchUsers,
@@ -191,6 +229,273 @@ var _ = Describe("RedditScraper", func() {
 			Expect(resp[0].Community.ID).To(Equal("comm1"))
 		})
 
+		It("should call ScrapeSubreddit for the scrapesubreddit QueryType", func() {
+			job.Arguments = map[string]any{
+				"type":      "scrapesubreddit",
+				"subreddit": "r/golang",
+				"sort":      "TOP",
+				"time":      "week",
+				"max_items": 50,
+			}
+
+			mockClient.ScrapeSubredditFunc = func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(subreddit).To(Equal("golang"))
+				Expect(sort).To(Equal(teetypes.RedditSortTop))
+				Expect(window).To(Equal(redditapify.TimeWindowWeek))
+				Expect(cArgs.MaxItems).To(BeEquivalentTo(50))
+				Expect(maxResults).To(BeEquivalentTo(50))
+				return []*reddit.Response{
+					{TypeSwitch: &reddit.TypeSwitch{Type: reddit.CommunityResponse}, Community: &reddit.Community{ID: "other", Name: "r/rust", DataType: string(reddit.CommunityResponse)}},
+					{TypeSwitch: &reddit.TypeSwitch{Type: reddit.PostResponse}, Post: &reddit.Post{ID: "post1", Stickied: true, DataType: string(reddit.PostResponse)}},
+					{TypeSwitch: &reddit.TypeSwitch{Type: reddit.CommunityResponse}, Community: &reddit.Community{ID: "comm1", Name: "r/golang", NumberOfMembers: 250000, DataType: string(reddit.CommunityResponse)}},
+					{TypeSwitch: &reddit.TypeSwitch{Type: reddit.PostResponse}, Post: &reddit.Post{ID: "post2", DataType: string(reddit.PostResponse)}},
+				}, "next-sub", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.NextCursor).To(Equal("next-sub"))
+			var resp reddit.SubredditResponse
+			Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+			Expect(resp.Subreddit).To(Equal("golang"))
+			Expect(resp.Sort).To(Equal("top"))
+			Expect(resp.Time).To(Equal("week"))
+			Expect(resp.Community).NotTo(BeNil())
+			Expect(resp.Community.ID).To(Equal("comm1"))
+			Expect(resp.Community.NumberOfMembers).To(Equal(250000))
+			Expect(resp.Pinned).To(HaveLen(1))
+			Expect(resp.Pinned[0].ID).To(Equal("post1"))
+			Expect(resp.Posts).To(HaveLen(1))
+			Expect(resp.Posts[0].ID).To(Equal("post2"))
+		})
+
+		It("should default to the top posts of the day", func() {
+			job.Arguments = map[string]any{"type": "scrapesubreddit", "subreddit": "golang"}
+
+			mockClient.ScrapeSubredditFunc = func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(sort).To(Equal(teetypes.RedditSortTop))
+				Expect(window).To(Equal(redditapify.TimeWindowDay))
+				Expect(maxResults).To(BeEquivalentTo(10))
+				return nil, "", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result.Data)).To(ContainSubstring(`"posts":[]`))
+		})
+
+		It("should reject invalid scrapesubreddit arguments", func() {
+			for _, args := range []map[string]any{
+				{"type": "scrapesubreddit"},
+				{"type": "scrapesubreddit", "subreddit": "not a subreddit"},
+				{"type": "scrapesubreddit", "subreddit": "golang", "sort": "relevance"},
+				{"type": "scrapesubreddit", "subreddit": "golang", "time": "decade"},
+			} {
+				job.Arguments = args
+				_, err := scraper.ExecuteJob(job)
+				Expect(err).To(HaveOccurred(), "%v", args)
+			}
+		})
+
+		It("should report scrapesubreddit as a capability", func() {
+			Expect(scraper.GetStructuredCapabilities()[teetypes.RedditJob]).To(ContainElement(jobs.CapScrapeSubreddit))
+		})
+
+		It("should merge the posts and comments of a user for the getuseractivity QueryType", func() {
+			job.Arguments = map[string]any{
+				"type":         "getuseractivity",
+				"username":     "u/Alice",
+				"time":         "month",
+				"max_posts":    2,
+				"max_comments": 1,
+			}
+
+			now := time.Now()
+			post := func(id, username string, age time.Duration) *reddit.Response {
+				return &reddit.Response{TypeSwitch: &reddit.TypeSwitch{Type: reddit.PostResponse}, Post: &reddit.Post{ID: id, Username: username, CreatedAt: now.Add(-age), DataType: string(reddit.PostResponse)}}
+			}
+			comment := func(id, username string, age time.Duration) *reddit.Response {
+				return &reddit.Response{TypeSwitch: &reddit.TypeSwitch{Type: reddit.CommentResponse}, Comment: &reddit.Comment{ID: id, Username: username, CreatedAt: now.Add(-age), DataType: string(reddit.CommentResponse)}}
+			}
+
+			mockClient.ScrapeUserActivityFunc = func(username string, since time.Time, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(username).To(Equal("Alice"))
+				Expect(since).To(BeTemporally("~", now.AddDate(0, -1, 0), time.Second))
+				Expect(cArgs.MaxPosts).To(BeEquivalentTo(2))
+				Expect(cArgs.MaxComments).To(BeEquivalentTo(1))
+				Expect(maxResults).To(BeEquivalentTo(3))
+				return []*reddit.Response{
+					{TypeSwitch: &reddit.TypeSwitch{Type: reddit.UserResponse}, User: &reddit.User{ID: "alice", Username: "Alice", DataType: string(reddit.UserResponse)}},
+					post("post1", "Alice", 3*time.Hour),
+					comment("comment1", "alice", time.Hour),
+					comment("reply", "bob", 30*time.Minute),
+					post("old", "Alice", 60*24*time.Hour),
+					post("post2", "Alice", 2*time.Hour),
+					comment("comment2", "Alice", 4*time.Hour),
+					post("post3", "Alice", 5*time.Hour),
+				}, "next-activity", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.NextCursor).To(Equal("next-activity"))
+			var items []map[string]any
+			Expect(json.Unmarshal(result.Data, &items)).To(Succeed())
+			ids := make([]any, len(items))
+			for i, item := range items {
+				ids[i] = item["id"]
+			}
+			Expect(ids).To(Equal([]any{"comment1", "post2", "post1"}))
+			Expect(items[0]["dataType"]).To(Equal("comment"))
+			Expect(items[1]["dataType"]).To(Equal("post"))
+
+			Eventually(func() uint {
+				statsCollector.Stats.Lock()
+				defer statsCollector.Stats.Unlock()
+				return statsCollector.Stats.Stats[job.WorkerID][stats.RedditUserActivityPosts]
+			}).Should(BeEquivalentTo(2))
+			Eventually(func() uint {
+				statsCollector.Stats.Lock()
+				defer statsCollector.Stats.Unlock()
+				return statsCollector.Stats.Stats[job.WorkerID][stats.RedditUserActivityComments]
+			}).Should(BeEquivalentTo(1))
+		})
+
+		It("should default to 25 posts and comments of the last week", func() {
+			job.Arguments = map[string]any{"type": "getuseractivity", "username": "alice"}
+
+			mockClient.ScrapeUserActivityFunc = func(username string, since time.Time, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(since).To(BeTemporally("~", time.Now().AddDate(0, 0, -7), time.Second))
+				Expect(cArgs.MaxPosts).To(BeEquivalentTo(25))
+				Expect(cArgs.MaxComments).To(BeEquivalentTo(25))
+				Expect(maxResults).To(BeEquivalentTo(50))
+				return nil, "", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result.Data)).To(Equal("[]"))
+		})
+
+		It("should reject invalid getuseractivity arguments", func() {
+			for _, args := range []map[string]any{
+				{"type": "getuseractivity"},
+				{"type": "getuseractivity", "username": "not a user"},
+				{"type": "getuseractivity", "username": "alice", "time": "decade"},
+			} {
+				job.Arguments = args
+				_, err := scraper.ExecuteJob(job)
+				Expect(err).To(HaveOccurred(), "%v", args)
+			}
+		})
+
+		It("should report getuseractivity as a capability", func() {
+			Expect(scraper.GetStructuredCapabilities()[teetypes.RedditJob]).To(ContainElement(jobs.CapGetUserActivity))
+		})
+
+		It("should emit the new posts of every monitored source", func() {
+			job.Arguments = map[string]any{
+				"type":       jobs.CapMonitor,
+				"subreddits": []string{"r/golang"},
+				"queries":    []string{"tee worker"},
+			}
+			job.Stream = types.NewResultStream()
+			job.Stream.SetWatermark("r/golang", "t3_b")
+
+			post := func(id string) *reddit.Response {
+				return &reddit.Response{TypeSwitch: &reddit.TypeSwitch{Type: reddit.PostResponse}, Post: &reddit.Post{ID: id, DataType: string(reddit.PostResponse)}}
+			}
+			mockClient.ScrapeSubredditFunc = func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(subreddit).To(Equal("golang"))
+				Expect(sort).To(Equal(teetypes.RedditSortNew))
+				Expect(maxResults).To(Equal(uint(25)))
+				return []*reddit.Response{post("t3_c"), post("t3_b"), post("t3_a")}, "", nil
+			}
+			mockClient.SearchPostsFunc = func(queries []string, after time.Time, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				Expect(queries).To(Equal([]string{"tee worker"}))
+				Expect(cArgs.Sort).To(Equal(teetypes.RedditSortNew))
+				return []*reddit.Response{post("t3_z1"), post("t3_z0")}, "", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+
+			var res jobs.RedditMonitorResult
+			Expect(json.Unmarshal(result.Data, &res)).To(Succeed())
+			Expect(res.Polls).To(Equal(1))
+			ids := []string{}
+			for _, p := range res.Posts {
+				ids = append(ids, p.ID)
+			}
+			Expect(ids).To(Equal([]string{"t3_c", "t3_z1", "t3_z0"}))
+			Expect(res.Watermarks).To(Equal(map[string]string{"r/golang": "t3_c", "q:tee worker": "t3_z1"}))
+
+			batches, _, _, err := job.Stream.Since(0, func(data []byte) (string, error) { return string(data), nil })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(batches).To(HaveLen(1))
+			var batch jobs.RedditMonitorBatch
+			Expect(json.Unmarshal([]byte(batches[0].Data), &batch)).To(Succeed())
+			Expect(batch.Poll).To(Equal(1))
+			Expect(batch.Posts).To(HaveLen(3))
+		})
+
+		It("should poll again after the interval until the job is cancelled", func() {
+			job.Arguments = map[string]any{
+				"type":             jobs.CapMonitor,
+				"subreddits":       []string{"golang"},
+				"interval_seconds": 10,
+			}
+			job.Timeout = time.Minute
+			cancelled := make(chan struct{})
+			job.Cancelled = cancelled
+
+			polls := 0
+			mockClient.ScrapeSubredditFunc = func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				polls++
+				close(cancelled)
+				return nil, "", nil
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(polls).To(Equal(1))
+
+			var res jobs.RedditMonitorResult
+			Expect(json.Unmarshal(result.Data, &res)).To(Succeed())
+			Expect(res.Polls).To(Equal(1))
+			Expect(res.Posts).To(BeEmpty())
+		})
+
+		It("should fail if a source can't be polled the first time", func() {
+			job.Arguments = map[string]any{
+				"type":       jobs.CapMonitor,
+				"subreddits": []string{"golang"},
+			}
+			mockClient.ScrapeSubredditFunc = func(subreddit string, sort teetypes.RedditSortType, window redditapify.TimeWindow, cArgs redditapify.CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+				return nil, "", errors.New("actor failed")
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Error).To(ContainSubstring("actor failed"))
+		})
+
+		It("should reject invalid monitor arguments", func() {
+			for _, args := range []map[string]any{
+				{"type": "monitor"},
+				{"type": "monitor", "subreddits": []string{"not a subreddit"}},
+				{"type": "monitor", "queries": []string{"tee"}, "interval_seconds": 5},
+			} {
+				job.Arguments = args
+				_, err := scraper.ExecuteJob(job)
+				Expect(err).To(HaveOccurred(), "%v", args)
+			}
+		})
+
+		It("should report monitor as a capability", func() {
+			Expect(scraper.GetStructuredCapabilities()[teetypes.RedditJob]).To(ContainElement(jobs.CapMonitor))
+		})
+
 		It("should return an error for an invalid QueryType", func() {
 			job.Arguments = map[string]any{
 				"type": "invalid-type",
@@ -220,7 +525,7 @@ var _ = Describe("RedditScraper", func() {
 		})
 
 		It("should handle errors when creating the client", func() {
-			jobs.NewRedditApifyClient = func(apiKey string, _ *stats.StatsCollector) (jobs.RedditApifyClient, error) {
+			jobs.NewRedditApifyClient = func(apiKey string, _ *stats.StatsCollector, _ ...client.Option) (jobs.RedditApifyClient, error) {
 				return nil, errors.New("client creation failed")
 			}
 			job.Arguments = map[string]any{
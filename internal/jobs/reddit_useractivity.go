@@ -0,0 +1,162 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/internal/jobs/redditapify"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/client"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// CapGetUserActivity returns the recent posts and comments of a Reddit user across all subreddits. Like
+// scrapesubreddit, it is handled by the RedditScraper before the arguments are validated against the tee-types
+// capabilities.
+const CapGetUserActivity teetypes.Capability = "getuseractivity"
+
+const (
+	defaultUserActivityMaxPosts    = 25
+	defaultUserActivityMaxComments = 25
+	defaultUserActivityTime        = redditapify.TimeWindowWeek
+)
+
+// redditUsernamePattern matches the names Reddit allows for users
+var redditUsernamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]{3,20}$`)
+
+// RedditGetUserActivityArguments are the arguments of a getuseractivity job
+type RedditGetUserActivityArguments struct {
+	QueryType   string                 `json:"type"`
+	Username    string                 `json:"username"` // The name of the user, with or without the u/ prefix
+	Time        redditapify.TimeWindow `json:"time"`     // day, week, month or year, default week
+	IncludeNSFW bool                   `json:"include_nsfw"`
+	MaxPosts    uint                   `json:"max_posts"`    // Max number of posts, default 25
+	MaxComments uint                   `json:"max_comments"` // Max number of comments, default 25
+	MaxResults  uint                   `json:"max_results"`  // Max number of items per page, default MaxPosts + MaxComments
+	NextCursor  string                 `json:"next_cursor"`
+}
+
+// parseGetUserActivityArguments unmarshals and validates the arguments of a getuseractivity job
+func parseGetUserActivityArguments(args map[string]any) (*RedditGetUserActivityArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getuseractivity arguments: %w", err)
+	}
+
+	parsed := &RedditGetUserActivityArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getuseractivity arguments: %w", err)
+	}
+
+	parsed.Username = strings.TrimPrefix(strings.TrimSpace(parsed.Username), "/")
+	for _, prefix := range []string{"u/", "user/"} {
+		parsed.Username = strings.TrimPrefix(parsed.Username, prefix)
+	}
+	if !redditUsernamePattern.MatchString(parsed.Username) {
+		return nil, fmt.Errorf("invalid username %q", parsed.Username)
+	}
+
+	parsed.Time = redditapify.TimeWindow(strings.ToLower(string(parsed.Time)))
+	if parsed.Time == "" {
+		parsed.Time = defaultUserActivityTime
+	}
+	if !slices.Contains(redditapify.AllTimeWindows, parsed.Time) {
+		return nil, fmt.Errorf("invalid time %q, must be one of %v", parsed.Time, redditapify.AllTimeWindows)
+	}
+
+	if parsed.MaxPosts == 0 && parsed.MaxComments == 0 {
+		parsed.MaxPosts = defaultUserActivityMaxPosts
+		parsed.MaxComments = defaultUserActivityMaxComments
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = parsed.MaxPosts + parsed.MaxComments
+	}
+
+	return parsed, nil
+}
+
+// executeGetUserActivity returns a page of the posts and comments of a user, newest first
+func (r *RedditScraper) executeGetUserActivity(j types.Job) (types.JobResult, error) {
+	args, err := parseGetUserActivityArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	redditClient, err := NewRedditApifyClient(r.configuration.ApifyApiKey, r.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error while scraping Reddit"}, fmt.Errorf("error creating Reddit Apify client: %w", err)
+	}
+
+	commonArgs := redditapify.CommonArgs{
+		Sort:        teetypes.RedditSortNew,
+		IncludeNSFW: args.IncludeNSFW,
+		MaxItems:    args.MaxPosts + args.MaxComments,
+		MaxPosts:    args.MaxPosts,
+		MaxComments: args.MaxComments,
+	}
+	since := args.Time.Since(time.Now())
+	resp, cursor, err := redditClient.ScrapeUserActivity(j.WorkerID, args.Username, since, commonArgs, client.Cursor(args.NextCursor), args.MaxResults)
+	if err != nil {
+		return processRedditResponse(j, nil, cursor, err)
+	}
+
+	activity := userActivity(args, since, resp)
+	if r.statsCollector != nil {
+		var posts, comments uint
+		for _, item := range activity {
+			if item.Post != nil {
+				posts++
+			} else {
+				comments++
+			}
+		}
+		r.statsCollector.Add(j.WorkerID, stats.RedditUserActivityPosts, posts)
+		r.statsCollector.Add(j.WorkerID, stats.RedditUserActivityComments, comments)
+	}
+	return processRedditResponse(j, activity, cursor, nil)
+}
+
+// userActivity keeps the posts and comments of the user created since the given time, up to the maximum number of
+// each, and merges them newest first. Other items, such as the profile of the user or replies by other users, are
+// left out.
+func userActivity(args *RedditGetUserActivityArguments, since time.Time, resp []*reddit.Response) []*reddit.Response {
+	activity := []*reddit.Response{}
+	var posts, comments uint
+	for _, item := range resp {
+		switch {
+		case item.Post != nil && strings.EqualFold(item.Post.Username, args.Username) && !item.Post.CreatedAt.Before(since):
+			if posts < args.MaxPosts {
+				posts++
+				activity = append(activity, item)
+			}
+		case item.Comment != nil && strings.EqualFold(item.Comment.Username, args.Username) && !item.Comment.CreatedAt.Before(since):
+			if comments < args.MaxComments {
+				comments++
+				activity = append(activity, item)
+			}
+		}
+	}
+
+	slices.SortStableFunc(activity, func(a, b *reddit.Response) int {
+		return activityCreatedAt(b).Compare(activityCreatedAt(a))
+	})
+	return activity
+}
+
+// activityCreatedAt returns the creation time of a post or comment
+func activityCreatedAt(item *reddit.Response) time.Time {
+	if item.Post != nil {
+		return item.Post.CreatedAt
+	}
+	return item.Comment.CreatedAt
+}
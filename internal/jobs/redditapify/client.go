@@ -3,10 +3,12 @@ package redditapify
 import (
 	"encoding/json"
 	"fmt"
+	"net/url"
 	"time"
 
 	"github.com/sirupsen/logrus"
 
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/api/types/reddit"
 	"github.com/masa-finance/tee-worker/internal/apify"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
@@ -49,6 +51,33 @@ func (args *CommonArgs) ToActorRequest() RedditActorRequest {
 	}
 }
 
+// TimeWindow is the period of time in which the posts of a subreddit listing were created
+type TimeWindow string
+
+const (
+	TimeWindowDay   TimeWindow = "day"
+	TimeWindowWeek  TimeWindow = "week"
+	TimeWindowMonth TimeWindow = "month"
+	TimeWindowYear  TimeWindow = "year"
+)
+
+// AllTimeWindows are the valid time windows
+var AllTimeWindows = []TimeWindow{TimeWindowDay, TimeWindowWeek, TimeWindowMonth, TimeWindowYear}
+
+// Since returns the start of the window which ends at now
+func (w TimeWindow) Since(now time.Time) time.Time {
+	switch w {
+	case TimeWindowWeek:
+		return now.AddDate(0, 0, -7)
+	case TimeWindowMonth:
+		return now.AddDate(0, -1, 0)
+	case TimeWindowYear:
+		return now.AddDate(-1, 0, 0)
+	default:
+		return now.AddDate(0, 0, -1)
+	}
+}
+
 // RedditActorRequest represents the query parameters for the Apify Reddit Scraper actor.
 // Based on the input schema of https://apify.com/trudax/reddit-scraper
 type RedditActorRequest struct {
@@ -79,13 +108,13 @@ type RedditApifyClient struct {
 
 // NewInternalClient is a function variable that can be replaced in tests.
 // It defaults to the actual implementation.
-var NewInternalClient = func(apiKey string) (client.Apify, error) {
-	return client.NewApifyClient(apiKey)
+var NewInternalClient = func(apiKey string, opts ...client.Option) (client.Apify, error) {
+	return client.NewApifyClient(apiKey, opts...)
 }
 
 // NewClient creates a new Reddit Apify client
-func NewClient(apiToken string, statsCollector *stats.StatsCollector) (*RedditApifyClient, error) {
-	apifyClient, err := NewInternalClient(apiToken)
+func NewClient(apiToken string, statsCollector *stats.StatsCollector, opts ...client.Option) (*RedditApifyClient, error) {
+	apifyClient, err := NewInternalClient(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create apify client: %w", err)
 	}
@@ -118,6 +147,47 @@ func (c *RedditApifyClient) ScrapeUrls(workerID string, urls []teetypes.RedditSt
 	return c.queryReddit(workerID, input, cursor, maxResults)
 }
 
+// ScrapeSubreddit scrapes the listing of a subreddit, e.g. https://www.reddit.com/r/golang/top/?t=week, returning its
+// posts created within the time window together with the subreddit itself. Comments are not scraped.
+func (c *RedditApifyClient) ScrapeSubreddit(workerID string, subreddit string, sort teetypes.RedditSortType, window TimeWindow, args CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+	u := url.URL{Scheme: "https", Host: "www.reddit.com", Path: "/r/" + subreddit + "/" + string(sort) + "/"}
+	if sort == teetypes.RedditSortTop {
+		u.RawQuery = url.Values{"t": {string(window)}}.Encode()
+	}
+	since := window.Since(time.Now())
+
+	input := args.ToActorRequest()
+	input.StartUrls = []teetypes.RedditStartURL{{URL: u.String(), Method: "GET"}}
+	input.Searches = nil
+	input.Sort = sort
+	input.PostDateLimit = &since
+	input.MaxComments = 0
+	input.SkipComments = true
+	input.SearchPosts = true
+	input.SearchCommunities = true
+
+	return c.queryReddit(workerID, input, cursor, maxResults)
+}
+
+// ScrapeUserActivity scrapes the profile page of a user, e.g. https://www.reddit.com/user/spez/, returning the posts
+// and comments the user made across all subreddits since the given time. Comments are only scraped if
+// args.MaxComments is not zero.
+func (c *RedditApifyClient) ScrapeUserActivity(workerID string, username string, since time.Time, args CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
+	u := url.URL{Scheme: "https", Host: "www.reddit.com", Path: "/user/" + username + "/"}
+
+	input := args.ToActorRequest()
+	input.StartUrls = []teetypes.RedditStartURL{{URL: u.String(), Method: "GET"}}
+	input.Searches = nil
+	input.Sort = teetypes.RedditSortNew
+	input.PostDateLimit = &since
+	input.SearchPosts = true
+	input.SearchComments = true
+	input.SkipUserPosts = false
+	input.SkipComments = input.MaxComments == 0
+
+	return c.queryReddit(workerID, input, cursor, maxResults)
+}
+
 // SearchPosts searches Reddit posts
 func (c *RedditApifyClient) SearchPosts(workerID string, queries []string, after time.Time, args CommonArgs, cursor client.Cursor, maxResults uint) ([]*reddit.Response, client.Cursor, error) {
 	input := args.ToActorRequest()
@@ -183,7 +253,13 @@ func (c *RedditApifyClient) queryReddit(workerID string, input RedditActorReques
 	}
 
 	if c.statsCollector != nil {
-		c.statsCollector.Add(workerID, stats.RedditReturnedItems, uint(len(response)))
+		byType := make(map[reddit.ResponseType]uint)
+		for _, resp := range response {
+			byType[resp.TypeSwitch.Type]++
+		}
+		for typ, n := range byType {
+			c.statsCollector.AddWithDimensions(workerID, stats.RedditReturnedItems, n, stats.Dimensions{Provider: string(types.AuthSourceApify), ResultType: string(typ)})
+		}
 	}
 
 	return response, nextCursor, nil
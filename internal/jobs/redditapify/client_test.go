@@ -53,7 +53,7 @@ var _ = Describe("RedditApifyClient", func() {
 	BeforeEach(func() {
 		mockClient = &MockApifyClient{}
 		// Replace the client creation function with one that returns the mock
-		redditapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+		redditapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 			return mockClient, nil
 		}
 		var err error
@@ -151,6 +151,80 @@ var _ = Describe("RedditApifyClient", func() {
 		})
 	})
 
+	Describe("ScrapeSubreddit", func() {
+		It("should scrape the top posts of the time window", func() {
+			args := redditapify.CommonArgs{MaxItems: 25, MaxPosts: 25, MaxComments: 10}
+
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				Expect(actorID).To(Equal(apify.ActorIds.RedditScraper))
+				req := input.(redditapify.RedditActorRequest)
+				Expect(req.StartUrls).To(Equal([]teetypes.RedditStartURL{{URL: "https://www.reddit.com/r/golang/top/?t=week", Method: "GET"}}))
+				Expect(req.Searches).To(BeNil())
+				Expect(req.Sort).To(Equal(teetypes.RedditSortTop))
+				Expect(*req.PostDateLimit).To(BeTemporally("~", time.Now().AddDate(0, 0, -7), time.Second))
+				Expect(req.SearchPosts).To(BeTrue())
+				Expect(req.SearchCommunities).To(BeTrue())
+				Expect(req.SearchUsers).To(BeFalse())
+				Expect(req.SkipComments).To(BeTrue())
+				Expect(req.MaxComments).To(BeZero())
+				Expect(req.MaxItems).To(Equal(uint(25)))
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "next", nil
+			}
+
+			_, cursor, err := redditClient.ScrapeSubreddit("", "golang", teetypes.RedditSortTop, redditapify.TimeWindowWeek, args, "", 25)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cursor).To(Equal(client.Cursor("next")))
+		})
+
+		It("should only add the time window to the URL of top posts", func() {
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				req := input.(redditapify.RedditActorRequest)
+				Expect(req.StartUrls[0].URL).To(Equal("https://www.reddit.com/r/golang/new/"))
+				Expect(*req.PostDateLimit).To(BeTemporally("~", time.Now().AddDate(0, -1, 0), time.Second))
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "", nil
+			}
+
+			_, _, err := redditClient.ScrapeSubreddit("", "golang", teetypes.RedditSortNew, redditapify.TimeWindowMonth, redditapify.CommonArgs{}, "", 10)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
+	Describe("ScrapeUserActivity", func() {
+		It("should scrape the posts and comments of the user since the given time", func() {
+			since := time.Now().AddDate(0, 0, -7)
+			args := redditapify.CommonArgs{MaxItems: 30, MaxPosts: 20, MaxComments: 10}
+
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				req := input.(redditapify.RedditActorRequest)
+				Expect(req.StartUrls).To(Equal([]teetypes.RedditStartURL{{URL: "https://www.reddit.com/user/alice/", Method: "GET"}}))
+				Expect(req.Searches).To(BeNil())
+				Expect(req.Sort).To(Equal(teetypes.RedditSortNew))
+				Expect(*req.PostDateLimit).To(Equal(since))
+				Expect(req.SearchPosts).To(BeTrue())
+				Expect(req.SearchComments).To(BeTrue())
+				Expect(req.SkipUserPosts).To(BeFalse())
+				Expect(req.SkipComments).To(BeFalse())
+				Expect(req.MaxPostCount).To(Equal(uint(20)))
+				Expect(req.MaxComments).To(Equal(uint(10)))
+				Expect(limit).To(Equal(uint(30)))
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "", nil
+			}
+
+			_, _, err := redditClient.ScrapeUserActivity("", "alice", since, args, "", 30)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("should skip comments if none are requested", func() {
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				Expect(input.(redditapify.RedditActorRequest).SkipComments).To(BeTrue())
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "", nil
+			}
+
+			_, _, err := redditClient.ScrapeUserActivity("", "alice", time.Now(), redditapify.CommonArgs{MaxPosts: 5}, "", 5)
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+
 	Context("queryReddit", func() {
 		It("should handle errors from the apify client", func() {
 			expectedErr := errors.New("apify error")
@@ -0,0 +1,17 @@
+//go:build !nodiscord
+
+package jobs
+
+import (
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The Discord scraper is left out of binaries built with the nodiscord tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewDiscordScraper(jc, s)
+	}, DiscordJob)
+	RegisterCredentials(DiscordJob, "discord_bot_token")
+	RegisterResultLimit(DiscordJob, "max_results")
+}
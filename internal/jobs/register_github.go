@@ -0,0 +1,17 @@
+//go:build !nogithub
+
+package jobs
+
+import (
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The GitHub scraper is left out of binaries built with the nogithub tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewGitHubScraper(jc, s)
+	}, GitHubJob)
+	RegisterCredentials(GitHubJob, "github_token")
+	RegisterResultLimit(GitHubJob, "max_results")
+}
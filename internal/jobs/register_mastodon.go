@@ -0,0 +1,16 @@
+//go:build !nomastodon
+
+package jobs
+
+import (
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The Mastodon scraper is left out of binaries built with the nomastodon tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewMastodonScraper(jc, s)
+	}, MastodonJob)
+	RegisterResultLimit(MastodonJob, "max_results")
+}
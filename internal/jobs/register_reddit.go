@@ -0,0 +1,18 @@
+//go:build !noreddit
+
+package jobs
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The Reddit scraper is left out of binaries built with the noreddit tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewRedditScraper(jc, s)
+	}, teetypes.RedditJob)
+	RegisterCredentials(teetypes.RedditJob, "apify_api_key")
+	RegisterResultLimit(teetypes.RedditJob, "max_results")
+}
@@ -0,0 +1,17 @@
+//go:build !noresearch
+
+package jobs
+
+import (
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// Research jobs are left out of binaries built with the noresearch tag. They only search the sources whose scrapers are included.
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewResearchScraper(jc, s)
+	}, ResearchJob)
+	// Research jobs search the sources with workers of their own
+	RegisterCredentials(ResearchJob, "twitter_accounts", "twitter_api_keys", "apify_api_key", "gemini_api_key")
+}
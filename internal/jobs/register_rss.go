@@ -0,0 +1,16 @@
+//go:build !norss
+
+package jobs
+
+import (
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The RSS scraper is left out of binaries built with the norss tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewRSSScraper(jc, s)
+	}, RSSJob)
+	RegisterResultLimit(RSSJob, "max_results")
+}
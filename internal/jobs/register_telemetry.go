@@ -0,0 +1,14 @@
+package jobs
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The telemetry job is always available, so it has no build tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewTelemetryJob(jc, s)
+	}, teetypes.TelemetryJob)
+}
@@ -0,0 +1,18 @@
+//go:build !notiktok
+
+package jobs
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The TikTok scraper is left out of binaries built with the notiktok tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewTikTokScraper(jc, s)
+	}, teetypes.TiktokJob)
+	RegisterCredentials(teetypes.TiktokJob, "apify_api_key")
+	RegisterResultLimit(teetypes.TiktokJob, "max_items")
+}
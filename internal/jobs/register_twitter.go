@@ -0,0 +1,36 @@
+//go:build !notwitter
+
+package jobs
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// twitterJobTypes are the job types executed by the Twitter scraper
+var twitterJobTypes = []teetypes.JobType{teetypes.TwitterJob, teetypes.TwitterCredentialJob, teetypes.TwitterApiJob, teetypes.TwitterApifyJob}
+
+// The Twitter scraper, which executes all Twitter job types, is left out of binaries built with the notwitter tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewTwitterScraper(jc, s)
+	}, twitterJobTypes...)
+	RegisterCredentials(teetypes.TwitterJob, "twitter_accounts", "twitter_api_keys", "apify_api_key")
+
+	// Tweets need an ID and a text, except media tweets which may have no text. Profiles need a username, which is
+	// named differently by the scraper library, the API and Apify.
+	tweets := []teetypes.Capability{
+		teetypes.CapSearchByQuery, teetypes.CapSearchByFullArchive, teetypes.CapGetById, teetypes.CapGetReplies,
+		teetypes.CapGetTweets, teetypes.CapGetHomeTweets, teetypes.CapGetForYouTweets, CapGetByIds, CapGetListTweets,
+		CapGetCommunityTweets,
+	}
+	profiles := []teetypes.Capability{teetypes.CapGetRetweeters, teetypes.CapGetFollowers, teetypes.CapGetFollowing}
+	for _, jobType := range twitterJobTypes {
+		RegisterResultLimit(jobType, "max_results")
+		RegisterResultValidators(jobType, tweets, RequireFields("tweet_id", "text"))
+		RegisterResultValidators(jobType, []teetypes.Capability{teetypes.CapGetMedia}, RequireFields("tweet_id"))
+		RegisterResultValidators(jobType, profiles, RequireAnyField("Username", "screen_name"))
+		RegisterResultValidators(jobType, []teetypes.Capability{teetypes.CapGetProfileById}, RequireAnyField("Username", "data.username"))
+	}
+}
@@ -0,0 +1,17 @@
+//go:build !noweb
+
+package jobs
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// The web scraper is left out of binaries built with the noweb tag
+func init() {
+	RegisterWorker(func(jc config.JobConfiguration, s *stats.StatsCollector) Worker {
+		return NewWebScraper(jc, s)
+	}, teetypes.WebJob)
+	RegisterCredentials(teetypes.WebJob, "apify_api_key", "gemini_api_key")
+}
@@ -0,0 +1,136 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// Worker executes the jobs of one or more job types
+type Worker interface {
+	GetStructuredCapabilities() teetypes.WorkerCapabilities
+	ExecuteJob(j types.Job) (types.JobResult, error)
+}
+
+// WorkerFactory creates a worker from the job configuration
+type WorkerFactory func(jc config.JobConfiguration, s *stats.StatsCollector) Worker
+
+// registration is a worker factory, and the job types executed by the worker it creates
+type registration struct {
+	factory  WorkerFactory
+	jobTypes []teetypes.JobType
+}
+
+var (
+	registryLock  sync.RWMutex
+	registrations []registration
+	// credentialKeys are the credentials each job type is executed with, by their key in the job configuration
+	credentialKeys = map[teetypes.JobType][]string{}
+	// resultLimitKeys are the arguments which cap the number of items fetched by each job type
+	resultLimitKeys = map[teetypes.JobType][]string{}
+)
+
+// RegisterWorker registers the factory of the worker which executes the given job types. A single worker is created
+// for all of them. Scrapers register themselves from init() in a file excluded by a build tag, such as notiktok, so
+// they can be left out of the binary. It panics if one of the job types has already been registered.
+func RegisterWorker(factory WorkerFactory, jobTypes ...teetypes.JobType) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+
+	for _, r := range registrations {
+		for _, jobType := range jobTypes {
+			if slices.Contains(r.jobTypes, jobType) {
+				panic(fmt.Sprintf("worker for job type %s registered twice", jobType))
+			}
+		}
+	}
+	registrations = append(registrations, registration{factory: factory, jobTypes: jobTypes})
+}
+
+// RegisterCredentials declares the credentials the worker of the job type is created with, by their key in the job
+// configuration. When the credentials are reloaded, only the workers using one which changed are created again, so the
+// others keep their state. Like workers, they are registered from init().
+func RegisterCredentials(jobType teetypes.JobType, keys ...string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	credentialKeys[jobType] = append(credentialKeys[jobType], keys...)
+}
+
+// JobTypesUsingCredentials returns the sorted job types whose worker uses at least one of the given credentials. A
+// worker executing several job types is created for all of them, so they are all returned.
+func JobTypesUsingCredentials(keys []string) []teetypes.JobType {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var jobTypes []teetypes.JobType
+	for _, r := range registrations {
+		uses := slices.ContainsFunc(r.jobTypes, func(jobType teetypes.JobType) bool {
+			return slices.ContainsFunc(credentialKeys[jobType], func(key string) bool { return slices.Contains(keys, key) })
+		})
+		if uses {
+			jobTypes = append(jobTypes, r.jobTypes...)
+		}
+	}
+	slices.Sort(jobTypes)
+	return jobTypes
+}
+
+// RegisterResultLimit declares the arguments which cap the number of items fetched by the job type, such as
+// max_results. The job server lowers them to the result limits of the job, so the scraper stops at the limit instead
+// of fetching items which are cut from its result. Like workers, they are registered from init().
+func RegisterResultLimit(jobType teetypes.JobType, keys ...string) {
+	registryLock.Lock()
+	defer registryLock.Unlock()
+	resultLimitKeys[jobType] = append(resultLimitKeys[jobType], keys...)
+}
+
+// ResultLimitArguments returns the arguments which cap the number of items fetched by the job type
+func ResultLimitArguments(jobType teetypes.JobType) []string {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+	return resultLimitKeys[jobType]
+}
+
+// RegisteredJobTypes returns the sorted job types which have a registered worker
+func RegisteredJobTypes() []teetypes.JobType {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	var jobTypes []teetypes.JobType
+	for _, r := range registrations {
+		jobTypes = append(jobTypes, r.jobTypes...)
+	}
+	slices.Sort(jobTypes)
+	return jobTypes
+}
+
+// NewRegisteredWorkers creates the registered workers, by the job type they execute. If jobTypes are given, only the
+// workers which execute at least one of them are created, and only those job types are returned.
+func NewRegisteredWorkers(jc config.JobConfiguration, s *stats.StatsCollector, jobTypes ...teetypes.JobType) map[teetypes.JobType]Worker {
+	registryLock.RLock()
+	defer registryLock.RUnlock()
+
+	workers := make(map[teetypes.JobType]Worker)
+	for _, r := range registrations {
+		wanted := r.jobTypes
+		if len(jobTypes) > 0 {
+			wanted = slices.DeleteFunc(slices.Clone(r.jobTypes), func(jobType teetypes.JobType) bool {
+				return !slices.Contains(jobTypes, jobType)
+			})
+		}
+		if len(wanted) == 0 {
+			continue
+		}
+
+		w := r.factory(jc, s)
+		for _, jobType := range wanted {
+			workers[jobType] = w
+		}
+	}
+	return workers
+}
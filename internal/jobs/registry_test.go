@@ -0,0 +1,60 @@
+package jobs_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+var _ = Describe("Worker registry", func() {
+	It("registers the scrapers included in the binary", func() {
+		Expect(RegisteredJobTypes()).To(ContainElements(
+			teetypes.WebJob,
+			teetypes.TwitterJob,
+			teetypes.TwitterCredentialJob,
+			teetypes.TwitterApiJob,
+			teetypes.TwitterApifyJob,
+			teetypes.TiktokJob,
+			teetypes.RedditJob,
+			MastodonJob,
+			GitHubJob,
+			DiscordJob,
+			ResearchJob,
+			teetypes.TelemetryJob,
+		))
+	})
+
+	It("creates a single worker for the job types of a registration", func() {
+		workers := NewRegisteredWorkers(config.JobConfiguration{}, nil, teetypes.TwitterJob, teetypes.TwitterApifyJob, teetypes.TelemetryJob)
+		Expect(workers).To(HaveLen(3))
+		Expect(workers[teetypes.TwitterJob]).To(BeAssignableToTypeOf(&TwitterScraper{}))
+		Expect(workers[teetypes.TwitterJob]).To(BeIdenticalTo(workers[teetypes.TwitterApifyJob]))
+		Expect(workers[teetypes.TelemetryJob]).To(BeAssignableToTypeOf(TelemetryJob{}))
+	})
+
+	It("tells which workers use credentials", func() {
+		Expect(JobTypesUsingCredentials([]string{"github_token"})).To(Equal([]teetypes.JobType{GitHubJob}))
+		// All the job types executed by the Twitter scraper use its credentials
+		Expect(JobTypesUsingCredentials([]string{"twitter_accounts"})).To(ContainElements(
+			teetypes.TwitterJob, teetypes.TwitterCredentialJob, teetypes.TwitterApiJob, teetypes.TwitterApifyJob, ResearchJob,
+		))
+		Expect(JobTypesUsingCredentials([]string{"apify_api_key"})).NotTo(ContainElements(MastodonJob, teetypes.TelemetryJob))
+		Expect(JobTypesUsingCredentials(nil)).To(BeEmpty())
+	})
+
+	It("tells which arguments cap the number of items fetched by a job type", func() {
+		Expect(ResultLimitArguments(teetypes.TwitterApifyJob)).To(Equal([]string{"max_results"}))
+		Expect(ResultLimitArguments(teetypes.TiktokJob)).To(Equal([]string{"max_items"}))
+		Expect(ResultLimitArguments(teetypes.TelemetryJob)).To(BeEmpty())
+	})
+
+	It("rejects job types which are registered twice", func() {
+		Expect(func() {
+			RegisterWorker(func(config.JobConfiguration, *stats.StatsCollector) Worker { return nil }, teetypes.TelemetryJob)
+		}).To(Panic())
+	})
+})
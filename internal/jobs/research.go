@@ -0,0 +1,437 @@
+package jobs
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// ResearchJob searches several sources for a topic at once and returns the results as a single bundle of documents.
+// It is not part of tee-types yet, so its arguments are validated here.
+const ResearchJob teetypes.JobType = "research"
+
+// ResearchCaps are the capabilities of the research job type
+var ResearchCaps = []teetypes.Capability{teetypes.CapSearchByQuery}
+
+const (
+	defaultResearchMaxResults = 10
+	maxResearchMaxResults     = 100
+)
+
+// ResearchArguments are the arguments of a research job
+type ResearchArguments struct {
+	QueryType teetypes.Capability `json:"type"`
+	Query     string              `json:"query"`
+	// Sources restricts the sources which are searched. All available sources are searched by default.
+	Sources []string `json:"sources"`
+	// MaxResults is the maximum number of results requested from each source
+	MaxResults int `json:"max_results"`
+}
+
+// ResearchWorker executes the searches of a research job on one of its sources
+type ResearchWorker interface {
+	GetStructuredCapabilities() teetypes.WorkerCapabilities
+	ExecuteJob(j types.Job) (types.JobResult, error)
+}
+
+// NewResearchWorkers is a function variable that can be replaced in tests.
+// It returns the registered workers research jobs delegate to, by the job type they execute.
+var NewResearchWorkers = func(jc config.JobConfiguration, s *stats.StatsCollector) map[teetypes.JobType]ResearchWorker {
+	workers := make(map[teetypes.JobType]ResearchWorker)
+	for jobType, w := range NewRegisteredWorkers(jc, s, teetypes.TwitterJob, teetypes.TwitterApifyJob, teetypes.RedditJob, teetypes.TiktokJob, teetypes.WebJob) {
+		workers[jobType] = w
+	}
+	return workers
+}
+
+// researchSource is a source searched by research jobs
+type researchSource struct {
+	name string
+	// jobTypes are the job types which can search the source, in order of preference
+	jobTypes   []teetypes.JobType
+	capability teetypes.Capability
+	arguments  func(cfg config.ResearchConfig, query string, maxResults int) types.JobArguments
+	normalize  func(data []byte) ([]types.Document, error)
+}
+
+// researchSources are all the sources research jobs can search, in the order their documents are added to a bundle
+var researchSources = []researchSource{
+	{
+		name:       "twitter",
+		jobTypes:   []teetypes.JobType{teetypes.TwitterJob, teetypes.TwitterApifyJob},
+		capability: teetypes.CapSearchByQuery,
+		arguments: func(_ config.ResearchConfig, query string, maxResults int) types.JobArguments {
+			return types.JobArguments{"type": string(teetypes.CapSearchByQuery), "query": query, "max_results": maxResults}
+		},
+		normalize: normalizeTweets,
+	},
+	{
+		name:       "reddit",
+		jobTypes:   []teetypes.JobType{teetypes.RedditJob},
+		capability: teetypes.CapSearchPosts,
+		arguments: func(_ config.ResearchConfig, query string, maxResults int) types.JobArguments {
+			return types.JobArguments{"type": string(teetypes.RedditSearchPosts), "queries": []string{query}, "max_items": maxResults}
+		},
+		normalize: normalizeRedditItems,
+	},
+	{
+		name:       "tiktok",
+		jobTypes:   []teetypes.JobType{teetypes.TiktokJob},
+		capability: teetypes.CapSearchByQuery,
+		arguments: func(_ config.ResearchConfig, query string, maxResults int) types.JobArguments {
+			return types.JobArguments{"type": string(teetypes.CapSearchByQuery), "search": []string{query}, "max_items": maxResults}
+		},
+		normalize: normalizeTikToks,
+	},
+	{
+		name:       "web",
+		jobTypes:   []teetypes.JobType{teetypes.WebJob},
+		capability: teetypes.CapScraper,
+		arguments: func(cfg config.ResearchConfig, query string, maxResults int) types.JobArguments {
+			// The search page itself is crawled too, so one more page is needed to get maxResults results
+			searchURL := strings.ReplaceAll(cfg.WebSearchURL, "{query}", url.QueryEscape(query))
+			return types.JobArguments{"type": string(teetypes.WebScraper), "url": searchURL, "max_depth": 1, "max_pages": maxResults + 1}
+		},
+		normalize: normalizeWebPages,
+	},
+}
+
+type ResearchScraper struct {
+	configuration config.ResearchConfig
+	workers       map[teetypes.JobType]ResearchWorker
+}
+
+func NewResearchScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *ResearchScraper {
+	return &ResearchScraper{
+		configuration: jc.GetResearchConfig(),
+		workers:       NewResearchWorkers(jc, statsCollector),
+	}
+}
+
+// sourceJobType returns the job type used to search a source, or an empty job type if the source isn't available
+func (rs *ResearchScraper) sourceJobType(source researchSource) teetypes.JobType {
+	for _, jobType := range source.jobTypes {
+		w, ok := rs.workers[jobType]
+		if ok && slices.Contains(w.GetStructuredCapabilities()[jobType], source.capability) {
+			return jobType
+		}
+	}
+	return ""
+}
+
+// GetStructuredCapabilities returns the capabilities of the research job, which is available as long as at least one of its sources is
+func (rs *ResearchScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	capabilities := make(teetypes.WorkerCapabilities)
+	for _, source := range researchSources {
+		if rs.sourceJobType(source) != "" {
+			capabilities[ResearchJob] = ResearchCaps
+			break
+		}
+	}
+	return capabilities
+}
+
+// parseArguments unmarshals and validates the arguments of a research job
+func (rs *ResearchScraper) parseArguments(args types.JobArguments) (*ResearchArguments, error) {
+	parsed := &ResearchArguments{}
+	if err := args.Unmarshal(parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal research arguments: %w", err)
+	}
+
+	parsed.QueryType = teetypes.Capability(strings.ToLower(string(parsed.QueryType)))
+	if parsed.QueryType == teetypes.CapEmpty {
+		parsed.QueryType = teetypes.CapSearchByQuery
+	}
+	if !slices.Contains(ResearchCaps, parsed.QueryType) {
+		return nil, fmt.Errorf("invalid type %q for research job, valid types are %v", parsed.QueryType, ResearchCaps)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if parsed.Query == "" {
+		return nil, errors.New("query is required")
+	}
+
+	for i, name := range parsed.Sources {
+		parsed.Sources[i] = strings.ToLower(strings.TrimSpace(name))
+		if !slices.ContainsFunc(researchSources, func(s researchSource) bool { return s.name == parsed.Sources[i] }) {
+			return nil, fmt.Errorf("unknown source %q, valid sources are %v", name, researchSourceNames())
+		}
+	}
+
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultResearchMaxResults
+	}
+	if parsed.MaxResults < 0 || parsed.MaxResults > maxResearchMaxResults {
+		return nil, fmt.Errorf("max_results must be between 1 and %d, got %d", maxResearchMaxResults, parsed.MaxResults)
+	}
+
+	return parsed, nil
+}
+
+func researchSourceNames() []string {
+	names := make([]string, 0, len(researchSources))
+	for _, s := range researchSources {
+		names = append(names, s.name)
+	}
+	return names
+}
+
+// researchLeg is the outcome of the search of a single source
+type researchLeg struct {
+	documents []types.Document
+	err       error
+	duration  time.Duration
+}
+
+func (rs *ResearchScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for research")
+
+	args, err := rs.parseArguments(j.Arguments)
+	if err != nil {
+		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+	logrus.Debugf("research job args: %+v", *args)
+
+	// Sources which were requested explicitly are reported as failed if they aren't available, other sources are skipped
+	var sources []researchSource
+	for _, source := range researchSources {
+		if len(args.Sources) == 0 && rs.sourceJobType(source) == "" {
+			continue
+		}
+		if len(args.Sources) > 0 && !slices.Contains(args.Sources, source.name) {
+			continue
+		}
+		sources = append(sources, source)
+	}
+	if len(sources) == 0 {
+		return types.JobResult{Error: "no research sources are available"}, fmt.Errorf("no research sources are available: %w", types.ErrNotConfigured)
+	}
+
+	legs := make([]researchLeg, len(sources))
+	var wg sync.WaitGroup
+	for i, source := range sources {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			legs[i].documents, legs[i].err = rs.search(j, source, args)
+			legs[i].duration = time.Since(start)
+		}()
+	}
+	wg.Wait()
+
+	bundle := types.ResearchBundle{Query: args.Query, Documents: []types.Document{}}
+	fanOut := &types.MultiError{}
+	seen := make(map[string]bool)
+	for i, source := range sources {
+		leg := legs[i]
+		sourceStats := types.ResearchSourceStats{Source: source.name, DurationMs: leg.duration.Milliseconds()}
+		if leg.err != nil {
+			logrus.Warnf("Research job %s: searching %s failed: %s", j.UUID, source.name, leg.err)
+			fanOut.Failed(source.name, args.Query, leg.err)
+			sourceStats.ErrorCode = types.ClassifyError(leg.err)
+			sourceStats.Error = leg.err.Error()
+			bundle.Sources = append(bundle.Sources, sourceStats)
+			continue
+		}
+
+		for _, doc := range leg.documents {
+			keys := documentKeys(doc)
+			if slices.ContainsFunc(keys, func(k string) bool { return seen[k] }) {
+				sourceStats.Duplicates++
+				continue
+			}
+			for _, k := range keys {
+				seen[k] = true
+			}
+			bundle.Documents = append(bundle.Documents, doc)
+			sourceStats.Items++
+		}
+		fanOut.Succeeded(source.name, args.Query, sourceStats.Items)
+		bundle.Sources = append(bundle.Sources, sourceStats)
+	}
+
+	if err := fanOut.ErrOrNil(); err != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while researching: %s", err.Error()), FanOut: fanOut.Statuses}, fmt.Errorf("error researching: %w", err)
+	}
+
+	dat, err := json.Marshal(bundle)
+	if err != nil {
+		return types.JobResult{Error: "error marshalling research bundle"}, fmt.Errorf("error marshalling research bundle: %w", err)
+	}
+
+	return types.JobResult{
+		Data:   dat,
+		Job:    j,
+		FanOut: fanOut.Statuses,
+	}, nil
+}
+
+// search runs the job searching a source and normalizes its results. The job shares the bandwidth meter, provenance
+// and cost recorders of the research job, so they account for all of its sources.
+func (rs *ResearchScraper) search(j types.Job, source researchSource, args *ResearchArguments) ([]types.Document, error) {
+	jobType := rs.sourceJobType(source)
+	if jobType == "" {
+		return nil, fmt.Errorf("%s: %w", source.name, types.ErrNotConfigured)
+	}
+
+	sub := j
+	sub.Type = jobType
+	sub.Arguments = source.arguments(rs.configuration, args.Query, args.MaxResults)
+
+	res, err := rs.workers[jobType].ExecuteJob(sub)
+	if err == nil && res.Error != "" {
+		err = errors.New(res.Error)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := source.normalize(res.Data)
+	if err != nil {
+		return nil, fmt.Errorf("error normalizing %s results: %w", source.name, err)
+	}
+	return documents, nil
+}
+
+// documentKeys returns the keys identifying a document. Two documents are duplicates if they share any of them.
+func documentKeys(doc types.Document) []string {
+	var keys []string
+	if doc.ID != "" {
+		keys = append(keys, "id:"+doc.Source+":"+doc.ID)
+	}
+	if u := canonicalURL(doc.URL); u != "" {
+		keys = append(keys, "url:"+u)
+	}
+	if text := strings.Join(strings.Fields(strings.ToLower(doc.Text)), " "); text != "" {
+		sum := sha256.Sum256([]byte(text))
+		keys = append(keys, fmt.Sprintf("text:%x", sum))
+	}
+	return keys
+}
+
+// canonicalURL returns a URL in a form in which trivially different URLs of the same page compare equal
+func canonicalURL(raw string) string {
+	u, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || u.Host == "" {
+		return ""
+	}
+	canonical := strings.TrimPrefix(strings.ToLower(u.Host), "www.") + strings.TrimRight(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		canonical += "?" + u.RawQuery
+	}
+	return canonical
+}
+
+// documentTime returns the creation time of a document in UTC, or nil if the source didn't provide it
+func documentTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	t = t.UTC()
+	return &t
+}
+
+func normalizeTweets(data []byte) ([]types.Document, error) {
+	var tweets []*teetypes.TweetResult
+	if err := json.Unmarshal(data, &tweets); err != nil {
+		return nil, err
+	}
+	documents := make([]types.Document, 0, len(tweets))
+	for _, t := range tweets {
+		if t == nil {
+			continue
+		}
+		id := t.TweetID
+		if id == "" && t.ID != 0 {
+			id = strconv.FormatInt(t.ID, 10)
+		}
+		doc := types.Document{Source: "twitter", ID: id, Author: t.Username, Text: strings.TrimSpace(t.Text), CreatedAt: documentTime(t.CreatedAt)}
+		if t.Username != "" && id != "" {
+			doc.URL = fmt.Sprintf("https://x.com/%s/status/%s", t.Username, id)
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
+
+func normalizeRedditItems(data []byte) ([]types.Document, error) {
+	var items []*reddit.Response
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	documents := make([]types.Document, 0, len(items))
+	for _, item := range items {
+		switch {
+		case item == nil:
+		case item.Post != nil:
+			p := item.Post
+			documents = append(documents, types.Document{Source: "reddit", ID: p.ID, URL: p.URL, Author: p.Username, Title: strings.TrimSpace(p.Title), Text: strings.TrimSpace(p.Body), CreatedAt: documentTime(p.CreatedAt)})
+		case item.Comment != nil:
+			c := item.Comment
+			documents = append(documents, types.Document{Source: "reddit", ID: c.ID, URL: c.URL, Author: c.Username, Text: strings.TrimSpace(c.Body), CreatedAt: documentTime(c.CreatedAt)})
+		}
+	}
+	return documents, nil
+}
+
+func normalizeTikToks(data []byte) ([]types.Document, error) {
+	var videos []*teetypes.TikTokSearchByQueryResult
+	if err := json.Unmarshal(data, &videos); err != nil {
+		return nil, err
+	}
+	documents := make([]types.Document, 0, len(videos))
+	for _, v := range videos {
+		if v == nil {
+			continue
+		}
+		doc := types.Document{Source: "tiktok", ID: v.ID, URL: v.URL, Author: v.Author, Text: strings.TrimSpace(v.Desc)}
+		if secs, err := strconv.ParseInt(v.CreateTime, 10, 64); err == nil {
+			doc.CreatedAt = documentTime(time.Unix(secs, 0))
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
+
+// normalizeWebPages returns the pages linked from the search page. The search page itself is the only page at depth 0.
+func normalizeWebPages(data []byte) ([]types.Document, error) {
+	var pages []*teetypes.WebScraperResult
+	if err := json.Unmarshal(data, &pages); err != nil {
+		return nil, err
+	}
+	documents := make([]types.Document, 0, len(pages))
+	for _, p := range pages {
+		if p == nil || p.Crawl.Depth == 0 {
+			continue
+		}
+		doc := types.Document{Source: "web", URL: p.URL, Title: strings.TrimSpace(p.Metadata.Title), Text: strings.TrimSpace(p.Text)}
+		if p.Metadata.CanonicalURL != "" {
+			doc.URL = p.Metadata.CanonicalURL
+		}
+		if doc.Text == "" {
+			doc.Text = strings.TrimSpace(p.Markdown)
+		}
+		if p.Metadata.Author != nil {
+			doc.Author = *p.Metadata.Author
+		}
+		documents = append(documents, doc)
+	}
+	return documents, nil
+}
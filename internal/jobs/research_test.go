@@ -0,0 +1,189 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// fakeResearchWorker returns a canned result for the job type it is registered for, and records the jobs it executes
+type fakeResearchWorker struct {
+	jobType    teetypes.JobType
+	capability teetypes.Capability
+	data       any
+	err        error
+
+	mu   sync.Mutex
+	jobs []types.Job
+}
+
+func (w *fakeResearchWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{w.jobType: {w.capability}}
+}
+
+func (w *fakeResearchWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	w.mu.Lock()
+	w.jobs = append(w.jobs, j)
+	w.mu.Unlock()
+	if w.err != nil {
+		return types.JobResult{Error: w.err.Error()}, w.err
+	}
+	data, err := json.Marshal(w.data)
+	Expect(err).NotTo(HaveOccurred())
+	return types.JobResult{Data: data}, nil
+}
+
+var _ = Describe("ResearchScraper", func() {
+	var (
+		workers map[teetypes.JobType]*fakeResearchWorker
+		scraper *jobs.ResearchScraper
+	)
+
+	created := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	newScraper := func() *jobs.ResearchScraper {
+		jobs.NewResearchWorkers = func(config.JobConfiguration, *stats.StatsCollector) map[teetypes.JobType]jobs.ResearchWorker {
+			res := make(map[teetypes.JobType]jobs.ResearchWorker)
+			for jobType, w := range workers {
+				res[jobType] = w
+			}
+			return res
+		}
+		return jobs.NewResearchScraper(config.JobConfiguration{
+			"research_web_search_url": "https://search.example.com/?q={query}",
+		}, stats.StartCollector(128, config.JobConfiguration{}))
+	}
+
+	execute := func(args types.JobArguments) (types.ResearchBundle, types.JobResult, error) {
+		res, err := scraper.ExecuteJob(types.Job{UUID: "research-uuid", Type: jobs.ResearchJob, Arguments: args})
+		var bundle types.ResearchBundle
+		if err == nil {
+			Expect(json.Unmarshal(res.Data, &bundle)).To(Succeed())
+		}
+		return bundle, res, err
+	}
+
+	BeforeEach(func() {
+		workers = map[teetypes.JobType]*fakeResearchWorker{
+			teetypes.TwitterJob: {
+				jobType:    teetypes.TwitterJob,
+				capability: teetypes.CapSearchByQuery,
+				data: []*teetypes.TweetResult{
+					{TweetID: "1", Username: "alice", Text: "  Zero knowledge proofs are neat ", CreatedAt: created},
+					{TweetID: "2", Username: "bob", Text: "Read https://example.com/zk"},
+				},
+			},
+			teetypes.RedditJob: {
+				jobType:    teetypes.RedditJob,
+				capability: teetypes.CapSearchPosts,
+				data: []map[string]any{
+					{"dataType": "post", "id": "t3_a", "url": "https://www.reddit.com/r/zk/a/", "username": "carol", "title": "ZK", "body": "zero  knowledge PROOFS are neat", "createdAt": created},
+					{"dataType": "post", "id": "t3_b", "url": "https://www.reddit.com/r/zk/b/", "username": "dave", "title": "ZK", "body": "Another post"},
+				},
+			},
+			teetypes.TiktokJob: {
+				jobType:    teetypes.TiktokJob,
+				capability: teetypes.CapSearchByQuery,
+				err:        errors.New("apify rate limit exceeded"),
+			},
+			teetypes.WebJob: {
+				jobType:    teetypes.WebJob,
+				capability: teetypes.CapScraper,
+				data: []*teetypes.WebScraperResult{
+					{URL: "https://search.example.com/?q=zero+knowledge", Text: "Search results"},
+					{URL: "https://www.example.com/zk/", Crawl: teetypes.WebCrawlInfo{Depth: 1}, Metadata: teetypes.WebMetadata{Title: "ZK"}, Text: "All about ZK"},
+					{URL: "http://example.com/zk", Crawl: teetypes.WebCrawlInfo{Depth: 1}, Text: "All about ZK, again"},
+				},
+			},
+		}
+		scraper = newScraper()
+	})
+
+	It("should be available as long as any source is", func() {
+		Expect(scraper.GetStructuredCapabilities()).To(Equal(teetypes.WorkerCapabilities{jobs.ResearchJob: jobs.ResearchCaps}))
+
+		workers = map[teetypes.JobType]*fakeResearchWorker{
+			teetypes.WebJob: {jobType: teetypes.WebJob},
+		}
+		Expect(newScraper().GetStructuredCapabilities()).To(BeEmpty())
+	})
+
+	It("should search all sources and bundle their deduplicated documents", func() {
+		bundle, res, err := execute(types.JobArguments{"query": "zero knowledge", "max_results": 5})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(bundle.Query).To(Equal("zero knowledge"))
+		Expect(bundle.Documents).To(Equal([]types.Document{
+			{Source: "twitter", ID: "1", URL: "https://x.com/alice/status/1", Author: "alice", Text: "Zero knowledge proofs are neat", CreatedAt: &created},
+			{Source: "twitter", ID: "2", URL: "https://x.com/bob/status/2", Author: "bob", Text: "Read https://example.com/zk"},
+			{Source: "reddit", ID: "t3_b", URL: "https://www.reddit.com/r/zk/b/", Author: "dave", Title: "ZK", Text: "Another post"},
+			{Source: "web", URL: "https://www.example.com/zk/", Title: "ZK", Text: "All about ZK"},
+		}))
+
+		Expect(bundle.Sources).To(HaveLen(4))
+		Expect(bundle.Sources[0]).To(And(HaveField("Source", Equal("twitter")), HaveField("Items", Equal(2)), HaveField("Duplicates", Equal(0))))
+		Expect(bundle.Sources[1]).To(And(HaveField("Source", Equal("reddit")), HaveField("Items", Equal(1)), HaveField("Duplicates", Equal(1))))
+		Expect(bundle.Sources[2]).To(And(HaveField("Source", Equal("tiktok")), HaveField("Items", Equal(0)), HaveField("ErrorCode", Equal(types.ErrorCodeRateLimited))))
+		Expect(bundle.Sources[3]).To(And(HaveField("Source", Equal("web")), HaveField("Items", Equal(1)), HaveField("Duplicates", Equal(1))))
+
+		Expect(res.FanOut).To(HaveLen(4))
+		Expect(res.FanOut[2].Success).To(BeFalse())
+
+		Expect(workers[teetypes.TwitterJob].jobs).To(HaveLen(1))
+		Expect(workers[teetypes.TwitterJob].jobs[0].UUID).To(Equal("research-uuid"))
+		Expect(workers[teetypes.TwitterJob].jobs[0].Type).To(Equal(teetypes.TwitterJob))
+		Expect(workers[teetypes.TwitterJob].jobs[0].Arguments).To(HaveKeyWithValue("query", "zero knowledge"))
+		Expect(workers[teetypes.WebJob].jobs[0].Arguments).To(And(
+			HaveKeyWithValue("url", "https://search.example.com/?q=zero+knowledge"),
+			HaveKeyWithValue("max_pages", 6),
+		))
+	})
+
+	It("should only search the requested sources", func() {
+		bundle, _, err := execute(types.JobArguments{"query": "zk", "sources": []string{"Reddit"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bundle.Sources).To(HaveLen(1))
+		Expect(bundle.Sources[0].Source).To(Equal("reddit"))
+		Expect(workers[teetypes.TwitterJob].jobs).To(BeEmpty())
+	})
+
+	It("should report requested sources which are not available", func() {
+		delete(workers, teetypes.RedditJob)
+		scraper = newScraper()
+
+		bundle, _, err := execute(types.JobArguments{"query": "zk", "sources": []string{"twitter", "reddit"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(bundle.Sources[1].Source).To(Equal("reddit"))
+		Expect(bundle.Sources[1].Error).To(ContainSubstring("not configured"))
+	})
+
+	It("should fail if all sources fail", func() {
+		res, err := scraper.ExecuteJob(types.Job{Type: jobs.ResearchJob, Arguments: types.JobArguments{"query": "zk", "sources": []string{"tiktok"}}})
+		Expect(err).To(HaveOccurred())
+		Expect(res.Error).To(ContainSubstring("rate limit"))
+		Expect(res.FanOut).To(HaveLen(1))
+	})
+
+	It("should reject invalid arguments", func() {
+		for _, args := range []types.JobArguments{
+			{},
+			{"query": "  "},
+			{"query": "zk", "type": "getprofile"},
+			{"query": "zk", "sources": []string{"myspace"}},
+			{"query": "zk", "max_results": 101},
+		} {
+			_, err := scraper.ExecuteJob(types.Job{Type: jobs.ResearchJob, Arguments: args})
+			Expect(err).To(HaveOccurred(), "%v", args)
+		}
+	})
+})
@@ -0,0 +1,287 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/rss"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/rssfeed"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// RSSJob fetches RSS and Atom feeds. It is not part of tee-types yet, so its arguments are validated here.
+const RSSJob teetypes.JobType = "rss"
+
+const (
+	// CapGetFeed fetches a single feed
+	CapGetFeed teetypes.Capability = "getfeed"
+	// CapSearchFeeds searches the items of the feeds of a configured feed list
+	CapSearchFeeds teetypes.Capability = "searchfeeds"
+)
+
+// RSSCaps are the capabilities of the rss job type
+var RSSCaps = []teetypes.Capability{CapGetFeed, CapSearchFeeds}
+
+const (
+	defaultRSSMaxResults = 50
+	maxRSSMaxResults     = 500
+
+	// maxConcurrentFeeds is the number of feeds of a list fetched at the same time by a searchfeeds job
+	maxConcurrentFeeds = 8
+)
+
+// RSSClient defines the interface for the feed client. This allows for mocking in tests.
+type RSSClient interface {
+	Fetch(feedURL string) (*rss.Feed, bool, error)
+}
+
+// NewRSSClient is a function variable that can be replaced in tests.
+// It defaults to the actual implementation.
+var NewRSSClient = func(dataDir string, network types.Network) RSSClient {
+	c := rssfeed.NewClient(dataDir)
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// RSSArguments are the arguments of an rss job
+type RSSArguments struct {
+	QueryType teetypes.Capability `json:"type"`
+	// URL is the feed to fetch with getfeed
+	URL string `json:"url"`
+	// Query are the terms all matching items of a searchfeeds job contain
+	Query string `json:"query"`
+	// List is the name of the feed list searched by a searchfeeds job, the default list if empty
+	List       string `json:"list"`
+	MaxResults int    `json:"max_results"`
+}
+
+type RSSScraper struct {
+	configuration  config.RSSConfig
+	statsCollector *stats.StatsCollector
+}
+
+func NewRSSScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *RSSScraper {
+	config := jc.GetRSSConfig()
+	logrus.Infof("RSS scraper initialized with feed lists %v", slices.Sorted(maps.Keys(config.FeedLists)))
+	return &RSSScraper{
+		configuration:  config,
+		statsCollector: statsCollector,
+	}
+}
+
+// GetStructuredCapabilities returns the capabilities of the RSS scraper. Feeds can always be fetched, but they can only
+// be searched if a feed list is configured.
+func (rs *RSSScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	caps := []teetypes.Capability{CapGetFeed}
+	if len(rs.configuration.FeedLists) > 0 {
+		caps = append(caps, CapSearchFeeds)
+	}
+	return teetypes.WorkerCapabilities{RSSJob: caps}
+}
+
+// GetCapabilityDetails returns the auth source of each capability. Feeds are public, so no authentication is needed.
+func (rs *RSSScraper) GetCapabilityDetails() types.CapabilityDetails {
+	return types.NewCapabilityDetails(rs.GetStructuredCapabilities(), types.AuthSourceNone)
+}
+
+// parseArguments unmarshals and validates the arguments of an rss job
+func (rs *RSSScraper) parseArguments(args types.JobArguments) (*RSSArguments, error) {
+	parsed := &RSSArguments{}
+	if err := args.Unmarshal(parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal rss arguments: %w", err)
+	}
+
+	parsed.QueryType = teetypes.Capability(strings.ToLower(string(parsed.QueryType)))
+	if parsed.QueryType == teetypes.CapEmpty {
+		parsed.QueryType = CapGetFeed
+	}
+	if !slices.Contains(RSSCaps, parsed.QueryType) {
+		return nil, fmt.Errorf("invalid type %q for rss job, valid types are %v", parsed.QueryType, RSSCaps)
+	}
+
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultRSSMaxResults
+	}
+	if parsed.MaxResults < 0 || parsed.MaxResults > maxRSSMaxResults {
+		return nil, fmt.Errorf("max_results must be between 1 and %d, got %d", maxRSSMaxResults, parsed.MaxResults)
+	}
+
+	switch parsed.QueryType {
+	case CapGetFeed:
+		parsed.URL = strings.TrimSpace(parsed.URL)
+		u, err := url.Parse(parsed.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("url must be an http or https URL, got %q", parsed.URL)
+		}
+
+	case CapSearchFeeds:
+		parsed.Query = strings.TrimSpace(parsed.Query)
+		if parsed.Query == "" {
+			return nil, errors.New("query is required")
+		}
+		parsed.List = strings.ToLower(strings.TrimSpace(parsed.List))
+		if parsed.List == "" {
+			parsed.List = config.DefaultRSSFeedList
+		}
+		if len(rs.configuration.FeedLists[parsed.List]) == 0 {
+			return nil, fmt.Errorf("feed list %q is not configured", parsed.List)
+		}
+	}
+
+	return parsed, nil
+}
+
+// addStat adds to a statistic, broken down by the capability of the job and the host of the feed as provider if enabled
+func (rs *RSSScraper) addStat(j types.Job, feedURL string, typ stats.StatType, num uint) {
+	dims := stats.DimensionsForJob(j)
+	if u, err := url.Parse(feedURL); err == nil {
+		dims.Provider = u.Host
+	}
+	rs.statsCollector.AddWithDimensions(j.WorkerID, typ, num, dims)
+}
+
+func (rs *RSSScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for RSS")
+
+	args, err := rs.parseArguments(j.Arguments)
+	if err != nil {
+		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+	logrus.Debugf("rss job args: %+v", *args)
+
+	client := NewRSSClient(rs.configuration.DataDir, j.Network)
+
+	if args.QueryType == CapSearchFeeds {
+		return rs.searchFeeds(j, args, client)
+	}
+
+	feed, err := rs.fetch(j, client, args.URL)
+	if err != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while fetching feed: %s", err.Error())}, fmt.Errorf("error fetching feed: %w", err)
+	}
+	if len(feed.Items) > args.MaxResults {
+		feed.Items = feed.Items[:args.MaxResults]
+	}
+	rs.addStat(j, args.URL, stats.RSSItems, uint(len(feed.Items)))
+
+	dat, err := json.Marshal(feed)
+	if err != nil {
+		return types.JobResult{Error: "error marshalling feed"}, fmt.Errorf("error marshalling feed: %w", err)
+	}
+	return types.JobResult{Data: dat, Job: j}, nil
+}
+
+// fetch fetches a feed and records the outcome. The feed may be shared with other jobs through the cache of the client,
+// so it is copied before the caller changes it.
+func (rs *RSSScraper) fetch(j types.Job, client RSSClient, feedURL string) (*rss.Feed, error) {
+	feed, cached, err := client.Fetch(feedURL)
+	if err != nil {
+		rs.addStat(j, feedURL, stats.RSSErrors, 1)
+		return nil, err
+	}
+	if cached {
+		rs.addStat(j, feedURL, stats.RSSCachedFeeds, 1)
+	} else {
+		rs.addStat(j, feedURL, stats.RSSFetchedFeeds, 1)
+	}
+
+	copied := *feed
+	copied.Items = slices.Clone(feed.Items)
+	return &copied, nil
+}
+
+// searchFeeds fetches the feeds of the list in parallel, and returns their items containing all terms of the query,
+// newest first. The job only fails if no feed could be fetched; the outcome of each feed is reported in fan_out.
+func (rs *RSSScraper) searchFeeds(j types.Job, args *RSSArguments, client RSSClient) (types.JobResult, error) {
+	feedURLs := rs.configuration.FeedLists[args.List]
+	feeds := make([]*rss.Feed, len(feedURLs))
+	errs := make([]error, len(feedURLs))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, maxConcurrentFeeds)
+	for i, feedURL := range feedURLs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			feeds[i], errs[i] = rs.fetch(j, client, feedURL)
+		}()
+	}
+	wg.Wait()
+
+	terms := strings.Fields(strings.ToLower(args.Query))
+	result := rss.SearchResult{Query: args.Query, List: args.List, Items: []rss.Item{}}
+	fanOut := &types.MultiError{}
+	for i, feedURL := range feedURLs {
+		if errs[i] != nil {
+			logrus.Warnf("RSS job %s: fetching %s failed: %s", j.UUID, feedURL, errs[i])
+			fanOut.Failed(feedURL, args.Query, errs[i])
+			continue
+		}
+
+		matches := 0
+		for _, item := range feeds[i].Items {
+			if itemMatches(item, terms) {
+				item.FeedURL = feedURL
+				result.Items = append(result.Items, item)
+				matches++
+			}
+		}
+		fanOut.Succeeded(feedURL, args.Query, matches)
+	}
+
+	if err := fanOut.ErrOrNil(); err != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while searching feeds: %s", err.Error()), FanOut: fanOut.Statuses}, fmt.Errorf("error searching feeds: %w", err)
+	}
+
+	// Items without a date sort last
+	slices.SortStableFunc(result.Items, func(a, b rss.Item) int {
+		switch {
+		case a.Published == nil && b.Published == nil:
+			return 0
+		case a.Published == nil:
+			return 1
+		case b.Published == nil:
+			return -1
+		}
+		return b.Published.Compare(*a.Published)
+	})
+	if len(result.Items) > args.MaxResults {
+		result.Items = result.Items[:args.MaxResults]
+	}
+	rs.statsCollector.AddWithDimensions(j.WorkerID, stats.RSSItems, uint(len(result.Items)), stats.DimensionsForJob(j))
+
+	dat, err := json.Marshal(result)
+	if err != nil {
+		return types.JobResult{Error: "error marshalling feed search result"}, fmt.Errorf("error marshalling feed search result: %w", err)
+	}
+	return types.JobResult{
+		Data:   dat,
+		Job:    j,
+		FanOut: fanOut.Statuses,
+	}, nil
+}
+
+// itemMatches returns true if the title, summary, content or categories of the item contain all terms, ignoring case
+func itemMatches(item rss.Item, terms []string) bool {
+	text := strings.ToLower(strings.Join(append([]string{item.Title, item.Summary, item.Content}, item.Categories...), "\n"))
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,158 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/rss"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/rssfeed"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// MockRSSClient is a mock implementation of the RSSClient.
+type MockRSSClient struct {
+	FetchFunc func(feedURL string) (*rss.Feed, bool, error)
+}
+
+func (m *MockRSSClient) Fetch(feedURL string) (*rss.Feed, bool, error) {
+	return m.FetchFunc(feedURL)
+}
+
+// feedItem returns an item published the given number of hours ago
+func feedItem(title string, hoursAgo int) rss.Item {
+	published := time.Now().UTC().Add(-time.Duration(hoursAgo) * time.Hour).Truncate(time.Second)
+	return rss.Item{ID: title, Title: title, Published: &published}
+}
+
+var _ = Describe("RSSScraper", func() {
+	var (
+		scraper        *jobs.RSSScraper
+		statsCollector *stats.StatsCollector
+		mockClient     *MockRSSClient
+		job            types.Job
+	)
+
+	BeforeEach(func() {
+		statsCollector = stats.StartCollector(128, config.JobConfiguration{})
+		scraper = jobs.NewRSSScraper(config.JobConfiguration{
+			"rss_feed_lists": map[string][]string{
+				"default": {"https://a.example/feed.xml", "https://b.example/atom.xml", "https://c.example/rss"},
+				"crypto":  {"https://d.example/feed"},
+			},
+		}, statsCollector)
+
+		mockClient = &MockRSSClient{}
+		jobs.NewRSSClient = func(_ string, _ types.Network) jobs.RSSClient {
+			return mockClient
+		}
+
+		job = types.Job{
+			UUID:     "test-uuid",
+			Type:     jobs.RSSJob,
+			WorkerID: "rss-test",
+		}
+	})
+
+	It("should report its capabilities", func() {
+		Expect(scraper.GetStructuredCapabilities()[jobs.RSSJob]).To(ConsistOf(jobs.RSSCaps))
+
+		// Feeds can't be searched without feed lists
+		scraper = jobs.NewRSSScraper(config.JobConfiguration{}, statsCollector)
+		Expect(scraper.GetStructuredCapabilities()[jobs.RSSJob]).To(ConsistOf(jobs.CapGetFeed))
+		Expect(scraper.GetCapabilityDetails()[jobs.RSSJob][0].AuthSource).To(Equal(types.AuthSourceNone))
+	})
+
+	It("should get feeds", func() {
+		mockClient.FetchFunc = func(feedURL string) (*rss.Feed, bool, error) {
+			Expect(feedURL).To(Equal("https://a.example/feed.xml"))
+			return &rss.Feed{URL: feedURL, Title: "A", Items: []rss.Item{feedItem("one", 1), feedItem("two", 2), feedItem("three", 3)}}, true, nil
+		}
+
+		job.Arguments = map[string]any{"url": "https://a.example/feed.xml", "max_results": 2}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var feed rss.Feed
+		Expect(json.Unmarshal(res.Data, &feed)).To(Succeed())
+		Expect(feed.Title).To(Equal("A"))
+		Expect(feed.Items).To(HaveLen(2))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.RSSCachedFeeds]
+		}).Should(BeNumerically("==", 1))
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.RSSItems]
+		}).Should(BeNumerically("==", 2))
+	})
+
+	It("should search the feeds of a list, newest first, and report the feeds which failed", func() {
+		mockClient.FetchFunc = func(feedURL string) (*rss.Feed, bool, error) {
+			switch feedURL {
+			case "https://a.example/feed.xml":
+				return &rss.Feed{URL: feedURL, Items: []rss.Item{feedItem("Bitcoin ETF approved", 5), feedItem("Weather", 1)}}, false, nil
+			case "https://b.example/atom.xml":
+				item := feedItem("Markets", 2)
+				item.Summary = "The bitcoin etf saw inflows"
+				undated := rss.Item{ID: "undated", Title: "Bitcoin ETF explained"}
+				return &rss.Feed{URL: feedURL, Items: []rss.Item{undated, item}}, false, nil
+			default:
+				return nil, false, rssfeed.ErrNotFound
+			}
+		}
+
+		job.Arguments = map[string]any{"type": "searchfeeds", "query": "bitcoin ETF"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).NotTo(HaveOccurred())
+
+		var result rss.SearchResult
+		Expect(json.Unmarshal(res.Data, &result)).To(Succeed())
+		Expect(result.List).To(Equal("default"))
+		Expect(result.Items).To(HaveLen(3))
+		Expect(result.Items[0].Title).To(Equal("Markets"))
+		Expect(result.Items[0].FeedURL).To(Equal("https://b.example/atom.xml"))
+		Expect(result.Items[1].Title).To(Equal("Bitcoin ETF approved"))
+		Expect(result.Items[2].ID).To(Equal("undated"))
+
+		Expect(res.FanOut).To(HaveLen(3))
+		Expect(res.FanOut[0].Items).To(Equal(1))
+		Expect(res.FanOut[2].Success).To(BeFalse())
+		Expect(res.FanOut[2].ErrorCode).To(Equal(types.ErrorCodeNotFound))
+	})
+
+	It("should fail if no feed of the list could be fetched", func() {
+		mockClient.FetchFunc = func(feedURL string) (*rss.Feed, bool, error) {
+			Expect(feedURL).To(Equal("https://d.example/feed"))
+			return nil, false, rssfeed.ErrRateLimited
+		}
+
+		job.Arguments = map[string]any{"type": "searchfeeds", "query": "bitcoin", "list": "Crypto"}
+		res, err := scraper.ExecuteJob(job)
+		Expect(err).To(HaveOccurred())
+		Expect(res.Error).To(ContainSubstring("rate limit exceeded"))
+
+		Eventually(func() uint {
+			return statsCollector.Stats.Stats[job.WorkerID][stats.RSSErrors]
+		}).Should(BeNumerically("==", 1))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]any) {
+			job.Arguments = args
+			res, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).NotTo(BeEmpty())
+		},
+		Entry("unknown type", map[string]any{"type": "getprofile", "url": "https://a.example/feed.xml"}),
+		Entry("missing url", map[string]any{"type": "getfeed"}),
+		Entry("non-http url", map[string]any{"type": "getfeed", "url": "file:///etc/passwd"}),
+		Entry("missing query", map[string]any{"type": "searchfeeds"}),
+		Entry("unconfigured list", map[string]any{"type": "searchfeeds", "query": "a", "list": "sports"}),
+		Entry("too many results", map[string]any{"type": "searchfeeds", "query": "a", "max_results": 1000}),
+	)
+})
@@ -0,0 +1,181 @@
+package rssfeed
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/rss"
+)
+
+// MaxFeedSize is the maximum size of a feed document. Larger feeds are rejected rather than truncated, since a
+// truncated document can't be parsed.
+const MaxFeedSize = 10 << 20
+
+var (
+	// ErrRateLimited is returned when the server responds with 429 Too Many Requests
+	ErrRateLimited = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	// ErrNotFound is returned when the feed does not exist
+	ErrNotFound = types.NewCodedError(types.ErrorCodeNotFound, "feed not found")
+)
+
+// cacheEntry is a fetched feed with the validators the server returned for it
+type cacheEntry struct {
+	ETag         string    `json:"etag,omitempty"`
+	LastModified string    `json:"last_modified,omitempty"`
+	FetchedAt    time.Time `json:"fetched_at"`
+	Feed         *rss.Feed `json:"feed"`
+}
+
+// Client fetches feeds. If CacheDir is set, the normalized feeds are cached there together with their ETag and
+// Last-Modified headers, and fetched again with a conditional GET, so unchanged feeds are neither transferred nor
+// parsed again.
+type Client struct {
+	HTTPClient *http.Client
+	CacheDir   string
+}
+
+// NewClient creates a new client caching the feeds below dataDir, or not caching them if dataDir is empty
+func NewClient(dataDir string) *Client {
+	c := &Client{HTTPClient: &http.Client{Timeout: 30 * time.Second}}
+	if dataDir != "" {
+		c.CacheDir = filepath.Join(dataDir, "rss_cache")
+	}
+	return c
+}
+
+// Fetch returns the feed at the given URL, and whether it was served from the cache because it has not changed
+func (c *Client) Fetch(feedURL string) (*rss.Feed, bool, error) {
+	cached := c.load(feedURL)
+
+	req, err := http.NewRequest(http.MethodGet, feedURL, nil)
+	if err != nil {
+		return nil, false, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/rdf+xml, application/xml;q=0.9, text/xml;q=0.9, */*;q=0.1")
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("error fetching %s: %w", feedURL, err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if cached == nil {
+			return nil, false, fmt.Errorf("%s returned 304 Not Modified for an unconditional request", feedURL)
+		}
+		return cached.Feed, true, nil
+	case http.StatusTooManyRequests:
+		return nil, false, ErrRateLimited
+	case http.StatusNotFound, http.StatusGone:
+		return nil, false, ErrNotFound
+	default:
+		return nil, false, fmt.Errorf("%s returned status code %d", feedURL, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, MaxFeedSize+1))
+	if err != nil {
+		return nil, false, fmt.Errorf("error reading %s: %w", feedURL, err)
+	}
+	if len(body) > MaxFeedSize {
+		return nil, false, fmt.Errorf("%s is larger than %d bytes", feedURL, MaxFeedSize)
+	}
+
+	feed, err := Parse(body)
+	if err != nil {
+		return nil, false, fmt.Errorf("error parsing %s: %w", feedURL, err)
+	}
+	feed.URL = feedURL
+
+	c.store(feedURL, &cacheEntry{
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		FetchedAt:    time.Now().UTC(),
+		Feed:         feed,
+	})
+	return feed, false, nil
+}
+
+// cachePath returns the path of the cache file of a feed, named after the hash of its URL
+func (c *Client) cachePath(feedURL string) string {
+	sum := sha256.Sum256([]byte(feedURL))
+	return filepath.Join(c.CacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the cached feed, or nil if it is not cached or the cache can't be read
+func (c *Client) load(feedURL string) *cacheEntry {
+	if c.CacheDir == "" {
+		return nil
+	}
+	data, err := os.ReadFile(c.cachePath(feedURL))
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			logrus.Warnf("Failed to read the cached feed %s: %v", feedURL, err)
+		}
+		return nil
+	}
+
+	entry := &cacheEntry{}
+	if err := json.Unmarshal(data, entry); err != nil || entry.Feed == nil || entry.Feed.URL != feedURL {
+		logrus.Warnf("Ignoring invalid cached feed %s", feedURL)
+		return nil
+	}
+	return entry
+}
+
+// store caches a feed if the server returned a validator for it, since it can't be fetched conditionally otherwise.
+// Failing to cache a feed is not an error, it is only fetched in full again next time.
+func (c *Client) store(feedURL string, entry *cacheEntry) {
+	if c.CacheDir == "" || (entry.ETag == "" && entry.LastModified == "") {
+		return
+	}
+
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = writeFile(c.cachePath(feedURL), data)
+	}
+	if err != nil {
+		logrus.Warnf("Failed to cache the feed %s: %v", feedURL, err)
+	}
+}
+
+// writeFile replaces the file atomically, so concurrent jobs fetching the same feed never read a truncated file
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
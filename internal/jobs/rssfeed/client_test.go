@@ -0,0 +1,121 @@
+package rssfeed_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/rssfeed"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		handler  http.HandlerFunc
+		dataDir  string
+		c        *rssfeed.Client
+		requests int
+	)
+
+	BeforeEach(func() {
+		requests = 0
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			handler(w, r)
+		}))
+		dataDir = GinkgoT().TempDir()
+		c = rssfeed.NewClient(dataDir)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should fetch feeds conditionally and serve unchanged feeds from the cache", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				Expect(r.Header.Get("If-Modified-Since")).To(Equal("Tue, 10 Jun 2025 04:00:00 GMT"))
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Header().Set("Last-Modified", "Tue, 10 Jun 2025 04:00:00 GMT")
+			_, _ = w.Write([]byte(rss2Feed))
+		}
+
+		feed, cached, err := c.Fetch(server.URL + "/feed.xml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeFalse())
+		Expect(feed.URL).To(Equal(server.URL + "/feed.xml"))
+		Expect(feed.Items).To(HaveLen(2))
+
+		entries, err := os.ReadDir(filepath.Join(dataDir, "rss_cache"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+
+		feed, cached, err = c.Fetch(server.URL + "/feed.xml")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeTrue())
+		Expect(feed.Title).To(Equal("Example News"))
+		Expect(feed.Items).To(HaveLen(2))
+		Expect(requests).To(Equal(2))
+	})
+
+	It("should not cache feeds without validators", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("If-None-Match")).To(BeEmpty())
+			Expect(r.Header.Get("If-Modified-Since")).To(BeEmpty())
+			_, _ = w.Write([]byte(atomFeed))
+		}
+
+		for range 2 {
+			_, cached, err := c.Fetch(server.URL + "/atom.xml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeFalse())
+		}
+		Expect(filepath.Join(dataDir, "rss_cache")).NotTo(BeADirectory())
+	})
+
+	It("should not cache without a data directory", func() {
+		c = rssfeed.NewClient("")
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("If-None-Match")).To(BeEmpty())
+			w.Header().Set("ETag", `"v1"`)
+			_, _ = w.Write([]byte(rss2Feed))
+		}
+
+		for range 2 {
+			_, cached, err := c.Fetch(server.URL + "/feed.xml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(cached).To(BeFalse())
+		}
+	})
+
+	It("should report errors", func() {
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			case "/limited":
+				w.WriteHeader(http.StatusTooManyRequests)
+			case "/html":
+				_, _ = w.Write([]byte(`<html><body>Hello</body></html>`))
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+
+		_, _, err := c.Fetch(server.URL + "/missing")
+		Expect(err).To(MatchError(rssfeed.ErrNotFound))
+		_, _, err = c.Fetch(server.URL + "/limited")
+		Expect(err).To(MatchError(rssfeed.ErrRateLimited))
+		_, _, err = c.Fetch(server.URL + "/html")
+		Expect(err).To(MatchError(rssfeed.ErrNotAFeed))
+		_, _, err = c.Fetch(server.URL + "/broken")
+		Expect(err).To(MatchError(ContainSubstring("status code 500")))
+	})
+})
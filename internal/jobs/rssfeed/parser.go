@@ -0,0 +1,287 @@
+package rssfeed
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+
+	"github.com/masa-finance/tee-worker/api/types/rss"
+)
+
+// ErrNotAFeed is returned when the document is well-formed XML, but neither an RSS nor an Atom feed
+var ErrNotAFeed = errors.New("not an RSS or Atom feed")
+
+// text is the character data of an element with its name, so elements of other namespaces with the same local name,
+// e.g. atom:link inside an RSS channel, can be told apart
+type text struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+// local returns the value of the first element without a namespace
+func local(elements []text) string {
+	for _, e := range elements {
+		if e.XMLName.Space == "" {
+			return strings.TrimSpace(e.Value)
+		}
+	}
+	return ""
+}
+
+type rssDocument struct {
+	Channel struct {
+		Title       string    `xml:"title"`
+		Links       []text    `xml:"link"`
+		Description string    `xml:"description"`
+		PubDate     string    `xml:"pubDate"`
+		LastBuild   string    `xml:"lastBuildDate"`
+		Items       []rssItem `xml:"item"`
+	} `xml:"channel"`
+}
+
+// rdfDocument is an RSS 1.0 feed, whose items are siblings of the channel
+type rdfDocument struct {
+	Channel struct {
+		Title       string `xml:"title"`
+		Links       []text `xml:"link"`
+		Description string `xml:"description"`
+	} `xml:"channel"`
+	Items []rssItem `xml:"item"`
+}
+
+type rssItem struct {
+	Title       string   `xml:"title"`
+	Links       []text   `xml:"link"`
+	GUID        string   `xml:"guid"`
+	About       string   `xml:"about,attr"` // The URI of an RSS 1.0 item
+	Author      string   `xml:"author"`
+	Creator     string   `xml:"http://purl.org/dc/elements/1.1/ creator"`
+	PubDate     string   `xml:"pubDate"`
+	Date        string   `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"http://purl.org/rss/1.0/modules/content/ encoded"`
+	Categories  []string `xml:"category"`
+	Enclosures  []struct {
+		URL    string `xml:"url,attr"`
+		Type   string `xml:"type,attr"`
+		Length string `xml:"length,attr"`
+	} `xml:"enclosure"`
+}
+
+type atomDocument struct {
+	Title    string     `xml:"title"`
+	Subtitle string     `xml:"subtitle"`
+	Links    []atomLink `xml:"link"`
+	Updated  string     `xml:"updated"`
+	Entries  []struct {
+		ID        string     `xml:"id"`
+		Title     string     `xml:"title"`
+		Links     []atomLink `xml:"link"`
+		Published string     `xml:"published"`
+		Updated   string     `xml:"updated"`
+		Authors   []struct {
+			Name string `xml:"name"`
+		} `xml:"author"`
+		Summary    string `xml:"summary"`
+		Content    string `xml:"content"`
+		Categories []struct {
+			Term string `xml:"term,attr"`
+		} `xml:"category"`
+	} `xml:"entry"`
+}
+
+type atomLink struct {
+	Href   string `xml:"href,attr"`
+	Rel    string `xml:"rel,attr"`
+	Type   string `xml:"type,attr"`
+	Length string `xml:"length,attr"`
+}
+
+// alternate returns the URL of the first alternate link, which is the default relation
+func alternate(links []atomLink) string {
+	for _, l := range links {
+		if l.Rel == "" || l.Rel == "alternate" {
+			return l.Href
+		}
+	}
+	return ""
+}
+
+// Parse parses an RSS 2.0, RSS 1.0 or Atom feed. Feeds in other encodings than UTF-8 are converted, as long as they
+// declare their encoding.
+func Parse(data []byte) (*rss.Feed, error) {
+	root, err := rootElement(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch strings.ToLower(root.Local) {
+	case "rss":
+		var doc rssDocument
+		if err := unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		ch := doc.Channel
+		feed := &rss.Feed{Format: rss.FormatRSS, Title: strings.TrimSpace(ch.Title), Link: local(ch.Links), Description: strings.TrimSpace(ch.Description)}
+		feed.Updated = parseTime(ch.LastBuild)
+		if feed.Updated == nil {
+			feed.Updated = parseTime(ch.PubDate)
+		}
+		feed.Items = convertItems(ch.Items)
+		return feed, nil
+
+	case "rdf":
+		var doc rdfDocument
+		if err := unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		ch := doc.Channel
+		feed := &rss.Feed{Format: rss.FormatRDF, Title: strings.TrimSpace(ch.Title), Link: local(ch.Links), Description: strings.TrimSpace(ch.Description)}
+		feed.Items = convertItems(doc.Items)
+		return feed, nil
+
+	case "feed":
+		var doc atomDocument
+		if err := unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		feed := &rss.Feed{Format: rss.FormatAtom, Title: strings.TrimSpace(doc.Title), Link: alternate(doc.Links), Description: strings.TrimSpace(doc.Subtitle), Updated: parseTime(doc.Updated)}
+		feed.Items = make([]rss.Item, 0, len(doc.Entries))
+		for _, e := range doc.Entries {
+			item := rss.Item{
+				ID:        strings.TrimSpace(e.ID),
+				Title:     strings.TrimSpace(e.Title),
+				Link:      alternate(e.Links),
+				Published: parseTime(e.Published),
+				Updated:   parseTime(e.Updated),
+				Summary:   strings.TrimSpace(e.Summary),
+				Content:   strings.TrimSpace(e.Content),
+			}
+			if item.Published == nil {
+				item.Published = item.Updated
+			}
+			if len(e.Authors) > 0 {
+				item.Author = strings.TrimSpace(e.Authors[0].Name)
+			}
+			for _, c := range e.Categories {
+				item.Categories = append(item.Categories, c.Term)
+			}
+			for _, l := range e.Links {
+				if l.Rel == "enclosure" {
+					length, _ := strconv.ParseInt(l.Length, 10, 64)
+					item.Enclosures = append(item.Enclosures, rss.Enclosure{URL: l.Href, Type: l.Type, Length: length})
+				}
+			}
+			if item.ID == "" {
+				item.ID = item.Link
+			}
+			feed.Items = append(feed.Items, item)
+		}
+		return feed, nil
+
+	default:
+		return nil, fmt.Errorf("%w: root element is <%s>", ErrNotAFeed, root.Local)
+	}
+}
+
+// convertItems normalizes the items of an RSS 2.0 or 1.0 feed
+func convertItems(items []rssItem) []rss.Item {
+	converted := make([]rss.Item, 0, len(items))
+	for _, i := range items {
+		item := rss.Item{
+			ID:         strings.TrimSpace(i.GUID),
+			Title:      strings.TrimSpace(i.Title),
+			Link:       local(i.Links),
+			Author:     strings.TrimSpace(i.Author),
+			Published:  parseTime(i.PubDate),
+			Summary:    strings.TrimSpace(i.Description),
+			Content:    strings.TrimSpace(i.Content),
+			Categories: i.Categories,
+		}
+		if item.Author == "" {
+			item.Author = strings.TrimSpace(i.Creator)
+		}
+		if item.Published == nil {
+			item.Published = parseTime(i.Date)
+		}
+		for _, e := range i.Enclosures {
+			length, _ := strconv.ParseInt(e.Length, 10, 64)
+			item.Enclosures = append(item.Enclosures, rss.Enclosure{URL: e.URL, Type: e.Type, Length: length})
+		}
+		if item.ID == "" {
+			item.ID = i.About
+		}
+		if item.ID == "" {
+			item.ID = item.Link
+		}
+		converted = append(converted, item)
+	}
+	return converted
+}
+
+func newDecoder(data []byte) *xml.Decoder {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	d.CharsetReader = charset.NewReaderLabel
+	// Many feeds use HTML entities such as &nbsp; which are not defined in XML
+	d.Strict = false
+	d.Entity = xml.HTMLEntity
+	return d
+}
+
+func unmarshal(data []byte, v any) error {
+	if err := newDecoder(data).Decode(v); err != nil {
+		return fmt.Errorf("invalid feed: %w", err)
+	}
+	return nil
+}
+
+// rootElement returns the name of the root element of the document
+func rootElement(data []byte) (xml.Name, error) {
+	d := newDecoder(data)
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("%w: %s", ErrNotAFeed, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// timeLayouts are the date formats found in feeds: RFC 822 dates in RSS, with or without the day of the week and
+// seconds, and RFC 3339 dates in Atom and Dublin Core
+var timeLayouts = []string{
+	time.RFC1123Z,
+	time.RFC1123,
+	"Mon, 2 Jan 2006 15:04:05 -0700",
+	"Mon, 2 Jan 2006 15:04:05 MST",
+	"Mon, 02 Jan 2006 15:04 -0700",
+	"Mon, 2 Jan 2006 15:04 MST",
+	"2 Jan 2006 15:04:05 -0700",
+	"2 Jan 2006 15:04:05 MST",
+	time.RFC3339,
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+}
+
+// parseTime parses a date of a feed, and returns nil if it is missing or in an unknown format
+func parseTime(s string) *time.Time {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			t = t.UTC()
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package rssfeed_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types/rss"
+	"github.com/masa-finance/tee-worker/internal/jobs/rssfeed"
+)
+
+const rss2Feed = `<?xml version="1.0" encoding="UTF-8"?>
+<rss version="2.0" xmlns:atom="http://www.w3.org/2005/Atom" xmlns:content="http://purl.org/rss/1.0/modules/content/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel>
+    <title>Example News</title>
+    <atom:link href="https://example.com/feed.xml" rel="self" type="application/rss+xml"/>
+    <link>https://example.com/</link>
+    <description>News&nbsp;from example.com</description>
+    <lastBuildDate>Tue, 10 Jun 2025 04:00:00 GMT</lastBuildDate>
+    <item>
+      <title>First post</title>
+      <link>https://example.com/first</link>
+      <guid isPermaLink="false">post-1</guid>
+      <dc:creator>Alice</dc:creator>
+      <pubDate>Mon, 9 Jun 2025 10:30:00 +0200</pubDate>
+      <description>A summary</description>
+      <content:encoded><![CDATA[<p>The full content</p>]]></content:encoded>
+      <category>go</category>
+      <category>feeds</category>
+      <enclosure url="https://example.com/first.mp3" type="audio/mpeg" length="1234"/>
+    </item>
+    <item>
+      <title>Second post</title>
+      <link>https://example.com/second</link>
+    </item>
+  </channel>
+</rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+  <title>Example Blog</title>
+  <subtitle>Thoughts</subtitle>
+  <link href="https://blog.example.com/atom.xml" rel="self"/>
+  <link href="https://blog.example.com/"/>
+  <updated>2025-06-10T04:00:00Z</updated>
+  <entry>
+    <id>urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a</id>
+    <title>An entry</title>
+    <link rel="alternate" href="https://blog.example.com/entry"/>
+    <link rel="enclosure" href="https://blog.example.com/entry.mp4" type="video/mp4" length="99"/>
+    <updated>2025-06-09T12:00:00+02:00</updated>
+    <author><name>Bob</name></author>
+    <summary>Short</summary>
+    <content type="html">&lt;p&gt;Long&lt;/p&gt;</content>
+    <category term="blogging"/>
+  </entry>
+</feed>`
+
+const rdfFeed = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#" xmlns="http://purl.org/rss/1.0/" xmlns:dc="http://purl.org/dc/elements/1.1/">
+  <channel rdf:about="https://old.example.com/">
+    <title>Old Site</title>
+    <link>https://old.example.com/</link>
+  </channel>
+  <item rdf:about="https://old.example.com/item">
+    <title>Caf` + "\xe9" + `</title>
+    <link>https://old.example.com/item</link>
+    <dc:date>2025-06-08T08:00:00Z</dc:date>
+  </item>
+</rdf:RDF>`
+
+var _ = Describe("Parse", func() {
+	It("should parse RSS 2.0 feeds", func() {
+		feed, err := rssfeed.Parse([]byte(rss2Feed))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(feed.Format).To(Equal(rss.FormatRSS))
+		Expect(feed.Title).To(Equal("Example News"))
+		Expect(feed.Link).To(Equal("https://example.com/"))
+		Expect(feed.Description).To(Equal("News from example.com"))
+		Expect(*feed.Updated).To(Equal(time.Date(2025, 6, 10, 4, 0, 0, 0, time.UTC)))
+		Expect(feed.Items).To(HaveLen(2))
+
+		item := feed.Items[0]
+		Expect(item.ID).To(Equal("post-1"))
+		Expect(item.Title).To(Equal("First post"))
+		Expect(item.Link).To(Equal("https://example.com/first"))
+		Expect(item.Author).To(Equal("Alice"))
+		Expect(*item.Published).To(Equal(time.Date(2025, 6, 9, 8, 30, 0, 0, time.UTC)))
+		Expect(item.Summary).To(Equal("A summary"))
+		Expect(item.Content).To(Equal("<p>The full content</p>"))
+		Expect(item.Categories).To(Equal([]string{"go", "feeds"}))
+		Expect(item.Enclosures).To(Equal([]rss.Enclosure{{URL: "https://example.com/first.mp3", Type: "audio/mpeg", Length: 1234}}))
+
+		// Items without a guid are identified by their link
+		Expect(feed.Items[1].ID).To(Equal("https://example.com/second"))
+		Expect(feed.Items[1].Published).To(BeNil())
+	})
+
+	It("should parse Atom feeds", func() {
+		feed, err := rssfeed.Parse([]byte(atomFeed))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(feed.Format).To(Equal(rss.FormatAtom))
+		Expect(feed.Title).To(Equal("Example Blog"))
+		Expect(feed.Link).To(Equal("https://blog.example.com/"))
+		Expect(feed.Description).To(Equal("Thoughts"))
+		Expect(feed.Items).To(HaveLen(1))
+
+		item := feed.Items[0]
+		Expect(item.ID).To(Equal("urn:uuid:1225c695-cfb8-4ebb-aaaa-80da344efa6a"))
+		Expect(item.Link).To(Equal("https://blog.example.com/entry"))
+		Expect(item.Author).To(Equal("Bob"))
+		// Entries without a published date fall back to their updated date
+		Expect(*item.Published).To(Equal(time.Date(2025, 6, 9, 10, 0, 0, 0, time.UTC)))
+		Expect(item.Summary).To(Equal("Short"))
+		Expect(item.Content).To(Equal("<p>Long</p>"))
+		Expect(item.Categories).To(Equal([]string{"blogging"}))
+		Expect(item.Enclosures).To(Equal([]rss.Enclosure{{URL: "https://blog.example.com/entry.mp4", Type: "video/mp4", Length: 99}}))
+	})
+
+	It("should parse RSS 1.0 feeds in other encodings", func() {
+		feed, err := rssfeed.Parse([]byte(rdfFeed))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(feed.Format).To(Equal(rss.FormatRDF))
+		Expect(feed.Title).To(Equal("Old Site"))
+		Expect(feed.Items).To(HaveLen(1))
+		Expect(feed.Items[0].ID).To(Equal("https://old.example.com/item"))
+		Expect(feed.Items[0].Title).To(Equal("Café"))
+		Expect(*feed.Items[0].Published).To(Equal(time.Date(2025, 6, 8, 8, 0, 0, 0, time.UTC)))
+	})
+
+	It("should reject documents which are not feeds", func() {
+		_, err := rssfeed.Parse([]byte(`<html><body>Hello</body></html>`))
+		Expect(err).To(MatchError(rssfeed.ErrNotAFeed))
+
+		_, err = rssfeed.Parse([]byte(`not xml at all`))
+		Expect(err).To(MatchError(rssfeed.ErrNotAFeed))
+	})
+})
@@ -0,0 +1,13 @@
+package rssfeed_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestRSSFeed(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "RSS Feed Suite")
+}
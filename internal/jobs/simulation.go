@@ -0,0 +1,321 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/api/types/discord"
+	"github.com/masa-finance/tee-worker/api/types/github"
+	"github.com/masa-finance/tee-worker/api/types/mastodon"
+	"github.com/masa-finance/tee-worker/api/types/reddit"
+	"github.com/masa-finance/tee-worker/api/types/rss"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+const defaultSimulatedItems = 10
+
+// SimulationProfile describes the synthetic capabilities and behaviour of a worker in simulation mode.
+// In simulation mode no real scraping is done, so schedulers can be tested at scale without consuming credentials or Apify budget.
+type SimulationProfile struct {
+	JobTypes map[teetypes.JobType]SimulatedJobType `json:"job_types"`
+}
+
+// SimulatedJobType is the simulated behaviour of a single job type
+type SimulatedJobType struct {
+	Capabilities []teetypes.Capability `json:"capabilities"`
+	AuthSource   types.AuthSource      `json:"auth_source"`
+	// MinLatencyMs and MaxLatencyMs bound the uniformly distributed time each job takes
+	MinLatencyMs int `json:"min_latency_ms"`
+	MaxLatencyMs int `json:"max_latency_ms"`
+	// ErrorRate is the probability (between 0 and 1) that a job fails with a transient error
+	ErrorRate float64 `json:"error_rate"`
+	// Items is the number of items returned by each job
+	Items int `json:"items"`
+	// RateLimit is advertised in the capability details and enforced: further jobs within the window fail with a rate limit error
+	RateLimit *types.RateLimitEstimate `json:"rate_limit"`
+}
+
+// LoadSimulationProfile reads a simulation profile from a JSON file
+func LoadSimulationProfile(path string) (*SimulationProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading simulation profile: %w", err)
+	}
+	return ParseSimulationProfile(data)
+}
+
+// ParseSimulationProfile parses and validates a simulation profile
+func ParseSimulationProfile(data []byte) (*SimulationProfile, error) {
+	profile := &SimulationProfile{}
+	if err := json.Unmarshal(data, profile); err != nil {
+		return nil, fmt.Errorf("error parsing simulation profile: %w", err)
+	}
+
+	if len(profile.JobTypes) == 0 {
+		return nil, fmt.Errorf("simulation profile has no job types")
+	}
+
+	for jobType, jt := range profile.JobTypes {
+		if jobType == teetypes.TelemetryJob {
+			return nil, fmt.Errorf("the %s job type cannot be simulated", jobType)
+		}
+		if len(jt.Capabilities) == 0 {
+			return nil, fmt.Errorf("job type %s has no capabilities", jobType)
+		}
+		if jt.MinLatencyMs < 0 || jt.MaxLatencyMs < jt.MinLatencyMs {
+			return nil, fmt.Errorf("job type %s: invalid latency range %d-%dms", jobType, jt.MinLatencyMs, jt.MaxLatencyMs)
+		}
+		if jt.ErrorRate < 0 || jt.ErrorRate > 1 {
+			return nil, fmt.Errorf("job type %s: error_rate must be between 0 and 1", jobType)
+		}
+		if jt.RateLimit != nil && (jt.RateLimit.Requests <= 0 || jt.RateLimit.WindowSeconds <= 0) {
+			return nil, fmt.Errorf("job type %s: rate_limit requests and window_seconds must be positive", jobType)
+		}
+		if jt.AuthSource == "" {
+			jt.AuthSource = types.AuthSourceNone
+		}
+		if jt.Items <= 0 {
+			jt.Items = defaultSimulatedItems
+		}
+		profile.JobTypes[jobType] = jt
+	}
+
+	return profile, nil
+}
+
+// SimulatedWorker advertises the capabilities of a job type from a simulation profile, and serves mock results with the configured latency
+type SimulatedWorker struct {
+	jobType        teetypes.JobType
+	profile        SimulatedJobType
+	statsCollector *stats.StatsCollector
+
+	mu       sync.Mutex
+	requests []time.Time // start times of the jobs within the current rate limit window
+}
+
+func NewSimulatedWorker(jobType teetypes.JobType, profile SimulatedJobType, statsCollector *stats.StatsCollector) *SimulatedWorker {
+	return &SimulatedWorker{
+		jobType:        jobType,
+		profile:        profile,
+		statsCollector: statsCollector,
+	}
+}
+
+// GetStructuredCapabilities returns the simulated capabilities
+func (sw *SimulatedWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{sw.jobType: sw.profile.Capabilities}
+}
+
+// GetCapabilityDetails returns the simulated auth source and rate limit of each capability
+func (sw *SimulatedWorker) GetCapabilityDetails() types.CapabilityDetails {
+	details := types.NewCapabilityDetails(sw.GetStructuredCapabilities(), sw.profile.AuthSource)
+	for i := range details[sw.jobType] {
+		details[sw.jobType][i].RateLimit = sw.profile.RateLimit
+	}
+	return details
+}
+
+// IsRateLimited returns true if the simulated rate limit has been reached
+func (sw *SimulatedWorker) IsRateLimited() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	return sw.rateLimitedLocked(time.Now())
+}
+
+func (sw *SimulatedWorker) rateLimitedLocked(now time.Time) bool {
+	if sw.profile.RateLimit == nil {
+		return false
+	}
+	window := time.Duration(sw.profile.RateLimit.WindowSeconds) * time.Second
+	i := 0
+	for i < len(sw.requests) && now.Sub(sw.requests[i]) >= window {
+		i++
+	}
+	sw.requests = sw.requests[i:]
+	return len(sw.requests) >= sw.profile.RateLimit.Requests
+}
+
+// acquire records a request against the rate limit, returning false if the limit has been reached
+func (sw *SimulatedWorker) acquire() bool {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+	now := time.Now()
+	if sw.rateLimitedLocked(now) {
+		return false
+	}
+	if sw.profile.RateLimit != nil {
+		sw.requests = append(sw.requests, now)
+	}
+	return true
+}
+
+func (sw *SimulatedWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	logrus.WithField("job_uuid", j.UUID).Debugf("Simulating %s job", j.Type)
+
+	if !sw.acquire() {
+		sw.addStat(j, stats.SimulatedErrors, 1)
+		return types.JobResult{Error: "rate limit exceeded"}, fmt.Errorf("simulated %s job: rate limit exceeded", sw.jobType)
+	}
+
+	latency := sw.profile.MinLatencyMs
+	if spread := sw.profile.MaxLatencyMs - sw.profile.MinLatencyMs; spread > 0 {
+		latency += rand.IntN(spread + 1)
+	}
+	time.Sleep(time.Duration(latency) * time.Millisecond)
+
+	if rand.Float64() < sw.profile.ErrorRate {
+		sw.addStat(j, stats.SimulatedErrors, 1)
+		return types.JobResult{Error: "temporarily unavailable"}, fmt.Errorf("simulated %s job: temporarily unavailable", sw.jobType)
+	}
+
+	data, err := json.Marshal(simulatedItems(j.Type, sw.profile.Items))
+	if err != nil {
+		return types.JobResult{Error: "error marshalling simulated result"}, fmt.Errorf("error marshalling simulated result: %w", err)
+	}
+
+	sw.addStat(j, stats.SimulatedJobs, 1)
+	return types.JobResult{Data: data, Job: j}, nil
+}
+
+func (sw *SimulatedWorker) addStat(j types.Job, typ stats.StatType, num uint) {
+	if sw.statsCollector != nil {
+		sw.statsCollector.AddWithDimensions(j.WorkerID, typ, num, stats.DimensionsForJob(j))
+	}
+}
+
+// simulatedItems returns n mock items with the same structure as the results of the given job type
+func simulatedItems(jobType teetypes.JobType, n int) any {
+	now := time.Now().UTC()
+	base := rand.Int64N(1 << 50)
+
+	switch jobType {
+	case teetypes.TwitterJob, teetypes.TwitterCredentialJob, teetypes.TwitterApiJob, teetypes.TwitterApifyJob:
+		tweets := make([]*teetypes.TweetResult, n)
+		for i := range tweets {
+			id := strconv.FormatInt(base+int64(i), 10)
+			tweets[i] = &teetypes.TweetResult{
+				ID:        base + int64(i),
+				TweetID:   id,
+				UserID:    "1",
+				Username:  "simulated",
+				Text:      "Simulated tweet " + id,
+				CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+				Timestamp: now.Add(-time.Duration(i) * time.Minute).Unix(),
+				Likes:     rand.IntN(1000),
+				Retweets:  rand.IntN(100),
+				Replies:   rand.IntN(100),
+			}
+		}
+		return tweets
+
+	case teetypes.WebJob:
+		pages := make([]*teetypes.WebScraperResult, n)
+		for i := range pages {
+			url := fmt.Sprintf("https://example.com/simulated/%d", base+int64(i))
+			pages[i] = &teetypes.WebScraperResult{
+				URL:      url,
+				Text:     "Simulated page",
+				Markdown: "# Simulated page",
+				Crawl:    teetypes.WebCrawlInfo{LoadedURL: url, LoadedTime: now, Depth: min(i, 1), HTTPStatusCode: 200},
+			}
+		}
+		return pages
+
+	case teetypes.RedditJob:
+		posts := make([]*reddit.Post, n)
+		for i := range posts {
+			id := strconv.FormatInt(base+int64(i), 36)
+			posts[i] = &reddit.Post{
+				ID:            "t3_" + id,
+				ParsedID:      id,
+				URL:           "https://www.reddit.com/r/simulated/comments/" + id,
+				Username:      "simulated",
+				Title:         "Simulated post " + id,
+				CommunityName: "r/simulated",
+				CreatedAt:     now.Add(-time.Duration(i) * time.Minute),
+				ScrapedAt:     now,
+				DataType:      string(reddit.PostResponse),
+			}
+		}
+		return posts
+
+	case MastodonJob:
+		statuses := make([]mastodon.Status, n)
+		for i := range statuses {
+			id := strconv.FormatInt(base+int64(i), 10)
+			statuses[i] = mastodon.Status{
+				ID:         id,
+				URI:        "https://mastodon.example/statuses/" + id,
+				CreatedAt:  now.Add(-time.Duration(i) * time.Minute),
+				Account:    mastodon.Account{ID: "1", Username: "simulated", Acct: "simulated"},
+				Content:    "<p>Simulated status " + id + "</p>",
+				Visibility: "public",
+			}
+		}
+		return statuses
+
+	case GitHubJob:
+		issues := make([]github.Issue, n)
+		for i := range issues {
+			number := int(base%1000000) + i
+			issues[i] = github.Issue{
+				ID:        base + int64(i),
+				Number:    number,
+				Title:     "Simulated issue " + strconv.Itoa(number),
+				State:     "open",
+				HTMLURL:   "https://github.com/simulated/simulated/issues/" + strconv.Itoa(number),
+				User:      github.User{Login: "simulated", Type: "User"},
+				CreatedAt: now.Add(-time.Duration(i) * time.Minute),
+				UpdatedAt: now,
+			}
+		}
+		return issues
+
+	case DiscordJob:
+		messages := make([]discord.Message, n)
+		for i := range messages {
+			id := strconv.FormatInt(base+int64(i), 10)
+			messages[i] = discord.Message{
+				ID:        id,
+				ChannelID: "1",
+				GuildID:   "1",
+				URL:       "https://discord.com/channels/1/1/" + id,
+				Author:    discord.User{ID: "1", Username: "simulated"},
+				Content:   "Simulated message " + id,
+				Timestamp: now.Add(-time.Duration(i) * time.Minute),
+			}
+		}
+		return messages
+
+	case RSSJob:
+		feed := rss.Feed{URL: "https://example.com/simulated.xml", Format: rss.FormatRSS, Title: "Simulated feed", Items: make([]rss.Item, n)}
+		for i := range feed.Items {
+			id := strconv.FormatInt(base+int64(i), 10)
+			published := now.Add(-time.Duration(i) * time.Minute)
+			feed.Items[i] = rss.Item{
+				ID:        id,
+				Title:     "Simulated item " + id,
+				Link:      "https://example.com/simulated/" + id,
+				Published: &published,
+				Summary:   "Simulated item",
+			}
+		}
+		return feed
+
+	default:
+		items := make([]map[string]any, n)
+		for i := range items {
+			items[i] = map[string]any{"id": strconv.FormatInt(base+int64(i), 10), "text": "Simulated item", "created_at": now}
+		}
+		return items
+	}
+}
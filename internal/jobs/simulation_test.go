@@ -0,0 +1,119 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+var _ = Describe("Simulation mode", func() {
+	Context("ParseSimulationProfile", func() {
+		It("should parse a profile and apply defaults", func() {
+			profile, err := jobs.ParseSimulationProfile([]byte(`{
+				"job_types": {
+					"twitter": {"capabilities": ["searchbyquery"], "min_latency_ms": 10, "max_latency_ms": 20, "rate_limit": {"requests": 5, "window_seconds": 60}}
+				}
+			}`))
+			Expect(err).NotTo(HaveOccurred())
+
+			jt := profile.JobTypes[teetypes.TwitterJob]
+			Expect(jt.Capabilities).To(ConsistOf(teetypes.CapSearchByQuery))
+			Expect(jt.AuthSource).To(Equal(types.AuthSourceNone))
+			Expect(jt.Items).To(Equal(10))
+			Expect(jt.RateLimit).To(Equal(&types.RateLimitEstimate{Requests: 5, WindowSeconds: 60}))
+		})
+
+		DescribeTable("should reject invalid profiles",
+			func(profile string) {
+				_, err := jobs.ParseSimulationProfile([]byte(profile))
+				Expect(err).To(HaveOccurred())
+			},
+			Entry("invalid JSON", `{`),
+			Entry("no job types", `{"job_types": {}}`),
+			Entry("no capabilities", `{"job_types": {"web": {}}}`),
+			Entry("telemetry", `{"job_types": {"telemetry": {"capabilities": ["telemetry"]}}}`),
+			Entry("inverted latency range", `{"job_types": {"web": {"capabilities": ["scraper"], "min_latency_ms": 20, "max_latency_ms": 10}}}`),
+			Entry("error rate above 1", `{"job_types": {"web": {"capabilities": ["scraper"], "error_rate": 1.5}}}`),
+			Entry("empty rate limit", `{"job_types": {"web": {"capabilities": ["scraper"], "rate_limit": {"requests": 0, "window_seconds": 60}}}}`),
+		)
+	})
+
+	Context("SimulatedWorker", func() {
+		It("should advertise the simulated capabilities and rate limit", func() {
+			w := jobs.NewSimulatedWorker(teetypes.TwitterJob, jobs.SimulatedJobType{
+				Capabilities: []teetypes.Capability{teetypes.CapSearchByQuery, teetypes.CapGetById},
+				AuthSource:   types.AuthSourceCredential,
+				RateLimit:    &types.RateLimitEstimate{Requests: 10, WindowSeconds: 60},
+			}, nil)
+
+			Expect(w.GetStructuredCapabilities()).To(Equal(teetypes.WorkerCapabilities{
+				teetypes.TwitterJob: {teetypes.CapSearchByQuery, teetypes.CapGetById},
+			}))
+
+			details := w.GetCapabilityDetails()[teetypes.TwitterJob]
+			Expect(details).To(HaveLen(2))
+			for _, d := range details {
+				Expect(d.AuthSource).To(Equal(types.AuthSourceCredential))
+				Expect(d.RateLimit).To(Equal(&types.RateLimitEstimate{Requests: 10, WindowSeconds: 60}))
+			}
+		})
+
+		It("should return mock results with the structure of the job type after the configured latency", func() {
+			w := jobs.NewSimulatedWorker(teetypes.TwitterJob, jobs.SimulatedJobType{
+				Capabilities: []teetypes.Capability{teetypes.CapSearchByQuery},
+				MinLatencyMs: 20,
+				MaxLatencyMs: 30,
+				Items:        3,
+			}, nil)
+
+			start := time.Now()
+			res, err := w.ExecuteJob(types.Job{Type: teetypes.TwitterJob, UUID: "sim"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(time.Since(start)).To(BeNumerically(">=", 20*time.Millisecond))
+
+			var tweets []*teetypes.TweetResult
+			Expect(json.Unmarshal(res.Data, &tweets)).To(Succeed())
+			Expect(tweets).To(HaveLen(3))
+			Expect(tweets[0].TweetID).NotTo(BeEmpty())
+			Expect(res.Job.UUID).To(Equal("sim"))
+		})
+
+		It("should fail jobs at the configured error rate", func() {
+			w := jobs.NewSimulatedWorker(teetypes.WebJob, jobs.SimulatedJobType{
+				Capabilities: []teetypes.Capability{teetypes.CapScraper},
+				ErrorRate:    1,
+			}, nil)
+
+			res, err := w.ExecuteJob(types.Job{Type: teetypes.WebJob})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("temporarily unavailable"))
+		})
+
+		It("should enforce the simulated rate limit", func() {
+			w := jobs.NewSimulatedWorker(teetypes.WebJob, jobs.SimulatedJobType{
+				Capabilities: []teetypes.Capability{teetypes.CapScraper},
+				RateLimit:    &types.RateLimitEstimate{Requests: 2, WindowSeconds: 60},
+			}, nil)
+
+			for range 2 {
+				_, err := w.ExecuteJob(types.Job{Type: teetypes.WebJob})
+				Expect(err).NotTo(HaveOccurred())
+			}
+			Expect(w.IsRateLimited()).To(BeTrue())
+
+			res, err := w.ExecuteJob(types.Job{Type: teetypes.WebJob})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("rate limit exceeded"))
+
+			err = w.Admit(types.Job{Type: teetypes.WebJob})
+			Expect(err).To(BeAssignableToTypeOf(&types.AdmissionError{}))
+			Expect(err.(*types.AdmissionError).RetryAfter).To(BeNumerically("~", time.Minute, time.Second))
+		})
+	})
+})
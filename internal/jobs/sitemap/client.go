@@ -0,0 +1,261 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultUserAgent is the user agent sent when fetching robots.txt and sitemaps, and matched against the
+	// user-agent groups of robots.txt
+	DefaultUserAgent = "masa-tee-worker"
+
+	// DefaultMaxCrawlDelay caps the Crawl-delay of robots.txt, so a site cannot stall a job indefinitely
+	DefaultMaxCrawlDelay = 30 * time.Second
+
+	// DefaultMaxSitemaps is the maximum number of sitemap files fetched for a single site, including nested sitemaps
+	DefaultMaxSitemaps = 50
+
+	// maxNestingDepth is the maximum depth of nested sitemap indexes that are followed
+	maxNestingDepth = 3
+
+	// maxSitemapBytes is the maximum size of an uncompressed sitemap, as defined by the sitemap protocol
+	maxSitemapBytes = 50 << 20
+)
+
+// ErrNoPages is returned when the sitemaps of a site list no pages which may be crawled
+var ErrNoPages = errors.New("the sitemaps list no pages which may be crawled")
+
+// Client lists the pages of a site from its sitemaps, honouring robots.txt. Requests to the same domain are at least
+// Delay apart, or the Crawl-delay of the domain's robots.txt if that is longer.
+type Client struct {
+	HTTPClient    *http.Client
+	UserAgent     string
+	Delay         time.Duration
+	MaxCrawlDelay time.Duration
+	MaxSitemaps   int
+
+	robots      map[string]*Robots
+	lastRequest map[string]time.Time
+}
+
+// NewClient creates a new sitemap client which waits at least delay between requests to the same domain
+func NewClient(delay time.Duration) *Client {
+	return &Client{
+		HTTPClient:    &http.Client{Timeout: 30 * time.Second},
+		UserAgent:     DefaultUserAgent,
+		Delay:         delay,
+		MaxCrawlDelay: DefaultMaxCrawlDelay,
+		MaxSitemaps:   DefaultMaxSitemaps,
+		robots:        map[string]*Robots{},
+		lastRequest:   map[string]time.Time{},
+	}
+}
+
+type location struct {
+	Loc string `xml:"loc"`
+}
+
+// document is either a urlset, which lists pages, or a sitemapindex, which lists further sitemaps
+type document struct {
+	XMLName  xml.Name
+	URLs     []location `xml:"url"`
+	Sitemaps []location `xml:"sitemap"`
+}
+
+type pendingSitemap struct {
+	url   string
+	depth int
+}
+
+// Pages returns up to maxPages pages of the site listed in its sitemaps, in the order they are listed. If siteURL
+// points to an XML file it is used as the sitemap; otherwise the sitemaps are taken from robots.txt, falling back to
+// /sitemap.xml. Pages on other hosts and pages disallowed by robots.txt are skipped.
+func (c *Client) Pages(siteURL string, maxPages int) ([]string, error) {
+	site, err := url.Parse(siteURL)
+	if err != nil || site.Host == "" {
+		return nil, fmt.Errorf("invalid site URL %q", siteURL)
+	}
+
+	robots, err := c.Robots(site)
+	if err != nil {
+		return nil, err
+	}
+
+	var queue []pendingSitemap
+	switch {
+	case isSitemapPath(site.Path):
+		queue = append(queue, pendingSitemap{url: site.String()})
+	case len(robots.Sitemaps) > 0:
+		for _, s := range robots.Sitemaps {
+			queue = append(queue, pendingSitemap{url: s})
+		}
+	default:
+		queue = append(queue, pendingSitemap{url: site.Scheme + "://" + site.Host + "/sitemap.xml"})
+	}
+
+	var (
+		pages    []string
+		seen     = map[string]bool{}
+		fetched  = map[string]bool{}
+		firstErr error
+	)
+	for len(queue) > 0 && len(pages) < maxPages && len(fetched) < c.MaxSitemaps {
+		next := queue[0]
+		queue = queue[1:]
+		if fetched[next.url] {
+			continue
+		}
+		fetched[next.url] = true
+
+		doc, err := c.fetchSitemap(next.url)
+		if err != nil {
+			logrus.Warnf("Skipping sitemap %s: %s", next.url, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if next.depth < maxNestingDepth {
+			for _, s := range doc.Sitemaps {
+				if loc := strings.TrimSpace(s.Loc); loc != "" {
+					queue = append(queue, pendingSitemap{url: loc, depth: next.depth + 1})
+				}
+			}
+		}
+
+		for _, u := range doc.URLs {
+			page, err := url.Parse(strings.TrimSpace(u.Loc))
+			if err != nil || !strings.EqualFold(page.Host, site.Host) || seen[page.String()] || !robots.Allowed(page) {
+				continue
+			}
+			seen[page.String()] = true
+			pages = append(pages, page.String())
+			if len(pages) == maxPages {
+				break
+			}
+		}
+	}
+
+	if len(pages) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, ErrNoPages
+	}
+	return pages, nil
+}
+
+// Robots returns the robots.txt rules of the site's host. A missing robots.txt allows everything.
+func (c *Client) Robots(site *url.URL) (*Robots, error) {
+	origin := site.Scheme + "://" + site.Host
+	if r, ok := c.robots[origin]; ok {
+		return r, nil
+	}
+
+	robots := &Robots{}
+	body, status, err := c.get(origin + "/robots.txt")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching robots.txt: %w", err)
+	}
+	// As per RFC 9309, a robots.txt which is not available (4xx) allows everything
+	if status == http.StatusOK {
+		robots = ParseRobots(string(body), c.UserAgent)
+	} else if status >= 500 {
+		return nil, fmt.Errorf("robots.txt returned status code %d", status)
+	}
+
+	c.robots[origin] = robots
+	return robots, nil
+}
+
+// fetchSitemap fetches and parses a sitemap, which may be gzip compressed
+func (c *Client) fetchSitemap(sitemapURL string) (*document, error) {
+	body, status, err := c.get(sitemapURL)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, fmt.Errorf("sitemap returned status code %d", status)
+	}
+
+	if bytes.HasPrefix(body, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("error decompressing sitemap: %w", err)
+		}
+		defer zr.Close()
+		if body, err = io.ReadAll(io.LimitReader(zr, maxSitemapBytes)); err != nil {
+			return nil, fmt.Errorf("error decompressing sitemap: %w", err)
+		}
+	}
+
+	var doc document
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing sitemap: %w", err)
+	}
+	if doc.XMLName.Local != "urlset" && doc.XMLName.Local != "sitemapindex" {
+		return nil, fmt.Errorf("unexpected sitemap root element %q", doc.XMLName.Local)
+	}
+	return &doc, nil
+}
+
+// get fetches a URL once the politeness delay of its domain has passed
+func (c *Client) get(rawURL string) ([]byte, int, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, 0, err
+	}
+	c.wait(u)
+
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	req.Header.Set("User-Agent", c.UserAgent)
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSitemapBytes))
+	if err != nil {
+		return nil, 0, err
+	}
+	return body, resp.StatusCode, nil
+}
+
+// wait sleeps until the politeness delay since the last request to the domain of u has passed
+func (c *Client) wait(u *url.URL) {
+	host := strings.ToLower(u.Host)
+	delay := c.Delay
+	if r, ok := c.robots[u.Scheme+"://"+u.Host]; ok && r.CrawlDelay > delay {
+		delay = min(r.CrawlDelay, c.MaxCrawlDelay)
+	}
+
+	if last, ok := c.lastRequest[host]; ok {
+		if d := time.Until(last.Add(delay)); d > 0 {
+			time.Sleep(d)
+		}
+	}
+	c.lastRequest[host] = time.Now()
+}
+
+// isSitemapPath returns true if the path points to an XML file, which is then used as the sitemap
+func isSitemapPath(path string) bool {
+	path = strings.ToLower(path)
+	return strings.HasSuffix(path, ".xml") || strings.HasSuffix(path, ".xml.gz")
+}
@@ -0,0 +1,162 @@
+package sitemap_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/sitemap"
+)
+
+func urlset(locs ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, loc := range locs {
+		b.WriteString("<url><loc>" + loc + "</loc></url>")
+	}
+	b.WriteString("</urlset>")
+	return b.String()
+}
+
+func sitemapIndex(locs ...string) string {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?><sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+	for _, loc := range locs {
+		b.WriteString("<sitemap><loc>" + loc + "</loc></sitemap>")
+	}
+	b.WriteString("</sitemapindex>")
+	return b.String()
+}
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		files    map[string]string
+		requests []time.Time
+		c        *sitemap.Client
+	)
+
+	BeforeEach(func() {
+		files = map[string]string{}
+		requests = nil
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, time.Now())
+			Expect(r.Header.Get("User-Agent")).To(Equal(sitemap.DefaultUserAgent))
+			body, ok := files[r.URL.Path]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			_, _ = w.Write([]byte(body))
+		}))
+		DeferCleanup(server.Close)
+		c = sitemap.NewClient(0)
+	})
+
+	It("should list the pages of /sitemap.xml if robots.txt does not name a sitemap", func() {
+		files["/sitemap.xml"] = urlset(server.URL+"/a", server.URL+"/b", server.URL+"/c")
+
+		pages, err := c.Pages(server.URL+"/blog", 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]string{server.URL + "/a", server.URL + "/b"}))
+	})
+
+	It("should follow nested sitemaps named in robots.txt", func() {
+		files["/robots.txt"] = "Sitemap: " + server.URL + "/index.xml\n"
+		files["/index.xml"] = sitemapIndex(server.URL+"/posts.xml", server.URL+"/missing.xml", server.URL+"/pages.xml")
+		files["/posts.xml"] = urlset(server.URL+"/post/1", server.URL+"/post/2")
+		files["/pages.xml"] = urlset(server.URL+"/about", server.URL+"/post/1")
+
+		pages, err := c.Pages(server.URL, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]string{server.URL + "/post/1", server.URL + "/post/2", server.URL + "/about"}))
+	})
+
+	It("should use the given URL as the sitemap if it is an XML file", func() {
+		files["/feeds/news.xml"] = urlset(server.URL + "/news/1")
+
+		pages, err := c.Pages(server.URL+"/feeds/news.xml", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]string{server.URL + "/news/1"}))
+	})
+
+	It("should read gzip compressed sitemaps", func() {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, _ = zw.Write([]byte(urlset(server.URL + "/compressed")))
+		Expect(zw.Close()).To(Succeed())
+		files["/sitemap.xml.gz"] = buf.String()
+
+		pages, err := c.Pages(server.URL+"/sitemap.xml.gz", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]string{server.URL + "/compressed"}))
+	})
+
+	It("should skip pages disallowed by robots.txt and pages on other hosts", func() {
+		files["/robots.txt"] = "User-agent: *\nDisallow: /private\n"
+		files["/sitemap.xml"] = urlset(server.URL+"/public", server.URL+"/private/1", "https://elsewhere.example.com/page")
+
+		pages, err := c.Pages(server.URL, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pages).To(Equal([]string{server.URL + "/public"}))
+	})
+
+	It("should return ErrNoPages if no page may be crawled", func() {
+		files["/robots.txt"] = "User-agent: *\nDisallow: /\n"
+		files["/sitemap.xml"] = urlset(server.URL + "/page")
+
+		_, err := c.Pages(server.URL, 10)
+		Expect(err).To(MatchError(sitemap.ErrNoPages))
+	})
+
+	It("should return an error if the sitemap does not exist", func() {
+		_, err := c.Pages(server.URL, 10)
+		Expect(err).To(MatchError(ContainSubstring("status code 404")))
+	})
+
+	It("should wait for the crawl delay between requests to the same domain", func() {
+		files["/robots.txt"] = "User-agent: *\nCrawl-delay: 0.1\n"
+		files["/sitemap.xml"] = urlset(server.URL + "/page")
+
+		_, err := c.Pages(server.URL, 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(HaveLen(2))
+		Expect(requests[1].Sub(requests[0])).To(BeNumerically(">=", 100*time.Millisecond))
+	})
+})
+
+var _ = Describe("ParseRobots", func() {
+	allowed := func(r *sitemap.Robots, path string) bool {
+		u, err := url.Parse("https://example.com" + path)
+		Expect(err).NotTo(HaveOccurred())
+		return r.Allowed(u)
+	}
+
+	It("should prefer the group of the worker's user agent", func() {
+		r := sitemap.ParseRobots("User-agent: *\nDisallow: /\n\nUser-agent: other\nUser-agent: masa-tee-worker\nDisallow: /admin\nCrawl-delay: 2\n", "masa-tee-worker/1.0")
+		Expect(allowed(r, "/")).To(BeTrue())
+		Expect(allowed(r, "/admin/users")).To(BeFalse())
+		Expect(r.CrawlDelay).To(Equal(2 * time.Second))
+	})
+
+	It("should let the longest matching rule win", func() {
+		r := sitemap.ParseRobots("User-agent: *\nDisallow: /docs\nAllow: /docs/public\nDisallow: /*.pdf$\n", sitemap.DefaultUserAgent)
+		Expect(allowed(r, "/docs/internal")).To(BeFalse())
+		Expect(allowed(r, "/docs/public/intro")).To(BeTrue())
+		Expect(allowed(r, "/files/report.pdf")).To(BeFalse())
+		Expect(allowed(r, "/files/report.pdf?download=1")).To(BeTrue())
+	})
+
+	It("should collect the sitemaps of all groups", func() {
+		r := sitemap.ParseRobots("Sitemap: https://example.com/a.xml\nUser-agent: *\nDisallow:\n# comment\nSitemap: https://example.com/b.xml\n", sitemap.DefaultUserAgent)
+		Expect(r.Sitemaps).To(Equal([]string{"https://example.com/a.xml", "https://example.com/b.xml"}))
+		Expect(allowed(r, "/anything")).To(BeTrue())
+	})
+})
@@ -0,0 +1,140 @@
+package sitemap
+
+import (
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Robots are the robots.txt rules which apply to the worker
+type Robots struct {
+	Sitemaps   []string
+	CrawlDelay time.Duration
+	rules      []rule
+}
+
+type rule struct {
+	allow   bool
+	length  int
+	pattern *regexp.Regexp
+}
+
+// group is a set of rules for one or more user agents
+type group struct {
+	agents     []string
+	rules      []rule
+	crawlDelay time.Duration
+}
+
+// ParseRobots parses robots.txt and returns the rules of the group matching userAgent, or of the `*` group if there
+// is none. Sitemap lines are collected regardless of the group they appear in.
+func ParseRobots(content, userAgent string) *Robots {
+	robots := &Robots{}
+	var (
+		groups []*group
+		cur    *group
+		inRule bool
+	)
+
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "sitemap":
+			if value != "" {
+				robots.Sitemaps = append(robots.Sitemaps, value)
+			}
+		case "user-agent":
+			// Consecutive user-agent lines share the rules which follow them
+			if cur == nil || inRule {
+				cur = &group{}
+				groups = append(groups, cur)
+				inRule = false
+			}
+			cur.agents = append(cur.agents, strings.ToLower(value))
+		case "allow", "disallow":
+			if cur == nil {
+				continue
+			}
+			inRule = true
+			// An empty disallow allows everything
+			if value != "" {
+				cur.rules = append(cur.rules, rule{allow: key == "allow", length: len(value), pattern: compilePattern(value)})
+			}
+		case "crawl-delay":
+			if cur == nil {
+				continue
+			}
+			inRule = true
+			if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+				cur.crawlDelay = time.Duration(secs * float64(time.Second))
+			}
+		}
+	}
+
+	token := strings.ToLower(strings.SplitN(userAgent, "/", 2)[0])
+	var wildcard, specific []*group
+	for _, g := range groups {
+		for _, agent := range g.agents {
+			if agent == "*" {
+				wildcard = append(wildcard, g)
+			} else if agent == token {
+				specific = append(specific, g)
+			}
+		}
+	}
+	matching := specific
+	if len(matching) == 0 {
+		matching = wildcard
+	}
+	for _, g := range matching {
+		robots.rules = append(robots.rules, g.rules...)
+		robots.CrawlDelay = max(robots.CrawlDelay, g.crawlDelay)
+	}
+
+	return robots
+}
+
+// compilePattern compiles a robots.txt path pattern, in which `*` matches any characters and a trailing `$` anchors
+// the pattern to the end of the path
+func compilePattern(pattern string) *regexp.Regexp {
+	anchored := strings.HasSuffix(pattern, "$")
+	pattern = strings.TrimSuffix(pattern, "$")
+	expr := "^" + strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*")
+	if anchored {
+		expr += "$"
+	}
+	return regexp.MustCompile(expr)
+}
+
+// Allowed returns true if the rules allow crawling u. The longest matching rule wins, and allow wins a tie.
+func (r *Robots) Allowed(u *url.URL) bool {
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+
+	allowed, longest := true, -1
+	for _, rl := range r.rules {
+		if !rl.pattern.MatchString(path) {
+			continue
+		}
+		if rl.length > longest || (rl.length == longest && rl.allow) {
+			allowed, longest = rl.allow, rl.length
+		}
+	}
+	return allowed
+}
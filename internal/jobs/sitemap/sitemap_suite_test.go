@@ -0,0 +1,13 @@
+package sitemap_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestSitemap(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Sitemap Client Suite")
+}
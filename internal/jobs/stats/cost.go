@@ -0,0 +1,85 @@
+package stats
+
+import (
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// ApifyJobTypeCost is the cost of the Apify actor runs of a job type, in total and by capability
+type ApifyJobTypeCost struct {
+	types.ApifyCost
+	ByCapability map[string]*types.ApifyCost `json:"by_capability,omitempty"`
+}
+
+// AddApifyCost adds the cost of the Apify actor runs of a job to the costs of its job type and capability. The
+// number of distinct capabilities is capped like the values of a dimension.
+func (s *StatsCollector) AddApifyCost(j types.Job, cost types.ApifyCost) {
+	if s == nil || cost.Runs == 0 {
+		return
+	}
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	s.Stats.LastOperationUnix = time.Now().Unix()
+	s.Stats.addApifyCost(j.Type, DimensionsForJob(j).Capability, cost, s.breakdowns.maxValues)
+}
+
+// addApifyCost adds to the costs of a job type and capability. It must be called with the Stats lock held.
+func (s *Stats) addApifyCost(jobType teetypes.JobType, capability string, cost types.ApifyCost, maxCapabilities int) {
+	jobTypeCost := s.apifyJobTypeCost(jobType)
+	jobTypeCost.Add(cost)
+
+	if capability == "" {
+		return
+	}
+	if _, exists := jobTypeCost.ByCapability[capability]; !exists && len(jobTypeCost.ByCapability) >= maxCapabilities {
+		capability = OtherDimensionValue
+	}
+	jobTypeCost.capabilityCost(capability).Add(cost)
+}
+
+// apifyJobTypeCost returns the costs of a job type, adding them if needed. It must be called with the Stats lock held.
+func (s *Stats) apifyJobTypeCost(jobType teetypes.JobType) *ApifyJobTypeCost {
+	if s.ApifyCosts == nil {
+		s.ApifyCosts = make(map[teetypes.JobType]*ApifyJobTypeCost)
+	}
+	cost, ok := s.ApifyCosts[jobType]
+	if !ok {
+		cost = &ApifyJobTypeCost{}
+		s.ApifyCosts[jobType] = cost
+	}
+	return cost
+}
+
+func (c *ApifyJobTypeCost) capabilityCost(capability string) *types.ApifyCost {
+	if c.ByCapability == nil {
+		c.ByCapability = make(map[string]*types.ApifyCost)
+	}
+	cost, ok := c.ByCapability[capability]
+	if !ok {
+		cost = &types.ApifyCost{}
+		c.ByCapability[capability] = cost
+	}
+	return cost
+}
+
+// ApifyCostOf returns the cost of the Apify actor runs of the jobs of a capability, or of all the jobs of the job type
+// if none of the capability started a run. It returns false if no job of the type started a run.
+func (s *StatsCollector) ApifyCostOf(jobType teetypes.JobType, capability string) (types.ApifyCost, bool) {
+	if s == nil {
+		return types.ApifyCost{}, false
+	}
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	jobTypeCost, ok := s.Stats.ApifyCosts[jobType]
+	if !ok {
+		return types.ApifyCost{}, false
+	}
+	if cost, ok := jobTypeCost.ByCapability[capability]; ok && cost.Runs > 0 {
+		return *cost, true
+	}
+	return jobTypeCost.ApifyCost, jobTypeCost.Runs > 0
+}
@@ -0,0 +1,75 @@
+package stats_test
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Apify costs", func() {
+	job := func(capability string) types.Job {
+		return types.Job{Type: teetypes.TwitterApifyJob, Arguments: types.JobArguments{"type": capability}}
+	}
+
+	costs := func(c *stats.StatsCollector) map[teetypes.JobType]*stats.ApifyJobTypeCost {
+		c.Stats.Lock()
+		defer c.Stats.Unlock()
+		return c.Stats.ApifyCosts
+	}
+
+	It("aggregates the costs by job type and capability", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		c.AddApifyCost(job("getfollowers"), types.ApifyCost{Runs: 1, ComputeUnits: 0.5, DatasetReads: 100, UsageUSD: 0.5})
+		c.AddApifyCost(job("getfollowers"), types.ApifyCost{Runs: 2, ComputeUnits: 0.25, DatasetReads: 50, UsageUSD: 0.25})
+		c.AddApifyCost(job("getfollowing"), types.ApifyCost{Runs: 1, ComputeUnits: 1, DatasetReads: 10, UsageUSD: 1})
+		c.AddApifyCost(job("getfollowing"), types.ApifyCost{})
+
+		Expect(costs(c)).To(Equal(map[teetypes.JobType]*stats.ApifyJobTypeCost{
+			teetypes.TwitterApifyJob: {
+				ApifyCost: types.ApifyCost{Runs: 4, ComputeUnits: 1.75, DatasetReads: 160, UsageUSD: 1.75},
+				ByCapability: map[string]*types.ApifyCost{
+					"getfollowers": {Runs: 3, ComputeUnits: 0.75, DatasetReads: 150, UsageUSD: 0.75},
+					"getfollowing": {Runs: 1, ComputeUnits: 1, DatasetReads: 10, UsageUSD: 1},
+				},
+			},
+		}))
+
+		dat, err := c.Json()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dat)).To(ContainSubstring(`"apify_costs":{"twitter-apify":{"runs":4,"compute_units":1.75,"dataset_reads":160,`))
+	})
+
+	It("folds capabilities into other once the limit is reached", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{"stats_max_dimension_values": 1})
+		c.AddApifyCost(job("getfollowers"), types.ApifyCost{Runs: 1})
+		c.AddApifyCost(job("getfollowing"), types.ApifyCost{Runs: 1})
+
+		Expect(costs(c)[teetypes.TwitterApifyJob].ByCapability).To(Equal(map[string]*types.ApifyCost{
+			"getfollowers":            {Runs: 1},
+			stats.OtherDimensionValue: {Runs: 1},
+		}))
+	})
+
+	It("keeps the costs across restarts", func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+		jc := config.JobConfiguration{"data_dir": GinkgoT().TempDir()}
+
+		c := stats.StartCollector(16, jc)
+		c.AddApifyCost(job("getfollowers"), types.ApifyCost{Runs: 1, ComputeUnits: 0.5})
+		Expect(c.Persist()).To(Succeed())
+
+		restarted := stats.StartCollector(16, jc)
+		restarted.AddApifyCost(job("getfollowers"), types.ApifyCost{Runs: 1, ComputeUnits: 0.5})
+		Expect(costs(restarted)[teetypes.TwitterApifyJob].ApifyCost).To(Equal(types.ApifyCost{Runs: 2, ComputeUnits: 1}))
+		Expect(costs(restarted)[teetypes.TwitterApifyJob].ByCapability["getfollowers"]).To(Equal(&types.ApifyCost{Runs: 2, ComputeUnits: 1}))
+	})
+})
@@ -0,0 +1,118 @@
+package stats
+
+import (
+	"fmt"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// Dimension is an optional breakdown of a statistic. Breakdowns are disabled unless enabled through the configuration.
+type Dimension string
+
+const (
+	DimensionCapability Dimension = "capability"
+	DimensionProvider   Dimension = "provider"
+	DimensionResultType Dimension = "result_type"
+)
+
+// OtherDimensionValue is used for all values of a dimension once the maximum number of distinct values has been reached
+const OtherDimensionValue = "other"
+
+const defaultMaxDimensionValues = 20
+
+// Dimensions are the values of each dimension for a single statistic. Empty values are not recorded.
+type Dimensions struct {
+	Capability string
+	Provider   string
+	ResultType string
+}
+
+func (d Dimensions) values() map[Dimension]string {
+	return map[Dimension]string{
+		DimensionCapability: d.Capability,
+		DimensionProvider:   d.Provider,
+		DimensionResultType: d.ResultType,
+	}
+}
+
+// DimensionsForJob returns the capability and provider of a job. The provider is only known for job types which are bound to an auth source.
+func DimensionsForJob(j types.Job) Dimensions {
+	d := Dimensions{}
+	if c, ok := j.Arguments["type"].(string); ok {
+		d.Capability = c
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob:
+		d.Provider = string(types.AuthSourceCredential)
+	case teetypes.TwitterApiJob:
+		d.Provider = string(types.AuthSourceAPI)
+	case teetypes.TwitterApifyJob:
+		d.Provider = string(types.AuthSourceApify)
+	}
+
+	return d
+}
+
+// ParseDimensions parses a list of dimension names
+func ParseDimensions(names []string) ([]Dimension, error) {
+	dims := make([]Dimension, 0, len(names))
+	for _, name := range names {
+		d := Dimension(strings.ToLower(strings.TrimSpace(name)))
+		switch d {
+		case "":
+			continue
+		case DimensionCapability, DimensionProvider, DimensionResultType:
+			dims = append(dims, d)
+		default:
+			return nil, fmt.Errorf("unknown stats dimension %q", name)
+		}
+	}
+	return dims, nil
+}
+
+// breakdowns holds the per-dimension counters of each statistic, capping the number of distinct values per dimension
+type breakdowns struct {
+	enabled   map[Dimension]bool
+	maxValues int
+}
+
+// record adds num to the breakdown of a statistic. It must be called with the Stats lock held.
+func (b *breakdowns) record(s *Stats, stat AddStat) {
+	if len(b.enabled) == 0 {
+		return
+	}
+
+	for dim, value := range stat.Dimensions.values() {
+		if value == "" || !b.enabled[dim] {
+			continue
+		}
+
+		if s.Breakdowns == nil {
+			s.Breakdowns = make(map[string]map[StatType]map[Dimension]map[string]uint)
+		}
+		byStat, ok := s.Breakdowns[stat.WorkerID]
+		if !ok {
+			byStat = make(map[StatType]map[Dimension]map[string]uint)
+			s.Breakdowns[stat.WorkerID] = byStat
+		}
+		byDim, ok := byStat[stat.Type]
+		if !ok {
+			byDim = make(map[Dimension]map[string]uint)
+			byStat[stat.Type] = byDim
+		}
+		counts, ok := byDim[dim]
+		if !ok {
+			counts = make(map[string]uint)
+			byDim[dim] = counts
+		}
+
+		// Cardinality safeguard: once the limit is reached, new values are folded into "other"
+		if _, exists := counts[value]; !exists && len(counts) >= b.maxValues {
+			value = OtherDimensionValue
+		}
+		counts[value] += stat.Num
+	}
+}
@@ -0,0 +1,86 @@
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+var _ = Describe("Stats dimensions", func() {
+	breakdown := func(c *stats.StatsCollector, typ stats.StatType, dim stats.Dimension) map[string]uint {
+		c.Stats.Lock()
+		defer c.Stats.Unlock()
+		out := map[string]uint{}
+		for k, v := range c.Stats.Breakdowns["worker"][typ][dim] {
+			out[k] = v
+		}
+		return out
+	}
+
+	total := func(c *stats.StatsCollector, typ stats.StatType) uint {
+		c.Stats.Lock()
+		defer c.Stats.Unlock()
+		return c.Stats.Stats["worker"][typ]
+	}
+
+	It("does not record breakdowns unless enabled", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		c.AddWithDimensions("worker", stats.TwitterTweets, 3, stats.Dimensions{Capability: "searchbyquery"})
+
+		Eventually(func() uint { return total(c, stats.TwitterTweets) }).Should(Equal(uint(3)))
+		Expect(c.Stats.Breakdowns).To(BeNil())
+	})
+
+	It("records breakdowns for the enabled dimensions only", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{
+			"stats_dimensions": []string{"capability"},
+		})
+		c.AddWithDimensions("worker", stats.TwitterTweets, 3, stats.Dimensions{Capability: "searchbyquery", Provider: "api"})
+		c.AddWithDimensions("worker", stats.TwitterTweets, 2, stats.Dimensions{Capability: "getbyid", Provider: "api"})
+
+		Eventually(func() map[string]uint { return breakdown(c, stats.TwitterTweets, stats.DimensionCapability) }).
+			Should(Equal(map[string]uint{"searchbyquery": 3, "getbyid": 2}))
+		Expect(total(c, stats.TwitterTweets)).To(Equal(uint(5)))
+		Expect(breakdown(c, stats.TwitterTweets, stats.DimensionProvider)).To(BeEmpty())
+	})
+
+	It("folds values beyond the cardinality limit into other", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{
+			"stats_dimensions":           []string{"result_type"},
+			"stats_max_dimension_values": 2,
+		})
+		for _, rt := range []string{"post", "comment", "user", "community"} {
+			c.AddWithDimensions("worker", stats.RedditReturnedItems, 1, stats.Dimensions{ResultType: rt})
+		}
+
+		Eventually(func() map[string]uint { return breakdown(c, stats.RedditReturnedItems, stats.DimensionResultType) }).
+			Should(Equal(map[string]uint{"post": 1, "comment": 1, stats.OtherDimensionValue: 2}))
+	})
+
+	It("can be toggled at runtime", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		c.SetDimensions([]stats.Dimension{stats.DimensionProvider})
+		Expect(c.Dimensions()).To(Equal([]stats.Dimension{stats.DimensionProvider}))
+		c.AddWithDimensions("worker", stats.TwitterTweets, 1, stats.Dimensions{Provider: "credential"})
+
+		Eventually(func() map[string]uint { return breakdown(c, stats.TwitterTweets, stats.DimensionProvider) }).
+			Should(Equal(map[string]uint{"credential": 1}))
+	})
+
+	It("rejects unknown dimensions", func() {
+		_, err := stats.ParseDimensions([]string{"capability", "country"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("derives the capability and provider from the job", func() {
+		d := stats.DimensionsForJob(types.Job{
+			Type:      teetypes.TwitterApiJob,
+			Arguments: map[string]any{"type": "searchbyquery"},
+		})
+		Expect(d).To(Equal(stats.Dimensions{Capability: "searchbyquery", Provider: "api"}))
+	})
+})
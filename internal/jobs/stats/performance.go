@@ -0,0 +1,258 @@
+package stats
+
+import (
+	"math"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// LatencyBucketsMs are the upper bounds of the buckets of the latency histograms, in milliseconds. The histograms have
+// one more bucket, counting the jobs which took longer than the last bound.
+var LatencyBucketsMs = []int64{100, 250, 500, 1000, 2500, 5000, 10000, 30000, 60000, 120000, 300000}
+
+// performanceWindows are the rolling windows over which the performance of each job type and capability is reported
+var performanceWindows = []struct {
+	name     string
+	duration time.Duration
+}{
+	{"5m", 5 * time.Minute},
+	{"1h", time.Hour},
+}
+
+// performanceSlots is the number of one minute slots kept, i.e. the length of the longest window in minutes
+const performanceSlots = 60
+
+// WindowPerformance is the latency and error rate of the jobs which finished in a rolling window. Latencies are
+// estimated from the histogram, which counts the jobs per bucket of LatencyBucketsMs.
+type WindowPerformance struct {
+	Jobs      uint    `json:"jobs"`
+	Errors    uint    `json:"errors"`
+	ErrorRate float64 `json:"error_rate"`
+	P50Ms     int64   `json:"p50_ms"`
+	P90Ms     int64   `json:"p90_ms"`
+	P99Ms     int64   `json:"p99_ms"`
+	MaxMs     int64   `json:"max_ms"`
+	Histogram []uint  `json:"histogram"`
+}
+
+// JobTypePerformance is the performance of a job type in each rolling window, in total and by capability
+type JobTypePerformance struct {
+	Windows      map[string]*WindowPerformance            `json:"windows"`
+	ByCapability map[string]map[string]*WindowPerformance `json:"by_capability,omitempty"`
+}
+
+// RecordJob records the latency of an attempt to execute a job, and whether it failed, for the performance of its job
+// type and capability. The number of distinct capabilities is capped like the values of a dimension.
+func (s *StatsCollector) RecordJob(j types.Job, latency time.Duration, failed bool) {
+	if s == nil {
+		return
+	}
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	now := time.Now()
+	s.Stats.LastOperationUnix = now.Unix()
+	s.performance.record(j.Type, DimensionsForJob(j).Capability, now, latency, failed, s.breakdowns.maxValues)
+}
+
+// performance holds the rolling performance of each job type. It is guarded by the Stats lock.
+type performance struct {
+	byJobType map[teetypes.JobType]*jobTypePerformance
+}
+
+type jobTypePerformance struct {
+	total        rollingPerformance
+	byCapability map[string]*rollingPerformance
+}
+
+func newPerformance() *performance {
+	return &performance{byJobType: make(map[teetypes.JobType]*jobTypePerformance)}
+}
+
+func (p *performance) record(jobType teetypes.JobType, capability string, now time.Time, latency time.Duration, failed bool, maxCapabilities int) {
+	jp, ok := p.byJobType[jobType]
+	if !ok {
+		jp = &jobTypePerformance{byCapability: make(map[string]*rollingPerformance)}
+		p.byJobType[jobType] = jp
+	}
+	jp.total.record(now, latency, failed)
+
+	if capability == "" {
+		return
+	}
+	if _, exists := jp.byCapability[capability]; !exists && len(jp.byCapability) >= maxCapabilities {
+		capability = OtherDimensionValue
+	}
+	cp, ok := jp.byCapability[capability]
+	if !ok {
+		cp = &rollingPerformance{}
+		jp.byCapability[capability] = cp
+	}
+	cp.record(now, latency, failed)
+}
+
+// snapshot returns the performance in each window. Job types and capabilities without jobs in the longest window are
+// left out.
+func (p *performance) snapshot(now time.Time) map[teetypes.JobType]*JobTypePerformance {
+	if len(p.byJobType) == 0 {
+		return nil
+	}
+
+	res := make(map[teetypes.JobType]*JobTypePerformance)
+	for jobType, jp := range p.byJobType {
+		windows := jp.total.windows(now)
+		if windows == nil {
+			continue
+		}
+		perf := &JobTypePerformance{Windows: windows}
+		for capability, cp := range jp.byCapability {
+			if windows := cp.windows(now); windows != nil {
+				if perf.ByCapability == nil {
+					perf.ByCapability = make(map[string]map[string]*WindowPerformance)
+				}
+				perf.ByCapability[capability] = windows
+			}
+		}
+		res[jobType] = perf
+	}
+	if len(res) == 0 {
+		return nil
+	}
+	return res
+}
+
+// minuteSlot counts the jobs which finished in a minute
+type minuteSlot struct {
+	minute  int64
+	jobs    uint
+	errors  uint
+	maxMs   int64
+	buckets []uint
+}
+
+// rollingPerformance keeps the counts of the last performanceSlots minutes in a ring
+type rollingPerformance struct {
+	slots [performanceSlots]minuteSlot
+}
+
+func (r *rollingPerformance) record(now time.Time, latency time.Duration, failed bool) {
+	minute := now.Unix() / 60
+	slot := &r.slots[minute%performanceSlots]
+	if slot.minute != minute || slot.buckets == nil {
+		*slot = minuteSlot{minute: minute, buckets: make([]uint, len(LatencyBucketsMs)+1)}
+	}
+
+	ms := latency.Milliseconds()
+	slot.jobs++
+	if failed {
+		slot.errors++
+	}
+	slot.maxMs = max(slot.maxMs, ms)
+	slot.buckets[bucketIndex(ms)]++
+}
+
+// windows returns the performance in each window, or nil if there were no jobs in the longest window
+func (r *rollingPerformance) windows(now time.Time) map[string]*WindowPerformance {
+	res := make(map[string]*WindowPerformance, len(performanceWindows))
+	var jobs uint
+	for _, w := range performanceWindows {
+		wp := r.window(now, w.duration)
+		jobs = max(jobs, wp.Jobs)
+		res[w.name] = wp
+	}
+	if jobs == 0 {
+		return nil
+	}
+	return res
+}
+
+// window sums the slots of the minutes in the window ending now, including the current minute
+func (r *rollingPerformance) window(now time.Time, d time.Duration) *WindowPerformance {
+	minute := now.Unix() / 60
+	oldest := minute - int64(d/time.Minute) + 1
+
+	wp := &WindowPerformance{Histogram: make([]uint, len(LatencyBucketsMs)+1)}
+	for i := range r.slots {
+		slot := &r.slots[i]
+		if slot.buckets == nil || slot.minute < oldest || slot.minute > minute {
+			continue
+		}
+		wp.Jobs += slot.jobs
+		wp.Errors += slot.errors
+		wp.MaxMs = max(wp.MaxMs, slot.maxMs)
+		for b, n := range slot.buckets {
+			wp.Histogram[b] += n
+		}
+	}
+
+	if wp.Jobs > 0 {
+		wp.ErrorRate = float64(wp.Errors) / float64(wp.Jobs)
+		wp.P50Ms = wp.percentile(0.5)
+		wp.P90Ms = wp.percentile(0.9)
+		wp.P99Ms = wp.percentile(0.99)
+	}
+	return wp
+}
+
+// percentile estimates the latency below which the fraction q of the jobs finished, by interpolating linearly within
+// the bucket the percentile falls into. The estimate never exceeds the longest latency.
+func (wp *WindowPerformance) percentile(q float64) int64 {
+	rank := uint(math.Ceil(q * float64(wp.Jobs)))
+	var seen uint
+	for b, n := range wp.Histogram {
+		if n == 0 || seen+n < rank {
+			seen += n
+			continue
+		}
+		lower, upper := int64(0), wp.MaxMs
+		if b > 0 {
+			lower = LatencyBucketsMs[b-1]
+		}
+		if b < len(LatencyBucketsMs) {
+			upper = min(LatencyBucketsMs[b], wp.MaxMs)
+		}
+		if upper <= lower {
+			return upper
+		}
+		return lower + int64(float64(upper-lower)*float64(rank-seen)/float64(n))
+	}
+	return wp.MaxMs
+}
+
+// bucketIndex returns the index of the histogram bucket of a latency
+func bucketIndex(ms int64) int {
+	for i, bound := range LatencyBucketsMs {
+		if ms <= bound {
+			return i
+		}
+	}
+	return len(LatencyBucketsMs)
+}
+
+// RecentPerformance returns the performance of the jobs of a capability which finished within the last hour, or of
+// all the jobs of the job type if none of the capability did. It returns nil if no job of the type finished within the
+// last hour.
+func (s *StatsCollector) RecentPerformance(jobType teetypes.JobType, capability string) *WindowPerformance {
+	if s == nil {
+		return nil
+	}
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	jp, ok := s.performance.byJobType[jobType]
+	if !ok {
+		return nil
+	}
+	now := time.Now()
+	if cp, ok := jp.byCapability[capability]; ok {
+		if wp := cp.window(now, time.Hour); wp.Jobs > 0 {
+			return wp
+		}
+	}
+	if wp := jp.total.window(now, time.Hour); wp.Jobs > 0 {
+		return wp
+	}
+	return nil
+}
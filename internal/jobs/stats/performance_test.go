@@ -0,0 +1,84 @@
+package stats_test
+
+import (
+	"encoding/json"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+var _ = Describe("Performance", func() {
+	job := func(capability string) types.Job {
+		return types.Job{Type: teetypes.TwitterApiJob, Arguments: types.JobArguments{"type": capability}}
+	}
+
+	performance := func(c *stats.StatsCollector) map[teetypes.JobType]*stats.JobTypePerformance {
+		dat, err := c.Json()
+		Expect(err).NotTo(HaveOccurred())
+		var s stats.Stats
+		Expect(json.Unmarshal(dat, &s)).To(Succeed())
+		return s.Performance
+	}
+
+	It("reports nothing before any job finished", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		dat, err := c.Json()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(dat)).NotTo(ContainSubstring(`"performance"`))
+		Expect(string(dat)).NotTo(ContainSubstring(`"latency_buckets_ms"`))
+	})
+
+	It("reports the latency percentiles and error rate of each window", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		for i := 0; i < 9; i++ {
+			c.RecordJob(job("searchbyquery"), 50*time.Millisecond, false)
+		}
+		c.RecordJob(job("searchbyquery"), 2*time.Second, true)
+
+		perf := performance(c)
+		Expect(perf).To(HaveKey(teetypes.TwitterApiJob))
+		for _, window := range []string{"5m", "1h"} {
+			wp := perf[teetypes.TwitterApiJob].Windows[window]
+			Expect(wp).NotTo(BeNil(), window)
+			Expect(wp.Jobs).To(Equal(uint(10)))
+			Expect(wp.Errors).To(Equal(uint(1)))
+			Expect(wp.ErrorRate).To(Equal(0.1))
+			Expect(wp.P50Ms).To(Equal(int64(55)))
+			Expect(wp.P90Ms).To(Equal(int64(100)))
+			Expect(wp.P99Ms).To(Equal(int64(2000)))
+			Expect(wp.MaxMs).To(Equal(int64(2000)))
+			Expect(wp.Histogram).To(HaveLen(len(stats.LatencyBucketsMs) + 1))
+			Expect(wp.Histogram[0]).To(Equal(uint(9)))
+			Expect(wp.Histogram[4]).To(Equal(uint(1)))
+		}
+		Expect(perf[teetypes.TwitterApiJob].ByCapability["searchbyquery"]["1h"]).To(Equal(perf[teetypes.TwitterApiJob].Windows["1h"]))
+	})
+
+	It("counts latencies above the last bucket in the overflow bucket", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{})
+		c.RecordJob(job("searchbyquery"), 10*time.Minute, false)
+
+		wp := performance(c)[teetypes.TwitterApiJob].Windows["5m"]
+		Expect(wp.Histogram[len(stats.LatencyBucketsMs)]).To(Equal(uint(1)))
+		Expect(wp.P50Ms).To(Equal(int64(600000)))
+	})
+
+	It("folds capabilities into other once the limit is reached", func() {
+		c := stats.StartCollector(16, config.JobConfiguration{"stats_max_dimension_values": 1})
+		c.RecordJob(job("searchbyquery"), time.Second, false)
+		c.RecordJob(job("getbyid"), time.Second, false)
+		c.RecordJob(types.Job{Type: teetypes.WebJob}, time.Second, false)
+
+		perf := performance(c)
+		Expect(perf[teetypes.TwitterApiJob].ByCapability).To(HaveKey("searchbyquery"))
+		Expect(perf[teetypes.TwitterApiJob].ByCapability).To(HaveKey(stats.OtherDimensionValue))
+		Expect(perf[teetypes.TwitterApiJob].Windows["1h"].Jobs).To(Equal(uint(2)))
+		Expect(perf[teetypes.WebJob].ByCapability).To(BeEmpty())
+	})
+})
@@ -0,0 +1,179 @@
+package stats
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// defaultPersistInterval is how often the cumulative statistics are saved if stats_persist_interval_seconds is not set
+const defaultPersistInterval = 60 * time.Second
+
+const (
+	statsFileName = "stats.json"
+	statsPurpose  = "stats"
+)
+
+// errSealingUnavailable is returned when saving the statistics before the sealing key has been set
+var errSealingUnavailable = errors.New("sealing key not available")
+
+// persistedStats are the counters which are kept across restarts
+type persistedStats struct {
+	Stats      map[string]map[StatType]uint                          `json:"stats"`
+	Breakdowns map[string]map[StatType]map[Dimension]map[string]uint `json:"breakdowns,omitempty"`
+	ApifyCosts map[teetypes.JobType]*ApifyJobTypeCost                `json:"apify_costs,omitempty"`
+	SavedAt    int64                                                 `json:"saved_at"`
+}
+
+// persistence saves the statistics to a sealed file in the data directory, and adds the saved counters to the
+// statistics of the running worker. The saved file is only overwritten once it has been loaded, which has to wait
+// until the sealing key is available, so a restart never loses the counters of previous runs.
+type persistence struct {
+	sync.Mutex
+	path   string
+	loaded bool
+}
+
+func newPersistence(dataDir string) *persistence {
+	return &persistence{path: filepath.Join(dataDir, statsFileName)}
+}
+
+// load adds the saved counters to the statistics, unless they have already been loaded. It returns false if the
+// saved counters can't be read yet.
+func (p *persistence) load(s *Stats) bool {
+	if p.loaded {
+		return true
+	}
+	if !tee.SealingAvailable() {
+		return false
+	}
+
+	data, legacy, err := tee.ReadSecretFile(p.path, statsPurpose)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		logrus.Info("No saved statistics found, starting from zero")
+	case err != nil:
+		// The file was sealed with a key which is no longer in the key ring, so the counters are lost either way
+		logrus.Errorf("Failed to read saved statistics, starting from zero: %v", err)
+	case legacy:
+		logrus.Errorf("Saved statistics in %s are not sealed, ignoring them", p.path)
+	default:
+		saved := persistedStats{}
+		if err := json.Unmarshal(data, &saved); err != nil {
+			logrus.Errorf("Failed to parse saved statistics, starting from zero: %v", err)
+			break
+		}
+		s.Lock()
+		saved.addTo(s)
+		s.Unlock()
+		logrus.Infof("Loaded statistics saved at %s", time.Unix(saved.SavedAt, 0).UTC())
+	}
+
+	p.loaded = true
+	return true
+}
+
+// save loads the saved counters if that hasn't happened yet, and then overwrites them with the current ones
+func (p *persistence) save(s *Stats) error {
+	p.Lock()
+	defer p.Unlock()
+
+	if !p.load(s) {
+		return errSealingUnavailable
+	}
+
+	s.Lock()
+	data, err := json.Marshal(persistedStats{Stats: s.Stats, Breakdowns: s.Breakdowns, ApifyCosts: s.ApifyCosts, SavedAt: time.Now().Unix()})
+	s.Unlock()
+	if err != nil {
+		return fmt.Errorf("error marshalling statistics: %w", err)
+	}
+
+	return tee.WriteSecretFile(p.path, statsPurpose, data)
+}
+
+// addTo adds the saved counters to the statistics. It must be called with the Stats lock held.
+func (saved persistedStats) addTo(s *Stats) {
+	for workerID, counters := range saved.Stats {
+		if _, ok := s.Stats[workerID]; !ok {
+			s.Stats[workerID] = make(map[StatType]uint)
+		}
+		for typ, num := range counters {
+			s.Stats[workerID][typ] += num
+		}
+	}
+
+	for workerID, byStat := range saved.Breakdowns {
+		for typ, byDim := range byStat {
+			for dim, counts := range byDim {
+				for value, num := range counts {
+					if s.Breakdowns == nil {
+						s.Breakdowns = make(map[string]map[StatType]map[Dimension]map[string]uint)
+					}
+					if _, ok := s.Breakdowns[workerID]; !ok {
+						s.Breakdowns[workerID] = make(map[StatType]map[Dimension]map[string]uint)
+					}
+					if _, ok := s.Breakdowns[workerID][typ]; !ok {
+						s.Breakdowns[workerID][typ] = make(map[Dimension]map[string]uint)
+					}
+					if _, ok := s.Breakdowns[workerID][typ][dim]; !ok {
+						s.Breakdowns[workerID][typ][dim] = make(map[string]uint)
+					}
+					s.Breakdowns[workerID][typ][dim][value] += num
+				}
+			}
+		}
+	}
+
+	// The saved capabilities were already capped, so they are not capped again
+	for jobType, saved := range saved.ApifyCosts {
+		if saved == nil {
+			continue
+		}
+		cost := s.apifyJobTypeCost(jobType)
+		cost.Add(saved.ApifyCost)
+		for capability, capabilityCost := range saved.ByCapability {
+			if capabilityCost != nil {
+				cost.capabilityCost(capability).Add(*capabilityCost)
+			}
+		}
+	}
+}
+
+// Persist saves the cumulative statistics, so they survive a restart of the worker. It is called periodically,
+// and should be called when the worker shuts down. It does nothing if persistence is disabled.
+func (s *StatsCollector) Persist() error {
+	if s == nil || s.persistence == nil {
+		return nil
+	}
+	return s.persistence.save(s.Stats)
+}
+
+// startPersisting loads the saved statistics and then saves them on every tick of the interval
+func (s *StatsCollector) startPersisting(interval time.Duration) {
+	s.persistence.Lock()
+	if !s.persistence.load(s.Stats) {
+		logrus.Info("Sealing key not available yet, saved statistics will be loaded once it is")
+	}
+	s.persistence.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := s.Persist(); errors.Is(err, errSealingUnavailable) {
+				logrus.Debugf("Not saving statistics: %v", err)
+			} else if err != nil {
+				logrus.Errorf("Failed to save statistics: %v", err)
+			}
+		}
+	}()
+}
@@ -0,0 +1,85 @@
+package stats_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Stats persistence", func() {
+	var (
+		dataDir string
+		jc      config.JobConfiguration
+	)
+
+	total := func(c *stats.StatsCollector, typ stats.StatType) uint {
+		c.Stats.Lock()
+		defer c.Stats.Unlock()
+		return c.Stats.Stats["worker"][typ]
+	}
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+
+		dataDir = GinkgoT().TempDir()
+		jc = config.JobConfiguration{
+			"data_dir":         dataDir,
+			"stats_dimensions": []string{"capability"},
+		}
+	})
+
+	It("keeps the counters across restarts", func() {
+		c := stats.StartCollector(16, jc)
+		c.AddWithDimensions("worker", stats.TwitterTweets, 3, stats.Dimensions{Capability: "searchbyquery"})
+		Eventually(func() uint { return total(c, stats.TwitterTweets) }).Should(Equal(uint(3)))
+		Expect(c.Persist()).To(Succeed())
+
+		data, err := os.ReadFile(filepath.Join(dataDir, "stats.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tee.IsSealedSecret(data)).To(BeTrue())
+
+		restarted := stats.StartCollector(16, jc)
+		Expect(total(restarted, stats.TwitterTweets)).To(Equal(uint(3)))
+
+		restarted.AddWithDimensions("worker", stats.TwitterTweets, 2, stats.Dimensions{Capability: "searchbyquery"})
+		Eventually(func() uint { return total(restarted, stats.TwitterTweets) }).Should(Equal(uint(5)))
+		restarted.Stats.Lock()
+		Expect(restarted.Stats.Breakdowns["worker"][stats.TwitterTweets][stats.DimensionCapability]).To(Equal(map[string]uint{"searchbyquery": 5}))
+		restarted.Stats.Unlock()
+	})
+
+	It("waits for the sealing key before loading or overwriting the saved counters", func() {
+		c := stats.StartCollector(16, jc)
+		c.Add("worker", stats.WebQueries, 4)
+		Eventually(func() uint { return total(c, stats.WebQueries) }).Should(Equal(uint(4)))
+		Expect(c.Persist()).To(Succeed())
+
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		restarted := stats.StartCollector(16, jc)
+		restarted.Add("worker", stats.WebQueries, 1)
+		Eventually(func() uint { return total(restarted, stats.WebQueries) }).Should(Equal(uint(1)))
+		Expect(restarted.Persist()).To(MatchError(ContainSubstring("sealing key")))
+
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		Expect(restarted.Persist()).To(Succeed())
+		Expect(total(restarted, stats.WebQueries)).To(Equal(uint(5)))
+	})
+
+	It("does not save the counters if persistence is disabled", func() {
+		jc["stats_persist_interval_seconds"] = time.Duration(0)
+		c := stats.StartCollector(16, jc)
+		Expect(c.Persist()).To(Succeed())
+		Expect(filepath.Join(dataDir, "stats.json")).NotTo(BeAnExistingFile())
+	})
+})
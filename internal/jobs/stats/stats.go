@@ -2,12 +2,16 @@ package stats
 
 import (
 	"encoding/json"
+	"maps"
+	"slices"
 	"sync"
 	"time"
 
 	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
 	"github.com/masa-finance/tee-worker/internal/versioning"
+	"github.com/masa-finance/tee-worker/pkg/client"
 	"github.com/sirupsen/logrus"
 )
 
@@ -16,6 +20,21 @@ type WorkerCapabilitiesProvider interface {
 	GetWorkerCapabilities() teetypes.WorkerCapabilities
 }
 
+// keyCapabilitiesProvider is implemented by providers which also report the capability matrices of their API keys
+type keyCapabilitiesProvider interface {
+	GetKeyCapabilities() []types.KeyCapabilities
+}
+
+// quotaUsageProvider is implemented by providers which also report the usage of the quotas of the miners
+type quotaUsageProvider interface {
+	GetQuotaUsage() map[string]types.QuotaUsage
+}
+
+// queueMetricsProvider is implemented by providers which also report how busy their queue is
+type queueMetricsProvider interface {
+	QueueMetrics() types.QueueMetrics
+}
+
 // These are the types of statistics that we can add. The value is the JSON key that will be used for serialization.
 type StatType string
 
@@ -33,6 +52,9 @@ const (
 	WebScrapedPages            StatType = "web_scraped_pages"
 	WebProcessedPages          StatType = "web_processed_pages"
 	WebErrors                  StatType = "web_errors"
+	WebStaticScrapes           StatType = "web_static_scrapes"
+	WebRenderedScrapes         StatType = "web_rendered_scrapes"
+	WebScreenshots             StatType = "web_screenshots"
 	LLMQueries                 StatType = "llm_queries"
 	LLMProcessedItems          StatType = "llm_processed_items"
 	LLMErrors                  StatType = "llm_errors"
@@ -45,26 +67,81 @@ const (
 	RedditReturnedItems        StatType = "reddit_returned_items"
 	RedditQueries              StatType = "reddit_queries"
 	RedditErrors               StatType = "reddit_errors"
+	RedditUserActivityPosts    StatType = "reddit_user_activity_posts"
+	RedditUserActivityComments StatType = "reddit_user_activity_comments"
+	MastodonQueries            StatType = "mastodon_queries"
+	MastodonStatuses           StatType = "mastodon_returned_statuses"
+	MastodonProfiles           StatType = "mastodon_returned_profiles"
+	MastodonErrors             StatType = "mastodon_errors"
+	MastodonRateErrors         StatType = "mastodon_ratelimit_errors"
+	GitHubQueries              StatType = "github_queries"
+	GitHubRepos                StatType = "github_returned_repos"
+	GitHubIssues               StatType = "github_returned_issues"
+	GitHubPullRequests         StatType = "github_returned_pull_requests"
+	GitHubProfiles             StatType = "github_returned_profiles"
+	GitHubCodeResults          StatType = "github_returned_code_results"
+	GitHubErrors               StatType = "github_errors"
+	GitHubRateErrors           StatType = "github_ratelimit_errors"
+	DiscordQueries             StatType = "discord_queries"
+	DiscordMessages            StatType = "discord_returned_messages"
+	DiscordScannedMessages     StatType = "discord_scanned_messages"
+	DiscordGuilds              StatType = "discord_returned_guilds"
+	DiscordErrors              StatType = "discord_errors"
+	DiscordRateErrors          StatType = "discord_ratelimit_errors"
+	RSSFetchedFeeds            StatType = "rss_fetched_feeds"
+	RSSCachedFeeds             StatType = "rss_cached_feeds"
+	RSSItems                   StatType = "rss_returned_items"
+	RSSErrors                  StatType = "rss_errors"
+	SimulatedJobs              StatType = "simulated_jobs"
+	SimulatedErrors            StatType = "simulated_errors"
+	InvalidResultItems         StatType = "invalid_result_items"   // items dropped by the result validation of their job type
+	InvalidResults             StatType = "invalid_results"        // results none of whose items were valid
+	ContentFilteredItems       StatType = "content_filtered_items" // items flagged as NSFW removed by the content policy
+	TruncatedResults           StatType = "truncated_results"      // results cut at the result limits
 	// TODO: Should we add stats for calls to each of the Twitter capabilities to decouple business / scoring logic?
 )
 
 // AddStat is the struct used in the rest of the tee-worker for sending statistics
 type AddStat struct {
-	Type     StatType
-	WorkerID string
-	Num      uint
+	Type       StatType
+	WorkerID   string
+	Num        uint
+	Dimensions Dimensions
 }
 
 // Stats is the structure we use to store the statistics
 type Stats struct {
-	BootTimeUnix         int64                        `json:"boot_time"`
-	LastOperationUnix    int64                        `json:"last_operation_time"`
-	CurrentTimeUnix      int64                        `json:"current_time"`
-	WorkerID             string                       `json:"worker_id"`
-	Stats                map[string]map[StatType]uint `json:"stats"`
-	ReportedCapabilities teetypes.WorkerCapabilities  `json:"reported_capabilities"`
-	WorkerVersion        string                       `json:"worker_version"`
-	ApplicationVersion   string                       `json:"application_version"`
+	BootTimeUnix      int64                        `json:"boot_time"`
+	LastOperationUnix int64                        `json:"last_operation_time"`
+	CurrentTimeUnix   int64                        `json:"current_time"`
+	WorkerID          string                       `json:"worker_id"`
+	Stats             map[string]map[StatType]uint `json:"stats"`
+	// Breakdowns of the statistics by the enabled dimensions, by worker ID, stat type, dimension and dimension value
+	Breakdowns           map[string]map[StatType]map[Dimension]map[string]uint `json:"breakdowns,omitempty"`
+	ReportedCapabilities teetypes.WorkerCapabilities                           `json:"reported_capabilities"`
+	KeyCapabilities      []types.KeyCapabilities                               `json:"key_capabilities,omitempty"`
+	WorkerVersion        string                                                `json:"worker_version"`
+	ApplicationVersion   string                                                `json:"application_version"`
+
+	// ApifyCosts is the cost of the Apify actor runs of each job type
+	ApifyCosts map[teetypes.JobType]*ApifyJobTypeCost `json:"apify_costs,omitempty"`
+
+	// Performance is the latency and error rate of each job type and capability over the last 5 minutes and hour.
+	// The histograms count the jobs per bucket of LatencyBucketsMs.
+	Performance      map[teetypes.JobType]*JobTypePerformance `json:"performance,omitempty"`
+	LatencyBucketsMs []int64                                  `json:"latency_buckets_ms,omitempty"`
+
+	// Benchmark is the latest self-benchmark of the worker, reporting its capacity
+	Benchmark *types.BenchmarkReport `json:"benchmark,omitempty"`
+
+	// HTTPConnections is how many connections the requests of the HTTP clients opened and reused since the worker started
+	HTTPConnections *client.ConnectionStats `json:"http_connections,omitempty"`
+
+	// Quotas is the usage of the quotas of each miner with an API key, see MINER_API_KEYS
+	Quotas map[string]types.QuotaUsage `json:"quotas,omitempty"`
+
+	// Queue is the depth of the queue, the estimated wait of each capability and whether the worker signals backpressure
+	Queue *types.QueueMetrics `json:"queue,omitempty"`
 	sync.Mutex
 }
 
@@ -74,6 +151,9 @@ type StatsCollector struct {
 	Chan             chan AddStat
 	jobServer        WorkerCapabilitiesProvider
 	jobConfiguration config.JobConfiguration
+	breakdowns       *breakdowns
+	performance      *performance
+	persistence      *persistence
 }
 
 // StartCollector starts a goroutine that listens to a channel for AddStat messages and updates the stats accordingly.
@@ -87,6 +167,18 @@ func StartCollector(bufSize uint, jc config.JobConfiguration) *StatsCollector {
 		ApplicationVersion: versioning.ApplicationVersion,
 	}
 
+	b := &breakdowns{enabled: map[Dimension]bool{}, maxValues: defaultMaxDimensionValues}
+	if maxValues, err := jc.GetInt("stats_max_dimension_values", defaultMaxDimensionValues); err == nil && maxValues > 0 {
+		b.maxValues = maxValues
+	}
+	dims, err := ParseDimensions(jc.GetStringSlice("stats_dimensions", nil))
+	if err != nil {
+		logrus.Errorf("Invalid stats_dimensions config, stats breakdowns disabled: %v", err)
+	}
+	for _, d := range dims {
+		b.enabled[d] = true
+	}
+
 	ch := make(chan AddStat, bufSize)
 
 	go func(s *Stats, ch chan AddStat) {
@@ -98,19 +190,53 @@ func StartCollector(bufSize uint, jc config.JobConfiguration) *StatsCollector {
 				s.Stats[stat.WorkerID] = make(map[StatType]uint)
 			}
 			s.Stats[stat.WorkerID][stat.Type] += stat.Num
+			b.record(s, stat)
 			s.Unlock()
 			logrus.Debugf("Added %d to stat %s. Current stats: %#v", stat.Num, stat.Type, s)
 		}
 	}(&s, ch)
 
-	return &StatsCollector{Stats: &s, Chan: ch, jobConfiguration: jc}
+	collector := &StatsCollector{Stats: &s, Chan: ch, jobConfiguration: jc, breakdowns: b, performance: newPerformance()}
+
+	// The cumulative statistics are saved in the data directory, unless persistence is disabled with an interval of 0
+	interval := jc.GetDuration("stats_persist_interval_seconds", int(defaultPersistInterval.Seconds()))
+	if dataDir := jc.GetString("data_dir", ""); dataDir != "" && interval > 0 {
+		collector.persistence = newPersistence(dataDir)
+		collector.startPersisting(interval)
+	}
+
+	return collector
 }
 
 // Json returns the current statistics as a JSON byte array
 func (s *StatsCollector) Json() ([]byte, error) {
+	// The wait estimates are computed from the performance statistics, so the queue is measured before locking them
+	s.Stats.Lock()
+	js := s.jobServer
+	s.Stats.Unlock()
+	var queue *types.QueueMetrics
+	if q, ok := js.(queueMetricsProvider); ok {
+		metrics := q.QueueMetrics()
+		queue = &metrics
+	}
+
 	s.Stats.Lock()
 	defer s.Stats.Unlock()
-	s.Stats.CurrentTimeUnix = time.Now().Unix()
+	now := time.Now()
+	s.Stats.CurrentTimeUnix = now.Unix()
+	s.Stats.Performance = s.performance.snapshot(now)
+	s.Stats.LatencyBucketsMs = nil
+	if s.Stats.Performance != nil {
+		s.Stats.LatencyBucketsMs = LatencyBucketsMs
+	}
+	s.Stats.HTTPConnections = nil
+	if conns := client.GetConnectionStats(); conns.New+conns.Reused > 0 {
+		s.Stats.HTTPConnections = &conns
+	}
+	if q, ok := s.jobServer.(quotaUsageProvider); ok {
+		s.Stats.Quotas = q.GetQuotaUsage()
+	}
+	s.Stats.Queue = queue
 	return json.Marshal(s.Stats)
 }
 
@@ -119,6 +245,30 @@ func (s *StatsCollector) Add(workerID string, typ StatType, num uint) {
 	s.Chan <- AddStat{WorkerID: workerID, Type: typ, Num: num}
 }
 
+// AddWithDimensions adds a number to a statistic, and to its breakdown by each of the enabled dimensions
+func (s *StatsCollector) AddWithDimensions(workerID string, typ StatType, num uint, dims Dimensions) {
+	s.Chan <- AddStat{WorkerID: workerID, Type: typ, Num: num, Dimensions: dims}
+}
+
+// SetDimensions replaces the set of enabled dimensions at runtime. Counters already recorded are kept.
+func (s *StatsCollector) SetDimensions(dims []Dimension) {
+	enabled := make(map[Dimension]bool, len(dims))
+	for _, d := range dims {
+		enabled[d] = true
+	}
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	s.breakdowns.enabled = enabled
+}
+
+// Dimensions returns the enabled dimensions, sorted by name
+func (s *StatsCollector) Dimensions() []Dimension {
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	return slices.Sorted(maps.Keys(s.breakdowns.enabled))
+}
+
 // SetWorkerID sets the worker ID for the stats collector
 func (s *StatsCollector) SetWorkerID(workerID string) {
 	s.Stats.Lock()
@@ -126,16 +276,45 @@ func (s *StatsCollector) SetWorkerID(workerID string) {
 	s.Stats.WorkerID = workerID
 }
 
+// SetBenchmark publishes the latest self-benchmark of the worker
+func (s *StatsCollector) SetBenchmark(report types.BenchmarkReport) {
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	s.Stats.Benchmark = &report
+}
+
 // SetJobServer sets the JobServer reference and updates capabilities
 func (s *StatsCollector) SetJobServer(js WorkerCapabilitiesProvider) {
-	s.jobServer = js
-
 	// Now that we have the JobServer, update capabilities
 	s.Stats.Lock()
 	defer s.Stats.Unlock()
+	s.jobServer = js
 
 	// Get capabilities from the JobServer directly
 	s.Stats.ReportedCapabilities = js.GetWorkerCapabilities()
+	if k, ok := js.(keyCapabilitiesProvider); ok {
+		s.Stats.KeyCapabilities = k.GetKeyCapabilities()
+	}
 
 	logrus.Infof("Updated structured capabilities with JobServer: %+v", s.Stats.ReportedCapabilities)
 }
+
+// RefreshCapabilities updates the reported capabilities from the JobServer, after the capabilities of a worker changed
+// at runtime
+func (s *StatsCollector) RefreshCapabilities() {
+	if s == nil {
+		return
+	}
+	s.Stats.Lock()
+	js := s.jobServer
+	s.Stats.Unlock()
+	if js == nil {
+		return
+	}
+	capabilities := js.GetWorkerCapabilities()
+
+	s.Stats.Lock()
+	defer s.Stats.Unlock()
+	s.Stats.ReportedCapabilities = capabilities
+	logrus.Infof("Updated structured capabilities: %+v", capabilities)
+}
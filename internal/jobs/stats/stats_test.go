@@ -0,0 +1,41 @@
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+)
+
+// capabilitiesProvider is a JobServer whose capabilities can be changed
+type capabilitiesProvider struct {
+	capabilities teetypes.WorkerCapabilities
+}
+
+func (p *capabilitiesProvider) GetWorkerCapabilities() teetypes.WorkerCapabilities {
+	return p.capabilities
+}
+
+var _ = Describe("Reported capabilities", func() {
+	It("are refreshed when the capabilities of a worker change", func() {
+		c := stats.StartCollector(128, config.JobConfiguration{})
+		provider := &capabilitiesProvider{capabilities: teetypes.WorkerCapabilities{
+			teetypes.TwitterCredentialJob: {teetypes.CapSearchByQuery},
+			teetypes.TwitterApiJob:        {teetypes.CapSearchByQuery},
+		}}
+		c.SetJobServer(provider)
+		Expect(c.Stats.ReportedCapabilities).To(HaveKey(teetypes.TwitterCredentialJob))
+
+		provider.capabilities = teetypes.WorkerCapabilities{teetypes.TwitterApiJob: {teetypes.CapSearchByQuery}}
+		c.RefreshCapabilities()
+		Expect(c.Stats.ReportedCapabilities).To(Equal(provider.capabilities))
+	})
+
+	It("are not refreshed without a JobServer", func() {
+		var c *stats.StatsCollector
+		Expect(c.RefreshCapabilities).NotTo(Panic())
+		Expect(stats.StartCollector(128, config.JobConfiguration{}).RefreshCapabilities).NotTo(Panic())
+	})
+})
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	teetypes "github.com/masa-finance/tee-types/types"
 	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
 	"github.com/masa-finance/tee-worker/internal/jobs/tiktokapify"
 	"github.com/masa-finance/tee-worker/pkg/client"
@@ -42,8 +44,9 @@ type TikTokTranscriber struct {
 
 // GetStructuredCapabilities returns the structured capabilities supported by the TikTok transcriber
 func (t *TikTokTranscriber) GetStructuredCapabilities() teetypes.WorkerCapabilities {
-	caps := make([]teetypes.Capability, 0, len(teetypes.AlwaysAvailableTiktokCaps)+len(teetypes.TiktokSearchCaps))
+	caps := make([]teetypes.Capability, 0, len(teetypes.AlwaysAvailableTiktokCaps)+1+len(teetypes.TiktokSearchCaps))
 	caps = append(caps, teetypes.AlwaysAvailableTiktokCaps...)
+	caps = append(caps, CapListLanguages)
 	if t.configuration.ApifyApiKey != "" {
 		caps = append(caps, teetypes.TiktokSearchCaps...)
 	}
@@ -52,6 +55,36 @@ func (t *TikTokTranscriber) GetStructuredCapabilities() teetypes.WorkerCapabilit
 	}
 }
 
+// GetCapabilityDetails returns the auth source of each capability. Transcription needs no authentication, search goes through Apify.
+func (t *TikTokTranscriber) GetCapabilityDetails() types.CapabilityDetails {
+	always := append(slices.Clone(teetypes.AlwaysAvailableTiktokCaps), CapListLanguages)
+	details := types.NewCapabilityDetails(teetypes.WorkerCapabilities{teetypes.TiktokJob: always}, types.AuthSourceNone)
+	if t.configuration.ApifyApiKey != "" {
+		apify := types.NewCapabilityDetails(teetypes.WorkerCapabilities{teetypes.TiktokJob: teetypes.TiktokSearchCaps}, types.AuthSourceApify)
+		details[teetypes.TiktokJob] = append(details[teetypes.TiktokJob], apify[teetypes.TiktokJob]...)
+	}
+	return details
+}
+
+// ProbeDependencies checks that the transcription endpoint is reachable. It only accepts transcription requests, so
+// any response which is not a server error is considered healthy.
+func (t *TikTokTranscriber) ProbeDependencies() error {
+	if t.configuration.TranscriptionEndpoint == "" {
+		return types.ErrNotConfigured
+	}
+
+	resp, err := t.httpClient.Head(t.configuration.TranscriptionEndpoint)
+	if err != nil {
+		return fmt.Errorf("transcription endpoint unreachable: %w", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("transcription endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
 // NewTikTokTranscriber creates and initializes a new TikTokTranscriber.
 // It sets default values for the API configuration.
 func NewTikTokTranscriber(jc config.JobConfiguration, statsCollector *stats.StatsCollector) *TikTokTranscriber {
@@ -69,11 +102,6 @@ func NewTikTokTranscriber(jc config.JobConfiguration, statsCollector *stats.Stat
 	// Get Apify key from configuration (validation now handled at startup by capability detection)
 	config.ApifyApiKey = jc.GetString("apify_api_key", config.ApifyApiKey)
 
-	// Note: APIUserAgent is optional, it can be set later or use a default
-	if config.APIUserAgent == "" {
-		config.APIUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/126.0.0.0 Safari/537.36"
-	}
-
 	// If a default language is set in the configuration, use it
 	if config.DefaultLanguage == "" {
 		config.DefaultLanguage = "eng-US"
@@ -99,10 +127,19 @@ type APIResponse struct {
 	Error        string            `json:"error,omitempty"` // Optional error from API
 }
 
+// addStat adds to a statistic, broken down by the capability of the job if enabled
+func (ttt *TikTokTranscriber) addStat(j types.Job, typ stats.StatType, num uint) {
+	ttt.stats.AddWithDimensions(j.WorkerID, typ, num, stats.DimensionsForJob(j))
+}
+
 // ExecuteJob processes a single TikTok transcription job.
 func (ttt *TikTokTranscriber) ExecuteJob(j types.Job) (types.JobResult, error) {
 	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for TikTok job")
 
+	if isCapabilityJob(j, CapListLanguages) {
+		return ttt.executeListLanguages(j)
+	}
+
 	// Use the centralized type-safe unmarshaller
 	jobArgs, err := teeargs.UnmarshalJobArguments(teetypes.JobType(j.Type), map[string]any(j.Arguments))
 	if err != nil {
@@ -126,7 +163,7 @@ func (ttt *TikTokTranscriber) executeTranscription(j types.Job, a *teeargs.TikTo
 	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for TikTok transcription")
 
 	if ttt.configuration.TranscriptionEndpoint == "" {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
 		return types.JobResult{Error: "TikTok transcription endpoint is not configured for the worker"}, fmt.Errorf("tiktok transcription endpoint not configured")
 	}
 
@@ -148,114 +185,61 @@ func (ttt *TikTokTranscriber) executeTranscription(j types.Job, a *teeargs.TikTo
 
 	// VideoURL validation is now handled by the unmarshaller, but we check again for safety
 	if tiktokArgs.GetVideoURL() == "" {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
 		return types.JobResult{Error: "VideoURL is required"}, fmt.Errorf("videoURL is required")
 	}
 
-	// Sub-Step 3.1: Call TikTok Transcription API
-	apiRequestBody := map[string]string{"url": tiktokArgs.GetVideoURL()}
-	jsonBody, err := json.Marshal(apiRequestBody)
+	strict, err := strictLanguage(j.Arguments)
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: "Failed to marshal API request body"}, fmt.Errorf("marshal API request body: %w", err)
-	}
-
-	req, err := http.NewRequest("POST", ttt.configuration.TranscriptionEndpoint, bytes.NewBuffer(jsonBody))
-	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: "Failed to create API request"}, fmt.Errorf("create API request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	if ttt.configuration.APIOrigin != "" {
-		req.Header.Set("Origin", ttt.configuration.APIOrigin)
-	}
-	if ttt.configuration.APIReferer != "" {
-		req.Header.Set("Referer", ttt.configuration.APIReferer)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return types.JobResult{Error: err.Error()}, err
 	}
-	// User-Agent is set from config or default in NewTikTokTranscriber
-	req.Header.Set("User-Agent", ttt.configuration.APIUserAgent)
 
-	logrus.WithFields(logrus.Fields{
-		"job_uuid":     j.UUID,
-		"url":          tiktokArgs.GetVideoURL(),
-		"method":       "POST",
-		"api_endpoint": ttt.configuration.TranscriptionEndpoint,
-	}).Info("Calling TikTok Transcription API")
-
-	apiResp, err := ttt.httpClient.Do(req)
+	// Sub-Step 3.1: Call TikTok Transcription API
+	parsedAPIResponse, errResult, err := ttt.fetchTranscripts(j, tiktokArgs.GetVideoURL())
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: "API request failed"}, fmt.Errorf("API request execution: %w", err)
-	}
-	defer apiResp.Body.Close()
-
-	if apiResp.StatusCode != http.StatusOK {
-		// Try to read body for more error details from API
-		bodyBytes, _ := io.ReadAll(apiResp.Body)
-		errMsg := fmt.Sprintf("API request failed with status code %d. Response: %s", apiResp.StatusCode, string(bodyBytes))
-		logrus.WithField("job_uuid", j.UUID).Error(errMsg)
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
-	}
-
-	var parsedAPIResponse APIResponse
-	if err := json.NewDecoder(apiResp.Body).Decode(&parsedAPIResponse); err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: "Failed to parse API response"}, fmt.Errorf("parse API response: %w", err)
-	}
-
-	if parsedAPIResponse.Error != "" {
-		errMsg := fmt.Sprintf("API returned an error: %s", parsedAPIResponse.Error)
-		logrus.WithField("job_uuid", j.UUID).Error(errMsg)
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
-		return types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
+		return errResult, err
 	}
 
 	// Sub-Step 3.2: Extract Transcription and Metadata
 	if len(parsedAPIResponse.Transcripts) == 0 {
 		errMsg := "No transcripts found in API response"
 		logrus.WithField("job_uuid", j.UUID).Warn(errMsg)
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1) // Or a different stat for "no_transcript_found"
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1) // Or a different stat for "no_transcript_found"
 		return types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 
 	vttText := ""
 	languageCode := tiktokArgs.GetLanguageCode() // either requested or default
 
-	if tiktokArgs.HasLanguagePreference() {
-		if transcript, ok := parsedAPIResponse.Transcripts[tiktokArgs.Language]; ok && strings.TrimSpace(transcript) != "" {
-			vttText = transcript
-		}
-	} else {
-		// Attempt to use the default language
-		if transcript, ok := parsedAPIResponse.Transcripts[languageCode]; ok && strings.TrimSpace(transcript) != "" {
-			vttText = transcript
-		} else {
-			// No preference and default not found - return the first available transcript
-			for langCode, transcript := range parsedAPIResponse.Transcripts {
-				if strings.TrimSpace(transcript) != "" {
-					vttText = transcript
-					languageCode = langCode
-					break
-				}
-			}
-		}
+	if transcript := parsedAPIResponse.Transcripts[languageCode]; strings.TrimSpace(transcript) != "" {
+		vttText = transcript
+	} else if !tiktokArgs.HasLanguagePreference() || !strict {
+		// Fall back to the default language, then to the first available transcript, and report the language used
+		languageCode, vttText = parsedAPIResponse.fallbackTranscript(ttt.configuration.DefaultLanguage)
 	}
 
 	if vttText == "" {
 		errMsg := "No transcripts found in API response"
 		if tiktokArgs.HasLanguagePreference() {
-			errMsg = fmt.Sprintf("Transcript for requested language %s not found in API response", languageCode)
+			errMsg = fmt.Sprintf("Transcript for requested language %s not found in API response", tiktokArgs.GetLanguageCode())
 		}
 		logrus.WithFields(logrus.Fields{
 			"job_uuid":       j.UUID,
-			"requested_lang": languageCode,
+			"requested_lang": tiktokArgs.GetLanguageCode(),
 		}).Error(errMsg)
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
 		return types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 
+	if tiktokArgs.HasLanguagePreference() && languageCode != tiktokArgs.GetLanguageCode() {
+		logrus.WithFields(logrus.Fields{
+			"job_uuid":          j.UUID,
+			"requested_lang":    tiktokArgs.GetLanguageCode(),
+			"detected_language": languageCode,
+		}).Info("Requested transcript language not available, falling back")
+	}
+
 	logrus.Debugf("Job %s: Raw VTT content for language %s:\n%s", j.UUID, languageCode, vttText)
 
 	// Convert VTT to Plain Text
@@ -264,7 +248,7 @@ func (ttt *TikTokTranscriber) executeTranscription(j types.Job, a *teeargs.TikTo
 		// This error is more about our parsing than the API
 		errMsg := fmt.Sprintf("Failed to convert VTT to plain text: %v", err)
 		logrus.WithField("job_uuid", j.UUID).Error(errMsg)
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
 		return types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
 	}
 
@@ -279,7 +263,7 @@ func (ttt *TikTokTranscriber) executeTranscription(j types.Job, a *teeargs.TikTo
 
 	jsonData, err := json.Marshal(resultData)
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionErrors, 1)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
 		return types.JobResult{Error: "Failed to marshal result data"}, fmt.Errorf("marshal result data: %w", err)
 	}
 
@@ -288,15 +272,88 @@ func (ttt *TikTokTranscriber) executeTranscription(j types.Job, a *teeargs.TikTo
 		"video_title":       resultData.VideoTitle,
 		"detected_language": resultData.DetectedLanguage,
 	}).Info("Successfully processed TikTok transcription job")
-	ttt.stats.Add(j.WorkerID, stats.TikTokTranscriptionSuccess, 1)
+	ttt.addStat(j, stats.TikTokTranscriptionSuccess, 1)
 	return types.JobResult{Data: jsonData}, nil
 }
 
+// fetchTranscripts calls the transcription API for a video. On failure it returns the result to report for the job,
+// and records the error.
+func (ttt *TikTokTranscriber) fetchTranscripts(j types.Job, videoURL string) (*APIResponse, types.JobResult, error) {
+	apiRequestBody := map[string]string{"url": videoURL}
+	jsonBody, err := json.Marshal(apiRequestBody)
+	if err != nil {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: "Failed to marshal API request body"}, fmt.Errorf("marshal API request body: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", ttt.configuration.TranscriptionEndpoint, bytes.NewBuffer(jsonBody))
+	if err != nil {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: "Failed to create API request"}, fmt.Errorf("create API request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if ttt.configuration.APIOrigin != "" {
+		req.Header.Set("Origin", ttt.configuration.APIOrigin)
+	}
+	if ttt.configuration.APIReferer != "" {
+		req.Header.Set("Referer", ttt.configuration.APIReferer)
+	}
+	// TIKTOK_API_USER_AGENT takes precedence over the fingerprint profile presented to the transcription API
+	profileKey := fingerprint.DomainKey(domainOf(ttt.configuration.TranscriptionEndpoint))
+	if ttt.configuration.APIUserAgent != "" {
+		req.Header.Set("User-Agent", ttt.configuration.APIUserAgent)
+	} else {
+		fingerprint.For(profileKey).Apply(req.Header)
+	}
+
+	logrus.WithFields(logrus.Fields{
+		"job_uuid":     j.UUID,
+		"url":          videoURL,
+		"method":       "POST",
+		"api_endpoint": ttt.configuration.TranscriptionEndpoint,
+	}).Info("Calling TikTok Transcription API")
+
+	apiResp, err := j.Network.Client(ttt.httpClient).Do(req)
+	if err != nil {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: "API request failed"}, fmt.Errorf("API request execution: %w", err)
+	}
+	defer apiResp.Body.Close()
+
+	if apiResp.StatusCode != http.StatusOK {
+		if isBlockStatus(apiResp.StatusCode) && ttt.configuration.APIUserAgent == "" {
+			fingerprint.Rotate(profileKey)
+		}
+		// Try to read body for more error details from API
+		bodyBytes, _ := io.ReadAll(apiResp.Body)
+		errMsg := fmt.Sprintf("API request failed with status code %d. Response: %s", apiResp.StatusCode, string(bodyBytes))
+		logrus.WithField("job_uuid", j.UUID).Error(errMsg)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
+	}
+
+	var parsedAPIResponse APIResponse
+	if err := json.NewDecoder(apiResp.Body).Decode(&parsedAPIResponse); err != nil {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: "Failed to parse API response"}, fmt.Errorf("parse API response: %w", err)
+	}
+
+	if parsedAPIResponse.Error != "" {
+		errMsg := fmt.Sprintf("API returned an error: %s", parsedAPIResponse.Error)
+		logrus.WithField("job_uuid", j.UUID).Error(errMsg)
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return nil, types.JobResult{Error: errMsg}, fmt.Errorf(errMsg)
+	}
+
+	return &parsedAPIResponse, types.JobResult{}, nil
+}
+
 // executeSearchByQuery runs the epctex/tiktok-search-scraper actor and returns results
 func (ttt *TikTokTranscriber) executeSearchByQuery(j types.Job, a *teeargs.TikTokSearchByQueryArguments) (types.JobResult, error) {
-	c, err := tiktokapify.NewTikTokApifyClient(ttt.configuration.ApifyApiKey)
+	c, err := tiktokapify.NewTikTokApifyClient(ttt.configuration.ApifyApiKey, apifyOptions(j)...)
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokAuthErrors, 1)
+		ttt.addStat(j, stats.TikTokAuthErrors, 1)
 		return types.JobResult{Error: "Failed to create Apify client"}, fmt.Errorf("apify client: %w", err)
 	}
 
@@ -307,27 +364,21 @@ func (ttt *TikTokTranscriber) executeSearchByQuery(j types.Job, a *teeargs.TikTo
 
 	items, next, err := c.SearchByQuery(*a, client.EmptyCursor, limit)
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokErrors, 1)
+		ttt.addStat(j, stats.TikTokErrors, 1)
 		return types.JobResult{Error: err.Error()}, err
 	}
 
-	data, err := json.Marshal(items)
-	if err != nil {
-		// Do not increment error stats for marshal errors; not the worker's fault
-		return types.JobResult{Error: "Failed to marshal results"}, fmt.Errorf("marshal results: %w", err)
-	}
-
 	// Increment returned videos based on the number of items
-	ttt.stats.Add(j.WorkerID, stats.TikTokVideos, uint(len(items)))
-	ttt.stats.Add(j.WorkerID, stats.TikTokQueries, 1)
-	return types.JobResult{Data: data, NextCursor: next.String()}, nil
+	ttt.addStat(j, stats.TikTokVideos, uint(len(items)))
+	ttt.addStat(j, stats.TikTokQueries, 1)
+	return apifyPageResult(items, next)
 }
 
 // executeSearchByTrending runs the lexis-solutions/tiktok-trending-videos-scraper actor and returns results
 func (ttt *TikTokTranscriber) executeSearchByTrending(j types.Job, a *teeargs.TikTokSearchByTrendingArguments) (types.JobResult, error) {
-	c, err := tiktokapify.NewTikTokApifyClient(ttt.configuration.ApifyApiKey)
+	c, err := tiktokapify.NewTikTokApifyClient(ttt.configuration.ApifyApiKey, apifyOptions(j)...)
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokAuthErrors, 1)
+		ttt.addStat(j, stats.TikTokAuthErrors, 1)
 		return types.JobResult{Error: "Failed to create Apify client"}, fmt.Errorf("apify client: %w", err)
 	}
 
@@ -338,20 +389,14 @@ func (ttt *TikTokTranscriber) executeSearchByTrending(j types.Job, a *teeargs.Ti
 
 	items, next, err := c.SearchByTrending(*a, client.EmptyCursor, uint(limit))
 	if err != nil {
-		ttt.stats.Add(j.WorkerID, stats.TikTokErrors, 1)
+		ttt.addStat(j, stats.TikTokErrors, 1)
 		return types.JobResult{Error: err.Error()}, err
 	}
 
-	data, err := json.Marshal(items)
-	if err != nil {
-		// Do not increment error stats for marshal errors; not the worker's fault
-		return types.JobResult{Error: "Failed to marshal results"}, fmt.Errorf("marshal results: %w", err)
-	}
-
 	// Increment returned videos based on the number of items
-	ttt.stats.Add(j.WorkerID, stats.TikTokVideos, uint(len(items)))
-	ttt.stats.Add(j.WorkerID, stats.TikTokQueries, 1)
-	return types.JobResult{Data: data, NextCursor: next.String()}, nil
+	ttt.addStat(j, stats.TikTokVideos, uint(len(items)))
+	ttt.addStat(j, stats.TikTokQueries, 1)
+	return apifyPageResult(items, next)
 }
 
 // convertVTTToPlainText parses a VTT string and extracts the dialogue lines.
@@ -0,0 +1,127 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	teeargs "github.com/masa-finance/tee-types/args"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/sirupsen/logrus"
+)
+
+// CapListLanguages returns the languages in which transcripts of a TikTok video are available, so that a
+// transcription can be requested in one of them. Like the capabilities of the Twitter scraper which are not part of
+// tee-types yet, it is handled before the arguments are validated against the tee-types capabilities.
+const CapListLanguages teetypes.Capability = "list_languages"
+
+// strictLanguageArgumentKey is the transcription argument which controls whether a job fails if no transcript is
+// available in the requested language. It defaults to true; if false, the transcript of another language is returned,
+// and its language is reported in detected_language.
+const strictLanguageArgumentKey = "strict_language"
+
+// TikTokListLanguagesArguments are the arguments of a list_languages job
+type TikTokListLanguagesArguments struct {
+	QueryType string `json:"type"`
+	VideoURL  string `json:"video_url"`
+}
+
+// TikTokListLanguagesResult is the result of a list_languages job. Languages are sorted.
+type TikTokListLanguagesResult struct {
+	VideoURL   string   `json:"video_url"`
+	VideoTitle string   `json:"video_title,omitempty"`
+	Languages  []string `json:"languages"`
+}
+
+// parseListLanguagesArguments unmarshals and validates the arguments of a list_languages job. The video URL is
+// validated like the one of a transcription.
+func parseListLanguagesArguments(args map[string]any) (*TikTokListLanguagesArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal list_languages arguments: %w", err)
+	}
+
+	parsed := &TikTokListLanguagesArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal list_languages arguments: %w", err)
+	}
+
+	parsed.VideoURL = strings.TrimSpace(parsed.VideoURL)
+	if err := (&teeargs.TikTokTranscriptionArguments{VideoURL: parsed.VideoURL}).Validate(); err != nil {
+		return nil, err
+	}
+
+	return parsed, nil
+}
+
+// strictLanguage returns the strict_language argument of a transcription job, which is true if not set
+func strictLanguage(args types.JobArguments) (bool, error) {
+	v, ok := args[strictLanguageArgumentKey]
+	if !ok || v == nil {
+		return true, nil
+	}
+	strict, ok := v.(bool)
+	if !ok {
+		return false, fmt.Errorf("%s must be a boolean, got %T", strictLanguageArgumentKey, v)
+	}
+	return strict, nil
+}
+
+// Languages returns the sorted languages of the transcripts which are not empty
+func (r *APIResponse) Languages() []string {
+	languages := make([]string, 0, len(r.Transcripts))
+	for language, transcript := range r.Transcripts {
+		if strings.TrimSpace(transcript) != "" {
+			languages = append(languages, language)
+		}
+	}
+	slices.Sort(languages)
+	return languages
+}
+
+// fallbackTranscript returns the transcript in the default language if there is one, or else the first transcript
+// in the order of Languages, together with its language. It returns empty strings if there are no transcripts.
+func (r *APIResponse) fallbackTranscript(defaultLanguage string) (string, string) {
+	if transcript := r.Transcripts[defaultLanguage]; strings.TrimSpace(transcript) != "" {
+		return defaultLanguage, transcript
+	}
+	if languages := r.Languages(); len(languages) > 0 {
+		return languages[0], r.Transcripts[languages[0]]
+	}
+	return "", ""
+}
+
+// executeListLanguages returns the languages of the transcripts available for a video
+func (ttt *TikTokTranscriber) executeListLanguages(j types.Job) (types.JobResult, error) {
+	args, err := parseListLanguagesArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	if ttt.configuration.TranscriptionEndpoint == "" {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return types.JobResult{Error: "TikTok transcription endpoint is not configured for the worker"}, fmt.Errorf("tiktok transcription endpoint not configured")
+	}
+
+	resp, errResult, err := ttt.fetchTranscripts(j, args.VideoURL)
+	if err != nil {
+		return errResult, err
+	}
+
+	jsonData, err := json.Marshal(TikTokListLanguagesResult{
+		VideoURL:   args.VideoURL,
+		VideoTitle: resp.VideoTitle,
+		Languages:  resp.Languages(),
+	})
+	if err != nil {
+		ttt.addStat(j, stats.TikTokTranscriptionErrors, 1)
+		return types.JobResult{Error: "Failed to marshal result data"}, fmt.Errorf("marshal result data: %w", err)
+	}
+
+	ttt.addStat(j, stats.TikTokTranscriptionSuccess, 1)
+	return types.JobResult{Data: jsonData}, nil
+}
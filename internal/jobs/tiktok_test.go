@@ -3,6 +3,8 @@ package jobs_test
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"strings"
 	"time"
@@ -161,6 +163,100 @@ var _ = Describe("TikTok", func() {
 		})
 	})
 
+	Context("with a transcription endpoint returning several languages", func() {
+		const videoURL = "https://www.tiktok.com/@example/video/7230000000000000000"
+		var server *httptest.Server
+
+		BeforeEach(func() {
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(APIResponse{
+					VideoTitle: "Example video",
+					Transcripts: map[string]string{
+						"spa-ES": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nhola mundo\n",
+						"fra-FR": "WEBVTT\n\n00:00:00.000 --> 00:00:01.000\nbonjour le monde\n",
+						"deu-DE": " ",
+					},
+				})
+			}))
+			DeferCleanup(server.Close)
+
+			tikTokTranscriber = NewTikTokTranscriber(config.JobConfiguration{
+				"tiktok_transcription_endpoint": server.URL,
+				"tiktok_default_language":       "eng-US",
+			}, statsCollector)
+		})
+
+		transcribe := func(args map[string]interface{}) (types.JobResult, error) {
+			args["type"] = teetypes.CapTranscription
+			args["video_url"] = videoURL
+			return tikTokTranscriber.ExecuteJob(types.Job{Type: teetypes.TiktokJob, Arguments: args, WorkerID: "tiktok-test-worker-languages", UUID: "test-uuid-languages"})
+		}
+
+		It("should return the requested language", func() {
+			res, err := transcribe(map[string]interface{}{"language": "spa-ES"})
+			Expect(err).NotTo(HaveOccurred())
+
+			var result teetypes.TikTokTranscriptionResult
+			Expect(json.Unmarshal(res.Data, &result)).To(Succeed())
+			Expect(result.DetectedLanguage).To(Equal("spa-ES"))
+			Expect(result.TranscriptionText).To(ContainSubstring("hola mundo"))
+		})
+
+		It("should fail if the requested language is missing and strict_language is not disabled", func() {
+			res, err := transcribe(map[string]interface{}{"language": "eng-US"})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(ContainSubstring("Transcript for requested language eng-US not found"))
+		})
+
+		It("should fall back to the first available language if strict_language is false", func() {
+			res, err := transcribe(map[string]interface{}{"language": "eng-US", "strict_language": false})
+			Expect(err).NotTo(HaveOccurred())
+
+			var result teetypes.TikTokTranscriptionResult
+			Expect(json.Unmarshal(res.Data, &result)).To(Succeed())
+			Expect(result.DetectedLanguage).To(Equal("fra-FR"))
+			Expect(result.TranscriptionText).To(ContainSubstring("bonjour le monde"))
+			Expect(result.VideoTitle).To(Equal("Example video"))
+		})
+
+		It("should reject a strict_language which is not a boolean", func() {
+			res, err := transcribe(map[string]interface{}{"language": "eng-US", "strict_language": "no"})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(ContainSubstring("strict_language must be a boolean"))
+		})
+
+		It("should list the available languages", func() {
+			res, err := tikTokTranscriber.ExecuteJob(types.Job{
+				Type:      teetypes.TiktokJob,
+				Arguments: map[string]interface{}{"type": CapListLanguages, "video_url": videoURL},
+				WorkerID:  "tiktok-test-worker-languages",
+				UUID:      "test-uuid-list-languages",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var result TikTokListLanguagesResult
+			Expect(json.Unmarshal(res.Data, &result)).To(Succeed())
+			Expect(result.VideoURL).To(Equal(videoURL))
+			Expect(result.VideoTitle).To(Equal("Example video"))
+			Expect(result.Languages).To(Equal([]string{"fra-FR", "spa-ES"}))
+		})
+
+		It("should reject list_languages without a TikTok URL", func() {
+			res, err := tikTokTranscriber.ExecuteJob(types.Job{
+				Type:      teetypes.TiktokJob,
+				Arguments: map[string]interface{}{"type": CapListLanguages, "video_url": "https://example.com/video"},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		})
+
+		It("should report list_languages as a capability without authentication", func() {
+			Expect(tikTokTranscriber.GetStructuredCapabilities()[teetypes.TiktokJob]).To(ContainElement(CapListLanguages))
+			Expect(tikTokTranscriber.ArgumentKeys()).To(ContainElement("strict_language"))
+		})
+	})
+
 	Context("TikTok Apify search", func() {
 		It("should search by query via Apify", func() {
 			apifyKey := os.Getenv("APIFY_API_KEY")
@@ -29,8 +29,8 @@ type TikTokApifyClient struct {
 	apify client.Apify
 }
 
-func NewTikTokApifyClient(apiToken string) (*TikTokApifyClient, error) {
-	apifyClient, err := client.NewApifyClient(apiToken)
+func NewTikTokApifyClient(apiToken string, opts ...client.Option) (*TikTokApifyClient, error) {
+	apifyClient, err := client.NewApifyClient(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Apify client: %w", err)
 	}
@@ -1,9 +1,10 @@
 package jobs
 
 import (
-	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -112,7 +113,7 @@ func (ts *TwitterScraper) getCredentialScraper(j types.Job, baseDir string) (*tw
 
 	account := ts.accountManager.GetNextAccount()
 	if account == nil {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
 		return nil, nil, fmt.Errorf("no Twitter credentials available")
 	}
 
@@ -123,25 +124,41 @@ func (ts *TwitterScraper) getCredentialScraper(j types.Job, baseDir string) (*tw
 	}
 	scraper := twitter.NewScraper(authConfig)
 	if scraper == nil {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
 		logrus.Errorf("Authentication failed for %s", account.Username)
+		// The account may be suspended or locked, so it is tried again later
+		ts.accountManager.MarkAccountSuspended(account)
 		return nil, account, fmt.Errorf("twitter authentication failed for %s", account.Username)
 	}
 
+	j.Provenance.SetAuthSource(types.AuthSourceCredential)
+	j.Trace.Strategy(types.AuthSourceCredential, account.Username)
 	return scraper, account, nil
 }
 
+// newTwitterXClient returns a Twitter API client whose traffic is counted towards the bandwidth of the job
+func newTwitterXClient(j types.Job, apiKey string) *client.TwitterXClient {
+	return client.NewTwitterXClient(apiKey, client.WrapTransport(j.Network.Transport))
+}
+
 // getApiScraper returns a TwitterX API scraper and API key
 func (ts *TwitterScraper) getApiScraper(j types.Job) (*twitterx.TwitterXScraper, *twitter.TwitterApiKey, error) {
-	apiKey := ts.accountManager.GetNextApiKey()
+	return ts.getApiScraperFor(j, "")
+}
+
+// getApiScraperFor returns an API scraper using a key which has access to the endpoint, if there is one
+func (ts *TwitterScraper) getApiScraperFor(j types.Job, endpoint string) (*twitterx.TwitterXScraper, *twitter.TwitterApiKey, error) {
+	apiKey := ts.accountManager.GetNextApiKeyFor(endpoint)
 	if apiKey == nil {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
 		return nil, nil, fmt.Errorf("no Twitter API keys available")
 	}
 
-	apiClient := client.NewTwitterXClient(apiKey.Key)
+	apiClient := newTwitterXClient(j, apiKey.Key)
 	twitterXScraper := twitterx.NewTwitterXScraper(apiClient)
 
+	j.Provenance.SetAuthSource(types.AuthSourceAPI)
+	j.Trace.Strategy(types.AuthSourceAPI, apiKey.Key)
 	return twitterXScraper, apiKey, nil
 }
 
@@ -149,21 +166,23 @@ func (ts *TwitterScraper) getApiScraper(j types.Job) (*twitterx.TwitterXScraper,
 func (ts *TwitterScraper) getApifyScraper(j types.Job) (*twitterapify.TwitterApifyClient, error) {
 	// TODO: We should verify whether each of the actors is actually available through this API key
 	if ts.configuration.ApifyApiKey == "" {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
 		return nil, fmt.Errorf("no Apify API key available")
 	}
 
-	apifyScraper, err := twitterapify.NewTwitterApifyClient(ts.configuration.ApifyApiKey)
+	apifyScraper, err := twitterapify.NewTwitterApifyClient(ts.configuration.ApifyApiKey, apifyOptions(j)...)
 	if err != nil {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
 		return nil, fmt.Errorf("failed to create apify scraper: %w", err)
 	}
+	j.Provenance.SetAuthSource(types.AuthSourceApify)
+	j.Trace.Strategy(types.AuthSourceApify, ts.configuration.ApifyApiKey)
 	return apifyScraper, nil
 }
 
 func (ts *TwitterScraper) handleError(j types.Job, err error, account *twitter.TwitterAccount) bool {
 	if strings.Contains(err.Error(), "Rate limit exceeded") || strings.Contains(err.Error(), "status code 429") {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterRateErrors, 1)
+		ts.addStat(j, stats.TwitterRateErrors, 1)
 		if account != nil {
 			ts.accountManager.MarkAccountRateLimited(account)
 			logrus.Warnf("rate limited: %s", account.Username)
@@ -172,7 +191,13 @@ func (ts *TwitterScraper) handleError(j types.Job, err error, account *twitter.T
 		}
 		return true
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterErrors, 1)
+	if account != nil && twitter.IsSuspensionError(err) {
+		ts.addStat(j, stats.TwitterAuthErrors, 1)
+		ts.accountManager.MarkAccountSuspended(account)
+		logrus.Warnf("suspended or locked: %s", account.Username)
+		return false
+	}
+	ts.addStat(j, stats.TwitterErrors, 1)
 	return false
 }
 
@@ -192,7 +217,7 @@ func (ts *TwitterScraper) ScrapeFollowersForProfile(j types.Job, baseDir string,
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	followingResponse, errString, _ := scraper.FetchFollowers(username, count, "")
 	if errString != "" {
 		fetchErr := fmt.Errorf("error fetching followers: %s", errString)
@@ -203,21 +228,21 @@ func (ts *TwitterScraper) ScrapeFollowersForProfile(j types.Job, baseDir string,
 		return nil, fetchErr
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, uint(len(followingResponse)))
+	ts.addStat(j, stats.TwitterProfiles, uint(len(followingResponse)))
 	return followingResponse, nil
 }
 
 func (ts *TwitterScraper) ScrapeTweetsProfile(j types.Job, baseDir string, username string) (twitterscraper.Profile, error) {
-	logrus.Infof("[ScrapeTweetsProfile] Starting profile scraping for username: %s", username)
+	logrus.Debugf("[ScrapeTweetsProfile] Starting profile scraping for username: %s", username)
 	scraper, account, err := ts.getCredentialScraper(j, baseDir)
 	if err != nil {
 		logrus.Errorf("[ScrapeTweetsProfile] Failed to get credential scraper: %v", err)
 		return twitterscraper.Profile{}, err
 	}
 
-	logrus.Infof("[ScrapeTweetsProfile] About to increment TwitterScrapes stat for WorkerID: %s", j.WorkerID)
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
-	logrus.Infof("[ScrapeTweetsProfile] TwitterScrapes incremented, now calling scraper.GetProfile")
+	logrus.Debugf("[ScrapeTweetsProfile] About to increment TwitterScrapes stat for WorkerID: %s", j.WorkerID)
+	ts.addStat(j, stats.TwitterScrapes, 1)
+	logrus.Debugf("[ScrapeTweetsProfile] TwitterScrapes incremented, now calling scraper.GetProfile")
 
 	profile, err := scraper.GetProfile(username)
 	if err != nil {
@@ -226,36 +251,55 @@ func (ts *TwitterScraper) ScrapeTweetsProfile(j types.Job, baseDir string, usern
 		return twitterscraper.Profile{}, err
 	}
 
-	logrus.Infof("[ScrapeTweetsProfile] Profile retrieved successfully for username: %s, profile: %+v", username, profile)
-	logrus.Infof("[ScrapeTweetsProfile] About to increment TwitterProfiles stat for WorkerID: %s", j.WorkerID)
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, 1)
-	logrus.Infof("[ScrapeTweetsProfile] TwitterProfiles incremented successfully")
+	logrus.Debugf("[ScrapeTweetsProfile] Profile retrieved successfully for username: %s, profile: %+v", username, profile)
+	logrus.Debugf("[ScrapeTweetsProfile] About to increment TwitterProfiles stat for WorkerID: %s", j.WorkerID)
+	ts.addStat(j, stats.TwitterProfiles, 1)
+	logrus.Debugf("[ScrapeTweetsProfile] TwitterProfiles incremented successfully")
 
 	return profile, nil
 }
 
 func (ts *TwitterScraper) ScrapeTweetsByFullArchiveSearchQuery(j types.Job, baseDir string, query string, count int) ([]*teetypes.TweetResult, error) {
-	return ts.queryTweets(j, twitterx.TweetsAll, baseDir, query, count)
+	tweets, fanOut := ts.queryTweets(j, twitterx.TweetsAll, baseDir, query, count)
+	return tweets, fanOut.ErrOrNil()
 }
 
 func (ts *TwitterScraper) ScrapeTweetsByRecentSearchQuery(j types.Job, baseDir string, query string, count int) ([]*teetypes.TweetResult, error) {
-	return ts.queryTweets(j, twitterx.TweetsSearchRecent, baseDir, query, count)
+	tweets, fanOut := ts.queryTweets(j, twitterx.TweetsSearchRecent, baseDir, query, count)
+	return tweets, fanOut.ErrOrNil()
 }
 
-func (ts *TwitterScraper) queryTweets(j types.Job, baseQueryEndpoint string, baseDir string, query string, count int) ([]*teetypes.TweetResult, error) {
-	// Try credentials first, fallback to API for CapSearchByQuery
+// queryTweets tries credentials first and falls back to the API. The outcome of each provider is recorded in the returned MultiError.
+func (ts *TwitterScraper) queryTweets(j types.Job, baseQueryEndpoint string, baseDir string, query string, count int) ([]*teetypes.TweetResult, *types.MultiError) {
+	fanOut := &types.MultiError{}
+
 	scraper, account, err := ts.getCredentialScraper(j, baseDir)
 	if err == nil {
-		return ts.scrapeTweetsWithCredentials(j, query, count, scraper, account)
+		tweets, err := ts.scrapeTweetsWithCredentials(j, query, count, scraper, account)
+		if err == nil {
+			fanOut.Succeeded("credentials", query, len(tweets))
+			return tweets, fanOut
+		}
+		fanOut.Failed("credentials", query, err)
 	}
 
 	// Fallback to API
-	twitterXScraper, apiKey, apiErr := ts.getApiScraper(j)
+	twitterXScraper, apiKey, apiErr := ts.getApiScraperFor(j, baseQueryEndpoint)
 	if apiErr != nil {
-		ts.statsCollector.Add(j.WorkerID, stats.TwitterAuthErrors, 1)
-		return nil, fmt.Errorf("no Twitter accounts or API keys available")
+		if len(fanOut.Statuses) == 0 {
+			ts.addStat(j, stats.TwitterAuthErrors, 1)
+			fanOut.Failed("twitter", query, fmt.Errorf("no Twitter accounts or API keys available"))
+		}
+		return nil, fanOut
 	}
-	return ts.scrapeTweets(j, baseQueryEndpoint, query, count, twitterXScraper, apiKey)
+
+	tweets, err := ts.scrapeTweets(j, baseQueryEndpoint, query, count, twitterXScraper, apiKey)
+	if err != nil {
+		fanOut.Failed("api", query, err)
+		return nil, fanOut
+	}
+	fanOut.Succeeded("api", query, len(tweets))
+	return tweets, fanOut
 }
 
 func (ts *TwitterScraper) queryTweetsWithCredentials(j types.Job, baseDir string, query string, count int) ([]*teetypes.TweetResult, error) {
@@ -267,7 +311,7 @@ func (ts *TwitterScraper) queryTweetsWithCredentials(j types.Job, baseDir string
 }
 
 func (ts *TwitterScraper) queryTweetsWithApiKey(j types.Job, baseQueryEndpoint string, query string, count int) ([]*teetypes.TweetResult, error) {
-	twitterXScraper, apiKey, err := ts.getApiScraper(j)
+	twitterXScraper, apiKey, err := ts.getApiScraperFor(j, baseQueryEndpoint)
 	if err != nil {
 		return nil, err
 	}
@@ -275,30 +319,37 @@ func (ts *TwitterScraper) queryTweetsWithApiKey(j types.Job, baseQueryEndpoint s
 }
 
 func (ts *TwitterScraper) scrapeTweetsWithCredentials(j types.Job, query string, count int, scraper *twitter.Scraper, account *twitter.TwitterAccount) ([]*teetypes.TweetResult, error) {
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	tweets := make([]*teetypes.TweetResult, 0, count)
 
-	ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+	ctx, cancel := jobContext(j)
 	defer cancel()
 
 	scraper.SetSearchMode(twitterscraper.SearchLatest)
 
-	for tweetScraped := range scraper.SearchTweets(ctx, query, count) {
+	idRange := tweetIDRangeFromJob(j)
+	for tweetScraped := range scraper.SearchTweets(ctx, idRange.searchQuery(query), count) {
 		if tweetScraped.Error != nil {
 			_ = ts.handleError(j, tweetScraped.Error, account)
 			return nil, tweetScraped.Error
 		}
 		newTweetResult := ts.convertTwitterScraperTweetToTweetResult(tweetScraped.Tweet)
-		tweets = append(tweets, newTweetResult)
+		// The latest tweets come first, so the search is done once it reaches since_id
+		if idRange.reachedSince(newTweetResult) {
+			break
+		}
+		if idRange.contains(newTweetResult.ID) {
+			tweets = append(tweets, newTweetResult)
+		}
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nil
 }
 
 // scrapeTweets uses an existing scraper instance
 func (ts *TwitterScraper) scrapeTweets(j types.Job, baseQueryEndpoint string, query string, count int, twitterXScraper *twitterx.TwitterXScraper, apiKey *twitter.TwitterApiKey) ([]*teetypes.TweetResult, error) {
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	if baseQueryEndpoint == twitterx.TweetsAll && apiKey.Type == twitter.TwitterApiKeyTypeBase {
 		return nil, fmt.Errorf("this API key is a base/Basic key and does not have access to full archive search. Please use an elevated/Pro API key")
@@ -307,15 +358,23 @@ func (ts *TwitterScraper) scrapeTweets(j types.Job, baseQueryEndpoint string, qu
 	tweets := make([]*teetypes.TweetResult, 0, count)
 
 	cursor := ""
-	deadline := time.Now().Add(j.Timeout)
+	ctx, cancel := jobContext(j)
+	defer cancel()
+	idRange := tweetIDRangeFromJob(j)
 
-	for len(tweets) < count && time.Now().Before(deadline) {
+	for len(tweets) < count && ctx.Err() == nil {
 		numToFetch := count - len(tweets)
 		if numToFetch <= 0 {
 			break
 		}
 
-		result, err := twitterXScraper.ScrapeTweetsByQuery(baseQueryEndpoint, query, numToFetch, cursor)
+		result, err := twitterXScraper.ScrapeTweetsBySearchParams(baseQueryEndpoint, twitterx.SearchParams{
+			Query:      query,
+			MaxResults: numToFetch,
+			NextToken:  cursor,
+			SinceID:    apiBound(idRange.since),
+			UntilID:    apiBound(idRange.until),
+		})
 		if err != nil {
 			if ts.handleError(j, err, nil) {
 				if len(tweets) > 0 {
@@ -328,7 +387,7 @@ func (ts *TwitterScraper) scrapeTweets(j types.Job, baseQueryEndpoint string, qu
 
 		if result == nil || len(result.Data) == 0 {
 			if len(tweets) == 0 {
-				logrus.Infof("No tweets found for query: %s with API key.", query)
+				logrus.Debugf("No tweets found for query: %s with API key.", query)
 			}
 			break
 		}
@@ -389,13 +448,13 @@ func (ts *TwitterScraper) scrapeTweets(j types.Job, baseQueryEndpoint string, qu
 	}
 EndLoop:
 
-	logrus.Infof("Scraped %d tweets (target: %d) using API key for query: %s", len(tweets), count, query)
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	logrus.Debugf("Scraped %d tweets (target: %d) using API key for query: %s", len(tweets), count, query)
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nil
 }
 
 func (ts *TwitterScraper) ScrapeTweetByID(j types.Job, baseDir string, tweetID string) (*teetypes.TweetResult, error) {
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	scraper, account, err := ts.getCredentialScraper(j, baseDir)
 	if err != nil {
@@ -412,7 +471,7 @@ func (ts *TwitterScraper) ScrapeTweetByID(j types.Job, baseDir string, tweetID s
 	}
 
 	tweetResult := ts.convertTwitterScraperTweetToTweetResult(*tweet)
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, 1)
+	ts.addStat(j, stats.TwitterTweets, 1)
 	return tweetResult, nil
 }
 
@@ -422,7 +481,7 @@ func (ts *TwitterScraper) GetTweet(j types.Job, baseDir, tweetID string) (*teety
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	scrapedTweet, err := scraper.GetTweet(tweetID)
 	if err != nil {
 		_ = ts.handleError(j, err, account)
@@ -432,7 +491,7 @@ func (ts *TwitterScraper) GetTweet(j types.Job, baseDir, tweetID string) (*teety
 		return nil, fmt.Errorf("scrapedTweet not found or error occurred, but error was nil")
 	}
 	tweetResult := ts.convertTwitterScraperTweetToTweetResult(*scrapedTweet)
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, 1)
+	ts.addStat(j, stats.TwitterTweets, 1)
 	return tweetResult, nil
 }
 
@@ -442,7 +501,7 @@ func (ts *TwitterScraper) GetTweetReplies(j types.Job, baseDir, tweetID string,
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	var replies []*teetypes.TweetResult
 
 	scrapedTweets, threadEntries, err := scraper.GetTweetReplies(tweetID, cursor)
@@ -464,7 +523,7 @@ func (ts *TwitterScraper) GetTweetReplies(j types.Job, baseDir, tweetID string,
 		replies = append(replies, newTweetResult)
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(replies)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(replies)))
 	return replies, nil
 }
 
@@ -474,14 +533,14 @@ func (ts *TwitterScraper) GetTweetRetweeters(j types.Job, baseDir, tweetID strin
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	retweeters, _, err := scraper.GetTweetRetweeters(tweetID, count, cursor)
 	if err != nil {
 		_ = ts.handleError(j, err, account)
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, uint(len(retweeters)))
+	ts.addStat(j, stats.TwitterProfiles, uint(len(retweeters)))
 	return retweeters, nil
 }
 
@@ -490,11 +549,15 @@ func (ts *TwitterScraper) GetUserTweets(j types.Job, baseDir, username string, c
 	if err != nil {
 		return nil, "", err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	var tweets []*teetypes.TweetResult
 	var nextCursor string
 
+	// The timeline is ordered from newest to oldest, so it is not paginated further once it reaches since_id
+	idRange := tweetIDRangeFromJob(j)
+	reachedSince := false
+
 	if cursor != "" {
 		fetchedTweets, fetchCursor, fetchErr := scraper.FetchTweets(username, count, cursor)
 		if fetchErr != nil {
@@ -503,11 +566,17 @@ func (ts *TwitterScraper) GetUserTweets(j types.Job, baseDir, username string, c
 		}
 		for _, tweet := range fetchedTweets {
 			newTweetResult := ts.convertTwitterScraperTweetToTweetResult(*tweet)
-			tweets = append(tweets, newTweetResult)
+			if idRange.reachedSince(newTweetResult) {
+				reachedSince = true
+				break
+			}
+			if idRange.contains(newTweetResult.ID) {
+				tweets = append(tweets, newTweetResult)
+			}
 		}
 		nextCursor = fetchCursor
 	} else {
-		ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+		ctx, cancel := jobContext(j)
 		defer cancel()
 		for tweetScraped := range scraper.GetTweets(ctx, username, count) {
 			if tweetScraped.Error != nil {
@@ -515,13 +584,22 @@ func (ts *TwitterScraper) GetUserTweets(j types.Job, baseDir, username string, c
 				return nil, "", tweetScraped.Error
 			}
 			newTweetResult := ts.convertTwitterScraperTweetToTweetResult(tweetScraped.Tweet)
-			tweets = append(tweets, newTweetResult)
+			if idRange.reachedSince(newTweetResult) {
+				reachedSince = true
+				break
+			}
+			if idRange.contains(newTweetResult.ID) {
+				tweets = append(tweets, newTweetResult)
+			}
 		}
 		if len(tweets) > 0 {
 			nextCursor = strconv.FormatInt(tweets[len(tweets)-1].ID, 10)
 		}
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	if reachedSince {
+		nextCursor = ""
+	}
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nextCursor, nil
 }
 
@@ -530,11 +608,11 @@ func (ts *TwitterScraper) GetUserMedia(j types.Job, baseDir, username string, co
 	if err != nil {
 		return nil, "", err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	var media []*teetypes.TweetResult
 	var nextCursor string
-	ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+	ctx, cancel := jobContext(j)
 	defer cancel()
 
 	if cursor != "" {
@@ -582,7 +660,7 @@ func (ts *TwitterScraper) GetUserMedia(j types.Job, baseDir, username string, co
 			nextCursor = strconv.FormatInt(media[len(media)-1].ID, 10)
 		}
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterOther, uint(len(media)))
+	ts.addStat(j, stats.TwitterOther, uint(len(media)))
 	return media, nextCursor, nil
 }
 
@@ -591,7 +669,7 @@ func (ts *TwitterScraper) GetHomeTweets(j types.Job, baseDir string, count int,
 	if err != nil {
 		return nil, "", err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	var tweets []*teetypes.TweetResult
 	var nextCursor string
@@ -608,7 +686,7 @@ func (ts *TwitterScraper) GetHomeTweets(j types.Job, baseDir string, count int,
 		}
 		nextCursor = fetchCursor
 	} else {
-		ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+		ctx, cancel := jobContext(j)
 		defer cancel()
 		for tweetScraped := range scraper.GetHomeTweets(ctx, count) {
 			if tweetScraped.Error != nil {
@@ -625,7 +703,7 @@ func (ts *TwitterScraper) GetHomeTweets(j types.Job, baseDir string, count int,
 			nextCursor = strconv.FormatInt(tweets[len(tweets)-1].ID, 10)
 		}
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nextCursor, nil
 }
 
@@ -634,7 +712,7 @@ func (ts *TwitterScraper) GetForYouTweets(j types.Job, baseDir string, count int
 	if err != nil {
 		return nil, "", err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	var tweets []*teetypes.TweetResult
 	var nextCursor string
@@ -651,7 +729,7 @@ func (ts *TwitterScraper) GetForYouTweets(j types.Job, baseDir string, count int
 		}
 		nextCursor = fetchCursor
 	} else {
-		ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+		ctx, cancel := jobContext(j)
 		defer cancel()
 		for tweetScraped := range scraper.GetForYouTweets(ctx, count) {
 			if tweetScraped.Error != nil {
@@ -668,7 +746,7 @@ func (ts *TwitterScraper) GetForYouTweets(j types.Job, baseDir string, count int
 			nextCursor = strconv.FormatInt(tweets[len(tweets)-1].ID, 10)
 		}
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nextCursor, nil
 }
 
@@ -677,10 +755,10 @@ func (ts *TwitterScraper) GetBookmarks(j types.Job, baseDir string, count int, c
 	if err != nil {
 		return nil, "", err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	var bookmarks []*teetypes.TweetResult
 
-	ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+	ctx, cancel := jobContext(j)
 	defer cancel()
 	cursorInt := 0
 	if cursor != "" {
@@ -713,7 +791,7 @@ func (ts *TwitterScraper) GetBookmarks(j types.Job, baseDir string, count int, c
 		nextCursor = cursor
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(bookmarks)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(bookmarks)))
 	return bookmarks, nextCursor, nil
 }
 
@@ -723,21 +801,21 @@ func (ts *TwitterScraper) GetProfileByID(j types.Job, baseDir, userID string) (*
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	profile, err := scraper.GetProfileByID(userID)
 	if err != nil {
 		_ = ts.handleError(j, err, account)
 		return nil, err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, 1)
+	ts.addStat(j, stats.TwitterProfiles, 1)
 	return &profile, nil
 }
 
 // GetProfileByIDWithApiKey fetches user profile using Twitter API key
 func (ts *TwitterScraper) GetProfileByIDWithApiKey(j types.Job, userID string, apiKey *twitter.TwitterApiKey) (*twitterx.TwitterXProfileResponse, error) {
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
-	apiClient := client.NewTwitterXClient(apiKey.Key)
+	apiClient := newTwitterXClient(j, apiKey.Key)
 	twitterXScraper := twitterx.NewTwitterXScraper(apiClient)
 
 	profile, err := twitterXScraper.GetProfileByID(userID)
@@ -748,15 +826,15 @@ func (ts *TwitterScraper) GetProfileByIDWithApiKey(j types.Job, userID string, a
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, 1)
+	ts.addStat(j, stats.TwitterProfiles, 1)
 	return profile, nil
 }
 
 // GetTweetByIDWithApiKey fetches a tweet using Twitter API key
 func (ts *TwitterScraper) GetTweetByIDWithApiKey(j types.Job, tweetID string, apiKey *twitter.TwitterApiKey) (*teetypes.TweetResult, error) {
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
-	apiClient := client.NewTwitterXClient(apiKey.Key)
+	apiClient := newTwitterXClient(j, apiKey.Key)
 	twitterXScraper := twitterx.NewTwitterXScraper(apiClient)
 
 	tweetData, err := twitterXScraper.GetTweetByID(tweetID)
@@ -767,7 +845,17 @@ func (ts *TwitterScraper) GetTweetByIDWithApiKey(j types.Job, tweetID string, ap
 		return nil, err
 	}
 
-	// Convert TwitterXTweetData to TweetResult
+	tweetResult, err := convertTwitterXTweetToTweetResult(tweetData)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterTweets, 1)
+	return tweetResult, nil
+}
+
+// convertTwitterXTweetToTweetResult converts a tweet returned by the TwitterX API to a TweetResult
+func convertTwitterXTweetToTweetResult(tweetData *twitterx.TwitterXTweetData) (*teetypes.TweetResult, error) {
 	tweetIDInt, convErr := strconv.ParseInt(tweetData.ID, 10, 64)
 	if convErr != nil {
 		logrus.Errorf("Failed to convert tweet ID '%s' to int64: %v", tweetData.ID, convErr)
@@ -800,7 +888,6 @@ func (ts *TwitterScraper) GetTweetByIDWithApiKey(j types.Job, tweetID string, ap
 		},
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, 1)
 	return tweetResult, nil
 }
 
@@ -810,9 +897,9 @@ func (ts *TwitterScraper) SearchProfile(j types.Job, query string, count int) ([
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	var profiles []*twitterscraper.ProfileResult
-	ctx, cancel := context.WithTimeout(context.Background(), j.Timeout)
+	ctx, cancel := jobContext(j)
 	defer cancel()
 
 	for profile := range scraper.SearchProfiles(ctx, query, count) {
@@ -821,24 +908,17 @@ func (ts *TwitterScraper) SearchProfile(j types.Job, query string, count int) ([
 			break
 		}
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, uint(len(profiles)))
+	ts.addStat(j, stats.TwitterProfiles, uint(len(profiles)))
 	return profiles, nil
 }
 
+// GetTrends returns the current trends. They are also stored, so gettrendhistory jobs can rank them over time.
 func (ts *TwitterScraper) GetTrends(j types.Job, baseDir string) ([]string, error) {
-	scraper, account, err := ts.getCredentialScraper(j, baseDir)
-	if err != nil {
-		return nil, err
-	}
-
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
-	trends, err := scraper.GetTrends()
+	snapshot, err := ts.takeTrendSnapshot(j, baseDir)
 	if err != nil {
-		_ = ts.handleError(j, err, account)
 		return nil, err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterOther, uint(len(trends)))
-	return trends, nil
+	return snapshot.Trends, nil
 }
 
 func (ts *TwitterScraper) GetFollowers(j types.Job, baseDir, user string, count int) ([]*twitterscraper.Profile, error) {
@@ -847,13 +927,13 @@ func (ts *TwitterScraper) GetFollowers(j types.Job, baseDir, user string, count
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	followers, _, fetchErr := scraper.FetchFollowers(user, count, "")
 	if fetchErr != nil {
 		_ = ts.handleError(j, fetchErr, account)
 		return nil, fetchErr
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, uint(len(followers)))
+	ts.addStat(j, stats.TwitterProfiles, uint(len(followers)))
 	return followers, nil
 }
 
@@ -863,13 +943,13 @@ func (ts *TwitterScraper) GetFollowing(j types.Job, baseDir, username string, co
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	following, _, fetchErr := scraper.FetchFollowing(username, count, "")
 	if fetchErr != nil {
 		_ = ts.handleError(j, fetchErr, account) // Assuming FetchFollowing returns error, not errString
 		return nil, fetchErr
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterProfiles, uint(len(following)))
+	ts.addStat(j, stats.TwitterProfiles, uint(len(following)))
 	return following, nil
 }
 
@@ -880,14 +960,14 @@ func (ts *TwitterScraper) getFollowersApify(j types.Job, username string, maxRes
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	followers, nextCursor, err := apifyScraper.GetFollowers(username, maxResults, cursor)
 	if err != nil {
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterFollowers, uint(len(followers)))
+	ts.addStat(j, stats.TwitterFollowers, uint(len(followers)))
 	return followers, nextCursor, nil
 }
 
@@ -898,14 +978,14 @@ func (ts *TwitterScraper) getFollowingApify(j types.Job, username string, maxRes
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 
 	following, nextCursor, err := apifyScraper.GetFollowing(username, cursor, maxResults)
 	if err != nil {
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterFollowers, uint(len(following)))
+	ts.addStat(j, stats.TwitterFollowers, uint(len(following)))
 	return following, nextCursor, nil
 }
 
@@ -915,13 +995,13 @@ func (ts *TwitterScraper) GetSpace(j types.Job, baseDir, spaceID string) (*twitt
 		return nil, err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	space, err := scraper.GetSpace(spaceID)
 	if err != nil {
 		_ = ts.handleError(j, err, account)
 		return nil, err
 	}
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterOther, 1)
+	ts.addStat(j, stats.TwitterOther, 1)
 	return space, nil
 }
 
@@ -931,14 +1011,14 @@ func (ts *TwitterScraper) FetchHomeTweets(j types.Job, baseDir string, count int
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	tweets, nextCursor, fetchErr := scraper.FetchHomeTweets(count, cursor)
 	if fetchErr != nil {
 		_ = ts.handleError(j, fetchErr, account)
 		return nil, "", fetchErr
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nextCursor, nil
 }
 
@@ -948,14 +1028,14 @@ func (ts *TwitterScraper) FetchForYouTweets(j types.Job, baseDir string, count i
 		return nil, "", err
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterScrapes, 1)
+	ts.addStat(j, stats.TwitterScrapes, 1)
 	tweets, nextCursor, fetchErr := scraper.FetchForYouTweets(count, cursor)
 	if fetchErr != nil {
 		_ = ts.handleError(j, fetchErr, account)
 		return nil, "", fetchErr
 	}
 
-	ts.statsCollector.Add(j.WorkerID, stats.TwitterTweets, uint(len(tweets)))
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
 	return tweets, nextCursor, nil
 }
 
@@ -971,6 +1051,11 @@ type TwitterScraper struct {
 	capabilities   map[teetypes.Capability]bool
 }
 
+// addStat adds to a statistic, broken down by the capability and provider of the job if enabled
+func (ts *TwitterScraper) addStat(j types.Job, typ stats.StatType, num uint) {
+	ts.statsCollector.AddWithDimensions(j.WorkerID, typ, num, stats.DimensionsForJob(j))
+}
+
 func NewTwitterScraper(jc config.JobConfiguration, c *stats.StatsCollector) *TwitterScraper {
 	// Use direct config access instead of JSON marshaling/unmarshaling
 	config := jc.GetTwitterConfig()
@@ -982,6 +1067,10 @@ func NewTwitterScraper(jc config.JobConfiguration, c *stats.StatsCollector) *Twi
 
 	config.SkipLoginVerification = jc.GetBool("twitter_skip_login_verification", false)
 
+	// The capabilities of the accounts are withdrawn while all of them are unavailable, so the telemetry is updated
+	// as soon as that changes
+	accountManager.OnAvailabilityChange(func(bool) { c.RefreshCapabilities() })
+
 	return &TwitterScraper{
 		configuration:  config,
 		accountManager: accountManager,
@@ -1002,17 +1091,72 @@ func NewTwitterScraper(jc config.JobConfiguration, c *stats.StatsCollector) *Twi
 			teetypes.CapGetFollowing:        true,
 			teetypes.CapGetFollowers:        true,
 			teetypes.CapGetSpace:            true,
+			CapGetListTweets:                true,
+			CapGetCommunityTweets:           true,
+			CapSearchSpaces:                 true,
+			CapDownloadMedia:                true,
+			CapSampleFollowers:              true,
+			CapGetTrendHistory:              true,
 		},
 	}
 }
 
+// IsRateLimited returns true if all credential accounts are rate limited or suspended and there are no API keys to fall
+// back to
+func (ts *TwitterScraper) IsRateLimited() bool {
+	if ts.accountManager == nil || len(ts.accountManager.GetApiKeys()) > 0 {
+		return false
+	}
+	return ts.accountManager.AllAccountsUnavailable()
+}
+
+// ProbeDependencies checks that at least one of the configured authentication methods works. Accounts are not logged
+// in, since that is slow and risks getting them locked, so they are considered working unless all are rate limited or
+// suspended.
+func (ts *TwitterScraper) ProbeDependencies() error {
+	var errs []error
+	if len(ts.configuration.Accounts) > 0 {
+		if !ts.accountManager.AllAccountsUnavailable() {
+			return nil
+		}
+		errs = append(errs, errors.New("all Twitter accounts are rate limited or suspended"))
+	}
+	for _, key := range ts.accountManager.GetApiKeys() {
+		err := twitter.ValidateApiKey(key.Key)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("Twitter API key: %w", err))
+	}
+	if ts.configuration.ApifyApiKey != "" {
+		err := validateApifyApiKey(ts.configuration.ApifyApiKey)
+		if err == nil {
+			return nil
+		}
+		errs = append(errs, fmt.Errorf("Apify: %w", err))
+	}
+
+	if len(errs) == 0 {
+		return types.ErrNotConfigured
+	}
+	return errors.Join(errs...)
+}
+
+// hasUsableAccounts returns true if accounts are configured and at least one of them is neither rate limited nor
+// suspended
+func (ts *TwitterScraper) hasUsableAccounts() bool {
+	return len(ts.configuration.Accounts) > 0 && (ts.accountManager == nil || !ts.accountManager.AllAccountsUnavailable())
+}
+
 // GetStructuredCapabilities returns the structured capabilities supported by this Twitter scraper
-// based on the available credentials and API keys
+// based on the available credentials and API keys. The capabilities of the accounts are left out while all of them are
+// rate limited or suspended, and come back once one of them is available again.
 func (ts *TwitterScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
 	capabilities := make(teetypes.WorkerCapabilities)
+	hasAccounts := ts.hasUsableAccounts()
 
-	// Check if we have Twitter accounts for credential-based scraping
-	if len(ts.configuration.Accounts) > 0 {
+	// Check if we have usable Twitter accounts for credential-based scraping
+	if hasAccounts {
 		var credCaps []teetypes.Capability
 		for capability, enabled := range ts.capabilities {
 			if enabled {
@@ -1049,9 +1193,9 @@ func (ts *TwitterScraper) GetStructuredCapabilities() teetypes.WorkerCapabilitie
 	}
 
 	// Add general twitter scraper capability (uses best available method)
-	if len(ts.configuration.Accounts) > 0 || len(ts.configuration.ApiKeys) > 0 {
+	if hasAccounts || len(ts.configuration.ApiKeys) > 0 {
 		var generalCaps []teetypes.Capability
-		if len(ts.configuration.Accounts) > 0 {
+		if hasAccounts {
 			// Use all capabilities if we have accounts
 			for capability, enabled := range ts.capabilities {
 				if enabled {
@@ -1076,9 +1220,210 @@ func (ts *TwitterScraper) GetStructuredCapabilities() teetypes.WorkerCapabilitie
 		capabilities[teetypes.TwitterJob] = generalCaps
 	}
 
+	// getbyids and getpoll are available wherever getbyid is available through credentials or API keys
+	for jobType, caps := range capabilities {
+		if jobType != teetypes.TwitterApifyJob && slices.Contains(caps, teetypes.CapGetById) {
+			capabilities[jobType] = append(caps, CapGetByIds, CapGetPoll)
+		}
+	}
+
+	// getfollowerdelta is available wherever followers can be fetched
+	for jobType, caps := range capabilities {
+		if slices.Contains(caps, teetypes.CapGetFollowers) {
+			capabilities[jobType] = append(slices.Clip(caps), CapGetFollowerDelta)
+		}
+	}
+
+	// getthread is available wherever replies can be fetched
+	for jobType, caps := range capabilities {
+		if slices.Contains(caps, teetypes.CapGetReplies) {
+			capabilities[jobType] = append(slices.Clip(caps), CapGetThread)
+		}
+	}
+
 	return capabilities
 }
 
+// GetKeyCapabilities returns the tier and the accessible endpoints of each Twitter API key
+func (ts *TwitterScraper) GetKeyCapabilities() []types.KeyCapabilities {
+	if ts.accountManager == nil {
+		return nil
+	}
+	var keys []types.KeyCapabilities
+	for _, apiKey := range ts.accountManager.GetApiKeys() {
+		keys = append(keys, types.KeyCapabilities{
+			Provider:    "twitter",
+			Fingerprint: apiKey.Fingerprint(),
+			Tier:        string(apiKey.Type),
+			Endpoints:   apiKey.Capabilities.Map(),
+		})
+	}
+	return keys
+}
+
+// Estimated request budgets per account or API key, based on the limits published by Twitter
+const (
+	twitterRateLimitWindowSeconds    = 15 * 60
+	twitterCredentialRequestsPerAcct = 50
+	twitterApiRequestsPerKey         = 60
+	twitterElevatedRequestsPerKey    = 300
+)
+
+// GetCapabilityDetails returns the auth source, estimated rate limit and full-archive availability of each capability.
+// The accounts are left out as an auth source while all of them are rate limited or suspended.
+func (ts *TwitterScraper) GetCapabilityDetails() types.CapabilityDetails {
+	details := make(types.CapabilityDetails)
+	hasAccounts := ts.hasUsableAccounts()
+
+	elevatedKeys := 0
+	if ts.accountManager != nil {
+		for _, apiKey := range ts.accountManager.GetApiKeys() {
+			if apiKey.Type == twitter.TwitterApiKeyTypeElevated {
+				elevatedKeys++
+			}
+		}
+	}
+
+	for jobType, caps := range ts.GetStructuredCapabilities() {
+		for _, c := range caps {
+			sources := slices.DeleteFunc(ts.authSourcesFor(jobType, c), func(source types.AuthSource) bool {
+				return source == types.AuthSourceCredential && !hasAccounts
+			})
+			for i, source := range sources {
+				detail := types.CapabilityDetail{
+					Capability:  c,
+					AuthSource:  source,
+					FullArchive: c == teetypes.CapSearchByFullArchive,
+				}
+				if len(sources) > 1 {
+					detail.Priority = i + 1
+				}
+
+				switch {
+				case source == types.AuthSourceCredential:
+					detail.RateLimit = &types.RateLimitEstimate{
+						Requests:      twitterCredentialRequestsPerAcct * len(ts.configuration.Accounts),
+						WindowSeconds: twitterRateLimitWindowSeconds,
+					}
+				case source == types.AuthSourceAPI && c == teetypes.CapSearchByFullArchive:
+					detail.RateLimit = &types.RateLimitEstimate{
+						Requests:      twitterElevatedRequestsPerKey * elevatedKeys,
+						WindowSeconds: twitterRateLimitWindowSeconds,
+					}
+				case source == types.AuthSourceAPI:
+					detail.RateLimit = &types.RateLimitEstimate{
+						Requests:      twitterApiRequestsPerKey * len(ts.configuration.ApiKeys),
+						WindowSeconds: twitterRateLimitWindowSeconds,
+					}
+				}
+
+				details[jobType] = append(details[jobType], detail)
+			}
+		}
+	}
+
+	return details
+}
+
+// defaultTwitterAuthPriority is the order in which the general Twitter job tries the auth sources of the capabilities
+// it can provide through more than one of them
+var defaultTwitterAuthPriority = map[teetypes.Capability][]types.AuthSource{
+	teetypes.CapGetById:             {types.AuthSourceCredential, types.AuthSourceAPI},
+	CapGetByIds:                     {types.AuthSourceAPI, types.AuthSourceCredential}, // The API fetches 100 tweets per request
+	CapGetPoll:                      {types.AuthSourceCredential, types.AuthSourceAPI},
+	teetypes.CapGetFollowers:        {types.AuthSourceApify, types.AuthSourceCredential},
+	teetypes.CapGetFollowing:        {types.AuthSourceApify, types.AuthSourceCredential},
+	teetypes.CapGetProfileById:      {types.AuthSourceCredential, types.AuthSourceAPI},
+	teetypes.CapSearchByFullArchive: {types.AuthSourceCredential, types.AuthSourceAPI},
+	teetypes.CapSearchByQuery:       {types.AuthSourceCredential, types.AuthSourceAPI},
+}
+
+// authPriority returns the order in which the general Twitter job tries the auth sources of the capability: the ones
+// listed in TWITTER_AUTH_PRIORITY_<CAPABILITY> first, then the others in their default order. Sources which can't
+// provide the capability are ignored.
+func (ts *TwitterScraper) authPriority(c teetypes.Capability) []types.AuthSource {
+	defaults := defaultTwitterAuthPriority[c]
+	order := make([]types.AuthSource, 0, len(defaults))
+	for _, s := range ts.configuration.AuthPriority[string(c)] {
+		if source := types.AuthSource(s); slices.Contains(defaults, source) && !slices.Contains(order, source) {
+			order = append(order, source)
+		}
+	}
+	for _, source := range defaults {
+		if !slices.Contains(order, source) {
+			order = append(order, source)
+		}
+	}
+	return order
+}
+
+// authSourcesFor returns the auth sources through which the given job type can provide the capability, in the order
+// in which it tries them
+func (ts *TwitterScraper) authSourcesFor(jobType teetypes.JobType, c teetypes.Capability) []types.AuthSource {
+	switch jobType {
+	case teetypes.TwitterCredentialJob:
+		return []types.AuthSource{types.AuthSourceCredential}
+	case teetypes.TwitterApiJob:
+		return []types.AuthSource{types.AuthSourceAPI}
+	case teetypes.TwitterApifyJob:
+		return []types.AuthSource{types.AuthSourceApify}
+	}
+
+	// getbyids and getpoll have an order of their own, the capabilities they are provided with don't
+	priority := ts.authPriority(c)
+
+	// getbyids and getpoll are provided wherever getbyid is, getfollowerdelta wherever getfollowers is, and getthread
+	// wherever getreplies is
+	switch c {
+	case CapGetByIds, CapGetPoll:
+		c = teetypes.CapGetById
+	case CapGetFollowerDelta:
+		c = teetypes.CapGetFollowers
+	case CapGetThread:
+		c = teetypes.CapGetReplies
+	}
+
+	// The general Twitter job uses the best available method
+	var sources []types.AuthSource
+	if len(ts.configuration.Accounts) > 0 && ts.capabilities[c] {
+		sources = append(sources, types.AuthSourceCredential)
+	}
+	if len(ts.configuration.ApiKeys) > 0 && (slices.Contains(teetypes.TwitterAPICaps, c) || c == teetypes.CapSearchByFullArchive) {
+		sources = append(sources, types.AuthSourceAPI)
+	}
+	if ts.configuration.ApifyApiKey != "" && slices.Contains(teetypes.TwitterApifyCaps, c) {
+		sources = append(sources, types.AuthSourceApify)
+	}
+	if len(sources) == 0 {
+		sources = append(sources, types.AuthSourceNone)
+	}
+	if len(priority) == 0 {
+		priority = ts.authPriority(c)
+	}
+	if len(priority) > 0 {
+		slices.SortStableFunc(sources, func(a, b types.AuthSource) int {
+			return slices.Index(priority, a) - slices.Index(priority, b)
+		})
+	}
+	return sources
+}
+
+// availableAuthSource returns the first auth source of the capability which is one of the supported ones and is
+// available, i.e. not the accounts while all of them are rate limited or suspended. If there is none, it returns the
+// first supported one, whose error tells why the job can't be executed.
+func (ts *TwitterScraper) availableAuthSource(jobType teetypes.JobType, c teetypes.Capability, supported ...types.AuthSource) types.AuthSource {
+	for _, source := range ts.authSourcesFor(jobType, c) {
+		if !slices.Contains(supported, source) {
+			continue
+		}
+		if source == types.AuthSourceCredential && !ts.hasUsableAccounts() {
+			continue
+		}
+		return source
+	}
+	return supported[0]
+}
+
 type TwitterScrapeStrategy interface {
 	Execute(j types.Job, ts *TwitterScraper, jobArgs *teeargs.TwitterSearchArguments) (types.JobResult, error)
 }
@@ -1150,10 +1495,10 @@ func (s *ApifyScrapeStrategy) Execute(j types.Job, ts *TwitterScraper, jobArgs *
 	switch capability {
 	case teetypes.CapGetFollowers:
 		followers, nextCursor, err := ts.getFollowersApify(j, jobArgs.Query, uint(jobArgs.MaxResults), client.Cursor(jobArgs.NextCursor))
-		return processResponse(followers, nextCursor.String(), err)
+		return processApifyResponse(followers, nextCursor, err)
 	case teetypes.CapGetFollowing:
 		following, nextCursor, err := ts.getFollowingApify(j, jobArgs.Query, uint(jobArgs.MaxResults), client.Cursor(jobArgs.NextCursor))
-		return processResponse(following, nextCursor.String(), err)
+		return processApifyResponse(following, nextCursor, err)
 	default:
 		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for Apify job", capability)}, fmt.Errorf("unsupported capability %s for Apify job", capability)
 	}
@@ -1161,38 +1506,74 @@ func (s *ApifyScrapeStrategy) Execute(j types.Job, ts *TwitterScraper, jobArgs *
 
 type DefaultScrapeStrategy struct{}
 
-// FIXED: Now using validated QueryType from centralized unmarshaller (addresses the TODO comment)
+// Execute runs the job through the auth sources of the capability in the order given by authSourcesFor, falling back to
+// the next one if a source fails, and to the syndication endpoints if all of them failed and the fallback is enabled.
+// The outcome of every source which was tried is attached to the result if there was more than one.
 func (s *DefaultScrapeStrategy) Execute(j types.Job, ts *TwitterScraper, jobArgs *teeargs.TwitterSearchArguments) (types.JobResult, error) {
 	capability := teetypes.Capability(jobArgs.QueryType)
-	switch capability {
-	case teetypes.CapGetFollowers, teetypes.CapGetFollowing:
-		// Priority: Apify > Credentials for general TwitterJob
-		// TODO: We should verify whether each of the actors is actually available through this API key
-		if ts.configuration.ApifyApiKey != "" {
-			// Use Apify strategy
-			apifyStrategy := &ApifyScrapeStrategy{}
-			return apifyStrategy.Execute(j, ts, jobArgs)
-		}
-		// Fall back to credential-based strategy
-		credentialStrategy := &CredentialScrapeStrategy{}
-		return credentialStrategy.Execute(j, ts, jobArgs)
-	case teetypes.CapSearchByQuery:
-		// Priority: Credentials > API for searchbyquery
-		if len(ts.configuration.Accounts) > 0 {
-			credentialStrategy := &CredentialScrapeStrategy{}
-			return credentialStrategy.Execute(j, ts, jobArgs)
+	if _, ok := defaultTwitterAuthPriority[capability]; !ok {
+		result, err := defaultStrategyFallback(j, ts, jobArgs)
+		if (err != nil || result.Error != "") && ts.syndicationFallbackFor(capability) {
+			if err == nil {
+				err = errors.New(result.Error)
+			}
+			var fanOut types.MultiError
+			fanOut.Failed(string(types.AuthSourceCredential), jobArgs.Query, err)
+			return ts.fallBackToSyndication(j, jobArgs, &fanOut)
 		}
-		// Fall back to API strategy
-		tweets, err := ts.queryTweets(j, twitterx.TweetsSearchRecent, ts.configuration.DataDir, jobArgs.Query, jobArgs.MaxResults)
-		return processResponse(tweets, "", err)
-	case teetypes.CapSearchByFullArchive:
-		tweets, err := ts.queryTweets(j, twitterx.TweetsAll, ts.configuration.DataDir, jobArgs.Query, jobArgs.MaxResults)
-		return processResponse(tweets, "", err)
+		return result, err
+	}
+
+	var (
+		fanOut types.MultiError
+		result types.JobResult
+		err    error
+	)
+	for _, source := range ts.authSourcesFor(teetypes.TwitterJob, capability) {
+		result, err = scrapeStrategyFor(source).Execute(j, ts, jobArgs)
+		if err == nil && result.Error == "" {
+			if len(fanOut.Statuses) > 0 {
+				fanOut.Succeeded(string(source), jobArgs.Query, countResultItems(result.Data))
+				result.FanOut = fanOut.Statuses
+			}
+			return result, nil
+		}
+		if err == nil {
+			err = errors.New(result.Error)
+		}
+		fanOut.Failed(string(source), jobArgs.Query, err)
+	}
+	if ts.syndicationFallbackFor(capability) {
+		return ts.fallBackToSyndication(j, jobArgs, &fanOut)
+	}
+	if len(fanOut.Statuses) > 1 {
+		result.FanOut = fanOut.Statuses
+	}
+	return result, err
+}
+
+// scrapeStrategyFor returns the strategy which scrapes through the given auth source. Jobs which can't use any source
+// go through the credentials, which reports that there are none.
+func scrapeStrategyFor(source types.AuthSource) TwitterScrapeStrategy {
+	switch source {
+	case types.AuthSourceAPI:
+		return &ApiKeyScrapeStrategy{}
+	case types.AuthSourceApify:
+		return &ApifyScrapeStrategy{}
 	default:
-		return defaultStrategyFallback(j, ts, jobArgs)
+		return &CredentialScrapeStrategy{}
 	}
 }
 
+// countResultItems returns the number of items of a JSON-encoded result: the length of an array, or 1 for anything else
+func countResultItems(data []byte) int {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return 1
+	}
+	return len(items)
+}
+
 func retryWithCursor[T any](
 	j types.Job,
 	baseDir string,
@@ -1201,10 +1582,11 @@ func retryWithCursor[T any](
 	fn func(j types.Job, baseDir string, currentCount int, currentCursor string) ([]*T, string, error),
 ) (types.JobResult, error) {
 	records := make([]*T, 0, count)
-	deadline := time.Now().Add(j.Timeout)
+	ctx, cancel := jobContext(j)
+	defer cancel()
 	currentCursor := cursor // Use 'currentCursor' to manage pagination state within the loop
 
-	for (len(records) < count || count == 0) && time.Now().Before(deadline) { // Allow count == 0 to fetch all available up to timeout
+	for (len(records) < count || count == 0) && ctx.Err() == nil { // Allow count == 0 to fetch all available up to timeout
 		numToFetch := count - len(records)
 		if count == 0 { // If count is 0, fetch a reasonable batch size, e.g. 100, or let fn decide
 			numToFetch = 100 // Or another default batch size if fn doesn't handle count=0 well for batching
@@ -1262,12 +1644,22 @@ func processResponse(response any, nextCursor string, err error) (types.JobResul
 		logrus.Debugf("Processing response with error: %v, NextCursor: %s", err, nextCursor)
 		return types.JobResult{Error: err.Error(), NextCursor: nextCursor}, err
 	}
-	dat, marshalErr := json.Marshal(response)
-	if marshalErr != nil {
-		logrus.Errorf("Error marshalling response: %v", marshalErr)
-		return types.JobResult{Error: marshalErr.Error()}, marshalErr
+	return pageResult(response, nextCursor)
+}
+
+// processApifyResponse is like processResponse, for the pages of the dataset of an Apify actor run
+func processApifyResponse(response any, nextCursor client.Cursor, err error) (types.JobResult, error) {
+	if err != nil {
+		return processResponse(response, nextCursor.String(), err)
 	}
-	return types.JobResult{Data: dat, NextCursor: nextCursor}, nil
+	return apifyPageResult(response, nextCursor)
+}
+
+// processFanOutResponse is like processResponse, but attaches the per-provider outcome of a fan-out to the result
+func processFanOutResponse(response any, nextCursor string, fanOut *types.MultiError) (types.JobResult, error) {
+	result, err := processResponse(response, nextCursor, fanOut.ErrOrNil())
+	result.FanOut = fanOut.Statuses
+	return result, err
 }
 
 func defaultStrategyFallback(j types.Job, ts *TwitterScraper, jobArgs *teeargs.TwitterSearchArguments) (types.JobResult, error) {
@@ -1326,6 +1718,44 @@ func defaultStrategyFallback(j types.Job, ts *TwitterScraper, jobArgs *teeargs.T
 // If the unmarshaling fails, it returns an error.
 // If the unmarshaled result is empty, it returns an error.
 func (ts *TwitterScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
+	// getbyids, getpoll, getthread, getfollowerdelta, samplefollowers, gettrendhistory, getlisttweets, getcommunitytweets, searchspaces and downloadmedia are not part of the tee-types capabilities yet, so they're handled before the centralized unmarshaller
+	if isGetByIdsJob(j) {
+		return ts.executeGetByIds(j)
+	}
+	if isCapabilityJob(j, CapGetPoll) {
+		return ts.executeGetPoll(j)
+	}
+	if isCapabilityJob(j, CapGetThread) {
+		return ts.executeGetThread(j)
+	}
+	if isCapabilityJob(j, CapGetFollowerDelta) {
+		return ts.executeFollowerDelta(j)
+	}
+	if isCapabilityJob(j, CapSampleFollowers) {
+		return ts.executeSampleFollowers(j)
+	}
+	if isCapabilityJob(j, CapGetTrendHistory) {
+		return ts.executeTrendHistory(j)
+	}
+	if isCapabilityJob(j, CapGetListTweets) {
+		return ts.executeGetListTweets(j)
+	}
+	if isCapabilityJob(j, CapGetCommunityTweets) {
+		return ts.executeGetCommunityTweets(j)
+	}
+	if isCapabilityJob(j, CapSearchSpaces) {
+		return ts.executeSearchSpaces(j)
+	}
+	if isCapabilityJob(j, CapDownloadMedia) {
+		return ts.executeDownloadMedia(j)
+	}
+
+	// since_id and until_id are not part of the tee-types arguments yet, so they're validated separately
+	if _, err := tweetIDRangeFromArguments(j.Arguments); err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
 	// Use the centralized unmarshaller from tee-types - this addresses the TODO comment!
 	jobArgs, err := teeargs.UnmarshalJobArguments(teetypes.JobType(j.Type), map[string]any(j.Arguments))
 	if err != nil {
@@ -1348,7 +1778,7 @@ func (ts *TwitterScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
 	jobResult, err := strategy.Execute(j, ts, args)
 	if err != nil {
 		logrus.Errorf("Error executing job ID %s, type %s: %v", j.UUID, j.Type, err)
-		return types.JobResult{Error: "error executing job"}, err
+		return types.JobResult{Error: "error executing job", FanOut: jobResult.FanOut}, err
 	}
 
 	// Check if raw data is empty
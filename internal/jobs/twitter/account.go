@@ -1,8 +1,12 @@
 package twitter
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/sirupsen/logrus"
 	"strings"
 	"sync"
 	"time"
@@ -13,6 +17,22 @@ type TwitterAccount struct {
 	Password         string
 	TwoFACode        string
 	RateLimitedUntil time.Time
+	// SuspendedUntil is set when the account can't log in or was suspended or locked by Twitter, after which it is
+	// tried again
+	SuspendedUntil time.Time
+}
+
+// available returns true if the account is neither rate limited nor suspended
+func (a *TwitterAccount) available(now time.Time) bool {
+	return now.After(a.RateLimitedUntil) && now.After(a.SuspendedUntil)
+}
+
+// unavailableUntil returns when the account becomes available again
+func (a *TwitterAccount) unavailableUntil() time.Time {
+	if a.SuspendedUntil.After(a.RateLimitedUntil) {
+		return a.SuspendedUntil
+	}
+	return a.RateLimitedUntil
 }
 
 type TwitterApiKeyType string
@@ -25,15 +45,76 @@ const (
 )
 
 type TwitterApiKey struct {
-	Key  string
-	Type TwitterApiKeyType // "base" or "elevated"
+	Key          string
+	Type         TwitterApiKeyType // "base" or "elevated"
+	Capabilities ApiKeyCapabilities
+}
+
+// ApiKeyCapabilities are the Twitter API endpoints which an API key was found to have access to. The tiers of the
+// Twitter API differ in more than full archive search, so each endpoint is probed separately.
+type ApiKeyCapabilities struct {
+	RecentSearch   bool `json:"recent_search"`
+	FullArchive    bool `json:"full_archive"`
+	Counts         bool `json:"counts"`
+	FilteredStream bool `json:"filtered_stream"`
+}
+
+// Harmless requests used to probe the endpoints of an API key
+const (
+	probeRecentSearch   = "tweets/search/recent?query=from:twitterdev&max_results=10"
+	probeFullArchive    = "tweets/search/all?query=from:twitterdev&max_results=10"
+	probeCounts         = "tweets/counts/recent?query=from:twitterdev"
+	probeFilteredStream = "tweets/search/stream/rules"
+)
+
+// Supports returns true if the key has access to the endpoint, e.g. "tweets/search/all". Endpoints which are not
+// probed are available with every key.
+func (c ApiKeyCapabilities) Supports(endpoint string) bool {
+	switch {
+	case strings.HasPrefix(endpoint, "tweets/search/recent"):
+		return c.RecentSearch
+	case strings.HasPrefix(endpoint, "tweets/search/all"):
+		return c.FullArchive
+	case strings.HasPrefix(endpoint, "tweets/counts"):
+		return c.Counts
+	case strings.HasPrefix(endpoint, "tweets/search/stream"):
+		return c.FilteredStream
+	default:
+		return true
+	}
 }
 
+// Map returns the capabilities by endpoint name, as reported in telemetry
+func (c ApiKeyCapabilities) Map() map[string]bool {
+	return map[string]bool{
+		"recent_search":   c.RecentSearch,
+		"full_archive":    c.FullArchive,
+		"counts":          c.Counts,
+		"filtered_stream": c.FilteredStream,
+	}
+}
+
+// Fingerprint identifies the key in logs and telemetry without revealing it
+func (k *TwitterApiKey) Fingerprint() string {
+	sum := sha256.Sum256([]byte(k.Key))
+	return hex.EncodeToString(sum[:4])
+}
+
+// detectedKeys caches the detected type and capabilities of each API key. The Twitter scraper is created once per
+// Twitter job type, so without it every key would be probed several times on startup.
+var detectedKeys sync.Map // map[string]TwitterApiKey
+
 type TwitterAccountManager struct {
 	accounts []*TwitterAccount
 	apiKeys  []*TwitterApiKey
 	index    int
 	mutex    sync.Mutex
+
+	// exhausted is set while all the accounts are unavailable, and recovery fires when the first of them becomes
+	// available again
+	exhausted      bool
+	recovery       *time.Timer
+	onAvailability func(available bool)
 }
 
 func NewTwitterAccountManager(accounts []*TwitterAccount, apiKeys []*TwitterApiKey) *TwitterAccountManager {
@@ -47,17 +128,98 @@ func NewTwitterAccountManager(accounts []*TwitterAccount, apiKeys []*TwitterApiK
 func (manager *TwitterAccountManager) GetNextAccount() *TwitterAccount {
 	manager.mutex.Lock()
 	defer manager.mutex.Unlock()
+	now := time.Now()
 	for i := 0; i < len(manager.accounts); i++ {
 		account := manager.accounts[manager.index]
 		manager.index = (manager.index + 1) % len(manager.accounts)
-		if time.Now().After(account.RateLimitedUntil) {
+		if account.available(now) {
 			return account
 		}
 	}
 	return nil
 }
 
-// DetectAllApiKeyTypes checks and sets the Type for all apiKeys in the manager.
+// AllAccountsUnavailable returns true if there are accounts and all of them are currently rate limited or suspended
+func (manager *TwitterAccountManager) AllAccountsUnavailable() bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return manager.allUnavailable(time.Now())
+}
+
+func (manager *TwitterAccountManager) allUnavailable(now time.Time) bool {
+	if len(manager.accounts) == 0 {
+		return false
+	}
+	for _, account := range manager.accounts {
+		if account.available(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// UnavailableFor returns how long it takes until the first account is available again, if all accounts are currently
+// rate limited or suspended. It returns zero if there are no accounts or if any account is available.
+func (manager *TwitterAccountManager) UnavailableFor() time.Duration {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return manager.unavailableFor(time.Now())
+}
+
+func (manager *TwitterAccountManager) unavailableFor(now time.Time) time.Duration {
+	var wait time.Duration
+	for _, account := range manager.accounts {
+		if account.available(now) {
+			return 0
+		}
+		if d := account.unavailableUntil().Sub(now); wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// OnAvailabilityChange sets a function which is called with false as soon as all the accounts are unavailable, and
+// with true once one of them is available again. It is called without holding the lock of the manager.
+func (manager *TwitterAccountManager) OnAvailabilityChange(f func(available bool)) {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	manager.onAvailability = f
+}
+
+// updateAvailability re-evaluates whether all the accounts are unavailable after one of them was marked, or when the
+// first of them should have become available again, and notifies the availability handler of any change
+func (manager *TwitterAccountManager) updateAvailability() {
+	manager.mutex.Lock()
+	now := time.Now()
+	exhausted := manager.allUnavailable(now)
+	changed := exhausted != manager.exhausted
+	manager.exhausted = exhausted
+
+	if manager.recovery != nil {
+		manager.recovery.Stop()
+		manager.recovery = nil
+	}
+	if exhausted {
+		manager.recovery = time.AfterFunc(manager.unavailableFor(now)+time.Millisecond, manager.updateAvailability)
+	}
+	handler := manager.onAvailability
+	manager.mutex.Unlock()
+
+	if !changed {
+		return
+	}
+	if exhausted {
+		logrus.Warnf("All %d Twitter accounts are rate limited or suspended, withdrawing their capabilities", len(manager.accounts))
+	} else {
+		logrus.Info("A Twitter account is available again, restoring its capabilities")
+	}
+	if handler != nil {
+		handler(!exhausted)
+	}
+}
+
+// DetectAllApiKeyTypes checks and sets the Type and Capabilities for all apiKeys in the manager.
 func (manager *TwitterAccountManager) DetectAllApiKeyTypes() {
 	for _, key := range manager.apiKeys {
 		err := key.SetKeyType()
@@ -67,6 +229,11 @@ func (manager *TwitterAccountManager) DetectAllApiKeyTypes() {
 	}
 }
 
+// GetAccounts returns all accounts managed by this manager
+func (manager *TwitterAccountManager) GetAccounts() []*TwitterAccount {
+	return manager.accounts
+}
+
 // GetApiKeys returns all api keys managed by this manager
 func (manager *TwitterAccountManager) GetApiKeys() []*TwitterApiKey {
 	return manager.apiKeys
@@ -82,40 +249,159 @@ func (manager *TwitterAccountManager) GetNextApiKey() *TwitterApiKey {
 	return key
 }
 
+// GetNextApiKeyFor returns the next API key which has access to the endpoint. If no key is known to have access,
+// e.g. because detection failed, it returns the next key of any kind and lets the API decide.
+func (manager *TwitterAccountManager) GetNextApiKeyFor(endpoint string) *TwitterApiKey {
+	manager.mutex.Lock()
+	for i := 0; i < len(manager.apiKeys); i++ {
+		key := manager.apiKeys[manager.index]
+		manager.index = (manager.index + 1) % len(manager.apiKeys)
+		if key.Capabilities.Supports(endpoint) {
+			manager.mutex.Unlock()
+			return key
+		}
+	}
+	manager.mutex.Unlock()
+	return manager.GetNextApiKey()
+}
+
 func (manager *TwitterAccountManager) MarkAccountRateLimited(account *TwitterAccount) {
 	manager.mutex.Lock()
-	defer manager.mutex.Unlock()
 	account.RateLimitedUntil = time.Now().Add(GetRateLimitDuration())
+	manager.mutex.Unlock()
+	manager.updateAvailability()
+}
+
+// MarkAccountSuspended takes an account out of rotation for SuspensionBackoff, after it failed to log in or was
+// suspended or locked by Twitter. The account presents another browser profile once it is tried again.
+func (manager *TwitterAccountManager) MarkAccountSuspended(account *TwitterAccount) {
+	manager.mutex.Lock()
+	account.SuspendedUntil = time.Now().Add(SuspensionBackoff)
+	manager.mutex.Unlock()
+	fingerprint.Rotate(fingerprint.TwitterAccountKey(account.Username))
+	manager.updateAvailability()
+}
+
+// RateLimitedUntil returns when the rate limit of an account ends, which is in the past if it is not rate limited
+func (manager *TwitterAccountManager) RateLimitedUntil(account *TwitterAccount) time.Time {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return account.RateLimitedUntil
+}
+
+// SuspendedUntil returns when an account which was set aside is tried again, which is in the past if it is not
+func (manager *TwitterAccountManager) SuspendedUntil(account *TwitterAccount) time.Time {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return account.SuspendedUntil
+}
+
+// MarkAccountWorking puts an account which was set aside back into rotation, after its session was verified to work
+func (manager *TwitterAccountManager) MarkAccountWorking(account *TwitterAccount) {
+	manager.mutex.Lock()
+	account.SuspendedUntil = time.Time{}
+	manager.mutex.Unlock()
+	manager.updateAvailability()
+}
+
+// IsSuspensionError returns true if the error tells that the account was suspended or locked by Twitter
+func IsSuspensionError(err error) bool {
+	msg := strings.ToLower(err.Error())
+	for _, s := range []string{"account is suspended", "account has been suspended", "temporarily locked", "(64)", "(326)"} {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// probeEndpoint returns true if the API key has access to the endpoint, and false if it is refused
+func probeEndpoint(tx *client.TwitterXClient, endpoint string) (bool, error) {
+	resp, err := tx.Get(endpoint)
+	if err != nil {
+		return false, fmt.Errorf("request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case 200:
+		return true, nil
+	case 401, 403:
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
 }
 
-func detectTwitterKeyType(apiKey string) (TwitterApiKeyType, error) {
+// detectTwitterKeyType probes the endpoints the API key has access to. Keys with full archive access are elevated.
+// Only a failed full archive probe is an error, since the type can't be determined without it; the other endpoints
+// are considered inaccessible if probing them fails.
+func detectTwitterKeyType(apiKey string) (TwitterApiKeyType, ApiKeyCapabilities, error) {
+	caps := ApiKeyCapabilities{}
 	if strings.Contains(apiKey, ":") {
-		return TwitterApiKeyTypeCredential, nil
+		return TwitterApiKeyTypeCredential, caps, nil
 	}
-	// Try a harmless full archive search (tweets/search/all)
+
 	tx := client.NewTwitterXClient(apiKey)
-	endpoint := "tweets/search/all?query=from:twitterdev&max_results=10"
-	resp, err := tx.Get(endpoint)
+	var err error
+	if caps.FullArchive, err = probeEndpoint(tx, probeFullArchive); err != nil {
+		return "", caps, err
+	}
+	for endpoint, accessible := range map[string]*bool{
+		probeRecentSearch:   &caps.RecentSearch,
+		probeCounts:         &caps.Counts,
+		probeFilteredStream: &caps.FilteredStream,
+	} {
+		if *accessible, err = probeEndpoint(tx, endpoint); err != nil {
+			logrus.Debugf("Failed to probe %s for a Twitter API key: %v", endpoint, err)
+		}
+	}
+
+	if caps.FullArchive {
+		return TwitterApiKeyTypeElevated, caps, nil
+	}
+	return TwitterApiKeyTypeBase, caps, nil
+}
+
+// ValidateApiKey checks that an API key can be used, with a harmless recent search. Credential keys (consumer key and
+// secret) can't be checked without signing the request, so they are considered valid.
+func ValidateApiKey(apiKey string) error {
+	if strings.Contains(apiKey, ":") {
+		return nil
+	}
+	tx := client.NewTwitterXClient(apiKey)
+	resp, err := tx.Get("tweets/search/recent?query=from:twitterdev&max_results=10")
 	if err != nil {
-		return "", fmt.Errorf("request error: %w", err)
+		return fmt.Errorf("request error: %w", err)
 	}
 	defer resp.Body.Close()
 
 	switch resp.StatusCode {
 	case 200:
-		return TwitterApiKeyTypeElevated, nil
+		return nil
 	case 401, 403:
-		return TwitterApiKeyTypeBase, nil
+		return fmt.Errorf("invalid API key")
+	case 429:
+		return fmt.Errorf("rate limit exceeded")
 	default:
-		return "", fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
 	}
 }
 
 func (k *TwitterApiKey) SetKeyType() error {
-	typeStr, err := detectTwitterKeyType(k.Key)
+	if detected, ok := detectedKeys.Load(k.Key); ok {
+		k.Type = detected.(TwitterApiKey).Type
+		k.Capabilities = detected.(TwitterApiKey).Capabilities
+		return nil
+	}
+
+	typeStr, caps, err := detectTwitterKeyType(k.Key)
 	if err != nil {
 		return err
 	}
 	k.Type = typeStr
+	k.Capabilities = caps
+	detectedKeys.Store(k.Key, TwitterApiKey{Type: typeStr, Capabilities: caps})
+	logrus.Infof("Twitter API key %s is %s with capabilities %+v", k.Fingerprint(), typeStr, caps)
 	return nil
 }
@@ -0,0 +1,81 @@
+package twitter
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
+)
+
+var _ = Describe("TwitterAccountManager", func() {
+	var (
+		accounts []*TwitterAccount
+		manager  *TwitterAccountManager
+		changes  chan bool
+	)
+
+	BeforeEach(func() {
+		accounts = []*TwitterAccount{{Username: "alice"}, {Username: "bob"}}
+		manager = NewTwitterAccountManager(accounts, nil)
+		changes = make(chan bool, 4)
+		manager.OnAvailabilityChange(func(available bool) { changes <- available })
+	})
+
+	It("reports when all the accounts become unavailable and when one of them recovers", func() {
+		manager.MarkAccountRateLimited(accounts[0])
+		Expect(changes).NotTo(Receive())
+		Expect(manager.GetNextAccount()).To(Equal(accounts[1]))
+
+		manager.MarkAccountSuspended(accounts[1])
+		Expect(changes).To(Receive(BeFalse()))
+		Expect(manager.GetNextAccount()).To(BeNil())
+		Expect(manager.AllAccountsUnavailable()).To(BeTrue())
+		Expect(manager.UnavailableFor()).To(BeNumerically("~", RateLimitDuration, time.Second))
+
+		// The rate limit of alice ends soon, which is noticed without any other account being marked
+		manager.mutex.Lock()
+		accounts[0].RateLimitedUntil = time.Now().Add(50 * time.Millisecond)
+		manager.mutex.Unlock()
+		manager.updateAvailability()
+		Expect(changes).NotTo(Receive())
+
+		Eventually(changes, "1s").Should(Receive(BeTrue()))
+		Expect(manager.AllAccountsUnavailable()).To(BeFalse())
+		Expect(manager.UnavailableFor()).To(BeZero())
+		Expect(manager.GetNextAccount()).To(Equal(accounts[0]))
+	})
+
+	It("keeps suspended accounts out of rotation for the suspension backoff", func() {
+		manager.MarkAccountSuspended(accounts[0])
+		for range 3 {
+			Expect(manager.GetNextAccount()).To(Equal(accounts[1]))
+		}
+		Expect(accounts[0].SuspendedUntil).To(BeTemporally("~", time.Now().Add(SuspensionBackoff), time.Second))
+	})
+
+	It("presents another browser profile once a suspended account is tried again", func() {
+		key := fingerprint.TwitterAccountKey("carol")
+		before := fingerprint.For(key)
+		manager.MarkAccountSuspended(&TwitterAccount{Username: "Carol"})
+		Expect(fingerprint.For(key)).NotTo(Equal(before))
+	})
+
+	It("is never exhausted without accounts", func() {
+		manager = NewTwitterAccountManager(nil, nil)
+		Expect(manager.AllAccountsUnavailable()).To(BeFalse())
+		Expect(manager.UnavailableFor()).To(BeZero())
+	})
+
+	DescribeTable("recognizes suspension errors",
+		func(msg string, expected bool) {
+			Expect(IsSuspensionError(errors.New(msg))).To(Equal(expected))
+		},
+		Entry("suspended", "(64) Your account is suspended and is not permitted to access this feature.", true),
+		Entry("locked", "(326) To protect our users from spam and other malicious activity, this account is temporarily locked.", true),
+		Entry("rate limit", "Rate limit exceeded", false),
+		Entry("not found", "user not found", false),
+	)
+})
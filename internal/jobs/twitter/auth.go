@@ -4,6 +4,8 @@ import (
 	"fmt"
 
 	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 )
 
 // AuthConfig holds authentication configuration
@@ -27,6 +29,9 @@ func NewScraper(config AuthConfig) *Scraper {
 
 	scraper := &Scraper{Scraper: newTwitterScraper()}
 
+	// Each account always presents the same browser, until it is suspended or locked
+	scraper.SetUserAgent(fingerprint.For(fingerprint.TwitterAccountKey(config.Account.Username)).UserAgent)
+
 	// Configure whether to skip login verification
 	scraper.SetSkipLoginVerification(config.SkipLoginVerification)
 
@@ -0,0 +1,230 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// communityTweetsURL is the GraphQL endpoint of the timeline of a Community. The scraper library doesn't support
+// Communities, so the request is made through its RequestAPI, which authenticates it like the library's own requests.
+const communityTweetsURL = "https://x.com/i/api/graphql/7B2AdxSuC-Er8qUr3Plm_w/CommunityTweetsTimeline"
+
+// communityTweetsMaxPerPage is the largest page of the community timeline
+const communityTweetsMaxPerPage = 50
+
+var communityFeatures = map[string]any{
+	"communities_web_enable_tweet_community_results_fetch":                    true,
+	"c9s_tweet_anatomy_moderator_badge_enabled":                               true,
+	"creator_subscriptions_tweet_preview_api_enabled":                         true,
+	"freedom_of_speech_not_reach_fetch_enabled":                               true,
+	"graphql_is_translatable_rweb_tweet_is_translatable_enabled":              true,
+	"longform_notetweets_consumption_enabled":                                 true,
+	"longform_notetweets_inline_media_enabled":                                true,
+	"longform_notetweets_rich_text_read_enabled":                              true,
+	"responsive_web_edit_tweet_api_enabled":                                   true,
+	"responsive_web_enhance_cards_enabled":                                    false,
+	"responsive_web_graphql_exclude_directive_enabled":                        true,
+	"responsive_web_graphql_skip_user_profile_image_extensions_enabled":       false,
+	"responsive_web_graphql_timeline_navigation_enabled":                      true,
+	"responsive_web_twitter_article_tweet_consumption_enabled":                true,
+	"standardized_nudges_misinfo":                                             true,
+	"tweet_awards_web_tipping_enabled":                                        false,
+	"tweet_with_visibility_results_prefer_gql_limited_actions_policy_enabled": true,
+	"tweetypie_unmention_optimization_enabled":                                true,
+	"verified_phone_label_enabled":                                            false,
+	"view_counts_everywhere_api_enabled":                                      true,
+}
+
+type communityTweet struct {
+	RestID string `json:"rest_id"`
+	Core   struct {
+		UserResults struct {
+			Result struct {
+				RestID string `json:"rest_id"`
+				Legacy struct {
+					Name       string `json:"name"`
+					ScreenName string `json:"screen_name"`
+				} `json:"legacy"`
+			} `json:"result"`
+		} `json:"user_results"`
+	} `json:"core"`
+	Views struct {
+		Count string `json:"count"`
+	} `json:"views"`
+	Legacy struct {
+		ConversationIDStr string `json:"conversation_id_str"`
+		CreatedAt         string `json:"created_at"`
+		FullText          string `json:"full_text"`
+		FavoriteCount     int    `json:"favorite_count"`
+		ReplyCount        int    `json:"reply_count"`
+		RetweetCount      int    `json:"retweet_count"`
+		InReplyToStatus   string `json:"in_reply_to_status_id_str"`
+		PossiblySensitive bool   `json:"possibly_sensitive"`
+		Entities          struct {
+			Hashtags []struct {
+				Text string `json:"text"`
+			} `json:"hashtags"`
+			URLs []struct {
+				ExpandedURL string `json:"expanded_url"`
+			} `json:"urls"`
+		} `json:"entities"`
+	} `json:"legacy"`
+	// Tweet is set instead of the fields above for tweets with visibility restrictions
+	Tweet *communityTweet `json:"tweet"`
+}
+
+type communityEntry struct {
+	Content struct {
+		CursorType  string `json:"cursorType"`
+		Value       string `json:"value"`
+		ItemContent struct {
+			TweetResults struct {
+				Result communityTweet `json:"result"`
+			} `json:"tweet_results"`
+		} `json:"itemContent"`
+	} `json:"content"`
+}
+
+type communityTimeline struct {
+	Data struct {
+		CommunityResults struct {
+			Result struct {
+				RankedCommunityTimeline struct {
+					Timeline struct {
+						Instructions []struct {
+							Type    string           `json:"type"`
+							Entries []communityEntry `json:"entries"`
+							Entry   communityEntry   `json:"entry"`
+						} `json:"instructions"`
+					} `json:"timeline"`
+				} `json:"ranked_community_timeline"`
+			} `json:"result"`
+		} `json:"communityResults"`
+	} `json:"data"`
+}
+
+// FetchCommunityTweets returns a page of the latest tweets posted to a Community, and the cursor of the next page
+func (s *Scraper) FetchCommunityTweets(communityID string, count int, cursor string) ([]*twitterscraper.Tweet, string, error) {
+	count = min(count, communityTweetsMaxPerPage)
+
+	variables := map[string]any{
+		"communityId":     communityID,
+		"count":           count,
+		"displayLocation": "Community",
+		"rankingMode":     "Recency",
+		"withCommunity":   true,
+	}
+	if cursor != "" {
+		variables["cursor"] = cursor
+	}
+
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return nil, "", err
+	}
+	featuresJSON, err := json.Marshal(communityFeatures)
+	if err != nil {
+		return nil, "", err
+	}
+	q := url.Values{}
+	q.Set("variables", string(variablesJSON))
+	q.Set("features", string(featuresJSON))
+
+	req, err := http.NewRequest("GET", communityTweetsURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var timeline communityTimeline
+	if err := s.RequestAPI(req, &timeline); err != nil {
+		return nil, "", err
+	}
+	tweets, nextCursor := timeline.parseTweets()
+	return tweets, nextCursor, nil
+}
+
+// parseTweets returns the tweets of a page of a community timeline, and the cursor of the next page
+func (timeline *communityTimeline) parseTweets() ([]*twitterscraper.Tweet, string) {
+	tweets := make([]*twitterscraper.Tweet, 0)
+	cursor := ""
+	for _, instruction := range timeline.Data.CommunityResults.Result.RankedCommunityTimeline.Timeline.Instructions {
+		if instruction.Entry.Content.CursorType == "Bottom" {
+			cursor = instruction.Entry.Content.Value
+		}
+		for _, entry := range instruction.Entries {
+			if entry.Content.CursorType == "Bottom" {
+				cursor = entry.Content.Value
+				continue
+			}
+			if tweet := entry.Content.ItemContent.TweetResults.Result.toTweet(); tweet != nil {
+				tweets = append(tweets, tweet)
+			}
+		}
+	}
+	return tweets, cursor
+}
+
+// toTweet converts a tweet of a community timeline to a scraper tweet, or returns nil if the entry is not a tweet
+func (t *communityTweet) toTweet() *twitterscraper.Tweet {
+	if t.Tweet != nil {
+		return t.Tweet.toTweet()
+	}
+	if t.RestID == "" {
+		return nil
+	}
+
+	user := t.Core.UserResults.Result
+	tweet := &twitterscraper.Tweet{
+		ID:                t.RestID,
+		ConversationID:    t.Legacy.ConversationIDStr,
+		Text:              t.Legacy.FullText,
+		UserID:            user.RestID,
+		Username:          user.Legacy.ScreenName,
+		Name:              user.Legacy.Name,
+		Likes:             t.Legacy.FavoriteCount,
+		Replies:           t.Legacy.ReplyCount,
+		Retweets:          t.Legacy.RetweetCount,
+		InReplyToStatusID: t.Legacy.InReplyToStatus,
+		IsReply:           t.Legacy.InReplyToStatus != "",
+		SensitiveContent:  t.Legacy.PossiblySensitive,
+		PermanentURL:      fmt.Sprintf("https://twitter.com/%s/status/%s", user.Legacy.ScreenName, t.RestID),
+	}
+	tweet.Views, _ = strconv.Atoi(t.Views.Count)
+	if createdAt, err := time.Parse(time.RubyDate, t.Legacy.CreatedAt); err == nil {
+		tweet.TimeParsed = createdAt
+		tweet.Timestamp = createdAt.Unix()
+	}
+	for _, h := range t.Legacy.Entities.Hashtags {
+		tweet.Hashtags = append(tweet.Hashtags, h.Text)
+	}
+	for _, u := range t.Legacy.Entities.URLs {
+		tweet.URLs = append(tweet.URLs, u.ExpandedURL)
+	}
+	return tweet
+}
+
+// ParseSpaceID returns the ID of the Space a URL links to, e.g. https://x.com/i/spaces/1eaKbgRQzWQxX
+func ParseSpaceID(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return "", false
+	}
+	switch strings.TrimPrefix(u.Hostname(), "www.") {
+	case "x.com", "twitter.com":
+	default:
+		return "", false
+	}
+	id, ok := strings.CutPrefix(u.Path, "/i/spaces/")
+	id = strings.TrimSuffix(id, "/")
+	if !ok || id == "" || strings.Contains(id, "/") {
+		return "", false
+	}
+	return id, true
+}
@@ -4,56 +4,71 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"path/filepath"
 
 	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 
 	"github.com/sirupsen/logrus"
 )
 
+// cookieFile returns the path of the cookie file of an account
+func cookieFile(account *TwitterAccount, baseDir string) string {
+	return filepath.Join(baseDir, fmt.Sprintf("%s_twitter_cookies.json", account.Username))
+}
+
+// cookiePurpose binds the sealed cookies to the account they belong to
+func cookiePurpose(account *TwitterAccount) string {
+	return "twitter-cookies:" + account.Username
+}
+
+// SaveCookies seals the session cookies of the account and writes them to its cookie file
 func SaveCookies(scraper *twitterscraper.Scraper, account *TwitterAccount, baseDir string) error {
 	logrus.Debugf("Saving cookies for user %s", account.Username)
-	cookieFile := filepath.Join(baseDir, fmt.Sprintf("%s_twitter_cookies.json", account.Username))
 	cookies := scraper.GetCookies()
 	logrus.Debugf("Got %d cookies to save", len(cookies))
 
+	if err := writeCookies(account, baseDir, cookies); err != nil {
+		return err
+	}
+	logrus.Debug("Successfully saved cookies")
+	return nil
+}
+
+// writeCookies seals the cookies and writes them to the cookie file of the account
+func writeCookies(account *TwitterAccount, baseDir string, cookies []*http.Cookie) error {
 	data, err := json.Marshal(cookies)
 	if err != nil {
 		return fmt.Errorf("error marshaling cookies: %v", err)
 	}
 
-	logrus.Debugf("Writing cookies to file: %s", cookieFile)
-	if err = os.WriteFile(cookieFile, data, 0644); err != nil {
+	path := cookieFile(account, baseDir)
+	logrus.Debugf("Writing cookies to file: %s", path)
+	if err = tee.WriteSecretFile(path, cookiePurpose(account), data); err != nil {
 		return fmt.Errorf("error saving cookies: %v", err)
 	}
-	logrus.Debug("Successfully saved cookies")
 	return nil
 }
 
-func LoadCookies(scraper *twitterscraper.Scraper, account *TwitterAccount, baseDir string) error {
+// readCookies reads the cookies from the cookie file of the account. legacy is true if the file was written before
+// cookies were sealed.
+func readCookies(account *TwitterAccount, baseDir string) (cookies []*http.Cookie, legacy bool, err error) {
+	path := cookieFile(account, baseDir)
 
-	// let's logout first before loading cookies
-	if err := scraper.Logout(); err != nil { // logout first
-		logrus.Errorf("Error logging out: %v", err) // log error but continue
-	}
-
-	logrus.Debugf("Loading cookies for user %s", account.Username)
-	cookieFile := filepath.Join(baseDir, fmt.Sprintf("%s_twitter_cookies.json", account.Username))
-
-	logrus.Debugf("Reading cookie file: %s", cookieFile)
-	data, err := os.ReadFile(cookieFile)
+	logrus.Debugf("Reading cookie file: %s", path)
+	data, legacy, err := tee.ReadSecretFile(path, cookiePurpose(account))
 	if err != nil {
-		return fmt.Errorf("error reading cookies: %v", err)
+		return nil, false, fmt.Errorf("error reading cookies: %w", err)
 	}
 
-	var cookies []*http.Cookie
 	if err = json.Unmarshal(data, &cookies); err != nil {
-		return fmt.Errorf("error unmarshaling cookies: %v", err)
+		return nil, false, fmt.Errorf("error unmarshaling cookies: %v", err)
 	}
-	logrus.Debugf("Loaded %d cookies from file", len(cookies))
+	return cookies, legacy, nil
+}
 
-	// Verify critical cookies are present
+// checkAuthCookies returns an error unless the cookies include those of a logged in session
+func checkAuthCookies(cookies []*http.Cookie) error {
 	var hasAuthToken, hasCSRFToken bool
 	for _, cookie := range cookies {
 		if cookie.Name == "auth_token" {
@@ -70,9 +85,40 @@ func LoadCookies(scraper *twitterscraper.Scraper, account *TwitterAccount, baseD
 		logrus.Debug("Missing critical authentication cookies")
 		return fmt.Errorf("missing critical authentication cookies")
 	}
+	return nil
+}
+
+// LoadCookies reads the sealed session cookies of the account and sets them in the scraper.
+// Cookie files written before cookies were sealed are sealed once they have been loaded.
+func LoadCookies(scraper *twitterscraper.Scraper, account *TwitterAccount, baseDir string) error {
+
+	// let's logout first before loading cookies
+	if err := scraper.Logout(); err != nil { // logout first
+		logrus.Errorf("Error logging out: %v", err) // log error but continue
+	}
+
+	logrus.Debugf("Loading cookies for user %s", account.Username)
+	cookies, legacy, err := readCookies(account, baseDir)
+	if err != nil {
+		return err
+	}
+	logrus.Debugf("Loaded %d cookies from file", len(cookies))
+
+	// Verify critical cookies are present
+	if err := checkAuthCookies(cookies); err != nil {
+		return err
+	}
 
 	logrus.Debug("Setting cookies in scraper")
 	scraper.SetCookies(cookies)
 	logrus.Debug("Successfully loaded and set cookies")
+
+	if legacy {
+		logrus.Infof("Sealing plaintext cookie file of user %s", account.Username)
+		if err := writeCookies(account, baseDir, cookies); err != nil {
+			logrus.Warnf("Failed to seal cookie file of user %s: %v", account.Username, err)
+		}
+	}
+
 	return nil
 }
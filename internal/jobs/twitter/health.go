@@ -0,0 +1,61 @@
+package twitter
+
+import (
+	"strings"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// CheckAccount verifies that the session of an account still works by fetching its own profile, logging in again if
+// the session expired. The cookies of a working session are saved, so they are fresh for the next jobs.
+func CheckAccount(account *TwitterAccount, baseDir string) types.TwitterAccountStatus {
+	status := types.TwitterAccountStatus{Username: account.Username}
+	fail := func(err error) types.TwitterAccountStatus {
+		status.Status = AccountStatusOf(err)
+		status.Error = err.Error()
+		logrus.Warnf("Twitter account %s is %s: %v", account.Username, status.Status, err)
+		return status
+	}
+
+	scraper := &Scraper{Scraper: newTwitterScraper()}
+	if err := LoadCookies(scraper.Scraper, account, baseDir); err != nil || !scraper.IsLoggedIn() {
+		var err error
+		if account.TwoFACode != "" {
+			err = scraper.Login(account.Username, account.Password, account.TwoFACode)
+		} else {
+			err = scraper.Login(account.Username, account.Password)
+		}
+		if err != nil {
+			return fail(err)
+		}
+		status.LoggedIn = true
+	}
+
+	if _, err := scraper.GetProfile(account.Username); err != nil {
+		return fail(err)
+	}
+	status.Status = types.TwitterAccountOK
+
+	if err := SaveCookies(scraper.Scraper, account, baseDir); err != nil {
+		logrus.WithError(err).Errorf("Failed to save cookies for %s", account.Username)
+	} else {
+		status.CookiesRefreshed = true
+	}
+	return status
+}
+
+// AccountStatusOf returns the status of an account whose session failed with the error
+func AccountStatusOf(err error) string {
+	msg := err.Error()
+	switch {
+	case strings.Contains(msg, "Rate limit exceeded") || strings.Contains(msg, "status code 429"):
+		return types.TwitterAccountRateLimited
+	case IsSuspensionError(err):
+		return types.TwitterAccountLocked
+	case strings.Contains(msg, "LoginAcid") || strings.Contains(msg, "DenyLoginSubtask") || strings.Contains(msg, "LoginTwoFactorAuthChallenge"):
+		return types.TwitterAccountChallengeRequired
+	default:
+		return types.TwitterAccountFailed
+	}
+}
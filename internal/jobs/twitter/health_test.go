@@ -0,0 +1,21 @@
+package twitter
+
+import (
+	"errors"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = DescribeTable("AccountStatusOf",
+	func(msg string, status string) {
+		Expect(AccountStatusOf(errors.New(msg))).To(Equal(status))
+	},
+	Entry("rate limited", "response status code 429: Rate limit exceeded", types.TwitterAccountRateLimited),
+	Entry("suspended", "(64) Your account is suspended and is not permitted to access this feature", types.TwitterAccountLocked),
+	Entry("locked", "(326) To protect our users from spam, your account has been temporarily locked", types.TwitterAccountLocked),
+	Entry("identity confirmation", "login failed: confirmation data required for LoginAcid", types.TwitterAccountChallengeRequired),
+	Entry("login denied", "login failed: auth error: DenyLoginSubtask", types.TwitterAccountChallengeRequired),
+	Entry("wrong password", "login failed: auth error: (399) Wrong password!", types.TwitterAccountFailed),
+)
@@ -0,0 +1,165 @@
+package twitter
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// tweetResultURL is the GraphQL endpoint returning a single tweet with its card. The scraper library drops the
+// cards of tweets, which hold the polls, so the request is made through its RequestAPI like FetchCommunityTweets.
+const tweetResultURL = "https://x.com/i/api/graphql/Xl5pC_lBk_gcO2ItU39DQw/TweetResultByRestId"
+
+// maxPollChoices is the largest number of options of a poll
+const maxPollChoices = 4
+
+var tweetResultFeatures = map[string]any{
+	"creator_subscriptions_tweet_preview_api_enabled":                         true,
+	"freedom_of_speech_not_reach_fetch_enabled":                               true,
+	"graphql_is_translatable_rweb_tweet_is_translatable_enabled":              true,
+	"longform_notetweets_consumption_enabled":                                 true,
+	"longform_notetweets_inline_media_enabled":                                true,
+	"longform_notetweets_rich_text_read_enabled":                              true,
+	"responsive_web_edit_tweet_api_enabled":                                   true,
+	"responsive_web_enhance_cards_enabled":                                    false,
+	"responsive_web_graphql_exclude_directive_enabled":                        true,
+	"responsive_web_graphql_skip_user_profile_image_extensions_enabled":       false,
+	"responsive_web_graphql_timeline_navigation_enabled":                      true,
+	"standardized_nudges_misinfo":                                             true,
+	"tweet_with_visibility_results_prefer_gql_limited_actions_policy_enabled": true,
+	"verified_phone_label_enabled":                                            false,
+	"view_counts_everywhere_api_enabled":                                      true,
+}
+
+// TweetCard is the card of a tweet, e.g. a link preview or a poll such as poll2choice_text_only or poll4choice_image
+type TweetCard struct {
+	Name          string `json:"name"`
+	BindingValues []struct {
+		Key   string `json:"key"`
+		Value struct {
+			StringValue  string `json:"string_value"`
+			BooleanValue bool   `json:"boolean_value"`
+		} `json:"value"`
+	} `json:"binding_values"`
+}
+
+type tweetCardResult struct {
+	RestID string `json:"rest_id"`
+	Card   struct {
+		Legacy TweetCard `json:"legacy"`
+	} `json:"card"`
+	// Tweet is set instead of the fields above for tweets with visibility restrictions
+	Tweet *tweetCardResult `json:"tweet"`
+}
+
+type tweetResultResponse struct {
+	Data struct {
+		TweetResult struct {
+			Result tweetCardResult `json:"result"`
+		} `json:"tweetResult"`
+	} `json:"data"`
+}
+
+// PollChoice is an option of a poll parsed from its card
+type PollChoice struct {
+	Label string
+	Count int
+}
+
+// Poll is a poll parsed from the card of a tweet
+type Poll struct {
+	Choices []PollChoice
+	// EndTime is when voting closes, and LastUpdated when the counts were last updated
+	EndTime     time.Time
+	LastUpdated time.Time
+	Duration    time.Duration
+	// Final is set once voting has closed and the counts won't change anymore
+	Final bool
+}
+
+// FetchTweetCard returns the card of a tweet, or nil if the tweet has no card
+func (s *Scraper) FetchTweetCard(tweetID string) (*TweetCard, error) {
+	variables := map[string]any{
+		"tweetId":                tweetID,
+		"withCommunity":          false,
+		"includePromotedContent": false,
+		"withVoice":              false,
+	}
+
+	variablesJSON, err := json.Marshal(variables)
+	if err != nil {
+		return nil, err
+	}
+	featuresJSON, err := json.Marshal(tweetResultFeatures)
+	if err != nil {
+		return nil, err
+	}
+	q := url.Values{}
+	q.Set("variables", string(variablesJSON))
+	q.Set("features", string(featuresJSON))
+
+	req, err := http.NewRequest("GET", tweetResultURL+"?"+q.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var resp tweetResultResponse
+	if err := s.RequestAPI(req, &resp); err != nil {
+		return nil, err
+	}
+
+	result := &resp.Data.TweetResult.Result
+	if result.Tweet != nil {
+		result = result.Tweet
+	}
+	if result.RestID == "" {
+		return nil, fmt.Errorf("tweet %s not found", tweetID)
+	}
+	if result.Card.Legacy.Name == "" {
+		return nil, nil
+	}
+	return &result.Card.Legacy, nil
+}
+
+// IsPoll returns true if the card holds a poll
+func (c *TweetCard) IsPoll() bool {
+	return strings.HasPrefix(c.Name, "poll") && strings.Contains(c.Name, "choice")
+}
+
+// Poll parses the options, vote counts and end time of the poll held by the card
+func (c *TweetCard) Poll() (*Poll, error) {
+	if !c.IsPoll() {
+		return nil, fmt.Errorf("card %s is not a poll", c.Name)
+	}
+
+	values := make(map[string]string, len(c.BindingValues))
+	flags := make(map[string]bool, len(c.BindingValues))
+	for _, v := range c.BindingValues {
+		values[v.Key] = v.Value.StringValue
+		flags[v.Key] = v.Value.BooleanValue
+	}
+
+	poll := &Poll{Final: flags["counts_are_final"]}
+	for i := 1; i <= maxPollChoices; i++ {
+		label, ok := values[fmt.Sprintf("choice%d_label", i)]
+		if !ok {
+			break
+		}
+		count, _ := strconv.Atoi(values[fmt.Sprintf("choice%d_count", i)])
+		poll.Choices = append(poll.Choices, PollChoice{Label: label, Count: count})
+	}
+	if len(poll.Choices) == 0 {
+		return nil, fmt.Errorf("card %s has no choices", c.Name)
+	}
+
+	poll.EndTime, _ = time.Parse(time.RFC3339, values["end_datetime_utc"])
+	poll.LastUpdated, _ = time.Parse(time.RFC3339, values["last_updated_datetime_utc"])
+	if minutes, err := strconv.Atoi(values["duration_minutes"]); err == nil {
+		poll.Duration = time.Duration(minutes) * time.Minute
+	}
+	return poll, nil
+}
@@ -0,0 +1,222 @@
+package twitter
+
+/*
+The followers of an account are listed newest first, in pages linked by cursors of the form "<sort index>|<entry ID>".
+The sort index decreases along the list at a rate which can be measured from the cursors of two consecutive pages,
+so the cursor of a page further down the list can be made up by lowering it. SampleFollowers uses this to read a few
+pages spread over the whole list and sample the followers in them, instead of paginating through millions of
+followers. When a made up cursor is rejected, it falls back to reading the pages one after the other.
+*/
+
+import (
+	"math"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/sirupsen/logrus"
+)
+
+// FollowerPageSize is the number of followers requested per page, the most the followers endpoint returns
+const FollowerPageSize = 200
+
+// Sampling methods of a FollowerSample
+const (
+	// SampleExhaustive means that all the followers were read
+	SampleExhaustive = "exhaustive"
+	// SampleSkip means that pages spread over the list of followers were read by skipping the pages between them
+	SampleSkip = "skip"
+	// SampleSequential means that pages were read one after the other until the page budget ran out, so only the
+	// most recent followers could be sampled
+	SampleSequential = "sequential"
+)
+
+// confidenceZ is the z-score of the 95% confidence level of the margins of error
+const confidenceZ = 1.96
+
+// FollowerPageFetcher returns the page of followers after a cursor, or the first page for an empty cursor, and the
+// cursor of the next page, which is empty at the end of the list
+type FollowerPageFetcher func(cursor string) ([]*twitterscraper.Profile, string, error)
+
+// SampleOptions configure SampleFollowers
+type SampleOptions struct {
+	// SampleSize is the number of followers to sample
+	SampleSize int
+	// MaxPages is the number of pages which may be requested
+	MaxPages int
+	// Population is the number of followers of the account, or 0 if it is not known
+	Population int
+	// Deadline stops the sampling with the pages read so far, if set
+	Deadline time.Time
+	// Rand picks the sample, e.g. seeded for reproducible samples
+	Rand *rand.Rand
+}
+
+// FollowerSample is a random sample of the followers of an account
+type FollowerSample struct {
+	Account string `json:"account"`
+	// Population is the number of followers of the account, or the number of followers read if it is not known
+	Population int                       `json:"population"`
+	SampleSize int                       `json:"sample_size"`
+	Followers  []*twitterscraper.Profile `json:"followers"`
+	Method     string                    `json:"method"`
+	PagesRead  int                       `json:"pages_read"`
+	// FollowersRead is the number of distinct followers the sample was drawn from
+	FollowersRead int `json:"followers_read"`
+	// Coverage is the estimated share of the list of followers spanned by the pages which were read. The followers
+	// beyond it, i.e. the oldest ones, could not be sampled.
+	Coverage float64 `json:"coverage"`
+	// MarginOfError is the largest half-width of the confidence interval of a proportion estimated from the sample,
+	// e.g. the share of verified followers, at ConfidenceLevel
+	MarginOfError   float64 `json:"margin_of_error"`
+	ConfidenceLevel float64 `json:"confidence_level"`
+}
+
+// MarginOfError returns the half-width of the 95% confidence interval of a proportion of 0.5, the widest one,
+// estimated from a simple random sample of n out of population members. The finite population correction shrinks it
+// as the sample approaches the population; a population of 0 is treated as infinite.
+func MarginOfError(n, population int) float64 {
+	if n <= 0 {
+		return 1
+	}
+	margin := confidenceZ * math.Sqrt(0.25/float64(n))
+	if population > 1 && n <= population {
+		margin *= math.Sqrt(float64(population-n) / float64(population-1))
+	}
+	return margin
+}
+
+// cursorSortIndex returns the sort index of a followers cursor
+func cursorSortIndex(cursor string) (int64, bool) {
+	index, _, ok := strings.Cut(cursor, "|")
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(index, 10, 64)
+	return n, err == nil && n > 0
+}
+
+// skipCursor returns a cursor pointing further down the list of followers, by lowering its sort index by delta
+func skipCursor(cursor string, delta int64) (string, bool) {
+	index, ok := cursorSortIndex(cursor)
+	if !ok || delta <= 0 || index-delta <= 0 {
+		return "", false
+	}
+	_, entry, _ := strings.Cut(cursor, "|")
+	return strconv.FormatInt(index-delta, 10) + "|" + entry, true
+}
+
+// SampleFollowers samples the followers of an account with at most opts.MaxPages requests. If the account has more
+// followers than fit in these pages, pages are skipped so that the ones read are spread evenly over the list of
+// followers. Errors after the first page end the sampling with the followers read so far.
+func SampleFollowers(fetch FollowerPageFetcher, opts SampleOptions) (*FollowerSample, error) {
+	rng := opts.Rand
+	if rng == nil {
+		rng = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64()))
+	}
+
+	// The followers between two pages which are read, if the budget does not cover all the pages
+	stride := 0
+	if opts.Population > opts.MaxPages*FollowerPageSize {
+		stride = opts.Population/opts.MaxPages - FollowerPageSize
+	}
+
+	var (
+		read      []*twitterscraper.Profile
+		seen      = map[string]struct{}{}
+		pages     int
+		cursor    string
+		fallback  string
+		jumped    bool
+		skipped   bool
+		exhausted bool
+		position  int
+		reached   int
+		rate      float64
+	)
+	for pages < opts.MaxPages {
+		if !opts.Deadline.IsZero() && time.Now().After(opts.Deadline) {
+			break
+		}
+
+		profiles, next, err := fetch(cursor)
+		pages++
+		if jumped && (err != nil || len(profiles) == 0) {
+			// The made up cursor was rejected or pointed past the end of the list, so the remaining pages are read
+			// one after the other
+			logrus.Debugf("Skipping followers failed, reading them sequentially: %v", err)
+			cursor, jumped, stride, position = fallback, false, 0, reached
+			continue
+		}
+		if err != nil {
+			if len(read) == 0 {
+				return nil, err
+			}
+			logrus.Warnf("Error while sampling followers, returning a sample of the %d followers read: %v", len(read), err)
+			break
+		}
+		if len(profiles) == 0 {
+			exhausted = true
+			break
+		}
+
+		for _, p := range profiles {
+			if _, dup := seen[p.UserID]; p.UserID == "" || dup {
+				continue
+			}
+			seen[p.UserID] = struct{}{}
+			read = append(read, p)
+		}
+		reached = position + len(profiles)
+
+		// The rate at which the sort index decreases is measured on every page, since it changes along the list
+		if from, ok := cursorSortIndex(cursor); ok {
+			if to, ok := cursorSortIndex(next); ok && from > to {
+				rate = float64(from-to) / float64(len(profiles))
+			}
+		}
+		if next == "" || next == cursor {
+			exhausted = true
+			break
+		}
+
+		cursor, fallback, jumped, position = next, next, false, reached
+		if stride > 0 && rate > 0 {
+			if c, ok := skipCursor(next, int64(rate*float64(stride))); ok {
+				cursor, jumped, skipped = c, true, true
+				position += stride
+			}
+		}
+	}
+
+	sample := &FollowerSample{
+		Population:      opts.Population,
+		PagesRead:       pages,
+		FollowersRead:   len(read),
+		ConfidenceLevel: 0.95,
+	}
+	switch {
+	case exhausted && !skipped:
+		sample.Method = SampleExhaustive
+	case skipped:
+		sample.Method = SampleSkip
+	default:
+		sample.Method = SampleSequential
+	}
+	if sample.Population <= 0 {
+		sample.Population = len(read)
+	}
+	if exhausted {
+		sample.Coverage = 1
+	} else if sample.Population > 0 {
+		sample.Coverage = min(1, float64(reached)/float64(sample.Population))
+	}
+
+	rng.Shuffle(len(read), func(a, b int) { read[a], read[b] = read[b], read[a] })
+	sample.Followers = read[:min(len(read), opts.SampleSize)]
+	sample.SampleSize = len(sample.Followers)
+	sample.MarginOfError = MarginOfError(sample.SampleSize, sample.Population)
+	return sample, nil
+}
@@ -0,0 +1,87 @@
+package twitter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"slices"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// sessionsPurpose binds exported sessions to their use, so they can't be unsealed as any other secret
+const sessionsPurpose = "twitter-sessions"
+
+// ExportSessions seals the session cookies of all the accounts which have a cookie file, so that a worker with the
+// same sealing key can import them with ImportSessions instead of logging in again. It returns the sealed sessions
+// and the sorted usernames of the accounts they belong to.
+func ExportSessions(accounts []*TwitterAccount, baseDir string) (string, []string, error) {
+	sessions := make(map[string][]*http.Cookie)
+	usernames := []string{}
+	for _, account := range accounts {
+		cookies, _, err := readCookies(account, baseDir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			logrus.Warnf("Not exporting the session of user %s: %v", account.Username, err)
+			continue
+		}
+		sessions[account.Username] = cookies
+		usernames = append(usernames, account.Username)
+	}
+	slices.Sort(usernames)
+
+	data, err := json.Marshal(sessions)
+	if err != nil {
+		return "", nil, fmt.Errorf("error marshaling sessions: %w", err)
+	}
+	sealed, err := tee.SealSecret(sessionsPurpose, data)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(sealed), usernames, nil
+}
+
+// ImportSessions unseals sessions exported with ExportSessions, and replaces the cookie files of the accounts with
+// them. Sessions of users which are not among the accounts, and sessions without authentication cookies, are
+// skipped. It returns the sorted usernames of the imported and of the skipped sessions, or types.ErrInvalidSessions
+// if the sessions can't be unsealed.
+func ImportSessions(accounts []*TwitterAccount, baseDir string, sealed string) (imported []string, skipped []string, err error) {
+	data, err := tee.UnsealSecret(sessionsPurpose, []byte(sealed))
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", types.ErrInvalidSessions, err)
+	}
+
+	var sessions map[string][]*http.Cookie
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", types.ErrInvalidSessions, err)
+	}
+
+	imported = []string{}
+	for username, cookies := range sessions {
+		i := slices.IndexFunc(accounts, func(account *TwitterAccount) bool { return account.Username == username })
+		if i < 0 {
+			logrus.Infof("Not importing the session of user %s, which is not a configured account", username)
+			skipped = append(skipped, username)
+			continue
+		}
+		if err := checkAuthCookies(cookies); err != nil {
+			logrus.Warnf("Not importing the session of user %s: %v", username, err)
+			skipped = append(skipped, username)
+			continue
+		}
+		if err := writeCookies(accounts[i], baseDir, cookies); err != nil {
+			return nil, nil, err
+		}
+		logrus.Infof("Imported the session of user %s", username)
+		imported = append(imported, username)
+	}
+	slices.Sort(imported)
+	slices.Sort(skipped)
+	return imported, skipped, nil
+}
@@ -0,0 +1,161 @@
+package twitter
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// FollowerRelation is the relation of the accounts in a FollowerSnapshot to the account it was taken of
+type FollowerRelation string
+
+const (
+	RelationFollowers FollowerRelation = "followers"
+	RelationFollowing FollowerRelation = "following"
+)
+
+// ErrSnapshotNotFound is returned when a follower snapshot does not exist
+var ErrSnapshotNotFound = types.NewCodedError(types.ErrorCodeNotFound, "follower snapshot not found")
+
+// usernameRegexp matches valid Twitter usernames, which are also used as directory names
+var usernameRegexp = regexp.MustCompile(`^[A-Za-z0-9_]{1,15}$`)
+
+// FollowerMember is an account in a FollowerSnapshot
+type FollowerMember struct {
+	ID       string `json:"id"`
+	Username string `json:"username"`
+}
+
+// FollowerSnapshot is the list of followers or followed accounts of an account at some point in time
+type FollowerSnapshot struct {
+	ID       string           `json:"id"`
+	Account  string           `json:"account"`
+	Relation FollowerRelation `json:"relation"`
+	TakenAt  time.Time        `json:"taken_at"`
+	// MaxResults is the number of accounts which were requested. If the snapshot has that many members, the
+	// account may have more followers than were fetched.
+	MaxResults int              `json:"max_results"`
+	Members    []FollowerMember `json:"members"`
+}
+
+// PossiblyTruncated returns true if the account may have more followers than were fetched for the snapshot
+func (s *FollowerSnapshot) PossiblyTruncated() bool {
+	return s.MaxResults > 0 && len(s.Members) >= s.MaxResults
+}
+
+// NormalizeUsername returns the username in the form used to store snapshots, i.e. lowercase and without a leading @
+func NormalizeUsername(username string) (string, error) {
+	username = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(username), "@"))
+	if !usernameRegexp.MatchString(username) {
+		return "", fmt.Errorf("invalid Twitter username %q", username)
+	}
+	return username, nil
+}
+
+// SnapshotStore persists follower snapshots in the data directory. Snapshots are sealed, so they can't be read or
+// altered outside of the worker. Only the most recent snapshots of each account and relation are kept.
+type SnapshotStore struct {
+	baseDir string
+	max     int
+}
+
+// NewSnapshotStore returns a store which keeps up to max snapshots per account and relation below baseDir
+func NewSnapshotStore(baseDir string, max int) *SnapshotStore {
+	return &SnapshotStore{baseDir: filepath.Join(baseDir, "follower_snapshots"), max: max}
+}
+
+func (s *SnapshotStore) dir(account string, relation FollowerRelation) string {
+	return filepath.Join(s.baseDir, string(relation), account)
+}
+
+func snapshotPurpose(account string, relation FollowerRelation) string {
+	return fmt.Sprintf("twitter-%s:%s", relation, account)
+}
+
+// Save stores a new snapshot, assigning it an ID which sorts after the IDs of earlier snapshots. The oldest
+// snapshots are removed if there are more than the store keeps.
+func (s *SnapshotStore) Save(snapshot *FollowerSnapshot) error {
+	dir := s.dir(snapshot.Account, snapshot.Relation)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating snapshot directory: %w", err)
+	}
+
+	snapshot.ID = strconv.FormatInt(snapshot.TakenAt.UnixNano(), 10)
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error marshalling snapshot: %w", err)
+	}
+	if err := tee.WriteSecretFile(filepath.Join(dir, snapshot.ID+".json"), snapshotPurpose(snapshot.Account, snapshot.Relation), data); err != nil {
+		return err
+	}
+
+	ids, err := s.List(snapshot.Account, snapshot.Relation)
+	if err != nil {
+		return err
+	}
+	for len(ids) > s.max {
+		if err := os.Remove(filepath.Join(dir, ids[0]+".json")); err != nil {
+			logrus.Warnf("Failed to remove follower snapshot %s of %s: %v", ids[0], snapshot.Account, err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// List returns the IDs of the stored snapshots of an account, oldest first
+func (s *SnapshotStore) List(account string, relation FollowerRelation) ([]string, error) {
+	entries, err := os.ReadDir(s.dir(account, relation))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing snapshots: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if _, err := strconv.ParseInt(id, 10, 64); ok && err == nil {
+			ids = append(ids, id)
+		}
+	}
+	// The IDs are timestamps with the same number of digits, so they sort chronologically
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Load reads a stored snapshot
+func (s *SnapshotStore) Load(account string, relation FollowerRelation, id string) (*FollowerSnapshot, error) {
+	if _, err := strconv.ParseInt(id, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid snapshot ID %q", id)
+	}
+
+	data, legacy, err := tee.ReadSecretFile(filepath.Join(s.dir(account, relation), id+".json"), snapshotPurpose(account, relation))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrSnapshotNotFound, id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// Snapshots have always been sealed, so a plaintext one has been put there by someone else
+	if legacy {
+		return nil, fmt.Errorf("snapshot %s is not sealed", id)
+	}
+
+	snapshot := &FollowerSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return nil, fmt.Errorf("error unmarshalling snapshot: %w", err)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,118 @@
+package twitter
+
+import (
+	"cmp"
+	"slices"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+)
+
+// ThreadNode is a tweet of a conversation thread with its place in the thread
+type ThreadNode struct {
+	Tweet    *twitterscraper.Tweet
+	ParentID string
+	// ChildIDs are the IDs of the tweets of the thread replying to this one, the self-thread continuation first
+	ChildIDs []string
+	// Depth is 0 for the tweets of the self-thread, and increases by one for every level of replies below them
+	Depth      int
+	SelfThread bool
+}
+
+// BuildThread orders the tweets of a conversation into a thread starting at the root tweet.
+//
+// The self-thread, i.e. the chain of tweets by the author of the root replying to their previous tweet, comes first,
+// in the order it was written. Then the replies to each tweet of the self-thread follow depth first, so every reply
+// comes after the tweet it replies to. Only the maxReplies replies with the most likes are kept per tweet, at most
+// maxDepth levels below the self-thread. Tweets which are not connected to the root are dropped.
+func BuildThread(rootID string, tweets []*twitterscraper.Tweet, maxDepth, maxReplies int) []ThreadNode {
+	byID := make(map[string]*twitterscraper.Tweet, len(tweets))
+	children := make(map[string][]*twitterscraper.Tweet)
+	for _, t := range tweets {
+		if t == nil || t.ID == "" {
+			continue
+		}
+		if _, dup := byID[t.ID]; dup {
+			continue
+		}
+		byID[t.ID] = t
+		if t.InReplyToStatusID != "" && t.ID != rootID {
+			children[t.InReplyToStatusID] = append(children[t.InReplyToStatusID], t)
+		}
+	}
+
+	root, ok := byID[rootID]
+	if !ok {
+		return nil
+	}
+
+	// The self-thread continues with the earliest reply of the author to the last tweet of the self-thread
+	selfThread := []*twitterscraper.Tweet{root}
+	next := make(map[string]string)
+	for last := root; ; {
+		var cont *twitterscraper.Tweet
+		for _, c := range children[last.ID] {
+			if c.UserID == root.UserID && (cont == nil || c.Timestamp < cont.Timestamp) {
+				cont = c
+			}
+		}
+		if cont == nil {
+			break
+		}
+		next[last.ID] = cont.ID
+		selfThread = append(selfThread, cont)
+		last = cont
+	}
+
+	// keyReplies returns the replies to a tweet, without its self-thread continuation, with the most liked first
+	keyReplies := func(id string) []*twitterscraper.Tweet {
+		var replies []*twitterscraper.Tweet
+		for _, c := range children[id] {
+			if c.ID != next[id] {
+				replies = append(replies, c)
+			}
+		}
+		slices.SortStableFunc(replies, func(a, b *twitterscraper.Tweet) int {
+			if c := cmp.Compare(b.Likes, a.Likes); c != 0 {
+				return c
+			}
+			return cmp.Compare(a.Timestamp, b.Timestamp)
+		})
+		if len(replies) > maxReplies {
+			replies = replies[:maxReplies]
+		}
+		return replies
+	}
+
+	var nodes []ThreadNode
+	index := make(map[string]int)
+	add := func(t *twitterscraper.Tweet, parentID string, depth int, selfThread bool) {
+		index[t.ID] = len(nodes)
+		nodes = append(nodes, ThreadNode{Tweet: t, ParentID: parentID, Depth: depth, SelfThread: selfThread})
+		if parentID != "" {
+			parent := &nodes[index[parentID]]
+			parent.ChildIDs = append(parent.ChildIDs, t.ID)
+		}
+	}
+
+	parentID := ""
+	for _, t := range selfThread {
+		add(t, parentID, 0, true)
+		parentID = t.ID
+	}
+
+	var addReplies func(id string, depth int)
+	addReplies = func(id string, depth int) {
+		if depth > maxDepth {
+			return
+		}
+		for _, r := range keyReplies(id) {
+			add(r, id, depth, false)
+			addReplies(r.ID, depth+1)
+		}
+	}
+	for _, t := range selfThread {
+		addReplies(t.ID, 1)
+	}
+
+	return nodes
+}
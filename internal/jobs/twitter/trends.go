@@ -0,0 +1,257 @@
+package twitter
+
+import (
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// trendPurpose is the sealing purpose of trend snapshots
+const trendPurpose = "twitter-trends"
+
+// Kinds of trends
+const (
+	TrendHashtag = "hashtag"
+	TrendCashtag = "cashtag"
+	TrendTopic   = "topic"
+)
+
+// TrendKind returns whether a trend is a hashtag, a cashtag or another topic
+func TrendKind(trend string) string {
+	switch {
+	case strings.HasPrefix(trend, "#"):
+		return TrendHashtag
+	case strings.HasPrefix(trend, "$"):
+		return TrendCashtag
+	}
+	return TrendTopic
+}
+
+// TrendSnapshot is the list of trends at some point in time, highest ranked first
+type TrendSnapshot struct {
+	ID      string    `json:"id"`
+	TakenAt time.Time `json:"taken_at"`
+	Trends  []string  `json:"trends"`
+}
+
+// TrendStore persists trend snapshots in the data directory, sealed like follower snapshots. Only the most recent
+// snapshots are kept.
+type TrendStore struct {
+	dir string
+	max int
+}
+
+// NewTrendStore returns a store which keeps up to max trend snapshots below baseDir
+func NewTrendStore(baseDir string, max int) *TrendStore {
+	return &TrendStore{dir: filepath.Join(baseDir, "trend_snapshots"), max: max}
+}
+
+// Save stores a new snapshot, assigning it an ID which sorts after the IDs of earlier snapshots. The oldest
+// snapshots are removed if there are more than the store keeps.
+func (s *TrendStore) Save(snapshot *TrendSnapshot) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("error creating trend snapshot directory: %w", err)
+	}
+
+	snapshot.ID = strconv.FormatInt(snapshot.TakenAt.UnixNano(), 10)
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("error marshalling trend snapshot: %w", err)
+	}
+	if err := tee.WriteSecretFile(filepath.Join(s.dir, snapshot.ID+".json"), trendPurpose, data); err != nil {
+		return err
+	}
+
+	ids, err := s.List()
+	if err != nil {
+		return err
+	}
+	for len(ids) > s.max {
+		if err := os.Remove(filepath.Join(s.dir, ids[0]+".json")); err != nil {
+			logrus.Warnf("Failed to remove trend snapshot %s: %v", ids[0], err)
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+// List returns the IDs of the stored snapshots, oldest first
+func (s *TrendStore) List() ([]string, error) {
+	entries, err := os.ReadDir(s.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error listing trend snapshots: %w", err)
+	}
+
+	var ids []string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if _, err := strconv.ParseInt(id, 10, 64); ok && err == nil {
+			ids = append(ids, id)
+		}
+	}
+	// The IDs are timestamps with the same number of digits, so they sort chronologically
+	slices.Sort(ids)
+	return ids, nil
+}
+
+// Since returns the stored snapshots taken at or after since, oldest first. Snapshots which can't be read are skipped.
+func (s *TrendStore) Since(since time.Time) ([]*TrendSnapshot, error) {
+	ids, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []*TrendSnapshot
+	for _, id := range ids {
+		if nanos, _ := strconv.ParseInt(id, 10, 64); time.Unix(0, nanos).Before(since) {
+			continue
+		}
+		data, legacy, err := tee.ReadSecretFile(filepath.Join(s.dir, id+".json"), trendPurpose)
+		if err == nil && legacy {
+			// Snapshots have always been sealed, so a plaintext one has been put there by someone else
+			err = errors.New("not sealed")
+		}
+		snapshot := &TrendSnapshot{}
+		if err == nil {
+			err = json.Unmarshal(data, snapshot)
+		}
+		if err != nil {
+			logrus.Warnf("Skipping trend snapshot %s: %v", id, err)
+			continue
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// TrendRank is the position of a trend in a snapshot, starting at 1
+type TrendRank struct {
+	TakenAt time.Time `json:"taken_at"`
+	Rank    int       `json:"rank"`
+}
+
+// TrendSeries is the ranking of a trend over time. Snapshots in which the trend does not appear have no rank.
+type TrendSeries struct {
+	Name        string      `json:"name"`
+	Kind        string      `json:"kind"`
+	FirstSeen   time.Time   `json:"first_seen"`
+	LastSeen    time.Time   `json:"last_seen"`
+	Appearances int         `json:"appearances"`
+	BestRank    int         `json:"best_rank"`
+	LatestRank  int         `json:"latest_rank"`
+	Ranks       []TrendRank `json:"ranks"`
+}
+
+// TrendHistory is the ranking of trends over a list of snapshots
+type TrendHistory struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// SnapshotTimes are the times of the snapshots, oldest first, so absences of a trend can be told apart from gaps
+	SnapshotTimes []time.Time `json:"snapshot_times"`
+	// SamplesTaken is the number of snapshots taken by the job, the others were stored by earlier jobs
+	SamplesTaken int           `json:"samples_taken"`
+	Trends       []TrendSeries `json:"trends"`
+}
+
+// BuildTrendHistory ranks the trends of the given kind, or of any kind if kind is empty, over the snapshots. The
+// trends which appeared most often come first, then those which ranked highest. At most maxTrends are returned
+// unless it is 0. Ranks are positions among all the trends of a snapshot, not only those of the kind.
+func BuildTrendHistory(snapshots []*TrendSnapshot, kind string, maxTrends int) TrendHistory {
+	snapshots = slices.Clone(snapshots)
+	slices.SortFunc(snapshots, func(a, b *TrendSnapshot) int { return a.TakenAt.Compare(b.TakenAt) })
+
+	history := TrendHistory{SnapshotTimes: make([]time.Time, 0, len(snapshots)), Trends: []TrendSeries{}}
+	if len(snapshots) == 0 {
+		return history
+	}
+	history.From = snapshots[0].TakenAt
+	history.To = snapshots[len(snapshots)-1].TakenAt
+
+	series := make(map[string]*TrendSeries)
+	for _, snapshot := range snapshots {
+		history.SnapshotTimes = append(history.SnapshotTimes, snapshot.TakenAt)
+		seen := make(map[string]bool, len(snapshot.Trends))
+		for i, trend := range snapshot.Trends {
+			trendKind := TrendKind(trend)
+			if seen[trend] || (kind != "" && trendKind != kind) {
+				continue
+			}
+			seen[trend] = true
+
+			s, ok := series[trend]
+			if !ok {
+				s = &TrendSeries{Name: trend, Kind: trendKind, FirstSeen: snapshot.TakenAt, BestRank: i + 1}
+				series[trend] = s
+			}
+			s.LastSeen = snapshot.TakenAt
+			s.Appearances++
+			s.BestRank = min(s.BestRank, i+1)
+			s.LatestRank = i + 1
+			s.Ranks = append(s.Ranks, TrendRank{TakenAt: snapshot.TakenAt, Rank: i + 1})
+		}
+	}
+
+	for _, s := range series {
+		history.Trends = append(history.Trends, *s)
+	}
+	slices.SortFunc(history.Trends, func(a, b TrendSeries) int {
+		return cmp.Or(
+			cmp.Compare(b.Appearances, a.Appearances),
+			cmp.Compare(a.BestRank, b.BestRank),
+			cmp.Compare(a.Name, b.Name),
+		)
+	})
+	if maxTrends > 0 && len(history.Trends) > maxTrends {
+		history.Trends = history.Trends[:maxTrends]
+	}
+	return history
+}
+
+// TrendFetcher takes a snapshot of the current trends
+type TrendFetcher func() (*TrendSnapshot, error)
+
+// TrendSampleOptions configure SampleTrends
+type TrendSampleOptions struct {
+	// Samples is the number of snapshots to take
+	Samples int
+	// Interval is the time between two snapshots
+	Interval time.Duration
+	// Deadline stops the sampling with the snapshots taken so far, if set. No snapshot is taken if the wait for it
+	// would end after the deadline.
+	Deadline time.Time
+}
+
+// SampleTrends takes snapshots of the trends at regular intervals. If a snapshot fails, the snapshots taken so far
+// are returned with the error.
+func SampleTrends(fetch TrendFetcher, opts TrendSampleOptions) ([]*TrendSnapshot, error) {
+	var snapshots []*TrendSnapshot
+	for i := 0; i < opts.Samples; i++ {
+		if i > 0 {
+			if !opts.Deadline.IsZero() && time.Now().Add(opts.Interval).After(opts.Deadline) {
+				logrus.Debugf("Stopping trend sampling after %d of %d snapshots at the deadline", i, opts.Samples)
+				break
+			}
+			time.Sleep(opts.Interval)
+		}
+
+		snapshot, err := fetch()
+		if err != nil {
+			return snapshots, err
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
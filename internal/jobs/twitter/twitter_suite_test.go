@@ -0,0 +1,13 @@
+package twitter
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTwitter(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Twitter Suite")
+}
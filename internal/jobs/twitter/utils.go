@@ -11,6 +11,8 @@ const (
 	minSleepDuration  = 500 * time.Millisecond
 	maxSleepDuration  = 2 * time.Second
 	RateLimitDuration = 15 * time.Minute
+	// SuspensionBackoff is how long an account which failed to log in or was suspended is left out of rotation
+	SuspensionBackoff = time.Hour
 )
 
 var (
@@ -0,0 +1,116 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetCommunityTweets fetches the latest tweets posted to a Twitter Community. It is only available through
+// credentials, and like getbyids it is handled by the TwitterScraper before the arguments are validated against the
+// tee-types capabilities.
+const CapGetCommunityTweets teetypes.Capability = "getcommunitytweets"
+
+// defaultCommunityTweetsMaxResults is the number of tweets returned if max_results is not set
+const defaultCommunityTweetsMaxResults = 20
+
+// TwitterCommunityTweetsArguments are the arguments of a getcommunitytweets job
+type TwitterCommunityTweetsArguments struct {
+	QueryType  string `json:"type"`
+	Query      string `json:"query"` // The ID of the Community
+	MaxResults int    `json:"max_results"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// parseCommunityTweetsArguments unmarshals and validates the arguments of a getcommunitytweets job
+func parseCommunityTweetsArguments(args map[string]any) (*TwitterCommunityTweetsArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getcommunitytweets arguments: %w", err)
+	}
+
+	parsed := &TwitterCommunityTweetsArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getcommunitytweets arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if _, err := strconv.ParseUint(parsed.Query, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid Community ID %q", parsed.Query)
+	}
+
+	if parsed.MaxResults < 0 {
+		return nil, fmt.Errorf("max_results must be non-negative, got: %d", parsed.MaxResults)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultCommunityTweetsMaxResults
+	}
+
+	return parsed, nil
+}
+
+// executeGetCommunityTweets returns a page of the tweets of a Community, with the cursor of the next page
+func (ts *TwitterScraper) executeGetCommunityTweets(j types.Job) (types.JobResult, error) {
+	args, err := parseCommunityTweetsArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetCommunityTweets, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetCommunityTweets, j.Type)
+	}
+
+	tweets, nextCursor, err := ts.GetCommunityTweets(j, ts.configuration.DataDir, args.Query, args.MaxResults, args.NextCursor)
+	return processResponse(tweets, nextCursor, err)
+}
+
+// GetCommunityTweets fetches the latest tweets of a Community with credentials, a page at a time until count tweets
+// have been fetched
+func (ts *TwitterScraper) GetCommunityTweets(j types.Job, baseDir, communityID string, count int, cursor string) ([]*teetypes.TweetResult, string, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, "", err
+	}
+
+	tweets := make([]*teetypes.TweetResult, 0, count)
+	ctx, cancel := jobContext(j)
+	defer cancel()
+
+	for len(tweets) < count {
+		if ctx.Err() != nil {
+			break
+		}
+
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		fetchedTweets, nextCursor, err := scraper.FetchCommunityTweets(communityID, count-len(tweets), cursor)
+		if err != nil {
+			if ts.handleError(j, err, account) && len(tweets) > 0 {
+				logrus.Warnf("Rate limit hit, returning partial results (%d tweets) for Community %s", len(tweets), communityID)
+				break
+			}
+			return nil, "", err
+		}
+
+		for _, tweet := range fetchedTweets {
+			tweets = append(tweets, ts.convertTwitterScraperTweetToTweetResult(*tweet))
+		}
+		// An empty page or an unchanged cursor means the end of the timeline has been reached
+		if len(fetchedTweets) == 0 || nextCursor == "" || nextCursor == cursor {
+			cursor = ""
+			break
+		}
+		cursor = nextCursor
+	}
+
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
+	return tweets, cursor, nil
+}
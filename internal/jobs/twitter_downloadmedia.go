@@ -0,0 +1,187 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/sirupsen/logrus"
+)
+
+// CapDownloadMedia downloads the photos and videos of a tweet, so consumers don't depend on media URLs which expire or
+// are geo-blocked. It is only available through credentials, since the API doesn't return media, and like getbyids it
+// is handled by the TwitterScraper before the arguments are validated against the tee-types capabilities.
+const CapDownloadMedia teetypes.Capability = "downloadmedia"
+
+// ErrMediaTooLarge is returned when downloading media would exceed the configured maximum size
+var ErrMediaTooLarge = errors.New("media exceeds the maximum size")
+
+// TwitterDownloadMediaArguments are the arguments of a downloadmedia job
+type TwitterDownloadMediaArguments struct {
+	QueryType string `json:"type"`
+	Query     string `json:"query"` // The ID of the tweet
+}
+
+// TweetMedia is a photo or video of a tweet together with its content. Data is encoded as base64 in JSON.
+type TweetMedia struct {
+	TweetID     string `json:"tweet_id"`
+	MediaID     string `json:"media_id"`
+	Type        string `json:"type"` // photo or video
+	URL         string `json:"url"`
+	ContentType string `json:"content_type"`
+	Size        int    `json:"size"`
+	Data        []byte `json:"data"`
+}
+
+// parseDownloadMediaArguments unmarshals and validates the arguments of a downloadmedia job
+func parseDownloadMediaArguments(args map[string]any) (*TwitterDownloadMediaArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal downloadmedia arguments: %w", err)
+	}
+
+	parsed := &TwitterDownloadMediaArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal downloadmedia arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if _, err := strconv.ParseUint(parsed.Query, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid tweet ID %q", parsed.Query)
+	}
+
+	return parsed, nil
+}
+
+// executeDownloadMedia fetches a tweet and downloads its media. Media which could not be downloaded is left out of
+// the result and reported in its fan-out.
+func (ts *TwitterScraper) executeDownloadMedia(j types.Job) (types.JobResult, error) {
+	args, err := parseDownloadMediaArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapDownloadMedia, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapDownloadMedia, j.Type)
+	}
+
+	tweet, err := ts.GetTweet(j, ts.configuration.DataDir, args.Query)
+	if err != nil {
+		return processResponse(nil, "", err)
+	}
+
+	media, fanOut := DownloadTweetMedia(j.Network.Client(nil), tweet, ts.configuration.MaxMediaBytes)
+	ts.addStat(j, stats.TwitterOther, uint(len(media)))
+	return processFanOutResponse(media, "", fanOut)
+}
+
+// DownloadTweetMedia downloads the photos and videos of a tweet, photos in their original size and videos in the
+// variant with the highest bitrate. Downloads stop once maxBytes have been downloaded in total; a maxBytes of 0
+// disables the limit.
+func DownloadTweetMedia(client *http.Client, tweet *teetypes.TweetResult, maxBytes int64) ([]TweetMedia, *types.MultiError) {
+	fanOut := &types.MultiError{}
+	media := []TweetMedia{}
+	if tweet == nil {
+		return media, fanOut
+	}
+
+	var assets []TweetMedia
+	for _, p := range tweet.Photos {
+		assets = append(assets, TweetMedia{TweetID: tweet.TweetID, MediaID: p.ID, Type: "photo", URL: originalPhotoURL(p.URL)})
+	}
+	for _, v := range tweet.Videos {
+		assets = append(assets, TweetMedia{TweetID: tweet.TweetID, MediaID: v.ID, Type: "video", URL: v.URL})
+	}
+
+	remaining := maxBytes
+	for _, asset := range assets {
+		if asset.URL == "" {
+			fanOut.Failed(asset.Type, asset.MediaID, errors.New("no downloadable URL"))
+			continue
+		}
+		if maxBytes > 0 && remaining <= 0 {
+			fanOut.Failed(asset.Type, asset.MediaID, ErrMediaTooLarge)
+			continue
+		}
+
+		data, contentType, err := downloadMedia(client, asset.URL, remaining, maxBytes > 0)
+		if err != nil {
+			fanOut.Failed(asset.Type, asset.MediaID, err)
+			continue
+		}
+		remaining -= int64(len(data))
+
+		asset.Data = data
+		asset.Size = len(data)
+		asset.ContentType = contentType
+		media = append(media, asset)
+		fanOut.Succeeded(asset.Type, asset.MediaID, 1)
+	}
+
+	return media, fanOut
+}
+
+// downloadMedia downloads a single asset, failing if it is larger than limit bytes when limited is set
+func downloadMedia(client *http.Client, u string, limit int64, limited bool) ([]byte, string, error) {
+	resp, err := client.Get(u)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading media: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("error downloading media: unexpected status %d", resp.StatusCode)
+	}
+	if limited && resp.ContentLength > limit {
+		return nil, "", fmt.Errorf("%w: %d bytes", ErrMediaTooLarge, resp.ContentLength)
+	}
+
+	var body io.Reader = resp.Body
+	if limited {
+		body = io.LimitReader(resp.Body, limit+1)
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, "", fmt.Errorf("error downloading media: %w", err)
+	}
+	if limited && int64(len(data)) > limit {
+		return nil, "", ErrMediaTooLarge
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = http.DetectContentType(data)
+	}
+	return data, contentType, nil
+}
+
+// originalPhotoURL returns the URL of the original size of a photo hosted on pbs.twimg.com. Tweets link to a resized
+// variant, e.g. https://pbs.twimg.com/media/abc.jpg, while https://pbs.twimg.com/media/abc?format=jpg&name=orig is the
+// photo as it was uploaded.
+func originalPhotoURL(photoURL string) string {
+	u, err := url.Parse(photoURL)
+	if err != nil || u.Host != "pbs.twimg.com" {
+		return photoURL
+	}
+
+	q := u.Query()
+	if ext := path.Ext(u.Path); ext != "" {
+		u.Path = strings.TrimSuffix(u.Path, ext)
+		q.Set("format", strings.TrimPrefix(ext, "."))
+	}
+	q.Set("name", "orig")
+	u.RawQuery = q.Encode()
+	return u.String()
+}
@@ -0,0 +1,240 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	teeargs "github.com/masa-finance/tee-types/args"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetFollowerDelta compares two follower snapshots of an account and returns only the changes between them.
+// Snapshots are stored by the worker, so the full lists of followers never leave it. Like getbyids, it is handled
+// by the TwitterScraper before the arguments are validated against the tee-types capabilities.
+const CapGetFollowerDelta teetypes.Capability = "getfollowerdelta"
+
+// defaultFollowerDeltaMaxResults is the number of followers fetched for a new snapshot if max_results is not set
+const defaultFollowerDeltaMaxResults = 1000
+
+// TwitterFollowerDeltaArguments are the arguments of a getfollowerdelta job
+type TwitterFollowerDeltaArguments struct {
+	QueryType string                   `json:"type"`
+	Query     string                   `json:"query"`
+	Relation  twitter.FollowerRelation `json:"relation"`
+	// MaxResults is the number of followers fetched when a new snapshot is taken
+	MaxResults int `json:"max_results"`
+	// From and To are the IDs of the snapshots to compare. If To is not set, a new snapshot is taken. If From is
+	// not set, the latest snapshot taken before To is used.
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// FollowerDelta is the result of a getfollowerdelta job. Added and Removed are the new followers and the
+// unfollowers, or the newly followed and unfollowed accounts for the following relation.
+type FollowerDelta struct {
+	Account     string                   `json:"account"`
+	Relation    twitter.FollowerRelation `json:"relation"`
+	From        string                   `json:"from,omitempty"`
+	To          string                   `json:"to"`
+	FromTakenAt *time.Time               `json:"from_taken_at,omitempty"`
+	ToTakenAt   time.Time                `json:"to_taken_at"`
+	FromCount   int                      `json:"from_count"`
+	ToCount     int                      `json:"to_count"`
+	Added       []twitter.FollowerMember `json:"added"`
+	Removed     []twitter.FollowerMember `json:"removed"`
+	NetChange   int                      `json:"net_change"`
+	// ChurnRate is the share of the accounts in the From snapshot which are not in the To snapshot
+	ChurnRate float64 `json:"churn_rate"`
+	// PossiblyTruncated is set if either snapshot may not contain all followers, in which case followers beyond
+	// max_results show up as added or removed
+	PossiblyTruncated bool `json:"possibly_truncated"`
+}
+
+// parseFollowerDeltaArguments unmarshals and validates the arguments of a getfollowerdelta job
+func parseFollowerDeltaArguments(args map[string]any) (*TwitterFollowerDeltaArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getfollowerdelta arguments: %w", err)
+	}
+
+	parsed := &TwitterFollowerDeltaArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getfollowerdelta arguments: %w", err)
+	}
+
+	if parsed.Query, err = twitter.NormalizeUsername(parsed.Query); err != nil {
+		return nil, err
+	}
+
+	switch parsed.Relation {
+	case "":
+		parsed.Relation = twitter.RelationFollowers
+	case twitter.RelationFollowers, twitter.RelationFollowing:
+	default:
+		return nil, fmt.Errorf("relation must be %q or %q, got %q", twitter.RelationFollowers, twitter.RelationFollowing, parsed.Relation)
+	}
+
+	if parsed.MaxResults < 0 {
+		return nil, fmt.Errorf("max_results must be non-negative, got: %d", parsed.MaxResults)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultFollowerDeltaMaxResults
+	}
+
+	if parsed.From != "" && parsed.From == parsed.To {
+		return nil, fmt.Errorf("from and to must be different snapshots")
+	}
+
+	return parsed, nil
+}
+
+// ComputeFollowerDelta returns the changes between two snapshots of the same account and relation
+func ComputeFollowerDelta(from, to *twitter.FollowerSnapshot) FollowerDelta {
+	delta := FollowerDelta{
+		Account:           to.Account,
+		Relation:          to.Relation,
+		From:              from.ID,
+		To:                to.ID,
+		FromTakenAt:       &from.TakenAt,
+		ToTakenAt:         to.TakenAt,
+		FromCount:         len(from.Members),
+		ToCount:           len(to.Members),
+		Added:             []twitter.FollowerMember{},
+		Removed:           []twitter.FollowerMember{},
+		NetChange:         len(to.Members) - len(from.Members),
+		PossiblyTruncated: from.PossiblyTruncated() || to.PossiblyTruncated(),
+	}
+
+	inFrom := make(map[string]struct{}, len(from.Members))
+	for _, m := range from.Members {
+		inFrom[m.ID] = struct{}{}
+	}
+	inTo := make(map[string]struct{}, len(to.Members))
+	for _, m := range to.Members {
+		inTo[m.ID] = struct{}{}
+		if _, ok := inFrom[m.ID]; !ok {
+			delta.Added = append(delta.Added, m)
+		}
+	}
+	for _, m := range from.Members {
+		if _, ok := inTo[m.ID]; !ok {
+			delta.Removed = append(delta.Removed, m)
+		}
+	}
+
+	if len(from.Members) > 0 {
+		delta.ChurnRate = float64(len(delta.Removed)) / float64(len(from.Members))
+	}
+	return delta
+}
+
+// executeFollowerDelta compares two follower snapshots of an account, taking and storing a new one unless the
+// job asks for an existing one. The first job for an account only stores the snapshot, and returns a delta
+// without a from snapshot.
+func (ts *TwitterScraper) executeFollowerDelta(j types.Job) (types.JobResult, error) {
+	args, err := parseFollowerDeltaArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	store := twitter.NewSnapshotStore(ts.configuration.DataDir, ts.configuration.MaxFollowerSnapshots)
+
+	var to *twitter.FollowerSnapshot
+	if args.To != "" {
+		to, err = store.Load(args.Query, args.Relation, args.To)
+	} else {
+		to, err = ts.takeFollowerSnapshot(j, args)
+		if err == nil {
+			err = store.Save(to)
+		}
+	}
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	fromID := args.From
+	if fromID == "" {
+		ids, err := store.List(args.Query, args.Relation)
+		if err != nil {
+			return types.JobResult{Error: err.Error()}, err
+		}
+		for _, id := range ids {
+			if id < to.ID {
+				fromID = id
+			}
+		}
+	}
+
+	if fromID == "" {
+		logrus.Infof("Stored the first %s snapshot of %s", args.Relation, args.Query)
+		return processResponse(FollowerDelta{
+			Account:           to.Account,
+			Relation:          to.Relation,
+			To:                to.ID,
+			ToTakenAt:         to.TakenAt,
+			ToCount:           len(to.Members),
+			Added:             []twitter.FollowerMember{},
+			Removed:           []twitter.FollowerMember{},
+			PossiblyTruncated: to.PossiblyTruncated(),
+		}, "", nil)
+	}
+
+	from, err := store.Load(args.Query, args.Relation, fromID)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+	return processResponse(ComputeFollowerDelta(from, to), "", nil)
+}
+
+// takeFollowerSnapshot fetches the followers or followed accounts of the account in the job, using the same
+// auth sources as getfollowers and getfollowing jobs
+func (ts *TwitterScraper) takeFollowerSnapshot(j types.Job, args *TwitterFollowerDeltaArguments) (*twitter.FollowerSnapshot, error) {
+	capability := teetypes.CapGetFollowers
+	if args.Relation == twitter.RelationFollowing {
+		capability = teetypes.CapGetFollowing
+	}
+
+	takenAt := time.Now().UTC()
+	fetchArgs := &teeargs.TwitterSearchArguments{QueryType: string(capability), Query: args.Query, MaxResults: args.MaxResults}
+	res, err := getScrapeStrategy(j.Type).Execute(j, ts, fetchArgs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Profiles fetched with credentials have UserID and Username, profiles fetched through Apify id_str and screen_name
+	var profiles []struct {
+		UserID     string
+		Username   string
+		IDStr      string `json:"id_str"`
+		ScreenName string `json:"screen_name"`
+	}
+	if err := res.Unmarshal(&profiles); err != nil {
+		return nil, fmt.Errorf("error unmarshalling %s: %w", args.Relation, err)
+	}
+
+	snapshot := &twitter.FollowerSnapshot{
+		Account:    args.Query,
+		Relation:   args.Relation,
+		TakenAt:    takenAt,
+		MaxResults: args.MaxResults,
+		Members:    make([]twitter.FollowerMember, 0, len(profiles)),
+	}
+	seen := make(map[string]struct{}, len(profiles))
+	for _, p := range profiles {
+		m := twitter.FollowerMember{ID: p.UserID, Username: p.Username}
+		if m.ID == "" {
+			m = twitter.FollowerMember{ID: p.IDStr, Username: p.ScreenName}
+		}
+		if _, dup := seen[m.ID]; m.ID == "" || dup {
+			continue
+		}
+		seen[m.ID] = struct{}{}
+		snapshot.Members = append(snapshot.Members, m)
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,209 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitterx"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetByIds fetches several tweets by ID in a single job. The maximum number of IDs is configured with TWITTER_MAX_IDS_PER_JOB.
+// It is handled by the TwitterScraper before the arguments are validated against the tee-types capabilities.
+const CapGetByIds teetypes.Capability = "getbyids"
+
+// TwitterGetByIdsArguments are the arguments of a getbyids job
+type TwitterGetByIdsArguments struct {
+	QueryType string   `json:"type"`
+	IDs       []string `json:"ids"`
+}
+
+// isGetByIdsJob returns true if the job requests the getbyids capability
+func isGetByIdsJob(j types.Job) bool {
+	return isCapabilityJob(j, CapGetByIds)
+}
+
+// isCapabilityJob returns true if the job requests the given capability
+func isCapabilityJob(j types.Job, c teetypes.Capability) bool {
+	switch queryType := j.Arguments["type"].(type) {
+	case string:
+		return teetypes.Capability(strings.ToLower(queryType)) == c
+	case teetypes.Capability:
+		return queryType == c
+	default:
+		return false
+	}
+}
+
+// parseGetByIdsArguments unmarshals and validates the arguments of a getbyids job. Duplicate IDs are removed, keeping the order of first appearance.
+func parseGetByIdsArguments(args map[string]any, maxIds int) (*TwitterGetByIdsArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getbyids arguments: %w", err)
+	}
+
+	parsed := &TwitterGetByIdsArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getbyids arguments: %w", err)
+	}
+
+	seen := make(map[string]struct{}, len(parsed.IDs))
+	ids := make([]string, 0, len(parsed.IDs))
+	for _, id := range parsed.IDs {
+		id = strings.TrimSpace(id)
+		if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+			return nil, fmt.Errorf("invalid tweet ID %q", id)
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+
+	if len(ids) == 0 {
+		return nil, fmt.Errorf("ids must contain at least one tweet ID")
+	}
+	if len(ids) > maxIds {
+		return nil, fmt.Errorf("ids must contain at most %d tweet IDs, got %d", maxIds, len(ids))
+	}
+
+	parsed.IDs = ids
+	return parsed, nil
+}
+
+// executeGetByIds fetches all tweets of a getbyids job and returns them as a single array, in the order they were requested.
+// Tweets which could not be retrieved are reported in the fan-out of the result.
+func (ts *TwitterScraper) executeGetByIds(j types.Job) (types.JobResult, error) {
+	args, err := parseGetByIdsArguments(j.Arguments, ts.configuration.MaxIdsPerJob)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	var useApi bool
+	switch j.Type {
+	case teetypes.TwitterCredentialJob:
+		useApi = false
+	case teetypes.TwitterApiJob:
+		useApi = true
+	case teetypes.TwitterJob:
+		useApi = ts.availableAuthSource(j.Type, CapGetByIds, types.AuthSourceCredential, types.AuthSourceAPI) == types.AuthSourceAPI
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetByIds, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetByIds, j.Type)
+	}
+
+	var tweets []*teetypes.TweetResult
+	var fanOut *types.MultiError
+	if useApi {
+		tweets, fanOut = ts.getTweetsByIDsWithApiKey(j, args.IDs)
+	} else {
+		tweets, fanOut = ts.getTweetsByIDsWithCredentials(j, args.IDs)
+	}
+
+	return processFanOutResponse(tweets, "", fanOut)
+}
+
+// getTweetsByIDsWithApiKey fetches the tweets using the bulk lookup endpoint, with one request per batch of twitterx.MaxTweetIDsPerLookup IDs
+func (ts *TwitterScraper) getTweetsByIDsWithApiKey(j types.Job, ids []string) ([]*teetypes.TweetResult, *types.MultiError) {
+	fanOut := &types.MultiError{}
+
+	twitterXScraper, _, err := ts.getApiScraper(j)
+	if err != nil {
+		fanOut.Failed("api", "", err)
+		return nil, fanOut
+	}
+
+	byID := make(map[string]*teetypes.TweetResult, len(ids))
+	for batch := range slices.Chunk(ids, twitterx.MaxTweetIDsPerLookup) {
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		tweetData, lookupErrors, err := twitterXScraper.GetTweetsByIDs(batch)
+		if err != nil {
+			_ = ts.handleError(j, err, nil)
+			for _, id := range batch {
+				fanOut.Failed("api", id, err)
+			}
+			continue
+		}
+
+		for i := range tweetData {
+			tweet, err := convertTwitterXTweetToTweetResult(&tweetData[i])
+			if err != nil {
+				fanOut.Failed("api", tweetData[i].ID, err)
+				continue
+			}
+			byID[tweet.TweetID] = tweet
+		}
+
+		for _, lookupErr := range lookupErrors {
+			fanOut.Failed("api", lookupErr.ResourceID, fmt.Errorf("%s: %s", lookupErr.Title, lookupErr.Detail))
+		}
+		fanOut.Succeeded("api", "", len(tweetData))
+	}
+
+	tweets := make([]*teetypes.TweetResult, 0, len(byID))
+	for _, id := range ids {
+		if tweet, ok := byID[id]; ok {
+			tweets = append(tweets, tweet)
+		}
+	}
+
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
+	return tweets, fanOut
+}
+
+// getTweetsByIDsWithCredentials fetches the tweets one by one with the same account. If the account gets
+// rate limited, the remaining tweets are reported as failed with its error, and if the job times out or is cancelled,
+// with types.ErrJobTimedOut or types.ErrJobCancelled.
+func (ts *TwitterScraper) getTweetsByIDsWithCredentials(j types.Job, ids []string) ([]*teetypes.TweetResult, *types.MultiError) {
+	fanOut := &types.MultiError{}
+
+	scraper, account, err := ts.getCredentialScraper(j, ts.configuration.DataDir)
+	if err != nil {
+		fanOut.Failed("credentials", "", err)
+		return nil, fanOut
+	}
+
+	ctx, cancel := jobContext(j)
+	defer cancel()
+	tweets := make([]*teetypes.TweetResult, 0, len(ids))
+	var abortErr error
+
+	for _, id := range ids {
+		if abortErr == nil {
+			abortErr = context.Cause(ctx)
+		}
+		if abortErr != nil {
+			fanOut.Failed("credentials", id, abortErr)
+			continue
+		}
+
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		scrapedTweet, err := scraper.GetTweet(id)
+		if err == nil && scrapedTweet == nil {
+			err = twitterx.ErrTweetNotFound
+		}
+		if err != nil {
+			if ts.handleError(j, err, account) {
+				abortErr = err
+			}
+			fanOut.Failed("credentials", id, err)
+			continue
+		}
+
+		tweets = append(tweets, ts.convertTwitterScraperTweetToTweetResult(*scrapedTweet))
+	}
+
+	if len(tweets) > 0 {
+		fanOut.Succeeded("credentials", "", len(tweets))
+	}
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
+	return tweets, fanOut
+}
@@ -0,0 +1,178 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitterx"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetPoll returns the options, vote counts and end time of the poll of a tweet. It is available wherever getbyid
+// is available through credentials or API keys, and like getbyids it is handled by the TwitterScraper before the
+// arguments are validated against the tee-types capabilities.
+const CapGetPoll teetypes.Capability = "getpoll"
+
+// ErrNoPoll is returned by getpoll jobs for tweets without a poll
+var ErrNoPoll = errors.New("tweet has no poll")
+
+// TwitterGetPollArguments are the arguments of a getpoll job
+type TwitterGetPollArguments struct {
+	QueryType string `json:"type"`
+	Query     string `json:"query"`
+}
+
+// PollResult is the result of a getpoll job
+type PollResult struct {
+	TweetID    string       `json:"tweet_id"`
+	Options    []PollOption `json:"options"`
+	TotalVotes int          `json:"total_votes"`
+	EndTime    time.Time    `json:"end_time"`
+	// DurationMinutes is how long the poll is open for, if known
+	DurationMinutes int `json:"duration_minutes,omitempty"`
+	// Closed is set once voting has ended, after which the counts are final
+	Closed bool `json:"closed"`
+}
+
+// PollOption is an option of a poll, numbered from 1 in the order it is shown
+type PollOption struct {
+	Position int    `json:"position"`
+	Label    string `json:"label"`
+	Votes    int    `json:"votes"`
+}
+
+// parseGetPollArguments unmarshals and validates the arguments of a getpoll job
+func parseGetPollArguments(args map[string]any) (*TwitterGetPollArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getpoll arguments: %w", err)
+	}
+
+	parsed := &TwitterGetPollArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getpoll arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if _, err := strconv.ParseUint(parsed.Query, 10, 64); err != nil {
+		return nil, fmt.Errorf("query must be a tweet ID, got %q", parsed.Query)
+	}
+
+	return parsed, nil
+}
+
+// executeGetPoll returns the poll of the tweet of the job
+func (ts *TwitterScraper) executeGetPoll(j types.Job) (types.JobResult, error) {
+	args, err := parseGetPollArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	var useApi bool
+	switch j.Type {
+	case teetypes.TwitterCredentialJob:
+		useApi = false
+	case teetypes.TwitterApiJob:
+		useApi = true
+	case teetypes.TwitterJob:
+		useApi = ts.authSourcesFor(j.Type, CapGetPoll)[0] == types.AuthSourceAPI
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetPoll, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetPoll, j.Type)
+	}
+
+	var poll *PollResult
+	if useApi {
+		poll, err = ts.GetPollWithApiKey(j, args.Query)
+	} else {
+		poll, err = ts.GetPoll(j, ts.configuration.DataDir, args.Query)
+	}
+	return processResponse(poll, "", err)
+}
+
+// GetPoll fetches the poll of a tweet with credentials, by parsing the card the poll is attached to the tweet with
+func (ts *TwitterScraper) GetPoll(j types.Job, baseDir, tweetID string) (*PollResult, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterScrapes, 1)
+	card, err := scraper.FetchTweetCard(tweetID)
+	if err != nil {
+		_ = ts.handleError(j, err, account)
+		return nil, err
+	}
+	if card == nil || !card.IsPoll() {
+		return nil, ErrNoPoll
+	}
+
+	poll, err := card.Poll()
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterOther, 1)
+	return pollResultFromCard(tweetID, poll), nil
+}
+
+// GetPollWithApiKey fetches the poll of a tweet using the poll fields of the TwitterX API
+func (ts *TwitterScraper) GetPollWithApiKey(j types.Job, tweetID string) (*PollResult, error) {
+	twitterXScraper, _, err := ts.getApiScraper(j)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterScrapes, 1)
+	poll, err := twitterXScraper.GetPoll(tweetID)
+	if err != nil {
+		_ = ts.handleError(j, err, nil)
+		return nil, err
+	}
+	if poll == nil {
+		return nil, ErrNoPoll
+	}
+
+	ts.addStat(j, stats.TwitterOther, 1)
+	return pollResultFromTwitterX(tweetID, poll), nil
+}
+
+// pollResultFromCard converts a poll parsed from the card of a tweet to a PollResult
+func pollResultFromCard(tweetID string, poll *twitter.Poll) *PollResult {
+	result := &PollResult{
+		TweetID:         tweetID,
+		Options:         make([]PollOption, 0, len(poll.Choices)),
+		EndTime:         poll.EndTime,
+		DurationMinutes: int(poll.Duration / time.Minute),
+		Closed:          poll.Final || (!poll.EndTime.IsZero() && time.Now().After(poll.EndTime)),
+	}
+	for i, choice := range poll.Choices {
+		result.Options = append(result.Options, PollOption{Position: i + 1, Label: choice.Label, Votes: choice.Count})
+		result.TotalVotes += choice.Count
+	}
+	return result
+}
+
+// pollResultFromTwitterX converts a poll returned by the TwitterX API to a PollResult
+func pollResultFromTwitterX(tweetID string, poll *twitterx.TwitterXPoll) *PollResult {
+	result := &PollResult{
+		TweetID:         tweetID,
+		Options:         make([]PollOption, 0, len(poll.Options)),
+		EndTime:         poll.EndDatetime,
+		DurationMinutes: poll.DurationMinutes,
+		Closed:          poll.VotingStatus == "closed",
+	}
+	for _, option := range poll.Options {
+		result.Options = append(result.Options, PollOption{Position: option.Position, Label: option.Label, Votes: option.Votes})
+		result.TotalVotes += option.Votes
+	}
+	return result
+}
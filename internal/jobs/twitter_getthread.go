@@ -0,0 +1,245 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetThread reconstructs the conversation thread of a tweet: the self-thread of its root and the key replies, with
+// their parent/child relations. It is available wherever getreplies is available, i.e. through credentials, and like
+// getbyids it is handled by the TwitterScraper before the arguments are validated against the tee-types capabilities.
+const CapGetThread teetypes.Capability = "getthread"
+
+const (
+	// defaultThreadMaxReplies is the number of replies kept per tweet if max_replies is not set
+	defaultThreadMaxReplies = 5
+	// maxThreadPages is the largest number of conversation pages fetched by a getthread job
+	maxThreadPages = 20
+	// maxThreadRootPages is the largest number of pages of the conversation of the root fetched by a getthread job
+	maxThreadRootPages = 5
+)
+
+// TwitterGetThreadArguments are the arguments of a getthread job
+type TwitterGetThreadArguments struct {
+	QueryType string `json:"type"`
+	Query     string `json:"query"` // The ID of any tweet of the conversation
+	// MaxDepth is the number of levels of replies below the self-thread, at most TWITTER_THREAD_MAX_DEPTH
+	MaxDepth *int `json:"max_depth"`
+	// MaxReplies is the number of replies kept per tweet, the most liked first
+	MaxReplies int `json:"max_replies"`
+}
+
+// ThreadResult is the result of a getthread job
+type ThreadResult struct {
+	RootID  string `json:"root_id"`
+	FocalID string `json:"focal_id"`
+	// Tweets are the self-thread in order, followed depth first by the replies to each of its tweets
+	Tweets []ThreadTweet `json:"tweets"`
+}
+
+// ThreadTweet is a tweet of a thread with its place in the thread
+type ThreadTweet struct {
+	Position int    `json:"position"`
+	ParentID string `json:"parent_id,omitempty"`
+	// ChildIDs are the IDs of the tweets of the thread replying to this one, the self-thread continuation first
+	ChildIDs []string `json:"child_ids,omitempty"`
+	// Depth is 0 for the tweets of the self-thread, and increases by one for every level of replies below them
+	Depth      int                   `json:"depth"`
+	SelfThread bool                  `json:"self_thread"`
+	Tweet      *teetypes.TweetResult `json:"tweet"`
+}
+
+// parseGetThreadArguments unmarshals and validates the arguments of a getthread job
+func parseGetThreadArguments(args map[string]any, maxDepth int) (*TwitterGetThreadArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getthread arguments: %w", err)
+	}
+
+	parsed := &TwitterGetThreadArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getthread arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if _, err := strconv.ParseUint(parsed.Query, 10, 64); err != nil {
+		return nil, fmt.Errorf("query must be a tweet ID, got %q", parsed.Query)
+	}
+
+	if parsed.MaxDepth == nil {
+		parsed.MaxDepth = &maxDepth
+	}
+	if *parsed.MaxDepth < 0 || *parsed.MaxDepth > maxDepth {
+		return nil, fmt.Errorf("max_depth must be between 0 and %d, got %d", maxDepth, *parsed.MaxDepth)
+	}
+
+	if parsed.MaxReplies < 0 {
+		return nil, fmt.Errorf("max_replies must be non-negative, got: %d", parsed.MaxReplies)
+	}
+	if parsed.MaxReplies == 0 {
+		parsed.MaxReplies = defaultThreadMaxReplies
+	}
+
+	return parsed, nil
+}
+
+// executeGetThread returns the thread of the conversation of the tweet of the job
+func (ts *TwitterScraper) executeGetThread(j types.Job) (types.JobResult, error) {
+	args, err := parseGetThreadArguments(j.Arguments, ts.configuration.MaxThreadDepth)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetThread, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetThread, j.Type)
+	}
+
+	thread, err := ts.GetThread(j, ts.configuration.DataDir, args.Query, *args.MaxDepth, args.MaxReplies)
+	return processResponse(thread, "", err)
+}
+
+// GetThread reconstructs the thread of the conversation of a tweet with credentials. It fetches the conversation from
+// its root, following the cursors of the conversation until the self-thread is complete, and then the replies of the
+// key replies level by level down to maxDepth, within the timeout of the job.
+func (ts *TwitterScraper) GetThread(j types.Job, baseDir, tweetID string, maxDepth, maxReplies int) (*ThreadResult, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tweets []*twitterscraper.Tweet
+	byID := make(map[string]*twitterscraper.Tweet)
+	pages := 0
+	ctx, cancel := jobContext(j)
+	defer cancel()
+
+	// canFetch returns false once the page budget is exhausted, or the job has timed out or has been cancelled
+	canFetch := func() bool {
+		return pages < maxThreadPages && ctx.Err() == nil
+	}
+
+	// fetch adds a page of the conversation around the given tweet, and returns the number of new tweets and the
+	// cursor of the next page, if any
+	fetched := make(map[string]bool)
+	fetch := func(id, cursor string) (int, string, error) {
+		pages++
+		fetched[id] = true
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		fetched, cursors, err := scraper.GetTweetReplies(id, cursor)
+		if err != nil {
+			return 0, "", err
+		}
+
+		added := 0
+		for _, t := range fetched {
+			if _, ok := byID[t.ID]; !ok {
+				byID[t.ID] = t
+				tweets = append(tweets, t)
+				added++
+			}
+		}
+		for _, c := range cursors {
+			if c.CursorType == "Bottom" && c.Cursor != cursor {
+				return added, c.Cursor, nil
+			}
+		}
+		return added, "", nil
+	}
+
+	// A rate limit after the first page returns the thread fetched so far
+	partial := func(err error) bool {
+		if ts.handleError(j, err, account) && len(tweets) > 0 {
+			logrus.Warnf("Rate limit hit, returning partial thread (%d tweets) for tweet %s", len(tweets), tweetID)
+			return true
+		}
+		return false
+	}
+
+	_, cursor, err := fetch(tweetID, "")
+	if err != nil {
+		_ = ts.handleError(j, err, account)
+		return nil, err
+	}
+	focal, ok := byID[tweetID]
+	if !ok {
+		return nil, fmt.Errorf("tweet %s not found", tweetID)
+	}
+
+	// Fetch the conversation from its root, so the whole self-thread is included, and follow its cursor for a few
+	// pages while they add tweets
+	rootID := focal.ConversationID
+	if rootID != "" && rootID != tweetID {
+		_, cursor, err = fetch(rootID, "")
+	} else {
+		rootID = tweetID
+	}
+	for page := 1; err == nil && cursor != "" && page < maxThreadRootPages && canFetch(); page++ {
+		var added int
+		if added, cursor, err = fetch(rootID, cursor); added == 0 {
+			break
+		}
+	}
+	if err != nil && !partial(err) {
+		return nil, err
+	}
+	if _, ok := byID[rootID]; !ok {
+		// The root was deleted or is not visible, so the thread starts at the tweet of the job
+		rootID = tweetID
+	}
+
+	// Fetch the replies of the tweets of the thread which are missing some of their key replies, a level at a time,
+	// starting with the self-thread
+	for depth := 0; depth < maxDepth && err == nil; depth++ {
+		nodes := twitter.BuildThread(rootID, tweets, depth+1, maxReplies)
+		selfThread := make(map[string]bool)
+		for _, node := range nodes {
+			selfThread[node.Tweet.ID] = node.SelfThread
+		}
+		for _, node := range nodes {
+			// The self-thread continuation is not one of the replies
+			replies := 0
+			for _, id := range node.ChildIDs {
+				if !selfThread[id] {
+					replies++
+				}
+			}
+			if node.Depth != depth || fetched[node.Tweet.ID] || replies >= min(maxReplies, node.Tweet.Replies) || !canFetch() {
+				continue
+			}
+			if _, _, err = fetch(node.Tweet.ID, ""); err != nil {
+				break
+			}
+		}
+		if err != nil && !partial(err) {
+			return nil, err
+		}
+	}
+
+	nodes := twitter.BuildThread(rootID, tweets, maxDepth, maxReplies)
+	result := &ThreadResult{RootID: rootID, FocalID: tweetID, Tweets: make([]ThreadTweet, 0, len(nodes))}
+	for i, node := range nodes {
+		result.Tweets = append(result.Tweets, ThreadTweet{
+			Position:   i + 1,
+			ParentID:   node.ParentID,
+			ChildIDs:   node.ChildIDs,
+			Depth:      node.Depth,
+			SelfThread: node.SelfThread,
+			Tweet:      ts.convertTwitterScraperTweetToTweetResult(*node.Tweet),
+		})
+	}
+
+	ts.addStat(j, stats.TwitterTweets, uint(len(result.Tweets)))
+	return result, nil
+}
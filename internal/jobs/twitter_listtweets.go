@@ -0,0 +1,119 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetListTweets fetches the timeline of a Twitter List, i.e. the latest tweets of its members. It is only available
+// through credentials, and like getbyids it is handled by the TwitterScraper before the arguments are validated
+// against the tee-types capabilities.
+const CapGetListTweets teetypes.Capability = "getlisttweets"
+
+// defaultListTweetsMaxResults is the number of tweets returned if max_results is not set
+const defaultListTweetsMaxResults = 20
+
+// TwitterListTweetsArguments are the arguments of a getlisttweets job
+type TwitterListTweetsArguments struct {
+	QueryType  string `json:"type"`
+	Query      string `json:"query"` // The ID of the List
+	MaxResults int    `json:"max_results"`
+	NextCursor string `json:"next_cursor"`
+}
+
+// parseListTweetsArguments unmarshals and validates the arguments of a getlisttweets job
+func parseListTweetsArguments(args map[string]any) (*TwitterListTweetsArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal getlisttweets arguments: %w", err)
+	}
+
+	parsed := &TwitterListTweetsArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal getlisttweets arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if _, err := strconv.ParseUint(parsed.Query, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid List ID %q", parsed.Query)
+	}
+
+	if parsed.MaxResults < 0 {
+		return nil, fmt.Errorf("max_results must be non-negative, got: %d", parsed.MaxResults)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultListTweetsMaxResults
+	}
+
+	return parsed, nil
+}
+
+// executeGetListTweets returns a page of the timeline of a List, with the cursor of the next page
+func (ts *TwitterScraper) executeGetListTweets(j types.Job) (types.JobResult, error) {
+	args, err := parseListTweetsArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetListTweets, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetListTweets, j.Type)
+	}
+
+	tweets, nextCursor, err := ts.GetListTweets(j, ts.configuration.DataDir, args.Query, args.MaxResults, args.NextCursor)
+	return processResponse(tweets, nextCursor, err)
+}
+
+// GetListTweets fetches the timeline of a List with credentials, a page at a time until count tweets have been
+// fetched. The scraper has no List timeline endpoint, so the timeline is read through the list: search operator,
+// which returns the latest tweets of the members of the List.
+func (ts *TwitterScraper) GetListTweets(j types.Job, baseDir, listID string, count int, cursor string) ([]*teetypes.TweetResult, string, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, "", err
+	}
+	scraper.SetSearchMode(twitterscraper.SearchLatest)
+
+	tweets := make([]*teetypes.TweetResult, 0, count)
+	ctx, cancel := jobContext(j)
+	defer cancel()
+
+	for len(tweets) < count {
+		if ctx.Err() != nil {
+			break
+		}
+
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		fetchedTweets, nextCursor, err := scraper.FetchSearchTweets("list:"+listID, count-len(tweets), cursor)
+		if err != nil {
+			if ts.handleError(j, err, account) && len(tweets) > 0 {
+				logrus.Warnf("Rate limit hit, returning partial results (%d tweets) for List %s", len(tweets), listID)
+				break
+			}
+			return nil, "", err
+		}
+
+		for _, tweet := range fetchedTweets {
+			tweets = append(tweets, ts.convertTwitterScraperTweetToTweetResult(*tweet))
+		}
+		// An empty page or an unchanged cursor means the end of the timeline has been reached
+		if len(fetchedTweets) == 0 || nextCursor == "" || nextCursor == cursor {
+			cursor = ""
+			break
+		}
+		cursor = nextCursor
+	}
+
+	ts.addStat(j, stats.TwitterTweets, uint(len(tweets)))
+	return tweets, cursor, nil
+}
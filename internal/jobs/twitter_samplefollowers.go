@@ -0,0 +1,135 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+// CapSampleFollowers returns a random sample of the followers of an account, with the size of the population it was
+// drawn from and its margin of error, reading a bounded number of pages instead of all the followers. It needs
+// credentials, since only their follower cursors can be skipped ahead.
+const CapSampleFollowers teetypes.Capability = "samplefollowers"
+
+// Defaults and limits of the arguments of samplefollowers jobs
+const (
+	defaultSampleFollowersSize  = 100
+	maxSampleFollowersSize      = 1000
+	defaultSampleFollowersPages = 10
+	maxSampleFollowersPages     = 100
+)
+
+// TwitterSampleFollowersArguments are the arguments of a samplefollowers job
+type TwitterSampleFollowersArguments struct {
+	QueryType  string `json:"type"`
+	Query      string `json:"query"`
+	SampleSize int    `json:"sample_size"`
+	// MaxPages is the number of pages of followers which may be requested, which bounds the cost of the job
+	MaxPages int `json:"max_pages"`
+	// Seed makes the sample reproducible for the same pages of followers, if set
+	Seed *uint64 `json:"seed,omitempty"`
+}
+
+// parseSampleFollowersArguments unmarshals and validates the arguments of a samplefollowers job
+func parseSampleFollowersArguments(args map[string]any) (*TwitterSampleFollowersArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal samplefollowers arguments: %w", err)
+	}
+
+	parsed := &TwitterSampleFollowersArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal samplefollowers arguments: %w", err)
+	}
+
+	if parsed.Query, err = twitter.NormalizeUsername(parsed.Query); err != nil {
+		return nil, err
+	}
+
+	if parsed.SampleSize < 0 || parsed.SampleSize > maxSampleFollowersSize {
+		return nil, fmt.Errorf("sample_size must be between 0 and %d, got: %d", maxSampleFollowersSize, parsed.SampleSize)
+	}
+	if parsed.SampleSize == 0 {
+		parsed.SampleSize = defaultSampleFollowersSize
+	}
+
+	if parsed.MaxPages < 0 || parsed.MaxPages > maxSampleFollowersPages {
+		return nil, fmt.Errorf("max_pages must be between 0 and %d, got: %d", maxSampleFollowersPages, parsed.MaxPages)
+	}
+	if parsed.MaxPages == 0 {
+		parsed.MaxPages = defaultSampleFollowersPages
+	}
+
+	return parsed, nil
+}
+
+// executeSampleFollowers samples the followers of the account in the job with credentials
+func (ts *TwitterScraper) executeSampleFollowers(j types.Job) (types.JobResult, error) {
+	args, err := parseSampleFollowersArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapSampleFollowers, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapSampleFollowers, j.Type)
+	}
+
+	sample, err := ts.SampleFollowers(j, ts.configuration.DataDir, args)
+	return processResponse(sample, "", err)
+}
+
+// SampleFollowers samples the followers of an account with credentials. The number of followers in its profile is
+// the population the sample is drawn from, and tells how many pages have to be skipped.
+func (ts *TwitterScraper) SampleFollowers(j types.Job, baseDir string, args *TwitterSampleFollowersArguments) (*twitter.FollowerSample, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterScrapes, 1)
+	profile, err := scraper.GetProfile(args.Query)
+	if err != nil {
+		_ = ts.handleError(j, err, account)
+		return nil, err
+	}
+	ts.addStat(j, stats.TwitterProfiles, 1)
+
+	opts := twitter.SampleOptions{
+		SampleSize: args.SampleSize,
+		MaxPages:   args.MaxPages,
+		Population: profile.FollowersCount,
+	}
+	if j.Timeout > 0 {
+		opts.Deadline = time.Now().Add(j.Timeout)
+	}
+	if args.Seed != nil {
+		opts.Rand = rand.New(rand.NewPCG(*args.Seed, 0))
+	}
+
+	sample, err := twitter.SampleFollowers(func(cursor string) ([]*twitterscraper.Profile, string, error) {
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		followers, next, err := scraper.FetchFollowersByUserID(profile.UserID, twitter.FollowerPageSize, cursor)
+		if err != nil {
+			_ = ts.handleError(j, err, account)
+			return nil, "", err
+		}
+		ts.addStat(j, stats.TwitterProfiles, uint(len(followers)))
+		return followers, next, nil
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	sample.Account = args.Query
+	return sample, nil
+}
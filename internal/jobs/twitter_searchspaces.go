@@ -0,0 +1,144 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+// CapSearchSpaces finds live and recorded Spaces by keyword, so that getspace can be used without knowing the ID of
+// a Space in advance. It is only available through credentials, and like getbyids it is handled by the
+// TwitterScraper before the arguments are validated against the tee-types capabilities.
+const CapSearchSpaces teetypes.Capability = "searchspaces"
+
+// defaultSearchSpacesMaxResults is the number of Spaces returned if max_results is not set
+const defaultSearchSpacesMaxResults = 10
+
+// maxSearchSpacesPages limits the number of search pages read for a single job, since most tweets matching the
+// query may link the same few Spaces
+const maxSearchSpacesPages = 5
+
+// TwitterSearchSpacesArguments are the arguments of a searchspaces job
+type TwitterSearchSpacesArguments struct {
+	QueryType  string `json:"type"`
+	Query      string `json:"query"`
+	MaxResults int    `json:"max_results"`
+}
+
+// parseSearchSpacesArguments unmarshals and validates the arguments of a searchspaces job
+func parseSearchSpacesArguments(args map[string]any) (*TwitterSearchSpacesArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal searchspaces arguments: %w", err)
+	}
+
+	parsed := &TwitterSearchSpacesArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal searchspaces arguments: %w", err)
+	}
+
+	parsed.Query = strings.TrimSpace(parsed.Query)
+	if parsed.Query == "" {
+		return nil, fmt.Errorf("query is required")
+	}
+
+	if parsed.MaxResults < 0 {
+		return nil, fmt.Errorf("max_results must be non-negative, got: %d", parsed.MaxResults)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultSearchSpacesMaxResults
+	}
+
+	return parsed, nil
+}
+
+// executeSearchSpaces returns the Spaces matching the query of the job
+func (ts *TwitterScraper) executeSearchSpaces(j types.Job) (types.JobResult, error) {
+	args, err := parseSearchSpacesArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapSearchSpaces, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapSearchSpaces, j.Type)
+	}
+
+	spaces, err := ts.SearchSpaces(j, ts.configuration.DataDir, args.Query, args.MaxResults)
+	return processResponse(spaces, "", err)
+}
+
+// SearchSpaces finds Spaces matching a query with credentials. The scraper has no Space search endpoint, so the
+// latest tweets matching the query which link a Space are searched with the filter:spaces operator, and the details
+// of each linked Space are then fetched like getspace does.
+func (ts *TwitterScraper) SearchSpaces(j types.Job, baseDir, query string, count int) ([]*twitterscraper.Space, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, err
+	}
+	scraper.SetSearchMode(twitterscraper.SearchLatest)
+
+	var ids []string
+	seen := make(map[string]struct{})
+	cursor := ""
+	ctx, cancel := jobContext(j)
+	defer cancel()
+
+	for page := 0; page < maxSearchSpacesPages && len(ids) < count; page++ {
+		if ctx.Err() != nil {
+			break
+		}
+
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		tweets, nextCursor, err := scraper.FetchSearchTweets(query+" filter:spaces", 50, cursor)
+		if err != nil {
+			if ts.handleError(j, err, account) && len(ids) > 0 {
+				logrus.Warnf("Rate limit hit, returning partial results (%d Spaces) for query %q", len(ids), query)
+				break
+			}
+			return nil, err
+		}
+
+		for _, tweet := range tweets {
+			for _, link := range tweet.URLs {
+				id, ok := twitter.ParseSpaceID(link)
+				if _, dup := seen[id]; !ok || dup {
+					continue
+				}
+				seen[id] = struct{}{}
+				ids = append(ids, id)
+			}
+		}
+		if len(tweets) == 0 || nextCursor == "" || nextCursor == cursor {
+			break
+		}
+		cursor = nextCursor
+	}
+
+	spaces := make([]*twitterscraper.Space, 0, min(len(ids), count))
+	for _, id := range ids[:min(len(ids), count)] {
+		ts.addStat(j, stats.TwitterScrapes, 1)
+		space, err := scraper.GetSpace(id)
+		if err != nil {
+			if ts.handleError(j, err, account) {
+				break
+			}
+			// The Space may have been deleted since it was linked
+			logrus.Debugf("Skipping Space %s: %v", id, err)
+			continue
+		}
+		spaces = append(spaces, space)
+	}
+
+	ts.addStat(j, stats.TwitterOther, uint(len(spaces)))
+	return spaces, nil
+}
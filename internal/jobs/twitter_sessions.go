@@ -0,0 +1,78 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+)
+
+// ExportSessions returns the sealed sessions of the Twitter accounts which have logged in, or types.ErrNotConfigured
+// if no accounts are configured
+func (ts *TwitterScraper) ExportSessions() (types.TwitterSessions, error) {
+	accounts := ts.accountManager.GetAccounts()
+	if len(accounts) == 0 {
+		return types.TwitterSessions{}, fmt.Errorf("no Twitter accounts: %w", types.ErrNotConfigured)
+	}
+
+	sealed, usernames, err := twitter.ExportSessions(accounts, ts.configuration.DataDir)
+	if err != nil {
+		return types.TwitterSessions{}, err
+	}
+	return types.TwitterSessions{Sessions: sealed, Accounts: usernames}, nil
+}
+
+// ImportSessions replaces the sessions of the Twitter accounts with the exported ones. They are used from the next
+// job on, since every job loads the session of its account.
+func (ts *TwitterScraper) ImportSessions(sessions types.TwitterSessions) (types.TwitterSessionsImport, error) {
+	accounts := ts.accountManager.GetAccounts()
+	if len(accounts) == 0 {
+		return types.TwitterSessionsImport{}, fmt.Errorf("no Twitter accounts: %w", types.ErrNotConfigured)
+	}
+
+	imported, skipped, err := twitter.ImportSessions(accounts, ts.configuration.DataDir, sessions.Sessions)
+	if err != nil {
+		return types.TwitterSessionsImport{}, err
+	}
+	return types.TwitterSessionsImport{Imported: imported, Skipped: skipped}, nil
+}
+
+// CheckTwitterAccount verifies the session of a Twitter account, see twitter.CheckAccount. It is a variable so that
+// tests can replace it.
+var CheckTwitterAccount = twitter.CheckAccount
+
+// CheckAccounts verifies the session of every Twitter account one after the other, refreshing their cookies, and
+// returns the status of each. Accounts which are rate limited are not checked. Accounts which are locked or need to
+// pass a challenge are set aside like after a failed login, and accounts which were set aside but work again are put
+// back into rotation right away.
+func (ts *TwitterScraper) CheckAccounts() (types.TwitterAuthCheck, error) {
+	accounts := ts.accountManager.GetAccounts()
+	if len(accounts) == 0 {
+		return types.TwitterAuthCheck{}, fmt.Errorf("no Twitter accounts: %w", types.ErrNotConfigured)
+	}
+
+	check := types.TwitterAuthCheck{CheckedAt: time.Now(), Accounts: make([]types.TwitterAccountStatus, 0, len(accounts))}
+	for _, account := range accounts {
+		if until := ts.accountManager.RateLimitedUntil(account); until.After(time.Now()) {
+			check.Accounts = append(check.Accounts, types.TwitterAccountStatus{Username: account.Username, Status: types.TwitterAccountRateLimited, UnavailableUntil: &until})
+			continue
+		}
+
+		status := CheckTwitterAccount(account, ts.configuration.DataDir)
+		switch status.Status {
+		case types.TwitterAccountOK:
+			ts.accountManager.MarkAccountWorking(account)
+		case types.TwitterAccountRateLimited:
+			ts.accountManager.MarkAccountRateLimited(account)
+			until := ts.accountManager.RateLimitedUntil(account)
+			status.UnavailableUntil = &until
+		default:
+			ts.accountManager.MarkAccountSuspended(account)
+			until := ts.accountManager.SuspendedUntil(account)
+			status.UnavailableUntil = &until
+		}
+		check.Accounts = append(check.Accounts, status)
+	}
+	return check, nil
+}
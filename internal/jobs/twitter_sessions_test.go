@@ -0,0 +1,157 @@
+package jobs_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Twitter sessions", func() {
+	var (
+		sourceDir string
+		targetDir string
+		source    *TwitterScraper
+		target    *TwitterScraper
+	)
+
+	writeCookies := func(dir, username string, cookies []*http.Cookie) {
+		data, err := json.Marshal(cookies)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tee.WriteSecretFile(filepath.Join(dir, username+"_twitter_cookies.json"), "twitter-cookies:"+username, data)).To(Succeed())
+	}
+
+	readCookies := func(dir, username string) []*http.Cookie {
+		data, legacy, err := tee.ReadSecretFile(filepath.Join(dir, username+"_twitter_cookies.json"), "twitter-cookies:"+username)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(legacy).To(BeFalse())
+		var cookies []*http.Cookie
+		Expect(json.Unmarshal(data, &cookies)).To(Succeed())
+		return cookies
+	}
+
+	loggedIn := []*http.Cookie{{Name: "auth_token", Value: "token"}, {Name: "ct0", Value: "csrf"}}
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+
+		sourceDir = GinkgoT().TempDir()
+		targetDir = GinkgoT().TempDir()
+		source = NewTwitterScraper(config.JobConfiguration{
+			"twitter_accounts": []string{"alice:secret", "bob:secret", "carol:secret", "dave:secret"},
+			"data_dir":         sourceDir,
+		}, nil)
+		target = NewTwitterScraper(config.JobConfiguration{
+			"twitter_accounts": []string{"alice:secret", "carol:secret", "erin:secret"},
+			"data_dir":         targetDir,
+		}, nil)
+	})
+
+	It("moves the sessions of the accounts configured on both workers", func() {
+		writeCookies(sourceDir, "alice", loggedIn)
+		writeCookies(sourceDir, "bob", loggedIn)
+		writeCookies(sourceDir, "carol", []*http.Cookie{{Name: "guest_id", Value: "guest"}})
+
+		sessions, err := source.ExportSessions()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sessions.Accounts).To(Equal([]string{"alice", "bob", "carol"}))
+		Expect(sessions.Sessions).NotTo(ContainSubstring("token"))
+
+		imported, err := target.ImportSessions(sessions)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(imported.Imported).To(Equal([]string{"alice"}))
+		Expect(imported.Skipped).To(Equal([]string{"bob", "carol"}))
+
+		cookies := readCookies(targetDir, "alice")
+		Expect(cookies).To(HaveLen(2))
+		Expect(cookies[0].Value).To(Equal("token"))
+		Expect(filepath.Join(targetDir, "carol_twitter_cookies.json")).NotTo(BeAnExistingFile())
+	})
+
+	It("rejects sessions sealed with another key", func() {
+		writeCookies(sourceDir, "alice", loggedIn)
+		sessions, err := source.ExportSessions()
+		Expect(err).NotTo(HaveOccurred())
+
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("abcdef0123456789abcdef0123456789")
+		_, err = target.ImportSessions(sessions)
+		Expect(errors.Is(err, types.ErrInvalidSessions)).To(BeTrue())
+
+		_, err = target.ImportSessions(types.TwitterSessions{Sessions: "not sealed"})
+		Expect(errors.Is(err, types.ErrInvalidSessions)).To(BeTrue())
+	})
+
+	It("requires Twitter accounts", func() {
+		scraper := NewTwitterScraper(config.JobConfiguration{"data_dir": sourceDir}, nil)
+		_, err := scraper.ExportSessions()
+		Expect(errors.Is(err, types.ErrNotConfigured)).To(BeTrue())
+	})
+
+	Context("when checking the accounts", func() {
+		var statuses map[string]string
+
+		BeforeEach(func() {
+			statuses = map[string]string{}
+			original := CheckTwitterAccount
+			DeferCleanup(func() { CheckTwitterAccount = original })
+			CheckTwitterAccount = func(account *twitter.TwitterAccount, baseDir string) types.TwitterAccountStatus {
+				Expect(baseDir).To(Equal(sourceDir))
+				return types.TwitterAccountStatus{Username: account.Username, Status: statuses[account.Username]}
+			}
+		})
+
+		It("reports the status of every account and sets aside the dead ones", func() {
+			statuses["alice"] = types.TwitterAccountOK
+			statuses["bob"] = types.TwitterAccountChallengeRequired
+			statuses["carol"] = types.TwitterAccountLocked
+			statuses["dave"] = types.TwitterAccountRateLimited
+
+			check, err := source.CheckAccounts()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Accounts).To(HaveLen(4))
+			for i, username := range []string{"alice", "bob", "carol", "dave"} {
+				Expect(check.Accounts[i].Username).To(Equal(username))
+				Expect(check.Accounts[i].Status).To(Equal(statuses[username]))
+			}
+			Expect(check.Accounts[0].UnavailableUntil).To(BeNil())
+			Expect(*check.Accounts[1].UnavailableUntil).To(BeTemporally(">", check.CheckedAt))
+			Expect(*check.Accounts[3].UnavailableUntil).To(BeTemporally(">", check.CheckedAt))
+			Expect(source.GetStructuredCapabilities()).To(HaveKey(teetypes.TwitterCredentialJob))
+
+			// Rate limited accounts are not checked again until their rate limit ends
+			statuses["alice"] = types.TwitterAccountLocked
+			statuses["dave"] = types.TwitterAccountOK
+			check, err = source.CheckAccounts()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(check.Accounts[3].Status).To(Equal(types.TwitterAccountRateLimited))
+			Expect(source.GetStructuredCapabilities()).NotTo(HaveKey(teetypes.TwitterCredentialJob))
+
+			// Accounts which work again are put back into rotation
+			statuses["bob"] = types.TwitterAccountOK
+			_, err = source.CheckAccounts()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(source.GetStructuredCapabilities()).To(HaveKey(teetypes.TwitterCredentialJob))
+		})
+
+		It("requires Twitter accounts", func() {
+			scraper := NewTwitterScraper(config.JobConfiguration{"data_dir": sourceDir}, nil)
+			_, err := scraper.CheckAccounts()
+			Expect(errors.Is(err, types.ErrNotConfigured)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,106 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// TwitterTweetRangeArguments are the since_id and until_id arguments of gettweets, searchbyquery and
+// searchbyfullarchive. They let indexers poll a profile or query incrementally, only receiving the tweets they
+// haven't seen yet.
+type TwitterTweetRangeArguments struct {
+	SinceID string `json:"since_id"` // Only return tweets with a greater ID, i.e. newer tweets
+	UntilID string `json:"until_id"` // Only return tweets with a smaller ID, i.e. older tweets
+}
+
+// tweetIDRange is the range of tweet IDs a job returns, exclusive at both ends. A zero bound is unbounded.
+type tweetIDRange struct {
+	since int64
+	until int64
+}
+
+// tweetIDRangeFromArguments parses the since_id and until_id job arguments
+func tweetIDRangeFromArguments(args types.JobArguments) (tweetIDRange, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return tweetIDRange{}, fmt.Errorf("failed to marshal tweet ID range arguments: %w", err)
+	}
+
+	var parsed TwitterTweetRangeArguments
+	if err := json.Unmarshal(dat, &parsed); err != nil {
+		return tweetIDRange{}, fmt.Errorf("since_id and until_id must be strings: %w", err)
+	}
+
+	var r tweetIDRange
+	if r.since, err = parseTweetIDBound("since_id", parsed.SinceID); err != nil {
+		return tweetIDRange{}, err
+	}
+	if r.until, err = parseTweetIDBound("until_id", parsed.UntilID); err != nil {
+		return tweetIDRange{}, err
+	}
+	if r.since != 0 && r.until != 0 && r.since >= r.until {
+		return tweetIDRange{}, fmt.Errorf("since_id %d must be smaller than until_id %d", r.since, r.until)
+	}
+	return r, nil
+}
+
+func parseTweetIDBound(name, value string) (int64, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, nil
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("invalid %s %q, must be a tweet ID", name, value)
+	}
+	return id, nil
+}
+
+// tweetIDRangeFromJob returns the tweet ID range of a job. The arguments are validated before the job is executed,
+// so invalid ones are ignored here.
+func tweetIDRangeFromJob(j types.Job) tweetIDRange {
+	r, _ := tweetIDRangeFromArguments(j.Arguments)
+	return r
+}
+
+// isSet returns true if the range is bounded at either end
+func (r tweetIDRange) isSet() bool {
+	return r.since != 0 || r.until != 0
+}
+
+// contains returns true if the tweet ID is within the range
+func (r tweetIDRange) contains(id int64) bool {
+	return (r.since == 0 || id > r.since) && (r.until == 0 || id < r.until)
+}
+
+// reachedSince returns true if a timeline, which is ordered from newest to oldest, has reached tweets which are not
+// newer than since_id, so no further pages need to be fetched. Pinned tweets are out of order, so they never end a
+// timeline.
+func (r tweetIDRange) reachedSince(tweet *teetypes.TweetResult) bool {
+	return r.since != 0 && !tweet.IsPin && tweet.ID <= r.since
+}
+
+// searchQuery adds the since_id and max_id search operators to a query, so the search itself skips the tweets outside
+// of the range. max_id is inclusive, unlike until_id.
+func (r tweetIDRange) searchQuery(query string) string {
+	if r.since != 0 {
+		query += fmt.Sprintf(" since_id:%d", r.since)
+	}
+	if r.until != 0 {
+		query += fmt.Sprintf(" max_id:%d", r.until-1)
+	}
+	return query
+}
+
+// apiBound formats a bound of the range as a parameter of the Twitter API, which is empty if it is unbounded
+func apiBound(id int64) string {
+	if id == 0 {
+		return ""
+	}
+	return strconv.FormatInt(id, 10)
+}
@@ -0,0 +1,170 @@
+package jobs
+
+import (
+	"errors"
+	"slices"
+	"strconv"
+	"time"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	teeargs "github.com/masa-finance/tee-types/args"
+	teetypes "github.com/masa-finance/tee-types/types"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twittersyndication"
+)
+
+// TwitterSyndicationClient fetches public tweets and profiles without credentials
+type TwitterSyndicationClient interface {
+	GetTweet(id string) (*twittersyndication.Tweet, error)
+	GetProfileByID(userID string) (*twittersyndication.User, error)
+	GetProfileByUsername(username string) (*twittersyndication.User, error)
+}
+
+// NewTwitterSyndicationClient is a function variable that can be replaced in tests.
+// It defaults to the actual implementation.
+var NewTwitterSyndicationClient = func(network types.Network) TwitterSyndicationClient {
+	c := twittersyndication.NewClient()
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// syndicationCaps are the capabilities the general Twitter job can provide through the syndication endpoints
+var syndicationCaps = []teetypes.Capability{teetypes.CapGetById, teetypes.CapGetProfileById, teetypes.CapSearchByProfile}
+
+// syndicationFallbackFor returns true if the general Twitter job falls back to the syndication endpoints for the
+// capability once all its auth sources failed
+func (ts *TwitterScraper) syndicationFallbackFor(c teetypes.Capability) bool {
+	return ts.configuration.SyndicationFallback && slices.Contains(syndicationCaps, c)
+}
+
+// fallBackToSyndication fetches the tweet or profile of a job whose auth sources all failed from the endpoints behind
+// the embeddable widgets, and adds the outcome to the fan-out of the job. The data of these endpoints is served by a
+// CDN to logged out visitors, so it can be stale or incomplete, and the result is flagged as lower trust in its
+// provenance.
+func (ts *TwitterScraper) fallBackToSyndication(j types.Job, jobArgs *teeargs.TwitterSearchArguments, fanOut *types.MultiError) (types.JobResult, error) {
+	j.Provenance.SetAuthSource(types.AuthSourceSyndication)
+	j.Trace.Strategy(types.AuthSourceSyndication, "")
+	ts.addStat(j, stats.TwitterScrapes, 1)
+
+	c := NewTwitterSyndicationClient(j.Network)
+	var (
+		response any
+		err      error
+	)
+	switch teetypes.Capability(jobArgs.QueryType) {
+	case teetypes.CapGetById:
+		var tweet *twittersyndication.Tweet
+		if tweet, err = c.GetTweet(jobArgs.Query); err == nil {
+			response = syndicationTweetResult(tweet)
+			ts.addStat(j, stats.TwitterTweets, 1)
+		}
+	case teetypes.CapGetProfileById, teetypes.CapSearchByProfile:
+		var user *twittersyndication.User
+		if jobArgs.QueryType == string(teetypes.CapGetProfileById) {
+			user, err = c.GetProfileByID(jobArgs.Query)
+		} else {
+			user, err = c.GetProfileByUsername(jobArgs.Query)
+		}
+		if err == nil {
+			response = syndicationProfile(user)
+			ts.addStat(j, stats.TwitterProfiles, 1)
+		}
+	}
+
+	if err != nil {
+		if errors.Is(err, twittersyndication.ErrRateLimited) {
+			ts.addStat(j, stats.TwitterRateErrors, 1)
+		} else {
+			ts.addStat(j, stats.TwitterErrors, 1)
+		}
+		fanOut.Failed(string(types.AuthSourceSyndication), jobArgs.Query, err)
+		return types.JobResult{Error: err.Error(), FanOut: fanOut.Statuses}, err
+	}
+
+	fanOut.Succeeded(string(types.AuthSourceSyndication), jobArgs.Query, 1)
+	result, err := processResponse(response, "", nil)
+	result.FanOut = fanOut.Statuses
+	return result, err
+}
+
+// syndicationTweetResult converts a tweet returned by the syndication endpoints to a TweetResult
+func syndicationTweetResult(tweet *twittersyndication.Tweet) *teetypes.TweetResult {
+	id, _ := strconv.ParseInt(tweet.IDStr, 10, 64)
+	createdAt, _ := time.Parse(time.RFC3339, tweet.CreatedAt)
+
+	result := &teetypes.TweetResult{
+		ID:                id,
+		TweetID:           tweet.IDStr,
+		UserID:            tweet.User.IDStr,
+		AuthorID:          tweet.User.IDStr,
+		Username:          tweet.User.ScreenName,
+		Text:              tweet.Text,
+		CreatedAt:         createdAt.UTC(),
+		Timestamp:         createdAt.Unix(),
+		IsReply:           tweet.InReplyToStatusIDStr != "",
+		IsQuoted:          tweet.QuotedTweet != nil,
+		Likes:             tweet.FavoriteCount,
+		Replies:           tweet.ConversationCount,
+		Lang:              tweet.Lang,
+		SensitiveContent:  tweet.PossiblySensitive,
+		PossiblySensitive: tweet.PossiblySensitive,
+	}
+	// The conversation of a reply is not known
+	if !result.IsReply {
+		result.ConversationID = tweet.IDStr
+	}
+	for _, h := range tweet.Entities.Hashtags {
+		result.Hashtags = append(result.Hashtags, h.Text)
+	}
+	for _, u := range tweet.Entities.URLs {
+		result.URLs = append(result.URLs, u.ExpandedURL)
+	}
+	for _, m := range tweet.MediaDetails {
+		if m.Type == "photo" {
+			result.Photos = append(result.Photos, teetypes.Photo{ID: m.IDStr, URL: m.MediaURLHTTPS})
+			continue
+		}
+		video := teetypes.Video{ID: m.IDStr, Preview: m.MediaURLHTTPS}
+		for _, v := range m.VideoInfo.Variants {
+			switch v.ContentType {
+			case "video/mp4":
+				video.URL = v.URL
+			case "application/x-mpegURL":
+				video.HLSURL = v.URL
+			}
+		}
+		result.Videos = append(result.Videos, video)
+	}
+	return result
+}
+
+// syndicationProfile converts a user returned by the syndication endpoints to a profile, as returned by the scraper
+func syndicationProfile(user *twittersyndication.User) *twitterscraper.Profile {
+	profile := &twitterscraper.Profile{
+		Avatar:         user.ProfileImageURLHTTPS,
+		Banner:         user.ProfileBannerURL,
+		Biography:      user.Description,
+		FollowersCount: user.FollowersCount,
+		FollowingCount: user.FriendsCount,
+		FriendsCount:   user.FriendsCount,
+		IsPrivate:      user.Protected,
+		IsVerified:     user.Verified,
+		IsBlueVerified: user.IsBlueVerified,
+		LikesCount:     user.FavouritesCount,
+		ListedCount:    user.ListedCount,
+		Location:       user.Location,
+		MediaCount:     user.MediaCount,
+		Name:           user.Name,
+		TweetsCount:    user.StatusesCount,
+		URL:            "https://twitter.com/" + user.ScreenName,
+		UserID:         user.IDStr,
+		Username:       user.ScreenName,
+		Website:        user.URL,
+	}
+	if joined, err := time.Parse(time.RubyDate, user.CreatedAt); err == nil {
+		profile.Joined = &joined
+	}
+	return profile
+}
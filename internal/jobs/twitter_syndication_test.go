@@ -0,0 +1,114 @@
+package jobs_test
+
+import (
+	"errors"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	twitterscraper "github.com/imperatrona/twitter-scraper"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twittersyndication"
+)
+
+// mockSyndicationClient returns the same tweet and user for every request
+type mockSyndicationClient struct {
+	tweet *twittersyndication.Tweet
+	user  *twittersyndication.User
+	err   error
+}
+
+func (m *mockSyndicationClient) GetTweet(string) (*twittersyndication.Tweet, error) {
+	return m.tweet, m.err
+}
+
+func (m *mockSyndicationClient) GetProfileByID(string) (*twittersyndication.User, error) {
+	return m.user, m.err
+}
+
+func (m *mockSyndicationClient) GetProfileByUsername(string) (*twittersyndication.User, error) {
+	return m.user, m.err
+}
+
+var _ = Describe("Twitter syndication fallback", func() {
+	var syndication *mockSyndicationClient
+
+	BeforeEach(func() {
+		syndication = &mockSyndicationClient{
+			tweet: &twittersyndication.Tweet{IDStr: "20", Text: "just setting up my twttr", CreatedAt: "2006-03-21T20:50:14.000Z", FavoriteCount: 3, User: twittersyndication.User{IDStr: "12", ScreenName: "jack"}},
+			user:  &twittersyndication.User{IDStr: "12", ScreenName: "jack", Name: "jack", FollowersCount: 10, CreatedAt: "Tue Mar 21 20:50:14 +0000 2006"},
+		}
+		original := NewTwitterSyndicationClient
+		DeferCleanup(func() { NewTwitterSyndicationClient = original })
+		NewTwitterSyndicationClient = func(types.Network) TwitterSyndicationClient {
+			return syndication
+		}
+	})
+
+	execute := func(jc config.JobConfiguration, capability teetypes.Capability, query string) (types.Job, types.JobResult, error) {
+		j := types.Job{
+			Type:       teetypes.TwitterJob,
+			Arguments:  map[string]any{"type": capability, "query": query},
+			Provenance: &types.ProvenanceRecorder{},
+		}
+		res, err := NewTwitterScraper(jc, stats.StartCollector(128, jc)).ExecuteJob(j)
+		return j, res, err
+	}
+
+	It("fetches tweets once the auth sources failed, and flags them as lower trust", func() {
+		j, res, err := execute(config.JobConfiguration{"twitter_syndication_fallback": true}, teetypes.CapGetById, "20")
+		Expect(err).NotTo(HaveOccurred())
+
+		var tweet teetypes.TweetResult
+		Expect(res.Unmarshal(&tweet)).To(Succeed())
+		Expect(tweet.TweetID).To(Equal("20"))
+		Expect(tweet.Text).To(Equal("just setting up my twttr"))
+		Expect(tweet.Username).To(Equal("jack"))
+		Expect(tweet.Likes).To(Equal(3))
+		Expect(tweet.CreatedAt.Year()).To(Equal(2006))
+
+		Expect(j.Provenance.AuthSource()).To(Equal(types.AuthSourceSyndication))
+		Expect(j.Provenance.AuthSource().LowerTrust()).To(BeTrue())
+		Expect(res.FanOut).To(HaveLen(2))
+		Expect(res.FanOut[0].Success).To(BeFalse())
+		Expect(res.FanOut[1].Provider).To(Equal("syndication"))
+		Expect(res.FanOut[1].Success).To(BeTrue())
+	})
+
+	It("fetches profiles by ID and by username", func() {
+		jc := config.JobConfiguration{"twitter_syndication_fallback": true}
+		for _, capability := range []teetypes.Capability{teetypes.CapGetProfileById, teetypes.CapSearchByProfile} {
+			_, res, err := execute(jc, capability, "12")
+			Expect(err).NotTo(HaveOccurred())
+
+			var profile twitterscraper.Profile
+			Expect(res.Unmarshal(&profile)).To(Succeed())
+			Expect(profile.Username).To(Equal("jack"))
+			Expect(profile.FollowersCount).To(Equal(10))
+			Expect(profile.Joined).NotTo(BeNil())
+		}
+	})
+
+	It("reports the error of the fallback if it fails too", func() {
+		syndication.err = errors.New("boom")
+		_, res, err := execute(config.JobConfiguration{"twitter_syndication_fallback": true}, teetypes.CapGetById, "20")
+		Expect(err).To(MatchError("boom"))
+		Expect(res.FanOut).To(HaveLen(2))
+		Expect(res.FanOut[1].Success).To(BeFalse())
+	})
+
+	It("is not used unless it is enabled", func() {
+		j, _, err := execute(config.JobConfiguration{}, teetypes.CapGetById, "20")
+		Expect(err).To(MatchError("no Twitter credentials available"))
+		Expect(j.Provenance.AuthSource()).To(BeEmpty())
+	})
+
+	It("is not used for capabilities it can't provide", func() {
+		_, _, err := execute(config.JobConfiguration{"twitter_syndication_fallback": true}, teetypes.CapSearchByQuery, "nasa")
+		Expect(err).To(MatchError("no Twitter credentials available"))
+	})
+})
@@ -3,7 +3,12 @@ package jobs_test
 import (
 	"encoding/json"
 	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,7 +23,9 @@ import (
 	"github.com/masa-finance/tee-worker/internal/config"
 	. "github.com/masa-finance/tee-worker/internal/jobs"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
 	"github.com/masa-finance/tee-worker/internal/jobs/twitterx"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 )
 
 // parseTwitterAccounts parses TWITTER_ACCOUNTS environment variable like production does
@@ -647,6 +654,32 @@ var _ = Describe("Twitter Scraper", func() {
 			Expect(tweet.CreatedAt).NotTo(BeZero())
 		})
 
+		It("should use API key for twitter-api with getbyids", func() {
+			if len(twitterApiKeys) == 0 {
+				Skip("TWITTER_API_KEYS is not set")
+			}
+			scraper := NewTwitterScraper(config.JobConfiguration{
+				"twitter_api_keys": twitterApiKeys,
+				"data_dir":         tempDir,
+			}, statsCollector)
+			res, err := scraper.ExecuteJob(types.Job{
+				Type: teetypes.TwitterApiJob,
+				Arguments: map[string]interface{}{
+					"type": CapGetByIds,
+					"ids":  []string{"1881258110712492142", "1881258110712492142", "1880970069396836638"},
+				},
+				Timeout: 10 * time.Second,
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(res.Error).To(BeEmpty())
+
+			var tweets []*teetypes.TweetResult
+			err = res.Unmarshal(&tweets)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tweets).To(HaveLen(2))
+			Expect(tweets[0].TweetID).To(Equal("1881258110712492142"))
+		})
+
 		It("should use API key for twitter-api with getprofilebyid", func() {
 			if len(twitterApiKeys) == 0 {
 				Skip("TWITTER_API_KEYS is not set")
@@ -991,3 +1024,967 @@ var _ = Describe("Twitter Scraper", func() {
 		})
 	})
 })
+
+var _ = Describe("Twitter getbyids", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts":        []string{"user:pass"},
+			"twitter_max_ids_per_job": 3,
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	It("should be reported wherever getbyid is available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetByIds))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetByIds))
+	})
+
+	DescribeTable("should reject invalid IDs",
+		func(ids []string) {
+			res, err := scraper.ExecuteJob(types.Job{
+				Type:      teetypes.TwitterCredentialJob,
+				Arguments: map[string]interface{}{"type": CapGetByIds, "ids": ids},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no IDs", []string{}),
+		Entry("non-numeric ID", []string{"123", "abc"}),
+		Entry("more IDs than configured", []string{"1", "2", "3", "4"}),
+	)
+
+	It("should not be supported by the Apify job type", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type:      teetypes.TwitterApifyJob,
+			Arguments: map[string]interface{}{"type": CapGetByIds, "ids": []string{"123"}},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+	})
+
+	It("should report that there are no credentials if no auth source is available", func() {
+		jc := config.JobConfiguration{}
+		res, err := NewTwitterScraper(jc, stats.StartCollector(128, jc)).ExecuteJob(types.Job{
+			Type:      teetypes.TwitterJob,
+			Arguments: map[string]interface{}{"type": CapGetByIds, "ids": []string{"123"}},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(res.FanOut).To(ContainElement(HaveField("Error", ContainSubstring("no Twitter credentials available"))))
+	})
+})
+
+var _ = Describe("Twitter getpoll", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	It("should be reported wherever getbyid is available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetPoll))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetPoll))
+	})
+
+	DescribeTable("should reject invalid tweet IDs",
+		func(query string) {
+			res, err := scraper.ExecuteJob(types.Job{
+				Type:      teetypes.TwitterCredentialJob,
+				Arguments: map[string]interface{}{"type": CapGetPoll, "query": query},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no ID", ""),
+		Entry("non-numeric ID", "abc"),
+	)
+
+	It("should not be supported by the Apify job type", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type:      teetypes.TwitterApifyJob,
+			Arguments: map[string]interface{}{"type": CapGetPoll, "query": "123"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+	})
+
+	It("should parse the poll of a card", func() {
+		var card twitter.TweetCard
+		Expect(json.Unmarshal([]byte(`{
+			"name": "poll3choice_text_only",
+			"binding_values": [
+				{"key": "choice1_label", "value": {"string_value": "Yes"}},
+				{"key": "choice1_count", "value": {"string_value": "120"}},
+				{"key": "choice2_label", "value": {"string_value": "No"}},
+				{"key": "choice2_count", "value": {"string_value": "45"}},
+				{"key": "choice3_label", "value": {"string_value": "Maybe"}},
+				{"key": "choice3_count", "value": {"string_value": "0"}},
+				{"key": "end_datetime_utc", "value": {"string_value": "2025-01-21T12:00:00Z"}},
+				{"key": "duration_minutes", "value": {"string_value": "1440"}},
+				{"key": "counts_are_final", "value": {"boolean_value": true}}
+			]
+		}`), &card)).To(Succeed())
+		Expect(card.IsPoll()).To(BeTrue())
+
+		poll, err := card.Poll()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(poll.Choices).To(Equal([]twitter.PollChoice{{Label: "Yes", Count: 120}, {Label: "No", Count: 45}, {Label: "Maybe", Count: 0}}))
+		Expect(poll.EndTime).To(Equal(time.Date(2025, 1, 21, 12, 0, 0, 0, time.UTC)))
+		Expect(poll.Duration).To(Equal(24 * time.Hour))
+		Expect(poll.Final).To(BeTrue())
+	})
+
+	It("should not parse cards which are not polls", func() {
+		card := twitter.TweetCard{Name: "summary_large_image"}
+		Expect(card.IsPoll()).To(BeFalse())
+		_, err := card.Poll()
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Twitter getthread", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts":         []string{"user:pass"},
+			"twitter_thread_max_depth": 2,
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	tweet := func(id, userID, inReplyTo string, likes int, timestamp int64) *twitterscraper.Tweet {
+		return &twitterscraper.Tweet{ID: id, UserID: userID, InReplyToStatusID: inReplyTo, Likes: likes, Timestamp: timestamp}
+	}
+
+	ids := func(nodes []twitter.ThreadNode) []string {
+		var result []string
+		for _, node := range nodes {
+			result = append(result, node.Tweet.ID)
+		}
+		return result
+	}
+
+	It("should be reported wherever getreplies is available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetThread))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetThread))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]interface{}) {
+			args["type"] = CapGetThread
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no ID", map[string]interface{}{"query": ""}),
+		Entry("non-numeric ID", map[string]interface{}{"query": "abc"}),
+		Entry("depth above the limit", map[string]interface{}{"query": "1", "max_depth": 3}),
+		Entry("negative depth", map[string]interface{}{"query": "1", "max_depth": -1}),
+		Entry("negative replies", map[string]interface{}{"query": "1", "max_replies": -1}),
+	)
+
+	It("should not be supported by the API job type", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type:      teetypes.TwitterApiJob,
+			Arguments: map[string]interface{}{"type": CapGetThread, "query": "123"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+	})
+
+	It("should order the self-thread first and nest the key replies below their parents", func() {
+		tweets := []*twitterscraper.Tweet{
+			tweet("1", "author", "", 100, 1),
+			tweet("2", "author", "1", 50, 2),
+			tweet("3", "author", "2", 10, 3),
+			tweet("10", "fan", "1", 5, 4),
+			tweet("11", "critic", "1", 20, 5),
+			tweet("12", "troll", "1", 0, 6),
+			tweet("20", "author", "11", 30, 7),
+			tweet("30", "fan", "20", 1, 8),
+			tweet("40", "fan", "3", 2, 9),
+			tweet("99", "other", "98", 0, 10),
+		}
+
+		nodes := twitter.BuildThread("1", tweets, 2, 2)
+		Expect(ids(nodes)).To(Equal([]string{"1", "2", "3", "11", "20", "10", "40"}))
+
+		Expect(nodes[0].SelfThread).To(BeTrue())
+		Expect(nodes[0].ChildIDs).To(Equal([]string{"2", "11", "10"}))
+		Expect(nodes[2].ParentID).To(Equal("2"))
+		Expect(nodes[2].Depth).To(BeZero())
+
+		// The reply of the author to a reply is not part of the self-thread
+		Expect(nodes[4].ParentID).To(Equal("11"))
+		Expect(nodes[4].Depth).To(Equal(2))
+		Expect(nodes[4].SelfThread).To(BeFalse())
+		Expect(nodes[6].ParentID).To(Equal("3"))
+		Expect(nodes[6].Depth).To(Equal(1))
+	})
+
+	It("should only return the self-thread without depth", func() {
+		tweets := []*twitterscraper.Tweet{
+			tweet("1", "author", "", 0, 1),
+			tweet("3", "author", "2", 0, 3),
+			tweet("2", "author", "1", 0, 2),
+			tweet("10", "fan", "1", 0, 4),
+		}
+		Expect(ids(twitter.BuildThread("1", tweets, 0, 5))).To(Equal([]string{"1", "2", "3"}))
+		Expect(twitter.BuildThread("5", tweets, 0, 5)).To(BeEmpty())
+	})
+})
+
+var _ = Describe("Twitter getfollowerdelta", func() {
+	var (
+		scraper *TwitterScraper
+		store   *twitter.SnapshotStore
+		first   *twitter.FollowerSnapshot
+		second  *twitter.FollowerSnapshot
+	)
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+
+		dataDir := GinkgoT().TempDir()
+		jc := config.JobConfiguration{
+			"twitter_accounts":               []string{"user:pass"},
+			"data_dir":                       dataDir,
+			"twitter_max_follower_snapshots": 10,
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+		store = twitter.NewSnapshotStore(dataDir, 10)
+
+		now := time.Now().UTC()
+		first = &twitter.FollowerSnapshot{
+			Account:    "masa_finance",
+			Relation:   twitter.RelationFollowers,
+			TakenAt:    now.Add(-time.Hour),
+			MaxResults: 100,
+			Members:    []twitter.FollowerMember{{ID: "1", Username: "a"}, {ID: "2", Username: "b"}, {ID: "3", Username: "c"}, {ID: "4", Username: "d"}},
+		}
+		second = &twitter.FollowerSnapshot{
+			Account:    "masa_finance",
+			Relation:   twitter.RelationFollowers,
+			TakenAt:    now,
+			MaxResults: 100,
+			Members:    []twitter.FollowerMember{{ID: "2", Username: "b"}, {ID: "3", Username: "c"}, {ID: "5", Username: "e"}, {ID: "6", Username: "f"}, {ID: "7", Username: "g"}},
+		}
+		Expect(store.Save(first)).To(Succeed())
+		Expect(store.Save(second)).To(Succeed())
+	})
+
+	It("should be reported wherever getfollowers is available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetFollowerDelta))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetFollowerDelta))
+		Expect(caps[teetypes.TwitterApiJob]).ToNot(ContainElement(CapGetFollowerDelta))
+	})
+
+	It("should compute new followers, unfollowers and churn rate", func() {
+		delta := ComputeFollowerDelta(first, second)
+		Expect(delta.Added).To(ConsistOf(
+			twitter.FollowerMember{ID: "5", Username: "e"},
+			twitter.FollowerMember{ID: "6", Username: "f"},
+			twitter.FollowerMember{ID: "7", Username: "g"},
+		))
+		Expect(delta.Removed).To(ConsistOf(
+			twitter.FollowerMember{ID: "1", Username: "a"},
+			twitter.FollowerMember{ID: "4", Username: "d"},
+		))
+		Expect(delta.FromCount).To(Equal(4))
+		Expect(delta.ToCount).To(Equal(5))
+		Expect(delta.NetChange).To(Equal(1))
+		Expect(delta.ChurnRate).To(BeNumerically("~", 0.5))
+		Expect(delta.PossiblyTruncated).To(BeFalse())
+	})
+
+	It("should flag deltas of snapshots which may be truncated", func() {
+		second.MaxResults = len(second.Members)
+		Expect(ComputeFollowerDelta(first, second).PossiblyTruncated).To(BeTrue())
+	})
+
+	It("should return only the delta between two stored snapshots", func() {
+		res, err := scraper.ExecuteJob(types.Job{
+			Type: teetypes.TwitterCredentialJob,
+			Arguments: map[string]interface{}{
+				"type":  CapGetFollowerDelta,
+				"query": "@Masa_Finance",
+				"to":    second.ID,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Error).To(BeEmpty())
+
+		var delta FollowerDelta
+		Expect(res.Unmarshal(&delta)).To(Succeed())
+		Expect(delta.Account).To(Equal("masa_finance"))
+		Expect(delta.From).To(Equal(first.ID))
+		Expect(delta.To).To(Equal(second.ID))
+		Expect(delta.Added).To(HaveLen(3))
+		Expect(delta.Removed).To(HaveLen(2))
+	})
+
+	It("should fail for snapshots which were not stored", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type: teetypes.TwitterCredentialJob,
+			Arguments: map[string]interface{}{
+				"type":  CapGetFollowerDelta,
+				"query": "masa_finance",
+				"from":  "12345",
+				"to":    second.ID,
+			},
+		})
+		Expect(err).To(MatchError(twitter.ErrSnapshotNotFound))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]interface{}) {
+			args["type"] = CapGetFollowerDelta
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no account", map[string]interface{}{}),
+		Entry("invalid account", map[string]interface{}{"query": "../etc"}),
+		Entry("unknown relation", map[string]interface{}{"query": "masa_finance", "relation": "likes"}),
+		Entry("negative max_results", map[string]interface{}{"query": "masa_finance", "max_results": -1}),
+		Entry("same snapshots", map[string]interface{}{"query": "masa_finance", "from": "1", "to": "1"}),
+	)
+})
+
+var _ = Describe("Twitter samplefollowers", func() {
+	const sortStep = 10
+
+	// followerList serves the pages of a list of followers, whose cursors have a sort index decreasing by sortStep
+	// per follower like those of Twitter. Made up cursors are rejected if strict is set.
+	type followerList struct {
+		size     int
+		strict   bool
+		issued   map[string]bool
+		requests int
+	}
+	base := int64(1 << 40)
+	fetcher := func(l *followerList) twitter.FollowerPageFetcher {
+		l.issued = map[string]bool{}
+		return func(cursor string) ([]*twitterscraper.Profile, string, error) {
+			l.requests++
+			position := 0
+			if cursor != "" {
+				if l.strict && !l.issued[cursor] {
+					return nil, "", fmt.Errorf("invalid cursor")
+				}
+				index, err := strconv.ParseInt(strings.Split(cursor, "|")[0], 10, 64)
+				Expect(err).NotTo(HaveOccurred())
+				position = int((base - index) / sortStep)
+			}
+			var profiles []*twitterscraper.Profile
+			for i := position; i < min(position+twitter.FollowerPageSize, l.size); i++ {
+				profiles = append(profiles, &twitterscraper.Profile{UserID: strconv.Itoa(i), Username: fmt.Sprintf("follower%d", i)})
+			}
+			if position+twitter.FollowerPageSize >= l.size {
+				return profiles, "", nil
+			}
+			next := fmt.Sprintf("%d|entry", base-int64(position+twitter.FollowerPageSize)*sortStep)
+			l.issued[next] = true
+			return profiles, next, nil
+		}
+	}
+	positions := func(sample *twitter.FollowerSample) []int {
+		var ps []int
+		for _, p := range sample.Followers {
+			i, err := strconv.Atoi(p.UserID)
+			Expect(err).NotTo(HaveOccurred())
+			ps = append(ps, i)
+		}
+		return ps
+	}
+
+	It("should read all the followers of small accounts", func() {
+		list := &followerList{size: 450}
+		sample, err := twitter.SampleFollowers(fetcher(list), twitter.SampleOptions{SampleSize: 100, MaxPages: 10, Population: 450})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sample.Method).To(Equal(twitter.SampleExhaustive))
+		Expect(sample.PagesRead).To(Equal(3))
+		Expect(sample.FollowersRead).To(Equal(450))
+		Expect(sample.Followers).To(HaveLen(100))
+		Expect(sample.Coverage).To(Equal(1.0))
+	})
+
+	It("should skip pages to spread the sample over the followers of large accounts", func() {
+		list := &followerList{size: 1_000_000}
+		sample, err := twitter.SampleFollowers(fetcher(list), twitter.SampleOptions{SampleSize: 500, MaxPages: 10, Population: 1_000_000})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.requests).To(Equal(10))
+		Expect(sample.Method).To(Equal(twitter.SampleSkip))
+		Expect(sample.FollowersRead).To(Equal(2000))
+		Expect(sample.SampleSize).To(Equal(500))
+		Expect(sample.Coverage).To(BeNumerically(">", 0.8))
+		Expect(slices.Max(positions(sample))).To(BeNumerically(">", 800_000))
+		Expect(sample.MarginOfError).To(BeNumerically("~", 0.0438, 0.001))
+	})
+
+	It("should read the pages sequentially if skipped cursors are rejected", func() {
+		list := &followerList{size: 1_000_000, strict: true}
+		sample, err := twitter.SampleFollowers(fetcher(list), twitter.SampleOptions{SampleSize: 100, MaxPages: 10, Population: 1_000_000})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(list.requests).To(Equal(10))
+		Expect(sample.FollowersRead).To(Equal(1800))
+		Expect(slices.Max(positions(sample))).To(BeNumerically("<", 1800))
+		Expect(sample.Coverage).To(BeNumerically("~", 0.0018))
+	})
+
+	It("should draw the same sample for the same seed", func() {
+		sample := func() []int {
+			list := &followerList{size: 5000}
+			s, err := twitter.SampleFollowers(fetcher(list), twitter.SampleOptions{SampleSize: 50, MaxPages: 30, Rand: rand.New(rand.NewPCG(42, 0))})
+			Expect(err).NotTo(HaveOccurred())
+			return positions(s)
+		}
+		Expect(sample()).To(Equal(sample()))
+	})
+
+	It("should fail if no followers could be read", func() {
+		_, err := twitter.SampleFollowers(func(string) ([]*twitterscraper.Profile, string, error) {
+			return nil, "", fmt.Errorf("rate limit exceeded")
+		}, twitter.SampleOptions{SampleSize: 10, MaxPages: 5})
+		Expect(err).To(MatchError("rate limit exceeded"))
+	})
+
+	It("should narrow the margin of error as the sample approaches the population", func() {
+		Expect(twitter.MarginOfError(100, 0)).To(BeNumerically("~", 0.098, 0.001))
+		Expect(twitter.MarginOfError(100, 200)).To(BeNumerically("<", twitter.MarginOfError(100, 0)))
+		Expect(twitter.MarginOfError(100, 100)).To(BeZero())
+	})
+
+	It("should be reported wherever credentials are available", func() {
+		jc := config.JobConfiguration{"twitter_accounts": []string{"user:pass"}}
+		caps := NewTwitterScraper(jc, stats.StartCollector(128, jc)).GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapSampleFollowers))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapSampleFollowers))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]interface{}) {
+			args["type"] = CapSampleFollowers
+			jc := config.JobConfiguration{"twitter_accounts": []string{"user:pass"}}
+			res, err := NewTwitterScraper(jc, stats.StartCollector(128, jc)).ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no account", map[string]interface{}{}),
+		Entry("negative sample_size", map[string]interface{}{"query": "nasa", "sample_size": -1}),
+		Entry("too large sample_size", map[string]interface{}{"query": "nasa", "sample_size": 5000}),
+		Entry("too many pages", map[string]interface{}{"query": "nasa", "max_pages": 500}),
+	)
+})
+
+var _ = Describe("Twitter gettrendhistory", func() {
+	var (
+		scraper *TwitterScraper
+		store   *twitter.TrendStore
+		now     time.Time
+	)
+
+	BeforeEach(func() {
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		standalone := tee.SealStandaloneMode
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+
+		dataDir := GinkgoT().TempDir()
+		jc := config.JobConfiguration{
+			"twitter_accounts":            []string{"user:pass"},
+			"data_dir":                    dataDir,
+			"twitter_max_trend_snapshots": 3,
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+		store = twitter.NewTrendStore(dataDir, 3)
+
+		now = time.Now().UTC()
+		for i, trends := range [][]string{
+			{"#Bitcoin", "World Cup", "$TSLA"},
+			{"$TSLA", "#Bitcoin", "#Masa"},
+			{"#Masa", "$TSLA", "Elections"},
+		} {
+			Expect(store.Save(&twitter.TrendSnapshot{TakenAt: now.Add(time.Duration(2*i-5) * 30 * time.Minute), Trends: trends})).To(Succeed())
+		}
+	})
+
+	It("should be reported wherever credentials are available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetTrendHistory))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetTrendHistory))
+	})
+
+	It("should keep only the most recent snapshots", func() {
+		Expect(store.Save(&twitter.TrendSnapshot{TakenAt: now, Trends: []string{"#New"}})).To(Succeed())
+		ids, err := store.List()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids).To(HaveLen(3))
+
+		snapshots, err := store.Since(now.Add(-2 * time.Hour))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshots).To(HaveLen(3))
+		Expect(snapshots[2].Trends).To(Equal([]string{"#New"}))
+	})
+
+	It("should rank the stored trends over time", func() {
+		res, err := scraper.ExecuteJob(types.Job{
+			Type: teetypes.TwitterCredentialJob,
+			Arguments: map[string]interface{}{
+				"type":    CapGetTrendHistory,
+				"samples": 0,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.Error).To(BeEmpty())
+
+		var history twitter.TrendHistory
+		Expect(res.Unmarshal(&history)).To(Succeed())
+		Expect(history.SamplesTaken).To(BeZero())
+		Expect(history.SnapshotTimes).To(HaveLen(3))
+		Expect(history.From).To(BeTemporally("~", now.Add(-150*time.Minute), time.Millisecond))
+		Expect(history.Trends).To(HaveLen(5))
+
+		tsla := history.Trends[0]
+		Expect(tsla.Name).To(Equal("$TSLA"))
+		Expect(tsla.Kind).To(Equal(twitter.TrendCashtag))
+		Expect(tsla.Appearances).To(Equal(3))
+		Expect(tsla.BestRank).To(Equal(1))
+		Expect(tsla.LatestRank).To(Equal(2))
+		Expect(tsla.Ranks).To(HaveLen(3))
+		Expect(history.Trends[1].Name).To(Equal("#Bitcoin"))
+		Expect(history.Trends[2].Name).To(Equal("#Masa"))
+	})
+
+	It("should only return the trends of the kind and within the window", func() {
+		res, err := scraper.ExecuteJob(types.Job{
+			Type: teetypes.TwitterCredentialJob,
+			Arguments: map[string]interface{}{
+				"type":         CapGetTrendHistory,
+				"samples":      0,
+				"kind":         "hashtag",
+				"window_hours": 2,
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		var history twitter.TrendHistory
+		Expect(res.Unmarshal(&history)).To(Succeed())
+		Expect(history.SnapshotTimes).To(HaveLen(2))
+		Expect(history.Trends).To(HaveLen(2))
+		Expect(history.Trends[0].Name).To(Equal("#Masa"))
+		Expect(history.Trends[0].Ranks).To(Equal([]twitter.TrendRank{
+			{TakenAt: history.SnapshotTimes[0], Rank: 3},
+			{TakenAt: history.SnapshotTimes[1], Rank: 1},
+		}))
+		Expect(history.Trends[1].Name).To(Equal("#Bitcoin"))
+	})
+
+	It("should sample the trends until the deadline", func() {
+		calls := 0
+		fetch := func() (*twitter.TrendSnapshot, error) {
+			calls++
+			return &twitter.TrendSnapshot{TakenAt: time.Now(), Trends: []string{"#Masa"}}, nil
+		}
+		snapshots, err := twitter.SampleTrends(fetch, twitter.TrendSampleOptions{Samples: 3, Interval: 10 * time.Millisecond})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshots).To(HaveLen(3))
+
+		calls = 0
+		snapshots, err = twitter.SampleTrends(fetch, twitter.TrendSampleOptions{
+			Samples:  3,
+			Interval: time.Hour,
+			Deadline: time.Now().Add(time.Minute),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshots).To(HaveLen(1))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("should return the snapshots taken before a sample failed", func() {
+		calls := 0
+		snapshots, err := twitter.SampleTrends(func() (*twitter.TrendSnapshot, error) {
+			if calls++; calls > 1 {
+				return nil, fmt.Errorf("rate limit exceeded")
+			}
+			return &twitter.TrendSnapshot{TakenAt: time.Now()}, nil
+		}, twitter.TrendSampleOptions{Samples: 3, Interval: time.Millisecond})
+		Expect(err).To(MatchError("rate limit exceeded"))
+		Expect(snapshots).To(HaveLen(1))
+	})
+
+	It("should classify hashtags and cashtags", func() {
+		Expect(twitter.TrendKind("#Masa")).To(Equal(twitter.TrendHashtag))
+		Expect(twitter.TrendKind("$BTC")).To(Equal(twitter.TrendCashtag))
+		Expect(twitter.TrendKind("World Cup")).To(Equal(twitter.TrendTopic))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]interface{}) {
+			args["type"] = CapGetTrendHistory
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("unknown kind", map[string]interface{}{"kind": "emoji"}),
+		Entry("negative samples", map[string]interface{}{"samples": -1}),
+		Entry("too many samples", map[string]interface{}{"samples": 500}),
+		Entry("too short interval", map[string]interface{}{"interval_seconds": 1}),
+		Entry("too long window", map[string]interface{}{"window_hours": 10000}),
+		Entry("negative max_results", map[string]interface{}{"max_results": -1}),
+	)
+})
+
+var _ = Describe("Twitter API key capabilities", func() {
+	It("routes requests to keys which have access to the endpoint", func() {
+		base := &twitter.TwitterApiKey{Key: "base", Type: twitter.TwitterApiKeyTypeBase, Capabilities: twitter.ApiKeyCapabilities{RecentSearch: true}}
+		elevated := &twitter.TwitterApiKey{Key: "elevated", Type: twitter.TwitterApiKeyTypeElevated, Capabilities: twitter.ApiKeyCapabilities{RecentSearch: true, FullArchive: true, Counts: true}}
+		manager := twitter.NewTwitterAccountManager(nil, []*twitter.TwitterApiKey{base, elevated})
+
+		for i := 0; i < 3; i++ {
+			Expect(manager.GetNextApiKeyFor(twitterx.TweetsAll)).To(Equal(elevated))
+		}
+		Expect([]*twitter.TwitterApiKey{
+			manager.GetNextApiKeyFor(twitterx.TweetsSearchRecent),
+			manager.GetNextApiKeyFor(twitterx.TweetsSearchRecent),
+		}).To(ConsistOf(base, elevated))
+	})
+
+	It("falls back to any key if none is known to have access", func() {
+		unknown := &twitter.TwitterApiKey{Key: "unknown", Type: twitter.TwitterApiKeyTypeUnknown}
+		manager := twitter.NewTwitterAccountManager(nil, []*twitter.TwitterApiKey{unknown})
+		Expect(manager.GetNextApiKeyFor("tweets/search/stream")).To(Equal(unknown))
+	})
+
+	It("reports the capability matrix of each key without the key itself", func() {
+		key := &twitter.TwitterApiKey{Key: "secret", Capabilities: twitter.ApiKeyCapabilities{Counts: true}}
+		Expect(key.Fingerprint()).NotTo(ContainSubstring("secret"))
+		Expect(key.Capabilities.Map()).To(Equal(map[string]bool{
+			"recent_search":   false,
+			"full_archive":    false,
+			"counts":          true,
+			"filtered_stream": false,
+		}))
+	})
+})
+
+var _ = Describe("Twitter getlisttweets", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	It("should be reported wherever credentials are available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapGetListTweets))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapGetListTweets))
+		Expect(scraper.GetCapabilityDetails()[teetypes.TwitterJob]).To(ContainElement(HaveField("Capability", CapGetListTweets)))
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(args map[string]interface{}) {
+			args["type"] = CapGetListTweets
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no List ID", map[string]interface{}{}),
+		Entry("non-numeric List ID", map[string]interface{}{"query": "my-list"}),
+		Entry("negative max_results", map[string]interface{}{"query": "1234", "max_results": -1}),
+	)
+
+	It("should not be supported by the API job type", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type:      teetypes.TwitterApiJob,
+			Arguments: map[string]interface{}{"type": CapGetListTweets, "query": "1234"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+	})
+})
+
+var _ = Describe("Twitter searchspaces and getcommunitytweets", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	It("should be reported wherever credentials are available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		for _, c := range []teetypes.Capability{CapSearchSpaces, CapGetCommunityTweets} {
+			Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(c))
+			Expect(caps[teetypes.TwitterJob]).To(ContainElement(c))
+		}
+	})
+
+	DescribeTable("should reject invalid arguments",
+		func(capability teetypes.Capability, args map[string]interface{}) {
+			args["type"] = capability
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("searchspaces without a query", CapSearchSpaces, map[string]interface{}{"query": " "}),
+		Entry("searchspaces with negative max_results", CapSearchSpaces, map[string]interface{}{"query": "bitcoin", "max_results": -1}),
+		Entry("getcommunitytweets without a Community ID", CapGetCommunityTweets, map[string]interface{}{}),
+		Entry("getcommunitytweets with a non-numeric Community ID", CapGetCommunityTweets, map[string]interface{}{"query": "golang"}),
+	)
+
+	It("should not be supported by the API job type", func() {
+		for _, c := range []teetypes.Capability{CapSearchSpaces, CapGetCommunityTweets} {
+			_, err := scraper.ExecuteJob(types.Job{
+				Type:      teetypes.TwitterApiJob,
+				Arguments: map[string]interface{}{"type": c, "query": "1234"},
+			})
+			Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+		}
+	})
+
+	DescribeTable("should find the ID of linked Spaces",
+		func(link, id string, ok bool) {
+			parsed, found := twitter.ParseSpaceID(link)
+			Expect(found).To(Equal(ok))
+			Expect(parsed).To(Equal(id))
+		},
+		Entry("x.com", "https://x.com/i/spaces/1eaKbgRQzWQxX", "1eaKbgRQzWQxX", true),
+		Entry("twitter.com with a trailing slash", "https://twitter.com/i/spaces/1eaKbgRQzWQxX/", "1eaKbgRQzWQxX", true),
+		Entry("a Space with a query string", "https://x.com/i/spaces/1eaKbgRQzWQxX?s=20", "1eaKbgRQzWQxX", true),
+		Entry("a Space recording page", "https://x.com/i/spaces/1eaKbgRQzWQxX/peek", "", false),
+		Entry("another host", "https://example.com/i/spaces/1eaKbgRQzWQxX", "", false),
+		Entry("a tweet", "https://x.com/NASA/status/1234", "", false),
+	)
+})
+
+var _ = Describe("Twitter since_id and until_id", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	DescribeTable("should reject invalid tweet ID ranges",
+		func(capability teetypes.Capability, args map[string]interface{}) {
+			args["type"] = capability
+			args["query"] = "NASA"
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("non-numeric since_id", teetypes.CapGetTweets, map[string]interface{}{"since_id": "latest"}),
+		Entry("numeric since_id", teetypes.CapGetTweets, map[string]interface{}{"since_id": 1234}),
+		Entry("negative until_id", teetypes.CapSearchByQuery, map[string]interface{}{"until_id": "-1"}),
+		Entry("since_id after until_id", teetypes.CapSearchByQuery, map[string]interface{}{"since_id": "200", "until_id": "100"}),
+	)
+
+	It("should only return tweets newer than since_id", func() {
+		accounts := strings.Split(os.Getenv("TWITTER_ACCOUNTS"), ",")
+		if os.Getenv("TWITTER_ACCOUNTS") == "" {
+			Skip("TWITTER_ACCOUNTS is not set")
+		}
+		jc := config.JobConfiguration{"twitter_accounts": accounts, "data_dir": GinkgoT().TempDir()}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+
+		newest := func(args map[string]interface{}) []*teetypes.TweetResult {
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args, Timeout: 10 * time.Second})
+			Expect(err).NotTo(HaveOccurred())
+			var tweets []*teetypes.TweetResult
+			Expect(res.Unmarshal(&tweets)).To(Succeed())
+			return tweets
+		}
+
+		tweets := newest(map[string]interface{}{"type": teetypes.CapGetTweets, "query": "NASA", "max_results": 5})
+		Expect(len(tweets)).To(BeNumerically(">", 1))
+		sinceID := tweets[1].ID
+
+		for _, tweet := range newest(map[string]interface{}{"type": teetypes.CapGetTweets, "query": "NASA", "max_results": 5, "since_id": strconv.FormatInt(sinceID, 10)}) {
+			Expect(tweet.ID).To(BeNumerically(">", sinceID))
+		}
+	})
+})
+
+var _ = Describe("Twitter argument keys", func() {
+	It("should include the arguments of the capabilities which are not part of tee-types", func() {
+		jc := config.JobConfiguration{}
+		keys := NewTwitterScraper(jc, stats.StartCollector(128, jc)).ArgumentKeys()
+		Expect(keys).To(ContainElements("type", "query", "max_results", "next_cursor", "ids", "relation", "from", "to", "since_id", "until_id"))
+		Expect(slices.IsSorted(keys)).To(BeTrue())
+	})
+})
+
+// mediaTransport serves media of the given sizes by URL and records the requested URLs
+type mediaTransport struct {
+	sizes     map[string]int
+	requested []string
+}
+
+func (t *mediaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requested = append(t.requested, req.URL.String())
+	size, ok := t.sizes[req.URL.String()]
+	if !ok {
+		return &http.Response{StatusCode: http.StatusNotFound, Body: io.NopCloser(strings.NewReader("")), Request: req}, nil
+	}
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		Header:        http.Header{"Content-Type": []string{"image/jpeg"}},
+		Body:          io.NopCloser(strings.NewReader(strings.Repeat("x", size))),
+		ContentLength: int64(size),
+		Request:       req,
+	}, nil
+}
+
+var _ = Describe("Twitter downloadmedia", func() {
+	var scraper *TwitterScraper
+
+	BeforeEach(func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+		}
+		scraper = NewTwitterScraper(jc, stats.StartCollector(128, jc))
+	})
+
+	It("should be reported wherever credentials are available", func() {
+		caps := scraper.GetStructuredCapabilities()
+		Expect(caps[teetypes.TwitterCredentialJob]).To(ContainElement(CapDownloadMedia))
+		Expect(caps[teetypes.TwitterJob]).To(ContainElement(CapDownloadMedia))
+		Expect(caps[teetypes.TwitterApiJob]).NotTo(ContainElement(CapDownloadMedia))
+	})
+
+	DescribeTable("should reject invalid tweet IDs",
+		func(args map[string]interface{}) {
+			args["type"] = CapDownloadMedia
+			res, err := scraper.ExecuteJob(types.Job{Type: teetypes.TwitterCredentialJob, Arguments: args})
+			Expect(err).To(HaveOccurred())
+			Expect(res.Error).To(Equal("error unmarshalling job arguments"))
+		},
+		Entry("no tweet ID", map[string]interface{}{}),
+		Entry("non-numeric tweet ID", map[string]interface{}{"query": "abc"}),
+	)
+
+	It("should not be supported by the API job type", func() {
+		_, err := scraper.ExecuteJob(types.Job{
+			Type:      teetypes.TwitterApiJob,
+			Arguments: map[string]interface{}{"type": CapDownloadMedia, "query": "1234"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unsupported capability")))
+	})
+
+	It("should download photos in their original size and videos", func() {
+		transport := &mediaTransport{sizes: map[string]int{
+			"https://pbs.twimg.com/media/abc?format=jpg&name=orig": 10,
+			"https://video.twimg.com/vid/720x1280/v.mp4":           20,
+		}}
+		tweet := &teetypes.TweetResult{
+			TweetID: "1",
+			Photos:  []teetypes.Photo{{ID: "p1", URL: "https://pbs.twimg.com/media/abc.jpg"}},
+			Videos:  []teetypes.Video{{ID: "v1", URL: "https://video.twimg.com/vid/720x1280/v.mp4"}},
+		}
+
+		media, fanOut := DownloadTweetMedia(&http.Client{Transport: transport}, tweet, 0)
+		Expect(fanOut.HasFailures()).To(BeFalse())
+		Expect(media).To(HaveLen(2))
+		Expect(media[0]).To(And(HaveField("MediaID", "p1"), HaveField("Type", "photo"), HaveField("Size", 10), HaveField("ContentType", "image/jpeg")))
+		Expect(media[1]).To(And(HaveField("MediaID", "v1"), HaveField("Type", "video"), HaveField("Size", 20)))
+		Expect(media[1].Data).To(HaveLen(20))
+	})
+
+	It("should stop downloading once the maximum size is reached", func() {
+		transport := &mediaTransport{sizes: map[string]int{
+			"https://example.com/1.jpg": 10,
+			"https://example.com/2.jpg": 10,
+			"https://example.com/3.jpg": 5,
+		}}
+		tweet := &teetypes.TweetResult{
+			TweetID: "1",
+			Photos: []teetypes.Photo{
+				{ID: "1", URL: "https://example.com/1.jpg"},
+				{ID: "2", URL: "https://example.com/2.jpg"},
+				{ID: "3", URL: "https://example.com/3.jpg"},
+				{ID: "4", URL: "https://example.com/missing.jpg"},
+			},
+		}
+
+		media, fanOut := DownloadTweetMedia(&http.Client{Transport: transport}, tweet, 15)
+		Expect(media).To(HaveLen(2))
+		Expect(media[0].MediaID).To(Equal("1"))
+		Expect(media[1].MediaID).To(Equal("3"))
+		Expect(fanOut.Statuses).To(ContainElement(And(HaveField("Query", "2"), HaveField("Error", ContainSubstring("maximum size")))))
+		Expect(fanOut.Statuses).To(ContainElement(And(HaveField("Query", "4"), HaveField("Success", false))))
+	})
+})
+
+var _ = Describe("Twitter auth priority", func() {
+	// sourcesOf returns the auth sources of a capability of the general Twitter job, as reported in its details
+	sourcesOf := func(scraper *TwitterScraper, c teetypes.Capability) []types.AuthSource {
+		var sources []types.AuthSource
+		for _, detail := range scraper.GetCapabilityDetails()[teetypes.TwitterJob] {
+			if detail.Capability == c {
+				Expect(detail.Priority).To(Equal(len(sources) + 1))
+				sources = append(sources, detail.AuthSource)
+			}
+		}
+		return sources
+	}
+
+	It("should report the default order of the auth sources", func() {
+		jc := config.JobConfiguration{"twitter_accounts": []string{"user:pass"}, "twitter_api_keys": []string{"key"}, "apify_api_key": "apify_api_key"}
+		scraper := NewTwitterScraper(jc, stats.StartCollector(128, jc))
+		Expect(sourcesOf(scraper, teetypes.CapGetFollowers)).To(Equal([]types.AuthSource{types.AuthSourceApify, types.AuthSourceCredential}))
+		Expect(sourcesOf(scraper, teetypes.CapSearchByQuery)).To(Equal([]types.AuthSource{types.AuthSourceCredential, types.AuthSourceAPI}))
+		Expect(sourcesOf(scraper, CapGetByIds)).To(Equal([]types.AuthSource{types.AuthSourceAPI, types.AuthSourceCredential}))
+
+		// Capabilities with a single auth source have no priority
+		Expect(scraper.GetCapabilityDetails()[teetypes.TwitterJob]).To(ContainElement(And(
+			HaveField("Capability", teetypes.CapGetTrends),
+			HaveField("Priority", BeZero()),
+		)))
+	})
+
+	It("should try the configured auth sources first", func() {
+		jc := config.JobConfiguration{
+			"twitter_accounts": []string{"user:pass"},
+			"twitter_api_keys": []string{"key"},
+			"apify_api_key":    "apify_api_key",
+			"twitter_auth_priority": map[string][]string{
+				"getfollowers":  {"credential"},
+				"searchbyquery": {"apify", "api", "credential"},
+				"getbyids":      {"credential"},
+			},
+		}
+		scraper := NewTwitterScraper(jc, stats.StartCollector(128, jc))
+		Expect(sourcesOf(scraper, teetypes.CapGetFollowers)).To(Equal([]types.AuthSource{types.AuthSourceCredential, types.AuthSourceApify}))
+		Expect(sourcesOf(scraper, teetypes.CapSearchByQuery)).To(Equal([]types.AuthSource{types.AuthSourceAPI, types.AuthSourceCredential}))
+		Expect(sourcesOf(scraper, teetypes.CapGetById)).To(Equal([]types.AuthSource{types.AuthSourceCredential, types.AuthSourceAPI}))
+		Expect(sourcesOf(scraper, CapGetByIds)).To(Equal([]types.AuthSource{types.AuthSourceCredential, types.AuthSourceAPI}))
+	})
+
+	It("should fail with the error of the only auth source", func() {
+		jc := config.JobConfiguration{"twitter_auth_priority": map[string][]string{"getbyid": {"api"}}}
+		res, err := NewTwitterScraper(jc, stats.StartCollector(128, jc)).ExecuteJob(types.Job{
+			Type:      teetypes.TwitterJob,
+			Arguments: map[string]interface{}{"type": teetypes.CapGetById, "query": "1"},
+		})
+		Expect(err).To(MatchError("no Twitter credentials available"))
+		Expect(res.FanOut).To(BeEmpty())
+	})
+})
@@ -0,0 +1,182 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/sirupsen/logrus"
+)
+
+// CapGetTrendHistory returns the ranking of the trends over time, from the trend snapshots stored by earlier jobs and
+// from snapshots it takes at regular intervals while it runs. Like gettrends, it needs credentials to take snapshots.
+const CapGetTrendHistory teetypes.Capability = "gettrendhistory"
+
+// Defaults and limits of the arguments of gettrendhistory jobs
+const (
+	defaultTrendHistorySamples    = 1
+	maxTrendHistorySamples        = 60
+	defaultTrendHistoryInterval   = 5 * time.Minute
+	minTrendHistoryInterval       = 10 * time.Second
+	maxTrendHistoryInterval       = time.Hour
+	defaultTrendHistoryWindow     = 24 * time.Hour
+	maxTrendHistoryWindow         = 30 * 24 * time.Hour
+	defaultTrendHistoryMaxResults = 50
+)
+
+// TwitterTrendHistoryArguments are the arguments of a gettrendhistory job
+type TwitterTrendHistoryArguments struct {
+	QueryType string `json:"type"`
+	// Kind restricts the history to hashtags or cashtags, if set
+	Kind string `json:"kind"`
+	// Samples is the number of snapshots the job takes. If it is 0, only stored snapshots are returned.
+	Samples *int `json:"samples,omitempty"`
+	// IntervalSeconds is the time between two snapshots taken by the job
+	IntervalSeconds int `json:"interval_seconds"`
+	// WindowHours is how far back stored snapshots are included
+	WindowHours int `json:"window_hours"`
+	// MaxResults is the number of trends returned
+	MaxResults int `json:"max_results"`
+}
+
+// samples returns the number of snapshots the job takes
+func (a *TwitterTrendHistoryArguments) samples() int {
+	if a.Samples == nil {
+		return defaultTrendHistorySamples
+	}
+	return *a.Samples
+}
+
+// parseTrendHistoryArguments unmarshals and validates the arguments of a gettrendhistory job
+func parseTrendHistoryArguments(args map[string]any) (*TwitterTrendHistoryArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal gettrendhistory arguments: %w", err)
+	}
+
+	parsed := &TwitterTrendHistoryArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal gettrendhistory arguments: %w", err)
+	}
+
+	switch parsed.Kind {
+	case "", twitter.TrendHashtag, twitter.TrendCashtag:
+	default:
+		return nil, fmt.Errorf("kind must be %q or %q, got %q", twitter.TrendHashtag, twitter.TrendCashtag, parsed.Kind)
+	}
+
+	if samples := parsed.samples(); samples < 0 || samples > maxTrendHistorySamples {
+		return nil, fmt.Errorf("samples must be between 0 and %d, got: %d", maxTrendHistorySamples, samples)
+	}
+
+	interval := time.Duration(parsed.IntervalSeconds) * time.Second
+	if parsed.IntervalSeconds == 0 {
+		parsed.IntervalSeconds = int(defaultTrendHistoryInterval.Seconds())
+	} else if interval < minTrendHistoryInterval || interval > maxTrendHistoryInterval {
+		return nil, fmt.Errorf("interval_seconds must be between %d and %d, got: %d", int(minTrendHistoryInterval.Seconds()), int(maxTrendHistoryInterval.Seconds()), parsed.IntervalSeconds)
+	}
+
+	if window := time.Duration(parsed.WindowHours) * time.Hour; parsed.WindowHours < 0 || window > maxTrendHistoryWindow {
+		return nil, fmt.Errorf("window_hours must be between 0 and %d, got: %d", int(maxTrendHistoryWindow.Hours()), parsed.WindowHours)
+	}
+	if parsed.WindowHours == 0 {
+		parsed.WindowHours = int(defaultTrendHistoryWindow.Hours())
+	}
+
+	if parsed.MaxResults < 0 {
+		return nil, fmt.Errorf("max_results must be non-negative, got: %d", parsed.MaxResults)
+	}
+	if parsed.MaxResults == 0 {
+		parsed.MaxResults = defaultTrendHistoryMaxResults
+	}
+
+	return parsed, nil
+}
+
+// executeTrendHistory takes the snapshots of the trends asked for by the job, until its timeout, and ranks the trends
+// over them and the snapshots stored within the window of the job
+func (ts *TwitterScraper) executeTrendHistory(j types.Job) (types.JobResult, error) {
+	args, err := parseTrendHistoryArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	switch j.Type {
+	case teetypes.TwitterCredentialJob, teetypes.TwitterJob:
+	default:
+		return types.JobResult{Error: fmt.Sprintf("unsupported capability %s for %s job", CapGetTrendHistory, j.Type)}, fmt.Errorf("unsupported capability %s for %s job", CapGetTrendHistory, j.Type)
+	}
+
+	opts := twitter.TrendSampleOptions{
+		Samples:  args.samples(),
+		Interval: time.Duration(args.IntervalSeconds) * time.Second,
+	}
+	if j.Timeout > 0 {
+		opts.Deadline = time.Now().Add(j.Timeout)
+	}
+	sampled, sampleErr := twitter.SampleTrends(func() (*twitter.TrendSnapshot, error) {
+		return ts.takeTrendSnapshot(j, ts.configuration.DataDir)
+	}, opts)
+
+	window := time.Duration(args.WindowHours) * time.Hour
+	stored, err := ts.trendStore().Since(time.Now().Add(-window))
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	// The sampled snapshots have been stored, unless storing them failed
+	snapshots := stored
+	storedIDs := make(map[string]bool, len(stored))
+	for _, s := range stored {
+		storedIDs[s.ID] = true
+	}
+	for _, s := range sampled {
+		if s.ID == "" || !storedIDs[s.ID] {
+			snapshots = append(snapshots, s)
+		}
+	}
+
+	if sampleErr != nil {
+		if len(snapshots) == 0 {
+			return types.JobResult{Error: sampleErr.Error()}, sampleErr
+		}
+		logrus.Warnf("Returning the trend history of job %s after %d of %d snapshots: %v", j.UUID, len(sampled), opts.Samples, sampleErr)
+	}
+
+	history := twitter.BuildTrendHistory(snapshots, args.Kind, args.MaxResults)
+	history.SamplesTaken = len(sampled)
+	return processResponse(history, "", nil)
+}
+
+// trendStore returns the store of the trend snapshots
+func (ts *TwitterScraper) trendStore() *twitter.TrendStore {
+	return twitter.NewTrendStore(ts.configuration.DataDir, ts.configuration.MaxTrendSnapshots)
+}
+
+// takeTrendSnapshot fetches the current trends with credentials and stores them for gettrendhistory jobs. Failing to
+// store them is only logged, since the trends were fetched anyway.
+func (ts *TwitterScraper) takeTrendSnapshot(j types.Job, baseDir string) (*twitter.TrendSnapshot, error) {
+	scraper, account, err := ts.getCredentialScraper(j, baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	ts.addStat(j, stats.TwitterScrapes, 1)
+	snapshot := &twitter.TrendSnapshot{TakenAt: time.Now().UTC()}
+	if snapshot.Trends, err = scraper.GetTrends(); err != nil {
+		_ = ts.handleError(j, err, account)
+		return nil, err
+	}
+	ts.addStat(j, stats.TwitterOther, uint(len(snapshot.Trends)))
+
+	if err := ts.trendStore().Save(snapshot); err != nil {
+		snapshot.ID = ""
+		logrus.Warnf("Failed to store the trend snapshot: %v", err)
+	}
+	return snapshot, nil
+}
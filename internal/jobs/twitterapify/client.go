@@ -27,8 +27,8 @@ type TwitterApifyClient struct {
 }
 
 // NewTwitterApifyClient creates a new Twitter Apify client
-func NewTwitterApifyClient(apiToken string) (*TwitterApifyClient, error) {
-	apifyClient, err := client.NewApifyClient(apiToken)
+func NewTwitterApifyClient(apiToken string, opts ...client.Option) (*TwitterApifyClient, error) {
+	apifyClient, err := client.NewApifyClient(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create apify client: %w", err)
 	}
@@ -0,0 +1,269 @@
+package twittersyndication
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+const (
+	// DefaultTweetURL is the endpoint the embedded tweet widget fetches tweets from
+	DefaultTweetURL = "https://cdn.syndication.twimg.com/tweet-result"
+
+	// DefaultTimelineURL is the base URL of the pages the embedded timeline widget is rendered from
+	DefaultTimelineURL = "https://syndication.twitter.com/srv/timeline-profile"
+)
+
+var (
+	// ErrRateLimited is returned when the syndication endpoints respond with 429 Too Many Requests
+	ErrRateLimited = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	// ErrNotFound is returned when the tweet or user doesn't exist, is protected or is not embeddable
+	ErrNotFound = types.NewCodedError(types.ErrorCodeNotFound, "not found")
+)
+
+// User is a Twitter user as returned by the syndication endpoints, in the format of the v1.1 API
+type User struct {
+	IDStr                string `json:"id_str"`
+	Name                 string `json:"name"`
+	ScreenName           string `json:"screen_name"`
+	Description          string `json:"description"`
+	Location             string `json:"location"`
+	URL                  string `json:"url"`
+	ProfileImageURLHTTPS string `json:"profile_image_url_https"`
+	ProfileBannerURL     string `json:"profile_banner_url"`
+	FollowersCount       int    `json:"followers_count"`
+	FriendsCount         int    `json:"friends_count"`
+	ListedCount          int    `json:"listed_count"`
+	FavouritesCount      int    `json:"favourites_count"`
+	StatusesCount        int    `json:"statuses_count"`
+	MediaCount           int    `json:"media_count"`
+	CreatedAt            string `json:"created_at"`
+	Protected            bool   `json:"protected"`
+	Verified             bool   `json:"verified"`
+	IsBlueVerified       bool   `json:"is_blue_verified"`
+}
+
+// Media is a photo or video attached to a tweet
+type Media struct {
+	IDStr         string `json:"id_str"`
+	Type          string `json:"type"`
+	MediaURLHTTPS string `json:"media_url_https"`
+	VideoInfo     struct {
+		Variants []struct {
+			URL         string `json:"url"`
+			ContentType string `json:"content_type"`
+		} `json:"variants"`
+	} `json:"video_info"`
+}
+
+// Tweet is a tweet as returned by the embedded tweet widget endpoint
+type Tweet struct {
+	TypeName             string `json:"__typename"`
+	IDStr                string `json:"id_str"`
+	Text                 string `json:"text"`
+	CreatedAt            string `json:"created_at"`
+	Lang                 string `json:"lang"`
+	FavoriteCount        int    `json:"favorite_count"`
+	ConversationCount    int    `json:"conversation_count"`
+	InReplyToStatusIDStr string `json:"in_reply_to_status_id_str"`
+	PossiblySensitive    bool   `json:"possibly_sensitive"`
+	User                 User   `json:"user"`
+	Entities             struct {
+		Hashtags []struct {
+			Text string `json:"text"`
+		} `json:"hashtags"`
+		URLs []struct {
+			ExpandedURL string `json:"expanded_url"`
+		} `json:"urls"`
+	} `json:"entities"`
+	MediaDetails []Media `json:"mediaDetails"`
+	QuotedTweet  *Tweet  `json:"quoted_tweet"`
+}
+
+// Client fetches public tweets and profiles from the endpoints behind Twitter's embeddable widgets. They need no
+// credentials, but only return what a logged out visitor of an embedding page can see, and are cached by a CDN.
+type Client struct {
+	TweetURL    string
+	TimelineURL string
+	HTTPClient  *http.Client
+}
+
+// NewClient creates a new syndication client
+func NewClient() *Client {
+	return &Client{
+		TweetURL:    DefaultTweetURL,
+		TimelineURL: DefaultTimelineURL,
+		HTTPClient:  &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// GetTweet returns the tweet with the given ID
+func (c *Client) GetTweet(id string) (*Tweet, error) {
+	if _, err := strconv.ParseUint(id, 10, 64); err != nil {
+		return nil, fmt.Errorf("invalid tweet ID %q", id)
+	}
+
+	params := url.Values{}
+	params.Set("id", id)
+	params.Set("lang", "en")
+	params.Set("token", tweetToken(id))
+	body, err := c.get(c.TweetURL + "?" + params.Encode())
+	if err != nil {
+		return nil, err
+	}
+
+	var tweet Tweet
+	// Tweets which are not available anymore are answered with an empty object or a tombstone
+	if err := json.Unmarshal(body, &tweet); err != nil {
+		return nil, fmt.Errorf("error parsing response from %s: %w", c.TweetURL, err)
+	}
+	if tweet.IDStr == "" || tweet.TypeName == "TweetTombstone" {
+		return nil, ErrNotFound
+	}
+	return &tweet, nil
+}
+
+// GetProfileByUsername returns the user with the given screen name
+func (c *Client) GetProfileByUsername(username string) (*User, error) {
+	return c.timelineUser("screen-name/"+url.PathEscape(strings.TrimPrefix(username, "@")), func(u User) bool {
+		return strings.EqualFold(u.ScreenName, strings.TrimPrefix(username, "@"))
+	})
+}
+
+// GetProfileByID returns the user with the given ID
+func (c *Client) GetProfileByID(userID string) (*User, error) {
+	return c.timelineUser("user-id/"+url.PathEscape(userID), func(u User) bool {
+		return u.IDStr == userID
+	})
+}
+
+// nextData is the data the timeline page is rendered from
+var nextData = regexp.MustCompile(`(?s)<script id="__NEXT_DATA__"[^>]*>(.*?)</script>`)
+
+type timelinePage struct {
+	Props struct {
+		PageProps struct {
+			Timeline struct {
+				Entries []struct {
+					Content struct {
+						Tweet *struct {
+							User User `json:"user"`
+						} `json:"tweet"`
+					} `json:"content"`
+				} `json:"entries"`
+			} `json:"timeline"`
+		} `json:"pageProps"`
+	} `json:"props"`
+}
+
+// timelineUser returns the user of the timeline page at the given path, taken from the first of its tweets whose
+// author matches. Users without any tweet can't be found.
+func (c *Client) timelineUser(path string, matches func(User) bool) (*User, error) {
+	body, err := c.get(strings.TrimRight(c.TimelineURL, "/") + "/" + path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := nextData.FindSubmatch(body)
+	if m == nil {
+		return nil, fmt.Errorf("no timeline data in response from %s", c.TimelineURL)
+	}
+	var page timelinePage
+	if err := json.Unmarshal(m[1], &page); err != nil {
+		return nil, fmt.Errorf("error parsing timeline data from %s: %w", c.TimelineURL, err)
+	}
+
+	for _, entry := range page.Props.PageProps.Timeline.Entries {
+		if tweet := entry.Content.Tweet; tweet != nil && matches(tweet.User) {
+			return &tweet.User, nil
+		}
+	}
+	return nil, ErrNotFound
+}
+
+func (c *Client) get(u string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error querying %s: %w", req.URL.Host, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response from %s: %w", req.URL.Host, err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusTooManyRequests:
+		return nil, ErrRateLimited
+	case http.StatusNotFound:
+		return nil, ErrNotFound
+	default:
+		return nil, fmt.Errorf("%s returned status code %d", req.URL.Host, resp.StatusCode)
+	}
+}
+
+// tweetToken returns the token the embedded tweet widget sends along with the ID of a tweet: (id / 1e15) * π in base
+// 36, without its zeros and point
+func tweetToken(id string) string {
+	n, _ := strconv.ParseFloat(id, 64)
+	return strings.NewReplacer("0", "", ".", "").Replace(formatBase36(n / 1e15 * math.Pi))
+}
+
+// formatBase36 formats a non-negative number in base 36 with the shortest fraction which identifies it, as
+// JavaScript's Number.prototype.toString(36) does
+func formatBase36(v float64) string {
+	const digits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+	integer := math.Floor(v)
+	fraction := v - integer
+	// Digits are only added while they tell v apart from its neighbours
+	delta := max(0.5*(math.Nextafter(v, math.Inf(1))-v), math.Nextafter(0, 1))
+
+	var frac []byte
+	for fraction >= delta {
+		fraction *= 36
+		delta *= 36
+		d := int(fraction)
+		frac = append(frac, digits[d])
+		fraction -= float64(d)
+		if (fraction > 0.5 || (fraction == 0.5 && d&1 == 1)) && fraction+delta > 1 {
+			// Round up, carrying over to the previous digits
+			for {
+				if len(frac) == 0 {
+					integer++
+					break
+				}
+				last := strings.IndexByte(digits, frac[len(frac)-1])
+				frac = frac[:len(frac)-1]
+				if last+1 < len(digits) {
+					frac = append(frac, digits[last+1])
+					break
+				}
+			}
+			break
+		}
+	}
+
+	s := strconv.FormatInt(int64(integer), 36)
+	if len(frac) > 0 {
+		s += "." + string(frac)
+	}
+	return s
+}
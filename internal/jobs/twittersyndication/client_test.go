@@ -0,0 +1,125 @@
+package twittersyndication_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/twittersyndication"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+		status   int
+		body     string
+		c        *twittersyndication.Client
+	)
+
+	BeforeEach(func() {
+		requests, status, body = nil, http.StatusOK, ""
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests = append(requests, r)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}))
+		c = twittersyndication.NewClient()
+		c.TweetURL = server.URL + "/tweet-result"
+		c.TimelineURL = server.URL + "/srv/timeline-profile"
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Describe("GetTweet", func() {
+		It("returns the tweet", func() {
+			body = `{"__typename":"Tweet","id_str":"1234567890123456789","text":"hello #go","created_at":"2024-01-02T03:04:05.000Z","favorite_count":3,"user":{"id_str":"42","screen_name":"jack"},"entities":{"hashtags":[{"text":"go"}]}}`
+
+			tweet, err := c.GetTweet("1234567890123456789")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tweet.Text).To(Equal("hello #go"))
+			Expect(tweet.User.ScreenName).To(Equal("jack"))
+			Expect(tweet.Entities.Hashtags).To(HaveLen(1))
+		})
+
+		It("sends the token of the embedded tweet widget", func() {
+			body = `{"__typename":"Tweet","id_str":"20","text":"just setting up my twttr"}`
+
+			_, err := c.GetTweet("20")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests[0].URL.Path).To(Equal("/tweet-result"))
+			Expect(requests[0].URL.Query().Get("id")).To(Equal("20"))
+			Expect(requests[0].URL.Query().Get("token")).To(Equal("6dq1a2xwd93"))
+
+			_, _ = c.GetTweet("1234567890123456789")
+			Expect(requests[1].URL.Query().Get("token")).To(Equal("2zqic77uqyk"))
+		})
+
+		It("returns ErrNotFound for missing and unavailable tweets", func() {
+			body = `{}`
+			_, err := c.GetTweet("20")
+			Expect(err).To(MatchError(twittersyndication.ErrNotFound))
+
+			body = `{"__typename":"TweetTombstone","id_str":"20"}`
+			_, err = c.GetTweet("20")
+			Expect(err).To(MatchError(twittersyndication.ErrNotFound))
+
+			status, body = http.StatusNotFound, ""
+			_, err = c.GetTweet("20")
+			Expect(err).To(MatchError(twittersyndication.ErrNotFound))
+		})
+
+		It("returns ErrRateLimited if it is rate limited", func() {
+			status = http.StatusTooManyRequests
+			_, err := c.GetTweet("20")
+			Expect(err).To(MatchError(twittersyndication.ErrRateLimited))
+		})
+
+		It("rejects IDs which are not numbers", func() {
+			_, err := c.GetTweet("../20")
+			Expect(err).To(HaveOccurred())
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Describe("profiles", func() {
+		const page = `<html><body><script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"timeline":{"entries":[
+			{"type":"tweet","content":{"tweet":{"id_str":"1","user":{"id_str":"7","screen_name":"other"}}}},
+			{"type":"tweet","content":{"tweet":{"id_str":"2","user":{"id_str":"42","screen_name":"Jack","followers_count":10}}}}
+		]}}}}</script></body></html>`
+
+		It("returns the user of a timeline by screen name", func() {
+			body = page
+			user, err := c.GetProfileByUsername("@jack")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests[0].URL.Path).To(Equal("/srv/timeline-profile/screen-name/jack"))
+			Expect(user.IDStr).To(Equal("42"))
+			Expect(user.FollowersCount).To(Equal(10))
+		})
+
+		It("returns the user of a timeline by ID", func() {
+			body = page
+			user, err := c.GetProfileByID("42")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests[0].URL.Path).To(Equal("/srv/timeline-profile/user-id/42"))
+			Expect(user.ScreenName).To(Equal("Jack"))
+		})
+
+		It("returns ErrNotFound if no tweet of the timeline is by the user", func() {
+			body = `<script id="__NEXT_DATA__" type="application/json">{"props":{"pageProps":{"timeline":{"entries":[]}}}}</script>`
+			_, err := c.GetProfileByID("42")
+			Expect(err).To(MatchError(twittersyndication.ErrNotFound))
+		})
+
+		It("fails if the page has no timeline data", func() {
+			body = `<html></html>`
+			_, err := c.GetProfileByUsername("jack")
+			Expect(err).To(HaveOccurred())
+			Expect(err).NotTo(MatchError(twittersyndication.ErrNotFound))
+		})
+	})
+})
@@ -0,0 +1,13 @@
+package twittersyndication_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestTwitterSyndication(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Twitter Syndication Client Suite")
+}
@@ -2,7 +2,6 @@ package twitterx
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -11,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/pkg/client"
 	"github.com/sirupsen/logrus"
 )
@@ -21,10 +21,10 @@ const (
 )
 
 var (
-	ErrInvalidAPIKey     = errors.New("invalid API key")
-	ErrRateLimitExceeded = errors.New("rate limit exceeded")
-	ErrUserNotFound      = errors.New("user not found")
-	ErrTweetNotFound     = errors.New("tweet not found")
+	ErrInvalidAPIKey     = types.NewCodedError(types.ErrorCodeAuth, "invalid API key")
+	ErrRateLimitExceeded = types.NewCodedError(types.ErrorCodeRateLimited, "rate limit exceeded")
+	ErrUserNotFound      = types.NewCodedError(types.ErrorCodeNotFound, "user not found")
+	ErrTweetNotFound     = types.NewCodedError(types.ErrorCodeNotFound, "tweet not found")
 )
 
 type TwitterXScraper struct {
@@ -97,20 +97,6 @@ type TwitterMeta struct {
 	NextCursor  string `json:"next_token"`
 }
 
-// UserLookupResponse structure for the user lookup endpoint
-type UserLookupResponse struct {
-	Data struct {
-		ID       string `json:"id"`
-		Name     string `json:"name"`
-		Username string `json:"username"`
-	} `json:"data"`
-	Errors []struct {
-		Message string `json:"message"`
-		Code    int    `json:"code"`
-		Title   string `json:"title"`
-	} `json:"errors,omitempty"`
-}
-
 // TwitterXProfileResponse represents the complete user profile response from TwitterX API
 type TwitterXProfileResponse struct {
 	Data   TwitterXProfileData `json:"data"`
@@ -168,6 +154,26 @@ type TwitterXTweetResponse struct {
 	} `json:"errors,omitempty"`
 }
 
+// TwitterXTweetsResponse represents the response of the bulk tweet lookup endpoint. Tweets which could not be
+// retrieved are reported in Errors, with their ID in ResourceID.
+type TwitterXTweetsResponse struct {
+	Data     []TwitterXTweetData `json:"data"`
+	Includes struct {
+		Users []struct {
+			ID       string `json:"id"`
+			Username string `json:"username"`
+		} `json:"users"`
+	} `json:"includes,omitempty"`
+	Errors []TwitterXLookupError `json:"errors,omitempty"`
+}
+
+// TwitterXLookupError is a partial error of a bulk lookup, e.g. a deleted or protected tweet
+type TwitterXLookupError struct {
+	ResourceID string `json:"resource_id"`
+	Title      string `json:"title"`
+	Detail     string `json:"detail"`
+}
+
 // TwitterXTweetData represents the tweet data from TwitterX API
 type TwitterXTweetData struct {
 	ID                  string                      `json:"id"`
@@ -306,6 +312,13 @@ func NewTwitterXScraper(client *client.TwitterXClient) *TwitterXScraper {
 }
 
 func (s *TwitterXScraper) ScrapeTweetsByQuery(baseQueryEndpoint string, query string, count int, cursor string) (*TwitterXSearchQueryResult, error) {
+	return s.ScrapeTweetsBySearchParams(baseQueryEndpoint, SearchParams{Query: query, MaxResults: count, NextToken: cursor})
+}
+
+// ScrapeTweetsBySearchParams searches tweets like ScrapeTweetsByQuery, additionally limiting the results to the tweet
+// IDs between SinceID and UntilID if they are set. TweetFields is ignored, all tweet fields are always requested.
+func (s *TwitterXScraper) ScrapeTweetsBySearchParams(baseQueryEndpoint string, searchParams SearchParams) (*TwitterXSearchQueryResult, error) {
+	query, count, cursor := searchParams.Query, searchParams.MaxResults, searchParams.NextToken
 	switch baseQueryEndpoint {
 	case TweetsAll:
 		count = min(max(count, 10), 499)
@@ -338,6 +351,13 @@ func (s *TwitterXScraper) ScrapeTweetsByQuery(baseQueryEndpoint string, query st
 		params.Add("next_token", cursor)
 	}
 
+	if searchParams.SinceID != "" {
+		params.Add("since_id", searchParams.SinceID)
+	}
+	if searchParams.UntilID != "" {
+		params.Add("until_id", searchParams.UntilID)
+	}
+
 	// Add tweet fields
 	params.Add("tweet.fields", "created_at,author_id,public_metrics,context_annotations,geo,lang,possibly_sensitive,source,withheld,attachments,entities,conversation_id,in_reply_to_user_id,referenced_tweets,reply_settings,media_metadata,note_tweet,display_text_range,edit_controls,edit_history_tweet_ids,article,card_uri,community_id")
 
@@ -402,153 +422,198 @@ func (s *TwitterXScraper) containsSpecialChars(str string) bool {
 	return strings.ContainsAny(str, "$@#!%^&*()+={}[]:;'\"\\|<>,.?/~` ")
 }
 
-// fetchUsernames retrieves the username for each author_id in the search results
-func (s *TwitterXScraper) fetchUsernames(result *TwitterXSearchQueryResult) error {
-	// Early return if no results
-	if len(result.Data) == 0 {
-		return nil
-	}
+// GetProfileByID fetches complete user profile information by user ID
+func (s *TwitterXScraper) GetProfileByID(userID string) (*TwitterXProfileResponse, error) {
+	logrus.Infof("Looking up profile for user with ID: %s", userID)
 
-	logrus.Infof("Fetching usernames for %d tweets", len(result.Data))
+	// Construct endpoint URL with user fields
+	endpoint := fmt.Sprintf("users/%s?user.fields=id,name,username,description,location,url,verified,protected,created_at,profile_image_url,profile_banner_url,public_metrics", userID)
 
-	// Create a map to track which author IDs we've already processed
-	// to avoid duplicate lookups for the same author
-	processedAuthors := make(map[string]string)
+	// Make the request
+	resp, err := s.twitterXClient.Get(endpoint)
+	if err != nil {
+		logrus.Errorf("Error looking up profile: %v", err)
+		return nil, fmt.Errorf("error looking up profile: %w", err)
+	}
+	defer resp.Body.Close()
 
-	// For each tweet in the result
-	for i, tweet := range result.Data {
-		// Skip if author ID is empty
-		if tweet.AuthorID == "" {
-			continue
-		}
+	// Read response body
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logrus.Errorf("Error reading response body: %v", err)
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
 
-		// Check if we've already looked up this author
-		if username, exists := processedAuthors[tweet.AuthorID]; exists {
-			// Use the cached username
-			result.Data[i].Username = username
-			continue
+	// Check response status first
+	switch resp.StatusCode {
+	case http.StatusOK:
+		// Parse into structured type
+		var profileResp TwitterXProfileResponse
+		if err := json.Unmarshal(body, &profileResp); err != nil {
+			logrus.Errorf("Error parsing response: %v", err)
+			return nil, fmt.Errorf("error parsing response: %w", err)
 		}
 
-		// Look up the user by ID
-		username, err := s.lookupUserByID(tweet.AuthorID)
-		if err != nil {
-			logrus.Warnf("Failed to lookup user ID %s: %v", tweet.AuthorID, err)
-			continue
+		// Check for API errors
+		if len(profileResp.Errors) > 0 {
+			logrus.Errorf("API error: %s (code: %d)", profileResp.Errors[0].Message, profileResp.Errors[0].Code)
+			return nil, fmt.Errorf("API error: %s", profileResp.Errors[0].Message)
 		}
 
-		// Store the username in the tweet data
-		result.Data[i].Username = username
+		logrus.Infof("Successfully retrieved profile for user %s (@%s)", profileResp.Data.Name, profileResp.Data.Username)
+		return &profileResp, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidAPIKey
+	case http.StatusTooManyRequests:
+		return nil, ErrRateLimitExceeded
+	case http.StatusNotFound:
+		return nil, ErrUserNotFound
+	default:
+		return nil, fmt.Errorf("API profile lookup failed with status: %d, body: %s", resp.StatusCode, string(body))
+	}
+}
 
-		// Cache the username for potential reuse
-		processedAuthors[tweet.AuthorID] = username
+// MaxTweetIDsPerLookup is the maximum number of IDs accepted by the bulk tweet lookup endpoint
+const MaxTweetIDsPerLookup = 100
 
-		// Add a small delay to avoid hitting rate limits
-		time.Sleep(50 * time.Millisecond)
-	}
+const tweetLookupFields = "tweet.fields=created_at,author_id,public_metrics,context_annotations,geo,lang,possibly_sensitive,source,withheld,attachments,entities,conversation_id,in_reply_to_user_id,referenced_tweets,reply_settings,edit_controls,edit_history_tweet_ids&user.fields=username&expansions=author_id"
 
-	logrus.Infof("Successfully fetched usernames for tweets")
-	return nil
-}
+// GetTweetsByIDs fetches up to MaxTweetIDsPerLookup tweets with a single request using the TwitterX bulk lookup endpoint.
+// Tweets which could not be retrieved are not returned as an error, but as lookup errors.
+func (s *TwitterXScraper) GetTweetsByIDs(tweetIDs []string) ([]TwitterXTweetData, []TwitterXLookupError, error) {
+	if len(tweetIDs) == 0 {
+		return nil, nil, nil
+	}
+	if len(tweetIDs) > MaxTweetIDsPerLookup {
+		return nil, nil, fmt.Errorf("at most %d tweet IDs can be looked up at once, got %d", MaxTweetIDsPerLookup, len(tweetIDs))
+	}
 
-// ScrapeTweetsByQueryExtended Example extended version that supports pagination and additional parameters
-// lookupUserByID fetches user information by user ID
-func (s *TwitterXScraper) lookupUserByID(userID string) (string, error) {
-	logrus.Infof("Looking up user with ID: %s", userID)
+	logrus.Infof("Looking up %d tweets", len(tweetIDs))
 
-	// Construct endpoint URL
-	endpoint := fmt.Sprintf("users/%s", userID)
+	endpoint := "tweets?ids=" + strings.Join(tweetIDs, ",") + "&" + tweetLookupFields
 
-	// Make the request
 	resp, err := s.twitterXClient.Get(endpoint)
 	if err != nil {
-		logrus.Errorf("Error looking up user: %v", err)
-		return "", fmt.Errorf("error looking up user: %w", err)
+		logrus.Errorf("Error looking up tweets: %v", err)
+		return nil, nil, fmt.Errorf("error looking up tweets: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.Errorf("Error reading response body: %v", err)
-		return "", fmt.Errorf("error reading response body: %w", err)
-	}
-
-	// Parse response
-	var userResp UserLookupResponse
-	if err := json.Unmarshal(body, &userResp); err != nil {
-		logrus.Errorf("Error parsing response: %v", err)
-		return "", fmt.Errorf("error parsing response: %w", err)
+		return nil, nil, fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// Check for errors
-	if len(userResp.Errors) > 0 {
-		logrus.Errorf("API error: %s (code: %d)", userResp.Errors[0].Message, userResp.Errors[0].Code)
-		return "", fmt.Errorf("API error: %s (code: %d)", userResp.Errors[0].Message, userResp.Errors[0].Code)
-	}
-
-	// Check response status
 	switch resp.StatusCode {
 	case http.StatusOK:
-		return userResp.Data.Username, nil
+		var tweetsResp TwitterXTweetsResponse
+		if err := json.Unmarshal(body, &tweetsResp); err != nil {
+			logrus.Errorf("Error parsing response: %v", err)
+			return nil, nil, fmt.Errorf("error parsing response: %w", err)
+		}
+
+		usernames := make(map[string]string, len(tweetsResp.Includes.Users))
+		for _, user := range tweetsResp.Includes.Users {
+			usernames[user.ID] = user.Username
+		}
+		for i := range tweetsResp.Data {
+			tweetsResp.Data[i].Username = usernames[tweetsResp.Data[i].AuthorID]
+		}
+
+		logrus.Infof("Successfully retrieved %d of %d tweets", len(tweetsResp.Data), len(tweetIDs))
+		return tweetsResp.Data, tweetsResp.Errors, nil
 	case http.StatusUnauthorized:
-		return "", ErrInvalidAPIKey
+		return nil, nil, ErrInvalidAPIKey
 	case http.StatusTooManyRequests:
-		return "", ErrRateLimitExceeded
-	case http.StatusNotFound:
-		return "", ErrUserNotFound
+		return nil, nil, ErrRateLimitExceeded
 	default:
-		return "", fmt.Errorf("API user lookup failed with status: %d", resp.StatusCode)
+		return nil, nil, fmt.Errorf("API tweets lookup failed with status: %d, body: %s", resp.StatusCode, string(body))
 	}
 }
 
-// GetProfileByID fetches complete user profile information by user ID
-func (s *TwitterXScraper) GetProfileByID(userID string) (*TwitterXProfileResponse, error) {
-	logrus.Infof("Looking up profile for user with ID: %s", userID)
+// TwitterXPoll is a poll attached to a tweet, as returned by the poll.fields of the TwitterX API
+type TwitterXPoll struct {
+	ID              string               `json:"id"`
+	Options         []TwitterXPollOption `json:"options"`
+	DurationMinutes int                  `json:"duration_minutes"`
+	EndDatetime     time.Time            `json:"end_datetime"`
+	VotingStatus    string               `json:"voting_status"`
+}
 
-	// Construct endpoint URL with user fields
-	endpoint := fmt.Sprintf("users/%s?user.fields=id,name,username,description,location,url,verified,protected,created_at,profile_image_url,profile_banner_url,public_metrics", userID)
+// TwitterXPollOption is an option of a poll, with the number of votes it received
+type TwitterXPollOption struct {
+	Position int    `json:"position"`
+	Label    string `json:"label"`
+	Votes    int    `json:"votes"`
+}
+
+// twitterXPollResponse represents the response of a tweet lookup with the poll expansion
+type twitterXPollResponse struct {
+	Data struct {
+		ID          string `json:"id"`
+		Attachments struct {
+			PollIDs []string `json:"poll_ids"`
+		} `json:"attachments"`
+	} `json:"data"`
+	Includes struct {
+		Polls []TwitterXPoll `json:"polls"`
+	} `json:"includes,omitempty"`
+	Errors []struct {
+		Title  string `json:"title"`
+		Detail string `json:"detail"`
+	} `json:"errors,omitempty"`
+}
+
+// GetPoll fetches the poll attached to a tweet using the TwitterX API. It returns nil if the tweet has no poll.
+func (s *TwitterXScraper) GetPoll(tweetID string) (*TwitterXPoll, error) {
+	logrus.Infof("Looking up the poll of tweet %s", tweetID)
+
+	endpoint := fmt.Sprintf("tweets/%s?expansions=attachments.poll_ids&poll.fields=duration_minutes,end_datetime,options,voting_status", tweetID)
 
-	// Make the request
 	resp, err := s.twitterXClient.Get(endpoint)
 	if err != nil {
-		logrus.Errorf("Error looking up profile: %v", err)
-		return nil, fmt.Errorf("error looking up profile: %w", err)
+		logrus.Errorf("Error looking up poll: %v", err)
+		return nil, fmt.Errorf("error looking up poll: %w", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		logrus.Errorf("Error reading response body: %v", err)
 		return nil, fmt.Errorf("error reading response body: %w", err)
 	}
 
-	// Check response status first
 	switch resp.StatusCode {
 	case http.StatusOK:
-		// Parse into structured type
-		var profileResp TwitterXProfileResponse
-		if err := json.Unmarshal(body, &profileResp); err != nil {
+		var pollResp twitterXPollResponse
+		if err := json.Unmarshal(body, &pollResp); err != nil {
 			logrus.Errorf("Error parsing response: %v", err)
 			return nil, fmt.Errorf("error parsing response: %w", err)
 		}
-
-		// Check for API errors
-		if len(profileResp.Errors) > 0 {
-			logrus.Errorf("API error: %s (code: %d)", profileResp.Errors[0].Message, profileResp.Errors[0].Code)
-			return nil, fmt.Errorf("API error: %s", profileResp.Errors[0].Message)
+		if pollResp.Data.ID == "" {
+			if len(pollResp.Errors) > 0 {
+				return nil, fmt.Errorf("%w: %s", ErrTweetNotFound, pollResp.Errors[0].Detail)
+			}
+			return nil, ErrTweetNotFound
 		}
 
-		logrus.Infof("Successfully retrieved profile for user %s (@%s)", profileResp.Data.Name, profileResp.Data.Username)
-		return &profileResp, nil
+		for _, pollID := range pollResp.Data.Attachments.PollIDs {
+			for i := range pollResp.Includes.Polls {
+				if pollResp.Includes.Polls[i].ID == pollID {
+					return &pollResp.Includes.Polls[i], nil
+				}
+			}
+		}
+		return nil, nil
 	case http.StatusUnauthorized:
 		return nil, ErrInvalidAPIKey
 	case http.StatusTooManyRequests:
 		return nil, ErrRateLimitExceeded
 	case http.StatusNotFound:
-		return nil, ErrUserNotFound
+		return nil, ErrTweetNotFound
 	default:
-		return nil, fmt.Errorf("API profile lookup failed with status: %d, body: %s", resp.StatusCode, string(body))
+		return nil, fmt.Errorf("API poll lookup failed with status: %d, body: %s", resp.StatusCode, string(body))
 	}
 }
 
@@ -557,7 +622,7 @@ func (s *TwitterXScraper) GetTweetByID(tweetID string) (*TwitterXTweetData, erro
 	logrus.Infof("Looking up tweet with ID: %s", tweetID)
 
 	// Construct endpoint URL with tweet fields and expansions
-	endpoint := fmt.Sprintf("tweets/%s?tweet.fields=created_at,author_id,public_metrics,context_annotations,geo,lang,possibly_sensitive,source,withheld,attachments,entities,conversation_id,in_reply_to_user_id,referenced_tweets,reply_settings,edit_controls,edit_history_tweet_ids&user.fields=username&expansions=author_id", tweetID)
+	endpoint := fmt.Sprintf("tweets/%s?%s", tweetID, tweetLookupFields)
 
 	// Make the request
 	resp, err := s.twitterXClient.Get(endpoint)
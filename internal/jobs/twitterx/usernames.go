@@ -0,0 +1,170 @@
+package twitterx
+
+import (
+	"container/list"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// MaxUserIDsPerLookup is the maximum number of IDs accepted by the bulk user lookup endpoint
+const MaxUserIDsPerLookup = 100
+
+// maxCachedUsernames is the number of usernames kept by the username cache
+const maxCachedUsernames = 10000
+
+// UsersLookupResponse represents the response of the bulk user lookup endpoint. Users which could not be retrieved
+// are reported in Errors, with their ID in ResourceID.
+type UsersLookupResponse struct {
+	Data []struct {
+		ID       string `json:"id"`
+		Name     string `json:"name"`
+		Username string `json:"username"`
+	} `json:"data"`
+	Errors []TwitterXLookupError `json:"errors,omitempty"`
+}
+
+// usernameCache is a least recently used cache of the usernames of user IDs. Usernames rarely change, and the authors
+// of tweets repeat a lot across jobs, so it is shared by all scrapers of the process.
+type usernameCache struct {
+	sync.Mutex
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+}
+
+type usernameEntry struct {
+	id       string
+	username string
+}
+
+var usernames = newUsernameCache(maxCachedUsernames)
+
+func newUsernameCache(size int) *usernameCache {
+	return &usernameCache{size: size, order: list.New(), entries: make(map[string]*list.Element)}
+}
+
+func (c *usernameCache) get(id string) (string, bool) {
+	c.Lock()
+	defer c.Unlock()
+	e, ok := c.entries[id]
+	if !ok {
+		return "", false
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(*usernameEntry).username, true
+}
+
+// add caches a username, evicting the least recently used one if the cache is full
+func (c *usernameCache) add(id, username string) {
+	c.Lock()
+	defer c.Unlock()
+	if e, ok := c.entries[id]; ok {
+		e.Value.(*usernameEntry).username = username
+		c.order.MoveToFront(e)
+		return
+	}
+	c.entries[id] = c.order.PushFront(&usernameEntry{id: id, username: username})
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*usernameEntry).id)
+	}
+}
+
+// fetchUsernames sets the username of the author of each tweet in the search results. Usernames which are not cached
+// are looked up in batches of MaxUserIDsPerLookup. Tweets whose author could not be looked up keep an empty username.
+func (s *TwitterXScraper) fetchUsernames(result *TwitterXSearchQueryResult) error {
+	var missing []string
+	seen := make(map[string]bool)
+	for _, tweet := range result.Data {
+		if tweet.AuthorID == "" || seen[tweet.AuthorID] {
+			continue
+		}
+		seen[tweet.AuthorID] = true
+		if _, ok := usernames.get(tweet.AuthorID); !ok {
+			missing = append(missing, tweet.AuthorID)
+		}
+	}
+
+	logrus.Infof("Fetching usernames for %d tweets, %d authors are not cached", len(result.Data), len(missing))
+
+	var errs []error
+	found := make(map[string]string, len(missing))
+	for batch := range slices.Chunk(missing, MaxUserIDsPerLookup) {
+		batchUsernames, err := s.lookupUsernames(batch)
+		if err != nil {
+			logrus.Warnf("Failed to lookup %d users: %v", len(batch), err)
+			errs = append(errs, err)
+			continue
+		}
+		for id, username := range batchUsernames {
+			usernames.add(id, username)
+			found[id] = username
+		}
+	}
+
+	for i, tweet := range result.Data {
+		// The usernames looked up by this job are used even if they have already been evicted from the cache again
+		if username, ok := found[tweet.AuthorID]; ok {
+			result.Data[i].Username = username
+		} else if username, ok := usernames.get(tweet.AuthorID); ok {
+			result.Data[i].Username = username
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// lookupUsernames fetches the usernames of up to MaxUserIDsPerLookup users with a single request using the bulk user
+// lookup endpoint. Users which could not be retrieved, e.g. suspended ones, are left out.
+func (s *TwitterXScraper) lookupUsernames(userIDs []string) (map[string]string, error) {
+	if len(userIDs) > MaxUserIDsPerLookup {
+		return nil, fmt.Errorf("at most %d user IDs can be looked up at once, got %d", MaxUserIDsPerLookup, len(userIDs))
+	}
+
+	logrus.Infof("Looking up %d users", len(userIDs))
+
+	resp, err := s.twitterXClient.Get("users?ids=" + strings.Join(userIDs, ","))
+	if err != nil {
+		logrus.Errorf("Error looking up users: %v", err)
+		return nil, fmt.Errorf("error looking up users: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		logrus.Errorf("Error reading response body: %v", err)
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		var usersResp UsersLookupResponse
+		if err := json.Unmarshal(body, &usersResp); err != nil {
+			logrus.Errorf("Error parsing response: %v", err)
+			return nil, fmt.Errorf("error parsing response: %w", err)
+		}
+		for _, e := range usersResp.Errors {
+			logrus.Debugf("Failed to lookup user ID %s: %s", e.ResourceID, e.Detail)
+		}
+
+		found := make(map[string]string, len(usersResp.Data))
+		for _, user := range usersResp.Data {
+			found[user.ID] = user.Username
+		}
+		return found, nil
+	case http.StatusUnauthorized:
+		return nil, ErrInvalidAPIKey
+	case http.StatusTooManyRequests:
+		return nil, ErrRateLimitExceeded
+	default:
+		return nil, fmt.Errorf("API users lookup failed with status: %d, body: %s", resp.StatusCode, string(body))
+	}
+}
@@ -0,0 +1,140 @@
+package twitterx
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/pkg/client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// redirect sends all requests of a client to the test server
+type redirect struct {
+	target *url.URL
+	next   http.RoundTripper
+}
+
+func (r *redirect) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.URL.Scheme = r.target.Scheme
+	req.URL.Host = r.target.Host
+	return r.next.RoundTrip(req)
+}
+
+var _ = Describe("Usernames", func() {
+	It("evicts the least recently used usernames", func() {
+		c := newUsernameCache(2)
+		c.add("1", "one")
+		c.add("2", "two")
+		_, _ = c.get("1")
+		c.add("3", "three")
+
+		_, ok := c.get("2")
+		Expect(ok).To(BeFalse())
+		username, ok := c.get("1")
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("one"))
+		username, ok = c.get("3")
+		Expect(ok).To(BeTrue())
+		Expect(username).To(Equal("three"))
+	})
+
+	Context("when fetching the usernames of search results", func() {
+		var (
+			server   *httptest.Server
+			scraper  *TwitterXScraper
+			mu       sync.Mutex
+			lookups  [][]string
+			status   int
+			previous *usernameCache
+		)
+
+		BeforeEach(func() {
+			previous = usernames
+			usernames = newUsernameCache(maxCachedUsernames)
+			lookups = nil
+			status = http.StatusOK
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				Expect(r.URL.Path).To(Equal("/2/users"))
+				ids := strings.Split(r.URL.Query().Get("ids"), ",")
+				mu.Lock()
+				lookups = append(lookups, ids)
+				mu.Unlock()
+
+				w.WriteHeader(status)
+				resp := UsersLookupResponse{}
+				for _, id := range ids {
+					if id == "suspended" {
+						resp.Errors = append(resp.Errors, TwitterXLookupError{ResourceID: id, Title: "Forbidden"})
+						continue
+					}
+					resp.Data = append(resp.Data, struct {
+						ID       string `json:"id"`
+						Name     string `json:"name"`
+						Username string `json:"username"`
+					}{ID: id, Username: "user" + id})
+				}
+				_ = json.NewEncoder(w).Encode(resp)
+			}))
+			target, err := url.Parse(server.URL)
+			Expect(err).NotTo(HaveOccurred())
+
+			scraper = NewTwitterXScraper(client.NewTwitterXClient("key", client.WrapTransport(func(next http.RoundTripper) http.RoundTripper {
+				return &redirect{target: target, next: next}
+			})))
+		})
+
+		AfterEach(func() {
+			server.Close()
+			usernames = previous
+		})
+
+		searchResult := func(authorIDs ...string) *TwitterXSearchQueryResult {
+			result := &TwitterXSearchQueryResult{}
+			for _, id := range authorIDs {
+				result.Data = append(result.Data, TwitterXData{AuthorID: id})
+			}
+			return result
+		}
+
+		It("looks up the authors in batches", func() {
+			var ids []string
+			for i := 0; i < 150; i++ {
+				ids = append(ids, fmt.Sprint(i), fmt.Sprint(i))
+			}
+			result := searchResult(append(ids, "suspended")...)
+
+			Expect(scraper.fetchUsernames(result)).To(Succeed())
+			Expect(lookups).To(HaveLen(2))
+			Expect(lookups[0]).To(HaveLen(MaxUserIDsPerLookup))
+			Expect(lookups[1]).To(HaveLen(51))
+			Expect(result.Data[0].Username).To(Equal("user0"))
+			Expect(result.Data[299].Username).To(Equal("user149"))
+			Expect(result.Data[300].Username).To(BeEmpty())
+		})
+
+		It("shares the usernames between jobs", func() {
+			Expect(scraper.fetchUsernames(searchResult("1", "2"))).To(Succeed())
+
+			result := searchResult("2", "3")
+			Expect(scraper.fetchUsernames(result)).To(Succeed())
+			Expect(lookups).To(Equal([][]string{{"1", "2"}, {"3"}}))
+			Expect(result.Data[0].Username).To(Equal("user2"))
+			Expect(result.Data[1].Username).To(Equal("user3"))
+		})
+
+		It("returns the errors of failed lookups", func() {
+			status = http.StatusTooManyRequests
+			result := searchResult("1")
+			Expect(scraper.fetchUsernames(result)).To(MatchError(ErrRateLimitExceeded))
+			Expect(result.Data[0].Username).To(BeEmpty())
+		})
+	})
+})
@@ -0,0 +1,152 @@
+package jobs
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// ErrInvalidResult is the error of a job which returned items, none of which passed the validation of its job type
+var ErrInvalidResult = errors.New("none of the result items are valid")
+
+// ItemValidator checks a single item of a job result, e.g. a tweet, decoded from JSON. It returns why the item is
+// invalid, or nil.
+type ItemValidator func(item map[string]any) error
+
+var (
+	validatorsLock sync.RWMutex
+	validators     = map[teetypes.JobType]map[teetypes.Capability][]ItemValidator{}
+)
+
+// RegisterResultValidators registers validators for the items of the results of the given capabilities of a job
+// type. Like workers, they are registered from init(), next to the registration of the worker of the job type.
+func RegisterResultValidators(jobType teetypes.JobType, capabilities []teetypes.Capability, itemValidators ...ItemValidator) {
+	validatorsLock.Lock()
+	defer validatorsLock.Unlock()
+
+	if validators[jobType] == nil {
+		validators[jobType] = map[teetypes.Capability][]ItemValidator{}
+	}
+	for _, c := range capabilities {
+		validators[jobType][c] = append(validators[jobType][c], itemValidators...)
+	}
+}
+
+// resultValidators returns the validators registered for the job type and capability of a job
+func resultValidators(j types.Job) []ItemValidator {
+	validatorsLock.RLock()
+	defer validatorsLock.RUnlock()
+	return validators[j.Type][jobCapability(j)]
+}
+
+// HasResultValidators returns true if validators are registered for the job type and capability of a job
+func HasResultValidators(j types.Job) bool {
+	return len(resultValidators(j)) > 0
+}
+
+// RequireFields returns an ItemValidator rejecting items in which one of the fields is missing, null or empty. Fields
+// of nested objects are separated by dots, e.g. data.username.
+func RequireFields(fields ...string) ItemValidator {
+	return func(item map[string]any) error {
+		for _, field := range fields {
+			if isEmptyField(item, field) {
+				return fmt.Errorf("%s is missing", field)
+			}
+		}
+		return nil
+	}
+}
+
+// RequireAnyField returns an ItemValidator rejecting items in which all of the fields are missing, null or empty. It
+// validates items which come in several shapes, e.g. profiles returned by different providers.
+func RequireAnyField(fields ...string) ItemValidator {
+	return func(item map[string]any) error {
+		for _, field := range fields {
+			if !isEmptyField(item, field) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%s is missing", strings.Join(fields, " or "))
+	}
+}
+
+func isEmptyField(item map[string]any, field string) bool {
+	var v any = item
+	for _, name := range strings.Split(field, ".") {
+		m, ok := v.(map[string]any)
+		if !ok {
+			return true
+		}
+		v = m[name]
+	}
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return strings.TrimSpace(v) == ""
+	default:
+		return false
+	}
+}
+
+// ValidateResult validates the items of the JSON-encoded result of a job with the validators registered for its job
+// type and capability, and drops the invalid ones. A result which is a JSON array keeps its valid items, any other
+// result is a single item. It returns the validated data and, if any item was dropped, a report of the dropped items.
+// If none of the items are valid, the error is ErrInvalidResult. Results of jobs without validators, empty results and
+// results which aren't JSON are returned as they are.
+func ValidateResult(j types.Job, data []byte) ([]byte, *types.ResultValidation, error) {
+	itemValidators := resultValidators(j)
+	if len(itemValidators) == 0 || len(data) == 0 {
+		return data, nil, nil
+	}
+
+	var items []json.RawMessage
+	array := json.Unmarshal(data, &items) == nil
+	if !array {
+		if !json.Valid(data) {
+			return data, nil, nil
+		}
+		items = []json.RawMessage{data}
+	}
+
+	report := &types.ResultValidation{Items: len(items), Reasons: map[string]int{}}
+	valid := make([]json.RawMessage, 0, len(items))
+	for _, raw := range items {
+		if err := validateItem(raw, itemValidators); err != nil {
+			report.Invalid++
+			report.Reasons[err.Error()]++
+			continue
+		}
+		valid = append(valid, raw)
+	}
+
+	if report.Invalid == 0 {
+		return data, nil, nil
+	}
+	if len(valid) == 0 {
+		return nil, report, ErrInvalidResult
+	}
+	dat, err := json.Marshal(valid)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error marshalling validated result: %w", err)
+	}
+	return dat, report, nil
+}
+
+func validateItem(raw json.RawMessage, itemValidators []ItemValidator) error {
+	var item map[string]any
+	if err := json.Unmarshal(raw, &item); err != nil || item == nil {
+		return errors.New("item is not an object")
+	}
+	for _, validate := range itemValidators {
+		if err := validate(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package jobs_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	. "github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+var _ = Describe("Result validation", func() {
+	job := func(jobType teetypes.JobType, capability string) types.Job {
+		return types.Job{Type: jobType, Arguments: map[string]any{"type": capability}}
+	}
+
+	It("drops the tweets without an ID or a text", func() {
+		data := []byte(`[{"tweet_id":"1","text":"hello"},{"tweet_id":"","text":"no ID"},{"tweet_id":"3","text":" "},{"tweet_id":"4","text":"world"}]`)
+
+		validated, report, err := ValidateResult(job(teetypes.TwitterJob, "searchbyquery"), data)
+		Expect(err).NotTo(HaveOccurred())
+		var tweets []map[string]any
+		Expect(json.Unmarshal(validated, &tweets)).To(Succeed())
+		Expect(tweets).To(HaveLen(2))
+		Expect(tweets[0]["tweet_id"]).To(Equal("1"))
+		Expect(tweets[1]["tweet_id"]).To(Equal("4"))
+		Expect(report).To(Equal(&types.ResultValidation{Items: 4, Invalid: 2, Reasons: map[string]int{"tweet_id is missing": 1, "text is missing": 1}}))
+	})
+
+	It("fails results without any valid item", func() {
+		_, report, err := ValidateResult(job(teetypes.TwitterApiJob, "getbyid"), []byte(`{"tweet_id":"1","text":""}`))
+		Expect(err).To(MatchError(ErrInvalidResult))
+		Expect(report.Items).To(Equal(1))
+		Expect(report.Invalid).To(Equal(1))
+
+		_, _, err = ValidateResult(job(teetypes.TwitterJob, "searchbyquery"), []byte(`["not a tweet"]`))
+		Expect(err).To(MatchError(ErrInvalidResult))
+	})
+
+	It("accepts the profiles of all providers which have a username", func() {
+		data := []byte(`[{"Username":"scraper"},{"screen_name":"apify"},{"Name":"no username"}]`)
+		validated, report, err := ValidateResult(job(teetypes.TwitterApifyJob, ""), data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Invalid).To(Equal(1))
+		Expect(validated).To(MatchJSON(`[{"Username":"scraper"},{"screen_name":"apify"}]`))
+
+		_, report, err = ValidateResult(job(teetypes.TwitterApiJob, "getprofilebyid"), []byte(`{"data":{"username":"api"}}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(BeNil())
+	})
+
+	It("leaves valid results and the results of job types without validators as they are", func() {
+		data := []byte(`[{"tweet_id":"1","text":"hello"}]`)
+		validated, report, err := ValidateResult(job(teetypes.TwitterJob, "searchbyquery"), data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(BeNil())
+		Expect(validated).To(Equal(data))
+
+		Expect(HasResultValidators(job(teetypes.WebJob, "scraper"))).To(BeFalse())
+		validated, report, err = ValidateResult(job(teetypes.WebJob, "scraper"), []byte(`[{}]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report).To(BeNil())
+		Expect(validated).To(Equal([]byte(`[{}]`)))
+	})
+
+	It("validates nested fields", func() {
+		validate := RequireFields("data.username")
+		Expect(validate(map[string]any{"data": map[string]any{"username": "a"}})).To(Succeed())
+		Expect(validate(map[string]any{"data": "a"})).To(MatchError("data.username is missing"))
+		Expect(RequireAnyField("a", "b")(map[string]any{})).To(MatchError("a or b is missing"))
+	})
+})
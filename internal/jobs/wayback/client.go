@@ -0,0 +1,88 @@
+package wayback
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultBaseURL is the base URL of the Wayback Machine availability API
+	DefaultBaseURL = "https://archive.org/wayback/available"
+
+	// TimestampLayout is the layout of Wayback Machine snapshot timestamps
+	TimestampLayout = "20060102150405"
+)
+
+// ErrNoSnapshot is returned when the Wayback Machine has no snapshot of a URL
+var ErrNoSnapshot = errors.New("no archived snapshot available")
+
+// Snapshot is an archived copy of a page
+type Snapshot struct {
+	URL       string
+	Timestamp time.Time
+}
+
+// Client looks up snapshots using the Wayback Machine availability API
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+// NewClient creates a new Wayback Machine client
+func NewClient() *Client {
+	return &Client{
+		BaseURL:    DefaultBaseURL,
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type availabilityResponse struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// LatestSnapshot returns the most recent successful snapshot of the given URL
+func (c *Client) LatestSnapshot(pageURL string) (*Snapshot, error) {
+	resp, err := c.HTTPClient.Get(c.BaseURL + "?url=" + url.QueryEscape(pageURL))
+	if err != nil {
+		return nil, fmt.Errorf("error querying the Wayback Machine: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading Wayback Machine response: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("the Wayback Machine returned status code %d: %s", resp.StatusCode, string(body))
+	}
+
+	var availability availabilityResponse
+	if err := json.Unmarshal(body, &availability); err != nil {
+		return nil, fmt.Errorf("error parsing Wayback Machine response: %w", err)
+	}
+
+	closest := availability.ArchivedSnapshots.Closest
+	if closest == nil || !closest.Available || closest.Status != "200" {
+		return nil, ErrNoSnapshot
+	}
+
+	ts, err := time.Parse(TimestampLayout, closest.Timestamp)
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot timestamp %q: %w", closest.Timestamp, err)
+	}
+
+	return &Snapshot{URL: closest.URL, Timestamp: ts}, nil
+}
@@ -0,0 +1,56 @@
+package wayback_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/jobs/wayback"
+)
+
+var _ = Describe("Client", func() {
+	var (
+		server *httptest.Server
+		body   string
+		c      *wayback.Client
+	)
+
+	BeforeEach(func() {
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Query().Get("url")).To(Equal("https://example.com/gone"))
+			_, _ = w.Write([]byte(body))
+		}))
+		c = wayback.NewClient()
+		c.BaseURL = server.URL
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should return the latest snapshot", func() {
+		body = `{"archived_snapshots":{"closest":{"available":true,"url":"http://web.archive.org/web/20240102030405/https://example.com/gone","timestamp":"20240102030405","status":"200"}}}`
+
+		snapshot, err := c.LatestSnapshot("https://example.com/gone")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(snapshot.URL).To(Equal("http://web.archive.org/web/20240102030405/https://example.com/gone"))
+		Expect(snapshot.Timestamp).To(Equal(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)))
+	})
+
+	It("should return ErrNoSnapshot if the page was never archived", func() {
+		body = `{"archived_snapshots":{}}`
+
+		_, err := c.LatestSnapshot("https://example.com/gone")
+		Expect(err).To(MatchError(wayback.ErrNoSnapshot))
+	})
+
+	It("should ignore snapshots of error pages", func() {
+		body = `{"archived_snapshots":{"closest":{"available":true,"url":"http://web.archive.org/web/20240102030405/https://example.com/gone","timestamp":"20240102030405","status":"404"}}}`
+
+		_, err := c.LatestSnapshot("https://example.com/gone")
+		Expect(err).To(MatchError(wayback.ErrNoSnapshot))
+	})
+})
@@ -0,0 +1,13 @@
+package wayback_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestWayback(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Wayback Client Suite")
+}
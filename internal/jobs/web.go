@@ -1,16 +1,21 @@
 package jobs
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/sirupsen/logrus"
 
 	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
 	"github.com/masa-finance/tee-worker/internal/jobs/llmapify"
+	"github.com/masa-finance/tee-worker/internal/jobs/sitemap"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/wayback"
 	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
 	"github.com/masa-finance/tee-worker/pkg/client"
 
@@ -21,13 +26,16 @@ import (
 
 // WebApifyClient defines the interface for the Web Apify client to allow mocking in tests
 type WebApifyClient interface {
-	Scrape(workerID string, args teeargs.WebArguments, cursor client.Cursor) ([]*teetypes.WebScraperResult, string, client.Cursor, error)
+	Scrape(workerID string, args teeargs.WebArguments, opts webapify.ScrapeOptions, cursor client.Cursor) ([]*webapify.Page, string, client.Cursor, error)
+	ScrapePages(workerID string, urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error)
+	ScrapeURLs(workerID string, urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error)
+	Screenshot(workerID string, url string, opts webapify.ScreenshotOptions) (*webapify.Screenshot, error)
 }
 
 // NewWebApifyClient is a function variable that can be replaced in tests.
 // It defaults to the actual implementation.
-var NewWebApifyClient = func(apiKey string, statsCollector *stats.StatsCollector) (WebApifyClient, error) {
-	return webapify.NewClient(apiKey, statsCollector)
+var NewWebApifyClient = func(apiKey string, statsCollector *stats.StatsCollector, opts ...client.Option) (WebApifyClient, error) {
+	return webapify.NewClient(apiKey, statsCollector, opts...)
 }
 
 // LLMApify is the interface for the LLM processor client
@@ -37,8 +45,76 @@ type LLMApify interface {
 }
 
 // NewLLMApifyClient is a function variable to allow injection in tests
-var NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, statsCollector *stats.StatsCollector) (LLMApify, error) {
-	return llmapify.NewClient(apiKey, llmConfig, statsCollector)
+var NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, statsCollector *stats.StatsCollector, opts ...client.Option) (LLMApify, error) {
+	return llmapify.NewClient(apiKey, llmConfig, statsCollector, opts...)
+}
+
+// WaybackClient defines the interface for the Wayback Machine client to allow mocking in tests
+type WaybackClient interface {
+	LatestSnapshot(url string) (*wayback.Snapshot, error)
+}
+
+// NewWaybackClient is a function variable that can be replaced in tests.
+var NewWaybackClient = func(network types.Network) WaybackClient {
+	c := wayback.NewClient()
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// SitemapClient defines the interface for the sitemap client to allow mocking in tests
+type SitemapClient interface {
+	Pages(siteURL string, maxPages int) ([]string, error)
+}
+
+// NewSitemapClient is a function variable that can be replaced in tests.
+var NewSitemapClient = func(network types.Network, delay time.Duration) SitemapClient {
+	c := sitemap.NewClient(delay)
+	c.HTTPClient = network.Client(c.HTTPClient)
+	return c
+}
+
+// archiveFallbackArgumentKey is the job argument used to request the Wayback Machine fallback for dead or paywalled pages
+const archiveFallbackArgumentKey = "archive_fallback"
+
+// renderJSArgumentKey is the job argument used to load the pages in a headless browser, for pages which are rendered with JavaScript
+const renderJSArgumentKey = "render_js"
+
+// modeArgumentKey is the job argument which selects how the pages to scrape are found
+const modeArgumentKey = "mode"
+
+// extractArgumentKey is the job argument which selects what is extracted from the content of each page, see
+// webapify.Extraction
+const extractArgumentKey = "extract"
+
+// respectRobotsTxtArgumentKey is the job argument used to skip the pages disallowed by robots.txt, see
+// WEB_RESPECT_ROBOTS_TXT
+const respectRobotsTxtArgumentKey = "respect_robots_txt"
+
+// maxRequestsPerMinuteArgumentKey is the job argument capping the request rate to the scraped domain, which can only
+// be lower than WEB_DOMAIN_MAX_REQUESTS_PER_MINUTE
+const maxRequestsPerMinuteArgumentKey = "max_requests_per_minute"
+
+const (
+	// webModeCrawl follows the links of the URL up to max_depth. This is the default.
+	webModeCrawl = "crawl"
+	// webModeSitemap scrapes the pages listed in the sitemaps of the site instead of following links
+	webModeSitemap = "sitemap"
+)
+
+// paywallMarkers are phrases which indicate that only a paywall was scraped instead of the page content
+var paywallMarkers = []string{
+	"subscribe to continue reading",
+	"subscribe to read",
+	"to continue reading, subscribe",
+	"this content is for subscribers",
+	"this article is for subscribers",
+	"already a subscriber? log in",
+}
+
+// webResult is a scraped page, with the provenance of the result if it was not scraped from the original page
+type webResult struct {
+	*webapify.Page
+	Provenance *types.Provenance `json:"provenance,omitempty"`
 }
 
 type WebScraper struct {
@@ -60,12 +136,22 @@ func NewWebScraper(jc config.JobConfiguration, statsCollector *stats.StatsCollec
 func (w *WebScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
 	logrus.WithField("job_uuid", j.UUID).Info("Starting ExecuteJob for Web scrape")
 
+	// Screenshots are not summarized, so they don't need a Gemini key
+	if isCapabilityJob(j, CapScreenshot) {
+		return w.executeScreenshot(j)
+	}
+
 	// Require Gemini key for LLM processing in Web flow
 	if !w.configuration.GeminiApiKey.IsValid() {
 		msg := errors.New("Gemini API key is required for Web job")
 		return types.JobResult{Error: msg.Error()}, msg
 	}
 
+	// Jobs with a list of pages don't need a url, so they are handled before the arguments are validated
+	if _, ok := j.Arguments[urlsArgumentKey]; ok {
+		return w.executeURLs(j)
+	}
+
 	jobArgs, err := teeargs.UnmarshalJobArguments(teetypes.JobType(j.Type), map[string]any(j.Arguments))
 	if err != nil {
 		msg := fmt.Errorf("failed to unmarshal job arguments: %w", err)
@@ -78,59 +164,248 @@ func (w *WebScraper) ExecuteJob(j types.Job) (types.JobResult, error) {
 	}
 	logrus.Debugf("web job args: %+v", *webArgs)
 
-	webClient, err := NewWebApifyClient(w.configuration.ApifyApiKey, w.statsCollector)
+	mode, _ := j.Arguments[modeArgumentKey].(string)
+	if mode != "" && mode != webModeCrawl && mode != webModeSitemap {
+		msg := fmt.Errorf("invalid mode %q, expected %q or %q", mode, webModeCrawl, webModeSitemap)
+		return types.JobResult{Error: msg.Error()}, msg
+	}
+
+	extract, err := extractionFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	webClient, err := NewWebApifyClient(w.configuration.ApifyApiKey, w.statsCollector, apifyOptions(j)...)
 	if err != nil {
 		return types.JobResult{Error: "error while scraping Web"}, fmt.Errorf("error creating Web Apify client: %w", err)
 	}
 
-	webResp, datasetId, cursor, err := webClient.Scrape(j.WorkerID, *webArgs, client.EmptyCursor)
+	respectRobotsTxt, maxRequestsPerMinute, err := w.politenessFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	renderJS, _ := j.Arguments[renderJSArgumentKey].(bool)
+	opts := webapify.ScrapeOptions{RenderJS: renderJS, Extract: extract, RespectRobotsTxt: respectRobotsTxt, MaxRequestsPerMinute: maxRequestsPerMinute}
+
+	// Jobs wait for a slot if WEB_MAX_CONCURRENT_DOMAINS other domains are already being scraped
+	domain := domainOf(webArgs.URL)
+	release := webDomains.acquire(domain, w.configuration.MaxConcurrentDomains)
+	defer release()
+	opts.Profile = domainProfile(domain)
+
+	var (
+		webResp   []*webapify.Page
+		datasetId string
+		cursor    = client.EmptyCursor
+	)
+	if mode == webModeSitemap {
+		webResp, datasetId, err = w.scrapeSitemap(j, *webArgs, opts, webClient)
+	} else {
+		webResp, datasetId, cursor, err = webClient.Scrape(j.WorkerID, *webArgs, opts, client.EmptyCursor)
+	}
 	if err != nil {
 		return types.JobResult{Error: fmt.Sprintf("error while scraping Web: %s", err.Error())}, fmt.Errorf("error scraping Web: %w", err)
 	}
+	rotateIfBlocked(domain, webResp)
+
+	var provenance *types.Provenance
+	if fallback, _ := j.Arguments[archiveFallbackArgumentKey].(bool); fallback && mode != webModeSitemap && needsArchiveFallback(webArgs.URL, webResp) {
+		archivedResp, archivedDatasetId, snapshot, err := w.scrapeArchived(j, *webArgs, opts, webClient)
+		if err != nil {
+			logrus.WithField("job_uuid", j.UUID).Warnf("Archive fallback for %s failed: %s", webArgs.URL, err)
+		} else {
+			webResp, datasetId, cursor = archivedResp, archivedDatasetId, client.EmptyCursor
+			provenance = &types.Provenance{
+				Type:              types.ProvenanceArchived,
+				Source:            "archive.org",
+				SnapshotURL:       snapshot.URL,
+				SnapshotTimestamp: snapshot.Timestamp,
+			}
+		}
+	}
 
 	// Run LLM processing and inject into results (Gemini key already validated)
 	if datasetId == "" {
 		return types.JobResult{Error: "missing dataset id from web scraping"}, errors.New("missing dataset id from web scraping")
 	}
 
-	llmClient, err := NewLLMApifyClient(w.configuration.ApifyApiKey, w.configuration.LlmConfig, w.statsCollector)
+	llmClient, err := NewLLMApifyClient(w.configuration.ApifyApiKey, w.configuration.LlmConfig, w.statsCollector, apifyOptions(j)...)
 	if err != nil {
 		return types.JobResult{Error: "error creating LLM Apify client"}, fmt.Errorf("failed to create LLM Apify client: %w", err)
 	}
 
+	max, llmErr := summarizePages(j, llmClient, datasetId, webResp)
+	if llmErr != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while processing LLM: %s", llmErr.Error())}, fmt.Errorf("error processing LLM: %w", llmErr)
+	}
+
+	results := make([]webResult, 0, len(webResp))
+	for _, r := range webResp {
+		if r != nil {
+			extractContent(r, extract)
+		}
+		results = append(results, webResult{Page: r, Provenance: provenance})
+	}
+
+	res, err := apifyPageResult(results, cursor)
+	if err != nil {
+		return res, err
+	}
+
+	if w.statsCollector != nil {
+		w.statsCollector.Add(j.WorkerID, stats.WebProcessedPages, uint(max))
+	}
+
+	res.Job = j
+	return res, nil
+}
+
+// summarizePages adds a summary generated from the dataset of the actor run which scraped the pages to each of them,
+// and returns the number of pages which were summarized
+func summarizePages(j types.Job, llmClient LLMApify, datasetId string, pages []*webapify.Page) (int, error) {
 	llmArgs := teeargs.LLMProcessorArguments{
 		DatasetId:   datasetId,
 		Prompt:      "summarize the content of this webpage, focusing on keywords and topics: ${markdown}",
 		MaxTokens:   teeargs.LLMDefaultMaxTokens,
 		Temperature: teeargs.LLMDefaultTemperature,
-		Items:       uint(len(webResp)),
+		Items:       uint(len(pages)),
 	}
-	llmResp, _, llmErr := llmClient.Process(j.WorkerID, llmArgs, client.EmptyCursor)
-	if llmErr != nil {
-		return types.JobResult{Error: fmt.Sprintf("error while processing LLM: %s", llmErr.Error())}, fmt.Errorf("error processing LLM: %w", llmErr)
+	llmResp, _, err := llmClient.Process(j.WorkerID, llmArgs, client.EmptyCursor)
+	if err != nil {
+		return 0, err
 	}
 
-	max := util.Min(len(webResp), len(llmResp))
+	max := util.Min(len(pages), len(llmResp))
 	for i := 0; i < max; i++ {
-		if webResp[i] != nil {
-			webResp[i].LLMResponse = llmResp[i].LLMResponse
+		if pages[i] != nil {
+			pages[i].LLMResponse = llmResp[i].LLMResponse
+		}
+	}
+	return max, nil
+}
+
+// extractContent leaves only the content of the page which was asked for. The markdown is always scraped, since it is
+// what the summary of the page is generated from.
+func extractContent(page *webapify.Page, extract webapify.Extraction) {
+	switch extract {
+	case webapify.ExtractionMarkdown:
+		page.Text = ""
+	case webapify.ExtractionRawHTML:
+		page.Text = ""
+		page.Markdown = ""
+	}
+}
+
+// needsArchiveFallback returns true if the page could not be scraped, was not found or is behind a paywall
+func needsArchiveFallback(url string, resp []*webapify.Page) bool {
+	var page *webapify.Page
+	for _, r := range resp {
+		if r != nil && (r.URL == url || r.Crawl.Depth == 0) {
+			page = r
+			break
 		}
 	}
+	if page == nil {
+		return true
+	}
+
+	switch page.Crawl.HTTPStatusCode {
+	case http.StatusNotFound, http.StatusGone, http.StatusPaymentRequired:
+		return true
+	}
 
-	data, err := json.Marshal(webResp)
+	text := strings.ToLower(page.Text + " " + page.Markdown)
+	for _, marker := range paywallMarkers {
+		if strings.Contains(text, marker) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scrapeSitemap scrapes up to max_pages pages listed in the sitemaps of the site, instead of following the links of the URL
+func (w *WebScraper) scrapeSitemap(j types.Job, args teeargs.WebArguments, opts webapify.ScrapeOptions, webClient WebApifyClient) ([]*webapify.Page, string, error) {
+	delay := w.configuration.CrawlDelay
+	if opts.MaxRequestsPerMinute > 0 {
+		delay = max(delay, time.Minute/time.Duration(opts.MaxRequestsPerMinute))
+	}
+	pages, err := NewSitemapClient(j.Network, delay).Pages(args.URL, args.MaxPages)
 	if err != nil {
-		return types.JobResult{Error: fmt.Sprintf("error marshalling Web response")}, fmt.Errorf("error marshalling Web response: %w", err)
+		return nil, "", fmt.Errorf("error reading the sitemaps of %s: %w", args.URL, err)
 	}
+	logrus.WithField("job_uuid", j.UUID).Debugf("Scraping %d pages from the sitemaps of %s", len(pages), args.URL)
 
-	if w.statsCollector != nil {
-		w.statsCollector.Add(j.WorkerID, stats.WebProcessedPages, uint(max))
+	return webClient.ScrapePages(j.WorkerID, pages, opts)
+}
+
+// scrapeArchived scrapes the latest Wayback Machine snapshot of the page. Only the snapshot itself is scraped, links are not followed.
+func (w *WebScraper) scrapeArchived(j types.Job, args teeargs.WebArguments, opts webapify.ScrapeOptions, webClient WebApifyClient) ([]*webapify.Page, string, *wayback.Snapshot, error) {
+	snapshot, err := NewWaybackClient(j.Network).LatestSnapshot(args.URL)
+	if err != nil {
+		return nil, "", nil, err
 	}
 
-	return types.JobResult{
-		Data:       data,
-		Job:        j,
-		NextCursor: cursor.String(),
-	}, nil
+	args.URL = snapshot.URL
+	args.MaxDepth = 0
+	args.MaxPages = 1
+
+	resp, datasetId, _, err := webClient.Scrape(j.WorkerID, args, opts, client.EmptyCursor)
+	if err != nil {
+		return nil, "", nil, fmt.Errorf("error scraping snapshot %s: %w", snapshot.URL, err)
+	}
+	if len(resp) == 0 || datasetId == "" {
+		return nil, "", nil, fmt.Errorf("snapshot %s returned no content", snapshot.URL)
+	}
+
+	return resp, datasetId, snapshot, nil
+}
+
+// politenessFromArguments returns whether a job honors robots.txt and its request rate to the scraped domain. Jobs can
+// be more polite than the configuration, but not less.
+func (w *WebScraper) politenessFromArguments(args types.JobArguments) (bool, int, error) {
+	respectRobotsTxt := w.configuration.RespectRobotsTxt
+	if v, ok := args[respectRobotsTxtArgumentKey]; ok && v != nil {
+		b, ok := v.(bool)
+		if !ok {
+			return false, 0, fmt.Errorf("%s must be a boolean, got %T", respectRobotsTxtArgumentKey, v)
+		}
+		respectRobotsTxt = respectRobotsTxt || b
+	}
+
+	maxRequestsPerMinute := w.configuration.DomainMaxRequestsPerMinute
+	if v, ok := args[maxRequestsPerMinuteArgumentKey]; ok && v != nil {
+		f, ok := v.(float64)
+		if !ok || f != float64(int(f)) || f < 1 {
+			return false, 0, fmt.Errorf("%s must be a positive integer, got %v", maxRequestsPerMinuteArgumentKey, v)
+		}
+		if maxRequestsPerMinute == 0 || int(f) < maxRequestsPerMinute {
+			maxRequestsPerMinute = int(f)
+		}
+	}
+
+	return respectRobotsTxt, maxRequestsPerMinute, nil
+}
+
+// extractionFromArguments returns the extraction requested by a job, or ExtractionSections if none is
+func extractionFromArguments(args types.JobArguments) (webapify.Extraction, error) {
+	v, ok := args[extractArgumentKey]
+	if !ok || v == nil {
+		return webapify.ExtractionSections, nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", extractArgumentKey, v)
+	}
+	extract := webapify.Extraction(strings.ToLower(s))
+	if extract == "" {
+		return webapify.ExtractionSections, nil
+	}
+	if !slices.Contains(webapify.AllExtractions, extract) {
+		return "", fmt.Errorf("invalid %s %q, must be one of %v", extractArgumentKey, s, webapify.AllExtractions)
+	}
+	return extract, nil
 }
 
 // GetStructuredCapabilities returns the structured capabilities supported by the Web scraper
@@ -141,6 +416,14 @@ func (ws *WebScraper) GetStructuredCapabilities() teetypes.WorkerCapabilities {
 	if ws.configuration.ApifyApiKey != "" && ws.configuration.GeminiApiKey.IsValid() {
 		capabilities[teetypes.WebJob] = teetypes.WebCaps
 	}
+	if ws.configuration.ApifyApiKey != "" {
+		capabilities[teetypes.WebJob] = append(slices.Clone(capabilities[teetypes.WebJob]), CapScreenshot)
+	}
 
 	return capabilities
 }
+
+// GetCapabilityDetails returns the auth source of each capability. All Web capabilities are provided through Apify.
+func (ws *WebScraper) GetCapabilityDetails() types.CapabilityDetails {
+	return types.NewCapabilityDetails(ws.GetStructuredCapabilities(), types.AuthSourceApify)
+}
@@ -0,0 +1,79 @@
+package jobs
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
+	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
+)
+
+// domainSlots limits how many domains are scraped at the same time. Jobs scraping a domain which is already being
+// scraped share its slot, so a site is never blocked by itself.
+type domainSlots struct {
+	mu     sync.Mutex
+	cond   *sync.Cond
+	active map[string]int
+}
+
+// webDomains are the domains being scraped by web jobs. They are shared by all web scrapers, which are recreated when
+// the credentials are reloaded.
+var webDomains = newDomainSlots()
+
+func newDomainSlots() *domainSlots {
+	d := &domainSlots{active: map[string]int{}}
+	d.cond = sync.NewCond(&d.mu)
+	return d
+}
+
+// acquire waits until the domain can be scraped with at most limit domains scraped at once, and returns the function
+// releasing it. A limit of 0 is unlimited.
+func (d *domainSlots) acquire(domain string, limit int) func() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for limit > 0 && d.active[domain] == 0 && len(d.active) >= limit {
+		d.cond.Wait()
+	}
+	d.active[domain]++
+
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if d.active[domain]--; d.active[domain] == 0 {
+			delete(d.active, domain)
+			d.cond.Broadcast()
+		}
+	}
+}
+
+// domainOf returns the host of the URL without the www. prefix, so www.example.com and example.com share a slot
+func domainOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// domainProfile returns the browser profile presented to the domain
+func domainProfile(domain string) *fingerprint.Profile {
+	p := fingerprint.For(fingerprint.DomainKey(domain))
+	return &p
+}
+
+// isBlockStatus returns true if a site answered with a status which means that it blocks the scraper
+func isBlockStatus(status int) bool {
+	return status == http.StatusForbidden || status == http.StatusTooManyRequests
+}
+
+// rotateIfBlocked moves the domain to the next browser profile if it blocked one of the pages
+func rotateIfBlocked(domain string, pages []*webapify.Page) {
+	for _, page := range pages {
+		if page != nil && isBlockStatus(page.Crawl.HTTPStatusCode) {
+			fingerprint.Rotate(fingerprint.DomainKey(domain))
+			return
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package jobs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+)
+
+// CapScreenshot captures a page in a headless browser, for content verification and archival. It only needs an Apify
+// API key, and like the capabilities of the other job types which are not in tee-types, it is handled by the
+// WebScraper before the arguments are validated against the tee-types capabilities.
+const CapScreenshot teetypes.Capability = "screenshot"
+
+const (
+	defaultScreenshotViewportWidth = 1280
+	minScreenshotViewportWidth     = 320
+	maxScreenshotViewportWidth     = 3840
+	// maxScreenshotBytes is the largest screenshot which is downloaded, full-page screenshots of long pages can get big
+	maxScreenshotBytes = 20 << 20
+)
+
+// WebScreenshotArguments are the arguments of a screenshot job
+type WebScreenshotArguments struct {
+	QueryType string `json:"type"`
+	URL       string `json:"url"`
+	// FullPage captures the whole page instead of the viewport only
+	FullPage bool   `json:"full_page"`
+	Format   string `json:"format"` // png (default) or jpeg
+	// Quality is the quality of JPEG screenshots from 1 to 100
+	Quality       int `json:"quality"`
+	ViewportWidth int `json:"viewport_width"` // In pixels, defaults to the viewport of the profile of the domain
+}
+
+// WebScreenshot is the result of a screenshot job. Data is encoded as base64 in JSON.
+type WebScreenshot struct {
+	URL           string    `json:"url"`
+	Format        string    `json:"format"`
+	ContentType   string    `json:"content_type"`
+	FullPage      bool      `json:"full_page"`
+	ViewportWidth int       `json:"viewport_width"`
+	Size          int       `json:"size"`
+	Data          []byte    `json:"data"`
+	CapturedAt    time.Time `json:"captured_at"`
+}
+
+// parseScreenshotArguments unmarshals and validates the arguments of a screenshot job
+func parseScreenshotArguments(args map[string]any) (*WebScreenshotArguments, error) {
+	dat, err := json.Marshal(args)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal screenshot arguments: %w", err)
+	}
+
+	parsed := &WebScreenshotArguments{}
+	if err := json.Unmarshal(dat, parsed); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal screenshot arguments: %w", err)
+	}
+
+	parsed.URL = strings.TrimSpace(parsed.URL)
+	if u, err := url.Parse(parsed.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("invalid URL %q", parsed.URL)
+	}
+
+	parsed.Format = strings.ToLower(strings.TrimSpace(parsed.Format))
+	switch parsed.Format {
+	case "":
+		parsed.Format = string(webapify.ScreenshotPNG)
+	case "jpg":
+		parsed.Format = string(webapify.ScreenshotJPEG)
+	}
+	if !slices.Contains(webapify.AllScreenshotFormats, webapify.ScreenshotFormat(parsed.Format)) {
+		return nil, fmt.Errorf("invalid format %q, must be one of %v", parsed.Format, webapify.AllScreenshotFormats)
+	}
+
+	if parsed.Quality != 0 {
+		if parsed.Format != string(webapify.ScreenshotJPEG) {
+			return nil, fmt.Errorf("quality is only supported for %s screenshots", webapify.ScreenshotJPEG)
+		}
+		if parsed.Quality < 1 || parsed.Quality > 100 {
+			return nil, fmt.Errorf("quality must be between 1 and 100, got %d", parsed.Quality)
+		}
+	}
+
+	if parsed.ViewportWidth != 0 && (parsed.ViewportWidth < minScreenshotViewportWidth || parsed.ViewportWidth > maxScreenshotViewportWidth) {
+		return nil, fmt.Errorf("viewport_width must be between %d and %d, got %d", minScreenshotViewportWidth, maxScreenshotViewportWidth, parsed.ViewportWidth)
+	}
+
+	return parsed, nil
+}
+
+// executeScreenshot captures a page with the screenshot actor and downloads the image, so the result doesn't depend
+// on the key-value store of the actor run, which Apify deletes after a while
+func (w *WebScraper) executeScreenshot(j types.Job) (types.JobResult, error) {
+	args, err := parseScreenshotArguments(j.Arguments)
+	if err != nil {
+		logrus.Errorf("Error while unmarshalling job arguments for job ID %s, type %s: %v", j.UUID, j.Type, err)
+		return types.JobResult{Error: "error unmarshalling job arguments"}, err
+	}
+
+	webClient, err := NewWebApifyClient(w.configuration.ApifyApiKey, w.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error while taking screenshot"}, fmt.Errorf("error creating Web Apify client: %w", err)
+	}
+
+	// Jobs wait for a slot if WEB_MAX_CONCURRENT_DOMAINS other domains are already being scraped
+	domain := domainOf(args.URL)
+	release := webDomains.acquire(domain, w.configuration.MaxConcurrentDomains)
+	defer release()
+
+	// The page is captured in the window of the browser presented to the domain, unless the job chose its width
+	if args.ViewportWidth == 0 {
+		args.ViewportWidth = defaultScreenshotViewportWidth
+		if width := domainProfile(domain).ViewportWidth; width > 0 {
+			args.ViewportWidth = min(max(width, minScreenshotViewportWidth), maxScreenshotViewportWidth)
+		}
+	}
+
+	opts := webapify.ScreenshotOptions{
+		FullPage:      args.FullPage,
+		Format:        webapify.ScreenshotFormat(args.Format),
+		Quality:       args.Quality,
+		ViewportWidth: args.ViewportWidth,
+	}
+	screenshot, err := webClient.Screenshot(j.WorkerID, args.URL, opts)
+	if err != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while taking screenshot: %s", err.Error())}, fmt.Errorf("error taking screenshot: %w", err)
+	}
+
+	data, contentType, err := downloadMedia(j.Network.Client(nil), screenshot.ScreenshotURL, maxScreenshotBytes, true)
+	if err != nil {
+		return types.JobResult{Error: fmt.Sprintf("error while downloading screenshot: %s", err.Error())}, fmt.Errorf("error downloading screenshot: %w", err)
+	}
+
+	dat, err := json.Marshal(WebScreenshot{
+		URL:           args.URL,
+		Format:        args.Format,
+		ContentType:   contentType,
+		FullPage:      args.FullPage,
+		ViewportWidth: args.ViewportWidth,
+		Size:          len(data),
+		Data:          data,
+		CapturedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return types.JobResult{Error: "error marshalling screenshot"}, fmt.Errorf("error marshalling screenshot: %w", err)
+	}
+
+	return types.JobResult{Data: dat}, nil
+}
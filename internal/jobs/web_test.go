@@ -3,16 +3,23 @@ package jobs_test
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"sync"
+	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
 	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 	"github.com/masa-finance/tee-worker/internal/jobs"
 	"github.com/masa-finance/tee-worker/internal/jobs/llmapify"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/wayback"
 	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
 	"github.com/masa-finance/tee-worker/pkg/client"
 
@@ -22,17 +29,54 @@ import (
 
 // MockWebApifyClient is a mock implementation of the WebApifyClient.
 type MockWebApifyClient struct {
-	ScrapeFunc func(args teeargs.WebArguments) ([]*teetypes.WebScraperResult, string, client.Cursor, error)
+	ScrapeFunc      func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error)
+	ScrapePagesFunc func(urls []string) ([]*webapify.Page, string, error)
+	ScreenshotFunc  func(url string, opts webapify.ScreenshotOptions) (*webapify.Screenshot, error)
+	// ScrapeURLsFunc is called concurrently by the runs of a job with urls
+	ScrapeURLsFunc func(urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error)
+	Options        webapify.ScrapeOptions
 }
 
-func (m *MockWebApifyClient) Scrape(_ string, args teeargs.WebArguments, _ client.Cursor) ([]*teetypes.WebScraperResult, string, client.Cursor, error) {
+func (m *MockWebApifyClient) Scrape(_ string, args teeargs.WebArguments, opts webapify.ScrapeOptions, _ client.Cursor) ([]*webapify.Page, string, client.Cursor, error) {
 	if m != nil && m.ScrapeFunc != nil {
+		m.Options = opts
 		res, datasetId, next, err := m.ScrapeFunc(args)
 		return res, datasetId, next, err
 	}
 	return nil, "", client.EmptyCursor, nil
 }
 
+func (m *MockWebApifyClient) ScrapePages(_ string, urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error) {
+	if m != nil && m.ScrapePagesFunc != nil {
+		m.Options = opts
+		return m.ScrapePagesFunc(urls)
+	}
+	return nil, "", nil
+}
+
+func (m *MockWebApifyClient) ScrapeURLs(_ string, urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error) {
+	if m != nil && m.ScrapeURLsFunc != nil {
+		return m.ScrapeURLsFunc(urls, opts)
+	}
+	return nil, "", nil
+}
+
+func (m *MockWebApifyClient) Screenshot(_ string, url string, opts webapify.ScreenshotOptions) (*webapify.Screenshot, error) {
+	if m != nil && m.ScreenshotFunc != nil {
+		return m.ScreenshotFunc(url, opts)
+	}
+	return nil, webapify.ErrNoScreenshot
+}
+
+// webPages returns the pages scraped with the given results
+func webPages(results ...teetypes.WebScraperResult) []*webapify.Page {
+	pages := make([]*webapify.Page, len(results))
+	for i, r := range results {
+		pages[i] = &webapify.Page{WebScraperResult: r}
+	}
+	return pages
+}
+
 // MockLLMApifyClient is a mock implementation of the LLMApify interface
 // used to prevent external calls during unit tests.
 type MockLLMApifyClient struct {
@@ -46,6 +90,29 @@ func (m *MockLLMApifyClient) Process(workerID string, args teeargs.LLMProcessorA
 	return []*teetypes.LLMProcessorResult{}, client.EmptyCursor, nil
 }
 
+// MockWaybackClient is a mock implementation of the WaybackClient
+type MockWaybackClient struct {
+	Snapshot *wayback.Snapshot
+	Err      error
+}
+
+func (m *MockWaybackClient) LatestSnapshot(_ string) (*wayback.Snapshot, error) {
+	return m.Snapshot, m.Err
+}
+
+// MockSitemapClient is a mock implementation of the SitemapClient
+type MockSitemapClient struct {
+	URLs     []string
+	Err      error
+	SiteURL  string
+	MaxPages int
+}
+
+func (m *MockSitemapClient) Pages(siteURL string, maxPages int) ([]string, error) {
+	m.SiteURL, m.MaxPages = siteURL, maxPages
+	return m.URLs, m.Err
+}
+
 var _ = Describe("WebScraper", func() {
 	var (
 		scraper        *jobs.WebScraper
@@ -58,6 +125,8 @@ var _ = Describe("WebScraper", func() {
 	// Keep originals to restore after each test to avoid leaking globals
 	originalNewWebApifyClient := jobs.NewWebApifyClient
 	originalNewLLMApifyClient := jobs.NewLLMApifyClient
+	originalNewWaybackClient := jobs.NewWaybackClient
+	originalNewSitemapClient := jobs.NewSitemapClient
 
 	BeforeEach(func() {
 		statsCollector = stats.StartCollector(128, config.JobConfiguration{})
@@ -75,10 +144,10 @@ var _ = Describe("WebScraper", func() {
 		}
 
 		// Replace the client creation function with one that returns the mocks
-		jobs.NewWebApifyClient = func(apiKey string, _ *stats.StatsCollector) (jobs.WebApifyClient, error) {
+		jobs.NewWebApifyClient = func(apiKey string, _ *stats.StatsCollector, _ ...client.Option) (jobs.WebApifyClient, error) {
 			return mockClient, nil
 		}
-		jobs.NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, _ *stats.StatsCollector) (jobs.LLMApify, error) {
+		jobs.NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, _ *stats.StatsCollector, _ ...client.Option) (jobs.LLMApify, error) {
 			return mockLLM, nil
 		}
 
@@ -91,6 +160,8 @@ var _ = Describe("WebScraper", func() {
 	AfterEach(func() {
 		jobs.NewWebApifyClient = originalNewWebApifyClient
 		jobs.NewLLMApifyClient = originalNewLLMApifyClient
+		jobs.NewWaybackClient = originalNewWaybackClient
+		jobs.NewSitemapClient = originalNewSitemapClient
 	})
 
 	Context("ExecuteJob", func() {
@@ -109,9 +180,9 @@ var _ = Describe("WebScraper", func() {
 				"max_pages": 2,
 			}
 
-			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*teetypes.WebScraperResult, string, client.Cursor, error) {
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
 				Expect(args.URL).To(Equal("https://example.com"))
-				return []*teetypes.WebScraperResult{{URL: "https://example.com", Markdown: "# Hello"}}, "dataset-123", client.Cursor("next-cursor"), nil
+				return webPages(teetypes.WebScraperResult{URL: "https://example.com", Markdown: "# Hello"}), "dataset-123", client.Cursor("next-cursor"), nil
 			}
 
 			result, err := scraper.ExecuteJob(job)
@@ -126,6 +197,177 @@ var _ = Describe("WebScraper", func() {
 			Expect(resp[0].URL).To(Equal("https://example.com"))
 		})
 
+		It("should render JavaScript only if requested", func() {
+			job.Arguments = map[string]any{
+				"type":      teetypes.WebScraper,
+				"url":       "https://example.com",
+				"max_depth": 0,
+				"max_pages": 1,
+			}
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				return webPages(teetypes.WebScraperResult{URL: "https://example.com", Markdown: "# Hello"}), "dataset-123", client.EmptyCursor, nil
+			}
+
+			_, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockClient.Options.RenderJS).To(BeFalse())
+
+			job.Arguments["render_js"] = true
+			_, err = scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockClient.Options.RenderJS).To(BeTrue())
+		})
+
+		It("should present the profile of the domain until the domain blocks it", func() {
+			key := fingerprint.DomainKey("blocking.example.org")
+			profile := fingerprint.For(key)
+			job.Arguments = map[string]any{
+				"type":      teetypes.WebScraper,
+				"url":       "https://www.blocking.example.org/page",
+				"max_depth": 0,
+				"max_pages": 1,
+			}
+			status := http.StatusOK
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				return webPages(teetypes.WebScraperResult{URL: args.URL, Crawl: teetypes.WebCrawlInfo{HTTPStatusCode: status}}), "dataset-123", client.EmptyCursor, nil
+			}
+
+			_, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*mockClient.Options.Profile).To(Equal(profile))
+			Expect(fingerprint.For(key)).To(Equal(profile))
+
+			status = http.StatusForbidden
+			_, err = scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*mockClient.Options.Profile).To(Equal(profile))
+			Expect(fingerprint.For(key)).NotTo(Equal(profile))
+		})
+
+		It("should extract the requested content", func() {
+			job.Arguments = map[string]any{
+				"type":      teetypes.WebScraper,
+				"url":       "https://example.com",
+				"max_depth": 0,
+				"max_pages": 1,
+			}
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				return []*webapify.Page{{
+					WebScraperResult: teetypes.WebScraperResult{URL: "https://example.com", Text: "Hello", Markdown: "# Hello"},
+					HTML:             "<h1>Hello</h1>",
+				}}, "dataset-123", client.EmptyCursor, nil
+			}
+
+			pages := func() []map[string]any {
+				result, err := scraper.ExecuteJob(job)
+				Expect(err).NotTo(HaveOccurred())
+				var resp []map[string]any
+				Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+				Expect(resp).To(HaveLen(1))
+				return resp
+			}
+
+			page := pages()[0]
+			Expect(mockClient.Options.Extract).To(Equal(webapify.ExtractionSections))
+			Expect(page).To(HaveKeyWithValue("text", "Hello"))
+			Expect(page).To(HaveKeyWithValue("markdown", "# Hello"))
+
+			job.Arguments["extract"] = "markdown"
+			page = pages()[0]
+			Expect(mockClient.Options.Extract).To(Equal(webapify.ExtractionMarkdown))
+			Expect(page).To(HaveKeyWithValue("text", ""))
+			Expect(page).To(HaveKeyWithValue("markdown", "# Hello"))
+
+			job.Arguments["extract"] = "raw_html"
+			page = pages()[0]
+			Expect(mockClient.Options.Extract).To(Equal(webapify.ExtractionRawHTML))
+			Expect(page).To(HaveKeyWithValue("html", "<h1>Hello</h1>"))
+			Expect(page).To(HaveKeyWithValue("markdown", ""))
+
+			job.Arguments["extract"] = "summary"
+			_, err := scraper.ExecuteJob(job)
+			Expect(err).To(MatchError(ContainSubstring("invalid extract")))
+		})
+
+		It("should be as polite as configured or requested", func() {
+			job.Arguments = map[string]any{
+				"type":      teetypes.WebScraper,
+				"url":       "https://example.com",
+				"max_depth": 0,
+				"max_pages": 1,
+			}
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				return webPages(teetypes.WebScraperResult{URL: "https://example.com"}), "dataset-123", client.EmptyCursor, nil
+			}
+
+			_, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockClient.Options.RespectRobotsTxt).To(BeFalse())
+			Expect(mockClient.Options.MaxRequestsPerMinute).To(BeZero())
+
+			job.Arguments["respect_robots_txt"] = true
+			job.Arguments["max_requests_per_minute"] = float64(120)
+			_, err = scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockClient.Options.RespectRobotsTxt).To(BeTrue())
+			Expect(mockClient.Options.MaxRequestsPerMinute).To(Equal(120))
+
+			// Jobs can't be less polite than the configuration
+			scraper = jobs.NewWebScraper(config.JobConfiguration{
+				"apify_api_key":                      "test-key",
+				"gemini_api_key":                     "test-gemini-key",
+				"web_respect_robots_txt":             true,
+				"web_domain_max_requests_per_minute": 60,
+			}, statsCollector)
+			job.Arguments["respect_robots_txt"] = false
+			_, err = scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mockClient.Options.RespectRobotsTxt).To(BeTrue())
+			Expect(mockClient.Options.MaxRequestsPerMinute).To(Equal(60))
+
+			job.Arguments["max_requests_per_minute"] = float64(0)
+			_, err = scraper.ExecuteJob(job)
+			Expect(err).To(MatchError(ContainSubstring("max_requests_per_minute must be a positive integer")))
+		})
+
+		It("should limit the number of domains scraped at the same time", func() {
+			scraper = jobs.NewWebScraper(config.JobConfiguration{
+				"apify_api_key":              "test-key",
+				"gemini_api_key":             "test-gemini-key",
+				"web_max_concurrent_domains": 1,
+			}, statsCollector)
+
+			unblock := make(chan struct{})
+			started := make(chan string, 3)
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				started <- args.URL
+				<-unblock
+				return webPages(teetypes.WebScraperResult{URL: args.URL}), "dataset-123", client.EmptyCursor, nil
+			}
+
+			run := func(url string) {
+				j := job
+				j.Arguments = map[string]any{"type": teetypes.WebScraper, "url": url, "max_depth": 0, "max_pages": 1}
+				go func() {
+					defer GinkgoRecover()
+					_, err := scraper.ExecuteJob(j)
+					Expect(err).NotTo(HaveOccurred())
+				}()
+			}
+
+			run("https://example.com/a")
+			Eventually(started).Should(Receive(Equal("https://example.com/a")))
+
+			// Another domain waits, while the same domain shares the slot
+			run("https://other.com")
+			Consistently(started, 100*time.Millisecond).ShouldNot(Receive())
+			run("https://www.example.com/b")
+			Eventually(started).Should(Receive(Equal("https://www.example.com/b")))
+
+			close(unblock)
+			Eventually(started).Should(Receive(Equal("https://other.com")))
+		})
+
 		It("should handle errors from the web client", func() {
 			job.Arguments = map[string]any{
 				"type":      teetypes.WebScraper,
@@ -135,7 +377,7 @@ var _ = Describe("WebScraper", func() {
 			}
 
 			expectedErr := errors.New("client error")
-			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*teetypes.WebScraperResult, string, client.Cursor, error) {
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
 				return nil, "", client.EmptyCursor, expectedErr
 			}
 
@@ -146,7 +388,7 @@ var _ = Describe("WebScraper", func() {
 		})
 
 		It("should handle errors when creating the client", func() {
-			jobs.NewWebApifyClient = func(apiKey string, _ *stats.StatsCollector) (jobs.WebApifyClient, error) {
+			jobs.NewWebApifyClient = func(apiKey string, _ *stats.StatsCollector, _ ...client.Option) (jobs.WebApifyClient, error) {
 				return nil, errors.New("client creation failed")
 			}
 			job.Arguments = map[string]any{
@@ -162,6 +404,349 @@ var _ = Describe("WebScraper", func() {
 		})
 	})
 
+	Context("Archive fallback", func() {
+		snapshot := &wayback.Snapshot{
+			URL:       "http://web.archive.org/web/20240101000000/https://example.com/gone",
+			Timestamp: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		BeforeEach(func() {
+			jobs.NewWaybackClient = func(_ types.Network) jobs.WaybackClient {
+				return &MockWaybackClient{Snapshot: snapshot}
+			}
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				if args.URL == snapshot.URL {
+					Expect(args.MaxDepth).To(Equal(0))
+					Expect(args.MaxPages).To(Equal(1))
+					return webPages(teetypes.WebScraperResult{URL: snapshot.URL, Markdown: "# Archived"}), "archived-dataset", client.EmptyCursor, nil
+				}
+				return webPages(teetypes.WebScraperResult{URL: args.URL, Crawl: teetypes.WebCrawlInfo{HTTPStatusCode: 404}}), "dataset-123", client.Cursor("next-cursor"), nil
+			}
+		})
+
+		It("should scrape the latest snapshot of a dead page and mark it as archived", func() {
+			job.Arguments = map[string]any{
+				"type":             teetypes.WebScraper,
+				"url":              "https://example.com/gone",
+				"archive_fallback": true,
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.NextCursor).To(BeEmpty())
+
+			var resp []struct {
+				teetypes.WebScraperResult
+				Provenance *types.Provenance `json:"provenance"`
+			}
+			Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+			Expect(resp).To(HaveLen(1))
+			Expect(resp[0].Markdown).To(Equal("# Archived"))
+			Expect(resp[0].Provenance).To(Equal(&types.Provenance{
+				Type:              types.ProvenanceArchived,
+				Source:            "archive.org",
+				SnapshotURL:       snapshot.URL,
+				SnapshotTimestamp: snapshot.Timestamp,
+			}))
+		})
+
+		It("should keep the original result if the fallback was not requested", func() {
+			job.Arguments = map[string]any{
+				"type": teetypes.WebScraper,
+				"url":  "https://example.com/gone",
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result.Data)).NotTo(ContainSubstring("provenance"))
+			Expect(result.NextCursor).To(Equal("next-cursor"))
+		})
+
+		It("should keep the original result if there is no snapshot", func() {
+			jobs.NewWaybackClient = func(_ types.Network) jobs.WaybackClient {
+				return &MockWaybackClient{Err: wayback.ErrNoSnapshot}
+			}
+			job.Arguments = map[string]any{
+				"type":             teetypes.WebScraper,
+				"url":              "https://example.com/gone",
+				"archive_fallback": true,
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result.Data)).NotTo(ContainSubstring("provenance"))
+		})
+
+		It("should use the fallback for paywalled pages", func() {
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				if args.URL == snapshot.URL {
+					return webPages(teetypes.WebScraperResult{URL: snapshot.URL, Markdown: "# Archived"}), "archived-dataset", client.EmptyCursor, nil
+				}
+				return webPages(teetypes.WebScraperResult{URL: args.URL, Text: "Subscribe to continue reading", Crawl: teetypes.WebCrawlInfo{HTTPStatusCode: 200}}), "dataset-123", client.EmptyCursor, nil
+			}
+			job.Arguments = map[string]any{
+				"type":             teetypes.WebScraper,
+				"url":              "https://example.com/gone",
+				"archive_fallback": true,
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result.Data)).To(ContainSubstring(`"type":"archived"`))
+		})
+	})
+
+	Context("Sitemap mode", func() {
+		var sitemapClient *MockSitemapClient
+
+		BeforeEach(func() {
+			sitemapClient = &MockSitemapClient{URLs: []string{"https://example.com/a", "https://example.com/b"}}
+			jobs.NewSitemapClient = func(_ types.Network, delay time.Duration) jobs.SitemapClient {
+				Expect(delay).To(Equal(time.Second))
+				return sitemapClient
+			}
+			mockClient.ScrapeFunc = func(args teeargs.WebArguments) ([]*webapify.Page, string, client.Cursor, error) {
+				Fail("links should not be followed in sitemap mode")
+				return nil, "", client.EmptyCursor, nil
+			}
+		})
+
+		It("should scrape the pages listed in the sitemaps", func() {
+			var scraped []string
+			mockClient.ScrapePagesFunc = func(urls []string) ([]*webapify.Page, string, error) {
+				scraped = urls
+				return webPages(teetypes.WebScraperResult{URL: urls[0]}, teetypes.WebScraperResult{URL: urls[1]}), "dataset-123", nil
+			}
+			job.Arguments = map[string]any{
+				"type":      teetypes.WebScraper,
+				"url":       "https://example.com",
+				"max_pages": 5,
+				"mode":      "sitemap",
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(sitemapClient.SiteURL).To(Equal("https://example.com"))
+			Expect(sitemapClient.MaxPages).To(Equal(5))
+			Expect(scraped).To(Equal([]string{"https://example.com/a", "https://example.com/b"}))
+
+			var resp []*teetypes.WebScraperResult
+			Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+			Expect(resp).To(HaveLen(2))
+		})
+
+		It("should fail if the sitemaps cannot be read", func() {
+			sitemapClient.Err = errors.New("sitemap returned status code 404")
+			job.Arguments = map[string]any{
+				"type": teetypes.WebScraper,
+				"url":  "https://example.com",
+				"mode": "sitemap",
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Error).To(ContainSubstring("error reading the sitemaps of https://example.com: sitemap returned status code 404"))
+		})
+
+		It("should reject unknown modes", func() {
+			job.Arguments = map[string]any{
+				"type": teetypes.WebScraper,
+				"url":  "https://example.com",
+				"mode": "spider",
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Error).To(ContainSubstring(`invalid mode "spider"`))
+		})
+	})
+
+	Context("Lists of pages", func() {
+		var (
+			mu   sync.Mutex
+			runs map[string][]string
+		)
+
+		BeforeEach(func() {
+			runs = map[string][]string{}
+			mockClient.ScrapeURLsFunc = func(urls []string, opts webapify.ScrapeOptions) ([]*webapify.Page, string, error) {
+				Expect(opts.MaxConcurrency).To(BeNumerically(">", 0))
+				mu.Lock()
+				defer mu.Unlock()
+				runs[urls[0]] = urls
+				switch urls[0] {
+				case "https://example.com/a":
+					return webPages(
+						teetypes.WebScraperResult{URL: "https://example.com/c/", Markdown: "# C", Crawl: teetypes.WebCrawlInfo{HTTPStatusCode: 404}},
+						teetypes.WebScraperResult{URL: "https://EXAMPLE.com/a", Markdown: "# A", Crawl: teetypes.WebCrawlInfo{HTTPStatusCode: 200}},
+					), "dataset-example", nil
+				case "https://other.org/x":
+					return nil, "", errors.New("actor run failed")
+				}
+				return nil, "", nil
+			}
+		})
+
+		It("should scrape the pages of each domain in a single run and return the status of every page", func() {
+			job.Arguments = map[string]any{
+				"type": "scraper",
+				"urls": []any{"https://example.com/a", "https://other.org/x", "https://example.com/b", "https://example.com/c", "https://example.com/a"},
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(HaveLen(2))
+			Expect(runs["https://example.com/a"]).To(Equal([]string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}))
+
+			var resp []jobs.WebURLResult
+			Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+			Expect(resp).To(HaveLen(4))
+			Expect(resp[0].URL).To(Equal("https://example.com/a"))
+			Expect(resp[0].Status).To(Equal(jobs.WebURLScraped))
+			Expect(resp[0].Page.Markdown).To(Equal("# A"))
+			Expect(resp[1].Status).To(Equal(jobs.WebURLFailed))
+			Expect(resp[1].Error).To(ContainSubstring("actor run failed"))
+			Expect(resp[2].Status).To(Equal(jobs.WebURLNotScraped))
+			Expect(resp[2].Page).To(BeNil())
+			Expect(resp[3].Status).To(Equal(jobs.WebURLHTTPError))
+			Expect(resp[3].Page.Markdown).To(Equal("# C"))
+		})
+
+		It("should fail if none of the pages could be scraped", func() {
+			job.Arguments = map[string]any{"type": "scraper", "urls": []any{"https://other.org/x"}}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).To(HaveOccurred())
+			Expect(result.Error).To(ContainSubstring("actor run failed"))
+		})
+
+		It("should split the pages of a domain into runs of bounded size", func() {
+			urls := make([]any, 250)
+			for i := range urls {
+				urls[i] = fmt.Sprintf("https://many.net/%d", i)
+			}
+			job.Arguments = map[string]any{"type": "scraper", "urls": urls}
+
+			_, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(HaveLen(3))
+			Expect(runs["https://many.net/0"]).To(HaveLen(100))
+			Expect(runs["https://many.net/200"]).To(HaveLen(50))
+		})
+
+		It("should reject invalid lists of pages", func() {
+			tooMany := make([]any, 501)
+			for i := range tooMany {
+				tooMany[i] = fmt.Sprintf("https://example.com/%d", i)
+			}
+			for _, args := range []map[string]any{
+				{"urls": "https://example.com"},
+				{"urls": []any{}},
+				{"urls": []any{"ftp://example.com"}},
+				{"urls": []any{42}},
+				{"urls": tooMany},
+				{"urls": []any{"https://example.com"}, "mode": "sitemap"},
+				{"urls": []any{"https://example.com"}, "max_depth": float64(1)},
+				{"urls": []any{"https://example.com"}, "archive_fallback": true},
+			} {
+				args["type"] = "scraper"
+				job.Arguments = args
+				_, err := scraper.ExecuteJob(job)
+				Expect(err).To(HaveOccurred(), "%v", args)
+			}
+			Expect(runs).To(BeEmpty())
+		})
+	})
+
+	Context("Screenshots", func() {
+		var images *httptest.Server
+
+		BeforeEach(func() {
+			images = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "image/png")
+				_, _ = w.Write([]byte("\x89PNG image"))
+			}))
+			DeferCleanup(images.Close)
+		})
+
+		It("should capture the page and return the image", func() {
+			var opts webapify.ScreenshotOptions
+			mockClient.ScreenshotFunc = func(url string, o webapify.ScreenshotOptions) (*webapify.Screenshot, error) {
+				Expect(url).To(Equal("https://example.com"))
+				opts = o
+				return &webapify.Screenshot{URL: url, ScreenshotURL: images.URL + "/screenshot"}, nil
+			}
+			job.Arguments = map[string]any{
+				"type":      "screenshot",
+				"url":       "https://example.com",
+				"full_page": true,
+				"format":    "JPG",
+				"quality":   70,
+			}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			// Without a viewport_width, the page is captured in the viewport of the profile of the domain
+			profile := fingerprint.For(fingerprint.DomainKey("example.com"))
+			Expect(opts).To(Equal(webapify.ScreenshotOptions{FullPage: true, Format: webapify.ScreenshotJPEG, Quality: 70, ViewportWidth: profile.ViewportWidth}))
+
+			var resp jobs.WebScreenshot
+			Expect(json.Unmarshal(result.Data, &resp)).To(Succeed())
+			Expect(resp.URL).To(Equal("https://example.com"))
+			Expect(resp.Format).To(Equal("jpeg"))
+			Expect(resp.ContentType).To(Equal("image/png"))
+			Expect(resp.FullPage).To(BeTrue())
+			Expect(resp.Data).To(Equal([]byte("\x89PNG image")))
+			Expect(resp.Size).To(Equal(len(resp.Data)))
+		})
+
+		It("should not need a Gemini key", func() {
+			scraper = jobs.NewWebScraper(config.JobConfiguration{"apify_api_key": "test-key"}, statsCollector)
+			Expect(scraper.GetStructuredCapabilities()[teetypes.WebJob]).To(ConsistOf(jobs.CapScreenshot))
+
+			mockClient.ScreenshotFunc = func(url string, _ webapify.ScreenshotOptions) (*webapify.Screenshot, error) {
+				return &webapify.Screenshot{URL: url, ScreenshotURL: images.URL}, nil
+			}
+			job.Arguments = map[string]any{"type": "screenshot", "url": "https://example.com"}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Error).To(BeEmpty())
+		})
+
+		It("should reject invalid arguments", func() {
+			mockClient.ScreenshotFunc = func(string, webapify.ScreenshotOptions) (*webapify.Screenshot, error) {
+				Fail("no screenshot should be taken")
+				return nil, nil
+			}
+			for _, args := range []map[string]any{
+				{"url": "ftp://example.com"},
+				{"url": "https://example.com", "format": "gif"},
+				{"url": "https://example.com", "quality": 70},
+				{"url": "https://example.com", "format": "jpeg", "quality": 101},
+				{"url": "https://example.com", "viewport_width": 100},
+			} {
+				args["type"] = "screenshot"
+				job.Arguments = args
+				result, err := scraper.ExecuteJob(job)
+				Expect(err).To(HaveOccurred(), "%v", args)
+				Expect(result.Error).To(Equal("error unmarshalling job arguments"))
+			}
+		})
+
+		It("should accept the screenshot arguments", func() {
+			Expect(scraper.ArgumentKeys()).To(ContainElements("full_page", "format", "quality", "viewport_width"))
+		})
+
+		It("should fail if no screenshot was taken", func() {
+			job.Arguments = map[string]any{"type": "screenshot", "url": "https://example.com"}
+
+			result, err := scraper.ExecuteJob(job)
+			Expect(err).To(MatchError(webapify.ErrNoScreenshot))
+			Expect(result.Error).To(ContainSubstring("no screenshot was taken"))
+		})
+	})
+
 	// Integration tests that use the real client
 	Context("Integration tests", func() {
 		var (
@@ -178,10 +763,10 @@ var _ = Describe("WebScraper", func() {
 			}
 
 			// Reset to use real client for integration tests
-			jobs.NewWebApifyClient = func(apiKey string, s *stats.StatsCollector) (jobs.WebApifyClient, error) {
+			jobs.NewWebApifyClient = func(apiKey string, s *stats.StatsCollector, _ ...client.Option) (jobs.WebApifyClient, error) {
 				return webapify.NewClient(apiKey, s)
 			}
-			jobs.NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, s *stats.StatsCollector) (jobs.LLMApify, error) {
+			jobs.NewLLMApifyClient = func(apiKey string, llmConfig config.LlmConfig, s *stats.StatsCollector, _ ...client.Option) (jobs.LLMApify, error) {
 				return llmapify.NewClient(apiKey, llmConfig, s)
 			}
 		})
@@ -0,0 +1,302 @@
+package jobs
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+// urlsArgumentKey is the job argument listing the pages to scrape, instead of crawling the links of url
+const urlsArgumentKey = "urls"
+
+const (
+	// maxWebURLs is the maximum number of pages listed in urls
+	maxWebURLs = 500
+	// webURLsPerRun is the maximum number of pages scraped by a single actor run
+	webURLsPerRun = 100
+	// maxParallelWebRuns is the maximum number of actor runs of a job at the same time
+	maxParallelWebRuns = 8
+	// webRunConcurrency is the number of pages fetched at the same time by an actor run, which only scrapes pages of
+	// a single domain
+	webRunConcurrency = 4
+)
+
+// WebURLStatus tells whether one of the pages listed in urls was scraped
+type WebURLStatus string
+
+const (
+	// WebURLScraped is a page which was scraped
+	WebURLScraped WebURLStatus = "ok"
+	// WebURLHTTPError is a page whose server responded with an HTTP error, the page is still returned
+	WebURLHTTPError WebURLStatus = "http_error"
+	// WebURLNotScraped is a page which could not be loaded, or was disallowed by robots.txt
+	WebURLNotScraped WebURLStatus = "not_scraped"
+	// WebURLFailed is a page whose actor run failed
+	WebURLFailed WebURLStatus = "failed"
+)
+
+// WebURLResult is the result of one of the pages listed in urls
+type WebURLResult struct {
+	URL    string         `json:"url"`
+	Status WebURLStatus   `json:"status"`
+	Error  string         `json:"error,omitempty"`
+	Page   *webapify.Page `json:"page,omitempty"`
+}
+
+// parseWebURLs returns the pages listed in urls, with url first if it is set too, without duplicates
+func parseWebURLs(args types.JobArguments) ([]string, error) {
+	var raw []any
+	switch v := args[urlsArgumentKey].(type) {
+	case []any:
+		raw = v
+	case []string:
+		for _, u := range v {
+			raw = append(raw, u)
+		}
+	default:
+		return nil, fmt.Errorf("%s must be a list of URLs, got %T", urlsArgumentKey, v)
+	}
+	if u, ok := args["url"]; ok && u != "" {
+		raw = append([]any{u}, raw...)
+	}
+
+	urls := make([]string, 0, len(raw))
+	seen := make(map[string]bool, len(raw))
+	for _, v := range raw {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("%s must be a list of URLs, got an element of type %T", urlsArgumentKey, v)
+		}
+		s = strings.TrimSpace(s)
+		if u, err := url.Parse(s); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid URL %q in %s", s, urlsArgumentKey)
+		}
+		if !seen[s] {
+			seen[s] = true
+			urls = append(urls, s)
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("%s must list at least one URL", urlsArgumentKey)
+	}
+	if len(urls) > maxWebURLs {
+		return nil, fmt.Errorf("%s must list at most %d URLs, got %d", urlsArgumentKey, maxWebURLs, len(urls))
+	}
+
+	// Links are not followed and there is no archived copy of a list of pages
+	if mode, _ := args[modeArgumentKey].(string); mode != "" && mode != webModeCrawl {
+		return nil, fmt.Errorf("%s can't be used with mode %q", urlsArgumentKey, mode)
+	}
+	if depth, _ := args["max_depth"].(float64); depth > 0 {
+		return nil, fmt.Errorf("%s can't be used with max_depth", urlsArgumentKey)
+	}
+	if fallback, _ := args[archiveFallbackArgumentKey].(bool); fallback {
+		return nil, fmt.Errorf("%s can't be used with %s", urlsArgumentKey, archiveFallbackArgumentKey)
+	}
+
+	return urls, nil
+}
+
+// webURLRuns splits the pages into the actor runs which scrape them. Each run only scrapes pages of a single domain,
+// so the politeness settings, which apply to a run, are per domain as for other web jobs.
+func webURLRuns(urls []string) [][]string {
+	var domains []string
+	byDomain := map[string][]string{}
+	for _, u := range urls {
+		d := domainOf(u)
+		if _, ok := byDomain[d]; !ok {
+			domains = append(domains, d)
+		}
+		byDomain[d] = append(byDomain[d], u)
+	}
+
+	var runs [][]string
+	for _, d := range domains {
+		for pages := byDomain[d]; len(pages) > 0; {
+			n := min(len(pages), webURLsPerRun)
+			runs = append(runs, pages[:n])
+			pages = pages[n:]
+		}
+	}
+	return runs
+}
+
+// executeURLs scrapes the pages listed in urls without following their links. The pages of each domain are scraped
+// by a single actor run, and runs for different domains run in parallel, so a job with hundreds of pages doesn't pay
+// for the start-up time of the actor for each of them. The result has the status of every page, in the order they
+// were listed, and the job only fails if none of the pages could be scraped.
+func (w *WebScraper) executeURLs(j types.Job) (types.JobResult, error) {
+	urls, err := parseWebURLs(j.Arguments)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	extract, err := extractionFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	respectRobotsTxt, maxRequestsPerMinute, err := w.politenessFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResult{Error: err.Error()}, err
+	}
+
+	renderJS, _ := j.Arguments[renderJSArgumentKey].(bool)
+	opts := webapify.ScrapeOptions{
+		RenderJS:             renderJS,
+		Extract:              extract,
+		RespectRobotsTxt:     respectRobotsTxt,
+		MaxRequestsPerMinute: maxRequestsPerMinute,
+		MaxConcurrency:       webRunConcurrency,
+	}
+
+	webClient, err := NewWebApifyClient(w.configuration.ApifyApiKey, w.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error while scraping Web"}, fmt.Errorf("error creating Web Apify client: %w", err)
+	}
+	llmClient, err := NewLLMApifyClient(w.configuration.ApifyApiKey, w.configuration.LlmConfig, w.statsCollector, apifyOptions(j)...)
+	if err != nil {
+		return types.JobResult{Error: "error creating LLM Apify client"}, fmt.Errorf("failed to create LLM Apify client: %w", err)
+	}
+
+	runs := webURLRuns(urls)
+	logrus.WithField("job_uuid", j.UUID).Debugf("Scraping %d pages in %d actor runs", len(urls), len(runs))
+
+	byURL := make(map[string]WebURLResult, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, maxParallelWebRuns)
+	for _, run := range runs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			// Runs wait for a slot if WEB_MAX_CONCURRENT_DOMAINS other domains are already being scraped
+			domain := domainOf(run[0])
+			release := webDomains.acquire(domain, w.configuration.MaxConcurrentDomains)
+			defer release()
+
+			runOpts := opts
+			runOpts.Profile = domainProfile(domain)
+			results := scrapeURLRun(j, run, runOpts, webClient, llmClient)
+			mu.Lock()
+			defer mu.Unlock()
+			for _, r := range results {
+				byURL[r.URL] = r
+			}
+		}()
+	}
+	wg.Wait()
+
+	results := make([]WebURLResult, len(urls))
+	scraped := 0
+	var runErr string
+	for i, u := range urls {
+		results[i] = byURL[u]
+		switch results[i].Status {
+		case WebURLScraped, WebURLHTTPError:
+			scraped++
+		case WebURLFailed:
+			runErr = results[i].Error
+		}
+	}
+	if scraped == 0 && runErr != "" {
+		msg := fmt.Sprintf("error while scraping Web: none of the %d pages could be scraped: %s", len(urls), runErr)
+		return types.JobResult{Error: msg}, errors.New(msg)
+	}
+
+	res, err := apifyPageResult(results, client.EmptyCursor)
+	if err != nil {
+		return res, err
+	}
+
+	if w.statsCollector != nil {
+		w.statsCollector.Add(j.WorkerID, stats.WebProcessedPages, uint(scraped))
+	}
+
+	res.Job = j
+	return res, nil
+}
+
+// scrapeURLRun scrapes and summarizes the pages of an actor run, and returns the result of each of them
+func scrapeURLRun(j types.Job, urls []string, opts webapify.ScrapeOptions, webClient WebApifyClient, llmClient LLMApify) []WebURLResult {
+	results := make([]WebURLResult, len(urls))
+	for i, u := range urls {
+		results[i] = WebURLResult{URL: u, Status: WebURLNotScraped}
+	}
+
+	pages, datasetId, err := webClient.ScrapeURLs(j.WorkerID, urls, opts)
+	rotateIfBlocked(domainOf(urls[0]), pages)
+	if err == nil && len(pages) > 0 {
+		if datasetId == "" {
+			err = errors.New("missing dataset id from web scraping")
+		} else if _, llmErr := summarizePages(j, llmClient, datasetId, pages); llmErr != nil {
+			err = fmt.Errorf("error while processing LLM: %w", llmErr)
+		}
+	}
+	if err != nil {
+		logrus.WithField("job_uuid", j.UUID).Warnf("Scraping %d pages of %s failed: %s", len(urls), domainOf(urls[0]), err)
+		for i := range results {
+			results[i].Status = WebURLFailed
+			results[i].Error = err.Error()
+		}
+		return results
+	}
+
+	index := make(map[string]int, len(urls))
+	for i, u := range urls {
+		index[normalizeWebURL(u)] = i
+	}
+	for _, page := range pages {
+		if page == nil {
+			continue
+		}
+		i, ok := index[normalizeWebURL(page.URL)]
+		if !ok {
+			i, ok = index[normalizeWebURL(page.Crawl.LoadedURL)]
+		}
+		// A page which was redirected to another URL can still be matched if it was the only page of the run
+		if !ok && len(urls) == 1 {
+			i, ok = 0, true
+		}
+		if !ok || results[i].Page != nil {
+			continue
+		}
+
+		extractContent(page, opts.Extract)
+		results[i].Page = page
+		results[i].Status = WebURLScraped
+		if code := page.Crawl.HTTPStatusCode; code >= http.StatusBadRequest {
+			results[i].Status = WebURLHTTPError
+			results[i].Error = fmt.Sprintf("HTTP status %d", code)
+		}
+	}
+	return results
+}
+
+// normalizeWebURL returns the URL without its fragment and trailing slash, and with a lower case scheme and host, to
+// match the pages returned by the crawler to the URLs they were requested with
+func normalizeWebURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	u.Fragment = ""
+	u.Path = strings.TrimSuffix(u.Path, "/")
+	return u.String()
+}
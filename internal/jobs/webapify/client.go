@@ -7,11 +7,96 @@ import (
 	teeargs "github.com/masa-finance/tee-types/args"
 	teetypes "github.com/masa-finance/tee-types/types"
 	"github.com/masa-finance/tee-worker/internal/apify"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
 	"github.com/masa-finance/tee-worker/pkg/client"
 	"github.com/sirupsen/logrus"
 )
 
+// CrawlerType selects how the website content crawler fetches pages
+type CrawlerType string
+
+const (
+	// CrawlerTypeStatic fetches pages with plain HTTP requests and parses the returned HTML
+	CrawlerTypeStatic CrawlerType = "cheerio"
+	// CrawlerTypeBrowser loads pages in a headless browser, so content rendered with JavaScript is scraped as well
+	CrawlerTypeBrowser CrawlerType = "playwright:firefox"
+)
+
+// Extraction selects what is extracted from the content of each page
+type Extraction string
+
+const (
+	// ExtractionSections returns the text and markdown of each page, extracted with the defaults of the crawler. This
+	// is the default.
+	ExtractionSections Extraction = "sections"
+	// ExtractionReadability returns the text and markdown of the main article of each page, leaving out navigation,
+	// headers, footers and sidebars
+	ExtractionReadability Extraction = "readability"
+	// ExtractionMarkdown returns only the markdown of each page
+	ExtractionMarkdown Extraction = "markdown"
+	// ExtractionRawHTML returns the whole HTML of each page, without removing any element
+	ExtractionRawHTML Extraction = "raw_html"
+)
+
+// AllExtractions are the supported extractions
+var AllExtractions = []Extraction{ExtractionSections, ExtractionReadability, ExtractionMarkdown, ExtractionRawHTML}
+
+// ScrapeOptions control how pages are fetched and what is extracted from them
+type ScrapeOptions struct {
+	// RenderJS loads pages in a headless browser, which is slower but also returns content rendered with JavaScript
+	RenderJS bool
+	Extract  Extraction
+	// RespectRobotsTxt skips the pages disallowed by the robots.txt of their site
+	RespectRobotsTxt bool
+	// MaxRequestsPerMinute caps the rate of the requests of the crawler, 0 leaves it to the crawler. A run crawls the
+	// domain of its start URLs, so this is the request rate to that domain.
+	MaxRequestsPerMinute int
+	// MaxConcurrency caps the number of pages fetched at the same time, 0 leaves it to the crawler
+	MaxConcurrency int
+	// Profile is the browser profile whose headers the start URLs are requested with, nil leaves them to the crawler
+	Profile *fingerprint.Profile
+}
+
+// Page is a scraped page. HTML is only set with ExtractionRawHTML.
+type Page struct {
+	teetypes.WebScraperResult
+	HTML string `json:"html,omitempty"`
+}
+
+// startURL is a start URL of the actor, with the headers it is requested with
+type startURL struct {
+	teetypes.WebStartURL
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// scrapeInput is the actor input, with the crawler settings added to the request built from the web arguments
+type scrapeInput struct {
+	teetypes.WebScraperRequest
+	// StartUrls replaces the start URLs of the request, which have no headers
+	StartUrls                 []startURL  `json:"startUrls"`
+	CrawlerType               CrawlerType `json:"crawlerType"`
+	MaxConcurrency            int         `json:"maxConcurrency,omitempty"`
+	MaxRequestsPerMinute      int         `json:"maxRequestsPerMinute,omitempty"`
+	HTMLTransformer           string      `json:"htmlTransformer,omitempty"`
+	RemoveElementsCSSSelector *string     `json:"removeElementsCssSelector,omitempty"`
+	SaveHTML                  bool        `json:"saveHtml,omitempty"`
+}
+
+// applyExtraction sets the crawler settings which select the content extracted from each page
+func (input *scrapeInput) applyExtraction(extract Extraction) {
+	switch extract {
+	case ExtractionReadability:
+		// Mozilla Readability extracts the main article of the page
+		input.HTMLTransformer = "readableText"
+	case ExtractionRawHTML:
+		noElements := ""
+		input.HTMLTransformer = "none"
+		input.RemoveElementsCSSSelector = &noElements
+		input.SaveHTML = true
+	}
+}
+
 type ApifyClient struct {
 	client         client.Apify
 	statsCollector *stats.StatsCollector
@@ -19,13 +104,13 @@ type ApifyClient struct {
 
 // NewInternalClient is a function variable that can be replaced in tests.
 // It defaults to the actual implementation.
-var NewInternalClient = func(apiKey string) (client.Apify, error) {
-	return client.NewApifyClient(apiKey)
+var NewInternalClient = func(apiKey string, opts ...client.Option) (client.Apify, error) {
+	return client.NewApifyClient(apiKey, opts...)
 }
 
 // NewClient creates a new Reddit Apify client
-func NewClient(apiToken string, statsCollector *stats.StatsCollector) (*ApifyClient, error) {
-	client, err := NewInternalClient(apiToken)
+func NewClient(apiToken string, statsCollector *stats.StatsCollector, opts ...client.Option) (*ApifyClient, error) {
+	client, err := NewInternalClient(apiToken, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create apify client: %w", err)
 	}
@@ -41,14 +126,67 @@ func (c *ApifyClient) ValidateApiKey() error {
 	return c.client.ValidateApiKey()
 }
 
-func (c *ApifyClient) Scrape(workerID string, args teeargs.WebArguments, cursor client.Cursor) ([]*teetypes.WebScraperResult, string, client.Cursor, error) {
+// Scrape crawls the pages selected by the arguments
+func (c *ApifyClient) Scrape(workerID string, args teeargs.WebArguments, opts ScrapeOptions, cursor client.Cursor) ([]*Page, string, client.Cursor, error) {
+	input := scrapeInput{WebScraperRequest: args.ToWebScraperRequest()}
+	return c.run(workerID, input, opts, cursor, uint(args.MaxPages))
+}
+
+// ScrapePages scrapes exactly the given pages without following their links. The pages are fetched one at a time and
+// robots.txt is respected, so a site is not hammered with requests for all of its pages at once.
+func (c *ApifyClient) ScrapePages(workerID string, urls []string, opts ScrapeOptions) ([]*Page, string, error) {
+	opts.MaxConcurrency = 1
+	opts.RespectRobotsTxt = true
+	return c.ScrapeURLs(workerID, urls, opts)
+}
+
+// ScrapeURLs scrapes exactly the given pages without following their links, in a single actor run, so the start-up
+// time of the actor is paid once for all of them. Pages which could not be loaded are not in the returned pages.
+func (c *ApifyClient) ScrapeURLs(workerID string, urls []string, opts ScrapeOptions) ([]*Page, string, error) {
+	startURLs := make([]teetypes.WebStartURL, len(urls))
+	for i, u := range urls {
+		startURLs[i] = teetypes.WebStartURL{URL: u, Method: teeargs.WebDefaultMethod}
+	}
+	input := scrapeInput{
+		WebScraperRequest: teetypes.WebScraperRequest{
+			StartUrls:     startURLs,
+			MaxCrawlDepth: 0,
+			MaxCrawlPages: len(urls),
+			SaveMarkdown:  teeargs.WebDefaultSaveMarkdown,
+		},
+	}
+
+	resp, datasetId, _, err := c.run(workerID, input, opts, client.EmptyCursor, uint(len(urls)))
+	return resp, datasetId, err
+}
+
+// run runs the actor with the given input and returns up to limit results
+func (c *ApifyClient) run(workerID string, input scrapeInput, opts ScrapeOptions, cursor client.Cursor, limit uint) ([]*Page, string, client.Cursor, error) {
+	input.CrawlerType = CrawlerTypeStatic
+	if opts.RenderJS {
+		input.CrawlerType = CrawlerTypeBrowser
+	}
+	input.applyExtraction(opts.Extract)
+	input.RespectRobotsTxtFile = input.RespectRobotsTxtFile || opts.RespectRobotsTxt
+	input.MaxRequestsPerMinute = opts.MaxRequestsPerMinute
+	input.MaxConcurrency = opts.MaxConcurrency
+	input.StartUrls = make([]startURL, len(input.WebScraperRequest.StartUrls))
+	for i, u := range input.WebScraperRequest.StartUrls {
+		input.StartUrls[i] = startURL{WebStartURL: u}
+		if opts.Profile != nil {
+			input.StartUrls[i].Headers = opts.Profile.Headers()
+		}
+	}
+
 	if c.statsCollector != nil {
 		c.statsCollector.Add(workerID, stats.WebQueries, 1)
+		if opts.RenderJS {
+			c.statsCollector.Add(workerID, stats.WebRenderedScrapes, 1)
+		} else {
+			c.statsCollector.Add(workerID, stats.WebStaticScrapes, 1)
+		}
 	}
 
-	input := args.ToWebScraperRequest()
-
-	limit := uint(args.MaxPages)
 	dataset, nextCursor, err := c.client.RunActorAndGetResponse(apify.ActorIds.WebScraper, input, cursor, limit)
 	if err != nil {
 		if c.statsCollector != nil {
@@ -57,10 +195,10 @@ func (c *ApifyClient) Scrape(workerID string, args teeargs.WebArguments, cursor
 		return nil, "", client.EmptyCursor, err
 	}
 
-	response := make([]*teetypes.WebScraperResult, 0, len(dataset.Data.Items))
+	response := make([]*Page, 0, len(dataset.Data.Items))
 
 	for i, item := range dataset.Data.Items {
-		var resp teetypes.WebScraperResult
+		var resp Page
 		if err := json.Unmarshal(item, &resp); err != nil {
 			logrus.Warnf("Failed to unmarshal scrape result at index %d: %v", i, err)
 			continue
@@ -9,6 +9,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/masa-finance/tee-worker/internal/apify"
+	"github.com/masa-finance/tee-worker/internal/fingerprint"
 	"github.com/masa-finance/tee-worker/internal/jobs/webapify"
 	"github.com/masa-finance/tee-worker/pkg/client"
 
@@ -56,7 +57,7 @@ var _ = Describe("WebApifyClient", func() {
 		geminiKey = os.Getenv("GEMINI_API_KEY")
 		mockClient = &MockApifyClient{}
 		// Replace the client creation function with one that returns the mock
-		webapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+		webapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 			return mockClient, nil
 		}
 		var err error
@@ -78,10 +79,96 @@ var _ = Describe("WebApifyClient", func() {
 				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "next", nil
 			}
 
-			_, _, _, err := webClient.Scrape("test-worker", args, client.EmptyCursor)
+			_, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
+		It("should select the crawler type depending on JavaScript rendering", func() {
+			args := teeargs.WebArguments{
+				URL:      "https://example.com",
+				MaxDepth: 0,
+				MaxPages: 1,
+			}
+
+			var crawlerType any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				var fields map[string]any
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				Expect(fields["startUrls"]).To(HaveLen(1))
+				crawlerType = fields["crawlerType"]
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, client.EmptyCursor, nil
+			}
+
+			_, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crawlerType).To(Equal(string(webapify.CrawlerTypeStatic)))
+
+			_, _, _, err = webClient.Scrape("test-worker", args, webapify.ScrapeOptions{RenderJS: true}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(crawlerType).To(Equal(string(webapify.CrawlerTypeBrowser)))
+		})
+
+		It("should configure the crawler for the extraction", func() {
+			args := teeargs.WebArguments{
+				URL:      "https://example.com",
+				MaxDepth: 0,
+				MaxPages: 1,
+			}
+
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				fields = nil
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				item := json.RawMessage(`{"url": "https://example.com", "markdown": "# Hello", "html": "<h1>Hello</h1>"}`)
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{item}}}, client.EmptyCursor, nil
+			}
+
+			_, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{Extract: webapify.ExtractionSections}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).NotTo(HaveKey("htmlTransformer"))
+			Expect(fields).NotTo(HaveKey("saveHtml"))
+
+			_, _, _, err = webClient.Scrape("test-worker", args, webapify.ScrapeOptions{Extract: webapify.ExtractionReadability}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).To(HaveKeyWithValue("htmlTransformer", "readableText"))
+
+			results, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{Extract: webapify.ExtractionRawHTML}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).To(HaveKeyWithValue("htmlTransformer", "none"))
+			Expect(fields).To(HaveKeyWithValue("removeElementsCssSelector", ""))
+			Expect(fields).To(HaveKeyWithValue("saveHtml", true))
+			Expect(results).To(HaveLen(1))
+			Expect(results[0].HTML).To(Equal("<h1>Hello</h1>"))
+			Expect(results[0].Markdown).To(Equal("# Hello"))
+		})
+
+		It("should configure the politeness of the crawler", func() {
+			args := teeargs.WebArguments{URL: "https://example.com", MaxDepth: 1, MaxPages: 10}
+
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				fields = nil
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				return &client.DatasetResponse{}, client.EmptyCursor, nil
+			}
+
+			_, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).To(HaveKeyWithValue("respectRobotsTxtFile", false))
+			Expect(fields).NotTo(HaveKey("maxRequestsPerMinute"))
+
+			_, _, _, err = webClient.Scrape("test-worker", args, webapify.ScrapeOptions{RespectRobotsTxt: true, MaxRequestsPerMinute: 30}, client.EmptyCursor)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).To(HaveKeyWithValue("respectRobotsTxtFile", true))
+			Expect(fields["maxRequestsPerMinute"]).To(BeEquivalentTo(30))
+		})
+
 		It("should handle errors from the apify client", func() {
 			expectedErr := errors.New("apify error")
 			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
@@ -93,7 +180,7 @@ var _ = Describe("WebApifyClient", func() {
 				MaxDepth: 0,
 				MaxPages: 1,
 			}
-			_, _, _, err := webClient.Scrape("test-worker", args, client.EmptyCursor)
+			_, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
 			Expect(err).To(MatchError(expectedErr))
 		})
 
@@ -113,7 +200,7 @@ var _ = Describe("WebApifyClient", func() {
 				MaxDepth: 0,
 				MaxPages: 1,
 			}
-			results, _, _, err := webClient.Scrape("test-worker", args, client.EmptyCursor)
+			results, _, _, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(results).To(BeEmpty()) // The invalid item should be skipped
 		})
@@ -138,7 +225,7 @@ var _ = Describe("WebApifyClient", func() {
 				MaxDepth: 0,
 				MaxPages: 1,
 			}
-			results, _, cursor, err := webClient.Scrape("test-worker", args, client.EmptyCursor)
+			results, _, cursor, err := webClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(cursor).To(Equal(client.Cursor("next")))
 			Expect(results).To(HaveLen(1))
@@ -147,6 +234,118 @@ var _ = Describe("WebApifyClient", func() {
 		})
 	})
 
+	Describe("ScrapePages", func() {
+		It("should scrape only the given pages, one at a time", func() {
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				Expect(actorID).To(Equal(apify.ActorIds.WebScraper))
+				Expect(limit).To(Equal(uint(2)))
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				return &client.DatasetResponse{DatasetId: "dataset-1", Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, "next", nil
+			}
+
+			_, datasetId, err := webClient.ScrapePages("test-worker", []string{"https://example.com/a", "https://example.com/b"}, webapify.ScrapeOptions{RenderJS: true})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(datasetId).To(Equal("dataset-1"))
+			Expect(fields["startUrls"]).To(Equal([]any{
+				map[string]any{"url": "https://example.com/a", "method": "GET"},
+				map[string]any{"url": "https://example.com/b", "method": "GET"},
+			}))
+			Expect(fields["maxCrawlDepth"]).To(BeEquivalentTo(0))
+			Expect(fields["maxCrawlPages"]).To(BeEquivalentTo(2))
+			Expect(fields["maxConcurrency"]).To(BeEquivalentTo(1))
+			Expect(fields["respectRobotsTxtFile"]).To(BeTrue())
+			Expect(fields["crawlerType"]).To(Equal(string(webapify.CrawlerTypeBrowser)))
+		})
+	})
+
+	Describe("ScrapeURLs", func() {
+		It("should scrape the given pages in a single run with the given concurrency", func() {
+			runs := 0
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				runs++
+				Expect(limit).To(Equal(uint(3)))
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				items := []json.RawMessage{json.RawMessage(`{"url":"https://example.com/a"}`), json.RawMessage(`{"url":"https://example.com/c"}`)}
+				return &client.DatasetResponse{DatasetId: "dataset-1", Data: client.ApifyDatasetData{Items: items}}, client.EmptyCursor, nil
+			}
+
+			pages, _, err := webClient.ScrapeURLs("test-worker", []string{"https://example.com/a", "https://example.com/b", "https://example.com/c"}, webapify.ScrapeOptions{MaxConcurrency: 4})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(runs).To(Equal(1))
+			Expect(pages).To(HaveLen(2))
+			Expect(fields["startUrls"]).To(HaveLen(3))
+			Expect(fields["maxCrawlDepth"]).To(BeEquivalentTo(0))
+			Expect(fields["maxConcurrency"]).To(BeEquivalentTo(4))
+			Expect(fields["respectRobotsTxtFile"]).To(BeFalse())
+		})
+
+		It("should request the pages with the headers of the profile", func() {
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				return &client.DatasetResponse{DatasetId: "dataset-1", Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, client.EmptyCursor, nil
+			}
+
+			profile := &fingerprint.Profile{UserAgent: "test-agent", AcceptLanguage: "de-DE"}
+			_, _, err := webClient.ScrapeURLs("test-worker", []string{"https://example.com/a"}, webapify.ScrapeOptions{Profile: profile})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields["startUrls"]).To(Equal([]any{
+				map[string]any{
+					"url":     "https://example.com/a",
+					"method":  "GET",
+					"headers": map[string]any{"User-Agent": "test-agent", "Accept-Language": "de-DE"},
+				},
+			}))
+		})
+	})
+
+	Describe("Screenshot", func() {
+		It("should run the screenshot actor with the options", func() {
+			var fields map[string]any
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				Expect(actorID).To(Equal(apify.ActorIds.WebScreenshot))
+				Expect(limit).To(Equal(uint(1)))
+				dat, err := json.Marshal(input)
+				Expect(err).NotTo(HaveOccurred())
+				fields = nil
+				Expect(json.Unmarshal(dat, &fields)).To(Succeed())
+				items := []json.RawMessage{json.RawMessage(`{"url":"https://example.com","screenshotUrl":"https://api.apify.com/v2/key-value-stores/kv/records/shot"}`)}
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: items}}, client.EmptyCursor, nil
+			}
+
+			screenshot, err := webClient.Screenshot("test-worker", "https://example.com", webapify.ScreenshotOptions{FullPage: true, Format: webapify.ScreenshotJPEG, Quality: 70, ViewportWidth: 1024})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(screenshot.ScreenshotURL).To(Equal("https://api.apify.com/v2/key-value-stores/kv/records/shot"))
+			Expect(fields).To(HaveKeyWithValue("format", "jpeg"))
+			Expect(fields).To(HaveKeyWithValue("quality", BeNumerically("==", 70)))
+			Expect(fields).To(HaveKeyWithValue("viewportWidth", BeNumerically("==", 1024)))
+			Expect(fields).To(HaveKeyWithValue("scrollToBottom", true))
+
+			// The quality only applies to JPEG screenshots
+			_, err = webClient.Screenshot("test-worker", "https://example.com", webapify.ScreenshotOptions{Quality: 70})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fields).To(HaveKeyWithValue("format", "png"))
+			Expect(fields).NotTo(HaveKey("quality"))
+		})
+
+		It("should fail if the run returned no screenshot", func() {
+			mockClient.RunActorAndGetResponseFunc = func(actorID apify.ActorId, input any, cursor client.Cursor, limit uint) (*client.DatasetResponse, client.Cursor, error) {
+				return &client.DatasetResponse{Data: client.ApifyDatasetData{Items: []json.RawMessage{}}}, client.EmptyCursor, nil
+			}
+
+			_, err := webClient.Screenshot("test-worker", "https://example.com", webapify.ScreenshotOptions{})
+			Expect(err).To(MatchError(webapify.ErrNoScreenshot))
+		})
+	})
+
 	Describe("ValidateApiKey", func() {
 		It("should validate the API key", func() {
 			mockClient.ValidateApiKeyFunc = func() error {
@@ -172,7 +371,7 @@ var _ = Describe("WebApifyClient", func() {
 			}
 
 			// Reset to use real client
-			webapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+			webapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 				return client.NewApifyClient(apiKey)
 			}
 
@@ -187,7 +386,7 @@ var _ = Describe("WebApifyClient", func() {
 			}
 
 			// Reset to use real client
-			webapify.NewInternalClient = func(apiKey string) (client.Apify, error) {
+			webapify.NewInternalClient = func(apiKey string, _ ...client.Option) (client.Apify, error) {
 				return client.NewApifyClient(apiKey)
 			}
 
@@ -200,7 +399,7 @@ var _ = Describe("WebApifyClient", func() {
 				MaxPages: 1,
 			}
 
-			results, datasetId, cursor, err := realClient.Scrape("test-worker", args, client.EmptyCursor)
+			results, datasetId, cursor, err := realClient.Scrape("test-worker", args, webapify.ScrapeOptions{}, client.EmptyCursor)
 			Expect(err).NotTo(HaveOccurred())
 			Expect(datasetId).NotTo(BeEmpty())
 			Expect(results).NotTo(BeEmpty())
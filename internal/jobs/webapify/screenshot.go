@@ -0,0 +1,102 @@
+package webapify
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/masa-finance/tee-worker/internal/apify"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/client"
+)
+
+// ScreenshotFormat is the image format of a screenshot
+type ScreenshotFormat string
+
+const (
+	// ScreenshotPNG is a lossless PNG image. This is the default.
+	ScreenshotPNG ScreenshotFormat = "png"
+	// ScreenshotJPEG is a JPEG image, which is smaller than a PNG and whose quality can be chosen
+	ScreenshotJPEG ScreenshotFormat = "jpeg"
+)
+
+// AllScreenshotFormats are the supported screenshot formats
+var AllScreenshotFormats = []ScreenshotFormat{ScreenshotPNG, ScreenshotJPEG}
+
+// ErrNoScreenshot is returned when the actor run finished without a screenshot of the page
+var ErrNoScreenshot = errors.New("no screenshot was taken")
+
+// ScreenshotOptions control how a page is captured
+type ScreenshotOptions struct {
+	// FullPage captures the whole page, scrolling to its bottom, instead of the viewport only
+	FullPage bool
+	Format   ScreenshotFormat
+	// Quality is the quality of JPEG screenshots from 1 to 100, 0 leaves it to the actor
+	Quality       int
+	ViewportWidth int
+}
+
+// Screenshot is the screenshot of a page, stored by the actor in its key-value store
+type Screenshot struct {
+	URL           string `json:"url"`
+	ScreenshotURL string `json:"screenshotUrl"`
+}
+
+// screenshotInput is the input of the screenshot actor
+type screenshotInput struct {
+	URLs           []screenshotURL  `json:"urls"`
+	Format         ScreenshotFormat `json:"format"`
+	Quality        int              `json:"quality,omitempty"`
+	ViewportWidth  int              `json:"viewportWidth,omitempty"`
+	ScrollToBottom bool             `json:"scrollToBottom"`
+	WaitUntil      string           `json:"waitUntil"`
+}
+
+type screenshotURL struct {
+	URL string `json:"url"`
+}
+
+// Screenshot captures a page in a headless browser. The image itself is not downloaded, it is linked from the returned
+// Screenshot.
+func (c *ApifyClient) Screenshot(workerID string, url string, opts ScreenshotOptions) (*Screenshot, error) {
+	input := screenshotInput{
+		URLs:           []screenshotURL{{URL: url}},
+		Format:         opts.Format,
+		ViewportWidth:  opts.ViewportWidth,
+		ScrollToBottom: opts.FullPage,
+		WaitUntil:      "networkidle2",
+	}
+	if input.Format == "" {
+		input.Format = ScreenshotPNG
+	}
+	if input.Format == ScreenshotJPEG {
+		input.Quality = opts.Quality
+	}
+
+	if c.statsCollector != nil {
+		c.statsCollector.Add(workerID, stats.WebScreenshots, 1)
+	}
+
+	dataset, _, err := c.client.RunActorAndGetResponse(apify.ActorIds.WebScreenshot, input, client.EmptyCursor, 1)
+	if err != nil {
+		if c.statsCollector != nil {
+			c.statsCollector.Add(workerID, stats.WebErrors, 1)
+		}
+		return nil, err
+	}
+
+	for i, item := range dataset.Data.Items {
+		var s Screenshot
+		if err := json.Unmarshal(item, &s); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal screenshot result at index %d: %w", i, err)
+		}
+		if s.ScreenshotURL != "" {
+			return &s, nil
+		}
+	}
+
+	if c.statsCollector != nil {
+		c.statsCollector.Add(workerID, stats.WebErrors, 1)
+	}
+	return nil, ErrNoScreenshot
+}
@@ -0,0 +1,47 @@
+package jobserver
+
+import (
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// admissionAware is implemented by workers which can tell that a job is bound to fail, e.g. because all the Twitter
+// accounts it could use are rate limited or the Apify quota is exhausted. Such jobs are rejected with a
+// types.AdmissionError instead of being queued.
+type admissionAware interface {
+	Admit(j types.Job) error
+}
+
+// admit returns an error if the capability of the job is withdrawn by its circuit breakers, if the queue is too busy for
+// the job, or if the worker for the job type rejects the job. Economy jobs are admitted by their worker, since they are
+// held back while it is rate limited anyway. High priority jobs jump the queue, so they are admitted however busy it is.
+func (js *JobServer) admit(j types.Job, executionClass ExecutionClass) error {
+	if err := js.admitCapability(j, time.Now()); err != nil {
+		logrus.Infof("Not admitting %s job: %s", j.Type, err)
+		return err
+	}
+	if executionClass == ExecutionClassEconomy {
+		return nil
+	}
+	if js.priorities.priority(j) != PriorityHigh {
+		if err := js.admitQueued(j); err != nil {
+			logrus.Infof("Not admitting %s job: %s", j.Type, err)
+			return err
+		}
+	}
+	entry, ok := js.workerEntries()[j.Type]
+	if !ok {
+		return nil
+	}
+	aw, ok := entry.w.(admissionAware)
+	if !ok {
+		return nil
+	}
+	if err := aw.Admit(j); err != nil {
+		logrus.Infof("Not admitting %s job: %s", j.Type, err)
+		return err
+	}
+	return nil
+}
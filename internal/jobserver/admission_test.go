@@ -0,0 +1,64 @@
+package jobserver
+
+import (
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type admissionWorker struct {
+	flakyWorker
+	rejected bool
+}
+
+func (a *admissionWorker) Admit(j types.Job) error {
+	if a.rejected {
+		return &types.AdmissionError{Reason: "rate limited", RetryAfter: 90 * time.Second}
+	}
+	return nil
+}
+
+var _ = Describe("Admission control", func() {
+	var (
+		js *JobServer
+		w  *admissionWorker
+	)
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		w = &admissionWorker{rejected: true}
+		js = NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.TwitterJob] = &jobWorkerEntry{w: w}
+	})
+
+	It("rejects interactive jobs which the worker does not admit, with a retry-after hint", func() {
+		job := types.Job{Type: teetypes.TwitterJob, Nonce: "nonce", Arguments: map[string]any{"type": "searchbyquery"}}
+		_, err := js.AddJob(job)
+		var admissionErr *types.AdmissionError
+		Expect(err).To(BeAssignableToTypeOf(admissionErr))
+		admissionErr = err.(*types.AdmissionError)
+		Expect(admissionErr.RetryAfterSeconds()).To(Equal(90))
+		Expect(js.GetQueueStatus().Pending).To(BeZero())
+
+		By("submitting the same job again once it is admitted")
+		w.rejected = false
+		_, err = js.AddJob(job)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("admits economy jobs, which wait for the worker anyway", func() {
+		_, err := js.AddJob(types.Job{Type: teetypes.TwitterJob, Arguments: map[string]any{"execution_class": "economy"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(js.GetQueueStatus().EconomyQueued).To(Equal(1))
+	})
+
+	It("admits jobs of workers which do not control admission", func() {
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: &flakyWorker{}}
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
@@ -0,0 +1,48 @@
+package jobserver
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// describingWorker accepts a fixed set of arguments
+type describingWorker struct {
+	flakyWorker
+	keys []string
+}
+
+func (d *describingWorker) ArgumentKeys() []string {
+	return d.keys
+}
+
+var _ = Describe("Argument keys", func() {
+	It("adds the common arguments to the arguments of the worker", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: &describingWorker{keys: []string{"url", "cache"}}}
+
+		keys, ok := js.ArgumentKeys(teetypes.WebJob)
+		Expect(ok).To(BeTrue())
+		Expect(keys).To(Equal([]string{"cache", "debug", "execution_class", "fields", "include_nsfw", "max_bandwidth_bytes", "max_result_bytes", "max_result_items", "paginated", "post_process", "priority", "provenance", "redact", "retain", "sample", "schedule", "url"}))
+	})
+
+	It("does not know the arguments of unknown or undescribed job types", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: &flakyWorker{}}
+
+		_, ok := js.ArgumentKeys(teetypes.WebJob)
+		Expect(ok).To(BeFalse())
+		_, ok = js.ArgumentKeys("not-a-job-type")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("knows the arguments of all real job types", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		for jobType := range js.jobWorkers {
+			keys, ok := js.ArgumentKeys(jobType)
+			Expect(ok).To(BeTrue(), string(jobType))
+			Expect(keys).To(ContainElement("type"), string(jobType))
+		}
+	})
+})
@@ -0,0 +1,396 @@
+package jobserver
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	auditLogFileName  = "audit.log"
+	auditHeadFileName = "audit.head"
+	auditLogPurpose   = "audit-log"
+	auditHeadPurpose  = "audit-log-head"
+	// maxAuditLineBytes is the longest line of the audit log which is read, far more than a sealed entry takes
+	maxAuditLineBytes = 1 << 20
+	// auditIndexInterval is the number of entries between two positions of the index of the log
+	auditIndexInterval = 256
+)
+
+// errAuditSealingUnavailable is returned when writing to or reading from the audit log before the sealing key has
+// been set
+var errAuditSealingUnavailable = errors.New("sealing key not available")
+
+// auditPosition is the position of an entry in the log file, with the hash of the entry before it, so the log can be
+// read and verified from this entry rather than from the first one
+type auditPosition struct {
+	Seq      uint64 `json:"seq"`
+	Offset   int64  `json:"offset"`
+	Line     int    `json:"line"`
+	PrevHash string `json:"prev_hash"`
+}
+
+// auditHead is the position and the hash of the head of the log, sealed in audit.head after every entry is written
+type auditHead struct {
+	auditPosition
+	Hash string `json:"hash"`
+}
+
+// auditLog is the append-only log of the jobs accepted and finished by the worker, for the resolution of disputes
+// about whether a job was served. Every line of the file is a types.AuditEntry sealed with the key ring, so entries
+// can't be read or forged outside the enclave, and every entry is chained to the hash of the one before, so entries
+// can't be removed or reordered either. The position of the head is sealed in a separate file after every entry, so
+// the head is loaded without reading the whole log and a log cut short is detected after a restart; removing or
+// rolling back both files together can't be detected without a monotonic counter, which the enclave doesn't have.
+// The positions of every auditIndexInterval entries are kept in memory once the chain up to them has been verified,
+// so ranges are read from the closest one. The head of the log is loaded lazily, since the sealing key may not be
+// available when the worker starts. A nil auditLog records nothing.
+type auditLog struct {
+	sync.Mutex
+	path     string
+	headPath string
+	loaded   bool
+	head     types.AuditEntry
+	// position is where the next entry is written
+	position auditPosition
+	// index holds verified positions, ordered by Seq
+	index []auditPosition
+	// broken is the break of the chain found when the log was loaded, reported with every range
+	broken error
+}
+
+func newAuditLog(dataDir string, enabled bool) *auditLog {
+	if !enabled || dataDir == "" {
+		return nil
+	}
+	return &auditLog{path: filepath.Join(dataDir, auditLogFileName), headPath: filepath.Join(dataDir, auditHeadFileName)}
+}
+
+// load reads the head of the log, unless it has already been loaded. The head entry is read at the position sealed
+// in the head file, followed by the entries written after it, if any. The whole log is only read if the head file
+// or the head entry is missing, which is a break of the chain unless the log is empty. It must be called with the
+// lock held.
+func (l *auditLog) load() error {
+	if l.loaded {
+		return nil
+	}
+	if !tee.SealingAvailable() {
+		return errAuditSealingUnavailable
+	}
+
+	start, broken := l.readHead()
+	last, end, scanBroken, err := l.scan(start, func(types.AuditEntry) bool { return true })
+	if err != nil {
+		return err
+	}
+	if broken == nil {
+		broken = scanBroken
+	}
+	if broken != nil {
+		// New entries are chained to the last entry which could be read, so the break stays visible
+		logrus.Errorf("The audit log %s is broken: %v", l.path, broken)
+	}
+	l.head, l.position, l.broken = last, end, broken
+	l.loaded = true
+	return nil
+}
+
+// readHead returns the position of the head entry sealed in the head file, once the entry has been found there, or
+// the start of the log and the reason why it hasn't been found
+func (l *auditLog) readHead() (auditPosition, error) {
+	data, legacy, err := tee.ReadSecretFile(l.headPath, auditHeadPurpose)
+	if errors.Is(err, os.ErrNotExist) {
+		if fi, err := os.Stat(l.path); err == nil && fi.Size() > 0 {
+			return auditPosition{Seq: 1, Line: 1}, errors.New("the head of the log is missing")
+		}
+		return auditPosition{Seq: 1, Line: 1}, nil
+	}
+	var head auditHead
+	if err == nil && legacy {
+		err = errors.New("not sealed by the worker")
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &head)
+	}
+	if err != nil {
+		return auditPosition{Seq: 1, Line: 1}, fmt.Errorf("the head of the log can't be read: %w", err)
+	}
+
+	e, err := l.readAt(head.Offset)
+	if err == nil && (e.Seq != head.Seq || e.Hash != head.Hash) {
+		err = errors.New("another entry is in its place")
+	}
+	if err == nil {
+		err = types.VerifyAuditChain(head.PrevHash, []types.AuditEntry{e})
+	}
+	if err != nil {
+		return auditPosition{Seq: 1, Line: 1}, fmt.Errorf("entry %d, the head of the log, can't be found: %w", head.Seq, err)
+	}
+	l.addIndex(head.auditPosition)
+	return head.auditPosition, nil
+}
+
+// readAt reads the entry of the line at the given offset of the log
+func (l *auditLog) readAt(offset int64) (types.AuditEntry, error) {
+	var e types.AuditEntry
+	f, err := os.Open(l.path)
+	if err != nil {
+		return e, err
+	}
+	defer f.Close()
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return e, err
+	}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineBytes)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return e, err
+		}
+		return e, io.ErrUnexpectedEOF
+	}
+	data, err := tee.UnsealSecret(auditLogPurpose, bytes.TrimSpace(scanner.Bytes()))
+	if err != nil {
+		return e, err
+	}
+	return e, json.Unmarshal(data, &e)
+}
+
+// addIndex adds a verified position to the index, unless it is already there
+func (l *auditLog) addIndex(p auditPosition) {
+	i := sort.Search(len(l.index), func(i int) bool { return l.index[i].Seq >= p.Seq })
+	if i < len(l.index) && l.index[i].Seq == p.Seq {
+		return
+	}
+	l.index = append(l.index, auditPosition{})
+	copy(l.index[i+1:], l.index[i:])
+	l.index[i] = p
+}
+
+// indexed returns the last verified position at or before the given entry, or the start of the log
+func (l *auditLog) indexed(seq uint64) auditPosition {
+	i := sort.Search(len(l.index), func(i int) bool { return l.index[i].Seq > seq })
+	if i == 0 {
+		return auditPosition{Seq: 1, Line: 1}
+	}
+	return l.index[i-1]
+}
+
+// scan reads the entries of the log in order from the given position and calls fn with each of them, until it
+// returns false. It returns the last entry it read, the position after it, and the first break of the chain it
+// found. Lines which can't be unsealed or parsed are breaks of the chain and are skipped. The positions of the
+// entries read before the first break are added to the index. It must be called with the lock held.
+func (l *auditLog) scan(start auditPosition, fn func(types.AuditEntry) bool) (last types.AuditEntry, end auditPosition, broken error, err error) {
+	last = types.AuditEntry{Seq: start.Seq - 1, Hash: start.PrevHash}
+	end = start
+	f, err := os.Open(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return last, end, nil, nil
+	}
+	if err != nil {
+		return last, end, nil, fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Seek(start.Offset, io.SeekStart); err != nil {
+		return last, end, nil, fmt.Errorf("error reading audit log: %w", err)
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxAuditLineBytes)
+	for scanner.Scan() {
+		line, offset := end.Line, end.Offset
+		end.Line++
+		end.Offset += int64(len(scanner.Bytes())) + 1
+
+		var e types.AuditEntry
+		data, err := tee.UnsealSecret(auditLogPurpose, bytes.TrimSpace(scanner.Bytes()))
+		if err == nil {
+			err = json.Unmarshal(data, &e)
+		}
+		if err != nil {
+			if broken == nil {
+				broken = fmt.Errorf("line %d can't be read: %w", line, err)
+			}
+			continue
+		}
+		if broken == nil {
+			if e.Seq != last.Seq+1 {
+				broken = fmt.Errorf("entry %d follows entry %d", e.Seq, last.Seq)
+			} else if err := types.VerifyAuditChain(last.Hash, []types.AuditEntry{e}); err != nil {
+				broken = err
+			} else if e.Seq%auditIndexInterval == 1 {
+				l.addIndex(auditPosition{Seq: e.Seq, Offset: offset, Line: line, PrevHash: e.PrevHash})
+			}
+		}
+		last = e
+		end.Seq, end.PrevHash = e.Seq+1, e.Hash
+		if !fn(e) {
+			break
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return last, end, broken, fmt.Errorf("error reading audit log: %w", err)
+	}
+	return last, end, broken, nil
+}
+
+// append numbers the entry, chains it to the head of the log, writes it and seals its position in the head file
+func (l *auditLog) append(e types.AuditEntry) error {
+	l.Lock()
+	defer l.Unlock()
+	if err := l.load(); err != nil {
+		return err
+	}
+
+	e.Seq = l.head.Seq + 1
+	e.PrevHash = l.head.Hash
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	e.Hash = e.ComputeHash()
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("error marshalling audit entry: %w", err)
+	}
+	sealed, err := tee.SealSecret(auditLogPurpose, data)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	defer f.Close()
+	// The entry is written at the end of the file, even if a line was cut short before it
+	fi, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("error opening audit log: %w", err)
+	}
+	if _, err := f.Write(append(sealed, '\n')); err != nil {
+		return fmt.Errorf("error writing audit log: %w", err)
+	}
+
+	pos := auditPosition{Seq: e.Seq, Offset: fi.Size(), Line: l.position.Line, PrevHash: e.PrevHash}
+	l.head = e
+	l.position = auditPosition{Seq: e.Seq + 1, Offset: pos.Offset + int64(len(sealed)) + 1, Line: pos.Line + 1, PrevHash: e.Hash}
+	if l.broken == nil && e.Seq%auditIndexInterval == 1 {
+		l.addIndex(pos)
+	}
+
+	head, err := json.Marshal(auditHead{auditPosition: pos, Hash: e.Hash})
+	if err != nil {
+		return fmt.Errorf("error marshalling audit log head: %w", err)
+	}
+	if err := tee.WriteSecretFile(l.headPath, auditHeadPurpose, head); err != nil {
+		return fmt.Errorf("error writing audit log head: %w", err)
+	}
+	return nil
+}
+
+// record appends an entry to the log, logging the error if it can't be written
+func (l *auditLog) record(e types.AuditEntry) {
+	if l == nil {
+		return
+	}
+	if err := l.append(e); err != nil {
+		logrus.Errorf("Failed to record %s event of job %s in the audit log: %v", e.Event, e.JobUUID, err)
+	}
+}
+
+// entries returns the entries from seq from to seq to, both included, of the given requester, or of all requesters if
+// it is empty. A to of 0 is the head of the log, and at most types.MaxAuditEntries entries are returned. The log is
+// read from the closest indexed position before the range, and the chain is verified from there up to the last entry
+// of the range, or to the end of the file if the range ends at the head, so lines appended after the head are
+// reported too. A break found when the log was loaded is reported with every range.
+func (l *auditLog) entries(from, to uint64, requester string) (types.AuditLog, error) {
+	l.Lock()
+	defer l.Unlock()
+	if err := l.load(); err != nil {
+		return types.AuditLog{}, err
+	}
+
+	from = max(from, 1)
+	if to == 0 || to > l.head.Seq {
+		to = l.head.Seq
+	}
+	to = min(to, from+types.MaxAuditEntries-1)
+
+	res := types.AuditLog{Entries: []types.AuditEntry{}, Head: l.head.Seq}
+	if from > to {
+		res.Verified = true
+		return res, nil
+	}
+	toHead := to == l.head.Seq
+	last, _, broken, err := l.scan(l.indexed(from), func(e types.AuditEntry) bool {
+		if e.Seq >= from && e.Seq <= to && (requester == "" || e.Requester == requester) {
+			res.Entries = append(res.Entries, e)
+		}
+		return toHead || e.Seq < to
+	})
+	if err != nil {
+		return types.AuditLog{}, err
+	}
+	if broken == nil && last.Seq < to {
+		broken = fmt.Errorf("the log ends at entry %d, before entry %d", last.Seq, to)
+	}
+	if broken == nil {
+		broken = l.broken
+	}
+	res.Last, res.LastHash = last.Seq, last.Hash
+	res.Verified = broken == nil
+	if broken != nil {
+		res.Error = broken.Error()
+	}
+	return res, nil
+}
+
+// auditEntry returns the entry of an event of a job
+func auditEntry(event types.AuditEvent, j types.Job) types.AuditEntry {
+	return types.AuditEntry{
+		Event:      event,
+		JobUUID:    j.UUID,
+		JobType:    j.Type,
+		Capability: jobCapability(j),
+		Requester:  j.WorkerID,
+	}
+}
+
+// finishedAuditEntry returns the entry of a job which finished with the given result
+func finishedAuditEntry(j types.Job, result types.JobResult) types.AuditEntry {
+	e := auditEntry(types.AuditJobFinished, j)
+	e.Error = result.Error
+	e.Cached = result.Cached
+	if result.Provenance != nil {
+		e.StartedAt, e.FinishedAt = &result.Provenance.StartedAt, &result.Provenance.FinishedAt
+	}
+	if result.Error == "" {
+		sum := sha256.Sum256(result.Data)
+		e.ResultHash = hex.EncodeToString(sum[:])
+	}
+	return e
+}
+
+// AuditLog returns a range of the audit log, see auditLog.entries. It returns types.ErrNotConfigured if the audit log
+// is disabled.
+func (js *JobServer) AuditLog(from, to uint64, requester string) (types.AuditLog, error) {
+	if js.audit == nil {
+		return types.AuditLog{}, types.ErrNotConfigured
+	}
+	return js.audit.entries(from, to, requester)
+}
@@ -0,0 +1,190 @@
+package jobserver
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Audit log", func() {
+	var (
+		js      *JobServer
+		dataDir string
+	)
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		config.MinersWhiteList = ""
+		dataDir = GinkgoT().TempDir()
+		js = NewJobServer(1, config.JobConfiguration{"data_dir": dataDir, "audit_log_enabled": true})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: cursorWorker{}}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+	})
+
+	run := func(requester, nonce string) string {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: requester, Nonce: nonce, Arguments: types.JobArguments{"type": "scraper"}})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		return uuid
+	}
+
+	It("records the acceptance and the result of every job in a verified chain", func() {
+		uuid := run("miner-1", "1")
+		run("miner-2", "2")
+
+		log, err := js.AuditLog(0, 0, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeTrue())
+		Expect(log.Head).To(BeEquivalentTo(4))
+		Expect(log.Entries).To(HaveLen(4))
+		Expect(types.VerifyAuditChain("", log.Entries)).To(Succeed())
+		Expect(log.LastHash).To(Equal(log.Entries[3].Hash))
+
+		Expect(log.Entries[0].Event).To(Equal(types.AuditJobAccepted))
+		Expect(log.Entries[0].JobUUID).To(Equal(uuid))
+		Expect(log.Entries[0].Requester).To(Equal("miner-1"))
+		Expect(log.Entries[0].Capability).To(BeEquivalentTo("scraper"))
+		Expect(log.Entries[1].Event).To(Equal(types.AuditJobFinished))
+		Expect(log.Entries[1].ResultHash).NotTo(BeEmpty())
+		Expect(log.Entries[1].FinishedAt).NotTo(BeNil())
+	})
+
+	It("returns ranges and filters them by requester", func() {
+		run("miner-1", "1")
+		run("miner-2", "2")
+
+		log, err := js.AuditLog(2, 3, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Entries).To(HaveLen(2))
+		Expect(log.Entries[0].Seq).To(BeEquivalentTo(2))
+		Expect(log.Last).To(BeEquivalentTo(3))
+
+		log, err = js.AuditLog(0, 0, "miner-2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Entries).To(HaveLen(2))
+		for _, e := range log.Entries {
+			Expect(e.Requester).To(Equal("miner-2"))
+		}
+	})
+
+	It("continues the chain after a restart", func() {
+		run("miner-1", "1")
+
+		restarted := newAuditLog(dataDir, true)
+		restarted.record(types.AuditEntry{Event: types.AuditJobAccepted, JobUUID: "after-restart"})
+		log, err := restarted.entries(0, 0, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeTrue())
+		Expect(log.Entries).To(HaveLen(3))
+		Expect(log.Entries[2].PrevHash).To(Equal(log.Entries[1].Hash))
+	})
+
+	It("detects removed entries", func() {
+		run("miner-1", "1")
+		run("miner-1", "2")
+
+		path := filepath.Join(dataDir, auditLogFileName)
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		lines := strings.SplitAfter(string(data), "\n")
+		Expect(os.WriteFile(path, []byte(lines[0]+strings.Join(lines[2:], "")), 0600)).To(Succeed())
+
+		log, err := newAuditLog(dataDir, true).entries(0, 0, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeFalse())
+		Expect(log.Error).To(ContainSubstring("entry 3 follows entry 1"))
+	})
+
+	It("detects entries which were not sealed by the worker", func() {
+		run("miner-1", "1")
+
+		f, err := os.OpenFile(filepath.Join(dataDir, auditLogFileName), os.O_APPEND|os.O_WRONLY, 0600)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = f.WriteString(`{"seq":3,"event":"finished"}` + "\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(f.Close()).To(Succeed())
+
+		log, err := newAuditLog(dataDir, true).entries(0, 0, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeFalse())
+		Expect(log.Error).To(ContainSubstring("line 3"))
+	})
+
+	It("detects a log cut short after a restart", func() {
+		run("miner-1", "1")
+		run("miner-1", "2")
+
+		path := filepath.Join(dataDir, auditLogFileName)
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		lines := strings.SplitAfter(string(data), "\n")
+		Expect(os.WriteFile(path, []byte(strings.Join(lines[:2], "")), 0600)).To(Succeed())
+
+		restarted := newAuditLog(dataDir, true)
+		log, err := restarted.entries(1, 2, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Entries).To(HaveLen(2))
+		Expect(log.Verified).To(BeFalse())
+		Expect(log.Error).To(ContainSubstring("entry 4, the head of the log, can't be found"))
+	})
+
+	It("detects a missing head", func() {
+		run("miner-1", "1")
+		Expect(os.Remove(filepath.Join(dataDir, auditHeadFileName))).To(Succeed())
+
+		log, err := newAuditLog(dataDir, true).entries(0, 0, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeFalse())
+		Expect(log.Error).To(ContainSubstring("the head of the log is missing"))
+	})
+
+	It("reads ranges from the closest indexed entry", func() {
+		l := newAuditLog(GinkgoT().TempDir(), true)
+		for i := 0; i < 2*auditIndexInterval+10; i++ {
+			l.record(types.AuditEntry{Event: types.AuditJobAccepted, JobUUID: "job"})
+		}
+		Expect(l.indexed(auditIndexInterval + 5).Seq).To(BeEquivalentTo(auditIndexInterval + 1))
+
+		restarted := newAuditLog(filepath.Dir(l.path), true)
+		Expect(restarted.load()).To(Succeed())
+		Expect(restarted.index).To(HaveLen(1), "the head is loaded without reading the log")
+		Expect(restarted.head.Seq).To(BeEquivalentTo(2*auditIndexInterval + 10))
+
+		log, err := restarted.entries(2*auditIndexInterval+2, 2*auditIndexInterval+4, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeTrue())
+		Expect(log.Entries).To(HaveLen(3))
+		Expect(types.VerifyAuditChain(log.Entries[0].PrevHash, log.Entries)).To(Succeed())
+
+		log, err = restarted.entries(auditIndexInterval+2, auditIndexInterval+2, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(log.Verified).To(BeTrue())
+		Expect(log.Entries[0].Seq).To(BeEquivalentTo(auditIndexInterval + 2))
+		Expect(restarted.indexed(2 * auditIndexInterval).Seq).To(BeEquivalentTo(auditIndexInterval + 1))
+	})
+
+	It("is disabled by default", func() {
+		disabled := NewJobServer(1, config.JobConfiguration{"data_dir": dataDir})
+		_, err := disabled.AuditLog(0, 0, "")
+		Expect(err).To(MatchError(types.ErrNotConfigured))
+	})
+})
@@ -0,0 +1,157 @@
+package jobserver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// waitingJobs counts the jobs of each type which were handed to the workers but not received by one yet, i.e. which
+// wait for a free worker
+type waitingJobs struct {
+	sync.Mutex
+	byType map[teetypes.JobType]int
+}
+
+func newWaitingJobs() *waitingJobs {
+	return &waitingJobs{byType: make(map[teetypes.JobType]int)}
+}
+
+func (w *waitingJobs) add(jobType teetypes.JobType, n int) {
+	w.Lock()
+	defer w.Unlock()
+
+	w.byType[jobType] += n
+	if w.byType[jobType] <= 0 {
+		delete(w.byType, jobType)
+	}
+}
+
+func (w *waitingJobs) counts() map[teetypes.JobType]int {
+	w.Lock()
+	defer w.Unlock()
+
+	counts := make(map[teetypes.JobType]int, len(w.byType))
+	for jobType, n := range w.byType {
+		counts[jobType] = n
+	}
+	return counts
+}
+
+// queueSnapshot is the number of jobs of each type waiting for a worker, running, and deferred for lack of a slot or of
+// memory
+type queueSnapshot struct {
+	waiting  map[teetypes.JobType]int
+	running  map[teetypes.JobType]int
+	deferred map[teetypes.JobType]int
+}
+
+func (js *JobServer) queueSnapshot() queueSnapshot {
+	running, deferred := js.slots.counts()
+	for jobType, n := range js.memory.deferredCounts() {
+		deferred[jobType] += n
+	}
+	return queueSnapshot{waiting: js.waiting.counts(), running: running, deferred: deferred}
+}
+
+// depth returns the number of jobs which wait to be executed
+func (q queueSnapshot) depth() int {
+	return sumCounts(q.waiting) + sumCounts(q.deferred)
+}
+
+func sumCounts(counts map[teetypes.JobType]int) int {
+	n := 0
+	for _, c := range counts {
+		n += c
+	}
+	return n
+}
+
+// waitEstimate returns how long a new job of the capability would wait before being executed. It is zero if a worker
+// and a slot of the job type are free. Otherwise the job waits for the jobs of its type ahead of it, which run as many
+// at a time as the job type is allowed to, each taking the median duration of the recent jobs of the capability.
+func (js *JobServer) waitEstimate(q queueSnapshot, jobType teetypes.JobType, capability string) time.Duration {
+	limit := min(js.slots.limit(jobType), js.workers)
+	ahead := q.waiting[jobType] + q.deferred[jobType]
+	workersFree := sumCounts(q.running)+sumCounts(q.waiting) < js.workers
+	if workersFree && q.running[jobType]+ahead < limit {
+		return 0
+	}
+	return time.Duration(ahead/limit+1) * js.expectedDuration(jobType, capability)
+}
+
+// expectedDuration returns the median duration of the recent jobs of the capability, or the default estimate of the
+// job type if none finished recently
+func (js *JobServer) expectedDuration(jobType teetypes.JobType, capability string) time.Duration {
+	if perf := js.stats.RecentPerformance(jobType, capability); perf != nil {
+		return time.Duration(perf.P50Ms) * time.Millisecond
+	}
+	cost, ok := callCosts[jobType]
+	if !ok {
+		cost = defaultCallCost
+	}
+	return time.Duration(cost.expectedMs(cost.results)) * time.Millisecond
+}
+
+// QueueMetrics returns the depth of the interactive queue, the estimated wait of a new job of each capability, and
+// whether the queue exceeds the thresholds of BackpressureConfig
+func (js *JobServer) QueueMetrics() types.QueueMetrics {
+	q := js.queueSnapshot()
+	metrics := types.QueueMetrics{
+		Depth:           q.depth(),
+		Running:         sumCounts(q.running),
+		Workers:         js.workers,
+		WaitEstimatesMs: make(map[teetypes.JobType]map[string]int64),
+	}
+
+	cfg := js.jobConfiguration.GetBackpressureConfig()
+	var longest, retryAfter time.Duration
+	for jobType, capabilities := range js.GetWorkerCapabilities() {
+		estimates := make(map[string]int64, len(capabilities))
+		for _, capability := range capabilities {
+			wait := js.waitEstimate(q, jobType, string(capability))
+			estimates[string(capability)] = wait.Milliseconds()
+			longest = max(longest, wait)
+			if cfg.MaxWait > 0 && wait > cfg.MaxWait {
+				metrics.Backpressure = true
+				retryAfter = max(retryAfter, wait-cfg.MaxWait)
+			}
+		}
+		metrics.WaitEstimatesMs[jobType] = estimates
+	}
+	if cfg.MaxDepth > 0 && metrics.Depth >= cfg.MaxDepth {
+		metrics.Backpressure = true
+		retryAfter = longest
+	}
+	if metrics.Backpressure {
+		metrics.RetryAfterSeconds = (&types.AdmissionError{RetryAfter: max(retryAfter, time.Second)}).RetryAfterSeconds()
+	}
+	return metrics
+}
+
+// admitQueued rejects an interactive job with a types.AdmissionError while the queue is deeper than
+// BackpressureConfig.MaxDepth, or while a job of its capability would wait longer than BackpressureConfig.MaxWait.
+// The job is asked to retry once the jobs ahead of it are expected to be executed.
+func (js *JobServer) admitQueued(j types.Job) error {
+	cfg := js.jobConfiguration.GetBackpressureConfig()
+	if cfg.MaxDepth == 0 && cfg.MaxWait == 0 {
+		return nil
+	}
+
+	q := js.queueSnapshot()
+	capability := jobCapability(j)
+	wait := js.waitEstimate(q, j.Type, string(capability))
+	if depth := q.depth(); cfg.MaxDepth > 0 && depth >= cfg.MaxDepth {
+		return &types.AdmissionError{Reason: fmt.Sprintf("%d jobs are queued", depth), RetryAfter: max(wait, time.Second)}
+	}
+	if cfg.MaxWait > 0 && wait > cfg.MaxWait {
+		return &types.AdmissionError{
+			Reason:     fmt.Sprintf("%s jobs of %s would wait for %s", capability, j.Type, wait.Round(time.Second)),
+			RetryAfter: max(wait-cfg.MaxWait, time.Second),
+		}
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// webGatedWorker is a gatedWorker which reports the default capability of web jobs
+type webGatedWorker struct {
+	gatedWorker
+}
+
+func (w *webGatedWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{teetypes.WebJob: {teetypes.JobDefaultCapabilityMap[teetypes.WebJob]}}
+}
+
+var _ = Describe("Queue backpressure", func() {
+	webCapability := string(teetypes.JobDefaultCapabilityMap[teetypes.WebJob])
+
+	// busyServer starts a job server with a blocked web job running and two more waiting for it
+	busyServer := func(workers int, jc config.JobConfiguration) (*JobServer, *webGatedWorker) {
+		config.MinersWhiteList = ""
+		js := NewJobServer(workers, jc)
+		web := &webGatedWorker{gatedWorker{release: make(chan struct{})}}
+		js.jobWorkers = map[teetypes.JobType]*jobWorkerEntry{
+			teetypes.WebJob:    {w: web},
+			teetypes.TiktokJob: {w: &orderWorker{}},
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(func() {
+			close(web.release)
+			cancel()
+		})
+		go js.Run(ctx)
+
+		for _, uuid := range []string{"w1", "w2", "w3"} {
+			Expect(js.dispatch(types.Job{UUID: uuid, Type: teetypes.WebJob}, ExecutionClassInteractive)).To(Succeed())
+		}
+		Eventually(web.running.Load, "5s").Should(BeEquivalentTo(1))
+		Eventually(func() int { return js.QueueMetrics().Depth }, "5s").Should(Equal(2))
+		return js, web
+	}
+
+	It("reports the depth of the queue and the wait of each capability", func() {
+		js, _ := busyServer(1, config.JobConfiguration{})
+
+		metrics := js.QueueMetrics()
+		Expect(metrics.Running).To(Equal(1))
+		Expect(metrics.Workers).To(Equal(1))
+		// The two web jobs ahead of a new one run one at a time, and take 3s each without recent jobs
+		Expect(metrics.WaitEstimatesMs[teetypes.WebJob][webCapability]).To(BeEquivalentTo(9000))
+		Expect(metrics.Backpressure).To(BeFalse())
+		Expect(js.admit(types.Job{Type: teetypes.WebJob}, ExecutionClassInteractive)).To(Succeed())
+
+		data, err := js.GetStats()
+		Expect(err).NotTo(HaveOccurred())
+		var stats struct {
+			Queue *types.QueueMetrics `json:"queue"`
+		}
+		Expect(json.Unmarshal(data, &stats)).To(Succeed())
+		Expect(stats.Queue).NotTo(BeNil())
+		Expect(stats.Queue.Depth).To(Equal(2))
+	})
+
+	It("has no wait while a worker and a slot are free", func() {
+		js := NewJobServer(2, config.JobConfiguration{})
+		js.jobWorkers = map[teetypes.JobType]*jobWorkerEntry{teetypes.WebJob: {w: &webGatedWorker{}}}
+
+		metrics := js.QueueMetrics()
+		Expect(metrics.Depth).To(BeZero())
+		Expect(metrics.WaitEstimatesMs[teetypes.WebJob][webCapability]).To(BeZero())
+	})
+
+	It("rejects interactive jobs once the queue reaches its depth threshold", func() {
+		js, _ := busyServer(1, config.JobConfiguration{
+			"queue_backpressure_depth": 2,
+			"priority_worker_ids":      []string{"miner1"},
+		})
+
+		metrics := js.QueueMetrics()
+		Expect(metrics.Backpressure).To(BeTrue())
+		Expect(metrics.RetryAfterSeconds).To(Equal(9))
+
+		err := js.admit(types.Job{Type: teetypes.WebJob}, ExecutionClassInteractive)
+		var admissionErr *types.AdmissionError
+		Expect(errors.As(err, &admissionErr)).To(BeTrue())
+		Expect(admissionErr.RetryAfterSeconds()).To(Equal(9))
+
+		// High priority jobs jump the queue, and economy jobs only run when the worker is idle
+		high := types.Job{Type: teetypes.WebJob, WorkerID: "miner1", Arguments: types.JobArguments{"priority": "high"}}
+		Expect(js.admit(high, ExecutionClassInteractive)).To(Succeed())
+		Expect(js.admit(types.Job{Type: teetypes.WebJob}, ExecutionClassEconomy)).To(Succeed())
+	})
+
+	It("rejects the jobs of capabilities which would wait longer than the wait threshold", func() {
+		js, _ := busyServer(2, config.JobConfiguration{"queue_backpressure_wait_seconds": 5 * time.Second})
+
+		metrics := js.QueueMetrics()
+		Expect(metrics.Backpressure).To(BeTrue())
+		Expect(metrics.RetryAfterSeconds).To(Equal(4))
+
+		err := js.admit(types.Job{Type: teetypes.WebJob}, ExecutionClassInteractive)
+		var admissionErr *types.AdmissionError
+		Expect(errors.As(err, &admissionErr)).To(BeTrue())
+		Expect(admissionErr.RetryAfter).To(Equal(4 * time.Second))
+
+		// The second worker is free for other job types
+		Expect(js.admit(types.Job{Type: teetypes.TiktokJob}, ExecutionClassInteractive)).To(Succeed())
+	})
+})
@@ -0,0 +1,146 @@
+package jobserver
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+// bandwidthArgumentKey is the job argument used by clients to lower the bandwidth cap of a job
+const bandwidthArgumentKey = "max_bandwidth_bytes"
+
+// ErrClientBandwidthExceeded is returned when a client submits a job after using up its bandwidth for the current window
+var ErrClientBandwidthExceeded = errors.New("client bandwidth cap exceeded")
+
+// bandwidthCapFromArguments returns the bandwidth cap requested by the client, or 0 if none was requested
+func bandwidthCapFromArguments(args types.JobArguments) (int64, error) {
+	v, ok := args[bandwidthArgumentKey]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	var limit float64
+	switch n := v.(type) {
+	case float64:
+		limit = n
+	case int:
+		limit = float64(n)
+	case int64:
+		limit = float64(n)
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", bandwidthArgumentKey, v)
+	}
+
+	if limit <= 0 || limit != math.Trunc(limit) {
+		return 0, fmt.Errorf("%s must be a positive integer, got %v", bandwidthArgumentKey, v)
+	}
+	return int64(limit), nil
+}
+
+type clientUsage struct {
+	windowStart time.Time
+	used        int64
+}
+
+// clientBandwidth tracks the bytes transferred by the jobs of each client (identified by its worker ID) in fixed windows.
+// Jobs which run concurrently are capped by the budget left when they start, so a client can slightly overshoot its cap.
+type clientBandwidth struct {
+	sync.Mutex
+	maxBytes int64
+	window   time.Duration
+	clients  map[string]*clientUsage
+}
+
+func newClientBandwidth(bc config.BandwidthConfig) *clientBandwidth {
+	return &clientBandwidth{maxBytes: bc.ClientMaxBytes, window: bc.ClientWindow, clients: make(map[string]*clientUsage)}
+}
+
+// remaining returns the number of bytes the client can still transfer in the current window. It returns
+// math.MaxInt64 if clients are not capped.
+func (cb *clientBandwidth) remaining(client string, now time.Time) int64 {
+	if cb.maxBytes <= 0 {
+		return math.MaxInt64
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+	u, ok := cb.clients[client]
+	if !ok || now.Sub(u.windowStart) >= cb.window {
+		return cb.maxBytes
+	}
+	return max(cb.maxBytes-u.used, 0)
+}
+
+// add records the bytes transferred by a job of the client
+func (cb *clientBandwidth) add(client string, n int64, now time.Time) {
+	if cb.maxBytes <= 0 {
+		return
+	}
+
+	cb.Lock()
+	defer cb.Unlock()
+	for c, u := range cb.clients {
+		if now.Sub(u.windowStart) >= cb.window {
+			delete(cb.clients, c)
+		}
+	}
+
+	u, ok := cb.clients[client]
+	if !ok {
+		u = &clientUsage{windowStart: now}
+		cb.clients[client] = u
+	}
+	u.used += n
+}
+
+// bandwidthCap returns the number of bytes the job can transfer, i.e. the lowest of the configured cap, the cap
+// requested in the job arguments and the bandwidth the client has left. It returns 0 if the job is not capped.
+func (js *JobServer) bandwidthCap(j types.Job, now time.Time) int64 {
+	limit := js.bandwidth.remaining(j.WorkerID, now)
+	if jobMax := js.jobConfiguration.GetBandwidthConfig().JobMaxBytes; jobMax > 0 {
+		limit = min(limit, jobMax)
+	}
+	if requested, err := bandwidthCapFromArguments(j.Arguments); err == nil && requested > 0 {
+		limit = min(limit, requested)
+	}
+	if limit == math.MaxInt64 {
+		return 0
+	}
+	// A client which used up its bandwidth while the job was queued can't transfer anything
+	return max(limit, 1)
+}
+
+// recordUsage accounts the traffic counted by the meter of an attempt of a job to its client and reports it in the
+// result. A job which exceeded its cap fails, unless it returned the results collected so far, in which case the result
+// is marked as truncated.
+func (js *JobServer) recordUsage(j types.Job, m *bandwidth.Meter, result *types.JobResult, err error) {
+	if m == nil {
+		return
+	}
+
+	js.bandwidth.add(j.WorkerID, m.Downloaded()+m.Uploaded(), time.Now())
+
+	result.Usage = &types.Usage{
+		BytesDownloaded: m.Downloaded(),
+		BytesUploaded:   m.Uploaded(),
+		BandwidthCap:    m.Limit(),
+	}
+
+	if !m.Exceeded() {
+		return
+	}
+	if err == nil && result.Error == "" {
+		result.Usage.Truncated = true
+		return
+	}
+	if result.Error == "" {
+		result.Error = err.Error()
+	}
+	result.Error = fmt.Sprintf("bandwidth cap of %d bytes exceeded: %s", m.Limit(), result.Error)
+}
@@ -0,0 +1,165 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// downloadingWorker fetches a URL with the metered client of the job. If partial is set, it succeeds even if the download fails.
+type downloadingWorker struct {
+	url     string
+	partial bool
+}
+
+func (d *downloadingWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (d *downloadingWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	resp, err := j.Network.Client(nil).Get(d.url)
+	if err != nil {
+		return types.JobResult{}, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil && !d.partial {
+		return types.JobResult{}, err
+	}
+	return types.JobResult{Data: body}, nil
+}
+
+var _ = Describe("Bandwidth", func() {
+	It("reads the bandwidth cap from the job arguments", func() {
+		limit, err := bandwidthCapFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limit).To(BeZero())
+
+		limit, err = bandwidthCapFromArguments(types.JobArguments{bandwidthArgumentKey: float64(1024)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limit).To(Equal(int64(1024)))
+
+		for _, v := range []any{"1024", float64(0), float64(-1), 1.5} {
+			_, err = bandwidthCapFromArguments(types.JobArguments{bandwidthArgumentKey: v})
+			Expect(err).To(HaveOccurred(), "%v", v)
+		}
+	})
+
+	It("tracks the bandwidth of each client in windows", func() {
+		cb := newClientBandwidth(config.BandwidthConfig{ClientMaxBytes: 1000, ClientWindow: time.Hour})
+		now := time.Now()
+
+		cb.add("a", 600, now)
+		Expect(cb.remaining("a", now)).To(Equal(int64(400)))
+		Expect(cb.remaining("b", now)).To(Equal(int64(1000)))
+
+		cb.add("a", 600, now.Add(time.Minute))
+		Expect(cb.remaining("a", now.Add(time.Minute))).To(BeZero())
+		Expect(cb.remaining("a", now.Add(time.Hour))).To(Equal(int64(1000)))
+	})
+
+	Context("when executing jobs", func() {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+			server *httptest.Server
+		)
+
+		BeforeEach(func() {
+			config.MinersWhiteList = ""
+			ctx, cancel = context.WithCancel(context.Background())
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(strings.Repeat("x", 10000)))
+			}))
+		})
+
+		AfterEach(func() {
+			cancel()
+			server.Close()
+		})
+
+		runJob := func(js *JobServer, w worker, args types.JobArguments) types.JobResult {
+			js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+
+			uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "client", Arguments: args, Nonce: time.Now().String()})
+			Expect(err).NotTo(HaveOccurred())
+
+			var res types.JobResult
+			Eventually(func() bool {
+				var ok bool
+				res, ok = js.GetJobResult(uuid)
+				return ok
+			}, 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+			return res
+		}
+
+		It("reports the usage of the job", func() {
+			js := NewJobServer(1, config.JobConfiguration{})
+			go js.Run(ctx)
+
+			res := runJob(js, &downloadingWorker{url: server.URL}, nil)
+			Expect(res.Error).To(BeEmpty())
+			Expect(res.Usage).NotTo(BeNil())
+			Expect(res.Usage.BytesDownloaded).To(BeNumerically(">", 10000))
+			Expect(res.Usage.BytesUploaded).To(BeNumerically(">", 0))
+			Expect(res.Usage.BandwidthCap).To(BeZero())
+			Expect(res.Truncated()).To(BeFalse())
+		})
+
+		It("fails jobs which exceed the cap requested in the arguments", func() {
+			js := NewJobServer(1, config.JobConfiguration{})
+			go js.Run(ctx)
+
+			res := runJob(js, &downloadingWorker{url: server.URL}, types.JobArguments{bandwidthArgumentKey: float64(5000)})
+			Expect(res.Error).To(ContainSubstring("bandwidth cap of 5000 bytes exceeded"))
+			Expect(res.Usage.BandwidthCap).To(Equal(int64(5000)))
+		})
+
+		It("marks results returned after exceeding the cap as truncated", func() {
+			js := NewJobServer(1, config.JobConfiguration{"bandwidth_job_max_bytes": 5000})
+			go js.Run(ctx)
+
+			res := runJob(js, &downloadingWorker{url: server.URL, partial: true}, nil)
+			Expect(res.Error).To(BeEmpty())
+			Expect(res.Truncated()).To(BeTrue())
+
+			dat, err := json.Marshal(res.Usage)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(dat)).To(ContainSubstring(`"truncated":true`))
+		})
+
+		It("rejects jobs of clients which used up their bandwidth", func() {
+			js := NewJobServer(1, config.JobConfiguration{
+				"bandwidth_client_max_bytes":      12000,
+				"bandwidth_client_window_seconds": time.Hour,
+			})
+			go js.Run(ctx)
+
+			res := runJob(js, &downloadingWorker{url: server.URL}, nil)
+			Expect(res.Error).To(BeEmpty())
+			Expect(res.Usage.BandwidthCap).To(Equal(int64(12000)))
+
+			// The second job only has the remaining bandwidth of the client
+			res = runJob(js, &downloadingWorker{url: server.URL}, nil)
+			Expect(res.Error).To(ContainSubstring("bandwidth cap of"))
+			Expect(res.Usage.BandwidthCap).To(BeNumerically("<", 2000))
+
+			_, err := js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "client", Nonce: "client"})
+			Expect(err).To(MatchError(ErrClientBandwidthExceeded))
+
+			_, err = js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "other", Nonce: "other"})
+			Expect(err).NotTo(HaveOccurred())
+		})
+	})
+})
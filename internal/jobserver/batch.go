@@ -0,0 +1,107 @@
+package jobserver
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// maxBatches is the number of batches whose jobs are remembered. The oldest batch is forgotten once it is exceeded,
+// but its jobs are not affected.
+const maxBatches = 1000
+
+// ErrBatchNotFound is returned for batches which are unknown, or have been forgotten
+var ErrBatchNotFound = errors.New("batch not found")
+
+// jobBatches remembers the jobs which were submitted together, so their status can be aggregated
+type jobBatches struct {
+	sync.Mutex
+	jobs  map[string][]string
+	order []string
+}
+
+func newJobBatches() *jobBatches {
+	return &jobBatches{jobs: make(map[string][]string)}
+}
+
+// add registers the jobs of a new batch and returns its ID
+func (b *jobBatches) add(jobUUIDs []string) string {
+	b.Lock()
+	defer b.Unlock()
+	if len(b.order) >= maxBatches {
+		delete(b.jobs, b.order[0])
+		b.order = b.order[1:]
+	}
+	id := uuid.New().String()
+	b.jobs[id] = jobUUIDs
+	b.order = append(b.order, id)
+	return id
+}
+
+func (b *jobBatches) get(id string) ([]string, bool) {
+	b.Lock()
+	defer b.Unlock()
+	jobUUIDs, ok := b.jobs[id]
+	return jobUUIDs, ok
+}
+
+// AddBatch groups jobs which have already been added, and returns the ID to get their aggregate status with
+func (js *JobServer) AddBatch(jobUUIDs []string) string {
+	return js.batches.add(jobUUIDs)
+}
+
+// GetBatchStatus returns the state of each job of a batch, and how many jobs are in each state. Results are not
+// consumed, so results of jobs submitted with no-store can still be read afterwards.
+func (js *JobServer) GetBatchStatus(id string) (types.BatchStatus, error) {
+	jobUUIDs, ok := js.batches.get(id)
+	if !ok {
+		return types.BatchStatus{}, ErrBatchNotFound
+	}
+
+	status := types.BatchStatus{BatchID: id, Total: len(jobUUIDs), Jobs: make([]types.BatchJobStatus, 0, len(jobUUIDs))}
+	for _, jobUUID := range jobUUIDs {
+		state := js.JobState(jobUUID)
+		switch state {
+		case types.BatchJobPending:
+			status.Pending++
+		case types.BatchJobSucceeded:
+			status.Succeeded++
+		case types.BatchJobFailed:
+			status.Failed++
+		default:
+			status.Expired++
+		}
+		status.Jobs = append(status.Jobs, types.BatchJobStatus{UID: jobUUID, State: state})
+	}
+	return status, nil
+}
+
+// JobState returns the state of a job without consuming its result. Jobs are only pending until their result has
+// been stored, so a job which is not pending anymore has a result unless it has expired.
+func (js *JobServer) JobState(jobUUID string) types.BatchJobState {
+	if js.pending.wait(jobUUID) != nil {
+		return types.BatchJobPending
+	}
+
+	res, ok := js.results.Peek(jobUUID)
+	if !ok {
+		res, ok = js.recurring.latest(jobUUID)
+	}
+	if !ok {
+		var held *heldResult
+		if held, ok = js.held.load(jobUUID); ok {
+			res = held.Result
+		}
+	}
+
+	switch {
+	case !ok:
+		return types.BatchJobExpired
+	case res.Success():
+		return types.BatchJobSucceeded
+	default:
+		return types.BatchJobFailed
+	}
+}
@@ -0,0 +1,93 @@
+package jobserver_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobserver"
+)
+
+var _ = Describe("Batches", func() {
+	var js *JobServer
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		js = NewJobServer(1, config.JobConfiguration{})
+	})
+
+	add := func(j types.Job) string {
+		uuid, err := js.AddJob(j)
+		Expect(err).NotTo(HaveOccurred())
+		return uuid
+	}
+
+	It("aggregates the status of the jobs of a batch", func() {
+		succeeding := add(types.Job{Type: teetypes.TelemetryJob, Nonce: "1"})
+		failing := add(types.Job{Type: "not-existing", Nonce: "2"})
+		id := js.AddBatch([]string{succeeding, failing})
+
+		status, err := js.GetBatchStatus(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status).To(Equal(types.BatchStatus{
+			BatchID: id,
+			Total:   2,
+			Pending: 2,
+			Jobs: []types.BatchJobStatus{
+				{UID: succeeding, State: types.BatchJobPending},
+				{UID: failing, State: types.BatchJobPending},
+			},
+		}))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+
+		Eventually(func() int {
+			status, _ := js.GetBatchStatus(id)
+			return status.Pending
+		}, "5s").Should(BeZero())
+
+		status, err = js.GetBatchStatus(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Succeeded).To(Equal(1))
+		Expect(status.Failed).To(Equal(1))
+		Expect(status.Jobs).To(Equal([]types.BatchJobStatus{
+			{UID: succeeding, State: types.BatchJobSucceeded},
+			{UID: failing, State: types.BatchJobFailed},
+		}))
+	})
+
+	It("doesn't consume results which are deleted on read", func() {
+		uuid := add(types.Job{Type: teetypes.TelemetryJob, Arguments: map[string]any{"cache": "no-store"}})
+		id := js.AddBatch([]string{uuid})
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+
+		for range 2 {
+			status, err := js.GetBatchStatus(id)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(status.Succeeded).To(Equal(1))
+		}
+
+		_, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		status, err := js.GetBatchStatus(id)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(status.Expired).To(Equal(1))
+		Expect(status.Jobs).To(Equal([]types.BatchJobStatus{{UID: uuid, State: types.BatchJobExpired}}))
+	})
+
+	It("reports unknown batches", func() {
+		_, err := js.GetBatchStatus("unknown")
+		Expect(err).To(MatchError(ErrBatchNotFound))
+	})
+})
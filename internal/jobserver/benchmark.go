@@ -0,0 +1,44 @@
+package jobserver
+
+import (
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+// benchmarks holds the latest self-benchmark of the worker. Only one benchmark runs at a time, so concurrent runs
+// don't skew each other's measurements.
+type benchmarks struct {
+	running sync.Mutex
+	lock    sync.Mutex
+	latest  *types.BenchmarkReport
+}
+
+// RunBenchmark benchmarks the worker, and publishes the report in the statistics reported by the telemetry job
+func (js *JobServer) RunBenchmark() types.BenchmarkReport {
+	js.benchmarks.running.Lock()
+	defer js.benchmarks.running.Unlock()
+
+	report := jobs.RunBenchmark(js.jobConfiguration, js.workers)
+
+	js.benchmarks.lock.Lock()
+	js.benchmarks.latest = &report
+	js.benchmarks.lock.Unlock()
+
+	if js.stats != nil {
+		js.stats.SetBenchmark(report)
+	}
+	return report
+}
+
+// LatestBenchmark returns the report of the latest benchmark, if the worker has been benchmarked
+func (js *JobServer) LatestBenchmark() (types.BenchmarkReport, bool) {
+	js.benchmarks.lock.Lock()
+	defer js.benchmarks.lock.Unlock()
+
+	if js.benchmarks.latest == nil {
+		return types.BenchmarkReport{}, false
+	}
+	return *js.benchmarks.latest, true
+}
@@ -0,0 +1,149 @@
+package jobserver
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/sirupsen/logrus"
+)
+
+// argumentsErrorMessage is the error of the jobs whose arguments are invalid, which says nothing about the capability
+const argumentsErrorMessage = "error unmarshalling job arguments"
+
+// breakerKey identifies a circuit breaker. An empty auth source stands for all the auth sources of the capability, for
+// workers which don't tell which one a job used.
+type breakerKey struct {
+	jobType    teetypes.JobType
+	capability teetypes.Capability
+	authSource types.AuthSource
+}
+
+type breaker struct {
+	failures  int
+	openUntil time.Time
+}
+
+// circuitBreakers withdraw the capabilities whose jobs keep failing, e.g. because the Twitter accounts of the worker
+// were suspended or an Apify actor is broken, so the jobs for them are rejected at once instead of running into the
+// same failure or timeout. Each capability has a circuit breaker per auth source, which opens after a number of
+// consecutive failures of its jobs. Once its cooldown is over, the next job probes the capability: a success closes
+// the circuit breaker, a failure opens it again.
+type circuitBreakers struct {
+	sync.Mutex
+	breakers map[breakerKey]*breaker
+}
+
+func newCircuitBreakers() *circuitBreakers {
+	return &circuitBreakers{breakers: make(map[breakerKey]*breaker)}
+}
+
+// record records the outcome of a job which used the capability with the auth source of the key
+func (cb *circuitBreakers) record(key breakerKey, failed bool, cfg config.CircuitBreakerConfig, now time.Time) {
+	cb.Lock()
+	defer cb.Unlock()
+
+	b, ok := cb.breakers[key]
+	if !failed {
+		if ok && b.failures >= cfg.Threshold {
+			logrus.Infof("Capability %s of %s jobs with auth source %q is available again", key.capability, key.jobType, key.authSource)
+		}
+		delete(cb.breakers, key)
+		return
+	}
+	if cfg.Threshold == 0 {
+		return
+	}
+	if !ok {
+		b = &breaker{}
+		cb.breakers[key] = b
+	}
+	b.failures++
+	if b.failures >= cfg.Threshold && !now.Before(b.openUntil) {
+		b.openUntil = now.Add(cfg.Cooldown)
+		logrus.Warnf("Capability %s of %s jobs with auth source %q failed %d times in a row, withdrawing it for %s", key.capability, key.jobType, key.authSource, b.failures, cfg.Cooldown)
+	}
+}
+
+// openFor returns how long the circuit breaker of the key remains open, or zero if it is closed
+func (cb *circuitBreakers) openFor(key breakerKey, now time.Time) time.Duration {
+	b, ok := cb.breakers[key]
+	if !ok || !now.Before(b.openUntil) {
+		return 0
+	}
+	return b.openUntil.Sub(now)
+}
+
+// unavailableFor returns how long a capability remains unavailable with an auth source, or zero if it is available
+func (cb *circuitBreakers) unavailableFor(key breakerKey, now time.Time) time.Duration {
+	cb.Lock()
+	defer cb.Unlock()
+	all := key
+	all.authSource = ""
+	return max(cb.openFor(key, now), cb.openFor(all, now))
+}
+
+// capabilityUnavailableFor returns how long a capability remains unavailable with all of its auth sources, or zero if
+// it is available with at least one of them
+func (cb *circuitBreakers) capabilityUnavailableFor(jobType teetypes.JobType, capability teetypes.Capability, sources []types.AuthSource, now time.Time) time.Duration {
+	if len(sources) == 0 {
+		sources = []types.AuthSource{""}
+	}
+	var wait time.Duration
+	for _, source := range sources {
+		d := cb.unavailableFor(breakerKey{jobType, capability, source}, now)
+		if d == 0 {
+			return 0
+		}
+		if wait == 0 || d < wait {
+			wait = d
+		}
+	}
+	return wait
+}
+
+// authSources returns the auth sources with which the worker of a job type provides a capability
+func authSources(details types.CapabilityDetails, jobType teetypes.JobType, capability teetypes.Capability) []types.AuthSource {
+	var sources []types.AuthSource
+	for _, detail := range details[jobType] {
+		if detail.Capability == capability {
+			sources = append(sources, detail.AuthSource)
+		}
+	}
+	return sources
+}
+
+// admitCapability rejects a job with a types.AdmissionError while its capability is unavailable with all of its auth
+// sources
+func (js *JobServer) admitCapability(j types.Job, now time.Time) error {
+	capability := teetypes.Capability(stats.DimensionsForJob(j).Capability)
+	sources := authSources(js.capabilityDetails(), j.Type, capability)
+	wait := js.breakers.capabilityUnavailableFor(j.Type, capability, sources, now)
+	if wait == 0 {
+		return nil
+	}
+	return &types.AdmissionError{Reason: fmt.Sprintf("capability %s of %s jobs is unavailable after consecutive failures", capability, j.Type), RetryAfter: wait}
+}
+
+// recordCapabilityOutcome feeds the outcome of an attempt of a job to the circuit breaker of its capability and auth
+// source. Jobs which were cancelled, exceeded their bandwidth cap or have invalid arguments failed for reasons of their
+// own and are not recorded, while jobs which timed out are failures.
+func (js *JobServer) recordCapabilityOutcome(j types.Job, meter *bandwidth.Meter, result types.JobResult, err error, elapsed time.Duration) {
+	if result.Provenance == nil || meter.Exceeded() || result.Error == argumentsErrorMessage {
+		return
+	}
+	timedOut := j.Timeout > 0 && elapsed >= j.Timeout
+	if js.pending.isCancelled(j.UUID) && !timedOut {
+		return
+	}
+
+	failed := err != nil || result.Error != ""
+	key := breakerKey{j.Type, result.Provenance.Capability, result.Provenance.AuthSource}
+	cfg := js.jobConfiguration.GetCircuitBreakerConfig(j.Type.String(), string(key.capability))
+	js.breakers.record(key, failed, cfg, time.Now())
+}
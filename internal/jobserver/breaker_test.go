@@ -0,0 +1,189 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// describedWorker provides the searchbyquery capability of Twitter jobs with credentials and with API keys, and
+// fails the jobs which use the auth source in fail
+type describedWorker struct {
+	fail types.AuthSource
+}
+
+func (d *describedWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{teetypes.TwitterJob: {teetypes.CapSearchByQuery}}
+}
+
+func (d *describedWorker) GetCapabilityDetails() types.CapabilityDetails {
+	return types.CapabilityDetails{teetypes.TwitterJob: {
+		{Capability: teetypes.CapSearchByQuery, AuthSource: types.AuthSourceCredential},
+		{Capability: teetypes.CapSearchByQuery, AuthSource: types.AuthSourceAPI},
+	}}
+}
+
+func (d *describedWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	source, _ := j.Arguments["source"].(string)
+	j.Provenance.SetAuthSource(types.AuthSource(source))
+	if types.AuthSource(source) == d.fail {
+		return types.JobResult{Error: "authentication failed"}, errors.New("authentication failed")
+	}
+	return types.JobResult{Data: []byte("[]")}, nil
+}
+
+var _ = Describe("Circuit breakers", func() {
+	key := breakerKey{teetypes.TwitterJob, teetypes.CapSearchByQuery, types.AuthSourceCredential}
+	cfg := config.CircuitBreakerConfig{Threshold: 3, Cooldown: time.Minute}
+	now := time.Now()
+
+	It("reads the settings per job type and capability", func() {
+		jc := config.JobConfiguration{
+			"circuit_breaker_threshold":                            5,
+			"circuit_breaker_cooldown_seconds":                     time.Minute,
+			"twitter_apify_circuit_breaker_threshold":              3,
+			"twitter_apify_getfollowers_circuit_breaker_threshold": 0,
+			"twitter_apify_getfollowers_circuit_breaker_cooldown":  10 * time.Minute,
+		}
+		Expect(jc.GetCircuitBreakerConfig("twitter-apify", "getfollowers")).To(Equal(config.CircuitBreakerConfig{Threshold: 0, Cooldown: 10 * time.Minute}))
+		Expect(jc.GetCircuitBreakerConfig("twitter-apify", "getfollowing")).To(Equal(config.CircuitBreakerConfig{Threshold: 3, Cooldown: time.Minute}))
+		Expect(jc.GetCircuitBreakerConfig("web", "scraper")).To(Equal(config.CircuitBreakerConfig{Threshold: 5, Cooldown: time.Minute}))
+		Expect(config.JobConfiguration{}.GetCircuitBreakerConfig("web", "")).To(Equal(config.CircuitBreakerConfig{Threshold: 5, Cooldown: time.Minute}))
+	})
+
+	It("opens after consecutive failures and closes after a success", func() {
+		cb := newCircuitBreakers()
+		cb.record(key, true, cfg, now)
+		cb.record(key, true, cfg, now)
+		Expect(cb.unavailableFor(key, now)).To(BeZero())
+
+		cb.record(key, true, cfg, now)
+		Expect(cb.unavailableFor(key, now)).To(Equal(time.Minute))
+		Expect(cb.unavailableFor(key, now.Add(20*time.Second))).To(Equal(40 * time.Second))
+
+		By("letting a job probe the capability once the cooldown is over")
+		later := now.Add(time.Minute)
+		Expect(cb.unavailableFor(key, later)).To(BeZero())
+		cb.record(key, true, cfg, later)
+		Expect(cb.unavailableFor(key, later)).To(Equal(time.Minute))
+
+		cb.record(key, false, cfg, later.Add(time.Minute))
+		Expect(cb.breakers).To(BeEmpty())
+	})
+
+	It("counts only consecutive failures", func() {
+		cb := newCircuitBreakers()
+		cb.record(key, true, cfg, now)
+		cb.record(key, true, cfg, now)
+		cb.record(key, false, cfg, now)
+		cb.record(key, true, cfg, now)
+		Expect(cb.unavailableFor(key, now)).To(BeZero())
+	})
+
+	It("never opens when it is disabled", func() {
+		cb := newCircuitBreakers()
+		for range 10 {
+			cb.record(key, true, config.CircuitBreakerConfig{Threshold: 0, Cooldown: time.Minute}, now)
+		}
+		Expect(cb.unavailableFor(key, now)).To(BeZero())
+	})
+
+	It("withdraws a capability once it is unavailable with all of its auth sources", func() {
+		cb := newCircuitBreakers()
+		sources := []types.AuthSource{types.AuthSourceCredential, types.AuthSourceAPI}
+		for range 3 {
+			cb.record(key, true, cfg, now)
+		}
+		Expect(cb.capabilityUnavailableFor(key.jobType, key.capability, sources, now)).To(BeZero())
+
+		apiKey := key
+		apiKey.authSource = types.AuthSourceAPI
+		for range 3 {
+			cb.record(apiKey, true, cfg, now.Add(10*time.Second))
+		}
+		Expect(cb.capabilityUnavailableFor(key.jobType, key.capability, sources, now)).To(Equal(time.Minute))
+
+		By("treating failures with an unknown auth source as failures of all of them")
+		cb = newCircuitBreakers()
+		unknown := key
+		unknown.authSource = ""
+		for range 3 {
+			cb.record(unknown, true, cfg, now)
+		}
+		Expect(cb.capabilityUnavailableFor(key.jobType, key.capability, sources, now)).To(Equal(time.Minute))
+	})
+
+	Context("in the job server", func() {
+		var (
+			js  *JobServer
+			w   *describedWorker
+			ctx context.Context
+		)
+
+		BeforeEach(func() {
+			config.MinersWhiteList = ""
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+
+			w = &describedWorker{fail: types.AuthSourceCredential}
+			js = NewJobServer(1, config.JobConfiguration{
+				"circuit_breaker_threshold":        2,
+				"circuit_breaker_cooldown_seconds": time.Minute,
+			})
+			js.jobWorkers = map[teetypes.JobType]*jobWorkerEntry{teetypes.TwitterJob: {w: w}}
+			go js.Run(ctx)
+		})
+
+		run := func(source types.AuthSource) error {
+			jobUUID, err := js.AddJob(types.Job{Type: teetypes.TwitterJob, Nonce: uuid.NewString(), Arguments: map[string]any{"type": "searchbyquery", "source": string(source)}})
+			if err != nil {
+				return err
+			}
+			Eventually(func() bool {
+				_, exists := js.GetJobResult(jobUUID)
+				return exists
+			}, "5s").Should(BeTrue())
+			return nil
+		}
+
+		It("withdraws the auth sources and capabilities whose jobs keep failing", func() {
+			Expect(run(types.AuthSourceCredential)).To(Succeed())
+			Expect(run(types.AuthSourceCredential)).To(Succeed())
+
+			Expect(js.GetCapabilityDetails()[teetypes.TwitterJob]).To(Equal([]types.CapabilityDetail{
+				{Capability: teetypes.CapSearchByQuery, AuthSource: types.AuthSourceAPI},
+			}))
+			Expect(js.GetWorkerCapabilities()[teetypes.TwitterJob]).To(ConsistOf(teetypes.CapSearchByQuery))
+			Expect(run(types.AuthSourceAPI)).To(Succeed())
+
+			By("rejecting the jobs of the capability once all of its auth sources are withdrawn")
+			w.fail = types.AuthSourceAPI
+			Expect(run(types.AuthSourceAPI)).To(Succeed())
+			Expect(run(types.AuthSourceAPI)).To(Succeed())
+			Expect(js.GetWorkerCapabilities()).NotTo(HaveKey(teetypes.TwitterJob))
+
+			err := run(types.AuthSourceAPI)
+			var admissionErr *types.AdmissionError
+			Expect(errors.As(err, &admissionErr)).To(BeTrue())
+			Expect(admissionErr.Reason).To(ContainSubstring("capability searchbyquery of twitter jobs is unavailable"))
+			Expect(admissionErr.RetryAfterSeconds()).To(BeNumerically("~", 60, 1))
+		})
+
+		It("does not count jobs with invalid arguments", func() {
+			w.fail = ""
+			js.jobWorkers[teetypes.TwitterJob] = &jobWorkerEntry{w: &flakyWorker{failures: 10, err: errors.New(argumentsErrorMessage)}}
+			for range 3 {
+				Expect(run("")).To(Succeed())
+			}
+			Expect(js.breakers.breakers).To(BeEmpty())
+		})
+	})
+})
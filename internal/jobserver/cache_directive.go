@@ -0,0 +1,163 @@
+package jobserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// cacheArgumentKey is the job argument used by clients to send cache directives
+const cacheArgumentKey = "cache"
+
+// CacheDirective controls how the result cache is used for a single job, similar to an HTTP Cache-Control request header.
+//
+//   - "no-store": always execute the job, don't reuse its result for other jobs and drop it after it has been read
+//   - "prefer-cached": reuse the result of an identical job if it is still in the cache
+//   - "max-age=N": reuse the result of an identical job only if it is at most N seconds old
+//
+// Without a directive the job is executed unless deduplication is enabled, see WithDefaultMaxAge, and its result can
+// be reused by later jobs.
+type CacheDirective struct {
+	NoStore      bool
+	PreferCached bool
+	MaxAge       time.Duration
+}
+
+// ParseCacheDirective parses a comma-separated list of cache directives
+func ParseCacheDirective(s string) (CacheDirective, error) {
+	var cd CacheDirective
+	for _, d := range strings.Split(s, ",") {
+		d = strings.ToLower(strings.TrimSpace(d))
+		switch {
+		case d == "":
+			continue
+		case d == "no-store":
+			cd.NoStore = true
+		case d == "prefer-cached":
+			cd.PreferCached = true
+		case strings.HasPrefix(d, "max-age="):
+			secs, err := strconv.Atoi(strings.TrimPrefix(d, "max-age="))
+			if err != nil || secs < 0 {
+				return CacheDirective{}, fmt.Errorf("invalid cache directive %q: max-age must be a non-negative number of seconds", d)
+			}
+			cd.MaxAge = time.Duration(secs) * time.Second
+		default:
+			return CacheDirective{}, fmt.Errorf("unknown cache directive %q", d)
+		}
+	}
+
+	if cd.NoStore && (cd.PreferCached || cd.MaxAge > 0) {
+		return CacheDirective{}, fmt.Errorf("cache directive no-store cannot be combined with prefer-cached or max-age")
+	}
+
+	return cd, nil
+}
+
+// cacheDirectiveFromArguments extracts the cache directive from the job arguments
+func cacheDirectiveFromArguments(args types.JobArguments) (CacheDirective, error) {
+	v, ok := args[cacheArgumentKey]
+	if !ok || v == nil {
+		return CacheDirective{}, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return CacheDirective{}, fmt.Errorf("%s must be a string, got %T", cacheArgumentKey, v)
+	}
+
+	return ParseCacheDirective(s)
+}
+
+// AllowsReuse returns true if a cached result of an identical job can be returned instead of executing the job
+func (cd CacheDirective) AllowsReuse() bool {
+	return cd.PreferCached || cd.MaxAge > 0
+}
+
+// WithDefaultMaxAge returns the directive of a job without a cache directive of its own, which reuses the result of an
+// identical job if it is at most maxAge old. This deduplicates jobs submitted again shortly after each other. Jobs
+// with a directive keep it, and a maxAge of 0 disables deduplication.
+func (cd CacheDirective) WithDefaultMaxAge(maxAge time.Duration) CacheDirective {
+	if cd == (CacheDirective{}) {
+		cd.MaxAge = maxAge
+	}
+	return cd
+}
+
+// fingerprintIgnoredArguments are the job arguments which don't change the result of a job, but only how it is
+// scheduled, cached or kept
+var fingerprintIgnoredArguments = []string{
+	cacheArgumentKey,
+	executionClassArgumentKey,
+	priorityArgumentKey,
+	types.DebugArgumentKey,
+	types.PaginatedArgumentKey,
+	types.RetainArgumentKey,
+}
+
+// jobFingerprint returns a key identifying jobs that would produce the same result. The arguments are normalized first,
+// so jobs which only differ in the arguments which don't change the result, in the case of the capability, in
+// whitespace or in the time zone of timestamps are identical.
+func jobFingerprint(j types.Job) (string, error) {
+	args := make(map[string]any, len(j.Arguments))
+	for k, v := range j.Arguments {
+		if !slices.Contains(fingerprintIgnoredArguments, k) {
+			args[k] = normalizeArgument(v)
+		}
+	}
+	if capability, ok := args["type"].(string); ok {
+		args["type"] = strings.ToLower(capability)
+	}
+	if args["type"] == nil || args["type"] == "" {
+		if capability, ok := teetypes.JobDefaultCapabilityMap[j.Type]; ok {
+			args["type"] = string(capability)
+		}
+	}
+
+	// json.Marshal sorts map keys, so the encoding is deterministic
+	dat, err := json.Marshal(struct {
+		Type      string         `json:"type"`
+		Arguments map[string]any `json:"arguments"`
+		Offset    int            `json:"offset,omitempty"`
+	}{Type: j.Type.String(), Arguments: args, Offset: j.ResultOffset})
+	if err != nil {
+		return "", fmt.Errorf("error computing job fingerprint: %w", err)
+	}
+
+	sum := sha256.Sum256(dat)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// normalizeArgument trims and collapses the whitespace of strings and converts RFC 3339 timestamps to UTC, also in
+// nested objects and arrays
+func normalizeArgument(v any) any {
+	switch v := v.(type) {
+	case string:
+		s := strings.Join(strings.Fields(v), " ")
+		if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+			return t.UTC().Format(time.RFC3339Nano)
+		}
+		return s
+	case map[string]any:
+		normalized := make(map[string]any, len(v))
+		for k, e := range v {
+			normalized[k] = normalizeArgument(e)
+		}
+		return normalized
+	case []any:
+		normalized := make([]any, len(v))
+		for i, e := range v {
+			normalized[i] = normalizeArgument(e)
+		}
+		return normalized
+	default:
+		return v
+	}
+}
@@ -0,0 +1,158 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CacheDirective", func() {
+	It("parses directives", func() {
+		cd, err := ParseCacheDirective("prefer-cached")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cd.PreferCached).To(BeTrue())
+		Expect(cd.AllowsReuse()).To(BeTrue())
+
+		cd, err = ParseCacheDirective("max-age=300")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cd.MaxAge).To(Equal(300 * time.Second))
+
+		cd, err = ParseCacheDirective("no-store")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cd.NoStore).To(BeTrue())
+		Expect(cd.AllowsReuse()).To(BeFalse())
+	})
+
+	It("rejects invalid directives", func() {
+		_, err := ParseCacheDirective("max-age=abc")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseCacheDirective("stale-while-revalidate")
+		Expect(err).To(HaveOccurred())
+		_, err = ParseCacheDirective("no-store, prefer-cached")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("ignores the directive when fingerprinting jobs", func() {
+		a, err := jobFingerprint(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com", "cache": "prefer-cached"}})
+		Expect(err).NotTo(HaveOccurred())
+		b, err := jobFingerprint(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		c, err := jobFingerprint(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.org"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+		Expect(a).NotTo(Equal(c))
+	})
+
+	It("applies the default max age only to jobs without a directive", func() {
+		Expect(CacheDirective{}.WithDefaultMaxAge(time.Minute)).To(Equal(CacheDirective{MaxAge: time.Minute}))
+		Expect(CacheDirective{}.WithDefaultMaxAge(0).AllowsReuse()).To(BeFalse())
+		Expect(CacheDirective{NoStore: true}.WithDefaultMaxAge(time.Minute)).To(Equal(CacheDirective{NoStore: true}))
+		Expect(CacheDirective{MaxAge: time.Second}.WithDefaultMaxAge(time.Minute)).To(Equal(CacheDirective{MaxAge: time.Second}))
+	})
+
+	It("normalizes the arguments when fingerprinting jobs", func() {
+		a, err := jobFingerprint(types.Job{Type: teetypes.TwitterJob, Arguments: map[string]any{
+			"type":       "searchbyquery",
+			"query":      "bitcoin  price",
+			"start_time": "2026-10-01T00:00:00Z",
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		b, err := jobFingerprint(types.Job{Type: teetypes.TwitterJob, Arguments: map[string]any{
+			"type":       "SearchByQuery",
+			"query":      " bitcoin price\n",
+			"start_time": "2026-10-01T02:00:00+02:00",
+			"priority":   "high",
+			"debug":      true,
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+
+		c, err := jobFingerprint(types.Job{Type: teetypes.TwitterJob, Arguments: map[string]any{
+			"type":       "searchbyquery",
+			"query":      "bitcoin price",
+			"start_time": "2026-10-02T00:00:00Z",
+		}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).NotTo(Equal(c))
+	})
+
+	It("fingerprints jobs without a capability like those of the default capability", func() {
+		a, err := jobFingerprint(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com"}})
+		Expect(err).NotTo(HaveOccurred())
+		b, err := jobFingerprint(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com", "type": string(teetypes.JobDefaultCapabilityMap[teetypes.WebJob])}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(a).To(Equal(b))
+	})
+
+	It("reuses results of identical jobs when requested", func() {
+		config.MinersWhiteList = ""
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w := &flakyWorker{}
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+
+		waitFor := func(uuid string) {
+			Eventually(func() bool {
+				_, exists := js.GetJobResult(uuid)
+				return exists
+			}, "5s").Should(BeTrue())
+		}
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com"}, Nonce: "1"})
+		Expect(err).NotTo(HaveOccurred())
+		waitFor(uuid)
+
+		uuid, err = js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com", "cache": "prefer-cached"}, Nonce: "2"})
+		Expect(err).NotTo(HaveOccurred())
+		res, exists := js.GetJobResult(uuid)
+		Expect(exists).To(BeTrue())
+		Expect(res.Job.Nonce).To(Equal("2"))
+		Expect(w.calls.Load()).To(Equal(int32(1)))
+
+		uuid, err = js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com"}, Nonce: "3"})
+		Expect(err).NotTo(HaveOccurred())
+		waitFor(uuid)
+		Expect(w.calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("deduplicates identical jobs within the TTL", func() {
+		config.MinersWhiteList = ""
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w := &flakyWorker{}
+		js := NewJobServer(1, config.JobConfiguration{"dedup_ttl_seconds": time.Minute})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+
+		first, err := js.SubmitJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com"}, Nonce: "1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(first.Cached).To(BeFalse())
+		Eventually(func() bool {
+			_, exists := js.GetJobResult(first.UID)
+			return exists
+		}, "5s").Should(BeTrue())
+
+		second, err := js.SubmitJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": " https://example.com "}, Nonce: "2"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.Cached).To(BeTrue())
+		res, exists := js.GetJobResult(second.UID)
+		Expect(exists).To(BeTrue())
+		Expect(res.Cached).To(BeTrue())
+		Expect(res.Job.Nonce).To(Equal("2"))
+		Expect(w.calls.Load()).To(Equal(int32(1)))
+
+		third, err := js.SubmitJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"url": "https://example.com", "cache": "no-store"}, Nonce: "3"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(third.Cached).To(BeFalse())
+		Eventually(func() int32 { return w.calls.Load() }, "5s").Should(Equal(int32(2)))
+	})
+})
@@ -0,0 +1,124 @@
+package jobserver
+
+import (
+	"sync"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// typeSlots limits how many jobs of each job type run at the same time. A job whose type is at its limit is deferred
+// in a queue of its type instead of holding on to a worker, so slow job types (e.g. web scrapes) can't take up all the
+// workers and starve the other types. When a job finishes, its slot is handed to the next deferred job of its type.
+type typeSlots struct {
+	sync.Mutex
+	limit    func(teetypes.JobType) int
+	running  map[teetypes.JobType]int
+	deferred map[teetypes.JobType][]deferredJob
+}
+
+type deferredJob struct {
+	job  types.Job
+	high bool
+}
+
+func newTypeSlots(limit func(teetypes.JobType) int) *typeSlots {
+	return &typeSlots{
+		limit:    limit,
+		running:  make(map[teetypes.JobType]int),
+		deferred: make(map[teetypes.JobType][]deferredJob),
+	}
+}
+
+// acquire takes a slot for the job and returns true, or defers the job and returns false if its type is at its limit.
+// High priority jobs are deferred ahead of the other jobs of their type.
+func (s *typeSlots) acquire(j types.Job, high bool) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.running[j.Type] < s.limit(j.Type) {
+		s.running[j.Type]++
+		return true
+	}
+
+	queue := s.deferred[j.Type]
+	i := len(queue)
+	if high {
+		i = 0
+		for i < len(queue) && queue[i].high {
+			i++
+		}
+	}
+	queue = append(queue, deferredJob{})
+	copy(queue[i+1:], queue[i:])
+	queue[i] = deferredJob{job: j, high: high}
+	s.deferred[j.Type] = queue
+	return false
+}
+
+// release frees the slot of a finished job. If a job of the same type is deferred, the slot is handed over to it, and
+// the job is returned to be executed by the caller.
+func (s *typeSlots) release(jobType teetypes.JobType) (types.Job, bool) {
+	s.Lock()
+	defer s.Unlock()
+
+	queue := s.deferred[jobType]
+	if len(queue) > 0 {
+		next := queue[0].job
+		if len(queue) == 1 {
+			delete(s.deferred, jobType)
+		} else {
+			s.deferred[jobType] = queue[1:]
+		}
+		return next, true
+	}
+
+	s.running[jobType]--
+	if s.running[jobType] <= 0 {
+		delete(s.running, jobType)
+	}
+	return types.Job{}, false
+}
+
+// deferredLen returns the number of deferred jobs of all types
+func (s *typeSlots) deferredLen() int {
+	s.Lock()
+	defer s.Unlock()
+
+	n := 0
+	for _, queue := range s.deferred {
+		n += len(queue)
+	}
+	return n
+}
+
+// counts returns the number of running and deferred jobs of each type
+func (s *typeSlots) counts() (running, deferred map[teetypes.JobType]int) {
+	s.Lock()
+	defer s.Unlock()
+
+	running = make(map[teetypes.JobType]int, len(s.running))
+	for jobType, n := range s.running {
+		running[jobType] = n
+	}
+	deferred = make(map[teetypes.JobType]int, len(s.deferred))
+	for jobType, queue := range s.deferred {
+		deferred[jobType] = len(queue)
+	}
+	return running, deferred
+}
+
+// saturated returns true if the job type is at its limit and already has deferred jobs, i.e. a new job of the type
+// would have to wait for the jobs deferred before it
+func (s *typeSlots) saturated(jobType teetypes.JobType) bool {
+	s.Lock()
+	defer s.Unlock()
+
+	return s.running[jobType] >= s.limit(jobType) && len(s.deferred[jobType]) > 0
+}
+
+// OverCapacity returns true if the jobs of the given type are at their concurrency limit and others are already
+// waiting for a slot, so a new job of the type would be delayed. Such jobs can be delegated to peer workers.
+func (js *JobServer) OverCapacity(jobType teetypes.JobType) bool {
+	return js.slots.saturated(jobType)
+}
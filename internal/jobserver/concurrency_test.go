@@ -0,0 +1,128 @@
+package jobserver
+
+import (
+	"context"
+	"sync/atomic"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// gatedWorker blocks every job until it is released, and tracks how many jobs run at the same time
+type gatedWorker struct {
+	flakyWorker
+	release chan struct{}
+	running atomic.Int32
+	peak    atomic.Int32
+	done    atomic.Int32
+}
+
+func (b *gatedWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	n := b.running.Add(1)
+	for {
+		peak := b.peak.Load()
+		if n <= peak || b.peak.CompareAndSwap(peak, n) {
+			break
+		}
+	}
+	<-b.release
+	b.running.Add(-1)
+	b.done.Add(1)
+	return types.JobResult{Data: []byte("ok")}, nil
+}
+
+var _ = Describe("Per job type concurrency limits", func() {
+	limits := func(jobType teetypes.JobType) int {
+		if jobType == teetypes.TwitterJob {
+			return 2
+		}
+		return 1
+	}
+
+	It("defers jobs of a type at its limit and hands the slot over when a job finishes", func() {
+		s := newTypeSlots(limits)
+		Expect(s.acquire(types.Job{UUID: "t1", Type: teetypes.TwitterJob}, false)).To(BeTrue())
+		Expect(s.acquire(types.Job{UUID: "t2", Type: teetypes.TwitterJob}, false)).To(BeTrue())
+		Expect(s.acquire(types.Job{UUID: "t3", Type: teetypes.TwitterJob}, false)).To(BeFalse())
+		Expect(s.acquire(types.Job{UUID: "w1", Type: teetypes.WebJob}, false)).To(BeTrue())
+		Expect(s.deferredLen()).To(Equal(1))
+
+		next, ok := s.release(teetypes.TwitterJob)
+		Expect(ok).To(BeTrue())
+		Expect(next.UUID).To(Equal("t3"))
+		Expect(s.deferredLen()).To(BeZero())
+
+		_, ok = s.release(teetypes.TwitterJob)
+		Expect(ok).To(BeFalse())
+		Expect(s.acquire(types.Job{UUID: "t4", Type: teetypes.TwitterJob}, false)).To(BeTrue())
+	})
+
+	It("defers high priority jobs ahead of the other jobs of their type", func() {
+		s := newTypeSlots(limits)
+		Expect(s.acquire(types.Job{UUID: "w1", Type: teetypes.WebJob}, false)).To(BeTrue())
+		Expect(s.acquire(types.Job{UUID: "w2", Type: teetypes.WebJob}, false)).To(BeFalse())
+		Expect(s.acquire(types.Job{UUID: "h1", Type: teetypes.WebJob}, true)).To(BeFalse())
+		Expect(s.acquire(types.Job{UUID: "h2", Type: teetypes.WebJob}, true)).To(BeFalse())
+
+		var order []string
+		for {
+			next, ok := s.release(teetypes.WebJob)
+			if !ok {
+				break
+			}
+			order = append(order, next.UUID)
+		}
+		Expect(order).To(Equal([]string{"h1", "h2", "w2"}))
+	})
+
+	It("is over capacity once jobs of a type at its limit are deferred", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		Expect(js.slots.acquire(types.Job{UUID: "w1", Type: teetypes.WebJob}, false)).To(BeTrue())
+		Expect(js.OverCapacity(teetypes.WebJob)).To(BeFalse())
+
+		Expect(js.slots.acquire(types.Job{UUID: "w2", Type: teetypes.WebJob}, false)).To(BeFalse())
+		Expect(js.OverCapacity(teetypes.WebJob)).To(BeTrue())
+		Expect(js.OverCapacity(teetypes.TwitterJob)).To(BeFalse())
+
+		_, ok := js.slots.release(teetypes.WebJob)
+		Expect(ok).To(BeTrue())
+		Expect(js.OverCapacity(teetypes.WebJob)).To(BeFalse())
+	})
+
+	It("reads the limits from the configuration", func() {
+		jc := config.JobConfiguration{"web_max_concurrent": 2, "twitter_credential_max_concurrent": 0}
+		Expect(jc.GetMaxConcurrent("web")).To(Equal(2))
+		Expect(jc.GetMaxConcurrent("twitter-credential")).To(Equal(1))
+		Expect(jc.GetMaxConcurrent("tiktok")).To(Equal(1))
+	})
+
+	It("keeps executing other job types while a job type is at its limit", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(3, config.JobConfiguration{"web_max_concurrent": 2})
+		web := &gatedWorker{release: make(chan struct{})}
+		other := &orderWorker{}
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: web}
+		js.jobWorkers[teetypes.TiktokJob] = &jobWorkerEntry{w: other}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go js.Run(ctx)
+
+		for _, uuid := range []string{"w1", "w2", "w3", "w4"} {
+			Expect(js.dispatch(types.Job{UUID: uuid, Type: teetypes.WebJob}, ExecutionClassInteractive)).To(Succeed())
+		}
+		Eventually(web.running.Load, "5s").Should(BeEquivalentTo(2))
+		Eventually(js.GetQueueStatus, "5s").Should(HaveField("Deferred", 2))
+
+		Expect(js.dispatch(types.Job{UUID: "t1", Type: teetypes.TiktokJob}, ExecutionClassInteractive)).To(Succeed())
+		Eventually(other.executed, "5s").Should(Equal([]string{"t1"}))
+
+		close(web.release)
+		Eventually(web.done.Load, "5s").Should(BeEquivalentTo(4))
+		Expect(web.peak.Load()).To(BeEquivalentTo(2))
+		Expect(js.GetQueueStatus().Deferred).To(BeZero())
+	})
+})
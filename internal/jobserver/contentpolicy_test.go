@@ -0,0 +1,63 @@
+package jobserver
+
+import (
+	"context"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Content policy", func() {
+	const data = `[{"id":"t3_a","over18":true},{"id":"t1_b","parentId":"t3_a"},{"id":"t3_c","over18":false}]`
+
+	execute := func(jc config.JobConfiguration, args map[string]any) (*JobServer, types.JobResult) {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, jc)
+		js.jobWorkers[teetypes.RedditJob] = &jobWorkerEntry{w: &dataWorker{data: data}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.RedditJob, Arguments: args})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		return js, res
+	}
+
+	It("filters the flagged items by default, and counts them", func() {
+		js, res := execute(config.JobConfiguration{}, map[string]any{"type": "scrapeurls"})
+		Expect(res.Error).To(BeEmpty())
+		Expect(res.Data).To(MatchJSON(`[{"id":"t3_c","over18":false}]`))
+		Eventually(func() uint {
+			js.stats.Stats.Lock()
+			defer js.stats.Stats.Unlock()
+			n := uint(0)
+			for _, byType := range js.stats.Stats.Stats {
+				n += byType["content_filtered_items"]
+			}
+			return n
+		}).Should(Equal(uint(2)))
+	})
+
+	It("filters the flagged items of jobs which opt in if the operator doesn't allow it", func() {
+		_, res := execute(config.JobConfiguration{}, map[string]any{"type": "scrapeurls", "include_nsfw": true})
+		Expect(res.Data).To(MatchJSON(`[{"id":"t3_c","over18":false}]`))
+	})
+
+	It("keeps the flagged items of jobs which opt in if the operator allows it", func() {
+		_, res := execute(config.JobConfiguration{"content_policy_allow_nsfw": true}, map[string]any{"type": "scrapeurls", "include_nsfw": true})
+		Expect(res.Data).To(MatchJSON(data))
+	})
+
+	It("rejects jobs whose opt-in is not a boolean", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		_, err := js.AddJob(types.Job{Type: teetypes.RedditJob, Arguments: map[string]any{"include_nsfw": "yes"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,69 @@
+package jobserver
+
+import (
+	"maps"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+// workerEntries returns the workers of all job types. The map is replaced rather than modified when the credentials
+// are reloaded, so it can be used without holding any lock.
+func (js *JobServer) workerEntries() map[teetypes.JobType]*jobWorkerEntry {
+	js.workersLock.RLock()
+	defer js.workersLock.RUnlock()
+	return js.jobWorkers
+}
+
+// credentialConfiguration returns the job configuration holding the credentials reloaded last. It has to be used
+// instead of jobConfiguration wherever the job server itself uses credentials, such as for the post-processing of
+// results with the Gemini API key.
+func (js *JobServer) credentialConfiguration() config.JobConfiguration {
+	js.workersLock.RLock()
+	defer js.workersLock.RUnlock()
+	return js.credentials
+}
+
+// ReloadCredentials replaces the workers using one of the changed credentials with workers created from jc, which
+// holds the new credentials, and reports the resulting capabilities in the statistics. The other workers are kept
+// along with their state. Jobs which are already running finish with the previous workers. The credentials used by
+// the job server itself, see credentialConfiguration, are replaced too. Simulated workers don't use credentials, so
+// nothing is reloaded in simulation mode.
+func (js *JobServer) ReloadCredentials(jc config.JobConfiguration, changed []string) {
+	if js.jobConfiguration.GetString("simulation_profile", "") != "" {
+		logrus.Info("Running in simulation mode, not reloading credentials")
+		return
+	}
+
+	js.workersLock.Lock()
+	js.credentials = jc
+	js.workersLock.Unlock()
+
+	jobTypes := jobs.JobTypesUsingCredentials(changed)
+	if len(jobTypes) == 0 {
+		logrus.Infof("No worker uses the changed credentials %v", changed)
+		return
+	}
+
+	reloaded := realJobWorkers(jc, js.stats, jobTypes...)
+	js.workersLock.Lock()
+	jobWorkers := maps.Clone(js.jobWorkers)
+	for _, jobType := range jobTypes {
+		delete(jobWorkers, jobType)
+		if workerEntry, ok := reloaded[jobType]; ok && workerEntry.w != nil {
+			jobWorkers[jobType] = workerEntry
+		} else {
+			logrus.Errorf("Failed to initialize worker for job type: %s. This worker will not be available.", jobType)
+		}
+	}
+	js.jobWorkers = jobWorkers
+	js.workersLock.Unlock()
+
+	if js.stats != nil {
+		js.stats.SetJobServer(js)
+	}
+	logrus.Infof("Reloaded credentials of job types %v, capabilities: %+v", jobTypes, js.GetWorkerCapabilities())
+}
@@ -0,0 +1,56 @@
+package jobserver
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Credential reloading", func() {
+	It("replaces the workers with ones using the new credentials", func() {
+		jc := config.JobConfiguration{"twitter_skip_login_verification": true}
+		js := NewJobServer(1, jc)
+		Expect(js.GetWorkerCapabilities()).NotTo(HaveKey(teetypes.TwitterCredentialJob))
+
+		reloaded := config.JobConfiguration{"twitter_skip_login_verification": true, "twitter_accounts": []string{"alice:secret"}}
+		js.ReloadCredentials(reloaded, []string{"twitter_accounts"})
+		Expect(js.GetWorkerCapabilities()).To(HaveKey(teetypes.TwitterCredentialJob))
+		Expect(js.stats.Stats.ReportedCapabilities).To(HaveKey(teetypes.TwitterCredentialJob))
+	})
+
+	It("keeps the workers which don't use the changed credentials", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		before := js.workerEntries()
+
+		js.ReloadCredentials(config.JobConfiguration{"github_token": "token"}, []string{"github_token"})
+		after := js.workerEntries()
+		Expect(after[jobs.GitHubJob]).NotTo(BeIdenticalTo(before[jobs.GitHubJob]))
+		Expect(after[teetypes.TwitterJob]).To(BeIdenticalTo(before[teetypes.TwitterJob]))
+		Expect(after[teetypes.WebJob]).To(BeIdenticalTo(before[teetypes.WebJob]))
+		Expect(after[jobs.MastodonJob]).To(BeIdenticalTo(before[jobs.MastodonJob]))
+
+		// Credentials which no worker uses don't replace any
+		js.ReloadCredentials(config.JobConfiguration{}, []string{"unused"})
+		Expect(js.workerEntries()).To(Equal(after))
+	})
+
+	It("uses the reloaded credentials for the post-processing of results", func() {
+		js := NewJobServer(1, config.JobConfiguration{"apify_api_key": "apify", "gemini_api_key": "old"})
+
+		js.ReloadCredentials(config.JobConfiguration{"apify_api_key": "apify", "gemini_api_key": "new"}, []string{"gemini_api_key"})
+		Expect(js.credentialConfiguration().GetWebConfig().LlmConfig.GeminiApiKey).To(Equal(config.LlmApiKey("new")))
+
+		js.ReloadCredentials(config.JobConfiguration{"apify_api_key": "apify"}, []string{"gemini_api_key"})
+		Expect(jobs.PostProcessConfigured(js.credentialConfiguration())).To(BeFalse())
+	})
+
+	It("doesn't reload simulated workers", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobConfiguration = config.JobConfiguration{"simulation_profile": "profile.json"}
+
+		js.ReloadCredentials(config.JobConfiguration{"twitter_accounts": []string{"alice:secret"}}, []string{"twitter_accounts"})
+		Expect(js.GetWorkerCapabilities()).NotTo(HaveKey(teetypes.TwitterCredentialJob))
+	})
+})
@@ -0,0 +1,192 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ExecutionClass determines when a job is executed
+type ExecutionClass string
+
+const (
+	// ExecutionClassInteractive jobs are executed as soon as a worker is available. This is the default.
+	ExecutionClassInteractive ExecutionClass = "interactive"
+	// ExecutionClassEconomy jobs are queued and only executed when the worker is idle, or when they have been waiting for too long
+	ExecutionClassEconomy ExecutionClass = "economy"
+)
+
+// executionClassArgumentKey is the job argument used by clients to select the execution class
+const executionClassArgumentKey = "execution_class"
+
+const (
+	defaultEconomyQueueSize   = 1000
+	defaultEconomyMaxWaitSecs = 3600
+	economyDispatchInterval   = 250 * time.Millisecond
+)
+
+var ErrEconomyQueueFull = errors.New("economy job queue is full")
+
+// rateLimitAware is implemented by workers that can tell whether they are currently rate limited.
+// Economy jobs are held back while their worker is rate limited.
+type rateLimitAware interface {
+	IsRateLimited() bool
+}
+
+// executionClassFromArguments extracts the execution class from the job arguments. Jobs with low priority are
+// economy jobs, and high priority jobs are interactive.
+func executionClassFromArguments(args types.JobArguments) (ExecutionClass, error) {
+	priority, err := priorityFromArguments(args)
+	if err != nil {
+		return "", err
+	}
+
+	v, ok := args[executionClassArgumentKey]
+	if !ok || v == nil {
+		if priority == PriorityLow {
+			return ExecutionClassEconomy, nil
+		}
+		return ExecutionClassInteractive, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", executionClassArgumentKey, v)
+	}
+
+	var class ExecutionClass
+	switch ExecutionClass(s) {
+	case "", ExecutionClassInteractive:
+		class = ExecutionClassInteractive
+	case ExecutionClassEconomy:
+		class = ExecutionClassEconomy
+	default:
+		return "", fmt.Errorf("invalid execution class %q, valid classes are %q and %q", s, ExecutionClassInteractive, ExecutionClassEconomy)
+	}
+
+	if (priority == PriorityLow && class == ExecutionClassInteractive) || (priority == PriorityHigh && class == ExecutionClassEconomy) {
+		return "", fmt.Errorf("%s %q cannot be combined with %s %q", priorityArgumentKey, priority, executionClassArgumentKey, class)
+	}
+	return class, nil
+}
+
+type queuedJob struct {
+	job      types.Job
+	queuedAt time.Time
+}
+
+// economyQueue holds economy jobs until the worker is idle
+type economyQueue struct {
+	sync.Mutex
+	jobs    []queuedJob
+	maxSize int
+	maxWait time.Duration
+}
+
+func newEconomyQueue(maxSize int, maxWait time.Duration) *economyQueue {
+	if maxSize <= 0 {
+		maxSize = defaultEconomyQueueSize
+	}
+	if maxWait <= 0 {
+		maxWait = defaultEconomyMaxWaitSecs * time.Second
+	}
+	return &economyQueue{maxSize: maxSize, maxWait: maxWait}
+}
+
+func (q *economyQueue) push(j types.Job) error {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.jobs) >= q.maxSize {
+		return ErrEconomyQueueFull
+	}
+	q.jobs = append(q.jobs, queuedJob{job: j, queuedAt: time.Now()})
+	return nil
+}
+
+// len returns the number of queued economy jobs
+func (q *economyQueue) len() int {
+	q.Lock()
+	defer q.Unlock()
+	return len(q.jobs)
+}
+
+// pop returns the oldest job that can run. If idle is false, only jobs that have exceeded the max wait are returned.
+// canRun is used to skip jobs whose worker is currently unavailable (e.g. rate limited).
+func (q *economyQueue) pop(idle bool, canRun func(types.Job) bool) (types.Job, bool) {
+	q.Lock()
+	defer q.Unlock()
+	for i, qj := range q.jobs {
+		overdue := time.Since(qj.queuedAt) > q.maxWait
+		if !overdue && (!idle || !canRun(qj.job)) {
+			continue
+		}
+		q.jobs = append(q.jobs[:i], q.jobs[i+1:]...)
+		return qj.job, true
+	}
+	return types.Job{}, false
+}
+
+// isIdle returns true if no interactive jobs are queued or running, and there is a free economy slot.
+// At least one worker is always kept free of economy jobs so they don't delay interactive traffic.
+func (js *JobServer) isIdle() bool {
+	slots := int64(js.workers - 1)
+	if slots < 1 {
+		slots = 1
+	}
+	return js.interactiveJobs.Load() == 0 && js.economyJobs.Load() < slots
+}
+
+// jobFinished updates the bookkeeping of queued and running jobs once a job has its final result
+func (js *JobServer) jobFinished(j types.Job) {
+	if class, _ := executionClassFromArguments(j.Arguments); class == ExecutionClassEconomy {
+		js.economyJobs.Add(-1)
+	} else {
+		js.interactiveJobs.Add(-1)
+	}
+}
+
+// workerAvailable returns false if the worker for the job type reports being rate limited
+func (js *JobServer) workerAvailable(j types.Job) bool {
+	entry, ok := js.workerEntries()[j.Type]
+	if !ok {
+		return true
+	}
+	if rl, ok := entry.w.(rateLimitAware); ok {
+		return !rl.IsRateLimited()
+	}
+	return true
+}
+
+// dispatchEconomyJobs sends economy jobs to the workers whenever the job server is idle
+func (js *JobServer) dispatchEconomyJobs(ctx context.Context) {
+	ticker := time.NewTicker(economyDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for {
+				j, ok := js.economy.pop(js.isIdle(), js.workerAvailable)
+				if !ok {
+					break
+				}
+				logrus.Debugf("Dispatching economy job %s", j.UUID)
+				js.economyJobs.Add(1)
+				js.waiting.add(j.Type, 1)
+				select {
+				case js.jobChan <- j:
+				case <-ctx.Done():
+					js.waiting.add(j.Type, -1)
+					return
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type rateLimitedWorker struct {
+	flakyWorker
+	limited bool
+}
+
+func (r *rateLimitedWorker) IsRateLimited() bool {
+	return r.limited
+}
+
+var _ = Describe("Economy execution class", func() {
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+	})
+
+	It("parses the execution class", func() {
+		class, err := executionClassFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(class).To(Equal(ExecutionClassInteractive))
+
+		class, err = executionClassFromArguments(types.JobArguments{"execution_class": "economy"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(class).To(Equal(ExecutionClassEconomy))
+
+		_, err = executionClassFromArguments(types.JobArguments{"execution_class": "premium"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only pops jobs when idle, unless they are overdue", func() {
+		q := newEconomyQueue(10, 50*time.Millisecond)
+		Expect(q.push(types.Job{UUID: "a"})).To(Succeed())
+		always := func(types.Job) bool { return true }
+
+		_, ok := q.pop(false, always)
+		Expect(ok).To(BeFalse())
+
+		j, ok := q.pop(true, always)
+		Expect(ok).To(BeTrue())
+		Expect(j.UUID).To(Equal("a"))
+
+		Expect(q.push(types.Job{UUID: "b"})).To(Succeed())
+		time.Sleep(60 * time.Millisecond)
+		j, ok = q.pop(false, always)
+		Expect(ok).To(BeTrue())
+		Expect(j.UUID).To(Equal("b"))
+	})
+
+	It("rejects jobs when the queue is full", func() {
+		q := newEconomyQueue(1, time.Minute)
+		Expect(q.push(types.Job{})).To(Succeed())
+		Expect(q.push(types.Job{})).To(MatchError(ErrEconomyQueueFull))
+	})
+
+	It("executes economy jobs when the worker is idle", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w := &flakyWorker{}
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"execution_class": "economy"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			_, exists := js.GetJobResult(uuid)
+			return exists
+		}, "5s").Should(BeTrue())
+		Expect(js.economyJobs.Load()).To(BeZero())
+	})
+
+	It("holds economy jobs while the worker is rate limited", func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		w := &rateLimitedWorker{limited: true}
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: map[string]any{"execution_class": "economy"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Consistently(func() bool {
+			_, exists := js.GetJobResult(uuid)
+			return exists
+		}, "600ms").Should(BeFalse())
+		Expect(js.economy.len()).To(Equal(1))
+	})
+})
@@ -0,0 +1,142 @@
+package jobserver
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// callCost is the estimated number of requests a job of a type makes to its data source, and how long they take
+type callCost struct {
+	resultsPerCall int64
+	results        int64 // The number of results assumed if the job doesn't ask for a number
+	msPerCall      int64
+}
+
+// callCosts are rough estimates of the requests made by the jobs of each type, used when no job of the type finished
+// recently. Twitter returns 20 tweets per page, Reddit up to 100 items, and web jobs fetch one page per request.
+var callCosts = map[teetypes.JobType]callCost{
+	teetypes.WebJob:               {resultsPerCall: 1, results: 1, msPerCall: 3000},
+	teetypes.TiktokJob:            {resultsPerCall: 1, results: 1, msPerCall: 10000},
+	teetypes.TwitterJob:           {resultsPerCall: 20, results: 20, msPerCall: 1500},
+	teetypes.TwitterCredentialJob: {resultsPerCall: 20, results: 20, msPerCall: 1500},
+	teetypes.TwitterApiJob:        {resultsPerCall: 20, results: 20, msPerCall: 1000},
+	teetypes.TwitterApifyJob:      {resultsPerCall: 20, results: 20, msPerCall: 5000},
+	teetypes.RedditJob:            {resultsPerCall: 100, results: 10, msPerCall: 2000},
+	teetypes.TelemetryJob:         {},
+}
+
+// defaultCallCost is the call cost of the job types which are not in callCosts
+var defaultCallCost = callCost{resultsPerCall: 20, results: 20, msPerCall: 2000}
+
+// calls returns the number of requests made by a job asking for the given number of results
+func (c callCost) calls(results int64) int64 {
+	if c.resultsPerCall <= 0 {
+		return 0
+	}
+	return (results + c.resultsPerCall - 1) / c.resultsPerCall
+}
+
+// expectedMs returns the expected duration of a job asking for the given number of results
+func (c callCost) expectedMs(results int64) int64 {
+	return c.calls(results) * c.msPerCall
+}
+
+// EstimateJob returns what a job is expected to cost without executing it: its duration, from the performance of the
+// recent jobs of its capability, the number of API calls and the Apify compute units it makes, and whether it would be
+// admitted if it was submitted now. It returns an error if the common arguments of the job are invalid.
+func (js *JobServer) EstimateJob(j types.Job) (types.JobEstimate, error) {
+	executionClass, err := executionClassFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobEstimate{}, err
+	}
+	if _, err := bandwidthCapFromArguments(j.Arguments); err != nil {
+		return types.JobEstimate{}, err
+	}
+	if err := validateResultLimitArguments(j.Arguments); err != nil {
+		return types.JobEstimate{}, err
+	}
+	if _, err := js.openNextCursor(j); err != nil {
+		return types.JobEstimate{}, err
+	}
+
+	capability := string(jobCapability(j))
+	estimate := types.JobEstimate{
+		JobType:        j.Type,
+		Capability:     capability,
+		TimeoutSeconds: int(js.jobTimeout(j) / time.Second),
+		QueuedJobs:     js.pending.len(),
+	}
+
+	cost, ok := callCosts[j.Type]
+	if !ok {
+		cost = defaultCallCost
+	}
+	results := requestedResults(j, cost.results)
+	estimate.APICalls = cost.calls(results)
+
+	if perf := js.stats.RecentPerformance(j.Type, capability); perf != nil {
+		estimate.ExpectedDurationMs = perf.P50Ms
+		estimate.P90DurationMs = perf.P90Ms
+		estimate.ErrorRate = perf.ErrorRate
+		estimate.DurationSource = types.EstimateFromHistory
+	} else {
+		estimate.ExpectedDurationMs = cost.expectedMs(results)
+		estimate.P90DurationMs = min(2*estimate.ExpectedDurationMs, int64(estimate.TimeoutSeconds)*1000)
+		estimate.DurationSource = types.EstimateFromDefaults
+	}
+
+	// Apify runs are billed by the items they return, so the compute units of earlier runs are scaled by the number
+	// of results, assuming they read one item per result
+	if apifyCost, ok := js.stats.ApifyCostOf(j.Type, capability); ok {
+		perJob := 1.0 / float64(apifyCost.Runs)
+		if apifyCost.DatasetReads > 0 {
+			perJob = float64(results) / float64(apifyCost.DatasetReads)
+		}
+		estimate.ApifyComputeUnits = apifyCost.ComputeUnits * perJob
+		estimate.ApifyUsageUSD = apifyCost.UsageUSD * perJob
+	}
+
+	if err := js.admissionCheck(j, executionClass); err != nil {
+		estimate.Reason = err.Error()
+		var admissionErr *types.AdmissionError
+		var quotaErr *types.QuotaError
+		switch {
+		case errors.As(err, &admissionErr):
+			estimate.RetryAfterSeconds = admissionErr.RetryAfterSeconds()
+		case errors.As(err, &quotaErr):
+			estimate.RetryAfterSeconds = quotaErr.RetryAfterSeconds(time.Now())
+		}
+		return estimate, nil
+	}
+	estimate.CanServe = true
+	estimate.Delayed = js.OverCapacity(j.Type)
+	return estimate, nil
+}
+
+// admissionCheck returns the error SubmitJob would reject the job with if it was submitted now for lack of capacity,
+// without counting it against the quotas of its miner
+func (js *JobServer) admissionCheck(j types.Job, executionClass ExecutionClass) error {
+	if _, ok := js.workerEntries()[j.Type]; !ok {
+		return fmt.Errorf("unknown job type: %s", j.Type)
+	}
+	if js.bandwidth.remaining(j.WorkerID, time.Now()) == 0 {
+		return ErrClientBandwidthExceeded
+	}
+	if err := js.quotas.check(j.WorkerID, time.Now()); err != nil {
+		return err
+	}
+	if err := js.admit(j, executionClass); err != nil {
+		return err
+	}
+	if err := js.memory.admit(j); err != nil {
+		return err
+	}
+	if executionClass == ExecutionClassEconomy && js.economy.len() >= js.economy.maxSize {
+		return ErrEconomyQueueFull
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package jobserver
+
+import (
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Job estimates", func() {
+	var (
+		js *JobServer
+		w  *admissionWorker
+	)
+
+	BeforeEach(func() {
+		w = &admissionWorker{}
+		js = NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.TwitterJob] = &jobWorkerEntry{w: w}
+	})
+
+	job := func(args types.JobArguments) types.Job {
+		return types.Job{Type: teetypes.TwitterJob, WorkerID: "miner1", Arguments: args}
+	}
+
+	It("estimates jobs from defaults until jobs of their type finished", func() {
+		estimate, err := js.EstimateJob(job(types.JobArguments{"type": "searchbyquery", "query": "masa", "max_results": float64(50)}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.Capability).To(Equal("searchbyquery"))
+		Expect(estimate.APICalls).To(Equal(int64(3)))
+		Expect(estimate.DurationSource).To(Equal(types.EstimateFromDefaults))
+		Expect(estimate.ExpectedDurationMs).To(Equal(int64(4500)))
+		Expect(estimate.P90DurationMs).To(Equal(int64(9000)))
+		Expect(estimate.TimeoutSeconds).To(BeNumerically(">", 0))
+		Expect(estimate.ApifyComputeUnits).To(BeZero())
+		Expect(estimate.CanServe).To(BeTrue())
+		Expect(estimate.Delayed).To(BeFalse())
+	})
+
+	It("estimates jobs from the recent jobs and Apify runs of their capability", func() {
+		for _, latency := range []time.Duration{200 * time.Millisecond, 300 * time.Millisecond, 400 * time.Millisecond} {
+			js.stats.RecordJob(job(types.JobArguments{"type": "searchbyquery"}), latency, false)
+		}
+		js.stats.RecordJob(job(types.JobArguments{"type": "getprofilebyid"}), 20*time.Second, true)
+		js.stats.AddApifyCost(job(types.JobArguments{"type": "searchbyquery"}), types.ApifyCost{Runs: 2, ComputeUnits: 0.5, DatasetReads: 100, UsageUSD: 0.2})
+
+		estimate, err := js.EstimateJob(job(types.JobArguments{"type": "searchbyquery", "max_results": float64(50)}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.DurationSource).To(Equal(types.EstimateFromHistory))
+		Expect(estimate.ExpectedDurationMs).To(BeNumerically("~", 300, 100))
+		Expect(estimate.ErrorRate).To(BeZero())
+		Expect(estimate.ApifyComputeUnits).To(BeNumerically("~", 0.25, 1e-9))
+		Expect(estimate.ApifyUsageUSD).To(BeNumerically("~", 0.1, 1e-9))
+
+		// Capabilities without recent jobs fall back to the job type
+		estimate, err = js.EstimateJob(job(types.JobArguments{"type": "getfollowers"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.DurationSource).To(Equal(types.EstimateFromHistory))
+		Expect(estimate.ErrorRate).To(BeNumerically("~", 0.25, 1e-9))
+	})
+
+	It("reports why jobs can't be served now", func() {
+		w.rejected = true
+		estimate, err := js.EstimateJob(job(types.JobArguments{"type": "searchbyquery"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.CanServe).To(BeFalse())
+		Expect(estimate.Reason).To(ContainSubstring("rate limited"))
+		Expect(estimate.RetryAfterSeconds).To(Equal(90))
+
+		estimate, err = js.EstimateJob(types.Job{Type: "unknown"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.CanServe).To(BeFalse())
+		Expect(estimate.Reason).To(ContainSubstring("unknown job type"))
+	})
+
+	It("checks the quotas of the miner without using them", func() {
+		js.quotas = newMinerQuotas([]config.MinerAPIKey{{Miner: "miner1", Key: "key1", JobsPerHour: 1}})
+		for range 3 {
+			estimate, err := js.EstimateJob(job(types.JobArguments{"type": "searchbyquery"}))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(estimate.CanServe).To(BeTrue())
+		}
+
+		Expect(js.quotas.admit("miner1", time.Now())).To(Succeed())
+		estimate, err := js.EstimateJob(job(types.JobArguments{"type": "searchbyquery"}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(estimate.CanServe).To(BeFalse())
+		Expect(estimate.RetryAfterSeconds).To(BeNumerically("~", 3600, 5))
+	})
+
+	It("rejects invalid common arguments", func() {
+		_, err := js.EstimateJob(job(types.JobArguments{"execution_class": "whenever"}))
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,108 @@
+package jobserver
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// jobEventBufferSize is the number of events buffered for each subscriber. Events which don't fit are dropped, so a
+// slow subscriber never blocks the workers.
+const jobEventBufferSize = 16
+
+var (
+	// ErrJobNotFound is returned when cancelling a job which is unknown
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobFinished is returned when cancelling a job which already has its result
+	ErrJobFinished = errors.New("job has already finished")
+	// ErrJobCancelled is the error of the result of a cancelled job
+	ErrJobCancelled = types.ErrJobCancelled
+)
+
+// jobEvents delivers the changes of the status of jobs to their subscribers
+type jobEvents struct {
+	sync.Mutex
+	subscribers map[string]map[chan types.JobEvent]struct{}
+}
+
+func newJobEvents() *jobEvents {
+	return &jobEvents{subscribers: make(map[string]map[chan types.JobEvent]struct{})}
+}
+
+// subscribe returns a channel receiving the events of a job, and a function to stop receiving them
+func (e *jobEvents) subscribe(uuid string) (<-chan types.JobEvent, func()) {
+	e.Lock()
+	defer e.Unlock()
+	ch := make(chan types.JobEvent, jobEventBufferSize)
+	if e.subscribers[uuid] == nil {
+		e.subscribers[uuid] = make(map[chan types.JobEvent]struct{})
+	}
+	e.subscribers[uuid][ch] = struct{}{}
+
+	return ch, func() {
+		e.Lock()
+		defer e.Unlock()
+		delete(e.subscribers[uuid], ch)
+		if len(e.subscribers[uuid]) == 0 {
+			delete(e.subscribers, uuid)
+		}
+	}
+}
+
+// publish sends an event to the subscribers of its job
+func (e *jobEvents) publish(event types.JobEvent) {
+	e.Lock()
+	defer e.Unlock()
+	for ch := range e.subscribers[event.JobUUID] {
+		select {
+		case ch <- event:
+		default:
+			logrus.Debugf("Dropping %s event of job %s for a slow subscriber", event.Status, event.JobUUID)
+		}
+	}
+}
+
+// SubscribeJobEvents returns a channel receiving the progress of a job, and a function to unsubscribe. The channel is
+// never closed; JobDone tells when the job has finished.
+func (js *JobServer) SubscribeJobEvents(uuid string) (<-chan types.JobEvent, func()) {
+	return js.events.subscribe(uuid)
+}
+
+// JobDone returns a channel which is closed once the job has its result, or nil if the job is not pending
+func (js *JobServer) JobDone(uuid string) <-chan struct{} {
+	return js.pending.wait(uuid)
+}
+
+// CancelJob cancels a job which has not finished yet. A queued job is never executed, and the result of a running
+// job is discarded once it finishes, since workers can't be interrupted; the Apify actor runs of a running job are
+// aborted though, see types.Job.Cancelled. The result of a cancelled job is an ErrJobCancelled error. Cancelling a
+// recurring job also stops its future runs.
+func (js *JobServer) CancelJob(uuid string) error {
+	running, recurring := js.recurring.remove(uuid)
+
+	cancelled := js.pending.cancel(uuid, func(requester string) {
+		// The requester is kept, so only the miner which submitted the job can get its result
+		js.results.Set(uuid, types.JobResult{Job: types.Job{UUID: uuid, WorkerID: requester}, Error: ErrJobCancelled.Error()})
+	})
+	if cancelled {
+		logrus.Infof("Cancelled job %s", uuid)
+		return nil
+	}
+
+	if recurring {
+		if s, ok := js.streams.get(uuid); ok && !running {
+			s.stream.End()
+		}
+		if !running {
+			js.executions.remove(uuid)
+		}
+		logrus.Infof("Cancelled future runs of recurring job %s", uuid)
+		return nil
+	}
+	if _, ok := js.GetJobResult(uuid); ok {
+		return ErrJobFinished
+	}
+	return ErrJobNotFound
+}
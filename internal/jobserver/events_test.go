@@ -0,0 +1,87 @@
+package jobserver_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/jobserver"
+)
+
+var _ = Describe("Job events and cancellation", func() {
+	var js *JobServer
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		js = NewJobServer(1, config.JobConfiguration{})
+	})
+
+	run := func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+	}
+
+	It("reports when a job starts running", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.TelemetryJob})
+		Expect(err).NotTo(HaveOccurred())
+		events, unsubscribe := js.SubscribeJobEvents(uuid)
+		defer unsubscribe()
+		done := js.JobDone(uuid)
+		Expect(done).NotTo(BeNil())
+
+		run()
+
+		Eventually(events, "5s").Should(Receive(Equal(types.JobEvent{JobUUID: uuid, Status: types.JobStatusRunning})))
+		Eventually(done, "5s").Should(BeClosed())
+		Expect(js.JobDone(uuid)).To(BeNil())
+	})
+
+	It("never executes a cancelled job", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.TelemetryJob})
+		Expect(err).NotTo(HaveOccurred())
+		done := js.JobDone(uuid)
+
+		Expect(js.CancelJob(uuid)).To(Succeed())
+		Expect(done).To(BeClosed())
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(res.Error).To(Equal(ErrJobCancelled.Error()))
+
+		run()
+
+		Consistently(func() string {
+			res, _ := js.GetJobResult(uuid)
+			return res.Error
+		}, "1s").Should(Equal(ErrJobCancelled.Error()))
+	})
+
+	It("can't cancel jobs which are unknown or finished", func() {
+		Expect(js.CancelJob("unknown")).To(MatchError(ErrJobNotFound))
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.TelemetryJob})
+		Expect(err).NotTo(HaveOccurred())
+		run()
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+
+		Expect(js.CancelJob(uuid)).To(MatchError(ErrJobFinished))
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(res.Error).To(BeEmpty())
+	})
+
+	It("stops the future runs of a cancelled recurring job", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.TelemetryJob, Arguments: map[string]any{"schedule": "@every 1m"}})
+		Expect(err).NotTo(HaveOccurred())
+		run()
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+
+		Expect(js.CancelJob(uuid)).To(Succeed())
+		Expect(js.RemoveRecurringJob(uuid)).To(MatchError(ErrRecurringJobNotFound))
+	})
+})
@@ -0,0 +1,81 @@
+package jobserver
+
+import (
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/tracing"
+)
+
+// execution is the runtime state of a job which the job server keeps itself, rather than in types.Job, which is also
+// the type clients submit jobs with. It records the phases of the job in both its execution trace and its span.
+type execution struct {
+	parent trace.SpanContext    // span of the API request which submitted the job, if any
+	trace  *types.TraceRecorder // execution trace of jobs submitted with DebugArgumentKey
+	span   *tracing.JobSpan     // span of the current run of the job
+}
+
+// phase records a phase of the job which has just finished, e.g. the sampling of its result
+func (e execution) phase(name string, startedAt time.Time, err error) {
+	finishedAt := time.Now()
+	e.trace.Phase(name, startedAt, finishedAt, err)
+	e.span.Phase(name, startedAt, finishedAt, err)
+}
+
+// retry records that the job is retried after the delay because of the error
+func (e execution) retry(delay time.Duration, err error) {
+	e.trace.Retry(delay, err)
+	e.span.Retry(delay, err)
+}
+
+// jobExecutions holds the executions of the jobs from their submission until their result is stored, or until a
+// recurring job is no longer scheduled
+type jobExecutions struct {
+	sync.Mutex
+	executions map[string]*execution
+}
+
+func newJobExecutions() *jobExecutions {
+	return &jobExecutions{executions: make(map[string]*execution)}
+}
+
+// add starts keeping the execution of a submitted job
+func (e *jobExecutions) add(j types.Job, parent trace.SpanContext) {
+	e.Lock()
+	defer e.Unlock()
+	e.executions[j.UUID] = &execution{parent: parent, trace: j.Trace}
+}
+
+// startRun starts the span of a run of the job. Every run of a recurring job has its own span, which ends once its
+// result is stored.
+func (e *jobExecutions) startRun(j types.Job) execution {
+	e.Lock()
+	defer e.Unlock()
+	ex, ok := e.executions[j.UUID]
+	if !ok {
+		return execution{trace: j.Trace}
+	}
+	ex.span = tracing.StartJob(ex.parent, j.UUID, j.Type.String())
+	return *ex
+}
+
+// get returns the execution of a job. A job which was not submitted, e.g. one executed by the tests, gets an
+// execution which only records its execution trace.
+func (e *jobExecutions) get(j types.Job) execution {
+	e.Lock()
+	defer e.Unlock()
+	if ex, ok := e.executions[j.UUID]; ok {
+		return *ex
+	}
+	return execution{trace: j.Trace}
+}
+
+// remove forgets the execution of a job
+func (e *jobExecutions) remove(jobUUID string) {
+	e.Lock()
+	defer e.Unlock()
+	delete(e.executions, jobUUID)
+}
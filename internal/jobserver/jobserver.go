@@ -3,9 +3,13 @@ package jobserver
 import (
 	"context"
 	"errors"
+	"fmt"
+	"path/filepath"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sirupsen/logrus"
 	"golang.org/x/exp/maps"
@@ -14,27 +18,60 @@ import (
 	teetypes "github.com/masa-finance/tee-types/types"
 	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/contentpolicy"
 	"github.com/masa-finance/tee-worker/internal/jobs"
 	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/redaction"
+	"github.com/masa-finance/tee-worker/internal/tracing"
 	"github.com/masa-finance/tee-worker/pkg/tee"
 )
 
 type JobServer struct {
 	sync.Mutex
 
-	jobChan chan types.Job
-	workers int
+	jobChan         chan types.Job
+	priorityJobChan chan types.Job // high priority jobs, which workers take before those in jobChan
+	workers         int
 
 	results          *ResultCache
 	jobConfiguration config.JobConfiguration
 
 	jobWorkers   map[teetypes.JobType]*jobWorkerEntry
 	executedJobs map[string]bool
+
+	workersLock sync.RWMutex            // guards replacing jobWorkers and credentials when the credentials are reloaded
+	credentials config.JobConfiguration // configuration holding the credentials reloaded last, see ReloadCredentials
+
+	economy         *economyQueue
+	interactiveJobs atomic.Int64 // interactive jobs that are queued or running
+	economyJobs     atomic.Int64 // economy jobs that have been dispatched to the workers
+	waiting         *waitingJobs // jobs that have been handed to the workers but not received by one yet
+
+	recurring  *recurringJobs
+	bandwidth  *clientBandwidth
+	priorities *priorityManager
+	pending    *pendingJobs
+	events     *jobEvents
+	batches    *jobBatches
+	traces     *jobTraces
+	streams    *jobStreams
+	stats      *stats.StatsCollector
+	benchmarks benchmarks
+	slots      *typeSlots
+	memory     *memoryGuard
+	quotas     *minerQuotas
+	breakers   *circuitBreakers
+	audit      *auditLog
+	executions *jobExecutions
+	redaction  *redaction.KeyStore
+
+	held           *heldResults
+	maxHeldResults int
+	holdLock       sync.Mutex // serializes holding and releasing results
 }
 
 type jobWorkerEntry struct {
 	w worker
-	sync.Mutex
 }
 
 func NewJobServer(workers int, jc config.JobConfiguration) *JobServer {
@@ -80,31 +117,11 @@ func NewJobServer(workers int, jc config.JobConfiguration) *JobServer {
 
 	// Initialize job workers
 	logrus.Info("Setting up job workers...")
-	jobworkers := map[teetypes.JobType]*jobWorkerEntry{
-		teetypes.WebJob: {
-			w: jobs.NewWebScraper(jc, s),
-		},
-		teetypes.TwitterJob: {
-			w: jobs.NewTwitterScraper(jc, s),
-		},
-		teetypes.TwitterCredentialJob: {
-			w: jobs.NewTwitterScraper(jc, s), // Uses the same implementation as standard Twitter scraper
-		},
-		teetypes.TwitterApiJob: {
-			w: jobs.NewTwitterScraper(jc, s), // Uses the same implementation as standard Twitter scraper
-		},
-		teetypes.TwitterApifyJob: {
-			w: jobs.NewTwitterScraper(jc, s), // Register Apify job type with Twitter scraper
-		},
-		teetypes.TiktokJob: {
-			w: jobs.NewTikTokScraper(jc, s),
-		},
-		teetypes.RedditJob: {
-			w: jobs.NewRedditScraper(jc, s),
-		},
-		teetypes.TelemetryJob: {
-			w: jobs.NewTelemetryJob(jc, s),
-		},
+	var jobworkers map[teetypes.JobType]*jobWorkerEntry
+	if path := jc.GetString("simulation_profile", ""); path != "" {
+		jobworkers = simulatedJobWorkers(path, jc, s)
+	} else {
+		jobworkers = realJobWorkers(jc, s)
 	}
 	// Validate that all workers were initialized successfully
 	for jobType, workerEntry := range jobworkers {
@@ -133,14 +150,75 @@ func NewJobServer(workers int, jc config.JobConfiguration) *JobServer {
 		resultCacheMaxSize = 1000
 	}
 
+	economyQueueSize, err := jc.GetInt("economy_queue_size", defaultEconomyQueueSize)
+	if err != nil {
+		logrus.Errorf("Invalid economy_queue_size config: %v", err)
+		economyQueueSize = defaultEconomyQueueSize
+	}
+
+	maxRecurringJobs, err := jc.GetInt("max_recurring_jobs", defaultMaxRecurringJobs)
+	if err != nil {
+		logrus.Errorf("Invalid max_recurring_jobs config: %v", err)
+		maxRecurringJobs = defaultMaxRecurringJobs
+	}
+
+	maxHeldResults, err := jc.GetInt("result_max_held", defaultMaxHeldResults)
+	if err != nil {
+		logrus.Errorf("Invalid result_max_held config: %v", err)
+		maxHeldResults = defaultMaxHeldResults
+	}
+
+	memoryCeiling, err := jc.GetInt("memory_ceiling_bytes", 0)
+	if err != nil {
+		logrus.Errorf("Invalid memory_ceiling_bytes config: %v", err)
+		memoryCeiling = 0
+	}
+
+	results := NewResultCache(resultCacheMaxSize, jc.GetDuration("result_cache_max_age_seconds", 600))
+	if maxBytes, err := jc.GetInt("result_cache_max_bytes", 0); err == nil {
+		results.SetMaxBytes(int64(maxBytes))
+	} else {
+		logrus.Errorf("Invalid result_cache_max_bytes config: %v", err)
+	}
+	if dataDir := jc.GetString("data_dir", ""); dataDir != "" {
+		spillBytes, err := jc.GetInt("result_cache_spill_bytes", 0)
+		if err != nil {
+			logrus.Errorf("Invalid result_cache_spill_bytes config: %v", err)
+		} else if err := results.SetSpill(filepath.Join(dataDir, "result_cache"), int64(spillBytes)); err != nil {
+			logrus.Errorf("Failed to set up spilling of large results, keeping them in memory: %v", err)
+		}
+	}
+
 	js := &JobServer{
-		jobChan: make(chan types.Job),
+		jobChan:         make(chan types.Job),
+		priorityJobChan: make(chan types.Job),
 		// TODO The defaults here should come from config.go, but during tests the config is not necessarily read
-		results:          NewResultCache(resultCacheMaxSize, jc.GetDuration("result_cache_max_age_seconds", 600)),
+		results:          results,
 		workers:          workers,
 		jobConfiguration: jc,
 		jobWorkers:       jobworkers,
+		credentials:      jc,
 		executedJobs:     make(map[string]bool),
+		waiting:          newWaitingJobs(),
+		economy:          newEconomyQueue(economyQueueSize, jc.GetDuration("economy_max_wait_seconds", defaultEconomyMaxWaitSecs)),
+		recurring:        newRecurringJobs(maxRecurringJobs),
+		bandwidth:        newClientBandwidth(jc.GetBandwidthConfig()),
+		priorities:       newPriorityManager(jc.GetStringSlice("priority_worker_ids", nil)),
+		pending:          newPendingJobs(),
+		events:           newJobEvents(),
+		batches:          newJobBatches(),
+		traces:           newJobTraces(),
+		streams:          newJobStreams(),
+		slots:            newTypeSlots(func(jobType teetypes.JobType) int { return jc.GetMaxConcurrent(string(jobType)) }),
+		memory:           newMemoryGuard(int64(memoryCeiling)),
+		quotas:           newMinerQuotas(jc.GetMinerAPIKeys()),
+		breakers:         newCircuitBreakers(),
+		executions:       newJobExecutions(),
+		audit:            newAuditLog(jc.GetString("data_dir", ""), jc.GetBool("audit_log_enabled", false)),
+		redaction:        redaction.NewKeyStore(jc.GetString("data_dir", "")),
+		stats:            s,
+		held:             newHeldResults(jc.GetString("data_dir", "")),
+		maxHeldResults:   maxHeldResults,
 	}
 
 	// Set the JobServer reference in the stats collector for capability reporting
@@ -151,12 +229,47 @@ func NewJobServer(workers int, jc config.JobConfiguration) *JobServer {
 	return js
 }
 
-// GetWorkerCapabilities returns the structured capabilities for all registered workers
+// SetWorkerID sets the worker ID reported in the statistics, after the worker ID was rotated
+func (js *JobServer) SetWorkerID(workerID string) {
+	js.stats.SetWorkerID(workerID)
+}
+
+// StatsDimensions returns the dimensions the statistics are broken down by
+func (js *JobServer) StatsDimensions() []stats.Dimension {
+	return js.stats.Dimensions()
+}
+
+// SetStatsDimensions changes the dimensions the statistics are broken down by, without restarting the worker
+func (js *JobServer) SetStatsDimensions(dims []stats.Dimension) {
+	js.stats.SetDimensions(dims)
+	logrus.Infof("Statistics broken down by %v", dims)
+}
+
+// GetWorkerCapabilities returns the structured capabilities for all registered workers, without the capabilities
+// withdrawn by their circuit breakers
 func (js *JobServer) GetWorkerCapabilities() teetypes.WorkerCapabilities {
+	details := js.capabilityDetails()
+	now := time.Now()
+	available := make(teetypes.WorkerCapabilities)
+	for jobType, capabilities := range js.workerCapabilities() {
+		if len(capabilities) == 0 {
+			available[jobType] = capabilities
+		}
+		for _, capability := range capabilities {
+			if js.breakers.capabilityUnavailableFor(jobType, capability, authSources(details, jobType, capability), now) == 0 {
+				available[jobType] = append(available[jobType], capability)
+			}
+		}
+	}
+	return available
+}
+
+// workerCapabilities returns the structured capabilities for all registered workers
+func (js *JobServer) workerCapabilities() teetypes.WorkerCapabilities {
 	// Use a map to deduplicate capabilities by job type
 	jobTypeCapMap := make(map[teetypes.JobType]map[teetypes.Capability]struct{})
 
-	for _, workerEntry := range js.jobWorkers {
+	for _, workerEntry := range js.workerEntries() {
 		workerCapabilities := workerEntry.w.GetStructuredCapabilities()
 		for jobType, capabilities := range workerCapabilities {
 			if _, exists := jobTypeCapMap[jobType]; !exists {
@@ -178,60 +291,406 @@ func (js *JobServer) GetWorkerCapabilities() teetypes.WorkerCapabilities {
 	return allCapabilities
 }
 
+// GetCapabilityDetails returns the details of the capabilities of all registered workers, without the capabilities
+// withdrawn by their circuit breakers. Workers which don't describe their capabilities are reported as not needing
+// any authentication.
+func (js *JobServer) GetCapabilityDetails() types.CapabilityDetails {
+	now := time.Now()
+	available := make(types.CapabilityDetails)
+	for jobType, jobDetails := range js.capabilityDetails() {
+		for _, detail := range jobDetails {
+			if js.breakers.unavailableFor(breakerKey{jobType, detail.Capability, detail.AuthSource}, now) == 0 {
+				available[jobType] = append(available[jobType], detail)
+			}
+		}
+	}
+	return available
+}
+
+// capabilityDetails returns the details of the capabilities of all registered workers
+func (js *JobServer) capabilityDetails() types.CapabilityDetails {
+	type detailKey struct {
+		capability teetypes.Capability
+		source     types.AuthSource
+	}
+	seen := make(map[teetypes.JobType]map[detailKey]struct{})
+	allDetails := make(types.CapabilityDetails)
+
+	for _, workerEntry := range js.workerEntries() {
+		var details types.CapabilityDetails
+		if d, ok := workerEntry.w.(capabilityDescriber); ok {
+			details = d.GetCapabilityDetails()
+		} else {
+			details = types.NewCapabilityDetails(workerEntry.w.GetStructuredCapabilities(), types.AuthSourceNone)
+		}
+
+		for jobType, jobDetails := range details {
+			if _, exists := seen[jobType]; !exists {
+				seen[jobType] = make(map[detailKey]struct{})
+			}
+			for _, detail := range jobDetails {
+				key := detailKey{detail.Capability, detail.AuthSource}
+				if _, dup := seen[jobType][key]; dup {
+					continue
+				}
+				seen[jobType][key] = struct{}{}
+				allDetails[jobType] = append(allDetails[jobType], detail)
+			}
+		}
+	}
+
+	for _, jobDetails := range allDetails {
+		slices.SortFunc(jobDetails, func(a, b types.CapabilityDetail) int {
+			if c := strings.Compare(string(a.Capability), string(b.Capability)); c != 0 {
+				return c
+			}
+			return strings.Compare(string(a.AuthSource), string(b.AuthSource))
+		})
+	}
+
+	return allDetails
+}
+
+// GetKeyCapabilities returns the capability matrices of the provider API keys of all registered workers. Workers
+// which share keys report them once.
+func (js *JobServer) GetKeyCapabilities() []types.KeyCapabilities {
+	type keyID struct{ provider, fingerprint string }
+	seen := make(map[keyID]struct{})
+	var all []types.KeyCapabilities
+
+	for _, workerEntry := range js.workerEntries() {
+		r, ok := workerEntry.w.(keyCapabilityReporter)
+		if !ok {
+			continue
+		}
+		for _, k := range r.GetKeyCapabilities() {
+			id := keyID{k.Provider, k.Fingerprint}
+			if _, dup := seen[id]; dup {
+				continue
+			}
+			seen[id] = struct{}{}
+			all = append(all, k)
+		}
+	}
+
+	slices.SortFunc(all, func(a, b types.KeyCapabilities) int {
+		if c := strings.Compare(a.Provider, b.Provider); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+	return all
+}
+
 func (js *JobServer) Run(ctx context.Context) {
 	for i := 0; i < js.workers; i++ {
 		go js.worker(ctx)
 	}
 
+	go js.dispatchEconomyJobs(ctx)
+	go js.dispatchRecurringJobs(ctx)
+
 	<-ctx.Done()
 }
 
+// Shutdown saves the state which has to survive a restart of the worker, i.e. the cumulative statistics
+func (js *JobServer) Shutdown() {
+	if err := js.stats.Persist(); err != nil {
+		logrus.Errorf("Failed to save statistics on shutdown: %v", err)
+	}
+}
+
+// AddJob adds a job and returns its UUID, see SubmitJob
 func (js *JobServer) AddJob(j types.Job) (string, error) {
+	res, err := js.SubmitJob(j)
+	return res.UID, err
+}
+
+// SubmitJob adds a job. Unless the job is answered with the result of an identical earlier job, which is flagged as
+// cached in the response, it is queued for execution.
+func (js *JobServer) SubmitJob(j types.Job) (types.JobResponse, error) {
+	return js.SubmitJobContext(context.Background(), j)
+}
+
+// SubmitJobContext is SubmitJob for a job submitted by the API request of ctx, whose OpenTelemetry span becomes the
+// parent of the spans of the job
+func (js *JobServer) SubmitJobContext(ctx context.Context, j types.Job) (types.JobResponse, error) {
 	js.Lock()
 	defer js.Unlock()
 
 	if _, ok := js.executedJobs[j.Nonce]; ok {
-		return "", errors.New("job already executed")
+		return types.JobResponse{}, errors.New("job already executed")
 	}
 
 	js.executedJobs[j.Nonce] = true
 
-	if j.TargetWorker != "" && j.TargetWorker != tee.WorkerID {
-		return "", errors.New("this job is not for this worker")
+	if j.TargetWorker != "" && !tee.IsWorkerID(j.TargetWorker) {
+		return types.JobResponse{}, errors.New("this job is not for this worker")
 	}
 
 	if j.Type != teetypes.TelemetryJob && config.MinersWhiteList != "" {
-		var miners []string
+		var whitelisted bool
 
-		// In standalone mode, we just whitelist ourselves
+		// In standalone mode, we just whitelist ourselves, including the worker IDs replaced by a rotation whose grace
+		// period isn't over
 		if js.jobConfiguration.IsStandaloneMode() {
-			miners = []string{tee.WorkerID}
+			logrus.Debugf("Checking if job from miner %s is from this worker", j.WorkerID)
+			whitelisted = tee.IsWorkerID(j.WorkerID)
 		} else {
-			miners = strings.Split(config.MinersWhiteList, ",")
+			miners := strings.Split(config.MinersWhiteList, ",")
+			logrus.Debugf("Checking if job from miner %s is whitelisted. Miners white list: %+v", j.WorkerID, miners)
+			whitelisted = slices.Contains(miners, j.WorkerID)
 		}
 
-		logrus.Debugf("Checking if job from miner %s is whitelisted. Miners white list: %+v", j.WorkerID, miners)
-
-		if !slices.Contains(miners, j.WorkerID) {
+		if !whitelisted {
 			logrus.Debugf("Job from non-whitelisted miner %s", j.WorkerID)
-			return "", errors.New("this job is not from a whitelisted miner")
+			return types.JobResponse{}, errors.New("this job is not from a whitelisted miner")
 		}
 		logrus.Debugf("Job from whitelisted miner %s", j.WorkerID)
 	}
 
-	// TODO The default should come from config.go, but during tests the config is not necessarily read
-	j.Timeout = js.jobConfiguration.GetDuration("job_timeout_seconds", 300)
+	if _, err := redaction.ModeFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if _, err := contentpolicy.IncludeFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	cacheDirective, err := cacheDirectiveFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResponse{}, err
+	}
+
+	executionClass, err := executionClassFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResponse{}, err
+	}
+
+	schedule, err := scheduleFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if err := validateProvenanceArgument(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if err := validateDebugArgument(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if err := validatePaginatedArgument(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	// The job is executed with the cursor of the data source sealed in its next_cursor argument
+	j, err = js.openNextCursor(j)
+	if err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if _, err := sampleFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if _, err := projectionFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+
+	if p, err := jobs.PostProcessFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	} else if p != nil && !jobs.PostProcessConfigured(js.credentialConfiguration()) {
+		return types.JobResponse{}, jobs.ErrPostProcessNotConfigured
+	}
+
+	_, retain, err := types.HoldTagFromArguments(j.Arguments)
+	if err != nil {
+		return types.JobResponse{}, err
+	}
+	if retain && cacheDirective.NoStore {
+		return types.JobResponse{}, fmt.Errorf("cache directive no-store cannot be combined with %s", types.RetainArgumentKey)
+	}
+
+	if _, err := bandwidthCapFromArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+	if err := validateResultLimitArguments(j.Arguments); err != nil {
+		return types.JobResponse{}, err
+	}
+	if js.bandwidth.remaining(j.WorkerID, time.Now()) == 0 {
+		return types.JobResponse{}, ErrClientBandwidthExceeded
+	}
+	if schedule != nil && cacheDirective.NoStore {
+		return types.JobResponse{}, fmt.Errorf("cache directive no-store cannot be combined with %s", scheduleArgumentKey)
+	}
+
+	// A job which is rejected for the quotas of its miner may be submitted again once they reset. A job which is counted
+	// here but not accepted later on is taken back from the quotas.
+	if err := js.quotas.admit(j.WorkerID, time.Now()); err != nil {
+		logrus.Infof("Not admitting job of miner %s: %s", j.WorkerID, err)
+		delete(js.executedJobs, j.Nonce)
+		return types.JobResponse{}, err
+	}
+
+	j.Timeout = js.jobTimeout(j)
 
 	jobUUID := uuid.New().String()
 	j.UUID = jobUUID
+	if types.DebugRequested(j.Arguments) {
+		j.Trace = js.traces.add(jobUUID)
+	}
+
+	// Every run of a recurring job is executed, so its results are never taken from the cache. Jobs without a cache
+	// directive reuse the result of an identical job completed within the deduplication TTL, if there is one.
+	cacheDirective = cacheDirective.WithDefaultMaxAge(js.jobConfiguration.GetDuration("dedup_ttl_seconds", 0))
+	if schedule == nil && cacheDirective.AllowsReuse() {
+		if fingerprint, err := jobFingerprint(j); err == nil {
+			if cached, ok := js.results.GetByFingerprint(fingerprint, cacheDirective.MaxAge); ok {
+				logrus.Debugf("Reusing cached result for job %s", jobUUID)
+				// The result is sealed with the nonce of the job, so it needs to be associated with the new job
+				cached.Job = j
+				// No bandwidth was used to serve this job
+				cached.Usage = nil
+				now := time.Now()
+				j.Trace.Phase("cache", now, now, nil)
+				cached.Trace = j.Trace.Trace()
+				cached.Cached = true
+				js.streams.add(j).End()
+				js.quotas.addResultBytes(j.WorkerID, len(cached.Data), now)
+				js.results.Set(jobUUID, cached)
+				js.retainIfRequested(j)
+				js.audit.record(auditEntry(types.AuditJobAccepted, j))
+				js.audit.record(finishedAuditEntry(j, cached))
+				return types.JobResponse{UID: jobUUID, Cached: true}, nil
+			}
+		}
+	}
+
+	// Jobs which can be served from the cache are admitted even if the data source is unavailable. A job which is not
+	// admitted may be submitted again once it is.
+	if err := js.admit(j, executionClass); err != nil {
+		js.quotas.refund(j.WorkerID, time.Now())
+		delete(js.executedJobs, j.Nonce)
+		return types.JobResponse{}, err
+	}
+	if err := js.memory.admit(j); err != nil {
+		logrus.Infof("Not admitting %s job: %s", j.Type, err)
+		js.quotas.refund(j.WorkerID, time.Now())
+		delete(js.executedJobs, j.Nonce)
+		return types.JobResponse{}, err
+	}
+
+	// The stream is shared by the attempts of the job and the runs of a recurring job. It is ended if the job is not
+	// accepted after all, so nothing waits for its batches.
+	j.Stream = js.streams.add(j)
+	js.executions.add(j, tracing.SpanContext(ctx))
+
+	// The latest result of a recurring job is always stored under the UUID returned here
+	if schedule != nil {
+		if err := js.recurring.add(j, executionClass, schedule, time.Now(), js.quotas.maxRecurringJobs(j.WorkerID)); err != nil {
+			// The job may be submitted again once another recurring job is removed
+			js.quotas.refund(j.WorkerID, time.Now())
+			j.Stream.End()
+			js.executions.remove(jobUUID)
+			delete(js.executedJobs, j.Nonce)
+			return types.JobResponse{}, err
+		}
+		logrus.Infof("Added recurring job %s (type %s) with schedule %q", jobUUID, j.Type, j.Arguments[scheduleArgumentKey])
+	}
+
+	// The job is recorded as accepted before it is dispatched, so its acceptance always precedes its result in the
+	// audit log
+	js.audit.record(auditEntry(types.AuditJobAccepted, j))
+	js.pending.add(jobUUID, j.WorkerID)
+	if err := js.dispatch(j, executionClass); err != nil {
+		js.pending.finish(jobUUID)
+		js.recurring.remove(jobUUID)
+		js.quotas.refund(j.WorkerID, time.Now())
+		j.Stream.End()
+		js.executions.remove(jobUUID)
+		js.audit.record(finishedAuditEntry(j, types.JobResult{Error: err.Error()}))
+		return types.JobResponse{}, err
+	}
+
+	return types.JobResponse{UID: jobUUID}, nil
+}
 
-	go func() {
-		js.jobChan <- j
-	}()
+// dispatch queues the job for execution according to its execution class
+func (js *JobServer) dispatch(j types.Job, executionClass ExecutionClass) error {
+	j.Trace.Queued(time.Now())
+	ex := js.executions.startRun(j)
+	if executionClass == ExecutionClassEconomy {
+		if err := js.economy.push(j); err != nil {
+			ex.span.End(err)
+			return err
+		}
+		return nil
+	}
+
+	js.interactiveJobs.Add(1)
+	go js.queue(j)
 
-	return jobUUID, nil
+	return nil
 }
 
+// GetJobResult returns the result of a job. For recurring jobs this is the result of the latest finished run,
+// which is kept for as long as the job is scheduled even if it has expired from the result cache. Held results
+// which are not in the cache anymore, e.g. after a restart, are read from disk.
 func (js *JobServer) GetJobResult(uuid string) (types.JobResult, bool) {
-	return js.results.Get(uuid)
+	if res, ok := js.results.Get(uuid); ok {
+		return res, true
+	}
+	if res, ok := js.recurring.latest(uuid); ok {
+		return res, true
+	}
+	return js.loadHeldResult(uuid)
+}
+
+// GetQueueStatus returns how many jobs are waiting or running
+func (js *JobServer) GetQueueStatus() types.QueueStatus {
+	return types.QueueStatus{
+		Pending:        js.pending.len(),
+		Interactive:    int(js.interactiveJobs.Load()),
+		EconomyQueued:  js.economy.len(),
+		EconomyRunning: int(js.economyJobs.Load()),
+		Recurring:      js.recurring.len(),
+		Deferred:       js.slots.deferredLen(),
+		MemoryDeferred: js.memory.deferredLen(),
+	}
+}
+
+// GetStats returns the current statistics of the worker as JSON
+func (js *JobServer) GetStats() ([]byte, error) {
+	return js.stats.Json()
+}
+
+// realJobWorkers returns the workers which execute jobs against the real data sources, i.e. the workers registered by
+// the scrapers included in the binary. If jobTypes are given, only the workers executing them are created.
+func realJobWorkers(jc config.JobConfiguration, s *stats.StatsCollector, jobTypes ...teetypes.JobType) map[teetypes.JobType]*jobWorkerEntry {
+	jobworkers := make(map[teetypes.JobType]*jobWorkerEntry)
+	for jobType, w := range jobs.NewRegisteredWorkers(jc, s, jobTypes...) {
+		jobworkers[jobType] = &jobWorkerEntry{w: w}
+	}
+	return jobworkers
+}
+
+// simulatedJobWorkers returns workers which advertise the capabilities of a simulation profile and serve mock results.
+// None of the real workers are created, so no credentials are used. The telemetry job still reports real statistics.
+func simulatedJobWorkers(path string, jc config.JobConfiguration, s *stats.StatsCollector) map[teetypes.JobType]*jobWorkerEntry {
+	profile, err := jobs.LoadSimulationProfile(path)
+	if err != nil {
+		logrus.Fatalf("Invalid simulation profile %s: %s", path, err)
+	}
+	logrus.Warnf("Running in simulation mode with profile %s, jobs will return mock results", path)
+
+	jobworkers := map[teetypes.JobType]*jobWorkerEntry{
+		teetypes.TelemetryJob: {
+			w: jobs.NewTelemetryJob(jc, s),
+		},
+	}
+	for jobType, jt := range profile.JobTypes {
+		jobworkers[jobType] = &jobWorkerEntry{w: jobs.NewSimulatedWorker(jobType, jt, s)}
+	}
+	return jobworkers
 }
@@ -2,8 +2,11 @@ package jobserver_test
 
 import (
 	"context"
+	"fmt"
 	_ "os"
-	_ "time"
+	"time"
+
+	"github.com/google/uuid"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -13,6 +16,7 @@ import (
 	"github.com/masa-finance/tee-worker/api/types"
 	"github.com/masa-finance/tee-worker/internal/config"
 	. "github.com/masa-finance/tee-worker/internal/jobserver"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 )
 
 var _ = Describe("Jobserver", func() {
@@ -77,6 +81,26 @@ var _ = Describe("Jobserver", func() {
 		_, exists := jobserver.GetJobResult(uuid)
 		Expect(exists).ToNot(BeTrue())
 	})
+	It("whitelists the previous worker IDs in standalone mode during their grace period", func() {
+		originalWorkerID, originalStandalone := tee.WorkerID, tee.SealStandaloneMode
+		tee.WorkerID, tee.SealStandaloneMode = uuid.NewString(), true
+		DeferCleanup(func() { tee.WorkerID, tee.SealStandaloneMode = originalWorkerID, originalStandalone })
+
+		config.MinersWhiteList = "miner1"
+		jobserver := NewJobServer(2, config.JobConfiguration{"standalone_mode": true})
+
+		previous := tee.CurrentWorkerID()
+		_, err := tee.RotateWorkerID(GinkgoT().TempDir(), "", time.Hour)
+		Expect(err).ToNot(HaveOccurred())
+
+		for i, workerID := range []string{previous, tee.CurrentWorkerID()} {
+			_, err := jobserver.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: workerID, Nonce: fmt.Sprintf("standalone-%d", i)})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		_, err = jobserver.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "miner1", Nonce: "standalone-miner"})
+		Expect(err).To(MatchError(ContainSubstring("this job is not from a whitelisted miner")))
+	})
 	It("won't execute same jobs twice", func() {
 		jobserver := NewJobServer(2, config.JobConfiguration{})
 
@@ -107,3 +131,44 @@ var _ = Describe("Jobserver", func() {
 		Expect(err.Error()).To(ContainSubstring("job already executed"))
 	})
 })
+
+var _ = Describe("Capability details", func() {
+	It("reports the auth source and estimated rate limit of each capability", func() {
+		jobserver := NewJobServer(1, config.JobConfiguration{
+			"twitter_accounts": []string{"user1:pass1", "user2:pass2"},
+		})
+
+		details := jobserver.GetCapabilityDetails()
+
+		Expect(details[teetypes.TwitterCredentialJob]).To(ContainElement(types.CapabilityDetail{
+			Capability: teetypes.CapSearchByQuery,
+			AuthSource: types.AuthSourceCredential,
+			RateLimit:  &types.RateLimitEstimate{Requests: 100, WindowSeconds: 900},
+		}))
+		Expect(details[teetypes.TelemetryJob]).To(ContainElement(types.CapabilityDetail{
+			Capability: teetypes.CapTelemetry,
+			AuthSource: types.AuthSourceNone,
+		}))
+		Expect(details).ToNot(HaveKey(teetypes.TwitterApiJob))
+
+		// The Twitter scraper is registered for several job types, but each detail must only be reported once
+		seen := map[types.CapabilityDetail]int{}
+		for _, d := range details[teetypes.TwitterJob] {
+			seen[types.CapabilityDetail{Capability: d.Capability, AuthSource: d.AuthSource}]++
+		}
+		for d, n := range seen {
+			Expect(n).To(Equal(1), "duplicate detail %v", d)
+		}
+	})
+})
+
+var _ = Describe("Dependency probes", func() {
+	It("reports whether the workers can use their dependencies", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		Expect(js.ProbeDependencies(teetypes.TwitterJob)).To(MatchError(types.ErrNotConfigured))
+		Expect(js.ProbeDependencies(teetypes.TelemetryJob)).To(MatchError(types.ErrNotConfigured))
+
+		js = NewJobServer(1, config.JobConfiguration{"twitter_accounts": []string{"user1:pass1"}})
+		Expect(js.ProbeDependencies(teetypes.TwitterJob)).To(Succeed())
+	})
+})
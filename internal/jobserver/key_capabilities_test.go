@@ -0,0 +1,43 @@
+package jobserver
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// keyReportingWorker reports the capability matrices of a fixed set of keys
+type keyReportingWorker struct {
+	keys []types.KeyCapabilities
+}
+
+func (k *keyReportingWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{teetypes.TwitterApiJob: {teetypes.CapSearchByQuery}}
+}
+
+func (k *keyReportingWorker) GetKeyCapabilities() []types.KeyCapabilities {
+	return k.keys
+}
+
+func (k *keyReportingWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	return types.JobResult{}, nil
+}
+
+var _ = Describe("Key capabilities", func() {
+	It("reports each key once, even if it is shared by several workers", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		w := &keyReportingWorker{keys: []types.KeyCapabilities{
+			{Provider: "twitter", Fingerprint: "bbbb", Tier: "base", Endpoints: map[string]bool{"full_archive": false}},
+			{Provider: "twitter", Fingerprint: "aaaa", Tier: "elevated", Endpoints: map[string]bool{"full_archive": true}},
+		}}
+		js.jobWorkers[teetypes.TwitterApiJob] = &jobWorkerEntry{w: w}
+		js.jobWorkers[teetypes.TwitterJob] = &jobWorkerEntry{w: w}
+
+		keys := js.GetKeyCapabilities()
+		Expect(keys).To(HaveLen(2))
+		Expect(keys[0].Fingerprint).To(Equal("aaaa"))
+		Expect(keys[1].Tier).To(Equal("base"))
+	})
+})
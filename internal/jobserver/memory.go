@@ -0,0 +1,202 @@
+package jobserver
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// memoryCost is the estimated memory used by a job of a type: a fixed cost, and a cost per result the job asks for
+type memoryCost struct {
+	base      int64
+	perResult int64
+	results   int64 // The number of results assumed if the job doesn't ask for a number
+}
+
+// memoryCosts are rough estimates of the peak memory used by the jobs of each type, on top of the memory used by the
+// worker itself. Web jobs render whole pages and TikTok jobs download the videos they transcribe.
+var memoryCosts = map[teetypes.JobType]memoryCost{
+	teetypes.WebJob:               {base: 16 << 20, perResult: 4 << 20, results: 1},
+	teetypes.TiktokJob:            {base: 64 << 20, perResult: 256 << 10, results: 10},
+	teetypes.TwitterJob:           {base: 4 << 20, perResult: 16 << 10, results: 20},
+	teetypes.TwitterCredentialJob: {base: 4 << 20, perResult: 16 << 10, results: 20},
+	teetypes.TwitterApiJob:        {base: 4 << 20, perResult: 16 << 10, results: 20},
+	teetypes.TwitterApifyJob:      {base: 4 << 20, perResult: 16 << 10, results: 20},
+	teetypes.RedditJob:            {base: 4 << 20, perResult: 32 << 10, results: 10},
+	teetypes.TelemetryJob:         {base: 1 << 20},
+}
+
+// defaultMemoryCost is the memory cost of the job types which are not in memoryCosts
+var defaultMemoryCost = memoryCost{base: 8 << 20, perResult: 32 << 10, results: 20}
+
+// maxEstimatedResults caps the number of results a job is assumed to return, so absurd arguments don't overflow
+const maxEstimatedResults = 1_000_000
+
+// resultArgumentKeys are the arguments by which the job types ask for a number of results
+var resultArgumentKeys = []string{"max_results", "max_items", "max_pages", "count"}
+
+// estimateMemory returns the estimated peak memory used by a job, from its type and the number of results it asks for
+func estimateMemory(j types.Job) int64 {
+	cost, ok := memoryCosts[j.Type]
+	if !ok {
+		cost = defaultMemoryCost
+	}
+	return cost.base + cost.perResult*requestedResults(j, cost.results)
+}
+
+// requestedResults returns the number of results a job asks for, or def if it doesn't ask for a number
+func requestedResults(j types.Job, def int64) int64 {
+	results := int64(0)
+	for _, key := range resultArgumentKeys {
+		var n int64
+		switch v := j.Arguments[key].(type) {
+		case float64:
+			n = int64(v)
+		case int:
+			n = int64(v)
+		case int64:
+			n = v
+		}
+		results = max(results, min(n, maxEstimatedResults))
+	}
+	// Web jobs with a list of pages return a result for each of them
+	if urls, ok := j.Arguments["urls"].([]any); ok {
+		results = max(results, min(int64(len(urls)), maxEstimatedResults))
+	}
+	if results <= 0 {
+		return def
+	}
+	return results
+}
+
+// processRSS returns the resident set size of the worker process. Where /proc is not available, e.g. inside an
+// enclave, it falls back to the memory obtained by the Go runtime from the OS and not yet returned to it.
+func processRSS() int64 {
+	if statm, err := os.ReadFile("/proc/self/statm"); err == nil {
+		if fields := bytes.Fields(statm); len(fields) > 1 {
+			if pages, err := strconv.ParseInt(string(fields[1]), 10, 64); err == nil {
+				return pages * int64(os.Getpagesize())
+			}
+		}
+	}
+
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return int64(m.Sys - m.HeapReleased)
+}
+
+// memoryGuard keeps the memory used by the worker below MEMORY_CEILING_BYTES, since running out of memory kills the
+// whole worker, including the enclave. Every running job reserves its estimated memory. A job is started if both the
+// reservations and the current RSS leave room for its estimate, and is deferred otherwise until a running job
+// finishes. A job is always started if no other job is running, so the worker makes progress even if its RSS stays
+// high. Jobs which could never fit are rejected, see admit.
+type memoryGuard struct {
+	sync.Mutex
+	ceiling  int64
+	rss      func() int64
+	reserved map[string]int64 // The estimates of the running jobs by UUID
+	total    int64
+	deferred []deferredJob
+}
+
+func newMemoryGuard(ceiling int64) *memoryGuard {
+	return &memoryGuard{ceiling: ceiling, rss: processRSS, reserved: make(map[string]int64)}
+}
+
+// admit returns a types.AdmissionError if the job can't be executed within the ceiling, because its estimate alone
+// exceeds it or the RSS of the worker already does
+func (g *memoryGuard) admit(j types.Job) error {
+	if g.ceiling <= 0 {
+		return nil
+	}
+	if estimate := estimateMemory(j); estimate > g.ceiling {
+		return &types.AdmissionError{Reason: fmt.Sprintf("the job needs an estimated %d bytes of memory, more than the ceiling of %d bytes", estimate, g.ceiling)}
+	}
+	if rss := g.rss(); rss > g.ceiling {
+		return &types.AdmissionError{Reason: fmt.Sprintf("the worker uses %d bytes of memory, more than the ceiling of %d bytes", rss, g.ceiling)}
+	}
+	return nil
+}
+
+// acquire reserves the estimated memory of the job and returns true, or defers the job and returns false if it would
+// exceed the ceiling. High priority jobs are deferred ahead of the other jobs.
+func (g *memoryGuard) acquire(j types.Job, high bool) bool {
+	if g.ceiling <= 0 {
+		return true
+	}
+	estimate := estimateMemory(j)
+
+	g.Lock()
+	defer g.Unlock()
+
+	if g.total == 0 || (g.total+estimate <= g.ceiling && g.rss()+estimate <= g.ceiling) {
+		g.reserved[j.UUID] = estimate
+		g.total += estimate
+		return true
+	}
+
+	i := len(g.deferred)
+	if high {
+		i = 0
+		for i < len(g.deferred) && g.deferred[i].high {
+			i++
+		}
+	}
+	g.deferred = append(g.deferred, deferredJob{})
+	copy(g.deferred[i+1:], g.deferred[i:])
+	g.deferred[i] = deferredJob{job: j, high: high}
+	return false
+}
+
+// release frees the memory reserved by a finished job, and returns the deferred jobs whose estimates fit in the
+// reservations left, in order, to be queued again by the caller. They are checked against the RSS once they are
+// picked up again.
+func (g *memoryGuard) release(jobUUID string) []types.Job {
+	if g.ceiling <= 0 {
+		return nil
+	}
+
+	g.Lock()
+	defer g.Unlock()
+
+	g.total -= g.reserved[jobUUID]
+	delete(g.reserved, jobUUID)
+
+	var ready []types.Job
+	budget := g.ceiling - g.total
+	for len(g.deferred) > 0 {
+		estimate := estimateMemory(g.deferred[0].job)
+		if estimate > budget && (g.total > 0 || len(ready) > 0) {
+			break
+		}
+		budget -= estimate
+		ready = append(ready, g.deferred[0].job)
+		g.deferred = g.deferred[1:]
+	}
+	return ready
+}
+
+// deferredLen returns the number of jobs deferred for lack of memory
+func (g *memoryGuard) deferredLen() int {
+	g.Lock()
+	defer g.Unlock()
+	return len(g.deferred)
+}
+
+// deferredCounts returns the number of jobs of each type deferred for lack of memory
+func (g *memoryGuard) deferredCounts() map[teetypes.JobType]int {
+	g.Lock()
+	defer g.Unlock()
+
+	counts := make(map[teetypes.JobType]int)
+	for _, d := range g.deferred {
+		counts[d.job.Type]++
+	}
+	return counts
+}
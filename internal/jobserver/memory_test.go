@@ -0,0 +1,101 @@
+package jobserver
+
+import (
+	"context"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory guard", func() {
+	// Web jobs asking for a single page are estimated at 20 MiB
+	webJob := func(uuid string) types.Job {
+		return types.Job{UUID: uuid, Type: teetypes.WebJob, Arguments: types.JobArguments{"max_pages": float64(1)}}
+	}
+
+	It("estimates the memory of jobs from their type and the number of results they ask for", func() {
+		Expect(estimateMemory(webJob("w"))).To(Equal(int64(20 << 20)))
+		Expect(estimateMemory(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_pages": float64(3)}})).To(Equal(int64(28 << 20)))
+		Expect(estimateMemory(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"urls": []any{"https://a.com", "https://b.com", "https://c.com"}}})).To(Equal(int64(28 << 20)))
+		Expect(estimateMemory(types.Job{Type: teetypes.TwitterJob})).To(Equal(int64(4<<20 + 20*16<<10)))
+		Expect(estimateMemory(types.Job{Type: teetypes.TwitterJob, Arguments: types.JobArguments{"max_results": 100}})).To(Equal(int64(4<<20 + 100*16<<10)))
+		Expect(estimateMemory(types.Job{Type: "mastodon"})).To(Equal(int64(8<<20 + 20*32<<10)))
+	})
+
+	It("does nothing without a ceiling", func() {
+		g := newMemoryGuard(0)
+		Expect(g.admit(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_pages": float64(1e9)}})).To(Succeed())
+		Expect(g.acquire(webJob("w1"), false)).To(BeTrue())
+		Expect(g.acquire(webJob("w2"), false)).To(BeTrue())
+		Expect(g.release("w1")).To(BeEmpty())
+	})
+
+	It("defers jobs which would exceed the ceiling until a running job finishes", func() {
+		g := newMemoryGuard(50 << 20)
+		g.rss = func() int64 { return 0 }
+
+		Expect(g.acquire(webJob("w1"), false)).To(BeTrue())
+		Expect(g.acquire(webJob("w2"), false)).To(BeTrue())
+		Expect(g.acquire(webJob("w3"), false)).To(BeFalse())
+		Expect(g.acquire(webJob("h1"), true)).To(BeFalse())
+		Expect(g.deferredLen()).To(Equal(2))
+
+		ready := g.release("w1")
+		Expect(ready).To(HaveLen(1))
+		Expect(ready[0].UUID).To(Equal("h1"))
+		Expect(g.acquire(ready[0], true)).To(BeTrue())
+
+		Expect(g.release("w2")).To(HaveLen(1))
+		Expect(g.deferredLen()).To(BeZero())
+	})
+
+	It("defers jobs while the RSS leaves no room, unless no other job is running", func() {
+		g := newMemoryGuard(50 << 20)
+		g.rss = func() int64 { return 40 << 20 }
+
+		Expect(g.acquire(webJob("w1"), false)).To(BeTrue())
+		Expect(g.acquire(webJob("w2"), false)).To(BeFalse())
+
+		ready := g.release("w1")
+		Expect(ready).To(HaveLen(1))
+		Expect(g.acquire(ready[0], false)).To(BeTrue())
+	})
+
+	It("rejects jobs which can't fit below the ceiling", func() {
+		g := newMemoryGuard(50 << 20)
+		g.rss = func() int64 { return 10 << 20 }
+		Expect(g.admit(webJob("w1"))).To(Succeed())
+
+		var admissionErr *types.AdmissionError
+		Expect(g.admit(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_pages": float64(20)}})).To(BeAssignableToTypeOf(admissionErr))
+
+		g.rss = func() int64 { return 60 << 20 }
+		Expect(g.admit(webJob("w2"))).To(MatchError(ContainSubstring("more than the ceiling")))
+	})
+
+	It("executes the deferred jobs once memory is freed", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(3, config.JobConfiguration{"web_max_concurrent": 3, "memory_ceiling_bytes": 50 << 20})
+		js.memory.rss = func() int64 { return 0 }
+		web := &gatedWorker{release: make(chan struct{})}
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: web}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go js.Run(ctx)
+
+		for _, uuid := range []string{"w1", "w2", "w3"} {
+			Expect(js.dispatch(webJob(uuid), ExecutionClassInteractive)).To(Succeed())
+		}
+		Eventually(web.running.Load, "5s").Should(BeEquivalentTo(2))
+		Eventually(js.GetQueueStatus, "5s").Should(HaveField("MemoryDeferred", 1))
+
+		close(web.release)
+		Eventually(web.done.Load, "5s").Should(BeEquivalentTo(3))
+		Expect(web.peak.Load()).To(BeEquivalentTo(2))
+		Expect(js.GetQueueStatus().MemoryDeferred).To(BeZero())
+	})
+})
@@ -0,0 +1,79 @@
+package jobserver
+
+import (
+	"fmt"
+	"maps"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// validatePaginatedArgument checks that the paginated argument, if present, is a boolean
+func validatePaginatedArgument(args types.JobArguments) error {
+	v, ok := args[types.PaginatedArgumentKey]
+	if !ok || v == nil {
+		return nil
+	}
+	if _, ok := v.(bool); !ok {
+		return fmt.Errorf("%s must be a boolean, got %T", types.PaginatedArgumentKey, v)
+	}
+	return nil
+}
+
+// defaultNextCursorTTL is how long the cursor returned by a job is accepted if NEXT_CURSOR_TTL_SECONDS is not set
+const defaultNextCursorTTL = 24 * time.Hour
+
+// openNextCursor replaces the next_cursor argument of a job, if present, with the cursor of the data source it seals.
+// A cursor which was not issued by a worker for the same job type and capability, or which has expired, is rejected
+// so the job doesn't silently start over from the first page. The arguments of the job are copied, not modified.
+func (js *JobServer) openNextCursor(j types.Job) (types.Job, error) {
+	v, ok := j.Arguments[types.NextCursorArgumentKey]
+	if !ok || v == nil || v == "" {
+		return j, nil
+	}
+	sealed, ok := v.(string)
+	if !ok {
+		return j, fmt.Errorf("%s must be a string, got %T", types.NextCursorArgumentKey, v)
+	}
+	ttl := js.jobConfiguration.GetDuration("next_cursor_ttl_seconds", int(defaultNextCursorTTL/time.Second))
+	c, err := types.OpenNextCursor(sealed, j.Type, jobCapability(j), ttl, time.Now())
+	if err != nil {
+		return j, err
+	}
+	j.Arguments = maps.Clone(j.Arguments)
+	if c.Cursor == "" {
+		// The cursor continues a truncated first page
+		delete(j.Arguments, types.NextCursorArgumentKey)
+	} else {
+		j.Arguments[types.NextCursorArgumentKey] = c.Cursor
+	}
+	j.ResultOffset = c.Offset
+	return j, nil
+}
+
+// sealNextCursor returns the cursor of the data source returned by a job in a sealed NextCursor envelope, bound to the
+// job type and capability of the job. offset is the number of items of the page of the cursor which were already
+// returned, so a truncated result is continued with the same page.
+func sealNextCursor(j types.Job, cursor string, offset int, now time.Time) (string, error) {
+	if cursor == "" && offset == 0 {
+		return "", nil
+	}
+	return types.NextCursor{
+		Version:    types.NextCursorVersion,
+		JobType:    j.Type,
+		Capability: jobCapability(j),
+		Cursor:     cursor,
+		Offset:     offset,
+		IssuedAt:   now,
+	}.Seal()
+}
+
+// jobCapability returns the capability of a job, or the default capability of its job type
+func jobCapability(j types.Job) teetypes.Capability {
+	if c, ok := j.Arguments["type"].(string); ok && c != "" {
+		return teetypes.Capability(c)
+	}
+	return teetypes.JobDefaultCapabilityMap[j.Type]
+}
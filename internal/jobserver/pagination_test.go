@@ -0,0 +1,118 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// cursorWorker returns the cursor it was given as its data, and the next page as its next cursor
+type cursorWorker struct{}
+
+func (w cursorWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (w cursorWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	cursor, _ := j.Arguments[types.NextCursorArgumentKey].(string)
+	return types.JobResult{Data: []byte(cursor), NextCursor: cursor + "+"}, nil
+}
+
+var _ = Describe("Next cursors", func() {
+	var js *JobServer
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		config.MinersWhiteList = ""
+		js = NewJobServer(1, config.JobConfiguration{"next_cursor_ttl_seconds": time.Hour})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: cursorWorker{}}
+		js.jobWorkers[teetypes.RedditJob] = &jobWorkerEntry{w: cursorWorker{}}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+	})
+
+	run := func(j types.Job) types.JobResult {
+		uuid, err := js.AddJob(j)
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(res.Error).To(BeEmpty())
+		return res
+	}
+
+	It("returns sealed cursors, and executes jobs with the cursor of the data source", func() {
+		first := run(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"type": "scraper"}})
+		Expect(first.NextCursor).NotTo(BeEmpty())
+		Expect(first.NextCursor).NotTo(Equal("+"))
+
+		c, err := types.OpenNextCursor(first.NextCursor, teetypes.WebJob, "scraper", time.Hour, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Cursor).To(Equal("+"))
+
+		second := run(types.Job{Type: teetypes.WebJob, Nonce: "2", Arguments: types.JobArguments{"type": "scraper", "next_cursor": first.NextCursor}})
+		Expect(string(second.Data)).To(Equal("+"))
+		c, err = types.OpenNextCursor(second.NextCursor, teetypes.WebJob, "scraper", time.Hour, time.Now())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Cursor).To(Equal("++"))
+	})
+
+	It("rejects cursors which were not issued by the worker", func() {
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"next_cursor": "eyJvZmZzZXQiOjEwMH0="}})
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "2", Arguments: types.JobArguments{"next_cursor": 100}})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("rejects cursors issued for another job type or capability", func() {
+		first := run(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"type": "scraper"}})
+
+		_, err := js.AddJob(types.Job{Type: teetypes.RedditJob, Nonce: "2", Arguments: types.JobArguments{"type": "scraper", "next_cursor": first.NextCursor}})
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "3", Arguments: types.JobArguments{"type": "crawler", "next_cursor": first.NextCursor}})
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+
+		_, err = js.EstimateJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"type": "crawler", "next_cursor": first.NextCursor}})
+		Expect(err).To(MatchError(types.ErrInvalidCursor))
+	})
+
+	It("rejects expired cursors", func() {
+		sealed, err := types.NextCursor{
+			Version:    types.NextCursorVersion,
+			JobType:    teetypes.WebJob,
+			Capability: "scraper",
+			Cursor:     "+",
+			IssuedAt:   time.Now().Add(-2 * time.Hour),
+		}.Seal()
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"type": "scraper", "next_cursor": sealed}})
+		Expect(err).To(MatchError(types.ErrExpiredCursor))
+	})
+
+	It("doesn't modify the arguments of the submitted job", func() {
+		first := run(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"type": "scraper"}})
+
+		args := types.JobArguments{"type": "scraper", "next_cursor": first.NextCursor}
+		run(types.Job{Type: teetypes.WebJob, Nonce: "2", Arguments: args})
+		Expect(args["next_cursor"]).To(Equal(first.NextCursor))
+	})
+})
@@ -0,0 +1,128 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// echoLLM answers every item with the prompt and the item
+type echoLLM struct {
+	items *[]json.RawMessage
+	err   error
+}
+
+func (e echoLLM) ProcessItems(workerID string, items []json.RawMessage, prompt, model string, maxTokens uint) ([]*teetypes.LLMProcessorResult, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	*e.items = items
+	res := make([]*teetypes.LLMProcessorResult, len(items))
+	for i, item := range items {
+		res[i] = &teetypes.LLMProcessorResult{LLMResponse: fmt.Sprintf("%s %s: %s", model, prompt, item)}
+	}
+	return res, nil
+}
+
+var _ = Describe("Post-processing", func() {
+	var (
+		js        *JobServer
+		processed []json.RawMessage
+		llmErr    error
+	)
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		processed, llmErr = nil, nil
+
+		original := jobs.NewLLMItemProcessor
+		DeferCleanup(func() { jobs.NewLLMItemProcessor = original })
+		jobs.NewLLMItemProcessor = func(string, config.LlmConfig, *stats.StatsCollector, ...client.Option) (jobs.LLMItemProcessor, error) {
+			return echoLLM{items: &processed, err: llmErr}, nil
+		}
+
+		js = NewJobServer(1, config.JobConfiguration{"apify_api_key": "apify", "gemini_api_key": "gemini"})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: itemsWorker{items: 2}}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+	})
+
+	run := func(args types.JobArguments) types.JobResult {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: args})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		return res
+	}
+
+	It("returns both the raw and the processed result", func() {
+		res := run(types.JobArguments{"post_process": map[string]any{"prompt": "summarize", "model": "gemini-2.0-flash"}})
+		Expect(res.Error).To(BeEmpty())
+
+		var pp types.PostProcessedResult
+		Expect(json.Unmarshal(res.Data, &pp)).To(Succeed())
+		Expect(ids(pp.Raw)).To(Equal([]string{"0", "1"}))
+		Expect(pp.Processed).To(Equal([]string{
+			`gemini-2.0-flash summarize: {"id":"0","text":"tweet 0"}`,
+			`gemini-2.0-flash summarize: {"id":"1","text":"tweet 1"}`,
+		}))
+		Expect(pp.Report).NotTo(BeNil())
+		Expect(pp.Report.Chunks).To(Equal(1))
+	})
+
+	It("only sends redacted items to the LLM", func() {
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: piiWorker{}}
+		res := run(types.JobArguments{"redact": "strip", "post_process": map[string]any{"prompt": "summarize"}})
+		Expect(res.Error).To(BeEmpty())
+		Expect(processed).To(HaveLen(1))
+		Expect(string(processed[0])).NotTo(ContainSubstring("jane@example.com"))
+	})
+
+	It("fails the job if the LLM fails", func() {
+		llmErr = errors.New("quota exceeded")
+		res := run(types.JobArguments{"post_process": map[string]any{"prompt": "summarize"}})
+		Expect(res.Error).To(ContainSubstring("quota exceeded"))
+		Expect(res.Data).To(BeEmpty())
+	})
+
+	It("rejects invalid post-processing requests", func() {
+		for _, invalid := range []any{
+			"summarize",
+			map[string]any{},
+			map[string]any{"prompt": "summarize", "max_tokens": -1.0},
+			map[string]any{"prompt": "summarize", "temperature": 0.5},
+		} {
+			_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"post_process": invalid}})
+			Expect(err).To(HaveOccurred(), "%v", invalid)
+		}
+	})
+
+	It("rejects post-processing if the worker has no LLM keys", func() {
+		js := NewJobServer(1, config.JobConfiguration{"apify_api_key": "apify"})
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"post_process": map[string]any{"prompt": "summarize"}}})
+		Expect(err).To(MatchError(jobs.ErrPostProcessNotConfigured))
+	})
+})
+
+// piiWorker returns a single item with an email address
+type piiWorker struct{}
+
+func (piiWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (piiWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	return types.JobResult{Data: []byte(`[{"text":"contact jane@example.com"}]`)}, nil
+}
@@ -0,0 +1,77 @@
+package jobserver
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// Priority is a hint of the client about how urgent a job is
+type Priority string
+
+const (
+	// PriorityHigh jobs are executed before any other queued job, if their client is allowed to use it
+	PriorityHigh Priority = "high"
+	// PriorityNormal jobs are executed in the order they arrive. This is the default.
+	PriorityNormal Priority = "normal"
+	// PriorityLow jobs are executed like economy jobs, i.e. only when the worker is idle
+	PriorityLow Priority = "low"
+)
+
+// priorityArgumentKey is the job argument used by clients to set the priority of a job
+const priorityArgumentKey = "priority"
+
+// priorityFromArguments extracts the priority from the job arguments
+func priorityFromArguments(args types.JobArguments) (Priority, error) {
+	v, ok := args[priorityArgumentKey]
+	if !ok || v == nil {
+		return PriorityNormal, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s must be a string, got %T", priorityArgumentKey, v)
+	}
+
+	switch Priority(s) {
+	case "", PriorityNormal:
+		return PriorityNormal, nil
+	case PriorityHigh, PriorityLow:
+		return Priority(s), nil
+	default:
+		return "", fmt.Errorf("invalid priority %q, valid priorities are %q, %q and %q", s, PriorityHigh, PriorityNormal, PriorityLow)
+	}
+}
+
+// priorityManager combines the priority asked for by a job with the list of worker IDs which are allowed to jump
+// the queue
+type priorityManager struct {
+	workerIDs []string
+}
+
+func newPriorityManager(workerIDs []string) *priorityManager {
+	return &priorityManager{workerIDs: workerIDs}
+}
+
+// priority returns the priority a job is executed with. High priority is only granted to the jobs of the allowed
+// workers, other jobs asking for it are executed with normal priority.
+func (pm *priorityManager) priority(j types.Job) Priority {
+	p, _ := priorityFromArguments(j.Arguments)
+	if p == PriorityHigh && !slices.Contains(pm.workerIDs, j.WorkerID) {
+		logrus.Debugf("Worker %s is not allowed to submit high priority jobs, executing job %s with normal priority", j.WorkerID, j.UUID)
+		return PriorityNormal
+	}
+	return p
+}
+
+// queue hands an interactive job to the next available worker, ahead of the other queued jobs if it has high priority
+func (js *JobServer) queue(j types.Job) {
+	js.waiting.add(j.Type, 1)
+	if js.priorities.priority(j) == PriorityHigh {
+		js.priorityJobChan <- j
+		return
+	}
+	js.jobChan <- j
+}
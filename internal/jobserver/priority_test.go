@@ -0,0 +1,99 @@
+package jobserver
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// orderWorker records the order in which jobs are executed
+type orderWorker struct {
+	flakyWorker
+	sync.Mutex
+	order []string
+}
+
+func (o *orderWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	o.Lock()
+	defer o.Unlock()
+	o.order = append(o.order, j.UUID)
+	return types.JobResult{Data: []byte("ok")}, nil
+}
+
+func (o *orderWorker) executed() []string {
+	o.Lock()
+	defer o.Unlock()
+	return o.order
+}
+
+var _ = Describe("Job priority", func() {
+	It("parses the priority", func() {
+		p, err := priorityFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(PriorityNormal))
+
+		p, err = priorityFromArguments(types.JobArguments{"priority": "high"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(PriorityHigh))
+
+		_, err = priorityFromArguments(types.JobArguments{"priority": "urgent"})
+		Expect(err).To(HaveOccurred())
+		_, err = priorityFromArguments(types.JobArguments{"priority": 1})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("executes low priority jobs as economy jobs", func() {
+		class, err := executionClassFromArguments(types.JobArguments{"priority": "low"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(class).To(Equal(ExecutionClassEconomy))
+
+		class, err = executionClassFromArguments(types.JobArguments{"priority": "high"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(class).To(Equal(ExecutionClassInteractive))
+
+		_, err = executionClassFromArguments(types.JobArguments{"priority": "low", "execution_class": "interactive"})
+		Expect(err).To(HaveOccurred())
+		_, err = executionClassFromArguments(types.JobArguments{"priority": "high", "execution_class": "economy"})
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("only grants high priority to the allowed workers", func() {
+		pm := newPriorityManager([]string{"miner1"})
+		high := types.JobArguments{"priority": "high"}
+		Expect(pm.priority(types.Job{WorkerID: "miner1", Arguments: high})).To(Equal(PriorityHigh))
+		Expect(pm.priority(types.Job{WorkerID: "miner2", Arguments: high})).To(Equal(PriorityNormal))
+		Expect(pm.priority(types.Job{WorkerID: "miner1"})).To(Equal(PriorityNormal))
+	})
+
+	It("executes high priority jobs before the other queued jobs", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{"priority_worker_ids": []string{"miner1"}})
+		w := &orderWorker{}
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+
+		for _, uuid := range []string{"a", "b"} {
+			Expect(js.dispatch(types.Job{UUID: uuid, Type: teetypes.WebJob}, ExecutionClassInteractive)).To(Succeed())
+		}
+		Expect(js.dispatch(types.Job{
+			UUID:      "urgent",
+			Type:      teetypes.WebJob,
+			WorkerID:  "miner1",
+			Arguments: types.JobArguments{"priority": "high"},
+		}, ExecutionClassInteractive)).To(Succeed())
+		// Let the jobs reach the queue before the worker starts
+		time.Sleep(50 * time.Millisecond)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go js.worker(ctx)
+
+		Eventually(w.executed, "5s").Should(HaveLen(3))
+		Expect(w.executed()[0]).To(Equal("urgent"))
+	})
+})
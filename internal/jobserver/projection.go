@@ -0,0 +1,143 @@
+package jobserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// fieldsArgumentKey is the job argument used by clients to get only some of the fields of the items of the result
+const fieldsArgumentKey = "fields"
+
+// maxProjectionFields is the maximum number of fields which can be selected
+const maxProjectionFields = 100
+
+// Projection selects fields of the items of a result. Each key is the JSON key of a field, and the value is nil if the
+// whole field is kept, or the projection of its own fields for nested fields such as `public_metrics.like_count`.
+type Projection map[string]Projection
+
+// projectionFromArguments extracts the projection from the job arguments, which is either a list of fields or a
+// comma-separated string. It returns nil if no projection was requested.
+func projectionFromArguments(args types.JobArguments) (Projection, error) {
+	v, ok := args[fieldsArgumentKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	var fields []string
+	switch v := v.(type) {
+	case string:
+		fields = strings.Split(v, ",")
+	case []any:
+		for _, f := range v {
+			s, ok := f.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s must be a list of strings, got an element of type %T", fieldsArgumentKey, f)
+			}
+			fields = append(fields, s)
+		}
+	case []string:
+		fields = v
+	default:
+		return nil, fmt.Errorf("%s must be a list of field names or a comma-separated string, got %T", fieldsArgumentKey, v)
+	}
+
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s must select at least one field", fieldsArgumentKey)
+	}
+	if len(fields) > maxProjectionFields {
+		return nil, fmt.Errorf("%s can select at most %d fields, got %d", fieldsArgumentKey, maxProjectionFields, len(fields))
+	}
+
+	p := Projection{}
+	for _, f := range fields {
+		path := strings.Split(strings.TrimSpace(f), ".")
+		for _, key := range path {
+			if key == "" {
+				return nil, fmt.Errorf("invalid %s entry %q, fields are JSON keys separated by dots", fieldsArgumentKey, f)
+			}
+		}
+		p.add(path)
+	}
+	return p, nil
+}
+
+// add adds a field to the projection. Selecting a field keeps all of it, even if some of its fields were also selected.
+func (p Projection) add(path []string) {
+	child, ok := p[path[0]]
+	if len(path) == 1 {
+		p[path[0]] = nil
+		return
+	}
+	if ok && child == nil {
+		return
+	}
+	if !ok {
+		child = Projection{}
+		p[path[0]] = child
+	}
+	child.add(path[1:])
+}
+
+// Apply returns a JSON-encoded job result with only the selected fields of its items. Results which are a single
+// object, e.g. a profile, are projected as well, and nested arrays have each of their elements projected. Results
+// which are neither a JSON array nor an object are returned as they are. Values are copied verbatim, so large
+// numbers such as tweet IDs keep their precision.
+func (p Projection) Apply(data []byte) ([]byte, error) {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || (trimmed[0] != '[' && trimmed[0] != '{') || !json.Valid(trimmed) {
+		return data, nil
+	}
+
+	projected, err := p.project(trimmed)
+	if err != nil {
+		return nil, fmt.Errorf("error projecting result: %w", err)
+	}
+	return projected, nil
+}
+
+// project projects a JSON value. Values which are neither arrays nor objects, e.g. when a nested field was selected
+// in a field which is a string, are kept as they are.
+func (p Projection) project(value json.RawMessage) (json.RawMessage, error) {
+	switch value[0] {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(value, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			projected, err := p.project(bytes.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			items[i] = projected
+		}
+		return json.Marshal(items)
+
+	case '{':
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(value, &fields); err != nil {
+			return nil, err
+		}
+		projected := make(map[string]json.RawMessage, len(p))
+		for key, child := range p {
+			v, ok := fields[key]
+			if !ok {
+				continue
+			}
+			if child != nil && len(bytes.TrimSpace(v)) > 0 {
+				var err error
+				if v, err = child.project(bytes.TrimSpace(v)); err != nil {
+					return nil, err
+				}
+			}
+			projected[key] = v
+		}
+		return json.Marshal(projected)
+	}
+
+	return value, nil
+}
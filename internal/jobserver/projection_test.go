@@ -0,0 +1,92 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Projection", func() {
+	It("parses the fields argument", func() {
+		p, err := projectionFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(BeNil())
+
+		p, err = projectionFromArguments(types.JobArguments{"fields": []any{"tweet_id", "text", "public_metrics.like_count"}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(Projection{"tweet_id": nil, "text": nil, "public_metrics": Projection{"like_count": nil}}))
+
+		p, err = projectionFromArguments(types.JobArguments{"fields": "id, text,crawl.depth,crawl"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p).To(Equal(Projection{"id": nil, "text": nil, "crawl": nil}))
+
+		for _, invalid := range []any{
+			42,
+			[]any{},
+			[]any{"id", 42},
+			"id,,text",
+			[]any{"public_metrics."},
+			make([]any, maxProjectionFields+1),
+		} {
+			_, err := projectionFromArguments(types.JobArguments{"fields": invalid})
+			Expect(err).To(HaveOccurred(), "%v", invalid)
+		}
+	})
+
+	It("keeps only the selected fields of every item", func() {
+		p := Projection{"tweet_id": nil, "id": nil, "public_metrics": Projection{"like_count": nil}, "photos": Projection{"url": nil}}
+		projected, err := p.Apply([]byte(`[
+			{"id": 1852340145283645440, "tweet_id": "1", "text": "gm", "public_metrics": {"like_count": 3, "retweet_count": 1}, "photos": [{"id": "p", "url": "https://example.com/p.jpg"}]},
+			{"tweet_id": "2", "text": "gn", "public_metrics": null}
+		]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(projected).To(MatchJSON(`[
+			{"id": 1852340145283645440, "tweet_id": "1", "public_metrics": {"like_count": 3}, "photos": [{"url": "https://example.com/p.jpg"}]},
+			{"tweet_id": "2", "public_metrics": null}
+		]`))
+		// Large numbers are copied verbatim rather than going through float64
+		Expect(string(projected)).To(ContainSubstring("1852340145283645440"))
+	})
+
+	It("projects results which are a single object", func() {
+		projected, err := Projection{"username": nil}.Apply([]byte(`{"username":"masa","followers_count":42}`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(projected).To(MatchJSON(`{"username":"masa"}`))
+	})
+
+	It("leaves results which are not arrays or objects untouched", func() {
+		for _, data := range []string{`"transcript"`, `42`, `not json`, ``} {
+			projected, err := Projection{"id": nil}.Apply([]byte(data))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(projected)).To(Equal(data))
+		}
+	})
+
+	It("projects the results of jobs", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: itemsWorker{items: 3}}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "1", Arguments: types.JobArguments{"fields": 42}})
+		Expect(err).To(HaveOccurred())
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "2", Arguments: types.JobArguments{"fields": []any{"id"}}})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(res.Error).To(BeEmpty())
+
+		var v []map[string]any
+		Expect(json.Unmarshal(res.Data, &v)).To(Succeed())
+		Expect(v).To(Equal([]map[string]any{{"id": "0"}, {"id": "1"}, {"id": "2"}}))
+	})
+})
@@ -0,0 +1,75 @@
+package jobserver
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/versioning"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// validateProvenanceArgument checks that the provenance argument, if present, is a boolean
+func validateProvenanceArgument(args types.JobArguments) error {
+	v, ok := args[types.ProvenanceArgumentKey]
+	if !ok || v == nil {
+		return nil
+	}
+	if _, ok := v.(bool); !ok {
+		return fmt.Errorf("%s must be a boolean, got %T", types.ProvenanceArgumentKey, v)
+	}
+	return nil
+}
+
+// resultProvenance describes how the result of a job was produced. Workers which don't record the auth source they
+// used are assumed to use the only one they advertise for the capability, if there is exactly one.
+func (js *JobServer) resultProvenance(j types.Job, startedAt, finishedAt time.Time) *types.ResultProvenance {
+	capability := teetypes.Capability(stats.DimensionsForJob(j).Capability)
+
+	authSource := j.Provenance.AuthSource()
+	if authSource == "" {
+		var sources []types.AuthSource
+		for _, detail := range js.capabilityDetails()[j.Type] {
+			if detail.Capability == capability && !slices.Contains(sources, detail.AuthSource) {
+				sources = append(sources, detail.AuthSource)
+			}
+		}
+		if len(sources) == 1 {
+			authSource = sources[0]
+		}
+	}
+
+	return &types.ResultProvenance{
+		JobType:            j.Type,
+		Capability:         capability,
+		AuthSource:         authSource,
+		LowerTrust:         authSource.LowerTrust(),
+		ActorRunIDs:        j.Provenance.ActorRunIDs(),
+		StartedAt:          startedAt.UTC(),
+		FinishedAt:         finishedAt.UTC(),
+		WorkerID:           tee.CurrentWorkerID(),
+		WorkerVersion:      versioning.TEEWorkerVersion,
+		ApplicationVersion: versioning.ApplicationVersion,
+		CapabilityVersion:  capabilityVersion(js.GetWorkerCapabilities()),
+	}
+}
+
+// capabilityVersion returns a short hash identifying a set of capabilities, which doesn't depend on their order
+func capabilityVersion(caps teetypes.WorkerCapabilities) string {
+	var entries []string
+	for jobType, capabilities := range caps {
+		for _, c := range capabilities {
+			entries = append(entries, string(jobType)+"/"+string(c))
+		}
+	}
+	slices.Sort(entries)
+
+	sum := sha256.Sum256([]byte(strings.Join(entries, "\n")))
+	return hex.EncodeToString(sum[:8])
+}
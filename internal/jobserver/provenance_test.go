@@ -0,0 +1,114 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// provenanceWorker records an auth source and an actor run in the provenance of the job
+type provenanceWorker struct {
+	source types.AuthSource
+}
+
+func (p *provenanceWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{teetypes.WebJob: {"scraper"}}
+}
+
+func (p *provenanceWorker) GetCapabilityDetails() types.CapabilityDetails {
+	return types.NewCapabilityDetails(p.GetStructuredCapabilities(), types.AuthSourceApify)
+}
+
+func (p *provenanceWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	j.Provenance.SetAuthSource(p.source)
+	j.Provenance.AddActorRun("run-1")
+	return types.JobResult{Data: []byte(`{}`)}, nil
+}
+
+var _ = Describe("Provenance", func() {
+	It("validates the provenance argument", func() {
+		Expect(validateProvenanceArgument(types.JobArguments{})).To(Succeed())
+		Expect(validateProvenanceArgument(types.JobArguments{types.ProvenanceArgumentKey: true})).To(Succeed())
+		Expect(validateProvenanceArgument(types.JobArguments{types.ProvenanceArgumentKey: "yes"})).NotTo(Succeed())
+	})
+
+	It("validates the paginated argument", func() {
+		Expect(validatePaginatedArgument(types.JobArguments{types.PaginatedArgumentKey: false})).To(Succeed())
+		Expect(validatePaginatedArgument(types.JobArguments{types.PaginatedArgumentKey: "yes"})).NotTo(Succeed())
+	})
+
+	It("identifies capabilities independently of their order", func() {
+		a := capabilityVersion(teetypes.WorkerCapabilities{teetypes.WebJob: {"scraper"}, teetypes.TiktokJob: {"transcription", "searchbyquery"}})
+		b := capabilityVersion(teetypes.WorkerCapabilities{teetypes.TiktokJob: {"searchbyquery", "transcription"}, teetypes.WebJob: {"scraper"}})
+		Expect(a).To(Equal(b))
+		Expect(a).NotTo(Equal(capabilityVersion(teetypes.WorkerCapabilities{teetypes.WebJob: {"scraper"}})))
+	})
+
+	Context("when executing jobs", func() {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+			js     *JobServer
+		)
+
+		BeforeEach(func() {
+			config.MinersWhiteList = ""
+			ctx, cancel = context.WithCancel(context.Background())
+			js = NewJobServer(1, config.JobConfiguration{})
+			go js.Run(ctx)
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		runJob := func(w worker) types.JobResult {
+			js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+
+			args := types.JobArguments{"type": "scraper", types.ProvenanceArgumentKey: true}
+			uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: args, Nonce: time.Now().String()})
+			Expect(err).NotTo(HaveOccurred())
+
+			var res types.JobResult
+			Eventually(func() bool {
+				var ok bool
+				res, ok = js.GetJobResult(uuid)
+				return ok
+			}, 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+			return res
+		}
+
+		It("records the provenance reported by the worker", func() {
+			res := runJob(&provenanceWorker{source: types.AuthSourceCredential})
+			Expect(res.Error).To(BeEmpty())
+
+			p := res.Provenance
+			Expect(p).NotTo(BeNil())
+			Expect(p.JobType).To(Equal(teetypes.WebJob))
+			Expect(p.Capability).To(Equal(teetypes.Capability("scraper")))
+			Expect(p.AuthSource).To(Equal(types.AuthSourceCredential))
+			Expect(p.ActorRunIDs).To(Equal([]string{"run-1"}))
+			Expect(p.FinishedAt).NotTo(BeTemporally("<", p.StartedAt))
+			Expect(p.CapabilityVersion).To(Equal(capabilityVersion(js.GetWorkerCapabilities())))
+		})
+
+		It("flags results obtained through lower trust auth sources", func() {
+			res := runJob(&provenanceWorker{source: types.AuthSourceCredential})
+			Expect(res.Provenance.LowerTrust).To(BeFalse())
+
+			res = runJob(&provenanceWorker{source: types.AuthSourceSyndication})
+			Expect(res.Provenance.AuthSource).To(Equal(types.AuthSourceSyndication))
+			Expect(res.Provenance.LowerTrust).To(BeTrue())
+		})
+
+		It("derives the auth source from the capabilities of the worker", func() {
+			res := runJob(&provenanceWorker{})
+			Expect(res.Provenance.AuthSource).To(Equal(types.AuthSourceApify))
+		})
+	})
+})
@@ -0,0 +1,178 @@
+package jobserver
+
+import (
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+const (
+	jobsQuotaWindow        = time.Hour
+	resultBytesQuotaWindow = 24 * time.Hour
+)
+
+// quotaWindow counts the usage of a quota in a fixed window, which starts with the first use after the previous
+// window has ended
+type quotaWindow struct {
+	start time.Time
+	used  int64
+}
+
+// current returns the usage in the window at the given time, resetting the window if it has ended
+func (w *quotaWindow) current(now time.Time, length time.Duration) int64 {
+	if !w.start.IsZero() && now.Sub(w.start) >= length {
+		*w = quotaWindow{}
+	}
+	return w.used
+}
+
+func (w *quotaWindow) add(n int64, now time.Time, length time.Duration) {
+	w.current(now, length)
+	if w.start.IsZero() {
+		w.start = now
+	}
+	w.used += n
+}
+
+// resetAt returns when the window ends, or nil if it has not started
+func (w *quotaWindow) resetAt(length time.Duration) *time.Time {
+	if w.start.IsZero() {
+		return nil
+	}
+	t := w.start.Add(length)
+	return &t
+}
+
+type minerQuota struct {
+	key         config.MinerAPIKey
+	jobs        quotaWindow
+	resultBytes quotaWindow
+	rejected    int
+}
+
+// minerQuotas enforces the quotas of the miners with an API key in MINER_API_KEYS, identified by their worker ID:
+// the number of jobs they submit per hour, and the size of the results of their jobs per day. Jobs of other clients
+// are not limited. Results are counted once they are stored, so a miner may exceed its result quota with the jobs
+// which are running when it is reached; jobs submitted after that are rejected until the window resets.
+type minerQuotas struct {
+	sync.Mutex
+	miners map[string]*minerQuota
+}
+
+func newMinerQuotas(keys []config.MinerAPIKey) *minerQuotas {
+	q := &minerQuotas{miners: make(map[string]*minerQuota, len(keys))}
+	for _, k := range keys {
+		q.miners[k.Miner] = &minerQuota{key: k}
+	}
+	return q
+}
+
+// admit counts a job submitted by the miner, or returns a types.QuotaError if the miner has used up one of its quotas
+func (q *minerQuotas) admit(miner string, now time.Time) error {
+	q.Lock()
+	defer q.Unlock()
+	m, ok := q.miners[miner]
+	if !ok {
+		return nil
+	}
+
+	if err := m.exceeded(miner, now); err != nil {
+		m.rejected++
+		return err
+	}
+
+	m.jobs.add(1, now, jobsQuotaWindow)
+	return nil
+}
+
+// refund takes back a job counted by admit which was not accepted after all
+func (q *minerQuotas) refund(miner string, now time.Time) {
+	q.Lock()
+	defer q.Unlock()
+	if m, ok := q.miners[miner]; ok && m.jobs.current(now, jobsQuotaWindow) > 0 {
+		m.jobs.used--
+	}
+}
+
+// check returns a types.QuotaError if the miner has used up one of its quotas, without counting a job
+func (q *minerQuotas) check(miner string, now time.Time) error {
+	q.Lock()
+	defer q.Unlock()
+	m, ok := q.miners[miner]
+	if !ok {
+		return nil
+	}
+	return m.exceeded(miner, now)
+}
+
+// exceeded returns a types.QuotaError if one of the quotas is used up. It must be called with the minerQuotas lock held.
+func (m *minerQuota) exceeded(miner string, now time.Time) error {
+	if limit := int64(m.key.JobsPerHour); limit > 0 && m.jobs.current(now, jobsQuotaWindow) >= limit {
+		return &types.QuotaError{Miner: miner, Quota: types.QuotaJobsPerHour, Limit: limit, ResetAt: *m.jobs.resetAt(jobsQuotaWindow)}
+	}
+	if limit := m.key.ResultBytesPerDay; limit > 0 && m.resultBytes.current(now, resultBytesQuotaWindow) >= limit {
+		return &types.QuotaError{Miner: miner, Quota: types.QuotaResultBytesPerDay, Limit: limit, ResetAt: *m.resultBytes.resetAt(resultBytesQuotaWindow)}
+	}
+	return nil
+}
+
+// maxRecurringJobs returns the number of recurring jobs the miner may have scheduled, or 0 if it is not limited
+func (q *minerQuotas) maxRecurringJobs(miner string) int {
+	q.Lock()
+	defer q.Unlock()
+	if m, ok := q.miners[miner]; ok {
+		return m.key.MaxRecurringJobs
+	}
+	return 0
+}
+
+// addResultBytes counts the size of a result of a job of the miner
+func (q *minerQuotas) addResultBytes(miner string, n int, now time.Time) {
+	q.Lock()
+	defer q.Unlock()
+	if m, ok := q.miners[miner]; ok && n > 0 {
+		m.resultBytes.add(int64(n), now, resultBytesQuotaWindow)
+	}
+}
+
+// usage returns the usage of the quotas of each miner with an API key
+func (q *minerQuotas) usage(now time.Time) map[string]types.QuotaUsage {
+	q.Lock()
+	defer q.Unlock()
+	if len(q.miners) == 0 {
+		return nil
+	}
+
+	usage := make(map[string]types.QuotaUsage, len(q.miners))
+	for miner, m := range q.miners {
+		usage[miner] = types.QuotaUsage{
+			JobsPerHour:        m.key.JobsPerHour,
+			Jobs:               int(m.jobs.current(now, jobsQuotaWindow)),
+			JobsResetAt:        m.jobs.resetAt(jobsQuotaWindow),
+			ResultBytesPerDay:  m.key.ResultBytesPerDay,
+			ResultBytes:        m.resultBytes.current(now, resultBytesQuotaWindow),
+			ResultBytesResetAt: m.resultBytes.resetAt(resultBytesQuotaWindow),
+			Rejected:           m.rejected,
+		}
+	}
+	return usage
+}
+
+// AdmitDelegatedJob counts a job which is delegated to a peer worker against the quotas of its miner, like a job
+// executed by this worker, or returns a types.QuotaError if the miner has used up one of them. The peer only sees the
+// API key of this worker, so it can't enforce the quotas of the miner itself.
+func (js *JobServer) AdmitDelegatedJob(miner string) error {
+	return js.quotas.admit(miner, time.Now())
+}
+
+// RefundDelegatedJob takes back a job counted by AdmitDelegatedJob which no peer accepted
+func (js *JobServer) RefundDelegatedJob(miner string) {
+	js.quotas.refund(miner, time.Now())
+}
+
+// GetQuotaUsage returns the usage of the quotas of each miner with an API key, which is reported in telemetry
+func (js *JobServer) GetQuotaUsage() map[string]types.QuotaUsage {
+	return js.quotas.usage(time.Now())
+}
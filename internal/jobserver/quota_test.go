@@ -0,0 +1,117 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Miner quotas", func() {
+	It("limits the jobs of a miner per hour", func() {
+		q := newMinerQuotas([]config.MinerAPIKey{{Miner: "miner1", Key: "key1", JobsPerHour: 2}})
+		now := time.Now()
+
+		Expect(q.admit("miner1", now)).To(Succeed())
+		Expect(q.admit("miner1", now.Add(time.Minute))).To(Succeed())
+
+		err := q.admit("miner1", now.Add(2*time.Minute))
+		var quotaErr *types.QuotaError
+		Expect(errors.As(err, &quotaErr)).To(BeTrue())
+		Expect(quotaErr.Quota).To(Equal(types.QuotaJobsPerHour))
+		Expect(quotaErr.Limit).To(Equal(int64(2)))
+		Expect(quotaErr.ResetAt).To(Equal(now.Add(time.Hour)))
+		Expect(quotaErr.RetryAfterSeconds(now.Add(2 * time.Minute))).To(Equal(58 * 60))
+
+		// Other clients are not limited, and the window resets after an hour
+		Expect(q.admit("other", now)).To(Succeed())
+		Expect(q.admit("miner1", now.Add(time.Hour))).To(Succeed())
+
+		usage := q.usage(now.Add(time.Hour))
+		Expect(usage).To(HaveLen(1))
+		Expect(usage["miner1"].Jobs).To(Equal(1))
+		Expect(usage["miner1"].JobsPerHour).To(Equal(2))
+		Expect(usage["miner1"].Rejected).To(Equal(1))
+	})
+
+	It("rejects the jobs of a miner which used its result quota", func() {
+		q := newMinerQuotas([]config.MinerAPIKey{{Miner: "miner1", Key: "key1", ResultBytesPerDay: 1000}})
+		now := time.Now()
+
+		Expect(q.admit("miner1", now)).To(Succeed())
+		q.addResultBytes("miner1", 1200, now)
+
+		err := q.admit("miner1", now.Add(time.Hour))
+		var quotaErr *types.QuotaError
+		Expect(errors.As(err, &quotaErr)).To(BeTrue())
+		Expect(quotaErr.Quota).To(Equal(types.QuotaResultBytesPerDay))
+		Expect(quotaErr.ResetAt).To(Equal(now.Add(24 * time.Hour)))
+
+		Expect(q.usage(now)["miner1"].ResultBytes).To(Equal(int64(1200)))
+		Expect(q.admit("miner1", now.Add(24*time.Hour))).To(Succeed())
+	})
+
+	It("does not report usage without miner API keys", func() {
+		Expect(newMinerQuotas(nil).usage(time.Now())).To(BeNil())
+	})
+
+	Context("when submitting jobs", func() {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			config.MinersWhiteList = ""
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		It("rejects the jobs of a miner over its quota, and allows them to be submitted again", func() {
+			js := NewJobServer(1, config.JobConfiguration{"miner_api_keys": []string{"miner1:key1:2:0"}})
+			go js.Run(ctx)
+
+			job := func(n int) types.Job {
+				return types.Job{Type: teetypes.WebJob, WorkerID: "miner1", Nonce: fmt.Sprintf("quota-%d", n)}
+			}
+			for i := 0; i < 2; i++ {
+				_, err := js.SubmitJob(job(i))
+				Expect(err).NotTo(HaveOccurred())
+			}
+
+			_, err := js.SubmitJob(job(2))
+			var quotaErr *types.QuotaError
+			Expect(errors.As(err, &quotaErr)).To(BeTrue())
+			Expect(quotaErr.Miner).To(Equal("miner1"))
+
+			// The nonce of the rejected job was not used up
+			_, err = js.SubmitJob(job(2))
+			Expect(errors.As(err, &quotaErr)).To(BeTrue())
+
+			usage := js.GetQuotaUsage()
+			Expect(usage["miner1"].Jobs).To(Equal(2))
+			Expect(usage["miner1"].Rejected).To(Equal(2))
+		})
+
+		It("does not count the jobs of a miner which are not admitted", func() {
+			js := NewJobServer(1, config.JobConfiguration{"miner_api_keys": []string{"miner1:key1:2:0"}, "memory_ceiling_bytes": 50 << 20})
+			go js.Run(ctx)
+
+			// The estimated memory of the job is above the ceiling
+			_, err := js.SubmitJob(types.Job{Type: teetypes.WebJob, WorkerID: "miner1", Nonce: "quota-big", Arguments: types.JobArguments{"max_pages": float64(20)}})
+			var admissionErr *types.AdmissionError
+			Expect(errors.As(err, &admissionErr)).To(BeTrue())
+
+			Expect(js.GetQuotaUsage()["miner1"].Jobs).To(Equal(0))
+		})
+	})
+})
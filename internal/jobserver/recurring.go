@@ -0,0 +1,223 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// scheduleArgumentKey is the job argument used by clients to make a job recurring
+const scheduleArgumentKey = "schedule"
+
+const (
+	defaultMaxRecurringJobs   = 100
+	recurringDispatchInterval = time.Second
+)
+
+var (
+	ErrTooManyRecurringJobs = errors.New("too many recurring jobs")
+	ErrRecurringJobNotFound = errors.New("recurring job not found")
+)
+
+// scheduleFromArguments extracts the schedule from the job arguments. It returns nil if the job is not recurring.
+func scheduleFromArguments(args types.JobArguments) (Schedule, error) {
+	v, ok := args[scheduleArgumentKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return nil, fmt.Errorf("%s must be a string, got %T", scheduleArgumentKey, v)
+	}
+	if s == "" {
+		return nil, nil
+	}
+
+	return ParseSchedule(s)
+}
+
+type recurringJob struct {
+	job      types.Job
+	class    ExecutionClass
+	schedule Schedule
+	next     time.Time
+	running  bool
+	latest   *types.JobResult
+}
+
+// recurringJobs holds the jobs which are re-executed on a schedule, together with their latest result.
+// A run is skipped if the previous one has not finished yet.
+type recurringJobs struct {
+	sync.Mutex
+	jobs    map[string]*recurringJob
+	maxSize int
+}
+
+func newRecurringJobs(maxSize int) *recurringJobs {
+	if maxSize <= 0 {
+		maxSize = defaultMaxRecurringJobs
+	}
+	return &recurringJobs{jobs: make(map[string]*recurringJob), maxSize: maxSize}
+}
+
+// add registers a job whose first run has just been dispatched. Unless maxPerRequester is 0, the requester of the job
+// may have at most that many recurring jobs, so a single miner can't take all of them.
+func (r *recurringJobs) add(j types.Job, class ExecutionClass, s Schedule, now time.Time, maxPerRequester int) error {
+	r.Lock()
+	defer r.Unlock()
+	if len(r.jobs) >= r.maxSize {
+		return ErrTooManyRecurringJobs
+	}
+	if maxPerRequester > 0 {
+		n := 0
+		for _, rj := range r.jobs {
+			if rj.job.WorkerID == j.WorkerID {
+				n++
+			}
+		}
+		if n >= maxPerRequester {
+			return fmt.Errorf("%w: miner %s may have at most %d", ErrTooManyRecurringJobs, j.WorkerID, maxPerRequester)
+		}
+	}
+	r.jobs[j.UUID] = &recurringJob{job: j, class: class, schedule: s, next: s.Next(now), running: true}
+	return nil
+}
+
+// len returns the number of scheduled jobs
+func (r *recurringJobs) len() int {
+	r.Lock()
+	defer r.Unlock()
+	return len(r.jobs)
+}
+
+// remove unschedules a job. It returns false if the job is not recurring, and whether a run of the job is in progress.
+func (r *recurringJobs) remove(uuid string) (running, ok bool) {
+	r.Lock()
+	defer r.Unlock()
+	rj, ok := r.jobs[uuid]
+	if !ok {
+		return false, false
+	}
+	delete(r.jobs, uuid)
+	return rj.running, true
+}
+
+// scheduled returns true if the job is recurring
+func (r *recurringJobs) scheduled(uuid string) bool {
+	r.Lock()
+	defer r.Unlock()
+	_, ok := r.jobs[uuid]
+	return ok
+}
+
+// due returns the jobs which should run at the given time and marks them as running
+func (r *recurringJobs) due(now time.Time) []*recurringJob {
+	r.Lock()
+	defer r.Unlock()
+
+	var due []*recurringJob
+	for _, rj := range r.jobs {
+		if now.Before(rj.next) {
+			continue
+		}
+		rj.next = rj.schedule.Next(now)
+		if rj.running {
+			logrus.Debugf("Skipping run of recurring job %s, the previous run has not finished yet", rj.job.UUID)
+			continue
+		}
+		rj.running = true
+		due = append(due, rj)
+	}
+	return due
+}
+
+// finished records the result of a run. It is a no-op for jobs which are not (or no longer) recurring.
+func (r *recurringJobs) finished(uuid string, result *types.JobResult) {
+	r.Lock()
+	defer r.Unlock()
+	rj, ok := r.jobs[uuid]
+	if !ok {
+		return
+	}
+	rj.running = false
+	if result != nil {
+		rj.latest = result
+	}
+}
+
+// requester returns the worker ID of the miner which submitted a recurring job
+func (r *recurringJobs) requester(uuid string) (string, bool) {
+	r.Lock()
+	defer r.Unlock()
+	rj, ok := r.jobs[uuid]
+	if !ok {
+		return "", false
+	}
+	return rj.job.WorkerID, true
+}
+
+// latest returns the result of the latest finished run of a recurring job
+func (r *recurringJobs) latest(uuid string) (types.JobResult, bool) {
+	r.Lock()
+	defer r.Unlock()
+	rj, ok := r.jobs[uuid]
+	if !ok || rj.latest == nil {
+		return types.JobResult{}, false
+	}
+	return *rj.latest, true
+}
+
+// RemoveRecurringJob stops re-executing a recurring job. Its latest result stays in the result cache until it expires.
+func (js *JobServer) RemoveRecurringJob(uuid string) error {
+	running, ok := js.recurring.remove(uuid)
+	if !ok {
+		return ErrRecurringJobNotFound
+	}
+	// Otherwise the stream ends and the execution is forgotten with the run in progress
+	if s, ok := js.streams.get(uuid); ok && !running {
+		s.stream.End()
+	}
+	if !running {
+		js.executions.remove(uuid)
+	}
+	logrus.Infof("Removed recurring job %s", uuid)
+	return nil
+}
+
+// runDueRecurringJobs dispatches all recurring jobs which are due at the given time. Every run counts against the
+// quotas of the miner of the job like a submitted job, and runs over the quotas are skipped.
+func (js *JobServer) runDueRecurringJobs(now time.Time) {
+	for _, rj := range js.recurring.due(now) {
+		if err := js.quotas.admit(rj.job.WorkerID, now); err != nil {
+			logrus.Infof("Skipping run of recurring job %s: %s", rj.job.UUID, err)
+			js.recurring.finished(rj.job.UUID, nil)
+			continue
+		}
+		logrus.Debugf("Dispatching recurring job %s", rj.job.UUID)
+		if err := js.dispatch(rj.job, rj.class); err != nil {
+			logrus.Errorf("Error while dispatching recurring job %s: %s", rj.job.UUID, err)
+			js.recurring.finished(rj.job.UUID, nil)
+		}
+	}
+}
+
+// dispatchRecurringJobs re-executes recurring jobs on their schedule
+func (js *JobServer) dispatchRecurringJobs(ctx context.Context) {
+	ticker := time.NewTicker(recurringDispatchInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			js.runDueRecurringJobs(now)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Recurring jobs", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		w      *flakyWorker
+		js     *JobServer
+	)
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		ctx, cancel = context.WithCancel(context.Background())
+		w = &flakyWorker{}
+		js = NewJobServer(2, config.JobConfiguration{"max_recurring_jobs": 1})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("executes the job immediately and again when it is due", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"schedule": "@every 5m"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			_, exists := js.GetJobResult(uuid)
+			return exists
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+		Expect(w.calls.Load()).To(Equal(int32(1)))
+
+		js.runDueRecurringJobs(time.Now().Add(6 * time.Minute))
+		Eventually(w.calls.Load, 2*time.Second, 10*time.Millisecond).Should(Equal(int32(2)))
+
+		res, exists := js.GetJobResult(uuid)
+		Expect(exists).To(BeTrue())
+		Expect(res.Job.UUID).To(Equal(uuid))
+		Expect(res.Data).To(Equal([]byte("ok")))
+	})
+
+	It("keeps the latest result after it expires from the result cache", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"schedule": "@hourly"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		Eventually(func() bool {
+			_, exists := js.recurring.latest(uuid)
+			return exists
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+		js.results = NewResultCache(10, time.Minute)
+		_, exists := js.GetJobResult(uuid)
+		Expect(exists).To(BeTrue())
+	})
+
+	It("skips a run while the previous one is in progress", func() {
+		rj := newRecurringJobs(10)
+		s, err := ParseSchedule("@every 1m")
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now()
+		Expect(rj.add(types.Job{UUID: "a"}, ExecutionClassInteractive, s, now, 0)).To(Succeed())
+		Expect(rj.due(now.Add(2 * time.Minute))).To(BeEmpty())
+
+		rj.finished("a", &types.JobResult{})
+		Expect(rj.due(now.Add(4 * time.Minute))).To(HaveLen(1))
+	})
+
+	It("limits the recurring jobs of each miner", func() {
+		rj := newRecurringJobs(10)
+		s, err := ParseSchedule("@every 1m")
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now()
+		Expect(rj.add(types.Job{UUID: "a", WorkerID: "miner1"}, ExecutionClassInteractive, s, now, 2)).To(Succeed())
+		Expect(rj.add(types.Job{UUID: "b", WorkerID: "miner1"}, ExecutionClassInteractive, s, now, 2)).To(Succeed())
+		Expect(rj.add(types.Job{UUID: "c", WorkerID: "miner1"}, ExecutionClassInteractive, s, now, 2)).To(MatchError(ErrTooManyRecurringJobs))
+		Expect(rj.add(types.Job{UUID: "d", WorkerID: "miner2"}, ExecutionClassInteractive, s, now, 2)).To(Succeed())
+	})
+
+	It("counts every run against the quotas of the miner", func() {
+		js.quotas = newMinerQuotas([]config.MinerAPIKey{{Miner: "miner1", Key: "key1", JobsPerHour: 2}})
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "miner1", Nonce: "a", Arguments: map[string]any{"schedule": "@every 5m"}})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.calls.Load, 2*time.Second, 10*time.Millisecond).Should(Equal(int32(1)))
+		Eventually(func() bool {
+			_, ok := js.recurring.latest(uuid)
+			return ok
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+		js.runDueRecurringJobs(time.Now().Add(6 * time.Minute))
+		Eventually(w.calls.Load, 2*time.Second, 10*time.Millisecond).Should(Equal(int32(2)))
+		Eventually(func() bool {
+			js.recurring.Lock()
+			defer js.recurring.Unlock()
+			return !js.recurring.jobs[uuid].running
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+		// The quota of 2 jobs per hour is used up, so the next run is skipped
+		js.runDueRecurringJobs(time.Now().Add(12 * time.Minute))
+		Consistently(w.calls.Load, 200*time.Millisecond, 10*time.Millisecond).Should(Equal(int32(2)))
+		Expect(js.GetQuotaUsage()["miner1"].Rejected).To(Equal(1))
+	})
+
+	It("stops re-executing removed jobs", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"schedule": "@every 5m"}})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(w.calls.Load, 2*time.Second, 10*time.Millisecond).Should(Equal(int32(1)))
+
+		Expect(js.RemoveRecurringJob(uuid)).To(Succeed())
+		Expect(js.RemoveRecurringJob(uuid)).To(MatchError(ErrRecurringJobNotFound))
+
+		js.runDueRecurringJobs(time.Now().Add(6 * time.Minute))
+		Consistently(w.calls.Load, 200*time.Millisecond, 10*time.Millisecond).Should(Equal(int32(1)))
+	})
+
+	It("rejects invalid schedules and too many recurring jobs", func() {
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"schedule": "every day"}})
+		Expect(err).To(HaveOccurred())
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "b", Arguments: map[string]any{"schedule": "@daily", "cache": "no-store"}})
+		Expect(err).To(HaveOccurred())
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "c", Arguments: map[string]any{"schedule": "@daily"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "d", Arguments: map[string]any{"schedule": "@daily"}})
+		Expect(err).To(MatchError(ErrTooManyRecurringJobs))
+
+		// The nonce of the rejected job was not used up
+		Expect(js.RemoveRecurringJob(uuid)).To(Succeed())
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "d", Arguments: map[string]any{"schedule": "@daily"}})
+		Expect(err).NotTo(HaveOccurred())
+	})
+})
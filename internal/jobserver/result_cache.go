@@ -2,9 +2,17 @@ package jobserver
 
 import (
 	"container/list"
-	"github.com/masa-finance/tee-worker/api/types"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
 )
 
 // Default values
@@ -14,18 +22,30 @@ const (
 )
 
 type cacheEntry struct {
-	key       string
-	result    types.JobResult
-	timestamp time.Time
-	element   *list.Element // pointer to the element in the list
+	key          string
+	result       types.JobResult
+	timestamp    time.Time
+	element      *list.Element // pointer to the element in the list
+	fingerprint  string        // identifies identical jobs whose result can be reused, empty if not reusable
+	deleteOnRead bool
+	size         int64             // size of the result data, counted towards maxBytes unless the entry is held
+	hold         *types.ResultHold // set if the result is retained until it is released
+	spilled      bool              // the result data is in a sealed file in the spill directory instead of in memory
 }
 
 type ResultCache struct {
-	lock    sync.Mutex
-	entries map[string]*cacheEntry
-	order   *list.List // oldest at Front, newest at Back
-	maxSize int
-	maxAge  time.Duration
+	lock          sync.Mutex
+	entries       map[string]*cacheEntry
+	byFingerprint map[string]*cacheEntry // most recent reusable entry for each job fingerprint
+	order         *list.List             // least recently used at Front, most recently used at Back
+	maxSize       int
+	maxAge        time.Duration
+	maxBytes      int64 // maximum total size of the result data, 0 for no limit
+	bytes         int64 // total size of the result data of the entries which are not held
+	held          int   // number of held entries
+
+	spillDir       string // directory large results are spilled to, empty to keep all results in memory
+	spillThreshold int64  // results with more data than this are spilled to disk
 }
 
 // NewResultCache creates a new ResultCache with the specified maxSize and maxAge (in seconds)
@@ -37,41 +57,269 @@ func NewResultCache(maxSize int, maxAge time.Duration) *ResultCache {
 		maxAge = defaultMaxAgeSecs
 	}
 	rc := &ResultCache{
-		entries: make(map[string]*cacheEntry),
-		order:   list.New(),
-		maxSize: maxSize,
-		maxAge:  maxAge,
+		entries:       make(map[string]*cacheEntry),
+		byFingerprint: make(map[string]*cacheEntry),
+		order:         list.New(),
+		maxSize:       maxSize,
+		maxAge:        maxAge,
 	}
 	go rc.periodicCleanup()
 	return rc
 }
 
+// SetMaxBytes limits the total size of the result data in the cache. Results which are held don't count towards
+// the limit. A maxBytes of 0 removes the limit.
+func (rc *ResultCache) SetMaxBytes(maxBytes int64) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	rc.maxBytes = maxBytes
+	rc.evict()
+}
+
+// SetSpill makes the cache write the data of results larger than threshold bytes to sealed files in dir instead of
+// keeping it in memory. Spilled results still count towards the byte limit. Files left in dir by a previous run are
+// removed, since the results they belong to are gone. An empty dir or a threshold of 0 keeps all results in memory.
+func (rc *ResultCache) SetSpill(dir string, threshold int64) error {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if dir == "" || threshold <= 0 {
+		rc.spillDir, rc.spillThreshold = "", 0
+		return nil
+	}
+	if err := os.RemoveAll(dir); err != nil {
+		return fmt.Errorf("error removing spilled results: %w", err)
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return fmt.Errorf("error creating result spill directory: %w", err)
+	}
+	rc.spillDir, rc.spillThreshold = dir, threshold
+	return nil
+}
+
+// spillPath returns the file the data of a spilled result is written to. Keys are hashed, so they can't escape the
+// spill directory.
+func (rc *ResultCache) spillPath(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(rc.spillDir, hex.EncodeToString(sum[:]))
+}
+
+func spillPurpose(key string) string {
+	return "result-cache:" + key
+}
+
+// spill writes the data of an entry to disk if it is large enough, keeping it in memory if that fails. The caller
+// must hold the lock.
+func (rc *ResultCache) spill(entry *cacheEntry) {
+	if rc.spillDir == "" || entry.size <= rc.spillThreshold {
+		return
+	}
+	if err := tee.WriteSecretFile(rc.spillPath(entry.key), spillPurpose(entry.key), entry.result.Data); err != nil {
+		logrus.Warnf("Failed to spill result of job %s to disk, keeping it in memory: %v", entry.key, err)
+		return
+	}
+	entry.result.Data = nil
+	entry.spilled = true
+}
+
+// unspill removes the spilled data of an entry. The caller must hold the lock.
+func (rc *ResultCache) unspill(entry *cacheEntry) {
+	if !entry.spilled {
+		return
+	}
+	if err := os.Remove(rc.spillPath(entry.key)); err != nil && !os.IsNotExist(err) {
+		logrus.Warnf("Failed to remove spilled result of job %s: %v", entry.key, err)
+	}
+	entry.spilled = false
+}
+
+// load returns the result of an entry, reading its data from disk if it was spilled. Entries whose data can't be
+// read anymore are removed. The caller must hold the lock.
+func (rc *ResultCache) load(entry *cacheEntry) (types.JobResult, bool) {
+	if !entry.spilled {
+		return entry.result, true
+	}
+	data, _, err := tee.ReadSecretFile(rc.spillPath(entry.key), spillPurpose(entry.key))
+	if err != nil {
+		logrus.Errorf("Failed to read spilled result of job %s: %v", entry.key, err)
+		rc.remove(entry)
+		return types.JobResult{}, false
+	}
+	result := entry.result
+	result.Data = data
+	return result, true
+}
+
 func (rc *ResultCache) Set(key string, result types.JobResult) {
+	rc.set(key, result, "", false)
+}
+
+// SetReusable stores a result that can be returned for later identical jobs with the same fingerprint
+func (rc *ResultCache) SetReusable(key, fingerprint string, result types.JobResult) {
+	rc.set(key, result, fingerprint, false)
+}
+
+// SetDeleteOnRead stores a result that is removed from the cache as soon as it has been read
+func (rc *ResultCache) SetDeleteOnRead(key string, result types.JobResult) {
+	rc.set(key, result, "", true)
+}
+
+func (rc *ResultCache) set(key string, result types.JobResult, fingerprint string, deleteOnRead bool) {
 	rc.lock.Lock()
 	defer rc.lock.Unlock()
 	if entry, exists := rc.entries[key]; exists {
 		// Update and move to back
+		rc.unindex(entry)
+		rc.unspill(entry)
 		entry.result = result
 		entry.timestamp = time.Now()
+		entry.fingerprint = fingerprint
+		entry.deleteOnRead = deleteOnRead && entry.hold == nil
+		rc.resize(entry, int64(len(result.Data)))
+		rc.spill(entry)
+		rc.index(entry)
 		rc.order.MoveToBack(entry.element)
+		rc.evict()
 		return
 	}
 	// New entry
 	entry := &cacheEntry{
-		key:       key,
-		result:    result,
-		timestamp: time.Now(),
+		key:          key,
+		result:       result,
+		timestamp:    time.Now(),
+		fingerprint:  fingerprint,
+		deleteOnRead: deleteOnRead,
 	}
 	entry.element = rc.order.PushBack(entry)
 	rc.entries[key] = entry
-	// Evict if over size
-	for len(rc.entries) > rc.maxSize {
-		oldest := rc.order.Front()
-		if oldest != nil {
-			oldestEntry := oldest.Value.(*cacheEntry)
-			delete(rc.entries, oldestEntry.key)
-			rc.order.Remove(oldest)
+	rc.resize(entry, int64(len(result.Data)))
+	rc.spill(entry)
+	rc.index(entry)
+	rc.evict()
+}
+
+// resize updates the size of an entry. The caller must hold the lock.
+func (rc *ResultCache) resize(entry *cacheEntry, size int64) {
+	if entry.hold == nil {
+		rc.bytes += size - entry.size
+	}
+	entry.size = size
+}
+
+// evict removes the least recently used entries which are not held while the cache is over its size limits. Held
+// entries don't count towards the limits. The caller must hold the lock.
+func (rc *ResultCache) evict() {
+	e := rc.order.Front()
+	for e != nil && (len(rc.entries)-rc.held > rc.maxSize || (rc.maxBytes > 0 && rc.bytes > rc.maxBytes)) {
+		next := e.Next()
+		if entry := e.Value.(*cacheEntry); entry.hold == nil {
+			rc.remove(entry)
 		}
+		e = next
+	}
+}
+
+// Hold retains a result until it is released, excluding it from eviction and expiry. It returns false if there is
+// no such result. Holding a result which is already held replaces its hold.
+func (rc *ResultCache) Hold(key string, hold types.ResultHold) bool {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	entry, exists := rc.entries[key]
+	if !exists || rc.expired(entry, time.Now()) {
+		return false
+	}
+	if entry.hold == nil {
+		rc.held++
+		rc.bytes -= entry.size
+	}
+	entry.hold = &hold
+	entry.deleteOnRead = false
+	return true
+}
+
+// Release removes the hold of a result, which is then evicted and expires like any other. It returns false if the
+// result is not held.
+func (rc *ResultCache) Release(key string) bool {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	entry, exists := rc.entries[key]
+	if !exists || entry.hold == nil {
+		return false
+	}
+	entry.hold = nil
+	rc.held--
+	rc.bytes += entry.size
+	// The result expires relative to its release, so it is still available for a while
+	entry.timestamp = time.Now()
+	rc.order.MoveToBack(entry.element)
+	rc.evict()
+	return true
+}
+
+// HeldCount returns the number of held results in the cache
+func (rc *ResultCache) HeldCount() int {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	return rc.held
+}
+
+// GetHold returns the hold of a result, if it is held
+func (rc *ResultCache) GetHold(key string) (types.ResultHold, bool) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	if entry, exists := rc.entries[key]; exists && entry.hold != nil {
+		return *entry.hold, true
+	}
+	return types.ResultHold{}, false
+}
+
+// expired returns true if the entry is older than maxAge and not held. The caller must hold the lock.
+func (rc *ResultCache) expired(entry *cacheEntry, now time.Time) bool {
+	return entry.hold == nil && rc.maxAge > 0 && now.Sub(entry.timestamp) > rc.maxAge
+}
+
+// GetByFingerprint returns the most recent reusable result for the given job fingerprint, as long as it is not older than maxAge.
+// A maxAge of 0 accepts any result that has not yet expired.
+func (rc *ResultCache) GetByFingerprint(fingerprint string, maxAge time.Duration) (types.JobResult, bool) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	entry, exists := rc.byFingerprint[fingerprint]
+	if !exists {
+		return types.JobResult{}, false
+	}
+	age := time.Since(entry.timestamp)
+	if rc.expired(entry, time.Now()) {
+		rc.remove(entry)
+		return types.JobResult{}, false
+	}
+	if maxAge > 0 && age > maxAge {
+		return types.JobResult{}, false
+	}
+	rc.order.MoveToBack(entry.element)
+	return rc.load(entry)
+}
+
+func (rc *ResultCache) index(entry *cacheEntry) {
+	if entry.fingerprint != "" {
+		rc.byFingerprint[entry.fingerprint] = entry
+	}
+}
+
+func (rc *ResultCache) unindex(entry *cacheEntry) {
+	if entry.fingerprint != "" && rc.byFingerprint[entry.fingerprint] == entry {
+		delete(rc.byFingerprint, entry.fingerprint)
+	}
+}
+
+// remove deletes an entry from the cache. The caller must hold the lock.
+func (rc *ResultCache) remove(entry *cacheEntry) {
+	rc.unindex(entry)
+	delete(rc.entries, entry.key)
+	rc.order.Remove(entry.element)
+	rc.unspill(entry)
+	if entry.hold != nil {
+		rc.held--
+	} else {
+		rc.bytes -= entry.size
 	}
 }
 
@@ -83,12 +331,36 @@ func (rc *ResultCache) Get(key string) (types.JobResult, bool) {
 		return types.JobResult{}, false
 	}
 	// If expired, remove
-	if rc.maxAge > 0 && time.Since(entry.timestamp) > rc.maxAge {
-		rc.order.Remove(entry.element)
-		delete(rc.entries, key)
+	if rc.expired(entry, time.Now()) {
+		rc.remove(entry)
+		return types.JobResult{}, false
+	}
+	result, ok := rc.load(entry)
+	if !ok {
+		return types.JobResult{}, false
+	}
+	if entry.deleteOnRead {
+		rc.remove(entry)
+	} else {
+		rc.order.MoveToBack(entry.element)
+	}
+	return result, true
+}
+
+// Peek returns a result like Get, but leaves results which are deleted on read in the cache and doesn't count as a
+// use for eviction
+func (rc *ResultCache) Peek(key string) (types.JobResult, bool) {
+	rc.lock.Lock()
+	defer rc.lock.Unlock()
+	entry, exists := rc.entries[key]
+	if !exists {
+		return types.JobResult{}, false
+	}
+	if rc.expired(entry, time.Now()) {
+		rc.remove(entry)
 		return types.JobResult{}, false
 	}
-	return entry.result, true
+	return rc.load(entry)
 }
 
 func (rc *ResultCache) periodicCleanup() {
@@ -106,9 +378,8 @@ func (rc *ResultCache) cleanupExpired() {
 	for e := rc.order.Front(); e != nil; {
 		next := e.Next()
 		entry := e.Value.(*cacheEntry)
-		if rc.maxAge > 0 && now.Sub(entry.timestamp) > rc.maxAge {
-			delete(rc.entries, entry.key)
-			rc.order.Remove(e)
+		if rc.expired(entry, now) {
+			rc.remove(entry)
 		}
 		e = next
 	}
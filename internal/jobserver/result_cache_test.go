@@ -1,9 +1,12 @@
 package jobserver
 
 import (
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -48,3 +51,209 @@ var _ = Describe("ResultCache", func() {
 		Expect(ok).To(BeFalse())
 	})
 })
+
+var _ = Describe("ResultCache fingerprints", func() {
+	It("returns reusable results by fingerprint", func() {
+		cache := NewResultCache(10, time.Duration(600)*time.Second)
+		cache.SetReusable("a", "fp", types.JobResult{Data: []byte("result")})
+		got, ok := cache.GetByFingerprint("fp", 0)
+		Expect(ok).To(BeTrue())
+		Expect(got.Data).To(Equal([]byte("result")))
+
+		_, ok = cache.GetByFingerprint("other", 0)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("honors the requested max age", func() {
+		cache := NewResultCache(10, time.Duration(600)*time.Second)
+		cache.SetReusable("a", "fp", types.JobResult{Data: []byte("result")})
+		time.Sleep(20 * time.Millisecond)
+		_, ok := cache.GetByFingerprint("fp", 10*time.Millisecond)
+		Expect(ok).To(BeFalse())
+		_, ok = cache.GetByFingerprint("fp", time.Second)
+		Expect(ok).To(BeTrue())
+	})
+
+	It("drops the fingerprint when the entry is evicted", func() {
+		cache := NewResultCache(1, time.Duration(600)*time.Second)
+		cache.SetReusable("a", "fp", types.JobResult{})
+		cache.Set("b", types.JobResult{})
+		_, ok := cache.GetByFingerprint("fp", 0)
+		Expect(ok).To(BeFalse())
+		Expect(cache.byFingerprint).To(BeEmpty())
+	})
+
+	It("deletes no-store results after they have been read", func() {
+		cache := NewResultCache(10, time.Duration(600)*time.Second)
+		cache.SetDeleteOnRead("a", types.JobResult{Data: []byte("once")})
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get("a")
+		Expect(ok).To(BeFalse())
+	})
+})
+
+var _ = Describe("ResultCache holds", func() {
+	It("does not evict or expire held results", func() {
+		cache := NewResultCache(2, 50*time.Millisecond)
+		cache.Set("held", types.JobResult{})
+		Expect(cache.Hold("held", types.ResultHold{JobID: "held", Tag: types.HoldTagLegalHold})).To(BeTrue())
+
+		for _, key := range []string{"a", "b", "c"} {
+			cache.Set(key, types.JobResult{})
+		}
+		Expect(cache.entries).To(HaveLen(3))
+		Expect(cache.HeldCount()).To(Equal(1))
+
+		time.Sleep(100 * time.Millisecond)
+		_, ok := cache.Get("held")
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get("c")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("evicts the oldest results to stay within the byte limit", func() {
+		cache := NewResultCache(10, time.Minute)
+		cache.SetMaxBytes(10)
+		cache.Set("a", types.JobResult{Data: []byte("123456")})
+		cache.Set("held", types.JobResult{Data: []byte("123456")})
+		Expect(cache.Hold("held", types.ResultHold{JobID: "held"})).To(BeTrue())
+		cache.Set("b", types.JobResult{Data: []byte("123456")})
+
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Get("held")
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get("b")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("evicts released results like any other", func() {
+		cache := NewResultCache(1, time.Minute)
+		cache.Set("a", types.JobResult{})
+		Expect(cache.Hold("a", types.ResultHold{JobID: "a"})).To(BeTrue())
+		Expect(cache.Hold("missing", types.ResultHold{JobID: "missing"})).To(BeFalse())
+		cache.Set("b", types.JobResult{})
+
+		Expect(cache.Release("a")).To(BeTrue())
+		Expect(cache.Release("a")).To(BeFalse())
+		_, held := cache.GetHold("a")
+		Expect(held).To(BeFalse())
+
+		// The released result is now the newest, so the other one is evicted
+		Expect(cache.entries).To(HaveLen(1))
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+	})
+
+	It("keeps held results which were stored to be deleted on read", func() {
+		cache := NewResultCache(10, time.Minute)
+		cache.SetDeleteOnRead("a", types.JobResult{})
+		Expect(cache.Hold("a", types.ResultHold{JobID: "a"})).To(BeTrue())
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+		_, ok = cache.Get("a")
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("ResultCache eviction", func() {
+	It("evicts the least recently read results first", func() {
+		cache := NewResultCache(10, time.Minute)
+		cache.SetMaxBytes(10)
+		cache.Set("a", types.JobResult{Data: []byte("1234")})
+		cache.Set("b", types.JobResult{Data: []byte("1234")})
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeTrue())
+		cache.Set("c", types.JobResult{Data: []byte("1234")})
+
+		_, ok = cache.Peek("b")
+		Expect(ok).To(BeFalse())
+		_, ok = cache.Peek("a")
+		Expect(ok).To(BeTrue())
+	})
+})
+
+var _ = Describe("ResultCache spilling", func() {
+	var (
+		cache *ResultCache
+		dir   string
+	)
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		dir = filepath.Join(GinkgoT().TempDir(), "result_cache")
+		cache = NewResultCache(10, time.Minute)
+		Expect(cache.SetSpill(dir, 8)).To(Succeed())
+	})
+
+	spilledFiles := func() []string {
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		var names []string
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		return names
+	}
+
+	It("keeps small results in memory and spills large ones to sealed files", func() {
+		cache.Set("small", types.JobResult{Data: []byte("tiny")})
+		cache.Set("large", types.JobResult{Data: []byte("a large result"), NextCursor: "next"})
+
+		Expect(cache.entries["small"].spilled).To(BeFalse())
+		Expect(cache.entries["large"].spilled).To(BeTrue())
+		Expect(cache.entries["large"].result.Data).To(BeNil())
+		Expect(spilledFiles()).To(HaveLen(1))
+
+		sealed, err := os.ReadFile(filepath.Join(dir, spilledFiles()[0]))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(sealed)).NotTo(ContainSubstring("a large result"))
+
+		res, ok := cache.Get("large")
+		Expect(ok).To(BeTrue())
+		Expect(string(res.Data)).To(Equal("a large result"))
+		Expect(res.NextCursor).To(Equal("next"))
+
+		res, ok = cache.Get("large")
+		Expect(ok).To(BeTrue())
+		Expect(string(res.Data)).To(Equal("a large result"))
+	})
+
+	It("counts spilled results towards the byte limit and removes their files", func() {
+		cache.SetMaxBytes(20)
+		cache.Set("a", types.JobResult{Data: []byte("0123456789")})
+		cache.Set("b", types.JobResult{Data: []byte("0123456789")})
+		Expect(spilledFiles()).To(HaveLen(2))
+
+		cache.Set("c", types.JobResult{Data: []byte("0123456789")})
+		Expect(spilledFiles()).To(HaveLen(2))
+		_, ok := cache.Get("a")
+		Expect(ok).To(BeFalse())
+
+		cache.SetDeleteOnRead("d", types.JobResult{Data: []byte("0123456789")})
+		_, ok = cache.Get("d")
+		Expect(ok).To(BeTrue())
+		Expect(spilledFiles()).To(HaveLen(1))
+
+		cache.Set("c", types.JobResult{Data: []byte("small")})
+		Expect(spilledFiles()).To(BeEmpty())
+	})
+
+	It("removes results spilled by a previous run", func() {
+		cache.Set("large", types.JobResult{Data: []byte("a large result")})
+		Expect(spilledFiles()).To(HaveLen(1))
+
+		Expect(NewResultCache(10, time.Minute).SetSpill(dir, 8)).To(Succeed())
+		Expect(spilledFiles()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,127 @@
+package jobserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"math"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+)
+
+// Job arguments used by clients to lower the result limits of a job
+const (
+	resultMaxBytesArgumentKey = "max_result_bytes"
+	resultMaxItemsArgumentKey = "max_result_items"
+)
+
+// resultLimitFromArguments returns the result limit requested by the client in the given argument, or 0 if none was
+// requested
+func resultLimitFromArguments(args types.JobArguments, key string) (int, error) {
+	v, ok := args[key]
+	if !ok || v == nil {
+		return 0, nil
+	}
+
+	var limit float64
+	switch n := v.(type) {
+	case float64:
+		limit = n
+	case int:
+		limit = float64(n)
+	case int64:
+		limit = float64(n)
+	default:
+		return 0, fmt.Errorf("%s must be a number, got %T", key, v)
+	}
+
+	if limit <= 0 || limit != math.Trunc(limit) || limit > math.MaxInt32 {
+		return 0, fmt.Errorf("%s must be a positive integer, got %v", key, v)
+	}
+	return int(limit), nil
+}
+
+// validateResultLimitArguments checks the result limits requested by the client, if any
+func validateResultLimitArguments(args types.JobArguments) error {
+	for _, key := range []string{resultMaxItemsArgumentKey, resultMaxBytesArgumentKey} {
+		if _, err := resultLimitFromArguments(args, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resultLimits returns the maximum number of items and bytes of the result of the job, i.e. the lowest of the
+// configured limits and those requested in the job arguments. A limit of 0 means unlimited.
+func (js *JobServer) resultLimits(j types.Job) (maxItems, maxBytes int) {
+	rc := js.jobConfiguration.GetResultLimitConfig()
+	maxItems, maxBytes = rc.MaxItems, rc.MaxBytes
+	if requested, err := resultLimitFromArguments(j.Arguments, resultMaxItemsArgumentKey); err == nil && requested > 0 && (maxItems == 0 || requested < maxItems) {
+		maxItems = requested
+	}
+	if requested, err := resultLimitFromArguments(j.Arguments, resultMaxBytesArgumentKey); err == nil && requested > 0 && (maxBytes == 0 || requested < maxBytes) {
+		maxBytes = requested
+	}
+	return maxItems, maxBytes
+}
+
+// limitedJob returns the job with the arguments which cap the number of items it fetches, see
+// jobs.RegisterResultLimit, lowered to the items its result can hold: the items of the page already returned by
+// earlier truncated results, and maxItems more. Arguments which are not set are left to the default of the job type.
+// It returns whether an argument was lowered.
+func limitedJob(j types.Job, maxItems int) (types.Job, bool) {
+	if maxItems == 0 {
+		return j, false
+	}
+	limit := j.ResultOffset + maxItems
+	lowered := false
+	for _, key := range jobs.ResultLimitArguments(j.Type) {
+		if requested, err := resultLimitFromArguments(j.Arguments, key); err != nil || requested <= limit {
+			continue
+		}
+		if !lowered {
+			j.Arguments = maps.Clone(j.Arguments)
+			lowered = true
+		}
+		j.Arguments[key] = limit
+	}
+	return j, lowered
+}
+
+// limitResult skips the first offset items of a result which is a JSON array, as they were returned by an earlier
+// truncated result of the same page, and keeps the following items as long as they fit in maxItems and maxBytes, in
+// their order. At least one item is kept, so paging through a result always makes progress. It returns the number of
+// items kept, and whether items were left out. Results which are not an array are returned unchanged.
+func limitResult(data []byte, offset, maxItems, maxBytes int) ([]byte, int, bool, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil || items == nil {
+		return data, 0, false, nil
+	}
+	items = items[min(offset, len(items)):]
+
+	kept, size := 0, len("[]")
+	for kept < len(items) {
+		if maxItems > 0 && kept >= maxItems {
+			break
+		}
+		itemSize := len(items[kept])
+		if kept > 0 {
+			itemSize++ // The comma separating it from the previous item
+		}
+		if maxBytes > 0 && kept > 0 && size+itemSize > maxBytes {
+			break
+		}
+		size += itemSize
+		kept++
+	}
+
+	if offset == 0 && kept == len(items) {
+		return data, kept, false, nil
+	}
+	limited, err := json.Marshal(items[:kept])
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("error marshalling limited result: %w", err)
+	}
+	return limited, kept, kept < len(items), nil
+}
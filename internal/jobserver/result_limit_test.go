@@ -0,0 +1,252 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// pageWorker returns a page of five items named after its cursor, and the cursor of the next page
+type pageWorker struct{}
+
+func (w pageWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (w pageWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	cursor, _ := j.Arguments[types.NextCursorArgumentKey].(string)
+	items := make([]string, 5)
+	for i := range items {
+		items[i] = fmt.Sprintf("%s%d", cursor, i)
+	}
+	data, err := json.Marshal(items)
+	return types.JobResult{Data: data, NextCursor: cursor + "+"}, err
+}
+
+// limitedPageWorker returns a page of max_results items named after its cursor, or of five items if max_results isn't
+// set, and the cursor of the next page unless noCursor is set
+type limitedPageWorker struct {
+	noCursor bool
+}
+
+func (w limitedPageWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (w limitedPageWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	cursor, _ := j.Arguments[types.NextCursorArgumentKey].(string)
+	count := 5
+	switch n := j.Arguments["max_results"].(type) {
+	case int:
+		count = n
+	case float64:
+		count = int(n)
+	}
+	items := make([]string, count)
+	for i := range items {
+		items[i] = fmt.Sprintf("%s%d", cursor, i)
+	}
+	data, err := json.Marshal(items)
+	if w.noCursor {
+		return types.JobResult{Data: data}, err
+	}
+	return types.JobResult{Data: data, NextCursor: fmt.Sprintf("%s+%d", cursor, count)}, err
+}
+
+// limitedJobType is a job type whose max_results argument caps the number of items it fetches
+const limitedJobType teetypes.JobType = "result-limit-test"
+
+func init() {
+	jobs.RegisterResultLimit(limitedJobType, "max_results")
+}
+
+var _ = Describe("Result limits", func() {
+	Describe("limitResult", func() {
+		It("keeps the items which fit in the limits, in their order", func() {
+			limited, kept, truncated, err := limitResult([]byte(`["a","b","c","d"]`), 0, 3, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`["a","b","c"]`))
+			Expect(kept).To(Equal(3))
+			Expect(truncated).To(BeTrue())
+
+			// ["a","b"] is 9 bytes
+			limited, kept, truncated, err = limitResult([]byte(`["a","b","c","d"]`), 0, 0, 10)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`["a","b"]`))
+			Expect(kept).To(Equal(2))
+			Expect(truncated).To(BeTrue())
+		})
+
+		It("skips the items returned by earlier results", func() {
+			limited, kept, truncated, err := limitResult([]byte(`["a","b","c","d"]`), 2, 3, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`["c","d"]`))
+			Expect(kept).To(Equal(2))
+			Expect(truncated).To(BeFalse())
+
+			limited, _, truncated, err = limitResult([]byte(`["a","b"]`), 5, 0, 0)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`[]`))
+			Expect(truncated).To(BeFalse())
+		})
+
+		It("keeps at least one item", func() {
+			limited, kept, truncated, err := limitResult([]byte(`["abcdef","b"]`), 0, 0, 4)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`["abcdef"]`))
+			Expect(kept).To(Equal(1))
+			Expect(truncated).To(BeTrue())
+		})
+
+		It("returns results which are not a list unchanged", func() {
+			limited, _, truncated, err := limitResult([]byte(`{"text":"abcdef"}`), 0, 1, 4)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(limited)).To(Equal(`{"text":"abcdef"}`))
+			Expect(truncated).To(BeFalse())
+		})
+	})
+
+	Describe("limitedJob", func() {
+		It("only lowers the arguments which are above the limit", func() {
+			j := types.Job{Type: limitedJobType, Arguments: types.JobArguments{"max_results": float64(10)}, ResultOffset: 4}
+			limited, lowered := limitedJob(j, 3)
+			Expect(lowered).To(BeTrue())
+			Expect(limited.Arguments["max_results"]).To(Equal(7))
+			Expect(j.Arguments["max_results"]).To(Equal(float64(10)))
+
+			_, lowered = limitedJob(j, 6)
+			Expect(lowered).To(BeFalse())
+			_, lowered = limitedJob(types.Job{Type: limitedJobType, Arguments: types.JobArguments{}}, 3)
+			Expect(lowered).To(BeFalse())
+			_, lowered = limitedJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_results": 10}}, 3)
+			Expect(lowered).To(BeFalse())
+		})
+	})
+
+	Describe("jobs", func() {
+		var js *JobServer
+
+		BeforeEach(func() {
+			keyRing := tee.CurrentKeyRing
+			standalone := tee.SealStandaloneMode
+			tee.CurrentKeyRing = tee.NewKeyRing()
+			Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+			tee.SealStandaloneMode = false
+			DeferCleanup(func() {
+				tee.CurrentKeyRing = keyRing
+				tee.SealStandaloneMode = standalone
+			})
+
+			config.MinersWhiteList = ""
+			js = NewJobServer(1, config.JobConfiguration{"next_cursor_ttl_seconds": time.Hour, "result_max_items": 3})
+			js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: pageWorker{}}
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			go js.Run(ctx)
+		})
+
+		nonce := 0
+		run := func(args types.JobArguments) (types.JobResult, []string) {
+			nonce++
+			uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: fmt.Sprint(nonce), Arguments: args})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+			res, ok := js.GetJobResult(uuid)
+			Expect(ok).To(BeTrue())
+			Expect(res.Error).To(BeEmpty())
+			var items []string
+			Expect(json.Unmarshal(res.Data, &items)).To(Succeed())
+			return res, items
+		}
+
+		It("truncates results and continues with the items which were left out", func() {
+			first, items := run(types.JobArguments{"type": "scraper", "max_result_items": 2})
+			Expect(items).To(Equal([]string{"0", "1"}))
+			Expect(first.Truncated()).To(BeTrue())
+			Expect(first.HasMore).To(BeTrue())
+			Expect(first.Paginated().Truncated).To(BeTrue())
+
+			second, items := run(types.JobArguments{"type": "scraper", "max_result_items": 2, "next_cursor": first.NextCursor})
+			Expect(items).To(Equal([]string{"2", "3"}))
+			Expect(second.Truncated()).To(BeTrue())
+
+			third, items := run(types.JobArguments{"type": "scraper", "max_result_items": 2, "next_cursor": second.NextCursor})
+			Expect(items).To(Equal([]string{"4"}))
+			Expect(third.Truncated()).To(BeFalse())
+			Expect(third.Paginated().Truncated).To(BeFalse())
+
+			// The last part of the page continues with the next page of the data source
+			c, err := types.OpenNextCursor(third.NextCursor, teetypes.WebJob, "scraper", time.Hour, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Cursor).To(Equal("+"))
+			Expect(c.Offset).To(BeZero())
+
+			_, items = run(types.JobArguments{"type": "scraper", "next_cursor": third.NextCursor})
+			Expect(items).To(Equal([]string{"+0", "+1", "+2"}))
+		})
+
+		It("lowers the number of items fetched by the job to the limits", func() {
+			js.jobWorkers[limitedJobType] = &jobWorkerEntry{w: limitedPageWorker{}}
+			uuid, err := js.AddJob(types.Job{Type: limitedJobType, Nonce: "limited", Arguments: types.JobArguments{"type": "scraper", "max_results": 100}})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+			res, ok := js.GetJobResult(uuid)
+			Expect(ok).To(BeTrue())
+			Expect(res.Error).To(BeEmpty())
+			Expect(string(res.Data)).To(Equal(`["0","1","2"]`))
+			Expect(res.Truncated()).To(BeTrue())
+			Expect(res.HasMore).To(BeTrue())
+
+			// The next page starts from the cursor of the data source
+			c, err := types.OpenNextCursor(res.NextCursor, limitedJobType, "scraper", time.Hour, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Cursor).To(Equal("+3"))
+			Expect(c.Offset).To(BeZero())
+		})
+
+		It("continues from the cursor the job started from if the job doesn't return one", func() {
+			js.jobWorkers[limitedJobType] = &jobWorkerEntry{w: limitedPageWorker{noCursor: true}}
+			submit := func(nonce string, args types.JobArguments) types.JobResult {
+				uuid, err := js.AddJob(types.Job{Type: limitedJobType, Nonce: nonce, Arguments: args})
+				Expect(err).NotTo(HaveOccurred())
+				Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+				res, ok := js.GetJobResult(uuid)
+				Expect(ok).To(BeTrue())
+				Expect(res.Error).To(BeEmpty())
+				return res
+			}
+
+			first := submit("first", types.JobArguments{"type": "scraper", "max_results": 100})
+			Expect(string(first.Data)).To(Equal(`["0","1","2"]`))
+			c, err := types.OpenNextCursor(first.NextCursor, limitedJobType, "scraper", time.Hour, time.Now())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.Offset).To(Equal(3))
+
+			// The job fetches the items returned so far and three more
+			second := submit("second", types.JobArguments{"type": "scraper", "max_results": 100, "next_cursor": first.NextCursor})
+			Expect(string(second.Data)).To(Equal(`["3","4","5"]`))
+		})
+
+		It("doesn't let jobs raise the configured limits", func() {
+			res, items := run(types.JobArguments{"type": "scraper", "max_result_items": 10})
+			Expect(items).To(HaveLen(3))
+			Expect(res.Truncated()).To(BeTrue())
+		})
+
+		It("rejects invalid limits", func() {
+			_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_result_bytes": -1}})
+			Expect(err).To(HaveOccurred())
+			_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{"max_result_items": "10"}})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
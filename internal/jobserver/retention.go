@@ -0,0 +1,202 @@
+package jobserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+const defaultMaxHeldResults = 1000
+
+var (
+	// ErrResultNotFound is returned when holding a result which doesn't exist or has already expired
+	ErrResultNotFound = errors.New("result not found")
+	// ErrResultNotHeld is returned when releasing a result which is not held
+	ErrResultNotHeld = errors.New("result is not held")
+	// ErrTooManyHeldResults is returned when holding a result while the maximum number of results is held
+	ErrTooManyHeldResults = errors.New("too many held results")
+)
+
+// heldResult is a held result as it is stored on disk
+type heldResult struct {
+	Result types.JobResult  `json:"result"`
+	Hold   types.ResultHold `json:"hold"`
+}
+
+// heldResults persists held results as sealed files in the data directory, so they survive restarts. They are
+// loaded lazily when they are requested, since the sealing key may not be available when the worker starts. An
+// empty directory keeps held results in memory only.
+type heldResults struct {
+	dir string
+}
+
+func newHeldResults(dataDir string) *heldResults {
+	if dataDir == "" {
+		return &heldResults{}
+	}
+	return &heldResults{dir: filepath.Join(dataDir, "held_results")}
+}
+
+func (h *heldResults) path(jobID string) (string, error) {
+	// The job ID is used as the file name, so it has to be validated
+	if _, err := uuid.Parse(jobID); err != nil {
+		return "", fmt.Errorf("invalid job ID %q", jobID)
+	}
+	return filepath.Join(h.dir, jobID+".json"), nil
+}
+
+func heldResultPurpose(jobID string) string {
+	return "held-result:" + jobID
+}
+
+func (h *heldResults) save(result types.JobResult, hold types.ResultHold) error {
+	if h.dir == "" {
+		return nil
+	}
+	path, err := h.path(hold.JobID)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(h.dir, 0700); err != nil {
+		return fmt.Errorf("error creating held results directory: %w", err)
+	}
+	data, err := json.Marshal(heldResult{Result: result, Hold: hold})
+	if err != nil {
+		return fmt.Errorf("error marshalling held result: %w", err)
+	}
+	return tee.WriteSecretFile(path, heldResultPurpose(hold.JobID), data)
+}
+
+func (h *heldResults) load(jobID string) (*heldResult, bool) {
+	if h.dir == "" {
+		return nil, false
+	}
+	path, err := h.path(jobID)
+	if err != nil {
+		return nil, false
+	}
+	data, legacy, err := tee.ReadSecretFile(path, heldResultPurpose(jobID))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, false
+	}
+	if err != nil || legacy {
+		logrus.Errorf("Failed to read held result of job %s: %v (unsealed: %t)", jobID, err, legacy)
+		return nil, false
+	}
+
+	held := &heldResult{}
+	if err := json.Unmarshal(data, held); err != nil {
+		logrus.Errorf("Failed to parse held result of job %s: %v", jobID, err)
+		return nil, false
+	}
+	held.Result.Job.UUID = jobID
+	return held, true
+}
+
+func (h *heldResults) remove(jobID string) bool {
+	if h.dir == "" {
+		return false
+	}
+	path, err := h.path(jobID)
+	if err != nil {
+		return false
+	}
+	return os.Remove(path) == nil
+}
+
+// count returns the number of results held on disk
+func (h *heldResults) count() int {
+	if h.dir == "" {
+		return 0
+	}
+	entries, err := os.ReadDir(h.dir)
+	if err != nil {
+		return 0
+	}
+	n := 0
+	for _, e := range entries {
+		if strings.HasSuffix(e.Name(), ".json") {
+			n++
+		}
+	}
+	return n
+}
+
+// HoldResult retains the result of a job until it is released with ReleaseResult. Held results don't expire, are
+// not evicted from the result cache and are kept across restarts.
+func (js *JobServer) HoldResult(jobID string, tag types.HoldTag) (types.ResultHold, error) {
+	js.holdLock.Lock()
+	defer js.holdLock.Unlock()
+
+	result, ok := js.GetJobResult(jobID)
+	if !ok {
+		return types.ResultHold{}, ErrResultNotFound
+	}
+
+	if _, alreadyHeld := js.results.GetHold(jobID); !alreadyHeld && max(js.results.HeldCount(), js.held.count()) >= js.maxHeldResults {
+		return types.ResultHold{}, ErrTooManyHeldResults
+	}
+
+	hold := types.ResultHold{JobID: jobID, Tag: tag, HeldAt: time.Now().UTC()}
+	if !js.results.Hold(jobID, hold) {
+		// The result of a recurring job may only be kept with the schedule, so it is added to the cache
+		js.results.Set(jobID, result)
+		if !js.results.Hold(jobID, hold) {
+			return types.ResultHold{}, ErrResultNotFound
+		}
+	}
+	if err := js.held.save(result, hold); err != nil {
+		logrus.Errorf("Failed to persist held result of job %s, it will be lost on restart: %v", jobID, err)
+	}
+
+	logrus.Infof("Holding result of job %s (%s)", jobID, tag)
+	return hold, nil
+}
+
+// ReleaseResult removes the hold of a result, which then expires like any other
+func (js *JobServer) ReleaseResult(jobID string) error {
+	js.holdLock.Lock()
+	defer js.holdLock.Unlock()
+
+	released := js.results.Release(jobID)
+	if js.held.remove(jobID) {
+		released = true
+	}
+	if !released {
+		return ErrResultNotHeld
+	}
+
+	logrus.Infof("Released result of job %s", jobID)
+	return nil
+}
+
+// loadHeldResult returns a held result from disk, adding it to the result cache so it is only read once
+func (js *JobServer) loadHeldResult(jobID string) (types.JobResult, bool) {
+	held, ok := js.held.load(jobID)
+	if !ok {
+		return types.JobResult{}, false
+	}
+	js.results.Set(jobID, held.Result)
+	js.results.Hold(jobID, held.Hold)
+	return held.Result, true
+}
+
+// retainIfRequested holds the result of a job which asked for it with the retain argument
+func (js *JobServer) retainIfRequested(j types.Job) {
+	tag, requested, _ := types.HoldTagFromArguments(j.Arguments)
+	if !requested {
+		return
+	}
+	if _, err := js.HoldResult(j.UUID, tag); err != nil {
+		logrus.Errorf("Failed to hold result of job %s: %v", j.UUID, err)
+	}
+}
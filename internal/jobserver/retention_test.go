@@ -0,0 +1,128 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Result retention", func() {
+	var (
+		ctx     context.Context
+		cancel  context.CancelFunc
+		dataDir string
+	)
+
+	newServer := func(maxHeld int) *JobServer {
+		js := NewJobServer(2, config.JobConfiguration{
+			"data_dir":                     dataDir,
+			"result_max_held":              maxHeld,
+			"result_cache_max_age_seconds": 100 * time.Millisecond,
+		})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: &flakyWorker{}}
+		go js.Run(ctx)
+		return js
+	}
+
+	waitForResult := func(js *JobServer, uuid string) {
+		Eventually(func() bool {
+			_, exists := js.GetJobResult(uuid)
+			return exists
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+	}
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		ctx, cancel = context.WithCancel(context.Background())
+		dataDir = GinkgoT().TempDir()
+
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("holds results of jobs submitted with the retain argument", func() {
+		js := newServer(10)
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"retain": "legal-hold"}})
+		Expect(err).NotTo(HaveOccurred())
+		waitForResult(js, uuid)
+
+		Eventually(func() types.HoldTag {
+			hold, _ := js.results.GetHold(uuid)
+			return hold.Tag
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal(types.HoldTagLegalHold))
+
+		time.Sleep(200 * time.Millisecond)
+		js.results.cleanupExpired()
+		_, exists := js.GetJobResult(uuid)
+		Expect(exists).To(BeTrue())
+	})
+
+	It("keeps held results across restarts until they are released", func() {
+		js := newServer(10)
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a"})
+		Expect(err).NotTo(HaveOccurred())
+		waitForResult(js, uuid)
+
+		hold, err := js.HoldResult(uuid, types.HoldTagRetained)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(hold.JobID).To(Equal(uuid))
+		Expect(hold.Tag).To(Equal(types.HoldTagRetained))
+
+		restarted := newServer(10)
+		res, exists := restarted.GetJobResult(uuid)
+		Expect(exists).To(BeTrue())
+		Expect(res.Data).To(Equal([]byte("ok")))
+
+		Expect(restarted.ReleaseResult(uuid)).To(Succeed())
+		Expect(restarted.ReleaseResult(uuid)).To(MatchError(ErrResultNotHeld))
+
+		_, exists = newServer(10).GetJobResult(uuid)
+		Expect(exists).To(BeFalse())
+	})
+
+	It("rejects holds beyond the limit and of unknown results", func() {
+		js := newServer(1)
+		first, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a"})
+		Expect(err).NotTo(HaveOccurred())
+		second, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "b"})
+		Expect(err).NotTo(HaveOccurred())
+		waitForResult(js, first)
+		waitForResult(js, second)
+
+		_, err = js.HoldResult(first, types.HoldTagRetained)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = js.HoldResult(first, types.HoldTagLegalHold)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = js.HoldResult(second, types.HoldTagRetained)
+		Expect(err).To(MatchError(ErrTooManyHeldResults))
+
+		_, err = js.HoldResult("00000000-0000-0000-0000-000000000000", types.HoldTagRetained)
+		Expect(err).To(MatchError(ErrResultNotFound))
+	})
+
+	It("rejects invalid retain arguments", func() {
+		js := newServer(10)
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "a", Arguments: map[string]any{"retain": "forever"}})
+		Expect(err).To(HaveOccurred())
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "b", Arguments: map[string]any{"retain": true, "cache": "no-store"}})
+		Expect(err).To(HaveOccurred())
+	})
+})
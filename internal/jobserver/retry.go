@@ -0,0 +1,94 @@
+package jobserver
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/retry"
+	"github.com/sirupsen/logrus"
+)
+
+// retryableErrorMessages are substrings of the messages of errors which are not typed, but indicate a transient failure
+var retryableErrorMessages = []string{
+	"rate limit exceeded",
+	"status code 429",
+	"status code 502",
+	"status code 503",
+	"status code 504",
+	"connection reset",
+	"connection refused",
+	"temporarily unavailable",
+	"unexpected eof",
+}
+
+// isRetryable returns true if the error returned by a job is transient (rate limit, network) and the job can be re-executed.
+// A job which timed out or was cancelled is never retried.
+func isRetryable(err error) bool {
+	if err == nil || errors.Is(err, ErrJobTimedOut) || errors.Is(err, ErrJobCancelled) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+	if errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) {
+		return true
+	}
+	var codedErr *types.CodedError
+	if errors.As(err, &codedErr) {
+		return codedErr.Code == types.ErrorCodeRateLimited
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, m := range retryableErrorMessages {
+		if strings.Contains(msg, m) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay before executing the given attempt, doubling on every attempt up to the configured maximum
+func backoff(rc config.RetryConfig, attempt int) time.Duration {
+	return retry.Exponential(rc.Backoff, rc.MaxBackoff).Delay(attempt)
+}
+
+// maybeRetry re-queues the job with exponential backoff if the error is retryable and the job has attempts left.
+// It returns true if the job was re-queued.
+func (js *JobServer) maybeRetry(j types.Job, err error) bool {
+	if !isRetryable(err) {
+		return false
+	}
+	// The result of a job whose timeout fired, or which was cancelled, has already been stored
+	select {
+	case <-j.Cancelled:
+		return false
+	default:
+	}
+
+	rc := js.jobConfiguration.GetRetryConfig(j.Type.String())
+	if j.Attempt >= rc.MaxRetries {
+		return false
+	}
+
+	j.Attempt++
+	delay := backoff(rc, j.Attempt)
+	logrus.Infof("Retrying job %s (type %s) in %s, attempt %d of %d: %s", j.UUID, j.Type, delay, j.Attempt, rc.MaxRetries, err)
+	js.events.publish(types.JobEvent{JobUUID: j.UUID, Status: types.JobStatusRetrying, Attempt: j.Attempt})
+	js.executions.get(j).retry(delay, err)
+
+	time.AfterFunc(delay, func() {
+		j.Trace.Queued(time.Now())
+		js.queue(j)
+	})
+
+	return true
+}
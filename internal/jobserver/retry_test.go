@@ -0,0 +1,127 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+type flakyWorker struct {
+	calls    atomic.Int32
+	failures int32
+	err      error
+}
+
+func (f *flakyWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (f *flakyWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	if f.calls.Add(1) <= f.failures {
+		return types.JobResult{Error: f.err.Error()}, f.err
+	}
+	return types.JobResult{Data: []byte("ok")}, nil
+}
+
+var _ = Describe("Retry", func() {
+	It("classifies retryable errors", func() {
+		Expect(isRetryable(errors.New("Rate limit exceeded"))).To(BeTrue())
+		Expect(isRetryable(errors.New("unexpected status code 429: slow down"))).To(BeTrue())
+		Expect(isRetryable(errors.New("read tcp: connection reset by peer"))).To(BeTrue())
+		Expect(isRetryable(errors.New("invalid argument type"))).To(BeFalse())
+		Expect(isRetryable(nil)).To(BeFalse())
+
+		Expect(isRetryable(fmt.Errorf("error reading the response: %w", io.ErrUnexpectedEOF))).To(BeTrue())
+		Expect(isRetryable(fmt.Errorf("error fetching the feed: %w", types.NewCodedError(types.ErrorCodeRateLimited, "slow down")))).To(BeTrue())
+		Expect(isRetryable(types.NewCodedError(types.ErrorCodeNotFound, "feed not found"))).To(BeFalse())
+		Expect(isRetryable(fmt.Errorf("%w after 1m0s", ErrJobTimedOut))).To(BeFalse())
+		// Messages which only mention a timeout or EOF are not transient failures
+		Expect(isRetryable(errors.New("invalid timeout argument"))).To(BeFalse())
+		Expect(isRetryable(errors.New("unknown geoflag"))).To(BeFalse())
+	})
+
+	It("backs off exponentially up to the maximum", func() {
+		rc := config.RetryConfig{MaxRetries: 5, Backoff: time.Second, MaxBackoff: 5 * time.Second}
+		Expect(backoff(rc, 1)).To(Equal(time.Second))
+		Expect(backoff(rc, 2)).To(Equal(2 * time.Second))
+		Expect(backoff(rc, 3)).To(Equal(4 * time.Second))
+		Expect(backoff(rc, 4)).To(Equal(5 * time.Second))
+	})
+
+	It("reads the max retries per job type", func() {
+		jc := config.JobConfiguration{"twitter_credential_max_retries": 3}
+		Expect(jc.GetRetryConfig(string(teetypes.TwitterCredentialJob)).MaxRetries).To(Equal(3))
+		Expect(jc.GetRetryConfig(string(teetypes.WebJob)).MaxRetries).To(Equal(0))
+	})
+
+	Context("with a flaky worker", func() {
+		var (
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+
+		BeforeEach(func() {
+			config.MinersWhiteList = ""
+			ctx, cancel = context.WithCancel(context.Background())
+		})
+
+		AfterEach(func() {
+			cancel()
+		})
+
+		runJob := func(jc config.JobConfiguration, w *flakyWorker) string {
+			js := NewJobServer(1, jc)
+			js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+			go js.Run(ctx)
+
+			uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob})
+			Expect(err).NotTo(HaveOccurred())
+
+			var res types.JobResult
+			Eventually(func() bool {
+				var exists bool
+				res, exists = js.GetJobResult(uuid)
+				return exists
+			}, "5s").Should(BeTrue())
+			return res.Error
+		}
+
+		It("retries transient failures", func() {
+			w := &flakyWorker{failures: 2, err: errors.New("Rate limit exceeded")}
+			jc := config.JobConfiguration{
+				"web_max_retries":           2,
+				"retry_backoff_seconds":     10 * time.Millisecond,
+				"retry_max_backoff_seconds": 50 * time.Millisecond,
+			}
+			Expect(runJob(jc, w)).To(BeEmpty())
+			Expect(w.calls.Load()).To(Equal(int32(3)))
+		})
+
+		It("gives up after the max retries", func() {
+			w := &flakyWorker{failures: 5, err: errors.New("Rate limit exceeded")}
+			jc := config.JobConfiguration{
+				"web_max_retries":           1,
+				"retry_backoff_seconds":     10 * time.Millisecond,
+				"retry_max_backoff_seconds": 50 * time.Millisecond,
+			}
+			Expect(runJob(jc, w)).To(Equal("Rate limit exceeded"))
+			Expect(w.calls.Load()).To(Equal(int32(2)))
+		})
+
+		It("does not retry permanent failures", func() {
+			w := &flakyWorker{failures: 5, err: errors.New("invalid argument type")}
+			jc := config.JobConfiguration{"web_max_retries": 3}
+			Expect(runJob(jc, w)).To(Equal("invalid argument type"))
+			Expect(w.calls.Load()).To(Equal(int32(1)))
+		})
+	})
+})
@@ -0,0 +1,103 @@
+package jobserver
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// sampleArgumentKey is the job argument used by clients to get a random sample of the result instead of all of it
+const sampleArgumentKey = "sample"
+
+// Sample selects a random subset of the items of a result. Each item is kept with probability Rate, decided by
+// hashing it together with Seed, so the same seed always selects the same items, whichever other items are part of
+// the result and in whichever order they are returned.
+type Sample struct {
+	Rate float64 `json:"rate"`
+	Seed int64   `json:"seed"`
+}
+
+// sampleFromArguments extracts the sample from the job arguments. It returns nil if no sample was requested.
+func sampleFromArguments(args types.JobArguments) (*Sample, error) {
+	v, ok := args[sampleArgumentKey]
+	if !ok || v == nil {
+		return nil, nil
+	}
+
+	m, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object with a rate and a seed, got %T", sampleArgumentKey, v)
+	}
+	for k := range m {
+		if k != "rate" && k != "seed" {
+			return nil, fmt.Errorf("unknown %s field %q, valid fields are rate and seed", sampleArgumentKey, k)
+		}
+	}
+
+	rate, ok := m["rate"].(float64)
+	if !ok {
+		return nil, fmt.Errorf("%s.rate must be a number, got %T", sampleArgumentKey, m["rate"])
+	}
+	if rate <= 0 || rate > 1 {
+		return nil, fmt.Errorf("%s.rate must be greater than 0 and at most 1, got %v", sampleArgumentKey, rate)
+	}
+
+	s := &Sample{Rate: rate}
+	if seed, ok := m["seed"]; ok && seed != nil {
+		f, ok := seed.(float64)
+		if !ok || f != math.Trunc(f) {
+			return nil, fmt.Errorf("%s.seed must be an integer, got %v", sampleArgumentKey, seed)
+		}
+		s.Seed = int64(f)
+	}
+	return s, nil
+}
+
+// Apply returns the sampled items of a JSON-encoded job result. Results which are not a JSON array, e.g. a single
+// profile, are returned as they are.
+func (s Sample) Apply(data []byte) ([]byte, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return data, nil
+	}
+
+	sampled := make([]json.RawMessage, 0, int(math.Ceil(float64(len(items))*s.Rate)))
+	for _, item := range items {
+		if s.keep(item) {
+			sampled = append(sampled, item)
+		}
+	}
+
+	dat, err := json.Marshal(sampled)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling sampled result: %w", err)
+	}
+	return dat, nil
+}
+
+// keep decides whether an item is part of the sample. Items are compacted first, so the decision doesn't depend on
+// how they are formatted.
+func (s Sample) keep(item json.RawMessage) bool {
+	if s.Rate >= 1 {
+		return true
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, item); err != nil {
+		compact.Reset()
+		compact.Write(item)
+	}
+
+	h := sha256.New()
+	_ = binary.Write(h, binary.BigEndian, s.Seed)
+	h.Write(compact.Bytes())
+	sum := h.Sum(nil)
+
+	// The first 53 bits of the hash are a uniformly distributed number in [0, 1)
+	return float64(binary.BigEndian.Uint64(sum[:8])>>11)/(1<<53) < s.Rate
+}
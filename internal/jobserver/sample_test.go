@@ -0,0 +1,137 @@
+package jobserver
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// itemsWorker returns a JSON array with the given number of items
+type itemsWorker struct {
+	items int
+}
+
+func (w itemsWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (w itemsWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	return types.JobResult{Data: items(0, w.items)}, nil
+}
+
+func items(from, to int) []byte {
+	var v []map[string]any
+	for i := from; i < to; i++ {
+		v = append(v, map[string]any{"id": fmt.Sprint(i), "text": fmt.Sprintf("tweet %d", i)})
+	}
+	dat, err := json.Marshal(v)
+	Expect(err).NotTo(HaveOccurred())
+	return dat
+}
+
+func ids(data []byte) []string {
+	var v []map[string]any
+	Expect(json.Unmarshal(data, &v)).To(Succeed())
+	var ids []string
+	for _, item := range v {
+		ids = append(ids, item["id"].(string))
+	}
+	return ids
+}
+
+var _ = Describe("Sampling", func() {
+	It("parses the sample argument", func() {
+		s, err := sampleFromArguments(types.JobArguments{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(BeNil())
+
+		s, err = sampleFromArguments(types.JobArguments{"sample": map[string]any{"rate": 0.1, "seed": float64(42)}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(&Sample{Rate: 0.1, Seed: 42}))
+
+		s, err = sampleFromArguments(types.JobArguments{"sample": map[string]any{"rate": 1.0}})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s).To(Equal(&Sample{Rate: 1}))
+
+		for _, invalid := range []any{
+			"0.1",
+			map[string]any{"seed": float64(42)},
+			map[string]any{"rate": 0.0},
+			map[string]any{"rate": 1.5},
+			map[string]any{"rate": 0.1, "seed": 4.2},
+			map[string]any{"rate": 0.1, "sed": float64(42)},
+		} {
+			_, err := sampleFromArguments(types.JobArguments{"sample": invalid})
+			Expect(err).To(HaveOccurred(), "%v", invalid)
+		}
+	})
+
+	It("selects the same items for the same seed", func() {
+		s := Sample{Rate: 0.1, Seed: 42}
+		sampled, err := s.Apply(items(0, 1000))
+		Expect(err).NotTo(HaveOccurred())
+		selected := ids(sampled)
+		Expect(len(selected)).To(BeNumerically("~", 100, 30))
+
+		again, err := s.Apply(items(0, 1000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(again)).To(Equal(selected))
+
+		other, err := Sample{Rate: 0.1, Seed: 43}.Apply(items(0, 1000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ids(other)).NotTo(Equal(selected))
+	})
+
+	It("selects items regardless of the other items of the result", func() {
+		s := Sample{Rate: 0.5, Seed: 7}
+		all, err := s.Apply(items(0, 200))
+		Expect(err).NotTo(HaveOccurred())
+		half, err := s.Apply(items(100, 200))
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(ids(half)).To(Equal(slices.DeleteFunc(ids(all), func(id string) bool {
+			var n int
+			fmt.Sscan(id, &n)
+			return n < 100
+		})))
+	})
+
+	It("leaves results which are not arrays untouched", func() {
+		data := []byte(`{"username":"masa"}`)
+		sampled, err := Sample{Rate: 0.1}.Apply(data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(sampled).To(Equal(data))
+	})
+
+	It("samples the results of jobs", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: itemsWorker{items: 1000}}
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+
+		run := func(nonce string) types.JobResult {
+			uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: nonce, Arguments: types.JobArguments{
+				"sample": map[string]any{"rate": 0.1, "seed": float64(42)},
+			}})
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+			res, ok := js.GetJobResult(uuid)
+			Expect(ok).To(BeTrue())
+			Expect(res.Error).To(BeEmpty())
+			return res
+		}
+
+		first := run("1")
+		Expect(len(ids(first.Data))).To(BeNumerically("<", 200))
+		Expect(ids(run("2").Data)).To(Equal(ids(first.Data)))
+	})
+})
@@ -0,0 +1,48 @@
+package jobserver
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+	base := time.Date(2025, time.March, 14, 10, 7, 30, 0, time.UTC)
+
+	next := func(expr string, t time.Time) time.Time {
+		s, err := ParseSchedule(expr)
+		Expect(err).NotTo(HaveOccurred())
+		return s.Next(t)
+	}
+
+	It("computes the next run of cron expressions", func() {
+		Expect(next("* * * * *", base)).To(Equal(time.Date(2025, time.March, 14, 10, 8, 0, 0, time.UTC)))
+		Expect(next("*/15 * * * *", base)).To(Equal(time.Date(2025, time.March, 14, 10, 15, 0, 0, time.UTC)))
+		Expect(next("0 9-17 * * *", base)).To(Equal(time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)))
+		Expect(next("30 6 * * *", base)).To(Equal(time.Date(2025, time.March, 15, 6, 30, 0, 0, time.UTC)))
+		Expect(next("0 0 1 1 *", base)).To(Equal(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(next("5,10 12 * * *", base)).To(Equal(time.Date(2025, time.March, 14, 12, 5, 0, 0, time.UTC)))
+	})
+
+	It("handles days of the week", func() {
+		// 14 March 2025 is a Friday
+		Expect(next("0 0 * * 1", base)).To(Equal(time.Date(2025, time.March, 17, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 * * 7", base)).To(Equal(time.Date(2025, time.March, 16, 0, 0, 0, 0, time.UTC)))
+		// Either the day of month or the day of week matches if both are restricted
+		Expect(next("0 0 20 * 6", base)).To(Equal(time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("supports descriptors and intervals", func() {
+		Expect(next("@hourly", base)).To(Equal(time.Date(2025, time.March, 14, 11, 0, 0, 0, time.UTC)))
+		Expect(next("@daily", base)).To(Equal(time.Date(2025, time.March, 15, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@every 90m", base)).To(Equal(base.Add(90 * time.Minute)))
+	})
+
+	It("rejects invalid schedules", func() {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *", "@every 10s", "@every soon"} {
+			_, err := ParseSchedule(expr)
+			Expect(err).To(HaveOccurred(), expr)
+		}
+	})
+})
@@ -0,0 +1,65 @@
+package jobserver
+
+import (
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// twitterSessionTransferer is implemented by the Twitter worker, whose accounts keep login sessions
+type twitterSessionTransferer interface {
+	ExportSessions() (types.TwitterSessions, error)
+	ImportSessions(sessions types.TwitterSessions) (types.TwitterSessionsImport, error)
+}
+
+// twitterSessions returns the worker which keeps the sessions of the Twitter accounts, or types.ErrNotConfigured if
+// there is none, e.g. in binaries built without the Twitter scraper or in simulation mode
+func (js *JobServer) twitterSessions() (twitterSessionTransferer, error) {
+	entry, ok := js.workerEntries()[teetypes.TwitterCredentialJob]
+	if !ok {
+		return nil, types.ErrNotConfigured
+	}
+	t, ok := entry.w.(twitterSessionTransferer)
+	if !ok {
+		return nil, types.ErrNotConfigured
+	}
+	return t, nil
+}
+
+// ExportTwitterSessions returns the sealed sessions of the Twitter accounts, to be imported by another worker with
+// the same sealing key
+func (js *JobServer) ExportTwitterSessions() (types.TwitterSessions, error) {
+	t, err := js.twitterSessions()
+	if err != nil {
+		return types.TwitterSessions{}, err
+	}
+	return t.ExportSessions()
+}
+
+// ImportTwitterSessions replaces the sessions of the Twitter accounts with sessions exported by another worker. It
+// returns types.ErrInvalidSessions if they can't be unsealed.
+func (js *JobServer) ImportTwitterSessions(sessions types.TwitterSessions) (types.TwitterSessionsImport, error) {
+	t, err := js.twitterSessions()
+	if err != nil {
+		return types.TwitterSessionsImport{}, err
+	}
+	return t.ImportSessions(sessions)
+}
+
+// twitterAccountChecker is implemented by the Twitter worker, which can verify the sessions of its accounts
+type twitterAccountChecker interface {
+	CheckAccounts() (types.TwitterAuthCheck, error)
+}
+
+// CheckTwitterAccounts verifies the session of every Twitter account and refreshes its cookies. It returns
+// types.ErrNotConfigured if there are no Twitter accounts.
+func (js *JobServer) CheckTwitterAccounts() (types.TwitterAuthCheck, error) {
+	entry, ok := js.workerEntries()[teetypes.TwitterCredentialJob]
+	if !ok {
+		return types.TwitterAuthCheck{}, types.ErrNotConfigured
+	}
+	c, ok := entry.w.(twitterAccountChecker)
+	if !ok {
+		return types.TwitterAuthCheck{}, types.ErrNotConfigured
+	}
+	return c.CheckAccounts()
+}
@@ -0,0 +1,80 @@
+package jobserver
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+// maxStreams is the number of jobs whose result stream is remembered. Once it is exceeded, the oldest stream which is
+// done is forgotten, or the oldest stream if they are all still open.
+const maxStreams = 1000
+
+type jobStream struct {
+	stream *types.ResultStream
+	nonce  string // The batches are sealed with the nonce of the job, like its result
+}
+
+// jobStreams remembers the result streams of the jobs, so the batches they emit can be read while they run
+type jobStreams struct {
+	sync.Mutex
+	streams map[string]jobStream
+	order   []string
+}
+
+func newJobStreams() *jobStreams {
+	return &jobStreams{streams: make(map[string]jobStream)}
+}
+
+// add returns a new result stream for the job
+func (s *jobStreams) add(j types.Job) *types.ResultStream {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.order) >= maxStreams {
+		i := max(slices.IndexFunc(s.order, func(uuid string) bool { return s.streams[uuid].stream.Done() }), 0)
+		delete(s.streams, s.order[i])
+		s.order = slices.Delete(s.order, i, i+1)
+	}
+	stream := types.NewResultStream()
+	s.streams[j.UUID] = jobStream{stream: stream, nonce: j.Nonce}
+	s.order = append(s.order, j.UUID)
+	return stream
+}
+
+func (s *jobStreams) get(jobUUID string) (jobStream, bool) {
+	s.Lock()
+	defer s.Unlock()
+	js, ok := s.streams[jobUUID]
+	return js, ok
+}
+
+// GetJobStream returns the batches a job emitted after the batch with the given sequence number, sealed like its
+// result. If there are none yet and the job has not finished, it waits for the next batch until ctx is done. It
+// returns false if the job is unknown, or its stream has been forgotten.
+func (js *JobServer) GetJobStream(ctx context.Context, jobUUID string, after int) (types.StreamResponse, bool, error) {
+	s, ok := js.streams.get(jobUUID)
+	if !ok {
+		return types.StreamResponse{}, false, nil
+	}
+	seal := func(data []byte) (string, error) {
+		return tee.SealWithKey(s.nonce, data)
+	}
+
+	for {
+		batches, done, changed, err := s.stream.Since(after, seal)
+		if err != nil {
+			return types.StreamResponse{}, true, err
+		}
+		if len(batches) > 0 || done {
+			return types.StreamResponse{JobUUID: jobUUID, Batches: batches, Done: done}, true, nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return types.StreamResponse{JobUUID: jobUUID, Batches: batches}, true, nil
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// streamingWorker emits a batch on the result stream of the job, and then waits until it is released
+type streamingWorker struct {
+	release chan struct{}
+}
+
+func (s *streamingWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (s *streamingWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	j.Stream.Emit([]byte(`{"poll":1}`))
+	<-s.release
+	return types.JobResult{Data: []byte("done")}, nil
+}
+
+var _ = Describe("Result streams", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		w      *streamingWorker
+		js     *JobServer
+	)
+
+	BeforeEach(func() {
+		keyRing := tee.CurrentKeyRing
+		standalone := tee.SealStandaloneMode
+		tee.CurrentKeyRing = tee.NewKeyRing()
+		Expect(tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")).To(BeTrue())
+		tee.SealStandaloneMode = false
+		DeferCleanup(func() {
+			tee.CurrentKeyRing = keyRing
+			tee.SealStandaloneMode = standalone
+		})
+
+		config.MinersWhiteList = ""
+		ctx, cancel = context.WithCancel(context.Background())
+		w = &streamingWorker{release: make(chan struct{})}
+		js = NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns the sealed batches emitted while the job runs, and ends with the job", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "nonce"})
+		Expect(err).NotTo(HaveOccurred())
+
+		waitCtx, waitCancel := context.WithTimeout(ctx, 2*time.Second)
+		defer waitCancel()
+		resp, ok, err := js.GetJobStream(waitCtx, uuid, 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.JobUUID).To(Equal(uuid))
+		Expect(resp.Done).To(BeFalse())
+		Expect(resp.Batches).To(HaveLen(1))
+		Expect(resp.Batches[0].Seq).To(Equal(1))
+
+		data, err := tee.UnsealWithKey("nonce", resp.Batches[0].Data)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`{"poll":1}`))
+
+		// Waiting for the next batch returns once the job has finished
+		close(w.release)
+		resp, ok, err = js.GetJobStream(waitCtx, uuid, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.Batches).To(BeEmpty())
+		Expect(resp.Done).To(BeTrue())
+	})
+
+	It("returns without batches once the wait expires", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "nonce"})
+		Expect(err).NotTo(HaveOccurred())
+		defer close(w.release)
+
+		waitCtx, waitCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer waitCancel()
+		Eventually(func() int {
+			resp, _, _ := js.GetJobStream(ctx, uuid, 0)
+			return len(resp.Batches)
+		}, 2*time.Second, 10*time.Millisecond).Should(Equal(1))
+
+		resp, ok, err := js.GetJobStream(waitCtx, uuid, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeTrue())
+		Expect(resp.Batches).To(BeEmpty())
+		Expect(resp.Done).To(BeFalse())
+	})
+
+	It("reports unknown jobs", func() {
+		_, ok, err := js.GetJobStream(ctx, "unknown", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ok).To(BeFalse())
+	})
+
+	It("keeps the stream of a recurring job open until it is unscheduled", func() {
+		close(w.release)
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "nonce", Arguments: map[string]any{"schedule": "@every 5m"}})
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(func() bool {
+			_, exists := js.recurring.latest(uuid)
+			return exists
+		}, 2*time.Second, 10*time.Millisecond).Should(BeTrue())
+
+		s, ok := js.streams.get(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(s.stream.Done()).To(BeFalse())
+
+		Expect(js.RemoveRecurringJob(uuid)).To(Succeed())
+		Expect(s.stream.Done()).To(BeTrue())
+	})
+
+	It("ends the stream of a job which is not accepted", func() {
+		close(w.release)
+		js.recurring = newRecurringJobs(1)
+		s, err := ParseSchedule("@every 5m")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(js.recurring.add(types.Job{UUID: "other"}, ExecutionClassInteractive, s, time.Now(), 0)).To(Succeed())
+
+		_, err = js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "nonce", Arguments: map[string]any{"schedule": "@every 5m"}})
+		Expect(err).To(MatchError(ErrTooManyRecurringJobs))
+
+		js.streams.Lock()
+		defer js.streams.Unlock()
+		Expect(js.streams.streams).To(HaveLen(1))
+		for _, s := range js.streams.streams {
+			Expect(s.stream.Done()).To(BeTrue())
+		}
+	})
+})
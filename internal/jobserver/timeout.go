@@ -0,0 +1,43 @@
+package jobserver
+
+import (
+	"fmt"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrJobTimedOut is the error of the result of a job which did not finish within its timeout and grace period
+var ErrJobTimedOut = types.ErrJobTimedOut
+
+// jobTimeout returns the timeout of a job, which depends on its job type and capability, see config.GetJobTimeout
+func (js *JobServer) jobTimeout(j types.Job) time.Duration {
+	capability, _ := j.Arguments["type"].(string)
+	if capability == "" {
+		capability = string(teetypes.JobDefaultCapabilityMap[j.Type])
+	}
+	return js.jobConfiguration.GetJobTimeout(j.Type.String(), capability)
+}
+
+// enforceTimeout times out a running job once its timeout and JOB_TIMEOUT_GRACE_SECONDS have passed. Jobs are expected
+// to stop by themselves at their timeout, the grace period lets them return what they have collected so far. A job
+// which is still running afterwards is cancelled like with CancelJob, so its result is an ErrJobTimedOut error and its
+// Apify actor runs are aborted. The returned function stops enforcing the timeout, and has to be called once the job
+// has finished.
+func (js *JobServer) enforceTimeout(j types.Job) (stop func()) {
+	if j.Timeout <= 0 {
+		return func() {}
+	}
+	grace := js.jobConfiguration.GetDuration("job_timeout_grace_seconds", 30)
+	timer := time.AfterFunc(j.Timeout+grace, func() {
+		timedOut := js.pending.cancel(j.UUID, func(string) {
+			js.results.Set(j.UUID, types.JobResult{Job: j, Error: fmt.Sprintf("%s after %s", ErrJobTimedOut, j.Timeout)})
+		})
+		if timedOut {
+			logrus.Warnf("Job %s of type %s timed out after %s", j.UUID, j.Type, j.Timeout)
+		}
+	})
+	return func() { timer.Stop() }
+}
@@ -0,0 +1,87 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Job timeouts", func() {
+	It("depends on the job type and capability", func() {
+		js := NewJobServer(1, config.JobConfiguration{
+			"job_timeout_seconds":                5 * time.Minute,
+			"twitter_apify_getfollowers_timeout": 20 * time.Minute,
+			"web_timeout":                        2 * time.Minute,
+		})
+
+		job := func(jobType teetypes.JobType, capability string) types.Job {
+			return types.Job{Type: jobType, Arguments: map[string]any{"type": capability}}
+		}
+		Expect(js.jobTimeout(job(teetypes.TwitterApifyJob, "getfollowers"))).To(Equal(20 * time.Minute))
+		Expect(js.jobTimeout(job(teetypes.TwitterApifyJob, "getfollowing"))).To(Equal(15 * time.Minute))
+		Expect(js.jobTimeout(job(teetypes.TwitterApiJob, "getbyid"))).To(Equal(time.Minute))
+		Expect(js.jobTimeout(job(teetypes.TwitterApiJob, "searchbyquery"))).To(Equal(5 * time.Minute))
+		Expect(js.jobTimeout(job(teetypes.WebJob, "scraper"))).To(Equal(2 * time.Minute))
+
+		// Jobs without a capability have the timeout of the default capability of their job type
+		Expect(js.jobTimeout(types.Job{Type: teetypes.TwitterApifyJob})).To(Equal(20 * time.Minute))
+	})
+
+	It("defaults to JOB_TIMEOUT_SECONDS", func() {
+		Expect(config.JobConfiguration{}.GetJobTimeout("tiktok", "transcription")).To(Equal(300 * time.Second))
+	})
+
+	It("times out jobs which are still running after their timeout and grace period", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{"job_timeout_grace_seconds": 100 * time.Millisecond})
+		web := &gatedWorker{release: make(chan struct{})}
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: web}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go js.Run(ctx)
+
+		js.pending.add("w1", "")
+		Expect(js.dispatch(types.Job{UUID: "w1", Type: teetypes.WebJob, Timeout: 100 * time.Millisecond}, ExecutionClassInteractive)).To(Succeed())
+
+		Eventually(js.JobDone("w1"), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult("w1")
+		Expect(ok).To(BeTrue())
+		Expect(res.Error).To(Equal("job timed out after 100ms"))
+		Expect(res.Job.Timeout).To(Equal(100 * time.Millisecond))
+		Expect(types.ClassifyError(errors.New(res.Error))).To(Equal(types.ErrorCodeTimeout))
+
+		// The result of the job is discarded once it finishes
+		close(web.release)
+		Eventually(web.done.Load, "5s").Should(BeEquivalentTo(1))
+		Consistently(func() string {
+			res, _ := js.GetJobResult("w1")
+			return res.Error
+		}, "500ms").Should(ContainSubstring(ErrJobTimedOut.Error()))
+	})
+
+	It("does not time out jobs which finish in time", func() {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{"job_timeout_grace_seconds": time.Duration(0)})
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: &flakyWorker{}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		go js.Run(ctx)
+
+		js.pending.add("w1", "")
+		Expect(js.dispatch(types.Job{UUID: "w1", Type: teetypes.WebJob, Timeout: 200 * time.Millisecond}, ExecutionClassInteractive)).To(Succeed())
+
+		Eventually(js.JobDone("w1"), "5s").Should(BeClosed())
+		Consistently(func() string {
+			res, _ := js.GetJobResult("w1")
+			return res.Error
+		}, "500ms").Should(BeEmpty())
+	})
+})
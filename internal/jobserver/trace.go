@@ -0,0 +1,67 @@
+package jobserver
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// maxTraces is the number of jobs whose execution trace is remembered. The oldest trace is forgotten once it is
+// exceeded.
+const maxTraces = 1000
+
+// validateDebugArgument checks that the debug argument, if present, is a boolean
+func validateDebugArgument(args types.JobArguments) error {
+	v, ok := args[types.DebugArgumentKey]
+	if !ok || v == nil {
+		return nil
+	}
+	if _, ok := v.(bool); !ok {
+		return fmt.Errorf("%s must be a boolean, got %T", types.DebugArgumentKey, v)
+	}
+	return nil
+}
+
+// jobTraces remembers the trace recorders of the jobs submitted with the debug argument, so their trace can be
+// read while they run and after they have finished
+type jobTraces struct {
+	sync.Mutex
+	recorders map[string]*types.TraceRecorder
+	order     []string
+}
+
+func newJobTraces() *jobTraces {
+	return &jobTraces{recorders: make(map[string]*types.TraceRecorder)}
+}
+
+// add returns a new trace recorder for the job
+func (t *jobTraces) add(jobUUID string) *types.TraceRecorder {
+	t.Lock()
+	defer t.Unlock()
+	if len(t.order) >= maxTraces {
+		delete(t.recorders, t.order[0])
+		t.order = t.order[1:]
+	}
+	r := types.NewTraceRecorder(jobUUID)
+	t.recorders[jobUUID] = r
+	t.order = append(t.order, jobUUID)
+	return r
+}
+
+func (t *jobTraces) get(jobUUID string) (*types.TraceRecorder, bool) {
+	t.Lock()
+	defer t.Unlock()
+	r, ok := t.recorders[jobUUID]
+	return r, ok
+}
+
+// GetJobTrace returns the execution trace of a job recorded so far. It returns false if the job was not submitted
+// with the debug argument, or its trace has been forgotten.
+func (js *JobServer) GetJobTrace(jobUUID string) (*types.JobTrace, bool) {
+	r, ok := js.traces.get(jobUUID)
+	if !ok {
+		return nil, false
+	}
+	return r.Trace(), true
+}
@@ -0,0 +1,130 @@
+package jobserver
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var _ = Describe("Trace", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		server *httptest.Server
+	)
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		ctx, cancel = context.WithCancel(context.Background())
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+		}))
+	})
+
+	AfterEach(func() {
+		cancel()
+		server.Close()
+	})
+
+	runJob := func(js *JobServer, w worker, args types.JobArguments) (string, types.JobResult) {
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: args, Nonce: time.Now().String()})
+		Expect(err).NotTo(HaveOccurred())
+
+		var res types.JobResult
+		Eventually(func() bool {
+			var ok bool
+			res, ok = js.GetJobResult(uuid)
+			return ok
+		}, 5*time.Second, 10*time.Millisecond).Should(BeTrue())
+		return uuid, res
+	}
+
+	kinds := func(trace *types.JobTrace) []string {
+		var names []string
+		for _, e := range trace.Events {
+			names = append(names, string(e.Kind)+":"+e.Name)
+		}
+		return names
+	}
+
+	It("exports the spans of the job and its HTTP requests", func() {
+		recorder := tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+		js := NewJobServer(1, config.JobConfiguration{})
+		_, res := runJob(js, &downloadingWorker{url: server.URL}, nil)
+		Expect(res.Error).To(BeEmpty())
+
+		spans := make(map[string]sdktrace.ReadOnlySpan)
+		for _, s := range recorder.Ended() {
+			spans[s.Name()] = s
+		}
+		Expect(spans).To(HaveKey("job web"))
+		Expect(spans).To(HaveKey("execute"))
+		Expect(spans).To(HaveKey("GET 127.0.0.1"))
+		Expect(spans["execute"].Parent().SpanID()).To(Equal(spans["job web"].SpanContext().SpanID()))
+		Expect(spans["GET 127.0.0.1"].Parent().SpanID()).To(Equal(spans["execute"].SpanContext().SpanID()))
+	})
+
+	It("rejects a debug argument which is not a boolean", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		_, err := js.AddJob(types.Job{Type: teetypes.WebJob, Arguments: types.JobArguments{types.DebugArgumentKey: "yes"}})
+		Expect(err).To(MatchError(ContainSubstring("debug must be a boolean")))
+	})
+
+	It("records no trace unless debug is requested", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		uuid, res := runJob(js, &downloadingWorker{url: server.URL}, nil)
+		Expect(res.Trace).To(BeNil())
+
+		_, ok := js.GetJobTrace(uuid)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("records the phases and HTTP requests of the job", func() {
+		js := NewJobServer(1, config.JobConfiguration{})
+		uuid, res := runJob(js, &downloadingWorker{url: server.URL + "/page?key=secret"}, types.JobArguments{types.DebugArgumentKey: true})
+		Expect(res.Error).To(BeEmpty())
+		Expect(res.Trace).NotTo(BeNil())
+		Expect(res.Trace.JobUUID).To(Equal(uuid))
+		Expect(kinds(res.Trace)).To(Equal([]string{"phase:queue", "http:", "phase:execute"}))
+
+		req := res.Trace.Events[1]
+		Expect(req.StatusCode).To(Equal(http.StatusAccepted))
+		Expect(req.URL).To(Equal(server.URL + "/page"))
+
+		trace, ok := js.GetJobTrace(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(trace.Events).To(Equal(res.Trace.Events))
+	})
+
+	It("records the retries of the job", func() {
+		js := NewJobServer(1, config.JobConfiguration{
+			"web_max_retries":           1,
+			"retry_backoff_seconds":     10 * time.Millisecond,
+			"retry_max_backoff_seconds": 50 * time.Millisecond,
+		})
+		w := &flakyWorker{failures: 1, err: errors.New("Rate limit exceeded")}
+		_, res := runJob(js, w, types.JobArguments{types.DebugArgumentKey: true})
+		Expect(res.Error).To(BeEmpty())
+		Expect(kinds(res.Trace)).To(Equal([]string{"phase:queue", "phase:execute", "retry:", "phase:queue", "phase:execute"}))
+		Expect(res.Trace.Events[1].Error).To(Equal("Rate limit exceeded"))
+		Expect(res.Trace.Events[4].Attempt).To(Equal(res.Trace.Events[0].Attempt + 1))
+	})
+})
@@ -0,0 +1,55 @@
+package jobserver
+
+import (
+	"context"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// dataWorker returns the same result data for every job
+type dataWorker struct {
+	flakyWorker
+	data string
+}
+
+func (d *dataWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	return types.JobResult{Data: []byte(d.data)}, nil
+}
+
+var _ = Describe("Result validation", func() {
+	execute := func(data string) types.JobResult {
+		config.MinersWhiteList = ""
+		js := NewJobServer(1, config.JobConfiguration{})
+		js.jobWorkers[teetypes.TwitterJob] = &jobWorkerEntry{w: &dataWorker{data: data}}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		DeferCleanup(cancel)
+		go js.Run(ctx)
+
+		js.pending.add("t1", "")
+		j := types.Job{UUID: "t1", Type: teetypes.TwitterJob, Arguments: map[string]any{"type": "searchbyquery"}}
+		Expect(js.dispatch(j, ExecutionClassInteractive)).To(Succeed())
+		Eventually(js.JobDone("t1"), "5s").Should(BeClosed())
+		res, ok := js.GetJobResult("t1")
+		Expect(ok).To(BeTrue())
+		return res
+	}
+
+	It("drops the invalid items of a result", func() {
+		res := execute(`[{"tweet_id":"1","text":"hello"},{"tweet_id":"2","text":""}]`)
+		Expect(res.Error).To(BeEmpty())
+		Expect(res.Data).To(MatchJSON(`[{"tweet_id":"1","text":"hello"}]`))
+		Expect(res.Validation.Invalid).To(Equal(1))
+	})
+
+	It("fails jobs whose result has no valid item", func() {
+		res := execute(`[{"tweet_id":"1","text":""}]`)
+		Expect(res.Error).To(Equal("error while validating result: none of the result items are valid"))
+		Expect(res.Data).To(BeEmpty())
+		Expect(res.Validation).To(Equal(&types.ResultValidation{Items: 1, Invalid: 1, Reasons: map[string]int{"text is missing": 1}}))
+	})
+})
@@ -0,0 +1,132 @@
+package jobserver
+
+import (
+	"context"
+	"sync"
+
+	"github.com/masa-finance/tee-worker/api/types"
+)
+
+// pendingJobs tracks the jobs which have been accepted but have no result yet, so clients can wait for them
+type pendingJobs struct {
+	sync.Mutex
+	done       map[string]chan struct{}
+	requesters map[string]string   // worker IDs of the miners which submitted the jobs
+	cancelled  map[string]struct{} // cancelled jobs which have not reached a worker or are still running
+}
+
+func newPendingJobs() *pendingJobs {
+	return &pendingJobs{done: make(map[string]chan struct{}), requesters: make(map[string]string), cancelled: make(map[string]struct{})}
+}
+
+// add starts tracking a job submitted by the given requester
+func (p *pendingJobs) add(uuid, requester string) {
+	p.Lock()
+	defer p.Unlock()
+	p.done[uuid] = make(chan struct{})
+	p.requesters[uuid] = requester
+}
+
+// requester returns the worker ID of the miner which submitted a pending job, and false if the job is not pending
+func (p *pendingJobs) requester(uuid string) (string, bool) {
+	p.Lock()
+	defer p.Unlock()
+	requester, ok := p.requesters[uuid]
+	return requester, ok
+}
+
+// finish stops tracking a job, waking up everyone waiting for it. It does nothing if the job is not tracked.
+func (p *pendingJobs) finish(uuid string) {
+	p.Lock()
+	defer p.Unlock()
+	if done, ok := p.done[uuid]; ok {
+		close(done)
+		delete(p.done, uuid)
+		delete(p.requesters, uuid)
+	}
+}
+
+// cancel stops tracking a pending job like finish, but first calls store with the requester of the job to save its
+// result, and remembers that it was cancelled. It returns false if the job is not pending.
+func (p *pendingJobs) cancel(uuid string, store func(requester string)) bool {
+	p.Lock()
+	defer p.Unlock()
+	done, ok := p.done[uuid]
+	if !ok {
+		return false
+	}
+	store(p.requesters[uuid])
+	p.cancelled[uuid] = struct{}{}
+	close(done)
+	delete(p.done, uuid)
+	delete(p.requesters, uuid)
+	return true
+}
+
+// isCancelled returns true if the job has been cancelled and its execution has not ended yet
+func (p *pendingJobs) isCancelled(uuid string) bool {
+	p.Lock()
+	defer p.Unlock()
+	_, ok := p.cancelled[uuid]
+	return ok
+}
+
+// endCancelled forgets a cancelled job once its execution has ended. It returns false if the job was not cancelled.
+func (p *pendingJobs) endCancelled(uuid string) bool {
+	p.Lock()
+	defer p.Unlock()
+	if _, ok := p.cancelled[uuid]; !ok {
+		return false
+	}
+	delete(p.cancelled, uuid)
+	return true
+}
+
+// len returns the number of pending jobs
+func (p *pendingJobs) len() int {
+	p.Lock()
+	defer p.Unlock()
+	return len(p.done)
+}
+
+// wait returns a channel which is closed once the job has finished, or nil if the job is not pending
+func (p *pendingJobs) wait(uuid string) <-chan struct{} {
+	p.Lock()
+	defer p.Unlock()
+	return p.done[uuid]
+}
+
+// WaitForJobResult returns the result of a job like GetJobResult, but if the job is still pending it first waits
+// until it has finished or ctx is done. It returns immediately for unknown jobs.
+func (js *JobServer) WaitForJobResult(ctx context.Context, uuid string) (types.JobResult, bool) {
+	if res, ok := js.GetJobResult(uuid); ok {
+		return res, true
+	}
+
+	if done := js.pending.wait(uuid); done != nil {
+		select {
+		case <-done:
+		case <-ctx.Done():
+		}
+	}
+	return js.GetJobResult(uuid)
+}
+
+// JobRequester returns the worker ID of the miner which submitted a job, without consuming its result. It returns
+// false if the job is unknown, or its result has expired.
+func (js *JobServer) JobRequester(uuid string) (string, bool) {
+	// A job is pending until its result is stored, so a job which is not pending anymore has its result
+	if requester, ok := js.pending.requester(uuid); ok {
+		return requester, true
+	}
+	if res, ok := js.results.Peek(uuid); ok {
+		return res.Job.WorkerID, true
+	}
+	if requester, ok := js.recurring.requester(uuid); ok {
+		return requester, true
+	}
+	if held, ok := js.held.load(uuid); ok {
+		return held.Result.Job.WorkerID, true
+	}
+	return "", false
+}
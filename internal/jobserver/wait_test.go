@@ -0,0 +1,102 @@
+package jobserver
+
+import (
+	"context"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// blockingWorker finishes its jobs once release is closed
+type blockingWorker struct {
+	release chan struct{}
+}
+
+func (b *blockingWorker) GetStructuredCapabilities() teetypes.WorkerCapabilities {
+	return teetypes.WorkerCapabilities{}
+}
+
+func (b *blockingWorker) ExecuteJob(j types.Job) (types.JobResult, error) {
+	<-b.release
+	return types.JobResult{Data: []byte("done")}, nil
+}
+
+var _ = Describe("Waiting for results", func() {
+	var (
+		ctx    context.Context
+		cancel context.CancelFunc
+		js     *JobServer
+		w      *blockingWorker
+	)
+
+	BeforeEach(func() {
+		config.MinersWhiteList = ""
+		ctx, cancel = context.WithCancel(context.Background())
+		js = NewJobServer(1, config.JobConfiguration{})
+		w = &blockingWorker{release: make(chan struct{})}
+		js.jobWorkers[teetypes.WebJob] = &jobWorkerEntry{w: w}
+		go js.Run(ctx)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("returns the result as soon as the job finishes", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "wait-finish"})
+		Expect(err).NotTo(HaveOccurred())
+
+		time.AfterFunc(50*time.Millisecond, func() { close(w.release) })
+
+		waitCtx, waitCancel := context.WithTimeout(ctx, 5*time.Second)
+		defer waitCancel()
+		start := time.Now()
+		res, ok := js.WaitForJobResult(waitCtx, uuid)
+		Expect(ok).To(BeTrue())
+		Expect(string(res.Data)).To(Equal("done"))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+
+	It("gives up when the wait expires", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, Nonce: "wait-expire"})
+		Expect(err).NotTo(HaveOccurred())
+		defer close(w.release)
+
+		waitCtx, waitCancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer waitCancel()
+		_, ok := js.WaitForJobResult(waitCtx, uuid)
+		Expect(ok).To(BeFalse())
+		Expect(waitCtx.Err()).To(HaveOccurred())
+	})
+
+	It("tells which miner submitted a job without consuming its result", func() {
+		uuid, err := js.AddJob(types.Job{Type: teetypes.WebJob, WorkerID: "miner-1", Nonce: "wait-requester", Arguments: map[string]any{"cache": "no-store"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		requester, ok := js.JobRequester(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(requester).To(Equal("miner-1"))
+
+		close(w.release)
+		Eventually(js.JobDone(uuid), "5s").Should(BeClosed())
+		requester, ok = js.JobRequester(uuid)
+		Expect(ok).To(BeTrue())
+		Expect(requester).To(Equal("miner-1"))
+		_, ok = js.GetJobResult(uuid)
+		Expect(ok).To(BeTrue())
+
+		_, ok = js.JobRequester("unknown")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("does not wait for unknown jobs", func() {
+		start := time.Now()
+		_, ok := js.WaitForJobResult(ctx, "unknown")
+		Expect(ok).To(BeFalse())
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+	})
+})
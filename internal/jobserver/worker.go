@@ -2,25 +2,63 @@ package jobserver
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	teetypes "github.com/masa-finance/tee-types/types"
 	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/contentpolicy"
+	"github.com/masa-finance/tee-worker/internal/jobs"
+	"github.com/masa-finance/tee-worker/internal/jobs/stats"
+	"github.com/masa-finance/tee-worker/internal/redaction"
 	"github.com/sirupsen/logrus"
 )
 
 func (js *JobServer) worker(c context.Context) {
 	for {
+		var j types.Job
+		// High priority jobs jump the queue
 		select {
-		case <-c.Done():
-			fmt.Println("Context done")
-			return
+		case j = <-js.priorityJobChan:
+		default:
+			select {
+			case <-c.Done():
+				fmt.Println("Context done")
+				return
+
+			case j = <-js.priorityJobChan:
+			case j = <-js.jobChan:
+			}
+		}
+
+		fmt.Println("Job received: ", j)
+		js.waiting.add(j.Type, -1)
+		if !js.slots.acquire(j, js.priorities.priority(j) == PriorityHigh) {
+			logrus.Debugf("Job type %s is at its concurrency limit, deferring job %s", j.Type, j.UUID)
+			continue
+		}
 
-		case j := <-js.jobChan:
-			fmt.Println("Job received: ", j)
-			if err := js.doWork(j); err != nil {
-				logrus.Errorf("Error while executing job %v: %s", j, err)
+		// The slot is handed over to the deferred jobs of the same type, which this worker executes next. A job
+		// deferred for lack of memory gives up its slot, and is queued again once a running job frees memory.
+		for {
+			if js.memory.acquire(j, js.priorities.priority(j) == PriorityHigh) {
+				if err := js.doWork(j); err != nil {
+					logrus.Errorf("Error while executing job %v: %s", j, err)
+				}
+				for _, ready := range js.memory.release(j.UUID) {
+					go js.queue(ready)
+				}
+			} else {
+				logrus.Debugf("Not enough memory for job %s of type %s, deferring it", j.UUID, j.Type)
+			}
+			next, ok := js.slots.release(j.Type)
+			if !ok {
+				break
 			}
+			j = next
 		}
 	}
 }
@@ -30,31 +68,332 @@ type worker interface {
 	ExecuteJob(j types.Job) (types.JobResult, error)
 }
 
+// capabilityDescriber is implemented by workers which can report how they provide each capability
+type capabilityDescriber interface {
+	GetCapabilityDetails() types.CapabilityDetails
+}
+
+// keyCapabilityReporter is implemented by workers which know what each of their provider API keys has access to
+type keyCapabilityReporter interface {
+	GetKeyCapabilities() []types.KeyCapabilities
+}
+
+// argumentDescriber is implemented by workers which know all the arguments their job types accept
+type argumentDescriber interface {
+	ArgumentKeys() []string
+}
+
+// dependencyProber is implemented by workers which can check that the external services they depend on work
+type dependencyProber interface {
+	ProbeDependencies() error
+}
+
+// commonArgumentKeys are the arguments handled by the job server, which are accepted by every job type
+var commonArgumentKeys = []string{
+	bandwidthArgumentKey,
+	cacheArgumentKey,
+	contentpolicy.ArgumentKey,
+	types.DebugArgumentKey,
+	executionClassArgumentKey,
+	fieldsArgumentKey,
+	resultMaxBytesArgumentKey,
+	resultMaxItemsArgumentKey,
+	jobs.PostProcessArgumentKey,
+	types.PaginatedArgumentKey,
+	priorityArgumentKey,
+	redaction.ArgumentKey,
+	sampleArgumentKey,
+	scheduleArgumentKey,
+	types.ProvenanceArgumentKey,
+	types.RetainArgumentKey,
+}
+
+// ArgumentKeys returns the sorted arguments accepted by a job type, including the common ones. It returns false if
+// the accepted arguments are not known, e.g. for job types which don't exist or are simulated.
+func (js *JobServer) ArgumentKeys(jobType teetypes.JobType) ([]string, bool) {
+	entry, ok := js.workerEntries()[jobType]
+	if !ok {
+		return nil, false
+	}
+	d, ok := entry.w.(argumentDescriber)
+	if !ok {
+		return nil, false
+	}
+	keys := append(slices.Clone(commonArgumentKeys), d.ArgumentKeys()...)
+	slices.Sort(keys)
+	return slices.Compact(keys), true
+}
+
+// ProbeDependencies checks the external services the worker of a job type depends on. It returns
+// types.ErrNotConfigured if there is no such worker, or if it doesn't depend on any service.
+func (js *JobServer) ProbeDependencies(jobType teetypes.JobType) error {
+	entry, ok := js.workerEntries()[jobType]
+	if !ok {
+		return types.ErrNotConfigured
+	}
+	if p, ok := entry.w.(dependencyProber); ok {
+		return p.ProbeDependencies()
+	}
+	return types.ErrNotConfigured
+}
+
 func (js *JobServer) doWork(j types.Job) error {
-	w, exists := js.jobWorkers[j.Type]
+	if js.pending.isCancelled(j.UUID) {
+		logrus.Infof("Skipping cancelled job %s", j.UUID)
+		js.storeResult(j, types.JobResult{Job: j, Error: ErrJobCancelled.Error()})
+		return nil
+	}
+
+	w, exists := js.workerEntries()[j.Type]
 
 	if !exists {
-		js.results.Set(j.UUID, types.JobResult{
+		js.storeResult(j, types.JobResult{
 			Job:   j,
 			Error: fmt.Sprintf("unknown job type: %s", j.Type),
 		})
 		return fmt.Errorf("unknown job type: %s", j.Type)
 	}
 
-	// TODO: Shall we lock the resource or create a new instance each time? Behavior is not defined yet as the only requirements we have is that some scrapers might have rate limits, so we don't want to create a new clients every time. We might use an object pool with a specific capacity, so we have a max number of workers (of each type?) running concurrently. See e.g. https://github.com/jolestar/go-commons-pool or https://github.com/theodesp/go-object-pool.
-	w.Lock()
-	defer w.Unlock()
-
-	result, err := w.w.ExecuteJob(j)
+	// How many jobs of the type run at the same time is limited by js.slots, see <JOB_TYPE>_MAX_CONCURRENT.
+	// The meter stays with the job server, the job only gets to wrap its HTTP transports with it.
+	meter := bandwidth.NewMeter(js.bandwidthCap(j, time.Now()))
+	j.Network = meter.Transport
+	j.Provenance = &types.ProvenanceRecorder{}
+	j.ApifyCost = &types.ApifyCostRecorder{}
+	// The channel of a pending job is closed when it is cancelled, or once its result has been stored
+	j.Cancelled = js.pending.wait(j.UUID)
+	defer js.enforceTimeout(j)()
+	// The requests made by the job are children of the span of the attempt
+	ex := js.executions.get(j)
+	attempt := ex.span.StartAttempt(j.Attempt)
+	if j.Trace != nil || attempt.Recording() {
+		meter.Observe(func(r bandwidth.Request) {
+			j.Trace.HTTP(r)
+			attempt.HTTP(r)
+		})
+	}
+	js.events.publish(types.JobEvent{JobUUID: j.UUID, Status: types.JobStatusRunning, Attempt: j.Attempt})
+	startedAt := time.Now()
+	j.Trace.Started(j.Attempt, startedAt)
+	// The scraper is asked for no more items than the result can hold, the job itself keeps the requested arguments
+	maxItems, maxBytes := js.resultLimits(j)
+	capped, lowered := limitedJob(j, maxItems)
+	result, err := w.w.ExecuteJob(capped)
+	j.Trace.Phase("execute", startedAt, time.Now(), err)
+	attempt.End(err)
+	js.stats.RecordJob(j, time.Since(startedAt), err != nil || result.Error != "")
+	js.recordUsage(j, meter, &result, err)
+	js.stats.AddApifyCost(j, j.ApifyCost.Cost())
+	result.Provenance = js.resultProvenance(j, startedAt, time.Now())
+	js.recordCapabilityOutcome(j, meter, result, err, time.Since(startedAt))
 	if err != nil {
 		logrus.Infof("Error executing job type %s: %s", j.Type, err.Error())
+		// Another attempt would run into the same cap
+		if !meter.Exceeded() && js.maybeRetry(j, err) {
+			return nil
+		}
 		if len(result.Error) == 0 {
 			result.Error = err.Error()
 		}
 	}
 
+	// Invalid items are dropped before any other processing, and a result without valid items is never returned
+	if result.Error == "" && jobs.HasResultValidators(j) {
+		phaseStartedAt := time.Now()
+		validated, report, err := jobs.ValidateResult(j, result.Data)
+		ex.phase("validate", phaseStartedAt, err)
+		if report != nil {
+			js.stats.AddWithDimensions(j.WorkerID, stats.InvalidResultItems, uint(report.Invalid), stats.DimensionsForJob(j))
+		}
+		if err != nil {
+			logrus.Errorf("Error while validating result of job %s: %s", j.UUID, err)
+			js.stats.AddWithDimensions(j.WorkerID, stats.InvalidResults, 1, stats.DimensionsForJob(j))
+			result = types.JobResult{Error: fmt.Sprintf("error while validating result: %s", err), Validation: report, Usage: result.Usage, Provenance: result.Provenance}
+		} else {
+			result.Data = validated
+			result.Validation = report
+		}
+	}
+
+	// Flagged items are filtered before sampling, so samples are only drawn from the items which can be returned
+	if result.Error == "" && contentpolicy.Applies(j.Type) {
+		if include, err := contentpolicy.IncludeFromArguments(j.Arguments); err == nil && !(include && js.jobConfiguration.GetContentPolicyConfig().AllowNSFW) {
+			phaseStartedAt := time.Now()
+			filtered, n, err := contentpolicy.Filter(j.Type, result.Data)
+			ex.phase("content_policy", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while filtering result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while filtering result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = filtered
+				if n > 0 {
+					js.stats.AddWithDimensions(j.WorkerID, stats.ContentFilteredItems, uint(n), stats.DimensionsForJob(j))
+				}
+			}
+		}
+	}
+
+	// Results are cut at their limits before sampling, so the offset in the continuation cursor counts the items returned
+	// by the data source
+	cursorOffset := 0
+	if result.Error == "" {
+		if maxItems > 0 || maxBytes > 0 || j.ResultOffset > 0 {
+			phaseStartedAt := time.Now()
+			limited, kept, truncated, err := limitResult(result.Data, j.ResultOffset, maxItems, maxBytes)
+			ex.phase("limit", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while limiting result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while limiting result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = limited
+				// A scraper which stopped at the lowered limit may have left items out, and the next page starts from
+				// the cursor it returned
+				stopped := lowered && kept == maxItems
+				if truncated || (stopped && result.NextCursor == "") {
+					// The rest of the page is fetched again from the cursor the job started from, skipping the items
+					// returned so far. It only happens if the page exceeds the byte limit, or if the scraper doesn't
+					// return a cursor.
+					cursorOffset = j.ResultOffset + kept
+					result.NextCursor, _ = j.Arguments[types.NextCursorArgumentKey].(string)
+				}
+				if truncated || stopped {
+					result.HasMore = true
+					if result.Usage == nil {
+						result.Usage = &types.Usage{}
+					}
+					result.Usage.Truncated = true
+					js.stats.AddWithDimensions(j.WorkerID, stats.TruncatedResults, 1, stats.DimensionsForJob(j))
+				}
+			}
+		}
+	}
+
+	// Sampling happens before redaction, so only the items which are kept are redacted
+	if result.Error == "" {
+		if sample, err := sampleFromArguments(j.Arguments); err == nil && sample != nil {
+			phaseStartedAt := time.Now()
+			sampled, err := sample.Apply(result.Data)
+			ex.phase("sample", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while sampling result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while sampling result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = sampled
+			}
+		}
+	}
+
+	// Projection happens before redaction and post-processing, so only the selected fields are redacted and sent to
+	// the LLM
+	if result.Error == "" {
+		if projection, err := projectionFromArguments(j.Arguments); err == nil && projection != nil {
+			phaseStartedAt := time.Now()
+			projected, err := projection.Apply(result.Data)
+			ex.phase("project", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while projecting result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while projecting result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = projected
+			}
+		}
+	}
+
+	// Redaction happens before the result is cached, so PII never reaches the sealed result
+	if result.Error == "" {
+		if mode, err := redaction.ModeFromArguments(j.Arguments); err == nil && mode != redaction.ModeNone {
+			phaseStartedAt := time.Now()
+			var key []byte
+			if mode == redaction.ModeHash {
+				key, err = js.redaction.Key()
+			}
+			var redacted []byte
+			if err == nil {
+				redacted, err = redaction.Apply(result.Data, mode, key)
+			}
+			ex.phase("redact", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while redacting result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while redacting result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = redacted
+			}
+		}
+	}
+
+	// Post-processing happens after redaction, so PII is never sent to the LLM
+	if result.Error == "" {
+		if p, err := jobs.PostProcessFromArguments(j.Arguments); err == nil && p != nil {
+			phaseStartedAt := time.Now()
+			processed, err := jobs.PostProcess(js.credentialConfiguration(), js.stats, j, result.Data, *p)
+			ex.phase("post_process", phaseStartedAt, err)
+			if err != nil {
+				logrus.Errorf("Error while post-processing result of job %s: %s", j.UUID, err)
+				result = types.JobResult{Error: fmt.Sprintf("error while post-processing result: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+			} else {
+				result.Data = processed
+			}
+		}
+	}
+
+	// The cursor of the data source is only returned sealed, so clients can't page with a cursor the worker didn't issue
+	if result.NextCursor != "" || cursorOffset > 0 {
+		sealed, err := sealNextCursor(j, result.NextCursor, cursorOffset, time.Now())
+		if err != nil {
+			logrus.Errorf("Error while sealing the next cursor of job %s: %s", j.UUID, err)
+			result = types.JobResult{Error: fmt.Sprintf("error while sealing next cursor: %s", err), Usage: result.Usage, Provenance: result.Provenance}
+		} else {
+			result.NextCursor = sealed
+		}
+	}
+
 	result.Job = j
-	js.results.Set(j.UUID, result)
+	result.Trace = j.Trace.Trace()
+	js.storeResult(j, result)
 
 	return nil
 }
+
+// storeResult saves the job result in the result cache, honoring the cache directive sent by the client
+func (js *JobServer) storeResult(j types.Job, result types.JobResult) {
+	defer js.jobFinished(j)
+	span := js.executions.get(j).span
+	if result.Error != "" {
+		span.End(errors.New(result.Error))
+	} else {
+		span.End(nil)
+	}
+	js.recurring.finished(j.UUID, &result)
+	if !js.recurring.scheduled(j.UUID) {
+		j.Stream.End()
+		js.executions.remove(j.UUID)
+	}
+	js.quotas.addResultBytes(j.WorkerID, len(result.Data), time.Now())
+
+	// The result of a cancelled job was stored when it was cancelled
+	if js.pending.endCancelled(j.UUID) {
+		js.audit.record(finishedAuditEntry(j, types.JobResult{Error: ErrJobCancelled.Error(), Provenance: result.Provenance}))
+		return
+	}
+	js.audit.record(finishedAuditEntry(j, result))
+
+	// Waiters are woken up once the result has been stored
+	defer js.pending.finish(j.UUID)
+
+	cacheDirective, _ := cacheDirectiveFromArguments(j.Arguments)
+	if cacheDirective.NoStore {
+		js.results.SetDeleteOnRead(j.UUID, result)
+		return
+	}
+	defer js.retainIfRequested(j)
+
+	// Only successful and complete results can be reused by other jobs
+	if result.Error == "" && !result.Truncated() {
+		if fingerprint, err := jobFingerprint(j); err == nil {
+			js.results.SetReusable(j.UUID, fingerprint, result)
+			return
+		}
+	}
+
+	js.results.Set(j.UUID, result)
+}
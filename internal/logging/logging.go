@@ -0,0 +1,165 @@
+// Package logging adds per-module log levels to logrus, so the verbosity of one subsystem can be raised at runtime
+// without flooding the logs with the other ones. The module of an entry is derived from the code which logged it, so
+// the rest of the worker keeps logging with the logrus package functions.
+package logging
+
+import (
+	"fmt"
+	"maps"
+	"path"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Modules are the subsystems whose log level can be set separately from the default log level
+var Modules = []string{"twitter", "tiktok", "web", "jobserver", "api"}
+
+var (
+	mu           sync.RWMutex
+	defaultLevel = logrus.InfoLevel
+	moduleLevels = map[string]logrus.Level{}
+	installOnce  sync.Once
+)
+
+// ParseLevel parses one of the log levels which can be configured: debug, info, warn or error
+func ParseLevel(s string) (logrus.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "debug":
+		return logrus.DebugLevel, nil
+	case "info":
+		return logrus.InfoLevel, nil
+	case "warn":
+		return logrus.WarnLevel, nil
+	case "error":
+		return logrus.ErrorLevel, nil
+	default:
+		return logrus.InfoLevel, fmt.Errorf("log level must be one of debug, info, warn or error, got %q", s)
+	}
+}
+
+// SetLevel sets the log level of the modules which don't have their own
+func SetLevel(level logrus.Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultLevel = level
+	apply()
+}
+
+// SetModuleLevel sets the log level of a module, overriding the default log level
+func SetModuleLevel(module string, level logrus.Level) error {
+	if !slices.Contains(Modules, module) {
+		return fmt.Errorf("unknown module %q, valid modules are %s", module, strings.Join(Modules, ", "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	moduleLevels[module] = level
+	apply()
+	return nil
+}
+
+// ResetModuleLevel makes a module use the default log level again
+func ResetModuleLevel(module string) error {
+	if !slices.Contains(Modules, module) {
+		return fmt.Errorf("unknown module %q, valid modules are %s", module, strings.Join(Modules, ", "))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	delete(moduleLevels, module)
+	apply()
+	return nil
+}
+
+// Levels returns the default log level and the log levels of the modules which have their own
+func Levels() (logrus.Level, map[string]logrus.Level) {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultLevel, maps.Clone(moduleLevels)
+}
+
+// Level returns the log level of a module
+func Level(module string) logrus.Level {
+	mu.RLock()
+	defer mu.RUnlock()
+	return levelOf(module)
+}
+
+func levelOf(module string) logrus.Level {
+	if level, ok := moduleLevels[module]; ok {
+		return level
+	}
+	return defaultLevel
+}
+
+// apply configures logrus for the current levels. Its level is the most verbose one, and the entries of the modules
+// with a less verbose level are dropped by the formatter. The caller of each entry, which tells its module, is only
+// looked up while a module has its own level, since it is not free.
+func apply() {
+	installOnce.Do(func() {
+		logrus.SetFormatter(&moduleFilter{Formatter: logrus.StandardLogger().Formatter})
+	})
+	level := defaultLevel
+	for _, l := range moduleLevels {
+		level = max(level, l)
+	}
+	logrus.SetLevel(level)
+	logrus.SetReportCaller(len(moduleLevels) > 0)
+}
+
+// moduleFilter drops the entries which are more verbose than the level of their module
+type moduleFilter struct {
+	logrus.Formatter
+}
+
+func (f *moduleFilter) Format(entry *logrus.Entry) ([]byte, error) {
+	if entry.Caller == nil {
+		return f.Formatter.Format(entry)
+	}
+	mu.RLock()
+	level := levelOf(moduleOf(entry.Caller))
+	mu.RUnlock()
+	if entry.Level > level {
+		return nil, nil
+	}
+	// The caller is only reported to find the module, so it is left out of the entry
+	e := *entry
+	e.Caller = nil
+	return f.Formatter.Format(&e)
+}
+
+// moduleOf returns the module of the code of a stack frame, or an empty string if it is not part of a module. The
+// scrapers of the jobs package are told apart by the prefix of their files, e.g. twitter_getbyids.go, and those in
+// their own packages by the prefix of the package, e.g. twitterx or webapify.
+func moduleOf(frame *runtime.Frame) string {
+	pkg := frame.Function
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		if j := strings.Index(pkg[i:], "."); j >= 0 {
+			pkg = pkg[:i+j]
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(pkg, "/internal/jobserver"):
+		return "jobserver"
+	case strings.HasSuffix(pkg, "/internal/api"):
+		return "api"
+	case strings.HasSuffix(pkg, "/internal/jobs"):
+		return scraperModule(filepath.Base(frame.File))
+	case strings.Contains(pkg, "/internal/jobs/"):
+		return scraperModule(path.Base(pkg))
+	}
+	return ""
+}
+
+func scraperModule(name string) string {
+	for _, module := range []string{"twitter", "tiktok", "web"} {
+		if strings.HasPrefix(name, module) {
+			return module
+		}
+	}
+	return ""
+}
@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"bytes"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Per-module log levels", func() {
+	frame := func(function, file string) *runtime.Frame {
+		return &runtime.Frame{Function: "github.com/masa-finance/tee-worker/internal/" + function, File: "/src/internal/" + file}
+	}
+
+	BeforeEach(func() {
+		for _, module := range Modules {
+			Expect(ResetModuleLevel(module)).To(Succeed())
+		}
+		SetLevel(logrus.InfoLevel)
+		DeferCleanup(func() {
+			for _, module := range Modules {
+				Expect(ResetModuleLevel(module)).To(Succeed())
+			}
+			SetLevel(logrus.InfoLevel)
+		})
+	})
+
+	It("tells the module of the code which logged an entry", func() {
+		Expect(moduleOf(frame("jobs.(*TwitterScraper).ExecuteJob", "jobs/twitter.go"))).To(Equal("twitter"))
+		Expect(moduleOf(frame("jobs.(*TwitterScraper).executeGetByIds", "jobs/twitter_getbyids.go"))).To(Equal("twitter"))
+		Expect(moduleOf(frame("jobs/twitterx.(*TwitterXScraper).lookupUsernames", "jobs/twitterx/usernames.go"))).To(Equal("twitter"))
+		Expect(moduleOf(frame("jobs.(*TikTokTranscriber).ExecuteJob", "jobs/tiktok.go"))).To(Equal("tiktok"))
+		Expect(moduleOf(frame("jobs/webapify.(*ApifyClient).Scrape", "jobs/webapify/client.go"))).To(Equal("web"))
+		Expect(moduleOf(frame("jobserver.(*JobServer).doWork", "jobserver/worker.go"))).To(Equal("jobserver"))
+		Expect(moduleOf(frame("api.status.func1", "api/routes.go"))).To(Equal("api"))
+		Expect(moduleOf(frame("jobs.(*RedditScraper).ExecuteJob", "jobs/reddit.go"))).To(BeEmpty())
+		Expect(moduleOf(frame("config.ReadConfig", "config/config.go"))).To(BeEmpty())
+	})
+
+	It("logs each module at its own level", func() {
+		Expect(SetModuleLevel("twitter", logrus.DebugLevel)).To(Succeed())
+		Expect(SetModuleLevel("api", logrus.ErrorLevel)).To(Succeed())
+		Expect(logrus.GetLevel()).To(Equal(logrus.DebugLevel))
+		Expect(logrus.StandardLogger().ReportCaller).To(BeTrue())
+
+		var out bytes.Buffer
+		logger := &logrus.Logger{Out: &out, Formatter: logrus.StandardLogger().Formatter, Level: logrus.DebugLevel, ReportCaller: true}
+		log := func(level logrus.Level, f *runtime.Frame, msg string) {
+			entry := logrus.NewEntry(logger)
+			entry.Level = level
+			entry.Caller = f
+			entry.Message = msg
+			formatted, err := logger.Formatter.Format(entry)
+			Expect(err).NotTo(HaveOccurred())
+			out.Write(formatted)
+		}
+		log(logrus.DebugLevel, frame("jobs.(*TwitterScraper).ExecuteJob", "jobs/twitter.go"), "twitter debug")
+		log(logrus.DebugLevel, frame("jobserver.(*JobServer).doWork", "jobserver/worker.go"), "jobserver debug")
+		log(logrus.InfoLevel, frame("jobserver.(*JobServer).doWork", "jobserver/worker.go"), "jobserver info")
+		log(logrus.WarnLevel, frame("api.status.func1", "api/routes.go"), "api warn")
+		log(logrus.ErrorLevel, frame("api.status.func1", "api/routes.go"), "api error")
+
+		Expect(out.String()).To(ContainSubstring("twitter debug"))
+		Expect(out.String()).NotTo(ContainSubstring("jobserver debug"))
+		Expect(out.String()).To(ContainSubstring("jobserver info"))
+		Expect(out.String()).NotTo(ContainSubstring("api warn"))
+		Expect(out.String()).To(ContainSubstring("api error"))
+		// The caller is not part of the entry
+		Expect(out.String()).NotTo(ContainSubstring("twitter.go"))
+
+		level, modules := Levels()
+		Expect(level).To(Equal(logrus.InfoLevel))
+		Expect(modules).To(Equal(map[string]logrus.Level{"twitter": logrus.DebugLevel, "api": logrus.ErrorLevel}))
+	})
+
+	It("goes back to the default level once the modules are reset", func() {
+		Expect(SetModuleLevel("web", logrus.DebugLevel)).To(Succeed())
+		Expect(ResetModuleLevel("web")).To(Succeed())
+		Expect(logrus.GetLevel()).To(Equal(logrus.InfoLevel))
+		Expect(logrus.StandardLogger().ReportCaller).To(BeFalse())
+		Expect(Level("web")).To(Equal(logrus.InfoLevel))
+	})
+
+	It("rejects unknown modules and levels", func() {
+		Expect(SetModuleLevel("reddit", logrus.DebugLevel)).To(MatchError(ContainSubstring("unknown module")))
+		_, err := ParseLevel("verbose")
+		Expect(err).To(HaveOccurred())
+		level, err := ParseLevel("WARN")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(level).To(Equal(logrus.WarnLevel))
+	})
+})
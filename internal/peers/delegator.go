@@ -0,0 +1,317 @@
+package peers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/sirupsen/logrus"
+)
+
+// DelegatedHeader is set on the jobs forwarded to a peer, so the peer executes them itself instead of delegating them
+// again
+const DelegatedHeader = "X-Delegated-By"
+
+// ErrNoPeer is returned when no verified peer with the capability of a job accepted it
+var ErrNoPeer = errors.New("no peer accepted the job")
+
+// QuoteVerifier verifies an SGX quote and returns the report data and the signer ID embedded in it, e.g. on top of
+// enclave.VerifyRemoteReport
+type QuoteVerifier func(quote []byte) (reportData, signerID []byte, err error)
+
+// peer is a trusted worker jobs can be delegated to
+type peer struct {
+	url    string
+	client *client.Client
+
+	capabilities teetypes.WorkerCapabilities
+	verifiedAt   time.Time
+}
+
+// delegatedJob is a job executed by a peer, whose status is proxied to the peer
+type delegatedJob struct {
+	peer        *peer
+	requester   string // The worker ID of the miner which submitted the job
+	delegatedAt time.Time
+}
+
+// Delegator forwards jobs to peer workers when this worker is over capacity, and proxies their status back.
+//
+// Peers are only trusted after they proved with a fresh attestation that they run in an enclave with the same signer
+// as this worker, i.e. an enclave the key distributor hands the sealing key to. The peers can therefore decrypt the
+// jobs sealed for this worker, and this worker can decrypt their results. In standalone mode there are no quotes, and
+// the peers are trusted as configured.
+type Delegator struct {
+	sync.Mutex
+
+	peers    []*peer
+	next     int // peer the next job is offered to first, so the jobs are spread over the peers
+	jobs     map[string]delegatedJob
+	verify   QuoteVerifier
+	signerID []byte
+	ttl      time.Duration
+	maxAge   time.Duration
+	apiKey   string
+	workerID string
+}
+
+// NewDelegator returns a Delegator for the peers of the configuration, or nil if there are none. The jobs are
+// proxied for maxAge after they were delegated, like the results of the local jobs. If verify is nil, the peers are
+// not attested.
+//
+// tlsConfig verifies the certificates of the peers before PEER_API_KEY or a job is sent to them, e.g. one of
+// enclave.CreateAttestationClientTLSConfig which checks the report embedded in the certificate of an enclave. If it
+// is nil, the certificates are verified with the system roots.
+func NewDelegator(cfg config.PeerConfig, workerID string, verify QuoteVerifier, signerID []byte, maxAge time.Duration, tlsConfig *tls.Config) (*Delegator, error) {
+	if len(cfg.Workers) == 0 {
+		return nil, nil
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	opts := []client.Option{client.HttpClient(&http.Client{Timeout: 30 * time.Second, Transport: transport})}
+	if cfg.APIKey != "" {
+		opts = append(opts, client.APIKey(cfg.APIKey))
+	}
+
+	d := &Delegator{
+		jobs:     make(map[string]delegatedJob),
+		verify:   verify,
+		signerID: signerID,
+		ttl:      cfg.AttestationTTL,
+		maxAge:   maxAge,
+		apiKey:   cfg.APIKey,
+		workerID: workerID,
+	}
+	for _, u := range cfg.Workers {
+		c, err := client.NewClient(u, opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the client of peer %s: %w", u, err)
+		}
+		d.peers = append(d.peers, &peer{url: u, client: c})
+	}
+
+	if verify == nil {
+		logrus.Warnf("Peer attestation is not available in standalone mode, trusting the %d configured peers as they are", len(d.peers))
+	}
+
+	return d, nil
+}
+
+// verifyPeer checks the attestation of a peer and fetches its capabilities, unless it was verified within the TTL
+func (d *Delegator) verifyPeer(p *peer) error {
+	d.Lock()
+	fresh := !p.verifiedAt.IsZero() && time.Since(p.verifiedAt) < d.ttl
+	d.Unlock()
+	if fresh {
+		return nil
+	}
+
+	caps, err := p.client.GetCapabilities()
+	if err != nil {
+		return fmt.Errorf("failed to get the capabilities: %w", err)
+	}
+
+	if d.verify != nil {
+		if err := d.attest(p, caps); err != nil {
+			return err
+		}
+	}
+
+	d.Lock()
+	p.capabilities = caps.Capabilities
+	p.verifiedAt = time.Now()
+	d.Unlock()
+	logrus.Infof("Verified peer %s (worker %s)", p.url, caps.WorkerID)
+	return nil
+}
+
+// attest checks that a fresh quote of the peer binds its worker ID, its capabilities and a random nonce, and that it
+// was generated by an enclave with the same signer as this worker
+func (d *Delegator) attest(p *peer, caps *types.CapabilitiesResponse) error {
+	n := make([]byte, 16)
+	if _, err := rand.Read(n); err != nil {
+		return fmt.Errorf("failed to generate a nonce: %w", err)
+	}
+	nonce := hex.EncodeToString(n)
+
+	att, err := p.client.GetAttestation(nonce)
+	if err != nil {
+		return fmt.Errorf("failed to get the attestation: %w", err)
+	}
+	if att.Nonce != nonce {
+		return fmt.Errorf("attestation is for nonce %q instead of %q", att.Nonce, nonce)
+	}
+	if att.WorkerID != caps.WorkerID || att.CapabilitiesHash != types.CapabilitiesHash(caps.Capabilities) {
+		return errors.New("attestation does not match the worker ID and capabilities of the peer")
+	}
+
+	quote, err := base64.StdEncoding.DecodeString(att.Quote)
+	if err != nil {
+		return fmt.Errorf("failed to decode the quote: %w", err)
+	}
+	reportData, signerID, err := d.verify(quote)
+	if err != nil {
+		return fmt.Errorf("invalid quote: %w", err)
+	}
+	if !bytes.HasPrefix(reportData, types.AttestationReportData(att.WorkerID, att.CapabilitiesHash, nonce)) {
+		return errors.New("report data of the quote does not match the attestation")
+	}
+	if !bytes.Equal(signerID, d.signerID) {
+		return fmt.Errorf("quote was signed by %s instead of %s", hex.EncodeToString(signerID), hex.EncodeToString(d.signerID))
+	}
+	return nil
+}
+
+// supports returns true if the verified peer has capabilities for the job type
+func (d *Delegator) supports(p *peer, jobType teetypes.JobType) bool {
+	d.Lock()
+	defer d.Unlock()
+	_, ok := p.capabilities[jobType]
+	return ok
+}
+
+// Forward offers a job to the peers in turn until one of them accepts it, skipping the peers which fail verification
+// or lack the capabilities of the job type. The job request is forwarded as it was received, still sealed; job is its
+// decrypted content.
+func (d *Delegator) Forward(jobRequest types.JobRequest, job types.Job) (types.JobResponse, error) {
+	jobType := job.Type
+
+	d.Lock()
+	start := d.next
+	d.next = (d.next + 1) % len(d.peers)
+	d.Unlock()
+
+	for i := range d.peers {
+		p := d.peers[(start+i)%len(d.peers)]
+		if err := d.verifyPeer(p); err != nil {
+			logrus.Warnf("Not delegating to peer %s: %s", p.url, err)
+			continue
+		}
+		if !d.supports(p, jobType) {
+			continue
+		}
+
+		res, err := d.submit(p, jobRequest)
+		if err != nil {
+			logrus.Infof("Peer %s did not accept %s job: %s", p.url, jobType, err)
+			continue
+		}
+
+		d.Lock()
+		d.prune()
+		d.jobs[res.UID] = delegatedJob{peer: p, requester: job.WorkerID, delegatedAt: time.Now()}
+		d.Unlock()
+		logrus.Infof("Delegated %s job %s to peer %s", jobType, res.UID, p.url)
+		return res, nil
+	}
+
+	return types.JobResponse{}, ErrNoPeer
+}
+
+// submit adds the job to the peer, flagging it as delegated
+func (d *Delegator) submit(p *peer, jobRequest types.JobRequest) (types.JobResponse, error) {
+	body, err := json.Marshal(jobRequest)
+	if err != nil {
+		return types.JobResponse{}, fmt.Errorf("error marshaling job: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", p.url+"/job/add", bytes.NewReader(body))
+	if err != nil {
+		return types.JobResponse{}, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DelegatedHeader, d.workerID)
+	d.setAPIKeyHeader(req)
+
+	resp, err := p.client.HTTPClient.Do(req)
+	if err != nil {
+		return types.JobResponse{}, fmt.Errorf("error sending POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return types.JobResponse{}, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return types.JobResponse{}, fmt.Errorf("received status code %d, body: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+
+	var res types.JobResponse
+	if err := json.Unmarshal(respBody, &res); err != nil {
+		return types.JobResponse{}, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	if res.UID == "" {
+		return types.JobResponse{}, errors.New("peer returned an empty job UUID")
+	}
+	return res, nil
+}
+
+// Requester returns the worker ID of the miner which submitted a delegated job. It returns false if the job was not
+// delegated.
+func (d *Delegator) Requester(uuid string) (string, bool) {
+	d.Lock()
+	defer d.Unlock()
+	job, ok := d.jobs[uuid]
+	return job.requester, ok
+}
+
+// Status requests the status of a delegated job from the peer executing it, passing on the query of the original
+// request, e.g. wait. It returns false if the job was not delegated. The caller must close the body of the response.
+func (d *Delegator) Status(uuid, rawQuery string) (*http.Response, bool, error) {
+	d.Lock()
+	job, ok := d.jobs[uuid]
+	d.Unlock()
+	if !ok {
+		return nil, false, nil
+	}
+
+	u := job.peer.url + "/job/status/" + uuid
+	if rawQuery != "" {
+		u += "?" + rawQuery
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, true, fmt.Errorf("error creating request: %w", err)
+	}
+	d.setAPIKeyHeader(req)
+
+	resp, err := job.peer.client.HTTPClient.Do(req)
+	if err != nil {
+		return nil, true, fmt.Errorf("error requesting the status of job %s from peer %s: %w", uuid, job.peer.url, err)
+	}
+	return resp, true, nil
+}
+
+func (d *Delegator) setAPIKeyHeader(req *http.Request) {
+	if d.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+d.apiKey)
+	}
+}
+
+// prune forgets the jobs delegated longer than maxAge ago. The caller must hold the lock.
+func (d *Delegator) prune() {
+	if d.maxAge <= 0 {
+		return
+	}
+	for uuid, job := range d.jobs {
+		if time.Since(job.delegatedAt) > d.maxAge {
+			delete(d.jobs, uuid)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package peers_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	teetypes "github.com/masa-finance/tee-types/types"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	. "github.com/masa-finance/tee-worker/internal/peers"
+)
+
+// fakePeer is a worker with web capabilities whose quotes are its report data prefixed with its signer
+type fakePeer struct {
+	*httptest.Server
+	signer       string
+	addStatus    int
+	requests     atomic.Int32
+	submitted    atomic.Int32
+	attestations atomic.Int32
+	delegatedBy  atomic.Value
+}
+
+func newFakePeer(signer string) *fakePeer {
+	p := newUnstartedFakePeer(signer)
+	p.Start()
+	return p
+}
+
+// newFakeTLSPeer is a fakePeer served over TLS with a self-signed certificate
+func newFakeTLSPeer(signer string) *fakePeer {
+	p := newUnstartedFakePeer(signer)
+	p.StartTLS()
+	return p
+}
+
+func newUnstartedFakePeer(signer string) *fakePeer {
+	p := &fakePeer{signer: signer, addStatus: http.StatusOK}
+	caps := teetypes.WorkerCapabilities{teetypes.WebJob: {"scraper"}}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /capabilities", func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(types.CapabilitiesResponse{WorkerID: "peer-" + signer, Capabilities: caps})
+	})
+	mux.HandleFunc("GET /attestation", func(w http.ResponseWriter, r *http.Request) {
+		p.attestations.Add(1)
+		nonce := r.URL.Query().Get("nonce")
+		capsHash := types.CapabilitiesHash(caps)
+		quote := append([]byte(p.signer+":"), types.AttestationReportData("peer-"+signer, capsHash, nonce)...)
+		_ = json.NewEncoder(w).Encode(types.AttestationResponse{
+			WorkerID:         "peer-" + signer,
+			CapabilitiesHash: capsHash,
+			Nonce:            nonce,
+			Quote:            base64.StdEncoding.EncodeToString(quote),
+		})
+	})
+	mux.HandleFunc("POST /job/add", func(w http.ResponseWriter, r *http.Request) {
+		p.delegatedBy.Store(r.Header.Get(DelegatedHeader))
+		if p.addStatus != http.StatusOK {
+			w.WriteHeader(p.addStatus)
+			_ = json.NewEncoder(w).Encode(types.JobError{Error: "over quota"})
+			return
+		}
+		p.submitted.Add(1)
+		_ = json.NewEncoder(w).Encode(types.JobResponse{UID: "job-" + signer})
+	})
+	mux.HandleFunc("GET /job/status/{id}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Cached-Result", "true")
+		_, _ = w.Write([]byte("sealed-" + r.PathValue("id") + "-" + r.URL.Query().Get("wait")))
+	})
+	p.Server = httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p.requests.Add(1)
+		mux.ServeHTTP(w, r)
+	}))
+	return p
+}
+
+// verifyFake splits a quote of a fakePeer into its report data and signer
+func verifyFake(quote []byte) ([]byte, []byte, error) {
+	signer, data, ok := strings.Cut(string(quote), ":")
+	if !ok {
+		return nil, nil, errors.New("malformed quote")
+	}
+	return []byte(data), []byte(signer), nil
+}
+
+var _ = Describe("Delegator", func() {
+	newDelegator := func(verify QuoteVerifier, urls ...string) *Delegator {
+		d, err := NewDelegator(config.PeerConfig{Workers: urls, AttestationTTL: time.Minute, APIKey: "secret"}, "self", verify, []byte("masa"), time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d).NotTo(BeNil())
+		return d
+	}
+
+	It("is not created without peers", func() {
+		d, err := NewDelegator(config.PeerConfig{}, "self", verifyFake, nil, time.Minute, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(d).To(BeNil())
+	})
+
+	It("forwards jobs to attested peers and proxies their status", func() {
+		peer := newFakePeer("masa")
+		defer peer.Close()
+		d := newDelegator(verifyFake, peer.URL)
+
+		res, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob, WorkerID: "miner-1"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(res.UID).To(Equal("job-masa"))
+		Expect(peer.delegatedBy.Load()).To(Equal("self"))
+		requester, ok := d.Requester("job-masa")
+		Expect(ok).To(BeTrue())
+		Expect(requester).To(Equal("miner-1"))
+
+		resp, delegated, err := d.Status("job-masa", "wait=5s")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delegated).To(BeTrue())
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal("sealed-job-masa-5s"))
+		Expect(resp.Header.Get("X-Cached-Result")).To(Equal("true"))
+
+		_, delegated, err = d.Status("local-job", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(delegated).To(BeFalse())
+	})
+
+	It("reuses the attestation of a peer until it expires", func() {
+		peer := newFakePeer("masa")
+		defer peer.Close()
+		d := newDelegator(verifyFake, peer.URL)
+
+		for range 3 {
+			_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(peer.attestations.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("does not delegate to peers with another signer", func() {
+		peer := newFakePeer("other")
+		defer peer.Close()
+		d := newDelegator(verifyFake, peer.URL)
+
+		_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+		Expect(err).To(MatchError(ErrNoPeer))
+		Expect(peer.submitted.Load()).To(BeZero())
+	})
+
+	It("does not delegate jobs the peers have no capabilities for", func() {
+		peer := newFakePeer("masa")
+		defer peer.Close()
+		d := newDelegator(verifyFake, peer.URL)
+
+		_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.TiktokJob})
+		Expect(err).To(MatchError(ErrNoPeer))
+		Expect(peer.submitted.Load()).To(BeZero())
+	})
+
+	It("offers the job to the next peer when a peer rejects it", func() {
+		busy := newFakePeer("masa")
+		busy.addStatus = http.StatusTooManyRequests
+		defer busy.Close()
+		idle := newFakePeer("masa")
+		defer idle.Close()
+		d := newDelegator(verifyFake, busy.URL, idle.URL)
+
+		for range 2 {
+			_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(idle.submitted.Load()).To(BeEquivalentTo(2))
+	})
+
+	It("sends nothing to peers whose certificate is not trusted", func() {
+		peer := newFakeTLSPeer("masa")
+		defer peer.Close()
+		d := newDelegator(verifyFake, peer.URL)
+
+		_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+		Expect(err).To(MatchError(ErrNoPeer))
+		Expect(peer.requests.Load()).To(BeZero())
+
+		roots := x509.NewCertPool()
+		roots.AddCert(peer.Certificate())
+		d, err = NewDelegator(config.PeerConfig{Workers: []string{peer.URL}, AttestationTTL: time.Minute, APIKey: "secret"}, "self", verifyFake, []byte("masa"), time.Minute, &tls.Config{RootCAs: roots})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(peer.submitted.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("trusts the peers without attestation in standalone mode", func() {
+		peer := newFakePeer("other")
+		defer peer.Close()
+		d := newDelegator(nil, peer.URL)
+
+		_, err := d.Forward(types.JobRequest{EncryptedJob: "sealed-job"}, types.Job{Type: teetypes.WebJob})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(peer.attestations.Load()).To(BeZero())
+	})
+})
@@ -0,0 +1,97 @@
+package redaction
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+const (
+	// keyFileName is the file in the data directory holding the sealed hash key
+	keyFileName = "redaction_key"
+	// keyPurpose is the purpose the hash key is sealed for
+	keyPurpose = "redaction-key"
+	// keySize is the size of the hash key in bytes
+	keySize = 32
+)
+
+// ErrKeyUnavailable is returned when hashing before the hash key can be unsealed or sealed
+var ErrKeyUnavailable = errors.New("the key of hashed redaction is not available until the worker has a sealing key")
+
+// KeyStore holds the key of the HMACs of ModeHash. The key is created when it is first needed and sealed in the data
+// directory, so it never leaves the enclave and only this worker can compute the hashes. Hashes are therefore only
+// stable across the results of the same worker. Without a data directory, the key only lasts until the worker
+// restarts.
+type KeyStore struct {
+	sync.Mutex
+	path string
+	key  []byte
+}
+
+// NewKeyStore creates a store for the hash key in the given data directory
+func NewKeyStore(dataDir string) *KeyStore {
+	if dataDir == "" {
+		return &KeyStore{}
+	}
+	return &KeyStore{path: filepath.Join(dataDir, keyFileName)}
+}
+
+// Key returns the hash key, reading it from the data directory or creating it on first use
+func (s *KeyStore) Key() ([]byte, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.key != nil {
+		return s.key, nil
+	}
+
+	if s.path == "" {
+		key, err := newKey()
+		if err != nil {
+			return nil, err
+		}
+		s.key = key
+		return s.key, nil
+	}
+
+	if !tee.SealingAvailable() {
+		return nil, ErrKeyUnavailable
+	}
+
+	key, legacy, err := tee.ReadSecretFile(s.path, keyPurpose)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if key, err = newKey(); err != nil {
+			return nil, err
+		}
+		if err := tee.WriteSecretFile(s.path, keyPurpose, key); err != nil {
+			return nil, fmt.Errorf("error saving the key of hashed redaction: %w", err)
+		}
+		logrus.Info("Created the key of hashed redaction")
+	case err != nil:
+		return nil, fmt.Errorf("error reading the key of hashed redaction: %w", err)
+	case legacy:
+		// A key which was not sealed may be known outside of the enclave, so it is never used
+		return nil, fmt.Errorf("the key of hashed redaction in %s is not sealed", s.path)
+	case len(key) != keySize:
+		return nil, fmt.Errorf("the key of hashed redaction in %s has %d bytes instead of %d", s.path, len(key), keySize)
+	}
+
+	s.key = key
+	return s.key, nil
+}
+
+func newKey() ([]byte, error) {
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("error creating the key of hashed redaction: %w", err)
+	}
+	return key, nil
+}
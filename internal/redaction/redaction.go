@@ -0,0 +1,185 @@
+package redaction
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Mode is the redaction mode requested for a job
+type Mode string
+
+const (
+	// ModeNone leaves the result untouched
+	ModeNone Mode = ""
+	// ModeStrip removes PII fields and replaces PII found in free text with a placeholder
+	ModeStrip Mode = "strip"
+	// ModeHash replaces PII with its HMAC-SHA256 keyed with the key of the worker, see KeyStore, so values can still be
+	// correlated across the results of the worker
+	ModeHash Mode = "hash"
+)
+
+// ArgumentKey is the job argument used to request redaction
+const ArgumentKey = "redact"
+
+// Placeholder replaces PII found in free text when using ModeStrip
+const Placeholder = "[redacted]"
+
+var (
+	emailRegexp = regexp.MustCompile(`[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	// Matches US-style numbers such as (555) 123-4567 and international numbers with a leading +, such as +44 20 7946 0958.
+	// Bare digit runs are deliberately not matched, since those are usually IDs.
+	phoneRegexp = regexp.MustCompile(`(?:\+\d{1,3}(?:[\s.\-]?\(?\d{2,4}\)?){2,5})|(?:\(?\b\d{3}\)?[\s.\-]\d{3}[\s.\-]\d{4}\b)`)
+)
+
+// locationKeys are JSON keys whose values are considered exact locations
+var locationKeys = map[string]struct{}{
+	"location":    {},
+	"geo":         {},
+	"coordinates": {},
+	"place":       {},
+	"latitude":    {},
+	"longitude":   {},
+	"lat":         {},
+	"lng":         {},
+	"lon":         {},
+	"address":     {},
+}
+
+// ParseMode parses a redaction mode from its string representation
+func ParseMode(s string) (Mode, error) {
+	switch Mode(strings.ToLower(strings.TrimSpace(s))) {
+	case ModeNone:
+		return ModeNone, nil
+	case ModeStrip:
+		return ModeStrip, nil
+	case ModeHash:
+		return ModeHash, nil
+	default:
+		return ModeNone, fmt.Errorf("invalid redaction mode %q, valid modes are %q and %q", s, ModeStrip, ModeHash)
+	}
+}
+
+// ModeFromArguments extracts the redaction mode from the job arguments. If no mode is requested it returns ModeNone.
+func ModeFromArguments(args map[string]any) (Mode, error) {
+	v, ok := args[ArgumentKey]
+	if !ok || v == nil {
+		return ModeNone, nil
+	}
+
+	s, ok := v.(string)
+	if !ok {
+		return ModeNone, fmt.Errorf("%s must be a string, got %T", ArgumentKey, v)
+	}
+
+	return ParseMode(s)
+}
+
+// Apply redacts PII from a JSON-encoded job result. Data that is not valid JSON is treated as free text. The key is
+// the key of the HMACs of ModeHash, and is not used by the other modes.
+func Apply(data []byte, mode Mode, key []byte) ([]byte, error) {
+	if mode == ModeNone || len(data) == 0 {
+		return data, nil
+	}
+	if mode == ModeHash && len(key) == 0 {
+		return nil, errors.New("hashed redaction requires a key")
+	}
+
+	r := redactor{mode: mode, key: key}
+	v, err := decodeJSON(data)
+	if err != nil {
+		return []byte(r.text(string(data))), nil
+	}
+
+	redacted, err := json.Marshal(r.value(v))
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling redacted result: %w", err)
+	}
+
+	return redacted, nil
+}
+
+// decodeJSON decodes numbers as json.Number, so IDs and integers don't lose precision when the result is encoded again
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the JSON value")
+	}
+	return v, nil
+}
+
+// redactor redacts the values of a result with a mode
+type redactor struct {
+	mode Mode
+	key  []byte
+}
+
+func (r redactor) value(v any) any {
+	switch val := v.(type) {
+	case map[string]any:
+		for k, child := range val {
+			if _, isLocation := locationKeys[strings.ToLower(k)]; isLocation {
+				if r.mode == ModeStrip {
+					delete(val, k)
+				} else {
+					val[k] = r.hashValue(child)
+				}
+				continue
+			}
+			val[k] = r.value(child)
+		}
+		return val
+	case []any:
+		for i, child := range val {
+			val[i] = r.value(child)
+		}
+		return val
+	case string:
+		return r.text(val)
+	default:
+		return val
+	}
+}
+
+func (r redactor) text(s string) string {
+	replace := func(match string) string {
+		if r.mode == ModeHash {
+			return r.hashString(match)
+		}
+		return Placeholder
+	}
+
+	s = emailRegexp.ReplaceAllStringFunc(s, replace)
+	return phoneRegexp.ReplaceAllStringFunc(s, replace)
+}
+
+func (r redactor) hashValue(v any) string {
+	if s, ok := v.(string); ok {
+		return r.hashString(s)
+	}
+
+	dat, err := json.Marshal(v)
+	if err != nil {
+		return Placeholder
+	}
+	return r.hashString(string(dat))
+}
+
+// hashString returns the keyed hash of the value. Unlike a plain digest, it can't be reversed by hashing every
+// possible value, such as every phone number, without the key.
+func (r redactor) hashString(s string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(s))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
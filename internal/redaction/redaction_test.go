@@ -0,0 +1,144 @@
+package redaction_test
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/redaction"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+)
+
+var _ = Describe("Redaction", func() {
+	Describe("ModeFromArguments", func() {
+		It("defaults to no redaction", func() {
+			mode, err := redaction.ModeFromArguments(map[string]any{"query": "foo"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(redaction.ModeNone))
+		})
+
+		It("parses valid modes case-insensitively", func() {
+			mode, err := redaction.ModeFromArguments(map[string]any{"redact": "HASH"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(mode).To(Equal(redaction.ModeHash))
+		})
+
+		It("rejects invalid modes", func() {
+			_, err := redaction.ModeFromArguments(map[string]any{"redact": "blur"})
+			Expect(err).To(HaveOccurred())
+
+			_, err = redaction.ModeFromArguments(map[string]any{"redact": true})
+			Expect(err).To(HaveOccurred())
+		})
+	})
+
+	Describe("Apply", func() {
+		key := []byte("0123456789abcdef0123456789abcdef")
+		input := []byte(`[{"id":"1234567890123","description":"Contact me at jane@example.com or +44 20 7946 0958","location":"221B Baker Street","created_at":"2024-01-15"}]`)
+
+		It("strips PII", func() {
+			out, err := redaction.Apply(input, redaction.ModeStrip, nil)
+			Expect(err).NotTo(HaveOccurred())
+
+			var res []map[string]any
+			Expect(json.Unmarshal(out, &res)).To(Succeed())
+			Expect(res).To(HaveLen(1))
+			Expect(res[0]).NotTo(HaveKey("location"))
+			Expect(res[0]["description"]).To(Equal("Contact me at [redacted] or [redacted]"))
+			Expect(res[0]["id"]).To(Equal("1234567890123"))
+			Expect(res[0]["created_at"]).To(Equal("2024-01-15"))
+		})
+
+		It("hashes PII deterministically", func() {
+			out, err := redaction.Apply(input, redaction.ModeHash, key)
+			Expect(err).NotTo(HaveOccurred())
+			again, err := redaction.Apply(input, redaction.ModeHash, key)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(Equal(again))
+
+			var res []map[string]any
+			Expect(json.Unmarshal(out, &res)).To(Succeed())
+			Expect(res[0]["location"]).To(HavePrefix("hmac-sha256:"))
+			Expect(res[0]["description"]).NotTo(ContainSubstring("jane@example.com"))
+			Expect(res[0]["description"]).To(ContainSubstring("hmac-sha256:"))
+		})
+
+		It("hashes PII with the key", func() {
+			out, err := redaction.Apply([]byte("call (555) 123-4567"), redaction.ModeHash, key)
+			Expect(err).NotTo(HaveOccurred())
+			other, err := redaction.Apply([]byte("call (555) 123-4567"), redaction.ModeHash, []byte("another key"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).NotTo(Equal(other))
+
+			_, err = redaction.Apply([]byte("call (555) 123-4567"), redaction.ModeHash, nil)
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("handles (555) 123-4567 style numbers in free text", func() {
+			out, err := redaction.Apply([]byte("call (555) 123-4567"), redaction.ModeStrip, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(out)).To(Equal("call [redacted]"))
+		})
+
+		It("keeps the precision of 64-bit IDs", func() {
+			out, err := redaction.Apply([]byte(`{"id":1790000000000000123,"score":0.5}`), redaction.ModeStrip, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(out)).To(Equal(`{"id":1790000000000000123,"score":0.5}`))
+		})
+
+		It("treats data followed by more than a JSON value as free text", func() {
+			out, err := redaction.Apply([]byte(`{"a":1} mail jane@example.com`), redaction.ModeStrip, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(out)).To(Equal(`{"a":1} mail [redacted]`))
+		})
+
+		It("leaves data untouched when no mode is requested", func() {
+			out, err := redaction.Apply(input, redaction.ModeNone, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(out).To(Equal(input))
+		})
+	})
+
+	Describe("KeyStore", func() {
+		BeforeEach(func() {
+			tee.CurrentKeyRing = tee.NewKeyRing()
+			tee.CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+			standalone := tee.SealStandaloneMode
+			tee.SealStandaloneMode = false
+			DeferCleanup(func() { tee.SealStandaloneMode = standalone })
+		})
+
+		It("seals the key and keeps it across restarts", func() {
+			dataDir := GinkgoT().TempDir()
+			key, err := redaction.NewKeyStore(dataDir).Key()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(key).To(HaveLen(32))
+
+			data, err := os.ReadFile(filepath.Join(dataDir, "redaction_key"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(tee.IsSealedSecret(data)).To(BeTrue())
+
+			again, err := redaction.NewKeyStore(dataDir).Key()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(again).To(Equal(key))
+		})
+
+		It("refuses a key which is not sealed", func() {
+			dataDir := GinkgoT().TempDir()
+			Expect(os.WriteFile(filepath.Join(dataDir, "redaction_key"), []byte("0123456789abcdef0123456789abcdef"), 0600)).To(Succeed())
+
+			_, err := redaction.NewKeyStore(dataDir).Key()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("waits for a sealing key", func() {
+			tee.CurrentKeyRing = tee.NewKeyRing()
+
+			_, err := redaction.NewKeyStore(GinkgoT().TempDir()).Key()
+			Expect(err).To(MatchError(redaction.ErrKeyUnavailable))
+		})
+	})
+})
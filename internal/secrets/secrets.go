@@ -0,0 +1,207 @@
+// Package secrets validates the provider secrets the worker is configured with, so that misconfigured workers are
+// visible at startup instead of when their first job fails.
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/jobs/twitter"
+	"github.com/masa-finance/tee-worker/pkg/client"
+	"github.com/sirupsen/logrus"
+)
+
+// checkTimeout is how long a secret can take to be validated before it is considered unverified
+const checkTimeout = 10 * time.Second
+
+// geminiModelsURL lists the models available to a Gemini API key, which is the cheapest way to check the key
+var geminiModelsURL = "https://generativelanguage.googleapis.com/v1beta/models"
+
+// The validators can be replaced in tests
+var (
+	validateApifyKey = func(apiKey string) error {
+		c, err := client.NewApifyClient(apiKey)
+		if err != nil {
+			return err
+		}
+		return c.ValidateApiKey()
+	}
+	validateGeminiKey     = geminiKeyValidator
+	validateTwitterApiKey = twitter.ValidateApiKey
+)
+
+var (
+	errCredentialKey      = errors.New("consumer keys can't be checked without signing requests")
+	errAccountNotLoggedIn = errors.New("accounts are not logged in to be checked, since that risks getting them locked")
+)
+
+// invalidErrorMessages and exhaustedErrorMessages are substrings of the errors of the validators which tell that a
+// secret was rejected, or that it has run out of quota
+var (
+	invalidErrorMessages   = []string{"invalid", "insufficient permissions", "unauthorized", "forbidden"}
+	exhaustedErrorMessages = []string{"rate limit", "quota", "status code: 402", "status code: 429", "status: 402", "status: 429"}
+)
+
+// check is a single secret to validate
+type check struct {
+	secret   string
+	value    string
+	validate func(string) error
+}
+
+// Validate checks every configured secret with its provider. Secrets are checked concurrently, and those which
+// can't be checked in time are reported as unverified. Secrets which are not configured are left out.
+func Validate(jc config.JobConfiguration) []types.SecretDiagnostic {
+	var checks []check
+	if key := jc.GetString("apify_api_key", ""); key != "" {
+		checks = append(checks, check{secret: "apify_api_key", value: key, validate: validateApifyKey})
+	}
+	if key := jc.GetString("gemini_api_key", ""); key != "" {
+		checks = append(checks, check{secret: "gemini_api_key", value: key, validate: validateGeminiKey})
+	}
+	for _, key := range jc.GetStringSlice("twitter_api_keys", nil) {
+		if key = strings.TrimSpace(key); key == "" {
+			continue
+		}
+		validate := validateTwitterApiKey
+		if strings.Contains(key, ":") {
+			validate = func(string) error { return errCredentialKey }
+		}
+		checks = append(checks, check{secret: "twitter_api_key", value: key, validate: validate})
+	}
+	for _, account := range jc.GetStringSlice("twitter_accounts", nil) {
+		if account = strings.TrimSpace(account); account == "" {
+			continue
+		}
+		checks = append(checks, check{secret: "twitter_account", value: account, validate: func(string) error { return errAccountNotLoggedIn }})
+	}
+
+	diagnostics := make([]types.SecretDiagnostic, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			diagnostics[i] = c.run()
+		}()
+	}
+	wg.Wait()
+
+	return diagnostics
+}
+
+// run validates the secret. A check which times out keeps running in the background, but its result is discarded.
+func (c check) run() types.SecretDiagnostic {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.validate(c.value)
+	}()
+
+	var err error
+	select {
+	case err = <-done:
+	case <-time.After(checkTimeout):
+		err = fmt.Errorf("timed out after %s", checkTimeout)
+	}
+
+	diagnostic := types.SecretDiagnostic{
+		Secret:      c.secret,
+		Fingerprint: fingerprint(c.value),
+		Status:      classify(err),
+		CheckedAt:   start.UTC(),
+	}
+	if err != nil {
+		// Some clients include the request URL, and with it the secret, in their errors
+		diagnostic.Error = strings.ReplaceAll(err.Error(), c.value, "<redacted>")
+	}
+	return diagnostic
+}
+
+// classify maps the error of a validator to the status of the secret. Errors which don't tell whether the secret is
+// valid, e.g. network errors, leave it unverified.
+func classify(err error) types.SecretStatus {
+	if err == nil {
+		return types.SecretValid
+	}
+	if errors.Is(err, errCredentialKey) || errors.Is(err, errAccountNotLoggedIn) {
+		return types.SecretUnverified
+	}
+
+	msg := strings.ToLower(err.Error())
+	if slices.ContainsFunc(exhaustedErrorMessages, func(m string) bool { return strings.Contains(msg, m) }) {
+		return types.SecretQuotaExhausted
+	}
+	if slices.ContainsFunc(invalidErrorMessages, func(m string) bool { return strings.Contains(msg, m) }) {
+		return types.SecretInvalid
+	}
+	return types.SecretUnverified
+}
+
+// fingerprint identifies a secret in logs and responses without revealing it, like the fingerprints of Twitter API keys
+func fingerprint(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:4])
+}
+
+// geminiKeyValidator checks a Gemini API key by listing the models it can use
+func geminiKeyValidator(apiKey string) error {
+	httpClient := &http.Client{Timeout: checkTimeout}
+	resp, err := httpClient.Get(geminiModelsURL + "?pageSize=1&key=" + url.QueryEscape(apiKey))
+	if err != nil {
+		// The URL contains the key, so it is left out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return fmt.Errorf("request error: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusForbidden:
+		return errors.New("invalid Gemini API key")
+	case http.StatusTooManyRequests:
+		return errors.New("Gemini API quota exhausted")
+	default:
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+}
+
+// Healthy returns false if any secret is invalid or out of quota
+func Healthy(diagnostics []types.SecretDiagnostic) bool {
+	return !slices.ContainsFunc(diagnostics, func(d types.SecretDiagnostic) bool {
+		return d.Status == types.SecretInvalid || d.Status == types.SecretQuotaExhausted
+	})
+}
+
+// Log reports the diagnostics, as warnings for the secrets which can't be used
+func Log(diagnostics []types.SecretDiagnostic) {
+	for _, d := range diagnostics {
+		entry := logrus.WithFields(logrus.Fields{
+			"secret":      d.Secret,
+			"fingerprint": d.Fingerprint,
+			"status":      d.Status,
+		})
+		switch d.Status {
+		case types.SecretValid:
+			entry.Info("Secret validated")
+		case types.SecretUnverified:
+			entry.Infof("Secret could not be validated: %s", d.Error)
+		default:
+			entry.Warnf("Secret can't be used: %s", d.Error)
+		}
+	}
+}
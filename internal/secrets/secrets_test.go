@@ -0,0 +1,97 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+)
+
+var _ = Describe("Secrets", func() {
+	BeforeEach(func() {
+		apify, gemini, twitter := validateApifyKey, validateGeminiKey, validateTwitterApiKey
+		DeferCleanup(func() {
+			validateApifyKey, validateGeminiKey, validateTwitterApiKey = apify, gemini, twitter
+		})
+	})
+
+	It("classifies the errors of the validators", func() {
+		Expect(classify(nil)).To(Equal(types.SecretValid))
+		Expect(classify(errors.New("invalid Apify API token"))).To(Equal(types.SecretInvalid))
+		Expect(classify(errors.New("insufficient permissions for Apify API token"))).To(Equal(types.SecretInvalid))
+		Expect(classify(errors.New("rate limit exceeded"))).To(Equal(types.SecretQuotaExhausted))
+		Expect(classify(errors.New("Apify API auth test failed with status: 402"))).To(Equal(types.SecretQuotaExhausted))
+		Expect(classify(errors.New("request error: dial tcp: connection refused"))).To(Equal(types.SecretUnverified))
+		Expect(classify(fmt.Errorf("wrapped: %w", errCredentialKey))).To(Equal(types.SecretUnverified))
+	})
+
+	It("validates every configured secret", func() {
+		validateApifyKey = func(string) error {
+			return errors.New("error making auth test request: https://api.apify.com/v2/users/me?token=apify-key: EOF")
+		}
+		validateGeminiKey = func(string) error { return nil }
+		validateTwitterApiKey = func(key string) error {
+			if key == "revoked" {
+				return errors.New("invalid API key")
+			}
+			return nil
+		}
+
+		diagnostics := Validate(config.JobConfiguration{
+			"apify_api_key":    "apify-key",
+			"gemini_api_key":   "gemini-key",
+			"twitter_api_keys": []string{"good", "revoked", "consumer:secret"},
+			"twitter_accounts": []string{"user:pass"},
+		})
+
+		statuses := map[string]types.SecretStatus{}
+		for _, d := range diagnostics {
+			Expect(d.Fingerprint).To(HaveLen(8))
+			Expect(d.CheckedAt).NotTo(BeZero())
+			Expect(d.Error).NotTo(ContainSubstring("apify-key"))
+			statuses[d.Secret+"/"+d.Fingerprint] = d.Status
+		}
+		Expect(statuses).To(Equal(map[string]types.SecretStatus{
+			"apify_api_key/" + fingerprint("apify-key"):         types.SecretUnverified,
+			"gemini_api_key/" + fingerprint("gemini-key"):       types.SecretValid,
+			"twitter_api_key/" + fingerprint("good"):            types.SecretValid,
+			"twitter_api_key/" + fingerprint("revoked"):         types.SecretInvalid,
+			"twitter_api_key/" + fingerprint("consumer:secret"): types.SecretUnverified,
+			"twitter_account/" + fingerprint("user:pass"):       types.SecretUnverified,
+		}))
+		Expect(Healthy(diagnostics)).To(BeFalse())
+	})
+
+	It("leaves out secrets which are not configured", func() {
+		diagnostics := Validate(config.JobConfiguration{"apify_api_key": ""})
+		Expect(diagnostics).To(BeEmpty())
+		Expect(Healthy(diagnostics)).To(BeTrue())
+	})
+
+	It("checks Gemini API keys by listing the models", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Query().Get("key") {
+			case "good":
+				w.WriteHeader(http.StatusOK)
+			case "exhausted":
+				w.WriteHeader(http.StatusTooManyRequests)
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		}))
+		DeferCleanup(server.Close)
+		url := geminiModelsURL
+		geminiModelsURL = server.URL
+		DeferCleanup(func() { geminiModelsURL = url })
+
+		Expect(classify(geminiKeyValidator("good"))).To(Equal(types.SecretValid))
+		Expect(classify(geminiKeyValidator("exhausted"))).To(Equal(types.SecretQuotaExhausted))
+		Expect(classify(geminiKeyValidator("wrong"))).To(Equal(types.SecretInvalid))
+	})
+})
@@ -0,0 +1,149 @@
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/pkg/retry"
+	"github.com/masa-finance/tee-worker/pkg/tee"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNoSecret is returned when pushing is enabled without a secret to sign the pushes with
+var ErrNoSecret = errors.New("TELEMETRY_PUSH_SECRET is not set")
+
+// StatsFunc returns the current statistics of the worker as JSON, e.g. JobServer.GetStats
+type StatsFunc func() ([]byte, error)
+
+// QuoteFunc generates an SGX quote embedding the given report data, e.g. enclave.GetRemoteReport
+type QuoteFunc func(reportData []byte) ([]byte, error)
+
+// Pusher posts the statistics of the worker to a collector on every tick of the interval, for workers behind NAT
+// which the indexer can't reach to pull them with telemetry jobs. Every push is signed with the shared secret, and
+// carries a quote binding it to the enclave if a QuoteFunc is given. Pushes which fail are retried with an
+// exponential backoff; a snapshot which could not be delivered is dropped, since the next one supersedes it.
+type Pusher struct {
+	HTTPClient *http.Client
+
+	cfg   config.TelemetryPushConfig
+	stats StatsFunc
+	quote QuoteFunc
+}
+
+// NewPusher returns a Pusher for the configuration. quote may be nil, e.g. in standalone mode.
+func NewPusher(cfg config.TelemetryPushConfig, stats StatsFunc, quote QuoteFunc) (*Pusher, error) {
+	if cfg.Secret == "" {
+		return nil, ErrNoSecret
+	}
+	return &Pusher{
+		HTTPClient: &http.Client{Timeout: 30 * time.Second},
+		cfg:        cfg,
+		stats:      stats,
+		quote:      quote,
+	}, nil
+}
+
+// Run pushes the statistics right away and then on every tick of the interval, until the context is done
+func (p *Pusher) Run(ctx context.Context) {
+	logrus.Infof("Pushing statistics to %s every %s", p.cfg.URL, p.cfg.Interval)
+	ticker := time.NewTicker(p.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		if err := p.Push(ctx); err != nil && ctx.Err() == nil {
+			logrus.Errorf("Failed to push statistics to %s: %v", p.cfg.URL, err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Push sends a snapshot of the statistics to the collector, retrying up to MaxRetries times
+func (p *Pusher) Push(ctx context.Context) error {
+	body, err := p.snapshot()
+	if err != nil {
+		return err
+	}
+
+	policy := retry.Policy{
+		MaxAttempts: p.cfg.MaxRetries + 1,
+		Initial:     p.cfg.Backoff,
+		Multiplier:  2,
+		OnRetry: func(_ int, wait time.Duration, err error) {
+			logrus.Warnf("Failed to push statistics, retrying in %s: %v", wait, err)
+		},
+	}
+	return retry.Do(ctx, policy, func() error {
+		retryAfter, err := p.send(ctx, body)
+		if err != nil && retryAfter < 0 {
+			return retry.Permanent(err)
+		}
+		return retry.After(err, retryAfter)
+	})
+}
+
+// snapshot returns the body of a push with the current statistics
+func (p *Pusher) snapshot() ([]byte, error) {
+	stats, err := p.stats()
+	if err != nil {
+		return nil, fmt.Errorf("error getting statistics: %w", err)
+	}
+
+	push := types.TelemetryPush{WorkerID: tee.CurrentWorkerID(), SentAt: time.Now().Unix(), Stats: stats}
+	if p.quote != nil {
+		quote, err := p.quote(types.TelemetryReportData(push.WorkerID, push.SentAt, stats))
+		if err != nil {
+			return nil, fmt.Errorf("error generating quote: %w", err)
+		}
+		push.Quote = base64.StdEncoding.EncodeToString(quote)
+	}
+
+	body, err := json.Marshal(push)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling statistics: %w", err)
+	}
+	return body, nil
+}
+
+// send posts the body once. If it fails, it returns how long the collector asked to wait before retrying, or a
+// negative duration if the push must not be retried, e.g. because the collector rejected the signature.
+func (p *Pusher) send(ctx context.Context, body []byte) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return -1, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(types.TelemetrySignatureHeader, types.TelemetrySignature(p.cfg.Secret, body))
+
+	resp, err := p.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		var retryAfter time.Duration
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && seconds > 0 {
+			retryAfter = min(time.Duration(seconds)*time.Second, p.cfg.Interval)
+		}
+		return retryAfter, fmt.Errorf("collector returned %s", resp.Status)
+	default:
+		return -1, fmt.Errorf("collector returned %s", resp.Status)
+	}
+}
@@ -0,0 +1,146 @@
+package telemetry_test
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/api/types"
+	"github.com/masa-finance/tee-worker/internal/config"
+	"github.com/masa-finance/tee-worker/internal/telemetry"
+)
+
+// collector records the pushes it receives and answers with the given status codes, then with 204
+type collector struct {
+	sync.Mutex
+	statuses []int
+	pushes   []types.TelemetryPush
+	verified []bool
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	var push types.TelemetryPush
+	Expect(json.Unmarshal(body, &push)).To(Succeed())
+
+	c.Lock()
+	defer c.Unlock()
+	c.pushes = append(c.pushes, push)
+	c.verified = append(c.verified, types.VerifyTelemetrySignature("secret", body, r.Header.Get(types.TelemetrySignatureHeader)))
+	status := http.StatusNoContent
+	if len(c.statuses) > 0 {
+		status, c.statuses = c.statuses[0], c.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (c *collector) received() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.pushes)
+}
+
+var _ = Describe("Pusher", func() {
+	var (
+		c      *collector
+		server *httptest.Server
+		cfg    config.TelemetryPushConfig
+		stats  = func() ([]byte, error) { return []byte(`{"stats":{"worker":{"twitter_scrapes":1}}}`), nil }
+	)
+
+	BeforeEach(func() {
+		c = &collector{}
+		server = httptest.NewServer(c)
+		DeferCleanup(server.Close)
+		cfg = config.TelemetryPushConfig{URL: server.URL, Secret: "secret", Interval: time.Hour, MaxRetries: 2, Backoff: time.Millisecond}
+	})
+
+	It("requires a secret", func() {
+		cfg.Secret = ""
+		_, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).To(MatchError(telemetry.ErrNoSecret))
+	})
+
+	It("pushes signed statistics", func() {
+		p, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(Succeed())
+
+		Expect(c.pushes).To(HaveLen(1))
+		Expect(c.verified).To(Equal([]bool{true}))
+		Expect(c.pushes[0].Stats).To(MatchJSON(`{"stats":{"worker":{"twitter_scrapes":1}}}`))
+		Expect(c.pushes[0].SentAt).To(BeNumerically("~", time.Now().Unix(), 5))
+		Expect(c.pushes[0].Quote).To(BeEmpty())
+	})
+
+	It("binds the quote to the push", func() {
+		var reportData []byte
+		p, err := telemetry.NewPusher(cfg, stats, func(data []byte) ([]byte, error) {
+			reportData = data
+			return []byte("quote"), nil
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(Succeed())
+
+		push := c.pushes[0]
+		Expect(push.Quote).To(Equal(base64.StdEncoding.EncodeToString([]byte("quote"))))
+		Expect(reportData).To(Equal(types.TelemetryReportData(push.WorkerID, push.SentAt, push.Stats)))
+	})
+
+	It("retries pushes which failed", func() {
+		c.statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		p, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(Succeed())
+		Expect(c.pushes).To(HaveLen(3))
+		Expect(c.pushes[2]).To(Equal(c.pushes[0]))
+	})
+
+	It("gives up after MaxRetries retries", func() {
+		c.statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+		p, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(MatchError(ContainSubstring("502")))
+		Expect(c.pushes).To(HaveLen(3))
+	})
+
+	It("doesn't retry pushes the collector rejected", func() {
+		c.statuses = []int{http.StatusUnauthorized}
+		p, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(MatchError(ContainSubstring("401")))
+		Expect(c.pushes).To(HaveLen(1))
+	})
+
+	It("doesn't push if the statistics can't be read", func() {
+		p, err := telemetry.NewPusher(cfg, func() ([]byte, error) { return nil, errors.New("broken") }, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(p.Push(context.Background())).To(MatchError(ContainSubstring("broken")))
+		Expect(c.pushes).To(BeEmpty())
+	})
+
+	It("pushes on every tick until the context is done", func() {
+		cfg.Interval = 20 * time.Millisecond
+		p, err := telemetry.NewPusher(cfg, stats, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			p.Run(ctx)
+			close(done)
+		}()
+		Eventually(c.received).Should(BeNumerically(">=", 3))
+		cancel()
+		Eventually(done).Should(BeClosed())
+	})
+})
@@ -0,0 +1,225 @@
+package tracing
+
+/*
+Package tracing traces the jobs with OpenTelemetry, from the API request which submitted them through the job server
+queue and their execution down to the requests made by the scrapers:
+
+   POST /job/add                 server span of the API request, see Middleware
+   └─ job <type>                 from the dispatch of the job until its result is stored, see StartJob
+      ├─ execute                 an attempt of the job, see JobSpan.StartAttempt
+      │  └─ GET api.x.com        a request made through the bandwidth meter of the job, see JobSpan.HTTP
+      └─ sample, redact, ...     the phases after the execution, see JobSpan.Phase
+
+The spans are exported with OTLP/HTTP if OTEL_EXPORTER_OTLP_ENDPOINT is set, see Setup. Otherwise the global tracer
+provider is a no-op, and so is every function of the package. A nil JobSpan is valid and traces nothing.
+*/
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	// ServiceName is the service.name of the spans of the worker
+	ServiceName = "tee-worker"
+
+	tracerName = "github.com/masa-finance/tee-worker"
+)
+
+// Setup exports the spans to the OTLP/HTTP endpoint, if there is one, and propagates the trace context of the API
+// requests in the W3C traceparent header. The exporter honors the standard OTEL_EXPORTER_OTLP_* variables, e.g. for
+// headers or TLS. The returned function flushes the pending spans and must be called before exiting.
+func Setup(ctx context.Context, endpoint, workerID string) (func(context.Context) error, error) {
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the OTLP exporter: %w", err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName(ServiceName),
+		semconv.ServiceInstanceID(workerID),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the resource of the tracer provider: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer of the worker, from the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// Middleware starts a server span for every API request, continuing the trace of the caller if the request carries
+// its context. Handlers find the span in the context of the request.
+func Middleware() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			req := c.Request()
+			ctx := otel.GetTextMapPropagator().Extract(req.Context(), propagation.HeaderCarrier(req.Header))
+
+			// The route is used instead of the path, which holds the job UUIDs
+			route := c.Path()
+			if route == "" {
+				route = req.URL.Path
+			}
+			ctx, span := Tracer().Start(ctx, req.Method+" "+route,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					semconv.HTTPRequestMethodKey.String(req.Method),
+					semconv.HTTPRoute(route),
+				),
+			)
+			defer span.End()
+
+			c.SetRequest(req.WithContext(ctx))
+			err := next(c)
+			if err != nil {
+				// Let echo write the error response, so its status code is known
+				c.Error(err)
+			}
+
+			status := c.Response().Status
+			span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+			if status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(status))
+			}
+			return err
+		}
+	}
+}
+
+// SpanContext returns the span context of the API request, which the spans of its job are children of
+func SpanContext(ctx context.Context) trace.SpanContext {
+	return trace.SpanContextFromContext(ctx)
+}
+
+// JobSpan is a span of a job, or of an attempt of a job
+type JobSpan struct {
+	ctx  context.Context
+	span trace.Span
+}
+
+// StartJob starts the span of a job as a child of the span context of the API request which submitted it, if it is
+// valid. The span has to be ended once the result of the job is stored.
+func StartJob(parent trace.SpanContext, jobUUID, jobType string) *JobSpan {
+	ctx := context.Background()
+	if parent.IsValid() {
+		ctx = trace.ContextWithRemoteSpanContext(ctx, parent)
+	}
+	ctx, span := Tracer().Start(ctx, "job "+jobType, trace.WithAttributes(
+		attribute.String("job.uuid", jobUUID),
+		attribute.String("job.type", jobType),
+	))
+	return &JobSpan{ctx: ctx, span: span}
+}
+
+// StartAttempt starts the span of an attempt of the job, which is a child of the span of the job. The time the job
+// waited in the queue is the time between the start of the span of the job, or of the previous attempt, and this one.
+func (s *JobSpan) StartAttempt(attempt int) *JobSpan {
+	if s == nil {
+		return nil
+	}
+	ctx, span := Tracer().Start(s.ctx, "execute", trace.WithAttributes(attribute.Int("job.attempt", attempt)))
+	return &JobSpan{ctx: ctx, span: span}
+}
+
+// Phase records a phase of the job which has already finished as a child span, e.g. the sampling of its result
+func (s *JobSpan) Phase(name string, startedAt, finishedAt time.Time, err error) {
+	if s == nil {
+		return
+	}
+	_, span := Tracer().Start(s.ctx, name, trace.WithTimestamp(startedAt))
+	setError(span, err)
+	span.End(trace.WithTimestamp(finishedAt))
+}
+
+// HTTP records a request made by the job as a client span. It can be passed to bandwidth.Meter.Observe.
+func (s *JobSpan) HTTP(req bandwidth.Request) {
+	if s == nil {
+		return
+	}
+
+	attrs := []attribute.KeyValue{semconv.HTTPRequestMethodKey.String(req.Method)}
+	name := req.Method
+	if req.URL != nil {
+		// The query and the user info may hold secrets
+		attrs = append(attrs, semconv.ServerAddress(req.URL.Hostname()), semconv.URLFull(req.URL.Scheme+"://"+req.URL.Host+req.URL.Path))
+		name += " " + req.URL.Hostname()
+	}
+	if req.StatusCode != 0 {
+		attrs = append(attrs, semconv.HTTPResponseStatusCode(req.StatusCode))
+	}
+
+	_, span := Tracer().Start(s.ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(req.StartedAt),
+		trace.WithAttributes(attrs...),
+	)
+	if req.Err == nil && req.StatusCode >= http.StatusBadRequest {
+		setError(span, errors.New(http.StatusText(req.StatusCode)))
+	} else {
+		setError(span, req.Err)
+	}
+	span.End(trace.WithTimestamp(req.StartedAt.Add(req.Duration)))
+}
+
+// Retry records that the job is retried after the delay because of the error
+func (s *JobSpan) Retry(delay time.Duration, err error) {
+	if s == nil {
+		return
+	}
+	attrs := []attribute.KeyValue{attribute.Int64("job.retry_delay_ms", delay.Milliseconds())}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	s.span.AddEvent("retry", trace.WithAttributes(attrs...))
+}
+
+// Recording returns true if the span is exported, so the caller can skip gathering what it would record
+func (s *JobSpan) Recording() bool {
+	return s != nil && s.span.IsRecording()
+}
+
+// End ends the span, with an error status if the job failed
+func (s *JobSpan) End(err error) {
+	if s == nil {
+		return
+	}
+	setError(s.span, err)
+	s.span.End()
+}
+
+// setError records the error on the span and sets its status, if there is an error
+func setError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
@@ -0,0 +1,132 @@
+package tracing_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/masa-finance/tee-worker/internal/bandwidth"
+	"github.com/masa-finance/tee-worker/internal/tracing"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+var _ = Describe("Tracing", func() {
+	var recorder *tracetest.SpanRecorder
+
+	BeforeEach(func() {
+		recorder = tracetest.NewSpanRecorder()
+		otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+		otel.SetTextMapPropagator(propagation.TraceContext{})
+	})
+
+	AfterEach(func() {
+		otel.SetTracerProvider(noop.NewTracerProvider())
+	})
+
+	spanNamed := func(name string) sdktrace.ReadOnlySpan {
+		for _, s := range recorder.Ended() {
+			if s.Name() == name {
+				return s
+			}
+		}
+		Fail("no span named " + name)
+		return nil
+	}
+
+	It("should do nothing without an endpoint", func() {
+		shutdown, err := tracing.Setup(GinkgoT().Context(), "", "worker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(shutdown(GinkgoT().Context())).To(Succeed())
+	})
+
+	It("should trace nothing with a nil span", func() {
+		var s *tracing.JobSpan
+		Expect(s.StartAttempt(0)).To(BeNil())
+		Expect(s.Recording()).To(BeFalse())
+		s.HTTP(bandwidth.Request{Method: "GET"})
+		s.Phase("sample", time.Now(), time.Now(), nil)
+		s.Retry(time.Second, errors.New("boom"))
+		s.End(nil)
+		Expect(recorder.Ended()).To(BeEmpty())
+	})
+
+	It("should record the spans of a job under the span of the request", func() {
+		ctx, request := tracing.Tracer().Start(GinkgoT().Context(), "POST /job/add")
+		job := tracing.StartJob(tracing.SpanContext(ctx), "uuid", "web")
+		Expect(job.Recording()).To(BeTrue())
+		request.End()
+
+		attempt := job.StartAttempt(1)
+		startedAt := time.Now().Add(-time.Second)
+		attempt.HTTP(bandwidth.Request{
+			Method:     "GET",
+			URL:        &url.URL{Scheme: "https", Host: "example.com", Path: "/page", RawQuery: "token=secret"},
+			StatusCode: http.StatusTooManyRequests,
+			StartedAt:  startedAt,
+			Duration:   300 * time.Millisecond,
+		})
+		attempt.End(nil)
+		job.Phase("sample", startedAt, startedAt.Add(time.Millisecond), nil)
+		job.Retry(time.Second, errors.New("rate limited"))
+		job.End(errors.New("failed"))
+
+		jobSpan := spanNamed("job web")
+		Expect(jobSpan.Parent().SpanID()).To(Equal(request.SpanContext().SpanID()))
+		Expect(jobSpan.Attributes()).To(ContainElement(attribute.String("job.uuid", "uuid")))
+		Expect(jobSpan.Status().Code).To(Equal(codes.Error))
+		Expect(jobSpan.Events()).To(ContainElement(HaveField("Name", "retry")))
+
+		attemptSpan := spanNamed("execute")
+		Expect(attemptSpan.Parent().SpanID()).To(Equal(jobSpan.SpanContext().SpanID()))
+		Expect(attemptSpan.Attributes()).To(ContainElement(attribute.Int("job.attempt", 1)))
+
+		httpSpan := spanNamed("GET example.com")
+		Expect(httpSpan.Parent().SpanID()).To(Equal(attemptSpan.SpanContext().SpanID()))
+		Expect(httpSpan.SpanKind()).To(Equal(trace.SpanKindClient))
+		Expect(httpSpan.StartTime()).To(Equal(startedAt))
+		Expect(httpSpan.EndTime()).To(Equal(startedAt.Add(300 * time.Millisecond)))
+		Expect(httpSpan.Status().Code).To(Equal(codes.Error))
+		Expect(httpSpan.Attributes()).To(ContainElement(attribute.String("url.full", "https://example.com/page")))
+
+		Expect(spanNamed("sample").Parent().SpanID()).To(Equal(jobSpan.SpanContext().SpanID()))
+	})
+
+	It("should start a job span without a parent", func() {
+		tracing.StartJob(trace.SpanContext{}, "uuid", "web").End(nil)
+		Expect(spanNamed("job web").Parent().IsValid()).To(BeFalse())
+	})
+
+	It("should continue the trace of the caller of the API", func() {
+		e := echo.New()
+		e.Use(tracing.Middleware())
+		var jobParent trace.SpanContext
+		e.GET("/job/status/:job_id", func(c echo.Context) error {
+			jobParent = tracing.SpanContext(c.Request().Context())
+			return c.String(http.StatusNotFound, "not found")
+		})
+
+		req := httptest.NewRequest(http.MethodGet, "/job/status/1234", nil)
+		req.Header.Set("traceparent", "00-0af7651916cd43dd8448eb211c80319c-b7ad6b7169203331-01")
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+
+		span := spanNamed("GET /job/status/:job_id")
+		Expect(span.SpanKind()).To(Equal(trace.SpanKindServer))
+		Expect(span.SpanContext().TraceID().String()).To(Equal("0af7651916cd43dd8448eb211c80319c"))
+		Expect(span.Parent().SpanID().String()).To(Equal("b7ad6b7169203331"))
+		Expect(span.Attributes()).To(ContainElement(attribute.Int("http.response.status_code", http.StatusNotFound)))
+		Expect(jobParent.SpanID()).To(Equal(span.SpanContext().SpanID()))
+	})
+})
@@ -2,22 +2,29 @@ package client
 
 import (
 	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"math"
 	"net/http"
+	"slices"
+	"strconv"
 	"time"
 
 	"github.com/masa-finance/tee-worker/internal/apify"
+	"github.com/masa-finance/tee-worker/pkg/errcode"
+	"github.com/masa-finance/tee-worker/pkg/retry"
 	"github.com/sirupsen/logrus"
 )
 
 const (
 	apifyBaseURL      = "https://api.apify.com/v2"
-	MaxActorPolls     = 60              // 5 minutes max wait time
-	ActorPollInterval = 5 * time.Second // polling interval between status checks
+	MaxActorPolls     = 60              // 5 minutes max wait time, unless set with ActorPolling
+	ActorPollInterval = 5 * time.Second // polling interval between status checks, unless set with ActorPolling
 
 	// Actor run status constants
 	ActorStatusSucceeded = "SUCCEEDED"
@@ -45,15 +52,33 @@ type ActorRunResponse struct {
 		ID               string `json:"id"`
 		Status           string `json:"status"`
 		DefaultDatasetId string `json:"defaultDatasetId"`
+		Stats            struct {
+			ComputeUnits float64 `json:"computeUnits"`
+		} `json:"stats"`
+		// Usage is the amount of each billed resource used by the run, e.g. DATASET_READS
+		Usage         map[string]float64 `json:"usage"`
+		UsageTotalUsd float64            `json:"usageTotalUsd"`
 	} `json:"data"`
 }
 
+// ActorRunCost is what an actor run cost, as reported by the run detail once the run has finished. DatasetReads
+// includes the reads of the run itself and the items the client read from its dataset.
+type ActorRunCost struct {
+	RunID        string
+	ActorID      apify.ActorId
+	ComputeUnits float64
+	DatasetReads uint
+	UsageUSD     float64
+}
+
 // ApifyDatasetData holds the items from an Apify dataset
 type ApifyDatasetData struct {
 	Items  []json.RawMessage `json:"items"`
 	Count  uint              `json:"count"`
 	Offset uint              `json:"offset"`
 	Limit  uint              `json:"limit"`
+	// Total is the number of items in the dataset, or 0 if Apify did not report it
+	Total uint `json:"total"`
 }
 
 // DatasetResponse represents the response from getting dataset items
@@ -65,6 +90,8 @@ type DatasetResponse struct {
 // CursorData represents the pagination data stored in cursor
 type CursorData struct {
 	Offset uint `json:"offset"`
+	// Total is the number of items in the dataset being paginated, if it is known
+	Total uint `json:"total,omitempty"`
 }
 
 // Cursor represents an encoded CursorData
@@ -77,6 +104,12 @@ func (c Cursor) String() string {
 	return string(c)
 }
 
+// Total returns the number of items of the dataset the cursor pages through, or 0 if it is not known
+func (c Cursor) Total() uint {
+	cursorData, _ := decodeCursor(c)
+	return cursorData.Total
+}
+
 // NewApifyClient creates a new Apify client with functional options
 func NewApifyClient(apiToken string, opts ...Option) (Apify, error) {
 	logrus.Info("Creating new ApifyClient with API token")
@@ -141,9 +174,20 @@ func (c *ApifyClient) ProbeActorAccess(actorId apify.ActorId, input map[string]a
 	return true, nil
 }
 
-// RunActor runs an actor with the given input
+// RunActor runs an actor with the given input, using the build it is pinned to. Only the actors used by the worker
+// may be run.
 func (c *ApifyClient) RunActor(actorId apify.ActorId, input any) (*ActorRunResponse, error) {
+	if !apify.Allowed(actorId) {
+		return nil, fmt.Errorf("%w: %s", ErrActorNotAllowed, actorId)
+	}
 	url := fmt.Sprintf("%s/acts/%s/runs?token=%s", c.baseUrl, actorId, c.apiToken)
+	if build, ok := apify.ActorBuilds[actorId]; ok {
+		url += "&build=" + build
+	}
+	// Apify stops the run by itself if the worker can't abort it in time, e.g. because it is restarted
+	if deadline := c.httpOptions.actorRunDeadline; !deadline.IsZero() {
+		url += fmt.Sprintf("&timeout=%d", max(1, int(math.Ceil(time.Until(deadline).Seconds()))))
+	}
 	logrus.Infof("Running actor %s", actorId)
 
 	// Marshal input to JSON
@@ -195,6 +239,143 @@ func (c *ApifyClient) RunActor(actorId apify.ActorId, input any) (*ActorRunRespo
 	return &runResp, nil
 }
 
+// actorResponse is the part of the actor detail listing its tagged builds
+type actorResponse struct {
+	Data struct {
+		TaggedBuilds map[string]struct {
+			BuildNumber string `json:"buildNumber"`
+		} `json:"taggedBuilds"`
+	} `json:"data"`
+}
+
+// actorBuildsResponse is a page of the builds of an actor
+type actorBuildsResponse struct {
+	Data struct {
+		Items []struct {
+			BuildNumber string `json:"buildNumber"`
+			Status      string `json:"status"`
+		} `json:"items"`
+	} `json:"data"`
+}
+
+// ActorBuildExists returns true if the actor has a build with the given tag, or a successful build with the given
+// build number
+func (c *ApifyClient) ActorBuildExists(actorId apify.ActorId, build string) (bool, error) {
+	var actor actorResponse
+	if err := c.getJSON(fmt.Sprintf("%s/acts/%s?token=%s", c.baseUrl, actorId, c.apiToken), &actor); err != nil {
+		return false, err
+	}
+	if _, ok := actor.Data.TaggedBuilds[build]; ok {
+		return true, nil
+	}
+
+	var builds actorBuildsResponse
+	if err := c.getJSON(fmt.Sprintf("%s/acts/%s/builds?token=%s&desc=1&limit=1000", c.baseUrl, actorId, c.apiToken), &builds); err != nil {
+		return false, err
+	}
+	for _, b := range builds.Data.Items {
+		if b.BuildNumber == build && b.Status == ActorStatusSucceeded {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ValidateActorBuilds checks that the builds the actors are pinned to in apify.ActorBuilds exist. Missing builds are
+// reported as ErrActorBuildNotFound.
+func ValidateActorBuilds(apiToken string, opts ...Option) error {
+	options, err := NewOptions(opts...)
+	if err != nil {
+		return fmt.Errorf("failed to create options: %w", err)
+	}
+	c := &ApifyClient{apiToken: apiToken, baseUrl: apifyBaseURL, httpOptions: options}
+
+	var errs []error
+	for _, actorId := range slices.Sorted(maps.Keys(apify.ActorBuilds)) {
+		build := apify.ActorBuilds[actorId]
+		exists, err := c.ActorBuildExists(actorId, build)
+		switch {
+		case err != nil:
+			errs = append(errs, fmt.Errorf("error checking build %s of actor %s: %w", build, actorId, err))
+		case !exists:
+			errs = append(errs, fmt.Errorf("%w: %s@%s", ErrActorBuildNotFound, actorId, build))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// ApifyLimits is the monthly usage of an Apify account and its limit
+type ApifyLimits struct {
+	MonthlyUsageUsd    float64
+	MaxMonthlyUsageUsd float64
+	// CycleEndAt is when the usage cycle ends and the usage is reset
+	CycleEndAt time.Time
+}
+
+// Exhausted returns true if the account has used up its monthly usage, so actors can't be run until the cycle ends
+func (l *ApifyLimits) Exhausted() bool {
+	return l.MaxMonthlyUsageUsd > 0 && l.MonthlyUsageUsd >= l.MaxMonthlyUsageUsd
+}
+
+// limitsResponse is the part of the limits of an account used by the worker
+type limitsResponse struct {
+	Data struct {
+		MonthlyUsageCycle struct {
+			EndAt time.Time `json:"endAt"`
+		} `json:"monthlyUsageCycle"`
+		Limits struct {
+			MaxMonthlyUsageUsd float64 `json:"maxMonthlyUsageUsd"`
+		} `json:"limits"`
+		Current struct {
+			MonthlyUsageUsd float64 `json:"monthlyUsageUsd"`
+		} `json:"current"`
+	} `json:"data"`
+}
+
+// GetLimits returns the monthly usage of the account and its limit. Like ValidateApiKey it doesn't consume any usage.
+func (c *ApifyClient) GetLimits() (*ApifyLimits, error) {
+	var limits limitsResponse
+	if err := c.getJSON(fmt.Sprintf("%s/users/me/limits?token=%s", c.baseUrl, c.apiToken), &limits); err != nil {
+		return nil, err
+	}
+	return &ApifyLimits{
+		MonthlyUsageUsd:    limits.Data.Current.MonthlyUsageUsd,
+		MaxMonthlyUsageUsd: limits.Data.Limits.MaxMonthlyUsageUsd,
+		CycleEndAt:         limits.Data.MonthlyUsageCycle.EndAt,
+	}, nil
+}
+
+// GetApifyLimits returns the monthly usage and limit of the account of the API token
+func GetApifyLimits(apiToken string, opts ...Option) (*ApifyLimits, error) {
+	options, err := NewOptions(opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create options: %w", err)
+	}
+	c := &ApifyClient{apiToken: apiToken, baseUrl: apifyBaseURL, httpOptions: options}
+	return c.GetLimits()
+}
+
+// getJSON gets a resource of the Apify API and decodes it into v
+func (c *ApifyClient) getJSON(url string, v any) error {
+	resp, err := c.httpOptions.HttpClient.Get(url)
+	if err != nil {
+		return fmt.Errorf("error making GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return fmt.Errorf("error parsing response: %w", err)
+	}
+	return nil
+}
+
 // GetActorRun gets the status of an actor run
 func (c *ApifyClient) GetActorRun(runId string) (*ActorRunResponse, error) {
 	url := fmt.Sprintf("%s/actor-runs/%s?token=%s", c.baseUrl, runId, c.apiToken)
@@ -288,11 +469,71 @@ func (c *ApifyClient) GetDatasetItems(datasetId string, offset, limit uint) (*Da
 			Limit:  limit,
 		},
 	}
+	if total, err := strconv.ParseUint(resp.Header.Get("X-Apify-Pagination-Total"), 10, 64); err == nil {
+		datasetResp.Data.Total = uint(total)
+	}
 
 	logrus.Debugf("Retrieved %d items from dataset", len(items))
 	return datasetResp, nil
 }
 
+// DatasetWriter is implemented by Apify clients which can store items in a new dataset, so actors which read from a
+// dataset can process data which was not scraped by an actor
+type DatasetWriter interface {
+	CreateDataset(items []json.RawMessage) (string, error)
+}
+
+// CreateDataset creates an unnamed dataset holding the given items, and returns its ID
+func (c *ApifyClient) CreateDataset(items []json.RawMessage) (string, error) {
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	body, err := c.post(fmt.Sprintf("%s/datasets?token=%s", c.baseUrl, c.apiToken), nil)
+	if err != nil {
+		return "", fmt.Errorf("error creating dataset: %w", err)
+	}
+	if err := json.Unmarshal(body, &created); err != nil || created.Data.ID == "" {
+		return "", fmt.Errorf("error parsing created dataset: %s", string(body))
+	}
+
+	itemsJSON, err := json.Marshal(items)
+	if err != nil {
+		return "", fmt.Errorf("error marshaling dataset items: %w", err)
+	}
+	if _, err := c.post(fmt.Sprintf("%s/datasets/%s/items?token=%s", c.baseUrl, created.Data.ID, c.apiToken), itemsJSON); err != nil {
+		return "", fmt.Errorf("error pushing dataset items: %w", err)
+	}
+
+	logrus.Debugf("Created dataset %s with %d items", created.Data.ID, len(items))
+	return created.Data.ID, nil
+}
+
+// post sends a JSON POST request, and returns the body of the response if the request succeeded
+func (c *ApifyClient) post(url string, payload []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", url, bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, fmt.Errorf("error creating POST request: %w", err)
+	}
+	req.Header.Add("Content-Type", "application/json")
+
+	resp, err := c.httpOptions.HttpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, string(body))
+	}
+	return body, nil
+}
+
 // ValidateApiKey tests if the API token is valid by making a request to /users/me
 // This endpoint doesn't consume any actor runs or quotas - it's perfect for validation
 func (c *ApifyClient) ValidateApiKey() error {
@@ -333,13 +574,25 @@ func (c *ApifyClient) ValidateApiKey() error {
 var (
 	ErrActorFailed  = errors.New("Actor run failed")
 	ErrActorAborted = errors.New("Actor run aborted")
+	// ErrActorRunTimedOut is returned when an actor run has not finished within the polls or the deadline of the
+	// client, in which case the run is aborted
+	ErrActorRunTimedOut = errcode.New(errcode.Timeout, "actor run timed out")
+	// ErrActorRunCancelled is returned when the actor run was aborted because the job was cancelled
+	ErrActorRunCancelled = errors.New("actor run cancelled")
+	// ErrActorNotAllowed is returned when running an actor which is not one of the actors used by the worker
+	ErrActorNotAllowed = errors.New("actor is not allowed")
+	// ErrActorBuildNotFound is returned when an actor is pinned to a build which doesn't exist
+	ErrActorBuildNotFound = errors.New("pinned actor build not found")
+	// ErrInvalidCursor is returned when the cursor to start from is not a cursor returned by RunActorAndGetResponse
+	ErrInvalidCursor = errcode.New(errcode.InvalidArgument, "invalid cursor")
 )
 
 // runActorAndGetProfiles runs the actor and retrieves profiles from the dataset
 func (c *ApifyClient) RunActorAndGetResponse(actorId apify.ActorId, input any, cursor Cursor, limit uint) (*DatasetResponse, Cursor, error) {
-	var offset uint
-	if cursor != EmptyCursor {
-		offset = parseCursor(cursor)
+	// An invalid cursor fails the run, instead of starting over from the first item
+	offset, err := parseCursor(cursor)
+	if err != nil {
+		return nil, "", err
 	}
 
 	// 1. Run the actor
@@ -347,37 +600,65 @@ func (c *ApifyClient) RunActorAndGetResponse(actorId apify.ActorId, input any, c
 	if err != nil {
 		return nil, "", fmt.Errorf("failed to run actor: %w", err)
 	}
+	if c.httpOptions.onActorRun != nil {
+		c.httpOptions.onActorRun(runResp.Data.ID)
+	}
+
+	// Failed and aborted runs are billed too, so the cost is reported whenever the run detail has been received
+	var lastStatus *ActorRunResponse
+	var itemsRead uint
+	if c.httpOptions.onActorRunCost != nil {
+		defer func() {
+			if lastStatus != nil {
+				c.httpOptions.onActorRunCost(actorRunCost(actorId, lastStatus, itemsRead))
+			}
+		}()
+	}
 
 	// 2. Poll for completion
 	logrus.Infof("Polling for actor run completion: %s", runResp.Data.ID)
-	pollCount := 0
+	maxPolls, pollInterval := MaxActorPolls, ActorPollInterval
+	if c.httpOptions.actorMaxPolls > 0 {
+		maxPolls = c.httpOptions.actorMaxPolls
+	}
+	if c.httpOptions.actorPollInterval > 0 {
+		pollInterval = c.httpOptions.actorPollInterval
+	}
+	ctx, cancel := c.actorRunContext()
+	defer cancel()
 
-PollLoop:
-	for {
+	err = retry.Poll(ctx, retry.Policy{MaxAttempts: maxPolls, Initial: pollInterval}, func() (bool, error) {
 		status, err := c.GetActorRun(runResp.Data.ID)
 		if err != nil {
-			return nil, "", fmt.Errorf("failed to get actor run status: %w", err)
+			return false, fmt.Errorf("failed to get actor run status: %w", err)
 		}
+		lastStatus = status
 
 		logrus.Debugf("Actor run status: %s", status.Data.Status)
 
 		switch status.Data.Status {
 		case ActorStatusSucceeded:
 			logrus.Debug("Actor run completed successfully")
-			break PollLoop
+			return true, nil
 		case ActorStatusFailed:
-			return nil, "", ErrActorFailed
+			return false, ErrActorFailed
 		case ActorStatusAborted:
-			return nil, "", ErrActorAborted
+			return false, ErrActorAborted
 		}
-
-		// TODO: Parametrize these two
-		pollCount++
-		if pollCount >= MaxActorPolls {
-			return nil, "", fmt.Errorf("actor run timed out after %d polls", MaxActorPolls)
-		}
-
-		time.Sleep(ActorPollInterval)
+		return false, nil
+	})
+	switch {
+	case errors.Is(err, retry.ErrExhausted):
+		c.abortUnfinishedRun(runResp.Data.ID)
+		return nil, "", fmt.Errorf("%w after %d polls", ErrActorRunTimedOut, maxPolls)
+	case errors.Is(err, context.DeadlineExceeded):
+		c.abortUnfinishedRun(runResp.Data.ID)
+		return nil, "", fmt.Errorf("%w: the job timed out", ErrActorRunTimedOut)
+	case errors.Is(err, ErrActorRunCancelled):
+		c.abortUnfinishedRun(runResp.Data.ID)
+		return nil, "", ErrActorRunCancelled
+	case err != nil:
+		return nil, "", err
 	}
 
 	// 3. Get dataset items with pagination
@@ -387,6 +668,8 @@ PollLoop:
 		return nil, "", fmt.Errorf("failed to get dataset items: %w", err)
 	}
 
+	itemsRead = uint(len(dataset.Data.Items))
+
 	// Propagate dataset id for downstream consumers
 	dataset.DatasetId = runResp.Data.DefaultDatasetId
 
@@ -394,7 +677,7 @@ PollLoop:
 	var nextCursor Cursor
 	if uint(len(dataset.Data.Items)) == limit {
 		nextOffset := offset + uint(len(dataset.Data.Items))
-		nextCursor = generateCursor(nextOffset)
+		nextCursor = generateCursor(nextOffset, dataset.Data.Total)
 		logrus.Debugf("Generated next cursor for offset %d", nextOffset)
 	}
 
@@ -406,30 +689,78 @@ PollLoop:
 	return dataset, nextCursor, nil
 }
 
+// actorRunContext returns the context of waiting for an actor run, which is done at the deadline of the client, or
+// with ErrActorRunCancelled as its cause when the job is cancelled
+func (c *ApifyClient) actorRunContext() (context.Context, context.CancelFunc) {
+	ctx, cancelCause := context.WithCancelCause(context.Background())
+	cancel := func() { cancelCause(nil) }
+	if deadline := c.httpOptions.actorRunDeadline; !deadline.IsZero() {
+		var cancelDeadline context.CancelFunc
+		ctx, cancelDeadline = context.WithDeadline(ctx, deadline)
+		cancel = func() {
+			cancelDeadline()
+			cancelCause(nil)
+		}
+	}
+	if cancelled := c.httpOptions.actorRunCancel; cancelled != nil {
+		go func() {
+			select {
+			case <-cancelled:
+				cancelCause(ErrActorRunCancelled)
+			case <-ctx.Done():
+			}
+		}()
+	}
+	return ctx, cancel
+}
+
+// abortUnfinishedRun aborts a run the client has stopped waiting for, so it doesn't keep consuming compute units.
+// The run is only aborted on a best-effort basis, since the job has failed either way.
+func (c *ApifyClient) abortUnfinishedRun(runId string) {
+	if err := c.AbortActorRun(runId); err != nil {
+		logrus.Warnf("Failed to abort actor run %s: %v", runId, err)
+	}
+}
+
+// actorRunCost returns the cost of a finished actor run from its run detail
+func actorRunCost(actorId apify.ActorId, run *ActorRunResponse, itemsRead uint) ActorRunCost {
+	return ActorRunCost{
+		RunID:        run.Data.ID,
+		ActorID:      actorId,
+		ComputeUnits: run.Data.Stats.ComputeUnits,
+		DatasetReads: uint(run.Data.Usage["DATASET_READS"]) + itemsRead,
+		UsageUSD:     run.Data.UsageTotalUsd,
+	}
+}
+
 // parseCursor decodes a base64 cursor to get the offset
-func parseCursor(cursor Cursor) uint {
-	if cursor == "" {
-		return 0
+func parseCursor(cursor Cursor) (uint, error) {
+	cursorData, err := decodeCursor(cursor)
+	return cursorData.Offset, err
+}
+
+// decodeCursor decodes a base64 cursor. The empty cursor is the start of the dataset.
+func decodeCursor(cursor Cursor) (CursorData, error) {
+	var cursorData CursorData
+	if cursor == EmptyCursor {
+		return cursorData, nil
 	}
 
 	decoded, err := base64.StdEncoding.DecodeString(cursor.String())
 	if err != nil {
-		logrus.Warnf("Failed to decode cursor: %v", err)
-		return 0
+		return CursorData{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
 	}
 
-	var cursorData CursorData
 	if err := json.Unmarshal(decoded, &cursorData); err != nil {
-		logrus.Warnf("Failed to unmarshal cursor data: %v", err)
-		return 0
+		return CursorData{}, fmt.Errorf("%w: %v", ErrInvalidCursor, err)
 	}
 
-	return cursorData.Offset
+	return cursorData, nil
 }
 
-// generateCursor encodes an offset as a base64 cursor
-func generateCursor(offset uint) Cursor {
-	cursorData := CursorData{Offset: offset}
+// generateCursor encodes an offset, and the number of items of the dataset if it is known, as a base64 cursor
+func generateCursor(offset, total uint) Cursor {
+	cursorData := CursorData{Offset: offset, Total: total}
 	data, err := json.Marshal(cursorData)
 	if err != nil {
 		logrus.Warnf("Failed to marshal cursor data: %v", err)
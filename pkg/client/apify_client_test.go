@@ -0,0 +1,272 @@
+package client_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/internal/apify"
+	. "github.com/masa-finance/tee-worker/pkg/client"
+)
+
+// apifyTransport serves canned responses of the Apify API, keyed by request path
+type apifyTransport map[string]string
+
+func (t apifyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	rec := httptest.NewRecorder()
+	body, ok := t[req.URL.Path]
+	switch {
+	case !ok:
+		rec.WriteHeader(http.StatusNotFound)
+	case req.Method == http.MethodPost:
+		rec.WriteHeader(http.StatusCreated)
+	}
+	_, _ = io.WriteString(rec, body)
+	return rec.Result(), nil
+}
+
+var _ = Describe("ApifyClient", func() {
+	run := func(status string) []ActorRunCost {
+		transport := apifyTransport{
+			"/v2/acts/apify~website-content-crawler/runs": `{"data":{"id":"run","status":"READY","defaultDatasetId":"dataset"}}`,
+			"/v2/actor-runs/run":                          `{"data":{"id":"run","status":"` + status + `","defaultDatasetId":"dataset","stats":{"computeUnits":0.05},"usage":{"DATASET_READS":3,"DATASET_WRITES":2},"usageTotalUsd":0.02}}`,
+			"/v2/datasets/dataset/items":                  `[{"id":1},{"id":2}]`,
+		}
+
+		var costs []ActorRunCost
+		c, err := NewApifyClient("token",
+			HttpClient(&http.Client{Transport: transport}),
+			OnActorRunCost(func(cost ActorRunCost) { costs = append(costs, cost) }),
+		)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, _ = c.RunActorAndGetResponse(apify.ActorIds.WebScraper, map[string]any{}, EmptyCursor, 10)
+		return costs
+	}
+
+	It("reports the cost of finished actor runs", func() {
+		Expect(run(ActorStatusSucceeded)).To(Equal([]ActorRunCost{
+			{RunID: "run", ActorID: apify.ActorIds.WebScraper, ComputeUnits: 0.05, DatasetReads: 5, UsageUSD: 0.02},
+		}))
+	})
+
+	It("reports the cost of failed actor runs", func() {
+		Expect(run(ActorStatusFailed)).To(Equal([]ActorRunCost{
+			{RunID: "run", ActorID: apify.ActorIds.WebScraper, ComputeUnits: 0.05, DatasetReads: 3, UsageUSD: 0.02},
+		}))
+	})
+
+	It("keeps the number of items of the dataset in the next cursor", func() {
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			rec := httptest.NewRecorder()
+			switch req.URL.Path {
+			case "/v2/acts/apify~website-content-crawler/runs":
+				rec.WriteHeader(http.StatusCreated)
+				_, _ = io.WriteString(rec, `{"data":{"id":"run","status":"READY","defaultDatasetId":"dataset"}}`)
+			case "/v2/actor-runs/run":
+				_, _ = io.WriteString(rec, `{"data":{"id":"run","status":"SUCCEEDED","defaultDatasetId":"dataset"}}`)
+			default:
+				rec.Header().Set("X-Apify-Pagination-Total", "5")
+				_, _ = io.WriteString(rec, `[{"id":1},{"id":2}]`)
+			}
+			return rec.Result(), nil
+		})
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: transport}))
+		Expect(err).NotTo(HaveOccurred())
+
+		resp, cursor, err := c.RunActorAndGetResponse(apify.ActorIds.WebScraper, map[string]any{}, EmptyCursor, 2)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Data.Total).To(Equal(uint(5)))
+		Expect(cursor.Total()).To(Equal(uint(5)))
+		Expect(EmptyCursor.Total()).To(BeZero())
+	})
+
+	It("fails without running the actor if the cursor is invalid", func() {
+		var requests int
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests++
+			return httptest.NewRecorder().Result(), nil
+		})
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: transport}))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, _, err = c.RunActorAndGetResponse(apify.ActorIds.WebScraper, map[string]any{}, Cursor("not a cursor"), 2)
+		Expect(err).To(MatchError(ErrInvalidCursor))
+		Expect(requests).To(BeZero())
+	})
+})
+
+var _ = Describe("Actor run abortion", func() {
+	// runUntilAborted runs an actor which never finishes, and returns the requests made and the error
+	runUntilAborted := func(opts ...Option) ([]string, error) {
+		var requests []string
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			requests = append(requests, req.Method+" "+req.URL.Path)
+			rec := httptest.NewRecorder()
+			if req.Method == http.MethodPost && req.URL.Path == "/v2/acts/apify~website-content-crawler/runs" {
+				rec.WriteHeader(http.StatusCreated)
+			}
+			_, _ = io.WriteString(rec, `{"data":{"id":"run","status":"RUNNING","defaultDatasetId":"dataset"}}`)
+			return rec.Result(), nil
+		})
+		c, err := NewApifyClient("token", append([]Option{HttpClient(&http.Client{Transport: transport})}, opts...)...)
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = c.RunActorAndGetResponse(apify.ActorIds.WebScraper, map[string]any{}, EmptyCursor, 10)
+		return requests, err
+	}
+
+	It("aborts runs which are still running after the last poll", func() {
+		requests, err := runUntilAborted(ActorPolling(3, time.Millisecond))
+		Expect(err).To(MatchError(ErrActorRunTimedOut))
+		Expect(requests).To(Equal([]string{
+			"POST /v2/acts/apify~website-content-crawler/runs",
+			"GET /v2/actor-runs/run",
+			"GET /v2/actor-runs/run",
+			"GET /v2/actor-runs/run",
+			"POST /v2/actor-runs/run/abort",
+		}))
+	})
+
+	It("aborts runs which are still running at the deadline", func() {
+		start := time.Now()
+		requests, err := runUntilAborted(ActorPolling(1000, 10*time.Millisecond), ActorRunDeadline(time.Now().Add(50*time.Millisecond)))
+		Expect(err).To(MatchError(ErrActorRunTimedOut))
+		Expect(time.Since(start)).To(BeNumerically("<", time.Second))
+		Expect(len(requests)).To(BeNumerically(">", 2))
+		Expect(requests[len(requests)-1]).To(Equal("POST /v2/actor-runs/run/abort"))
+	})
+
+	It("aborts runs of cancelled jobs", func() {
+		cancel := make(chan struct{})
+		time.AfterFunc(20*time.Millisecond, func() { close(cancel) })
+		requests, err := runUntilAborted(ActorPolling(1000, 5*time.Millisecond), ActorRunCancel(cancel))
+		Expect(err).To(MatchError(ErrActorRunCancelled))
+		Expect(requests[len(requests)-1]).To(Equal("POST /v2/actor-runs/run/abort"))
+	})
+
+	It("passes the deadline to Apify as the run timeout", func() {
+		var timeout string
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			timeout = req.URL.Query().Get("timeout")
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(rec, `{"data":{"id":"run"}}`)
+			return rec.Result(), nil
+		})
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: transport}), ActorRunDeadline(time.Now().Add(90*time.Second)))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.(*ApifyClient).RunActor(apify.ActorIds.WebScraper, map[string]any{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(timeout).To(Equal("90"))
+	})
+})
+
+var _ = Describe("Actor builds", func() {
+	BeforeEach(func() {
+		builds := apify.ActorBuilds
+		apify.ActorBuilds = map[apify.ActorId]string{apify.ActorIds.WebScraper: "0.3.67"}
+		DeferCleanup(func() { apify.ActorBuilds = builds })
+	})
+
+	It("refuses to run actors which are not used by the worker", func() {
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: apifyTransport{}}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.ProbeActorAccess(apify.ActorId("someone~else"), nil)
+		Expect(err).To(MatchError(ErrActorNotAllowed))
+	})
+
+	It("runs the build the actor is pinned to", func() {
+		var query string
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			query = req.URL.Query().Get("build")
+			rec := httptest.NewRecorder()
+			rec.WriteHeader(http.StatusCreated)
+			_, _ = io.WriteString(rec, `{"data":{"id":"run"}}`)
+			return rec.Result(), nil
+		})
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: transport}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.(*ApifyClient).RunActor(apify.ActorIds.WebScraper, map[string]any{})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(query).To(Equal("0.3.67"))
+	})
+
+	It("validates that the pinned builds exist", func() {
+		transport := apifyTransport{
+			"/v2/acts/apify~website-content-crawler":        `{"data":{"taggedBuilds":{"latest":{"buildNumber":"0.3.70"}}}}`,
+			"/v2/acts/apify~website-content-crawler/builds": `{"data":{"items":[{"buildNumber":"0.3.70","status":"SUCCEEDED"},{"buildNumber":"0.3.67","status":"SUCCEEDED"}]}}`,
+		}
+		Expect(ValidateActorBuilds("token", HttpClient(&http.Client{Transport: transport}))).To(Succeed())
+
+		apify.ActorBuilds[apify.ActorIds.WebScraper] = "latest"
+		Expect(ValidateActorBuilds("token", HttpClient(&http.Client{Transport: transport}))).To(Succeed())
+
+		apify.ActorBuilds[apify.ActorIds.WebScraper] = "0.3.1"
+		Expect(ValidateActorBuilds("token", HttpClient(&http.Client{Transport: transport}))).To(MatchError(ErrActorBuildNotFound))
+
+		err := ValidateActorBuilds("token", HttpClient(&http.Client{Transport: apifyTransport{}}))
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(ErrActorBuildNotFound))
+	})
+})
+
+var _ = Describe("GetApifyLimits", func() {
+	It("reports whether the monthly usage is exhausted", func() {
+		limits, err := GetApifyLimits("token", HttpClient(&http.Client{Transport: apifyTransport{
+			"/v2/users/me/limits": `{"data":{"monthlyUsageCycle":{"startAt":"2024-01-01T00:00:00Z","endAt":"2024-02-01T00:00:00Z"},"limits":{"maxMonthlyUsageUsd":49},"current":{"monthlyUsageUsd":49.5}}}`,
+		}}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(limits.MonthlyUsageUsd).To(Equal(49.5))
+		Expect(limits.CycleEndAt).To(Equal(time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(limits.Exhausted()).To(BeTrue())
+
+		limits.MonthlyUsageUsd = 10
+		Expect(limits.Exhausted()).To(BeFalse())
+	})
+})
+
+var _ = Describe("CreateDataset", func() {
+	It("creates a dataset holding the items", func() {
+		var pushed string
+		transport := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			rec := httptest.NewRecorder()
+			switch req.URL.Path {
+			case "/v2/datasets":
+				rec.WriteHeader(http.StatusCreated)
+				_, _ = io.WriteString(rec, `{"data":{"id":"dataset"}}`)
+			case "/v2/datasets/dataset/items":
+				body, _ := io.ReadAll(req.Body)
+				pushed = string(body)
+				rec.WriteHeader(http.StatusCreated)
+			default:
+				rec.WriteHeader(http.StatusNotFound)
+			}
+			return rec.Result(), nil
+		})
+
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: transport}))
+		Expect(err).NotTo(HaveOccurred())
+		id, err := c.(DatasetWriter).CreateDataset([]json.RawMessage{json.RawMessage(`{"id":1}`), json.RawMessage(`{"id":2}`)})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal("dataset"))
+		Expect(pushed).To(Equal(`[{"id":1},{"id":2}]`))
+	})
+
+	It("fails if the dataset cannot be created", func() {
+		c, err := NewApifyClient("token", HttpClient(&http.Client{Transport: apifyTransport{}}))
+		Expect(err).NotTo(HaveOccurred())
+		_, err = c.(DatasetWriter).CreateDataset([]json.RawMessage{json.RawMessage(`{"id":1}`)})
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+// roundTripFunc adapts a function to an http.RoundTripper
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
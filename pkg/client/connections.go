@@ -0,0 +1,58 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptrace"
+	"sync/atomic"
+)
+
+// ConnectionStats counts the connections used by the requests of the clients. A high ratio of new to reused
+// connections means the connection pool is too small for the load, see MaxIdleConnsPerHost.
+type ConnectionStats struct {
+	// New is the number of requests which opened a new connection
+	New uint64 `json:"new"`
+	// Reused is the number of requests which reused a connection of the pool
+	Reused uint64 `json:"reused"`
+}
+
+type connectionCounter struct {
+	new    atomic.Uint64
+	reused atomic.Uint64
+}
+
+func (c *connectionCounter) stats() ConnectionStats {
+	return ConnectionStats{New: c.new.Load(), Reused: c.reused.Load()}
+}
+
+// allConnections counts the connections of all the clients of the process
+var allConnections connectionCounter
+
+// GetConnectionStats returns how many connections the requests of all the clients of the process opened and reused
+func GetConnectionStats() ConnectionStats {
+	return allConnections.stats()
+}
+
+// connectionTracker counts whether each request opened a new connection or reused one
+type connectionTracker struct {
+	next    http.RoundTripper
+	counter *connectionCounter
+}
+
+func (t *connectionTracker) RoundTrip(req *http.Request) (*http.Response, error) {
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			if info.Reused {
+				t.counter.reused.Add(1)
+				allConnections.reused.Add(1)
+			} else {
+				t.counter.new.Add(1)
+				allConnections.new.Add(1)
+			}
+		},
+	}
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return next.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 	"time"
 
 	"github.com/masa-finance/tee-worker/api/types"
@@ -73,6 +75,42 @@ func (c *Client) CreateJobSignature(job types.Job) (JobSignature, error) {
 	return JobSignature(string(body)), nil
 }
 
+// EstimateJob asks the server what a job is expected to cost, and whether it can serve it now, without executing it
+func (c *Client) EstimateJob(job types.Job) (*types.JobEstimate, error) {
+	jobJSON, err := json.Marshal(job)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling job: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/job/estimate", bytes.NewBuffer(jobJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var estimate types.JobEstimate
+	if err := json.Unmarshal(body, &estimate); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &estimate, nil
+}
+
 // SubmitJob submits a new job to the server and returns the job result.
 func (c *Client) SubmitJob(JobSignature JobSignature) (*JobResult, error) {
 	jr := types.JobRequest{EncryptedJob: string(JobSignature)}
@@ -109,15 +147,100 @@ func (c *Client) SubmitJob(JobSignature JobSignature) (*JobResult, error) {
 		return nil, fmt.Errorf("error unmarshaling response: %w", err)
 	}
 
-	return &JobResult{UUID: jobResp.UID, client: c, maxRetries: 60, delay: 1 * time.Second}, nil
+	return &JobResult{UUID: jobResp.UID, Cached: jobResp.Cached, client: c, maxRetries: 60, delay: 1 * time.Second}, nil
+}
+
+// SubmitBatch submits several jobs in a single request. The response has the UUID or the error of each job, in
+// the order of the signatures, and the ID of the batch to pass to GetBatchStatus.
+func (c *Client) SubmitBatch(signatures []JobSignature) (*types.BatchResponse, error) {
+	batch := types.BatchRequest{Jobs: make([]types.JobRequest, len(signatures))}
+	for i, sig := range signatures {
+		batch.Jobs[i] = types.JobRequest{EncryptedJob: string(sig)}
+	}
+
+	batchJSON, err := json.Marshal(batch)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling batch: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/jobs/batch", bytes.NewBuffer(batchJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var batchResp types.BatchResponse
+	if err := json.Unmarshal(body, &batchResp); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &batchResp, nil
+}
+
+// GetBatchStatus fetches how many jobs of a batch are pending, have succeeded, have failed or have expired. The
+// results of the jobs are retrieved with GetResult.
+func (c *Client) GetBatchStatus(batchID string) (*types.BatchStatus, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/jobs/batch/"+batchID, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("batch not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var status types.BatchStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &status, nil
 }
 
 // Decrypt sends the encrypted result to the server to decrypt it.
 func (c *Client) Decrypt(JobSignature JobSignature, encryptedResult string) (string, error) {
+	return c.DecryptAs(JobSignature, encryptedResult, types.ResultFormatJSON)
+}
+
+// DecryptAs sends the encrypted result to the server to decrypt it, and returns it in the given format, e.g. NDJSON or
+// CSV for ingestion into data pipelines.
+func (c *Client) DecryptAs(JobSignature JobSignature, encryptedResult string, format types.ResultFormat) (string, error) {
 	decryptReq := types.EncryptedRequest{
 		EncryptedResult:  encryptedResult,
 		EncryptedRequest: string(JobSignature),
 	}
+	if format != types.ResultFormatJSON {
+		decryptReq.ResultFormat = format
+	}
 
 	decryptReqJSON, err := json.Marshal(decryptReq)
 	if err != nil {
@@ -150,7 +273,18 @@ func (c *Client) Decrypt(JobSignature JobSignature, encryptedResult string) (str
 
 // GetJobResult retrieves the encrypted result of a job.
 func (c *Client) GetResult(jobUUID string) (string, bool, error) {
-	req, err := http.NewRequest("GET", c.BaseURL+"/job/status/"+jobUUID, nil)
+	return c.WaitForResult(jobUUID, 0)
+}
+
+// WaitForResult retrieves the encrypted result of a job like GetResult, but if the job is still pending the server
+// waits up to the given time for it to finish before responding. The server caps the wait (30 seconds by default),
+// and the timeout of the HTTP client must be longer than the wait.
+func (c *Client) WaitForResult(jobUUID string, wait time.Duration) (string, bool, error) {
+	u := c.BaseURL + "/job/status/" + jobUUID
+	if wait > 0 {
+		u += "?wait=" + url.QueryEscape(wait.String())
+	}
+	req, err := http.NewRequest("GET", u, nil)
 	if err != nil {
 		return "", false, fmt.Errorf("error creating request: %w", err)
 	}
@@ -180,3 +314,414 @@ func (c *Client) GetResult(jobUUID string) (string, bool, error) {
 
 	return string(body), true, err
 }
+
+// GetCapabilities fetches the capabilities of the worker, including the auth source, estimated
+// rate limit and full-archive availability of each capability.
+func (c *Client) GetCapabilities() (*types.CapabilitiesResponse, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/capabilities", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var caps types.CapabilitiesResponse
+	if err := json.Unmarshal(body, &caps); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &caps, nil
+}
+
+// GetStatus fetches the diagnostics of the secrets the worker validated at startup. Healthy is false if any of
+// them is invalid or out of quota.
+func (c *Client) GetStatus() (*types.StatusResponse, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/status", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var status types.StatusResponse
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &status, nil
+}
+
+// GetConfig fetches the effective configuration of the worker, with its credentials redacted, and the job types it
+// can run.
+func (c *Client) GetConfig() (*types.ConfigResponse, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/config", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var cfg types.ConfigResponse
+	if err := json.Unmarshal(body, &cfg); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &cfg, nil
+}
+
+// GetAttestation fetches a fresh attestation of the worker bound to the given nonce. The caller should verify the
+// quote, and check that its report data matches types.AttestationReportData of the worker ID, the capabilities hash
+// and the nonce.
+func (c *Client) GetAttestation(nonce string) (*types.AttestationResponse, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/attestation?nonce="+url.QueryEscape(nonce), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var attestation types.AttestationResponse
+	if err := json.Unmarshal(body, &attestation); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &attestation, nil
+}
+
+// GetAuditLog fetches the entries of the audit log of the worker from from to to, both included, 0 being the head of
+// the log for to. If nonce is not empty, the caller should verify the quote, and check that its report data matches
+// types.AuditReportData of the worker ID, the last entry of the range and the nonce.
+func (c *Client) GetAuditLog(from, to uint64, nonce string) (*types.AuditLog, error) {
+	query := url.Values{}
+	query.Set("from", strconv.FormatUint(from, 10))
+	query.Set("to", strconv.FormatUint(to, 10))
+	if nonce != "" {
+		query.Set("nonce", nonce)
+	}
+	req, err := http.NewRequest("GET", c.BaseURL+"/audit?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var log types.AuditLog
+	if err := json.Unmarshal(body, &log); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &log, nil
+}
+
+// GetJobTrace fetches the execution trace of a job submitted with the debug argument. While the job is running, it
+// returns the events recorded so far.
+func (c *Client) GetJobTrace(jobUUID string) (*types.JobTrace, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/job/"+jobUUID+"/trace", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var trace types.JobTrace
+	if err := json.Unmarshal(body, &trace); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &trace, nil
+}
+
+// GetJobStream fetches the batches of results emitted by a job after the batch with the given sequence number, e.g. the
+// new posts of a Reddit monitor job. If there are none yet, it waits up to the given time for the next one. The data of
+// the batches is sealed like the result of the job, and can be decrypted with Decrypt. Poll again with the sequence
+// number of the last batch until the response is done.
+func (c *Client) GetJobStream(jobUUID string, after int, wait time.Duration) (*types.StreamResponse, error) {
+	u := c.BaseURL + "/job/" + jobUUID + "/stream?after=" + strconv.Itoa(after)
+	if wait > 0 {
+		u += "&wait=" + url.QueryEscape(wait.String())
+	}
+	req, err := http.NewRequest("GET", u, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var stream types.StreamResponse
+	if err := json.Unmarshal(body, &stream); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &stream, nil
+}
+
+// HoldResult retains the result of a job until it is released with ReleaseResult. The tag is
+// types.HoldTagRetained or types.HoldTagLegalHold.
+func (c *Client) HoldResult(jobUUID string, tag types.HoldTag) (*types.ResultHold, error) {
+	req, err := http.NewRequest("PUT", c.BaseURL+"/job/hold/"+jobUUID+"?tag="+url.QueryEscape(string(tag)), nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending PUT request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, fmt.Errorf("job result not found")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var hold types.ResultHold
+	if err := json.Unmarshal(body, &hold); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+	return &hold, nil
+}
+
+// ReleaseResult removes the hold of a job result, which then expires like any other.
+func (c *Client) ReleaseResult(jobUUID string) error {
+	req, err := http.NewRequest("DELETE", c.BaseURL+"/job/hold/"+jobUUID, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending DELETE request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("job result not held")
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// Unschedule stops re-executing a recurring job, i.e. a job submitted with a schedule.
+func (c *Client) Unschedule(jobUUID string) error {
+	req, err := http.NewRequest("DELETE", c.BaseURL+"/job/schedule/"+jobUUID, nil)
+	if err != nil {
+		return fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending DELETE request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("recurring job not found")
+	}
+	if resp.StatusCode != http.StatusNoContent {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// ExportTwitterSessions fetches the sealed sessions of the Twitter accounts of the worker. They can only be imported,
+// with ImportTwitterSessions, by a worker with the same sealing key.
+func (c *Client) ExportTwitterSessions() (*types.TwitterSessions, error) {
+	req, err := http.NewRequest("GET", c.BaseURL+"/twitter/sessions", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending GET request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var sessions types.TwitterSessions
+	if err := json.Unmarshal(body, &sessions); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &sessions, nil
+}
+
+// CheckTwitterAccounts verifies the session of every Twitter account of the worker, refreshing its cookies, and
+// returns the status of each. It fails if the client uses the API key of a miner.
+func (c *Client) CheckTwitterAccounts() (*types.TwitterAuthCheck, error) {
+	req, err := http.NewRequest("POST", c.BaseURL+"/admin/twitter/auth-check", nil)
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var check types.TwitterAuthCheck
+	if err := json.Unmarshal(body, &check); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &check, nil
+}
+
+// ImportTwitterSessions replaces the sessions of the Twitter accounts of the worker with sessions exported by another
+// worker. Sessions of accounts which the worker is not configured with are skipped.
+func (c *Client) ImportTwitterSessions(sessions types.TwitterSessions) (*types.TwitterSessionsImport, error) {
+	sessionsJSON, err := json.Marshal(sessions)
+	if err != nil {
+		return nil, fmt.Errorf("error marshaling sessions: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", c.BaseURL+"/twitter/sessions", bytes.NewBuffer(sessionsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("error creating request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	c.setAPIKeyHeader(req)
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error sending POST request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error reading response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("error: received status code %d, body: %s", resp.StatusCode, string(body))
+	}
+
+	var imported types.TwitterSessionsImport
+	if err := json.Unmarshal(body, &imported); err != nil {
+		return nil, fmt.Errorf("error unmarshaling response: %w", err)
+	}
+
+	return &imported, nil
+}
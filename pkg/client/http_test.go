@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/masa-finance/tee-worker/api/types"
 	. "github.com/masa-finance/tee-worker/pkg/client"
@@ -15,6 +16,8 @@ var _ = Describe("Client", func() {
 	var (
 		mockServer *httptest.Server
 		client     *Client
+		lastWait   string
+		lastFormat types.ResultFormat
 	)
 
 	BeforeEach(func() {
@@ -34,11 +37,15 @@ var _ = Describe("Client", func() {
 				}
 			case "/job/result":
 				if r.Method == http.MethodPost {
+					var req types.EncryptedRequest
+					_ = json.NewDecoder(r.Body).Decode(&req)
+					lastFormat = req.ResultFormat
 					w.WriteHeader(http.StatusOK)
 					w.Write([]byte(`decrypted-result`))
 				}
 			case "/job/status/mock-job-id":
 				if r.Method == http.MethodGet {
+					lastWait = r.URL.Query().Get("wait")
 					w.WriteHeader(http.StatusOK)
 					w.Write([]byte(`encrypted-result`))
 				}
@@ -80,6 +87,13 @@ var _ = Describe("Client", func() {
 			decryptedResult, err := client.Decrypt(signature, "mock-encrypted-result")
 			Expect(err).NotTo(HaveOccurred())
 			Expect(decryptedResult).To(Equal("decrypted-result"))
+			Expect(lastFormat).To(BeEmpty())
+		})
+
+		It("should request the result in the given format", func() {
+			_, err := client.DecryptAs(JobSignature("mock-signature"), "mock-encrypted-result", types.ResultFormatNDJSON)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(lastFormat).To(Equal(types.ResultFormatNDJSON))
 		})
 	})
 
@@ -89,6 +103,15 @@ var _ = Describe("Client", func() {
 			Expect(err).NotTo(HaveOccurred())
 			Expect(found).To(BeTrue())
 			Expect(result).To(Equal("encrypted-result"))
+			Expect(lastWait).To(BeEmpty())
+		})
+
+		It("should ask the server to wait for the job", func() {
+			result, found, err := client.WaitForResult("mock-job-id", 30*time.Second)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(found).To(BeTrue())
+			Expect(result).To(Equal("encrypted-result"))
+			Expect(lastWait).To(Equal("30s"))
 		})
 	})
 })
@@ -2,6 +2,7 @@ package client
 
 import (
 	"net/http"
+	"sync"
 	"time"
 )
 
@@ -13,7 +14,19 @@ type Options struct {
 	MaxIdleConnsPerHost int
 	MaxIdleConns        int
 	IdleConnTimeout     time.Duration
+	DialTimeout         time.Duration
+	TLSHandshakeTimeout time.Duration
+	TLSSessionCacheSize int
+	DisableHTTP2        bool
 	HttpClient          *http.Client
+	wrapTransport       func(http.RoundTripper) http.RoundTripper
+	onActorRun          func(runID string)
+	onActorRunCost      func(cost ActorRunCost)
+	actorMaxPolls       int
+	actorPollInterval   time.Duration
+	actorRunDeadline    time.Time
+	actorRunCancel      <-chan struct{}
+	connections         *connectionCounter
 }
 
 type Option func(*Options) error
@@ -72,7 +85,42 @@ func IdleConnTimeout(timeout time.Duration) Option {
 	}
 }
 
-// HttpClient specifies the http.Client to use. If provided the rest of the connection options are ignored.
+// DialTimeout sets the maximum time to wait for a TCP connection to be established. The default is 30 seconds.
+func DialTimeout(timeout time.Duration) Option {
+	return func(o *Options) error {
+		o.DialTimeout = timeout
+		return nil
+	}
+}
+
+// TLSHandshakeTimeout sets the maximum time to wait for a TLS handshake. The default is 10 seconds.
+func TLSHandshakeTimeout(timeout time.Duration) Option {
+	return func(o *Options) error {
+		o.TLSHandshakeTimeout = timeout
+		return nil
+	}
+}
+
+// TLSSessionCache keeps up to the given number of TLS sessions, so that new connections to the same hosts can resume
+// them instead of doing a full handshake. The default is 0, which disables session resumption.
+func TLSSessionCache(size uint) Option {
+	return func(o *Options) error {
+		o.TLSSessionCacheSize = int(size)
+		return nil
+	}
+}
+
+// HTTP2 enables or disables HTTP/2 for servers which support it. It is enabled by default; with HTTP/2 disabled every
+// concurrent request to a host needs its own connection.
+func HTTP2(enabled bool) Option {
+	return func(o *Options) error {
+		o.DisableHTTP2 = !enabled
+		return nil
+	}
+}
+
+// HttpClient specifies the http.Client to use. If provided the rest of the connection options are ignored, but its
+// connections are still counted.
 func HttpClient(c *http.Client) Option {
 	return func(o *Options) error {
 		o.HttpClient = c
@@ -80,6 +128,71 @@ func HttpClient(c *http.Client) Option {
 	}
 }
 
+// WrapTransport wraps the transport of the http.Client, e.g. to instrument it. It also applies to a client set with HttpClient.
+func WrapTransport(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(o *Options) error {
+		o.wrapTransport = wrap
+		return nil
+	}
+}
+
+// OnActorRun sets a function which is called with the ID of every Apify actor run started by the client
+func OnActorRun(f func(runID string)) Option {
+	return func(o *Options) error {
+		o.onActorRun = f
+		return nil
+	}
+}
+
+// OnActorRunCost sets a function which is called with the cost of every Apify actor run started by the client, once
+// the run has finished
+func OnActorRunCost(f func(cost ActorRunCost)) Option {
+	return func(o *Options) error {
+		o.onActorRunCost = f
+		return nil
+	}
+}
+
+// ActorPolling sets how many times, and how often, the status of an Apify actor run is polled before the run is
+// aborted. A value of 0 keeps the default, MaxActorPolls and ActorPollInterval respectively.
+func ActorPolling(maxPolls uint, interval time.Duration) Option {
+	return func(o *Options) error {
+		o.actorMaxPolls = int(maxPolls)
+		o.actorPollInterval = interval
+		return nil
+	}
+}
+
+// ActorRunDeadline sets when the Apify actor runs started by the client must have finished. Apify is asked to stop
+// the runs by then, and runs which are still running at the deadline are aborted.
+func ActorRunDeadline(deadline time.Time) Option {
+	return func(o *Options) error {
+		o.actorRunDeadline = deadline
+		return nil
+	}
+}
+
+// ActorRunCancel aborts the Apify actor runs started by the client once cancel is closed
+func ActorRunCancel(cancel <-chan struct{}) Option {
+	return func(o *Options) error {
+		o.actorRunCancel = cancel
+		return nil
+	}
+}
+
+var (
+	defaultOptionsLock sync.RWMutex
+	defaultOptions     []Option
+)
+
+// SetDefaults sets options which are applied to all the clients created afterwards, before their own options. It is
+// meant to tune the connection pool of all the clients of a process at once.
+func SetDefaults(opts ...Option) {
+	defaultOptionsLock.Lock()
+	defer defaultOptionsLock.Unlock()
+	defaultOptions = opts
+}
+
 func NewOptions(opts ...Option) (*Options, error) {
 	o := &Options{
 		Timeout:             1 * time.Minute,
@@ -87,7 +200,14 @@ func NewOptions(opts ...Option) (*Options, error) {
 		MaxIdleConns:        100,
 		MaxIdleConnsPerHost: 10,
 		IdleConnTimeout:     2 * time.Minute,
+		DialTimeout:         30 * time.Second,
+		TLSHandshakeTimeout: 10 * time.Second,
+		connections:         &connectionCounter{},
 	}
+
+	defaultOptionsLock.RLock()
+	opts = append(append([]Option{}, defaultOptions...), opts...)
+	defaultOptionsLock.RUnlock()
 	for _, opt := range opts {
 		if err := opt(o); err != nil {
 			return nil, err
@@ -99,15 +219,34 @@ func NewOptions(opts ...Option) (*Options, error) {
 			Timeout: o.Timeout,
 		}
 
-		t := http.DefaultTransport.(*http.Transport).Clone()
-		t.IdleConnTimeout = o.IdleConnTimeout
-		t.MaxIdleConns = o.MaxIdleConns
-		t.MaxIdleConnsPerHost = o.MaxIdleConnsPerHost
-		t.MaxConnsPerHost = o.MaxConnsPerHost
-		t.TLSClientConfig.InsecureSkipVerify = o.ignoreTLSCert
-		c.Transport = t
+		c.Transport = sharedTransport(transportSettings{
+			ignoreTLSCert:       o.ignoreTLSCert,
+			maxConnsPerHost:     o.MaxConnsPerHost,
+			maxIdleConnsPerHost: o.MaxIdleConnsPerHost,
+			maxIdleConns:        o.MaxIdleConns,
+			idleConnTimeout:     o.IdleConnTimeout,
+			dialTimeout:         o.DialTimeout,
+			tlsHandshakeTimeout: o.TLSHandshakeTimeout,
+			tlsSessionCacheSize: o.TLSSessionCacheSize,
+			disableHTTP2:        o.DisableHTTP2,
+		})
 
 		o.HttpClient = c
 	}
+
+	if o.wrapTransport != nil {
+		c := *o.HttpClient
+		c.Transport = o.wrapTransport(c.Transport)
+		o.HttpClient = &c
+	}
+
+	c := *o.HttpClient
+	c.Transport = &connectionTracker{next: c.Transport, counter: o.connections}
+	o.HttpClient = &c
 	return o, nil
 }
+
+// ConnectionStats returns how many connections the requests of the client opened and reused
+func (o *Options) ConnectionStats() ConnectionStats {
+	return o.connections.stats()
+}
@@ -0,0 +1,83 @@
+package client_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	. "github.com/masa-finance/tee-worker/pkg/client"
+)
+
+var _ = Describe("Options", func() {
+	get := func(c *http.Client, url string) *http.Response {
+		resp, err := c.Get(url)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = io.Copy(io.Discard, resp.Body)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.Body.Close()).To(Succeed())
+		return resp
+	}
+
+	AfterEach(func() {
+		SetDefaults()
+	})
+
+	It("counts new and reused connections", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		before := GetConnectionStats()
+		// A unique timeout gives the client a transport of its own, whose pool is empty
+		o, err := NewOptions(IdleConnTimeout(97 * time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		get(o.HttpClient, server.URL)
+		get(o.HttpClient, server.URL)
+
+		Expect(o.ConnectionStats()).To(Equal(ConnectionStats{New: 1, Reused: 1}))
+		after := GetConnectionStats()
+		Expect(after.New - before.New).To(BeNumerically(">=", 1))
+		Expect(after.Reused - before.Reused).To(BeNumerically(">=", 1))
+	})
+
+	It("reuses the connections of clients with the same settings", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		defer server.Close()
+
+		first, err := NewOptions(IdleConnTimeout(98 * time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		get(first.HttpClient, server.URL)
+
+		second, err := NewOptions(IdleConnTimeout(98 * time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		get(second.HttpClient, server.URL)
+		Expect(second.ConnectionStats()).To(Equal(ConnectionStats{New: 0, Reused: 1}))
+	})
+
+	It("enables and disables HTTP/2", func() {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		o, err := NewOptions(IgnoreTLSCert())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get(o.HttpClient, server.URL).ProtoMajor).To(Equal(2))
+
+		o, err = NewOptions(IgnoreTLSCert(), HTTP2(false))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(get(o.HttpClient, server.URL).ProtoMajor).To(Equal(1))
+	})
+
+	It("applies the defaults before the options of the client", func() {
+		SetDefaults(Timeout(5*time.Second), DialTimeout(3*time.Second))
+
+		o, err := NewOptions(Timeout(7 * time.Second))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(o.Timeout).To(Equal(7 * time.Second))
+		Expect(o.DialTimeout).To(Equal(3 * time.Second))
+	})
+})
@@ -1,16 +1,22 @@
 package client
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/masa-finance/tee-worker/pkg/retry"
 )
 
 type JobSignature string
 
 type JobResult struct {
 	UUID       string
+	Cached     bool // True if the job was answered with the result of an identical earlier job
 	maxRetries int
 	delay      time.Duration
+	wait       time.Duration
 	client     *Client
 }
 
@@ -22,29 +28,34 @@ func (jr *JobResult) SetDelay(delay time.Duration) {
 	jr.delay = delay
 }
 
+// SetWait makes each retry wait on the server for up to the given time for the job to finish, instead of polling
+// every delay. See Client.WaitForResult.
+func (jr *JobResult) SetWait(wait time.Duration) {
+	jr.wait = wait
+}
+
 // GetJobResult retrieves the encrypted result of a job.
 func (jr *JobResult) getResult() (string, bool, error) {
-	return jr.client.GetResult(jr.UUID)
+	return jr.client.WaitForResult(jr.UUID, jr.wait)
 }
 
 // Get polls the server until the job result is ready or a timeout occurs.
 func (jr *JobResult) Get() (result string, err error) {
-	retries := 0
-	var resultIsAvailable bool
-
-	for {
-		if retries >= jr.maxRetries {
-			return "", fmt.Errorf("max retries reached: %w", err)
-		}
-		retries++
+	if jr.maxRetries <= 0 {
+		return "", errors.New("max retries reached")
+	}
 
+	pollErr := retry.Do(context.Background(), retry.Policy{MaxAttempts: jr.maxRetries, Initial: jr.delay}, func() error {
+		var resultIsAvailable bool
 		result, resultIsAvailable, err = jr.getResult()
 		if err == nil || resultIsAvailable {
-			break
+			return nil
 		}
-		time.Sleep(jr.delay)
+		return err
+	})
+	if pollErr != nil {
+		return "", fmt.Errorf("max retries reached: %w", pollErr)
 	}
-
 	return
 }
 
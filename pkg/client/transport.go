@@ -0,0 +1,65 @@
+package client
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// transportSettings are the options which configure the transport of a client
+type transportSettings struct {
+	ignoreTLSCert       bool
+	maxConnsPerHost     int
+	maxIdleConnsPerHost int
+	maxIdleConns        int
+	idleConnTimeout     time.Duration
+	dialTimeout         time.Duration
+	tlsHandshakeTimeout time.Duration
+	tlsSessionCacheSize int
+	disableHTTP2        bool
+}
+
+var (
+	transportsLock sync.Mutex
+	transports     = make(map[transportSettings]*http.Transport)
+)
+
+// sharedTransport returns the transport for the given settings. Clients with the same settings share their transport,
+// so that connections opened by a client, e.g. for a job, are reused by the following ones instead of being closed.
+func sharedTransport(settings transportSettings) *http.Transport {
+	transportsLock.Lock()
+	defer transportsLock.Unlock()
+
+	if t, ok := transports[settings]; ok {
+		return t
+	}
+	t := newTransport(settings)
+	transports[settings] = t
+	return t
+}
+
+func newTransport(settings transportSettings) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.IdleConnTimeout = settings.idleConnTimeout
+	t.MaxIdleConns = settings.maxIdleConns
+	t.MaxIdleConnsPerHost = settings.maxIdleConnsPerHost
+	t.MaxConnsPerHost = settings.maxConnsPerHost
+	t.DialContext = (&net.Dialer{Timeout: settings.dialTimeout, KeepAlive: 30 * time.Second}).DialContext
+	t.TLSHandshakeTimeout = settings.tlsHandshakeTimeout
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+	t.TLSClientConfig.InsecureSkipVerify = settings.ignoreTLSCert
+	if settings.tlsSessionCacheSize > 0 {
+		t.TLSClientConfig.ClientSessionCache = tls.NewLRUClientSessionCache(settings.tlsSessionCacheSize)
+	}
+	if settings.disableHTTP2 {
+		// A non-nil empty TLSNextProto disables HTTP/2, and the cloned TLS config must not offer it either
+		t.ForceAttemptHTTP2 = false
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		t.TLSClientConfig.NextProtos = nil
+	}
+	return t
+}
@@ -18,13 +18,21 @@ type TwitterXClient struct {
 	httpClient *http.Client
 }
 
-func NewTwitterXClient(apiKey string) *TwitterXClient {
+// NewTwitterXClient creates a client for the Twitter API v2. The options tune its connections like those of the other
+// clients; if they are invalid, the default options are used.
+func NewTwitterXClient(apiKey string, opts ...Option) *TwitterXClient {
 	logrus.Info("Creating new TwitterXClient with API key")
+	options, err := NewOptions(opts...)
+	if err != nil {
+		logrus.Warnf("Invalid TwitterXClient options, using the defaults: %v", err)
+		options, _ = NewOptions()
+	}
+
 	// test if the API key is valid before returning the client
 	client := &TwitterXClient{
 		apiKey:     apiKey,
 		baseUrl:    baseURL,
-		httpClient: &http.Client{},
+		httpClient: options.HttpClient,
 	}
 
 	logrus.Info("TwitterXClient instantiated successfully using base URL: ", client.baseUrl)
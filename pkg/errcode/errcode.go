@@ -0,0 +1,31 @@
+// Package errcode holds the machine-readable codes of job errors, and the errors which carry one. It has no
+// dependencies of its own, so both the types of the API and the clients of the data sources can declare coded errors.
+package errcode
+
+// Code is a machine-readable classification of a job error
+type Code string
+
+const (
+	RateLimited     Code = "rate_limited"
+	Auth            Code = "auth"
+	Timeout         Code = "timeout"
+	NotFound        Code = "not_found"
+	InvalidArgument Code = "invalid_argument"
+	Unknown         Code = "unknown"
+)
+
+// Error is an error with the Code it is classified with. The clients of the data sources declare their errors with
+// New, so they are classified however they are wrapped, and without matching messages.
+type Error struct {
+	Code    Code
+	Message string
+}
+
+// New returns an error with the given code and message, to be compared with errors.Is
+func New(code Code, message string) error {
+	return &Error{Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
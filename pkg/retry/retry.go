@@ -0,0 +1,190 @@
+// Package retry runs operations again after they failed, or until they are done, waiting between the attempts as
+// configured by a Policy: with constant or exponentially growing delays, randomized by a jitter, and bounded by a
+// number of attempts, the elapsed time and a context.
+package retry
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrExhausted is returned by Poll when the operation is not done within the attempts or the elapsed time of the
+// policy
+var ErrExhausted = errors.New("retries exhausted")
+
+// Policy configures how often and after which delays an operation is attempted
+type Policy struct {
+	// MaxAttempts is the number of times the operation is attempted, including the first one. 0 is unlimited.
+	MaxAttempts int
+	// Initial is the delay after the first attempt
+	Initial time.Duration
+	// Multiplier grows the delay after every attempt, e.g. 2 doubles it. Values up to 1 keep it constant.
+	Multiplier float64
+	// Max caps the delay, unless it is 0
+	Max time.Duration
+	// Jitter randomizes every delay by up to this share of it in either direction, e.g. 0.2 for ±20%
+	Jitter float64
+	// MaxElapsed stops retrying when the next attempt would start later than this after the first one, unless it is 0
+	MaxElapsed time.Duration
+	// Retryable returns true if an error is transient. All errors are retried if it is nil, except those wrapped
+	// with Permanent.
+	Retryable func(error) bool
+	// OnRetry is called before waiting for the next attempt, e.g. to log the error
+	OnRetry func(attempt int, wait time.Duration, err error)
+}
+
+// Constant returns a Policy waiting interval between the attempts
+func Constant(interval time.Duration) Policy {
+	return Policy{Initial: interval}
+}
+
+// Exponential returns a Policy doubling the delay after every attempt, from initial up to max
+func Exponential(initial, max time.Duration) Policy {
+	return Policy{Initial: initial, Multiplier: 2, Max: max}
+}
+
+// Delay returns the delay after the given attempt, starting at 1, without the jitter
+func (p Policy) Delay(attempt int) time.Duration {
+	delay := p.Initial
+	for i := 1; i < attempt && p.Multiplier > 1; i++ {
+		delay = time.Duration(float64(delay) * p.Multiplier)
+		if p.Max > 0 && delay >= p.Max {
+			break
+		}
+	}
+	if p.Max > 0 && delay > p.Max {
+		return p.Max
+	}
+	return delay
+}
+
+// jittered returns the delay after the given attempt, with the jitter
+func (p Policy) jittered(attempt int) time.Duration {
+	delay := p.Delay(attempt)
+	if p.Jitter <= 0 || delay <= 0 {
+		return delay
+	}
+	return time.Duration(float64(delay) * (1 + p.Jitter*(2*rand.Float64()-1)))
+}
+
+// permanentError is an error which is not retried
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks an error as not to be retried, whatever the Retryable function of the policy says
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// afterError is an error after which the next attempt is delayed by at least wait
+type afterError struct {
+	err  error
+	wait time.Duration
+}
+
+func (e *afterError) Error() string { return e.err.Error() }
+func (e *afterError) Unwrap() error { return e.err }
+
+// After asks to wait at least the given time before the next attempt, e.g. as told by a Retry-After header
+func After(err error, wait time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &afterError{err: err, wait: wait}
+}
+
+// unwrap removes the wrappers of Permanent and After, which are only meant for Do
+func unwrap(err error) error {
+	for {
+		switch e := err.(type) {
+		case *permanentError:
+			err = e.err
+		case *afterError:
+			err = e.err
+		default:
+			return err
+		}
+	}
+}
+
+// Do attempts the operation until it succeeds, it fails with an error which is not retryable, or the policy allows
+// no more attempts, and returns the error of the last attempt. If the context is done while waiting for the next
+// attempt, the cause of the context is returned instead.
+func Do(ctx context.Context, p Policy, op func() error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil {
+			return nil
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) || (p.Retryable != nil && !p.Retryable(unwrap(err))) {
+			return unwrap(err)
+		}
+		if p.MaxAttempts > 0 && attempt >= p.MaxAttempts {
+			return unwrap(err)
+		}
+
+		wait := p.jittered(attempt)
+		var after *afterError
+		if errors.As(err, &after) {
+			wait = max(wait, after.wait)
+		}
+		if p.MaxElapsed > 0 && time.Since(start)+wait > p.MaxElapsed {
+			return unwrap(err)
+		}
+
+		if p.OnRetry != nil {
+			p.OnRetry(attempt, wait, unwrap(err))
+		}
+		if err := Wait(ctx, wait); err != nil {
+			return err
+		}
+	}
+}
+
+// Wait waits for the given time, or until the context is done, in which case the cause of the context is returned
+func Wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return context.Cause(ctx)
+	case <-timer.C:
+		return nil
+	}
+}
+
+// errPending is returned by the operation of Poll while the condition is not met
+var errPending = errors.New("pending")
+
+// Poll calls check until it reports that it is done, waiting the delays of the policy between the calls. An error
+// returned by check stops the polling and is returned. If the policy allows no more attempts, ErrExhausted is
+// returned. The Retryable and OnRetry functions of the policy are not used.
+func Poll(ctx context.Context, p Policy, check func() (bool, error)) error {
+	p.Retryable, p.OnRetry = nil, nil
+	err := Do(ctx, p, func() error {
+		done, err := check()
+		if err != nil {
+			return Permanent(err)
+		}
+		if !done {
+			return errPending
+		}
+		return nil
+	})
+	if errors.Is(err, errPending) {
+		return ErrExhausted
+	}
+	return err
+}
@@ -0,0 +1,145 @@
+package retry_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/masa-finance/tee-worker/pkg/retry"
+)
+
+var _ = Describe("Retry", func() {
+	errTransient := errors.New("rate limit exceeded")
+
+	It("backs off exponentially up to the maximum", func() {
+		p := retry.Exponential(time.Second, 5*time.Second)
+		Expect(p.Delay(1)).To(Equal(time.Second))
+		Expect(p.Delay(2)).To(Equal(2 * time.Second))
+		Expect(p.Delay(3)).To(Equal(4 * time.Second))
+		Expect(p.Delay(4)).To(Equal(5 * time.Second))
+		Expect(p.Delay(100)).To(Equal(5 * time.Second))
+		Expect(retry.Constant(time.Second).Delay(10)).To(Equal(time.Second))
+	})
+
+	It("retries until the operation succeeds", func() {
+		calls := 0
+		var waits []time.Duration
+		p := retry.Policy{Initial: time.Millisecond, Multiplier: 2, OnRetry: func(_ int, wait time.Duration, err error) {
+			Expect(err).To(Equal(errTransient))
+			waits = append(waits, wait)
+		}}
+		Expect(retry.Do(context.Background(), p, func() error {
+			if calls++; calls < 3 {
+				return errTransient
+			}
+			return nil
+		})).To(Succeed())
+		Expect(calls).To(Equal(3))
+		Expect(waits).To(Equal([]time.Duration{time.Millisecond, 2 * time.Millisecond}))
+	})
+
+	It("stops after the maximum number of attempts", func() {
+		calls := 0
+		err := retry.Do(context.Background(), retry.Policy{MaxAttempts: 3, Initial: time.Millisecond}, func() error {
+			calls++
+			return errTransient
+		})
+		Expect(err).To(Equal(errTransient))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("doesn't retry errors which are not retryable or permanent", func() {
+		errInvalid := errors.New("invalid argument")
+		p := retry.Policy{Initial: time.Millisecond, Retryable: func(err error) bool { return err == errTransient }}
+
+		calls := 0
+		Expect(retry.Do(context.Background(), p, func() error {
+			calls++
+			return errInvalid
+		})).To(Equal(errInvalid))
+		Expect(calls).To(Equal(1))
+
+		calls = 0
+		Expect(retry.Do(context.Background(), p, func() error {
+			calls++
+			return retry.Permanent(errTransient)
+		})).To(Equal(errTransient))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("waits at least as long as asked by the error", func() {
+		var wait time.Duration
+		p := retry.Policy{MaxAttempts: 2, Initial: time.Millisecond, OnRetry: func(_ int, w time.Duration, _ error) { wait = w }}
+		err := retry.Do(context.Background(), p, func() error { return retry.After(errTransient, 20*time.Millisecond) })
+		Expect(err).To(Equal(errTransient))
+		Expect(wait).To(Equal(20 * time.Millisecond))
+	})
+
+	It("stops once the next attempt would start after the maximum elapsed time", func() {
+		calls := 0
+		err := retry.Do(context.Background(), retry.Policy{Initial: time.Hour, MaxElapsed: time.Minute}, func() error {
+			calls++
+			return errTransient
+		})
+		Expect(err).To(Equal(errTransient))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("randomizes the delays within the jitter", func() {
+		var waits []time.Duration
+		p := retry.Policy{MaxAttempts: 20, Initial: 100 * time.Microsecond, Jitter: 0.5, OnRetry: func(_ int, w time.Duration, _ error) {
+			waits = append(waits, w)
+		}}
+		_ = retry.Do(context.Background(), p, func() error { return errTransient })
+		Expect(waits).To(HaveLen(19))
+		for _, w := range waits {
+			Expect(w).To(BeNumerically(">=", 50*time.Microsecond))
+			Expect(w).To(BeNumerically("<=", 150*time.Microsecond))
+		}
+		Expect(waits).To(ContainElement(Not(Equal(waits[0]))))
+	})
+
+	It("returns the cause of the context when it is done while waiting", func() {
+		errCancelled := errors.New("job cancelled")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		calls := 0
+		err := retry.Do(ctx, retry.Constant(time.Hour), func() error {
+			calls++
+			cancel(errCancelled)
+			return errTransient
+		})
+		Expect(err).To(Equal(errCancelled))
+		Expect(calls).To(Equal(1))
+	})
+
+	It("polls until the operation is done", func() {
+		calls := 0
+		Expect(retry.Poll(context.Background(), retry.Constant(time.Millisecond), func() (bool, error) {
+			calls++
+			return calls == 3, nil
+		})).To(Succeed())
+		Expect(calls).To(Equal(3))
+
+		Expect(retry.Poll(context.Background(), retry.Policy{MaxAttempts: 2, Initial: time.Millisecond}, func() (bool, error) {
+			return false, nil
+		})).To(MatchError(retry.ErrExhausted))
+
+		Expect(retry.Poll(context.Background(), retry.Constant(time.Millisecond), func() (bool, error) {
+			return false, errTransient
+		})).To(Equal(errTransient))
+	})
+
+	It("waits until the context is done", func() {
+		start := time.Now()
+		Expect(retry.Wait(context.Background(), 10*time.Millisecond)).To(Succeed())
+		Expect(time.Since(start)).To(BeNumerically(">=", 10*time.Millisecond))
+
+		errCancelled := errors.New("cancelled")
+		ctx, cancel := context.WithCancelCause(context.Background())
+		cancel(errCancelled)
+		Expect(retry.Wait(ctx, time.Hour)).To(Equal(errCancelled))
+	})
+})
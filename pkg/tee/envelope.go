@@ -0,0 +1,73 @@
+package tee
+
+/*
+Envelopes seal data together with metadata describing how it was produced, so the metadata is covered
+by the same seal as the data and can't be altered or detached from it:
+
+   // Seal a JSON document with its metadata
+   sealed, err := tee.SealEnvelope(nonce, data, metadata)
+
+   // Unseal it, unmarshalling the metadata
+   data, err := tee.UnsealEnvelope(nonce, sealed, &metadata)
+
+The sealed payload is the JSON-encoded Envelope, so it can also be decoded after unsealing it with UnsealWithKey.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEnvelopeData is returned when sealing data which is not a JSON document in an envelope
+var ErrInvalidEnvelopeData = errors.New("envelope data must be valid JSON")
+
+// Envelope is the payload sealed by SealEnvelope
+type Envelope struct {
+	Data     json.RawMessage `json:"data"`
+	Metadata json.RawMessage `json:"metadata"`
+}
+
+// SealEnvelope seals the JSON document data together with the JSON encoding of metadata, using the given salt
+func SealEnvelope(salt string, data []byte, metadata any) (string, error) {
+	if len(data) == 0 {
+		data = []byte("null")
+	}
+	if !json.Valid(data) {
+		return "", ErrInvalidEnvelopeData
+	}
+
+	md, err := json.Marshal(metadata)
+	if err != nil {
+		return "", fmt.Errorf("error marshalling envelope metadata: %w", err)
+	}
+
+	payload, err := json.Marshal(Envelope{Data: data, Metadata: md})
+	if err != nil {
+		return "", fmt.Errorf("error marshalling envelope: %w", err)
+	}
+
+	return SealWithKey(salt, payload)
+}
+
+// UnsealEnvelope unseals an envelope sealed with SealEnvelope. It returns the data, and unmarshals the metadata into
+// metadata unless it is nil.
+func UnsealEnvelope(salt, sealed string, metadata any) ([]byte, error) {
+	payload, err := UnsealWithKey(salt, sealed)
+	if err != nil {
+		return nil, err
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(payload, &envelope); err != nil {
+		return nil, fmt.Errorf("error parsing envelope: %w", err)
+	}
+
+	if metadata != nil && len(envelope.Metadata) > 0 {
+		if err := json.Unmarshal(envelope.Metadata, metadata); err != nil {
+			return nil, fmt.Errorf("error parsing envelope metadata: %w", err)
+		}
+	}
+
+	return envelope.Data, nil
+}
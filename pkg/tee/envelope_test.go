@@ -0,0 +1,51 @@
+package tee
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Envelopes", func() {
+	type metadata struct {
+		Source string `json:"source"`
+	}
+
+	BeforeEach(func() {
+		CurrentKeyRing = NewKeyRing()
+		CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		SealStandaloneMode = false
+	})
+
+	It("should seal data together with its metadata", func() {
+		sealed, err := SealEnvelope("nonce", []byte(`[{"id":"1"}]`), metadata{Source: "api"})
+		Expect(err).NotTo(HaveOccurred())
+
+		var md metadata
+		data, err := UnsealEnvelope("nonce", sealed, &md)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).To(Equal(`[{"id":"1"}]`))
+		Expect(md.Source).To(Equal("api"))
+
+		// The payload can also be decoded after unsealing it directly
+		payload, err := UnsealWithKey("nonce", sealed)
+		Expect(err).NotTo(HaveOccurred())
+		var envelope Envelope
+		Expect(json.Unmarshal(payload, &envelope)).To(Succeed())
+		Expect(string(envelope.Metadata)).To(Equal(`{"source":"api"}`))
+	})
+
+	It("should only unseal envelopes with the salt they were sealed with", func() {
+		sealed, err := SealEnvelope("nonce", []byte(`{}`), nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = UnsealEnvelope("other", sealed, nil)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject data which is not JSON", func() {
+		_, err := SealEnvelope("nonce", []byte("not json"), nil)
+		Expect(err).To(MatchError(ErrInvalidEnvelopeData))
+	})
+})
@@ -1,9 +1,12 @@
 package tee
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
+	"time"
 
 	"github.com/edgelesssys/ego/ecrypto"
 	"github.com/google/uuid"
@@ -11,12 +14,62 @@ import (
 
 const (
 	WorkerIdKey = "worker_id"
+	// WorkerIdHistoryKey is the file of the worker IDs which were replaced by a rotation
+	WorkerIdHistoryKey = "worker_id_history"
 )
 
 var (
 	WorkerID string // Global variable to store the worker ID
+
+	workerIDLock     sync.RWMutex
+	retiredWorkerIDs []RetiredWorkerID
+
+	// sealWithProductKey seals the files written by RotateWorkerID, and is replaced by the tests to make it fail
+	sealWithProductKey = ecrypto.SealWithProductKey
 )
 
+// RetiredWorkerID is a worker ID which was renamed by RotateWorkerID. It stays valid until ValidUntil, so jobs
+// which were assigned to it before the rotation are still accepted.
+type RetiredWorkerID struct {
+	ID         string    `json:"id"`
+	RotatedAt  time.Time `json:"rotated_at"`
+	ValidUntil time.Time `json:"valid_until"`
+	// ReplacedBy is the worker ID which replaced it
+	ReplacedBy string `json:"replaced_by"`
+	Reason     string `json:"reason,omitempty"`
+}
+
+// CurrentWorkerID returns the worker ID. Unlike WorkerID, it can be read while the worker ID is rotated.
+func CurrentWorkerID() string {
+	workerIDLock.RLock()
+	defer workerIDLock.RUnlock()
+	return WorkerID
+}
+
+// RetiredWorkerIDs returns the worker IDs which were replaced by a rotation, oldest first, including the ones whose
+// grace period is over. They link the reputation of the previous identities of the worker to the current one.
+func RetiredWorkerIDs() []RetiredWorkerID {
+	workerIDLock.RLock()
+	defer workerIDLock.RUnlock()
+	return append([]RetiredWorkerID(nil), retiredWorkerIDs...)
+}
+
+// IsWorkerID returns true if id is the worker ID, or a worker ID replaced by a rotation whose grace period isn't over
+func IsWorkerID(id string) bool {
+	workerIDLock.RLock()
+	defer workerIDLock.RUnlock()
+	if id == WorkerID {
+		return true
+	}
+	now := time.Now()
+	for _, r := range retiredWorkerIDs {
+		if r.ID == id && now.Before(r.ValidUntil) {
+			return true
+		}
+	}
+	return false
+}
+
 // generateWorkerID generates a new worker ID.
 func generateWorkerID() string {
 	return uuid.New().String()
@@ -79,6 +132,15 @@ func InitializeWorkerID(dataDir string) error {
 	}
 
 	// If the worker ID doesn't exist, generate a new one and save it
+	history, err := loadWorkerIDHistory(dataDir)
+	if err != nil {
+		return fmt.Errorf("error loading worker ID history: %w", err)
+	}
+
+	workerIDLock.Lock()
+	defer workerIDLock.Unlock()
+	retiredWorkerIDs = history
+
 	if existingID == "" {
 		newID := generateWorkerID()
 		if err := saveWorkerID(dataDir, newID); err != nil {
@@ -91,3 +153,81 @@ func InitializeWorkerID(dataDir string) error {
 
 	return nil
 }
+
+// RotateWorkerID renames the worker, replacing its worker ID with a new one, e.g. when the worker is migrated to new
+// hardware. Only the ID changes: the sealing keys of the worker and the identity the key distributor attested stay
+// the same, so a rotation is no remedy for a compromised worker, which has to be redeployed instead. The old worker
+// ID stays valid for the grace period and is kept in the history of the worker, which is saved before the new worker
+// ID so that a failed rotation never loses the old one. Both files have to be sealed, so the worker ID can't be
+// rotated outside of an enclave.
+func RotateWorkerID(dataDir, reason string, grace time.Duration) (RetiredWorkerID, error) {
+	workerIDLock.Lock()
+	defer workerIDLock.Unlock()
+
+	now := time.Now()
+	retired := RetiredWorkerID{
+		ID:         WorkerID,
+		RotatedAt:  now,
+		ValidUntil: now.Add(grace),
+		ReplacedBy: generateWorkerID(),
+		Reason:     reason,
+	}
+	history := append(append([]RetiredWorkerID(nil), retiredWorkerIDs...), retired)
+
+	if err := saveWorkerIDHistory(dataDir, history); err != nil {
+		return RetiredWorkerID{}, fmt.Errorf("error saving worker ID history: %w", err)
+	}
+	if err := writeSealedFile(filepath.Join(dataDir, WorkerIdKey), []byte(retired.ReplacedBy)); err != nil {
+		// The rotation didn't happen, so the history goes back to what it was
+		_ = saveWorkerIDHistory(dataDir, retiredWorkerIDs)
+		return RetiredWorkerID{}, fmt.Errorf("error saving worker ID: %w", err)
+	}
+
+	retiredWorkerIDs = history
+	WorkerID = retired.ReplacedBy
+	return retired, nil
+}
+
+// saveWorkerIDHistory saves the retired worker IDs to a file in the data directory, sealed like the worker ID
+func saveWorkerIDHistory(dataDir string, history []RetiredWorkerID) error {
+	data, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+
+	return writeSealedFile(filepath.Join(dataDir, WorkerIdHistoryKey), data)
+}
+
+// writeSealedFile seals the data with the product key and replaces the file with it atomically, readable only by the
+// owner. Unlike saveWorkerID, it never falls back to writing the data unsealed.
+func writeSealedFile(path string, data []byte) error {
+	sealed, err := sealWithProductKey(data, []byte{})
+	if err != nil {
+		return fmt.Errorf("error sealing %s: %w", filepath.Base(path), err)
+	}
+
+	return writeFile(path, sealed)
+}
+
+// loadWorkerIDHistory loads the retired worker IDs from a file in the data directory. A missing file means that the
+// worker ID was never rotated.
+func loadWorkerIDHistory(dataDir string) ([]RetiredWorkerID, error) {
+	sealed, err := os.ReadFile(filepath.Join(dataDir, WorkerIdHistoryKey))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read worker ID history: %w", err)
+	}
+
+	data, err := ecrypto.Unseal(sealed, []byte{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to unseal worker ID history: %w", err)
+	}
+
+	var history []RetiredWorkerID
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, fmt.Errorf("failed to decode worker ID history: %w", err)
+	}
+	return history, nil
+}
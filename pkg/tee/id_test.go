@@ -0,0 +1,109 @@
+package tee
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Worker ID rotation", func() {
+	var dataDir string
+
+	BeforeEach(func() {
+		dataDir = GinkgoT().TempDir()
+
+		originalWorkerID, originalRetired := WorkerID, retiredWorkerIDs
+		originalSeal := sealWithProductKey
+		WorkerID, retiredWorkerIDs = "worker-1", nil
+		DeferCleanup(func() {
+			WorkerID, retiredWorkerIDs = originalWorkerID, originalRetired
+			sealWithProductKey = originalSeal
+		})
+	})
+
+	It("should replace the worker ID and keep the previous one valid for the grace period", func() {
+		retired, err := RotateWorkerID(dataDir, "migration", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(retired.ID).To(Equal("worker-1"))
+		Expect(retired.Reason).To(Equal("migration"))
+		Expect(retired.ValidUntil).To(BeTemporally("~", retired.RotatedAt.Add(time.Hour)))
+
+		Expect(CurrentWorkerID()).To(Equal(retired.ReplacedBy))
+		Expect(CurrentWorkerID()).NotTo(Equal("worker-1"))
+		Expect(IsWorkerID(retired.ReplacedBy)).To(BeTrue())
+		Expect(IsWorkerID("worker-1")).To(BeTrue())
+		Expect(IsWorkerID("worker-2")).To(BeFalse())
+		Expect(RetiredWorkerIDs()).To(Equal([]RetiredWorkerID{retired}))
+
+		for _, name := range []string{WorkerIdKey, WorkerIdHistoryKey} {
+			info, err := os.Stat(filepath.Join(dataDir, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+			data, err := os.ReadFile(filepath.Join(dataDir, name))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(data)).NotTo(ContainSubstring(retired.ReplacedBy))
+		}
+	})
+
+	It("should stop accepting the previous worker ID after the grace period", func() {
+		retired, err := RotateWorkerID(dataDir, "", 0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(IsWorkerID(retired.ID)).To(BeFalse())
+		// It is still part of the history of the worker
+		Expect(RetiredWorkerIDs()).To(HaveLen(1))
+	})
+
+	It("should keep the history of the worker IDs across rotations and restarts", func() {
+		first, err := RotateWorkerID(dataDir, "migration", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		second, err := RotateWorkerID(dataDir, "renamed", time.Hour)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second.ID).To(Equal(first.ReplacedBy))
+
+		history, err := loadWorkerIDHistory(dataDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history).To(HaveLen(2))
+		Expect(history[0].ID).To(Equal("worker-1"))
+		Expect(history[1].ID).To(Equal(first.ReplacedBy))
+		Expect(history[1].ReplacedBy).To(Equal(CurrentWorkerID()))
+	})
+
+	It("should not require a history if the worker ID was never rotated", func() {
+		history, err := loadWorkerIDHistory(dataDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(history).To(BeEmpty())
+	})
+
+	It("should not lose the worker ID if the rotation fails", func() {
+		_, err := RotateWorkerID(filepath.Join(dataDir, "missing"), "", time.Hour)
+		Expect(err).To(HaveOccurred())
+		Expect(CurrentWorkerID()).To(Equal("worker-1"))
+		Expect(RetiredWorkerIDs()).To(BeEmpty())
+	})
+
+	It("should fail the rotation instead of saving the worker IDs unsealed", func() {
+		sealWithProductKey = func([]byte, []byte) ([]byte, error) {
+			return nil, errors.New("not in an enclave")
+		}
+
+		_, err := RotateWorkerID(dataDir, "", time.Hour)
+		Expect(err).To(HaveOccurred())
+		Expect(CurrentWorkerID()).To(Equal("worker-1"))
+		Expect(RetiredWorkerIDs()).To(BeEmpty())
+
+		entries, err := os.ReadDir(dataDir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(BeEmpty())
+	})
+
+	It("should not load a history which is not sealed", func() {
+		Expect(os.WriteFile(filepath.Join(dataDir, WorkerIdHistoryKey), []byte(`[{"id":"worker-0"}]`), 0600)).To(Succeed())
+
+		_, err := loadWorkerIDHistory(dataDir)
+		Expect(err).To(HaveOccurred())
+	})
+})
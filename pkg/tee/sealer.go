@@ -70,6 +70,11 @@ import (
 
 var SealStandaloneMode bool
 
+// SealingAvailable returns true if data can be sealed, i.e. in standalone mode or once a sealing key has been set
+func SealingAvailable() bool {
+	return SealStandaloneMode || (CurrentKeyRing != nil && len(CurrentKeyRing.Keys) > 0)
+}
+
 // Seal uses the TEE Product Key to encrypt the plaintext
 // The Product key is the one bound to the signer pubkey
 func Seal(plaintext []byte) (string, error) {
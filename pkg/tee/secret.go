@@ -0,0 +1,117 @@
+package tee
+
+/*
+Secrets are credentials and session artifacts (e.g. cookies) that scrapers persist between jobs.
+They are sealed with the current key ring before they are written to disk, so they are never
+stored in plaintext:
+
+   // Write a secret file
+   err := tee.WriteSecretFile(path, "twitter-cookies:alice", data)
+
+   // Read it back. Files written before secrets were sealed are still read, and reported as legacy.
+   data, legacy, err := tee.ReadSecretFile(path, "twitter-cookies:alice")
+
+The purpose is used as the salt of the sealing key, so a secret can only be unsealed for the
+purpose it was sealed for. Secrets sealed with a key that has been rotated out of the key ring
+can no longer be unsealed, and have to be recreated (e.g. by logging in again).
+*/
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// sealedSecretPrefix marks sealed secrets, so they can be told apart from legacy plaintext artifacts
+const sealedSecretPrefix = "tee-sealed:v1:"
+
+// ErrSecretNotSealed is returned when unsealing data that was not sealed with SealSecret
+var ErrSecretNotSealed = errors.New("secret is not sealed")
+
+func secretSalt(purpose string) string {
+	return "secret:" + purpose
+}
+
+// IsSealedSecret returns true if the data was sealed with SealSecret
+func IsSealedSecret(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(sealedSecretPrefix))
+}
+
+// SealSecret seals a credential or session artifact for the given purpose, e.g. "twitter-cookies:<username>"
+func SealSecret(purpose string, plaintext []byte) ([]byte, error) {
+	sealed, err := SealWithKey(secretSalt(purpose), plaintext)
+	if err != nil {
+		return nil, fmt.Errorf("error sealing secret: %w", err)
+	}
+	return []byte(sealedSecretPrefix + sealed), nil
+}
+
+// UnsealSecret unseals data sealed with SealSecret for the same purpose
+func UnsealSecret(purpose string, data []byte) ([]byte, error) {
+	if !IsSealedSecret(data) {
+		return nil, ErrSecretNotSealed
+	}
+	plaintext, err := UnsealWithKey(secretSalt(purpose), string(data[len(sealedSecretPrefix):]))
+	if err != nil {
+		return nil, fmt.Errorf("error unsealing secret: %w", err)
+	}
+	return plaintext, nil
+}
+
+// WriteSecretFile seals the secret and writes it to the given path, readable only by the owner.
+// The file is replaced atomically, so a failed write never leaves a truncated secret behind.
+func WriteSecretFile(path, purpose string, plaintext []byte) error {
+	sealed, err := SealSecret(purpose, plaintext)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(path, sealed)
+}
+
+// writeFile replaces the file atomically with one readable only by the owner, so a failed write never leaves a
+// truncated secret behind
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("error creating secret file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error writing secret file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error writing secret file: %w", err)
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return fmt.Errorf("error setting permissions of secret file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("error writing secret file: %w", err)
+	}
+
+	return nil
+}
+
+// ReadSecretFile reads and unseals a secret written with WriteSecretFile. Files written before secrets
+// were sealed are returned as they are, with legacy set to true, so callers can seal them again.
+func ReadSecretFile(path, purpose string) (plaintext []byte, legacy bool, err error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if !IsSealedSecret(data) {
+		return data, true, nil
+	}
+
+	plaintext, err = UnsealSecret(purpose, data)
+	if err != nil {
+		return nil, false, err
+	}
+	return plaintext, false, nil
+}
@@ -0,0 +1,92 @@
+package tee
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Secrets", func() {
+	var (
+		dir  string
+		path string
+	)
+
+	BeforeEach(func() {
+		CurrentKeyRing = NewKeyRing()
+		CurrentKeyRing.Add("0123456789abcdef0123456789abcdef")
+		SealStandaloneMode = false
+
+		dir = GinkgoT().TempDir()
+		path = filepath.Join(dir, "alice_twitter_cookies.json")
+	})
+
+	It("should seal and unseal secrets", func() {
+		sealed, err := SealSecret("twitter-cookies:alice", []byte(`[{"name":"auth_token"}]`))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(IsSealedSecret(sealed)).To(BeTrue())
+		Expect(string(sealed)).NotTo(ContainSubstring("auth_token"))
+
+		plaintext, err := UnsealSecret("twitter-cookies:alice", sealed)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(plaintext)).To(Equal(`[{"name":"auth_token"}]`))
+	})
+
+	It("should only unseal secrets for the purpose they were sealed for", func() {
+		sealed, err := SealSecret("twitter-cookies:alice", []byte("secret"))
+		Expect(err).NotTo(HaveOccurred())
+
+		_, err = UnsealSecret("twitter-cookies:bob", sealed)
+		Expect(err).To(HaveOccurred())
+
+		_, err = UnsealSecret("twitter-cookies:alice", []byte("secret"))
+		Expect(err).To(MatchError(ErrSecretNotSealed))
+	})
+
+	It("should fail to seal secrets without a key", func() {
+		CurrentKeyRing = NewKeyRing()
+		_, err := SealSecret("twitter-cookies:alice", []byte("secret"))
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should write sealed secret files readable only by the owner", func() {
+		Expect(WriteSecretFile(path, "twitter-cookies:alice", []byte("secret"))).To(Succeed())
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		raw, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(IsSealedSecret(raw)).To(BeTrue())
+
+		plaintext, legacy, err := ReadSecretFile(path, "twitter-cookies:alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(legacy).To(BeFalse())
+		Expect(plaintext).To(Equal([]byte("secret")))
+
+		entries, err := os.ReadDir(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entries).To(HaveLen(1))
+	})
+
+	It("should read legacy plaintext secret files", func() {
+		Expect(os.WriteFile(path, []byte("plaintext"), 0644)).To(Succeed())
+
+		plaintext, legacy, err := ReadSecretFile(path, "twitter-cookies:alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(legacy).To(BeTrue())
+		Expect(plaintext).To(Equal([]byte("plaintext")))
+	})
+
+	It("should still unseal secrets after a key rotation", func() {
+		Expect(WriteSecretFile(path, "twitter-cookies:alice", []byte("secret"))).To(Succeed())
+		CurrentKeyRing.Add("abcdef0123456789abcdef0123456789")
+
+		plaintext, _, err := ReadSecretFile(path, "twitter-cookies:alice")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plaintext).To(Equal([]byte("secret")))
+	})
+})
@@ -0,0 +1,143 @@
+package tee
+
+/*
+The credentials the worker is configured with, e.g. API keys, can be kept in a secrets file instead of plain
+environment variables. The configuration then only names the secret, and the value is looked up with a
+SecretsProvider:
+
+   secrets := tee.NewSecretsFile(filepath.Join(dataDir, tee.SecretsFileName))
+   err := secrets.SetSecret("apify", "apify_api_...")
+   apiKey, err := secrets.GetSecret("apify")
+
+The file is sealed with the product key of the enclave rather than the key ring, since the credentials are needed at
+startup, before a sealing key has been set. It can only be read by enclaves of the same signer and product.
+SecretsProvider is the extension point for other backends, e.g. Vault or a KMS.
+*/
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/edgelesssys/ego/ecrypto"
+)
+
+// SecretsFileName is the name of the secrets file in the data directory
+const SecretsFileName = "secrets.sealed"
+
+// secretsFileSalt binds the sealed secrets file to its purpose
+const secretsFileSalt = "secrets-file"
+
+// ErrSecretNotFound is returned when a secret is not known to the provider
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretsProvider looks up secrets by name
+type SecretsProvider interface {
+	GetSecret(name string) (string, error)
+}
+
+// SecretsFile is a SecretsProvider storing the secrets in a single sealed file. A missing file holds no secrets.
+type SecretsFile struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewSecretsFile returns the secrets file at the given path
+func NewSecretsFile(path string) *SecretsFile {
+	return &SecretsFile{path: path}
+}
+
+// GetSecret returns the secret with the given name, or ErrSecretNotFound
+func (f *SecretsFile) GetSecret(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.read()
+	if err != nil {
+		return "", err
+	}
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	return value, nil
+}
+
+// Names returns the names of the secrets in the file, sorted
+func (f *SecretsFile) Names() ([]string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(secrets)), nil
+}
+
+// SetSecret adds or replaces a secret
+func (f *SecretsFile) SetSecret(name, value string) error {
+	if name == "" {
+		return errors.New("the name of a secret can't be empty")
+	}
+	return f.update(func(secrets map[string]string) error {
+		secrets[name] = value
+		return nil
+	})
+}
+
+// DeleteSecret removes a secret, or returns ErrSecretNotFound
+func (f *SecretsFile) DeleteSecret(name string) error {
+	return f.update(func(secrets map[string]string) error {
+		if _, ok := secrets[name]; !ok {
+			return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+		}
+		delete(secrets, name)
+		return nil
+	})
+}
+
+func (f *SecretsFile) update(change func(map[string]string) error) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	secrets, err := f.read()
+	if err != nil {
+		return err
+	}
+	if err := change(secrets); err != nil {
+		return err
+	}
+
+	plaintext, err := json.Marshal(secrets)
+	if err != nil {
+		return fmt.Errorf("error encoding secrets: %w", err)
+	}
+	sealed, err := ecrypto.SealWithProductKey(plaintext, []byte(secretsFileSalt))
+	if err != nil {
+		return fmt.Errorf("error sealing secrets: %w", err)
+	}
+	return writeFile(f.path, sealed)
+}
+
+// read returns the secrets in the file, which are empty if it does not exist
+func (f *SecretsFile) read() (map[string]string, error) {
+	sealed, err := os.ReadFile(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error reading secrets file: %w", err)
+	}
+
+	plaintext, err := ecrypto.Unseal(sealed, []byte(secretsFileSalt))
+	if err != nil {
+		return nil, fmt.Errorf("error unsealing secrets file: %w", err)
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("error decoding secrets file: %w", err)
+	}
+	return secrets, nil
+}
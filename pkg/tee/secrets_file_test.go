@@ -0,0 +1,62 @@
+package tee
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("SecretsFile", func() {
+	var (
+		path    string
+		secrets *SecretsFile
+	)
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), SecretsFileName)
+		secrets = NewSecretsFile(path)
+	})
+
+	It("should store sealed secrets", func() {
+		Expect(secrets.SetSecret("apify", "apify_api_abcdef")).To(Succeed())
+		Expect(secrets.SetSecret("gemini", "AIzaSyabcdef")).To(Succeed())
+
+		data, err := os.ReadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(data)).NotTo(ContainSubstring("apify_api_abcdef"))
+
+		info, err := os.Stat(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0600)))
+
+		// The secrets can be read from another instance of the file, e.g. after a restart
+		value, err := NewSecretsFile(path).GetSecret("apify")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(value).To(Equal("apify_api_abcdef"))
+		Expect(secrets.Names()).To(Equal([]string{"apify", "gemini"}))
+	})
+
+	It("should delete secrets", func() {
+		Expect(secrets.SetSecret("apify", "apify_api_abcdef")).To(Succeed())
+		Expect(secrets.DeleteSecret("apify")).To(Succeed())
+
+		_, err := secrets.GetSecret("apify")
+		Expect(err).To(MatchError(ErrSecretNotFound))
+		Expect(secrets.DeleteSecret("apify")).To(MatchError(ErrSecretNotFound))
+	})
+
+	It("should hold no secrets if the file does not exist", func() {
+		Expect(secrets.Names()).To(BeEmpty())
+		_, err := secrets.GetSecret("apify")
+		Expect(err).To(MatchError(ErrSecretNotFound))
+	})
+
+	It("should fail to read a file which is not sealed", func() {
+		Expect(os.WriteFile(path, []byte(`{"apify":"apify_api_abcdef"}`), 0600)).To(Succeed())
+		_, err := secrets.GetSecret("apify")
+		Expect(err).To(HaveOccurred())
+		Expect(err).NotTo(MatchError(ErrSecretNotFound))
+	})
+})